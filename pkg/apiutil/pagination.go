@@ -0,0 +1,252 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiutil
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// NextCursorHeader is the response header carrying the cursor of the next page.
+// It is absent when the current page is the last one.
+const NextCursorHeader = "PD-Next-Cursor"
+
+// Page describes the pagination and the field selection of a list request.
+// It is parsed from the `limit`, `cursor` and `fields` query parameters:
+//   - limit: the max number of the items in a page, 0 means no limit.
+//   - cursor: the opaque cursor returned by the previous page.
+//   - fields: the comma separated fields to keep in each item, nested fields
+//     are separated by dots, e.g. `fields=store.id,status.leader_count`.
+//
+// The items are ordered by a stable key before being paged, so the cursor
+// still works if the items before or after it are added or removed.
+type Page struct {
+	Limit  int
+	Fields []string
+
+	after      string
+	nextCursor string
+}
+
+// ParsePage parses the page from the query. The limit is capped at maxLimit
+// if maxLimit is positive.
+func ParsePage(query url.Values, maxLimit int) (*Page, error) {
+	p := &Page{}
+	if s := query.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 0 {
+			return nil, errs.ErrInvalidPage.FastGenByArgs(fmt.Sprintf("limit %q", s))
+		}
+		p.Limit = limit
+	}
+	if maxLimit > 0 && p.Limit > maxLimit {
+		p.Limit = maxLimit
+	}
+	if s := query.Get("cursor"); s != "" {
+		after, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return nil, errs.ErrInvalidPage.FastGenByArgs(fmt.Sprintf("cursor %q", s))
+		}
+		p.after = string(after)
+	}
+	for _, v := range query["fields"] {
+		for _, field := range strings.Split(v, ",") {
+			if field = strings.TrimSpace(field); field != "" {
+				p.Fields = append(p.Fields, field)
+			}
+		}
+	}
+	return p, nil
+}
+
+// IsPaged returns true if the request asks for a page rather than all items.
+func (p *Page) IsPaged() bool {
+	return p.Limit > 0 || p.after != ""
+}
+
+// Apply sorts the items by their keys and returns the bounds of the requested
+// page. The keys must be unique among the items, and swap is used to reorder
+// the items along with their keys.
+func (p *Page) Apply(keys []string, swap func(i, j int)) (start, end int) {
+	p.nextCursor = ""
+	n := len(keys)
+	if !p.IsPaged() {
+		return 0, n
+	}
+	sort.Sort(&stringKeyedItems{keys: keys, swap: swap})
+	start = sort.SearchStrings(keys, p.after)
+	if start < n && keys[start] == p.after {
+		start++
+	}
+	end = p.end(start, n)
+	if end < n {
+		p.nextCursor = base64.RawURLEncoding.EncodeToString([]byte(keys[end-1]))
+	}
+	return start, end
+}
+
+// ApplyIDs is like Apply, but the items are keyed by their unique IDs. It
+// returns an error if the cursor is not an ID.
+func (p *Page) ApplyIDs(ids []uint64, swap func(i, j int)) (start, end int, err error) {
+	p.nextCursor = ""
+	n := len(ids)
+	if !p.IsPaged() {
+		return 0, n, nil
+	}
+	sort.Sort(&idKeyedItems{ids: ids, swap: swap})
+	if p.after != "" {
+		after, err := strconv.ParseUint(p.after, 10, 64)
+		if err != nil {
+			return 0, 0, errs.ErrInvalidPage.FastGenByArgs(fmt.Sprintf("cursor %q", p.after))
+		}
+		start = sort.Search(n, func(i int) bool { return ids[i] > after })
+	}
+	end = p.end(start, n)
+	if end < n {
+		p.nextCursor = base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatUint(ids[end-1], 10)))
+	}
+	return start, end, nil
+}
+
+func (p *Page) end(start, n int) int {
+	if p.Limit > 0 && start+p.Limit < n {
+		return start + p.Limit
+	}
+	return n
+}
+
+// NextCursor returns the cursor of the next page, or empty if there is none.
+func (p *Page) NextCursor() string {
+	return p.nextCursor
+}
+
+// SetHeader writes the cursor of the next page to the response header.
+func (p *Page) SetHeader(w http.ResponseWriter) {
+	if p.nextCursor != "" {
+		w.Header().Set(NextCursorHeader, p.nextCursor)
+	}
+}
+
+// SelectFields returns a partial copy of v which only contains the selected
+// fields. If v is a slice, the fields are selected from each of its elements.
+// The values which are not JSON objects are left unchanged. v is returned as
+// it is if no fields are selected.
+func (p *Page) SelectFields(v interface{}) (interface{}, error) {
+	if len(p.Fields) == 0 {
+		return v, nil
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	mask := newFieldMask(p.Fields)
+	if items, ok := raw.([]interface{}); ok {
+		for i := range items {
+			items[i] = mask.apply(items[i])
+		}
+		return items, nil
+	}
+	return mask.apply(raw), nil
+}
+
+// fieldMask is a tree of the selected fields. A nil subtree keeps the whole
+// value of the field.
+type fieldMask map[string]fieldMask
+
+func newFieldMask(fields []string) fieldMask {
+	root := fieldMask{}
+	for _, field := range fields {
+		node := root
+		parts := strings.Split(field, ".")
+		for i, part := range parts {
+			child, ok := node[part]
+			if ok && child == nil {
+				// The parent field is selected as a whole.
+				break
+			}
+			if i == len(parts)-1 {
+				node[part] = nil
+				break
+			}
+			if !ok {
+				child = fieldMask{}
+				node[part] = child
+			}
+			node = child
+		}
+	}
+	return root
+}
+
+func (m fieldMask) apply(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		res := make(map[string]interface{}, len(m))
+		for name, sub := range m {
+			value, ok := v[name]
+			if !ok {
+				continue
+			}
+			if sub != nil {
+				value = sub.apply(value)
+			}
+			res[name] = value
+		}
+		return res
+	case []interface{}:
+		for i := range v {
+			v[i] = m.apply(v[i])
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+type stringKeyedItems struct {
+	keys []string
+	swap func(i, j int)
+}
+
+func (s *stringKeyedItems) Len() int           { return len(s.keys) }
+func (s *stringKeyedItems) Less(i, j int) bool { return s.keys[i] < s.keys[j] }
+func (s *stringKeyedItems) Swap(i, j int) {
+	s.keys[i], s.keys[j] = s.keys[j], s.keys[i]
+	s.swap(i, j)
+}
+
+type idKeyedItems struct {
+	ids  []uint64
+	swap func(i, j int)
+}
+
+func (s *idKeyedItems) Len() int           { return len(s.ids) }
+func (s *idKeyedItems) Less(i, j int) bool { return s.ids[i] < s.ids[j] }
+func (s *idKeyedItems) Swap(i, j int) {
+	s.ids[i], s.ids[j] = s.ids[j], s.ids[i]
+	s.swap(i, j)
+}
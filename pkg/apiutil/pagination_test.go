@@ -0,0 +1,143 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package apiutil
+
+import (
+	"encoding/base64"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPagination(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+
+	ids := []uint64{7, 3, 100, 1, 20}
+	pageOf := func(query string) (*Page, []uint64) {
+		values, err := url.ParseQuery(query)
+		re.NoError(err)
+		page, err := ParsePage(values, 10)
+		re.NoError(err)
+		items := append([]uint64(nil), ids...)
+		start, end, err := page.ApplyIDs(items, func(i, j int) {})
+		re.NoError(err)
+		return page, items[start:end]
+	}
+
+	// Not paged, the order is kept.
+	page, items := pageOf("")
+	re.False(page.IsPaged())
+	re.Equal(ids, items)
+	re.Empty(page.NextCursor())
+
+	page, items = pageOf("limit=2")
+	re.Equal([]uint64{1, 3}, items)
+	cursor := page.NextCursor()
+	re.NotEmpty(cursor)
+	page, items = pageOf("limit=2&cursor=" + cursor)
+	re.Equal([]uint64{7, 20}, items)
+	// The cursor is still valid after the items around it are changed.
+	ids = append(ids[1:], 5)
+	page, items = pageOf("limit=2&cursor=" + page.NextCursor())
+	re.Equal([]uint64{100}, items)
+	re.Empty(page.NextCursor())
+
+	// The limit is capped.
+	values, _ := url.ParseQuery("limit=100")
+	page, err := ParsePage(values, 10)
+	re.NoError(err)
+	re.Equal(10, page.Limit)
+	for _, query := range []string{"limit=-1", "limit=a", "cursor=%21%21"} {
+		values, _ = url.ParseQuery(query)
+		_, err = ParsePage(values, 10)
+		re.Error(err, query)
+	}
+	// The cursor of the IDs must be an ID.
+	values, _ = url.ParseQuery("cursor=" + base64.RawURLEncoding.EncodeToString([]byte("a")))
+	page, err = ParsePage(values, 10)
+	re.NoError(err)
+	_, _, err = page.ApplyIDs([]uint64{1}, func(i, j int) {})
+	re.Error(err)
+}
+
+func TestPaginationByKeys(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+
+	keys := []string{"b", "d", "a", "c"}
+	items := []int{1, 3, 0, 2}
+	values, err := url.ParseQuery("limit=3")
+	re.NoError(err)
+	page, err := ParsePage(values, 0)
+	re.NoError(err)
+	start, end := page.Apply(keys, func(i, j int) { items[i], items[j] = items[j], items[i] })
+	re.Equal([]string{"a", "b", "c", "d"}, keys)
+	re.Equal([]int{0, 1, 2}, items[start:end])
+
+	values, err = url.ParseQuery("limit=3&cursor=" + page.NextCursor())
+	re.NoError(err)
+	page, err = ParsePage(values, 0)
+	re.NoError(err)
+	start, end = page.Apply(keys, func(i, j int) { items[i], items[j] = items[j], items[i] })
+	re.Equal([]int{3}, items[start:end])
+	re.Empty(page.NextCursor())
+}
+
+func TestSelectFields(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+
+	type status struct {
+		LeaderCount int `json:"leader_count"`
+		RegionCount int `json:"region_count"`
+	}
+	type item struct {
+		ID     uint64  `json:"id"`
+		Name   string  `json:"name"`
+		Status *status `json:"status"`
+	}
+	items := []*item{
+		{ID: 1, Name: "a", Status: &status{LeaderCount: 1, RegionCount: 2}},
+		{ID: 2, Name: "b"},
+	}
+
+	page := &Page{}
+	v, err := page.SelectFields(items)
+	re.NoError(err)
+	re.Equal(items, v)
+
+	page.Fields = []string{"id", "status.leader_count", "unknown"}
+	v, err = page.SelectFields(items)
+	re.NoError(err)
+	re.Equal([]interface{}{
+		map[string]interface{}{"id": float64(1), "status": map[string]interface{}{"leader_count": float64(1)}},
+		map[string]interface{}{"id": float64(2), "status": nil},
+	}, v)
+
+	// The whole field is kept if both it and its sub field are selected.
+	page.Fields = []string{"status.leader_count", "status"}
+	v, err = page.SelectFields(items[0])
+	re.NoError(err)
+	re.Equal(map[string]interface{}{
+		"status": map[string]interface{}{"leader_count": float64(1), "region_count": float64(2)},
+	}, v)
+
+	// The values which are not objects are not changed.
+	v, err = page.SelectFields([]string{"a", "b"})
+	re.NoError(err)
+	re.Equal([]interface{}{"a", "b"}, v)
+}
@@ -16,6 +16,7 @@ package audit
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/pingcap/log"
 	"github.com/prometheus/client_golang/prometheus"
@@ -69,6 +70,23 @@ type Backend interface {
 	ProcessBeforeHandler() bool
 }
 
+// Event is an action taken by PD itself rather than an HTTP request, such as
+// bypassing a safety check.
+type Event struct {
+	Time       time.Time
+	Component  string
+	Action     string
+	Target     string
+	Attributes map[string]string
+}
+
+// EventBackend is an audit backend which can also record the events.
+type EventBackend interface {
+	Backend
+	// ProcessEvent is used to perform the audit process of an event
+	ProcessEvent(event *Event) bool
+}
+
 // PrometheusHistogramBackend is an implementation of audit.Backend
 // and it uses Prometheus histogram data type to implement audit.
 // Note: histogram.WithLabelValues will degrade performance.
@@ -126,3 +144,14 @@ func (l *LocalLogBackend) ProcessHTTPRequest(r *http.Request) bool {
 	log.Info("Audit Log", zap.String("service-info", requestInfo.String()))
 	return true
 }
+
+// ProcessEvent is used to implement audit.EventBackend
+func (l *LocalLogBackend) ProcessEvent(event *Event) bool {
+	log.Info("Audit Log",
+		zap.Time("time", event.Time),
+		zap.String("component", event.Component),
+		zap.String("action", event.Action),
+		zap.String("target", event.Target),
+		zap.Any("attributes", event.Attributes))
+	return true
+}
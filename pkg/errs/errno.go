@@ -45,6 +45,7 @@ var (
 var (
 	ErrEtcdLeaderNotFound = errors.Normalize("etcd leader not found", errors.RFCCodeText("PD:member:ErrEtcdLeaderNotFound"))
 	ErrMarshalLeader      = errors.Normalize("marshal leader failed", errors.RFCCodeText("PD:member:ErrMarshalLeader"))
+	ErrPreCampaignCheck   = errors.Normalize("pre-campaign check failed, %s", errors.RFCCodeText("PD:member:ErrPreCampaignCheck"))
 )
 
 // core errors
@@ -74,6 +75,7 @@ var (
 var (
 	ErrUnexpectedOperatorStatus = errors.Normalize("operator with unexpected status", errors.RFCCodeText("PD:schedule:ErrUnexpectedOperatorStatus"))
 	ErrUnknownOperatorStep      = errors.Normalize("unknown operator step found", errors.RFCCodeText("PD:schedule:ErrUnknownOperatorStep"))
+	ErrUnsupportedOperatorStep  = errors.Normalize("operator step %s is not supported, %s", errors.RFCCodeText("PD:schedule:ErrUnsupportedOperatorStep"))
 	ErrMergeOperator            = errors.Normalize("merge operator error, %s", errors.RFCCodeText("PD:schedule:ErrMergeOperator"))
 	ErrCreateOperator           = errors.Normalize("unable to create operator, %s", errors.RFCCodeText("PD:schedule:ErrCreateOperator"))
 )
@@ -88,20 +90,24 @@ var (
 	ErrCacheOverflow                    = errors.Normalize("cache overflow", errors.RFCCodeText("PD:scheduler:ErrCacheOverflow"))
 	ErrInternalGrowth                   = errors.Normalize("unknown interval growth type error", errors.RFCCodeText("PD:scheduler:ErrInternalGrowth"))
 	ErrSchedulerCreateFuncNotRegistered = errors.Normalize("create func of %v is not registered", errors.RFCCodeText("PD:scheduler:ErrSchedulerCreateFuncNotRegistered"))
+	ErrSchedulerPrerequisitesNotMet     = errors.Normalize("prerequisites of scheduler %s are not met, %s", errors.RFCCodeText("PD:scheduler:ErrSchedulerPrerequisitesNotMet"))
 )
 
 // checker errors
 var (
-	ErrCheckerNotFound   = errors.Normalize("checker not found", errors.RFCCodeText("PD:checker:ErrCheckerNotFound"))
-	ErrCheckerMergeAgain = errors.Normalize("region will be merged again, %s", errors.RFCCodeText("PD:checker:ErrCheckerMergeAgain"))
+	ErrCheckerNotFound    = errors.Normalize("checker not found", errors.RFCCodeText("PD:checker:ErrCheckerNotFound"))
+	ErrCheckerMergeAgain  = errors.Normalize("region will be merged again, %s", errors.RFCCodeText("PD:checker:ErrCheckerMergeAgain"))
+	ErrRegionNotMergeable = errors.Normalize("region %d can not be merged with region %d, %s", errors.RFCCodeText("PD:checker:ErrRegionNotMergeable"))
 )
 
 // placement errors
 var (
-	ErrRuleContent   = errors.Normalize("invalid rule content, %s", errors.RFCCodeText("PD:placement:ErrRuleContent"))
-	ErrLoadRule      = errors.Normalize("load rule failed", errors.RFCCodeText("PD:placement:ErrLoadRule"))
-	ErrLoadRuleGroup = errors.Normalize("load rule group failed", errors.RFCCodeText("PD:placement:ErrLoadRuleGroup"))
-	ErrBuildRuleList = errors.Normalize("build rule list failed, %s", errors.RFCCodeText("PD:placement:ErrBuildRuleList"))
+	ErrRuleContent          = errors.Normalize("invalid rule content, %s", errors.RFCCodeText("PD:placement:ErrRuleContent"))
+	ErrLoadRule             = errors.Normalize("load rule failed", errors.RFCCodeText("PD:placement:ErrLoadRule"))
+	ErrLoadRuleGroup        = errors.Normalize("load rule group failed", errors.RFCCodeText("PD:placement:ErrLoadRuleGroup"))
+	ErrBuildRuleList        = errors.Normalize("build rule list failed, %s", errors.RFCCodeText("PD:placement:ErrBuildRuleList"))
+	ErrReplicationMigration = errors.Normalize("replication mode migration failed, %s", errors.RFCCodeText("PD:placement:ErrReplicationMigration"))
+	ErrRuleShadowed         = errors.Normalize("rules are fully shadowed by override rules, %s", errors.RFCCodeText("PD:placement:ErrRuleShadowed"))
 )
 
 // region label errors
@@ -112,8 +118,27 @@ var (
 
 // cluster errors
 var (
-	ErrNotBootstrapped = errors.Normalize("TiKV cluster not bootstrapped, please start TiKV first", errors.RFCCodeText("PD:cluster:ErrNotBootstrapped"))
-	ErrStoreIsUp       = errors.Normalize("store is still up, please remove store gracefully", errors.RFCCodeText("PD:cluster:ErrStoreIsUp"))
+	ErrNotBootstrapped        = errors.Normalize("TiKV cluster not bootstrapped, please start TiKV first", errors.RFCCodeText("PD:cluster:ErrNotBootstrapped"))
+	ErrStoreIsUp              = errors.Normalize("store is still up, please remove store gracefully", errors.RFCCodeText("PD:cluster:ErrStoreIsUp"))
+	ErrRegionQuarantined      = errors.Normalize("heartbeat of region %d is quarantined, %s", errors.RFCCodeText("PD:cluster:ErrRegionQuarantined"))
+	ErrStoreNoteNotFound      = errors.Normalize("note %d of store %d not found", errors.RFCCodeText("PD:cluster:ErrStoreNoteNotFound"))
+	ErrStoreNoteContent       = errors.Normalize("invalid store note, %s", errors.RFCCodeText("PD:cluster:ErrStoreNoteContent"))
+	ErrStoreStateChange       = errors.Normalize("invalid state change of store %d, %s", errors.RFCCodeText("PD:cluster:ErrStoreStateChange"))
+	ErrStoreStatesViolateRule = errors.Normalize("can not change the store states since rule %s needs %d stores while only %d would be up", errors.RFCCodeText("PD:cluster:ErrStoreStatesViolateRule"))
+	ErrStoreStatesNoCapacity  = errors.Normalize("can not change the store states since the region size %dMiB of the removed stores exceeds the available size %dMiB of the up stores", errors.RFCCodeText("PD:cluster:ErrStoreStatesNoCapacity"))
+	ErrStoreVersionSkew       = errors.Normalize("version %s of store %d exceeds the max version skew %d, the versions of the up stores range from %s to %s", errors.RFCCodeText("PD:cluster:ErrStoreVersionSkew"))
+	ErrTaskNotFound           = errors.Normalize("task %d not found", errors.RFCCodeText("PD:cluster:ErrTaskNotFound"))
+	ErrTaskInvalid            = errors.Normalize("invalid %s task, %s", errors.RFCCodeText("PD:cluster:ErrTaskInvalid"))
+	ErrTaskState              = errors.Normalize("can not %s task %d in state %s", errors.RFCCodeText("PD:cluster:ErrTaskState"))
+	ErrTaskConflict           = errors.Normalize("can not start %s task, %s", errors.RFCCodeText("PD:cluster:ErrTaskConflict"))
+	ErrImportModeNotFound     = errors.Normalize("import mode %s not found", errors.RFCCodeText("PD:cluster:ErrImportModeNotFound"))
+	ErrImportModeInvalid      = errors.Normalize("invalid import mode, %s", errors.RFCCodeText("PD:cluster:ErrImportModeInvalid"))
+	ErrSubsystemStart         = errors.Normalize("subsystem %s fails to start, %s", errors.RFCCodeText("PD:cluster:ErrSubsystemStart"))
+	ErrRegionCacheDropToken   = errors.Normalize("invalid token to drop the region cache, %s", errors.RFCCodeText("PD:cluster:ErrRegionCacheDropToken"))
+	ErrRegionCacheDropLimited = errors.Normalize("dropping the region cache is rate limited", errors.RFCCodeText("PD:cluster:ErrRegionCacheDropLimited"))
+	ErrBreakGlassEnabled      = errors.Normalize("break-glass mode is already enabled until %s", errors.RFCCodeText("PD:cluster:ErrBreakGlassEnabled"))
+	ErrBreakGlassNotEnabled   = errors.Normalize("break-glass mode is not enabled", errors.RFCCodeText("PD:cluster:ErrBreakGlassNotEnabled"))
+	ErrBreakGlassInvalid      = errors.Normalize("invalid break-glass mode, %s", errors.RFCCodeText("PD:cluster:ErrBreakGlassInvalid"))
 )
 
 // versioninfo errors
@@ -135,6 +160,7 @@ var (
 var (
 	ErrRedirect       = errors.Normalize("redirect failed", errors.RFCCodeText("PD:apiutil:ErrRedirect"))
 	ErrOptionNotExist = errors.Normalize("the option %s does not exist", errors.RFCCodeText("PD:apiutil:ErrOptionNotExist"))
+	ErrInvalidPage    = errors.Normalize("invalid pagination parameter, %s", errors.RFCCodeText("PD:apiutil:ErrInvalidPage"))
 )
 
 // grpcutil errors
@@ -144,13 +170,23 @@ var (
 
 // server errors
 var (
-	ErrServiceRegistered     = errors.Normalize("service with path [%s] already registered", errors.RFCCodeText("PD:server:ErrServiceRegistered"))
-	ErrAPIInformationInvalid = errors.Normalize("invalid api information, group %s version %s", errors.RFCCodeText("PD:server:ErrAPIInformationInvalid"))
-	ErrClientURLEmpty        = errors.Normalize("client url empty", errors.RFCCodeText("PD:server:ErrClientEmpty"))
-	ErrLeaderNil             = errors.Normalize("leader is nil", errors.RFCCodeText("PD:server:ErrLeaderNil"))
-	ErrCancelStartEtcd       = errors.Normalize("etcd start canceled", errors.RFCCodeText("PD:server:ErrCancelStartEtcd"))
-	ErrConfigItem            = errors.Normalize("cannot set invalid configuration", errors.RFCCodeText("PD:server:ErrConfiguration"))
-	ErrServerNotStarted      = errors.Normalize("server not started", errors.RFCCodeText("PD:server:ErrServerNotStarted"))
+	ErrServiceRegistered        = errors.Normalize("service with path [%s] already registered", errors.RFCCodeText("PD:server:ErrServiceRegistered"))
+	ErrAPIInformationInvalid    = errors.Normalize("invalid api information, group %s version %s", errors.RFCCodeText("PD:server:ErrAPIInformationInvalid"))
+	ErrClientURLEmpty           = errors.Normalize("client url empty", errors.RFCCodeText("PD:server:ErrClientEmpty"))
+	ErrLeaderNil                = errors.Normalize("leader is nil", errors.RFCCodeText("PD:server:ErrLeaderNil"))
+	ErrCancelStartEtcd          = errors.Normalize("etcd start canceled", errors.RFCCodeText("PD:server:ErrCancelStartEtcd"))
+	ErrConfigItem               = errors.Normalize("cannot set invalid configuration", errors.RFCCodeText("PD:server:ErrConfiguration"))
+	ErrServerNotStarted         = errors.Normalize("server not started", errors.RFCCodeText("PD:server:ErrServerNotStarted"))
+	ErrConfigWatchKind          = errors.Normalize("unknown config kind %s", errors.RFCCodeText("PD:server:ErrConfigWatchKind"))
+	ErrPersistFileName          = errors.Normalize("invalid persist file name %s", errors.RFCCodeText("PD:server:ErrPersistFileName"))
+	ErrPersistFileTooLarge      = errors.Normalize("the size of the persist file %d exceeds the limit %d", errors.RFCCodeText("PD:server:ErrPersistFileTooLarge"))
+	ErrListPersistFiles         = errors.Normalize("failed to list the persist files", errors.RFCCodeText("PD:server:ErrListPersistFiles"))
+	ErrOperatorTemplateNotFound = errors.Normalize("operator template %s not found", errors.RFCCodeText("PD:server:ErrOperatorTemplateNotFound"))
+	ErrOperatorTemplateInvalid  = errors.Normalize("invalid operator template %s, %s", errors.RFCCodeText("PD:server:ErrOperatorTemplateInvalid"))
+	ErrOperatorTemplateParam    = errors.Normalize("invalid parameter %s of operator template %s, %s", errors.RFCCodeText("PD:server:ErrOperatorTemplateParam"))
+	ErrBootstrapBundleNotFound  = errors.Normalize("bootstrap bundle not found", errors.RFCCodeText("PD:server:ErrBootstrapBundleNotFound"))
+	ErrBootstrapBundleInvalid   = errors.Normalize("invalid bootstrap bundle, %s", errors.RFCCodeText("PD:server:ErrBootstrapBundleInvalid"))
+	ErrAlreadyBootstrapped      = errors.Normalize("the cluster is already bootstrapped", errors.RFCCodeText("PD:server:ErrAlreadyBootstrapped"))
 )
 
 // logutil errors
@@ -284,6 +320,18 @@ var (
 	ErrLevelDBOpen  = errors.Normalize("leveldb open file error", errors.RFCCodeText("PD:leveldb:ErrLevelDBOpen"))
 )
 
+// region syncer errors
+var (
+	ErrRegionSyncerFollowerNotFound = errors.Normalize("server %s is not synchronizing with the region syncer", errors.RFCCodeText("PD:syncer:ErrRegionSyncerFollowerNotFound"))
+	ErrRegionSyncerFollowerMissed   = errors.Normalize("server %s has missed the history from index %d", errors.RFCCodeText("PD:syncer:ErrRegionSyncerFollowerMissed"))
+)
+
+// region storage migration errors
+var (
+	ErrRegionStorageUnavailable = errors.Normalize("the independent region storage is unavailable", errors.RFCCodeText("PD:storage:ErrRegionStorageUnavailable"))
+	ErrRegionStorageMigration   = errors.Normalize("region storage migration error, %s", errors.RFCCodeText("PD:storage:ErrRegionStorageMigration"))
+)
+
 // semver
 var (
 	ErrSemverNewVersion = errors.Normalize("new version error", errors.RFCCodeText("PD:semver:ErrSemverNewVersion"))
@@ -0,0 +1,55 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package grpcutil
+
+import (
+	"compress/gzip"
+	"io"
+
+	"google.golang.org/grpc/encoding"
+)
+
+// GzipCompressorName is the name of the gzip compressor, which is carried by
+// the streams compressed by gzip.
+const GzipCompressorName = "gzip"
+
+// gzipCompressor implements encoding.Compressor with the compression level
+// returned by the given function, so that the level can be adjusted at runtime.
+type gzipCompressor struct {
+	level func() int
+}
+
+// RegisterGzipCompressor registers the gzip compressor, so that the clients are
+// able to compress the messages with gzip, and the server responds in the same
+// encoding. It should be called before the server starts. Whether a client is
+// allowed to use it is checked by the server.
+func RegisterGzipCompressor(level func() int) {
+	encoding.RegisterCompressor(&gzipCompressor{level: level})
+}
+
+// Compress implements encoding.Compressor.
+func (c *gzipCompressor) Compress(w io.Writer) (io.WriteCloser, error) {
+	return gzip.NewWriterLevel(w, c.level())
+}
+
+// Decompress implements encoding.Compressor.
+func (c *gzipCompressor) Decompress(r io.Reader) (io.Reader, error) {
+	return gzip.NewReader(r)
+}
+
+// Name implements encoding.Compressor.
+func (c *gzipCompressor) Name() string {
+	return GzipCompressorName
+}
@@ -31,6 +31,25 @@ import (
 // ForwardMetadataKey is used to record the forwarded host of PD.
 const ForwardMetadataKey = "pd-forwarded-host"
 
+// StoreTokenMetadataKey is used to carry the token presented by a store.
+const StoreTokenMetadataKey = "pd-store-token"
+
+// HeartbeatShardMetadataKey is used to mark a region heartbeat stream as one of
+// multiple concurrent streams opened by the same store.
+const HeartbeatShardMetadataKey = "pd-heartbeat-shard"
+
+// StoreTopologyMetadataKey is used to carry the hardware topology of a store
+// encoded in JSON with its heartbeats.
+const StoreTopologyMetadataKey = "pd-store-topology"
+
+// The classes of the clients, which are decided by the methods of the streams
+// on the server side.
+const (
+	ClientClassStore = "store"
+	ClientClassSQL   = "sql"
+	ClientClassTool  = "tool"
+)
+
 // TLSConfig is the configuration for supporting tls.
 type TLSConfig struct {
 	// CAPath is the path of file that contains list of trusted SSL CAs. if set, following four settings shouldn't be empty
@@ -1,12 +1,16 @@
 package grpcutil
 
 import (
+	"bytes"
+	"compress/gzip"
+	"io"
 	"os"
 	"testing"
 
 	"github.com/pingcap/errors"
 	"github.com/stretchr/testify/require"
 	"github.com/tikv/pd/pkg/errs"
+	"google.golang.org/grpc/encoding"
 )
 
 func loadTLSContent(re *require.Assertions, caPath, certPath, keyPath string) (caData, certData, keyData []byte) {
@@ -51,3 +55,32 @@ func TestToTLSConfig(t *testing.T) {
 	_, err = tlsConfig.ToTLSConfig()
 	re.True(errors.ErrorEqual(err, errs.ErrCryptoAppendCertsFromPEM))
 }
+
+func TestGzipCompressor(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	level := gzip.BestSpeed
+	RegisterGzipCompressor(func() int { return level })
+	compressor := encoding.GetCompressor(GzipCompressorName)
+	re.NotNil(compressor)
+
+	data := bytes.Repeat([]byte("pd"), 1024)
+	var buf bytes.Buffer
+	w, err := compressor.Compress(&buf)
+	re.NoError(err)
+	_, err = w.Write(data)
+	re.NoError(err)
+	re.NoError(w.Close())
+	re.Less(buf.Len(), len(data))
+
+	r, err := compressor.Decompress(&buf)
+	re.NoError(err)
+	decompressed, err := io.ReadAll(r)
+	re.NoError(err)
+	re.Equal(data, decompressed)
+
+	// the level is read when compressing.
+	level = 10
+	_, err = compressor.Compress(&buf)
+	re.Error(err)
+}
@@ -15,6 +15,7 @@
 package jsonutil
 
 import (
+	"encoding/json"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -63,3 +64,101 @@ func TestJSONUtil(t *testing.T) {
 	re.False(update)
 	re.True(found)
 }
+
+func TestMergePatch(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	doc := []byte(`{"a":"b","c":{"d":"e","f":"g"},"h":[1,2]}`)
+	patched, err := MergePatch(doc, []byte(`{"a":"z","c":{"f":null},"h":[3],"i":{"j":1}}`))
+	re.NoError(err)
+	re.JSONEq(`{"a":"z","c":{"d":"e"},"h":[3],"i":{"j":1}}`, string(patched))
+	// A patch which is not an object replaces the whole document.
+	patched, err = MergePatch(doc, []byte(`["a"]`))
+	re.NoError(err)
+	re.JSONEq(`["a"]`, string(patched))
+	_, err = MergePatch(doc, []byte(`{`))
+	re.Error(err)
+}
+
+func TestApplyPatch(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	doc := []byte(`{"a":{"b":1,"c/d":2,"e~f":3},"g":[1,2,3]}`)
+	testCases := []struct {
+		ops      string
+		expected string
+	}{
+		{`[{"op":"add","path":"/a/h","value":4}]`, `{"a":{"b":1,"c/d":2,"e~f":3,"h":4},"g":[1,2,3]}`},
+		{`[{"op":"add","path":"/g/1","value":4},{"op":"add","path":"/g/-","value":5}]`, `{"a":{"b":1,"c/d":2,"e~f":3},"g":[1,4,2,3,5]}`},
+		{`[{"op":"remove","path":"/a/c~1d"},{"op":"remove","path":"/g/0"}]`, `{"a":{"b":1,"e~f":3},"g":[2,3]}`},
+		{`[{"op":"replace","path":"/a/e~0f","value":null}]`, `{"a":{"b":1,"c/d":2,"e~f":null},"g":[1,2,3]}`},
+		{`[{"op":"move","from":"/a/b","path":"/b"}]`, `{"a":{"c/d":2,"e~f":3},"b":1,"g":[1,2,3]}`},
+		{`[{"op":"copy","from":"/g","path":"/a/g"}]`, `{"a":{"b":1,"c/d":2,"e~f":3,"g":[1,2,3]},"g":[1,2,3]}`},
+		{`[{"op":"test","path":"/g","value":[1,2,3]},{"op":"replace","path":"","value":{}}]`, `{}`},
+	}
+	for _, testCase := range testCases {
+		var ops []PatchOperation
+		re.NoError(json.Unmarshal([]byte(testCase.ops), &ops))
+		patched, err := ApplyPatch(doc, ops)
+		re.NoError(err, testCase.ops)
+		re.JSONEq(testCase.expected, string(patched), testCase.ops)
+	}
+
+	for _, ops := range []string{
+		`[{"op":"add","path":"/x/y","value":1}]`,
+		`[{"op":"add","path":"/g/4","value":1}]`,
+		`[{"op":"add","path":"a","value":1}]`,
+		`[{"op":"add","path":"/a/b"}]`,
+		`[{"op":"remove","path":"/a/x"}]`,
+		`[{"op":"remove","path":"/g/-"}]`,
+		`[{"op":"replace","path":"/g/01","value":1}]`,
+		`[{"op":"move","from":"/a","path":"/a/b"}]`,
+		`[{"op":"add","path":"/a/h","value":4},{"op":"test","path":"/a/b","value":2}]`,
+		`[{"op":"unknown","path":"/a"}]`,
+	} {
+		var patch []PatchOperation
+		re.NoError(json.Unmarshal([]byte(ops), &patch))
+		_, err := ApplyPatch(doc, patch)
+		re.Error(err, ops)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	changes, err := Diff([]byte(`{"a":1,"b":{"c":"d","e/f":[1]},"g":true}`), []byte(`{"a":2,"b":{"c":"d","e/f":[1,2]},"h":null}`))
+	re.NoError(err)
+	re.Equal([]Change{
+		{Path: "/a", Old: float64(1), New: float64(2)},
+		{Path: "/b/e~1f", Old: []interface{}{float64(1)}, New: []interface{}{float64(1), float64(2)}},
+		{Path: "/g", Old: true, New: nil},
+	}, changes)
+
+	var v testJSONStructLevel2
+	re.NoError(UnmarshalStrict([]byte(`{"sub-name":"a"}`), &v))
+	re.Equal("a", v.SubName)
+	re.Error(UnmarshalStrict([]byte(`{"sub-name":"a","x":1}`), &v))
+}
+
+type testRemovedFieldsStruct struct {
+	A   int                                `json:"a"`
+	B   *testRemovedFieldsStruct           `json:"b,omitempty"`
+	M   map[string]testRemovedFieldsStruct `json:"m"`
+	Arr []int                              `json:"arr"`
+	Any interface{}                        `json:"any"`
+}
+
+func TestRemovedFields(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	old := []byte(`{"a":1,"b":{"a":2,"m":{}},"m":{"x":{"a":3},"y":{"a":4}},"arr":[1],"any":{"k":1}}`)
+	// The entries of the maps, the elements of the arrays and the members of
+	// the dynamic values can be removed.
+	removed, err := RemovedFields(&testRemovedFieldsStruct{}, old, []byte(`{"a":1,"b":{"a":2,"m":{}},"m":{"x":{"a":3}},"arr":[],"any":{}}`))
+	re.NoError(err)
+	re.Empty(removed)
+	// The fields of the structs can't be removed or set to null.
+	removed, err = RemovedFields(&testRemovedFieldsStruct{}, old, []byte(`{"b":{"m":null},"m":{"x":{},"y":{"a":null}},"arr":[1],"any":null}`))
+	re.NoError(err)
+	re.Equal([]string{"/a", "/any", "/b/a", "/b/m", "/m/x/a", "/m/y/a"}, removed)
+}
@@ -0,0 +1,436 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package jsonutil
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+// The operations of the JSON patch.
+const (
+	PatchOpAdd     = "add"
+	PatchOpRemove  = "remove"
+	PatchOpReplace = "replace"
+	PatchOpMove    = "move"
+	PatchOpCopy    = "copy"
+	PatchOpTest    = "test"
+)
+
+// PatchOperation is an operation of the JSON patch (RFC 6902), whose paths
+// are JSON pointers (RFC 6901).
+type PatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// Change is a value changed between two JSON documents.
+type Change struct {
+	// Path is the JSON pointer to the value.
+	Path string      `json:"path"`
+	Old  interface{} `json:"old"`
+	New  interface{} `json:"new"`
+}
+
+var pointerUnescaper = strings.NewReplacer("~1", "/", "~0", "~")
+var pointerEscaper = strings.NewReplacer("~", "~0", "/", "~1")
+
+// MergePatch applies the JSON merge patch (RFC 7386) to the document.
+func MergePatch(doc, patch []byte) ([]byte, error) {
+	var target, p interface{}
+	if err := json.Unmarshal(doc, &target); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(patch, &p); err != nil {
+		return nil, err
+	}
+	return json.Marshal(mergePatch(target, p))
+}
+
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for k, v := range p {
+		if v == nil {
+			delete(t, k)
+			continue
+		}
+		t[k] = mergePatch(t[k], v)
+	}
+	return t
+}
+
+// ApplyPatch applies the operations of the JSON patch to the document in
+// order. No operation takes effect if any of them fails.
+func ApplyPatch(doc []byte, ops []PatchOperation) ([]byte, error) {
+	var root interface{}
+	if err := json.Unmarshal(doc, &root); err != nil {
+		return nil, err
+	}
+	for i, op := range ops {
+		var err error
+		if root, err = applyPatchOperation(root, op); err != nil {
+			return nil, errors.Errorf("failed to apply the operation %d, %v", i, err)
+		}
+	}
+	return json.Marshal(root)
+}
+
+func applyPatchOperation(root interface{}, op PatchOperation) (interface{}, error) {
+	path, err := parsePointer(op.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch op.Op {
+	case PatchOpAdd, PatchOpReplace, PatchOpTest:
+		if op.Value == nil {
+			return nil, errors.Errorf("the value of %s is missing", op.Op)
+		}
+		var value interface{}
+		if err := json.Unmarshal(op.Value, &value); err != nil {
+			return nil, err
+		}
+		switch op.Op {
+		case PatchOpAdd:
+			return addValue(root, path, value)
+		case PatchOpReplace:
+			if len(path) == 0 {
+				return value, nil
+			}
+			if root, err = removeValue(root, path); err != nil {
+				return nil, err
+			}
+			return addValue(root, path, value)
+		default:
+			current, err := getValue(root, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, errors.Errorf("the value at %s is not %s", op.Path, op.Value)
+			}
+			return root, nil
+		}
+	case PatchOpRemove:
+		return removeValue(root, path)
+	case PatchOpMove, PatchOpCopy:
+		from, err := parsePointer(op.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := getValue(root, from)
+		if err != nil {
+			return nil, err
+		}
+		if op.Op == PatchOpMove {
+			if strings.HasPrefix(op.Path, op.From+"/") {
+				return nil, errors.Errorf("cannot move %s into its child %s", op.From, op.Path)
+			}
+			if root, err = removeValue(root, from); err != nil {
+				return nil, err
+			}
+		} else if value, err = deepCopy(value); err != nil {
+			return nil, err
+		}
+		return addValue(root, path, value)
+	}
+	return nil, errors.Errorf("unknown operation %q", op.Op)
+}
+
+// Diff returns the values changed from the old document to the new one,
+// ordered by the path. The arrays are compared as a whole.
+func Diff(old, new []byte) ([]Change, error) {
+	var o, n interface{}
+	if err := json.Unmarshal(old, &o); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(new, &n); err != nil {
+		return nil, err
+	}
+	return diffValue("", o, n, nil), nil
+}
+
+func diffValue(path string, old, new interface{}, changes []Change) []Change {
+	o, ok1 := old.(map[string]interface{})
+	n, ok2 := new.(map[string]interface{})
+	if !ok1 || !ok2 {
+		if !reflect.DeepEqual(old, new) {
+			changes = append(changes, Change{Path: path, Old: old, New: new})
+		}
+		return changes
+	}
+	keys := make([]string, 0, len(o)+len(n))
+	for k := range o {
+		keys = append(keys, k)
+	}
+	for k := range n {
+		if _, ok := o[k]; !ok {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		changes = diffValue(path+"/"+pointerEscaper.Replace(k), o[k], n[k], changes)
+	}
+	return changes
+}
+
+// RemovedFields returns the JSON pointers of the fields of v, which is a struct
+// or a pointer to it, that are in the old document but removed or set to null
+// in the new one, ordered by the path. Decoding the new document would reset
+// them to the zero values silently. The entries of the maps can be removed,
+// while the fields of the structs in them can't.
+func RemovedFields(v interface{}, old, new []byte) ([]string, error) {
+	var o, n interface{}
+	if err := json.Unmarshal(old, &o); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(new, &n); err != nil {
+		return nil, err
+	}
+	removed := removedFields(reflect.TypeOf(v), "", o, n, nil)
+	sort.Strings(removed)
+	return removed, nil
+}
+
+func removedFields(typ reflect.Type, path string, old, new interface{}, removed []string) []string {
+	for typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	o, ok1 := old.(map[string]interface{})
+	n, ok2 := new.(map[string]interface{})
+	if !ok1 || !ok2 {
+		return removed
+	}
+	switch typ.Kind() {
+	case reflect.Struct:
+		for name, fieldType := range jsonFields(typ) {
+			ov, ok := o[name]
+			if !ok || ov == nil {
+				continue
+			}
+			fieldPath := path + "/" + pointerEscaper.Replace(name)
+			nv, ok := n[name]
+			if !ok || nv == nil {
+				removed = append(removed, fieldPath)
+				continue
+			}
+			removed = removedFields(fieldType, fieldPath, ov, nv, removed)
+		}
+	case reflect.Map:
+		for k, ov := range o {
+			if nv, ok := n[k]; ok {
+				removed = removedFields(typ.Elem(), path+"/"+pointerEscaper.Replace(k), ov, nv, removed)
+			}
+		}
+	}
+	return removed
+}
+
+// jsonFields returns the types of the fields of the struct by their JSON names,
+// including the ones of the embedded structs.
+func jsonFields(typ reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Ptr {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				for k, v := range jsonFields(embedded) {
+					fields[k] = v
+				}
+				continue
+			}
+		}
+		if field.PkgPath != "" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+// UnmarshalStrict is like json.Unmarshal, but it rejects the unknown fields.
+func UnmarshalStrict(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+func parsePointer(pointer string) ([]string, error) {
+	if pointer == "" {
+		return nil, nil
+	}
+	if pointer[0] != '/' {
+		return nil, errors.Errorf("invalid JSON pointer %q", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, token := range tokens {
+		tokens[i] = pointerUnescaper.Replace(token)
+	}
+	return tokens, nil
+}
+
+// parseArrayIndex parses the index of an array with the given length. The
+// index can be the length, which is written as "-", only if it is for adding.
+func parseArrayIndex(token string, length int, adding bool) (int, error) {
+	if token == "-" && adding {
+		return length, nil
+	}
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || (len(token) > 1 && token[0] == '0') {
+		return 0, errors.Errorf("invalid array index %q", token)
+	}
+	if index > length || (index == length && !adding) {
+		return 0, errors.Errorf("array index %d out of range", index)
+	}
+	return index, nil
+}
+
+func getValue(node interface{}, path []string) (interface{}, error) {
+	for _, token := range path {
+		switch n := node.(type) {
+		case map[string]interface{}:
+			child, ok := n[token]
+			if !ok {
+				return nil, errors.Errorf("member %q not found", token)
+			}
+			node = child
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(n), false)
+			if err != nil {
+				return nil, err
+			}
+			node = n[index]
+		default:
+			return nil, errors.Errorf("cannot find %q in a scalar value", token)
+		}
+	}
+	return node, nil
+}
+
+// updateParent calls f with the parent of the value at the path, and the
+// parent is replaced by the result of f.
+func updateParent(node interface{}, path []string, f func(parent interface{}, token string) (interface{}, error)) (interface{}, error) {
+	if len(path) == 1 {
+		return f(node, path[0])
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[path[0]]
+		if !ok {
+			return nil, errors.Errorf("member %q not found", path[0])
+		}
+		child, err := updateParent(child, path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = child
+		return n, nil
+	case []interface{}:
+		index, err := parseArrayIndex(path[0], len(n), false)
+		if err != nil {
+			return nil, err
+		}
+		child, err := updateParent(n[index], path[1:], f)
+		if err != nil {
+			return nil, err
+		}
+		n[index] = child
+		return n, nil
+	}
+	return nil, errors.Errorf("cannot find %q in a scalar value", path[0])
+}
+
+func addValue(root interface{}, path []string, value interface{}) (interface{}, error) {
+	if len(path) == 0 {
+		return value, nil
+	}
+	return updateParent(root, path, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			p[token] = value
+			return p, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(p), true)
+			if err != nil {
+				return nil, err
+			}
+			p = append(p, nil)
+			copy(p[index+1:], p[index:])
+			p[index] = value
+			return p, nil
+		}
+		return nil, errors.Errorf("cannot add %q to a scalar value", token)
+	})
+}
+
+func removeValue(root interface{}, path []string) (interface{}, error) {
+	if len(path) == 0 {
+		return nil, errors.New("cannot remove the whole document")
+	}
+	return updateParent(root, path, func(parent interface{}, token string) (interface{}, error) {
+		switch p := parent.(type) {
+		case map[string]interface{}:
+			if _, ok := p[token]; !ok {
+				return nil, errors.Errorf("member %q not found", token)
+			}
+			delete(p, token)
+			return p, nil
+		case []interface{}:
+			index, err := parseArrayIndex(token, len(p), false)
+			if err != nil {
+				return nil, err
+			}
+			return append(p[:index], p[index+1:]...), nil
+		}
+		return nil, errors.Errorf("cannot find %q in a scalar value", token)
+	})
+}
+
+func deepCopy(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
@@ -0,0 +1,142 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricutil
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// The granularities of the store labels of the controlled metric families.
+const (
+	// LabelGranularityStore keeps a series for each store.
+	LabelGranularityStore = "store"
+	// LabelGranularityAggregated aggregates the series of all the stores into one.
+	LabelGranularityAggregated = "aggregated"
+)
+
+// AggregatedStoreLabel is the value of the store label of the aggregated series.
+const AggregatedStoreLabel = "all"
+
+// cardinality controls the number of the series of the metric families with
+// the store labels, which grows with the cluster and may overload Prometheus.
+var cardinality = struct {
+	sync.RWMutex
+	// families are the reset functions of the controlled families.
+	families   map[string]func()
+	aggregated bool
+	disabled   map[string]struct{}
+}{
+	families: make(map[string]func()),
+	disabled: make(map[string]struct{}),
+}
+
+// RegisterControlledFamily registers a metric family whose cardinality is
+// controlled. The reset function drops all the series of the family, it is
+// called when the family is disabled or the granularity is changed.
+func RegisterControlledFamily(name string, reset func()) {
+	cardinality.Lock()
+	defer cardinality.Unlock()
+	cardinality.families[name] = reset
+}
+
+// ApplyCardinality applies the label granularity and the disabled families.
+// The series of the affected families are dropped.
+func ApplyCardinality(granularity string, disabled []string) {
+	aggregated := granularity == LabelGranularityAggregated
+	newDisabled := make(map[string]struct{}, len(disabled))
+	for _, name := range disabled {
+		newDisabled[name] = struct{}{}
+	}
+	cardinality.Lock()
+	defer cardinality.Unlock()
+	for name, reset := range cardinality.families {
+		_, wasDisabled := cardinality.disabled[name]
+		_, isDisabled := newDisabled[name]
+		if aggregated != cardinality.aggregated || (isDisabled && !wasDisabled) {
+			reset()
+		}
+	}
+	cardinality.aggregated = aggregated
+	cardinality.disabled = newDisabled
+}
+
+// IsAggregated returns true if the series of all the stores are aggregated into one.
+func IsAggregated() bool {
+	cardinality.RLock()
+	defer cardinality.RUnlock()
+	return cardinality.aggregated
+}
+
+// IsFamilyDisabled returns true if the metric family is disabled.
+func IsFamilyDisabled(name string) bool {
+	cardinality.RLock()
+	defer cardinality.RUnlock()
+	_, ok := cardinality.disabled[name]
+	return ok
+}
+
+// FamilyCardinality is the number of the series of a metric family.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type FamilyCardinality struct {
+	Name   string `json:"name"`
+	Series int    `json:"series"`
+	// Controlled is true if the label granularity and the switch of the family
+	// are controlled by the configuration.
+	Controlled bool `json:"controlled"`
+	Disabled   bool `json:"disabled"`
+}
+
+// GetCardinality returns the number of the series of each metric family
+// gathered, sorted by the number in descending order. The disabled families
+// without any series are included too.
+func GetCardinality(gatherer prometheus.Gatherer) ([]*FamilyCardinality, error) {
+	mfs, err := gatherer.Gather()
+	if err != nil {
+		return nil, err
+	}
+	cardinality.RLock()
+	defer cardinality.RUnlock()
+	result := make([]*FamilyCardinality, 0, len(mfs))
+	gathered := make(map[string]struct{}, len(mfs))
+	for _, mf := range mfs {
+		name := mf.GetName()
+		_, controlled := cardinality.families[name]
+		_, disabled := cardinality.disabled[name]
+		result = append(result, &FamilyCardinality{
+			Name:       name,
+			Series:     len(mf.GetMetric()),
+			Controlled: controlled,
+			Disabled:   disabled,
+		})
+		gathered[name] = struct{}{}
+	}
+	for name := range cardinality.families {
+		if _, ok := gathered[name]; ok {
+			continue
+		}
+		_, disabled := cardinality.disabled[name]
+		result = append(result, &FamilyCardinality{Name: name, Controlled: true, Disabled: disabled})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Series != result[j].Series {
+			return result[i].Series > result[j].Series
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package metricutil
+
+import (
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCardinality(t *testing.T) {
+	re := require.New(t)
+	registry := prometheus.NewRegistry()
+	gauge := prometheus.NewGaugeVec(prometheus.GaugeOpts{Name: "test_store_status"}, []string{"store"})
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Name: "test_counter"})
+	registry.MustRegister(gauge, counter)
+	RegisterControlledFamily("test_store_status", gauge.Reset)
+	defer func() {
+		ApplyCardinality(LabelGranularityStore, nil)
+		cardinality.Lock()
+		delete(cardinality.families, "test_store_status")
+		cardinality.Unlock()
+	}()
+
+	counter.Inc()
+	for _, store := range []string{"1", "2", "3"} {
+		gauge.WithLabelValues(store).Set(1)
+	}
+	families, err := GetCardinality(registry)
+	re.NoError(err)
+	re.Equal([]*FamilyCardinality{
+		{Name: "test_store_status", Series: 3, Controlled: true},
+		{Name: "test_counter", Series: 1},
+	}, families)
+
+	// Nothing is dropped if the settings are not changed.
+	ApplyCardinality(LabelGranularityStore, nil)
+	re.False(IsAggregated())
+	families, err = GetCardinality(registry)
+	re.NoError(err)
+	re.Equal(3, families[0].Series)
+
+	// The series of the stores are dropped after switching to the aggregated granularity.
+	ApplyCardinality(LabelGranularityAggregated, nil)
+	re.True(IsAggregated())
+	families, err = GetCardinality(registry)
+	re.NoError(err)
+	re.Len(families, 2)
+	re.Equal(FamilyCardinality{Name: "test_store_status", Controlled: true}, *families[1])
+	gauge.WithLabelValues(AggregatedStoreLabel).Set(3)
+
+	// The disabled family is dropped too.
+	ApplyCardinality(LabelGranularityAggregated, []string{"test_store_status"})
+	re.True(IsFamilyDisabled("test_store_status"))
+	re.False(IsFamilyDisabled("test_counter"))
+	families, err = GetCardinality(registry)
+	re.NoError(err)
+	re.Equal(FamilyCardinality{Name: "test_store_status", Controlled: true, Disabled: true}, *families[1])
+}
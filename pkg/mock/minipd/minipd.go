@@ -0,0 +1,365 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package minipd provides a single-process PD for the scheduler developers. It
+// runs the checkers and schedulers against a mock cluster in the same way as the
+// coordinator, applies the operators step by step and advances a simulated
+// clock, so a scheduler can be tested without starting a PD cluster.
+package minipd
+
+import (
+	"bytes"
+	"context"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/checker"
+	"github.com/tikv/pd/server/schedule/hbstream"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/storage"
+
+	// Register the built-in schedulers.
+	_ "github.com/tikv/pd/server/schedulers"
+)
+
+// maxOperatorSteps is the max number of steps to apply in a round of RunUntilStable,
+// which is enough for any operator to finish.
+const maxOperatorSteps = 16
+
+// MiniPD is a single-process PD with a mock cluster. It is not thread-safe.
+type MiniPD struct {
+	ctx          context.Context
+	cancel       context.CancelFunc
+	cluster      *mockcluster.Cluster
+	storage      storage.Storage
+	opController *schedule.OperatorController
+	checkers     *checker.Controller
+	schedulers   []schedule.Scheduler
+	// now is the simulated time, which only moves on Advance.
+	now time.Time
+	// stopped are the stores which do not heartbeat.
+	stopped map[uint64]struct{}
+}
+
+// New creates a MiniPD with the given options. The default test options are
+// used if opts is nil.
+func New(ctx context.Context, opts *config.PersistOptions) *MiniPD {
+	if opts == nil {
+		opts = config.NewTestOptions()
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	cluster := mockcluster.NewCluster(ctx, opts)
+	// The stream runs to drain the messages, which are dropped as no store is bound.
+	stream := hbstream.NewTestHeartbeatStreams(ctx, cluster.ID, cluster, true)
+	opController := schedule.NewOperatorController(ctx, cluster, stream)
+	return &MiniPD{
+		ctx:          ctx,
+		cancel:       cancel,
+		cluster:      cluster,
+		storage:      storage.NewStorageWithMemoryBackend(),
+		opController: opController,
+		checkers:     checker.NewController(ctx, cluster, cluster.GetRuleManager(), cluster.GetRegionLabeler(), opController),
+		now:          time.Now(),
+		stopped:      make(map[uint64]struct{}),
+	}
+}
+
+// Close stops the MiniPD and cleans up the schedulers.
+func (m *MiniPD) Close() {
+	for _, s := range m.schedulers {
+		s.Cleanup(m.cluster)
+	}
+	m.schedulers = nil
+	m.cancel()
+}
+
+// Cluster returns the mock cluster, which is used to create and update the
+// stores and Regions.
+func (m *MiniPD) Cluster() *mockcluster.Cluster {
+	return m.cluster
+}
+
+// OperatorController returns the operator controller.
+func (m *MiniPD) OperatorController() *schedule.OperatorController {
+	return m.opController
+}
+
+// AddScheduler adds a scheduler, which is usually created by a plugin.
+func (m *MiniPD) AddScheduler(s schedule.Scheduler) error {
+	for _, scheduler := range m.schedulers {
+		if scheduler.GetName() == s.GetName() {
+			return errs.ErrSchedulerExisted.FastGenByArgs()
+		}
+	}
+	if err := s.Prepare(m.cluster); err != nil {
+		return err
+	}
+	m.schedulers = append(m.schedulers, s)
+	return nil
+}
+
+// AddSchedulerByType creates a registered scheduler with the arguments and adds it.
+func (m *MiniPD) AddSchedulerByType(typ string, args ...string) (schedule.Scheduler, error) {
+	s, err := schedule.CreateScheduler(typ, m.opController, m.storage, schedule.ConfigSliceDecoder(typ, args))
+	if err != nil {
+		return nil, err
+	}
+	if err := m.AddScheduler(s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// RemoveScheduler removes the scheduler with the name.
+func (m *MiniPD) RemoveScheduler(name string) error {
+	for i, s := range m.schedulers {
+		if s.GetName() == name {
+			s.Cleanup(m.cluster)
+			m.schedulers = append(m.schedulers[:i], m.schedulers[i+1:]...)
+			return nil
+		}
+	}
+	return errs.ErrSchedulerNotFound.FastGenByArgs()
+}
+
+// RunRounds runs n rounds of scheduling and returns the number of the added
+// operators. In each round, all Regions are checked by the checkers first as
+// the patrol of the coordinator does, then each allowed scheduler is run once.
+func (m *MiniPD) RunRounds(n int) int {
+	added := 0
+	for i := 0; i < n; i++ {
+		added += m.patrolRegions()
+		for _, s := range m.schedulers {
+			if !s.IsScheduleAllowed(m.cluster) {
+				continue
+			}
+			if ops, _ := s.Schedule(m.cluster, false); len(ops) > 0 {
+				added += m.opController.AddWaitingOperator(ops...)
+			}
+		}
+	}
+	return added
+}
+
+func (m *MiniPD) patrolRegions() int {
+	added := 0
+	for _, region := range m.cluster.GetRegions() {
+		if m.opController.GetOperator(region.GetID()) != nil {
+			continue
+		}
+		if ops := m.checkers.CheckRegion(region); len(ops) > 0 {
+			added += m.opController.AddWaitingOperator(ops...)
+		}
+	}
+	return added
+}
+
+// Step applies one step of each running operator to its Region and reports
+// the Region as a heartbeat does, repeated for n times. The finished operators
+// are removed from the operator controller. If a step cannot be applied, its
+// operator is removed and the error is returned.
+func (m *MiniPD) Step(n int) error {
+	for i := 0; i < n; i++ {
+		for _, op := range m.opController.GetOperators() {
+			origin := m.cluster.GetRegion(op.RegionID())
+			if origin == nil {
+				// The source Region of a merge is removed once it is merged.
+				m.opController.RemoveOperator(op)
+				continue
+			}
+			regions, err := m.applyStep(origin, op)
+			if err != nil {
+				m.opController.RemoveOperator(op)
+				return err
+			}
+			for _, region := range regions {
+				m.cluster.PutRegion(region)
+				m.updateStoreStatus(origin, region)
+				m.opController.Dispatch(region, schedule.DispatchFromHeartBeat)
+			}
+		}
+	}
+	return nil
+}
+
+// applyStep applies the current step of the operator, and returns the Regions
+// changed by it.
+func (m *MiniPD) applyStep(origin *core.RegionInfo, op *operator.Operator) ([]*core.RegionInfo, error) {
+	_ = op.Start()
+	switch s := op.Check(origin).(type) {
+	case nil:
+		return []*core.RegionInfo{origin}, nil
+	case operator.TransferLeader, operator.AddPeer, operator.RemovePeer, operator.AddLearner,
+		operator.PromoteLearner, operator.DemoteVoter, operator.ChangePeerV2Enter, operator.ChangePeerV2Leave:
+		return []*core.RegionInfo{schedule.ApplyOperatorStep(origin, op)}, nil
+	case operator.MergeRegion:
+		if s.IsPassive {
+			// The target Region waits for the source Region to be merged.
+			return []*core.RegionInfo{origin}, nil
+		}
+		return m.merge(origin, s)
+	case operator.SplitRegion:
+		if len(s.SplitKeys) == 0 {
+			return nil, errs.ErrUnsupportedOperatorStep.FastGenByArgs(s.String(), "the split keys are required")
+		}
+		return m.split(origin, s.SplitKeys)
+	default:
+		return nil, errs.ErrUnsupportedOperatorStep.FastGenByArgs(s.String(), "unknown step")
+	}
+}
+
+// merge merges the source Region into the adjacent target Region and removes
+// the source Region.
+func (m *MiniPD) merge(source *core.RegionInfo, step operator.MergeRegion) ([]*core.RegionInfo, error) {
+	target := m.cluster.GetRegion(step.ToRegion.GetId())
+	if target == nil {
+		return nil, errs.ErrUnsupportedOperatorStep.FastGenByArgs(step.String(), "the target Region is not found")
+	}
+	var merged *core.RegionInfo
+	switch {
+	case bytes.Equal(target.GetStartKey(), source.GetEndKey()):
+		merged = target.Clone(core.WithStartKey(source.GetStartKey()), core.WithIncVersion())
+	case bytes.Equal(target.GetEndKey(), source.GetStartKey()):
+		merged = target.Clone(core.WithEndKey(source.GetEndKey()), core.WithIncVersion())
+	default:
+		return nil, errs.ErrUnsupportedOperatorStep.FastGenByArgs(step.String(), "the Regions are not adjacent")
+	}
+	merged = merged.Clone(
+		core.SetApproximateSize(target.GetApproximateSize()+source.GetApproximateSize()),
+		core.SetApproximateKeys(target.GetApproximateKeys()+source.GetApproximateKeys()))
+	m.cluster.RemoveRegion(source)
+	return []*core.RegionInfo{merged}, nil
+}
+
+// split splits the Region by the keys. As TiKV does, the Region keeps the last
+// part, and the new Regions with the allocated IDs take the others.
+func (m *MiniPD) split(origin *core.RegionInfo, splitKeys [][]byte) ([]*core.RegionInfo, error) {
+	keys := make([][]byte, 0, len(splitKeys))
+	for _, key := range splitKeys {
+		if bytes.Compare(key, origin.GetStartKey()) <= 0 ||
+			(len(origin.GetEndKey()) > 0 && bytes.Compare(key, origin.GetEndKey()) >= 0) ||
+			(len(keys) > 0 && bytes.Compare(key, keys[len(keys)-1]) <= 0) {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return []*core.RegionInfo{origin}, nil
+	}
+	size := origin.GetApproximateSize() / int64(len(keys)+1)
+	approximateKeys := origin.GetApproximateKeys() / int64(len(keys)+1)
+	regions := make([]*core.RegionInfo, 0, len(keys)+1)
+	start := origin.GetStartKey()
+	for _, key := range keys {
+		id, err := m.cluster.GetAllocator().Alloc()
+		if err != nil {
+			return nil, err
+		}
+		peerIDs := make([]uint64, len(origin.GetPeers()))
+		for i := range peerIDs {
+			if peerIDs[i], err = m.cluster.GetAllocator().Alloc(); err != nil {
+				return nil, err
+			}
+		}
+		region := origin.Clone(core.WithNewRegionID(id), core.WithNewPeerIDs(peerIDs...),
+			core.WithStartKey(start), core.WithEndKey(key), core.WithIncVersion(),
+			core.SetApproximateSize(size), core.SetApproximateKeys(approximateKeys))
+		regions = append(regions, region.Clone(core.WithLeader(region.GetStorePeer(origin.GetLeader().GetStoreId()))))
+		start = key
+	}
+	regions = append(regions, origin.Clone(core.WithStartKey(start), core.WithIncVersion(),
+		core.SetApproximateSize(size), core.SetApproximateKeys(approximateKeys)))
+	return regions, nil
+}
+
+// Now returns the simulated time, which only moves on Advance.
+func (m *MiniPD) Now() time.Time {
+	return m.now
+}
+
+// Advance moves the simulated clock forward by d. The stores heartbeat in the
+// period unless they are stopped, and the running operators are checked as if
+// d has elapsed, so the timed out operators are removed.
+func (m *MiniPD) Advance(d time.Duration) {
+	m.now = m.now.Add(d)
+	for _, store := range m.cluster.GetStores() {
+		if _, ok := m.stopped[store.GetID()]; ok {
+			m.cluster.SetStoreLastHeartbeatInterval(store.GetID(), time.Since(store.GetLastHeartbeatTS())+d)
+			continue
+		}
+		m.cluster.UpdateStoreStatus(store.GetID())
+	}
+	for _, op := range m.opController.GetOperators() {
+		op.Rewind(d)
+		if region := m.cluster.GetRegion(op.RegionID()); region != nil {
+			m.opController.Dispatch(region, schedule.DispatchFromHeartBeat)
+		}
+	}
+}
+
+// StopStore stops the heartbeats of the store, so it becomes disconnected and
+// then down as the clock advances.
+func (m *MiniPD) StopStore(storeID uint64) {
+	m.stopped[storeID] = struct{}{}
+}
+
+// StartStore resumes the heartbeats of the store.
+func (m *MiniPD) StartStore(storeID uint64) {
+	delete(m.stopped, storeID)
+	m.cluster.UpdateStoreStatus(storeID)
+}
+
+// RunUntilStable runs the scheduling rounds and applies the operators until no
+// operator is created or maxRounds is reached. It returns the number of the
+// added operators, and the error if a step cannot be applied.
+func (m *MiniPD) RunUntilStable(maxRounds int) (int, error) {
+	total := 0
+	for i := 0; i < maxRounds; i++ {
+		added := m.RunRounds(1)
+		if added == 0 && len(m.opController.GetOperators()) == 0 {
+			break
+		}
+		total += added
+		for j := 0; j < maxOperatorSteps && len(m.opController.GetOperators()) > 0; j++ {
+			if err := m.Step(1); err != nil {
+				return total, err
+			}
+		}
+	}
+	return total, nil
+}
+
+// GetOperators returns the running operators.
+func (m *MiniPD) GetOperators() []*operator.Operator {
+	return m.opController.GetOperators()
+}
+
+// GetOperator returns the running operator of the Region.
+func (m *MiniPD) GetOperator(regionID uint64) *operator.Operator {
+	return m.opController.GetOperator(regionID)
+}
+
+func (m *MiniPD) updateStoreStatus(regions ...*core.RegionInfo) {
+	for _, region := range regions {
+		for id := range region.GetStoreIDs() {
+			if _, ok := m.stopped[id]; !ok {
+				m.cluster.UpdateStoreStatus(id)
+			}
+		}
+	}
+}
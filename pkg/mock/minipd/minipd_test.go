@@ -0,0 +1,164 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package minipd
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedulers"
+)
+
+func TestSchedulingRounds(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pd := New(ctx, nil)
+	defer pd.Close()
+
+	tc := pd.Cluster()
+	for id := uint64(1); id <= 4; id++ {
+		tc.AddLeaderStore(id, 0)
+	}
+	for id := uint64(1); id <= 8; id++ {
+		tc.AddLeaderRegion(id, 1, 2, 3)
+	}
+	for id := uint64(1); id <= 4; id++ {
+		tc.UpdateStoreStatus(id)
+	}
+
+	_, err := pd.AddSchedulerByType(schedulers.BalanceLeaderType, "", "")
+	re.NoError(err)
+	_, err = pd.AddSchedulerByType(schedulers.BalanceLeaderType, "", "")
+	re.Error(err)
+
+	re.Greater(pd.RunRounds(1), 0)
+	re.NotEmpty(pd.GetOperators())
+	re.NoError(pd.Step(1))
+	re.Empty(pd.GetOperators())
+	re.Less(tc.GetStore(1).GetLeaderCount(), 8)
+
+	_, err = pd.RunUntilStable(100)
+	re.NoError(err)
+	re.Empty(pd.GetOperators())
+	for id := uint64(1); id <= 3; id++ {
+		re.LessOrEqual(tc.GetStore(id).GetLeaderCount(), 4)
+	}
+
+	re.NoError(pd.RemoveScheduler(schedulers.BalanceLeaderName))
+	re.Error(pd.RemoveScheduler(schedulers.BalanceLeaderName))
+}
+
+func TestCheckersFixReplicas(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pd := New(ctx, nil)
+	defer pd.Close()
+
+	tc := pd.Cluster()
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddRegionStore(id, 0)
+	}
+	tc.AddLeaderRegion(1, 1)
+	for id := uint64(1); id <= 3; id++ {
+		tc.UpdateStoreStatus(id)
+	}
+
+	added, err := pd.RunUntilStable(10)
+	re.NoError(err)
+	re.Greater(added, 0)
+	re.Empty(pd.GetOperators())
+	re.Len(tc.GetRegion(1).GetVoters(), 3)
+}
+
+func TestMergeAndSplit(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pd := New(ctx, nil)
+	defer pd.Close()
+
+	tc := pd.Cluster()
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddRegionStore(id, 0)
+	}
+	tc.AddLeaderRegionWithRange(1, "a", "b", 1, 2, 3)
+	tc.AddLeaderRegionWithRange(2, "b", "c", 1, 2, 3)
+
+	ops, err := operator.CreateMergeRegionOperator("merge-region", tc, tc.GetRegion(1), tc.GetRegion(2), operator.OpMerge)
+	re.NoError(err)
+	re.True(pd.OperatorController().AddOperator(ops...))
+	re.NoError(pd.Step(2))
+	re.Empty(pd.GetOperators())
+	re.Nil(tc.GetRegion(1))
+	re.Equal("a", string(tc.GetRegion(2).GetStartKey()))
+	re.Equal("c", string(tc.GetRegion(2).GetEndKey()))
+
+	op, err := operator.CreateSplitRegionOperator("split-region", tc.GetRegion(2), operator.OpAdmin, pdpb.CheckPolicy_USEKEY, [][]byte{[]byte("b")})
+	re.NoError(err)
+	re.True(pd.OperatorController().AddOperator(op))
+	re.NoError(pd.Step(1))
+	re.Empty(pd.GetOperators())
+	re.Equal("b", string(tc.GetRegion(2).GetStartKey()))
+	left := tc.GetRegionByKey([]byte("a"))
+	re.NotEqual(uint64(2), left.GetID())
+	re.Equal("b", string(left.GetEndKey()))
+	re.Len(left.GetPeers(), 3)
+	re.Equal(uint64(1), left.GetLeader().GetStoreId())
+
+	// The split without the keys is rejected.
+	op, err = operator.CreateSplitRegionOperator("split-region", tc.GetRegion(2), operator.OpAdmin, pdpb.CheckPolicy_APPROXIMATE, nil)
+	re.NoError(err)
+	re.True(pd.OperatorController().AddOperator(op))
+	re.True(errs.ErrUnsupportedOperatorStep.Equal(pd.Step(1)))
+	re.Empty(pd.GetOperators())
+}
+
+func TestAdvanceClock(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	pd := New(ctx, nil)
+	defer pd.Close()
+
+	tc := pd.Cluster()
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddRegionStore(id, 0)
+	}
+	tc.AddLeaderRegion(1, 1, 2, 3)
+
+	start := pd.Now()
+	pd.StopStore(3)
+	op, err := operator.CreateTransferLeaderOperator("transfer-leader", tc, tc.GetRegion(1), 1, 2, []uint64{}, operator.OpLeader)
+	re.NoError(err)
+	re.True(pd.OperatorController().AddOperator(op))
+
+	pd.Advance(time.Hour)
+	re.Equal(time.Hour, pd.Now().Sub(start))
+	re.Less(tc.GetStore(1).DownTime(), time.Minute)
+	re.GreaterOrEqual(tc.GetStore(3).DownTime(), time.Hour)
+	// The operator times out.
+	re.Empty(pd.GetOperators())
+	re.True(op.IsEnd())
+
+	pd.StartStore(3)
+	re.Less(tc.GetStore(3).DownTime(), time.Minute)
+}
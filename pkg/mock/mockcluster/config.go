@@ -31,6 +31,11 @@ func (mc *Cluster) SetMaxMergeRegionKeys(v int) {
 	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.MaxMergeRegionKeys = uint64(v) })
 }
 
+// SetMergeHotWriteRatio updates the MergeHotWriteRatio configuration.
+func (mc *Cluster) SetMergeHotWriteRatio(v float64) {
+	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.MergeHotWriteRatio = v })
+}
+
 // SetSplitMergeInterval updates the SplitMergeInterval configuration.
 func (mc *Cluster) SetSplitMergeInterval(v time.Duration) {
 	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.SplitMergeInterval = typeutil.NewDuration(v) })
@@ -86,6 +91,16 @@ func (mc *Cluster) SetRegionScoreFormulaVersion(v string) {
 	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.RegionScoreFormulaVersion = v })
 }
 
+// SetEnableStoreQuotaBias updates the EnableStoreQuotaBias configuration.
+func (mc *Cluster) SetEnableStoreQuotaBias(v bool) {
+	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.EnableStoreQuotaBias = v })
+}
+
+// SetStoreLimitSizeRate updates the StoreLimitSizeRate configuration.
+func (mc *Cluster) SetStoreLimitSizeRate(v float64) {
+	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.StoreLimitSizeRate = v })
+}
+
 // SetLeaderScheduleLimit updates the LeaderScheduleLimit configuration.
 func (mc *Cluster) SetLeaderScheduleLimit(v int) {
 	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.LeaderScheduleLimit = uint64(v) })
@@ -101,6 +116,11 @@ func (mc *Cluster) SetMergeScheduleLimit(v int) {
 	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.MergeScheduleLimit = uint64(v) })
 }
 
+// SetLearnerBalanceScheduleLimit updates the LearnerBalanceScheduleLimit configuration.
+func (mc *Cluster) SetLearnerBalanceScheduleLimit(v int) {
+	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.LearnerBalanceScheduleLimit = uint64(v) })
+}
+
 // SetHotRegionScheduleLimit updates the HotRegionScheduleLimit configuration.
 func (mc *Cluster) SetHotRegionScheduleLimit(v int) {
 	mc.updateScheduleConfig(func(s *config.ScheduleConfig) { s.HotRegionScheduleLimit = uint64(v) })
@@ -119,6 +139,16 @@ func (mc *Cluster) SetEnablePlacementRules(v bool) {
 	}
 }
 
+// SetEnableDegradedReplicaFallback updates the EnableDegradedReplicaFallback configuration.
+func (mc *Cluster) SetEnableDegradedReplicaFallback(v bool) {
+	mc.updateReplicationConfig(func(r *config.ReplicationConfig) { r.EnableDegradedReplicaFallback = v })
+}
+
+// SetDegradedReplicaFallbackRole updates the DegradedReplicaFallbackRole configuration.
+func (mc *Cluster) SetDegradedReplicaFallbackRole(v string) {
+	mc.updateReplicationConfig(func(r *config.ReplicationConfig) { r.DegradedReplicaFallbackRole = v })
+}
+
 // SetMaxReplicas updates the maxReplicas configuration.
 func (mc *Cluster) SetMaxReplicas(v int) {
 	mc.updateReplicationConfig(func(r *config.ReplicationConfig) { r.MaxReplicas = uint64(v) })
@@ -56,7 +56,8 @@ type Cluster struct {
 	suspectRegions map[uint64]struct{}
 	*config.StoreConfigManager
 	*buckets.HotBucketCache
-	ctx context.Context
+	ctx           context.Context
+	zoneLatencies *statistics.ZoneLatencies
 }
 
 // NewCluster creates a new Cluster
@@ -70,6 +71,7 @@ func NewCluster(ctx context.Context, opts *config.PersistOptions) *Cluster {
 		suspectRegions:     map[uint64]struct{}{},
 		StoreConfigManager: config.NewTestStoreConfigManager(nil),
 		ctx:                ctx,
+		zoneLatencies:      statistics.NewZoneLatencies(),
 	}
 	if clus.PersistOptions.GetReplicationConfig().EnablePlacementRules {
 		clus.initRuleManager()
@@ -113,6 +115,16 @@ func (mc *Cluster) GetStoresLoads() map[uint64][]float64 {
 	return mc.HotStat.GetStoresLoads()
 }
 
+// GetZoneLatency returns the round-trip time between two zones.
+func (mc *Cluster) GetZoneLatency(zoneA, zoneB string) (time.Duration, bool) {
+	return mc.zoneLatencies.GetZoneLatency(zoneA, zoneB)
+}
+
+// ObserveZoneLatency records a round-trip time between two zones.
+func (mc *Cluster) ObserveZoneLatency(zoneA, zoneB string, rtt time.Duration) {
+	mc.zoneLatencies.ObserveZoneLatency(zoneA, zoneB, rtt)
+}
+
 // GetStoreRegionCount gets region count with a given store.
 func (mc *Cluster) GetStoreRegionCount(storeID uint64) int {
 	return mc.Regions.GetStoreRegionCount(storeID)
@@ -150,6 +162,11 @@ func (mc *Cluster) RegionWriteStats() map[uint64][]*statistics.HotPeerStat {
 	return mc.HotCache.RegionStats(statistics.Write, mc.GetHotRegionCacheHitsThreshold())
 }
 
+// RegionPeerWriteStats returns the write stats of the peers of the region.
+func (mc *Cluster) RegionPeerWriteStats(region *core.RegionInfo) []*statistics.HotPeerStat {
+	return mc.HotCache.GetRegionPeerStats(statistics.Write, region)
+}
+
 // HotRegionsFromStore picks hot regions in specify store.
 func (mc *Cluster) HotRegionsFromStore(store uint64, kind statistics.RWType) []*core.RegionInfo {
 	stats := hotRegionsFromStore(mc.HotCache, store, kind, mc.GetHotRegionCacheHitsThreshold())
@@ -491,6 +508,13 @@ func (mc *Cluster) UpdateStoreRegionWeight(storeID uint64, weight float64) {
 	mc.PutStore(newStore)
 }
 
+// UpdateStoreQuota updates the soft quotas of the leader and region count of the store.
+func (mc *Cluster) UpdateStoreQuota(storeID uint64, leaderQuota, regionQuota uint64) {
+	store := mc.GetStore(storeID)
+	newStore := store.Clone(core.SetStoreQuota(leaderQuota, regionQuota))
+	mc.PutStore(newStore)
+}
+
 // UpdateStoreLeaderSize updates store leader size.
 func (mc *Cluster) UpdateStoreLeaderSize(storeID uint64, size int64) {
 	store := mc.GetStore(storeID)
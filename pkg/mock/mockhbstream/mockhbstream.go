@@ -51,6 +51,9 @@ func (s HeartbeatStream) SendMsg(region *core.RegionInfo, msg *pdpb.RegionHeartb
 // BindStream mock method.
 func (s HeartbeatStream) BindStream(storeID uint64, stream hbstream.HeartbeatStream) {}
 
+// BindShardStream mock method.
+func (s HeartbeatStream) BindShardStream(storeID uint64, stream hbstream.HeartbeatStream) {}
+
 // Recv mocks method.
 func (s HeartbeatStream) Recv() *pdpb.RegionHeartbeatResponse {
 	select {
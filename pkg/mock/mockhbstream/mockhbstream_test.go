@@ -16,6 +16,7 @@ package mockhbstream
 
 import (
 	"context"
+	"errors"
 	"testing"
 
 	"github.com/gogo/protobuf/proto"
@@ -71,3 +72,63 @@ func TestActivity(t *testing.T) {
 		return stream1.Recv() != nil && stream2.Recv() == nil
 	})
 }
+
+type failedStream struct{}
+
+func (failedStream) Send(*pdpb.RegionHeartbeatResponse) error {
+	return errors.New("stream closed")
+}
+
+func TestShardStreams(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cluster := mockcluster.NewCluster(ctx, config.NewTestOptions())
+	cluster.AddRegionStore(1, 2)
+	cluster.AddRegionStore(2, 0)
+	cluster.AddLeaderRegion(2, 1)
+	cluster.AddLeaderRegion(3, 1)
+	region2, region3 := cluster.GetRegion(2), cluster.GetRegion(3)
+	msg := &pdpb.RegionHeartbeatResponse{
+		ChangePeer: &pdpb.ChangePeer{Peer: &metapb.Peer{Id: 2, StoreId: 2}, ChangeType: eraftpb.ConfChangeType_AddLearnerNode},
+	}
+
+	hbs := hbstream.NewTestHeartbeatStreams(ctx, cluster.ID, cluster, true)
+	stream1, stream2 := NewHeartbeatStream(), NewHeartbeatStream()
+
+	// Messages are sharded by region ID.
+	hbs.BindShardStream(1, stream1)
+	hbs.BindShardStream(1, stream2)
+	testutil.Eventually(re, func() bool {
+		hbs.SendMsg(region2, proto.Clone(msg).(*pdpb.RegionHeartbeatResponse))
+		return stream1.Recv() != nil && stream2.Recv() == nil
+	})
+	testutil.Eventually(re, func() bool {
+		hbs.SendMsg(region3, proto.Clone(msg).(*pdpb.RegionHeartbeatResponse))
+		return stream1.Recv() == nil && stream2.Recv() != nil
+	})
+	// Rebinding an existing stream does not change the sharding.
+	hbs.BindShardStream(1, stream1)
+	testutil.Eventually(re, func() bool {
+		hbs.SendMsg(region3, proto.Clone(msg).(*pdpb.RegionHeartbeatResponse))
+		return stream1.Recv() == nil && stream2.Recv() != nil
+	})
+
+	// Falls back to the remaining stream if the sharded one fails.
+	hbs.BindStream(1, stream1)
+	hbs.BindShardStream(1, failedStream{})
+	testutil.Eventually(re, func() bool {
+		hbs.SendMsg(region3, proto.Clone(msg).(*pdpb.RegionHeartbeatResponse))
+		return stream1.Recv() != nil && stream2.Recv() == nil
+	})
+
+	// BindStream replaces all sharded streams.
+	hbs.BindShardStream(1, stream1)
+	hbs.BindStream(1, stream2)
+	testutil.Eventually(re, func() bool {
+		hbs.SendMsg(region2, proto.Clone(msg).(*pdpb.RegionHeartbeatResponse))
+		return stream1.Recv() == nil && stream2.Recv() != nil
+	})
+}
@@ -35,3 +35,8 @@ func (alloc *IDAllocator) Alloc() (uint64, error) {
 func (alloc *IDAllocator) Rebase() error {
 	return nil
 }
+
+// Current implements the IDAllocator interface.
+func (alloc *IDAllocator) Current() (uint64, error) {
+	return atomic.LoadUint64(&alloc.base), nil
+}
@@ -85,6 +85,25 @@ func TestMedianFilter(t *testing.T) {
 	checkSet(re, mf, data, expected)
 }
 
+func TestPercentileFilter(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	var empty float64 = 0
+	data := []float64{2, 4, 2, 800, 600, 6, 3}
+	expected := []float64{2, 4, 4, 800, 800, 800, 800}
+
+	pf := NewPercentileFilter(5, 99)
+	re.Equal(empty, pf.Get())
+
+	checkReset(re, pf, empty)
+	checkAdd(re, pf, data, expected)
+	checkSet(re, pf, data, expected)
+	re.Equal(5, pf.Count())
+
+	pf = NewPercentileFilter(4, 50)
+	checkAdd(re, pf, []float64{4, 1, 3, 2, 10}, []float64{4, 1, 3, 2, 2})
+}
+
 type testCase struct {
 	ma       MovingAvg
 	expected []float64
@@ -110,6 +129,9 @@ func TestMovingAvg(t *testing.T) {
 	}, {
 		ma:       NewMaxFilter(5),
 		expected: []float64{1.000000, 1.000000, 1.000000, 1.000000, 5.000000, 5.000000, 5.000000, 5.000000},
+	}, {
+		ma:       NewPercentileFilter(5, 99),
+		expected: []float64{1.000000, 1.000000, 1.000000, 1.000000, 5.000000, 5.000000, 5.000000, 5.000000},
 	},
 	}
 	for _, testCase := range testCases {
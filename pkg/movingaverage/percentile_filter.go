@@ -0,0 +1,96 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package movingaverage
+
+import "github.com/montanaflynn/stats"
+
+// PercentileFilter works as a percentile filter with specified window size.
+// There are at most `size` data points for calculating, and the result is the
+// nearest-rank percentile of them, e.g. the p99 latency of the recent requests.
+type PercentileFilter struct {
+	records       []float64
+	size          uint64
+	count         uint64
+	percent       float64
+	instantaneous float64
+}
+
+// NewPercentileFilter returns a PercentileFilter. The percent should be in (0, 100].
+func NewPercentileFilter(size int, percent float64) *PercentileFilter {
+	return &PercentileFilter{
+		records: make([]float64, size),
+		size:    uint64(size),
+		percent: percent,
+	}
+}
+
+// Add adds a data point.
+func (r *PercentileFilter) Add(n float64) {
+	r.instantaneous = n
+	r.records[r.count%r.size] = n
+	r.count++
+}
+
+// Get returns the percentile of the data set.
+func (r *PercentileFilter) Get() float64 {
+	if r.count == 0 {
+		return 0
+	}
+	records := r.records
+	if r.count < r.size {
+		records = r.records[:r.count]
+	}
+	percentile, _ := stats.PercentileNearestRank(records, r.percent)
+	return percentile
+}
+
+// Reset cleans the data set.
+func (r *PercentileFilter) Reset() {
+	r.instantaneous = 0
+	r.count = 0
+}
+
+// Set = Reset + Add.
+func (r *PercentileFilter) Set(n float64) {
+	r.instantaneous = n
+	r.records[0] = n
+	r.count = 1
+}
+
+// GetInstantaneous returns the value just added.
+func (r *PercentileFilter) GetInstantaneous() float64 {
+	return r.instantaneous
+}
+
+// Count returns the number of the data points in the window.
+func (r *PercentileFilter) Count() int {
+	if r.count < r.size {
+		return int(r.count)
+	}
+	return int(r.size)
+}
+
+// Clone returns a copy of PercentileFilter
+func (r *PercentileFilter) Clone() *PercentileFilter {
+	records := make([]float64, len(r.records))
+	copy(records, r.records)
+	return &PercentileFilter{
+		records:       records,
+		size:          r.size,
+		count:         r.count,
+		percent:       r.percent,
+		instantaneous: r.instantaneous,
+	}
+}
@@ -52,6 +52,8 @@ type progressIndicator struct {
 	windowLengthLimit int
 	updateInterval    time.Duration
 	lastSpeed         float64
+	startTime         time.Time
+	updateTime        time.Time
 }
 
 // Reset resets the progress manager.
@@ -70,12 +72,15 @@ func (m *Manager) AddProgress(progress string, current, total float64, updateInt
 	history := list.New()
 	history.PushBack(current)
 	if _, exist = m.progesses[progress]; !exist {
+		now := time.Now()
 		m.progesses[progress] = &progressIndicator{
 			total:             total,
 			remaining:         total,
 			history:           history,
 			windowLengthLimit: int(speedStatisticalWindow / updateInterval),
 			updateInterval:    updateInterval,
+			startTime:         now,
+			updateTime:        now,
 		}
 	}
 	return
@@ -88,6 +93,7 @@ func (m *Manager) UpdateProgress(progress string, current, remaining float64, is
 
 	if p, exist := m.progesses[progress]; exist {
 		p.remaining = remaining
+		p.updateTime = time.Now()
 		if p.total < remaining {
 			p.total = remaining
 		}
@@ -178,3 +184,104 @@ func (m *Manager) Status(progress string) (process, leftSeconds, currentSpeed fl
 	err = errs.ErrProgressNotFound.FastGenByArgs(fmt.Sprintf("the progress: %s", progress))
 	return
 }
+
+// Snapshot records the states of the progresses, so that they can be restored
+// after the leadership of PD changes.
+type Snapshot struct {
+	// Timestamp is the unix time in seconds when the snapshot is taken.
+	Timestamp int64           `json:"timestamp"`
+	Items     []*SnapshotItem `json:"items"`
+}
+
+// SnapshotItem records the state of one progress in the snapshot.
+type SnapshotItem struct {
+	Name           string        `json:"name"`
+	Total          float64       `json:"total"`
+	Remaining      float64       `json:"remaining"`
+	History        []float64     `json:"history"`
+	UpdateInterval time.Duration `json:"update_interval"`
+	LastSpeed      float64       `json:"last_speed"`
+	// StartTime and UpdateTime are the unix time in seconds when the progress is
+	// added and last updated.
+	StartTime  int64 `json:"start_time"`
+	UpdateTime int64 `json:"update_time"`
+}
+
+// Snapshot takes a snapshot of the progresses according to the filter.
+func (m *Manager) Snapshot(filter func(p string) bool) *Snapshot {
+	m.RLock()
+	defer m.RUnlock()
+
+	snapshot := &Snapshot{Timestamp: time.Now().Unix(), Items: []*SnapshotItem{}}
+	for name, p := range m.progesses {
+		if !filter(name) {
+			continue
+		}
+		history := make([]float64, 0, p.history.Len())
+		for e := p.history.Front(); e != nil; e = e.Next() {
+			history = append(history, e.Value.(float64))
+		}
+		snapshot.Items = append(snapshot.Items, &SnapshotItem{
+			Name:           name,
+			Total:          p.total,
+			Remaining:      p.remaining,
+			History:        history,
+			UpdateInterval: p.updateInterval,
+			LastSpeed:      p.lastSpeed,
+			StartTime:      p.startTime.Unix(),
+			UpdateTime:     p.updateTime.Unix(),
+		})
+	}
+	return snapshot
+}
+
+// Restore restores the progresses accepted by the filter from the snapshot, the
+// existing progresses are not overwritten. The history is padded with the last
+// value for the intervals missed since the last update, so that the speed is
+// still calculated over the statistical window. It returns the number of the
+// restored progresses.
+func (m *Manager) Restore(snapshot *Snapshot, filter func(p string) bool) int {
+	m.Lock()
+	defer m.Unlock()
+
+	now := time.Now()
+	count := 0
+	for _, item := range snapshot.Items {
+		if _, exist := m.progesses[item.Name]; exist || !filter(item.Name) ||
+			item.UpdateInterval <= 0 || len(item.History) == 0 {
+			continue
+		}
+		p := &progressIndicator{
+			total:             item.Total,
+			remaining:         item.Remaining,
+			history:           list.New(),
+			windowLengthLimit: int(speedStatisticalWindow / item.UpdateInterval),
+			updateInterval:    item.UpdateInterval,
+			lastSpeed:         item.LastSpeed,
+			startTime:         time.Unix(item.StartTime, 0),
+			updateTime:        time.Unix(item.UpdateTime, 0),
+		}
+		for _, v := range item.History {
+			p.history.PushBack(v)
+		}
+		last := item.History[len(item.History)-1]
+		missed := int(now.Sub(p.updateTime) / p.updateInterval)
+		if missed > p.windowLengthLimit {
+			// The whole window is missed, the speed is recalculated from scratch.
+			p.history.Init()
+			missed = 0
+		}
+		for ; missed > 0; missed-- {
+			p.history.PushBack(last)
+		}
+		if p.history.Len() == 0 {
+			p.history.PushBack(last)
+		}
+		for p.history.Len() > p.windowLengthLimit+1 {
+			p.history.Remove(p.history.Front())
+		}
+		m.progesses[item.Name] = p
+		count++
+	}
+	return count
+}
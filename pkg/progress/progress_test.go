@@ -95,3 +95,48 @@ func TestAbnormal(t *testing.T) {
 	re.Equal(0.0, ls)
 	re.Equal(0.0, cs)
 }
+
+func TestSnapshotAndRestore(t *testing.T) {
+	t.Parallel()
+	re := require.New(t)
+	m := NewManager()
+	re.False(m.AddProgress("a", 100, 100, 10*time.Second))
+	m.UpdateProgress("a", 70, 70, false)
+	re.False(m.AddProgress("b", 0, 100, 10*time.Second))
+	snapshot := m.Snapshot(func(p string) bool { return p == "a" })
+	re.Len(snapshot.Items, 1)
+	re.Equal([]float64{100, 70}, snapshot.Items[0].History)
+
+	// The restored progress keeps the same status.
+	restored := NewManager()
+	re.Equal(1, restored.Restore(snapshot, func(string) bool { return true }))
+	p, ls, cs, err := m.Status("a")
+	re.NoError(err)
+	rp, rls, rcs, err := restored.Status("a")
+	re.NoError(err)
+	re.Equal(p, rp)
+	re.Equal(ls, rls)
+	re.Equal(cs, rcs)
+	// The existing progresses are not overwritten.
+	re.Equal(0, restored.Restore(snapshot, func(string) bool { return true }))
+	re.Equal(0, NewManager().Restore(snapshot, func(string) bool { return false }))
+
+	// The missed intervals are padded with the last value.
+	snapshot.Items[0].UpdateTime = time.Now().Add(-30 * time.Second).Unix()
+	restored = NewManager()
+	re.Equal(1, restored.Restore(snapshot, func(string) bool { return true }))
+	re.Equal(5, restored.progesses["a"].history.Len())
+	restored.UpdateProgress("a", 40, 40, false)
+	_, _, cs, err = restored.Status("a")
+	re.NoError(err)
+	re.Equal(60.0/50.0, cs)
+
+	// The history is dropped if the whole window is missed.
+	snapshot.Items[0].UpdateTime = time.Now().Add(-time.Hour).Unix()
+	restored = NewManager()
+	re.Equal(1, restored.Restore(snapshot, func(string) bool { return true }))
+	re.Equal(1, restored.progesses["a"].history.Len())
+	_, _, rcs, err = restored.Status("a")
+	re.NoError(err)
+	re.Equal(snapshot.Items[0].LastSpeed, rcs)
+}
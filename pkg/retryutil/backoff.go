@@ -0,0 +1,77 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retryutil
+
+import (
+	"context"
+	"math/rand"
+	"time"
+)
+
+// Backoff describes how to retry a failed operation with exponential backoff.
+type Backoff struct {
+	// MaxAttempts is the max number of times to run the operation, including
+	// the first one.
+	MaxAttempts int
+	// BaseInterval is the interval before the first retry, it doubles after
+	// each retry until it reaches MaxInterval.
+	BaseInterval time.Duration
+	MaxInterval  time.Duration
+	// Jitter is the fraction of the interval to randomize, in range [0, 1].
+	Jitter float64
+}
+
+// DefaultBackoff is used to persist the metadata, it reduces the probability
+// of the persistent error caused by a transient failure of the storage.
+var DefaultBackoff = Backoff{
+	MaxAttempts:  5,
+	BaseInterval: 50 * time.Millisecond,
+	MaxInterval:  time.Second,
+	Jitter:       0.2,
+}
+
+// Interval returns the interval to wait before the given retry, starting from 0.
+func (b Backoff) Interval(retry int) time.Duration {
+	interval := b.BaseInterval
+	for i := 0; i < retry && (b.MaxInterval <= 0 || interval < b.MaxInterval); i++ {
+		interval *= 2
+	}
+	if b.MaxInterval > 0 && interval > b.MaxInterval {
+		interval = b.MaxInterval
+	}
+	if b.Jitter > 0 {
+		delta := float64(interval) * b.Jitter
+		interval += time.Duration(delta * (2*rand.Float64() - 1))
+	}
+	return interval
+}
+
+// Do runs fn until it succeeds, the attempts are exhausted or the context is
+// done. fn always runs at least once. It returns the last error of fn.
+func (b Backoff) Do(ctx context.Context, fn func() error) error {
+	var err error
+	for i := 0; ; i++ {
+		if err = fn(); err == nil || i+1 >= b.MaxAttempts {
+			return err
+		}
+		timer := time.NewTimer(b.Interval(i))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package retryutil
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestInterval(t *testing.T) {
+	re := require.New(t)
+	b := Backoff{BaseInterval: 10 * time.Millisecond, MaxInterval: 50 * time.Millisecond}
+	re.Equal(10*time.Millisecond, b.Interval(0))
+	re.Equal(20*time.Millisecond, b.Interval(1))
+	re.Equal(40*time.Millisecond, b.Interval(2))
+	re.Equal(50*time.Millisecond, b.Interval(3))
+	re.Equal(50*time.Millisecond, b.Interval(100))
+
+	b.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		interval := b.Interval(1)
+		re.GreaterOrEqual(interval, 10*time.Millisecond)
+		re.LessOrEqual(interval, 30*time.Millisecond)
+	}
+}
+
+func TestDo(t *testing.T) {
+	re := require.New(t)
+	b := Backoff{MaxAttempts: 3, BaseInterval: time.Millisecond, MaxInterval: time.Millisecond}
+	errFailed := errors.New("failed")
+
+	var count int
+	re.NoError(b.Do(context.Background(), func() error {
+		count++
+		if count < 2 {
+			return errFailed
+		}
+		return nil
+	}))
+	re.Equal(2, count)
+
+	count = 0
+	re.ErrorIs(b.Do(context.Background(), func() error {
+		count++
+		return errFailed
+	}), errFailed)
+	re.Equal(3, count)
+
+	// Runs only once if the context is done.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	count = 0
+	re.ErrorIs(b.Do(ctx, func() error {
+		count++
+		return errFailed
+	}), errFailed)
+	re.Equal(1, count)
+}
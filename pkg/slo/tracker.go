@@ -0,0 +1,167 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slo
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/tikv/pd/pkg/movingaverage"
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+const (
+	// DefaultWindowSize is the default number of the recent requests used to calculate the latency of a route.
+	DefaultWindowSize = 200
+	// minSamples is the min number of the samples of a route to judge whether it is overloaded.
+	minSamples = 20
+	// overloadCheckInterval is the interval to refresh the overload signal.
+	overloadCheckInterval = time.Second
+	percentile            = 99
+)
+
+// LatencyStat is the rolling latency statistics of a route.
+type LatencyStat struct {
+	// P99 is the rolling p99 latency in milliseconds.
+	P99 float64 `json:"p99-ms"`
+	// Count is the total number of the observed requests.
+	Count uint64 `json:"count"`
+}
+
+// routeLatency is locked by itself, so that the requests of different routes
+// are observed without contention.
+type routeLatency struct {
+	mu     syncutil.Mutex
+	filter *movingaverage.PercentileFilter
+	count  uint64
+}
+
+// Tracker tracks the rolling p99 latencies of the API routes and gRPC methods,
+// and provides an overload signal based on them.
+type Tracker struct {
+	// RWMutex only protects the map of the routes, which is written only when
+	// a route is observed for the first time.
+	syncutil.RWMutex
+	windowSize int
+	routes     map[string]*routeLatency
+
+	// the cached overload signal.
+	overloadMu    syncutil.Mutex
+	lastCheck     time.Time
+	lastThreshold time.Duration
+	overloaded    bool
+}
+
+// NewTracker creates a Tracker with the given window size.
+func NewTracker(windowSize int) *Tracker {
+	return &Tracker{
+		windowSize: windowSize,
+		routes:     make(map[string]*routeLatency),
+	}
+}
+
+// Observe records the latency of a request of the route.
+func (t *Tracker) Observe(route string, latency time.Duration) {
+	r := t.getRoute(route)
+	if r == nil {
+		t.Lock()
+		if r = t.routes[route]; r == nil {
+			r = &routeLatency{filter: movingaverage.NewPercentileFilter(t.windowSize, percentile)}
+			t.routes[route] = r
+		}
+		t.Unlock()
+	}
+	r.mu.Lock()
+	r.filter.Add(float64(latency) / float64(time.Millisecond))
+	r.mu.Unlock()
+	atomic.AddUint64(&r.count, 1)
+}
+
+func (t *Tracker) getRoute(route string) *routeLatency {
+	t.RLock()
+	defer t.RUnlock()
+	return t.routes[route]
+}
+
+// getRoutes returns all the routes, which are observed without the lock of the map.
+func (t *Tracker) getRoutes() map[string]*routeLatency {
+	t.RLock()
+	defer t.RUnlock()
+	routes := make(map[string]*routeLatency, len(t.routes))
+	for route, r := range t.routes {
+		routes[route] = r
+	}
+	return routes
+}
+
+// GetP99 returns the rolling p99 latency of the route.
+func (t *Tracker) GetP99(route string) (time.Duration, bool) {
+	r := t.getRoute(route)
+	if r == nil {
+		return 0, false
+	}
+	return time.Duration(r.p99() * float64(time.Millisecond)), true
+}
+
+// IsSlow returns true if the route has enough samples and its p99 latency exceeds the threshold.
+func (t *Tracker) IsSlow(route string, threshold time.Duration) bool {
+	r := t.getRoute(route)
+	return r != nil && r.isSlow(threshold)
+}
+
+func (r *routeLatency) p99() float64 {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.filter.Get()
+}
+
+func (r *routeLatency) isSlow(threshold time.Duration) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.filter.Count() >= minSamples && r.filter.Get() > float64(threshold)/float64(time.Millisecond)
+}
+
+// GetStats returns the latency statistics of all routes.
+func (t *Tracker) GetStats() map[string]LatencyStat {
+	routes := t.getRoutes()
+	stats := make(map[string]LatencyStat, len(routes))
+	for route, r := range routes {
+		stats[route] = LatencyStat{P99: r.p99(), Count: atomic.LoadUint64(&r.count)}
+	}
+	return stats
+}
+
+// IsOverloaded returns true if the p99 latency of any route with enough samples
+// exceeds the threshold. The zero threshold means the signal is disabled. The
+// result is cached for a short interval so that it is cheap for the hot paths.
+func (t *Tracker) IsOverloaded(threshold time.Duration) bool {
+	if threshold <= 0 {
+		return false
+	}
+	t.overloadMu.Lock()
+	defer t.overloadMu.Unlock()
+	if threshold == t.lastThreshold && time.Since(t.lastCheck) < overloadCheckInterval {
+		return t.overloaded
+	}
+	t.overloaded = false
+	for _, r := range t.getRoutes() {
+		if r.isSlow(threshold) {
+			t.overloaded = true
+			break
+		}
+	}
+	t.lastCheck, t.lastThreshold = time.Now(), threshold
+	return t.overloaded
+}
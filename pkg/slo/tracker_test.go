@@ -0,0 +1,92 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package slo
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTracker(t *testing.T) {
+	re := require.New(t)
+	tracker := NewTracker(100)
+	_, ok := tracker.GetP99("GetRegion")
+	re.False(ok)
+
+	for i := 1; i <= 100; i++ {
+		tracker.Observe("GetRegion", time.Duration(i)*time.Millisecond)
+	}
+	p99, ok := tracker.GetP99("GetRegion")
+	re.True(ok)
+	re.Equal(99*time.Millisecond, p99)
+
+	tracker.Observe("GetStore", time.Second)
+	stats := tracker.GetStats()
+	re.Len(stats, 2)
+	re.Equal(uint64(100), stats["GetRegion"].Count)
+	re.Equal(99.0, stats["GetRegion"].P99)
+	re.Equal(1000.0, stats["GetStore"].P99)
+}
+
+func TestOverload(t *testing.T) {
+	re := require.New(t)
+	tracker := NewTracker(100)
+	// the zero threshold disables the signal.
+	re.False(tracker.IsOverloaded(0))
+
+	// the route without enough samples is ignored.
+	for i := 0; i < minSamples-1; i++ {
+		tracker.Observe("GetStore", time.Second)
+	}
+	re.False(tracker.IsOverloaded(100 * time.Millisecond))
+	re.False(tracker.IsSlow("GetStore", 100*time.Millisecond))
+
+	for i := 0; i < minSamples; i++ {
+		tracker.Observe("GetRegion", time.Second)
+	}
+	// the result is cached for the same threshold.
+	re.False(tracker.IsOverloaded(100 * time.Millisecond))
+	re.True(tracker.IsOverloaded(200 * time.Millisecond))
+	re.False(tracker.IsOverloaded(2 * time.Second))
+	re.True(tracker.IsSlow("GetRegion", 100*time.Millisecond))
+	re.False(tracker.IsSlow("GetRegion", 2*time.Second))
+	re.False(tracker.IsSlow("ScanRegions", 0))
+}
+
+func TestTrackerConcurrentObserve(t *testing.T) {
+	re := require.New(t)
+	tracker := NewTracker(DefaultWindowSize)
+	routes := []string{"GetRegion", "GetStore", "RegionHeartbeat"}
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				tracker.Observe(routes[(i+j)%len(routes)], time.Millisecond)
+				tracker.IsOverloaded(time.Second)
+			}
+		}(i)
+	}
+	wg.Wait()
+	var count uint64
+	for _, stat := range tracker.GetStats() {
+		count += stat.Count
+	}
+	re.Equal(uint64(800), count)
+}
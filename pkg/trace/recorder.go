@@ -0,0 +1,162 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"container/list"
+	"sync/atomic"
+
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+const (
+	// DefaultMaxTraces is the default number of the recent traces kept by the memory recorder.
+	DefaultMaxTraces = 1024
+	// maxSpansPerTrace limits the memory used by a long running trace, such as
+	// an operator which is dispatched many times.
+	maxSpansPerTrace = 256
+)
+
+// Recorder receives the finished spans. It can be implemented to export the
+// spans to an external tracing system.
+type Recorder interface {
+	Record(traceID TraceID, span *SpanData)
+}
+
+// Pinner is implemented by the recorders which can keep a trace from being
+// evicted until it's unpinned.
+type Pinner interface {
+	Pin(traceID TraceID)
+	Unpin(traceID TraceID)
+}
+
+type recorderHolder struct {
+	Recorder
+}
+
+var recorder atomic.Value
+
+func init() {
+	SetRecorder(NewMemoryRecorder(DefaultMaxTraces))
+}
+
+// SetRecorder replaces the global recorder.
+func SetRecorder(r Recorder) {
+	recorder.Store(recorderHolder{r})
+}
+
+// GetRecorder returns the global recorder.
+func GetRecorder() Recorder {
+	return recorder.Load().(recorderHolder).Recorder
+}
+
+// MemoryRecorder keeps the spans of the recent traces in memory. The pinned
+// traces are kept until they are unpinned, besides the recent ones.
+type MemoryRecorder struct {
+	syncutil.Mutex
+	maxTraces int
+	// order is the list of the IDs of the traces which are not pinned, ordered
+	// by the first recorded or the unpinned time.
+	order  *list.List
+	traces map[TraceID]*memoryTrace
+}
+
+type memoryTrace struct {
+	// elem is nil if the trace is pinned.
+	elem  *list.Element
+	pins  int
+	spans []*SpanData
+}
+
+// NewMemoryRecorder creates a MemoryRecorder which keeps at most maxTraces traces.
+func NewMemoryRecorder(maxTraces int) *MemoryRecorder {
+	return &MemoryRecorder{
+		maxTraces: maxTraces,
+		order:     list.New(),
+		traces:    make(map[TraceID]*memoryTrace),
+	}
+}
+
+// Record implements Recorder.
+func (r *MemoryRecorder) Record(traceID TraceID, span *SpanData) {
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.traces[traceID]
+	if !ok {
+		r.evictLocked()
+		t = &memoryTrace{elem: r.order.PushBack(traceID)}
+		r.traces[traceID] = t
+	}
+	if len(t.spans) >= maxSpansPerTrace {
+		return
+	}
+	t.spans = append(t.spans, span)
+}
+
+// Pin implements Pinner.
+func (r *MemoryRecorder) Pin(traceID TraceID) {
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.traces[traceID]
+	if !ok {
+		t = &memoryTrace{}
+		r.traces[traceID] = t
+	} else if t.elem != nil {
+		r.order.Remove(t.elem)
+		t.elem = nil
+	}
+	t.pins++
+}
+
+// Unpin implements Pinner. The trace is evicted as the newest one after it's
+// unpinned.
+func (r *MemoryRecorder) Unpin(traceID TraceID) {
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.traces[traceID]
+	if !ok || t.pins == 0 {
+		return
+	}
+	t.pins--
+	if t.pins > 0 {
+		return
+	}
+	if len(t.spans) == 0 {
+		delete(r.traces, traceID)
+		return
+	}
+	r.evictLocked()
+	t.elem = r.order.PushBack(traceID)
+}
+
+// evictLocked evicts the oldest traces to leave room for a new one.
+func (r *MemoryRecorder) evictLocked() {
+	for r.order.Len() >= r.maxTraces {
+		oldest := r.order.Front()
+		r.order.Remove(oldest)
+		delete(r.traces, oldest.Value.(TraceID))
+	}
+}
+
+// GetTrace returns the recorded spans of the trace, ordered by the finish time.
+func (r *MemoryRecorder) GetTrace(traceID TraceID) []*SpanData {
+	r.Lock()
+	defer r.Unlock()
+	t, ok := r.traces[traceID]
+	if !ok {
+		return nil
+	}
+	return append([]*SpanData(nil), t.spans...)
+}
@@ -0,0 +1,67 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import "sync/atomic"
+
+// Sampler decides whether a new trace is sampled when its root span starts.
+// The operations belonging to a trace which is not sampled are not traced.
+type Sampler interface {
+	ShouldSample(name string) bool
+}
+
+// RatioSampler samples the given fraction of the traces. It samples nothing if
+// the ratio is not positive, and samples all the traces if it's not less than 1.
+type RatioSampler float64
+
+// The samplers turning the tracing off and on.
+const (
+	NeverSample  = RatioSampler(0)
+	AlwaysSample = RatioSampler(1)
+)
+
+// ShouldSample implements Sampler.
+func (r RatioSampler) ShouldSample(string) bool {
+	switch {
+	case r <= 0:
+		return false
+	case r >= 1:
+		return true
+	}
+	idGenerator.Lock()
+	defer idGenerator.Unlock()
+	return idGenerator.r.Float64() < float64(r)
+}
+
+type samplerHolder struct {
+	Sampler
+}
+
+var sampler atomic.Value
+
+func init() {
+	SetSampler(NeverSample)
+}
+
+// SetSampler replaces the global sampler. The tracing is off until a sampler
+// is set.
+func SetSampler(s Sampler) {
+	sampler.Store(samplerHolder{s})
+}
+
+// GetSampler returns the global sampler.
+func GetSampler() Sampler {
+	return sampler.Load().(samplerHolder).Sampler
+}
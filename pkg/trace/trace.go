@@ -0,0 +1,279 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+// TraceID is the identifier of a trace. It has the same layout as the trace ID
+// of OpenTelemetry and W3C Trace Context.
+type TraceID [16]byte
+
+// IsValid returns whether the trace ID is not all zeros.
+func (t TraceID) IsValid() bool {
+	return t != TraceID{}
+}
+
+func (t TraceID) String() string {
+	return hex.EncodeToString(t[:])
+}
+
+// SpanID is the identifier of a span in a trace.
+type SpanID [8]byte
+
+// IsValid returns whether the span ID is not all zeros.
+func (s SpanID) IsValid() bool {
+	return s != SpanID{}
+}
+
+func (s SpanID) String() string {
+	return hex.EncodeToString(s[:])
+}
+
+// SpanContext is the part of a span which is propagated to its children.
+type SpanContext struct {
+	TraceID TraceID
+	SpanID  SpanID
+}
+
+// IsValid returns whether both the trace ID and the span ID are valid.
+func (sc SpanContext) IsValid() bool {
+	return sc.TraceID.IsValid() && sc.SpanID.IsValid()
+}
+
+// Traceparent encodes the span context as a W3C Trace Context `traceparent`
+// header with the sampled flag set.
+func (sc SpanContext) Traceparent() string {
+	return fmt.Sprintf("00-%s-%s-01", sc.TraceID, sc.SpanID)
+}
+
+// ParseTraceparent decodes a W3C Trace Context `traceparent` header.
+func ParseTraceparent(s string) (SpanContext, error) {
+	var sc SpanContext
+	parts := strings.Split(s, "-")
+	if len(parts) != 4 || len(parts[0]) != 2 {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	if err := decodeID(parts[1], sc.TraceID[:]); err != nil {
+		return sc, fmt.Errorf("invalid trace id in traceparent %q", s)
+	}
+	if err := decodeID(parts[2], sc.SpanID[:]); err != nil {
+		return sc, fmt.Errorf("invalid span id in traceparent %q", s)
+	}
+	if !sc.IsValid() {
+		return sc, fmt.Errorf("invalid traceparent %q", s)
+	}
+	return sc, nil
+}
+
+// ParseTraceID decodes a trace ID from its hex form.
+func ParseTraceID(s string) (TraceID, error) {
+	var id TraceID
+	if err := decodeID(s, id[:]); err != nil || !id.IsValid() {
+		return id, fmt.Errorf("invalid trace id %q", s)
+	}
+	return id, nil
+}
+
+func decodeID(s string, dst []byte) error {
+	if len(s) != hex.EncodedLen(len(dst)) {
+		return fmt.Errorf("invalid length")
+	}
+	_, err := hex.Decode(dst, []byte(s))
+	return err
+}
+
+var idGenerator = struct {
+	syncutil.Mutex
+	r *rand.Rand
+}{r: rand.New(rand.NewSource(time.Now().UnixNano()))}
+
+func newTraceID() (id TraceID) {
+	idGenerator.Lock()
+	defer idGenerator.Unlock()
+	for !id.IsValid() {
+		idGenerator.r.Read(id[:])
+	}
+	return
+}
+
+func newSpanID() (id SpanID) {
+	idGenerator.Lock()
+	defer idGenerator.Unlock()
+	for !id.IsValid() {
+		idGenerator.r.Read(id[:])
+	}
+	return
+}
+
+// Attribute is a key-value pair attached to a span.
+type Attribute struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// Span is a named and timed operation of a trace. All the methods of Span
+// can be called with a nil span, which does nothing, so that the callers
+// don't need to check whether the operation is traced.
+type Span struct {
+	mu         syncutil.Mutex
+	name       string
+	context    SpanContext
+	parentID   SpanID
+	startTime  time.Time
+	attributes []Attribute
+	finished   bool
+	// pinner keeps the trace in the recorder until the span finishes.
+	pinner Pinner
+}
+
+// SpanData is the snapshot of a finished span.
+type SpanData struct {
+	Name       string        `json:"name"`
+	TraceID    string        `json:"trace-id"`
+	SpanID     string        `json:"span-id"`
+	ParentID   string        `json:"parent-id,omitempty"`
+	StartTime  time.Time     `json:"start-time"`
+	Duration   time.Duration `json:"duration"`
+	Attributes []Attribute   `json:"attributes,omitempty"`
+}
+
+// StartRootSpan starts a span of a new trace. It returns a nil span if the
+// trace is not sampled.
+func StartRootSpan(name string) *Span {
+	if !GetSampler().ShouldSample(name) {
+		return nil
+	}
+	return &Span{
+		name:      name,
+		context:   SpanContext{TraceID: newTraceID(), SpanID: newSpanID()},
+		startTime: time.Now(),
+	}
+}
+
+// StartChildSpan starts a span as the child of the span context carried by
+// the context. The operation is traced only if it belongs to a trace, so it
+// returns a nil span and the unchanged context if there is no span context.
+func StartChildSpan(ctx context.Context, name string) (*Span, context.Context) {
+	parent := SpanContextFromContext(ctx)
+	if !parent.IsValid() {
+		return nil, ctx
+	}
+	span := &Span{
+		name:      name,
+		context:   SpanContext{TraceID: parent.TraceID, SpanID: newSpanID()},
+		parentID:  parent.SpanID,
+		startTime: time.Now(),
+	}
+	return span, ContextWithSpanContext(ctx, span.context)
+}
+
+// Context returns the span context of the span.
+func (s *Span) Context() SpanContext {
+	if s == nil {
+		return SpanContext{}
+	}
+	return s.context
+}
+
+// SetAttribute attaches a key-value pair to the span.
+func (s *Span) SetAttribute(key string, value interface{}) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.attributes = append(s.attributes, Attribute{Key: key, Value: fmt.Sprint(value)})
+}
+
+// Finish ends the span and hands it to the recorder. Only the first call
+// takes effect.
+func (s *Span) Finish() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.finished {
+		s.mu.Unlock()
+		return
+	}
+	s.finished = true
+	data := &SpanData{
+		Name:       s.name,
+		TraceID:    s.context.TraceID.String(),
+		SpanID:     s.context.SpanID.String(),
+		StartTime:  s.startTime,
+		Duration:   time.Since(s.startTime),
+		Attributes: append([]Attribute(nil), s.attributes...),
+	}
+	if s.parentID.IsValid() {
+		data.ParentID = s.parentID.String()
+	}
+	pinner := s.pinner
+	s.mu.Unlock()
+	GetRecorder().Record(s.context.TraceID, data)
+	if pinner != nil {
+		pinner.Unpin(s.context.TraceID)
+	}
+}
+
+// Pin keeps the trace of the span from being evicted by the newer traces
+// until the span finishes, e.g. the trace of a running operator. It takes
+// effect only if the recorder implements Pinner.
+func (s *Span) Pin() {
+	if s == nil {
+		return
+	}
+	pinner, ok := GetRecorder().(Pinner)
+	if !ok {
+		return
+	}
+	s.mu.Lock()
+	if s.finished || s.pinner != nil {
+		s.mu.Unlock()
+		return
+	}
+	s.pinner = pinner
+	s.mu.Unlock()
+	pinner.Pin(s.context.TraceID)
+}
+
+type spanContextKey struct{}
+
+// ContextWithSpanContext returns a copy of ctx which carries the span context.
+func ContextWithSpanContext(ctx context.Context, sc SpanContext) context.Context {
+	if !sc.IsValid() {
+		return ctx
+	}
+	return context.WithValue(ctx, spanContextKey{}, sc)
+}
+
+// SpanContextFromContext returns the span context carried by ctx, or an
+// invalid span context if there is none.
+func SpanContextFromContext(ctx context.Context) SpanContext {
+	if ctx == nil {
+		return SpanContext{}
+	}
+	sc, _ := ctx.Value(spanContextKey{}).(SpanContext)
+	return sc
+}
@@ -0,0 +1,154 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package trace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTraceparent(t *testing.T) {
+	re := require.New(t)
+	defer SetSampler(GetSampler())
+	SetSampler(AlwaysSample)
+	span := StartRootSpan("root")
+	sc := span.Context()
+	re.True(sc.IsValid())
+	parsed, err := ParseTraceparent(sc.Traceparent())
+	re.NoError(err)
+	re.Equal(sc, parsed)
+
+	parsed, err = ParseTraceparent("00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	re.NoError(err)
+	re.Equal("4bf92f3577b34da6a3ce929d0e0e4736", parsed.TraceID.String())
+	re.Equal("00f067aa0ba902b7", parsed.SpanID.String())
+
+	for _, s := range []string{
+		"",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7",
+		"00-4bf92f3577b34da6a3ce929d0e0e473-00f067aa0ba902b7-01",
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01",
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-zzf067aa0ba902b7-01",
+	} {
+		_, err = ParseTraceparent(s)
+		re.Error(err, s)
+	}
+}
+
+func TestSpan(t *testing.T) {
+	re := require.New(t)
+	recorder := NewMemoryRecorder(DefaultMaxTraces)
+	defer SetRecorder(GetRecorder())
+	SetRecorder(recorder)
+	defer SetSampler(GetSampler())
+	SetSampler(AlwaysSample)
+
+	// the operation is not traced without a parent.
+	span, ctx := StartChildSpan(context.Background(), "child")
+	re.Nil(span)
+	re.False(SpanContextFromContext(ctx).IsValid())
+	span.SetAttribute("key", "value")
+	span.Finish()
+
+	root := StartRootSpan("root")
+	ctx = ContextWithSpanContext(context.Background(), root.Context())
+	child, ctx := StartChildSpan(ctx, "child")
+	re.NotNil(child)
+	re.Equal(root.Context().TraceID, child.Context().TraceID)
+	re.Equal(child.Context(), SpanContextFromContext(ctx))
+	child.SetAttribute("region-id", 1)
+	child.Finish()
+	child.Finish()
+	root.Finish()
+
+	spans := recorder.GetTrace(root.Context().TraceID)
+	re.Len(spans, 2)
+	re.Equal("child", spans[0].Name)
+	re.Equal(root.Context().SpanID.String(), spans[0].ParentID)
+	re.Equal([]Attribute{{Key: "region-id", Value: "1"}}, spans[0].Attributes)
+	re.Equal("root", spans[1].Name)
+	re.Empty(spans[1].ParentID)
+}
+
+func TestMemoryRecorder(t *testing.T) {
+	re := require.New(t)
+	recorder := NewMemoryRecorder(2)
+	ids := []TraceID{newTraceID(), newTraceID(), newTraceID()}
+	for _, id := range ids {
+		recorder.Record(id, &SpanData{Name: "span"})
+	}
+	// the oldest trace is evicted.
+	re.Empty(recorder.GetTrace(ids[0]))
+	re.Len(recorder.GetTrace(ids[1]), 1)
+	re.Len(recorder.GetTrace(ids[2]), 1)
+
+	for i := 0; i < maxSpansPerTrace+10; i++ {
+		recorder.Record(ids[2], &SpanData{Name: "span"})
+	}
+	re.Len(recorder.GetTrace(ids[2]), maxSpansPerTrace)
+}
+
+func TestSampler(t *testing.T) {
+	re := require.New(t)
+	defer SetSampler(GetSampler())
+
+	// the tracing is off by default.
+	re.Nil(StartRootSpan("root"))
+	SetSampler(AlwaysSample)
+	re.NotNil(StartRootSpan("root"))
+
+	re.False(RatioSampler(-1).ShouldSample("root"))
+	re.True(RatioSampler(2).ShouldSample("root"))
+	sampled := 0
+	for i := 0; i < 1000; i++ {
+		if RatioSampler(0.5).ShouldSample("root") {
+			sampled++
+		}
+	}
+	re.Greater(sampled, 300)
+	re.Less(sampled, 700)
+}
+
+func TestPinnedTrace(t *testing.T) {
+	re := require.New(t)
+	recorder := NewMemoryRecorder(2)
+	defer SetRecorder(GetRecorder())
+	SetRecorder(recorder)
+	defer SetSampler(GetSampler())
+	SetSampler(AlwaysSample)
+
+	root := StartRootSpan("root")
+	root.Pin()
+	child, _ := StartChildSpan(ContextWithSpanContext(context.Background(), root.Context()), "child")
+	child.Finish()
+	// the pinned trace is not evicted by the newer traces.
+	ids := []TraceID{newTraceID(), newTraceID(), newTraceID()}
+	for _, id := range ids {
+		recorder.Record(id, &SpanData{Name: "span"})
+	}
+	re.Empty(recorder.GetTrace(ids[0]))
+	re.Len(recorder.GetTrace(root.Context().TraceID), 1)
+
+	// it's evicted as the newest trace after the span finishes.
+	root.Finish()
+	re.Len(recorder.GetTrace(root.Context().TraceID), 2)
+	re.Empty(recorder.GetTrace(ids[1]))
+	recorder.Record(newTraceID(), &SpanData{Name: "span"})
+	re.Len(recorder.GetTrace(root.Context().TraceID), 2)
+	recorder.Record(newTraceID(), &SpanData{Name: "span"})
+	re.Empty(recorder.GetTrace(root.Context().TraceID))
+}
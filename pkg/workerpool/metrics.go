@@ -0,0 +1,58 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import "github.com/prometheus/client_golang/prometheus"
+
+var (
+	sizeGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "worker_pool",
+			Name:      "size",
+			Help:      "The number of the workers of the pool.",
+		}, []string{"pool"})
+
+	busyGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "worker_pool",
+			Name:      "busy_workers",
+			Help:      "The number of the workers running tasks.",
+		}, []string{"pool"})
+
+	queueDepthGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "worker_pool",
+			Name:      "queue_depth",
+			Help:      "The number of the tasks waiting for the workers.",
+		}, []string{"pool"})
+
+	busySecondsCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "worker_pool",
+			Name:      "busy_seconds_total",
+			Help:      "The total time spent by the workers running tasks, its rate divided by the size is the utilization.",
+		}, []string{"pool"})
+)
+
+func init() {
+	prometheus.MustRegister(sizeGauge)
+	prometheus.MustRegister(busyGauge)
+	prometheus.MustRegister(queueDepthGauge)
+	prometheus.MustRegister(busySecondsCounter)
+}
@@ -0,0 +1,396 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"sort"
+	"sync/atomic"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+// Status is the status of a worker pool.
+type Status struct {
+	Name string `json:"name"`
+	// Size is the number of the workers.
+	Size int `json:"size"`
+	// DefaultSize is the size used when it is not configured.
+	DefaultSize int `json:"default-size"`
+	// Busy is the number of the workers running tasks.
+	Busy int `json:"busy"`
+	// QueueDepth is the number of the tasks waiting for the workers.
+	QueueDepth int `json:"queue-depth"`
+	// Utilization is the ratio of the busy workers.
+	Utilization float64 `json:"utilization"`
+}
+
+type keyedTask struct {
+	key  uint64
+	task func()
+}
+
+type worker struct {
+	// queue holds the keyed tasks, which are run by this worker only.
+	queue chan func()
+	stop  chan struct{}
+}
+
+// Pool is a pool of the workers, whose size can be adjusted at runtime. The
+// tasks are either shared by all the workers, or pinned to a worker by their
+// keys to keep the order of the tasks with the same key.
+type Pool struct {
+	ctx         context.Context
+	name        string
+	defaultSize int
+	queueCap    int
+	shared      chan func()
+	busy        int32
+
+	mu      syncutil.RWMutex
+	workers []*worker
+
+	// resizeMu serializes the resizes.
+	resizeMu syncutil.Mutex
+	// keyedMu protects pendingKeyed, drained and held.
+	keyedMu syncutil.Mutex
+	// pendingKeyed counts the keyed tasks which are not finished. A resize waits
+	// for them, so that the tasks with the same key never run out of order.
+	pendingKeyed int
+	// drained is closed once the pending keyed tasks are finished during a
+	// resize. It's nil if no resize is in progress.
+	drained chan struct{}
+	// held keeps the keyed tasks submitted during a resize in order, they are
+	// put into the queues of the workers after the resize.
+	held []keyedTask
+}
+
+// New creates a worker pool with the given size, the capacity of the queues
+// is queueCap. The workers exit when the context is done.
+func New(ctx context.Context, name string, size, queueCap int) *Pool {
+	if size < 1 {
+		size = 1
+	}
+	p := &Pool{
+		ctx:         ctx,
+		name:        name,
+		defaultSize: size,
+		queueCap:    queueCap,
+		shared:      make(chan func(), queueCap),
+	}
+	p.Resize(size)
+	return p
+}
+
+// Name returns the name of the pool.
+func (p *Pool) Name() string {
+	return p.name
+}
+
+// DefaultSize returns the size of the pool when it is not configured.
+func (p *Pool) DefaultSize() int {
+	return p.defaultSize
+}
+
+// Size returns the number of the workers.
+func (p *Pool) Size() int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.workers)
+}
+
+// Resize adjusts the number of the workers, which is at least 1. It waits for
+// the queued keyed tasks without blocking the other operations of the pool, and
+// the new keyed tasks are held meanwhile and queued in order after the resize.
+// It must not be called in a keyed task. It stops waiting once the pool is stopped.
+func (p *Pool) Resize(size int) {
+	if size < 1 {
+		size = 1
+	}
+	p.resizeMu.Lock()
+	defer p.resizeMu.Unlock()
+
+	p.keyedMu.Lock()
+	drained := make(chan struct{})
+	if p.pendingKeyed == 0 {
+		close(drained)
+	}
+	p.drained = drained
+	p.keyedMu.Unlock()
+	select {
+	case <-drained:
+	case <-p.ctx.Done():
+		// the workers exit, so the pending keyed tasks are never finished.
+	}
+
+	p.resizeLocked(size)
+	p.releaseHeld()
+}
+
+func (p *Pool) resizeLocked(size int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	for len(p.workers) < size {
+		w := &worker{
+			queue: make(chan func(), p.queueCap),
+			stop:  make(chan struct{}),
+		}
+		p.workers = append(p.workers, w)
+		go p.run(w)
+	}
+	for len(p.workers) > size {
+		last := len(p.workers) - 1
+		close(p.workers[last].stop)
+		p.workers[last] = nil
+		p.workers = p.workers[:last]
+	}
+	sizeGauge.WithLabelValues(p.name).Set(float64(size))
+}
+
+// releaseHeld queues the keyed tasks held during the resize. The tasks held
+// while releasing are released in the next round, so that the order is kept.
+func (p *Pool) releaseHeld() {
+	for {
+		p.keyedMu.Lock()
+		held := p.held
+		p.held = nil
+		if len(held) == 0 {
+			p.drained = nil
+			p.keyedMu.Unlock()
+			return
+		}
+		p.pendingKeyed += len(held)
+		p.keyedMu.Unlock()
+		for _, t := range held {
+			if !p.queueKeyed(t, true) {
+				p.finishKeyed()
+			}
+		}
+	}
+}
+
+// queueKeyed puts the keyed task into the queue of its worker. If wait is true,
+// it waits for the queue until the pool is stopped, otherwise it returns false
+// at once if the queue is full.
+func (p *Pool) queueKeyed(t keyedTask, wait bool) bool {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	w := p.workers[t.key%uint64(len(p.workers))]
+	task := func() {
+		defer p.finishKeyed()
+		t.task()
+	}
+	if wait {
+		select {
+		case w.queue <- task:
+			return true
+		case <-p.ctx.Done():
+			return false
+		}
+	}
+	select {
+	case w.queue <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// Submit puts the task into the shared queue, it blocks if the queue is full.
+// It returns false if the pool is stopped.
+func (p *Pool) Submit(task func()) bool {
+	select {
+	case p.shared <- task:
+		return true
+	case <-p.ctx.Done():
+		return false
+	}
+}
+
+// TrySubmit puts the task into the shared queue, it returns false at once if
+// the queue is full.
+func (p *Pool) TrySubmit(task func()) bool {
+	select {
+	case p.shared <- task:
+		return true
+	default:
+		return false
+	}
+}
+
+// TrySubmitKeyed puts the task into the queue of the worker of the key, it
+// returns false at once if the queue is full. The tasks with the same key run
+// in the order they are submitted. During a resize, the task is held until the
+// resize is done, and it returns false if queueCap tasks are already held.
+func (p *Pool) TrySubmitKeyed(key uint64, task func()) bool {
+	p.keyedMu.Lock()
+	if p.drained != nil {
+		defer p.keyedMu.Unlock()
+		if len(p.held) >= p.queueCap {
+			return false
+		}
+		p.held = append(p.held, keyedTask{key: key, task: task})
+		return true
+	}
+	p.pendingKeyed++
+	p.keyedMu.Unlock()
+
+	if !p.queueKeyed(keyedTask{key: key, task: task}, false) {
+		p.finishKeyed()
+		return false
+	}
+	return true
+}
+
+func (p *Pool) finishKeyed() {
+	p.keyedMu.Lock()
+	defer p.keyedMu.Unlock()
+	p.pendingKeyed--
+	if p.pendingKeyed == 0 && p.drained != nil {
+		select {
+		case <-p.drained:
+			// the held tasks are being released after the drain.
+		default:
+			close(p.drained)
+		}
+	}
+}
+
+// KeyedQueueLen returns the number of the tasks waiting in the queue of the
+// worker of the key.
+func (p *Pool) KeyedQueueLen(key uint64) int {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+	return len(p.workers[key%uint64(len(p.workers))].queue)
+}
+
+// Status returns the status of the pool.
+func (p *Pool) Status() Status {
+	p.mu.RLock()
+	size, depth := len(p.workers), len(p.shared)
+	for _, w := range p.workers {
+		depth += len(w.queue)
+	}
+	p.mu.RUnlock()
+	busy := int(atomic.LoadInt32(&p.busy))
+	return Status{
+		Name:        p.name,
+		Size:        size,
+		DefaultSize: p.defaultSize,
+		Busy:        busy,
+		QueueDepth:  depth,
+		Utilization: float64(busy) / float64(size),
+	}
+}
+
+// collect updates the metrics of the pool.
+func (p *Pool) collect() {
+	s := p.Status()
+	busyGauge.WithLabelValues(p.name).Set(float64(s.Busy))
+	queueDepthGauge.WithLabelValues(p.name).Set(float64(s.QueueDepth))
+}
+
+func (p *Pool) run(w *worker) {
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-w.stop:
+			return
+		case task := <-w.queue:
+			p.execute(task)
+		case task := <-p.shared:
+			p.execute(task)
+		}
+	}
+}
+
+func (p *Pool) execute(task func()) {
+	atomic.AddInt32(&p.busy, 1)
+	start := time.Now()
+	defer func() {
+		busySecondsCounter.WithLabelValues(p.name).Add(time.Since(start).Seconds())
+		atomic.AddInt32(&p.busy, -1)
+	}()
+	task()
+}
+
+// Registry holds the worker pools by their names.
+type Registry struct {
+	mu    syncutil.RWMutex
+	pools map[string]*Pool
+}
+
+// NewRegistry creates a Registry.
+func NewRegistry() *Registry {
+	return &Registry{pools: make(map[string]*Pool)}
+}
+
+// Register adds the pool to the registry, the pool with the same name is replaced.
+func (r *Registry) Register(p *Pool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.pools[p.name] = p
+}
+
+// Get returns the pool with the name, or nil if it does not exist.
+func (r *Registry) Get(name string) *Pool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.pools[name]
+}
+
+// Statuses returns the status of all the pools sorted by their names.
+func (r *Registry) Statuses() []Status {
+	pools := r.list()
+	statuses := make([]Status, 0, len(pools))
+	for _, p := range pools {
+		statuses = append(statuses, p.Status())
+	}
+	return statuses
+}
+
+// ApplySizes resizes the pools to the given sizes, the pools without a
+// positive size are resized to their default sizes.
+func (r *Registry) ApplySizes(sizes map[string]int) {
+	for _, p := range r.list() {
+		size := sizes[p.name]
+		if size <= 0 {
+			size = p.defaultSize
+		}
+		if p.Size() != size {
+			p.Resize(size)
+		}
+	}
+}
+
+// Collect updates the metrics of all the pools.
+func (r *Registry) Collect() {
+	for _, p := range r.list() {
+		p.collect()
+	}
+}
+
+func (r *Registry) list() []*Pool {
+	r.mu.RLock()
+	pools := make([]*Pool, 0, len(r.pools))
+	for _, p := range r.pools {
+		pools = append(pools, p)
+	}
+	r.mu.RUnlock()
+	sort.Slice(pools, func(i, j int) bool { return pools[i].name < pools[j].name })
+	return pools
+}
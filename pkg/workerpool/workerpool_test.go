@@ -0,0 +1,164 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package workerpool
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/testutil"
+)
+
+func TestResize(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := New(ctx, "test", 2, 10)
+	re.Equal(2, p.Size())
+
+	// block all the workers to check the busy workers and the queue depth.
+	block := make(chan struct{})
+	var started, done sync.WaitGroup
+	started.Add(2)
+	done.Add(3)
+	for i := 0; i < 3; i++ {
+		re.True(p.Submit(func() {
+			started.Done()
+			<-block
+			done.Done()
+		}))
+	}
+	started.Wait()
+	status := p.Status()
+	re.Equal(2, status.Busy)
+	re.Equal(1, status.QueueDepth)
+	re.Equal(1.0, status.Utilization)
+	close(block)
+	done.Wait()
+
+	p.Resize(4)
+	re.Equal(4, p.Size())
+	p.Resize(0)
+	re.Equal(1, p.Size())
+	re.Equal(2, p.DefaultSize())
+}
+
+func TestKeyedOrder(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := New(ctx, "test-keyed", 3, 1000)
+
+	var mu sync.Mutex
+	results := make(map[uint64][]int)
+	var wg sync.WaitGroup
+	for i := 0; i < 300; i++ {
+		key, seq := uint64(i%5), i
+		wg.Add(1)
+		re.True(p.TrySubmitKeyed(key, func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			results[key] = append(results[key], seq)
+		}))
+		// resizing in the middle keeps the order of the tasks with the same key.
+		if i == 150 {
+			p.Resize(2)
+		}
+	}
+	wg.Wait()
+	for key, seqs := range results {
+		re.Len(seqs, 60)
+		for i, seq := range seqs {
+			re.Equal(int(key)+i*5, seq)
+		}
+	}
+}
+
+func TestResizeWithPendingKeyed(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	p := New(ctx, "test-pending", 1, 10)
+
+	block := make(chan struct{})
+	re.True(p.TrySubmitKeyed(1, func() { <-block }))
+	resized := make(chan struct{})
+	go func() {
+		p.Resize(2)
+		close(resized)
+	}()
+	testutil.Eventually(re, func() bool {
+		p.keyedMu.Lock()
+		defer p.keyedMu.Unlock()
+		return p.drained != nil
+	})
+	// the keyed tasks are held until the pending ones are finished, and they run
+	// in order after the resize.
+	var (
+		mu   sync.Mutex
+		wg   sync.WaitGroup
+		seqs []int
+	)
+	for i := 0; i < 10; i++ {
+		seq := i
+		wg.Add(1)
+		re.True(p.TrySubmitKeyed(uint64(i), func() {
+			defer wg.Done()
+			mu.Lock()
+			defer mu.Unlock()
+			seqs = append(seqs, seq)
+		}))
+	}
+	// at most queueCap tasks are held.
+	re.False(p.TrySubmitKeyed(2, func() {}))
+	re.Equal(1, p.Size())
+	close(block)
+	<-resized
+	wg.Wait()
+	re.Equal(2, p.Size())
+	re.Len(seqs, 10)
+	re.True(p.TrySubmitKeyed(2, func() {}))
+
+	// the resize doesn't wait for the pending keyed tasks after the pool is stopped.
+	block = make(chan struct{})
+	defer close(block)
+	re.True(p.TrySubmitKeyed(1, func() { <-block }))
+	cancel()
+	p.Resize(3)
+	re.Equal(3, p.Size())
+}
+
+func TestRegistry(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	r := NewRegistry()
+	r.Register(New(ctx, "b", 2, 10))
+	r.Register(New(ctx, "a", 1, 10))
+	re.Nil(r.Get("c"))
+
+	r.ApplySizes(map[string]int{"a": 3, "c": 4})
+	statuses := r.Statuses()
+	re.Len(statuses, 2)
+	re.Equal("a", statuses[0].Name)
+	re.Equal(3, statuses[0].Size)
+	re.Equal(2, statuses[1].Size)
+
+	// the pools without the sizes are reset to their default sizes.
+	r.ApplySizes(nil)
+	re.Equal(1, r.Get("a").Size())
+}
@@ -0,0 +1,27 @@
+#!/usr/bin/env bash
+# Copyright 2022 TiKV Project Authors.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+#
+# Generates pkg/regionquerypb/region_query.pb.go from region_query.proto. The
+# imported messages, such as pdpb.Region, come from the kvproto module in use,
+# and the code is generated the same way as kvproto's, so that the messages can
+# be embedded into each other.
+set -euo pipefail
+
+cd -P .
+
+KVPROTO_DIR=$(go list -m -f '{{.Dir}}' github.com/pingcap/kvprotov2)
+cd pkg/regionquerypb
+protoc -I. -I"${KVPROTO_DIR}/proto" -I"${KVPROTO_DIR}/include" \
+	--gogofaster_out=plugins=grpc,paths=source_relative:. region_query.proto
@@ -15,14 +15,20 @@
 package api
 
 import (
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"io"
+	"mime"
 	"net/http"
 	"strconv"
+	"time"
 
 	"github.com/gorilla/mux"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/unrolled/render"
 )
 
@@ -44,6 +50,7 @@ func newAdminHandler(svr *server.Server, rd *render.Render) *adminHandler {
 // @Produce  json
 // @Success  200  {string}  string  "The region is removed from server cache."
 // @Failure  400  {string}  string  "The input is invalid."
+// @Failure  429  {string}  string  "Dropping the region cache is rate limited."
 // @Router   /admin/cache/region/{id} [delete]
 func (h *adminHandler) DeleteRegionCache(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r)
@@ -54,21 +61,83 @@ func (h *adminHandler) DeleteRegionCache(w http.ResponseWriter, r *http.Request)
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	rc.DropCacheRegion(regionID)
+	if err := rc.GuardedDropCacheRegion(regionID); err != nil {
+		h.rd.JSON(w, http.StatusTooManyRequests, err.Error())
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, "The region is removed from server cache.")
 }
 
 // @Tags     admin
 // @Summary  Drop all regions from cache.
+// @Param    token  query  string  false  "The token issued by /admin/cache/regions/token, required in the safe mode."
 // @Produce  json
 // @Success  200  {string}  string  "All regions are removed from server cache."
+// @Failure  403  {string}  string  "The token is missing or invalid."
 // @Router   /admin/cache/regions [delete]
 func (h *adminHandler) DeleteAllRegionCache(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r)
-	rc.DropCacheAllRegion()
+	if _, err := rc.GuardedDropCacheAllRegion(r.URL.Query().Get("token")); err != nil {
+		h.rd.JSON(w, http.StatusForbidden, err.Error())
+		return
+	}
 	h.rd.JSON(w, http.StatusOK, "All regions are removed from server cache.")
 }
 
+// RegionCacheDropToken is the token to drop all regions from cache.
+type RegionCacheDropToken struct {
+	Token  string    `json:"token"`
+	Expire time.Time `json:"expire"`
+}
+
+// @Tags     admin
+// @Summary  Issue a one-time token to drop all regions from cache.
+// @Produce  json
+// @Success  200  {object}  RegionCacheDropToken
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/cache/regions/token [post]
+func (h *adminHandler) IssueRegionCacheDropToken(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	token, expire, err := rc.IssueRegionCacheDropToken()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, RegionCacheDropToken{Token: token, Expire: expire})
+}
+
+// @Tags     admin
+// @Summary  List the regions dropped from cache which can be restored.
+// @Produce  json
+// @Success  200  {array}  cluster.DroppedRegion
+// @Router   /admin/cache/dropped [get]
+func (h *adminHandler) GetDroppedRegionCache(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	h.rd.JSON(w, http.StatusOK, rc.GetDroppedCacheRegions())
+}
+
+// @Tags     admin
+// @Summary  Restore the regions dropped from cache which have not been reported again.
+// @Param    region_id  query  integer  false  "The ID of the region to restore, can be repeated. All the dropped regions are restored if it is not given."
+// @Produce  json
+// @Success  200  {object}  map[string]int
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /admin/cache/dropped/restore [post]
+func (h *adminHandler) RestoreDroppedRegionCache(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var regionIDs []uint64
+	for _, idStr := range r.URL.Query()["region_id"] {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		regionIDs = append(regionIDs, id)
+	}
+	restored := rc.RestoreDroppedCacheRegions(regionIDs)
+	h.rd.JSON(w, http.StatusOK, map[string]int{"restored": restored})
+}
+
 // FIXME: details of input json body params
 // @Tags     admin
 // @Summary  Reset the ts.
@@ -107,23 +176,588 @@ func (h *adminHandler) ResetTS(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, "Reset ts successfully.")
 }
 
+// RestoreModeInput is the input of the restore mode API.
+type RestoreModeInput struct {
+	// StartKey and EndKey are the hex encoded key range being restored.
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	// StoreLimit is the store limit rate used during the restore window.
+	StoreLimit float64 `json:"store_limit"`
+	// TTLSecond is the length of the restore window.
+	TTLSecond int64 `json:"ttl_second"`
+}
+
+// @Tags     admin
+// @Summary  Enable the restore mode for a window of BR restore.
+// @Accept   json
+// @Param    body  body  RestoreModeInput  true  "The restore window"
+// @Produce  json
+// @Success  200  {string}  string  "The restore mode is enabled."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/restore-mode [post]
+func (h *adminHandler) EnableRestoreMode(w http.ResponseWriter, r *http.Request) {
+	var input RestoreModeInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	startKey, err := hex.DecodeString(input.StartKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "start_key should be in hex format")
+		return
+	}
+	endKey, err := hex.DecodeString(input.EndKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "end_key should be in hex format")
+		return
+	}
+	if input.TTLSecond <= 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "ttl_second should be positive")
+		return
+	}
+	if input.StoreLimit <= 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "store_limit should be positive")
+		return
+	}
+	err = h.svr.GetHandler().EnableRestoreMode(startKey, endKey, input.StoreLimit, time.Duration(input.TTLSecond)*time.Second)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The restore mode is enabled.")
+}
+
+// @Tags     admin
+// @Summary  Disable the restore mode before the restore window expires.
+// @Produce  json
+// @Success  200  {string}  string  "The restore mode is disabled."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/restore-mode [delete]
+func (h *adminHandler) DisableRestoreMode(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.GetHandler().DisableRestoreMode(); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The restore mode is disabled.")
+}
+
+// @Tags     admin
+// @Summary  Get the label rule of the current restore window.
+// @Produce  json
+// @Success  200  {object}  labeler.LabelRule
+// @Failure  404  {string}  string  "The restore mode is not enabled."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/restore-mode [get]
+func (h *adminHandler) GetRestoreMode(w http.ResponseWriter, r *http.Request) {
+	rule, err := h.svr.GetHandler().GetRestoreMode()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if rule == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The restore mode is not enabled.")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, rule)
+}
+
+// ReplicaFreezeInput is the input of the replica freeze API.
+type ReplicaFreezeInput struct {
+	// ID identifies the freeze, e.g. the name of the backup.
+	ID string `json:"id"`
+	// StartKey and EndKey are the hex encoded key range to freeze.
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	// TTLSecond is the time before the replicas are thawed automatically.
+	TTLSecond int64 `json:"ttl_second"`
+}
+
+// @Tags     admin
+// @Summary  Freeze the replicas of the regions in a key range, only their leaders can be transferred before they are thawed.
+// @Accept   json
+// @Param    body  body  ReplicaFreezeInput  true  "The key range to freeze"
+// @Produce  json
+// @Success  200  {object}  cluster.ReplicaFreeze
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/replica-freeze [post]
+func (h *adminHandler) FreezeReplicas(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var input ReplicaFreezeInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.ID == "" {
+		h.rd.JSON(w, http.StatusBadRequest, "id should not be empty")
+		return
+	}
+	startKey, err := hex.DecodeString(input.StartKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "start_key should be in hex format")
+		return
+	}
+	endKey, err := hex.DecodeString(input.EndKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "end_key should be in hex format")
+		return
+	}
+	if input.TTLSecond <= 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "ttl_second should be positive")
+		return
+	}
+	freeze, err := rc.FreezeReplicas(input.ID, startKey, endKey, time.Duration(input.TTLSecond)*time.Second)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, freeze)
+}
+
+// @Tags     admin
+// @Summary  List the replica freezes which are not expired.
+// @Produce  json
+// @Success  200  {array}  cluster.ReplicaFreeze
+// @Router   /admin/replica-freeze [get]
+func (h *adminHandler) GetReplicaFreezes(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	h.rd.JSON(w, http.StatusOK, rc.GetReplicaFreezes())
+}
+
+// @Tags     admin
+// @Summary  Thaw the replicas before the freeze expires.
+// @Param    id  path  string  true  "The id of the freeze"
+// @Produce  json
+// @Success  200  {string}  string  "The replicas are thawed."
+// @Failure  404  {string}  string  "The freeze is not found."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/replica-freeze/{id} [delete]
+func (h *adminHandler) ThawReplicas(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	err := rc.ThawReplicas(mux.Vars(r)["id"])
+	if err != nil {
+		if errs.ErrRegionRuleNotFound.Equal(err) {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The replicas are thawed.")
+}
+
+// BreakGlassInput is the input of the break-glass mode API.
+type BreakGlassInput struct {
+	// Checks are the safety checks to loosen, which are "remove-store",
+	// "frozen-replica", "schedule-disabled" and "paused-scheduler".
+	Checks []string `json:"checks"`
+	// Reason is required to explain why the checks are loosened.
+	Reason string `json:"reason"`
+	// TTLSecond is the time before the checks are restored automatically.
+	TTLSecond int64 `json:"ttl_second"`
+}
+
+// @Tags     admin
+// @Summary  Loosen the selected safety checks for a bounded time, every action taken by bypassing them is recorded as a cluster event and audited.
+// @Accept   json
+// @Param    body  body  BreakGlassInput  true  "The checks to loosen"
+// @Produce  json
+// @Success  200  {object}  cluster.BreakGlassMode
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  409  {string}  string  "The break-glass mode is already enabled."
+// @Router   /admin/break-glass [post]
+func (h *adminHandler) EnableBreakGlass(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var input BreakGlassInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	mode, err := rc.EnableBreakGlass(input.Checks, input.Reason, time.Duration(input.TTLSecond)*time.Second)
+	if err != nil {
+		if errs.ErrBreakGlassEnabled.Equal(err) {
+			h.rd.JSON(w, http.StatusConflict, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, mode)
+}
+
+// @Tags     admin
+// @Summary  Get the break-glass mode with the actions taken under it.
+// @Produce  json
+// @Success  200  {object}  cluster.BreakGlassMode
+// @Failure  404  {string}  string  "The break-glass mode is not enabled."
+// @Router   /admin/break-glass [get]
+func (h *adminHandler) GetBreakGlass(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	mode := rc.GetBreakGlassMode()
+	if mode == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The break-glass mode is not enabled.")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, mode)
+}
+
+// @Tags     admin
+// @Summary  Restore the safety checks before the break-glass mode expires.
+// @Produce  json
+// @Success  200  {string}  string  "The break-glass mode is disabled."
+// @Failure  404  {string}  string  "The break-glass mode is not enabled."
+// @Router   /admin/break-glass [delete]
+func (h *adminHandler) DisableBreakGlass(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	if err := rc.DisableBreakGlass(); err != nil {
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The break-glass mode is disabled.")
+}
+
+// ImportModeInput is the input of the import mode API.
+type ImportModeInput struct {
+	// ID identifies the import, e.g. the name of the Lightning task.
+	ID string `json:"id"`
+	// StartKey and EndKey are the hex encoded key range to import.
+	StartKey string `json:"start_key"`
+	EndKey   string `json:"end_key"`
+	// RegionCount is the number of the regions pre-split in the key range.
+	RegionCount int `json:"region_count"`
+	// StoreLimit is the store limit rate used during the import.
+	StoreLimit float64 `json:"store_limit"`
+	// Tolerance is the max deviation of the leader counts from the average,
+	// in proportion to the average, when the key range is ready to import.
+	Tolerance float64 `json:"tolerance"`
+	// TTLSecond is the length of the import window.
+	TTLSecond int64 `json:"ttl_second"`
+}
+
+// @Tags     admin
+// @Summary  Prepare a key range for the bulk import, the regions are pre-split and scattered by a task in the background.
+// @Accept   json
+// @Param    body  body  ImportModeInput  true  "The key range to import"
+// @Produce  json
+// @Success  200  {object}  cluster.ImportModeStatus
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/import-mode [post]
+func (h *adminHandler) EnableImportMode(w http.ResponseWriter, r *http.Request) {
+	var input ImportModeInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	startKey, err := hex.DecodeString(input.StartKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "start_key should be in hex format")
+		return
+	}
+	endKey, err := hex.DecodeString(input.EndKey)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "end_key should be in hex format")
+		return
+	}
+	status, err := h.svr.GetHandler().EnableImportMode(input.ID, startKey, endKey, input.RegionCount,
+		input.StoreLimit, input.Tolerance, time.Duration(input.TTLSecond)*time.Second)
+	if err != nil {
+		if errs.ErrImportModeInvalid.Equal(err) {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, status)
+}
+
+// @Tags     admin
+// @Summary  List the key ranges prepared for the bulk import.
+// @Produce  json
+// @Success  200  {array}   cluster.ImportModeStatus
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/import-mode [get]
+func (h *adminHandler) GetImportModes(w http.ResponseWriter, r *http.Request) {
+	statuses, err := h.svr.GetHandler().GetImportModes()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, statuses)
+}
+
+// @Tags     admin
+// @Summary  Get the status of an import, including whether the leaders are spread within the tolerance.
+// @Param    id  path  string  true  "The id of the import"
+// @Produce  json
+// @Success  200  {object}  cluster.ImportModeStatus
+// @Failure  404  {string}  string  "The import is not found."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/import-mode/{id} [get]
+func (h *adminHandler) GetImportMode(w http.ResponseWriter, r *http.Request) {
+	status, err := h.svr.GetHandler().GetImportMode(mux.Vars(r)["id"])
+	if err != nil {
+		if errs.ErrImportModeNotFound.Equal(err) {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, status)
+}
+
+// @Tags     admin
+// @Summary  End the import before it expires.
+// @Param    id  path  string  true  "The id of the import"
+// @Produce  json
+// @Success  200  {string}  string  "The import mode is disabled."
+// @Failure  404  {string}  string  "The import is not found."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/import-mode/{id} [delete]
+func (h *adminHandler) DisableImportMode(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.GetHandler().DisableImportMode(mux.Vars(r)["id"]); err != nil {
+		if errs.ErrImportModeNotFound.Equal(err) {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The import mode is disabled.")
+}
+
+// @Tags     admin
+// @Summary  Run the invariant checks against the cluster state and return the report.
+// @Produce  json
+// @Success  200  {object}  cluster.InvariantReport
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/invariants [get]
+func (h *adminHandler) CheckInvariants(w http.ResponseWriter, r *http.Request) {
+	report, err := h.svr.GetHandler().CheckInvariants()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, report)
+}
+
+// @Tags     admin
+// @Summary  Get the status of the token presented by stores.
+// @Produce  json
+// @Success  200  {object}  storeauth.TokenStatus
+// @Router   /admin/store-token [get]
+func (h *adminHandler) GetStoreTokenStatus(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetStoreTokenManager().GetStatus())
+}
+
+// @Tags     admin
+// @Summary  Issue a new token for stores. The previous token stays valid until it is revoked.
+// @Produce  json
+// @Success  200  {string}  string  "The new token."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/store-token [post]
+func (h *adminHandler) IssueStoreToken(w http.ResponseWriter, r *http.Request) {
+	token, err := h.svr.GetStoreTokenManager().Issue()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, token)
+}
+
+// @Tags     admin
+// @Summary  Revoke the previous token for stores after a rotation.
+// @Produce  json
+// @Success  200  {string}  string  "The previous store token is revoked."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/store-token/previous [delete]
+func (h *adminHandler) RevokePreviousStoreToken(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.GetStoreTokenManager().RevokePrevious(); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The previous store token is revoked.")
+}
+
+// @Tags     admin
+// @Summary  Get the status of migrating the regions to the independent region storage.
+// @Produce  json
+// @Success  200  {object}  server.RegionStorageMigrationStatus
+// @Router   /admin/region-storage [get]
+func (h *adminHandler) GetRegionStorageMigrationStatus(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetRegionStorageMigrationStatus())
+}
+
+// @Tags     admin
+// @Summary  Enable the dual-write mode to migrate the regions to the independent region storage.
+// @Produce  json
+// @Success  200  {string}  string  "The dual-write mode is enabled."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/region-storage/dual-write [post]
+func (h *adminHandler) EnableRegionStorageDualWrite(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.SetRegionStorageDualWrite(true); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The dual-write mode is enabled.")
+}
+
+// @Tags     admin
+// @Summary  Disable the dual-write mode of the region storage.
+// @Produce  json
+// @Success  200  {string}  string  "The dual-write mode is disabled."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/region-storage/dual-write [delete]
+func (h *adminHandler) DisableRegionStorageDualWrite(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.SetRegionStorageDualWrite(false); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The dual-write mode is disabled.")
+}
+
+// @Tags     admin
+// @Summary  Start to compare the regions in the etcd with the ones in the independent region storage.
+// @Param    repair  query  bool  false  "Whether to fix the independent region storage"
+// @Produce  json
+// @Success  200  {string}  string  "The verification is started."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/region-storage/verify [post]
+func (h *adminHandler) VerifyRegionStorage(w http.ResponseWriter, r *http.Request) {
+	var repair bool
+	if value := r.URL.Query().Get("repair"); value != "" {
+		var err error
+		if repair, err = strconv.ParseBool(value); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if err := h.svr.StartRegionStorageVerification(repair); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The verification is started.")
+}
+
+// @Tags     admin
+// @Summary  Switch to the independent region storage after it is verified to be consistent.
+// @Produce  json
+// @Success  200  {string}  string  "The region storage is switched."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/region-storage/cutover [post]
+func (h *adminHandler) CutoverRegionStorage(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.CutoverRegionStorage(); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The region storage is switched.")
+}
+
 // Intentionally no swagger mark as it is supposed to be only used in
-// server-to-server. For security reason, it only accepts JSON formatted data.
+// server-to-server. For security reason, it only accepts JSON formatted data
+// no larger than server.MaxPersistFileSize.
 func (h *adminHandler) SavePersistFile(w http.ResponseWriter, r *http.Request) {
-	data, err := io.ReadAll(r.Body)
+	defer r.Body.Close()
+	if contentType := r.Header.Get("Content-Type"); contentType != "" {
+		// The members of the old versions do not set the content type.
+		if mediaType, _, err := mime.ParseMediaType(contentType); err != nil || mediaType != server.PersistFileContentType {
+			h.rd.Text(w, http.StatusUnsupportedMediaType, "content type should be "+server.PersistFileContentType)
+			return
+		}
+	}
+	data, err := io.ReadAll(io.LimitReader(r.Body, server.MaxPersistFileSize+1))
 	if err != nil {
 		h.rd.Text(w, http.StatusInternalServerError, "")
 		return
 	}
-	defer r.Body.Close()
+	if len(data) > server.MaxPersistFileSize {
+		h.rd.Text(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("body should be no larger than %d bytes", server.MaxPersistFileSize))
+		return
+	}
 	if !json.Valid(data) {
 		h.rd.Text(w, http.StatusBadRequest, "body should be json format")
 		return
 	}
 	err = h.svr.PersistFile(mux.Vars(r)["file_name"], data)
 	if err != nil {
+		if errs.ErrPersistFileName.Equal(err) {
+			h.rd.Text(w, http.StatusBadRequest, err.Error())
+			return
+		}
 		h.rd.Text(w, http.StatusInternalServerError, err.Error())
 		return
 	}
 	h.rd.Text(w, http.StatusOK, "")
 }
+
+// @Tags     admin
+// @Summary  List the files persisted by the member, which are replicated by the leader.
+// @Produce  json
+// @Success  200  {array}   server.PersistedFile
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/persist-files [get]
+func (h *adminHandler) GetPersistedFiles(w http.ResponseWriter, r *http.Request) {
+	files, err := h.svr.GetPersistedFiles()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, files)
+}
+
+// @Tags     admin
+// @Summary  Check whether the replicas of the persisted files diverge across the members.
+// @Produce  json
+// @Success  200  {array}   server.ReplicatedFile
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/persist-files/divergence [get]
+func (h *adminHandler) CheckPersistFileDivergence(w http.ResponseWriter, r *http.Request) {
+	files, err := h.svr.CheckPersistFileDivergence(r.Context())
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, files)
+}
+
+// @Tags     admin
+// @Summary  Merge a region into the adjacent target region, instead of waiting for the merge checker to pick them.
+// @Accept   json
+// @Param    body  body  cluster.RegionMergeTaskParams  true  "The source region and the target region"
+// @Produce  json
+// @Success  200  {object}  endpoint.TaskRecord
+// @Failure  400  {string}  string  "The input is invalid or the regions can not be merged."
+// @Failure  409  {string}  string  "The regions already have operators."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /admin/regions/merge [post]
+func (h *adminHandler) MergeRegions(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var input cluster.RegionMergeTaskParams
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.SourceID == 0 || input.TargetID == 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "source_id and target_id should be specified")
+		return
+	}
+	if input.SourceID == input.TargetID {
+		h.rd.JSON(w, http.StatusBadRequest, "source_id and target_id should be different")
+		return
+	}
+	params, err := json.Marshal(input)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	task, err := rc.SubmitTask(cluster.TaskTypeRegionMerge, params)
+	switch {
+	case errs.ErrTaskInvalid.Equal(err), errs.ErrRegionNotMergeable.Equal(err):
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+	case errs.ErrTaskConflict.Equal(err):
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+	case err != nil:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusOK, task)
+	}
+}
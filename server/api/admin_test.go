@@ -15,17 +15,32 @@
 package api
 
 import (
+	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/stretchr/testify/suite"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/grpcutil"
 	tu "github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"github.com/tikv/pd/server/storage"
+	"github.com/tikv/pd/server/storeauth"
+	"github.com/tikv/pd/server/versioninfo"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	grpcstatus "google.golang.org/grpc/status"
 )
 
 type adminTestSuite struct {
@@ -162,6 +177,72 @@ func (suite *adminTestSuite) TestDropRegions() {
 	}
 }
 
+func (suite *adminTestSuite) TestRestoreDroppedRegion() {
+	re := suite.Require()
+	rc := suite.svr.GetRaftCluster()
+	// The version is higher than the regions left by the other tests.
+	region := newTestRegionInfo(1000, 1, []byte("restore-a"), []byte("restore-b"), core.SetRegionVersion(1000))
+	mustRegionHeartbeat(re, suite.svr, region)
+
+	url := fmt.Sprintf("%s/admin/cache/region/%d", suite.urlPrefix, region.GetID())
+	code, err := apiutil.DoDelete(testDialClient, url)
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, code)
+	suite.Nil(rc.GetRegion(region.GetID()))
+	var dropped []cluster.DroppedRegion
+	err = tu.ReadGetJSON(re, testDialClient, suite.urlPrefix+"/admin/cache/dropped", &dropped)
+	suite.NoError(err)
+	found := false
+	for _, d := range dropped {
+		if d.ID == region.GetID() {
+			found = true
+			suite.Equal(core.HexRegionKeyStr(region.GetStartKey()), d.StartKey)
+			suite.Equal(core.HexRegionKeyStr(region.GetEndKey()), d.EndKey)
+		}
+	}
+	suite.True(found)
+
+	output := make(map[string]int)
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/admin/cache/dropped/restore?region_id=%d", suite.urlPrefix, region.GetID()), nil,
+		tu.StatusOK(re), tu.ExtractJSON(re, &output))
+	suite.NoError(err)
+	suite.Equal(1, output["restored"])
+	suite.NotNil(rc.GetRegion(region.GetID()))
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/admin/cache/dropped/restore?region_id=abc", nil, tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+
+	// The drops of the regions one by one are rate limited in the safe mode.
+	opt := suite.svr.GetPersistOptions()
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.EnableRegionCacheSafeMode = true
+	opt.SetPDServerConfig(cfg)
+	defer func() {
+		cfg := opt.GetPDServerConfig().Clone()
+		cfg.EnableRegionCacheSafeMode = false
+		opt.SetPDServerConfig(cfg)
+	}()
+	limited := false
+	for i := 0; i < 100 && !limited; i++ {
+		code, err = apiutil.DoDelete(testDialClient, url)
+		suite.NoError(err)
+		limited = code == http.StatusTooManyRequests
+	}
+	suite.True(limited)
+
+	// Dropping all regions requires a token in the safe mode.
+	url = fmt.Sprintf("%s/admin/cache/regions", suite.urlPrefix)
+	code, err = apiutil.DoDelete(testDialClient, url)
+	suite.NoError(err)
+	suite.Equal(http.StatusForbidden, code)
+	code, err = apiutil.DoDelete(testDialClient, url+"?token=foo")
+	suite.NoError(err)
+	suite.Equal(http.StatusForbidden, code)
+	var token RegionCacheDropToken
+	err = tu.CheckPostJSON(testDialClient, url+"/token", nil, tu.StatusOK(re), tu.ExtractJSON(re, &token))
+	suite.NoError(err)
+	suite.NotEmpty(token.Token)
+}
+
 func (suite *adminTestSuite) TestPersistFile() {
 	data := []byte("#!/bin/sh\nrm -rf /")
 	re := suite.Require()
@@ -170,6 +251,31 @@ func (suite *adminTestSuite) TestPersistFile() {
 	data = []byte(`{"foo":"bar"}`)
 	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/admin/persist-file/good.json", data, tu.StatusOK(re))
 	suite.NoError(err)
+
+	// The content type should be JSON.
+	resp, err := testDialClient.Post(suite.urlPrefix+"/admin/persist-file/good.json", "text/plain", bytes.NewBuffer(data))
+	suite.NoError(err)
+	resp.Body.Close()
+	suite.Equal(http.StatusUnsupportedMediaType, resp.StatusCode)
+	// The size is limited.
+	large := []byte(`"` + strings.Repeat("x", server.MaxPersistFileSize) + `"`)
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/admin/persist-file/large.json", large, tu.Status(re, http.StatusRequestEntityTooLarge))
+	suite.NoError(err)
+
+	var files []server.PersistedFile
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, suite.urlPrefix+"/admin/persist-files", &files))
+	suite.Len(files, 1)
+	suite.Equal("good.json", files[0].Name)
+	suite.Equal(uint64(1), files[0].Version)
+	suite.Equal(len(data), files[0].Size)
+
+	// The only member holds the file, so it does not diverge.
+	var replicated []server.ReplicatedFile
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, suite.urlPrefix+"/admin/persist-files/divergence", &replicated))
+	suite.Len(replicated, 1)
+	suite.Equal(files[0].Checksum, replicated[0].Checksum)
+	suite.False(replicated[0].Diverged)
+	suite.Len(replicated[0].Replicas, 1)
 }
 
 func makeTS(offset time.Duration) uint64 {
@@ -223,3 +329,230 @@ func (suite *adminTestSuite) TestResetTS() {
 		tu.StringEqual(re, "\"invalid tso value\"\n"))
 	suite.NoError(err)
 }
+
+func (suite *adminTestSuite) TestRestoreMode() {
+	re := suite.Require()
+	url := fmt.Sprintf("%s/admin/restore-mode", suite.urlPrefix)
+	err := tu.CheckGetJSON(testDialClient, url, nil, tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+
+	input := &RestoreModeInput{StartKey: "7480", EndKey: "7490", StoreLimit: 1000, TTLSecond: 60}
+	data, err := json.Marshal(input)
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url, data, tu.StatusOK(re))
+	suite.NoError(err)
+	opt := suite.svr.GetPersistOptions()
+	suite.Equal(1000.0, opt.GetStoreLimitByType(1, storelimit.AddPeer))
+	suite.Equal(1000.0, opt.GetStoreLimitByType(1, storelimit.RemovePeer))
+	maxSnapshotCount := opt.GetMaxSnapshotCount()
+	suite.Equal(opt.GetScheduleConfig().MaxSnapshotCount*4, maxSnapshotCount)
+	// Enabling the mode again must not scale the limits up once more.
+	err = tu.CheckPostJSON(testDialClient, url, data, tu.StatusOK(re))
+	suite.NoError(err)
+	suite.Equal(maxSnapshotCount, opt.GetMaxSnapshotCount())
+	var rule labeler.LabelRule
+	err = tu.ReadGetJSON(re, testDialClient, url, &rule)
+	suite.NoError(err)
+	suite.Equal(server.RestoreModeRuleID, rule.ID)
+
+	_, err = apiutil.DoDelete(testDialClient, url)
+	suite.NoError(err)
+	err = tu.CheckGetJSON(testDialClient, url, nil, tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+	suite.NotEqual(1000.0, opt.GetStoreLimitByType(1, storelimit.AddPeer))
+
+	// Test invalid input.
+	for _, input := range []*RestoreModeInput{
+		{StartKey: "zz", StoreLimit: 1000, TTLSecond: 60},
+		{StoreLimit: 1000},
+		{TTLSecond: 60},
+	} {
+		data, err = json.Marshal(input)
+		suite.NoError(err)
+		err = tu.CheckPostJSON(testDialClient, url, data, tu.Status(re, http.StatusBadRequest))
+		suite.NoError(err)
+	}
+}
+
+func (suite *adminTestSuite) TestCheckInvariants() {
+	re := suite.Require()
+	mustPutStore(re, suite.svr, 1, metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+
+	var report cluster.InvariantReport
+	err := tu.ReadGetJSON(re, testDialClient, fmt.Sprintf("%s/admin/invariants", suite.urlPrefix), &report)
+	suite.NoError(err)
+	suite.True(report.Passed)
+	suite.Len(report.Checks, 4)
+	for _, check := range report.Checks {
+		suite.Empty(check.Violations, check.Name)
+	}
+}
+
+func (suite *adminTestSuite) TestRegionStorageMigration() {
+	re := suite.Require()
+	url := fmt.Sprintf("%s/admin/region-storage", suite.urlPrefix)
+	cfg := suite.svr.GetPDServerConfig()
+	cfg.UseRegionStorage = false
+	suite.NoError(suite.svr.SetPDServerConfig(*cfg))
+	storage.TrySwitchRegionStorage(suite.svr.GetStorage(), false)
+	defer func() {
+		cfg = suite.svr.GetPDServerConfig()
+		cfg.UseRegionStorage = true
+		cfg.RegionStorageDualWrite = false
+		suite.NoError(suite.svr.SetPDServerConfig(*cfg))
+		storage.TrySwitchRegionStorage(suite.svr.GetStorage(), true)
+	}()
+
+	// The dual-write mode is required.
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/verify", nil, tu.Status(re, http.StatusInternalServerError)))
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/cutover", nil, tu.Status(re, http.StatusInternalServerError)))
+
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/dual-write", nil, tu.StatusOK(re)))
+	var status server.RegionStorageMigrationStatus
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, url, &status))
+	suite.True(status.DualWrite)
+	suite.False(status.UseRegionStorage)
+	suite.Nil(status.LastVerification)
+	suite.True(suite.svr.GetPersistOptions().IsRegionStorageDualWrite())
+	// It cannot be cutover before being verified.
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/cutover", nil, tu.Status(re, http.StatusInternalServerError)))
+
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/verify?repair=invalid", nil, tu.Status(re, http.StatusBadRequest)))
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/verify?repair=true", nil, tu.StatusOK(re)))
+	tu.Eventually(re, func() bool {
+		status = server.RegionStorageMigrationStatus{}
+		suite.NoError(tu.ReadGetJSON(re, testDialClient, url, &status))
+		return !status.Verifying && status.LastVerification != nil
+	})
+	suite.Empty(status.LastVerifyError)
+	suite.NotNil(status.LastVerifyTime)
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/verify", nil, tu.StatusOK(re)))
+	tu.Eventually(re, func() bool {
+		status = server.RegionStorageMigrationStatus{}
+		suite.NoError(tu.ReadGetJSON(re, testDialClient, url, &status))
+		return !status.Verifying && status.LastVerification != nil
+	})
+	suite.True(status.LastVerification.IsConsistent())
+
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/cutover", nil, tu.StatusOK(re)))
+	status = server.RegionStorageMigrationStatus{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, url, &status))
+	suite.False(status.DualWrite)
+	suite.True(status.UseRegionStorage)
+	// The dual-write mode cannot be enabled again after the cutover.
+	suite.NoError(tu.CheckPostJSON(testDialClient, url+"/dual-write", nil, tu.Status(re, http.StatusInternalServerError)))
+}
+
+func (suite *adminTestSuite) TestStoreToken() {
+	re := suite.Require()
+	url := fmt.Sprintf("%s/admin/store-token", suite.urlPrefix)
+	var status storeauth.TokenStatus
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, url, &status))
+	suite.False(status.Issued)
+
+	cfg := suite.svr.GetPDServerConfig()
+	cfg.EnableStoreTokenAuth = true
+	suite.NoError(suite.svr.SetPDServerConfig(*cfg))
+	defer func() {
+		cfg.EnableStoreTokenAuth = false
+		suite.NoError(suite.svr.SetPDServerConfig(*cfg))
+	}()
+
+	grpcServer := &server.GrpcServer{Server: suite.svr}
+	putStore := func(token string) error {
+		ctx := context.Background()
+		if token != "" {
+			ctx = metadata.NewIncomingContext(ctx, metadata.Pairs(grpcutil.StoreTokenMetadataKey, token))
+		}
+		_, err := grpcServer.PutStore(ctx, &pdpb.PutStoreRequest{
+			Header: &pdpb.RequestHeader{ClusterId: suite.svr.ClusterID()},
+			Store: &metapb.Store{
+				Id:      100,
+				Address: "tikv100",
+				State:   metapb.StoreState_Up,
+				Version: versioninfo.MinSupportedVersion(versioninfo.Version2_0).String(),
+			},
+		})
+		return err
+	}
+	// The stores are not locked out before a token is issued.
+	suite.NoError(putStore(""))
+
+	var first, second string
+	suite.NoError(tu.CheckPostJSON(testDialClient, url, nil, tu.StatusOK(re), tu.ExtractJSON(re, &first)))
+	suite.Equal(codes.Unauthenticated, grpcstatus.Code(putStore("")))
+	suite.NoError(putStore(first))
+	suite.Equal(codes.Unauthenticated, grpcstatus.Code(putStore("invalid")))
+
+	// Rotate the token.
+	suite.NoError(tu.CheckPostJSON(testDialClient, url, nil, tu.StatusOK(re), tu.ExtractJSON(re, &second)))
+	suite.NoError(putStore(first))
+	suite.NoError(putStore(second))
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, url, &status))
+	suite.True(status.Issued)
+	suite.True(status.HasPrevious)
+
+	_, err := apiutil.DoDelete(testDialClient, url+"/previous")
+	suite.NoError(err)
+	suite.Equal(codes.Unauthenticated, grpcstatus.Code(putStore(first)))
+	suite.NoError(putStore(second))
+}
+
+func (suite *adminTestSuite) TestReplicaFreeze() {
+	re := suite.Require()
+	url := fmt.Sprintf("%s/admin/replica-freeze", suite.urlPrefix)
+
+	input := &ReplicaFreezeInput{ID: "br", StartKey: "7480", EndKey: "7490", TTLSecond: 60}
+	data, err := json.Marshal(input)
+	suite.NoError(err)
+	var freeze cluster.ReplicaFreeze
+	err = tu.CheckPostJSON(testDialClient, url, data, tu.StatusOK(re), func(res []byte, _ int) {
+		re.NoError(json.Unmarshal(res, &freeze))
+	})
+	suite.NoError(err)
+	suite.Equal("br", freeze.ID)
+	suite.Equal("7480", freeze.StartKey)
+	var freezes []*cluster.ReplicaFreeze
+	err = tu.ReadGetJSON(re, testDialClient, url, &freezes)
+	suite.NoError(err)
+	suite.Len(freezes, 1)
+	suite.Equal("7490", freezes[0].EndKey)
+
+	_, err = apiutil.DoDelete(testDialClient, url+"/br")
+	suite.NoError(err)
+	err = tu.ReadGetJSON(re, testDialClient, url, &freezes)
+	suite.NoError(err)
+	suite.Empty(freezes)
+	code, err := apiutil.DoDelete(testDialClient, url+"/br")
+	suite.NoError(err)
+	suite.Equal(http.StatusNotFound, code)
+
+	// Test invalid input.
+	for _, input := range []*ReplicaFreezeInput{
+		{StartKey: "7480", TTLSecond: 60},
+		{ID: "br", StartKey: "zz", TTLSecond: 60},
+		{ID: "br"},
+	} {
+		data, err = json.Marshal(input)
+		suite.NoError(err)
+		err = tu.CheckPostJSON(testDialClient, url, data, tu.Status(re, http.StatusBadRequest))
+		suite.NoError(err)
+	}
+}
+
+func (suite *adminTestSuite) TestMergeRegions() {
+	re := suite.Require()
+	url := fmt.Sprintf("%s/admin/regions/merge", suite.urlPrefix)
+
+	// Test invalid input.
+	for _, input := range []*cluster.RegionMergeTaskParams{
+		{SourceID: 1000},
+		{SourceID: 1000, TargetID: 1000},
+		{SourceID: 1000, TargetID: 1001},
+	} {
+		data, err := json.Marshal(input)
+		suite.NoError(err)
+		err = tu.CheckPostJSON(testDialClient, url, data, tu.Status(re, http.StatusBadRequest))
+		suite.NoError(err)
+	}
+}
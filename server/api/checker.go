@@ -16,13 +16,21 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
 	"github.com/gorilla/mux"
+	"github.com/pingcap/errors"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
 	"github.com/unrolled/render"
 )
 
+const (
+	defaultCheckerDryRunSample = 16
+	maxCheckerDryRunSample     = 1024
+)
+
 type checkerHandler struct {
 	*server.Handler
 	r *render.Render
@@ -93,3 +101,79 @@ func (c *checkerHandler) GetCheckerStatus(w http.ResponseWriter, r *http.Request
 	}
 	c.r.JSON(w, http.StatusOK, output)
 }
+
+// CheckerDryRunOperator is an operator proposed by the dry-run of a checker.
+type CheckerDryRunOperator struct {
+	Desc   string `json:"desc"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail"`
+}
+
+// CheckerDryRunResult is the dry-run result of a checker on a region.
+type CheckerDryRunResult struct {
+	RegionID  uint64                  `json:"region-id"`
+	Reason    string                  `json:"reason"`
+	Operators []CheckerDryRunOperator `json:"operators,omitempty"`
+}
+
+// @Tags     checker
+// @Summary  Run the checker on some regions without adding the operators it proposes.
+// @Param    name       path   string   true   "The name of the checker."
+// @Param    region_id  query  integer  false  "The ID of the region to check, can be repeated."
+// @Param    sample     query  integer  false  "The number of the random regions to check if no region is given."  default(16)
+// @Produce  json
+// @Success  200  {array}   CheckerDryRunResult
+// @Failure  400  {string}  string  "Bad format request."
+// @Failure  404  {string}  string  "The checker is not found."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /checker/{name}/dry-run [get]
+func (c *checkerHandler) DryRunChecker(w http.ResponseWriter, r *http.Request) {
+	name := mux.Vars(r)["name"]
+	var regionIDs []uint64
+	for _, idStr := range r.URL.Query()["region_id"] {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			c.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		regionIDs = append(regionIDs, id)
+	}
+	sample := defaultCheckerDryRunSample
+	if sampleStr := r.URL.Query().Get("sample"); sampleStr != "" {
+		var err error
+		sample, err = strconv.Atoi(sampleStr)
+		if err != nil || sample <= 0 {
+			c.r.JSON(w, http.StatusBadRequest, "sample should be a positive integer")
+			return
+		}
+	}
+	if sample > maxCheckerDryRunSample {
+		sample = maxCheckerDryRunSample
+	}
+
+	results, err := c.Handler.DryRunChecker(name, regionIDs, sample)
+	if err != nil {
+		if errors.ErrorEqual(err, errs.ErrCheckerNotFound.FastGenByArgs()) {
+			c.r.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		c.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	output := make([]CheckerDryRunResult, 0, len(results))
+	for _, result := range results {
+		res := CheckerDryRunResult{
+			RegionID: result.RegionID,
+			Reason:   result.Reason,
+		}
+		for _, op := range result.Operators {
+			res.Operators = append(res.Operators, CheckerDryRunOperator{
+				Desc:   op.Desc(),
+				Kind:   op.Kind().String(),
+				Detail: op.String(),
+			})
+		}
+		output = append(output, res)
+	}
+	c.r.JSON(w, http.StatusOK, output)
+}
@@ -17,6 +17,7 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"testing"
 	"time"
 
@@ -24,6 +25,7 @@ import (
 	"github.com/stretchr/testify/suite"
 	tu "github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/schedule/checker"
 )
 
 type checkerTestSuite struct {
@@ -169,3 +171,43 @@ func (suite *checkerTestSuite) testPauseOrResume(name string) {
 	suite.NoError(err)
 	suite.False(isPaused)
 }
+
+func (suite *checkerTestSuite) TestDryRun() {
+	re := suite.Require()
+	handler := suite.svr.GetHandler()
+	url := suite.urlPrefix + "/%s/dry-run%s"
+
+	err := tu.CheckGetJSON(testDialClient, fmt.Sprintf(url, "dummy", ""), nil, tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+	err = tu.CheckGetJSON(testDialClient, fmt.Sprintf(url, "rule", "?sample=0"), nil, tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	err = tu.CheckGetJSON(testDialClient, fmt.Sprintf(url, "rule", "?region_id=abc"), nil, tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+
+	// The replica checker does nothing when the placement rules are enabled.
+	var results []CheckerDryRunResult
+	err = tu.ReadGetJSON(re, testDialClient, fmt.Sprintf(url, "replica", "?region_id=8&region_id=1000"), &results)
+	suite.NoError(err)
+	suite.Len(results, 1)
+	suite.Equal(uint64(8), results[0].RegionID)
+	suite.Equal(checker.DryRunPlacementRulesEnabled, results[0].Reason)
+	suite.Empty(results[0].Operators)
+
+	// The sampled regions are checked if no region is given.
+	suite.NoError(handler.PauseOrResumeChecker("rule", 30))
+	err = tu.ReadGetJSON(re, testDialClient, fmt.Sprintf(url, "rule", ""), &results)
+	suite.NoError(err)
+	suite.Len(results, 1)
+	suite.Equal(uint64(8), results[0].RegionID)
+	suite.Equal(checker.DryRunCheckerPaused, results[0].Reason)
+	suite.NoError(handler.PauseOrResumeChecker("rule", 0))
+
+	err = tu.ReadGetJSON(re, testDialClient, fmt.Sprintf(url, "rule", "?region_id=8"), &results)
+	suite.NoError(err)
+	suite.Len(results, 1)
+	suite.NotEqual(checker.DryRunCheckerPaused, results[0].Reason)
+	for _, op := range results[0].Operators {
+		suite.NotEmpty(op.Desc)
+		suite.NotEmpty(op.Detail)
+	}
+}
@@ -16,7 +16,10 @@ package api
 
 import (
 	"net/http"
+	"strconv"
 
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
 	"github.com/unrolled/render"
 )
@@ -56,3 +59,130 @@ func (h *clusterHandler) GetClusterStatus(w http.ResponseWriter, r *http.Request
 	}
 	h.rd.JSON(w, http.StatusOK, status)
 }
+
+// @Tags     cluster
+// @Summary  Get the startup status of the subsystems of the cluster, which shows the pending or failed ones.
+// @Produce  json
+// @Success  200  {array}  cluster.SubsystemStatus
+// @Router   /cluster/startup [get]
+func (h *clusterHandler) GetClusterStartupStatus(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, h.svr.GetClusterStartupStatus())
+}
+
+// @Tags     cluster
+// @Summary  Get the progress of loading the regions from the storage when the cluster starts.
+// @Produce  json
+// @Success  200  {object}  Progress
+// @Failure  404  {string}  string  "The regions are not being loaded."
+// @Router   /cluster/startup/region-loading [get]
+func (h *clusterHandler) GetRegionLoadingProgress(w http.ResponseWriter, r *http.Request) {
+	progress, leftSeconds, currentSpeed, err := h.svr.GetRegionLoadingProgress()
+	if err != nil {
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &Progress{
+		Action:       "loading-regions",
+		Progress:     progress,
+		CurrentSpeed: currentSpeed,
+		LeftSeconds:  leftSeconds,
+	})
+}
+
+// @Tags     cluster
+// @Summary  Get the recent cluster events, such as a store running out of space soon.
+// @Param    since  query  integer  false  "Only return the events whose ID is greater than it"
+// @Produce  json
+// @Success  200  {array}   cluster.ClusterEvent
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /cluster/events [get]
+func (h *clusterHandler) GetClusterEvents(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var since uint64
+	if sinceStr := r.URL.Query().Get("since"); sinceStr != "" {
+		var err error
+		since, err = strconv.ParseUint(sinceStr, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetClusterEvents(since))
+}
+
+// @Tags     cluster
+// @Summary  Get the staleness of the region cache. The scheduling is paused while the cache is stale.
+// @Produce  json
+// @Success  200  {object}  cluster.RegionCacheStaleness
+// @Router   /cluster/region-cache [get]
+func (h *clusterHandler) GetRegionCacheStaleness(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, getCluster(r).GetRegionCacheStaleness())
+}
+
+// @Tags     cluster
+// @Summary  Stage the bootstrap bundle, which is validated and applied when the cluster is bootstrapped. The unset schedule config items are kept.
+// @Accept   json
+// @Param    body  body  server.BootstrapBundle  true  "The topology policy of the cluster"
+// @Produce  json
+// @Success  200  {string}  string  "The bootstrap bundle is staged."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  409  {string}  string  "The cluster is already bootstrapped."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /cluster/bootstrap-bundle [post]
+func (h *clusterHandler) StageBootstrapBundle(w http.ResponseWriter, r *http.Request) {
+	bundle := &server.BootstrapBundle{Schedule: h.svr.GetScheduleConfig()}
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, bundle); err != nil {
+		return
+	}
+	if err := h.svr.StageBootstrapBundle(bundle); err != nil {
+		switch {
+		case errs.ErrBootstrapBundleInvalid.Equal(err):
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		case errs.ErrAlreadyBootstrapped.Equal(err):
+			h.rd.JSON(w, http.StatusConflict, err.Error())
+		default:
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The bootstrap bundle is staged.")
+}
+
+// @Tags     cluster
+// @Summary  Get the staged bootstrap bundle.
+// @Produce  json
+// @Success  200  {object}  server.BootstrapBundle
+// @Failure  404  {string}  string  "No bootstrap bundle is staged."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /cluster/bootstrap-bundle [get]
+func (h *clusterHandler) GetBootstrapBundle(w http.ResponseWriter, r *http.Request) {
+	bundle, err := h.svr.GetBootstrapBundle()
+	if err != nil {
+		h.respondBootstrapBundleErr(w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, bundle)
+}
+
+// @Tags     cluster
+// @Summary  Remove the staged bootstrap bundle.
+// @Produce  json
+// @Success  200  {string}  string  "The bootstrap bundle is removed."
+// @Failure  404  {string}  string  "No bootstrap bundle is staged."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /cluster/bootstrap-bundle [delete]
+func (h *clusterHandler) DeleteBootstrapBundle(w http.ResponseWriter, r *http.Request) {
+	if err := h.svr.DeleteBootstrapBundle(); err != nil {
+		h.respondBootstrapBundleErr(w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The bootstrap bundle is removed.")
+}
+
+func (h *clusterHandler) respondBootstrapBundleErr(w http.ResponseWriter, err error) {
+	if errs.ErrBootstrapBundleNotFound.Equal(err) {
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+}
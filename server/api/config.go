@@ -24,21 +24,31 @@ import (
 	"strings"
 	"time"
 
+	"github.com/docker/go-units"
 	"github.com/pingcap/errcode"
 	"github.com/pingcap/errors"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/jsonutil"
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/pkg/reflectutil"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
 	"github.com/unrolled/render"
 )
 
 type confHandler struct {
 	svr *server.Server
 	rd  *render.Render
+	// patchMu serializes the read-modify-write of patching the config, so
+	// that the concurrent patches don't overwrite each other.
+	patchMu syncutil.Mutex
 }
 
 func newConfHandler(svr *server.Server, rd *render.Render) *confHandler {
@@ -283,6 +293,88 @@ func (h *confHandler) GetScheduleConfig(w http.ResponseWriter, r *http.Request)
 	h.rd.JSON(w, http.StatusOK, cfg)
 }
 
+// RegionScorePoint is a sample of the region score curve.
+type RegionScorePoint struct {
+	Utilization float64 `json:"utilization"`
+	Score       float64 `json:"score"`
+}
+
+// RegionScoreCurve describes how the region score changes with the space utilization of a store.
+type RegionScoreCurve struct {
+	Version        string                `json:"version"`
+	HighSpaceRatio float64               `json:"high-space-ratio"`
+	LowSpaceRatio  float64               `json:"low-space-ratio"`
+	Curve          core.RegionScoreCurve `json:"curve"`
+	Capacity       typeutil.ByteSize     `json:"capacity"`
+	Points         []RegionScorePoint    `json:"points"`
+}
+
+const (
+	defaultRegionScoreCurvePoints   = 21
+	maxRegionScoreCurvePoints       = 1001
+	defaultRegionScoreCurveCapacity = units.TiB
+)
+
+// @Tags     config
+// @Summary  Plot the region score against the space utilization of a store with the current config.
+// @Param    points    query  integer  false  "The number of sample points, 21 by default"
+// @Param    capacity  query  string   false  "The capacity of the simulated store, 1TiB by default"
+// @Produce  json
+// @Success  200  {object}  RegionScoreCurve
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /config/region-score-curve [get]
+func (h *confHandler) GetRegionScoreCurve(w http.ResponseWriter, r *http.Request) {
+	points := defaultRegionScoreCurvePoints
+	if s := r.URL.Query().Get("points"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 2 || n > maxRegionScoreCurvePoints {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("points should be an integer in [2, %d]", maxRegionScoreCurvePoints))
+			return
+		}
+		points = n
+	}
+	capacity := typeutil.ByteSize(defaultRegionScoreCurveCapacity)
+	if s := r.URL.Query().Get("capacity"); s != "" {
+		if err := capacity.UnmarshalText([]byte(s)); err != nil || capacity < units.MiB {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid capacity")
+			return
+		}
+	}
+
+	cfg := h.svr.GetScheduleConfig()
+	curve := cfg.GetRegionScoreCurve()
+	result := &RegionScoreCurve{
+		Version:        cfg.RegionScoreFormulaVersion,
+		HighSpaceRatio: cfg.HighSpaceRatio,
+		LowSpaceRatio:  cfg.LowSpaceRatio,
+		Curve:          curve,
+		Capacity:       capacity,
+		Points:         make([]RegionScorePoint, 0, points),
+	}
+	for i := 0; i < points; i++ {
+		utilization := float64(i) / float64(points-1)
+		used := uint64(float64(capacity) * utilization)
+		if used > uint64(capacity) {
+			used = uint64(capacity)
+		}
+		// Assume the region size equals to the used size, which means there is no compression.
+		store := core.NewStoreInfo(
+			&metapb.Store{},
+			core.SetNewStoreStats(&pdpb.StoreStats{
+				Capacity:  uint64(capacity),
+				Available: uint64(capacity) - used,
+				UsedSize:  used,
+			}),
+			core.SetRegionSize(int64(used/units.MiB)),
+		)
+		result.Points = append(result.Points, RegionScorePoint{
+			Utilization: utilization,
+			Score:       store.RegionScore(cfg.RegionScoreFormulaVersion, cfg.HighSpaceRatio, cfg.LowSpaceRatio, curve, 0),
+		})
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
 // @Tags     config
 // @Summary  Update a schedule config item.
 // @Accept   json
@@ -367,6 +459,39 @@ func (h *confHandler) SetReplicationConfig(w http.ResponseWriter, r *http.Reques
 	h.rd.JSON(w, http.StatusOK, "The config is updated.")
 }
 
+// ReplicationMigrationInput is the input of a replication mode migration.
+type ReplicationMigrationInput struct {
+	EnablePlacementRules bool `json:"enable-placement-rules"`
+	// SampleSize is the number of regions to verify, 0 means the default.
+	SampleSize int `json:"sample-size"`
+}
+
+// @Tags     config
+// @Summary  Switch between max-replicas and placement rules after verifying the fit of sampled regions.
+// @Accept   json
+// @Param    body  body  ReplicationMigrationInput  true  "The target replication mode"
+// @Produce  json
+// @Success  200  {object}  server.ReplicationMigrationResult
+// @Failure  400  {string}  string  "The input is invalid or the migration is rejected."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /config/replicate/migration [post]
+func (h *confHandler) MigrateReplicationMode(w http.ResponseWriter, r *http.Request) {
+	var input ReplicationMigrationInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	result, err := h.svr.MigrateReplicationMode(input.EnablePlacementRules, input.SampleSize)
+	if err != nil {
+		if errs.ErrReplicationMigration.Equal(err) {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
 // @Tags     config
 // @Summary  Get label property config.
 // @Produce  json
@@ -416,6 +541,21 @@ func (h *confHandler) GetClusterVersion(w http.ResponseWriter, r *http.Request)
 	h.rd.JSON(w, http.StatusOK, h.svr.GetClusterVersion())
 }
 
+// @Tags     config
+// @Summary  Get the schedulers and checkers disabled because they do not support the cluster version.
+// @Produce  json
+// @Success  200  {array}   endpoint.VersionGateRecord
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /config/cluster-version/gate [get]
+func (h *confHandler) GetClusterVersionGate(w http.ResponseWriter, r *http.Request) {
+	records, err := getCluster(r).GetVersionGateRecords()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, records)
+}
+
 // @Tags     config
 // @Summary  Update cluster version.
 // @Accept   json
@@ -482,3 +622,194 @@ func (h *confHandler) SetReplicationModeConfig(w http.ResponseWriter, r *http.Re
 func (h *confHandler) GetPDServerConfig(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, h.svr.GetPDServerConfig())
 }
+
+// jsonPatchContentType is the content type of the JSON patch, and the patches
+// of the config in the other content types are JSON merge patches.
+const jsonPatchContentType = "application/json-patch+json"
+
+// ConfigPatchResult is the result of patching the config.
+type ConfigPatchResult struct {
+	// Changes are the changes of the effective config, whose paths are
+	// relative to the patched config.
+	Changes []jsonutil.Change `json:"changes"`
+}
+
+// @Tags     config
+// @Summary  Patch the schedule config with a JSON merge patch, or a JSON patch if the content type is application/json-patch+json.
+// @Accept   json
+// @Param    body  body  object  true  "The JSON merge patch or the JSON patch"
+// @Produce  json
+// @Success  200  {object}  ConfigPatchResult
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /config/schedule [patch]
+func (h *confHandler) PatchScheduleConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := &config.ScheduleConfig{}
+	h.patchConfig(w, r, func() interface{} { return h.svr.GetScheduleConfig() }, cfg, func(changes []jsonutil.Change) error {
+		for _, change := range changes {
+			key := "schedule." + strings.SplitN(change.Path[1:], "/", 2)[0]
+			if h.svr.IsTTLConfigExist(key) {
+				return errors.Errorf("need to clean up TTL first for %s", key)
+			}
+		}
+		if err := cfg.Validate(); err != nil {
+			return err
+		}
+		return cfg.Deprecated()
+	}, func() error {
+		return h.svr.SetScheduleConfig(*cfg)
+	})
+}
+
+// @Tags     config
+// @Summary  Patch the replication config with a JSON merge patch, or a JSON patch if the content type is application/json-patch+json.
+// @Accept   json
+// @Param    body  body  object  true  "The JSON merge patch or the JSON patch"
+// @Produce  json
+// @Success  200  {object}  ConfigPatchResult
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /config/replicate [patch]
+func (h *confHandler) PatchReplicationConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := &config.ReplicationConfig{}
+	h.patchConfig(w, r, func() interface{} { return h.svr.GetReplicationConfig() }, cfg, func([]jsonutil.Change) error {
+		return cfg.Validate()
+	}, func() error {
+		return h.svr.SetReplicationConfig(*cfg)
+	})
+}
+
+// @Tags     config
+// @Summary  Patch the PD server config with a JSON merge patch, or a JSON patch if the content type is application/json-patch+json.
+// @Accept   json
+// @Param    body  body  object  true  "The JSON merge patch or the JSON patch"
+// @Produce  json
+// @Success  200  {object}  ConfigPatchResult
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /config/pd-server [patch]
+func (h *confHandler) PatchPDServerConfig(w http.ResponseWriter, r *http.Request) {
+	cfg := &config.PDServerConfig{}
+	h.patchConfig(w, r, func() interface{} { return h.svr.GetPDServerConfig() }, cfg, func([]jsonutil.Change) error {
+		return cfg.Validate()
+	}, func() error {
+		return h.svr.SetPDServerConfig(*cfg)
+	})
+}
+
+// patchConfig applies the patch in the request to the current config, and
+// decodes the result into the patched config with the unknown fields
+// rejected. The patched config is saved only if it is changed and validated.
+// The fields can't be removed by the patch, otherwise they would be reset to
+// the zero values rather than the defaults, e.g. a schedule limit of 0 stops
+// the scheduling.
+func (h *confHandler) patchConfig(w http.ResponseWriter, r *http.Request, getCurrent func() interface{}, patched interface{},
+	validate func([]jsonutil.Change) error, save func() error) {
+	patch, err := io.ReadAll(r.Body)
+	r.Body.Close()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.patchMu.Lock()
+	defer h.patchMu.Unlock()
+	old, err := json.Marshal(getCurrent())
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	var data []byte
+	if strings.HasPrefix(r.Header.Get("Content-Type"), jsonPatchContentType) {
+		var ops []jsonutil.PatchOperation
+		if err = json.Unmarshal(patch, &ops); err == nil {
+			data, err = jsonutil.ApplyPatch(old, ops)
+		}
+	} else {
+		data, err = jsonutil.MergePatch(old, patch)
+	}
+	if err == nil {
+		var removed []string
+		if removed, err = jsonutil.RemovedFields(patched, old, data); err == nil && len(removed) > 0 {
+			err = errors.Errorf("the config fields %s can't be removed or set to null", strings.Join(removed, ", "))
+		}
+	}
+	if err == nil {
+		err = jsonutil.UnmarshalStrict(data, patched)
+	}
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	// Compare the effective configs, so that the changes in the other forms
+	// of the same values are ignored.
+	if data, err = json.Marshal(patched); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	changes, err := jsonutil.Diff(old, data)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	if len(changes) > 0 {
+		if err := validate(changes); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := save(); err != nil {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, &ConfigPatchResult{Changes: changes})
+}
+
+const (
+	defaultConfigWatchTimeout = 30 * time.Second
+	maxConfigWatchTimeout     = 5 * time.Minute
+)
+
+// @Tags     config
+// @Summary  Watch the changes of the config. The request is blocked until there are changes after the revision or the timeout is reached.
+// @Param    revision  query  integer  false  "The revision to watch from, the current config is returned if it is 0 or too old"
+// @Param    kind      query  string   false  "The kinds of the config to watch, such as schedule, replication, label-property and pd-server. All kinds are watched if it is not given"
+// @Param    timeout   query  string   false  "The max time to wait, such as 30s"  default(30s)
+// @Produce  json
+// @Success  200  {object}  server.ConfigWatchResponse
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /config/watch [get]
+func (h *confHandler) WatchConfig(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	var revision uint64
+	if revisionStr := query.Get("revision"); revisionStr != "" {
+		var err error
+		revision, err = strconv.ParseUint(revisionStr, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	timeout := defaultConfigWatchTimeout
+	if timeoutStr := query.Get("timeout"); timeoutStr != "" {
+		var err error
+		timeout, err = time.ParseDuration(timeoutStr)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if timeout <= 0 || timeout > maxConfigWatchTimeout {
+			h.rd.JSON(w, http.StatusBadRequest, fmt.Sprintf("timeout should be in (0, %s]", maxConfigWatchTimeout))
+			return
+		}
+	}
+	resp, err := h.svr.WatchConfig(r.Context(), revision, query["kind"], timeout)
+	if err != nil {
+		if errs.ErrConfigWatchKind.Equal(err) {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, resp)
+}
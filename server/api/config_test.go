@@ -17,14 +17,22 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/tikv/pd/pkg/jsonutil"
 	tu "github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/versioninfo"
 )
 
@@ -174,6 +182,87 @@ func (suite *configTestSuite) TestConfigSchedule() {
 	suite.Equal(*sc1, *sc)
 }
 
+func (suite *configTestSuite) TestConfigWatch() {
+	re := suite.Require()
+	addr := fmt.Sprintf("%s/config/watch", suite.urlPrefix)
+
+	// The current config is returned without a revision.
+	resp := &server.ConfigWatchResponse{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr+"?kind=schedule", resp))
+	suite.True(resp.Compacted)
+	suite.Len(resp.Changes, 1)
+	suite.Equal(server.ConfigKindSchedule, resp.Changes[0].Kind)
+	revision := resp.Revision
+
+	// The watcher is woken up by the change.
+	sc := suite.svr.GetScheduleConfig()
+	sc.LeaderScheduleLimit++
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		postData, err := json.Marshal(sc)
+		re.NoError(err)
+		re.NoError(tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/config/schedule", suite.urlPrefix), postData, tu.StatusOK(re)))
+	}()
+	resp = &server.ConfigWatchResponse{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, fmt.Sprintf("%s?kind=schedule&revision=%d&timeout=10s", addr, revision), resp))
+	suite.False(resp.Compacted)
+	suite.Len(resp.Changes, 1)
+	suite.Equal(revision+1, resp.Revision)
+	suite.Equal(revision+1, resp.Changes[0].Revision)
+	suite.Equal(server.ConfigKindSchedule, resp.Changes[0].Kind)
+	suite.EqualValues(sc.LeaderScheduleLimit, resp.Changes[0].Config.(map[string]interface{})["leader-schedule-limit"])
+
+	// The changes of the other kinds are not returned.
+	resp = &server.ConfigWatchResponse{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, fmt.Sprintf("%s?kind=replication&revision=%d&timeout=100ms", addr, revision), resp))
+	suite.False(resp.Compacted)
+	suite.Empty(resp.Changes)
+	suite.Equal(revision+1, resp.Revision)
+
+	// The request is invalid.
+	for _, query := range []string{"?kind=unknown", "?revision=x", "?timeout=1h"} {
+		res, err := testDialClient.Get(addr + query)
+		suite.NoError(err)
+		res.Body.Close()
+		suite.Equal(http.StatusBadRequest, res.StatusCode)
+	}
+}
+
+func (suite *configTestSuite) TestRegionScoreCurve() {
+	re := suite.Require()
+	addr := fmt.Sprintf("%s/config", suite.urlPrefix)
+	postData, err := json.Marshal(map[string]interface{}{"region-score-formula-version": "v3"})
+	suite.NoError(err)
+	suite.NoError(tu.CheckPostJSON(testDialClient, addr, postData, tu.StatusOK(re)))
+	defer func() {
+		postData, err := json.Marshal(map[string]interface{}{"region-score-formula-version": "v2"})
+		suite.NoError(err)
+		suite.NoError(tu.CheckPostJSON(testDialClient, addr, postData, tu.StatusOK(re)))
+	}()
+
+	curve := &RegionScoreCurve{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr+"/region-score-curve?points=11&capacity=1GiB", curve))
+	suite.Equal("v3", curve.Version)
+	suite.Equal(4.0, curve.Curve.Amplification)
+	suite.Equal(2.0, curve.Curve.Exponent)
+	suite.Len(curve.Points, 11)
+	suite.Equal(0.0, curve.Points[0].Score)
+	suite.Equal(1.0, curve.Points[10].Utilization)
+	for i := 1; i < len(curve.Points); i++ {
+		suite.GreaterOrEqual(curve.Points[i].Score, curve.Points[i-1].Score)
+	}
+	// the score is proportional to the region size before the transition stage.
+	suite.InDelta(float64(512), curve.Points[5].Score, 1)
+	suite.Greater(curve.Points[10].Score, curve.Curve.LowSpacePenalty)
+
+	suite.NoError(tu.CheckGetJSON(testDialClient, addr+"/region-score-curve?points=1", nil, tu.Status(re, http.StatusBadRequest)))
+	suite.NoError(tu.CheckGetJSON(testDialClient, addr+"/region-score-curve?capacity=abc", nil, tu.Status(re, http.StatusBadRequest)))
+
+	postData, err = json.Marshal(map[string]interface{}{"region-score-v3-exponent": 0})
+	suite.NoError(err)
+	suite.NoError(tu.CheckPostJSON(testDialClient, addr, postData, tu.StatusNotOK(re)))
+}
+
 func (suite *configTestSuite) TestConfigReplication() {
 	re := suite.Require()
 	addr := fmt.Sprintf("%s/config/replicate", suite.urlPrefix)
@@ -313,6 +402,88 @@ func (suite *configTestSuite) TestConfigPDServer() {
 	suite.Equal(24*time.Hour, sc.MaxResetTSGap.Duration)
 }
 
+func (suite *configTestSuite) TestConfigPatch() {
+	re := suite.Require()
+	addr := fmt.Sprintf("%s/config/schedule", suite.urlPrefix)
+	sc := &config.ScheduleConfig{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, sc))
+
+	// The JSON merge patch.
+	result := &ConfigPatchResult{}
+	patch := []byte(`{"leader-schedule-limit":7,"max-store-down-time":"45m"}`)
+	suite.NoError(tu.CheckPatchJSON(testDialClient, addr, patch, tu.StatusOK(re), tu.ExtractJSON(re, result)))
+	suite.Equal([]jsonutil.Change{
+		{Path: "/leader-schedule-limit", Old: float64(sc.LeaderScheduleLimit), New: float64(7)},
+		{Path: "/max-store-down-time", Old: sc.MaxStoreDownTime.String(), New: "45m0s"},
+	}, result.Changes)
+	sc.LeaderScheduleLimit = 7
+	sc.MaxStoreDownTime.Duration = 45 * time.Minute
+	sc1 := &config.ScheduleConfig{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, sc1))
+	suite.Equal(*sc, *sc1)
+	// The same value in another form changes nothing.
+	suite.NoError(tu.CheckPatchJSON(testDialClient, addr, []byte(`{"max-store-down-time":"2700s"}`), tu.StatusOK(re), tu.ExtractJSON(re, result)))
+	suite.Empty(result.Changes)
+	// The unknown fields, the mismatched types and the invalid values are rejected.
+	for _, patch := range []string{`{"unknown":1}`, `{"leader-schedule-limit":"x"}`, `{"tolerant-size-ratio":-1}`} {
+		suite.NoError(tu.CheckPatchJSON(testDialClient, addr, []byte(patch), tu.Status(re, http.StatusBadRequest)))
+	}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, sc1))
+	suite.Equal(*sc, *sc1)
+	// The fields can't be removed, which would reset them to 0.
+	suite.NoError(tu.CheckPatchJSON(testDialClient, addr, []byte(`{"leader-schedule-limit":null}`), tu.Status(re, http.StatusBadRequest)))
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, sc1))
+	suite.Equal(*sc, *sc1)
+
+	// The JSON patch.
+	addr = fmt.Sprintf("%s/config/replicate", suite.urlPrefix)
+	rc := &config.ReplicationConfig{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, rc))
+	patchJSON := func(ops string) *http.Response {
+		req, err := http.NewRequest(http.MethodPatch, addr, strings.NewReader(ops))
+		suite.NoError(err)
+		req.Header.Set("Content-Type", "application/json-patch+json")
+		resp, err := testDialClient.Do(req)
+		suite.NoError(err)
+		return resp
+	}
+	resp := patchJSON(fmt.Sprintf(`[{"op":"test","path":"/max-replicas","value":%d},{"op":"replace","path":"/max-replicas","value":%d}]`, rc.MaxReplicas, rc.MaxReplicas+2))
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.NoError(json.NewDecoder(resp.Body).Decode(result))
+	resp.Body.Close()
+	suite.Equal([]jsonutil.Change{{Path: "/max-replicas", Old: float64(rc.MaxReplicas), New: float64(rc.MaxReplicas + 2)}}, result.Changes)
+	// The failed test rejects the whole patch.
+	resp = patchJSON(fmt.Sprintf(`[{"op":"replace","path":"/max-replicas","value":3},{"op":"test","path":"/max-replicas","value":%d}]`, rc.MaxReplicas))
+	suite.Equal(http.StatusBadRequest, resp.StatusCode)
+	resp.Body.Close()
+	rc1 := &config.ReplicationConfig{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, rc1))
+	suite.Equal(rc.MaxReplicas+2, rc1.MaxReplicas)
+	for _, ops := range []string{`[{"op":"remove","path":"/max-replicas"}]`, `[{"op":"replace","path":"/max-replicas","value":null}]`} {
+		resp = patchJSON(ops)
+		suite.Equal(http.StatusBadRequest, resp.StatusCode)
+		resp.Body.Close()
+	}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, rc1))
+	suite.Equal(rc.MaxReplicas+2, rc1.MaxReplicas)
+
+	// The concurrent patches don't overwrite each other.
+	addr = fmt.Sprintf("%s/config/schedule", suite.urlPrefix)
+	var wg sync.WaitGroup
+	for _, patch := range []string{`{"leader-schedule-limit":11}`, `{"region-schedule-limit":1111}`, `{"merge-schedule-limit":11}`} {
+		wg.Add(1)
+		go func(patch string) {
+			defer wg.Done()
+			suite.NoError(tu.CheckPatchJSON(testDialClient, addr, []byte(patch), tu.StatusOK(re)))
+		}(patch)
+	}
+	wg.Wait()
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, addr, sc1))
+	suite.Equal(uint64(11), sc1.LeaderScheduleLimit)
+	suite.Equal(uint64(1111), sc1.RegionScheduleLimit)
+	suite.Equal(uint64(11), sc1.MergeScheduleLimit)
+}
+
 var ttlConfig = map[string]interface{}{
 	"schedule.max-snapshot-count":             999,
 	"schedule.enable-location-replacement":    false,
@@ -421,3 +592,53 @@ func (suite *configTestSuite) TestTTLConflict() {
 	err = tu.CheckPostJSON(testDialClient, addr, postData, tu.StatusOK(re))
 	suite.NoError(err)
 }
+
+func TestReplicationMigration(t *testing.T) {
+	re := require.New(t)
+	svr, cleanup := mustNewServer(re, func(cfg *config.Config) {
+		cfg.Replication.EnablePlacementRules = false
+		cfg.Replication.MaxReplicas = 1
+	})
+	defer cleanup()
+	server.MustWaitLeader(re, []*server.Server{svr})
+	mustBootstrapCluster(re, svr)
+	mustPutStore(re, svr, 1, metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+	mustRegionHeartbeat(re, svr, core.NewRegionInfo(region, peers[0]))
+
+	addr := fmt.Sprintf("%s%s/api/v1/config/replicate", svr.GetAddr(), apiPrefix)
+	migrate := func(enable bool, checkOpts ...func([]byte, int)) {
+		postData, err := json.Marshal(&ReplicationMigrationInput{EnablePlacementRules: enable})
+		re.NoError(err)
+		re.NoError(tu.CheckPostJSON(testDialClient, addr+"/migration", postData, checkOpts...))
+	}
+
+	// max-replicas -> placement rules
+	var result server.ReplicationMigrationResult
+	migrate(true, tu.StatusOK(re), tu.ExtractJSON(re, &result))
+	re.True(result.Applied)
+	re.Equal(1, result.SampledRegions)
+	re.Len(result.Rules, 1)
+	re.Equal(1, result.Rules[0].Count)
+	re.True(svr.GetReplicationConfig().EnablePlacementRules)
+	migrate(true, tu.Status(re, http.StatusBadRequest))
+
+	// Only a single default rule can be converted back.
+	ruleManager := svr.GetRaftCluster().GetRuleManager()
+	learnerRule := &placement.Rule{GroupID: "test", ID: "learner", Role: placement.Learner, Count: 1}
+	re.NoError(ruleManager.SetRule(learnerRule))
+	migrate(false, tu.Status(re, http.StatusBadRequest), tu.StringContain(re, "single voter rule"))
+	re.True(svr.GetReplicationConfig().EnablePlacementRules)
+	re.NoError(ruleManager.DeleteRule("test", "learner"))
+
+	// placement rules -> max-replicas
+	result = server.ReplicationMigrationResult{}
+	migrate(false, tu.StatusOK(re), tu.ExtractJSON(re, &result))
+	re.True(result.Applied)
+	re.Equal(uint64(1), result.MaxReplicas)
+	re.False(svr.GetReplicationConfig().EnablePlacementRules)
+
+	// A stale rule makes the fit differ, the flag is left unchanged.
+	re.NoError(ruleManager.SetRule(learnerRule))
+	migrate(true, tu.Status(re, http.StatusBadRequest), tu.StringContain(re, "differs"))
+	re.False(svr.GetReplicationConfig().EnablePlacementRules)
+}
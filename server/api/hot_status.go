@@ -19,9 +19,13 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"sort"
 	"strconv"
 
+	"github.com/docker/go-units"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/statistics"
 	"github.com/tikv/pd/server/storage"
 	"github.com/unrolled/render"
@@ -247,3 +251,102 @@ func getAllRequestHistroyHotRegion(handler *server.Handler, request *HistoryHotR
 		HistoryHotRegion: results,
 	}, err
 }
+
+const openMetricsContentType = "application/openmetrics-text; version=1.0.0; charset=utf-8"
+
+// The families of the hot peer exemplars, which are the distributions of the
+// rates of the hottest peers of each store.
+var hotPeerExemplarFamilies = []struct {
+	name    string
+	help    string
+	rate    func(*cluster.HotPeerExemplar) float64
+	buckets []float64
+}{
+	{"pd_hotspot_top_peer_byte_rate", "The byte rate distribution of the hottest peers of the store.",
+		func(e *cluster.HotPeerExemplar) float64 { return e.ByteRate }, prometheus.ExponentialBuckets(4*units.KiB, 4, 8)},
+	{"pd_hotspot_top_peer_key_rate", "The key rate distribution of the hottest peers of the store.",
+		func(e *cluster.HotPeerExemplar) float64 { return e.KeyRate }, prometheus.ExponentialBuckets(16, 4, 8)},
+	{"pd_hotspot_top_peer_query_rate", "The query rate distribution of the hottest peers of the store.",
+		func(e *cluster.HotPeerExemplar) float64 { return e.QueryRate }, prometheus.ExponentialBuckets(1, 4, 8)},
+}
+
+// @Tags     hotspot
+// @Summary  Export the hottest peers of each store in the OpenMetrics text format, with their regions as the exemplars.
+// @Produce  plain
+// @Success  200  {string}  string  "The hot peers in the OpenMetrics text format."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /hotspot/exemplars [get]
+func (h *hotStatusHandler) GetHotPeerExemplars(w http.ResponseWriter, r *http.Request) {
+	exemplars, err := h.Handler.GetHotPeerExemplars()
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	w.Header().Set("Content-Type", openMetricsContentType)
+	w.WriteHeader(http.StatusOK)
+	writeHotPeerExemplars(w, exemplars)
+}
+
+// writeHotPeerExemplars writes the rates of the hot peers as gauge histograms
+// labeled by the store and the read or write type. OpenMetrics only allows the
+// exemplars on the counters and the buckets of the histograms, so each bucket
+// carries the hottest peer in it as the exemplar, with its region ID and the
+// hash of its key range, e.g.
+//
+//	pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="16384"} 1 # {region_id="2",key_range_hash="..."} 10240 1665900000.500
+//
+// The gauge histograms are used because the peers are a snapshot of the hot
+// statistics rather than accumulated observations.
+func writeHotPeerExemplars(w io.Writer, exemplars *cluster.HotPeerExemplars) {
+	ts := strconv.FormatFloat(float64(exemplars.UpdatedAt.UnixNano())/1e9, 'f', 3, 64)
+	for _, family := range hotPeerExemplarFamilies {
+		fmt.Fprintf(w, "# HELP %s %s\n", family.name, family.help)
+		fmt.Fprintf(w, "# TYPE %s gaugehistogram\n", family.name)
+		// The peers are ordered by the store and the type.
+		for start := 0; start < len(exemplars.Peers); {
+			end := start + 1
+			for end < len(exemplars.Peers) && exemplars.Peers[end].StoreID == exemplars.Peers[start].StoreID &&
+				exemplars.Peers[end].RWType == exemplars.Peers[start].RWType {
+				end++
+			}
+			writeHotPeerHistogram(w, family.name, family.rate, family.buckets, exemplars.Peers[start:end], ts)
+			start = end
+		}
+	}
+	fmt.Fprint(w, "# EOF\n")
+}
+
+// writeHotPeerHistogram writes the histogram of the peers of a store and a type.
+func writeHotPeerHistogram(w io.Writer, name string, rate func(*cluster.HotPeerExemplar) float64, buckets []float64, peers []cluster.HotPeerExemplar, ts string) {
+	labels := fmt.Sprintf("store=\"%d\",type=\"%s\"", peers[0].StoreID, peers[0].RWType)
+	// The last one is the +Inf bucket.
+	counts := make([]int, len(buckets)+1)
+	hottest := make([]*cluster.HotPeerExemplar, len(buckets)+1)
+	sum := 0.0
+	for i := range peers {
+		e := &peers[i]
+		r := rate(e)
+		sum += r
+		b := sort.SearchFloat64s(buckets, r)
+		counts[b]++
+		if hottest[b] == nil || rate(hottest[b]) < r {
+			hottest[b] = e
+		}
+	}
+	cumulative := 0
+	for b := range counts {
+		cumulative += counts[b]
+		le := "+Inf"
+		if b < len(buckets) {
+			le = strconv.FormatFloat(buckets[b], 'f', -1, 64)
+		}
+		fmt.Fprintf(w, "%s_bucket{%s,le=\"%s\"} %d", name, labels, le, cumulative)
+		if e := hottest[b]; e != nil {
+			fmt.Fprintf(w, " # {region_id=\"%d\",key_range_hash=\"%s\"} %s %s",
+				e.RegionID, e.KeyRangeHash, strconv.FormatFloat(rate(e), 'g', -1, 64), ts)
+		}
+		fmt.Fprint(w, "\n")
+	}
+	fmt.Fprintf(w, "%s_gcount{%s} %d\n", name, labels, len(peers))
+	fmt.Fprintf(w, "%s_gsum{%s} %s\n", name, labels, strconv.FormatFloat(sum, 'g', -1, 64))
+}
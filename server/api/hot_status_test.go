@@ -15,15 +15,21 @@
 package api
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
+	"io"
+	"net/http"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"github.com/syndtr/goleveldb/leveldb"
 	tu "github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	_ "github.com/tikv/pd/server/schedulers"
 	"github.com/tikv/pd/server/storage"
 	"github.com/tikv/pd/server/storage/kv"
@@ -61,6 +67,101 @@ func (suite *hotStatusTestSuite) TestGetHotStore() {
 	suite.NoError(err)
 }
 
+func (suite *hotStatusTestSuite) TestGetHotPeerExemplars() {
+	re := suite.Require()
+	res, err := testDialClient.Get(suite.urlPrefix + "/exemplars")
+	suite.NoError(err)
+	defer res.Body.Close()
+	suite.Equal(http.StatusOK, res.StatusCode)
+	suite.Equal(openMetricsContentType, res.Header.Get("Content-Type"))
+	body, err := io.ReadAll(res.Body)
+	re.NoError(err)
+	suite.True(strings.HasSuffix(string(body), "# EOF\n"))
+}
+
+func TestWriteHotPeerExemplars(t *testing.T) {
+	re := require.New(t)
+	exemplars := &cluster.HotPeerExemplars{
+		UpdatedAt: time.Unix(1665900000, 500000000),
+		TopN:      1,
+		Peers: []cluster.HotPeerExemplar{
+			{StoreID: 1, RWType: "write", Rank: 1, RegionID: 2, KeyRangeHash: "0123456789abcdef", ByteRate: 1024, KeyRate: 10.5},
+		},
+	}
+	var buf bytes.Buffer
+	writeHotPeerExemplars(&buf, exemplars)
+	re.Equal(`# HELP pd_hotspot_top_peer_byte_rate The byte rate distribution of the hottest peers of the store.
+# TYPE pd_hotspot_top_peer_byte_rate gaugehistogram
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="4096"} 1 # {region_id="2",key_range_hash="0123456789abcdef"} 1024 1665900000.500
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="16384"} 1
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="65536"} 1
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="262144"} 1
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="1048576"} 1
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="4194304"} 1
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="16777216"} 1
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="67108864"} 1
+pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="+Inf"} 1
+pd_hotspot_top_peer_byte_rate_gcount{store="1",type="write"} 1
+pd_hotspot_top_peer_byte_rate_gsum{store="1",type="write"} 1024
+# HELP pd_hotspot_top_peer_key_rate The key rate distribution of the hottest peers of the store.
+# TYPE pd_hotspot_top_peer_key_rate gaugehistogram
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="16"} 1 # {region_id="2",key_range_hash="0123456789abcdef"} 10.5 1665900000.500
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="64"} 1
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="256"} 1
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="1024"} 1
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="4096"} 1
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="16384"} 1
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="65536"} 1
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="262144"} 1
+pd_hotspot_top_peer_key_rate_bucket{store="1",type="write",le="+Inf"} 1
+pd_hotspot_top_peer_key_rate_gcount{store="1",type="write"} 1
+pd_hotspot_top_peer_key_rate_gsum{store="1",type="write"} 10.5
+# HELP pd_hotspot_top_peer_query_rate The query rate distribution of the hottest peers of the store.
+# TYPE pd_hotspot_top_peer_query_rate gaugehistogram
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="1"} 1 # {region_id="2",key_range_hash="0123456789abcdef"} 0 1665900000.500
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="4"} 1
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="16"} 1
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="64"} 1
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="256"} 1
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="1024"} 1
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="4096"} 1
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="16384"} 1
+pd_hotspot_top_peer_query_rate_bucket{store="1",type="write",le="+Inf"} 1
+pd_hotspot_top_peer_query_rate_gcount{store="1",type="write"} 1
+pd_hotspot_top_peer_query_rate_gsum{store="1",type="write"} 0
+# EOF
+`, buf.String())
+}
+
+func TestWriteHotPeerExemplarsBuckets(t *testing.T) {
+	re := require.New(t)
+	exemplars := &cluster.HotPeerExemplars{
+		UpdatedAt: time.Unix(1665900000, 500000000),
+		TopN:      2,
+		Peers: []cluster.HotPeerExemplar{
+			{StoreID: 1, RWType: "write", Rank: 1, RegionID: 3, KeyRangeHash: "0123456789abcdef", ByteRate: 2048},
+			{StoreID: 1, RWType: "write", Rank: 2, RegionID: 2, KeyRangeHash: "fedcba9876543210", ByteRate: 1024},
+			{StoreID: 2, RWType: "read", Rank: 1, RegionID: 4, ByteRate: 100000},
+		},
+	}
+	var buf bytes.Buffer
+	writeHotPeerExemplars(&buf, exemplars)
+	text := buf.String()
+	// Each bucket carries the hottest peer in it.
+	re.Contains(text, `pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="4096"} 2 # {region_id="3",key_range_hash="0123456789abcdef"} 2048 1665900000.500`+"\n")
+	re.Contains(text, `pd_hotspot_top_peer_byte_rate_bucket{store="1",type="write",le="16384"} 2`+"\n")
+	re.Contains(text, `pd_hotspot_top_peer_byte_rate_gcount{store="1",type="write"} 2`+"\n")
+	re.Contains(text, `pd_hotspot_top_peer_byte_rate_gsum{store="1",type="write"} 3072`+"\n")
+	re.Contains(text, `pd_hotspot_top_peer_byte_rate_bucket{store="2",type="read",le="65536"} 0`+"\n")
+	re.Contains(text, `pd_hotspot_top_peer_byte_rate_bucket{store="2",type="read",le="262144"} 1 # {region_id="4",key_range_hash=""} 100000 1665900000.500`+"\n")
+	// No exemplar is attached to the other samples.
+	for _, line := range strings.Split(text, "\n") {
+		if strings.Contains(line, " # {") {
+			re.Contains(line, "_bucket{")
+		}
+	}
+}
+
 func (suite *hotStatusTestSuite) TestGetHistoryHotRegionsBasic() {
 	request := HistoryHotRegionsRequest{
 		StartTime: 0,
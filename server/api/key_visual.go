@@ -0,0 +1,119 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/keyvisual"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/unrolled/render"
+)
+
+const defaultKeyVisualPeriod = time.Hour
+
+type keyVisualHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newKeyVisualHandler(s *server.Server, rd *render.Render) *keyVisualHandler {
+	return &keyVisualHandler{
+		svr: s,
+		rd:  rd,
+	}
+}
+
+// @Tags     key_visual
+// @Summary  Get a tile of the heatmap of the loads in key ranges and periods.
+// @Param    type        query  string   false  "The kind of the loads"  Enums(read_bytes, read_keys, read_query, write_bytes, write_keys, write_query)
+// @Param    start_key   query  string   false  "The hex encoded start key"
+// @Param    end_key     query  string   false  "The hex encoded end key"
+// @Param    start_time  query  integer  false  "The start Unix timestamp, defaults to an hour ago"
+// @Param    end_time    query  integer  false  "The end Unix timestamp, defaults to now"
+// @Param    max_ranges  query  integer  false  "The max number of key ranges"
+// @Produce  json
+// @Success  200  {object}  keyvisual.Matrix
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /keyvisual/heatmap [get]
+func (h *keyVisualHandler) GetHeatmap(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	query := r.URL.Query()
+
+	kind := statistics.RegionWriteBytes
+	if typ := query.Get("type"); typ != "" {
+		var ok bool
+		if kind, ok = parseRegionStatKind(typ); !ok {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid type")
+			return
+		}
+	}
+	startKey, err := hex.DecodeString(query.Get("start_key"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	endKey, err := hex.DecodeString(query.Get("end_key"))
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	endTime := time.Now()
+	if endTimeStr := query.Get("end_time"); endTimeStr != "" {
+		ts, err := strconv.ParseInt(endTimeStr, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		endTime = time.Unix(ts, 0)
+	}
+	startTime := endTime.Add(-defaultKeyVisualPeriod)
+	if startTimeStr := query.Get("start_time"); startTimeStr != "" {
+		ts, err := strconv.ParseInt(startTimeStr, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		startTime = time.Unix(ts, 0)
+	}
+	if !startTime.Before(endTime) {
+		h.rd.JSON(w, http.StatusBadRequest, "start_time should be earlier than end_time")
+		return
+	}
+	maxRanges := keyvisual.DefaultMatrixRanges
+	if maxRangesStr := query.Get("max_ranges"); maxRangesStr != "" {
+		maxRanges, err = strconv.Atoi(maxRangesStr)
+		if err != nil || maxRanges <= 0 || maxRanges > keyvisual.MaxMatrixRanges {
+			h.rd.JSON(w, http.StatusBadRequest, "max_ranges should be a positive integer no more than "+strconv.Itoa(keyvisual.MaxMatrixRanges))
+			return
+		}
+	}
+
+	matrix := rc.GetKeyVisualService().GetMatrix(kind, startKey, endKey, startTime, endTime, maxRanges)
+	h.rd.JSON(w, http.StatusOK, matrix)
+}
+
+func parseRegionStatKind(typ string) (statistics.RegionStatKind, bool) {
+	for kind := statistics.RegionStatKind(0); kind < statistics.RegionStatCount; kind++ {
+		if kind.String() == typ {
+			return kind, true
+		}
+	}
+	return 0, false
+}
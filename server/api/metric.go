@@ -20,8 +20,11 @@ import (
 	"net/url"
 	"strings"
 
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/pkg/apiutil/serverapi"
+	"github.com/tikv/pd/pkg/metricutil"
 	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
 )
 
 type queryMetric struct {
@@ -54,3 +57,36 @@ func (h *queryMetric) QueryMetric(w http.ResponseWriter, r *http.Request) {
 		http.Error(w, fmt.Sprintf("schema of metric storage address is no supported, address: %v", metricAddr), http.StatusInternalServerError)
 	}
 }
+
+type metricsCardinalityHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newMetricsCardinalityHandler(svr *server.Server, rd *render.Render) *metricsCardinalityHandler {
+	return &metricsCardinalityHandler{svr: svr, rd: rd}
+}
+
+// MetricsCardinality is the number of the series of the metric families exported by the PD server.
+type MetricsCardinality struct {
+	LabelGranularity string                          `json:"label_granularity"`
+	Families         []*metricutil.FamilyCardinality `json:"families"`
+}
+
+// @Tags     metric
+// @Summary  Get the number of the series of each metric family, and whether it is controlled by the configuration.
+// @Produce  json
+// @Success  200  {object}  MetricsCardinality
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /metric/cardinality [get]
+func (h *metricsCardinalityHandler) GetMetricsCardinality(w http.ResponseWriter, r *http.Request) {
+	families, err := metricutil.GetCardinality(prometheus.DefaultGatherer)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &MetricsCardinality{
+		LabelGranularity: h.svr.GetPersistOptions().GetMetricsLabelGranularity(),
+		Families:         families,
+	})
+}
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/pingcap/failpoint"
+	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/audit"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/requestutil"
@@ -38,7 +39,7 @@ type serviceMiddlewareBuilder struct {
 func newServiceMiddlewareBuilder(s *server.Server) *serviceMiddlewareBuilder {
 	return &serviceMiddlewareBuilder{
 		svr:      s,
-		handlers: []negroni.Handler{newRequestInfoMiddleware(s), newAuditMiddleware(s), newRateLimitMiddleware(s)},
+		handlers: []negroni.Handler{newSLOMiddleware(s), newRequestInfoMiddleware(s), newAuditMiddleware(s), newRateLimitMiddleware(s)},
 	}
 }
 
@@ -156,6 +157,29 @@ func (s *auditMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next
 	}
 }
 
+// sloMiddleware is used to track the latencies of the HTTP APIs.
+// It is placed before the rate limit middleware, so the rejected requests are also
+// tracked, which lets the latency of a shed API recover.
+type sloMiddleware struct {
+	svr *server.Server
+}
+
+func newSLOMiddleware(s *server.Server) negroni.Handler {
+	return &sloMiddleware{svr: s}
+}
+
+// ServeHTTP is used to implememt negroni.Handler for sloMiddleware
+func (s *sloMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+	route := apiutil.GetRouteName(r)
+	if route == "" {
+		next(w, r)
+		return
+	}
+	start := time.Now()
+	next(w, r)
+	s.svr.GetSLOTracker().Observe(route, time.Since(start))
+}
+
 type rateLimitMiddleware struct {
 	svr *server.Server
 }
@@ -177,6 +201,10 @@ func (s *rateLimitMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 
 	// There is no need to check whether rateLimiter is nil. CreateServer ensures that it is created
 	rateLimiter := s.svr.GetServiceRateLimiter()
+	if s.isShed(requestInfo.ServiceLabel) {
+		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+		return
+	}
 	if rateLimiter.Allow(requestInfo.ServiceLabel) {
 		defer rateLimiter.Release(requestInfo.ServiceLabel)
 		next(w, r)
@@ -184,3 +212,12 @@ func (s *rateLimitMiddleware) ServeHTTP(w http.ResponseWriter, r *http.Request,
 		http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
 	}
 }
+
+// isShed returns true if PD is overloaded and the API is one of the slow ones.
+// The APIs which are rarely called won't be shed, so the config can still be changed.
+func (s *rateLimitMiddleware) isShed(serviceLabel string) bool {
+	if s.svr.IsInRateLimitAllowList(serviceLabel) || !s.svr.IsOverloaded() {
+		return false
+	}
+	return s.svr.GetSLOTracker().IsSlow(serviceLabel, s.svr.GetServiceMiddlewarePersistOptions().GetOverloadLatencyThreshold())
+}
@@ -19,6 +19,7 @@ import (
 
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/unrolled/render"
 )
 
@@ -39,6 +40,10 @@ type minResolvedTS struct {
 	IsRealTime      bool              `json:"is_real_time,omitempty"`
 	MinResolvedTS   uint64            `json:"min_resolved_ts"`
 	PersistInterval typeutil.Duration `json:"persist_interval,omitempty"`
+	// MinStoreID is the store whose resolved ts is currently the minimum.
+	MinStoreID uint64 `json:"min_store_id,omitempty"`
+	// Stores are the stores counted in the min resolved ts, sorted by their resolved ts.
+	Stores []*cluster.StoreMinResolvedTS `json:"stores,omitempty"`
 }
 
 // @Tags     min_resolved_ts
@@ -51,9 +56,12 @@ func (h *minResolvedTSHandler) GetMinResolvedTS(w http.ResponseWriter, r *http.R
 	c := h.svr.GetRaftCluster()
 	value := c.GetMinResolvedTS()
 	persistInterval := c.GetOpts().GetPDServerConfig().MinResolvedTSPersistenceInterval
+	status := c.GetMinResolvedTSStatus()
 	h.rd.JSON(w, http.StatusOK, minResolvedTS{
 		MinResolvedTS:   value,
 		PersistInterval: persistInterval,
 		IsRealTime:      persistInterval.Duration != 0,
+		MinStoreID:      status.MinStoreID,
+		Stores:          status.Stores,
 	})
 }
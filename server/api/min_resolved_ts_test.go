@@ -66,10 +66,13 @@ func (suite *minResolvedTSTestSuite) TestMinResolvedTS() {
 	rc.SetMinResolvedTS(1, ts)
 
 	// no run job
+	stores := []*cluster.StoreMinResolvedTS{{StoreID: 1, MinResolvedTS: ts}}
 	result := &minResolvedTS{
 		MinResolvedTS:   0,
 		IsRealTime:      false,
 		PersistInterval: typeutil.Duration{Duration: 0},
+		MinStoreID:      1,
+		Stores:          stores,
 	}
 	res, err := testDialClient.Get(url)
 	suite.NoError(err)
@@ -89,6 +92,8 @@ func (suite *minResolvedTSTestSuite) TestMinResolvedTS() {
 		MinResolvedTS:   ts,
 		IsRealTime:      true,
 		PersistInterval: interval,
+		MinStoreID:      1,
+		Stores:          stores,
 	}
 	res, err = testDialClient.Get(url)
 	suite.NoError(err)
@@ -20,6 +20,7 @@ import (
 	"time"
 
 	"github.com/gorilla/mux"
+	"github.com/pingcap/errors"
 	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
@@ -68,9 +69,15 @@ func (h *operatorHandler) GetOperatorsByRegion(w http.ResponseWriter, r *http.Re
 
 // @Tags     operator
 // @Summary  List pending operators.
-// @Param    kind  query  string  false  "Specify the operator kind."  Enums(admin, leader, region)
+// @Param    kind       query  string   false  "Specify the operator kind."  Enums(admin, leader, region)
+// @Param    component  query  string   false  "Specify the component in the metadata of the operators."
+// @Param    ticket_id  query  string   false  "Specify the ticket ID in the metadata of the operators."
+// @Param    limit      query  integer  false  "The max number of operators in a page, at most 10000, 0 means no limit."
+// @Param    cursor     query  string   false  "The cursor returned by the previous page in the PD-Next-Cursor header."
+// @Param    fields     query  string   false  "The comma separated fields of operators to return, e.g. id,region_id,steps. The operators are returned as objects if set."
 // @Produce  json
 // @Success  200  {array}   operator.Operator
+// @Failure  400  {string}  string  "The input is invalid."
 // @Failure  500  {string}  string  "PD server failed to proceed the request."
 // @Router   /operators [get]
 func (h *operatorHandler) GetOperators(w http.ResponseWriter, r *http.Request) {
@@ -80,6 +87,11 @@ func (h *operatorHandler) GetOperators(w http.ResponseWriter, r *http.Request) {
 		err     error
 	)
 
+	page, ok := parsePage(h.r, w, r)
+	if !ok {
+		return
+	}
+
 	kinds, ok := r.URL.Query()["kind"]
 	if !ok {
 		results, err = h.Handler.GetOperators()
@@ -107,7 +119,63 @@ func (h *operatorHandler) GetOperators(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.r.JSON(w, http.StatusOK, results)
+	if filter := parseOperatorMetadataFilter(r); !filter.IsEmpty() {
+		filtered := results[:0]
+		for _, op := range results {
+			if op.GetMetadata().Match(filter) {
+				filtered = append(filtered, op)
+			}
+		}
+		results = filtered
+	}
+	ids := make([]uint64, len(results))
+	for i, op := range results {
+		ids[i] = op.ID()
+	}
+	start, end, err := page.ApplyIDs(ids, func(i, j int) { results[i], results[j] = results[j], results[i] })
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	results = results[start:end]
+	if len(page.Fields) == 0 {
+		renderPage(h.r, w, page, "", results, 0)
+		return
+	}
+	// The operators are rendered as strings, so the fields are selected from
+	// their structured views.
+	views := make([]*operatorView, 0, len(results))
+	for _, op := range results {
+		view, err := newOperatorView(op)
+		if err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+		views = append(views, view)
+	}
+	renderPage(h.r, w, page, "", views, 0)
+}
+
+// operatorView is the structured view of an operator, whose fields can be
+// selected by the `fields` query parameter.
+type operatorView struct {
+	ID         uint64    `json:"id"`
+	Status     string    `json:"status"`
+	CreateTime time.Time `json:"create_time"`
+	*operator.Snapshot
+}
+
+func newOperatorView(op *operator.Operator) (*operatorView, error) {
+	snapshot, err := op.Snapshot()
+	if err != nil {
+		return nil, err
+	}
+	return &operatorView{
+		ID:         op.ID(),
+		Status:     operator.OpStatusToString(op.Status()),
+		CreateTime: op.GetCreateTime(),
+		Snapshot:   snapshot,
+	}, nil
 }
 
 // FIXME: details of input json body params
@@ -125,12 +193,22 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
 		return
 	}
+	h.createOperator(w, input)
+}
 
+// createOperator creates the operator described by the input, which is also
+// the expansion of an operator template.
+func (h *operatorHandler) createOperator(w http.ResponseWriter, input map[string]interface{}) {
 	name, ok := input["name"].(string)
 	if !ok {
 		h.r.JSON(w, http.StatusBadRequest, "missing operator name")
 		return
 	}
+	opts, err := parseOperatorMetadata(input["metadata"])
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
 
 	switch name {
 	case "transfer-leader":
@@ -144,7 +222,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			h.r.JSON(w, http.StatusBadRequest, "missing store id to transfer leader to")
 			return
 		}
-		if err := h.AddTransferLeaderOperator(uint64(regionID), uint64(storeID)); err != nil {
+		if err := h.AddTransferLeaderOperator(uint64(regionID), uint64(storeID), opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -163,7 +241,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			h.r.JSON(w, http.StatusBadRequest, "missing store ids to transfer region to")
 			return
 		}
-		if err := h.AddTransferRegionOperator(uint64(regionID), storeIDs); err != nil {
+		if err := h.AddTransferRegionOperator(uint64(regionID), storeIDs, opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -183,7 +261,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddTransferPeerOperator(uint64(regionID), uint64(fromID), uint64(toID)); err != nil {
+		if err := h.AddTransferPeerOperator(uint64(regionID), uint64(fromID), uint64(toID), opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -198,7 +276,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddAddPeerOperator(uint64(regionID), uint64(storeID)); err != nil {
+		if err := h.AddAddPeerOperator(uint64(regionID), uint64(storeID), opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -213,7 +291,22 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddAddLearnerOperator(uint64(regionID), uint64(storeID)); err != nil {
+		if err := h.AddAddLearnerOperator(uint64(regionID), uint64(storeID), opts...); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case "demote-voter":
+		regionID, ok := input["region_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing region id")
+			return
+		}
+		storeID, ok := input["store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "invalid store id to demote voter")
+			return
+		}
+		if err := h.AddDemoteVoterOperator(uint64(regionID), uint64(storeID), opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -228,7 +321,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			h.r.JSON(w, http.StatusBadRequest, "invalid store id to transfer peer to")
 			return
 		}
-		if err := h.AddRemovePeerOperator(uint64(regionID), uint64(storeID)); err != nil {
+		if err := h.AddRemovePeerOperator(uint64(regionID), uint64(storeID), opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -243,7 +336,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			h.r.JSON(w, http.StatusBadRequest, "invalid target region id to merge to")
 			return
 		}
-		if err := h.AddMergeRegionOperator(uint64(regionID), uint64(targetID)); err != nil {
+		if err := h.AddMergeRegionOperator(uint64(regionID), uint64(targetID), opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -269,7 +362,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 				keys = append(keys, key)
 			}
 		}
-		if err := h.AddSplitRegionOperator(uint64(regionID), policy, keys); err != nil {
+		if err := h.AddSplitRegionOperator(uint64(regionID), policy, keys, opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -280,7 +373,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 			return
 		}
 		group, _ := input["group"].(string)
-		if err := h.AddScatterRegionOperator(uint64(regionID), group); err != nil {
+		if err := h.AddScatterRegionOperator(uint64(regionID), group, opts...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -299,7 +392,7 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 		if rl, ok := input["retry_limit"].(float64); ok {
 			retryLimit = int(rl)
 		}
-		processedPercentage, err := h.AddScatterRegionsOperators(ids, startKey, endKey, group, retryLimit)
+		processedPercentage, err := h.AddScatterRegionsOperators(ids, startKey, endKey, group, retryLimit, opts...)
 		errorMessage := ""
 		if err != nil {
 			errorMessage = err.Error()
@@ -313,6 +406,26 @@ func (h *operatorHandler) CreateOperator(w http.ResponseWriter, r *http.Request)
 		}
 		h.r.JSON(w, http.StatusOK, &s)
 		return
+	case "evict-peer":
+		regionID, ok := input["region_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "missing region id")
+			return
+		}
+		fromID, ok := input["from_store_id"].(float64)
+		if !ok {
+			h.r.JSON(w, http.StatusBadRequest, "invalid store id to evict peer from")
+			return
+		}
+		var constraints []placement.LabelConstraint
+		if key, ok := input["to_label_key"].(string); ok {
+			value, _ := input["to_label_value"].(string)
+			constraints = append(constraints, placement.LabelConstraint{Key: key, Op: placement.In, Values: []string{value}})
+		}
+		if err := h.AddEvictPeerOperator(uint64(regionID), uint64(fromID), constraints, opts...); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
 	default:
 		h.r.JSON(w, http.StatusBadRequest, "unknown operator")
 		return
@@ -345,9 +458,34 @@ func (h *operatorHandler) DeleteOperatorByRegion(w http.ResponseWriter, r *http.
 	h.r.JSON(w, http.StatusOK, "The pending operator is canceled.")
 }
 
+// @Tags     operator
+// @Summary  Cancel the pending operators whose metadata matches the filter.
+// @Param    component  query  string  false  "Specify the component in the metadata of the operators."
+// @Param    ticket_id  query  string  false  "Specify the ticket ID in the metadata of the operators."
+// @Produce  json
+// @Success  200  {integer}  int     "The number of the canceled operators."
+// @Failure  400  {string}   string  "The input is invalid."
+// @Failure  500  {string}   string  "PD server failed to proceed the request."
+// @Router   /operators [delete]
+func (h *operatorHandler) DeleteOperators(w http.ResponseWriter, r *http.Request) {
+	filter := parseOperatorMetadataFilter(r)
+	if filter.IsEmpty() {
+		h.r.JSON(w, http.StatusBadRequest, "missing component or ticket_id")
+		return
+	}
+	canceled, err := h.RemoveOperatorsByMetadata(filter)
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, canceled)
+}
+
 // @Tags     operator
 // @Summary  lists the finished operators since the given timestamp in second.
-// @Param    from  query  integer  false  "From Unix timestamp"
+// @Param    from       query  integer  false  "From Unix timestamp"
+// @Param    component  query  string   false  "Specify the component in the metadata of the operators."
+// @Param    ticket_id  query  string   false  "Specify the ticket ID in the metadata of the operators."
 // @Produce  json
 // @Success  200  {object}  []operator.OpRecord
 // @Failure  400  {string}  string  "The request is invalid."
@@ -368,9 +506,53 @@ func (h *operatorHandler) GetOperatorRecords(w http.ResponseWriter, r *http.Requ
 		h.r.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	if filter := parseOperatorMetadataFilter(r); !filter.IsEmpty() {
+		filtered := records[:0]
+		for _, record := range records {
+			if record.GetMetadata().Match(filter) {
+				filtered = append(filtered, record)
+			}
+		}
+		records = filtered
+	}
 	h.r.JSON(w, http.StatusOK, records)
 }
 
+// parseOperatorMetadata parses the optional metadata of the caller in the
+// input of creating operators.
+func parseOperatorMetadata(input interface{}) ([]server.OperatorOption, error) {
+	if input == nil {
+		return nil, nil
+	}
+	items, ok := input.(map[string]interface{})
+	if !ok {
+		return nil, errors.New("metadata should be an object")
+	}
+	metadata := &operator.Metadata{}
+	for key, field := range map[string]*string{
+		"component": &metadata.Component,
+		"ticket_id": &metadata.TicketID,
+		"comment":   &metadata.Comment,
+	} {
+		if v, ok := items[key]; ok {
+			str, ok := v.(string)
+			if !ok {
+				return nil, errors.Errorf("metadata %s should be a string", key)
+			}
+			*field = str
+		}
+	}
+	return []server.OperatorOption{server.WithOperatorMetadata(metadata)}, nil
+}
+
+func parseOperatorMetadataFilter(r *http.Request) *operator.Metadata {
+	query := r.URL.Query()
+	return &operator.Metadata{
+		Component: query.Get("component"),
+		TicketID:  query.Get("ticket_id"),
+	}
+}
+
 func parseStoreIDsAndPeerRole(ids interface{}, roles interface{}) (map[uint64]placement.PeerRoleType, bool) {
 	items, ok := ids.([]interface{})
 	if !ok {
@@ -400,3 +582,29 @@ func parseStoreIDsAndPeerRole(ids interface{}, roles interface{}) (map[uint64]pl
 	}
 	return storeIDToPeerRole, true
 }
+
+// @Tags     operator
+// @Summary  Check whether PD would accept a peer change without creating the operator.
+// @Accept   json
+// @Param    body  body  server.PeerChange  true  "The peer change to check"
+// @Produce  json
+// @Success  200  {object}  server.PeerChangePrecheck
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The region does not exist."
+// @Router   /operators/precheck [post]
+func (h *operatorHandler) PrecheckOperator(w http.ResponseWriter, r *http.Request) {
+	var change server.PeerChange
+	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &change); err != nil {
+		return
+	}
+	if getCluster(r).GetRegion(change.RegionID) == nil {
+		h.r.JSON(w, http.StatusNotFound, server.ErrRegionNotFound(change.RegionID).Error())
+		return
+	}
+	result, err := h.PrecheckPeerChange(&change)
+	if err != nil {
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, result)
+}
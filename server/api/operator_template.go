@@ -0,0 +1,159 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/storage/endpoint"
+)
+
+// creatableOperators are the names of the operators which can be created by
+// the operator API, and hence by the operator templates.
+var creatableOperators = map[string]struct{}{
+	"transfer-leader": {},
+	"transfer-region": {},
+	"transfer-peer":   {},
+	"add-peer":        {},
+	"add-learner":     {},
+	"demote-voter":    {},
+	"remove-peer":     {},
+	"merge-region":    {},
+	"split-region":    {},
+	"scatter-region":  {},
+	"scatter-regions": {},
+	"evict-peer":      {},
+}
+
+// OperatorTemplateApplyInput is the input to apply an operator template.
+type OperatorTemplateApplyInput struct {
+	Params   map[string]interface{} `json:"params"`
+	Metadata interface{}            `json:"metadata,omitempty"`
+}
+
+// @Tags     operator
+// @Summary  List the operator templates.
+// @Produce  json
+// @Success  200  {array}   endpoint.OperatorTemplate
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /operators/templates [get]
+func (h *operatorHandler) GetOperatorTemplates(w http.ResponseWriter, r *http.Request) {
+	templates, err := h.Handler.GetOperatorTemplates()
+	if err != nil {
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, templates)
+}
+
+// @Tags     operator
+// @Summary  Get an operator template.
+// @Param    name  path  string  true  "The name of the template"
+// @Produce  json
+// @Success  200  {object}  endpoint.OperatorTemplate
+// @Failure  404  {string}  string  "The template does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /operators/templates/{name} [get]
+func (h *operatorHandler) GetOperatorTemplate(w http.ResponseWriter, r *http.Request) {
+	template, err := h.Handler.GetOperatorTemplate(mux.Vars(r)["name"])
+	if err != nil {
+		h.responseOperatorTemplateErr(w, err)
+		return
+	}
+	h.r.JSON(w, http.StatusOK, template)
+}
+
+// @Tags     operator
+// @Summary  Create or replace an operator template.
+// @Accept   json
+// @Param    body  body  endpoint.OperatorTemplate  true  "The template"
+// @Produce  json
+// @Success  200  {string}  string  "The template is saved."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /operators/templates [post]
+func (h *operatorHandler) SaveOperatorTemplate(w http.ResponseWriter, r *http.Request) {
+	var template endpoint.OperatorTemplate
+	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &template); err != nil {
+		return
+	}
+	if _, ok := creatableOperators[template.Operator]; !ok {
+		h.r.JSON(w, http.StatusBadRequest, errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, "unknown operator "+template.Operator).Error())
+		return
+	}
+	if err := h.Handler.SaveOperatorTemplate(&template); err != nil {
+		h.responseOperatorTemplateErr(w, err)
+		return
+	}
+	h.r.JSON(w, http.StatusOK, "The template is saved.")
+}
+
+// @Tags     operator
+// @Summary  Delete an operator template.
+// @Param    name  path  string  true  "The name of the template"
+// @Produce  json
+// @Success  200  {string}  string  "The template is deleted."
+// @Failure  400  {string}  string  "The template is built-in."
+// @Failure  404  {string}  string  "The template does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /operators/templates/{name} [delete]
+func (h *operatorHandler) DeleteOperatorTemplate(w http.ResponseWriter, r *http.Request) {
+	if err := h.Handler.DeleteOperatorTemplate(mux.Vars(r)["name"]); err != nil {
+		h.responseOperatorTemplateErr(w, err)
+		return
+	}
+	h.r.JSON(w, http.StatusOK, "The template is deleted.")
+}
+
+// @Tags     operator
+// @Summary  Create an operator from a template with the given parameters.
+// @Accept   json
+// @Param    name  path  string                      true  "The name of the template"
+// @Param    body  body  OperatorTemplateApplyInput  true  "The parameters"
+// @Produce  json
+// @Success  200  {string}  string  "The operator is created."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The template does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /operators/templates/{name}/apply [post]
+func (h *operatorHandler) ApplyOperatorTemplate(w http.ResponseWriter, r *http.Request) {
+	var input OperatorTemplateApplyInput
+	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
+		return
+	}
+	operatorInput, err := h.Handler.ExpandOperatorTemplate(mux.Vars(r)["name"], input.Params)
+	if err != nil {
+		h.responseOperatorTemplateErr(w, err)
+		return
+	}
+	if input.Metadata != nil {
+		operatorInput["metadata"] = input.Metadata
+	}
+	h.createOperator(w, operatorInput)
+}
+
+func (h *operatorHandler) responseOperatorTemplateErr(w http.ResponseWriter, err error) {
+	switch {
+	case errs.ErrOperatorTemplateNotFound.Equal(err):
+		h.r.JSON(w, http.StatusNotFound, err.Error())
+	case errs.ErrOperatorTemplateInvalid.Equal(err), errs.ErrOperatorTemplateParam.Equal(err):
+		h.r.JSON(w, http.StatusBadRequest, err.Error())
+	default:
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net/http"
 	"strconv"
 	"strings"
 	"testing"
@@ -37,6 +38,7 @@ import (
 	"github.com/tikv/pd/server/core"
 	pdoperator "github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/schedule/plan"
 	"github.com/tikv/pd/server/versioninfo"
 )
 
@@ -137,6 +139,49 @@ func (suite *operatorTestSuite) TestAddRemovePeer() {
 	suite.Contains(records, "operator not found")
 }
 
+func (suite *operatorTestSuite) TestDemoteVoter() {
+	re := suite.Require()
+	for _, id := range []uint64{5, 6, 7} {
+		mustPutStore(re, suite.svr, id, metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+	}
+	peers := []*metapb.Peer{
+		{Id: 50, StoreId: 5},
+		{Id: 60, StoreId: 6},
+		{Id: 70, StoreId: 7, Role: metapb.PeerRole_Learner},
+	}
+	region := &metapb.Region{
+		Id:          40,
+		Peers:       peers,
+		StartKey:    []byte("x"),
+		EndKey:      []byte("y"),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	mustRegionHeartbeat(re, suite.svr, core.NewRegionInfo(region, peers[0]))
+	regionURL := fmt.Sprintf("%s/operators/%d", suite.urlPrefix, region.GetId())
+
+	// Fail to demote a learner or a peer which doesn't exist.
+	err := tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/operators", suite.urlPrefix), []byte(`{"name":"demote-voter", "region_id": 40, "store_id": 7}`),
+		tu.StatusNotOK(re), tu.StringContain(re, "no voter"))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/operators", suite.urlPrefix), []byte(`{"name":"demote-voter", "region_id": 40, "store_id": 8}`),
+		tu.StatusNotOK(re), tu.StringContain(re, "no voter"))
+	suite.NoError(err)
+
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/operators", suite.urlPrefix), []byte(`{"name":"demote-voter", "region_id": 40, "store_id": 6}`), tu.StatusOK(re))
+	suite.NoError(err)
+	operator := mustReadURL(re, regionURL)
+	suite.Contains(operator, "demote voter peer 60 on store 6 to learner")
+	suite.svr.GetHandler().RemoveOperator(40)
+
+	// Fail to demote the last voter.
+	peers[1].Role = metapb.PeerRole_Learner
+	region.RegionEpoch = &metapb.RegionEpoch{ConfVer: 2, Version: 1}
+	mustRegionHeartbeat(re, suite.svr, core.NewRegionInfo(region, peers[0]))
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/operators", suite.urlPrefix), []byte(`{"name":"demote-voter", "region_id": 40, "store_id": 5}`),
+		tu.StatusNotOK(re), tu.StringContain(re, "last voter"))
+	suite.NoError(err)
+}
+
 func (suite *operatorTestSuite) TestMergeRegionOperator() {
 	re := suite.Require()
 	r1 := newTestRegionInfo(10, 1, []byte(""), []byte("b"), core.SetWrittenBytes(1000), core.SetReadBytes(1000), core.SetRegionConfVer(1), core.SetRegionVersion(1))
@@ -163,6 +208,47 @@ func (suite *operatorTestSuite) TestMergeRegionOperator() {
 	suite.NoError(err)
 }
 
+func (suite *operatorTestSuite) TestOperatorMetadata() {
+	re := suite.Require()
+	mustPutStore(re, suite.svr, 11, metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+	mustPutStore(re, suite.svr, 12, metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+	peer := &metapb.Peer{Id: 101, StoreId: 11}
+	region := &metapb.Region{
+		Id:          100,
+		StartKey:    []byte("metadata"),
+		EndKey:      []byte("metadata-end"),
+		Peers:       []*metapb.Peer{peer},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}
+	mustRegionHeartbeat(re, suite.svr, core.NewRegionInfo(region, peer))
+
+	operatorsURL := fmt.Sprintf("%s/operators", suite.urlPrefix)
+	err := tu.CheckPostJSON(testDialClient, operatorsURL,
+		[]byte(`{"name":"add-peer", "region_id": 100, "store_id": 12, "metadata": {"component": "lightning", "ticket_id": "T-1"}}`), tu.StatusOK(re))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, operatorsURL,
+		[]byte(`{"name":"add-peer", "region_id": 100, "store_id": 12, "metadata": {"component": 1}}`), tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	suite.Contains(mustReadURL(re, fmt.Sprintf("%s/%d", operatorsURL, 100)), "metadata:{component=lightning, ticket-id=T-1}")
+
+	var ops []string
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, operatorsURL+"?component=lightning", &ops))
+	suite.Len(ops, 1)
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, operatorsURL+"?component=lightning&ticket_id=T-2", &ops))
+	suite.Empty(ops)
+
+	// A filter is required to cancel the operators.
+	code, err := apiutil.DoDelete(testDialClient, operatorsURL)
+	suite.NoError(err)
+	suite.Equal(http.StatusBadRequest, code)
+	code, err = apiutil.DoDelete(testDialClient, operatorsURL+"?ticket_id=T-1")
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, code)
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, operatorsURL+"?component=lightning", &ops))
+	suite.Empty(ops)
+	suite.Contains(mustReadURL(re, operatorsURL+"/records?component=lightning"), "ticket-id=T-1")
+}
+
 type transferRegionOperatorTestSuite struct {
 	suite.Suite
 	svr       *server.Server
@@ -170,6 +256,67 @@ type transferRegionOperatorTestSuite struct {
 	urlPrefix string
 }
 
+func (suite *operatorTestSuite) TestPrecheckOperator() {
+	re := suite.Require()
+	for _, id := range []uint64{11, 12, 13} {
+		mustPutStore(re, suite.svr, id, metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+	}
+	mustPutStore(re, suite.svr, 14, metapb.StoreState_Offline, metapb.NodeState_Removing, nil)
+	peer1 := &metapb.Peer{Id: 101, StoreId: 11}
+	peer2 := &metapb.Peer{Id: 102, StoreId: 12}
+	region := &metapb.Region{
+		Id:          100,
+		StartKey:    []byte("precheck"),
+		EndKey:      []byte("precheck1"),
+		Peers:       []*metapb.Peer{peer1, peer2},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 100},
+	}
+	mustRegionHeartbeat(re, suite.svr, core.NewRegionInfo(region, peer1))
+
+	url := fmt.Sprintf("%s/operators/precheck", suite.urlPrefix)
+	precheck := func(input string) *server.PeerChangePrecheck {
+		result := &server.PeerChangePrecheck{}
+		err := tu.CheckPostJSON(testDialClient, url, []byte(input), tu.StatusOK(re), tu.ExtractJSON(re, result))
+		suite.NoError(err)
+		return result
+	}
+	findCheck := func(result *server.PeerChangePrecheck, name string) *server.PrecheckResult {
+		for _, check := range result.Checks {
+			if check.Name == name {
+				return check
+			}
+		}
+		return nil
+	}
+
+	// Adding the missing replica passes all the checks.
+	result := precheck(`{"region_id": 100, "to_store_id": 13}`)
+	suite.True(result.Pass)
+	suite.NotNil(findCheck(result, server.PrecheckStoreLimit))
+	// Removing a voter makes the placement worse.
+	result = precheck(`{"region_id": 100, "from_store_id": 12}`)
+	suite.False(result.Pass)
+	suite.Equal(plan.StatusRuleNotMatch, findCheck(result, server.PrecheckRuleFit).StatusCode)
+	// The offline store can not be the target.
+	result = precheck(`{"region_id": 100, "from_store_id": 12, "to_store_id": 14}`)
+	suite.False(result.Pass)
+	check := findCheck(result, server.PrecheckStoreState)
+	suite.False(check.Pass)
+	suite.Equal(plan.StatusStoreDraining, check.StatusCode)
+	// The store which already has a peer is excluded.
+	result = precheck(`{"region_id": 100, "from_store_id": 12, "to_store_id": 11}`)
+	suite.Equal(plan.StatusStoreExcluded, findCheck(result, server.PrecheckStoreState).StatusCode)
+	// No operator is created.
+	suite.Nil(suite.svr.GetRaftCluster().GetOperatorController().GetOperator(100))
+
+	err := tu.CheckPostJSON(testDialClient, url, []byte(`{"region_id": 100}`), tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url, []byte(`{"region_id": 100, "to_store_id": 13, "role": "witness"}`), tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url, []byte(`{"region_id": 999, "to_store_id": 13}`), tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+}
+
 func TestTransferRegionOperatorTestSuite(t *testing.T) {
 	suite.Run(t, new(transferRegionOperatorTestSuite))
 }
@@ -0,0 +1,56 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/unrolled/render"
+)
+
+// maxPageLimit is the max number of the items in a page. A larger limit is
+// capped silently.
+const maxPageLimit = 10000
+
+// parsePage parses the pagination and the field selection of a list request.
+// It responds the error and returns false if the parameters are invalid.
+func parsePage(rd *render.Render, w http.ResponseWriter, r *http.Request) (*apiutil.Page, bool) {
+	page, err := apiutil.ParsePage(r.URL.Query(), maxPageLimit)
+	if err != nil {
+		rd.JSON(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	return page, true
+}
+
+// renderPage renders the list of a page with the selected fields. If key is
+// not empty, the list is wrapped with its count, e.g. {"count": 1, "stores": [...]}.
+func renderPage(rd *render.Render, w http.ResponseWriter, page *apiutil.Page, key string, list interface{}, count int) {
+	list, err := page.SelectFields(list)
+	if err != nil {
+		rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	page.SetHeader(w)
+	if key == "" {
+		rd.JSON(w, http.StatusOK, list)
+		return
+	}
+	rd.JSON(w, http.StatusOK, map[string]interface{}{
+		"count": count,
+		key:     list,
+	})
+}
@@ -17,6 +17,7 @@ package api
 import (
 	"container/heap"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"net/http"
 	"net/url"
@@ -30,12 +31,15 @@ import (
 	"github.com/pingcap/kvprotov2/pkg/replication_modepb"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule/filter"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/statistics/buckets"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
 )
@@ -197,6 +201,27 @@ func (s *RegionsInfo) Adjust() {
 	}
 }
 
+// RegionWatermark is the cluster-wide region change watermark, which is
+// increased once a region changes.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type RegionWatermark struct {
+	Watermark uint64 `json:"watermark"`
+}
+
+// RegionChangesInfo contains the regions changed since a watermark.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type RegionChangesInfo struct {
+	Since uint64 `json:"since"`
+	// Next is the watermark to get the following changes from, it is less
+	// than the watermark if there are more changes.
+	Next      uint64 `json:"next"`
+	Watermark uint64 `json:"watermark"`
+	// Truncated is true if some changes since the watermark are missing, the
+	// routes cached before the watermark should be reloaded by a full scan.
+	Truncated bool `json:"truncated"`
+	RegionsInfo
+}
+
 type regionHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -227,8 +252,30 @@ func (h *regionHandler) GetRegionByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	regionInfo := rc.GetRegion(regionID)
-	h.rd.JSON(w, http.StatusOK, NewAPIRegionInfo(regionInfo))
+	h.renderRegion(w, rc, fmt.Sprintf("api/region/id/%d", regionID), func() *core.RegionInfo {
+		return rc.GetRegion(regionID)
+	})
+}
+
+// @Tags     region
+// @Summary  Get the recent changes of the membership and the roles of the peers of a region.
+// @Param    id  path  integer  true  "Region Id"
+// @Produce  json
+// @Success  200  {array}   cluster.PeerChange
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /region/id/{id}/peer-history [get]
+func (h *regionHandler) GetRegionPeerHistory(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	history := rc.GetRegionPeerHistory(regionID)
+	if history == nil {
+		history = []*cluster.PeerChange{}
+	}
+	h.rd.JSON(w, http.StatusOK, history)
 }
 
 // @Tags     region
@@ -246,8 +293,41 @@ func (h *regionHandler) GetRegion(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	regionInfo := rc.GetRegionByKey([]byte(key))
-	h.rd.JSON(w, http.StatusOK, NewAPIRegionInfo(regionInfo))
+	h.renderRegion(w, rc, "api/region/key/"+key, func() *core.RegionInfo {
+		return rc.GetRegionByKey([]byte(key))
+	})
+}
+
+// renderRegion writes the region found by the query in JSON. If the region
+// query cache is enabled, the encoded region is served from the cache.
+func (h *regionHandler) renderRegion(w http.ResponseWriter, rc *cluster.RaftCluster, cacheKey string, query func() *core.RegionInfo) {
+	if h.svr.GetPersistOptions().GetRegionQueryCacheSize() == 0 {
+		h.rd.JSON(w, http.StatusOK, NewAPIRegionInfo(query()))
+		return
+	}
+	var regionInfo *core.RegionInfo
+	data, _ := rc.GetRegionQueryCache().GetOrLoad(cacheKey, func() (interface{}, uint64, uint64) {
+		regionInfo = query()
+		if regionInfo == nil {
+			return nil, 0, 0
+		}
+		data, err := json.MarshalIndent(NewAPIRegionInfo(regionInfo), "", "  ")
+		if err != nil {
+			return nil, 0, 0
+		}
+		// Keep the same format as the indented JSON rendered by h.rd.
+		data = append(data, '\n')
+		return data, regionInfo.GetID(), uint64(len(data))
+	}).([]byte)
+	if data == nil {
+		h.rd.JSON(w, http.StatusOK, NewAPIRegionInfo(regionInfo))
+		return
+	}
+	w.Header().Set("Content-Type", render.ContentJSON+"; charset=UTF-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(data); err != nil {
+		log.Error("failed to write the region", errs.ZapError(err))
+	}
 }
 
 // @Tags     region
@@ -320,14 +400,31 @@ func convertToAPIRegions(regions []*core.RegionInfo) *RegionsInfo {
 
 // @Tags     region
 // @Summary  List all regions in the cluster.
+// @Param    limit   query  integer  false  "The max number of regions in a page, at most 10000, 0 means no limit."
+// @Param    cursor  query  string   false  "The cursor returned by the previous page in the PD-Next-Cursor header."
+// @Param    fields  query  string   false  "The comma separated fields of regions to return, e.g. id,leader.store_id."
 // @Produce  json
 // @Success  200  {object}  RegionsInfo
+// @Failure  400  {string}  string  "The input is invalid."
 // @Router   /regions [get]
 func (h *regionsHandler) GetRegions(w http.ResponseWriter, r *http.Request) {
 	rc := getCluster(r)
+	page, ok := parsePage(h.rd, w, r)
+	if !ok {
+		return
+	}
 	regions := rc.GetRegions()
-	regionsInfo := convertToAPIRegions(regions)
-	h.rd.JSON(w, http.StatusOK, regionsInfo)
+	ids := make([]uint64, len(regions))
+	for i, region := range regions {
+		ids[i] = region.GetID()
+	}
+	start, end, err := page.ApplyIDs(ids, func(i, j int) { regions[i], regions[j] = regions[j], regions[i] })
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	regionsInfo := convertToAPIRegions(regions[start:end])
+	renderPage(h.rd, w, page, "regions", regionsInfo.Regions, regionsInfo.Count)
 }
 
 // @Tags     region
@@ -361,6 +458,72 @@ func (h *regionsHandler) ScanRegions(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
 
+// @Tags     region
+// @Summary  Get the spread score of the regions in a given range [startKey, endKey).
+// @Param    key             query  string  true   "Region range start key"
+// @Param    end_key         query  string  true   "Region range end key"
+// @Param    location_label  query  string  false  "The location label to calculate the spread over, such as zone"
+// @Produce  json
+// @Success  200  {object}  statistics.RegionSpread
+// @Router   /regions/spread [get]
+func (h *regionsHandler) GetRegionSpread(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	startKey := r.URL.Query().Get("key")
+	endKey := r.URL.Query().Get("end_key")
+	locationLabel := r.URL.Query().Get("location_label")
+	regions := rc.ScanRegions([]byte(startKey), []byte(endKey), -1)
+	h.rd.JSON(w, http.StatusOK, statistics.CalculateRegionSpread(regions, rc.GetStores(), locationLabel))
+}
+
+// RegionSample is a uniform random sample of the regions.
+type RegionSample struct {
+	// Total is the number of the regions the sample is taken from.
+	Total   int          `json:"total"`
+	Count   int          `json:"count"`
+	Regions []RegionInfo `json:"regions"`
+}
+
+// @Tags     region
+// @Summary  Get a uniform random sample of the regions overlapping [startKey, endKey) without replacement, with their full statistics.
+// @Param    count     query  integer  false  "The size of the sample"  default(16)
+// @Param    store_id  query  integer  false  "Only sample the regions with a peer on the store"
+// @Param    key       query  string   false  "Region range start key"
+// @Param    end_key   query  string   false  "Region range end key"
+// @Produce  json
+// @Success  200  {object}  RegionSample
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /regions/sample [get]
+func (h *regionsHandler) SampleRegions(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	query := r.URL.Query()
+	count := defaultRegionLimit
+	if countStr := query.Get("count"); countStr != "" {
+		var err error
+		if count, err = strconv.Atoi(countStr); err != nil || count <= 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "count should be a positive integer")
+			return
+		}
+	}
+	if count > maxRegionLimit {
+		count = maxRegionLimit
+	}
+	var storeID uint64
+	if storeIDStr := query.Get("store_id"); storeIDStr != "" {
+		var err error
+		if storeID, err = strconv.ParseUint(storeIDStr, 10, 64); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	regions, total := rc.SampleRegions(count, storeID, []byte(query.Get("key")), []byte(query.Get("end_key")))
+	regionsInfo := convertToAPIRegions(regions)
+	h.rd.JSON(w, http.StatusOK, &RegionSample{
+		Total:   total,
+		Count:   regionsInfo.Count,
+		Regions: regionsInfo.Regions,
+	})
+}
+
 // @Tags     region
 // @Summary  Get count of regions.
 // @Produce  json
@@ -372,6 +535,62 @@ func (h *regionsHandler) GetRegionCount(w http.ResponseWriter, r *http.Request)
 	h.rd.JSON(w, http.StatusOK, &RegionsInfo{Count: count})
 }
 
+// @Tags     region
+// @Summary  Get the cluster-wide region change watermark, the cached routes may be stale if it is changed.
+// @Produce  json
+// @Success  200  {object}  RegionWatermark
+// @Failure  404  {string}  string  "The region syncer is not enabled."
+// @Router   /regions/watermark [get]
+func (h *regionsHandler) GetRegionWatermark(w http.ResponseWriter, r *http.Request) {
+	regionSyncer := getCluster(r).GetRegionSyncer()
+	if regionSyncer == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The region syncer is not enabled.")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, &RegionWatermark{Watermark: regionSyncer.GetWatermark()})
+}
+
+// @Tags     region
+// @Summary  List the regions changed since a watermark.
+// @Param    since  query  integer  true   "The watermark to get the changes from."
+// @Param    limit  query  integer  false  "The max number of changes."  default(1024)
+// @Produce  json
+// @Success  200  {object}  RegionChangesInfo
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The region syncer is not enabled."
+// @Router   /regions/changes [get]
+func (h *regionsHandler) GetRegionChanges(w http.ResponseWriter, r *http.Request) {
+	regionSyncer := getCluster(r).GetRegionSyncer()
+	if regionSyncer == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The region syncer is not enabled.")
+		return
+	}
+	since, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, "invalid since watermark")
+		return
+	}
+	limit := defaultRegionChangeLimit
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		limit, err = strconv.Atoi(limitStr)
+		if err != nil || limit <= 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "invalid limit")
+			return
+		}
+	}
+	if limit > maxRegionLimit {
+		limit = maxRegionLimit
+	}
+	changes := regionSyncer.GetChangesSince(since, limit)
+	h.rd.JSON(w, http.StatusOK, &RegionChangesInfo{
+		Since:       changes.Since,
+		Next:        changes.Next,
+		Watermark:   changes.Watermark,
+		Truncated:   changes.Truncated,
+		RegionsInfo: *convertToAPIRegions(changes.Regions),
+	})
+}
+
 // @Tags     region
 // @Summary  List all regions of a specific store.
 // @Param    id  path  integer  true  "Store Id"
@@ -529,6 +748,113 @@ func (h *regionsHandler) GetUndersizedRegions(w http.ResponseWriter, r *http.Req
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
 
+// @Tags     region
+// @Summary  List all regions that have unhealthy or missing peers but can tolerate another voter failure.
+// @Produce  json
+// @Success  200  {object}  RegionsInfo
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /regions/check/degraded-region [get]
+func (h *regionsHandler) GetDegradedRegions(w http.ResponseWriter, r *http.Request) {
+	handler := h.svr.GetHandler()
+	regions, err := handler.GetRegionsByType(statistics.DegradedRegion)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	regionsInfo := convertToAPIRegions(regions)
+	h.rd.JSON(w, http.StatusOK, regionsInfo)
+}
+
+// @Tags     region
+// @Summary  List all regions that will lose the quorum if another voter fails.
+// @Produce  json
+// @Success  200  {object}  RegionsInfo
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /regions/check/at-risk-region [get]
+func (h *regionsHandler) GetAtRiskRegions(w http.ResponseWriter, r *http.Request) {
+	handler := h.svr.GetHandler()
+	regions, err := handler.GetRegionsByType(statistics.AtRiskRegion)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	regionsInfo := convertToAPIRegions(regions)
+	h.rd.JSON(w, http.StatusOK, regionsInfo)
+}
+
+// @Tags     region
+// @Summary  List all regions that have lost the quorum of voters.
+// @Produce  json
+// @Success  200  {object}  RegionsInfo
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /regions/check/unavailable-region [get]
+func (h *regionsHandler) GetUnavailableRegions(w http.ResponseWriter, r *http.Request) {
+	handler := h.svr.GetHandler()
+	regions, err := handler.GetRegionsByType(statistics.UnavailableRegion)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	regionsInfo := convertToAPIRegions(regions)
+	h.rd.JSON(w, http.StatusOK, regionsInfo)
+}
+
+// @Tags     region
+// @Summary  Get the number of the regions of each availability risk, which are healthy, degraded, at-risk and unavailable.
+// @Produce  json
+// @Success  200  {object}  map[string]int
+// @Router   /regions/availability [get]
+func (h *regionsHandler) GetRegionAvailability(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionAvailabilityCounts())
+}
+
+// @Tags     region
+// @Summary  List the region heartbeats quarantined for inspection due to anomalies.
+// @Produce  json
+// @Success  200  {array}  cluster.QuarantinedRegion
+// @Router   /regions/check/quarantined [get]
+func (h *regionsHandler) GetQuarantinedRegions(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	h.rd.JSON(w, http.StatusOK, rc.GetQuarantinedRegions())
+}
+
+// @Tags     region
+// @Summary  List the summaries of the recent scans of the regions violating the placement, the latest first.
+// @Param    limit  query  integer  false  "The max number of the scans, 0 means no limit."
+// @Produce  json
+// @Success  200  {array}   cluster.PlacementScanReport
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /regions/check/placement-scans [get]
+func (h *regionsHandler) GetPlacementScanReports(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	limit := 0
+	if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+		var err error
+		if limit, err = strconv.Atoi(limitStr); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetPlacementScanReports(limit))
+}
+
+// @Tags     region
+// @Summary  Get the latest scan of the regions violating the placement, with the violating regions.
+// @Produce  json
+// @Success  200  {object}  cluster.PlacementScanReport
+// @Failure  404  {string}  string  "No scan has finished."
+// @Router   /regions/check/placement-scans/latest [get]
+func (h *regionsHandler) GetLatestPlacementScanReport(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	report := rc.GetLatestPlacementScanReport()
+	if report == nil {
+		h.rd.JSON(w, http.StatusNotFound, "no placement scan has finished")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, report)
+}
+
 // @Tags     region
 // @Summary  List all empty regions.
 // @Produce  json
@@ -687,11 +1013,97 @@ func (h *regionsHandler) GetRegionSiblings(w http.ResponseWriter, r *http.Reques
 	h.rd.JSON(w, http.StatusOK, regionsInfo)
 }
 
+// @Tags     region
+// @Summary  Recommend the split keys and merge candidates of the regions according to their buckets. Nothing will be executed.
+// @Param    region_id    query  integer  false  "Region Id, the key range is ignored if it is given"
+// @Param    key          query  string   false  "Region range start key"
+// @Param    end_key      query  string   false  "Region range end key"
+// @Param    hot_degree   query  integer  false  "The minimum hot degree of the buckets to split"  default(3)
+// @Param    cold_degree  query  integer  false  "The maximum hot degree of the buckets to merge"  default(-3)
+// @Produce  json
+// @Success  200  {array}   buckets.RegionBucketsRecommendation
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The region does not exist."
+// @Router   /regions/buckets/recommendations [get]
+func (h *regionsHandler) GetBucketRecommendations(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	query := r.URL.Query()
+
+	hotDegree, coldDegree := buckets.DefaultRecommendHotDegree, buckets.DefaultRecommendColdDegree
+	var err error
+	if degreeStr := query.Get("hot_degree"); degreeStr != "" {
+		if hotDegree, err = strconv.Atoi(degreeStr); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if degreeStr := query.Get("cold_degree"); degreeStr != "" {
+		if coldDegree, err = strconv.Atoi(degreeStr); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	if coldDegree >= hotDegree {
+		h.rd.JSON(w, http.StatusBadRequest, "cold_degree should be less than hot_degree")
+		return
+	}
+
+	regions, ok := h.getBucketRegions(w, rc, query)
+	if !ok {
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, rc.RecommendBuckets(regions, hotDegree, coldDegree))
+}
+
+// @Tags     region
+// @Summary  List the bucket stats of the regions and the regions they are inherited from.
+// @Param    region_id  query  integer  false  "Region Id, the key range is ignored if it is given"
+// @Param    key        query  string   false  "Region range start key"
+// @Param    end_key    query  string   false  "Region range end key"
+// @Produce  json
+// @Success  200  {array}   buckets.RegionBuckets
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The region does not exist."
+// @Router   /regions/buckets [get]
+func (h *regionsHandler) GetRegionBuckets(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	regions, ok := h.getBucketRegions(w, rc, r.URL.Query())
+	if !ok {
+		return
+	}
+	regionIDs := make([]uint64, 0, len(regions))
+	for _, region := range regions {
+		regionIDs = append(regionIDs, region.GetID())
+	}
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionBuckets(regionIDs...))
+}
+
+// getBucketRegions returns the region given by the region_id or the regions in
+// the key range. It responds the error and returns false if the input is invalid.
+func (h *regionsHandler) getBucketRegions(w http.ResponseWriter, rc *cluster.RaftCluster, query url.Values) ([]*core.RegionInfo, bool) {
+	idStr := query.Get("region_id")
+	if idStr == "" {
+		return rc.ScanRegions([]byte(query.Get("key")), []byte(query.Get("end_key")), -1), true
+	}
+	id, err := strconv.ParseUint(idStr, 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return nil, false
+	}
+	region := rc.GetRegion(id)
+	if region == nil {
+		h.rd.JSON(w, http.StatusNotFound, server.ErrRegionNotFound(id).Error())
+		return nil, false
+	}
+	return []*core.RegionInfo{region}, true
+}
+
 const (
-	defaultRegionLimit     = 16
-	maxRegionLimit         = 10240
-	minRegionHistogramSize = 1
-	minRegionHistogramKeys = 1000
+	defaultRegionLimit       = 16
+	maxRegionLimit           = 10240
+	defaultRegionChangeLimit = 1024
+	minRegionHistogramSize   = 1
+	minRegionHistogramKeys   = 1000
 )
 
 // @Tags     region
@@ -222,3 +222,95 @@ func (h *regionLabelHandler) GetRegionLabels(w http.ResponseWriter, r *http.Requ
 	labels := cluster.GetRegionLabeler().GetRegionLabels(region)
 	h.rd.JSON(w, http.StatusOK, labels)
 }
+
+// @Tags     region_label
+// @Summary  Get the annotations of a region, including the ones inherited from the key ranges containing it.
+// @Param    id  path  integer  true  "Region Id"
+// @Produce  json
+// @Success  200  {object}  map[string]string
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The region does not exist."
+// @Router   /region/id/{id}/annotations [get]
+func (h *regionLabelHandler) GetRegionAnnotations(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r)
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	region := cluster.GetRegion(regionID)
+	if region == nil {
+		h.rd.JSON(w, http.StatusNotFound, nil)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.GetRegionAnnotations(region))
+}
+
+// @Tags     region_label
+// @Summary  Replace the annotations of a region, which are read by the filters and the schedulers, e.g. "pinned-leader-store".
+// @Param    id    path  integer            true  "Region Id"
+// @Param    body  body  map[string]string  true  "The annotations of the region"
+// @Produce  json
+// @Success  200  {string}  string  "Set the region annotations successfully."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The region does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /region/id/{id}/annotations [post]
+func (h *regionLabelHandler) SetRegionAnnotations(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r)
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	region := cluster.GetRegion(regionID)
+	if region == nil {
+		h.rd.JSON(w, http.StatusNotFound, nil)
+		return
+	}
+	var annotations map[string]string
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &annotations); err != nil {
+		return
+	}
+	if err := cluster.SetRegionAnnotations(region, annotations); err != nil {
+		if errs.ErrRegionRuleContent.Equal(err) {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		} else {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "Set the region annotations successfully.")
+}
+
+// @Tags     region_label
+// @Summary  Delete all the annotations set to the key range of a region.
+// @Param    id  path  integer  true  "Region Id"
+// @Produce  json
+// @Success  200  {string}  string  "Delete the region annotations successfully."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The region does not exist or has no annotations."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /region/id/{id}/annotations [delete]
+func (h *regionLabelHandler) DeleteRegionAnnotations(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r)
+	regionID, err := strconv.ParseUint(mux.Vars(r)["id"], 10, 64)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	region := cluster.GetRegion(regionID)
+	if region == nil {
+		h.rd.JSON(w, http.StatusNotFound, nil)
+		return
+	}
+	if err := cluster.DeleteRegionAnnotations(region); err != nil {
+		if errs.ErrRegionRuleNotFound.Equal(err) {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+		} else {
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "Delete the region annotations successfully.")
+}
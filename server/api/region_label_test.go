@@ -17,14 +17,17 @@ package api
 import (
 	"encoding/json"
 	"fmt"
+	"net/http"
 	"net/url"
 	"sort"
 	"testing"
 
+	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/stretchr/testify/suite"
 	"github.com/tikv/pd/pkg/apiutil"
 	tu "github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule/labeler"
 )
 
@@ -113,3 +116,52 @@ func makeKeyRanges(keys ...string) []interface{} {
 	}
 	return res
 }
+
+func (suite *regionLabelTestSuite) TestAnnotations() {
+	re := suite.Require()
+	mustPutStore(re, suite.svr, 1, metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+	mustRegionHeartbeat(re, suite.svr, newTestRegionInfo(2, 1, []byte("a"), []byte("b")))
+	annotationURL := fmt.Sprintf("%s%s/api/v1/region/id/2/annotations", suite.svr.GetAddr(), apiPrefix)
+
+	err := tu.CheckPostJSON(testDialClient, annotationURL, []byte(`{"pinned-leader-store":"100"}`), tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, annotationURL, []byte(`{"pinned-leader-store":"1","owner":"dba"}`), tu.StatusOK(re))
+	suite.NoError(err)
+	var annotations map[string]string
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, annotationURL, &annotations))
+	suite.Equal(map[string]string{"pinned-leader-store": "1", "owner": "dba"}, annotations)
+	region := suite.svr.GetRaftCluster().GetRegion(2)
+	suite.Equal(uint64(1), suite.svr.GetRaftCluster().GetRegionLabeler().PinnedLeaderStore(region))
+
+	_, err = apiutil.DoDelete(testDialClient, annotationURL)
+	suite.NoError(err)
+	annotations = nil
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, annotationURL, &annotations))
+	suite.Empty(annotations)
+	suite.Zero(suite.svr.GetRaftCluster().GetRegionLabeler().PinnedLeaderStore(region))
+	childURL := fmt.Sprintf("%s%s/api/v1/region/id/3/annotations", suite.svr.GetAddr(), apiPrefix)
+	err = tu.CheckPostJSON(testDialClient, childURL, []byte(`{"owner":"dba"}`), tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+
+	// The annotations belong to the key range, so the regions split from it inherit them.
+	err = tu.CheckPostJSON(testDialClient, annotationURL, []byte(`{"owner":"dba"}`), tu.StatusOK(re))
+	suite.NoError(err)
+	mustRegionHeartbeat(re, suite.svr, newTestRegionInfo(3, 1, []byte("a"), []byte("aa"), core.WithIncVersion()))
+	mustRegionHeartbeat(re, suite.svr, newTestRegionInfo(2, 1, []byte("aa"), []byte("b"), core.WithIncVersion()))
+	for _, u := range []string{annotationURL, childURL} {
+		annotations = nil
+		suite.NoError(tu.ReadGetJSON(re, testDialClient, u, &annotations))
+		suite.Equal(map[string]string{"owner": "dba"}, annotations)
+	}
+	// The inherited annotations can only be deleted on the annotated key range.
+	code, err := apiutil.DoDelete(testDialClient, childURL)
+	suite.NoError(err)
+	suite.Equal(http.StatusNotFound, code)
+
+	// The annotations are removed once the region is merged out of the key range.
+	mustRegionHeartbeat(re, suite.svr, newTestRegionInfo(4, 1, []byte("b"), []byte("c")))
+	mustRegionHeartbeat(re, suite.svr, newTestRegionInfo(2, 1, []byte("aa"), []byte("c"), core.WithIncVersion(), core.WithIncVersion()))
+	annotations = nil
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, childURL, &annotations))
+	suite.Empty(annotations)
+}
@@ -0,0 +1,123 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type regionSyncerHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newRegionSyncerHandler(svr *server.Server, rd *render.Render) *regionSyncerHandler {
+	return &regionSyncerHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags     region_syncer
+// @Summary  Get the status of the history buffer of the region syncer, including the utilization and the fallback counts.
+// @Produce  json
+// @Success  200  {object}  syncer.HistoryStatus
+// @Failure  404  {string}  string  "The region syncer is not enabled."
+// @Router   /region-syncer/history [get]
+func (h *regionSyncerHandler) GetHistoryStatus(w http.ResponseWriter, r *http.Request) {
+	regionSyncer := getCluster(r).GetRegionSyncer()
+	if regionSyncer == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The region syncer is not enabled.")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, regionSyncer.GetHistoryStatus())
+}
+
+// @Tags     region_syncer
+// @Summary  Get the status of the region syncer, including the history buffer and the positions of the followers.
+// @Produce  json
+// @Success  200  {object}  syncer.SyncerStatus
+// @Failure  404  {string}  string  "The region syncer is not enabled."
+// @Router   /region-syncer/status [get]
+func (h *regionSyncerHandler) GetStatus(w http.ResponseWriter, r *http.Request) {
+	regionSyncer := getCluster(r).GetRegionSyncer()
+	if regionSyncer == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The region syncer is not enabled.")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, regionSyncer.GetStatus())
+}
+
+// @Tags     region_syncer
+// @Summary  Pause sending the regions to the followers, the missed regions are sent after being resumed.
+// @Produce  json
+// @Success  200  {string}  string  "The region syncer is paused."
+// @Failure  404  {string}  string  "The region syncer is not enabled."
+// @Router   /region-syncer/pause [post]
+func (h *regionSyncerHandler) Pause(w http.ResponseWriter, r *http.Request) {
+	regionSyncer := getCluster(r).GetRegionSyncer()
+	if regionSyncer == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The region syncer is not enabled.")
+		return
+	}
+	regionSyncer.Pause()
+	h.rd.JSON(w, http.StatusOK, "The region syncer is paused.")
+}
+
+// @Tags     region_syncer
+// @Summary  Resume sending the regions to the followers.
+// @Produce  json
+// @Success  200  {string}  string  "The region syncer is resumed."
+// @Failure  404  {string}  string  "The region syncer is not enabled."
+// @Router   /region-syncer/resume [post]
+func (h *regionSyncerHandler) Resume(w http.ResponseWriter, r *http.Request) {
+	regionSyncer := getCluster(r).GetRegionSyncer()
+	if regionSyncer == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The region syncer is not enabled.")
+		return
+	}
+	regionSyncer.Resume()
+	h.rd.JSON(w, http.StatusOK, "The region syncer is resumed.")
+}
+
+// @Tags     region_syncer
+// @Summary  Force a follower to reload all regions from the leader.
+// @Param    name  path  string  true  "The name of the follower"
+// @Produce  json
+// @Success  200  {string}  string  "The follower is forced to do full synchronization."
+// @Failure  404  {string}  string  "The region syncer is not enabled or the follower is not synchronizing."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /region-syncer/followers/{name}/full-sync [post]
+func (h *regionSyncerHandler) ForceFullSync(w http.ResponseWriter, r *http.Request) {
+	regionSyncer := getCluster(r).GetRegionSyncer()
+	if regionSyncer == nil {
+		h.rd.JSON(w, http.StatusNotFound, "The region syncer is not enabled.")
+		return
+	}
+	if err := regionSyncer.ForceFullSync(mux.Vars(r)["name"]); err != nil {
+		if errs.ErrRegionSyncerFollowerNotFound.Equal(err) {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The follower is forced to do full synchronization.")
+}
@@ -35,6 +35,7 @@ import (
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/statistics"
 )
 
 func TestPeer(t *testing.T) {
@@ -197,6 +198,18 @@ func (suite *regionTestSuite) TestRegionCheck() {
 	r4.Adjust()
 	suite.Equal(&RegionsInfo{Count: 0, Regions: []RegionInfo{}}, r4)
 
+	// The region has lost the quorum since one of the two voters is down.
+	url = fmt.Sprintf("%s/regions/check/%s", suite.urlPrefix, "unavailable-region")
+	unavailable := &RegionsInfo{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, url, unavailable))
+	unavailable.Adjust()
+	suite.Contains(unavailable.Regions, *NewAPIRegionInfo(r))
+	url = fmt.Sprintf("%s/regions/availability", suite.urlPrefix)
+	counts := make(map[string]int)
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, url, &counts))
+	suite.GreaterOrEqual(counts["unavailable"], 1)
+	suite.Contains(counts, "healthy")
+
 	r = r.Clone(core.SetApproximateSize(1))
 	mustRegionHeartbeat(re, suite.svr, r)
 	url = fmt.Sprintf("%s/regions/check/%s", suite.urlPrefix, "empty-region")
@@ -336,6 +349,22 @@ func (suite *regionTestSuite) TestAccelerateRegionsScheduleInRange() {
 	suite.Len(idList, 2)
 }
 
+func (suite *regionTestSuite) TestRegionSpread() {
+	re := suite.Require()
+	r1 := newTestRegionInfo(601, 16, []byte("s1"), []byte("s2"))
+	r2 := newTestRegionInfo(602, 16, []byte("s2"), []byte("s3"))
+	mustRegionHeartbeat(re, suite.svr, r1)
+	mustRegionHeartbeat(re, suite.svr, r2)
+
+	spread := &statistics.RegionSpread{}
+	url := fmt.Sprintf("%s/regions/spread?key=%s&end_key=%s", suite.urlPrefix, "s1", "s3")
+	err := tu.ReadGetJSON(re, testDialClient, url, spread)
+	suite.NoError(err)
+	suite.Equal(2, spread.RegionCount)
+	suite.Equal(map[uint64]int{16: 2}, spread.StorePeerCount)
+	suite.Equal(map[uint64]int{16: 2}, spread.StoreLeaderCount)
+}
+
 func (suite *regionTestSuite) TestScatterRegions() {
 	re := suite.Require()
 	r1 := newTestRegionInfo(601, 13, []byte("b1"), []byte("b2"))
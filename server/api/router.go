@@ -128,19 +128,31 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	operatorHandler := newOperatorHandler(handler, rd)
 	registerFunc(apiRouter, "/operators", operatorHandler.GetOperators, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/operators", operatorHandler.CreateOperator, setMethods(http.MethodPost), setAuditBackend(prometheus))
+	registerFunc(apiRouter, "/operators", operatorHandler.DeleteOperators, setMethods(http.MethodDelete), setAuditBackend(prometheus))
 	registerFunc(apiRouter, "/operators/records", operatorHandler.GetOperatorRecords, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/operators/precheck", operatorHandler.PrecheckOperator, setMethods(http.MethodPost))
+	registerFunc(apiRouter, "/operators/templates", operatorHandler.GetOperatorTemplates, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/operators/templates", operatorHandler.SaveOperatorTemplate, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/operators/templates/{name}", operatorHandler.GetOperatorTemplate, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/operators/templates/{name}", operatorHandler.DeleteOperatorTemplate, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/operators/templates/{name}/apply", operatorHandler.ApplyOperatorTemplate, setMethods(http.MethodPost), setAuditBackend(localLog, prometheus))
 	registerFunc(apiRouter, "/operators/{region_id}", operatorHandler.GetOperatorsByRegion, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/operators/{region_id}", operatorHandler.DeleteOperatorByRegion, setMethods(http.MethodDelete))
 
 	checkerHandler := newCheckerHandler(svr, rd)
 	registerFunc(apiRouter, "/checker/{name}", checkerHandler.PauseOrResumeChecker, setMethods(http.MethodPost))
 	registerFunc(apiRouter, "/checker/{name}", checkerHandler.GetCheckerStatus, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/checker/{name}/dry-run", checkerHandler.DryRunChecker, setMethods(http.MethodGet))
 
 	schedulerHandler := newSchedulerHandler(svr, rd)
 	registerFunc(apiRouter, "/schedulers", schedulerHandler.GetSchedulers, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/schedulers", schedulerHandler.CreateScheduler, setMethods(http.MethodPost))
+	registerFunc(apiRouter, "/schedulers/simulation", schedulerHandler.SimulateScheduling, setMethods(http.MethodPost))
+	registerFunc(apiRouter, "/schedulers/state/{name}", schedulerHandler.GetSchedulerState, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/schedulers/{name}", schedulerHandler.DeleteScheduler, setMethods(http.MethodDelete))
 	registerFunc(apiRouter, "/schedulers/{name}", schedulerHandler.PauseOrResumeScheduler, setMethods(http.MethodPost))
+	registerFunc(apiRouter, "/schedulers/{name}/observe", schedulerHandler.SetSchedulerObserveOnly, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/schedulers/{name}/observe", schedulerHandler.GetSchedulerObservation, setMethods(http.MethodGet))
 
 	schedulerConfigHandler := newSchedulerConfigHandler(svr, rd)
 	registerPrefix(apiRouter, "/scheduler-config", schedulerConfigHandler.GetSchedulerConfig)
@@ -148,20 +160,32 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	clusterHandler := newClusterHandler(svr, rd)
 	registerFunc(apiRouter, "/cluster", clusterHandler.GetCluster, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/cluster/status", clusterHandler.GetClusterStatus)
+	registerFunc(apiRouter, "/cluster/startup", clusterHandler.GetClusterStartupStatus, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/cluster/startup/region-loading", clusterHandler.GetRegionLoadingProgress, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/cluster/bootstrap-bundle", clusterHandler.GetBootstrapBundle, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/cluster/bootstrap-bundle", clusterHandler.StageBootstrapBundle, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/cluster/bootstrap-bundle", clusterHandler.DeleteBootstrapBundle, setMethods(http.MethodDelete), setAuditBackend(localLog))
 
 	confHandler := newConfHandler(svr, rd)
 	registerFunc(apiRouter, "/config", confHandler.GetConfig, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config", confHandler.SetConfig, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(apiRouter, "/config/default", confHandler.GetDefaultConfig, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/config/watch", confHandler.WatchConfig, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/schedule", confHandler.GetScheduleConfig, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/schedule", confHandler.SetScheduleConfig, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/config/schedule", confHandler.PatchScheduleConfig, setMethods(http.MethodPatch), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/config/region-score-curve", confHandler.GetRegionScoreCurve, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/pd-server", confHandler.GetPDServerConfig, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/config/pd-server", confHandler.PatchPDServerConfig, setMethods(http.MethodPatch), setAuditBackend(localLog))
 	registerFunc(apiRouter, "/config/replicate", confHandler.GetReplicationConfig, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/replicate", confHandler.SetReplicationConfig, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/config/replicate", confHandler.PatchReplicationConfig, setMethods(http.MethodPatch), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/config/replicate/migration", confHandler.MigrateReplicationMode, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(apiRouter, "/config/label-property", confHandler.GetLabelPropertyConfig, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/label-property", confHandler.SetLabelPropertyConfig, setMethods(http.MethodPost))
 	registerFunc(apiRouter, "/config/cluster-version", confHandler.GetClusterVersion, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/cluster-version", confHandler.SetClusterVersion, setMethods(http.MethodPost))
+	registerFunc(clusterRouter, "/config/cluster-version/gate", confHandler.GetClusterVersionGate, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/replication-mode", confHandler.GetReplicationModeConfig, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/config/replication-mode", confHandler.SetReplicationModeConfig, setMethods(http.MethodPost))
 
@@ -170,8 +194,10 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	registerFunc(clusterRouter, "/config/rules", rulesHandler.SetAllRules, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/config/rules/batch", rulesHandler.BatchRules, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/config/rules/group/{group}", rulesHandler.GetRuleByGroup, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/config/rules/group/{group}/reconcile", rulesHandler.ReconcileRuleGroup, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/config/rules/region/{region}", rulesHandler.GetRulesByRegion, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/config/rules/key/{key}", rulesHandler.GetRulesByKey, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/config/rules/shadowed", rulesHandler.GetShadowedRules, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/config/rule/{group}/{id}", rulesHandler.GetRuleByGroupAndID, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/config/rule", rulesHandler.SetRule, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/config/rule/{group}/{id}", rulesHandler.DeleteRuleByGroup, setMethods(http.MethodDelete), setAuditBackend(localLog))
@@ -199,14 +225,24 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	registerFunc(clusterRouter, "/config/region-label/rules", regionLabelHandler.PatchRegionLabelRules, setMethods(http.MethodPatch), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/region/id/{id}/label/{key}", regionLabelHandler.GetRegionLabelByKey, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/region/id/{id}/labels", regionLabelHandler.GetRegionLabels, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/region/id/{id}/annotations", regionLabelHandler.GetRegionAnnotations, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/region/id/{id}/annotations", regionLabelHandler.SetRegionAnnotations, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/region/id/{id}/annotations", regionLabelHandler.DeleteRegionAnnotations, setMethods(http.MethodDelete), setAuditBackend(localLog))
 
 	storeHandler := newStoreHandler(handler, rd)
 	registerFunc(clusterRouter, "/store/{id}", storeHandler.GetStore, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/store/{id}", storeHandler.DeleteStore, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/store/{id}/decommission-estimate", storeHandler.GetDecommissionEstimate, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/store/{id}/state", storeHandler.SetStoreState, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/store/{id}/label", storeHandler.SetStoreLabel, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/store/{id}/weight", storeHandler.SetStoreWeight, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/store/{id}/quota", storeHandler.SetStoreQuota, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/store/{id}/topology", storeHandler.SetStoreTopology, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/store/{id}/limit", storeHandler.SetStoreLimit, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/store/{id}/notes", storeHandler.GetStoreNotes, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/store/{id}/notes", storeHandler.AddStoreNote, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/store/{id}/notes/{note_id}", storeHandler.UpdateStoreNote, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/store/{id}/notes/{note_id}", storeHandler.DeleteStoreNote, setMethods(http.MethodDelete), setAuditBackend(localLog))
 
 	storesHandler := newStoresHandler(handler, rd)
 	registerFunc(clusterRouter, "/stores", storesHandler.GetStores, setMethods(http.MethodGet))
@@ -215,20 +251,42 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	registerFunc(clusterRouter, "/stores/limit", storesHandler.SetAllStoresLimit, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/stores/limit/scene", storesHandler.SetStoreLimitScene, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/stores/limit/scene", storesHandler.GetStoreLimitScene, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/stores/labels", storesHandler.SetStoresLabels, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/stores/state", storesHandler.SetStoresState, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/stores/progress", storesHandler.GetStoresProgress, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/stores/preparing", storesHandler.GetStoresPreparingDetails, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/stores/topology-weight", storesHandler.GetStoresTopologyWeight, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/stores/versions", storesHandler.GetStoresVersionDistribution, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/stores/offline-simulation", storesHandler.SimulateStoreOffline, setMethods(http.MethodPost))
+
+	taskHandler := newTaskHandler(svr, rd)
+	registerFunc(clusterRouter, "/tasks", taskHandler.GetTasks, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/tasks", taskHandler.SubmitTask, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/tasks/{id}", taskHandler.GetTask, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/tasks/{id}/cancel", taskHandler.CancelTask, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/tasks/{id}/retry", taskHandler.RetryTask, setMethods(http.MethodPost), setAuditBackend(localLog))
+
+	zoneLatencyHandler := newZoneLatencyHandler(svr, rd)
+	registerFunc(clusterRouter, "/zones/latency", zoneLatencyHandler.GetZoneLatencies, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/zones/latency", zoneLatencyHandler.ReportZoneLatencies, setMethods(http.MethodPost))
 
 	labelsHandler := newLabelsHandler(svr, rd)
 	registerFunc(clusterRouter, "/labels", labelsHandler.GetLabels, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/labels/stores", labelsHandler.GetStoresByLabel, setMethods(http.MethodGet))
 
+	keyVisualHandler := newKeyVisualHandler(svr, rd)
+	registerFunc(clusterRouter, "/keyvisual/heatmap", keyVisualHandler.GetHeatmap, setMethods(http.MethodGet), setAuditBackend(prometheus))
+
 	hotStatusHandler := newHotStatusHandler(handler, rd)
 	registerFunc(apiRouter, "/hotspot/regions/write", hotStatusHandler.GetHotWriteRegions, setMethods(http.MethodGet), setAuditBackend(prometheus))
 	registerFunc(apiRouter, "/hotspot/regions/read", hotStatusHandler.GetHotReadRegions, setMethods(http.MethodGet), setAuditBackend(prometheus))
 	registerFunc(apiRouter, "/hotspot/regions/history", hotStatusHandler.GetHistoryHotRegions, setMethods(http.MethodGet), setAuditBackend(prometheus))
 	registerFunc(apiRouter, "/hotspot/stores", hotStatusHandler.GetHotStores, setMethods(http.MethodGet), setAuditBackend(prometheus))
+	registerFunc(apiRouter, "/hotspot/exemplars", hotStatusHandler.GetHotPeerExemplars, setMethods(http.MethodGet), setAuditBackend(prometheus))
 
 	regionHandler := newRegionHandler(svr, rd)
 	registerFunc(clusterRouter, "/region/id/{id}", regionHandler.GetRegionByID, setMethods(http.MethodGet), setAuditBackend(prometheus))
+	registerFunc(clusterRouter, "/region/id/{id}/peer-history", regionHandler.GetRegionPeerHistory, setMethods(http.MethodGet))
 	registerFunc(clusterRouter.UseEncodedPath(), "/region/key/{key}", regionHandler.GetRegion, setMethods(http.MethodGet), setAuditBackend(prometheus))
 
 	srd := createStreamingRender()
@@ -238,6 +296,10 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	regionsHandler := newRegionsHandler(svr, rd)
 	registerFunc(clusterRouter, "/regions/key", regionsHandler.ScanRegions, setMethods(http.MethodGet), setAuditBackend(prometheus))
 	registerFunc(clusterRouter, "/regions/count", regionsHandler.GetRegionCount, setMethods(http.MethodGet), setAuditBackend(prometheus))
+	registerFunc(clusterRouter, "/regions/watermark", regionsHandler.GetRegionWatermark, setMethods(http.MethodGet), setAuditBackend(prometheus))
+	registerFunc(clusterRouter, "/regions/changes", regionsHandler.GetRegionChanges, setMethods(http.MethodGet), setAuditBackend(prometheus))
+	registerFunc(clusterRouter, "/regions/spread", regionsHandler.GetRegionSpread, setMethods(http.MethodGet), setAuditBackend(prometheus))
+	registerFunc(clusterRouter, "/regions/sample", regionsHandler.SampleRegions, setMethods(http.MethodGet), setAuditBackend(prometheus))
 	registerFunc(clusterRouter, "/regions/store/{id}", regionsHandler.GetStoreRegions, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/regions/writeflow", regionsHandler.GetTopWriteFlowRegions, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/regions/readflow", regionsHandler.GetTopReadFlowRegions, setMethods(http.MethodGet))
@@ -254,6 +316,15 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	registerFunc(clusterRouter, "/regions/check/offline-peer", regionsHandler.GetOfflinePeerRegions, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/regions/check/oversized-region", regionsHandler.GetOverSizedRegions, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/regions/check/undersized-region", regionsHandler.GetUndersizedRegions, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/check/degraded-region", regionsHandler.GetDegradedRegions, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/check/at-risk-region", regionsHandler.GetAtRiskRegions, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/check/unavailable-region", regionsHandler.GetUnavailableRegions, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/availability", regionsHandler.GetRegionAvailability, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/check/quarantined", regionsHandler.GetQuarantinedRegions, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/check/placement-scans", regionsHandler.GetPlacementScanReports, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/check/placement-scans/latest", regionsHandler.GetLatestPlacementScanReport, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/cluster/events", clusterHandler.GetClusterEvents, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/cluster/region-cache", clusterHandler.GetRegionCacheStaleness, setMethods(http.MethodGet))
 
 	registerFunc(clusterRouter, "/regions/check/hist-size", regionsHandler.GetSizeHistogram, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/regions/check/hist-keys", regionsHandler.GetKeysHistogram, setMethods(http.MethodGet))
@@ -261,6 +332,8 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	registerFunc(clusterRouter, "/regions/accelerate-schedule", regionsHandler.AccelerateRegionsScheduleInRange, setMethods(http.MethodPost), setAuditBackend(localLog, prometheus))
 	registerFunc(clusterRouter, "/regions/scatter", regionsHandler.ScatterRegions, setMethods(http.MethodPost), setAuditBackend(localLog, prometheus))
 	registerFunc(clusterRouter, "/regions/split", regionsHandler.SplitRegions, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/regions/buckets", regionsHandler.GetRegionBuckets, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/regions/buckets/recommendations", regionsHandler.GetBucketRecommendations, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/regions/range-holes", regionsHandler.GetRangeHoles, setMethods(http.MethodGet))
 	registerFunc(clusterRouter, "/regions/replicated", regionsHandler.CheckRegionsReplicated, setMethods(http.MethodGet), setQueries("startKey", "{startKey}", "endKey", "{endKey}"))
 
@@ -280,6 +353,7 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 
 	statsHandler := newStatsHandler(svr, rd)
 	registerFunc(clusterRouter, "/stats/region", statsHandler.GetRegionStatus, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/stats/region/labels", statsHandler.GetRegionLabelStats, setMethods(http.MethodGet))
 
 	trendHandler := newTrendHandler(svr, rd)
 	registerFunc(apiRouter, "/trend", trendHandler.GetTrend, setMethods(http.MethodGet), setAuditBackend(prometheus))
@@ -287,8 +361,36 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	adminHandler := newAdminHandler(svr, rd)
 	registerFunc(clusterRouter, "/admin/cache/region/{id}", adminHandler.DeleteRegionCache, setMethods(http.MethodDelete), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/admin/cache/regions", adminHandler.DeleteAllRegionCache, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/cache/regions/token", adminHandler.IssueRegionCacheDropToken, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/cache/dropped", adminHandler.GetDroppedRegionCache, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/admin/cache/dropped/restore", adminHandler.RestoreDroppedRegionCache, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(clusterRouter, "/admin/reset-ts", adminHandler.ResetTS, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/restore-mode", adminHandler.GetRestoreMode, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/admin/restore-mode", adminHandler.EnableRestoreMode, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/restore-mode", adminHandler.DisableRestoreMode, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/replica-freeze", adminHandler.GetReplicaFreezes, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/admin/replica-freeze", adminHandler.FreezeReplicas, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/replica-freeze/{id}", adminHandler.ThawReplicas, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/regions/merge", adminHandler.MergeRegions, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/break-glass", adminHandler.GetBreakGlass, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/admin/break-glass", adminHandler.EnableBreakGlass, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/break-glass", adminHandler.DisableBreakGlass, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/import-mode", adminHandler.GetImportModes, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/admin/import-mode", adminHandler.EnableImportMode, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/import-mode/{id}", adminHandler.GetImportMode, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/admin/import-mode/{id}", adminHandler.DisableImportMode, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/admin/invariants", adminHandler.CheckInvariants, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/admin/store-token", adminHandler.GetStoreTokenStatus, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/admin/store-token", adminHandler.IssueStoreToken, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/admin/store-token/previous", adminHandler.RevokePreviousStoreToken, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/admin/region-storage", adminHandler.GetRegionStorageMigrationStatus, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/admin/region-storage/dual-write", adminHandler.EnableRegionStorageDualWrite, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/admin/region-storage/dual-write", adminHandler.DisableRegionStorageDualWrite, setMethods(http.MethodDelete), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/admin/region-storage/verify", adminHandler.VerifyRegionStorage, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/admin/region-storage/cutover", adminHandler.CutoverRegionStorage, setMethods(http.MethodPost), setAuditBackend(localLog))
 	registerFunc(apiRouter, "/admin/persist-file/{file_name}", adminHandler.SavePersistFile, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(apiRouter, "/admin/persist-files", adminHandler.GetPersistedFiles, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/admin/persist-files/divergence", adminHandler.CheckPersistFileDivergence, setMethods(http.MethodGet))
 
 	serviceMiddlewareHandler := newServiceMiddlewareHandler(svr, rd)
 	registerFunc(apiRouter, "/service-middleware/config", serviceMiddlewareHandler.GetServiceMiddlewareConfig, setMethods(http.MethodGet))
@@ -300,6 +402,16 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	replicationModeHandler := newReplicationModeHandler(svr, rd)
 	registerFunc(clusterRouter, "/replication_mode/status", replicationModeHandler.GetReplicationModeStatus)
 
+	regionSyncerHandler := newRegionSyncerHandler(svr, rd)
+	registerFunc(clusterRouter, "/region-syncer/history", regionSyncerHandler.GetHistoryStatus, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/region-syncer/status", regionSyncerHandler.GetStatus, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/region-syncer/pause", regionSyncerHandler.Pause, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/region-syncer/resume", regionSyncerHandler.Resume, setMethods(http.MethodPost), setAuditBackend(localLog))
+	registerFunc(clusterRouter, "/region-syncer/followers/{name}/full-sync", regionSyncerHandler.ForceFullSync, setMethods(http.MethodPost), setAuditBackend(localLog))
+
+	traceHandler := newTraceHandler(rd)
+	registerFunc(apiRouter, "/trace/{trace_id}", traceHandler.GetTrace, setMethods(http.MethodGet))
+
 	pluginHandler := newPluginHandler(handler, rd)
 	registerFunc(apiRouter, "/plugin", pluginHandler.LoadPlugin, setMethods(http.MethodPost))
 	registerFunc(apiRouter, "/plugin", pluginHandler.UnloadPlugin, setMethods(http.MethodDelete))
@@ -307,10 +419,12 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	healthHandler := newHealthHandler(svr, rd)
 	registerFunc(apiRouter, "/health", healthHandler.GetHealthStatus, setMethods(http.MethodGet))
 	registerFunc(apiRouter, "/ping", healthHandler.Ping, setMethods(http.MethodGet))
+	registerFunc(apiRouter, "/metrics-lite", newSLOHandler(svr, rd).GetLatencies, setMethods(http.MethodGet), setRateLimitAllowList())
 
 	// metric query use to query metric data, the protocol is compatible with prometheus.
 	registerFunc(apiRouter, "/metric/query", newQueryMetric(svr).QueryMetric, setMethods(http.MethodGet, http.MethodPost))
 	registerFunc(apiRouter, "/metric/query_range", newQueryMetric(svr).QueryMetric, setMethods(http.MethodGet, http.MethodPost))
+	registerFunc(apiRouter, "/metric/cardinality", newMetricsCardinalityHandler(svr, rd).GetMetricsCardinality, setMethods(http.MethodGet))
 
 	// tso API
 	tsoHandler := newTSOHandler(svr, rd)
@@ -338,6 +452,11 @@ func createRouter(prefix string, svr *server.Server) *mux.Router {
 	minResolvedTSHandler := newMinResolvedTSHandler(svr, rd)
 	registerFunc(clusterRouter, "/min-resolved-ts", minResolvedTSHandler.GetMinResolvedTS, setMethods(http.MethodGet))
 
+	// worker pool API
+	workerPoolHandler := newWorkerPoolHandler(svr, rd)
+	registerFunc(clusterRouter, "/worker-pools", workerPoolHandler.GetWorkerPools, setMethods(http.MethodGet))
+	registerFunc(clusterRouter, "/worker-pools/{name}", workerPoolHandler.SetWorkerPoolSize, setMethods(http.MethodPost), setAuditBackend(localLog))
+
 	// unsafe admin operation API
 	unsafeOperationHandler := newUnsafeOperationHandler(svr, rd)
 	registerFunc(clusterRouter, "/admin/unsafe/remove-failed-stores",
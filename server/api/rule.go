@@ -20,6 +20,7 @@ import (
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 
 	"github.com/gorilla/mux"
 	"github.com/pingcap/errors"
@@ -27,11 +28,16 @@ import (
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/storage/endpoint"
 	"github.com/unrolled/render"
 )
 
 var errPlacementDisabled = errors.New("placement rules feature is disabled")
 
+// shadowedRulesHeader is the response header listing the rules which become
+// fully shadowed after a rule update.
+const shadowedRulesHeader = "PD-Shadowed-Rules"
+
 type ruleHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -46,8 +52,12 @@ func newRulesHandler(svr *server.Server, rd *render.Render) *ruleHandler {
 
 // @Tags     rule
 // @Summary  List all rules of cluster.
+// @Param    limit   query  integer  false  "The max number of rules in a page, at most 10000, 0 means no limit."
+// @Param    cursor  query  string   false  "The cursor returned by the previous page in the PD-Next-Cursor header."
+// @Param    fields  query  string   false  "The comma separated fields of rules to return, e.g. group_id,id,count."
 // @Produce  json
 // @Success  200  {array}   placement.Rule
+// @Failure  400  {string}  string  "The input is invalid."
 // @Failure  412  {string}  string  "Placement rules feature is disabled."
 // @Router   /config/rules [get]
 func (h *ruleHandler) GetAllRules(w http.ResponseWriter, r *http.Request) {
@@ -56,8 +66,19 @@ func (h *ruleHandler) GetAllRules(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusPreconditionFailed, errPlacementDisabled.Error())
 		return
 	}
+	page, ok := parsePage(h.rd, w, r)
+	if !ok {
+		return
+	}
 	rules := cluster.GetRuleManager().GetAllRules()
-	h.rd.JSON(w, http.StatusOK, rules)
+	// The rules are paged in the order of their keys rather than their
+	// priorities, because the key is the only stable cursor.
+	keys := make([]string, len(rules))
+	for i, rule := range rules {
+		keys[i] = rule.GroupID + "\x00" + rule.ID
+	}
+	start, end := page.Apply(keys, func(i, j int) { rules[i], rules[j] = rules[j], rules[i] })
+	renderPage(h.rd, w, page, "", rules[start:end], 0)
 }
 
 // @Tags     rule
@@ -115,6 +136,28 @@ func (h *ruleHandler) GetRuleByGroup(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, rules)
 }
 
+// @Tags     rule
+// @Summary  Force re-evaluation of the regions matched by the rules of a group.
+// @Param    group  path  string  true  "The name of group"
+// @Produce  json
+// @Success  200  {integer}  int     "The number of affected regions."
+// @Failure  404  {string}   string  "The group does not have any rule."
+// @Failure  412  {string}   string  "Placement rules feature is disabled."
+// @Router   /config/rules/group/{group}/reconcile [post]
+func (h *ruleHandler) ReconcileRuleGroup(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r)
+	if !cluster.GetOpts().IsPlacementRulesEnabled() {
+		h.rd.JSON(w, http.StatusPreconditionFailed, errPlacementDisabled.Error())
+		return
+	}
+	group := mux.Vars(r)["group"]
+	if len(cluster.GetRuleManager().GetRulesByGroup(group)) == 0 {
+		h.rd.JSON(w, http.StatusNotFound, fmt.Sprintf("group %s does not have any rule", group))
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.ReconcileRuleGroup(group))
+}
+
 // @Tags     rule
 // @Summary  List all rules of cluster by region.
 // @Param    region  path  string  true  "The name of region"
@@ -194,9 +237,10 @@ func (h *ruleHandler) GetRuleByGroupAndID(w http.ResponseWriter, r *http.Request
 }
 
 // @Tags     rule
-// @Summary  Update rule of cluster.
+// @Summary  Update rule of cluster. The rules which become fully shadowed by override rules are listed in the PD-Shadowed-Rules header.
 // @Accept   json
-// @Param    rule  body  placement.Rule  true  "Parameters of rule"
+// @Param    rule             body   placement.Rule  true   "Parameters of rule"
+// @Param    reject_shadowed  query  bool            false  "Reject the update if any rule becomes fully shadowed"
 // @Produce  json
 // @Success  200  {string}  string  "Update rule successfully."
 // @Failure  400  {string}  string  "The input is invalid."
@@ -209,6 +253,14 @@ func (h *ruleHandler) SetRule(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusPreconditionFailed, errPlacementDisabled.Error())
 		return
 	}
+	rejectShadowed := false
+	if v := r.URL.Query().Get("reject_shadowed"); v != "" {
+		var err error
+		if rejectShadowed, err = strconv.ParseBool(v); err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
 	var rule placement.Rule
 	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &rule); err != nil {
 		return
@@ -218,22 +270,45 @@ func (h *ruleHandler) SetRule(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		return
 	}
-	if err := cluster.GetRuleManager().SetKeyType(h.svr.GetConfig().PDServerCfg.KeyType).
-		SetRule(&rule); err != nil {
-		if errs.ErrRuleContent.Equal(err) || errs.ErrHexDecodingString.Equal(err) {
+	shadowed, err := cluster.GetRuleManager().SetKeyType(h.svr.GetConfig().PDServerCfg.KeyType).
+		SetRuleWithShadowCheck(&rule, rejectShadowed)
+	if err != nil {
+		if errs.ErrRuleContent.Equal(err) || errs.ErrHexDecodingString.Equal(err) || errs.ErrRuleShadowed.Equal(err) {
 			h.rd.JSON(w, http.StatusBadRequest, err.Error())
 		} else {
 			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		}
 		return
 	}
-	cluster.AddSuspectKeyRange(rule.StartKey, rule.EndKey)
+	if len(shadowed) > 0 {
+		keys := make([]string, 0, len(shadowed))
+		for _, sr := range shadowed {
+			keys = append(keys, sr.GroupID+"/"+sr.ID)
+		}
+		w.Header().Set(shadowedRulesHeader, strings.Join(keys, ","))
+	}
+	cluster.AddSuspectKeyRange(rule.StartKey, rule.EndKey, endpoint.SuspectReasonRuleChanged)
 	if oldRule != nil {
-		cluster.AddSuspectKeyRange(oldRule.StartKey, oldRule.EndKey)
+		cluster.AddSuspectKeyRange(oldRule.StartKey, oldRule.EndKey, endpoint.SuspectReasonRuleChanged)
 	}
 	h.rd.JSON(w, http.StatusOK, "Update rule successfully.")
 }
 
+// @Tags     rule
+// @Summary  List the rules which are fully shadowed by override rules or rule groups, so they are never applied.
+// @Produce  json
+// @Success  200  {array}   placement.Rule
+// @Failure  412  {string}  string  "Placement rules feature is disabled."
+// @Router   /config/rules/shadowed [get]
+func (h *ruleHandler) GetShadowedRules(w http.ResponseWriter, r *http.Request) {
+	cluster := getCluster(r)
+	if !cluster.GetOpts().IsPlacementRulesEnabled() {
+		h.rd.JSON(w, http.StatusPreconditionFailed, errPlacementDisabled.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, cluster.GetRuleManager().GetShadowedRules())
+}
+
 // sync replicate config with default-rule
 func (h *ruleHandler) syncReplicateConfigWithDefaultRule(rule *placement.Rule) error {
 	// sync default rule with replicate config
@@ -269,7 +344,7 @@ func (h *ruleHandler) DeleteRuleByGroup(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	if rule != nil {
-		cluster.AddSuspectKeyRange(rule.StartKey, rule.EndKey)
+		cluster.AddSuspectKeyRange(rule.StartKey, rule.EndKey, endpoint.SuspectReasonRuleChanged)
 	}
 
 	h.rd.JSON(w, http.StatusOK, "Delete rule successfully.")
@@ -354,7 +429,7 @@ func (h *ruleHandler) SetGroupConfig(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	for _, r := range cluster.GetRuleManager().GetRulesByGroup(ruleGroup.ID) {
-		cluster.AddSuspectKeyRange(r.StartKey, r.EndKey)
+		cluster.AddSuspectKeyRange(r.StartKey, r.EndKey, endpoint.SuspectReasonRuleGroupChanged)
 	}
 	h.rd.JSON(w, http.StatusOK, "Update rule group successfully.")
 }
@@ -380,7 +455,7 @@ func (h *ruleHandler) DeleteGroupConfig(w http.ResponseWriter, r *http.Request)
 		return
 	}
 	for _, r := range cluster.GetRuleManager().GetRulesByGroup(id) {
-		cluster.AddSuspectKeyRange(r.StartKey, r.EndKey)
+		cluster.AddSuspectKeyRange(r.StartKey, r.EndKey, endpoint.SuspectReasonRuleGroupChanged)
 	}
 	h.rd.JSON(w, http.StatusOK, "Delete rule group successfully.")
 }
@@ -181,6 +181,32 @@ func (suite *ruleTestSuite) TestSet() {
 	}
 }
 
+func (suite *ruleTestSuite) TestShadowedRules() {
+	re := suite.Require()
+	rule := placement.Rule{GroupID: "pd", ID: "override", Index: 1, Override: true, Role: "voter", Count: 3}
+	data, err := json.Marshal(rule)
+	suite.NoError(err)
+
+	// Reject the rule which shadows the default rule.
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/rule?reject_shadowed=true", data, tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	var rules []*placement.Rule
+	err = tu.ReadGetJSON(re, testDialClient, suite.urlPrefix+"/rules/shadowed", &rules)
+	suite.NoError(err)
+	suite.Empty(rules)
+
+	// Warn the shadowed rules in the response header.
+	resp, err := apiutil.PostJSON(testDialClient, suite.urlPrefix+"/rule", data)
+	suite.NoError(err)
+	resp.Body.Close()
+	suite.Equal(http.StatusOK, resp.StatusCode)
+	suite.Equal("pd/default", resp.Header.Get(shadowedRulesHeader))
+	err = tu.ReadGetJSON(re, testDialClient, suite.urlPrefix+"/rules/shadowed", &rules)
+	suite.NoError(err)
+	suite.Len(rules, 1)
+	suite.Equal("default", rules[0].ID)
+}
+
 func (suite *ruleTestSuite) TestGet() {
 	rule := placement.Rule{GroupID: "a", ID: "20", StartKeyHex: "1111", EndKeyHex: "3333", Role: "voter", Count: 1}
 	data, err := json.Marshal(rule)
@@ -389,6 +415,27 @@ func (suite *ruleTestSuite) TestGetAllByGroup() {
 	}
 }
 
+func (suite *ruleTestSuite) TestReconcileGroup() {
+	re := suite.Require()
+	rule := placement.Rule{GroupID: "reconcile", ID: "20", StartKeyHex: "a000", EndKeyHex: "b000", Role: "voter", Count: 1}
+	data, err := json.Marshal(rule)
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/rule", data, tu.StatusOK(re))
+	suite.NoError(err)
+
+	r := newTestRegionInfo(8, 1, []byte{0xa0, 0x10}, []byte{0xa0, 0x20})
+	mustRegionHeartbeat(re, suite.svr, r)
+
+	var count int
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/rules/group/reconcile/reconcile", nil, tu.StatusOK(re), tu.ExtractJSON(re, &count))
+	suite.NoError(err)
+	suite.Equal(1, count)
+	suite.Contains(suite.svr.GetRaftCluster().GetSuspectRegions(), uint64(8))
+
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/rules/group/not-exist/reconcile", nil, tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+}
+
 func (suite *ruleTestSuite) TestGetAllByRegion() {
 	rule := placement.Rule{GroupID: "e", ID: "20", StartKeyHex: "1111", EndKeyHex: "3333", Role: "voter", Count: 1}
 	data, err := json.Marshal(rule)
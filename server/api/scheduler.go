@@ -15,8 +15,10 @@
 package api
 
 import (
+	"encoding/json"
 	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -25,6 +27,8 @@ import (
 	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/schedulers"
 	"github.com/unrolled/render"
 )
@@ -45,6 +49,19 @@ func newSchedulerHandler(svr *server.Server, r *render.Render) *schedulerHandler
 	}
 }
 
+type schedulerUnreadyReason struct {
+	Name   string `json:"name"`
+	Reason string `json:"reason"`
+}
+
+// SchedulingSimulationInput is the proposed change to simulate.
+type SchedulingSimulationInput struct {
+	// ScheduleConfig contains the schedule config items to change, keyed by
+	// the config names, e.g. "leader-schedule-limit".
+	ScheduleConfig map[string]interface{} `json:"schedule_config"`
+	Rules          []*placement.Rule      `json:"rules"`
+}
+
 type schedulerPausedPeriod struct {
 	Name     string    `json:"name"`
 	PausedAt time.Time `json:"paused_at"`
@@ -122,6 +139,20 @@ func (h *schedulerHandler) GetSchedulers(w http.ResponseWriter, r *http.Request)
 			}
 		}
 		h.r.JSON(w, http.StatusOK, disabledSchedulers)
+	case "unready":
+		unreadySchedulers := []schedulerUnreadyReason{}
+		for _, scheduler := range schedulers {
+			reason, err := h.Handler.GetSchedulerUnreadyReason(scheduler)
+			if err != nil {
+				h.r.JSON(w, http.StatusInternalServerError, err.Error())
+				return
+			}
+
+			if reason != "" {
+				unreadySchedulers = append(unreadySchedulers, schedulerUnreadyReason{Name: scheduler, Reason: reason})
+			}
+		}
+		h.r.JSON(w, http.StatusOK, unreadySchedulers)
 	default:
 		h.r.JSON(w, http.StatusOK, schedulers)
 	}
@@ -131,7 +162,7 @@ func (h *schedulerHandler) GetSchedulers(w http.ResponseWriter, r *http.Request)
 // @Tags     scheduler
 // @Summary  Create a scheduler.
 // @Accept   json
-// @Param    body  body  object  true  "json params"
+// @Param    body  body  object  true  "json params, observe_ttl is the seconds the scheduler starts in the observe-only mode"
 // @Produce  json
 // @Success  200  {string}  string  "The scheduler is created."
 // @Failure  400  {string}  string  "Bad format request."
@@ -148,25 +179,35 @@ func (h *schedulerHandler) CreateScheduler(w http.ResponseWriter, r *http.Reques
 		h.r.JSON(w, http.StatusBadRequest, "missing scheduler name")
 		return
 	}
+	// The scheduler records its operators instead of submitting them for the
+	// first observe_ttl seconds.
+	var observe int64
+	if t, ok := input["observe_ttl"].(float64); ok {
+		if t < 0 {
+			h.r.JSON(w, http.StatusBadRequest, "observe ttl should not be negative")
+			return
+		}
+		observe = int64(t)
+	}
 
 	switch name {
 	case schedulers.BalanceLeaderName:
-		if err := h.AddBalanceLeaderScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.BalanceLeaderType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	case schedulers.HotRegionName:
-		if err := h.AddBalanceHotRegionScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.HotRegionType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	case schedulers.BalanceRegionName:
-		if err := h.AddBalanceRegionScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.BalanceRegionType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	case schedulers.LabelName:
-		if err := h.AddLabelScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.LabelType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -190,27 +231,32 @@ func (h *schedulerHandler) CreateScheduler(w http.ResponseWriter, r *http.Reques
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
-		if err := h.AddScatterRangeScheduler(args...); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.ScatterRangeType, args...); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 
 	case schedulers.GrantLeaderName:
-		h.addEvictOrGrant(w, input, schedulers.GrantLeaderName)
+		h.addEvictOrGrant(w, input, schedulers.GrantLeaderName, observe)
 	case schedulers.EvictLeaderName:
-		h.addEvictOrGrant(w, input, schedulers.EvictLeaderName)
+		h.addEvictOrGrant(w, input, schedulers.EvictLeaderName, observe)
 	case schedulers.ShuffleLeaderName:
-		if err := h.AddShuffleLeaderScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.ShuffleLeaderType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	case schedulers.ShuffleRegionName:
-		if err := h.AddShuffleRegionScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.ShuffleRegionType); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case schedulers.BalanceLearnerName:
+		if err := h.AddObservingScheduler(observe, schedulers.BalanceLearnerType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	case schedulers.RandomMergeName:
-		if err := h.AddRandomMergeScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.RandomMergeType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -220,17 +266,22 @@ func (h *schedulerHandler) CreateScheduler(w http.ResponseWriter, r *http.Reques
 		if ok {
 			limit = uint64(l)
 		}
-		if err := h.AddShuffleHotRegionScheduler(limit); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.ShuffleHotRegionType, strconv.FormatUint(limit, 10)); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	case schedulers.EvictSlowStoreName:
-		if err := h.AddEvictSlowStoreScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.EvictSlowStoreType); err != nil {
+			h.r.JSON(w, http.StatusInternalServerError, err.Error())
+			return
+		}
+	case schedulers.EvictSlowTrendName:
+		if err := h.AddObservingScheduler(observe, schedulers.EvictSlowTrendType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
 	case schedulers.SplitBucketName:
-		if err := h.AddSplitBucketScheduler(); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.SplitBucketType); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -245,7 +296,7 @@ func (h *schedulerHandler) CreateScheduler(w http.ResponseWriter, r *http.Reques
 			h.r.JSON(w, http.StatusBadRequest, "missing store id")
 			return
 		}
-		if err := h.AddGrantHotRegionScheduler(leaderID, peerIDs); err != nil {
+		if err := h.AddObservingScheduler(observe, schedulers.GrantHotRegionType, leaderID, peerIDs); err != nil {
 			h.r.JSON(w, http.StatusInternalServerError, err.Error())
 			return
 		}
@@ -257,13 +308,13 @@ func (h *schedulerHandler) CreateScheduler(w http.ResponseWriter, r *http.Reques
 	h.r.JSON(w, http.StatusOK, "The scheduler is created.")
 }
 
-func (h *schedulerHandler) addEvictOrGrant(w http.ResponseWriter, input map[string]interface{}, name string) {
+func (h *schedulerHandler) addEvictOrGrant(w http.ResponseWriter, input map[string]interface{}, name string, observe int64) {
 	storeID, ok := input["store_id"].(float64)
 	if !ok {
 		h.r.JSON(w, http.StatusBadRequest, "missing store id")
 		return
 	}
-	err := h.AddEvictOrGrant(storeID, name)
+	err := h.AddEvictOrGrant(storeID, name, observe)
 	if err != nil {
 		h.r.JSON(w, http.StatusInternalServerError, err.Error())
 	}
@@ -345,6 +396,116 @@ func (h *schedulerHandler) PauseOrResumeScheduler(w http.ResponseWriter, r *http
 	h.r.JSON(w, http.StatusOK, "Pause or resume the scheduler successfully.")
 }
 
+// @Tags     scheduler
+// @Summary  Set or leave the observe-only mode of a scheduler, in which its operators are recorded but not submitted.
+// @Accept   json
+// @Param    name  path  string  true  "The name of the scheduler."
+// @Param    body  body  object  true  "json params, ttl is the seconds to observe, 0 leaves the observe-only mode"
+// @Produce  json
+// @Success  200  {string}  string  "Set the observe-only mode of the scheduler successfully."
+// @Failure  400  {string}  string  "Bad format request."
+// @Failure  404  {string}  string  "The scheduler is not found."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /schedulers/{name}/observe [post]
+func (h *schedulerHandler) SetSchedulerObserveOnly(w http.ResponseWriter, r *http.Request) {
+	var input map[string]int64
+	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
+		return
+	}
+	t, ok := input["ttl"]
+	if !ok {
+		h.r.JSON(w, http.StatusBadRequest, "missing observe ttl")
+		return
+	}
+	if t < 0 {
+		h.r.JSON(w, http.StatusBadRequest, "observe ttl should not be negative")
+		return
+	}
+	if err := h.Handler.SetSchedulerObserveOnly(mux.Vars(r)["name"], t); err != nil {
+		h.handleErr(w, err)
+		return
+	}
+	h.r.JSON(w, http.StatusOK, "Set the observe-only mode of the scheduler successfully.")
+}
+
+// @Tags     scheduler
+// @Summary  Get the operators recorded by a scheduler in observe-only mode.
+// @Param    name  path  string  true  "The name of the scheduler."
+// @Produce  json
+// @Success  200  {object}  schedule.SchedulerObservation
+// @Failure  404  {string}  string  "The scheduler is not found."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /schedulers/{name}/observe [get]
+func (h *schedulerHandler) GetSchedulerObservation(w http.ResponseWriter, r *http.Request) {
+	observation, err := h.Handler.GetSchedulerObservation(mux.Vars(r)["name"])
+	if err != nil {
+		h.handleErr(w, err)
+		return
+	}
+	h.r.JSON(w, http.StatusOK, observation)
+}
+
+// @Tags     scheduler
+// @Summary  Get a snapshot of the internal state of a scheduler.
+// @Param    name  path  string  true  "The name of the scheduler."
+// @Produce  json
+// @Success  200  {object}  schedule.SchedulerStateSnapshot
+// @Failure  404  {string}  string  "The scheduler is not found."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /schedulers/state/{name} [get]
+func (h *schedulerHandler) GetSchedulerState(w http.ResponseWriter, r *http.Request) {
+	state, err := h.Handler.GetSchedulerState(mux.Vars(r)["name"])
+	if err != nil {
+		h.handleErr(w, err)
+		return
+	}
+	h.r.JSON(w, http.StatusOK, state)
+}
+
+// @Tags     scheduler
+// @Summary  Simulate the impact of a schedule config or placement rule change on operators.
+// @Accept   json
+// @Param    body  body  SchedulingSimulationInput  true  "The proposed change"
+// @Produce  json
+// @Success  200  {array}   cluster.SchedulerImpact
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /schedulers/simulation [post]
+func (h *schedulerHandler) SimulateScheduling(w http.ResponseWriter, r *http.Request) {
+	var input SchedulingSimulationInput
+	if err := apiutil.ReadJSONRespondError(h.r, w, r.Body, &input); err != nil {
+		return
+	}
+	var scheduleCfg *config.ScheduleConfig
+	if len(input.ScheduleConfig) > 0 {
+		scheduleCfg = h.svr.GetScheduleConfig()
+		// Overlay the changed items on the current config.
+		data, err := json.Marshal(input.ScheduleConfig)
+		if err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := json.Unmarshal(data, scheduleCfg); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		if err := scheduleCfg.Validate(); err != nil {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	impacts, err := h.Handler.SimulateScheduling(scheduleCfg, input.Rules)
+	if err != nil {
+		if errs.ErrRuleContent.Equal(err) || errs.ErrHexDecodingString.Equal(err) {
+			h.r.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		h.r.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.r.JSON(w, http.StatusOK, impacts)
+}
+
 type schedulerConfigHandler struct {
 	svr *server.Server
 	rd  *render.Render
@@ -28,6 +28,7 @@ import (
 	tu "github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
 	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule"
 	_ "github.com/tikv/pd/server/schedulers"
 )
 
@@ -453,6 +454,59 @@ func (suite *scheduleTestSuite) TestDisable() {
 	suite.deleteScheduler(name)
 }
 
+func (suite *scheduleTestSuite) TestSchedulerState() {
+	re := suite.Require()
+	name := "balance-leader-scheduler"
+	input := make(map[string]interface{})
+	input["name"] = name
+	body, err := json.Marshal(input)
+	suite.NoError(err)
+	suite.addScheduler(body)
+
+	var state schedule.SchedulerStateSnapshot
+	stateURL := fmt.Sprintf("%s/state/%s", suite.urlPrefix, name)
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, stateURL, &state))
+	suite.Equal(name, state.Name)
+	suite.Equal("balance-leader", state.Type)
+	suite.False(state.Paused)
+	suite.NotEmpty(state.Config)
+
+	suite.deleteScheduler(name)
+	suite.NoError(tu.CheckGetJSON(testDialClient, stateURL, nil, tu.Status(re, http.StatusNotFound)))
+}
+
+func (suite *scheduleTestSuite) TestObserveOnly() {
+	re := suite.Require()
+	name := "balance-leader-scheduler"
+	input := make(map[string]interface{})
+	input["name"] = name
+	body, err := json.Marshal(input)
+	suite.NoError(err)
+	suite.addScheduler(body)
+
+	observeURL := fmt.Sprintf("%s/%s/observe", suite.urlPrefix, name)
+	suite.NoError(tu.CheckPostJSON(testDialClient, observeURL, []byte(`{}`), tu.Status(re, http.StatusBadRequest)))
+	suite.NoError(tu.CheckPostJSON(testDialClient, observeURL, []byte(`{"ttl":-1}`), tu.Status(re, http.StatusBadRequest)))
+	suite.NoError(tu.CheckPostJSON(testDialClient, observeURL, []byte(`{"ttl":60}`), tu.StatusOK(re)))
+	var observation schedule.SchedulerObservation
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, observeURL, &observation))
+	suite.True(observation.Observing)
+	suite.Equal(int64(60), observation.ObserveUntil.Unix()-observation.ObserveSince.Unix())
+	suite.NotNil(observation.Operators)
+	var state schedule.SchedulerStateSnapshot
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, fmt.Sprintf("%s/state/%s", suite.urlPrefix, name), &state))
+	suite.True(state.Observing)
+
+	suite.NoError(tu.CheckPostJSON(testDialClient, observeURL, []byte(`{"ttl":0}`), tu.StatusOK(re)))
+	observation = schedule.SchedulerObservation{}
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, observeURL, &observation))
+	suite.False(observation.Observing)
+
+	suite.deleteScheduler(name)
+	suite.NoError(tu.CheckPostJSON(testDialClient, observeURL, []byte(`{"ttl":60}`), tu.Status(re, http.StatusNotFound)))
+	suite.NoError(tu.CheckGetJSON(testDialClient, observeURL, nil, tu.Status(re, http.StatusNotFound)))
+}
+
 func (suite *scheduleTestSuite) addScheduler(body []byte) {
 	err := tu.CheckPostJSON(testDialClient, suite.urlPrefix, body, tu.StatusOK(suite.Require()))
 	suite.NoError(err)
@@ -0,0 +1,53 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/tikv/pd/pkg/slo"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type sloHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+// LatencyMetrics is the rolling latencies of the APIs and gRPC methods.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type LatencyMetrics struct {
+	Overloaded bool                       `json:"overloaded"`
+	Latencies  map[string]slo.LatencyStat `json:"latencies"`
+}
+
+func newSLOHandler(svr *server.Server, rd *render.Render) *sloHandler {
+	return &sloHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Summary  Get the rolling p99 latencies of the APIs and gRPC methods.
+// @Produce  json
+// @Success  200  {object}  LatencyMetrics
+// @Router   /metrics-lite [get]
+func (h *sloHandler) GetLatencies(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, &LatencyMetrics{
+		Overloaded: h.svr.IsOverloaded(),
+		Latencies:  h.svr.GetSLOTracker().GetStats(),
+	})
+}
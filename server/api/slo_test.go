@@ -0,0 +1,54 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	tu "github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server"
+)
+
+func TestGetLatencies(t *testing.T) {
+	re := require.New(t)
+	svr, cleanup := mustNewServer(re)
+	defer cleanup()
+	server.MustWaitLeader(re, []*server.Server{svr})
+	urlPrefix := fmt.Sprintf("%s%s/api/v1", svr.GetAddr(), apiPrefix)
+
+	for i := 0; i < 20; i++ {
+		re.NoError(tu.CheckGetJSON(testDialClient, urlPrefix+"/ping", nil, tu.StatusOK(re)))
+	}
+	metrics := &LatencyMetrics{}
+	re.NoError(tu.ReadGetJSON(re, testDialClient, urlPrefix+"/metrics-lite", metrics))
+	re.False(metrics.Overloaded)
+	re.Equal(uint64(20), metrics.Latencies["Ping"].Count)
+
+	// The slow APIs are shed when PD is overloaded.
+	data := []byte(`{"enable-rate-limit": "true", "overload-latency-threshold": "1ns"}`)
+	re.NoError(tu.CheckPostJSON(testDialClient, urlPrefix+"/service-middleware/config", data, tu.StatusOK(re)))
+	re.NoError(tu.CheckGetJSON(testDialClient, urlPrefix+"/ping", nil, tu.Status(re, http.StatusTooManyRequests)))
+	re.NoError(tu.ReadGetJSON(re, testDialClient, urlPrefix+"/metrics-lite", metrics))
+	re.True(metrics.Overloaded)
+
+	data = []byte(`{"overload-latency-threshold": "0s"}`)
+	re.NoError(tu.CheckPostJSON(testDialClient, urlPrefix+"/service-middleware/config", data, tu.StatusOK(re)))
+	re.NoError(tu.CheckGetJSON(testDialClient, urlPrefix+"/ping", nil, tu.StatusOK(re)))
+	re.NoError(tu.ReadGetJSON(re, testDialClient, urlPrefix+"/metrics-lite", metrics))
+	re.False(metrics.Overloaded)
+}
@@ -46,3 +46,13 @@ func (h *statsHandler) GetRegionStatus(w http.ResponseWriter, r *http.Request) {
 	stats := rc.GetRegionStats([]byte(startKey), []byte(endKey))
 	h.rd.JSON(w, http.StatusOK, stats)
 }
+
+// @Tags     stats
+// @Summary  Get region counts per region label and their placement rule satisfaction.
+// @Produce  json
+// @Success  200  {array}  statistics.RegionLabelStat
+// @Router   /stats/region/labels [get]
+func (h *statsHandler) GetRegionLabelStats(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	h.rd.JSON(w, http.StatusOK, rc.GetRegionLabelStats())
+}
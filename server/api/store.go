@@ -18,6 +18,8 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -26,48 +28,58 @@ import (
 	"github.com/pingcap/errcode"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/apiutil"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/storage/endpoint"
 	"github.com/unrolled/render"
+	"go.uber.org/zap"
 )
 
 // MetaStore contains meta information about a store.
 type MetaStore struct {
 	*metapb.Store
-	StateName string `json:"state_name"`
+	StateName string              `json:"state_name"`
+	Topology  *core.StoreTopology `json:"topology,omitempty"`
 }
 
 // StoreStatus contains status about a store.
 type StoreStatus struct {
-	Capacity           typeutil.ByteSize  `json:"capacity"`
-	Available          typeutil.ByteSize  `json:"available"`
-	UsedSize           typeutil.ByteSize  `json:"used_size"`
-	LeaderCount        int                `json:"leader_count"`
-	LeaderWeight       float64            `json:"leader_weight"`
-	LeaderScore        float64            `json:"leader_score"`
-	LeaderSize         int64              `json:"leader_size"`
-	RegionCount        int                `json:"region_count"`
-	RegionWeight       float64            `json:"region_weight"`
-	RegionScore        float64            `json:"region_score"`
-	RegionSize         int64              `json:"region_size"`
-	SlowScore          uint64             `json:"slow_score"`
-	SendingSnapCount   uint32             `json:"sending_snap_count,omitempty"`
-	ReceivingSnapCount uint32             `json:"receiving_snap_count,omitempty"`
-	IsBusy             bool               `json:"is_busy,omitempty"`
-	StartTS            *time.Time         `json:"start_ts,omitempty"`
-	LastHeartbeatTS    *time.Time         `json:"last_heartbeat_ts,omitempty"`
-	Uptime             *typeutil.Duration `json:"uptime,omitempty"`
+	Capacity           typeutil.ByteSize      `json:"capacity"`
+	Available          typeutil.ByteSize      `json:"available"`
+	UsedSize           typeutil.ByteSize      `json:"used_size"`
+	LeaderCount        int                    `json:"leader_count"`
+	LeaderWeight       float64                `json:"leader_weight"`
+	LeaderScore        float64                `json:"leader_score"`
+	LeaderSize         int64                  `json:"leader_size"`
+	RegionCount        int                    `json:"region_count"`
+	RegionWeight       float64                `json:"region_weight"`
+	RegionScore        float64                `json:"region_score"`
+	RegionSize         int64                  `json:"region_size"`
+	LeaderQuota        uint64                 `json:"leader_quota,omitempty"`
+	RegionQuota        uint64                 `json:"region_quota,omitempty"`
+	SlowScore          uint64                 `json:"slow_score"`
+	SlowTrend          *core.SlowTrend        `json:"slow_trend,omitempty"`
+	SlowTrendSeries    []core.SlowTrendSample `json:"slow_trend_series,omitempty"`
+	SendingSnapCount   uint32                 `json:"sending_snap_count,omitempty"`
+	ReceivingSnapCount uint32                 `json:"receiving_snap_count,omitempty"`
+	IsBusy             bool                   `json:"is_busy,omitempty"`
+	StartTS            *time.Time             `json:"start_ts,omitempty"`
+	LastHeartbeatTS    *time.Time             `json:"last_heartbeat_ts,omitempty"`
+	Uptime             *typeutil.Duration     `json:"uptime,omitempty"`
 }
 
 // StoreInfo contains information about a store.
 type StoreInfo struct {
-	Store  *MetaStore   `json:"store"`
-	Status *StoreStatus `json:"status"`
+	Store  *MetaStore            `json:"store"`
+	Status *StoreStatus          `json:"status"`
+	Notes  []*endpoint.StoreNote `json:"notes,omitempty"`
 }
 
 const (
@@ -80,6 +92,7 @@ func newStoreInfo(opt *config.ScheduleConfig, store *core.StoreInfo) *StoreInfo
 		Store: &MetaStore{
 			Store:     store.GetMeta(),
 			StateName: store.GetState().String(),
+			Topology:  store.GetTopology(),
 		},
 		Status: &StoreStatus{
 			Capacity:           typeutil.ByteSize(store.GetCapacity()),
@@ -91,9 +104,12 @@ func newStoreInfo(opt *config.ScheduleConfig, store *core.StoreInfo) *StoreInfo
 			LeaderSize:         store.GetLeaderSize(),
 			RegionCount:        store.GetRegionCount(),
 			RegionWeight:       store.GetRegionWeight(),
-			RegionScore:        store.RegionScore(opt.RegionScoreFormulaVersion, opt.HighSpaceRatio, opt.LowSpaceRatio, 0),
+			RegionScore:        store.RegionScore(opt.RegionScoreFormulaVersion, opt.HighSpaceRatio, opt.LowSpaceRatio, opt.GetRegionScoreCurve(), 0),
 			RegionSize:         store.GetRegionSize(),
+			LeaderQuota:        store.GetLeaderQuota(),
+			RegionQuota:        store.GetRegionQuota(),
 			SlowScore:          store.GetSlowScore(),
+			SlowTrend:          store.GetSlowTrend(),
 			SendingSnapCount:   store.GetSendingSnapCount(),
 			ReceivingSnapCount: store.GetReceivingSnapCount(),
 			IsBusy:             store.IsBusy(),
@@ -165,6 +181,13 @@ func (h *storeHandler) GetStore(w http.ResponseWriter, r *http.Request) {
 	}
 
 	storeInfo := newStoreInfo(h.handler.GetScheduleConfig(), store)
+	storeInfo.Status.SlowTrendSeries = rc.GetStoreSlowTrendSeries(storeID)
+	// The notes are auxiliary, so the store is still served without them.
+	notes, err := rc.GetStoreNotes(storeID)
+	if err != nil {
+		log.Warn("failed to load store notes", zap.Uint64("store-id", storeID), errs.ZapError(err))
+	}
+	storeInfo.Notes = notes
 	h.rd.JSON(w, http.StatusOK, storeInfo)
 }
 
@@ -199,6 +222,32 @@ func (h *storeHandler) DeleteStore(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, "The store is set as Offline.")
 }
 
+// @Tags     store
+// @Summary  Estimate the cost of taking down a store, grouped by placement rules.
+// @Param    id  path  integer  true  "Store Id"
+// @Produce  json
+// @Success  200  {object}  cluster.DecommissionEstimate
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The store does not exist."
+// @Failure  410  {string}  string  "The store has already been removed."
+// @Router   /store/{id}/decommission-estimate [get]
+func (h *storeHandler) GetDecommissionEstimate(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	estimate, err := rc.EstimateDecommission(storeID)
+	if err != nil {
+		h.responseStoreErr(w, err, storeID)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, estimate)
+}
+
 // @Tags     store
 // @Summary  Set the store's state.
 // @Param    id     path   integer  true  "Store Id"
@@ -297,6 +346,42 @@ func (h *storeHandler) SetStoreLabel(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, "The store's label is updated.")
 }
 
+// @Tags     store
+// @Summary  Report the hardware topology of a store.
+// @Param    id    path  integer             true  "Store Id"
+// @Param    body  body  core.StoreTopology  true  "The hardware topology of the store"
+// @Produce  json
+// @Success  200  {string}  string  "The store's topology is updated."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The store does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /store/{id}/topology [post]
+func (h *storeHandler) SetStoreTopology(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	topology := &core.StoreTopology{}
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, topology); err != nil {
+		return
+	}
+	if err := topology.Validate(); err != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+		return
+	}
+
+	if err := rc.UpdateStoreTopology(storeID, topology); err != nil {
+		h.responseStoreErr(w, err, storeID)
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, "The store's topology is updated.")
+}
+
 // FIXME: details of input json body params
 // @Tags     store
 // @Summary  Set the store's leader/region weight.
@@ -350,6 +435,55 @@ func (h *storeHandler) SetStoreWeight(w http.ResponseWriter, r *http.Request) {
 	h.rd.JSON(w, http.StatusOK, "The store's label is updated.")
 }
 
+// StoreQuotaInput is the soft quotas of the leader and region count of a store, 0 means unlimited.
+type StoreQuotaInput struct {
+	Leader *uint64 `json:"leader"`
+	Region *uint64 `json:"region"`
+}
+
+// @Tags     store
+// @Summary  Set the store's soft quotas of the leader and region count.
+// @Param    id    path  integer          true  "Store Id"
+// @Param    body  body  StoreQuotaInput  true  "The soft quotas, 0 means unlimited"
+// @Produce  json
+// @Success  200  {string}  string  "The store's quota is updated."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /store/{id}/quota [post]
+func (h *storeHandler) SetStoreQuota(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	var input StoreQuotaInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.Leader == nil {
+		h.rd.JSON(w, http.StatusBadRequest, "leader quota unset")
+		return
+	}
+	if input.Region == nil {
+		h.rd.JSON(w, http.StatusBadRequest, "region quota unset")
+		return
+	}
+
+	if err := rc.SetStoreQuota(storeID, *input.Leader, *input.Region); err != nil {
+		if errs.ErrStoreNotFound.Equal(err) {
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+			return
+		}
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+
+	h.rd.JSON(w, http.StatusOK, "The store's quota is updated.")
+}
+
 // FIXME: details of input json body params
 // @Tags     store
 // @Summary  Set the store's limit.
@@ -593,6 +727,141 @@ func (h *storesHandler) SetStoreLimitScene(w http.ResponseWriter, r *http.Reques
 	h.rd.JSON(w, http.StatusOK, "Set store limit scene successfully.")
 }
 
+// StoresLabelPatch is the input of the bulk store label API.
+type StoresLabelPatch struct {
+	// AddressPattern is a regular expression used to select stores by address.
+	AddressPattern string `json:"address-pattern,omitempty"`
+	// MatchLabels selects the stores which have all the given labels.
+	MatchLabels map[string]string `json:"match-labels,omitempty"`
+	// Labels is the label patch, a label with an empty value will be removed.
+	Labels map[string]string `json:"labels"`
+	// DryRun only returns the resulting labels without updating the stores.
+	DryRun bool `json:"dry-run,omitempty"`
+}
+
+// StoreLabels is the resulting labels of a store.
+type StoreLabels struct {
+	StoreID uint64               `json:"store_id"`
+	Address string               `json:"address"`
+	Labels  []*metapb.StoreLabel `json:"labels"`
+}
+
+// @Tags     store
+// @Summary  Apply a label patch to the selected stores.
+// @Accept   json
+// @Param    body  body  StoresLabelPatch  true  "The store selector and label patch"
+// @Produce  json
+// @Success  200  {array}   StoreLabels
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /stores/labels [post]
+func (h *storesHandler) SetStoresLabels(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var input StoresLabelPatch
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.AddressPattern == "" && len(input.MatchLabels) == 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "need address-pattern or match-labels to select stores")
+		return
+	}
+	if len(input.Labels) == 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "the label patch is empty")
+		return
+	}
+	var addressPattern *regexp.Regexp
+	if input.AddressPattern != "" {
+		var err error
+		if addressPattern, err = regexp.Compile(input.AddressPattern); err != nil {
+			apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+			return
+		}
+	}
+	patch := make([]*metapb.StoreLabel, 0, len(input.Labels))
+	for k, v := range input.Labels {
+		patch = append(patch, &metapb.StoreLabel{Key: k, Value: v})
+	}
+	if err := config.ValidateLabels(patch); err != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(err))
+		return
+	}
+
+	var storeIDs []uint64
+	for _, store := range rc.GetStores() {
+		if store.IsRemoved() {
+			continue
+		}
+		if addressPattern != nil && !addressPattern.MatchString(store.GetAddress()) {
+			continue
+		}
+		matched := true
+		for k, v := range input.MatchLabels {
+			if store.GetLabelValue(k) != v {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			storeIDs = append(storeIDs, store.GetID())
+		}
+	}
+	sort.Slice(storeIDs, func(i, j int) bool { return storeIDs[i] < storeIDs[j] })
+
+	labels, err := rc.UpdateStoresLabels(storeIDs, patch, input.DryRun)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	result := make([]*StoreLabels, 0, len(storeIDs))
+	for _, storeID := range storeIDs {
+		result = append(result, &StoreLabels{
+			StoreID: storeID,
+			Address: rc.GetStore(storeID).GetAddress(),
+			Labels:  labels[storeID],
+		})
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
+// @Tags     store
+// @Summary  Change the states of the stores at once. The final state is validated once and all the changes are applied or none of them is.
+// @Accept   json
+// @Param    body  body  []cluster.StoreStateChange  true  "The state changes of the stores"
+// @Produce  json
+// @Success  200  {string}  string  "The store states are updated."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The store does not exist."
+// @Failure  410  {string}  string  "The store has already been removed."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /stores/state [post]
+func (h *storesHandler) SetStoresState(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var changes []*cluster.StoreStateChange
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &changes); err != nil {
+		return
+	}
+	if len(changes) == 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "no state change is specified")
+		return
+	}
+	if err := rc.SetStoreStates(changes); err != nil {
+		switch {
+		case errs.ErrStoreNotFound.Equal(err):
+			h.rd.JSON(w, http.StatusNotFound, err.Error())
+		case errs.ErrStoreRemoved.Equal(err):
+			h.rd.JSON(w, http.StatusGone, err.Error())
+		case errs.ErrStoreStateChange.Equal(err), errs.ErrStoreIsUp.Equal(err), errs.ErrStoreDestroyed.Equal(err),
+			errs.ErrStoresNotEnough.Equal(err), errs.ErrNoStoreForRegionLeader.Equal(err),
+			errs.ErrStoreStatesViolateRule.Equal(err), errs.ErrStoreStatesNoCapacity.Equal(err):
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		default:
+			h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		}
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The store states are updated.")
+}
+
 // @Tags     store
 // @Summary  Get limit scene in the cluster.
 // @Produce  json
@@ -618,6 +887,70 @@ type Progress struct {
 	LeftSeconds  float64 `json:"left_seconds"`
 }
 
+// @Tags     stores
+// @Summary  Get the computed serving threshold and preparation details of the preparing stores.
+// @Produce  json
+// @Success  200  {array}  cluster.StorePreparingDetail
+// @Router   /stores/preparing [get]
+func (h *storesHandler) GetStoresPreparingDetails(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, getCluster(r).GetStorePreparingDetails())
+}
+
+// @Tags     stores
+// @Summary  Get the topology weights of the stores under the current location labels and placement rules.
+// @Param    store_id  query  integer  false  "Only return the weights of the stores, can be repeated"
+// @Produce  json
+// @Success  200  {object}  cluster.TopologyWeights
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /stores/topology-weight [get]
+func (h *storesHandler) GetStoresTopologyWeight(w http.ResponseWriter, r *http.Request) {
+	var storeIDs []uint64
+	for _, idStr := range r.URL.Query()["store_id"] {
+		id, err := strconv.ParseUint(idStr, 10, 64)
+		if err != nil {
+			h.rd.JSON(w, http.StatusBadRequest, err.Error())
+			return
+		}
+		storeIDs = append(storeIDs, id)
+	}
+	h.rd.JSON(w, http.StatusOK, getCluster(r).GetTopologyWeights(storeIDs...))
+}
+
+// @Tags     stores
+// @Summary  Get the version distribution of the stores and the stores exceeding the max version skew.
+// @Produce  json
+// @Success  200  {object}  cluster.StoreVersionDistribution
+// @Router   /stores/versions [get]
+func (h *storesHandler) GetStoresVersionDistribution(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, getCluster(r).GetStoreVersionDistribution())
+}
+
+// StoreOfflineSimulationInput is the planned sequence of store offlines.
+type StoreOfflineSimulationInput struct {
+	Steps []*cluster.OfflinePlanStep `json:"steps"`
+}
+
+// @Tags     stores
+// @Summary  Simulate setting the stores offline in sequence and report the steps where violations or capacity shortfalls occur. Nothing will be executed.
+// @Accept   json
+// @Param    body  body  StoreOfflineSimulationInput  true  "The offline plan"
+// @Produce  json
+// @Success  200  {object}  cluster.OfflineSimulation
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /stores/offline-simulation [post]
+func (h *storesHandler) SimulateStoreOffline(w http.ResponseWriter, r *http.Request) {
+	var input StoreOfflineSimulationInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	result, err := getCluster(r).SimulateStoreOffline(input.Steps)
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, result)
+}
+
 // @Tags     stores
 // @Summary  Get store progress in the cluster.
 // @Produce  json
@@ -670,9 +1003,13 @@ func (h *storesHandler) GetStoresProgress(w http.ResponseWriter, r *http.Request
 
 // @Tags     store
 // @Summary  Get stores in the cluster.
-// @Param    state  query  array  true  "Specify accepted store states."
+// @Param    state   query  array    true   "Specify accepted store states."
+// @Param    limit   query  integer  false  "The max number of stores in a page, at most 10000, 0 means no limit."
+// @Param    cursor  query  string   false  "The cursor returned by the previous page in the PD-Next-Cursor header."
+// @Param    fields  query  string   false  "The comma separated fields of stores to return, e.g. store.id,status.leader_count."
 // @Produce  json
 // @Success  200  {object}  StoresInfo
+// @Failure  400  {string}  string  "The input is invalid."
 // @Failure  500  {string}  string  "PD server failed to proceed the request."
 // @Router   /stores [get]
 func (h *storesHandler) GetStores(w http.ResponseWriter, r *http.Request) {
@@ -687,9 +1024,28 @@ func (h *storesHandler) GetStores(w http.ResponseWriter, r *http.Request) {
 		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
+	page, ok := parsePage(h.rd, w, r)
+	if !ok {
+		return
+	}
+
+	// The notes are auxiliary, so the stores are still served without them.
+	notes, err := rc.GetAllStoreNotes()
+	if err != nil {
+		log.Warn("failed to load store notes", errs.ZapError(err))
+	}
 
 	stores = urlFilter.filter(rc.GetMetaStores())
-	for _, s := range stores {
+	ids := make([]uint64, len(stores))
+	for i, s := range stores {
+		ids[i] = s.GetId()
+	}
+	start, end, err := page.ApplyIDs(ids, func(i, j int) { stores[i], stores[j] = stores[j], stores[i] })
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	for _, s := range stores[start:end] {
 		storeID := s.GetId()
 		store := rc.GetStore(storeID)
 		if store == nil {
@@ -698,11 +1054,12 @@ func (h *storesHandler) GetStores(w http.ResponseWriter, r *http.Request) {
 		}
 
 		storeInfo := newStoreInfo(h.GetScheduleConfig(), store)
+		storeInfo.Notes = notes[storeID]
 		StoresInfo.Stores = append(StoresInfo.Stores, storeInfo)
 	}
 	StoresInfo.Count = len(StoresInfo.Stores)
 
-	h.rd.JSON(w, http.StatusOK, StoresInfo)
+	renderPage(h.rd, w, page, "stores", StoresInfo.Stores, StoresInfo.Count)
 }
 
 type storeStateFilter struct {
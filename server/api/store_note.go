@@ -0,0 +1,165 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/errcode"
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// StoreNoteInput is the input of the store note APIs.
+type StoreNoteInput struct {
+	Content string `json:"content"`
+	Author  string `json:"author,omitempty"`
+}
+
+// @Tags     store
+// @Summary  Get the notes attached to a store.
+// @Param    id  path  integer  true  "Store Id"
+// @Produce  json
+// @Success  200  {array}   endpoint.StoreNote
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /store/{id}/notes [get]
+func (h *storeHandler) GetStoreNotes(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	notes, err := rc.GetStoreNotes(storeID)
+	if err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, notes)
+}
+
+// @Tags     store
+// @Summary  Attach a note to a store.
+// @Param    id    path  integer         true  "Store Id"
+// @Param    body  body  StoreNoteInput  true  "The note"
+// @Produce  json
+// @Success  200  {object}  endpoint.StoreNote
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The store does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /store/{id}/notes [post]
+func (h *storeHandler) AddStoreNote(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	var input StoreNoteInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+
+	note, err := rc.AddStoreNote(storeID, input.Content, input.Author)
+	if err != nil {
+		h.responseStoreNoteErr(w, err, storeID)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, note)
+}
+
+// @Tags     store
+// @Summary  Update a note of a store.
+// @Param    id       path  integer         true  "Store Id"
+// @Param    note_id  path  integer         true  "Note Id"
+// @Param    body     body  StoreNoteInput  true  "The note"
+// @Produce  json
+// @Success  200  {object}  endpoint.StoreNote
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The note does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /store/{id}/notes/{note_id} [post]
+func (h *storeHandler) UpdateStoreNote(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	noteID, errParse := apiutil.ParseUint64VarsField(vars, "note_id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	var input StoreNoteInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+
+	note, err := rc.UpdateStoreNote(storeID, noteID, input.Content, input.Author)
+	if err != nil {
+		h.responseStoreNoteErr(w, err, storeID)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, note)
+}
+
+// @Tags     store
+// @Summary  Delete a note of a store.
+// @Param    id       path  integer  true  "Store Id"
+// @Param    note_id  path  integer  true  "Note Id"
+// @Produce  json
+// @Success  200  {string}  string  "The note is deleted."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The note does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /store/{id}/notes/{note_id} [delete]
+func (h *storeHandler) DeleteStoreNote(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	vars := mux.Vars(r)
+	storeID, errParse := apiutil.ParseUint64VarsField(vars, "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	noteID, errParse := apiutil.ParseUint64VarsField(vars, "note_id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+
+	if err := rc.DeleteStoreNote(storeID, noteID); err != nil {
+		h.responseStoreNoteErr(w, err, storeID)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, "The note is deleted.")
+}
+
+func (h *storeHandler) responseStoreNoteErr(w http.ResponseWriter, err error, storeID uint64) {
+	switch {
+	case errors.ErrorEqual(err, errs.ErrStoreNotFound.FastGenByArgs(storeID)),
+		errs.ErrStoreNoteNotFound.Equal(err):
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	case errs.ErrStoreNoteContent.Equal(err):
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
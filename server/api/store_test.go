@@ -30,10 +30,13 @@ import (
 	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
+	"github.com/tikv/pd/pkg/apiutil"
 	tu "github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage/endpoint"
 )
 
 type storeTestSuite struct {
@@ -176,6 +179,158 @@ func (suite *storeTestSuite) TestStoreGet() {
 	checkStoresInfo(suite.Require(), []*StoreInfo{info}, suite.stores[:1])
 }
 
+func (suite *storeTestSuite) TestStoreTopology() {
+	url := fmt.Sprintf("%s/store/1", suite.urlPrefix)
+	re := suite.Require()
+	topology := &core.StoreTopology{NUMANodes: 2, DiskCount: 4, DiskType: "nvme"}
+	b, err := json.Marshal(topology)
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url+"/topology", b, tu.StatusOK(re))
+	suite.NoError(err)
+
+	var info StoreInfo
+	err = tu.ReadGetJSON(re, testDialClient, url, &info)
+	suite.NoError(err)
+	suite.Equal(topology, info.Store.Topology)
+
+	// Test invalid topology.
+	b, err = json.Marshal(&core.StoreTopology{NUMANodes: -1})
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url+"/topology", b, tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	// Test unknown store.
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/store/100/topology", suite.urlPrefix), []byte("{}"), tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+}
+
+func (suite *storeTestSuite) TestStoreQuota() {
+	url := fmt.Sprintf("%s/store/1", suite.urlPrefix)
+	re := suite.Require()
+	err := tu.CheckPostJSON(testDialClient, url+"/quota", []byte(`{"leader": 100, "region": 200}`), tu.StatusOK(re))
+	suite.NoError(err)
+
+	var info StoreInfo
+	err = tu.ReadGetJSON(re, testDialClient, url, &info)
+	suite.NoError(err)
+	suite.Equal(uint64(100), info.Status.LeaderQuota)
+	suite.Equal(uint64(200), info.Status.RegionQuota)
+
+	// Test invalid quota.
+	err = tu.CheckPostJSON(testDialClient, url+"/quota", []byte(`{"leader": 100}`), tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url+"/quota", []byte(`{"leader": -1, "region": 0}`), tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	// Test unknown store.
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/store/100/quota", suite.urlPrefix), []byte(`{"leader": 0, "region": 0}`), tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+
+	err = tu.CheckPostJSON(testDialClient, url+"/quota", []byte(`{"leader": 0, "region": 0}`), tu.StatusOK(re))
+	suite.NoError(err)
+}
+
+func (suite *storeTestSuite) TestStoreNotes() {
+	re := suite.Require()
+	url := fmt.Sprintf("%s/store/1/notes", suite.urlPrefix)
+
+	b, err := json.Marshal(&StoreNoteInput{Content: "disk replaced", Author: "ops"})
+	suite.NoError(err)
+	var note endpoint.StoreNote
+	err = tu.CheckPostJSON(testDialClient, url, b, tu.StatusOK(re), tu.ExtractJSON(re, &note))
+	suite.NoError(err)
+	suite.Equal(uint64(1), note.StoreID)
+	suite.Equal("disk replaced", note.Content)
+	suite.Equal("ops", note.Author)
+
+	// Update the note.
+	noteURL := fmt.Sprintf("%s/%d", url, note.ID)
+	b, err = json.Marshal(&StoreNoteInput{Content: "disk replaced 2024-05-01"})
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, noteURL, b, tu.StatusOK(re))
+	suite.NoError(err)
+
+	// The notes are returned inline with the stores API.
+	var info StoreInfo
+	err = tu.ReadGetJSON(re, testDialClient, fmt.Sprintf("%s/store/1", suite.urlPrefix), &info)
+	suite.NoError(err)
+	suite.Len(info.Notes, 1)
+	suite.Equal("disk replaced 2024-05-01", info.Notes[0].Content)
+	suite.Equal("ops", info.Notes[0].Author)
+	var infos StoresInfo
+	err = tu.ReadGetJSON(re, testDialClient, fmt.Sprintf("%s/stores", suite.urlPrefix), &infos)
+	suite.NoError(err)
+	for _, info := range infos.Stores {
+		if info.Store.GetId() == 1 {
+			suite.Len(info.Notes, 1)
+		} else {
+			suite.Empty(info.Notes)
+		}
+	}
+
+	// Test invalid inputs.
+	err = tu.CheckPostJSON(testDialClient, url, []byte(`{"content":""}`), tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/store/100/notes", suite.urlPrefix), b, tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, fmt.Sprintf("%s/%d", url, note.ID+1000), b, tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+
+	// Delete the note.
+	code, err := apiutil.DoDelete(testDialClient, noteURL)
+	suite.NoError(err)
+	suite.Equal(http.StatusOK, code)
+	code, err = apiutil.DoDelete(testDialClient, noteURL)
+	suite.NoError(err)
+	suite.Equal(http.StatusNotFound, code)
+	var notes []*endpoint.StoreNote
+	err = tu.ReadGetJSON(re, testDialClient, url, &notes)
+	suite.NoError(err)
+	suite.Empty(notes)
+}
+
+func (suite *storeTestSuite) TestStoresLabelPatch() {
+	re := suite.Require()
+	url := fmt.Sprintf("%s/stores/labels", suite.urlPrefix)
+	// Dry run should not change the labels of the stores.
+	patch := &StoresLabelPatch{
+		AddressPattern: "^tikv[14]$",
+		Labels:         map[string]string{"disk": "ssd"},
+		DryRun:         true,
+	}
+	b, err := json.Marshal(patch)
+	suite.NoError(err)
+	var result []*StoreLabels
+	err = tu.CheckPostJSON(testDialClient, url, b, tu.StatusOK(re), tu.ExtractJSON(re, &result))
+	suite.NoError(err)
+	suite.Len(result, 2)
+	for i, storeID := range []uint64{1, 4} {
+		suite.Equal(storeID, result[i].StoreID)
+		suite.Equal("ssd", labelValue(result[i].Labels, "disk"))
+		suite.Empty(labelValue(suite.svr.GetRaftCluster().GetStore(storeID).GetLabels(), "disk"))
+	}
+
+	// Test invalid input.
+	for _, patch := range []*StoresLabelPatch{
+		{Labels: map[string]string{"disk": "ssd"}},
+		{AddressPattern: "tikv1"},
+		{AddressPattern: "tikv(", Labels: map[string]string{"disk": "ssd"}},
+		{AddressPattern: "tikv1", Labels: map[string]string{"disk": "ssd*"}},
+	} {
+		b, err = json.Marshal(patch)
+		suite.NoError(err)
+		err = tu.CheckPostJSON(testDialClient, url, b, tu.Status(re, http.StatusBadRequest))
+		suite.NoError(err)
+	}
+}
+
+func labelValue(labels []*metapb.StoreLabel, key string) string {
+	for _, label := range labels {
+		if label.GetKey() == key {
+			return label.GetValue()
+		}
+	}
+	return ""
+}
+
 func (suite *storeTestSuite) TestStoreLabel() {
 	url := fmt.Sprintf("%s/store/1", suite.urlPrefix)
 	re := suite.Require()
@@ -357,6 +512,61 @@ func (suite *storeTestSuite) TestStoreSetState() {
 	suite.SetupSuite()
 }
 
+func (suite *storeTestSuite) TestStoresSetState() {
+	re := suite.Require()
+	for id := 1111; id <= 1115; id++ {
+		mustPutStore(re, suite.svr, uint64(id), metapb.StoreState_Up, metapb.NodeState_Serving, nil)
+	}
+	url := fmt.Sprintf("%s/stores/state", suite.urlPrefix)
+	checkState := func(storeID uint64, state metapb.StoreState) {
+		info := StoreInfo{}
+		err := tu.ReadGetJSON(re, testDialClient, fmt.Sprintf("%s/store/%d", suite.urlPrefix, storeID), &info)
+		suite.NoError(err)
+		suite.Equal(state, info.Store.State)
+	}
+
+	changes := []*cluster.StoreStateChange{
+		{StoreID: 1111, State: "Offline"},
+		{StoreID: 1112, State: "Offline"},
+	}
+	b, err := json.Marshal(changes)
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url, b, tu.StatusOK(re))
+	suite.NoError(err)
+	checkState(1111, metapb.StoreState_Offline)
+	checkState(1112, metapb.StoreState_Offline)
+
+	// nothing is changed if any of the changes is invalid.
+	changes = []*cluster.StoreStateChange{
+		{StoreID: 1111, State: "Up"},
+		{StoreID: 10086, State: "Offline"},
+	}
+	b, err = json.Marshal(changes)
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url, b, tu.Status(re, http.StatusNotFound))
+	suite.NoError(err)
+	changes[1] = &cluster.StoreStateChange{StoreID: 1113, State: "Foo"}
+	b, err = json.Marshal(changes)
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url, b, tu.Status(re, http.StatusBadRequest))
+	suite.NoError(err)
+	checkState(1111, metapb.StoreState_Offline)
+	checkState(1113, metapb.StoreState_Up)
+
+	changes = []*cluster.StoreStateChange{
+		{StoreID: 1111, State: "Up"},
+		{StoreID: 1112, State: "Up"},
+	}
+	b, err = json.Marshal(changes)
+	suite.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, url, b, tu.StatusOK(re))
+	suite.NoError(err)
+	checkState(1111, metapb.StoreState_Up)
+	checkState(1112, metapb.StoreState_Up)
+	suite.cleanup()
+	suite.SetupSuite()
+}
+
 func (suite *storeTestSuite) TestUrlStoreFilter() {
 	testCases := []struct {
 		u    string
@@ -399,6 +609,19 @@ func (suite *storeTestSuite) TestUrlStoreFilter() {
 	suite.Error(err)
 }
 
+func (suite *storeTestSuite) TestStoreDecommissionEstimate() {
+	re := suite.Require()
+	estimate := &cluster.DecommissionEstimate{}
+	url := fmt.Sprintf("%s/store/1/decommission-estimate", suite.urlPrefix)
+	suite.NoError(tu.ReadGetJSON(re, testDialClient, url, estimate))
+	suite.Equal(uint64(1), estimate.StoreID)
+
+	url = fmt.Sprintf("%s/store/100/decommission-estimate", suite.urlPrefix)
+	suite.Equal(http.StatusNotFound, suite.requestStatusBody(testDialClient, http.MethodGet, url))
+	url = fmt.Sprintf("%s/store/7/decommission-estimate", suite.urlPrefix)
+	suite.Equal(http.StatusGone, suite.requestStatusBody(testDialClient, http.MethodGet, url))
+}
+
 func (suite *storeTestSuite) TestDownState() {
 	store := core.NewStoreInfo(
 		&metapb.Store{
@@ -0,0 +1,160 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/errcode"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+// TaskInput is the input to submit a long-running task. The params depend on
+// the type, see cluster.ScatterTaskParams, cluster.StoreDrainTaskParams,
+// cluster.UnsafeRecoveryTaskParams and cluster.RegionMergeTaskParams.
+type TaskInput struct {
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+type taskHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newTaskHandler(svr *server.Server, rd *render.Render) *taskHandler {
+	return &taskHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags     task
+// @Summary  List the long-running tasks.
+// @Produce  json
+// @Success  200  {array}  endpoint.TaskRecord
+// @Router   /tasks [get]
+func (h *taskHandler) GetTasks(w http.ResponseWriter, r *http.Request) {
+	h.rd.JSON(w, http.StatusOK, getCluster(r).GetTasks())
+}
+
+// @Tags     task
+// @Summary  Submit a long-running task, such as scatter, store-drain, cache-rebuild, unsafe-recovery or region-merge.
+// @Param    body  body  TaskInput  true  "The type and the params of the task"
+// @Produce  json
+// @Success  200  {object}  endpoint.TaskRecord
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  409  {string}  string  "The task conflicts with the running actions."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /tasks [post]
+func (h *taskHandler) SubmitTask(w http.ResponseWriter, r *http.Request) {
+	var input TaskInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	task, err := getCluster(r).SubmitTask(input.Type, input.Params)
+	if err != nil {
+		h.responseTaskErr(w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, task)
+}
+
+// @Tags     task
+// @Summary  Inspect a long-running task.
+// @Param    id  path  integer  true  "Task Id"
+// @Produce  json
+// @Success  200  {object}  endpoint.TaskRecord
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The task does not exist."
+// @Router   /tasks/{id} [get]
+func (h *taskHandler) GetTask(w http.ResponseWriter, r *http.Request) {
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	task, err := getCluster(r).GetTask(id)
+	if err != nil {
+		h.responseTaskErr(w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, task)
+}
+
+// @Tags     task
+// @Summary  Cancel a running task.
+// @Param    id  path  integer  true  "Task Id"
+// @Produce  json
+// @Success  200  {object}  endpoint.TaskRecord
+// @Failure  400  {string}  string  "The task can not be cancelled."
+// @Failure  404  {string}  string  "The task does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /tasks/{id}/cancel [post]
+func (h *taskHandler) CancelTask(w http.ResponseWriter, r *http.Request) {
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	task, err := getCluster(r).CancelTask(id)
+	if err != nil {
+		h.responseTaskErr(w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, task)
+}
+
+// @Tags     task
+// @Summary  Retry a failed or cancelled task with the same params.
+// @Param    id  path  integer  true  "Task Id"
+// @Produce  json
+// @Success  200  {object}  endpoint.TaskRecord
+// @Failure  400  {string}  string  "The task can not be retried."
+// @Failure  404  {string}  string  "The task does not exist."
+// @Failure  409  {string}  string  "The task conflicts with the running actions."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /tasks/{id}/retry [post]
+func (h *taskHandler) RetryTask(w http.ResponseWriter, r *http.Request) {
+	id, errParse := apiutil.ParseUint64VarsField(mux.Vars(r), "id")
+	if errParse != nil {
+		apiutil.ErrorResp(h.rd, w, errcode.NewInvalidInputErr(errParse))
+		return
+	}
+	task, err := getCluster(r).RetryTask(id)
+	if err != nil {
+		h.responseTaskErr(w, err)
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, task)
+}
+
+func (h *taskHandler) responseTaskErr(w http.ResponseWriter, err error) {
+	switch {
+	case errs.ErrTaskNotFound.Equal(err):
+		h.rd.JSON(w, http.StatusNotFound, err.Error())
+	case errs.ErrTaskInvalid.Equal(err), errs.ErrTaskState.Equal(err), errs.ErrRegionNotMergeable.Equal(err):
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+	case errs.ErrTaskConflict.Equal(err):
+		h.rd.JSON(w, http.StatusConflict, err.Error())
+	default:
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+	}
+}
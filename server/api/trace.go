@@ -0,0 +1,61 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tikv/pd/pkg/trace"
+	"github.com/unrolled/render"
+)
+
+type traceHandler struct {
+	rd *render.Render
+}
+
+func newTraceHandler(rd *render.Render) *traceHandler {
+	return &traceHandler{
+		rd: rd,
+	}
+}
+
+// @Tags     trace
+// @Summary  Get the recorded spans of a trace, e.g. the trace of an operator.
+// @Param    trace_id  path  string  true  "The hex trace ID"
+// @Produce  json
+// @Success  200  {array}   trace.SpanData
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The trace is not found."
+// @Failure  501  {string}  string  "The traces are not kept in memory."
+// @Router   /trace/{trace_id} [get]
+func (h *traceHandler) GetTrace(w http.ResponseWriter, r *http.Request) {
+	traceID, err := trace.ParseTraceID(mux.Vars(r)["trace_id"])
+	if err != nil {
+		h.rd.JSON(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	recorder, ok := trace.GetRecorder().(*trace.MemoryRecorder)
+	if !ok {
+		h.rd.JSON(w, http.StatusNotImplemented, "The traces are not kept in memory.")
+		return
+	}
+	spans := recorder.GetTrace(traceID)
+	if len(spans) == 0 {
+		h.rd.JSON(w, http.StatusNotFound, "The trace is not found.")
+		return
+	}
+	h.rd.JSON(w, http.StatusOK, spans)
+}
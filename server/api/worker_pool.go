@@ -0,0 +1,97 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+type workerPoolHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newWorkerPoolHandler(svr *server.Server, rd *render.Render) *workerPoolHandler {
+	return &workerPoolHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// WorkerPoolSizeInput is the input to resize a worker pool.
+type WorkerPoolSizeInput struct {
+	// Size is the number of the workers, 0 means the default size of the pool.
+	Size int `json:"size"`
+}
+
+// @Tags     worker_pool
+// @Summary  Get the status of the worker pools, including their sizes, utilization and queue depth.
+// @Produce  json
+// @Success  200  {array}   workerpool.Status
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /worker-pools [get]
+func (h *workerPoolHandler) GetWorkerPools(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	h.rd.JSON(w, http.StatusOK, rc.GetWorkerPools().Statuses())
+}
+
+// @Tags     worker_pool
+// @Summary  Resize a worker pool at runtime, the size is persisted in the config.
+// @Accept   json
+// @Param    name  path  string               true  "The name of the worker pool"
+// @Param    body  body  WorkerPoolSizeInput  true  "The size of the worker pool"
+// @Produce  json
+// @Success  200  {object}  workerpool.Status
+// @Failure  400  {string}  string  "The input is invalid."
+// @Failure  404  {string}  string  "The worker pool does not exist."
+// @Failure  500  {string}  string  "PD server failed to proceed the request."
+// @Router   /worker-pools/{name} [post]
+func (h *workerPoolHandler) SetWorkerPoolSize(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	name := mux.Vars(r)["name"]
+	pool := rc.GetWorkerPools().Get(name)
+	if pool == nil {
+		h.rd.JSON(w, http.StatusNotFound, "worker pool "+name+" does not exist")
+		return
+	}
+	var input WorkerPoolSizeInput
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &input); err != nil {
+		return
+	}
+	if input.Size < 0 {
+		h.rd.JSON(w, http.StatusBadRequest, "size cannot be negative")
+		return
+	}
+	cfg := h.svr.GetPDServerConfig()
+	if cfg.WorkerPoolSizes == nil {
+		cfg.WorkerPoolSizes = make(map[string]int)
+	}
+	if input.Size == 0 {
+		delete(cfg.WorkerPoolSizes, name)
+	} else {
+		cfg.WorkerPoolSizes[name] = input.Size
+	}
+	if err := h.svr.SetPDServerConfig(*cfg); err != nil {
+		h.rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rc.AdjustWorkerPools()
+	h.rd.JSON(w, http.StatusOK, pool.Status())
+}
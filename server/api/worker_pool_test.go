@@ -0,0 +1,86 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/suite"
+	tu "github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/pkg/workerpool"
+	"github.com/tikv/pd/server"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/statistics"
+)
+
+type workerPoolTestSuite struct {
+	suite.Suite
+	svr       *server.Server
+	cleanup   cleanUpFunc
+	urlPrefix string
+}
+
+func TestWorkerPoolTestSuite(t *testing.T) {
+	suite.Run(t, new(workerPoolTestSuite))
+}
+
+func (suite *workerPoolTestSuite) SetupSuite() {
+	re := suite.Require()
+	suite.svr, suite.cleanup = mustNewServer(re)
+	server.MustWaitLeader(re, []*server.Server{suite.svr})
+
+	addr := suite.svr.GetAddr()
+	suite.urlPrefix = fmt.Sprintf("%s%s/api/v1/worker-pools", addr, apiPrefix)
+
+	mustBootstrapCluster(re, suite.svr)
+}
+
+func (suite *workerPoolTestSuite) TearDownSuite() {
+	suite.cleanup()
+}
+
+func (suite *workerPoolTestSuite) TestWorkerPools() {
+	re := suite.Require()
+	var statuses []workerpool.Status
+	re.NoError(tu.ReadGetJSON(re, testDialClient, suite.urlPrefix, &statuses))
+	re.Len(statuses, 2)
+	re.Equal(cluster.CheckerPoolName, statuses[0].Name)
+	re.Equal(1, statuses[0].Size)
+	re.Equal(statistics.HotStatPoolName, statuses[1].Name)
+	re.Equal(2, statuses[1].Size)
+
+	var status workerpool.Status
+	err := tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/"+cluster.CheckerPoolName, []byte(`{"size":4}`),
+		tu.StatusOK(re), tu.ExtractJSON(re, &status))
+	re.NoError(err)
+	re.Equal(4, status.Size)
+	re.Equal(4, suite.svr.GetPersistOptions().GetWorkerPoolSizes()[cluster.CheckerPoolName])
+
+	// 0 resets the pool to its default size.
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/"+cluster.CheckerPoolName, []byte(`{"size":0}`),
+		tu.StatusOK(re), tu.ExtractJSON(re, &status))
+	re.NoError(err)
+	re.Equal(1, status.Size)
+	re.NotContains(suite.svr.GetPersistOptions().GetWorkerPoolSizes(), cluster.CheckerPoolName)
+
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/"+cluster.CheckerPoolName, []byte(`{"size":-1}`),
+		tu.Status(re, http.StatusBadRequest))
+	re.NoError(err)
+	err = tu.CheckPostJSON(testDialClient, suite.urlPrefix+"/unknown", []byte(`{"size":1}`),
+		tu.Status(re, http.StatusNotFound))
+	re.NoError(err)
+}
@@ -0,0 +1,85 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package api
+
+import (
+	"net/http"
+
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server"
+	"github.com/unrolled/render"
+)
+
+// ZoneLatencyReport is a round-trip time between two zones measured by a store
+// or a probe.
+type ZoneLatencyReport struct {
+	ZoneA string            `json:"zone_a"`
+	ZoneB string            `json:"zone_b"`
+	RTT   typeutil.Duration `json:"rtt"`
+}
+
+type zoneLatencyHandler struct {
+	svr *server.Server
+	rd  *render.Render
+}
+
+func newZoneLatencyHandler(svr *server.Server, rd *render.Render) *zoneLatencyHandler {
+	return &zoneLatencyHandler{
+		svr: svr,
+		rd:  rd,
+	}
+}
+
+// @Tags     zone
+// @Summary  Get the matrix of the round-trip times between the zones.
+// @Produce  json
+// @Success  200  {array}  statistics.ZoneLatency
+// @Router   /zones/latency [get]
+func (h *zoneLatencyHandler) GetZoneLatencies(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	h.rd.JSON(w, http.StatusOK, rc.GetZoneLatencies().GetZoneLatencyMatrix())
+}
+
+// @Tags     zone
+// @Summary  Report the round-trip times between the zones.
+// @Accept   json
+// @Param    body  body  []ZoneLatencyReport  true  "The round-trip times"
+// @Produce  json
+// @Success  200  {string}  string  "The latencies are reported."
+// @Failure  400  {string}  string  "The input is invalid."
+// @Router   /zones/latency [post]
+func (h *zoneLatencyHandler) ReportZoneLatencies(w http.ResponseWriter, r *http.Request) {
+	rc := getCluster(r)
+	var reports []ZoneLatencyReport
+	if err := apiutil.ReadJSONRespondError(h.rd, w, r.Body, &reports); err != nil {
+		return
+	}
+	for _, report := range reports {
+		if report.ZoneA == "" || report.ZoneB == "" {
+			h.rd.JSON(w, http.StatusBadRequest, "zone should not be empty")
+			return
+		}
+		if report.RTT.Duration < 0 {
+			h.rd.JSON(w, http.StatusBadRequest, "rtt should not be negative")
+			return
+		}
+	}
+	latencies := rc.GetZoneLatencies()
+	for _, report := range reports {
+		latencies.ObserveZoneLatency(report.ZoneA, report.ZoneB, report.RTT.Duration)
+	}
+	h.rd.JSON(w, http.StatusOK, "The latencies are reported.")
+}
@@ -0,0 +1,207 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/storage"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"go.etcd.io/etcd/clientv3"
+	"go.uber.org/zap"
+)
+
+// BootstrapBundle is the topology policy staged before the cluster is
+// bootstrapped. It is validated against the store which bootstraps the
+// cluster and applied in the bootstrap transaction, so the cluster follows
+// the policy from the first region on.
+type BootstrapBundle struct {
+	// Rules replaces the default placement rule and enables the placement
+	// rules if it is not empty.
+	Rules          []*placement.Rule    `json:"rules,omitempty"`
+	LocationLabels typeutil.StringSlice `json:"location-labels"`
+	IsolationLevel string               `json:"isolation-level"`
+	// StrictlyMatchLabel requires every store, including the one which
+	// bootstraps the cluster, to be labeled with exactly the location labels.
+	StrictlyMatchLabel bool `json:"strictly-match-label"`
+	// Schedule replaces the schedule config if it is not nil.
+	Schedule *config.ScheduleConfig `json:"schedule,omitempty"`
+	StagedAt time.Time              `json:"staged-at"`
+}
+
+// StageBootstrapBundle validates the bootstrap bundle and stages it in place
+// of the staged one.
+func (s *Server) StageBootstrapBundle(bundle *BootstrapBundle) error {
+	bootstrapped, err := s.storage.LoadMeta(&metapb.Cluster{})
+	if err != nil {
+		return err
+	}
+	if bootstrapped {
+		return errs.ErrAlreadyBootstrapped.FastGenByArgs()
+	}
+	if _, _, err := s.validateBootstrapBundle(bundle); err != nil {
+		return err
+	}
+	bundle.StagedAt = time.Now()
+	if err := s.storage.SaveBootstrapBundle(bundle); err != nil {
+		return err
+	}
+	log.Info("bootstrap bundle is staged", zap.Reflect("bundle", bundle))
+	return nil
+}
+
+// GetBootstrapBundle returns the staged bootstrap bundle.
+func (s *Server) GetBootstrapBundle() (*BootstrapBundle, error) {
+	bundle := &BootstrapBundle{}
+	ok, err := s.storage.LoadBootstrapBundle(bundle)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, errs.ErrBootstrapBundleNotFound.FastGenByArgs()
+	}
+	return bundle, nil
+}
+
+// DeleteBootstrapBundle removes the staged bootstrap bundle.
+func (s *Server) DeleteBootstrapBundle() error {
+	if _, err := s.GetBootstrapBundle(); err != nil {
+		return err
+	}
+	return s.storage.RemoveBootstrapBundle()
+}
+
+// validateBootstrapBundle applies the bootstrap bundle to the current config
+// and validates the result, which is returned without being set.
+func (s *Server) validateBootstrapBundle(bundle *BootstrapBundle) (*config.ScheduleConfig, *config.ReplicationConfig, error) {
+	replication := s.persistOptions.GetReplicationConfig().Clone()
+	replication.LocationLabels = bundle.LocationLabels
+	replication.IsolationLevel = bundle.IsolationLevel
+	replication.StrictlyMatchLabel = bundle.StrictlyMatchLabel
+	if len(bundle.Rules) > 0 {
+		replication.EnablePlacementRules = true
+	}
+	if err := replication.Validate(); err != nil {
+		return nil, nil, errs.ErrBootstrapBundleInvalid.FastGenByArgs(err.Error())
+	}
+
+	schedule := s.persistOptions.GetScheduleConfig().Clone()
+	if bundle.Schedule != nil {
+		schedule = bundle.Schedule.Clone()
+		if err := schedule.Validate(); err != nil {
+			return nil, nil, errs.ErrBootstrapBundleInvalid.FastGenByArgs(err.Error())
+		}
+		if err := schedule.Deprecated(); err != nil {
+			return nil, nil, errs.ErrBootstrapBundleInvalid.FastGenByArgs(err.Error())
+		}
+		schedule.SchedulersPayload = nil
+	}
+
+	if len(bundle.Rules) > 0 {
+		// The rules are checked by a rule manager without stores, whose default
+		// rule is replaced by the rules just like the one of the cluster will be.
+		m := placement.NewRuleManager(storage.NewStorageWithMemoryBackend(), nil, nil).
+			SetKeyType(s.persistOptions.GetPDServerConfig().KeyType)
+		if err := m.Initialize(int(replication.MaxReplicas), replication.LocationLabels); err != nil {
+			return nil, nil, err
+		}
+		ops := []placement.RuleOp{{Rule: &placement.Rule{GroupID: "pd", ID: "default"}, Action: placement.RuleOpDel}}
+		for _, rule := range bundle.Rules {
+			ops = append(ops, placement.RuleOp{Rule: rule.Clone(), Action: placement.RuleOpAdd})
+		}
+		if err := m.Batch(ops); err != nil {
+			return nil, nil, errs.ErrBootstrapBundleInvalid.FastGenByArgs(err.Error())
+		}
+	}
+	return schedule, replication, nil
+}
+
+// bootstrapBundleOps validates the staged bootstrap bundle against the store
+// which bootstraps the cluster. It returns the operations to apply the bundle
+// in the bootstrap transaction, and the function to set the config after the
+// transaction succeeds. Both are nil if no bundle is staged.
+func (s *Server) bootstrapBundleOps(store *metapb.Store) ([]clientv3.Op, func(), error) {
+	bundle := &BootstrapBundle{}
+	ok, err := s.storage.LoadBootstrapBundle(bundle)
+	if err != nil || !ok {
+		return nil, nil, err
+	}
+	schedule, replication, err := s.validateBootstrapBundle(bundle)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := checkBootstrapStoreLabels(store, replication); err != nil {
+		return nil, nil, err
+	}
+
+	cfg := &config.Config{
+		Schedule:        *schedule,
+		Replication:     *replication,
+		PDServerCfg:     *s.persistOptions.GetPDServerConfig(),
+		ReplicationMode: *s.persistOptions.GetReplicationModeConfig(),
+		LabelProperty:   s.persistOptions.GetLabelPropertyConfig(),
+		ClusterVersion:  *s.persistOptions.GetClusterVersion(),
+	}
+	value, err := json.Marshal(cfg)
+	if err != nil {
+		return nil, nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	ops := []clientv3.Op{
+		clientv3.OpPut(endpoint.AppendToRootPath(s.rootPath, endpoint.ConfigPath()), string(value)),
+		clientv3.OpDelete(endpoint.AppendToRootPath(s.rootPath, endpoint.BootstrapBundlePath())),
+	}
+	for _, rule := range bundle.Rules {
+		value, err := json.Marshal(rule)
+		if err != nil {
+			return nil, nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+		}
+		ops = append(ops, clientv3.OpPut(endpoint.AppendToRootPath(s.rootPath, endpoint.RulePath(rule.StoreKey())), string(value)))
+	}
+	return ops, func() {
+		s.persistOptions.SetScheduleConfig(schedule)
+		s.persistOptions.SetReplicationConfig(replication)
+		log.Info("bootstrap bundle is applied", zap.Reflect("bundle", bundle))
+	}, nil
+}
+
+// checkBootstrapStoreLabels checks the labels of the store which bootstraps
+// the cluster like the labels of the other stores will be checked.
+func checkBootstrapStoreLabels(store *metapb.Store, replication *config.ReplicationConfig) error {
+	if !replication.StrictlyMatchLabel {
+		return nil
+	}
+	labels := make(map[string]string, len(store.GetLabels()))
+	for _, label := range store.GetLabels() {
+		labels[label.GetKey()] = label.GetValue()
+	}
+	for _, key := range replication.LocationLabels {
+		if len(labels[key]) == 0 {
+			return errs.ErrBootstrapBundleInvalid.FastGenByArgs(fmt.Sprintf("store %d does not have the location label %s", store.GetId(), key))
+		}
+		delete(labels, key)
+	}
+	for key := range labels {
+		return errs.ErrBootstrapBundleInvalid.FastGenByArgs(fmt.Sprintf("store %d has the label %s which is not a location label", store.GetId(), key))
+	}
+	return nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestBootstrapBundle(t *testing.T) {
+	re := require.New(t)
+	s := &Server{
+		storage:        storage.NewStorageWithMemoryBackend(),
+		persistOptions: config.NewTestOptions(),
+		rootPath:       "/pd/0",
+	}
+	_, err := s.GetBootstrapBundle()
+	re.True(errs.ErrBootstrapBundleNotFound.Equal(err))
+	ops, apply, err := s.bootstrapBundleOps(&metapb.Store{Id: 1})
+	re.NoError(err)
+	re.Empty(ops)
+	re.Nil(apply)
+
+	// The isolation level is not a location label.
+	err = s.StageBootstrapBundle(&BootstrapBundle{LocationLabels: []string{"zone"}, IsolationLevel: "host"})
+	re.True(errs.ErrBootstrapBundleInvalid.Equal(err))
+	// The rule has no peer.
+	err = s.StageBootstrapBundle(&BootstrapBundle{Rules: []*placement.Rule{{GroupID: "pd", ID: "voters", Role: placement.Voter}}})
+	re.True(errs.ErrBootstrapBundleInvalid.Equal(err))
+	// The rules do not cover the whole key space.
+	err = s.StageBootstrapBundle(&BootstrapBundle{Rules: []*placement.Rule{{GroupID: "pd", ID: "voters", Role: placement.Voter, Count: 3, EndKeyHex: "aa"}}})
+	re.True(errs.ErrBootstrapBundleInvalid.Equal(err))
+	schedule := s.GetScheduleConfig()
+	schedule.TolerantSizeRatio = -1
+	err = s.StageBootstrapBundle(&BootstrapBundle{Schedule: schedule})
+	re.True(errs.ErrBootstrapBundleInvalid.Equal(err))
+	_, err = s.GetBootstrapBundle()
+	re.True(errs.ErrBootstrapBundleNotFound.Equal(err))
+
+	schedule = s.GetScheduleConfig()
+	schedule.LeaderScheduleLimit = 8
+	re.NoError(s.StageBootstrapBundle(&BootstrapBundle{
+		Rules: []*placement.Rule{{
+			GroupID:        "pd",
+			ID:             "voters",
+			Role:           placement.Voter,
+			Count:          3,
+			LocationLabels: []string{"zone", "host"},
+		}},
+		LocationLabels:     []string{"zone", "host"},
+		IsolationLevel:     "zone",
+		StrictlyMatchLabel: true,
+		Schedule:           schedule,
+	}))
+	bundle, err := s.GetBootstrapBundle()
+	re.NoError(err)
+	re.Len(bundle.Rules, 1)
+	re.Equal("zone", bundle.IsolationLevel)
+	re.False(bundle.StagedAt.IsZero())
+
+	// The store which bootstraps the cluster should match the location labels.
+	_, _, err = s.bootstrapBundleOps(&metapb.Store{Id: 1, Labels: []*metapb.StoreLabel{{Key: "zone", Value: "z1"}}})
+	re.True(errs.ErrBootstrapBundleInvalid.Equal(err))
+	_, _, err = s.bootstrapBundleOps(&metapb.Store{Id: 1, Labels: []*metapb.StoreLabel{
+		{Key: "zone", Value: "z1"}, {Key: "host", Value: "h1"}, {Key: "disk", Value: "ssd"},
+	}})
+	re.True(errs.ErrBootstrapBundleInvalid.Equal(err))
+	ops, apply, err = s.bootstrapBundleOps(&metapb.Store{Id: 1, Labels: []*metapb.StoreLabel{
+		{Key: "zone", Value: "z1"}, {Key: "host", Value: "h1"},
+	}})
+	re.NoError(err)
+	// The config, the removal of the bundle and the rule.
+	re.Len(ops, 3)
+	re.False(s.persistOptions.GetStrictlyMatchLabel())
+	apply()
+	re.True(s.persistOptions.IsPlacementRulesEnabled())
+	re.True(s.persistOptions.GetStrictlyMatchLabel())
+	re.Equal([]string{"zone", "host"}, s.persistOptions.GetLocationLabels())
+	re.Equal("zone", s.persistOptions.GetIsolationLevel())
+	re.Equal(uint64(8), s.persistOptions.GetLeaderScheduleLimit())
+
+	re.NoError(s.DeleteBootstrapBundle())
+	re.True(errs.ErrBootstrapBundleNotFound.Equal(s.DeleteBootstrapBundle()))
+
+	// The bundle cannot be staged after the cluster is bootstrapped.
+	re.NoError(s.storage.SaveMeta(&metapb.Cluster{Id: 1}))
+	err = s.StageBootstrapBundle(&BootstrapBundle{})
+	re.True(errs.ErrAlreadyBootstrapped.Equal(err))
+}
@@ -0,0 +1,279 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/audit"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/schedule"
+	"go.uber.org/zap"
+)
+
+const (
+	// BreakGlassRemoveStore allows removing a store even if the remaining up
+	// stores are not enough for the replicas.
+	BreakGlassRemoveStore = "remove-store"
+	// MaxBreakGlassTTL is the max time the break-glass mode can last.
+	MaxBreakGlassTTL = time.Hour
+	// maxBreakGlassActions is the max number of the actions kept in the mode.
+	maxBreakGlassActions = 256
+)
+
+// breakGlassChecks are the safety checks which can be loosened.
+var breakGlassChecks = map[string]struct{}{
+	BreakGlassRemoveStore:               {},
+	schedule.BreakGlassFrozenReplica:    {},
+	schedule.BreakGlassScheduleDisabled: {},
+	schedule.BreakGlassPausedScheduler:  {},
+}
+
+// BreakGlassAction is an action taken by bypassing a safety check in the
+// break-glass mode.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type BreakGlassAction struct {
+	Time   time.Time `json:"time"`
+	Check  string    `json:"check"`
+	Target string    `json:"target"`
+	// Cause is the reason the check would fail without the mode.
+	Cause string `json:"cause,omitempty"`
+}
+
+// BreakGlassMode loosens the selected safety checks until it expires.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type BreakGlassMode struct {
+	Checks    []string            `json:"checks"`
+	Reason    string              `json:"reason"`
+	StartTime time.Time           `json:"start_time"`
+	ExpireAt  time.Time           `json:"expire_at"`
+	Actions   []*BreakGlassAction `json:"actions"`
+	// Truncated is true if the earliest actions are not kept.
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+func (m *BreakGlassMode) allows(check string) bool {
+	for _, c := range m.Checks {
+		if c == check {
+			return true
+		}
+	}
+	return false
+}
+
+// breakGlass keeps the break-glass mode in memory, so the mode ends once the
+// leader changes, which is the safe direction.
+type breakGlass struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	mode    *BreakGlassMode
+	// auditBackends record the actions taken in the mode.
+	auditBackends []audit.Backend
+}
+
+func newBreakGlass(cluster *RaftCluster) *breakGlass {
+	return &breakGlass{cluster: cluster}
+}
+
+func (b *breakGlass) enable(checks []string, reason string, ttl time.Duration, now time.Time) (*BreakGlassMode, error) {
+	if len(checks) == 0 {
+		return nil, errs.ErrBreakGlassInvalid.FastGenByArgs("no check is selected")
+	}
+	for _, check := range checks {
+		if _, ok := breakGlassChecks[check]; !ok {
+			return nil, errs.ErrBreakGlassInvalid.FastGenByArgs("unknown check " + check)
+		}
+	}
+	if len(reason) == 0 {
+		return nil, errs.ErrBreakGlassInvalid.FastGenByArgs("the reason is required")
+	}
+	if ttl <= 0 || ttl > MaxBreakGlassTTL {
+		return nil, errs.ErrBreakGlassInvalid.FastGenByArgs("the ttl should be in (0, " + MaxBreakGlassTTL.String() + "]")
+	}
+	b.Lock()
+	defer b.Unlock()
+	b.checkLocked(now)
+	if b.mode != nil {
+		return nil, errs.ErrBreakGlassEnabled.FastGenByArgs(b.mode.ExpireAt.Format(time.RFC3339))
+	}
+	b.mode = &BreakGlassMode{
+		Checks:    append([]string(nil), checks...),
+		Reason:    reason,
+		StartTime: now,
+		ExpireAt:  now.Add(ttl),
+		Actions:   make([]*BreakGlassAction, 0),
+	}
+	log.Warn("break-glass mode is enabled",
+		zap.Strings("checks", checks),
+		zap.String("reason", reason),
+		zap.Time("expire-at", b.mode.ExpireAt))
+	b.cluster.events.publish(&ClusterEvent{
+		Type:    EventBreakGlassEnabled,
+		Message: "the safety checks " + strings.Join(checks, ", ") + " are loosened until " + b.mode.ExpireAt.Format(time.RFC3339) + ", " + reason,
+		Attributes: map[string]string{
+			"checks":    strings.Join(checks, ","),
+			"reason":    reason,
+			"expire-at": b.mode.ExpireAt.Format(time.RFC3339),
+		},
+	})
+	return b.copyLocked(), nil
+}
+
+func (b *breakGlass) disable() error {
+	b.Lock()
+	defer b.Unlock()
+	b.checkLocked(time.Now())
+	if b.mode == nil {
+		return errs.ErrBreakGlassNotEnabled.FastGenByArgs()
+	}
+	b.revertLocked("disabled")
+	return nil
+}
+
+// check reverts the mode if it is expired.
+func (b *breakGlass) check(now time.Time) {
+	b.Lock()
+	defer b.Unlock()
+	b.checkLocked(now)
+}
+
+func (b *breakGlass) checkLocked(now time.Time) {
+	if b.mode != nil && !now.Before(b.mode.ExpireAt) {
+		b.revertLocked("expired")
+	}
+}
+
+func (b *breakGlass) revertLocked(cause string) {
+	mode := b.mode
+	b.mode = nil
+	log.Warn("break-glass mode is reverted",
+		zap.String("cause", cause),
+		zap.Strings("checks", mode.Checks),
+		zap.Int("actions", len(mode.Actions)))
+	b.cluster.events.publish(&ClusterEvent{
+		Type:    EventBreakGlassReverted,
+		Message: "the safety checks " + strings.Join(mode.Checks, ", ") + " are restored since the break-glass mode is " + cause,
+		Attributes: map[string]string{
+			"checks":  strings.Join(mode.Checks, ","),
+			"cause":   cause,
+			"actions": strconv.Itoa(len(mode.Actions)),
+		},
+	})
+}
+
+// allows returns true if the check is loosened. It does not record anything,
+// the caller should record the action once it is actually taken.
+func (b *breakGlass) allows(check string) bool {
+	b.Lock()
+	defer b.Unlock()
+	b.checkLocked(time.Now())
+	return b.mode != nil && b.mode.allows(check)
+}
+
+// record records the action taken by bypassing the check in the mode, and sends
+// it to the audit backends. The action is still audited if the mode has ended
+// since it was allowed.
+func (b *breakGlass) record(check, target, cause string) {
+	now := time.Now()
+	b.Lock()
+	b.checkLocked(now)
+	var reason string
+	if b.mode != nil {
+		reason = b.mode.Reason
+		if len(b.mode.Actions) >= maxBreakGlassActions {
+			b.mode.Actions = b.mode.Actions[1:]
+			b.mode.Truncated = true
+		}
+		b.mode.Actions = append(b.mode.Actions, &BreakGlassAction{Time: now, Check: check, Target: target, Cause: cause})
+	}
+	backends := b.auditBackends
+	b.Unlock()
+
+	breakGlassActionCounter.WithLabelValues(check).Inc()
+	attributes := map[string]string{
+		"check":  check,
+		"target": target,
+		"cause":  cause,
+		"reason": reason,
+	}
+	b.cluster.events.publish(&ClusterEvent{
+		Type:       EventBreakGlassAction,
+		Message:    "the safety check " + check + " is bypassed for " + target,
+		Attributes: attributes,
+	})
+	event := &audit.Event{
+		Time:       now,
+		Component:  "break-glass",
+		Action:     "bypass-" + check,
+		Target:     target,
+		Attributes: attributes,
+	}
+	for _, backend := range backends {
+		if backend, ok := backend.(audit.EventBackend); ok {
+			backend.ProcessEvent(event)
+		}
+	}
+}
+
+func (b *breakGlass) get() *BreakGlassMode {
+	b.Lock()
+	defer b.Unlock()
+	b.checkLocked(time.Now())
+	if b.mode == nil {
+		return nil
+	}
+	return b.copyLocked()
+}
+
+func (b *breakGlass) copyLocked() *BreakGlassMode {
+	mode := *b.mode
+	mode.Checks = append([]string(nil), b.mode.Checks...)
+	mode.Actions = append(make([]*BreakGlassAction, 0, len(b.mode.Actions)), b.mode.Actions...)
+	return &mode
+}
+
+// EnableBreakGlass loosens the selected safety checks for the ttl. Every action
+// taken by bypassing the checks is recorded as a cluster event and sent to the
+// audit backends, and the checks are restored automatically once the ttl ends.
+func (c *RaftCluster) EnableBreakGlass(checks []string, reason string, ttl time.Duration) (*BreakGlassMode, error) {
+	return c.breakGlass.enable(checks, reason, ttl, time.Now())
+}
+
+// DisableBreakGlass restores the safety checks before the break-glass mode expires.
+func (c *RaftCluster) DisableBreakGlass() error {
+	return c.breakGlass.disable()
+}
+
+// GetBreakGlassMode returns the current break-glass mode with the actions taken,
+// or nil if it is not enabled.
+func (c *RaftCluster) GetBreakGlassMode() *BreakGlassMode {
+	return c.breakGlass.get()
+}
+
+// AllowBreakGlass returns true if the safety check is loosened by the
+// break-glass mode.
+func (c *RaftCluster) AllowBreakGlass(check string) bool {
+	return c.breakGlass.allows(check)
+}
+
+// RecordBreakGlassAction records the action taken on the target by bypassing
+// the safety check.
+func (c *RaftCluster) RecordBreakGlassAction(check, target, cause string) {
+	c.breakGlass.record(check, target, cause)
+}
@@ -0,0 +1,141 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/audit"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedulers"
+	"github.com/tikv/pd/server/storage"
+)
+
+// mockEventBackend keeps the audited events.
+type mockEventBackend struct {
+	audit.Backend
+	events []*audit.Event
+}
+
+func (b *mockEventBackend) ProcessEvent(event *audit.Event) bool {
+	b.events = append(b.events, event)
+	return true
+}
+
+func TestBreakGlass(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	backend := &mockEventBackend{}
+	cluster.breakGlass.auditBackends = []audit.Backend{audit.NewPrometheusHistogramBackend(nil, false), backend}
+	for _, store := range newTestStores(3, "2.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	// The replicas are not enough without the store.
+	re.True(errs.ErrStoresNotEnough.Equal(cluster.RemoveStore(1, false)))
+
+	for _, c := range []struct {
+		checks []string
+		reason string
+		ttl    time.Duration
+	}{
+		{nil, "recovery", time.Minute},
+		{[]string{"unknown"}, "recovery", time.Minute},
+		{[]string{BreakGlassRemoveStore}, "", time.Minute},
+		{[]string{BreakGlassRemoveStore}, "recovery", 0},
+		{[]string{BreakGlassRemoveStore}, "recovery", MaxBreakGlassTTL + time.Second},
+	} {
+		_, err := cluster.EnableBreakGlass(c.checks, c.reason, c.ttl)
+		re.True(errs.ErrBreakGlassInvalid.Equal(err))
+	}
+	re.Nil(cluster.GetBreakGlassMode())
+	re.True(errs.ErrBreakGlassNotEnabled.Equal(cluster.DisableBreakGlass()))
+
+	mode, err := cluster.EnableBreakGlass([]string{BreakGlassRemoveStore, schedule.BreakGlassFrozenReplica}, "recovery", time.Minute)
+	re.NoError(err)
+	re.Empty(mode.Actions)
+	_, err = cluster.EnableBreakGlass([]string{BreakGlassRemoveStore}, "recovery", time.Minute)
+	re.True(errs.ErrBreakGlassEnabled.Equal(err))
+
+	// The store can be removed, and the action is recorded.
+	re.NoError(cluster.RemoveStore(1, false))
+	re.True(cluster.GetStore(1).IsRemoving())
+	re.False(cluster.AllowBreakGlass(schedule.BreakGlassScheduleDisabled))
+	re.True(cluster.AllowBreakGlass(schedule.BreakGlassFrozenReplica))
+	// Only the actions actually taken are recorded.
+	re.Len(cluster.GetBreakGlassMode().Actions, 1)
+	cluster.RecordBreakGlassAction(schedule.BreakGlassFrozenReplica, "operator", "replica frozen")
+	mode = cluster.GetBreakGlassMode()
+	re.Len(mode.Actions, 2)
+	re.Equal(BreakGlassRemoveStore, mode.Actions[0].Check)
+	re.Equal("store 1", mode.Actions[0].Target)
+	re.Equal(schedule.BreakGlassFrozenReplica, mode.Actions[1].Check)
+	// The actions are audited.
+	re.Len(backend.events, 2)
+	re.Equal("store 1", backend.events[0].Target)
+	re.Equal("bypass-"+BreakGlassRemoveStore, backend.events[0].Action)
+	re.Equal("recovery", backend.events[1].Attributes["reason"])
+
+	// The mode is reverted once it expires.
+	cluster.breakGlass.check(mode.ExpireAt)
+	re.Nil(cluster.GetBreakGlassMode())
+	re.False(cluster.AllowBreakGlass(schedule.BreakGlassFrozenReplica))
+	var types []string
+	for _, event := range cluster.GetClusterEvents(0) {
+		types = append(types, event.Type)
+	}
+	re.Equal([]string{EventBreakGlassEnabled, EventBreakGlassAction, EventBreakGlassAction, EventBreakGlassReverted}, types)
+	re.Equal("expired", cluster.GetClusterEvents(0)[3].Attributes["cause"])
+
+	// The mode can be disabled in advance.
+	_, err = cluster.EnableBreakGlass([]string{BreakGlassRemoveStore}, "recovery", time.Minute)
+	re.NoError(err)
+	re.NoError(cluster.DisableBreakGlass())
+	re.Nil(cluster.GetBreakGlassMode())
+	re.True(errs.ErrStoresNotEnough.Equal(cluster.RemoveStore(2, false)))
+}
+
+func TestBreakGlassPausedScheduler(t *testing.T) {
+	re := require.New(t)
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	defer cleanup()
+	re.NoError(tc.addLeaderRegion(1, 1))
+
+	scheduler, err := schedule.CreateScheduler(schedulers.BalanceLeaderType, co.opController, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(schedulers.BalanceLeaderType, []string{"", ""}))
+	re.NoError(err)
+	sc := newScheduleController(co, scheduler)
+	re.True(sc.AllowSchedule())
+	atomic.StoreInt64(&sc.delayUntil, time.Now().Add(time.Minute).Unix())
+	re.False(sc.AllowSchedule())
+
+	// The paused scheduler can schedule in the mode.
+	_, err = tc.EnableBreakGlass([]string{schedule.BreakGlassPausedScheduler}, "recovery", time.Minute)
+	re.NoError(err)
+	re.True(sc.AllowSchedule())
+	re.Empty(tc.GetBreakGlassMode().Actions)
+	re.NoError(tc.DisableBreakGlass())
+	re.False(sc.AllowSchedule())
+}
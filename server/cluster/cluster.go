@@ -16,12 +16,14 @@ package cluster
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"math"
 	"net/http"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/coreos/go-semver/semver"
@@ -31,23 +33,30 @@ import (
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/audit"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/keyutil"
 	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/pkg/metricutil"
 	"github.com/tikv/pd/pkg/netutil"
 	"github.com/tikv/pd/pkg/progress"
+	"github.com/tikv/pd/pkg/retryutil"
 	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/pkg/workerpool"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
 	"github.com/tikv/pd/server/id"
+	"github.com/tikv/pd/server/keyvisual"
 	syncer "github.com/tikv/pd/server/region_syncer"
 	"github.com/tikv/pd/server/replication"
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/schedule/checker"
 	"github.com/tikv/pd/server/schedule/hbstream"
 	"github.com/tikv/pd/server/schedule/labeler"
+	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/schedulers"
 	"github.com/tikv/pd/server/statistics"
@@ -74,12 +83,24 @@ const (
 	metricsCollectionJobInterval = 10 * time.Second
 	clientTimeout                = 3 * time.Second
 	defaultChangedRegionsLimit   = 10000
-	// persistLimitRetryTimes is used to reduce the probability of the persistent error
-	// since the once the store is add or remove, we shouldn't return an error even if the store limit is failed to persist.
-	persistLimitRetryTimes = 5
-	persistLimitWaitTime   = 100 * time.Millisecond
-	removingAction         = "removing"
-	preparingAction        = "preparing"
+	removingAction               = "removing"
+	preparingAction              = "preparing"
+)
+
+const (
+	// hotPeerSnapshotInterval is the interval to persist the snapshots of the hot peer cache.
+	hotPeerSnapshotInterval = time.Minute
+	// hotPeerSnapshotMaxStaleness is the max age of the hot peer snapshot which can be restored.
+	hotPeerSnapshotMaxStaleness = 5 * time.Minute
+)
+
+const (
+	// loadingRegionsProgress is the progress key of loading regions from the storage.
+	loadingRegionsProgress = "loading-regions"
+	// loadingRegionsProgressInterval is the interval to update the progress of loading regions.
+	loadingRegionsProgressInterval = time.Second
+	// progressSnapshotInterval is the interval to persist the snapshot of the store progresses.
+	progressSnapshotInterval = time.Minute
 )
 
 // Server is the interface for cluster.
@@ -120,6 +141,28 @@ type RaftCluster struct {
 	minResolvedTS      uint64
 	// Keep the previous store limit settings when removing a store.
 	prevStoreLimit map[uint64]map[storelimit.Type]float64
+	// storeNotes caches the notes of the stores grouped by the store ID. It is
+	// loaded from the storage at the first access, and nil means not loaded.
+	storeNotes struct {
+		syncutil.Mutex
+		notes map[uint64][]*endpoint.StoreNote
+	}
+	// optionsDirty and optionsRetrying coalesce the background retries of
+	// persisting the options, see retryPersistOptions.
+	optionsDirty    int32
+	optionsRetrying int32
+	// preparingDetails are the details of the preparing stores computed by the
+	// last store check.
+	preparingDetails struct {
+		syncutil.RWMutex
+		stores map[uint64]*StorePreparingDetail
+	}
+	// overQuotaStores records the stores exceeding their soft quotas found by
+	// the last metrics collection. It is only accessed by the metrics collection job.
+	overQuotaStores map[uint64]storeQuotaStatus
+	// hotPeerSnapshotChunks is the number of the persisted chunks of the hot peer
+	// snapshots by their kinds, the ones beyond the latest snapshot are deleted.
+	hotPeerSnapshotChunks map[statistics.RWType]int
 
 	// This below fields are all read-only, we cannot update itself after the raft cluster starts.
 	clusterID                uint64
@@ -129,16 +172,58 @@ type RaftCluster struct {
 	limiter                  *StoreLimiter
 	coordinator              *coordinator
 	labelLevelStats          *statistics.LabelStatistics
+	regionLabelStats         *statistics.RegionLabelStatistics
 	regionStats              *statistics.RegionStatistics
+	storeStats               *statistics.StoreStatisticsMap
 	hotStat                  *statistics.HotStat
 	hotBuckets               *buckets.HotBucketCache
+	keyVisual                *keyvisual.Service
 	ruleManager              *placement.RuleManager
 	regionLabeler            *labeler.RegionLabeler
 	replicationMode          *replication.ModeManager
 	unsafeRecoveryController *unsafeRecoveryController
+	regionInspection         *regionInspectionQueue
 	progressManager          *progress.Manager
+	regionLoading            *progress.Manager // created with the cluster, it is read without the cluster lock.
 	regionSyncer             *syncer.RegionSyncer
 	changedRegions           chan *core.RegionInfo
+	// regionCleaner is nil if it is not started, then the overlapped regions
+	// are deleted synchronously.
+	regionCleaner *regionCleaner
+	// statsObserver is nil if it is not started, then the region statistics
+	// are updated synchronously.
+	statsObserver *statisticsObserver
+	// topologyChanges is nil if it is not started, then the changes of the
+	// location labels are not detected.
+	topologyChanges *topologyChangeDetector
+	events          *eventBus
+	lowSpace        *lowSpaceDetector
+	slowTrend       *slowTrendDetector
+	replicaFreezes  *replicaFreezeTracker
+	breakGlass      *breakGlass
+	// newestStoreVersion is the newest version of the up stores, which the
+	// version lags of the stores are computed against.
+	newestStoreVersion *semver.Version
+	// regionQueryCache caches the results of the region queries, its entries
+	// are invalidated when the regions are notified as changed.
+	regionQueryCache *RegionQueryCache
+	regionCacheGuard *regionCacheGuard
+	// hotPeerExemplars keeps the hottest peers exported with their regions.
+	hotPeerExemplars *hotPeerExemplarCache
+	zoneLatencies    *statistics.ZoneLatencies
+	// tasks keeps the records of the long-running tasks.
+	tasks        *taskManager
+	staleRegions *staleRegionGuard
+	imports      *importModeManager
+	peerHistory  *peerHistory
+	// placementScans scans the regions violating the placement periodically.
+	placementScans *placementScanner
+	// minResolvedTSTracker tracks the stores missing from the min resolved ts.
+	minResolvedTSTracker *minResolvedTSTracker
+	// workerPools holds the worker pools of the hot statistics and the checkers.
+	workerPools *workerpool.Registry
+	// startup records the startup status of the subsystems.
+	startup *startupSequence
 }
 
 // Status saves some state information.
@@ -153,12 +238,14 @@ type Status struct {
 func NewRaftCluster(ctx context.Context, clusterID uint64, regionSyncer *syncer.RegionSyncer, etcdClient *clientv3.Client,
 	httpClient *http.Client) *RaftCluster {
 	return &RaftCluster{
-		serverCtx:    ctx,
-		running:      false,
-		clusterID:    clusterID,
-		regionSyncer: regionSyncer,
-		httpClient:   httpClient,
-		etcdClient:   etcdClient,
+		serverCtx:     ctx,
+		running:       false,
+		clusterID:     clusterID,
+		regionSyncer:  regionSyncer,
+		httpClient:    httpClient,
+		etcdClient:    etcdClient,
+		startup:       newStartupSequence(),
+		regionLoading: progress.NewManager(),
 	}
 }
 
@@ -221,12 +308,32 @@ func (c *RaftCluster) InitCluster(
 	c.core, c.opt, c.storage, c.id = basicCluster, opt, storage, id
 	c.ctx, c.cancel = context.WithCancel(c.serverCtx)
 	c.labelLevelStats = statistics.NewLabelStatistics()
+	c.regionLabelStats = statistics.NewRegionLabelStatistics()
+	c.workerPools = workerpool.NewRegistry()
 	c.hotStat = statistics.NewHotStat(c.ctx)
+	c.workerPools.Register(c.hotStat.GetWorkerPool())
 	c.hotBuckets = buckets.NewBucketsCache(c.ctx)
 	c.progressManager = progress.NewManager()
 	c.changedRegions = make(chan *core.RegionInfo, defaultChangedRegionsLimit)
 	c.prevStoreLimit = make(map[uint64]map[storelimit.Type]float64)
+	c.resetStoreNotes()
 	c.unsafeRecoveryController = newUnsafeRecoveryController(c)
+	c.regionInspection = newRegionInspectionQueue()
+	c.events = newEventBus(c)
+	c.lowSpace = newLowSpaceDetector(c)
+	c.slowTrend = newSlowTrendDetector(c)
+	c.replicaFreezes = newReplicaFreezeTracker(c)
+	c.breakGlass = newBreakGlass(c)
+	c.regionQueryCache = NewRegionQueryCache(opt.GetRegionQueryCacheSize)
+	c.regionCacheGuard = newRegionCacheGuard(c)
+	c.hotPeerExemplars = newHotPeerExemplarCache(c)
+	c.placementScans = newPlacementScanner(c)
+	c.zoneLatencies = statistics.NewZoneLatencies()
+	c.tasks = newTaskManager(c)
+	c.staleRegions = newStaleRegionGuard(c)
+	c.imports = newImportModeManager(c)
+	c.peerHistory = newPeerHistory()
+	c.minResolvedTSTracker = newMinResolvedTSTracker(c)
 }
 
 // Start starts a cluster.
@@ -240,6 +347,9 @@ func (c *RaftCluster) Start(s Server) error {
 	}
 
 	c.InitCluster(s.GetAllocator(), s.GetPersistOptions(), s.GetStorage(), s.GetBasicCluster())
+	if s, ok := s.(interface{ GetAuditBackend() []audit.Backend }); ok {
+		c.breakGlass.auditBackends = s.GetAuditBackend()
+	}
 	cluster, err := c.LoadClusterInfo()
 	if err != nil {
 		return err
@@ -248,42 +358,226 @@ func (c *RaftCluster) Start(s Server) error {
 		return nil
 	}
 
-	c.ruleManager = placement.NewRuleManager(c.storage, c, c.GetOpts())
-	if c.opt.IsPlacementRulesEnabled() {
-		err = c.ruleManager.Initialize(c.opt.GetMaxReplicas(), c.opt.GetLocationLabels())
-		if err != nil {
-			return err
-		}
+	c.registerSubsystems(s, cluster)
+	disabledSubsystems := c.opt.GetDisabledSubsystems()
+	if !c.opt.IsKeyVisualEnabled() {
+		disabledSubsystems = append(disabledSubsystems[:len(disabledSubsystems):len(disabledSubsystems)], SubsystemKeyVisual)
 	}
-
-	c.regionLabeler, err = labeler.NewRegionLabeler(c.ctx, c.storage, regionLabelGCInterval)
-	if err != nil {
-		return err
-	}
-
-	c.replicationMode, err = replication.NewReplicationModeManager(s.GetConfig().ReplicationMode, c.storage, cluster, s)
+	err = c.startup.start(disabledSubsystems, func(run func()) {
+		c.wg.Add(1)
+		go run()
+	})
 	if err != nil {
 		return err
 	}
-	c.storeConfigManager = config.NewStoreConfigManager(c.httpClient)
-	c.coordinator = newCoordinator(c.ctx, cluster, s.GetHBStreams())
-	c.regionStats = statistics.NewRegionStatistics(c.opt, c.ruleManager, c.storeConfigManager)
-	c.limiter = NewStoreLimiter(s.GetPersistOptions())
-
-	c.wg.Add(8)
-	go c.runCoordinator()
-	go c.runMetricsCollectionJob()
-	go c.runNodeStateCheckJob()
-	go c.runStatsBackgroundJobs()
-	go c.syncRegions()
-	go c.runReplicationMode()
-	go c.runMinResolvedTSJob()
-	go c.runSyncConfig()
 	c.running = true
 
 	return nil
 }
 
+// The names of the subsystems started with the cluster.
+const (
+	SubsystemPlacementRules   = "placement-rules"
+	SubsystemRegionLabeler    = "region-labeler"
+	SubsystemReplicationMode  = "replication-mode"
+	SubsystemCoordinator      = "coordinator"
+	SubsystemStateRestore     = "state-restore"
+	SubsystemMetrics          = "metrics-collection"
+	SubsystemNodeStateCheck   = "node-state-check"
+	SubsystemStatsJobs        = "stats-background-jobs"
+	SubsystemRegionSyncer     = "region-syncer"
+	SubsystemMinResolvedTS    = "min-resolved-ts"
+	SubsystemStoreConfigSync  = "store-config-sync"
+	SubsystemKeyVisual        = "key-visual"
+	SubsystemRegionCleaner    = "region-cleaner"
+	SubsystemStatsObserver    = "statistics-observer"
+	SubsystemTopologyChanges  = "topology-change-detector"
+	SubsystemReplicaFreezes   = "replica-freeze-tracker"
+	SubsystemPlacementScanner = "placement-scanner"
+	subsystemStoreStatistics  = "store-statistics"
+	subsystemBackgroundHelper = "background-helpers"
+)
+
+// registerSubsystems registers the subsystems of the cluster in the order of
+// their dependencies.
+func (c *RaftCluster) registerSubsystems(s Server, cluster *RaftCluster) {
+	c.startup.reset()
+	c.startup.register(&subsystem{
+		name: SubsystemPlacementRules,
+		gate: func() error {
+			c.ruleManager = placement.NewRuleManager(c.storage, c, c.GetOpts())
+			if c.opt.IsPlacementRulesEnabled() {
+				return c.ruleManager.Initialize(c.opt.GetMaxReplicas(), c.opt.GetLocationLabels())
+			}
+			return nil
+		},
+	})
+	c.startup.register(&subsystem{
+		name: SubsystemRegionLabeler,
+		gate: func() (err error) {
+			c.regionLabeler, err = labeler.NewRegionLabeler(c.ctx, c.storage, regionLabelGCInterval)
+			return err
+		},
+	})
+	c.startup.register(&subsystem{
+		name: SubsystemReplicationMode,
+		gate: func() (err error) {
+			c.replicationMode, err = replication.NewReplicationModeManager(s.GetConfig().ReplicationMode, c.storage, cluster, s)
+			return err
+		},
+		run: c.runReplicationMode,
+	})
+	c.startup.register(&subsystem{
+		name: subsystemStoreStatistics,
+		gate: func() error {
+			c.storeConfigManager = config.NewStoreConfigManager(c.httpClient)
+			c.regionStats = statistics.NewRegionStatistics(c.opt, c.ruleManager, c.storeConfigManager)
+			c.storeStats = statistics.NewStoreStatisticsMap(c.opt, c.storeConfigManager)
+			c.limiter = NewStoreLimiter(s.GetPersistOptions())
+			return nil
+		},
+		deps: []string{SubsystemPlacementRules},
+	})
+	c.startup.register(&subsystem{
+		name: SubsystemCoordinator,
+		deps: []string{SubsystemPlacementRules, SubsystemRegionLabeler, subsystemStoreStatistics},
+		gate: func() error {
+			c.coordinator = newCoordinator(c.ctx, cluster, s.GetHBStreams())
+			return nil
+		},
+		run: c.runCoordinator,
+	})
+	c.startup.register(&subsystem{
+		name: SubsystemStateRestore,
+		deps: []string{SubsystemCoordinator},
+		gate: func() error {
+			c.restoreHotPeerSnapshots()
+			c.restoreProgresses()
+			c.restoreVersionGate()
+			c.imports.restore()
+			c.tasks.restore()
+			c.placementScans.restore()
+			return nil
+		},
+	})
+	// The key visual service is always created, since it's referred by the APIs
+	// even if its job is disabled.
+	c.startup.register(&subsystem{
+		name: subsystemBackgroundHelper,
+		deps: []string{SubsystemCoordinator},
+		gate: func() error {
+			// The columns are kept in the local storage rather than etcd, or only in memory
+			// if there is no local storage.
+			keyVisualStorage, _ := storage.TryGetLocalRegionStorage(c.storage).(endpoint.KeyVisualStorage)
+			c.keyVisual = keyvisual.NewService(c, keyVisualStorage)
+			return nil
+		},
+	})
+	// The following workers are only created if they are started, otherwise
+	// their work is done synchronously or skipped, so that nothing is queued
+	// without being consumed.
+	c.regionCleaner, c.statsObserver, c.topologyChanges = nil, nil, nil
+	for _, sub := range []*subsystem{
+		{
+			name: SubsystemMetrics,
+			deps: []string{SubsystemCoordinator, subsystemStoreStatistics},
+			run:  c.runMetricsCollectionJob,
+		},
+		{
+			name: SubsystemNodeStateCheck,
+			deps: []string{subsystemStoreStatistics, SubsystemStateRestore},
+			run:  c.runNodeStateCheckJob,
+		},
+		{
+			// the hot peer snapshots must be restored before they are saved again.
+			name: SubsystemStatsJobs,
+			deps: []string{SubsystemStateRestore},
+			gate: func() error {
+				if c.hotStat == nil {
+					return errors.New("hot statistics are not initialized")
+				}
+				return nil
+			},
+			run: c.runStatsBackgroundJobs,
+		},
+		{
+			name: SubsystemRegionSyncer,
+			gate: func() error {
+				if c.regionSyncer == nil {
+					return errors.New("region syncer is not initialized")
+				}
+				return nil
+			},
+			run: c.syncRegions,
+		},
+		{
+			name: SubsystemMinResolvedTS,
+			gate: func() error {
+				_, err := c.storage.LoadMinResolvedTS()
+				return err
+			},
+			run:      c.runMinResolvedTSJob,
+			optional: true,
+		},
+		{
+			name:     SubsystemStoreConfigSync,
+			deps:     []string{subsystemStoreStatistics},
+			run:      c.runSyncConfig,
+			optional: true,
+		},
+		{
+			name:     SubsystemKeyVisual,
+			deps:     []string{subsystemBackgroundHelper},
+			run:      c.runKeyVisual,
+			optional: true,
+		},
+		{
+			name: SubsystemRegionCleaner,
+			gate: func() error {
+				c.regionCleaner = newRegionCleaner(c)
+				return nil
+			},
+			run:      c.runRegionCleaner,
+			optional: true,
+		},
+		{
+			name: SubsystemStatsObserver,
+			deps: []string{subsystemStoreStatistics},
+			gate: func() error {
+				c.statsObserver = newStatisticsObserver(c)
+				return nil
+			},
+			run:      c.runStatisticsObserver,
+			optional: true,
+		},
+		{
+			name: SubsystemTopologyChanges,
+			deps: []string{SubsystemCoordinator},
+			gate: func() error {
+				c.topologyChanges = newTopologyChangeDetector(c)
+				return nil
+			},
+			run:      c.runTopologyChangeDetector,
+			optional: true,
+		},
+		{
+			name:     SubsystemReplicaFreezes,
+			deps:     []string{SubsystemRegionLabeler},
+			run:      c.runReplicaFreezeTracker,
+			optional: true,
+		},
+		{
+			// the scans interrupted by the last leader must be restored first.
+			name:     SubsystemPlacementScanner,
+			deps:     []string{SubsystemPlacementRules, SubsystemStateRestore},
+			run:      c.runPlacementScanner,
+			optional: true,
+		},
+	} {
+		c.startup.register(sub)
+	}
+}
+
 // runSyncConfig runs the job to sync tikv config.
 func (c *RaftCluster) runSyncConfig() {
 	defer logutil.LogPanic()
@@ -353,11 +647,15 @@ func (c *RaftCluster) LoadClusterInfo() (*RaftCluster, error) {
 		zap.Int("count", c.GetStoreCount()),
 		zap.Duration("cost", time.Since(start)),
 	)
+	c.updateStoreVersionLagsLocked()
 
 	start = time.Now()
 
 	// used to load region from kv storage to cache storage.
-	if err := storage.TryLoadRegionsOnce(c.ctx, c.storage, c.core.CheckAndPutRegion); err != nil {
+	putRegion, finish := c.trackRegionLoading(c.core.CheckAndPutRegion)
+	err = storage.TryLoadRegionsOnce(c.ctx, c.storage, putRegion)
+	finish()
+	if err != nil {
 		return nil, err
 	}
 	log.Info("load regions",
@@ -371,6 +669,38 @@ func (c *RaftCluster) LoadClusterInfo() (*RaftCluster, error) {
 	return c, nil
 }
 
+// trackRegionLoading wraps f to report the progress of loading regions. Since the
+// regions are loaded in the order of their IDs, the progress is estimated by the
+// ID of the last loaded region against the current ID of the allocator, which is
+// not less than the IDs of all existing regions. The returned function should be
+// called once the loading is finished.
+func (c *RaftCluster) trackRegionLoading(f func(*core.RegionInfo) []*core.RegionInfo) (func(*core.RegionInfo) []*core.RegionInfo, func()) {
+	maxID, err := c.id.Current()
+	if err != nil {
+		log.Warn("failed to get the current id to track the progress of loading regions", errs.ZapError(err))
+		return f, func() {}
+	}
+	if maxID == 0 {
+		// No ID is allocated, so there is no region to load.
+		return f, func() {}
+	}
+	c.regionLoading.AddProgress(loadingRegionsProgress, 0, float64(maxID), loadingRegionsProgressInterval)
+	lastUpdate := time.Now()
+	put := func(region *core.RegionInfo) []*core.RegionInfo {
+		overlaps := f(region)
+		if time.Since(lastUpdate) >= loadingRegionsProgressInterval {
+			lastUpdate = time.Now()
+			current := math.Min(float64(region.GetID()), float64(maxID))
+			c.regionLoading.UpdateProgress(loadingRegionsProgress, current, float64(maxID)-current, true)
+		}
+		return overlaps
+	}
+	finish := func() {
+		c.regionLoading.RemoveProgress(loadingRegionsProgress)
+	}
+	return put, finish
+}
+
 func (c *RaftCluster) runMetricsCollectionJob() {
 	defer logutil.LogPanic()
 	defer c.wg.Done()
@@ -404,6 +734,8 @@ func (c *RaftCluster) runNodeStateCheckJob() {
 		ticker = time.NewTicker(2 * time.Second)
 	})
 	defer ticker.Stop()
+	snapshotTicker := time.NewTicker(progressSnapshotInterval)
+	defer snapshotTicker.Stop()
 
 	for {
 		select {
@@ -412,8 +744,55 @@ func (c *RaftCluster) runNodeStateCheckJob() {
 			return
 		case <-ticker.C:
 			c.checkStores()
+			c.tasks.refresh()
+			c.staleRegions.check(time.Now())
+			c.breakGlass.check(time.Now())
+		case <-snapshotTicker.C:
+			c.saveProgresses()
+		}
+	}
+}
+
+// isStoreProgress returns true if the progress is about removing or preparing a store.
+func isStoreProgress(progress string) bool {
+	return strings.HasPrefix(progress, removingAction+"-") || strings.HasPrefix(progress, preparingAction+"-")
+}
+
+// saveProgresses persists the snapshot of the store progresses, so that the
+// next leader can continue calculating their speeds and ETAs.
+func (c *RaftCluster) saveProgresses() {
+	snapshot := c.progressManager.Snapshot(isStoreProgress)
+	if err := c.storage.SaveProgressSnapshot(snapshot); err != nil {
+		log.Warn("failed to save progress snapshot", errs.ZapError(err))
+	}
+}
+
+// restoreProgresses restores the progresses of the stores which are still
+// being removed or prepared from the snapshot saved by the previous leader.
+func (c *RaftCluster) restoreProgresses() {
+	snapshot := &progress.Snapshot{}
+	ok, err := c.storage.LoadProgressSnapshot(snapshot)
+	if err != nil {
+		log.Warn("failed to load progress snapshot", errs.ZapError(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	inProgress := make(map[string]struct{})
+	for _, store := range c.GetStores() {
+		if store.IsPreparing() {
+			inProgress[encodePreparingProgressKey(store.GetID())] = struct{}{}
+		} else if store.IsRemoving() {
+			inProgress[encodeRemovingProgressKey(store.GetID())] = struct{}{}
 		}
 	}
+	count := c.progressManager.Restore(snapshot, func(p string) bool {
+		_, ok := inProgress[p]
+		return ok
+	})
+	log.Info("restored store progresses from snapshot", zap.Int("count", count),
+		zap.Time("snapshot-time", time.Unix(snapshot.Timestamp, 0)))
 }
 
 func (c *RaftCluster) runStatsBackgroundJobs() {
@@ -422,6 +801,8 @@ func (c *RaftCluster) runStatsBackgroundJobs() {
 
 	ticker := time.NewTicker(statistics.RegionsStatsObserveInterval)
 	defer ticker.Stop()
+	snapshotTicker := time.NewTicker(hotPeerSnapshotInterval)
+	defer snapshotTicker.Stop()
 
 	for {
 		select {
@@ -430,7 +811,91 @@ func (c *RaftCluster) runStatsBackgroundJobs() {
 			return
 		case <-ticker.C:
 			c.hotStat.ObserveRegionsStats(c.core.GetStoresWriteRate())
+		case <-snapshotTicker.C:
+			c.saveHotPeerSnapshots()
+		}
+	}
+}
+
+// saveHotPeerSnapshots persists the snapshots of the hot peer cache, so that
+// the next leader can warm up its hot peer cache with them. A snapshot is saved
+// in chunks, the chunks left by the larger snapshots before are deleted.
+func (c *RaftCluster) saveHotPeerSnapshots() {
+	if c.hotPeerSnapshotChunks == nil {
+		c.hotPeerSnapshotChunks = make(map[statistics.RWType]int)
+	}
+	for _, kind := range []statistics.RWType{statistics.Read, statistics.Write} {
+		snapshot := c.hotStat.Snapshot(kind)
+		if snapshot == nil {
+			continue
+		}
+		chunks := snapshot.Split(statistics.HotPeerSnapshotChunkSize)
+		if err := c.saveHotPeerSnapshotChunks(kind, chunks); err != nil {
+			log.Warn("failed to save hot peer snapshot", zap.String("kind", kind.String()), errs.ZapError(err))
+		}
+	}
+}
+
+func (c *RaftCluster) saveHotPeerSnapshotChunks(kind statistics.RWType, chunks []*statistics.HotPeerSnapshot) error {
+	for i, chunk := range chunks {
+		if err := c.storage.SaveHotPeerSnapshotChunk(kind.String(), i, chunk); err != nil {
+			return err
+		}
+	}
+	if c.hotPeerSnapshotChunks[kind] < len(chunks) {
+		c.hotPeerSnapshotChunks[kind] = len(chunks)
+	}
+	for i := c.hotPeerSnapshotChunks[kind] - 1; i >= len(chunks); i-- {
+		if err := c.storage.DeleteHotPeerSnapshotChunk(kind.String(), i); err != nil {
+			return err
+		}
+		c.hotPeerSnapshotChunks[kind] = i
+	}
+	return nil
+}
+
+// loadHotPeerSnapshot merges the persisted chunks of the latest snapshot, the
+// chunks of the earlier snapshots are ignored. It returns nil if there is no
+// snapshot.
+func (c *RaftCluster) loadHotPeerSnapshot(kind statistics.RWType) (*statistics.HotPeerSnapshot, error) {
+	var snapshot *statistics.HotPeerSnapshot
+	err := c.storage.LoadHotPeerSnapshotChunks(kind.String(), func(k, v string) {
+		if i, err := strconv.Atoi(k); err == nil && c.hotPeerSnapshotChunks[kind] <= i {
+			c.hotPeerSnapshotChunks[kind] = i + 1
+		}
+		chunk := &statistics.HotPeerSnapshot{}
+		if err := json.Unmarshal([]byte(v), chunk); err != nil {
+			log.Warn("failed to unmarshal hot peer snapshot", zap.String("kind", kind.String()), zap.String("chunk", k), errs.ZapError(errs.ErrJSONUnmarshal, err))
+			return
+		}
+		switch {
+		case snapshot == nil || snapshot.Timestamp < chunk.Timestamp:
+			snapshot = chunk
+		case snapshot.Timestamp == chunk.Timestamp:
+			snapshot.Items = append(snapshot.Items, chunk.Items...)
+		}
+	})
+	return snapshot, err
+}
+
+// restoreHotPeerSnapshots restores the hot peer cache from the snapshots saved
+// by the previous leader. The stale snapshots are ignored.
+func (c *RaftCluster) restoreHotPeerSnapshots() {
+	if c.hotPeerSnapshotChunks == nil {
+		c.hotPeerSnapshotChunks = make(map[statistics.RWType]int)
+	}
+	for _, kind := range []statistics.RWType{statistics.Read, statistics.Write} {
+		snapshot, err := c.loadHotPeerSnapshot(kind)
+		if err != nil {
+			log.Warn("failed to load hot peer snapshot", zap.String("kind", kind.String()), errs.ZapError(err))
+			continue
+		}
+		if snapshot == nil || snapshot.IsStale(hotPeerSnapshotMaxStaleness) {
+			continue
 		}
+		count := c.hotStat.Restore(kind, snapshot)
+		log.Info("restored hot peers from snapshot", zap.String("kind", kind.String()), zap.Int("count", count),
+			zap.Time("snapshot-time", time.Unix(snapshot.Timestamp, 0)))
 	}
 }
 
@@ -452,10 +917,68 @@ func (c *RaftCluster) runReplicationMode() {
 	c.replicationMode.Run(c.ctx)
 }
 
+func (c *RaftCluster) runKeyVisual() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+	c.keyVisual.Run(c.ctx)
+}
+
+func (c *RaftCluster) runRegionCleaner() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+	c.regionCleaner.run(c.ctx)
+}
+
+func (c *RaftCluster) runStatisticsObserver() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+	c.statsObserver.run(c.ctx)
+}
+
+func (c *RaftCluster) runTopologyChangeDetector() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+	c.topologyChanges.run(c.ctx)
+}
+
+func (c *RaftCluster) runReplicaFreezeTracker() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+	c.replicaFreezes.run(c.ctx)
+}
+
+func (c *RaftCluster) runPlacementScanner() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+	c.placementScans.run(c.ctx)
+}
+
+// GetPlacementScanReports returns the summaries of the recent placement scans,
+// the latest first. limit <= 0 means no limit.
+func (c *RaftCluster) GetPlacementScanReports(limit int) []*PlacementScanReport {
+	return c.placementScans.getReports(limit)
+}
+
+// GetLatestPlacementScanReport returns the latest placement scan with the regions
+// violating the placement, or nil if no scan has finished.
+func (c *RaftCluster) GetLatestPlacementScanReport() *PlacementScanReport {
+	return c.placementScans.getLatestReport()
+}
+
 // Stop stops the cluster.
 func (c *RaftCluster) Stop() {
 	c.Lock()
+	if !c.running {
+		c.Unlock()
+		return
+	}
+	coordinator := c.coordinator
+	c.Unlock()
+	// Drain the operators without holding the lock, so that the heartbeats are still handled
+	// and the operators can be finished.
+	coordinator.drainOperators(c.opt.GetOperatorDrainTimeout())
 
+	c.Lock()
 	if !c.running {
 		c.Unlock()
 		return
@@ -530,7 +1053,21 @@ func (c *RaftCluster) GetSchedulerHandlers() map[string]http.Handler {
 
 // AddScheduler adds a scheduler.
 func (c *RaftCluster) AddScheduler(scheduler schedule.Scheduler, args ...string) error {
-	return c.coordinator.addScheduler(scheduler, args...)
+	return c.AddObservingScheduler(scheduler, 0, args...)
+}
+
+// AddObservingScheduler adds a scheduler which records its operators instead
+// of submitting them for the first t seconds.
+func (c *RaftCluster) AddObservingScheduler(scheduler schedule.Scheduler, t int64, args ...string) error {
+	var observeUntil int64
+	if t > 0 {
+		observeUntil = time.Now().Unix() + t
+	}
+	if err := c.coordinator.addObservingScheduler(scheduler, observeUntil, args...); err != nil {
+		return err
+	}
+	c.clearVersionGate(versionGateKindScheduler, scheduler.GetName())
+	return nil
 }
 
 // RemoveScheduler removes a scheduler.
@@ -543,6 +1080,23 @@ func (c *RaftCluster) PauseOrResumeScheduler(name string, t int64) error {
 	return c.coordinator.pauseOrResumeScheduler(name, t)
 }
 
+// SetSchedulerObserveOnly makes a scheduler record its operators instead of
+// submitting them for t seconds, or leaves the observe-only mode if t is 0.
+func (c *RaftCluster) SetSchedulerObserveOnly(name string, t int64) error {
+	return c.coordinator.setSchedulerObserveOnly(name, t)
+}
+
+// GetSchedulerObservation returns the operators recorded by a scheduler in
+// observe-only mode.
+func (c *RaftCluster) GetSchedulerObservation(name string) (*schedule.SchedulerObservation, error) {
+	return c.coordinator.getSchedulerObservation(name)
+}
+
+// GetSchedulerState returns a snapshot of the state of a scheduler.
+func (c *RaftCluster) GetSchedulerState(name string) (*schedule.SchedulerStateSnapshot, error) {
+	return c.coordinator.getSchedulerState(name)
+}
+
 // IsSchedulerPaused checks if a scheduler is paused.
 func (c *RaftCluster) IsSchedulerPaused(name string) (bool, error) {
 	return c.coordinator.isSchedulerPaused(name)
@@ -558,6 +1112,11 @@ func (c *RaftCluster) IsSchedulerAllowed(name string) (bool, error) {
 	return c.coordinator.isSchedulerAllowed(name)
 }
 
+// GetSchedulerUnreadyReason returns the reason why a scheduler is not ready.
+func (c *RaftCluster) GetSchedulerUnreadyReason(name string) (string, error) {
+	return c.coordinator.getSchedulerUnreadyReason(name)
+}
+
 // IsSchedulerExisted checks if a scheduler is existed.
 func (c *RaftCluster) IsSchedulerExisted(name string) (bool, error) {
 	return c.coordinator.isSchedulerExisted(name)
@@ -565,7 +1124,13 @@ func (c *RaftCluster) IsSchedulerExisted(name string) (bool, error) {
 
 // PauseOrResumeChecker pauses or resumes checker.
 func (c *RaftCluster) PauseOrResumeChecker(name string, t int64) error {
-	return c.coordinator.pauseOrResumeChecker(name, t)
+	if err := c.coordinator.pauseOrResumeChecker(name, t); err != nil {
+		return err
+	}
+	if t == 0 {
+		c.clearVersionGate(versionGateKindChecker, name)
+	}
+	return nil
 }
 
 // IsCheckerPaused returns if checker is paused
@@ -573,6 +1138,12 @@ func (c *RaftCluster) IsCheckerPaused(name string) (bool, error) {
 	return c.coordinator.isCheckerPaused(name)
 }
 
+// DryRunChecker runs the checker on the given or sampled regions without
+// adding the proposed operators.
+func (c *RaftCluster) DryRunChecker(name string, regionIDs []uint64, sample int) ([]*checker.DryRunResult, error) {
+	return c.coordinator.dryRunChecker(name, regionIDs, sample)
+}
+
 // GetAllocator returns cluster's id allocator.
 func (c *RaftCluster) GetAllocator() id.Allocator {
 	return c.id
@@ -598,6 +1169,11 @@ func (c *RaftCluster) GetRegionLabeler() *labeler.RegionLabeler {
 	return c.regionLabeler
 }
 
+// GetKeyVisualService returns the key visual service.
+func (c *RaftCluster) GetKeyVisualService() *keyvisual.Service {
+	return c.keyVisual
+}
+
 // GetStorage returns the storage.
 func (c *RaftCluster) GetStorage() storage.Storage {
 	c.RLock()
@@ -628,6 +1204,16 @@ func (c *RaftCluster) GetSuspectRegions() []uint64 {
 	return c.coordinator.checkers.GetSuspectRegions()
 }
 
+// GetWorkerPools returns the worker pools of the cluster.
+func (c *RaftCluster) GetWorkerPools() *workerpool.Registry {
+	return c.workerPools
+}
+
+// AdjustWorkerPools resizes the worker pools to the configured sizes.
+func (c *RaftCluster) AdjustWorkerPools() {
+	c.workerPools.ApplySizes(c.opt.GetWorkerPoolSizes())
+}
+
 // GetHotStat gets hot stat for test.
 func (c *RaftCluster) GetHotStat() *statistics.HotStat {
 	return c.hotStat
@@ -638,6 +1224,32 @@ func (c *RaftCluster) RemoveSuspectRegion(id uint64) {
 	c.coordinator.checkers.RemoveSuspectRegion(id)
 }
 
+// ReconcileRuleGroup adds all regions matched by the rules of the given group
+// to the suspect list, so that they are checked without waiting for patrol.
+// It returns the number of affected regions.
+func (c *RaftCluster) ReconcileRuleGroup(group string) int {
+	regionIDs := make(map[uint64]struct{})
+	for _, rule := range c.ruleManager.GetRulesByGroup(group) {
+		for _, region := range c.ScanRegions(rule.StartKey, rule.EndKey, -1) {
+			regionIDs[region.GetID()] = struct{}{}
+		}
+	}
+	if len(regionIDs) == 0 {
+		return 0
+	}
+	ids := make([]uint64, 0, len(regionIDs))
+	for id := range regionIDs {
+		ids = append(ids, id)
+	}
+	c.AddSuspectRegions(ids...)
+	return len(ids)
+}
+
+// GetQuarantinedRegions returns the region heartbeats quarantined for inspection.
+func (c *RaftCluster) GetQuarantinedRegions() []*QuarantinedRegion {
+	return c.regionInspection.list()
+}
+
 // GetUnsafeRecoveryController returns the unsafe recovery controller.
 func (c *RaftCluster) GetUnsafeRecoveryController() *unsafeRecoveryController {
 	return c.unsafeRecoveryController
@@ -646,15 +1258,64 @@ func (c *RaftCluster) GetUnsafeRecoveryController() *unsafeRecoveryController {
 // AddSuspectKeyRange adds the key range with the its ruleID as the key
 // The instance of each keyRange is like following format:
 // [2][]byte: start key/end key
-func (c *RaftCluster) AddSuspectKeyRange(start, end []byte) {
+// The key range is also persisted with the reason, so that it can be reloaded
+// after the PD leader changes.
+func (c *RaftCluster) AddSuspectKeyRange(start, end []byte, reason string) {
 	c.coordinator.checkers.AddSuspectKeyRange(start, end)
+	if c.storage == nil {
+		return
+	}
+	keyRange := &endpoint.SuspectKeyRange{
+		StartKey:  start,
+		EndKey:    end,
+		Reason:    reason,
+		Timestamp: time.Now().Unix(),
+	}
+	if err := c.storage.SaveSuspectKeyRange(keyutil.BuildKeyRangeKey(start, end), keyRange); err != nil {
+		log.Warn("failed to persist suspect key range", zap.String("reason", reason), errs.ZapError(err))
+	}
 }
 
 // PopOneSuspectKeyRange gets one suspect keyRange group.
 // it would return value and true if pop success, or return empty [][2][]byte and false
 // if suspectKeyRanges couldn't pop keyRange group.
 func (c *RaftCluster) PopOneSuspectKeyRange() ([2][]byte, bool) {
-	return c.coordinator.checkers.PopOneSuspectKeyRange()
+	keyRange, ok := c.coordinator.checkers.PopOneSuspectKeyRange()
+	if ok && c.storage != nil {
+		if err := c.storage.DeleteSuspectKeyRange(keyutil.BuildKeyRangeKey(keyRange[0], keyRange[1])); err != nil {
+			log.Warn("failed to delete suspect key range", errs.ZapError(err))
+		}
+	}
+	return keyRange, ok
+}
+
+// gcSuspectKeyRanges removes the persisted suspect key ranges older than the
+// GC age. If reload is true, the remaining ones are added to the checkers.
+func (c *RaftCluster) gcSuspectKeyRanges(reload bool) {
+	if c.storage == nil {
+		return
+	}
+	gcAge := c.opt.GetSuspectKeyRangeGCAge()
+	now := time.Now()
+	var expired []string
+	err := c.storage.LoadSuspectKeyRanges(func(key string, keyRange *endpoint.SuspectKeyRange) {
+		if now.Sub(time.Unix(keyRange.Timestamp, 0)) > gcAge {
+			expired = append(expired, key)
+			return
+		}
+		if reload {
+			c.coordinator.checkers.AddSuspectKeyRange(keyRange.StartKey, keyRange.EndKey)
+		}
+	})
+	if err != nil {
+		log.Error("failed to load suspect key ranges", errs.ZapError(err))
+		return
+	}
+	for _, key := range expired {
+		if err := c.storage.DeleteSuspectKeyRange(key); err != nil {
+			log.Warn("failed to delete suspect key range", errs.ZapError(err))
+		}
+	}
 }
 
 // ClearSuspectKeyRanges clears the suspect keyRanges, only for unit test
@@ -671,7 +1332,15 @@ func (c *RaftCluster) HandleStoreHeartbeat(stats *pdpb.StoreStats) error {
 	if store == nil {
 		return errors.Errorf("store %v not found", storeID)
 	}
-	newStore := store.Clone(core.SetStoreStats(stats), core.SetLastHeartbeatTS(time.Now()))
+	now := time.Now()
+	newStore := store.Clone(core.SetStoreStats(stats), core.SetLastHeartbeatTS(now))
+	newStore = newStore.ShallowClone(
+		core.SetAddPeerLimitRatio(c.lowSpace.observe(newStore, now)),
+		core.SetSlowTrend(c.slowTrend.observe(newStore, now)),
+	)
+	if lag, ok := c.storeVersionLagLocked(newStore); ok && lag != newStore.GetVersionLag() {
+		newStore = newStore.ShallowClone(core.SetVersionLag(lag))
+	}
 	if newStore.IsLowSpace(c.opt.GetLowSpaceRatio()) {
 		log.Warn("store does not have enough disk space",
 			zap.Uint64("store-id", storeID),
@@ -772,11 +1441,25 @@ var regionGuide = core.GenerateRegionGuideFunc(true)
 
 // processRegionHeartbeat updates the region information.
 func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
+	origin := c.GetRegion(region.GetID())
+	reason := inspectRegionHeartbeat(region, origin)
+	if reason == "" {
+		reason = c.regionInspection.checkStats(region, origin)
+	}
+	if reason != "" {
+		c.regionInspection.put(region, reason)
+		regionEventCounter.WithLabelValues("quarantine").Inc()
+		log.Warn("region heartbeat is quarantined for inspection",
+			zap.Uint64("region-id", region.GetID()),
+			zap.String("reason", reason))
+		return errs.ErrRegionQuarantined.FastGenByArgs(region.GetID(), reason)
+	}
 	origin, err := c.core.PreCheckPutRegion(region)
 	if err != nil {
 		return err
 	}
 	region.Inherit(origin, c.storeConfigManager.GetStoreConfig().IsEnableRegionBucket())
+	c.staleRegions.observe(region.GetID(), time.Now())
 
 	c.hotStat.CheckWriteAsync(statistics.NewCheckExpiredItemTask(region))
 	c.hotStat.CheckReadAsync(statistics.NewCheckExpiredItemTask(region))
@@ -795,7 +1478,7 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		// Due to some config changes need to update the region stats as well,
 		// so we do some extra checks here.
 		if c.regionStats != nil && c.regionStats.RegionStatsNeedUpdate(region) {
-			c.regionStats.Observe(region, c.getRegionStoresLocked(region))
+			c.observeRegionStats(region)
 		}
 		return nil
 	}
@@ -817,10 +1500,8 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		}
 		overlaps = c.core.PutRegion(region)
 		for _, item := range overlaps {
-			if c.regionStats != nil {
-				c.regionStats.ClearDefunctRegion(item.GetID())
-			}
-			c.labelLevelStats.ClearDefunctRegion(item.GetID())
+			c.clearDefunctRegionStats(item.GetID())
+			c.regionLabelStats.ClearDefunctRegion(item.GetID())
 		}
 
 		// Update related stores.
@@ -843,19 +1524,42 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 		c.coordinator.prepareChecker.collect(region)
 	}
 
-	if c.regionStats != nil {
-		c.regionStats.Observe(region, c.getRegionStoresLocked(region))
-	}
+	// Only queue the region here to keep the order of the heartbeats, the
+	// statistics are updated after the lock is released.
+	c.observeRegionStats(region)
 
 	changedRegions := c.changedRegions
 	c.Unlock()
 
+	if len(overlaps) > 0 && c.regionLabeler != nil {
+		c.cleanupRegionAnnotations(region)
+	}
+
+	// A new region overlapping the cached ones, or any new region after the cluster is
+	// prepared, is split by TiKV. It prefers the split target stores when it is scattered,
+	// like the regions split by PD.
+	if origin == nil && saveCache && (len(overlaps) > 0 || c.IsPrepared()) && c.coordinator != nil {
+		c.coordinator.regionScatterer.RecordSplitRegions([]uint64{region.GetID()})
+	}
+
+	if origin != nil && origin.GetRegionEpoch().GetConfVer() != region.GetRegionEpoch().GetConfVer() {
+		var op *operator.Operator
+		if c.coordinator != nil {
+			op = c.coordinator.opController.GetOperator(region.GetID())
+		}
+		c.peerHistory.observe(origin, region, op, time.Now())
+	}
+
 	if c.storage != nil {
 		// If there are concurrent heartbeats from the same region, the last write will win even if
 		// writes to storage in the critical area. So don't use mutex to protect it.
 		// Not successfully saved to storage is not fatal, it only leads to longer warm-up
 		// after restart. Here we only log the error then go on updating cache.
 		for _, item := range overlaps {
+			if c.regionCleaner != nil {
+				c.regionCleaner.push(item.GetMeta())
+				continue
+			}
 			if err := c.storage.DeleteRegion(item.GetMeta()); err != nil {
 				log.Error("failed to delete region from storage",
 					zap.Uint64("region-id", item.GetID()),
@@ -875,9 +1579,19 @@ func (c *RaftCluster) processRegionHeartbeat(region *core.RegionInfo) error {
 	}
 
 	if saveKV || needSync {
+		regionIDs := make([]uint64, 0, len(overlaps)+1)
+		regionIDs = append(regionIDs, region.GetID())
+		for _, item := range overlaps {
+			regionIDs = append(regionIDs, item.GetID())
+		}
+		c.regionQueryCache.Invalidate(regionIDs...)
 		select {
 		case changedRegions <- region:
 		default:
+			// Let the region change watermark know the change is missing.
+			if c.regionSyncer != nil {
+				c.regionSyncer.MarkChangeLost(len(changedRegions))
+			}
 		}
 	}
 
@@ -913,6 +1627,36 @@ func (c *RaftCluster) GetRegionByKey(regionKey []byte) *core.RegionInfo {
 	return c.core.GetRegionByKey(regionKey)
 }
 
+// GetCachedRegionByKey is like GetRegionByKey, but the result is served from
+// the region query cache if it is enabled.
+func (c *RaftCluster) GetCachedRegionByKey(regionKey []byte) *core.RegionInfo {
+	region, _ := c.regionQueryCache.GetOrLoad(regionQueryByKeyPrefix+string(regionKey), func() (interface{}, uint64, uint64) {
+		region := c.core.GetRegionByKey(regionKey)
+		if region == nil {
+			return region, 0, 0
+		}
+		// The region is shared with the region tree, so only the pointer
+		// is counted.
+		return region, region.GetID(), 0
+	}).(*core.RegionInfo)
+	return region
+}
+
+// GetZoneLatency returns the round-trip time between two zones.
+func (c *RaftCluster) GetZoneLatency(zoneA, zoneB string) (time.Duration, bool) {
+	return c.zoneLatencies.GetZoneLatency(zoneA, zoneB)
+}
+
+// GetZoneLatencies returns the matrix of the latencies between the zones.
+func (c *RaftCluster) GetZoneLatencies() *statistics.ZoneLatencies {
+	return c.zoneLatencies
+}
+
+// GetRegionQueryCache returns the cache of the region query results.
+func (c *RaftCluster) GetRegionQueryCache() *RegionQueryCache {
+	return c.regionQueryCache
+}
+
 // GetPrevRegionByKey gets previous region and leader peer by the region key from cluster.
 func (c *RaftCluster) GetPrevRegionByKey(regionKey []byte) *core.RegionInfo {
 	return c.core.GetPrevRegionByKey(regionKey)
@@ -949,6 +1693,13 @@ func (c *RaftCluster) GetStoreRegions(storeID uint64) []*core.RegionInfo {
 	return c.core.GetStoreRegions(storeID)
 }
 
+// SampleRegions returns a uniform random sample of at most n regions overlapping
+// the key range, and the number of the regions sampled from. If storeID is not
+// 0, only the regions with a peer on the store are sampled.
+func (c *RaftCluster) SampleRegions(n int, storeID uint64, startKey, endKey []byte) ([]*core.RegionInfo, int) {
+	return c.core.SampleRegions(n, storeID, startKey, endKey)
+}
+
 // RandLeaderRegions returns some random regions that has leader on the store.
 func (c *RaftCluster) RandLeaderRegions(storeID uint64, ranges []core.KeyRange) []*core.RegionInfo {
 	return c.core.RandLeaderRegions(storeID, ranges)
@@ -1002,11 +1753,45 @@ func (c *RaftCluster) GetAverageRegionSize() int64 {
 // DropCacheRegion removes a region from the cache.
 func (c *RaftCluster) DropCacheRegion(id uint64) {
 	c.core.RemoveRegionIfExist(id)
+	c.regionQueryCache.Invalidate(id)
 }
 
 // DropCacheAllRegion removes all regions from the cache.
 func (c *RaftCluster) DropCacheAllRegion() {
 	c.core.ResetRegionCache()
+	c.regionQueryCache.Reset()
+}
+
+// IssueRegionCacheDropToken issues a one-time token to drop all regions from
+// the cache by the API, and returns it with its expiration.
+func (c *RaftCluster) IssueRegionCacheDropToken() (string, time.Time, error) {
+	return c.regionCacheGuard.issueToken(time.Now())
+}
+
+// GuardedDropCacheRegion removes a region from the cache by the API. The region
+// is kept to be restored, and the drop is rate limited in the safe mode.
+func (c *RaftCluster) GuardedDropCacheRegion(id uint64) error {
+	return c.regionCacheGuard.dropRegion(id, time.Now())
+}
+
+// GuardedDropCacheAllRegion removes all regions from the cache by the API and
+// returns the number of them. The regions are kept to be restored, and a token
+// issued by IssueRegionCacheDropToken is required in the safe mode.
+func (c *RaftCluster) GuardedDropCacheAllRegion(token string) (int, error) {
+	return c.regionCacheGuard.dropAllRegions(token, time.Now())
+}
+
+// GetDroppedCacheRegions returns the regions dropped from the cache by the API
+// which can be restored.
+func (c *RaftCluster) GetDroppedCacheRegions() []DroppedRegion {
+	return c.regionCacheGuard.getDroppedRegions(time.Now())
+}
+
+// RestoreDroppedCacheRegions puts the regions dropped by the API back to the
+// cache, or all of them if no region is given, and returns the number of the
+// restored regions.
+func (c *RaftCluster) RestoreDroppedCacheRegions(ids []uint64) int {
+	return c.regionCacheGuard.restore(ids, time.Now())
 }
 
 // GetMetaStores gets stores from cluster.
@@ -1052,6 +1837,68 @@ func (c *RaftCluster) UpdateStoreLabels(storeID uint64, labels []*metapb.StoreLa
 	return c.putStoreImpl(newStore, force)
 }
 
+// UpdateStoresLabels merges the label patch into the labels of the given stores,
+// a label with an empty value in the patch is removed from the stores. The
+// resulting labels of all stores are checked before any store is updated, and
+// if one of the stores fails to be persisted, the stores updated before it are
+// restored, so either all the stores or none of them will be updated unless the
+// storage keeps failing. If 'dryRun' is true, the stores are left unchanged. It
+// returns the resulting labels of the stores.
+func (c *RaftCluster) UpdateStoresLabels(storeIDs []uint64, patch []*metapb.StoreLabel, dryRun bool) (map[uint64][]*metapb.StoreLabel, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	origins := make([]*core.StoreInfo, 0, len(storeIDs))
+	newStores := make([]*core.StoreInfo, 0, len(storeIDs))
+	for _, storeID := range storeIDs {
+		store := c.GetStore(storeID)
+		if store == nil {
+			return nil, errs.ErrStoreNotFound.FastGenByArgs(storeID)
+		}
+		if store.IsRemoved() {
+			return nil, errs.ErrStoreRemoved.FastGenByArgs(storeID)
+		}
+		// Clone the store first, MergeLabels updates the labels in place.
+		newStore := store.Clone()
+		labels := make([]*metapb.StoreLabel, 0, len(patch))
+		for _, label := range patch {
+			labels = append(labels, &metapb.StoreLabel{Key: label.GetKey(), Value: label.GetValue()})
+		}
+		newStore = newStore.Clone(core.SetStoreLabels(newStore.MergeLabels(labels)))
+		if err := c.checkStoreLabels(newStore); err != nil {
+			return nil, errors.Annotatef(err, "store %d", storeID)
+		}
+		origins = append(origins, store)
+		newStores = append(newStores, newStore)
+	}
+
+	result := make(map[uint64][]*metapb.StoreLabel, len(newStores))
+	for _, store := range newStores {
+		result[store.GetID()] = store.GetLabels()
+	}
+	if dryRun {
+		return result, nil
+	}
+	for i, store := range newStores {
+		if err := c.putStoreLocked(store); err != nil {
+			c.restoreStoresLocked(origins[:i])
+			return nil, err
+		}
+	}
+	return result, nil
+}
+
+// restoreStoresLocked puts the origin stores back. The cluster lock is held
+// since the stores are fetched, so no heartbeat can update them in between.
+func (c *RaftCluster) restoreStoresLocked(origins []*core.StoreInfo) {
+	for _, origin := range origins {
+		if err := c.putStoreLocked(origin); err != nil {
+			log.Error("failed to restore the labels of the store",
+				zap.Uint64("store-id", origin.GetID()), errs.ZapError(err))
+		}
+	}
+}
+
 // PutStore puts a store.
 func (c *RaftCluster) PutStore(store *metapb.Store) error {
 	if err := c.putStoreImpl(store, false); err != nil {
@@ -1075,6 +1922,9 @@ func (c *RaftCluster) putStoreImpl(store *metapb.Store, force bool) error {
 	if err := c.checkStoreVersion(store); err != nil {
 		return err
 	}
+	if err := c.checkStoreVersionSkew(store); err != nil {
+		return err
+	}
 
 	// Store address can not be the same as other stores.
 	for _, s := range c.GetStores() {
@@ -1176,9 +2026,13 @@ func (c *RaftCluster) RemoveStore(storeID uint64, physicallyDestroyed bool) erro
 		return errs.ErrStoreDestroyed.FastGenByArgs(storeID)
 	}
 
+	var bypassed error
 	if (store.IsPreparing() || store.IsServing()) && !physicallyDestroyed {
 		if err := c.checkReplicaBeforeOfflineStore(storeID); err != nil {
-			return err
+			if !errs.ErrStoresNotEnough.Equal(err) || !c.breakGlass.allows(BreakGlassRemoveStore) {
+				return err
+			}
+			bypassed = err
 		}
 	}
 
@@ -1189,21 +2043,30 @@ func (c *RaftCluster) RemoveStore(storeID uint64, physicallyDestroyed bool) erro
 		zap.Bool("physically-destroyed", newStore.IsPhysicallyDestroyed()))
 	err := c.putStoreLocked(newStore)
 	if err == nil {
-		regionSize := float64(c.core.GetStoreRegionSize(storeID))
-		c.resetProgress(storeID, store.GetAddress())
-		c.progressManager.AddProgress(encodeRemovingProgressKey(storeID), regionSize, regionSize, nodeStateCheckJobInterval)
-		// record the current store limit in memory
-		c.prevStoreLimit[storeID] = map[storelimit.Type]float64{
-			storelimit.AddPeer:    c.GetStoreLimitByType(storeID, storelimit.AddPeer),
-			storelimit.RemovePeer: c.GetStoreLimitByType(storeID, storelimit.RemovePeer),
+		c.onStoreOfflineLocked(store)
+		if bypassed != nil {
+			c.breakGlass.record(BreakGlassRemoveStore, "store "+strconv.FormatUint(storeID, 10), bypassed.Error())
 		}
-		// TODO: if the persist operation encounters error, the "Unlimited" will be rollback.
-		// And considering the store state has changed, RemoveStore is actually successful.
-		_ = c.SetStoreLimit(storeID, storelimit.RemovePeer, storelimit.Unlimited)
 	}
 	return err
 }
 
+// onStoreOfflineLocked updates the progress and the store limit after the store is set as offline.
+func (c *RaftCluster) onStoreOfflineLocked(store *core.StoreInfo) {
+	storeID := store.GetID()
+	regionSize := float64(c.core.GetStoreRegionSize(storeID))
+	c.resetProgress(storeID, store.GetAddress())
+	c.progressManager.AddProgress(encodeRemovingProgressKey(storeID), regionSize, regionSize, nodeStateCheckJobInterval)
+	// record the current store limit in memory
+	c.prevStoreLimit[storeID] = map[storelimit.Type]float64{
+		storelimit.AddPeer:    c.GetStoreLimitByType(storeID, storelimit.AddPeer),
+		storelimit.RemovePeer: c.GetStoreLimitByType(storeID, storelimit.RemovePeer),
+	}
+	// TODO: if the persist operation encounters error, the "Unlimited" will be rollback.
+	// And considering the store state has changed, RemoveStore is actually successful.
+	_ = c.SetStoreLimit(storeID, storelimit.RemovePeer, storelimit.Unlimited)
+}
+
 func (c *RaftCluster) checkReplicaBeforeOfflineStore(storeID uint64) error {
 	upStores := c.getUpStores()
 	expectUpStoresNum := len(upStores) - 1
@@ -1300,15 +2163,22 @@ func (c *RaftCluster) BuryStore(storeID uint64, forceBury bool) error {
 	err := c.putStoreLocked(newStore)
 	c.onStoreVersionChangeLocked()
 	if err == nil {
-		// clean up the residual information.
-		delete(c.prevStoreLimit, storeID)
-		c.RemoveStoreLimit(storeID)
-		c.resetProgress(storeID, store.GetAddress())
-		c.hotStat.RemoveRollingStoreStats(storeID)
+		c.onStoreTombstoneLocked(store)
 	}
 	return err
 }
 
+// onStoreTombstoneLocked cleans up the residual information after the store is set as tombstone.
+func (c *RaftCluster) onStoreTombstoneLocked(store *core.StoreInfo) {
+	storeID := store.GetID()
+	delete(c.prevStoreLimit, storeID)
+	c.lowSpace.forget(storeID)
+	c.slowTrend.forget(storeID)
+	c.RemoveStoreLimit(storeID)
+	c.resetProgress(storeID, store.GetAddress())
+	c.hotStat.RemoveRollingStoreStats(storeID)
+}
+
 // PauseLeaderTransfer prevents the store from been selected as source or
 // target store of TransferLeader.
 func (c *RaftCluster) PauseLeaderTransfer(storeID uint64) error {
@@ -1354,29 +2224,39 @@ func (c *RaftCluster) UpStore(storeID uint64) error {
 		return nil
 	}
 
+	newStore := c.upStoreLocked(store)
+	log.Warn("store has been up",
+		zap.Uint64("store-id", storeID),
+		zap.String("store-address", newStore.GetAddress()))
+	err := c.putStoreLocked(newStore)
+	if err == nil {
+		c.onStoreUpLocked(store)
+	}
+	return err
+}
+
+// upStoreLocked returns the up store with the store limit recorded before it was set as offline.
+func (c *RaftCluster) upStoreLocked(store *core.StoreInfo) *core.StoreInfo {
 	options := []core.StoreCreateOption{core.UpStore()}
 	// get the previous store limit recorded in memory
-	limiter, exist := c.prevStoreLimit[storeID]
-	if exist {
+	if limiter, exist := c.prevStoreLimit[store.GetID()]; exist {
 		options = append(options,
 			core.ResetStoreLimit(storelimit.AddPeer, limiter[storelimit.AddPeer]),
 			core.ResetStoreLimit(storelimit.RemovePeer, limiter[storelimit.RemovePeer]),
 		)
 	}
-	newStore := store.Clone(options...)
-	log.Warn("store has been up",
-		zap.Uint64("store-id", storeID),
-		zap.String("store-address", newStore.GetAddress()))
-	err := c.putStoreLocked(newStore)
-	if err == nil {
-		if exist {
-			// persist the store limit
-			_ = c.SetStoreLimit(storeID, storelimit.AddPeer, limiter[storelimit.AddPeer])
-			_ = c.SetStoreLimit(storeID, storelimit.RemovePeer, limiter[storelimit.RemovePeer])
-		}
-		c.resetProgress(storeID, store.GetAddress())
+	return store.Clone(options...)
+}
+
+// onStoreUpLocked persists the recovered store limit and resets the progress after the store is set as up.
+func (c *RaftCluster) onStoreUpLocked(store *core.StoreInfo) {
+	storeID := store.GetID()
+	if limiter, exist := c.prevStoreLimit[storeID]; exist {
+		// persist the store limit
+		_ = c.SetStoreLimit(storeID, storelimit.AddPeer, limiter[storelimit.AddPeer])
+		_ = c.SetStoreLimit(storeID, storelimit.RemovePeer, limiter[storelimit.RemovePeer])
 	}
-	return err
+	c.resetProgress(storeID, store.GetAddress())
 }
 
 // ReadyToServe change store's node state to Serving.
@@ -1419,7 +2299,9 @@ func (c *RaftCluster) SetStoreWeight(storeID uint64, leaderWeight, regionWeight
 		return errs.ErrStoreNotFound.FastGenByArgs(storeID)
 	}
 
-	if err := c.storage.SaveStoreWeight(storeID, leaderWeight, regionWeight); err != nil {
+	if err := c.persistWithRetry("store-weight", func() error {
+		return c.storage.SaveStoreWeight(storeID, leaderWeight, regionWeight)
+	}); err != nil {
 		return err
 	}
 
@@ -1431,12 +2313,43 @@ func (c *RaftCluster) SetStoreWeight(storeID uint64, leaderWeight, regionWeight
 	return c.putStoreLocked(newStore)
 }
 
+// UpdateStoreTopology updates the hardware topology reported by a store with
+// its heartbeats or by the API. The topology is persisted only if it changes.
+func (c *RaftCluster) UpdateStoreTopology(storeID uint64, topology *core.StoreTopology) error {
+	if err := topology.Validate(); err != nil {
+		return err
+	}
+	c.Lock()
+	defer c.Unlock()
+	store := c.GetStore(storeID)
+	if store == nil {
+		return errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+	if store.GetTopology().Equal(topology) {
+		return nil
+	}
+	if c.storage != nil {
+		if err := c.persistLocked("store-topology", func() error {
+			return c.storage.SaveStoreTopology(storeID, topology)
+		}); err != nil {
+			return err
+		}
+	}
+	c.core.PutStore(store.Clone(core.SetStoreTopology(topology)))
+	return nil
+}
+
 func (c *RaftCluster) putStoreLocked(store *core.StoreInfo) error {
 	if c.storage != nil {
-		if err := c.storage.SaveStore(store.GetMeta()); err != nil {
+		if err := c.persistLocked("store", func() error {
+			return c.storage.SaveStore(store.GetMeta())
+		}); err != nil {
 			return err
 		}
 	}
+	if c.topologyChanges != nil {
+		c.topologyChanges.observe(c.GetStore(store.GetID()), store)
+	}
 	c.core.PutStore(store)
 	c.hotStat.GetOrCreateRollingStoreStats(store.GetID())
 	return nil
@@ -1446,6 +2359,8 @@ func (c *RaftCluster) checkStores() {
 	var offlineStores []*metapb.Store
 	var upStoreCount int
 	stores := c.GetStores()
+	preparingDetails := make(map[uint64]*StorePreparingDetail)
+	defer func() { c.updatePreparingDetails(preparingDetails) }()
 
 	for _, store := range stores {
 		// the store has already been tombstone
@@ -1462,9 +2377,10 @@ func (c *RaftCluster) checkStores() {
 						errs.ZapError(err))
 				}
 			} else if c.IsPrepared() {
-				threshold := c.getThreshold(stores, store)
+				threshold, weight := c.getThresholdWithWeight(stores, store)
 				log.Debug("store serving threshold", zap.Uint64("store-id", storeID), zap.Float64("threshold", threshold))
 				regionSize := float64(store.GetRegionSize())
+				preparingDetails[storeID] = c.newPreparingDetail(store, threshold, regionSize, weight)
 				if regionSize >= threshold {
 					if err := c.ReadyToServe(storeID); err != nil {
 						log.Error("change store to serving failed",
@@ -1519,51 +2435,56 @@ func (c *RaftCluster) checkStores() {
 }
 
 func (c *RaftCluster) getThreshold(stores []*core.StoreInfo, store *core.StoreInfo) float64 {
+	threshold, _ := c.getThresholdWithWeight(stores, store)
+	return threshold
+}
+
+// getThresholdWithWeight returns the region size threshold for the preparing
+// store to become serving, and the topology weight of the store, i.e. the
+// ratio of its expected region size to the total size of the replicas it may
+// hold.
+func (c *RaftCluster) getThresholdWithWeight(stores []*core.StoreInfo, store *core.StoreInfo) (float64, float64) {
 	start := time.Now()
 	if !c.opt.IsPlacementRulesEnabled() {
 		regionSize := c.core.GetRegionSizeByRange([]byte(""), []byte("")) * int64(c.opt.GetMaxReplicas())
 		weight := getStoreTopoWeight(store, stores, c.opt.GetLocationLabels())
-		return float64(regionSize) * weight * 0.9
+		return float64(regionSize) * weight * 0.9, weight
 	}
 
-	keys := c.ruleManager.GetSplitKeys([]byte(""), []byte(""))
-	if len(keys) == 0 {
-		return c.calculateRange(stores, store, []byte(""), []byte("")) * 0.9
-	}
-
-	storeSize := 0.0
+	var storeSize, replicaSize float64
 	startKey := []byte("")
-	for _, key := range keys {
+	for _, key := range c.ruleManager.GetSplitKeys([]byte(""), []byte("")) {
 		endKey := key
-		storeSize += c.calculateRange(stores, store, startKey, endKey)
+		s, r := c.calculateRange(stores, store, startKey, endKey)
+		storeSize, replicaSize = storeSize+s, replicaSize+r
 		startKey = endKey
 	}
 	// the range from the last split key to the last key
-	storeSize += c.calculateRange(stores, store, startKey, []byte(""))
+	s, r := c.calculateRange(stores, store, startKey, []byte(""))
+	storeSize, replicaSize = storeSize+s, replicaSize+r
 	log.Debug("threshold calculation time", zap.Duration("cost", time.Since(start)))
-	return storeSize * 0.9
+	var weight float64
+	if replicaSize > 0 {
+		weight = storeSize / replicaSize
+	}
+	return storeSize * 0.9, weight
 }
 
-func (c *RaftCluster) calculateRange(stores []*core.StoreInfo, store *core.StoreInfo, startKey, endKey []byte) float64 {
-	var storeSize float64
+// calculateRange returns the expected region size of the store in the range,
+// and the total size of the replicas in the range the store may hold.
+func (c *RaftCluster) calculateRange(stores []*core.StoreInfo, store *core.StoreInfo, startKey, endKey []byte) (float64, float64) {
+	var storeSize, replicaSize float64
 	rules := c.ruleManager.GetRulesForApplyRange(startKey, endKey)
 	for _, rule := range rules {
 		if !placement.MatchLabelConstraints(store, rule.LabelConstraints) {
 			continue
 		}
 
-		var matchStores []*core.StoreInfo
-		for _, s := range stores {
-			if s.IsRemoving() || s.IsRemoved() {
-				continue
-			}
-			if placement.MatchLabelConstraints(s, rule.LabelConstraints) {
-				matchStores = append(matchStores, s)
-			}
-		}
+		matchStores := matchRuleStores(stores, rule)
 		regionSize := c.core.GetRegionSizeByRange(startKey, endKey) * int64(rule.Count)
 		weight := getStoreTopoWeight(store, matchStores, rule.LocationLabels)
 		storeSize += float64(regionSize) * weight
+		replicaSize += float64(regionSize)
 		log.Debug("calculate range result",
 			logutil.ZapRedactString("start-key", string(core.HexRegionKey(startKey))),
 			logutil.ZapRedactString("end-key", string(core.HexRegionKey(endKey))),
@@ -1574,7 +2495,7 @@ func (c *RaftCluster) calculateRange(stores []*core.StoreInfo, store *core.Store
 			zap.Float64("store-size", storeSize),
 		)
 	}
-	return storeSize
+	return storeSize, replicaSize
 }
 
 func getStoreTopoWeight(store *core.StoreInfo, stores []*core.StoreInfo, locationLabels []string) float64 {
@@ -1742,26 +2663,29 @@ func (c *RaftCluster) deleteStoreLocked(store *core.StoreInfo) error {
 		}
 	}
 	c.core.DeleteStore(store)
+	c.deleteStoreNotes(store.GetID())
 	return nil
 }
 
 func (c *RaftCluster) collectMetrics() {
-	statsMap := statistics.NewStoreStatisticsMap(c.opt, c.storeConfigManager.GetStoreConfig())
 	stores := c.GetStores()
 	for _, s := range stores {
-		statsMap.Observe(s, c.hotStat.StoresStats)
+		c.storeStats.Observe(s, c.hotStat.StoresStats)
 	}
-	statsMap.Collect()
+	c.storeStats.Collect()
 
+	metricutil.ApplyCardinality(c.opt.GetMetricsLabelGranularity(), c.opt.GetDisabledMetrics())
 	c.coordinator.collectSchedulerMetrics()
 	c.coordinator.collectHotSpotMetrics()
 	c.collectClusterMetrics()
 	c.collectHealthStatus()
+	c.checkStoreQuotas()
+	c.AdjustWorkerPools()
+	c.workerPools.Collect()
 }
 
 func (c *RaftCluster) resetMetrics() {
-	statsMap := statistics.NewStoreStatisticsMap(c.opt, c.storeConfigManager.GetStoreConfig())
-	statsMap.Reset()
+	c.storeStats.Reset()
 
 	c.coordinator.resetSchedulerMetrics()
 	c.coordinator.resetHotSpotMetrics()
@@ -1776,6 +2700,7 @@ func (c *RaftCluster) collectClusterMetrics() {
 	}
 	c.regionStats.Collect()
 	c.labelLevelStats.Collect()
+	c.regionLabelStats.Collect()
 	// collect hot cache metrics
 	c.hotStat.CollectMetrics()
 }
@@ -1786,6 +2711,7 @@ func (c *RaftCluster) resetClusterMetrics() {
 	}
 	c.regionStats.Reset()
 	c.labelLevelStats.Reset()
+	c.regionLabelStats.Reset()
 	// reset hot cache metrics
 	c.hotStat.ResetMetrics()
 }
@@ -1821,6 +2747,7 @@ func (c *RaftCluster) GetRegionStatsByType(typ statistics.RegionStatisticType) [
 	if c.regionStats == nil {
 		return nil
 	}
+	c.waitStatisticsObserved()
 	return c.regionStats.GetRegionStatsByType(typ)
 }
 
@@ -1829,15 +2756,102 @@ func (c *RaftCluster) GetOfflineRegionStatsByType(typ statistics.RegionStatistic
 	if c.regionStats == nil {
 		return nil
 	}
+	c.waitStatisticsObserved()
 	return c.regionStats.GetOfflineRegionStatsByType(typ)
 }
 
+// GetRegionAvailabilityCounts returns the number of the regions of each
+// availability risk. The regions which are not classified are healthy.
+func (c *RaftCluster) GetRegionAvailabilityCounts() map[string]int {
+	counts := make(map[string]int)
+	if c.regionStats == nil {
+		return counts
+	}
+	c.waitStatisticsObserved()
+	healthy := c.GetRegionCount()
+	for availability, typ := range map[statistics.RegionAvailability]statistics.RegionStatisticType{
+		statistics.RegionDegraded:    statistics.DegradedRegion,
+		statistics.RegionAtRisk:      statistics.AtRiskRegion,
+		statistics.RegionUnavailable: statistics.UnavailableRegion,
+	} {
+		count := c.regionStats.GetRegionStatsCountByType(typ)
+		counts[availability.String()] = count
+		healthy -= count
+	}
+	if healthy < 0 {
+		healthy = 0
+	}
+	counts[statistics.RegionHealthy.String()] = healthy
+	return counts
+}
+
+// observeRegionStats updates the statistics of the region, it is done in the
+// background if the statistics observer is started.
+func (c *RaftCluster) observeRegionStats(region *core.RegionInfo) {
+	if c.statsObserver != nil {
+		c.statsObserver.observeRegion(region)
+		return
+	}
+	if c.regionStats != nil {
+		c.regionStats.Observe(region, c.getRegionStoresLocked(region))
+	}
+}
+
+// clearDefunctRegionStats removes the statistics of the overlapped region.
+func (c *RaftCluster) clearDefunctRegionStats(regionID uint64) {
+	if c.statsObserver != nil {
+		c.statsObserver.clearDefunctRegion(regionID)
+		return
+	}
+	if c.regionStats != nil {
+		c.regionStats.ClearDefunctRegion(regionID)
+	}
+	c.labelLevelStats.ClearDefunctRegion(regionID)
+}
+
+// waitStatisticsObserved applies the queued statistics updates, so that the
+// readers can see the results of the handled heartbeats.
+func (c *RaftCluster) waitStatisticsObserved() {
+	if c.statsObserver != nil {
+		c.statsObserver.flush()
+	}
+}
+
 func (c *RaftCluster) updateRegionsLabelLevelStats(regions []*core.RegionInfo) {
+	if c.statsObserver != nil {
+		c.statsObserver.observeLabelLevel(regions)
+		return
+	}
 	for _, region := range regions {
 		c.labelLevelStats.Observe(region, c.getStoresWithoutLabelLocked(region, core.EngineKey, core.EngineTiFlash), c.opt.GetLocationLabels())
 	}
 }
 
+func (c *RaftCluster) updateRegionsLabelComplianceStats(regions []*core.RegionInfo) {
+	if c.regionLabeler == nil {
+		return
+	}
+	for _, region := range regions {
+		regionLabels := c.regionLabeler.GetRegionLabels(region)
+		labels := make(map[string]string, len(regionLabels))
+		for _, label := range regionLabels {
+			labels[label.Key] = label.Value
+		}
+		var satisfied bool
+		if c.opt.IsPlacementRulesEnabled() {
+			satisfied = c.ruleManager.FitRegion(c, region).IsSatisfied()
+		} else {
+			satisfied = len(region.GetPeers()) == c.opt.GetMaxReplicas()
+		}
+		c.regionLabelStats.Observe(region.GetID(), labels, satisfied)
+	}
+}
+
+// GetRegionLabelStats returns the region statistics per region label.
+func (c *RaftCluster) GetRegionLabelStats() []*statistics.RegionLabelStat {
+	return c.regionLabelStats.GetStats()
+}
+
 func (c *RaftCluster) getRegionStoresLocked(region *core.RegionInfo) []*core.StoreInfo {
 	stores := make([]*core.StoreInfo, 0, len(region.GetPeers()))
 	for _, p := range region.GetPeers() {
@@ -1866,6 +2880,7 @@ func (c *RaftCluster) OnStoreVersionChange() {
 }
 
 func (c *RaftCluster) onStoreVersionChangeLocked() {
+	c.updateStoreVersionLagsLocked()
 	var minVersion *semver.Version
 	stores := c.GetStores()
 	for _, s := range stores {
@@ -1887,11 +2902,12 @@ func (c *RaftCluster) onStoreVersionChangeLocked() {
 	if minVersion == nil || clusterVersion.Equal(*minVersion) {
 		return
 	}
+	c.gateVersionChangeLocked(minVersion)
 
 	if !c.opt.CASClusterVersion(clusterVersion, minVersion) {
 		log.Error("cluster version changed by API at the same time")
 	}
-	err := c.opt.Persist(c.storage)
+	err := c.persistOptionsLocked("cluster-version")
 	if err != nil {
 		log.Error("persist cluster version meet error", errs.ZapError(err))
 	}
@@ -1961,6 +2977,54 @@ func (c *RaftCluster) BucketsStats(degree int) map[uint64][]*buckets.BucketStat
 	return task.WaitRet(c.ctx)
 }
 
+// GetRegionBuckets returns the bucket stats of the given regions and the regions
+// they are inherited from. The regions without buckets are skipped.
+func (c *RaftCluster) GetRegionBuckets(regionIDs ...uint64) []*buckets.RegionBuckets {
+	statsTask := buckets.NewCollectRegionBucketsTask(regionIDs...)
+	lineageTask := buckets.NewCollectBucketLineageTask(regionIDs...)
+	if !c.hotBuckets.CheckAsync(statsTask) || !c.hotBuckets.CheckAsync(lineageTask) {
+		return nil
+	}
+	stats, lineages := statsTask.WaitRet(c.ctx), lineageTask.WaitRet(c.ctx)
+	ret := make([]*buckets.RegionBuckets, 0, len(stats))
+	for _, regionID := range regionIDs {
+		if s, ok := stats[regionID]; ok {
+			ret = append(ret, buckets.NewRegionBuckets(regionID, s, lineages[regionID]))
+		}
+	}
+	return ret
+}
+
+// RecommendBuckets returns the split keys and merge candidates recommended by the buckets of the given regions.
+// Only the regions that have something to recommend are returned.
+func (c *RaftCluster) RecommendBuckets(regions []*core.RegionInfo, hotDegree, coldDegree int) []*buckets.RegionBucketsRecommendation {
+	regionIDs := make([]uint64, 0, len(regions))
+	for _, region := range regions {
+		regionIDs = append(regionIDs, region.GetID())
+	}
+	task := buckets.NewCollectRegionBucketsTask(regionIDs...)
+	if !c.hotBuckets.CheckAsync(task) {
+		return nil
+	}
+	stats := task.WaitRet(c.ctx)
+	storeConfig := c.GetStoreConfig()
+	recommendations := make([]*buckets.RegionBucketsRecommendation, 0)
+	for _, region := range regions {
+		rec := buckets.RecommendBuckets(stats[region.GetID()], &buckets.RecommendOption{
+			HotDegree:       hotDegree,
+			ColdDegree:      coldDegree,
+			RegionSize:      region.GetApproximateSize(),
+			RegionMaxSize:   storeConfig.GetRegionMaxSize(),
+			RegionSplitSize: storeConfig.GetRegionSplitSize(),
+			BucketSize:      storeConfig.GetRegionBucketSize(),
+		})
+		if rec != nil && !rec.IsEmpty() {
+			recommendations = append(recommendations, rec)
+		}
+	}
+	return recommendations
+}
+
 // RegionWriteStats returns hot region's write stats.
 // The result only includes peers that are hot enough.
 func (c *RaftCluster) RegionWriteStats() map[uint64][]*statistics.HotPeerStat {
@@ -1968,6 +3032,17 @@ func (c *RaftCluster) RegionWriteStats() map[uint64][]*statistics.HotPeerStat {
 	return c.hotStat.RegionStats(statistics.Write, c.GetOpts().GetHotRegionCacheHitsThreshold())
 }
 
+// GetHotPeerExemplars returns the hottest peers of each store with their regions,
+// which are refreshed at most once in the refresh interval.
+func (c *RaftCluster) GetHotPeerExemplars() *HotPeerExemplars {
+	return c.hotPeerExemplars.get(time.Now())
+}
+
+// RegionPeerWriteStats returns the write stats of the peers of the region.
+func (c *RaftCluster) RegionPeerWriteStats(region *core.RegionInfo) []*statistics.HotPeerStat {
+	return c.hotStat.GetRegionPeerStats(statistics.Write, region)
+}
+
 // TODO: remove me.
 // only used in test.
 func (c *RaftCluster) putRegion(region *core.RegionInfo) error {
@@ -2047,18 +3122,15 @@ func (c *RaftCluster) AddStoreLimit(store *metapb.Store) {
 
 	cfg.StoreLimit[storeID] = sc
 	c.opt.SetScheduleConfig(cfg)
-	var err error
-	for i := 0; i < persistLimitRetryTimes; i++ {
-		if err = c.opt.Persist(c.storage); err == nil {
-			log.Info("store limit added", zap.Uint64("store-id", storeID))
-			return
-		}
-		time.Sleep(persistLimitWaitTime)
+	if err := c.persistWithRetry("store-limit", c.persistOptions); err != nil {
+		log.Error("persist store limit meet error", errs.ZapError(err))
+		return
 	}
-	log.Error("persist store limit meet error", errs.ZapError(err))
+	log.Info("store limit added", zap.Uint64("store-id", storeID))
 }
 
 // RemoveStoreLimit remove a store limit for a given store ID.
+// It's called with the cluster lock held.
 func (c *RaftCluster) RemoveStoreLimit(storeID uint64) {
 	cfg := c.opt.GetScheduleConfig().Clone()
 	for _, limitType := range storelimit.TypeNameValue {
@@ -2066,18 +3138,79 @@ func (c *RaftCluster) RemoveStoreLimit(storeID uint64) {
 	}
 	delete(cfg.StoreLimit, storeID)
 	c.opt.SetScheduleConfig(cfg)
-	var err error
-	for i := 0; i < persistLimitRetryTimes; i++ {
-		if err = c.opt.Persist(c.storage); err == nil {
-			log.Info("store limit removed", zap.Uint64("store-id", storeID))
-			id := strconv.FormatUint(storeID, 10)
-			statistics.StoreLimitGauge.DeleteLabelValues(id, "add-peer")
-			statistics.StoreLimitGauge.DeleteLabelValues(id, "remove-peer")
-			return
-		}
-		time.Sleep(persistLimitWaitTime)
+	if err := c.persistOptionsLocked("store-limit"); err != nil {
+		log.Error("persist store limit meet error", errs.ZapError(err))
+		return
+	}
+	log.Info("store limit removed", zap.Uint64("store-id", storeID))
+	id := strconv.FormatUint(storeID, 10)
+	statistics.StoreLimitGauge.DeleteLabelValues(id, "add-peer")
+	statistics.StoreLimitGauge.DeleteLabelValues(id, "remove-peer")
+}
+
+// persistWithRetry runs the persist function with exponential backoff, and
+// records the failure if it still fails after retries. It must not be called
+// with the cluster lock held, use persistLocked instead.
+func (c *RaftCluster) persistWithRetry(typ string, persist func() error) error {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	err := retryutil.DefaultBackoff.Do(ctx, persist)
+	if err != nil {
+		persistFailureCounter.WithLabelValues(typ).Inc()
+	}
+	return err
+}
+
+// persistLocked runs the persist function only once, so that the cluster lock
+// is never held while waiting for a retry.
+func (c *RaftCluster) persistLocked(typ string, persist func() error) error {
+	err := persist()
+	if err != nil {
+		persistFailureCounter.WithLabelValues(typ).Inc()
 	}
-	log.Error("persist store limit meet error", errs.ZapError(err))
+	return err
+}
+
+// persistOptionsLocked persists the options with the cluster lock held. The
+// options have been changed in memory, so if it fails, the latest options are
+// persisted again in the background without the lock.
+func (c *RaftCluster) persistOptionsLocked(typ string) error {
+	err := c.persistLocked(typ, c.persistOptions)
+	if err != nil {
+		c.retryPersistOptions(typ)
+	}
+	return err
+}
+
+// retryPersistOptions persists the latest options with backoff in the
+// background. The retries requested at the same time are coalesced since any
+// of them persists the latest options.
+func (c *RaftCluster) retryPersistOptions(typ string) {
+	atomic.StoreInt32(&c.optionsDirty, 1)
+	if !atomic.CompareAndSwapInt32(&c.optionsRetrying, 0, 1) {
+		return
+	}
+	go func() {
+		defer logutil.LogPanic()
+		for {
+			for atomic.SwapInt32(&c.optionsDirty, 0) == 1 {
+				if err := c.persistWithRetry(typ, c.persistOptions); err != nil {
+					log.Error("retry persisting options meet error", zap.String("type", typ), errs.ZapError(err))
+				}
+			}
+			atomic.StoreInt32(&c.optionsRetrying, 0)
+			// another retry may be requested before the flag is cleared.
+			if atomic.LoadInt32(&c.optionsDirty) == 0 || !atomic.CompareAndSwapInt32(&c.optionsRetrying, 0, 1) {
+				return
+			}
+		}
+	}()
+}
+
+func (c *RaftCluster) persistOptions() error {
+	return c.opt.Persist(c.storage)
 }
 
 // SetMinResolvedTS sets up a store with min resolved ts.
@@ -2104,15 +3237,7 @@ func (c *RaftCluster) checkAndUpdateMinResolvedTS() (uint64, bool) {
 	if !c.isInitialized() {
 		return math.MaxUint64, false
 	}
-	curMinResolvedTS := uint64(math.MaxUint64)
-	for _, s := range c.GetStores() {
-		if !core.IsAvailableForMinResolvedTS(s) {
-			continue
-		}
-		if curMinResolvedTS > s.GetMinResolvedTS() {
-			curMinResolvedTS = s.GetMinResolvedTS()
-		}
-	}
+	curMinResolvedTS := c.minResolvedTSTracker.update(c.GetStores(), time.Now()).MinResolvedTS
 	if curMinResolvedTS == math.MaxUint64 || curMinResolvedTS <= c.minResolvedTS {
 		return c.minResolvedTS, false
 	}
@@ -2177,7 +3302,7 @@ func (c *RaftCluster) GetMinResolvedTS() uint64 {
 func (c *RaftCluster) SetStoreLimit(storeID uint64, typ storelimit.Type, ratePerMin float64) error {
 	old := c.opt.GetScheduleConfig().Clone()
 	c.opt.SetStoreLimit(storeID, typ, ratePerMin)
-	if err := c.opt.Persist(c.storage); err != nil {
+	if err := c.persistWithRetry("store-limit", c.persistOptions); err != nil {
 		// roll back the store limit
 		c.opt.SetScheduleConfig(old)
 		log.Error("persist store limit meet error", errs.ZapError(err))
@@ -2193,7 +3318,7 @@ func (c *RaftCluster) SetAllStoresLimit(typ storelimit.Type, ratePerMin float64)
 	oldAdd := config.DefaultStoreLimit.GetDefaultStoreLimit(storelimit.AddPeer)
 	oldRemove := config.DefaultStoreLimit.GetDefaultStoreLimit(storelimit.RemovePeer)
 	c.opt.SetAllStoresLimit(typ, ratePerMin)
-	if err := c.opt.Persist(c.storage); err != nil {
+	if err := c.persistWithRetry("store-limit", c.persistOptions); err != nil {
 		// roll back the store limit
 		c.opt.SetScheduleConfig(old)
 		config.DefaultStoreLimit.SetDefaultStoreLimit(storelimit.AddPeer, oldAdd)
@@ -2242,6 +3367,16 @@ func (c *RaftCluster) GetProgressByID(storeID string) (action string, process, l
 	return "", 0, 0, 0, errs.ErrProgressNotFound.FastGenByArgs(fmt.Sprintf("the given store ID: %s", storeID))
 }
 
+// GetRegionLoadingProgress returns the progress of loading the regions from the
+// storage. It works before the cluster is running, while the regions are loaded.
+func (c *RaftCluster) GetRegionLoadingProgress() (process, ls, cs float64, err error) {
+	process, ls, cs, err = c.regionLoading.Status(loadingRegionsProgress)
+	if err != nil {
+		return 0, 0, 0, errs.ErrProgressNotFound.FastGenByArgs(fmt.Sprintf("the action: %s", loadingRegionsProgress))
+	}
+	return process, ls, cs, nil
+}
+
 // GetProgressByAction returns the progress details for a given action.
 func (c *RaftCluster) GetProgressByAction(action string) (process, ls, cs float64, err error) {
 	filter := func(progress string) bool {
@@ -0,0 +1,170 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+	"go.uber.org/zap"
+)
+
+const (
+	maxClusterEvents       = 1024
+	eventWebhookTimeout    = 3 * time.Second
+	eventWebhookQueueLimit = 128
+)
+
+// The types of the cluster events.
+const (
+	EventStoreLowSpaceWarning   = "store-low-space-warning"
+	EventStoreLowSpaceCritical  = "store-low-space-critical"
+	EventStoreLowSpaceRecovered = "store-low-space-recovered"
+	EventReplicaFreezeExpired   = "replica-freeze-expired"
+	EventComponentDisabled      = "component-disabled"
+	EventRegionCacheStale       = "region-cache-stale"
+	EventRegionCacheRecovered   = "region-cache-recovered"
+	EventRegionCacheMutated     = "region-cache-mutated"
+	EventBreakGlassEnabled      = "break-glass-enabled"
+	EventBreakGlassAction       = "break-glass-action"
+	EventBreakGlassReverted     = "break-glass-reverted"
+)
+
+// ClusterEvent is an event of the cluster, which is kept in memory for the API and
+// posted to the event webhook if it is configured.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type ClusterEvent struct {
+	ID         uint64            `json:"id"`
+	Type       string            `json:"type"`
+	Time       time.Time         `json:"time"`
+	StoreID    uint64            `json:"store_id,omitempty"`
+	Message    string            `json:"message"`
+	Attributes map[string]string `json:"attributes,omitempty"`
+}
+
+// eventBus keeps the recent cluster events and posts them to the webhook.
+type eventBus struct {
+	syncutil.RWMutex
+	cluster *RaftCluster
+	nextID  uint64
+	events  []*ClusterEvent
+	// posting is the number of the events being posted to the webhook, the events
+	// are dropped from posting if the webhook is too slow.
+	posting int
+}
+
+func newEventBus(cluster *RaftCluster) *eventBus {
+	return &eventBus{cluster: cluster, nextID: 1}
+}
+
+// publish records the event and posts it to the webhook in background.
+func (b *eventBus) publish(event *ClusterEvent) {
+	b.Lock()
+	event.ID = b.nextID
+	b.nextID++
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	b.events = append(b.events, event)
+	if len(b.events) > maxClusterEvents {
+		b.events = b.events[len(b.events)-maxClusterEvents:]
+	}
+	url := b.cluster.opt.GetEventWebhookURL()
+	post := url != "" && b.posting < eventWebhookQueueLimit
+	if post {
+		b.posting++
+	}
+	b.Unlock()
+
+	log.Info("cluster event published",
+		zap.Uint64("id", event.ID),
+		zap.String("type", event.Type),
+		zap.Uint64("store-id", event.StoreID),
+		zap.String("message", event.Message))
+	clusterEventCounter.WithLabelValues(event.Type).Inc()
+	if url == "" {
+		return
+	}
+	if !post {
+		clusterEventWebhookCounter.WithLabelValues("dropped").Inc()
+		return
+	}
+	go func() {
+		defer func() {
+			b.Lock()
+			b.posting--
+			b.Unlock()
+		}()
+		b.postWebhook(url, event)
+	}()
+}
+
+func (b *eventBus) postWebhook(url string, event *ClusterEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Error("failed to marshal cluster event", errs.ZapError(errs.ErrJSONMarshal, err))
+		return
+	}
+	ctx, cancel := context.WithTimeout(b.cluster.ctx, eventWebhookTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(data))
+	if err != nil {
+		log.Warn("failed to create the request of event webhook", zap.String("url", url), errs.ZapError(err))
+		clusterEventWebhookCounter.WithLabelValues("failed").Inc()
+		return
+	}
+	req.Header.Set("Content-Type", "application/json")
+	client := b.cluster.httpClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Warn("failed to post cluster event to webhook", zap.String("url", url), zap.Uint64("id", event.ID), errs.ZapError(err))
+		clusterEventWebhookCounter.WithLabelValues("failed").Inc()
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		log.Warn("event webhook responded with error status", zap.String("url", url), zap.Uint64("id", event.ID), zap.Int("status", resp.StatusCode))
+		clusterEventWebhookCounter.WithLabelValues("failed").Inc()
+		return
+	}
+	clusterEventWebhookCounter.WithLabelValues("sent").Inc()
+}
+
+// list returns the events whose ID is greater than sinceID.
+func (b *eventBus) list(sinceID uint64) []*ClusterEvent {
+	b.RLock()
+	defer b.RUnlock()
+	events := make([]*ClusterEvent, 0, len(b.events))
+	for _, event := range b.events {
+		if event.ID > sinceID {
+			events = append(events, event)
+		}
+	}
+	return events
+}
+
+// GetClusterEvents returns the recent cluster events whose ID is greater than sinceID.
+func (c *RaftCluster) GetClusterEvents(sinceID uint64) []*ClusterEvent {
+	return c.events.list(sinceID)
+}
@@ -679,6 +679,269 @@ func TestBucketHeartbeat(t *testing.T) {
 	re.Empty(cluster.GetRegion(uint64(1)).GetBuckets().GetKeys())
 }
 
+func TestRegionHeartbeatInspection(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	for _, store := range newTestStores(3, "2.0.0") {
+		re.NoError(cluster.putStoreLocked(store))
+	}
+	regions := newTestRegions(3, 3, 3)
+	for _, region := range regions {
+		re.NoError(cluster.processRegionHeartbeat(region))
+	}
+	origin := regions[1]
+
+	testCases := []*core.RegionInfo{
+		// Duplicated peers on the same store.
+		origin.Clone(core.SetPeers(append(origin.GetPeers(), &metapb.Peer{Id: 100, StoreId: origin.GetPeers()[0].GetStoreId()}))),
+		// Invalid key range.
+		origin.Clone(core.WithStartKey(origin.GetEndKey())),
+		// Zero-length keys without version change.
+		origin.Clone(core.WithStartKey([]byte(""))),
+		origin.Clone(core.WithEndKey([]byte(""))),
+		// Absurd size and keys jumps.
+		origin.Clone(core.SetApproximateSize(origin.GetApproximateSize() * 100000)),
+		origin.Clone(core.SetApproximateKeys(origin.GetApproximateKeys() * 10000000)),
+	}
+	for i, region := range testCases {
+		err := cluster.processRegionHeartbeat(region)
+		re.True(errs.ErrRegionQuarantined.Equal(err))
+		re.Len(cluster.GetQuarantinedRegions(), i+1)
+		// The region in cache is not changed.
+		checkRegion(re, cluster.GetRegion(origin.GetID()), origin)
+	}
+	quarantined := cluster.GetQuarantinedRegions()
+	re.Contains(quarantined[0].Reason, "duplicated peers")
+	re.Contains(quarantined[1].Reason, "invalid key range")
+	re.Contains(quarantined[2].Reason, "zero-length start key")
+	re.Contains(quarantined[3].Reason, "zero-length end key")
+	re.Contains(quarantined[4].Reason, "approximate size")
+	re.Contains(quarantined[5].Reason, "approximate keys")
+
+	// The stale heartbeats are rejected without being quarantined.
+	err = cluster.processRegionHeartbeat(origin.Clone(core.WithDecVersion()))
+	re.Error(err)
+	re.False(errs.ErrRegionQuarantined.Equal(err))
+	re.Len(cluster.GetQuarantinedRegions(), len(testCases))
+
+	// Normal changes are applied.
+	region := origin.Clone(core.WithIncVersion(), core.WithEndKey([]byte("")), core.SetApproximateSize(origin.GetApproximateSize()*2))
+	re.NoError(cluster.processRegionHeartbeat(region))
+	re.Len(cluster.GetQuarantinedRegions(), len(testCases))
+
+	// A genuine jump is accepted after the consecutive heartbeats report it,
+	// and the count is reset by a heartbeat without the jump.
+	jumped := region.Clone(core.SetApproximateSize(region.GetApproximateSize() * 100000))
+	re.True(errs.ErrRegionQuarantined.Equal(cluster.processRegionHeartbeat(jumped)))
+	re.NoError(cluster.processRegionHeartbeat(region))
+	for i := 1; i < absurdJumpConfirmations; i++ {
+		re.True(errs.ErrRegionQuarantined.Equal(cluster.processRegionHeartbeat(jumped)))
+		checkRegion(re, cluster.GetRegion(region.GetID()), region)
+	}
+	re.NoError(cluster.processRegionHeartbeat(jumped))
+	re.Equal(jumped.GetApproximateSize(), cluster.GetRegion(region.GetID()).GetApproximateSize())
+}
+
+func TestReconcileRuleGroup(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	opt.SetPlacementRuleEnabled(true)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	for _, region := range newTestRegions(10, 3, 3) {
+		re.NoError(cluster.putRegion(region))
+	}
+
+	re.NoError(cluster.ruleManager.SetRule(&placement.Rule{GroupID: "g1", ID: "r1", StartKey: []byte{1}, EndKey: []byte{3}, Role: "voter", Count: 3}))
+	re.NoError(cluster.ruleManager.SetRule(&placement.Rule{GroupID: "g1", ID: "r2", StartKey: []byte{2}, EndKey: []byte{5}, Role: "learner", Count: 1}))
+	re.NoError(cluster.ruleManager.SetRule(&placement.Rule{GroupID: "g2", ID: "r1", StartKey: []byte{8}, EndKey: []byte{9}, Role: "learner", Count: 1}))
+
+	// regions 1, 2, 3, 4 are matched by the rules of group g1.
+	re.Equal(4, cluster.ReconcileRuleGroup("g1"))
+	re.ElementsMatch([]uint64{1, 2, 3, 4}, cluster.GetSuspectRegions())
+	re.Equal(1, cluster.ReconcileRuleGroup("g2"))
+	re.Zero(cluster.ReconcileRuleGroup("not-exist"))
+	re.Len(cluster.GetSuspectRegions(), 5)
+}
+
+func TestProgressSnapshot(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	s := storage.NewStorageWithMemoryBackend()
+	basicCluster := core.NewBasicCluster()
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, basicCluster)
+	for _, store := range newTestStores(4, "5.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	re.NoError(cluster.RemoveStore(1, false))
+	cluster.progressManager.AddProgress(encodeRemovingProgressKey(1), 100, 100, nodeStateCheckJobInterval)
+	cluster.progressManager.UpdateProgress(encodeRemovingProgressKey(1), 80, 80, false)
+	cluster.progressManager.AddProgress(encodeRemovingProgressKey(2), 100, 100, nodeStateCheckJobInterval)
+	cluster.progressManager.AddProgress(loadingRegionsProgress, 0, 100, loadingRegionsProgressInterval)
+	cluster.saveProgresses()
+
+	// the new leader continues the progresses of the stores still being removed.
+	newCluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, basicCluster)
+	newCluster.restoreProgresses()
+	p, ls, cs, err := cluster.progressManager.Status(encodeRemovingProgressKey(1))
+	re.NoError(err)
+	np, nls, ncs, err := newCluster.progressManager.Status(encodeRemovingProgressKey(1))
+	re.NoError(err)
+	re.Equal(p, np)
+	re.Equal(ls, nls)
+	re.Equal(cs, ncs)
+	_, _, _, err = newCluster.progressManager.Status(encodeRemovingProgressKey(2))
+	re.Error(err)
+	_, _, _, err = newCluster.progressManager.Status(loadingRegionsProgress)
+	re.Error(err)
+}
+
+func TestHotPeerSnapshot(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	s := storage.NewStorageWithMemoryBackend()
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	cluster.hotStat.Update(&statistics.HotPeerStat{
+		StoreID:   1,
+		RegionID:  1,
+		HotDegree: 5,
+		AntiCount: 2,
+		Kind:      statistics.Write,
+		Loads:     make([]float64, statistics.RegionStatCount),
+	})
+	cluster.saveHotPeerSnapshots()
+
+	// the new leader restores the hot peers from the snapshot.
+	newCluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	newCluster.restoreHotPeerSnapshots()
+	stats := newCluster.hotStat.RegionStats(statistics.Write, 0)
+	re.Len(stats[1], 1)
+	re.Equal(uint64(1), stats[1][0].RegionID)
+	re.Equal(5, stats[1][0].HotDegree)
+	re.Empty(newCluster.hotStat.RegionStats(statistics.Read, 0))
+
+	// the chunks of the earlier snapshots are ignored and deleted.
+	snapshot := cluster.hotStat.Snapshot(statistics.Write)
+	snapshot.Timestamp--
+	re.NoError(s.SaveHotPeerSnapshotChunk(statistics.Write.String(), 3, snapshot))
+	newCluster = newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	newCluster.restoreHotPeerSnapshots()
+	re.Len(newCluster.hotStat.RegionStats(statistics.Write, 0)[1], 1)
+	re.Equal(4, newCluster.hotPeerSnapshotChunks[statistics.Write])
+	newCluster.saveHotPeerSnapshots()
+	re.Equal(1, newCluster.hotPeerSnapshotChunks[statistics.Write])
+	chunks := 0
+	re.NoError(s.LoadHotPeerSnapshotChunks(statistics.Write.String(), func(k, v string) { chunks++ }))
+	re.Equal(1, chunks)
+
+	// the stale snapshot is ignored.
+	snapshot.Timestamp = time.Now().Add(-2 * hotPeerSnapshotMaxStaleness).Unix()
+	re.NoError(s.SaveHotPeerSnapshotChunk(statistics.Write.String(), 0, snapshot))
+	newCluster = newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	newCluster.restoreHotPeerSnapshots()
+	re.Empty(newCluster.hotStat.RegionStats(statistics.Write, 0))
+}
+
+func TestTrackRegionLoading(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	idAllocator := mockid.NewIDAllocator()
+	for i := 0; i < 100; i++ {
+		_, err = idAllocator.Alloc()
+		re.NoError(err)
+	}
+	cluster := newTestRaftCluster(ctx, idAllocator, opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	put, finish := cluster.trackRegionLoading(cluster.core.CheckAndPutRegion)
+	process, _, _, err := cluster.GetRegionLoadingProgress()
+	re.NoError(err)
+	re.Equal(0.0, process)
+	// No ID is consumed to track the progress.
+	id, err := idAllocator.Alloc()
+	re.NoError(err)
+	re.Equal(uint64(101), id)
+
+	time.Sleep(loadingRegionsProgressInterval)
+	put(core.NewRegionInfo(&metapb.Region{Id: 50, StartKey: []byte("a"), EndKey: []byte("b")}, nil))
+	re.Equal(1, cluster.core.GetRegionCount())
+	process, _, _, err = cluster.GetRegionLoadingProgress()
+	re.NoError(err)
+	re.Greater(process, 0.0)
+	re.Less(process, 1.0)
+
+	finish()
+	_, _, _, err = cluster.GetRegionLoadingProgress()
+	re.Error(err)
+}
+
+func TestStoreNotesCache(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	for _, store := range newTestStores(2, "2.0.0") {
+		re.NoError(cluster.putStoreLocked(store))
+	}
+	note1, err := cluster.AddStoreNote(1, "disk replaced", "ops")
+	re.NoError(err)
+	note2, err := cluster.AddStoreNote(1, "network checked", "ops")
+	re.NoError(err)
+	notes, err := cluster.GetStoreNotes(1)
+	re.NoError(err)
+	re.Len(notes, 2)
+
+	// The notes are served from the cache.
+	re.NoError(cluster.storage.DeleteStoreNote(1, note2.ID))
+	notes, err = cluster.GetStoreNotes(1)
+	re.NoError(err)
+	re.Len(notes, 2)
+	all, err := cluster.GetAllStoreNotes()
+	re.NoError(err)
+	re.Len(all[1], 2)
+	re.Empty(all[2])
+
+	// The returned notes are not changed by the later updates.
+	_, err = cluster.UpdateStoreNote(1, note1.ID, "disk replaced again", "")
+	re.NoError(err)
+	re.Equal("disk replaced", notes[0].Content)
+	re.NoError(cluster.DeleteStoreNote(1, note1.ID))
+	re.Len(notes, 2)
+	notes, err = cluster.GetStoreNotes(1)
+	re.NoError(err)
+	re.Len(notes, 1)
+	re.Equal(note2.ID, notes[0].ID)
+
+	// The cache is reloaded from the storage after being reset.
+	cluster.resetStoreNotes()
+	notes, err = cluster.GetStoreNotes(1)
+	re.NoError(err)
+	re.Empty(notes)
+}
+
 func TestRegionHeartbeat(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -1338,6 +1601,97 @@ func TestUpdateStorePendingPeerCount(t *testing.T) {
 	checkPendingPeerCount([]int{0, 0, 0, 1}, tc.RaftCluster, re)
 }
 
+func TestUpdateStoreTopology(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	store := newTestStores(1, "2.0.0")[0]
+	re.NoError(cluster.putStoreLocked(store))
+
+	topology := &core.StoreTopology{NUMANodes: 2, DiskCount: 4, DiskType: "nvme"}
+	re.Error(cluster.UpdateStoreTopology(2, topology))
+	re.Error(cluster.UpdateStoreTopology(1, &core.StoreTopology{DiskCount: -1}))
+	re.NoError(cluster.UpdateStoreTopology(1, topology))
+	re.Equal(topology, cluster.GetStore(1).GetTopology())
+
+	// The topology should be kept after the store heartbeat.
+	re.NoError(cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: 1, Capacity: 100, Available: 50}))
+	re.Equal(topology, cluster.GetStore(1).GetTopology())
+	re.Equal("nvme", cluster.GetStore(1).GetLabelOrTopologyValue(core.TopologyDiskTypeKey))
+
+	// The topology is persisted and loaded with the stores.
+	var loaded *core.StoreInfo
+	re.NoError(cluster.storage.LoadStores(func(store *core.StoreInfo) { loaded = store }))
+	re.NotNil(loaded)
+	re.Equal(topology, loaded.GetTopology())
+}
+
+func TestUpdateStoresLabels(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cfg := opt.GetReplicationConfig().Clone()
+	cfg.LocationLabels = []string{"zone", "host"}
+	cfg.StrictlyMatchLabel = true
+	opt.SetReplicationConfig(cfg)
+	s := &storeSaveFailStorage{Storage: storage.NewStorageWithMemoryBackend(), failures: make(map[uint64]int)}
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	for _, store := range newTestStores(3, "2.0.0") {
+		store = store.Clone(core.SetStoreLabels([]*metapb.StoreLabel{
+			{Key: "zone", Value: "z1"},
+			{Key: "host", Value: fmt.Sprintf("h%d", store.GetID())},
+		}))
+		re.NoError(cluster.putStoreLocked(store))
+	}
+
+	// Removing a location label is rejected, and none of the stores is updated.
+	_, err = cluster.UpdateStoresLabels([]uint64{1, 2}, []*metapb.StoreLabel{{Key: "zone", Value: "z2"}, {Key: "host", Value: ""}}, false)
+	re.Error(err)
+	for _, storeID := range []uint64{1, 2} {
+		re.Equal("z1", cluster.GetStore(storeID).GetLabelValue("zone"))
+	}
+
+	// Dry run returns the resulting labels only.
+	patch := []*metapb.StoreLabel{{Key: "zone", Value: "z2"}}
+	result, err := cluster.UpdateStoresLabels([]uint64{1, 2}, patch, true)
+	re.NoError(err)
+	re.Len(result, 2)
+	for _, storeID := range []uint64{1, 2} {
+		re.Equal("z1", cluster.GetStore(storeID).GetLabelValue("zone"))
+		re.Equal("z2", core.NewStoreInfo(&metapb.Store{Labels: result[storeID]}).GetLabelValue("zone"))
+	}
+
+	_, err = cluster.UpdateStoresLabels([]uint64{1, 2}, patch, false)
+	re.NoError(err)
+	re.Equal("z2", cluster.GetStore(1).GetLabelValue("zone"))
+	re.Equal("z2", cluster.GetStore(2).GetLabelValue("zone"))
+	re.Equal("z1", cluster.GetStore(3).GetLabelValue("zone"))
+	re.Equal("h1", cluster.GetStore(1).GetLabelValue("host"))
+
+	_, err = cluster.UpdateStoresLabels([]uint64{1, 4}, patch, false)
+	re.Error(err)
+
+	// The updated stores are restored if a later one fails to be persisted.
+	s.failures[2] = 1
+	_, err = cluster.UpdateStoresLabels([]uint64{1, 2}, []*metapb.StoreLabel{{Key: "zone", Value: "z3"}}, false)
+	re.Error(err)
+	for _, storeID := range []uint64{1, 2} {
+		re.Equal("z2", cluster.GetStore(storeID).GetLabelValue("zone"))
+		meta := &metapb.Store{}
+		ok, err := s.LoadStore(storeID, meta)
+		re.NoError(err)
+		re.True(ok)
+		re.Equal("z2", core.NewStoreInfo(meta).GetLabelValue("zone"))
+	}
+}
+
 func TestTopologyWeight(t *testing.T) {
 	re := require.New(t)
 
@@ -1444,11 +1798,73 @@ func TestCalculateStoreSize1(t *testing.T) {
 	store := cluster.GetStore(1)
 	// 100 * 100 * 2 (placement rule) / 4 (host) * 0.9 = 4500
 	re.Equal(4500.0, cluster.getThreshold(stores, store))
+	_, weight := cluster.getThresholdWithWeight(stores, store)
+	re.Equal(0.25, weight)
+	weights := cluster.GetTopologyWeights(1)
+	re.True(weights.PlacementRulesEnabled)
+	re.Len(weights.Rules, 3)
+	re.Len(weights.Stores, 1)
+	re.Equal(4500.0, weights.Stores[0].Threshold)
+	re.Equal(0.25, weights.Stores[0].Weight)
+	// store 1 is in zone 1, so it only matches the rule zone1.
+	re.Len(weights.Stores[0].RuleWeights, 1)
+	re.Equal("zone1", weights.Stores[0].RuleWeights[0].ID)
+	re.Equal(0.25, weights.Stores[0].RuleWeights[0].Weight)
+	for _, rule := range weights.Rules {
+		if rule.ID == "zone1" {
+			re.Equal(4, rule.StoreCount)
+			re.Len(rule.Topology, 2)
+		}
+	}
+	re.Len(cluster.GetTopologyWeights().Stores, 10)
 
 	cluster.opt.SetPlacementRuleEnabled(false)
 	cluster.opt.SetLocationLabels([]string{"zone", "rack", "host"})
 	// 30000 (total region size) / 3 (zone) / 4 (host) * 0.9 = 2250
 	re.Equal(2250.0, cluster.getThreshold(stores, store))
+	_, weight = cluster.getThresholdWithWeight(stores, store)
+	re.Equal(1.0/3/4, weight)
+	weights = cluster.GetTopologyWeights(1)
+	re.False(weights.PlacementRulesEnabled)
+	re.Equal([]string{"zone", "rack", "host"}, weights.LocationLabels)
+	re.Len(weights.Topology, 3)
+	re.Equal(1.0/3/4, weights.Stores[0].Weight)
+	re.Empty(weights.Stores[0].RuleWeights)
+}
+
+func TestStorePreparingDetails(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	for _, store := range newTestStores(2, "6.0.0") {
+		meta := store.GetMeta()
+		meta.NodeState = metapb.NodeState_Preparing
+		re.NoError(cluster.PutStore(meta))
+	}
+
+	store := cluster.GetStore(1)
+	cluster.updatePreparingDetails(map[uint64]*StorePreparingDetail{
+		1: cluster.newPreparingDetail(store, 100, 10, 0.5),
+		2: cluster.newPreparingDetail(cluster.GetStore(2), 100, 10, 0.5),
+	})
+	details := cluster.GetStorePreparingDetails()
+	re.Len(details, 2)
+	re.Equal(uint64(1), details[0].StoreID)
+	re.Equal(100.0, details[0].Threshold)
+	re.Equal(10.0, details[0].RegionSize)
+	re.Equal(0.5, details[0].TopologyWeight)
+	re.LessOrEqual(details[0].LeftSeconds, opt.GetMaxStorePreparingTime().Seconds())
+
+	// The serving stores are removed.
+	re.NoError(cluster.ReadyToServe(1))
+	cluster.updatePreparingDetails(map[uint64]*StorePreparingDetail{
+		1: cluster.newPreparingDetail(store, 100, 10, 0.5),
+	})
+	re.Empty(cluster.GetStorePreparingDetails())
 }
 
 func TestCalculateStoreSize2(t *testing.T) {
@@ -1699,7 +2115,7 @@ func newTestRaftCluster(
 	s storage.Storage,
 	basicCluster *core.BasicCluster,
 ) *RaftCluster {
-	rc := &RaftCluster{serverCtx: ctx}
+	rc := &RaftCluster{serverCtx: ctx, regionLoading: progress.NewManager()}
 	rc.InitCluster(id, opt, s, basicCluster)
 	rc.ruleManager = placement.NewRuleManager(storage.NewStorageWithMemoryBackend(), rc, opt)
 	if opt.IsPlacementRulesEnabled() {
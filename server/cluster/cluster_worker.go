@@ -16,6 +16,7 @@ package cluster
 
 import (
 	"bytes"
+	"context"
 
 	"github.com/gogo/protobuf/proto"
 	"github.com/pingcap/errors"
@@ -24,6 +25,7 @@ import (
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/pkg/trace"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/statistics/buckets"
@@ -33,7 +35,22 @@ import (
 
 // HandleRegionHeartbeat processes RegionInfo reports from client.
 func (c *RaftCluster) HandleRegionHeartbeat(region *core.RegionInfo) error {
-	if err := c.processRegionHeartbeat(region); err != nil {
+	// Only the heartbeats of the regions with running operators are traced,
+	// as the children of the operators' spans.
+	ctx := context.Background()
+	if op := c.coordinator.opController.GetOperator(region.GetID()); op != nil {
+		ctx = trace.ContextWithSpanContext(ctx, op.SpanContext())
+	}
+	span, ctx := trace.StartChildSpan(ctx, "region-heartbeat")
+	defer span.Finish()
+
+	processSpan, _ := trace.StartChildSpan(ctx, "process-region-heartbeat")
+	err := c.processRegionHeartbeat(region)
+	if err != nil {
+		processSpan.SetAttribute("error", err)
+	}
+	processSpan.Finish()
+	if err != nil {
 		return err
 	}
 
@@ -210,6 +227,9 @@ func (c *RaftCluster) HandleReportSplit(request *pdpb.ReportSplitRequest) (*pdpb
 	log.Info("region split, generate new region",
 		zap.Uint64("region-id", originRegion.GetId()),
 		logutil.ZapRedactStringer("region-meta", core.RegionToHexMeta(left)))
+	if c.coordinator != nil {
+		c.coordinator.regionScatterer.RecordSplitRegions([]uint64{left.GetId()})
+	}
 	return &pdpb.ReportSplitResponse{}, nil
 }
 
@@ -232,6 +252,13 @@ func (c *RaftCluster) HandleBatchReportSplit(request *pdpb.ReportBatchSplitReque
 		zap.Uint64("region-id", originRegion.GetId()),
 		zap.Stringer("origin", hrm),
 		zap.Int("total", last))
+	if c.coordinator != nil {
+		newRegions := make([]uint64, 0, last)
+		for _, region := range regions[:last] {
+			newRegions = append(newRegions, region.GetId())
+		}
+		c.coordinator.regionScatterer.RecordSplitRegions(newRegions)
+	}
 	return &pdpb.ReportBatchSplitResponse{}, nil
 }
 
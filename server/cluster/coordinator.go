@@ -17,6 +17,8 @@ package cluster
 import (
 	"bytes"
 	"context"
+	"encoding/json"
+	"math/rand"
 	"net/http"
 	"strconv"
 	"sync"
@@ -29,7 +31,10 @@ import (
 	"github.com/tikv/pd/pkg/cache"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/pkg/metricutil"
+	"github.com/tikv/pd/pkg/slice"
 	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/pkg/workerpool"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
@@ -39,18 +44,39 @@ import (
 	"github.com/tikv/pd/server/schedule/plan"
 	"github.com/tikv/pd/server/statistics"
 	"github.com/tikv/pd/server/storage"
+	"github.com/tikv/pd/server/storage/endpoint"
 	"go.uber.org/zap"
 )
 
 const (
 	runSchedulerCheckInterval  = 3 * time.Second
 	checkSuspectRangesInterval = 100 * time.Millisecond
+	gcSuspectRangesInterval    = time.Minute
 	collectFactor              = 0.9
 	collectTimeout             = 5 * time.Minute
 	maxScheduleRetries         = 10
 	maxLoadConfigRetries       = 10
+	// maxSchedulerOverrunDelay is the max delay of the next tick of a scheduler
+	// overrunning its execution budget.
+	maxSchedulerOverrunDelay = 30 * time.Second
 
 	patrolScanRegionLimit = 128 // It takes about 14 minutes to iterate 1 million regions.
+	// CheckerPoolName is the name of the worker pool which checks the patrolled regions.
+	CheckerPoolName = "checker"
+	// drainOperatorsCheckInterval is the interval to check if the running operators are
+	// finished when draining.
+	drainOperatorsCheckInterval = 100 * time.Millisecond
+	// waitingOperatorSnapshotInterval is the interval to persist the snapshot of the waiting operators.
+	waitingOperatorSnapshotInterval = 10 * time.Second
+	// waitingOperatorSnapshotMinInterval is the min interval to persist the snapshot of the waiting
+	// operators after some of them are promoted.
+	waitingOperatorSnapshotMinInterval = time.Second
+	// waitingOperatorSnapshotMaxSize is the max encoded size of the snapshot of the waiting operators,
+	// which is well below the request size limit of etcd.
+	waitingOperatorSnapshotMaxSize = 512 * 1024
+	// waitingOperatorSnapshotMaxStaleness is the max age of the snapshot of the waiting operators
+	// which can be restored.
+	waitingOperatorSnapshotMaxStaleness = 5 * time.Minute
 	// PluginLoad means action for load plugin
 	PluginLoad = "PluginLoad"
 	// PluginUnload means action for unload plugin
@@ -74,6 +100,7 @@ type coordinator struct {
 	hbStreams       *hbstream.HeartbeatStreams
 	pluginInterface *schedule.PluginInterface
 	diagnosis       *diagnosisManager
+	checkerPool     *workerpool.Pool
 }
 
 // newCoordinator creates a new coordinator.
@@ -81,19 +108,27 @@ func newCoordinator(ctx context.Context, cluster *RaftCluster, hbStreams *hbstre
 	ctx, cancel := context.WithCancel(ctx)
 	opController := schedule.NewOperatorController(ctx, cluster, hbStreams)
 	schedulers := make(map[string]*scheduleController)
+	regionScatterer := schedule.NewRegionScatterer(ctx, cluster)
+	regionSplitter := schedule.NewRegionSplitter(cluster, schedule.NewSplitRegionsHandler(cluster, opController))
+	regionSplitter.SetSplitObserver(regionScatterer.RecordSplitRegions)
+	checkerPool := workerpool.New(ctx, CheckerPoolName, 1, patrolScanRegionLimit)
+	if cluster.workerPools != nil {
+		cluster.workerPools.Register(checkerPool)
+	}
 	return &coordinator{
 		ctx:             ctx,
 		cancel:          cancel,
 		cluster:         cluster,
 		prepareChecker:  newPrepareChecker(),
 		checkers:        checker.NewController(ctx, cluster, cluster.ruleManager, cluster.regionLabeler, opController),
-		regionScatterer: schedule.NewRegionScatterer(ctx, cluster),
-		regionSplitter:  schedule.NewRegionSplitter(cluster, schedule.NewSplitRegionsHandler(cluster, opController)),
+		regionScatterer: regionScatterer,
+		regionSplitter:  regionSplitter,
 		schedulers:      schedulers,
 		opController:    opController,
 		hbStreams:       hbStreams,
 		pluginInterface: schedule.NewPluginInterface(),
 		diagnosis:       newDiagnosisManager(cluster, schedulers),
+		checkerPool:     checkerPool,
 	}
 }
 
@@ -144,29 +179,43 @@ func (c *coordinator) patrolRegions() {
 			continue
 		}
 
-		for _, region := range regions {
-			// Skips the region if there is already a pending operator.
-			if c.opController.GetOperator(region.GetID()) != nil {
-				continue
+		// The regions are checked by the checker pool in batches of its size, and
+		// the operators of a batch are added one by one.
+		for start := 0; start < len(regions); {
+			end := start + c.checkerPool.Size()
+			if end > len(regions) {
+				end = len(regions)
 			}
+			batch := regions[start:end]
+			results := c.checkRegions(batch)
+			start = end
+
+			for i, region := range batch {
+				// Skips the region if there is already a pending operator.
+				if c.opController.GetOperator(region.GetID()) != nil {
+					continue
+				}
 
-			ops := c.checkers.CheckRegion(region)
+				ops := results[i]
 
-			key = region.GetEndKey()
-			if len(ops) == 0 {
-				continue
-			}
+				key = region.GetEndKey()
+				if len(ops) == 0 {
+					continue
+				}
 
-			if !c.opController.ExceedStoreLimit(ops...) {
-				c.opController.AddWaitingOperator(ops...)
-				c.checkers.RemoveWaitingRegion(region.GetID())
-				c.checkers.RemoveSuspectRegion(region.GetID())
-			} else {
-				c.checkers.AddWaitingRegion(region)
+				if !c.opController.ExceedStoreLimit(ops...) {
+					c.opController.AddWaitingOperator(ops...)
+					c.checkers.RemoveWaitingRegion(region.GetID())
+					c.checkers.RemoveSuspectRegion(region.GetID())
+				} else {
+					c.checkers.AddWaitingRegion(region)
+				}
 			}
 		}
 		// Updates the label level isolation statistics.
 		c.cluster.updateRegionsLabelLevelStats(regions)
+		// Updates the region label compliance statistics.
+		c.cluster.updateRegionsLabelComplianceStats(regions)
 		if len(key) == 0 {
 			patrolCheckRegionsGauge.Set(time.Since(start).Seconds())
 			start = time.Now()
@@ -177,6 +226,49 @@ func (c *coordinator) patrolRegions() {
 	}
 }
 
+// checkRegions checks the regions concurrently with the checker pool, and returns
+// the operators in the order of the regions. The regions with pending operators
+// are skipped.
+func (c *coordinator) checkRegions(regions []*core.RegionInfo) [][]*operator.Operator {
+	results := make([][]*operator.Operator, len(regions))
+	var wg sync.WaitGroup
+	for i, region := range regions {
+		if c.opController.GetOperator(region.GetID()) != nil {
+			continue
+		}
+		i, region := i, region
+		wg.Add(1)
+		if !c.checkerPool.Submit(func() {
+			defer wg.Done()
+			results[i] = c.checkRegion(region)
+		}) {
+			wg.Done()
+		}
+	}
+	wg.Wait()
+	return results
+}
+
+// checkRegion checks the region with the checkers. Only the operators repairing
+// the replicas are kept if the region cache is stale, since the others may be
+// harmful when they are generated from the outdated regions. The operators
+// repairing the regions changed by the unsafe recovery are exempted from the
+// store limits.
+func (c *coordinator) checkRegion(region *core.RegionInfo) []*operator.Operator {
+	ops := c.checkers.CheckRegion(region)
+	c.cluster.GetUnsafeRecoveryController().exemptOperators(ops)
+	if len(ops) == 0 || !c.cluster.IsRegionCacheStale() {
+		return ops
+	}
+	repairs := make([]*operator.Operator, 0, len(ops))
+	for _, op := range ops {
+		if isRepairOperator(op) {
+			repairs = append(repairs, op)
+		}
+	}
+	return repairs
+}
+
 // checkPriorityRegions checks priority regions
 func (c *coordinator) checkPriorityRegions() {
 	items := c.checkers.GetPriorityRegions()
@@ -188,7 +280,7 @@ func (c *coordinator) checkPriorityRegions() {
 			removes = append(removes, id)
 			continue
 		}
-		ops := c.checkers.CheckRegion(region)
+		ops := c.checkRegion(region)
 		// it should skip if region needs to merge
 		if len(ops) == 0 || ops[0].Kind()&operator.OpMerge != 0 {
 			continue
@@ -213,7 +305,7 @@ func (c *coordinator) checkSuspectRegions() {
 			c.checkers.RemoveSuspectRegion(id)
 			continue
 		}
-		ops := c.checkers.CheckRegion(region)
+		ops := c.checkRegion(region)
 		if len(ops) == 0 {
 			continue
 		}
@@ -231,15 +323,21 @@ func (c *coordinator) checkSuspectRegions() {
 func (c *coordinator) checkSuspectRanges() {
 	defer c.wg.Done()
 	log.Info("coordinator begins to check suspect key ranges")
+	// Reloads the suspect key ranges persisted by the previous leader.
+	c.cluster.gcSuspectKeyRanges(true)
 	ticker := time.NewTicker(checkSuspectRangesInterval)
 	defer ticker.Stop()
+	gcTicker := time.NewTicker(gcSuspectRangesInterval)
+	defer gcTicker.Stop()
 	for {
 		select {
 		case <-c.ctx.Done():
 			log.Info("check suspect key ranges has been stopped")
 			return
+		case <-gcTicker.C:
+			c.cluster.gcSuspectKeyRanges(false)
 		case <-ticker.C:
-			keyRange, success := c.checkers.PopOneSuspectKeyRange()
+			keyRange, success := c.cluster.PopOneSuspectKeyRange()
 			if !success {
 				continue
 			}
@@ -257,7 +355,7 @@ func (c *coordinator) checkSuspectRanges() {
 			// keyRange[0] and keyRange[1] after scan regions, so we put the end key and keyRange[1] into Suspect KeyRanges
 			lastRegion := regions[len(regions)-1]
 			if lastRegion.GetEndKey() != nil && bytes.Compare(lastRegion.GetEndKey(), keyRange[1]) < 0 {
-				c.checkers.AddSuspectKeyRange(lastRegion.GetEndKey(), keyRange[1])
+				c.cluster.AddSuspectKeyRange(lastRegion.GetEndKey(), keyRange[1], endpoint.SuspectReasonRemainingRange)
 			}
 			c.checkers.AddSuspectRegions(regionIDList...)
 		}
@@ -278,7 +376,7 @@ func (c *coordinator) checkWaitingRegions() {
 			c.checkers.RemoveWaitingRegion(id)
 			continue
 		}
-		ops := c.checkers.CheckRegion(region)
+		ops := c.checkRegion(region)
 		if len(ops) == 0 {
 			continue
 		}
@@ -385,7 +483,7 @@ func (c *coordinator) run() {
 			continue
 		}
 		log.Info("create scheduler with independent configuration", zap.String("scheduler-name", s.GetName()))
-		if err = c.addScheduler(s); err != nil {
+		if err = c.addObservingScheduler(s, cfg.ObserveUntil); err != nil {
 			log.Error("can not add scheduler with independent configuration", zap.String("scheduler-name", s.GetName()), zap.Strings("scheduler-args", cfg.Args), errs.ZapError(err))
 		}
 	}
@@ -407,7 +505,7 @@ func (c *coordinator) run() {
 		}
 
 		log.Info("create scheduler", zap.String("scheduler-name", s.GetName()), zap.Strings("scheduler-args", schedulerCfg.Args))
-		if err = c.addScheduler(s, schedulerCfg.Args...); err != nil && !errors.ErrorEqual(err, errs.ErrSchedulerExisted.FastGenByArgs()) {
+		if err = c.addObservingScheduler(s, schedulerCfg.ObserveUntil, schedulerCfg.Args...); err != nil && !errors.ErrorEqual(err, errs.ErrSchedulerExisted.FastGenByArgs()) {
 			log.Error("can not add scheduler", zap.String("scheduler-name", s.GetName()), zap.Strings("scheduler-args", schedulerCfg.Args), errs.ZapError(err))
 		} else {
 			// Only records the valid scheduler config.
@@ -423,12 +521,80 @@ func (c *coordinator) run() {
 		log.Error("cannot persist schedule config", errs.ZapError(err))
 	}
 
-	c.wg.Add(3)
+	// Restores the waiting operators after the regions are collected, so that
+	// they can be validated with the latest regions.
+	c.restoreWaitingOperators()
+
+	c.wg.Add(4)
 	// Starts to patrol regions.
 	go c.patrolRegions()
 	// Checks suspect key ranges
 	go c.checkSuspectRanges()
 	go c.drivePushOperator()
+	go c.persistWaitingOperators()
+}
+
+// persistWaitingOperators persists the snapshot of the waiting operators
+// periodically, and soon after some of them are promoted, so that the next
+// leader can continue them without running the promoted ones again.
+func (c *coordinator) persistWaitingOperators() {
+	defer logutil.LogPanic()
+	defer c.wg.Done()
+	ticker := time.NewTicker(waitingOperatorSnapshotInterval)
+	defer ticker.Stop()
+	var (
+		lastEmpty bool
+		lastSave  time.Time
+		pending   <-chan time.Time
+	)
+	save := func() {
+		snapshot := c.opController.SnapshotWaitingOperators(waitingOperatorSnapshotMaxSize)
+		if snapshot.Truncated > 0 {
+			log.Warn("waiting operator snapshot is truncated", zap.Int("truncated-groups", snapshot.Truncated))
+		}
+		empty := len(snapshot.Groups) == 0
+		if empty && lastEmpty {
+			return
+		}
+		if err := c.cluster.storage.SaveWaitingOperatorSnapshot(snapshot); err != nil {
+			log.Warn("failed to save waiting operator snapshot", errs.ZapError(err))
+			return
+		}
+		lastEmpty, lastSave = empty, time.Now()
+	}
+	for {
+		select {
+		case <-c.ctx.Done():
+			log.Info("persist waiting operators has been stopped")
+			return
+		case <-ticker.C:
+			save()
+		case <-c.opController.WaitingOperatorsPromoted():
+			if pending == nil {
+				pending = time.After(waitingOperatorSnapshotMinInterval - time.Since(lastSave))
+			}
+		case <-pending:
+			pending = nil
+			save()
+		}
+	}
+}
+
+// restoreWaitingOperators restores the waiting operators from the snapshot
+// saved by the previous leader. The stale snapshot is ignored.
+func (c *coordinator) restoreWaitingOperators() {
+	snapshot := &schedule.WaitingOperatorSnapshot{}
+	ok, err := c.cluster.storage.LoadWaitingOperatorSnapshot(snapshot)
+	if err != nil {
+		log.Warn("failed to load waiting operator snapshot", errs.ZapError(err))
+		return
+	}
+	if !ok || snapshot.IsStale(waitingOperatorSnapshotMaxStaleness) {
+		return
+	}
+	restored, discarded := c.opController.RestoreWaitingOperators(snapshot)
+	log.Info("restored waiting operators from snapshot", zap.Int("restored", restored), zap.Int("discarded", discarded),
+		zap.Time("snapshot-time", time.Unix(snapshot.Timestamp, 0)))
 }
 
 // LoadPlugin load user plugin
@@ -493,6 +659,34 @@ func (c *coordinator) stop() {
 	c.cancel()
 }
 
+// drainOperators stops creating operators and waits for the running operators to finish
+// until the timeout. The operators still running then are canceled with the reason, so
+// that they are recorded in the operator history instead of being abandoned.
+func (c *coordinator) drainOperators(timeout time.Duration) {
+	c.opController.StartDraining()
+	if timeout > 0 {
+		log.Info("coordinator starts to drain operators", zap.Int("count", len(c.opController.GetOperators())), zap.Duration("timeout", timeout))
+		ticker := time.NewTicker(drainOperatorsCheckInterval)
+		defer ticker.Stop()
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+	wait:
+		for len(c.opController.GetOperators()) > 0 {
+			select {
+			case <-ticker.C:
+			case <-timer.C:
+				break wait
+			case <-c.ctx.Done():
+				return
+			}
+		}
+	}
+	reason := operator.NewReason("coordinator", "shutdown").With("drain-timeout", timeout)
+	if canceled := c.opController.CancelOperators(reason); canceled > 0 {
+		log.Info("coordinator cancels the operators not finished when draining", zap.Int("count", canceled))
+	}
+}
+
 func (c *coordinator) getHotRegionsByType(typ statistics.RWType) *statistics.StoreHotPeersInfos {
 	isTraceFlow := c.cluster.GetOpts().IsTraceRegionFlow()
 	storeLoads := c.cluster.GetStoresLoads()
@@ -519,6 +713,17 @@ func (c *coordinator) getSchedulers() []string {
 	return names
 }
 
+// getSchedulerTypes returns the types of the schedulers keyed by their names.
+func (c *coordinator) getSchedulerTypes() map[string]string {
+	c.RLock()
+	defer c.RUnlock()
+	types := make(map[string]string, len(c.schedulers))
+	for name, s := range c.schedulers {
+		types[name] = s.GetType()
+	}
+	return types
+}
+
 func (c *coordinator) getSchedulerHandlers() map[string]http.Handler {
 	c.RLock()
 	defer c.RUnlock()
@@ -536,7 +741,7 @@ func (c *coordinator) collectSchedulerMetrics() {
 		var allowScheduler float64
 		// If the scheduler is not allowed to schedule, it will disappear in Grafana panel.
 		// See issue #1341.
-		if !s.IsPaused() && !s.cluster.GetUnsafeRecoveryController().IsRunning() {
+		if !s.IsPaused() && !s.cluster.GetUnsafeRecoveryController().IsRunning() && s.IsReady() {
 			allowScheduler = 1
 		}
 		schedulerStatusGauge.WithLabelValues(s.GetName(), "allow").Set(allowScheduler)
@@ -548,6 +753,9 @@ func (c *coordinator) resetSchedulerMetrics() {
 }
 
 func (c *coordinator) collectHotSpotMetrics() {
+	if metricutil.IsFamilyDisabled(hotSpotStatusFamily) {
+		return
+	}
 	stores := c.cluster.GetStores()
 	// Collects hot write region metrics.
 	collectHotMetrics(c.cluster, stores, statistics.Write)
@@ -557,6 +765,42 @@ func (c *coordinator) collectHotSpotMetrics() {
 	collectPendingInfluence(stores)
 }
 
+// hotSpotStatus sets the hot spot status of the stores. The status of all the
+// stores are summed up and set by flush if the series are aggregated.
+type hotSpotStatus struct {
+	aggregated bool
+	sums       map[string]float64
+}
+
+func newHotSpotStatus() *hotSpotStatus {
+	return &hotSpotStatus{
+		aggregated: metricutil.IsAggregated(),
+		sums:       make(map[string]float64),
+	}
+}
+
+func (s *hotSpotStatus) set(store *core.StoreInfo, typ string, value float64) {
+	if s.aggregated {
+		s.sums[typ] += value
+		return
+	}
+	hotSpotStatusGauge.WithLabelValues(store.GetAddress(), strconv.FormatUint(store.GetID(), 10), typ).Set(value)
+}
+
+func (s *hotSpotStatus) delete(store *core.StoreInfo, typ string) {
+	if s.aggregated {
+		s.sums[typ] += 0
+		return
+	}
+	hotSpotStatusGauge.DeleteLabelValues(store.GetAddress(), strconv.FormatUint(store.GetID(), 10), typ)
+}
+
+func (s *hotSpotStatus) flush() {
+	for typ, value := range s.sums {
+		hotSpotStatusGauge.WithLabelValues("", metricutil.AggregatedStoreLabel, typ).Set(value)
+	}
+}
+
 func collectHotMetrics(cluster *RaftCluster, stores []*core.StoreInfo, typ statistics.RWType) {
 	var (
 		kind                      string
@@ -574,51 +818,50 @@ func collectHotMetrics(cluster *RaftCluster, stores []*core.StoreInfo, typ stati
 	}
 	status := statistics.GetHotStatus(stores, cluster.GetStoresLoads(), regionStats, typ, cluster.GetOpts().IsTraceRegionFlow())
 
+	hotSpot := newHotSpotStatus()
 	for _, s := range stores {
-		storeAddress := s.GetAddress()
 		storeID := s.GetID()
-		storeLabel := strconv.FormatUint(storeID, 10)
 		stat, ok := status.AsLeader[storeID]
 		if ok {
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "total_"+kind+"_bytes_as_leader").Set(stat.TotalLoads[byteTyp])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "total_"+kind+"_keys_as_leader").Set(stat.TotalLoads[keyTyp])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "total_"+kind+"_query_as_leader").Set(stat.TotalLoads[queryTyp])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "hot_"+kind+"_region_as_leader").Set(float64(stat.Count))
+			hotSpot.set(s, "total_"+kind+"_bytes_as_leader", stat.TotalLoads[byteTyp])
+			hotSpot.set(s, "total_"+kind+"_keys_as_leader", stat.TotalLoads[keyTyp])
+			hotSpot.set(s, "total_"+kind+"_query_as_leader", stat.TotalLoads[queryTyp])
+			hotSpot.set(s, "hot_"+kind+"_region_as_leader", float64(stat.Count))
 		} else {
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "total_"+kind+"_bytes_as_leader")
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "total_"+kind+"_keys_as_leader")
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "total_"+kind+"_query_as_leader")
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "hot_"+kind+"_region_as_leader")
+			hotSpot.delete(s, "total_"+kind+"_bytes_as_leader")
+			hotSpot.delete(s, "total_"+kind+"_keys_as_leader")
+			hotSpot.delete(s, "total_"+kind+"_query_as_leader")
+			hotSpot.delete(s, "hot_"+kind+"_region_as_leader")
 		}
 
 		stat, ok = status.AsPeer[storeID]
 		if ok {
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "total_"+kind+"_bytes_as_peer").Set(stat.TotalLoads[byteTyp])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "total_"+kind+"_keys_as_peer").Set(stat.TotalLoads[keyTyp])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "total_"+kind+"_query_as_peer").Set(stat.TotalLoads[queryTyp])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "hot_"+kind+"_region_as_peer").Set(float64(stat.Count))
+			hotSpot.set(s, "total_"+kind+"_bytes_as_peer", stat.TotalLoads[byteTyp])
+			hotSpot.set(s, "total_"+kind+"_keys_as_peer", stat.TotalLoads[keyTyp])
+			hotSpot.set(s, "total_"+kind+"_query_as_peer", stat.TotalLoads[queryTyp])
+			hotSpot.set(s, "hot_"+kind+"_region_as_peer", float64(stat.Count))
 		} else {
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "total_"+kind+"_bytes_as_peer")
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "total_"+kind+"_keys_as_peer")
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "total_"+kind+"_query_as_peer")
-			hotSpotStatusGauge.DeleteLabelValues(storeAddress, storeLabel, "hot_"+kind+"_region_as_peer")
+			hotSpot.delete(s, "total_"+kind+"_bytes_as_peer")
+			hotSpot.delete(s, "total_"+kind+"_keys_as_peer")
+			hotSpot.delete(s, "total_"+kind+"_query_as_peer")
+			hotSpot.delete(s, "hot_"+kind+"_region_as_peer")
 		}
 	}
+	hotSpot.flush()
 }
 
 func collectPendingInfluence(stores []*core.StoreInfo) {
 	pendings := statistics.GetPendingInfluence(stores)
+	hotSpot := newHotSpotStatus()
 	for _, s := range stores {
-		storeAddress := s.GetAddress()
-		storeID := s.GetID()
-		storeLabel := strconv.FormatUint(storeID, 10)
-		if infl := pendings[storeID]; infl != nil {
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "pending_influence_byte_rate").Set(infl.Loads[statistics.ByteDim])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "pending_influence_key_rate").Set(infl.Loads[statistics.KeyDim])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "pending_influence_query_rate").Set(infl.Loads[statistics.QueryDim])
-			hotSpotStatusGauge.WithLabelValues(storeAddress, storeLabel, "pending_influence_count").Set(infl.Count)
+		if infl := pendings[s.GetID()]; infl != nil {
+			hotSpot.set(s, "pending_influence_byte_rate", infl.Loads[statistics.ByteDim])
+			hotSpot.set(s, "pending_influence_key_rate", infl.Loads[statistics.KeyDim])
+			hotSpot.set(s, "pending_influence_query_rate", infl.Loads[statistics.QueryDim])
+			hotSpot.set(s, "pending_influence_count", infl.Count)
 		}
 	}
+	hotSpot.flush()
 }
 
 func (c *coordinator) resetHotSpotMetrics() {
@@ -630,6 +873,14 @@ func (c *coordinator) shouldRun() bool {
 }
 
 func (c *coordinator) addScheduler(scheduler schedule.Scheduler, args ...string) error {
+	return c.addObservingScheduler(scheduler, 0, args...)
+}
+
+// addObservingScheduler adds the scheduler in observe-only mode until the given
+// unix time, the mode is set before the scheduler runs so that no operator is
+// submitted before it. The mode is kept in the scheduler config, so it is not
+// lost when the leader changes.
+func (c *coordinator) addObservingScheduler(scheduler schedule.Scheduler, observeUntil int64, args ...string) error {
 	c.Lock()
 	defer c.Unlock()
 
@@ -641,11 +892,20 @@ func (c *coordinator) addScheduler(scheduler schedule.Scheduler, args ...string)
 	if err := s.Prepare(c.cluster); err != nil {
 		return err
 	}
+	c.diagnosis.loadSummary(s)
+	if now := time.Now().Unix(); observeUntil > now {
+		s.observeAt, s.observeUntil = now, observeUntil
+	} else {
+		observeUntil = 0
+	}
 
 	c.wg.Add(1)
 	go c.runScheduler(s)
 	c.schedulers[s.GetName()] = s
 	c.cluster.opt.AddSchedulerCfg(s.GetType(), args)
+	if err := c.setOptSchedulerObserveUntil(c.cluster.opt, s.GetName(), observeUntil); err != nil {
+		log.Error("can not set the observe-only mode in scheduler config", zap.String("scheduler-name", s.GetName()), errs.ZapError(err))
+	}
 	return nil
 }
 
@@ -679,31 +939,57 @@ func (c *coordinator) removeScheduler(name string) error {
 	s.Stop()
 	schedulerStatusGauge.DeleteLabelValues(name, "allow")
 	delete(c.schedulers, name)
+	c.diagnosis.removeSummary(name)
 
 	return nil
 }
 
 func (c *coordinator) removeOptScheduler(o *config.PersistOptions, name string) error {
 	v := o.GetScheduleConfig().Clone()
+	i, err := c.findOptScheduler(v, name)
+	if err != nil || i < 0 {
+		return err
+	}
+	if config.IsDefaultScheduler(v.Schedulers[i].Type) {
+		v.Schedulers[i].Disable = true
+	} else {
+		v.Schedulers = append(v.Schedulers[:i], v.Schedulers[i+1:]...)
+	}
+	o.SetScheduleConfig(v)
+	return nil
+}
+
+// setOptSchedulerObserveUntil sets the end of the observe-only mode in the
+// config of the scheduler.
+func (c *coordinator) setOptSchedulerObserveUntil(o *config.PersistOptions, name string, observeUntil int64) error {
+	v := o.GetScheduleConfig().Clone()
+	if observeUntil == 0 && !slice.AnyOf(v.Schedulers, func(i int) bool { return v.Schedulers[i].ObserveUntil != 0 }) {
+		return nil
+	}
+	i, err := c.findOptScheduler(v, name)
+	if err != nil || i < 0 || v.Schedulers[i].ObserveUntil == observeUntil {
+		return err
+	}
+	v.Schedulers[i].ObserveUntil = observeUntil
+	o.SetScheduleConfig(v)
+	return nil
+}
+
+// findOptScheduler returns the index of the config of the scheduler, or -1 if
+// it is not found.
+func (c *coordinator) findOptScheduler(v *config.ScheduleConfig, name string) (int, error) {
 	for i, schedulerCfg := range v.Schedulers {
 		// To create a temporary scheduler is just used to get scheduler's name
 		decoder := schedule.ConfigSliceDecoder(schedulerCfg.Type, schedulerCfg.Args)
 		tmp, err := schedule.CreateScheduler(schedulerCfg.Type, schedule.NewOperatorController(c.ctx, nil, nil), storage.NewStorageWithMemoryBackend(), decoder)
 		if err != nil {
-			return err
+			return -1, err
 		}
 		if tmp.GetName() == name {
-			if config.IsDefaultScheduler(tmp.GetType()) {
-				schedulerCfg.Disable = true
-				v.Schedulers[i] = schedulerCfg
-			} else {
-				v.Schedulers = append(v.Schedulers[:i], v.Schedulers[i+1:]...)
-			}
-			o.SetScheduleConfig(v)
-			return nil
+			return i, nil
 		}
 	}
-	return nil
+	return -1, nil
 }
 
 func (c *coordinator) pauseOrResumeScheduler(name string, t int64) error {
@@ -737,6 +1023,63 @@ func (c *coordinator) pauseOrResumeScheduler(name string, t int64) error {
 	return err
 }
 
+// setSchedulerObserveOnly makes the scheduler record its operators instead of
+// submitting them for t seconds, or leaves the observe-only mode if t is 0.
+func (c *coordinator) setSchedulerObserveOnly(name string, t int64) error {
+	c.Lock()
+	defer c.Unlock()
+	if c.cluster == nil {
+		return errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	s, ok := c.schedulers[name]
+	if !ok {
+		return errs.ErrSchedulerNotFound.FastGenByArgs()
+	}
+	var observeAt, observeUntil int64
+	if t > 0 {
+		observeAt = time.Now().Unix()
+		observeUntil = observeAt + t
+	}
+	opt := c.cluster.opt
+	if err := c.setOptSchedulerObserveUntil(opt, name, observeUntil); err != nil {
+		return err
+	}
+	if err := opt.Persist(c.cluster.storage); err != nil {
+		log.Error("the option can not persist scheduler config", errs.ZapError(err))
+		return err
+	}
+	if t > 0 {
+		// Drop the records of the last observation.
+		c.diagnosis.removeObservation(name)
+	}
+	atomic.StoreInt64(&s.observeAt, observeAt)
+	atomic.StoreInt64(&s.observeUntil, observeUntil)
+	return nil
+}
+
+// getSchedulerObservation returns the operators recorded by the scheduler in
+// observe-only mode.
+func (c *coordinator) getSchedulerObservation(name string) (*schedule.SchedulerObservation, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.cluster == nil {
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, errs.ErrSchedulerNotFound.FastGenByArgs()
+	}
+	observation := &schedule.SchedulerObservation{
+		Operators: c.diagnosis.getObservedOperators(name),
+	}
+	if until := s.getObserveUntil(); until > 0 {
+		observation.Observing = true
+		observation.ObserveSince = time.Unix(atomic.LoadInt64(&s.observeAt), 0)
+		observation.ObserveUntil = time.Unix(until, 0)
+	}
+	return observation, nil
+}
+
 // isSchedulerAllowed returns whether a scheduler is allowed to schedule, a scheduler is not allowed to schedule if it is paused or blocked by unsafe recovery.
 func (c *coordinator) isSchedulerAllowed(name string) (bool, error) {
 	c.RLock()
@@ -764,6 +1107,21 @@ func (c *coordinator) isSchedulerPaused(name string) (bool, error) {
 	return s.IsPaused(), nil
 }
 
+func (c *coordinator) getSchedulerState(name string) (*schedule.SchedulerStateSnapshot, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.cluster == nil {
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	s, ok := c.schedulers[name]
+	if !ok {
+		return nil, errs.ErrSchedulerNotFound.FastGenByArgs()
+	}
+	state := s.GetState()
+	state.Diagnosis = c.diagnosis.getSummary(name)
+	return state, nil
+}
+
 func (c *coordinator) isSchedulerDisabled(name string) (bool, error) {
 	c.RLock()
 	defer c.RUnlock()
@@ -784,6 +1142,24 @@ func (c *coordinator) isSchedulerDisabled(name string) (bool, error) {
 	return false, nil
 }
 
+// getSchedulerUnreadyReason returns the reason why the prerequisites of a
+// scheduler are not met, it returns an empty string if the scheduler is ready.
+func (c *coordinator) getSchedulerUnreadyReason(name string) (string, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.cluster == nil {
+		return "", errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	s, ok := c.schedulers[name]
+	if !ok {
+		return "", errs.ErrSchedulerNotFound.FastGenByArgs()
+	}
+	if err := schedule.CheckPrerequisites(s.Scheduler, s.cluster); err != nil {
+		return err.Error(), nil
+	}
+	return "", nil
+}
+
 func (c *coordinator) isSchedulerExisted(name string) (bool, error) {
 	c.RLock()
 	defer c.RUnlock()
@@ -810,11 +1186,35 @@ func (c *coordinator) runScheduler(s *scheduleController) {
 		case <-timer.C:
 			timer.Reset(s.GetInterval())
 			if !s.AllowSchedule() {
+				c.diagnosis.sampleIfSilent(s)
 				continue
 			}
-			if op := s.Schedule(); len(op) > 0 {
+			if s.IsObserving() {
+				ops, plans := s.scheduleObserved()
+				c.diagnosis.observe(s.GetName(), ops, plans)
+			} else if op := s.Schedule(); len(op) > 0 {
+				if s.IsPaused() {
+					// The scheduler is allowed by the break-glass mode, the check is
+					// recorded once the operators are added.
+					for _, o := range op {
+						o.SetBypassedCheck(schedule.BreakGlassPausedScheduler, "scheduler "+s.GetName()+" paused")
+					}
+				}
 				added := c.opController.AddWaitingOperator(op...)
 				log.Debug("add operator", zap.Int("added", added), zap.Int("total", len(op)), zap.String("scheduler", s.GetName()))
+				c.diagnosis.resetSummary(s.GetName())
+			} else {
+				c.diagnosis.sampleIfSilent(s)
+			}
+			// Yield the overrun to the other schedulers by delaying the next tick.
+			if overrun := s.takeOverrun(); overrun > 0 {
+				if !timer.Stop() {
+					select {
+					case <-timer.C:
+					default:
+					}
+				}
+				timer.Reset(s.GetInterval() + overrun)
 			}
 
 		case <-s.Ctx().Done():
@@ -853,6 +1253,62 @@ func (c *coordinator) isCheckerPaused(name string) (bool, error) {
 	return p.IsPaused(), nil
 }
 
+// dryRunChecker runs the checker on the given regions, or on at most sample
+// randomly picked regions if no region is given, without adding the proposed
+// operators. The regions which are not found are skipped.
+func (c *coordinator) dryRunChecker(name string, regionIDs []uint64, sample int) ([]*checker.DryRunResult, error) {
+	c.RLock()
+	defer c.RUnlock()
+	if c.cluster == nil {
+		return nil, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	if _, err := c.checkers.GetPauseController(name); err != nil {
+		return nil, err
+	}
+	var regions []*core.RegionInfo
+	if len(regionIDs) > 0 {
+		for _, id := range regionIDs {
+			if region := c.cluster.GetRegion(id); region != nil {
+				regions = append(regions, region)
+			}
+		}
+	} else {
+		regions = c.sampleRegions(sample)
+	}
+	results := make([]*checker.DryRunResult, 0, len(regions))
+	for _, region := range regions {
+		result, err := c.checkers.DryRun(name, region)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// sampleRegions picks at most n random regions by collecting the random
+// leader regions of the stores in a random order.
+func (c *coordinator) sampleRegions(n int) []*core.RegionInfo {
+	stores := c.cluster.GetStores()
+	rand.Shuffle(len(stores), func(i, j int) { stores[i], stores[j] = stores[j], stores[i] })
+	picked := make(map[uint64]struct{}, n)
+	regions := make([]*core.RegionInfo, 0, n)
+	ranges := []core.KeyRange{core.NewKeyRange("", "")}
+	for _, store := range stores {
+		for _, region := range c.cluster.RandLeaderRegions(store.GetID(), ranges) {
+			if len(regions) >= n {
+				return regions
+			}
+			if _, ok := picked[region.GetID()]; ok {
+				continue
+			}
+			picked[region.GetID()] = struct{}{}
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
 // scheduleController is used to manage a scheduler to schedule.
 type scheduleController struct {
 	schedule.Scheduler
@@ -863,18 +1319,36 @@ type scheduleController struct {
 	cancel       context.CancelFunc
 	delayAt      int64
 	delayUntil   int64
+	// observeAt and observeUntil are the period of the observe-only mode, in
+	// which the operators are recorded but not submitted. They are accessed
+	// atomically.
+	observeAt    int64
+	observeUntil int64
+	// lastScheduleAt and lastOperatorCount record the result of the last
+	// schedule round. They are accessed atomically.
+	lastScheduleAt    int64
+	lastOperatorCount int64
+	// lastOperatorAt is the time when the scheduler produced operators last
+	// time, it is accessed atomically.
+	lastOperatorAt int64
+	// lastDiagnosisSampleAt is only accessed in the goroutine running the scheduler.
+	lastDiagnosisSampleAt time.Time
+	// overrun is the time exceeding the execution budget in the last tick, it is
+	// only accessed in the goroutine running the scheduler.
+	overrun time.Duration
 }
 
 // newScheduleController creates a new scheduleController.
 func newScheduleController(c *coordinator, s schedule.Scheduler) *scheduleController {
 	ctx, cancel := context.WithCancel(c.ctx)
 	return &scheduleController{
-		Scheduler:    s,
-		cluster:      c.cluster,
-		opController: c.opController,
-		nextInterval: s.GetMinInterval(),
-		ctx:          ctx,
-		cancel:       cancel,
+		Scheduler:      s,
+		cluster:        c.cluster,
+		opController:   c.opController,
+		nextInterval:   s.GetMinInterval(),
+		ctx:            ctx,
+		cancel:         cancel,
+		lastOperatorAt: time.Now().UnixNano(),
 	}
 }
 
@@ -887,22 +1361,103 @@ func (s *scheduleController) Stop() {
 }
 
 func (s *scheduleController) Schedule() []*operator.Operator {
+	ops, _ := s.schedule(false)
+	return ops
+}
+
+// scheduleObserved runs the scheduler in the same way as Schedule, but also
+// collects the plans for the diagnosis.
+func (s *scheduleController) scheduleObserved() ([]*operator.Operator, []plan.Plan) {
+	return s.schedule(true)
+}
+
+func (s *scheduleController) schedule(diagnose bool) ([]*operator.Operator, []plan.Plan) {
+	budget := s.cluster.GetOpts().GetSchedulerExecutionBudget(s.GetName())
+	start := time.Now()
+	defer func() { s.observeExecution(time.Since(start), budget) }()
+	var plans []plan.Plan
 	for i := 0; i < maxScheduleRetries; i++ {
 		// no need to retry if schedule should stop to speed exit
 		select {
 		case <-s.ctx.Done():
-			return nil
+			return nil, nil
 		default:
 		}
+		// Stop retrying once the budget is used up, so that the scheduler does
+		// not contend with the others for too long.
+		if i > 0 && budget > 0 && time.Since(start) >= budget {
+			schedulerBudgetCounter.WithLabelValues(s.GetName(), "exhausted").Inc()
+			break
+		}
 		cacheCluster := newCacheCluster(s.cluster)
+		var ops []*operator.Operator
+		ops, plans = s.Scheduler.Schedule(cacheCluster, diagnose)
 		// If we have schedule, reset interval to the minimal interval.
-		if ops, _ := s.Scheduler.Schedule(cacheCluster, false); len(ops) > 0 {
+		if len(ops) > 0 {
 			s.nextInterval = s.Scheduler.GetMinInterval()
-			return ops
+			s.recordScheduleResult(len(ops))
+			return ops, plans
 		}
 	}
 	s.nextInterval = s.Scheduler.GetNextInterval(s.nextInterval)
-	return nil
+	s.recordScheduleResult(0)
+	return nil, plans
+}
+
+// observeExecution records the time spent in a tick, and the overrun if the
+// execution budget is exceeded.
+func (s *scheduleController) observeExecution(elapsed, budget time.Duration) {
+	schedulerExecutionDuration.WithLabelValues(s.GetName()).Observe(elapsed.Seconds())
+	s.overrun = 0
+	if budget > 0 && elapsed > budget {
+		schedulerBudgetCounter.WithLabelValues(s.GetName(), "overrun").Inc()
+		s.overrun = elapsed - budget
+		if s.overrun > maxSchedulerOverrunDelay {
+			s.overrun = maxSchedulerOverrunDelay
+		}
+	}
+}
+
+// takeOverrun returns and resets the overrun of the last tick.
+func (s *scheduleController) takeOverrun() time.Duration {
+	overrun := s.overrun
+	s.overrun = 0
+	return overrun
+}
+
+func (s *scheduleController) recordScheduleResult(opCount int) {
+	now := time.Now().UnixNano()
+	atomic.StoreInt64(&s.lastOperatorCount, int64(opCount))
+	atomic.StoreInt64(&s.lastScheduleAt, now)
+	if opCount > 0 {
+		atomic.StoreInt64(&s.lastOperatorAt, now)
+	}
+}
+
+// getLastOperatorTime returns the time when the scheduler produced operators last time.
+func (s *scheduleController) getLastOperatorTime() time.Time {
+	return time.Unix(0, atomic.LoadInt64(&s.lastOperatorAt))
+}
+
+// GetState returns a snapshot of the scheduler state.
+func (s *scheduleController) GetState() *schedule.SchedulerStateSnapshot {
+	snapshot := &schedule.SchedulerStateSnapshot{
+		Name:              s.GetName(),
+		Type:              s.GetType(),
+		Paused:            s.IsPaused(),
+		Observing:         s.IsObserving(),
+		LastOperatorCount: int(atomic.LoadInt64(&s.lastOperatorCount)),
+	}
+	if config, err := s.EncodeConfig(); err == nil && len(config) > 0 && json.Valid(config) {
+		snapshot.Config = config
+	}
+	if at := atomic.LoadInt64(&s.lastScheduleAt); at > 0 {
+		snapshot.LastScheduleTime = time.Unix(0, at)
+	}
+	if state, ok := s.Scheduler.(schedule.SchedulerState); ok {
+		snapshot.State = state.GetState()
+	}
+	return snapshot
 }
 
 func (s *scheduleController) DiagnoseDryRun() ([]*operator.Operator, []plan.Plan) {
@@ -915,9 +1470,17 @@ func (s *scheduleController) GetInterval() time.Duration {
 	return s.nextInterval
 }
 
-// AllowSchedule returns if a scheduler is allowed to schedule.
+// AllowSchedule returns if a scheduler is allowed to schedule. A paused
+// scheduler is allowed if the break-glass mode loosens the check.
 func (s *scheduleController) AllowSchedule() bool {
-	return s.Scheduler.IsScheduleAllowed(s.cluster) && !s.IsPaused() && !s.cluster.GetUnsafeRecoveryController().IsRunning()
+	return s.Scheduler.IsScheduleAllowed(s.cluster) && !s.cluster.GetUnsafeRecoveryController().IsRunning() &&
+		(!s.IsPaused() || s.cluster.AllowBreakGlass(schedule.BreakGlassPausedScheduler)) &&
+		!s.cluster.IsRegionCacheStale() && s.IsReady()
+}
+
+// IsReady returns if the prerequisites declared by the scheduler are met.
+func (s *scheduleController) IsReady() bool {
+	return schedule.CheckPrerequisites(s.Scheduler, s.cluster) == nil
 }
 
 // isPaused returns if a scheduler is paused.
@@ -926,6 +1489,21 @@ func (s *scheduleController) IsPaused() bool {
 	return time.Now().Unix() < delayUntil
 }
 
+// IsObserving returns if the operators of a scheduler are only recorded but
+// not submitted.
+func (s *scheduleController) IsObserving() bool {
+	return time.Now().Unix() < atomic.LoadInt64(&s.observeUntil)
+}
+
+// getObserveUntil returns the end of the observe-only mode, or 0 if the
+// scheduler is not observing.
+func (s *scheduleController) getObserveUntil() int64 {
+	if s.IsObserving() {
+		return atomic.LoadInt64(&s.observeUntil)
+	}
+	return 0
+}
+
 // GetPausedSchedulerDelayAt returns paused timestamp of a paused scheduler
 func (s *scheduleController) GetDelayAt() int64 {
 	if s.IsPaused() {
@@ -944,11 +1522,32 @@ func (s *scheduleController) GetDelayUntil() int64 {
 
 const maxDiagnosisResultNum = 6
 
+// maxObservedOperatorNum is the max number of the operators recorded for a
+// scheduler in observe-only mode.
+const maxObservedOperatorNum = 128
+
+// diagnosisSampleInterval is the min interval between two background samples
+// of a silent scheduler.
+var diagnosisSampleInterval = time.Minute
+
+const (
+	diagnosisReasonUnknown            = "Unknown"
+	diagnosisReasonUnsafeRecovery     = "Unsafe Recovery Running"
+	diagnosisReasonNotAllowed         = "Schedule Not Allowed"
+	diagnosisReasonPrerequisitePrefix = "Prerequisites Not Met: "
+)
+
 // diagnosisManager is used to manage diagnose mechanism which shares the actual scheduler with coordinator
 type diagnosisManager struct {
-	cluster      *RaftCluster
-	schedulers   map[string]*scheduleController
+	cluster    *RaftCluster
+	schedulers map[string]*scheduleController
+
+	mu           syncutil.RWMutex
 	dryRunResult map[string]*cache.FIFO
+	// summaries are the diagnosis of the silent schedulers sampled in background.
+	summaries map[string]*schedule.SchedulerDiagnosis
+	// observed are the operators recorded for the schedulers in observe-only mode.
+	observed map[string]*cache.FIFO
 }
 
 func newDiagnosisManager(cluster *RaftCluster, schedulerControllers map[string]*scheduleController) *diagnosisManager {
@@ -956,6 +1555,8 @@ func newDiagnosisManager(cluster *RaftCluster, schedulerControllers map[string]*
 		cluster:      cluster,
 		schedulers:   schedulerControllers,
 		dryRunResult: make(map[string]*cache.FIFO),
+		summaries:    make(map[string]*schedule.SchedulerDiagnosis),
+		observed:     make(map[string]*cache.FIFO),
 	}
 }
 
@@ -964,15 +1565,216 @@ func (d *diagnosisManager) diagnosisDryRun(name string) error {
 		return errs.ErrSchedulerNotFound.FastGenByArgs()
 	}
 	ops, plans := d.schedulers[name].DiagnoseDryRun()
-	result := newDiagnosisResult(ops, plans)
+	d.putDryRunResult(name, newDiagnosisResult(ops, plans))
+	return nil
+}
+
+func (d *diagnosisManager) putDryRunResult(name string, result *diagnosisResult) {
+	if result == nil {
+		return
+	}
+	d.mu.Lock()
+	defer d.mu.Unlock()
 	if _, ok := d.dryRunResult[name]; !ok {
 		d.dryRunResult[name] = cache.NewFIFO(maxDiagnosisResultNum)
 	}
 	queue := d.dryRunResult[name]
 	queue.Put(result.timestamp, result)
+}
+
+// observe records the result of a scheduler in observe-only mode. The plans go
+// to the dry run results and the operators are kept for inspection, none of
+// them is submitted.
+func (d *diagnosisManager) observe(name string, ops []*operator.Operator, plans []plan.Plan) {
+	d.putDryRunResult(name, newDiagnosisResult(ops, plans))
+	if len(ops) == 0 {
+		return
+	}
+	d.mu.Lock()
+	queue, ok := d.observed[name]
+	if !ok {
+		queue = cache.NewFIFO(maxObservedOperatorNum)
+		d.observed[name] = queue
+	}
+	d.mu.Unlock()
+	now := time.Now()
+	for _, op := range ops {
+		queue.Put(op.RegionID(), &schedule.ObservedOperator{
+			Time:     now,
+			RegionID: op.RegionID(),
+			Desc:     op.Desc(),
+			Kind:     op.Kind().String(),
+			Detail:   op.String(),
+		})
+	}
+	schedulerObservedOperatorCounter.WithLabelValues(name).Add(float64(len(ops)))
+	log.Debug("observe operators", zap.Int("total", len(ops)), zap.String("scheduler", name))
+}
+
+func (d *diagnosisManager) getObservedOperators(name string) []*schedule.ObservedOperator {
+	d.mu.RLock()
+	queue, ok := d.observed[name]
+	d.mu.RUnlock()
+	ops := []*schedule.ObservedOperator{}
+	if !ok {
+		return ops
+	}
+	for _, item := range queue.Elems() {
+		ops = append(ops, item.Value.(*schedule.ObservedOperator))
+	}
+	return ops
+}
+
+func (d *diagnosisManager) removeObservation(name string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.observed, name)
+}
+
+// sampleIfSilent samples a dry run of the scheduler and summarizes the reasons
+// why it does not schedule, if the scheduler has produced no operator for longer
+// than the diagnosis window. It must be called in the goroutine running the scheduler.
+// The summary is only persisted when the ranking of the reasons changes, so the
+// persisted counts may lag behind the ones in memory.
+func (d *diagnosisManager) sampleIfSilent(s *scheduleController) {
+	window := d.cluster.GetOpts().GetSchedulerDiagnosisWindow()
+	if window <= 0 {
+		return
+	}
+	now := time.Now()
+	silentSince := s.getLastOperatorTime()
+	if now.Sub(silentSince) < window || now.Sub(s.lastDiagnosisSampleAt) < diagnosisSampleInterval {
+		return
+	}
+	s.lastDiagnosisSampleAt = now
+	reasons := d.collectReasons(s)
+	// the scheduler may have been removed during the sampling.
+	if s.Ctx().Err() != nil {
+		return
+	}
+
+	name := s.GetName()
+	d.mu.Lock()
+	summary, ok := d.summaries[name]
+	if !ok || !summary.SilentSince.Equal(silentSince) {
+		summary = &schedule.SchedulerDiagnosis{SilentSince: silentSince}
+		d.summaries[name] = summary
+	}
+	changed := summary.AddSample(reasons, now)
+	summary = summary.Clone()
+	d.mu.Unlock()
+	if !changed {
+		return
+	}
+
+	if err := d.cluster.storage.SaveSchedulerDiagnosis(name, summary); err != nil {
+		log.Warn("failed to persist the scheduler diagnosis", zap.String("scheduler-name", name), errs.ZapError(err))
+	}
+}
+
+// collectReasons returns the reasons why the scheduler does not schedule at the moment.
+func (d *diagnosisManager) collectReasons(s *scheduleController) map[string]int {
+	reasons := make(map[string]int)
+	if s.IsPaused() {
+		reasons[plan.StatusText(plan.StatusPaused)]++
+		return reasons
+	}
+	if d.cluster.GetUnsafeRecoveryController().IsRunning() {
+		reasons[diagnosisReasonUnsafeRecovery]++
+		return reasons
+	}
+	if err := schedule.CheckPrerequisites(s.Scheduler, d.cluster); err != nil {
+		reasons[diagnosisReasonPrerequisitePrefix+err.Error()]++
+		return reasons
+	}
+	if !s.Scheduler.IsScheduleAllowed(d.cluster) {
+		reasons[diagnosisReasonNotAllowed]++
+		return reasons
+	}
+
+	ops, plans := s.DiagnoseDryRun()
+	result := newDiagnosisResult(ops, plans)
+	d.putDryRunResult(s.GetName(), result)
+	if len(ops) > 0 {
+		// The scheduler can schedule now, the operators may be rejected by the operator controller.
+		reasons[plan.StatusText(plan.StatusOK)]++
+		return reasons
+	}
+	if result == nil || len(result.unschedulablePlans) == 0 {
+		reasons[plan.StatusText(plan.StatusNoNeed)]++
+		return reasons
+	}
+	for _, p := range result.unschedulablePlans {
+		reasons[planReason(p)]++
+	}
+	return reasons
+}
+
+func planReason(p plan.Plan) string {
+	reporter, ok := p.(plan.StatusReporter)
+	if !ok || reporter.GetStatus() == nil {
+		return diagnosisReasonUnknown
+	}
+	status := reporter.GetStatus()
+	if status.DetailedReason == "" {
+		return plan.StatusText(status.StatusCode)
+	}
+	return status.String()
+}
+
+// getSummary returns the diagnosis of the scheduler, it returns nil if the
+// scheduler is not silent.
+func (d *diagnosisManager) getSummary(name string) *schedule.SchedulerDiagnosis {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if summary, ok := d.summaries[name]; ok {
+		return summary.Clone()
+	}
 	return nil
 }
 
+// loadSummary restores the persisted diagnosis of the scheduler, so that the
+// silent period is continued after the leader changes.
+func (d *diagnosisManager) loadSummary(s *scheduleController) {
+	summary := &schedule.SchedulerDiagnosis{}
+	ok, err := d.cluster.storage.LoadSchedulerDiagnosis(s.GetName(), summary)
+	if err != nil {
+		log.Warn("failed to load the scheduler diagnosis", zap.String("scheduler-name", s.GetName()), errs.ZapError(err))
+		return
+	}
+	if !ok {
+		return
+	}
+	d.mu.Lock()
+	d.summaries[s.GetName()] = summary
+	d.mu.Unlock()
+	atomic.StoreInt64(&s.lastOperatorAt, summary.SilentSince.UnixNano())
+}
+
+// resetSummary drops the diagnosis of the scheduler once it produces operators.
+func (d *diagnosisManager) resetSummary(name string) {
+	d.mu.Lock()
+	_, ok := d.summaries[name]
+	delete(d.summaries, name)
+	d.mu.Unlock()
+	if !ok {
+		return
+	}
+	if err := d.cluster.storage.RemoveSchedulerDiagnosis(name); err != nil {
+		log.Warn("failed to remove the scheduler diagnosis", zap.String("scheduler-name", name), errs.ZapError(err))
+	}
+}
+
+// removeSummary drops the diagnosis, the dry run results and the observed
+// operators of a removed scheduler.
+func (d *diagnosisManager) removeSummary(name string) {
+	d.mu.Lock()
+	delete(d.dryRunResult, name)
+	delete(d.observed, name)
+	d.mu.Unlock()
+	d.resetSummary(name)
+}
+
 type diagnosisResult struct {
 	timestamp          uint64
 	unschedulablePlans []plan.Plan
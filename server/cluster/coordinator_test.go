@@ -17,8 +17,10 @@ package cluster
 import (
 	"context"
 	"encoding/json"
+	"fmt"
 	"math/rand"
 	"sync"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -28,6 +30,7 @@ import (
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/mock/mockhbstream"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/pkg/typeutil"
@@ -35,12 +38,16 @@ import (
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
 	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/checker"
 	"github.com/tikv/pd/server/schedule/hbstream"
 	"github.com/tikv/pd/server/schedule/labeler"
 	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/schedule/plan"
 	"github.com/tikv/pd/server/schedulers"
 	"github.com/tikv/pd/server/statistics"
 	"github.com/tikv/pd/server/storage"
+	"github.com/tikv/pd/server/storage/endpoint"
 )
 
 func newTestOperator(regionID uint64, regionEpoch *metapb.RegionEpoch, kind operator.OpKind, steps ...operator.OpStep) *operator.Operator {
@@ -229,6 +236,50 @@ func TestDispatch(t *testing.T) {
 	waitNoResponse(re, stream)
 }
 
+func TestDrainOperators(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	defer cleanup()
+	oc := co.opController
+	stream := mockhbstream.NewHeartbeatStream()
+
+	re.NoError(tc.addRegionStore(1, 2))
+	re.NoError(tc.addRegionStore(2, 0))
+	re.NoError(tc.addLeaderRegion(1, 1, 2))
+	re.NoError(tc.addLeaderRegion(2, 1, 2))
+	op1 := newTestOperator(1, tc.GetRegion(1).GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	re.True(oc.AddOperator(op1))
+	op2 := newTestOperator(2, tc.GetRegion(2).GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	re.True(oc.AddOperator(op2))
+
+	done := make(chan struct{})
+	go func() {
+		co.drainOperators(time.Second)
+		close(done)
+	}()
+	testutil.Eventually(re, oc.IsDraining)
+
+	// No new operator is accepted when draining.
+	re.True(oc.RemoveOperator(op1))
+	op3 := newTestOperator(1, tc.GetRegion(1).GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	re.False(oc.AddOperator(op3))
+	re.Nil(oc.GetOperator(1))
+
+	// The operator finished when draining succeeds.
+	region := tc.GetRegion(2)
+	re.NoError(dispatchHeartbeat(co, region.Clone(core.WithLeader(region.GetStorePeer(2))), stream))
+	<-done
+	re.Equal(operator.SUCCESS, op2.Status())
+
+	// The operator not finished in time is canceled with the reason.
+	op4 := newTestOperator(1, tc.GetRegion(1).GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	oc.SetOperator(op4)
+	co.drainOperators(0)
+	re.Equal(operator.CANCELED, op4.Status())
+	re.Equal("coordinator:shutdown{drain-timeout=0s}", op4.GetReasonChain())
+}
+
 func dispatchHeartbeat(co *coordinator, region *core.RegionInfo, stream hbstream.HeartbeatStream) error {
 	co.hbStreams.BindStream(region.GetLeader().GetStoreId(), stream)
 	if err := co.cluster.putRegion(region.Clone()); err != nil {
@@ -313,6 +364,173 @@ func TestDiagnosisDryRun(t *testing.T) {
 	re.NoError(err)
 }
 
+type mockDiagnosisPlan struct {
+	status *plan.Status
+}
+
+func (p *mockDiagnosisPlan) GetStatus() *plan.Status {
+	return p.status
+}
+
+// mockSilentScheduler never produces any operator.
+type mockSilentScheduler struct {
+	schedule.Scheduler
+	plans []plan.Plan
+}
+
+func (s *mockSilentScheduler) Schedule(cluster schedule.Cluster, dryRun bool) ([]*operator.Operator, []plan.Plan) {
+	return nil, s.plans
+}
+
+// mockSlowScheduler takes the given time in each schedule and never produces any operator.
+type mockSlowScheduler struct {
+	schedule.Scheduler
+	cost  time.Duration
+	calls int
+}
+
+func (s *mockSlowScheduler) Schedule(cluster schedule.Cluster, dryRun bool) ([]*operator.Operator, []plan.Plan) {
+	s.calls++
+	time.Sleep(s.cost)
+	return nil, nil
+}
+
+func TestSchedulerExecutionBudget(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	defer cleanup()
+	scheduler, err := schedule.CreateScheduler(schedulers.BalanceLeaderType, co.opController, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(schedulers.BalanceLeaderType, []string{"", ""}))
+	re.NoError(err)
+	s := &mockSlowScheduler{Scheduler: scheduler, cost: 10 * time.Millisecond}
+	sc := newScheduleController(co, s)
+
+	// The budget is per scheduler.
+	cfg := tc.GetOpts().GetScheduleConfig().Clone()
+	cfg.SchedulerExecutionBudget.Duration = 0
+	cfg.SchedulerExecutionBudgets = map[string]typeutil.Duration{sc.GetName(): typeutil.NewDuration(25 * time.Millisecond)}
+	tc.GetOpts().SetScheduleConfig(cfg)
+	re.Zero(tc.GetOpts().GetSchedulerExecutionBudget(schedulers.BalanceRegionName))
+
+	// The scheduler stops retrying once the budget is used up.
+	re.Empty(sc.Schedule())
+	re.Less(s.calls, maxScheduleRetries)
+	re.GreaterOrEqual(s.calls, 2)
+	sc.takeOverrun()
+
+	// The next tick is delayed by the overrun.
+	s.calls, s.cost = 0, 40*time.Millisecond
+	re.Empty(sc.Schedule())
+	re.Equal(1, s.calls)
+	overrun := sc.takeOverrun()
+	re.Greater(overrun, time.Duration(0))
+	re.Zero(sc.takeOverrun())
+
+	// It is unlimited without the budget.
+	cfg.SchedulerExecutionBudgets = nil
+	tc.GetOpts().SetScheduleConfig(cfg)
+	s.calls, s.cost = 0, 0
+	re.Empty(sc.Schedule())
+	re.Equal(maxScheduleRetries, s.calls)
+	re.Zero(sc.takeOverrun())
+}
+
+func TestDiagnosisSampling(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(func(cfg *config.ScheduleConfig) {
+		cfg.SchedulerDiagnosisWindow.Duration = time.Minute
+	}, nil, nil, re)
+	defer cleanup()
+	defer func(old time.Duration) {
+		diagnosisSampleInterval = old
+	}(diagnosisSampleInterval)
+	diagnosisSampleInterval = 0
+
+	scheduler, err := schedule.CreateScheduler(schedulers.BalanceLeaderType, co.opController, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(schedulers.BalanceLeaderType, []string{"", ""}))
+	re.NoError(err)
+	lowSpace := plan.NewStatus(plan.StatusStoreLowSpace, "no space")
+	hot := plan.NewStatus(plan.StatusRegionHot)
+	s := &mockSilentScheduler{
+		Scheduler: scheduler,
+		plans:     []plan.Plan{&mockDiagnosisPlan{&lowSpace}, &mockDiagnosisPlan{&lowSpace}, &mockDiagnosisPlan{&hot}, "unknown"},
+	}
+	sc := newScheduleController(co, s)
+	name := sc.GetName()
+
+	// The scheduler has not been silent for long enough.
+	co.diagnosis.sampleIfSilent(sc)
+	re.Nil(co.diagnosis.getSummary(name))
+
+	silentSince := time.Now().Add(-2 * time.Minute)
+	atomic.StoreInt64(&sc.lastOperatorAt, silentSince.UnixNano())
+	co.diagnosis.sampleIfSilent(sc)
+	co.diagnosis.sampleIfSilent(sc)
+	summary := co.diagnosis.getSummary(name)
+	re.NotNil(summary)
+	re.Equal(2, summary.Samples)
+	re.Equal(silentSince.UnixNano(), summary.SilentSince.UnixNano())
+	re.Len(summary.Reasons, 3)
+	re.Equal(lowSpace.String(), summary.Reasons[0].Reason)
+	re.Equal(4, summary.Reasons[0].Count)
+	re.Equal(plan.StatusText(plan.StatusRegionHot), summary.Reasons[1].Reason)
+	re.Equal(2, summary.Reasons[1].Count)
+	re.Equal(diagnosisReasonUnknown, summary.Reasons[2].Reason)
+
+	// The summary is persisted once the ranking of the reasons changes, and can be
+	// restored by another leader.
+	d := newDiagnosisManager(tc.RaftCluster, co.schedulers)
+	sc2 := newScheduleController(co, s)
+	d.loadSummary(sc2)
+	restored := d.getSummary(name)
+	re.NotNil(restored)
+	re.Equal(1, restored.Samples)
+	re.Len(restored.Reasons, len(summary.Reasons))
+	for i, r := range restored.Reasons {
+		re.Equal(summary.Reasons[i].Reason, r.Reason)
+	}
+	re.Equal(silentSince.UnixNano(), sc2.getLastOperatorTime().UnixNano())
+
+	// The summary is dropped once the scheduler produces operators.
+	co.diagnosis.resetSummary(name)
+	re.Nil(co.diagnosis.getSummary(name))
+	loaded, err := tc.storage.LoadSchedulerDiagnosis(name, &schedule.SchedulerDiagnosis{})
+	re.NoError(err)
+	re.False(loaded)
+
+	// The background sampling can be disabled.
+	cfg := tc.GetOpts().GetScheduleConfig().Clone()
+	cfg.SchedulerDiagnosisWindow.Duration = 0
+	tc.GetOpts().SetScheduleConfig(cfg)
+	co.diagnosis.sampleIfSilent(sc)
+	re.Nil(co.diagnosis.getSummary(name))
+}
+
+func TestSchedulerDiagnosisReasonsCapped(t *testing.T) {
+	re := require.New(t)
+	summary := &schedule.SchedulerDiagnosis{}
+	now := time.Now()
+	re.True(summary.AddSample(map[string]int{"a": 2}, now))
+	re.False(summary.AddSample(map[string]int{"a": 1}, now))
+
+	reasons := make(map[string]int)
+	for i := 0; i < 2*schedule.MaxDiagnosisReasons; i++ {
+		reasons[fmt.Sprintf("reason-%02d", i)] = 1
+	}
+	re.True(summary.AddSample(reasons, now))
+	re.Len(summary.Reasons, schedule.MaxDiagnosisReasons)
+	re.Equal("a", summary.Reasons[0].Reason)
+	others := summary.Reasons[schedule.MaxDiagnosisReasons-1]
+	re.Equal(schedule.DiagnosisReasonOthers, others.Reason)
+	othersCount := 2*schedule.MaxDiagnosisReasons - (schedule.MaxDiagnosisReasons - 2)
+	re.Equal(othersCount, others.Count)
+
+	// The new rare reasons are merged without changing the ranking.
+	re.False(summary.AddSample(map[string]int{"a": 1, "unknown": 1}, now))
+	re.Len(summary.Reasons, schedule.MaxDiagnosisReasons)
+	re.Equal(othersCount+1, summary.Reasons[schedule.MaxDiagnosisReasons-1].Count)
+}
+
 func TestCheckRegion(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -358,6 +576,57 @@ func TestCheckRegion(t *testing.T) {
 	checkRegionAndOperator(re, tc, co, 1, 0)
 }
 
+func TestDryRunChecker(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	defer cleanup()
+
+	re.NoError(tc.addRegionStore(4, 4))
+	re.NoError(tc.addRegionStore(3, 3))
+	re.NoError(tc.addRegionStore(2, 2))
+	re.NoError(tc.addRegionStore(1, 1))
+	re.NoError(tc.addLeaderRegion(1, 2, 3))
+	name, other := "replica", "rule"
+	if tc.opt.IsPlacementRulesEnabled() {
+		name, other = other, name
+	}
+
+	// The proposed operator is returned but not added.
+	results, err := co.dryRunChecker(name, []uint64{1, 100}, 0)
+	re.NoError(err)
+	re.Len(results, 1)
+	re.Equal(uint64(1), results[0].RegionID)
+	re.Len(results[0].Operators, 1)
+	re.Equal(results[0].Operators[0].Desc(), results[0].Reason)
+	testutil.CheckAddPeer(re, results[0].Operators[0], operator.OpReplica, 1)
+	re.Nil(co.opController.GetOperator(1))
+
+	// The checker which does not work in the current mode proposes nothing.
+	results, err = co.dryRunChecker(other, []uint64{1}, 0)
+	re.NoError(err)
+	re.Len(results, 1)
+	re.Empty(results[0].Operators)
+
+	// The regions are sampled if no region is given.
+	re.NoError(tc.addLeaderRegion(2, 3, 4))
+	results, err = co.dryRunChecker(name, nil, 1)
+	re.NoError(err)
+	re.Len(results, 1)
+	results, err = co.dryRunChecker(name, nil, 10)
+	re.NoError(err)
+	re.Len(results, 2)
+
+	re.NoError(co.pauseOrResumeChecker(name, 60))
+	results, err = co.dryRunChecker(name, []uint64{1}, 0)
+	re.NoError(err)
+	re.Equal(checker.DryRunCheckerPaused, results[0].Reason)
+	re.Empty(results[0].Operators)
+
+	_, err = co.dryRunChecker("dummy", nil, 10)
+	re.Error(err)
+}
+
 func TestCheckRegionWithScheduleDeny(t *testing.T) {
 	re := require.New(t)
 
@@ -952,6 +1221,130 @@ func TestPauseScheduler(t *testing.T) {
 	re.False(allowed)
 }
 
+func TestSchedulerState(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, re)
+	defer cleanup()
+	re.NoError(tc.addLeaderStore(1, 1))
+	re.NoError(tc.addLeaderStore(2, 0))
+	re.NoError(tc.addLeaderRegion(1, 1, 2))
+
+	_, err := co.getSchedulerState("test")
+	re.Error(err)
+	state, err := co.getSchedulerState(schedulers.BalanceLeaderName)
+	re.NoError(err)
+	re.Equal(schedulers.BalanceLeaderName, state.Name)
+	re.Equal(schedulers.BalanceLeaderType, state.Type)
+	re.False(state.Paused)
+	re.NotEmpty(state.Config)
+	testutil.Eventually(re, func() bool {
+		state, err = co.getSchedulerState(schedulers.BalanceLeaderName)
+		return err == nil && !state.LastScheduleTime.IsZero()
+	})
+
+	re.NoError(co.pauseOrResumeScheduler(schedulers.BalanceLeaderName, 60))
+	state, err = co.getSchedulerState(schedulers.BalanceLeaderName)
+	re.NoError(err)
+	re.True(state.Paused)
+
+	state, err = co.getSchedulerState(schedulers.HotRegionName)
+	re.NoError(err)
+	re.NotNil(state.State)
+}
+
+func TestObserveOnlyScheduler(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, re)
+	defer cleanup()
+	re.Error(co.setSchedulerObserveOnly("test", 60))
+	_, err := co.getSchedulerObservation("test")
+	re.Error(err)
+	re.NoError(co.removeScheduler(schedulers.BalanceRegionName))
+	re.NoError(co.setSchedulerObserveOnly(schedulers.BalanceLeaderName, 60))
+	state, err := co.getSchedulerState(schedulers.BalanceLeaderName)
+	re.NoError(err)
+	re.True(state.Observing)
+
+	// Transfer leader from store 4 to store 2, which is only recorded.
+	re.NoError(tc.addLeaderStore(4, 50))
+	re.NoError(tc.addLeaderStore(3, 50))
+	re.NoError(tc.addLeaderStore(2, 20))
+	re.NoError(tc.addLeaderStore(1, 10))
+	re.NoError(tc.addLeaderRegion(2, 4, 3, 2))
+	var observation *schedule.SchedulerObservation
+	testutil.Eventually(re, func() bool {
+		observation, err = co.getSchedulerObservation(schedulers.BalanceLeaderName)
+		return err == nil && len(observation.Operators) > 0
+	})
+	re.True(observation.Observing)
+	re.Equal(int64(60), observation.ObserveUntil.Unix()-observation.ObserveSince.Unix())
+	re.Equal(uint64(2), observation.Operators[0].RegionID)
+	re.NotEmpty(observation.Operators[0].Detail)
+	re.Nil(co.opController.GetOperator(2))
+
+	// The operators are submitted again after leaving the observe-only mode,
+	// and the records are kept.
+	re.NoError(co.setSchedulerObserveOnly(schedulers.BalanceLeaderName, 0))
+	waitOperator(re, co, 2)
+	observation, err = co.getSchedulerObservation(schedulers.BalanceLeaderName)
+	re.NoError(err)
+	re.False(observation.Observing)
+	re.True(observation.ObserveUntil.IsZero())
+	re.NotEmpty(observation.Operators)
+
+	// The records are dropped once the scheduler is removed.
+	re.NoError(co.removeScheduler(schedulers.BalanceLeaderName))
+	re.Empty(co.diagnosis.getObservedOperators(schedulers.BalanceLeaderName))
+}
+
+func TestPersistObservingScheduler(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tc, co, cleanup := prepare(nil, nil, func(co *coordinator) { co.run() }, re)
+	hbStreams := co.hbStreams
+	defer cleanup()
+	storage := tc.RaftCluster.storage
+
+	// The scheduler is observing once it is added.
+	shuffle, err := schedule.CreateScheduler(schedulers.ShuffleLeaderType, co.opController, storage, schedule.ConfigSliceDecoder(schedulers.ShuffleLeaderType, nil))
+	re.NoError(err)
+	re.NoError(co.addObservingScheduler(shuffle, time.Now().Unix()+60))
+	state, err := co.getSchedulerState(schedulers.ShuffleLeaderName)
+	re.NoError(err)
+	re.True(state.Observing)
+	re.NoError(co.cluster.opt.Persist(storage))
+	co.stop()
+	co.wg.Wait()
+
+	// The observe-only mode is restored with the scheduler after the leader changes.
+	_, newOpt, err := newTestScheduleConfig()
+	re.NoError(err)
+	re.NoError(newOpt.Reload(storage))
+	tc.RaftCluster.opt = newOpt
+	co = newCoordinator(ctx, tc.RaftCluster, hbStreams)
+	co.run()
+	defer func() {
+		co.stop()
+		co.wg.Wait()
+	}()
+	state, err = co.getSchedulerState(schedulers.ShuffleLeaderName)
+	re.NoError(err)
+	re.True(state.Observing)
+
+	// Leaving the observe-only mode is persisted too.
+	re.NoError(co.setSchedulerObserveOnly(schedulers.ShuffleLeaderName, 0))
+	_, newOpt, err = newTestScheduleConfig()
+	re.NoError(err)
+	re.NoError(newOpt.Reload(storage))
+	for _, cfg := range newOpt.GetSchedulers() {
+		re.Zero(cfg.ObserveUntil, cfg.Type)
+	}
+}
+
 func BenchmarkPatrolRegion(b *testing.B) {
 	re := require.New(b)
 
@@ -1361,3 +1754,96 @@ func waitNoResponse(re *require.Assertions, stream mockhbstream.HeartbeatStream)
 		return res == nil
 	})
 }
+
+func TestSimulateScheduling(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	tc.RaftCluster.coordinator = co
+	defer cleanup()
+
+	re.NoError(tc.addRegionStore(1, 10))
+	re.NoError(tc.addRegionStore(2, 10))
+	re.NoError(tc.addRegionStore(3, 10))
+	for i := uint64(1); i <= 10; i++ {
+		re.NoError(tc.addLeaderRegion(i, 1, 2, 3))
+	}
+	bl, err := schedule.CreateScheduler(schedulers.BalanceLeaderType, co.opController, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(schedulers.BalanceLeaderType, []string{"", ""}))
+	re.NoError(err)
+	// Do not run the scheduler to keep the cluster unchanged.
+	co.schedulers[bl.GetName()] = newScheduleController(co, bl)
+
+	impacts, err := tc.SimulateScheduling(nil, nil)
+	re.NoError(err)
+	re.Len(impacts, 1)
+	re.Equal(bl.GetName(), impacts[0].Name)
+	re.Greater(impacts[0].CurrentOperators, 0)
+	re.Equal(impacts[0].CurrentOperators, impacts[0].SimulatedOperators)
+	re.Equal(0, impacts[0].Diff)
+	// The simulation is repeatable as it doesn't change the state of the running scheduler.
+	again, err := tc.SimulateScheduling(nil, nil)
+	re.NoError(err)
+	re.Equal(impacts, again)
+
+	// A large tolerant size ratio stops balancing leaders.
+	cfg := tc.GetOpts().GetScheduleConfig().Clone()
+	cfg.TolerantSizeRatio = 100
+	impacts, err = tc.SimulateScheduling(cfg, nil)
+	re.NoError(err)
+	re.Len(impacts, 1)
+	re.Equal(0, impacts[0].SimulatedOperators)
+	re.Equal(-impacts[0].CurrentOperators, impacts[0].Diff)
+	// The real config is not changed.
+	re.NotEqual(100.0, tc.GetOpts().GetTolerantSizeRatio())
+
+	// Rules can not be simulated if placement rules are disabled.
+	tc.GetOpts().SetPlacementRuleEnabled(false)
+	_, err = tc.SimulateScheduling(nil, []*placement.Rule{{GroupID: "pd", ID: "test", Role: placement.Voter, Count: 3}})
+	re.True(errs.ErrRuleContent.Equal(err))
+}
+
+func TestPersistSuspectKeyRanges(t *testing.T) {
+	re := require.New(t)
+
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	tc.RaftCluster.coordinator = co
+	defer cleanup()
+
+	tc.AddSuspectKeyRange([]byte("a"), []byte("b"), endpoint.SuspectReasonRuleChanged)
+	tc.AddSuspectKeyRange([]byte("c"), []byte("d"), endpoint.SuspectReasonRuleGroupChanged)
+	// An expired key range.
+	re.NoError(tc.storage.SaveSuspectKeyRange("expired", &endpoint.SuspectKeyRange{
+		StartKey:  []byte("e"),
+		EndKey:    []byte("f"),
+		Reason:    endpoint.SuspectReasonRuleChanged,
+		Timestamp: time.Now().Add(-2 * tc.opt.GetSuspectKeyRangeGCAge()).Unix(),
+	}))
+	reasons := make(map[string]string)
+	re.NoError(tc.storage.LoadSuspectKeyRanges(func(_ string, keyRange *endpoint.SuspectKeyRange) {
+		reasons[string(keyRange.StartKey)] = keyRange.Reason
+	}))
+	re.Equal(map[string]string{
+		"a": endpoint.SuspectReasonRuleChanged,
+		"c": endpoint.SuspectReasonRuleGroupChanged,
+		"e": endpoint.SuspectReasonRuleChanged,
+	}, reasons)
+
+	// Simulate the leader change, which loses the in-memory key ranges.
+	tc.ClearSuspectKeyRanges()
+	tc.gcSuspectKeyRanges(true)
+	var ranges [][2][]byte
+	for {
+		keyRange, ok := tc.PopOneSuspectKeyRange()
+		if !ok {
+			break
+		}
+		ranges = append(ranges, keyRange)
+	}
+	re.Len(ranges, 2)
+	// The expired one is removed and the popped ones are deleted.
+	count := 0
+	re.NoError(tc.storage.LoadSuspectKeyRanges(func(string, *endpoint.SuspectKeyRange) {
+		count++
+	}))
+	re.Equal(0, count)
+}
@@ -0,0 +1,173 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+	"sort"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/placement"
+)
+
+// DecommissionEstimate is the estimated cost of taking a store offline.
+type DecommissionEstimate struct {
+	StoreID uint64 `json:"store_id"`
+	// PeerCount is the number of peers which must be moved off the store.
+	PeerCount  int   `json:"peer_count"`
+	RegionSize int64 `json:"region_size"`
+	// OrphanPeerCount is the number of peers which match no rule, they are removed
+	// without being replaced.
+	OrphanPeerCount int `json:"orphan_peer_count"`
+	// EstimatedSeconds is the estimated duration to move all the peers off the store.
+	EstimatedSeconds float64 `json:"estimated_seconds"`
+	// Blocked is true if the peers of any rule have no store to move to.
+	Blocked bool                        `json:"blocked"`
+	Rules   []*RuleDecommissionEstimate `json:"rules"`
+}
+
+// RuleDecommissionEstimate is the estimated cost of moving the peers of a placement
+// rule off a store.
+type RuleDecommissionEstimate struct {
+	GroupID    string `json:"group_id"`
+	ID         string `json:"id"`
+	PeerCount  int    `json:"peer_count"`
+	RegionSize int64  `json:"region_size"`
+	// TargetStores are the stores which qualify to accommodate the peers of the rule.
+	TargetStores []uint64 `json:"target_stores"`
+	// EstimatedSeconds is the estimated duration limited by the remove-peer limit of
+	// the store and the add-peer limits of the target stores.
+	EstimatedSeconds float64 `json:"estimated_seconds"`
+	Blocked          bool    `json:"blocked"`
+}
+
+// EstimateDecommission estimates the cost of taking the store offline. The peers on the
+// store are grouped by the placement rules they fit, and the duration is estimated by
+// the current store limits.
+func (c *RaftCluster) EstimateDecommission(storeID uint64) (*DecommissionEstimate, error) {
+	store := c.GetStore(storeID)
+	if store == nil {
+		return nil, errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+	if store.IsRemoved() {
+		return nil, errs.ErrStoreRemoved.FastGenByArgs(storeID)
+	}
+
+	estimate := &DecommissionEstimate{StoreID: storeID}
+	ruleEstimates := make(map[[2]string]*RuleDecommissionEstimate)
+	getRuleEstimate := func(rule *placement.Rule) *RuleDecommissionEstimate {
+		key := [2]string{rule.GroupID, rule.ID}
+		if e, ok := ruleEstimates[key]; ok {
+			return e
+		}
+		e := &RuleDecommissionEstimate{GroupID: rule.GroupID, ID: rule.ID}
+		for _, s := range matchRuleStores(c.GetStores(), rule) {
+			if s.GetID() != storeID && s.IsUp() && !s.IsDisconnected() {
+				e.TargetStores = append(e.TargetStores, s.GetID())
+			}
+		}
+		sort.Slice(e.TargetStores, func(i, j int) bool { return e.TargetStores[i] < e.TargetStores[j] })
+		ruleEstimates[key] = e
+		estimate.Rules = append(estimate.Rules, e)
+		return e
+	}
+
+	// When placement rules are disabled, all the peers follow the max replicas.
+	defaultRule := &placement.Rule{
+		GroupID:        "pd",
+		ID:             "default",
+		Role:           placement.Voter,
+		Count:          c.opt.GetMaxReplicas(),
+		LocationLabels: c.opt.GetLocationLabels(),
+	}
+	for _, region := range c.GetStoreRegions(storeID) {
+		peer := region.GetStorePeer(storeID)
+		if peer == nil {
+			continue
+		}
+		rule := defaultRule
+		if c.opt.IsPlacementRulesEnabled() {
+			rule = fitRuleOfPeer(c.ruleManager.FitRegion(c, region), peer.GetId())
+		}
+		if rule == nil {
+			estimate.OrphanPeerCount++
+			continue
+		}
+		e := getRuleEstimate(rule)
+		e.PeerCount++
+		e.RegionSize += region.GetApproximateSize()
+		estimate.PeerCount++
+		estimate.RegionSize += region.GetApproximateSize()
+	}
+
+	// The remove-peer limit of the store is shared by all the rules.
+	removeRate := c.opt.GetStoreLimitByType(storeID, storelimit.RemovePeer) / schedule.StoreBalanceBaseTime
+	if removeRate > 0 {
+		estimate.EstimatedSeconds = float64(estimate.PeerCount+estimate.OrphanPeerCount) / removeRate
+	}
+	for _, e := range estimate.Rules {
+		addRate := 0.0
+		for _, id := range e.TargetStores {
+			addRate += c.opt.GetStoreLimitByType(id, storelimit.AddPeer) / schedule.StoreBalanceBaseTime
+		}
+		rate := math.Min(removeRate, addRate)
+		if rate <= 0 {
+			e.Blocked, estimate.Blocked = true, true
+			continue
+		}
+		e.EstimatedSeconds = float64(e.PeerCount) / rate
+		estimate.EstimatedSeconds = math.Max(estimate.EstimatedSeconds, e.EstimatedSeconds)
+	}
+	if estimate.Blocked {
+		estimate.EstimatedSeconds = 0
+	}
+	sort.Slice(estimate.Rules, func(i, j int) bool {
+		if estimate.Rules[i].GroupID != estimate.Rules[j].GroupID {
+			return estimate.Rules[i].GroupID < estimate.Rules[j].GroupID
+		}
+		return estimate.Rules[i].ID < estimate.Rules[j].ID
+	})
+	return estimate, nil
+}
+
+// fitRuleOfPeer returns the rule which the peer is selected by in the fit.
+func fitRuleOfPeer(fit *placement.RegionFit, peerID uint64) *placement.Rule {
+	for _, rf := range fit.RuleFits {
+		for _, p := range rf.Peers {
+			if p.GetId() == peerID {
+				return rf.Rule
+			}
+		}
+	}
+	return nil
+}
+
+// matchRuleStores returns the stores which match the label constraints of the rule,
+// excluding the stores being removed.
+func matchRuleStores(stores []*core.StoreInfo, rule *placement.Rule) []*core.StoreInfo {
+	var matchStores []*core.StoreInfo
+	for _, s := range stores {
+		if s.IsRemoving() || s.IsRemoved() {
+			continue
+		}
+		if placement.MatchLabelConstraints(s, rule.LabelConstraints) {
+			matchStores = append(matchStores, s)
+		}
+	}
+	return matchStores
+}
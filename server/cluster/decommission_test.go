@@ -0,0 +1,104 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestEstimateDecommission(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cfg := opt.GetReplicationConfig()
+	cfg.EnablePlacementRules = true
+	opt.SetReplicationConfig(cfg)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+
+	// zone1 has 1, 2, 3, 4, zone2 has 5.
+	for _, store := range newTestStores(5, "6.0.0") {
+		zone := "zone1"
+		if store.GetID() == 5 {
+			zone = "zone2"
+		}
+		s := store.Clone(
+			core.SetStoreLabels([]*metapb.StoreLabel{{Key: "zone", Value: zone}}),
+			core.SetLastHeartbeatTS(time.Now()),
+		)
+		re.NoError(cluster.putStoreLocked(s))
+	}
+	cluster.ruleManager.SetRule(&placement.Rule{GroupID: "pd", ID: "zone1", Role: placement.Voter, Count: 2,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: "in", Values: []string{"zone1"}}}})
+	cluster.ruleManager.SetRule(&placement.Rule{GroupID: "pd", ID: "zone2", Role: placement.Voter, Count: 1,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: "in", Values: []string{"zone2"}}}})
+	cluster.ruleManager.DeleteRule("pd", "default")
+
+	for i := uint64(1); i <= 10; i++ {
+		peers := []*metapb.Peer{{Id: i * 10, StoreId: 1}, {Id: i*10 + 1, StoreId: 2}, {Id: i*10 + 2, StoreId: 5}}
+		region := core.NewRegionInfo(&metapb.Region{
+			Id:          i,
+			StartKey:    []byte(fmt.Sprintf("%20d", i)),
+			EndKey:      []byte(fmt.Sprintf("%20d", i+1)),
+			Peers:       peers,
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}, peers[0], core.SetApproximateSize(10))
+		re.NoError(cluster.putRegion(region))
+	}
+
+	opt.SetStoreLimit(1, storelimit.RemovePeer, 6)
+	for _, id := range []uint64{2, 3, 4} {
+		opt.SetStoreLimit(id, storelimit.AddPeer, 1)
+	}
+	estimate, err := cluster.EstimateDecommission(1)
+	re.NoError(err)
+	re.Equal(10, estimate.PeerCount)
+	re.Equal(int64(100), estimate.RegionSize)
+	re.Zero(estimate.OrphanPeerCount)
+	re.False(estimate.Blocked)
+	re.Len(estimate.Rules, 1)
+	re.Equal("zone1", estimate.Rules[0].ID)
+	re.Equal([]uint64{2, 3, 4}, estimate.Rules[0].TargetStores)
+	// 10 peers / min(6 remove-peer, 3 add-peer) per minute
+	re.Equal(200.0, estimate.Rules[0].EstimatedSeconds)
+	re.Equal(200.0, estimate.EstimatedSeconds)
+
+	// The peers in zone2 have no store to move to.
+	estimate, err = cluster.EstimateDecommission(5)
+	re.NoError(err)
+	re.True(estimate.Blocked)
+	re.Len(estimate.Rules, 1)
+	re.Equal("zone2", estimate.Rules[0].ID)
+	re.Empty(estimate.Rules[0].TargetStores)
+	re.True(estimate.Rules[0].Blocked)
+
+	_, err = cluster.EstimateDecommission(6)
+	re.True(errors.ErrorEqual(err, errs.ErrStoreNotFound.FastGenByArgs(uint64(6))))
+}
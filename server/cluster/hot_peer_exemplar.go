@@ -0,0 +1,140 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"sort"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/statistics"
+)
+
+// HotPeerExemplar is one of the hottest peers of a store, which is exported with
+// its region as the exemplar so that the hot store can be traced to the regions.
+type HotPeerExemplar struct {
+	StoreID uint64
+	RWType  string
+	// Rank is the rank of the peer by the byte rate in the store, from 1.
+	Rank     int
+	RegionID uint64
+	// KeyRangeHash identifies the key range of the region without exposing the
+	// keys, it is empty if the region is not in the cache.
+	KeyRangeHash string
+	ByteRate     float64
+	KeyRate      float64
+	QueryRate    float64
+}
+
+// HotPeerExemplars is the hottest peers of all the stores at a time.
+type HotPeerExemplars struct {
+	UpdatedAt time.Time
+	TopN      int
+	Peers     []HotPeerExemplar
+}
+
+// hotPeerExemplarCache keeps the hot peer exemplars for the refresh interval, so
+// that the frequent scrapes do not walk the hot statistics each time.
+type hotPeerExemplarCache struct {
+	syncutil.Mutex
+	cluster   *RaftCluster
+	exemplars *HotPeerExemplars
+}
+
+func newHotPeerExemplarCache(cluster *RaftCluster) *hotPeerExemplarCache {
+	return &hotPeerExemplarCache{cluster: cluster}
+}
+
+func (c *hotPeerExemplarCache) get(now time.Time) *HotPeerExemplars {
+	opt := c.cluster.GetOpts()
+	topN := opt.GetHotPeerExemplarTopN()
+	if topN <= 0 {
+		return &HotPeerExemplars{UpdatedAt: now}
+	}
+	c.Lock()
+	defer c.Unlock()
+	if c.exemplars != nil && c.exemplars.TopN == topN &&
+		now.Sub(c.exemplars.UpdatedAt) < opt.GetHotPeerExemplarRefreshInterval() {
+		return c.exemplars
+	}
+	exemplars := &HotPeerExemplars{UpdatedAt: now, TopN: topN}
+	exemplars.Peers = append(exemplars.Peers, c.collect(statistics.Read, c.cluster.RegionReadStats(), topN)...)
+	exemplars.Peers = append(exemplars.Peers, c.collect(statistics.Write, c.cluster.RegionWriteStats(), topN)...)
+	sort.Slice(exemplars.Peers, func(i, j int) bool {
+		a, b := exemplars.Peers[i], exemplars.Peers[j]
+		if a.StoreID != b.StoreID {
+			return a.StoreID < b.StoreID
+		}
+		if a.RWType != b.RWType {
+			return a.RWType < b.RWType
+		}
+		return a.Rank < b.Rank
+	})
+	c.exemplars = exemplars
+	return exemplars
+}
+
+func (c *hotPeerExemplarCache) collect(rw statistics.RWType, stats map[uint64][]*statistics.HotPeerStat, topN int) []HotPeerExemplar {
+	var exemplars []HotPeerExemplar
+	for storeID, peers := range stats {
+		loads := make(map[*statistics.HotPeerStat][]float64, len(peers))
+		for _, peer := range peers {
+			loads[peer] = peer.GetLoads()
+		}
+		peers = append(peers[:0:0], peers...)
+		sort.Slice(peers, func(i, j int) bool {
+			li, lj := loads[peers[i]][statistics.ByteDim], loads[peers[j]][statistics.ByteDim]
+			if li != lj {
+				return li > lj
+			}
+			return peers[i].RegionID < peers[j].RegionID
+		})
+		if len(peers) > topN {
+			peers = peers[:topN]
+		}
+		for i, peer := range peers {
+			exemplar := HotPeerExemplar{
+				StoreID:   storeID,
+				RWType:    rw.String(),
+				Rank:      i + 1,
+				RegionID:  peer.RegionID,
+				ByteRate:  loads[peer][statistics.ByteDim],
+				KeyRate:   loads[peer][statistics.KeyDim],
+				QueryRate: loads[peer][statistics.QueryDim],
+			}
+			if region := c.cluster.GetRegion(peer.RegionID); region != nil {
+				exemplar.KeyRangeHash = keyRangeHash(region)
+			}
+			exemplars = append(exemplars, exemplar)
+		}
+	}
+	return exemplars
+}
+
+// keyRangeHash returns the FNV-1a hash of the key range of the region.
+func keyRangeHash(region *core.RegionInfo) string {
+	h := fnv.New64a()
+	var length [8]byte
+	for _, key := range [][]byte{region.GetStartKey(), region.GetEndKey()} {
+		binary.BigEndian.PutUint64(length[:], uint64(len(key)))
+		h.Write(length[:])
+		h.Write(key)
+	}
+	return fmt.Sprintf("%016x", h.Sum64())
+}
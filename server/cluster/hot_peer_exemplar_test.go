@@ -0,0 +1,94 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestHotPeerExemplars(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cfg := opt.GetScheduleConfig().Clone()
+	cfg.HotPeerExemplarTopN = 2
+	opt.SetScheduleConfig(cfg)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	regions := newTestRegions(4, 3, 3)
+	for _, region := range regions[:3] {
+		re.NoError(cluster.putRegion(region))
+	}
+	addHotPeer := func(kind statistics.RWType, storeID, regionID uint64, byteRate float64) {
+		loads := make([]float64, statistics.RegionStatCount)
+		loads[kind.RegionStats()[statistics.ByteDim]] = byteRate
+		loads[kind.RegionStats()[statistics.KeyDim]] = byteRate / 10
+		cluster.hotStat.Update(&statistics.HotPeerStat{
+			StoreID:   storeID,
+			RegionID:  regionID,
+			HotDegree: 100,
+			Kind:      kind,
+			Loads:     loads,
+		})
+	}
+	addHotPeer(statistics.Write, 1, 0, 100)
+	addHotPeer(statistics.Write, 1, 1, 300)
+	addHotPeer(statistics.Write, 1, 2, 200)
+	addHotPeer(statistics.Read, 2, 3, 50)
+
+	now := time.Now()
+	exemplars := cluster.hotPeerExemplars.get(now)
+	re.Equal(2, exemplars.TopN)
+	re.Len(exemplars.Peers, 3)
+	// The peers are sorted by the store, the type and the rank.
+	re.Equal(HotPeerExemplar{
+		StoreID:      1,
+		RWType:       "write",
+		Rank:         1,
+		RegionID:     1,
+		KeyRangeHash: keyRangeHash(regions[1]),
+		ByteRate:     300,
+		KeyRate:      30,
+	}, exemplars.Peers[0])
+	re.Equal(uint64(2), exemplars.Peers[1].RegionID)
+	re.Equal(2, exemplars.Peers[1].Rank)
+	// The region not in the cache has no key range hash.
+	re.Equal(uint64(3), exemplars.Peers[2].RegionID)
+	re.Equal("read", exemplars.Peers[2].RWType)
+	re.Empty(exemplars.Peers[2].KeyRangeHash)
+	re.NotEqual(keyRangeHash(regions[0]), keyRangeHash(regions[1]))
+
+	// The result is kept within the refresh interval.
+	addHotPeer(statistics.Write, 1, 0, 400)
+	re.Same(exemplars, cluster.hotPeerExemplars.get(now.Add(time.Second)))
+	exemplars = cluster.hotPeerExemplars.get(now.Add(opt.GetHotPeerExemplarRefreshInterval()))
+	re.Equal(uint64(0), exemplars.Peers[0].RegionID)
+	re.Equal(float64(400), exemplars.Peers[0].ByteRate)
+
+	cfg = opt.GetScheduleConfig().Clone()
+	cfg.HotPeerExemplarTopN = 0
+	opt.SetScheduleConfig(cfg)
+	re.Empty(cluster.hotPeerExemplars.get(now).Peers)
+}
@@ -0,0 +1,602 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"math/big"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"go.uber.org/zap"
+)
+
+const (
+	// ImportModeRulePrefix is the prefix of the region label rules used to
+	// deny merge in the key ranges being imported.
+	ImportModeRulePrefix = "import-mode-"
+	// importModeMaxRegionCount is the max number of regions pre-split for an import.
+	importModeMaxRegionCount = 10000
+	// importModeKeyPadding is the number of bytes appended to the boundaries
+	// to have enough resolution when generating the split keys.
+	importModeKeyPadding = 2
+	importModeRetryLimit = 3
+)
+
+// ImportModeStatus is the status of a key range prepared for the bulk import.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type ImportModeStatus struct {
+	ID          string  `json:"id"`
+	StartKey    string  `json:"start_key"`
+	EndKey      string  `json:"end_key"`
+	RegionCount int     `json:"region_count"`
+	Tolerance   float64 `json:"tolerance"`
+	// SplitRegions is the number of the regions created by the pre-split.
+	SplitRegions     int `json:"split_regions"`
+	ScatterFailures  int `json:"scatter_failures"`
+	PendingOperators int `json:"pending_operators"`
+	// LeaderCounts is the number of the leaders in the key range of each store.
+	LeaderCounts map[uint64]int `json:"leader_counts"`
+	// LeaderSpread is the max deviation of the leader counts from the average,
+	// in proportion to the average.
+	LeaderSpread float64 `json:"leader_spread"`
+	// TaskID is the ID of the task pre-splitting and scattering the regions.
+	TaskID uint64 `json:"task_id"`
+	// Ready is true if the scatter is finished and the leader spread is within
+	// the tolerance, then the import can be started.
+	Ready    bool      `json:"ready"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+type importMode struct {
+	id               string
+	startKey, endKey []byte
+	regionCount      int
+	tolerance        float64
+	taskID           uint64
+	expireAt         time.Time
+	// runner is the latest run of the task preparing the key range, it is nil
+	// if the task is ended before the leader changes.
+	runner *importModeTaskRunner
+}
+
+func (im *importMode) record() *endpoint.ImportModeRecord {
+	return &endpoint.ImportModeRecord{
+		ID:          im.id,
+		StartKey:    im.startKey,
+		EndKey:      im.endKey,
+		RegionCount: im.regionCount,
+		Tolerance:   im.tolerance,
+		TaskID:      im.taskID,
+		ExpireAt:    im.expireAt,
+	}
+}
+
+// importModeManager keeps the key ranges prepared for the bulk import, which
+// is the integration point of Lightning and BR. The key ranges are persisted,
+// and the pre-split and scatter run in the background as a task.
+type importModeManager struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	imports map[string]*importMode
+}
+
+func newImportModeManager(cluster *RaftCluster) *importModeManager {
+	return &importModeManager{
+		cluster: cluster,
+		imports: make(map[string]*importMode),
+	}
+}
+
+func importModeRuleID(id string) string {
+	return ImportModeRulePrefix + id
+}
+
+// splitKeysInRange returns the keys splitting the range into count parts
+// evenly. The keys are regarded as big-endian fractions, and the empty end key
+// is regarded as the end of the key space.
+func splitKeysInRange(startKey, endKey []byte, count int) ([][]byte, error) {
+	if count <= 1 {
+		return nil, nil
+	}
+	width := len(startKey)
+	if len(endKey) > width {
+		width = len(endKey)
+	}
+	width += importModeKeyPadding
+	toInt := func(key []byte) *big.Int {
+		padded := make([]byte, width)
+		copy(padded, key)
+		return new(big.Int).SetBytes(padded)
+	}
+	start := toInt(startKey)
+	end := new(big.Int).Lsh(big.NewInt(1), uint(width*8))
+	if len(endKey) > 0 {
+		end = toInt(endKey)
+	}
+	step := new(big.Int).Sub(end, start)
+	step.Div(step, big.NewInt(int64(count)))
+	if step.Sign() <= 0 {
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the key range is too narrow to be split")
+	}
+	keys := make([][]byte, 0, count-1)
+	cur := start
+	for i := 1; i < count; i++ {
+		cur = new(big.Int).Add(cur, step)
+		key := make([]byte, width)
+		cur.FillBytes(key)
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// restore loads the imports from the storage, the expired ones are dropped. It
+// should be called before the tasks are restored, so that the running tasks of
+// the imports can be resumed.
+func (m *importModeManager) restore() {
+	records, err := m.cluster.storage.LoadImportModes()
+	if err != nil {
+		log.Warn("failed to load import modes", errs.ZapError(err))
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	for _, record := range records {
+		m.imports[record.ID] = &importMode{
+			id:          record.ID,
+			startKey:    record.StartKey,
+			endKey:      record.EndKey,
+			regionCount: record.RegionCount,
+			tolerance:   record.Tolerance,
+			taskID:      record.TaskID,
+			expireAt:    record.ExpireAt,
+		}
+	}
+	m.gcLocked(time.Now())
+	log.Info("restored import modes", zap.Int("count", len(m.imports)))
+}
+
+func (m *importModeManager) enable(id string, startKey, endKey []byte, regionCount int, tolerance float64, ttl time.Duration) (*ImportModeStatus, error) {
+	switch {
+	case len(id) == 0:
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the id is empty")
+	case strings.Contains(id, "/"):
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the id should not contain '/'")
+	case len(endKey) > 0 && bytes.Compare(startKey, endKey) >= 0:
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the start key should be less than the end key")
+	case regionCount < 1 || regionCount > importModeMaxRegionCount:
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the region count should be in [1, 10000]")
+	case tolerance < 0:
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the tolerance should not be negative")
+	case ttl <= 0:
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the ttl should be positive")
+	}
+	if _, err := splitKeysInRange(startKey, endKey, regionCount); err != nil {
+		return nil, err
+	}
+
+	c := m.cluster
+	rule := &labeler.LabelRule{
+		ID:       importModeRuleID(id),
+		Labels:   []labeler.RegionLabel{{Key: "merge_option", Value: "deny", TTL: ttl.String()}},
+		RuleType: labeler.KeyRange,
+		Data:     []interface{}{map[string]interface{}{"start_key": hex.EncodeToString(startKey), "end_key": hex.EncodeToString(endKey)}},
+	}
+	if err := c.GetRegionLabeler().SetLabelRule(rule); err != nil {
+		return nil, err
+	}
+	im := &importMode{
+		id:          id,
+		startKey:    startKey,
+		endKey:      endKey,
+		regionCount: regionCount,
+		tolerance:   tolerance,
+		expireAt:    time.Now().Add(ttl),
+	}
+	m.Lock()
+	prev := m.imports[id]
+	m.imports[id] = im
+	m.Unlock()
+	if prev != nil {
+		m.cancelTask(prev.taskID)
+	}
+
+	params, err := json.Marshal(&ImportModeTaskParams{ID: id})
+	if err != nil {
+		return nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+	}
+	task, err := c.tasks.submit(TaskTypeImportMode, params)
+	if err == nil {
+		m.Lock()
+		im.taskID = task.ID
+		err = c.storage.SaveImportMode(im.record())
+		m.Unlock()
+		if err != nil {
+			m.cancelTask(task.ID)
+		}
+	}
+	if err != nil {
+		m.Lock()
+		if m.imports[id] == im {
+			delete(m.imports, id)
+		}
+		m.Unlock()
+		return nil, err
+	}
+	log.Info("import mode is enabled",
+		zap.String("id", id),
+		zap.String("start-key", hex.EncodeToString(startKey)),
+		zap.String("end-key", hex.EncodeToString(endKey)),
+		zap.Int("region-count", regionCount),
+		zap.Uint64("task-id", task.ID),
+		zap.Duration("ttl", ttl))
+
+	m.Lock()
+	defer m.Unlock()
+	return m.statusLocked(im), nil
+}
+
+// startTask starts preparing the key range of the import in the background.
+func (m *importModeManager) startTask(id string) (*importModeTaskRunner, error) {
+	m.Lock()
+	defer m.Unlock()
+	im, ok := m.imports[id]
+	if !ok {
+		return nil, errs.ErrImportModeNotFound.FastGenByArgs(id)
+	}
+	r := newImportModeTaskRunner(m.cluster, im)
+	im.runner = r
+	go r.run()
+	return r, nil
+}
+
+// cancelTask cancels the task of an import, it must be called without holding
+// the lock, since the task manager may start the task under its own lock.
+func (m *importModeManager) cancelTask(taskID uint64) {
+	_, err := m.cluster.tasks.cancel(taskID)
+	if err != nil && !errs.ErrTaskState.Equal(err) && !errs.ErrTaskNotFound.Equal(err) {
+		log.Warn("failed to cancel the import mode task", zap.Uint64("task-id", taskID), errs.ZapError(err))
+	}
+}
+
+func (m *importModeManager) disable(id string) error {
+	m.Lock()
+	m.gcLocked(time.Now())
+	im, ok := m.imports[id]
+	if !ok {
+		m.Unlock()
+		return errs.ErrImportModeNotFound.FastGenByArgs(id)
+	}
+	rl := m.cluster.GetRegionLabeler()
+	if rl.GetLabelRule(importModeRuleID(id)) != nil {
+		if err := rl.DeleteLabelRule(importModeRuleID(id)); err != nil {
+			m.Unlock()
+			return err
+		}
+	}
+	if err := m.cluster.storage.DeleteImportMode(id); err != nil {
+		m.Unlock()
+		return err
+	}
+	delete(m.imports, id)
+	m.Unlock()
+
+	m.cancelTask(im.taskID)
+	log.Info("import mode is disabled", zap.String("id", id))
+	return nil
+}
+
+func (m *importModeManager) get(id string) (*ImportModeStatus, error) {
+	m.Lock()
+	defer m.Unlock()
+	m.gcLocked(time.Now())
+	im, ok := m.imports[id]
+	if !ok {
+		return nil, errs.ErrImportModeNotFound.FastGenByArgs(id)
+	}
+	return m.statusLocked(im), nil
+}
+
+func (m *importModeManager) list() []*ImportModeStatus {
+	m.Lock()
+	defer m.Unlock()
+	m.gcLocked(time.Now())
+	statuses := make([]*ImportModeStatus, 0, len(m.imports))
+	for _, im := range m.imports {
+		statuses = append(statuses, m.statusLocked(im))
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].ID < statuses[j].ID })
+	return statuses
+}
+
+// gcLocked drops the expired imports, whose label rules have been expired too.
+// Their tasks are stopped by the runners directly, since the task manager can
+// not be called under the lock.
+func (m *importModeManager) gcLocked(now time.Time) {
+	for id, im := range m.imports {
+		if !now.After(im.expireAt) {
+			continue
+		}
+		if err := m.cluster.storage.DeleteImportMode(id); err != nil {
+			log.Warn("failed to delete the expired import mode", zap.String("id", id), errs.ZapError(err))
+			continue
+		}
+		if im.runner != nil {
+			im.runner.stop()
+		}
+		delete(m.imports, id)
+	}
+}
+
+func (m *importModeManager) statusLocked(im *importMode) *ImportModeStatus {
+	status := &ImportModeStatus{
+		ID:           im.id,
+		StartKey:     hex.EncodeToString(im.startKey),
+		EndKey:       hex.EncodeToString(im.endKey),
+		RegionCount:  im.regionCount,
+		Tolerance:    im.tolerance,
+		TaskID:       im.taskID,
+		LeaderCounts: make(map[uint64]int),
+		ExpireAt:     im.expireAt,
+	}
+	prepared := true
+	if im.runner != nil {
+		prepared = im.runner.fillStatus(status)
+	}
+	// Only the up TiKV stores are able to hold the leaders.
+	for _, store := range m.cluster.GetStores() {
+		if store.IsUp() && !store.IsTiFlash() {
+			status.LeaderCounts[store.GetID()] = 0
+		}
+	}
+	if len(status.LeaderCounts) == 0 {
+		return status
+	}
+	total := 0
+	for _, region := range m.cluster.ScanRegions(im.startKey, im.endKey, -1) {
+		storeID := region.GetLeader().GetStoreId()
+		if _, ok := status.LeaderCounts[storeID]; ok {
+			status.LeaderCounts[storeID]++
+			total++
+		}
+	}
+	if total == 0 {
+		return status
+	}
+	avg := float64(total) / float64(len(status.LeaderCounts))
+	maxDeviation := 0.0
+	for _, count := range status.LeaderCounts {
+		maxDeviation = math.Max(maxDeviation, math.Abs(float64(count)-avg))
+	}
+	status.LeaderSpread = maxDeviation / avg
+	// A deviation of one leader is unavoidable if the leaders can not be
+	// divided evenly.
+	status.Ready = prepared && status.PendingOperators == 0 && (status.LeaderSpread <= im.tolerance || maxDeviation < 1)
+	return status
+}
+
+// ImportModeTaskParams is the params of the import mode task.
+type ImportModeTaskParams struct {
+	ID string `json:"id"`
+}
+
+type importModeTaskAdapter struct{}
+
+func (importModeTaskAdapter) start(c *RaftCluster, params json.RawMessage) (taskRunner, error) {
+	p := &ImportModeTaskParams{}
+	if err := decodeTaskParams(TaskTypeImportMode, params, p); err != nil {
+		return nil, err
+	}
+	r, err := c.imports.startTask(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// resume starts the preparation again if the import is not expired, since
+// splitting at the existing keys does nothing.
+func (a importModeTaskAdapter) resume(c *RaftCluster, params json.RawMessage) taskRunner {
+	r, err := a.start(c, params)
+	if err != nil {
+		return nil
+	}
+	return r
+}
+
+// importModeTaskRunner pre-splits the key range of an import and scatters the
+// regions in the background.
+type importModeTaskRunner struct {
+	cluster          *RaftCluster
+	id               string
+	startKey, endKey []byte
+	regionCount      int
+	ctx              context.Context
+	cancelFunc       context.CancelFunc
+
+	mu              syncutil.Mutex
+	splitRegions    int
+	scatterFailures int
+	ops             []*operator.Operator
+	// scattered is true once the scatter operators are created.
+	scattered bool
+	err       error
+}
+
+func newImportModeTaskRunner(c *RaftCluster, im *importMode) *importModeTaskRunner {
+	r := &importModeTaskRunner{
+		cluster:     c,
+		id:          im.id,
+		startKey:    im.startKey,
+		endKey:      im.endKey,
+		regionCount: im.regionCount,
+	}
+	r.ctx, r.cancelFunc = context.WithCancel(c.ctx)
+	return r
+}
+
+func (r *importModeTaskRunner) run() {
+	defer logutil.LogPanic()
+	c := r.cluster
+	splitKeys, err := splitKeysInRange(r.startKey, r.endKey, r.regionCount)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	if len(splitKeys) > 0 {
+		_, newRegions := c.GetRegionSplitter().SplitRegions(r.ctx, splitKeys, importModeRetryLimit)
+		r.mu.Lock()
+		r.splitRegions = len(newRegions)
+		r.mu.Unlock()
+	}
+	if r.ctx.Err() != nil {
+		return
+	}
+	ops, failures, err := c.GetRegionScatter().ScatterRegionsByRange(r.startKey, r.endKey, r.id, importModeRetryLimit)
+	if err != nil {
+		r.fail(err)
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	// the operators are not added if the runner is stopped during the scatter.
+	if r.ctx.Err() != nil {
+		return
+	}
+	r.scatterFailures = len(failures)
+	for _, op := range ops {
+		op.AttachKind(operator.OpAdmin)
+		if c.GetOperatorController().AddOperator(op) {
+			r.ops = append(r.ops, op)
+		} else {
+			r.scatterFailures++
+		}
+	}
+	r.scattered = true
+	log.Info("import mode key range is prepared",
+		zap.String("id", r.id),
+		zap.Int("split-regions", r.splitRegions),
+		zap.Int("scatter-operators", len(r.ops)),
+		zap.Int("scatter-failures", r.scatterFailures))
+}
+
+func (r *importModeTaskRunner) fail(err error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.err = err
+}
+
+// fillStatus fills the progress of the preparation into the status, and
+// returns whether the scatter operators are created.
+func (r *importModeTaskRunner) fillStatus(status *ImportModeStatus) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	status.SplitRegions = r.splitRegions
+	status.ScatterFailures = r.scatterFailures
+	for _, op := range r.ops {
+		if !op.IsEnd() {
+			status.PendingOperators++
+		}
+	}
+	return r.scattered
+}
+
+func (r *importModeTaskRunner) check() (float64, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.err != nil {
+		return 0, true, r.err
+	}
+	if !r.scattered {
+		if r.ctx.Err() != nil {
+			return 0, true, errors.New("the import mode is stopped")
+		}
+		return 0, false, nil
+	}
+	total := len(r.ops) + r.scatterFailures
+	if total == 0 {
+		return 1, true, nil
+	}
+	ended, failures := 0, r.scatterFailures
+	for _, op := range r.ops {
+		if op.IsEnd() {
+			ended++
+			if !op.CheckSuccess() {
+				failures++
+			}
+		}
+	}
+	progress := float64(ended+r.scatterFailures) / float64(total)
+	if ended < len(r.ops) {
+		return progress, false, nil
+	}
+	if failures > 0 {
+		return progress, true, errors.Errorf("%d of %d regions failed to be scattered", failures, total)
+	}
+	return 1, true, nil
+}
+
+// stop stops the preparation and removes the unfinished scatter operators.
+func (r *importModeTaskRunner) stop() {
+	r.cancelFunc()
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, op := range r.ops {
+		if !op.IsEnd() {
+			r.cluster.GetOperatorController().RemoveOperator(op)
+		}
+	}
+}
+
+func (r *importModeTaskRunner) cancel() error {
+	r.stop()
+	return nil
+}
+
+// EnableImportMode denies merging the regions in the key range before the ttl
+// expires, and submits a task to pre-split the range into the given number of
+// regions and scatter them in the background. It replaces the previous import
+// with the same ID.
+func (c *RaftCluster) EnableImportMode(id string, startKey, endKey []byte, regionCount int, tolerance float64, ttl time.Duration) (*ImportModeStatus, error) {
+	return c.imports.enable(id, startKey, endKey, regionCount, tolerance, ttl)
+}
+
+// DisableImportMode ends the import in advance.
+func (c *RaftCluster) DisableImportMode(id string) error {
+	return c.imports.disable(id)
+}
+
+// GetImportMode returns the status of the import with the given ID.
+func (c *RaftCluster) GetImportMode(id string) (*ImportModeStatus, error) {
+	return c.imports.get(id)
+}
+
+// GetImportModes returns the status of all the imports which are not expired.
+func (c *RaftCluster) GetImportModes() []*ImportModeStatus {
+	return c.imports.list()
+}
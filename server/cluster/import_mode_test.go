@@ -0,0 +1,119 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+)
+
+func TestSplitKeysInRange(t *testing.T) {
+	re := require.New(t)
+	keys, err := splitKeysInRange([]byte("a"), []byte("b"), 4)
+	re.NoError(err)
+	re.Equal([][]byte{[]byte("a\x40\x00"), []byte("a\x80\x00"), []byte("a\xc0\x00")}, keys)
+
+	keys, err = splitKeysInRange(nil, nil, 2)
+	re.NoError(err)
+	re.Equal([][]byte{[]byte("\x80\x00")}, keys)
+
+	keys, err = splitKeysInRange([]byte("a"), []byte("b"), 1)
+	re.NoError(err)
+	re.Empty(keys)
+
+	_, err = splitKeysInRange([]byte("a"), []byte("a\x00"), 2)
+	re.True(errs.ErrImportModeInvalid.Equal(err))
+}
+
+func TestImportMode(t *testing.T) {
+	re := require.New(t)
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	defer cleanup()
+	tc.coordinator = co
+
+	for i := uint64(1); i <= 4; i++ {
+		re.NoError(tc.addRegionStore(i, 0))
+	}
+	re.NoError(tc.addLeaderRegion(1, 1, 2, 3))
+	re.NoError(tc.addLeaderRegion(2, 1, 2, 3))
+	re.NoError(tc.addLeaderRegion(3, 2, 3, 4))
+	re.NoError(tc.addLeaderRegion(4, 3, 4, 1))
+	startKey, endKey := newTestRegionMeta(1).GetStartKey(), newTestRegionMeta(4).GetEndKey()
+
+	for _, regionCount := range []int{0, importModeMaxRegionCount + 1} {
+		_, err := tc.EnableImportMode("lightning", startKey, endKey, regionCount, 0.5, time.Minute)
+		re.True(errs.ErrImportModeInvalid.Equal(err))
+	}
+	_, err := tc.EnableImportMode("lightning", endKey, startKey, 1, 0.5, time.Minute)
+	re.True(errs.ErrImportModeInvalid.Equal(err))
+
+	status, err := tc.EnableImportMode("lightning", startKey, endKey, 1, 0.5, time.Minute)
+	re.NoError(err)
+	re.Equal("lightning", status.ID)
+	re.Zero(status.SplitRegions)
+	re.NotNil(tc.GetRegionLabeler().GetLabelRule(ImportModeRulePrefix + "lightning"))
+	re.Len(tc.GetImportModes(), 1)
+	task, err := tc.GetTask(status.TaskID)
+	re.NoError(err)
+	re.Equal(TaskTypeImportMode, task.Type)
+
+	// The key range is prepared in the background.
+	runner := tc.imports.imports["lightning"].runner
+	re.Eventually(func() bool {
+		runner.mu.Lock()
+		defer runner.mu.Unlock()
+		return runner.scattered
+	}, 5*time.Second, 10*time.Millisecond)
+	// The scatter operators are not checked here, since they are randomly created.
+	runner.mu.Lock()
+	runner.ops = nil
+	runner.mu.Unlock()
+
+	// The import is persisted and restored after the leader changes.
+	records, err := tc.storage.LoadImportModes()
+	re.NoError(err)
+	re.Len(records, 1)
+	re.Equal(status.TaskID, records[0].TaskID)
+	imports := tc.imports
+	tc.imports = newImportModeManager(tc.RaftCluster)
+	tc.imports.restore()
+	re.Len(tc.GetImportModes(), 1)
+	tc.imports = imports
+
+	status, err = tc.GetImportMode("lightning")
+	re.NoError(err)
+	re.Equal(map[uint64]int{1: 2, 2: 1, 3: 1, 4: 0}, status.LeaderCounts)
+	re.Equal(1.0, status.LeaderSpread)
+	re.False(status.Ready)
+
+	re.NoError(tc.addLeaderRegion(2, 4, 2, 3))
+	status, err = tc.GetImportMode("lightning")
+	re.NoError(err)
+	re.Zero(status.LeaderSpread)
+	re.True(status.Ready)
+
+	re.NoError(tc.DisableImportMode("lightning"))
+	re.Nil(tc.GetRegionLabeler().GetLabelRule(ImportModeRulePrefix + "lightning"))
+	re.Empty(tc.GetImportModes())
+	records, err = tc.storage.LoadImportModes()
+	re.NoError(err)
+	re.Empty(records)
+	re.True(errs.ErrImportModeNotFound.Equal(tc.DisableImportMode("lightning")))
+	_, err = tc.GetImportMode("lightning")
+	re.True(errs.ErrImportModeNotFound.Equal(err))
+}
@@ -0,0 +1,209 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule/placement"
+)
+
+// maxInvariantViolations is the max number of violations reported by each
+// check, the rest are only counted.
+const maxInvariantViolations = 100
+
+const (
+	duplicateStorePeersCheck = "duplicate-store-peers"
+	ruleFitCacheCheck        = "rule-fit-cache"
+	storeLimitConfigCheck    = "store-limit-config"
+	storeProgressCheck       = "store-progress"
+)
+
+// InvariantViolation is a violation found by an invariant check.
+type InvariantViolation struct {
+	RegionID uint64 `json:"region_id,omitempty"`
+	StoreID  uint64 `json:"store_id,omitempty"`
+	Detail   string `json:"detail"`
+}
+
+// InvariantCheckResult is the result of an invariant check.
+type InvariantCheckResult struct {
+	Name           string                `json:"name"`
+	Passed         bool                  `json:"passed"`
+	Skipped        string                `json:"skipped,omitempty"`
+	CheckedCount   int                   `json:"checked_count"`
+	ViolationCount int                   `json:"violation_count"`
+	Violations     []*InvariantViolation `json:"violations,omitempty"`
+}
+
+func newInvariantCheckResult(name string) *InvariantCheckResult {
+	return &InvariantCheckResult{Name: name, Passed: true}
+}
+
+func (r *InvariantCheckResult) skip(reason string) *InvariantCheckResult {
+	r.Skipped = reason
+	return r
+}
+
+func (r *InvariantCheckResult) addViolation(regionID, storeID uint64, format string, args ...interface{}) {
+	r.Passed = false
+	r.ViolationCount++
+	if len(r.Violations) < maxInvariantViolations {
+		r.Violations = append(r.Violations, &InvariantViolation{
+			RegionID: regionID,
+			StoreID:  storeID,
+			Detail:   fmt.Sprintf(format, args...),
+		})
+	}
+}
+
+// InvariantReport is the report of the invariant checks against the cluster.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type InvariantReport struct {
+	StartTime time.Time               `json:"start_time"`
+	Duration  typeutil.Duration       `json:"duration"`
+	Passed    bool                    `json:"passed"`
+	Checks    []*InvariantCheckResult `json:"checks"`
+}
+
+// CheckInvariants runs the invariant checks against the current cluster state.
+// The checks only read the state, so it is safe to run them on a live cluster.
+func (c *RaftCluster) CheckInvariants() (*InvariantReport, error) {
+	report := &InvariantReport{StartTime: time.Now(), Passed: true}
+	storeLimitResult, err := c.checkStoreLimitConfig()
+	if err != nil {
+		return nil, err
+	}
+	report.Checks = []*InvariantCheckResult{
+		c.checkDuplicateStorePeers(),
+		c.checkRuleFitCache(),
+		storeLimitResult,
+		c.checkStoreProgress(),
+	}
+	for _, check := range report.Checks {
+		report.Passed = report.Passed && check.Passed
+	}
+	report.Duration = typeutil.NewDuration(time.Since(report.StartTime))
+	return report, nil
+}
+
+// checkDuplicateStorePeers checks no region has more than one peer on a store.
+func (c *RaftCluster) checkDuplicateStorePeers() *InvariantCheckResult {
+	result := newInvariantCheckResult(duplicateStorePeersCheck)
+	for _, region := range c.GetRegions() {
+		result.CheckedCount++
+		stores := make(map[uint64]struct{}, len(region.GetPeers()))
+		for _, peer := range region.GetPeers() {
+			if _, ok := stores[peer.GetStoreId()]; ok {
+				result.addViolation(region.GetID(), peer.GetStoreId(), "region has multiple peers on the store")
+				continue
+			}
+			stores[peer.GetStoreId()] = struct{}{}
+		}
+	}
+	return result
+}
+
+// checkRuleFitCache checks the cached rule fits are the same as the ones
+// calculated from scratch.
+func (c *RaftCluster) checkRuleFitCache() *InvariantCheckResult {
+	result := newInvariantCheckResult(ruleFitCacheCheck)
+	if !c.opt.IsPlacementRulesEnabled() {
+		return result.skip("placement rules are disabled")
+	}
+	if !c.opt.IsPlacementRulesCacheEnabled() {
+		return result.skip("placement rules cache is disabled")
+	}
+	for _, region := range c.GetRegions() {
+		fit := c.ruleManager.FitRegion(c, region)
+		if !fit.IsCached() {
+			continue
+		}
+		result.CheckedCount++
+		expected := c.ruleManager.FitRegionWithoutCache(c, region)
+		if fit.IsSatisfied() != expected.IsSatisfied() || placement.CompareRegionFit(fit, expected) != 0 {
+			result.addViolation(region.GetID(), 0, "cached rule fit (satisfied: %v) differs from the calculated one (satisfied: %v)",
+				fit.IsSatisfied(), expected.IsSatisfied())
+		}
+	}
+	return result
+}
+
+// checkStoreLimitConfig checks the store limits in memory are the same as the
+// persisted ones.
+func (c *RaftCluster) checkStoreLimitConfig() (*InvariantCheckResult, error) {
+	result := newInvariantCheckResult(storeLimitConfigCheck)
+	cfg := &config.Config{}
+	exist, err := c.storage.LoadConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if !exist {
+		return result.skip("config is not persisted"), nil
+	}
+	persisted := cfg.Schedule.StoreLimit
+	current := c.opt.GetAllStoresLimit()
+	for storeID, limit := range current {
+		result.CheckedCount++
+		persistedLimit, ok := persisted[storeID]
+		if !ok {
+			result.addViolation(0, storeID, "store limit is not persisted")
+			continue
+		}
+		if limit != persistedLimit {
+			result.addViolation(0, storeID, "store limit %+v differs from the persisted %+v", limit, persistedLimit)
+		}
+	}
+	for storeID := range persisted {
+		if _, ok := current[storeID]; !ok {
+			result.CheckedCount++
+			result.addViolation(0, storeID, "persisted store limit is not loaded")
+		}
+	}
+	return result, nil
+}
+
+// checkStoreProgress checks the progress of each store matches its state.
+func (c *RaftCluster) checkStoreProgress() *InvariantCheckResult {
+	result := newInvariantCheckResult(storeProgressCheck)
+	progresses := c.progressManager.GetProgresses(func(p string) bool {
+		return strings.HasPrefix(p, removingAction+"-") || strings.HasPrefix(p, preparingAction+"-")
+	})
+	for _, progress := range progresses {
+		result.CheckedCount++
+		idx := strings.LastIndex(progress, "-")
+		action := progress[:idx]
+		storeID, err := strconv.ParseUint(progress[idx+1:], 10, 64)
+		if err != nil {
+			result.addViolation(0, 0, "invalid progress %s", progress)
+			continue
+		}
+		store := c.GetStore(storeID)
+		switch {
+		case store == nil:
+			result.addViolation(0, storeID, "progress %s belongs to a non-existent store", progress)
+		case action == removingAction && !store.IsRemoving():
+			result.addViolation(0, storeID, "store has removing progress but its state is %s", store.GetNodeState())
+		case action == preparingAction && !store.IsPreparing():
+			result.addViolation(0, storeID, "store has preparing progress but its state is %s", store.GetNodeState())
+		}
+	}
+	return result
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestCheckInvariants(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	for _, store := range newTestStores(3, "6.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	for _, region := range newTestRegions(10, 3, 3) {
+		re.NoError(cluster.putRegion(region))
+	}
+
+	checkResult := func(report *InvariantReport, name string) *InvariantCheckResult {
+		for _, check := range report.Checks {
+			if check.Name == name {
+				return check
+			}
+		}
+		re.FailNow("check not found", name)
+		return nil
+	}
+
+	report, err := cluster.CheckInvariants()
+	re.NoError(err)
+	re.True(report.Passed)
+	re.Len(report.Checks, 4)
+	re.Equal(10, checkResult(report, duplicateStorePeersCheck).CheckedCount)
+	re.Equal(3, checkResult(report, storeLimitConfigCheck).CheckedCount)
+	re.NotEmpty(checkResult(report, ruleFitCacheCheck).Skipped)
+
+	// Break the invariants.
+	peers := []*metapb.Peer{{Id: 100, StoreId: 1}, {Id: 101, StoreId: 1}, {Id: 102, StoreId: 2}}
+	region := core.NewRegionInfo(&metapb.Region{
+		Id:          100,
+		Peers:       peers,
+		StartKey:    []byte("a"),
+		EndKey:      []byte("b"),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}, peers[0])
+	re.NoError(cluster.putRegion(region))
+	cluster.progressManager.AddProgress(encodeRemovingProgressKey(2), 0, 10, time.Second)
+	cluster.opt.SetStoreLimit(3, storelimit.AddPeer, 100)
+
+	report, err = cluster.CheckInvariants()
+	re.NoError(err)
+	re.False(report.Passed)
+	check := checkResult(report, duplicateStorePeersCheck)
+	re.False(check.Passed)
+	re.Equal(1, check.ViolationCount)
+	re.Equal(uint64(100), check.Violations[0].RegionID)
+	re.Equal(uint64(1), check.Violations[0].StoreID)
+	check = checkResult(report, storeProgressCheck)
+	re.False(check.Passed)
+	re.Equal(uint64(2), check.Violations[0].StoreID)
+	check = checkResult(report, storeLimitConfigCheck)
+	re.False(check.Passed)
+	re.Equal(uint64(3), check.Violations[0].StoreID)
+}
@@ -0,0 +1,201 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+)
+
+const (
+	// lowSpaceSampleWindow is the time window of the samples used to calculate
+	// the space consumption rate of a store.
+	lowSpaceSampleWindow = 30 * time.Minute
+	// lowSpaceMinSampleSpan is the min time span of the samples to predict.
+	lowSpaceMinSampleSpan = time.Minute
+	// criticalAddPeerLimitRatio is the ratio applied to the add peer limit of a
+	// store which is predicted to be full within the critical ETA.
+	criticalAddPeerLimitRatio = 0.2
+	// lowSpaceRecoverMargin is the margin by which the predicted time must exceed
+	// the threshold of the current level before the store leaves the level, so
+	// that a store near the threshold does not flap between the levels.
+	lowSpaceRecoverMargin = 0.2
+)
+
+type lowSpaceLevel int
+
+const (
+	lowSpaceNone lowSpaceLevel = iota
+	lowSpaceWarning
+	lowSpaceCritical
+)
+
+type spaceSample struct {
+	time      time.Time
+	available uint64
+}
+
+type storeSpaceTrend struct {
+	samples []spaceSample
+	level   lowSpaceLevel
+}
+
+// consumptionRate returns the consumed bytes per second in the sample window.
+// It returns false if the samples are not enough.
+func (t *storeSpaceTrend) consumptionRate() (float64, bool) {
+	if len(t.samples) < 2 {
+		return 0, false
+	}
+	first, last := t.samples[0], t.samples[len(t.samples)-1]
+	span := last.time.Sub(first.time)
+	if span < lowSpaceMinSampleSpan {
+		return 0, false
+	}
+	return (float64(first.available) - float64(last.available)) / span.Seconds(), true
+}
+
+// eta returns the predicted time before the store is full. It returns false if
+// the space is not consumed or the samples are not enough.
+func (t *storeSpaceTrend) eta() (time.Duration, bool) {
+	rate, ok := t.consumptionRate()
+	if !ok || rate <= 0 {
+		return 0, false
+	}
+	available := t.samples[len(t.samples)-1].available
+	return time.Duration(float64(available) / rate * float64(time.Second)), true
+}
+
+// threshold returns the threshold of the predicted time to enter or stay in the
+// given level. The threshold to stay is widened by lowSpaceRecoverMargin.
+func (t *storeSpaceTrend) threshold(level lowSpaceLevel, threshold time.Duration) time.Duration {
+	if t.level >= level {
+		return threshold + time.Duration(float64(threshold)*lowSpaceRecoverMargin)
+	}
+	return threshold
+}
+
+// lowSpaceDetector tracks the space consumption rate of the stores, predicts
+// the time before each store is full, publishes the events when the predicted
+// time drops below the thresholds, and tightens the add peer limit of the
+// nearly full stores.
+type lowSpaceDetector struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	stores  map[uint64]*storeSpaceTrend
+}
+
+func newLowSpaceDetector(cluster *RaftCluster) *lowSpaceDetector {
+	return &lowSpaceDetector{
+		cluster: cluster,
+		stores:  make(map[uint64]*storeSpaceTrend),
+	}
+}
+
+// observe records the available space reported by the store heartbeat, and
+// returns the ratio applied to the add peer limit of the store.
+func (d *lowSpaceDetector) observe(store *core.StoreInfo, now time.Time) float64 {
+	d.Lock()
+	defer d.Unlock()
+	storeID := store.GetID()
+	trend, ok := d.stores[storeID]
+	if !ok {
+		trend = &storeSpaceTrend{}
+		d.stores[storeID] = trend
+	}
+	trend.samples = append(trend.samples, spaceSample{time: now, available: store.GetAvailable()})
+	for len(trend.samples) > 2 && now.Sub(trend.samples[0].time) > lowSpaceSampleWindow {
+		trend.samples = trend.samples[1:]
+	}
+
+	storeLabel := strconv.FormatUint(storeID, 10)
+	eta, ok := trend.eta()
+	if ok {
+		storeSpaceETAGauge.WithLabelValues(storeLabel).Set(eta.Seconds())
+	} else {
+		storeSpaceETAGauge.DeleteLabelValues(storeLabel)
+	}
+	level := lowSpaceNone
+	opt := d.cluster.opt
+	if critical := trend.threshold(lowSpaceCritical, opt.GetLowSpaceETACritical()); ok && critical > 0 && eta < critical {
+		level = lowSpaceCritical
+	} else if warning := trend.threshold(lowSpaceWarning, opt.GetLowSpaceETAWarning()); ok && warning > 0 && eta < warning {
+		level = lowSpaceWarning
+	}
+	if level != trend.level {
+		d.publish(store, trend, level, eta)
+		trend.level = level
+	}
+	if level == lowSpaceCritical {
+		return criticalAddPeerLimitRatio
+	}
+	return 0
+}
+
+func (d *lowSpaceDetector) publish(store *core.StoreInfo, trend *storeSpaceTrend, level lowSpaceLevel, eta time.Duration) {
+	event := &ClusterEvent{
+		StoreID: store.GetID(),
+		Attributes: map[string]string{
+			"address":   store.GetAddress(),
+			"available": strconv.FormatUint(store.GetAvailable(), 10),
+			"capacity":  strconv.FormatUint(store.GetCapacity(), 10),
+		},
+	}
+	if rate, ok := trend.consumptionRate(); ok {
+		event.Attributes["consumption-rate"] = strconv.FormatFloat(rate, 'f', 0, 64)
+	}
+	switch level {
+	case lowSpaceCritical:
+		event.Type = EventStoreLowSpaceCritical
+		event.Message = fmt.Sprintf("store %d is predicted to be full in %s, its add peer limit is tightened", store.GetID(), eta.Round(time.Second))
+		event.Attributes["eta"] = eta.Round(time.Second).String()
+	case lowSpaceWarning:
+		event.Type = EventStoreLowSpaceWarning
+		event.Message = fmt.Sprintf("store %d is predicted to be full in %s", store.GetID(), eta.Round(time.Second))
+		event.Attributes["eta"] = eta.Round(time.Second).String()
+	default:
+		event.Type = EventStoreLowSpaceRecovered
+		event.Message = fmt.Sprintf("store %d is no longer predicted to be full soon", store.GetID())
+	}
+	d.cluster.events.publish(event)
+}
+
+// getETA returns the predicted time before the store is full.
+func (d *lowSpaceDetector) getETA(storeID uint64) (time.Duration, bool) {
+	d.Lock()
+	defer d.Unlock()
+	trend, ok := d.stores[storeID]
+	if !ok {
+		return 0, false
+	}
+	return trend.eta()
+}
+
+// forget removes the samples of the store.
+func (d *lowSpaceDetector) forget(storeID uint64) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.stores, storeID)
+	storeSpaceETAGauge.DeleteLabelValues(strconv.FormatUint(storeID, 10))
+}
+
+// GetStoreSpaceETA returns the predicted time before the store is full. It
+// returns false if the space is not consumed or the samples are not enough.
+func (c *RaftCluster) GetStoreSpaceETA(storeID uint64) (time.Duration, bool) {
+	return c.lowSpace.getETA(storeID)
+}
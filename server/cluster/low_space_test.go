@@ -0,0 +1,89 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestLowSpaceDetector(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	store := newTestStores(1, "6.0.0")[0]
+	observe := func(now time.Time, availableGB uint64) float64 {
+		stats := &pdpb.StoreStats{StoreId: 1, Capacity: 1000 * units.GiB, Available: availableGB * units.GiB}
+		return cluster.lowSpace.observe(store.Clone(core.SetStoreStats(stats)), now)
+	}
+
+	// The default thresholds are 24h for warning and 2h for critical.
+	start := time.Now()
+	re.Zero(observe(start, 100))
+	_, ok := cluster.GetStoreSpaceETA(1)
+	re.False(ok)
+	re.Empty(cluster.GetClusterEvents(0))
+
+	// 1GB per 10 minutes, 99GB is consumed in 16.5 hours.
+	re.Zero(observe(start.Add(10*time.Minute), 99))
+	eta, ok := cluster.GetStoreSpaceETA(1)
+	re.True(ok)
+	re.Equal(16*time.Hour+30*time.Minute, eta.Round(time.Minute))
+	// 10GB per 20 minutes, it is still a warning.
+	re.Zero(observe(start.Add(20*time.Minute), 90))
+	// 30GB per 30 minutes, 70GB is consumed in 70 minutes.
+	re.Equal(criticalAddPeerLimitRatio, observe(start.Add(30*time.Minute), 70))
+	eta, ok = cluster.GetStoreSpaceETA(1)
+	re.True(ok)
+	re.Equal(70*time.Minute, eta.Round(time.Minute))
+	// Some space is released, 19GB per 30 minutes, 80GB is consumed in more than 2
+	// hours. It is still critical until the predicted time exceeds the threshold
+	// by the margin.
+	re.Equal(criticalAddPeerLimitRatio, observe(start.Add(40*time.Minute), 80))
+	eta, ok = cluster.GetStoreSpaceETA(1)
+	re.True(ok)
+	re.Equal(126*time.Minute, eta.Round(time.Minute))
+	// The old samples are dropped, and the space is not consumed any more.
+	re.Zero(observe(start.Add(80*time.Minute), 80))
+	_, ok = cluster.GetStoreSpaceETA(1)
+	re.False(ok)
+
+	events := cluster.GetClusterEvents(0)
+	re.Len(events, 3)
+	re.Equal(EventStoreLowSpaceWarning, events[0].Type)
+	re.Equal(EventStoreLowSpaceCritical, events[1].Type)
+	re.Equal("1h10m0s", events[1].Attributes["eta"])
+	re.Equal(EventStoreLowSpaceRecovered, events[2].Type)
+	for _, event := range events {
+		re.Equal(uint64(1), event.StoreID)
+	}
+	re.Len(cluster.GetClusterEvents(events[0].ID), 2)
+
+	// The store is forgotten after it is buried.
+	cluster.lowSpace.forget(1)
+	re.Empty(cluster.lowSpace.stores)
+}
@@ -14,7 +14,14 @@
 
 package cluster
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikv/pd/pkg/metricutil"
+)
+
+// hotSpotStatusFamily is the name of the metric family of hotSpotStatusGauge,
+// whose cardinality grows with the number of the stores.
+const hotSpotStatusFamily = "pd_hotspot_status"
 
 var (
 	healthStatusGauge = prometheus.NewGaugeVec(
@@ -96,6 +103,14 @@ var (
 			Help:      "The current progress of corresponding action",
 		}, []string{"address", "store", "action"})
 
+	storePreparingGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_preparing",
+			Help:      "The details of the preparing stores to become serving.",
+		}, []string{"address", "store", "type"})
+
 	storesSpeedGauge = prometheus.NewGaugeVec(
 		prometheus.GaugeOpts{
 			Namespace: "pd",
@@ -119,6 +134,183 @@ var (
 			Name:      "store_sync",
 			Help:      "The state of store sync config",
 		}, []string{"address", "state"})
+
+	persistFailureCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "persist_failure",
+			Help:      "Counter of the failures to persist the metadata",
+		}, []string{"type"})
+
+	regionCleanerQueueGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_cleaner_queue",
+			Help:      "The number of overlapped regions waiting to be deleted from the storage",
+		})
+
+	statisticsObserverPendingGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "statistics_observer_pending_tasks",
+			Help:      "The number of the region statistics updates waiting to be applied",
+		})
+
+	statisticsObserverLagGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "statistics_observer_lag_seconds",
+			Help:      "The lag between queueing and applying the region statistics updates",
+		})
+
+	staleRegionRatioGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "stale_region_ratio",
+			Help:      "The ratio of the regions without recent heartbeats",
+		})
+
+	clusterEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "events",
+			Help:      "Counter of the published cluster events",
+		}, []string{"type"})
+
+	clusterEventWebhookCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "event_webhook",
+			Help:      "Counter of the cluster events posted to the webhook",
+		}, []string{"result"})
+
+	storeSpaceETAGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_space_eta_seconds",
+			Help:      "The predicted seconds before the store is full",
+		}, []string{"store"})
+
+	storeSlowTrendGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_slow_trend",
+			Help:      "The slow trend reported by the store",
+		}, []string{"store", "type"})
+
+	topologyChangeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "topology_change",
+			Help:      "Counter of the stores with changed location labels and the regions enqueued to be checked",
+		}, []string{"event"})
+
+	topologyChangePendingGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "topology_change_pending_regions",
+			Help:      "The number of regions waiting to be checked after the location labels of their stores are changed",
+		})
+
+	storeQuotaEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_quota_event",
+			Help:      "Counter of the events of the stores exceeding or returning below their soft quotas",
+		}, []string{"store", "kind", "event"})
+
+	storeVersionSkewedGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "store_version_skewed",
+			Help:      "Whether the version of the store lags behind the newest version of the up stores too much",
+		}, []string{"store"})
+
+	regionCleanerEventCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_cleaner_event",
+			Help:      "Counter of the events of deleting overlapped regions from the storage",
+		}, []string{"event"})
+
+	regionQueryCacheCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_query_cache",
+			Help:      "Counter of the hits, misses, evictions and invalidations of the region query cache",
+		}, []string{"event"})
+
+	regionQueryCacheSizeGauge = prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_query_cache_size_bytes",
+			Help:      "The estimated memory used by the region query cache",
+		})
+
+	schedulerExecutionDuration = prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "execution_duration_seconds",
+			Help:      "Bucketed histogram of the time spent by a scheduler in a tick.",
+			Buckets:   prometheus.ExponentialBuckets(0.001, 2, 16), // 1ms ~ 32s
+		}, []string{"scheduler"})
+
+	schedulerBudgetCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "execution_budget",
+			Help:      "Counter of the ticks of the schedulers exhausting or overrunning their execution budgets.",
+		}, []string{"scheduler", "event"})
+
+	schedulerObservedOperatorCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "scheduler",
+			Name:      "observed_operators",
+			Help:      "Counter of the operators produced by the schedulers in observe-only mode.",
+		}, []string{"scheduler"})
+
+	placementViolationGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "placement_violations",
+			Help:      "The number of the regions violating the placement found by the last scan.",
+		}, []string{"category"})
+
+	regionCacheMutationCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "region_cache_mutations",
+			Help:      "Counter of the region cache mutations by the API.",
+		}, []string{"action", "result"})
+
+	breakGlassActionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "cluster",
+			Name:      "break_glass_actions",
+			Help:      "Counter of the safety checks bypassed in the break-glass mode.",
+		}, []string{"check"})
 )
 
 func init() {
@@ -126,13 +318,37 @@ func init() {
 	prometheus.MustRegister(healthStatusGauge)
 	prometheus.MustRegister(schedulerStatusGauge)
 	prometheus.MustRegister(hotSpotStatusGauge)
+	metricutil.RegisterControlledFamily(hotSpotStatusFamily, hotSpotStatusGauge.Reset)
 	prometheus.MustRegister(patrolCheckRegionsGauge)
 	prometheus.MustRegister(clusterStateCPUGauge)
 	prometheus.MustRegister(clusterStateCurrent)
 	prometheus.MustRegister(regionListGauge)
 	prometheus.MustRegister(bucketEventCounter)
 	prometheus.MustRegister(storesProgressGauge)
+	prometheus.MustRegister(storePreparingGauge)
 	prometheus.MustRegister(storesSpeedGauge)
 	prometheus.MustRegister(storesETAGauge)
 	prometheus.MustRegister(storeSyncConfigEvent)
+	prometheus.MustRegister(persistFailureCounter)
+	prometheus.MustRegister(regionCleanerQueueGauge)
+	prometheus.MustRegister(regionCleanerEventCounter)
+	prometheus.MustRegister(statisticsObserverPendingGauge)
+	prometheus.MustRegister(statisticsObserverLagGauge)
+	prometheus.MustRegister(staleRegionRatioGauge)
+	prometheus.MustRegister(clusterEventCounter)
+	prometheus.MustRegister(clusterEventWebhookCounter)
+	prometheus.MustRegister(storeSpaceETAGauge)
+	prometheus.MustRegister(storeSlowTrendGauge)
+	prometheus.MustRegister(topologyChangeCounter)
+	prometheus.MustRegister(topologyChangePendingGauge)
+	prometheus.MustRegister(storeQuotaEventCounter)
+	prometheus.MustRegister(storeVersionSkewedGauge)
+	prometheus.MustRegister(regionQueryCacheCounter)
+	prometheus.MustRegister(regionQueryCacheSizeGauge)
+	prometheus.MustRegister(schedulerExecutionDuration)
+	prometheus.MustRegister(schedulerBudgetCounter)
+	prometheus.MustRegister(schedulerObservedOperatorCounter)
+	prometheus.MustRegister(regionCacheMutationCounter)
+	prometheus.MustRegister(placementViolationGauge)
+	prometheus.MustRegister(breakGlassActionCounter)
 }
@@ -0,0 +1,184 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/pkg/tsoutil"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+)
+
+// The types of the cluster events about the min resolved ts.
+const (
+	EventMinResolvedTSLagging   = "min-resolved-ts-lagging"
+	EventMinResolvedTSRecovered = "min-resolved-ts-recovered"
+)
+
+// StoreMinResolvedTS is the resolved ts of a store counted in the min resolved ts.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type StoreMinResolvedTS struct {
+	StoreID       uint64 `json:"store_id"`
+	MinResolvedTS uint64 `json:"min_resolved_ts"`
+	// Missing means the store has no leader or is disconnected, but its last
+	// resolved ts is still honored by the "hold" policy.
+	Missing bool `json:"missing,omitempty"`
+	// Lag is how long the resolved ts of the store is behind the median one.
+	Lag typeutil.Duration `json:"lag"`
+}
+
+// MinResolvedTSStatus is the status of the stores counted in the min resolved ts.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type MinResolvedTSStatus struct {
+	MinResolvedTS uint64                `json:"min_resolved_ts"`
+	MinStoreID    uint64                `json:"min_store_id"`
+	Stores        []*StoreMinResolvedTS `json:"stores"`
+}
+
+// minResolvedTSTracker tracks the stores missing from the min resolved ts and the
+// stores lagging behind the others.
+type minResolvedTSTracker struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	// missingSince is the time each store is found missing.
+	missingSince map[uint64]time.Time
+	lagging      map[uint64]struct{}
+}
+
+func newMinResolvedTSTracker(cluster *RaftCluster) *minResolvedTSTracker {
+	return &minResolvedTSTracker{
+		cluster:      cluster,
+		missingSince: make(map[uint64]time.Time),
+		lagging:      make(map[uint64]struct{}),
+	}
+}
+
+// evaluate returns the status of the stores counted in the min resolved ts.
+func (t *minResolvedTSTracker) evaluate(stores []*core.StoreInfo, now time.Time) *MinResolvedTSStatus {
+	t.Lock()
+	defer t.Unlock()
+	return t.evaluateLocked(stores, now)
+}
+
+func (t *minResolvedTSTracker) evaluateLocked(stores []*core.StoreInfo, now time.Time) *MinResolvedTSStatus {
+	opt := t.cluster.opt
+	hold := opt.GetMinResolvedTSMissingStorePolicy() == config.MinResolvedTSMissingStoreHold
+	holdTime := opt.GetMinResolvedTSMissingStoreHoldTime()
+	missingSince := make(map[uint64]time.Time)
+	status := &MinResolvedTSStatus{MinResolvedTS: math.MaxUint64}
+	for _, s := range stores {
+		var missing bool
+		if !hold {
+			if !core.IsAvailableForMinResolvedTS(s) {
+				continue
+			}
+		} else {
+			// The tombstone stores and the TiFlash stores are always skipped.
+			if s.IsRemoved() || s.IsTiFlash() {
+				continue
+			}
+			if missing = s.GetLeaderCount() == 0 || s.IsDisconnected(); missing {
+				since, ok := t.missingSince[s.GetID()]
+				if !ok {
+					since = now
+				}
+				missingSince[s.GetID()] = since
+				// The stores never reporting the resolved ts, e.g. the new ones, are skipped.
+				if s.GetMinResolvedTS() == 0 || (holdTime > 0 && now.Sub(since) > holdTime) {
+					continue
+				}
+			}
+		}
+		status.Stores = append(status.Stores, &StoreMinResolvedTS{
+			StoreID:       s.GetID(),
+			MinResolvedTS: s.GetMinResolvedTS(),
+			Missing:       missing,
+		})
+		if s.GetMinResolvedTS() < status.MinResolvedTS {
+			status.MinResolvedTS = s.GetMinResolvedTS()
+			status.MinStoreID = s.GetID()
+		}
+	}
+	t.missingSince = missingSince
+	if len(status.Stores) == 0 {
+		return status
+	}
+
+	sort.Slice(status.Stores, func(i, j int) bool { return status.Stores[i].MinResolvedTS < status.Stores[j].MinResolvedTS })
+	median, _ := tsoutil.ParseTS(status.Stores[len(status.Stores)/2].MinResolvedTS)
+	for _, s := range status.Stores {
+		if physical, _ := tsoutil.ParseTS(s.MinResolvedTS); physical.Before(median) {
+			s.Lag = typeutil.NewDuration(median.Sub(physical))
+		}
+	}
+	return status
+}
+
+// update evaluates the min resolved ts and publishes the events of the stores
+// starting or stopping lagging behind the others.
+func (t *minResolvedTSTracker) update(stores []*core.StoreInfo, now time.Time) *MinResolvedTSStatus {
+	t.Lock()
+	status := t.evaluateLocked(stores, now)
+	threshold := t.cluster.opt.GetMinResolvedTSLagThreshold()
+	var events []*ClusterEvent
+	lagging := make(map[uint64]struct{})
+	for _, s := range status.Stores {
+		if threshold <= 0 || s.Lag.Duration <= threshold {
+			continue
+		}
+		lagging[s.StoreID] = struct{}{}
+		if _, ok := t.lagging[s.StoreID]; !ok {
+			events = append(events, &ClusterEvent{
+				Type:    EventMinResolvedTSLagging,
+				StoreID: s.StoreID,
+				Message: fmt.Sprintf("the resolved ts of store %d lags behind the cluster by %s", s.StoreID, s.Lag.Round(time.Second)),
+				Attributes: map[string]string{
+					"lag":             s.Lag.Round(time.Second).String(),
+					"min-resolved-ts": strconv.FormatUint(s.MinResolvedTS, 10),
+					"missing":         strconv.FormatBool(s.Missing),
+				},
+			})
+		}
+	}
+	for id := range t.lagging {
+		if _, ok := lagging[id]; !ok {
+			events = append(events, &ClusterEvent{
+				Type:    EventMinResolvedTSRecovered,
+				StoreID: id,
+				Message: fmt.Sprintf("the resolved ts of store %d no longer lags behind the cluster", id),
+			})
+		}
+	}
+	t.lagging = lagging
+	t.Unlock()
+
+	for _, event := range events {
+		t.cluster.events.publish(event)
+	}
+	return status
+}
+
+// GetMinResolvedTSStatus returns the status of the stores counted in the min resolved ts,
+// including the store holding the current minimum.
+func (c *RaftCluster) GetMinResolvedTSStatus() *MinResolvedTSStatus {
+	return c.minResolvedTSTracker.evaluate(c.GetStores(), time.Now())
+}
@@ -0,0 +1,90 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/tsoutil"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestMinResolvedTSTracker(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	now := time.Now()
+	base := now.Add(-time.Hour)
+	ts := func(d time.Duration) uint64 {
+		return tsoutil.ComposeTS(base.Add(d).UnixNano()/int64(time.Millisecond), 0)
+	}
+	stores := newTestStores(3, "6.0.0")
+	stores[0] = stores[0].Clone(core.SetLeaderCount(1), core.SetMinResolvedTS(ts(10*time.Second)), core.SetLastHeartbeatTS(now))
+	stores[1] = stores[1].Clone(core.SetLeaderCount(1), core.SetMinResolvedTS(ts(10*time.Second)), core.SetLastHeartbeatTS(now))
+	// The leaders of store 3 are evicted.
+	stores[2] = stores[2].Clone(core.SetLeaderCount(0), core.SetMinResolvedTS(ts(0)), core.SetLastHeartbeatTS(now))
+
+	// The store without leaders is skipped at once by default.
+	status := cluster.minResolvedTSTracker.update(stores, now)
+	re.Equal(ts(10*time.Second), status.MinResolvedTS)
+	re.Len(status.Stores, 2)
+
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.MinResolvedTSMissingStorePolicy = config.MinResolvedTSMissingStoreHold
+	cfg.MinResolvedTSMissingStoreHoldTime = typeutil.NewDuration(time.Minute)
+	cfg.MinResolvedTSLagThreshold = typeutil.NewDuration(5 * time.Second)
+	opt.SetPDServerConfig(cfg)
+
+	// The last resolved ts of the missing store is honored, and it lags behind the others.
+	status = cluster.minResolvedTSTracker.update(stores, now)
+	re.Equal(ts(0), status.MinResolvedTS)
+	re.Equal(uint64(3), status.MinStoreID)
+	re.Len(status.Stores, 3)
+	re.True(status.Stores[0].Missing)
+	re.Equal(10*time.Second, status.Stores[0].Lag.Duration)
+	events := cluster.GetClusterEvents(0)
+	re.Len(events, 1)
+	re.Equal(EventMinResolvedTSLagging, events[0].Type)
+	re.Equal(uint64(3), events[0].StoreID)
+	// The event is not published again.
+	cluster.minResolvedTSTracker.update(stores, now.Add(30*time.Second))
+	re.Len(cluster.GetClusterEvents(0), 1)
+
+	// The missing store is skipped after the hold time.
+	status = cluster.minResolvedTSTracker.update(stores, now.Add(2*time.Minute))
+	re.Equal(ts(10*time.Second), status.MinResolvedTS)
+	re.Len(status.Stores, 2)
+	events = cluster.GetClusterEvents(0)
+	re.Len(events, 2)
+	re.Equal(EventMinResolvedTSRecovered, events[1].Type)
+
+	// The hold time restarts once the store is back.
+	stores[2] = stores[2].Clone(core.SetLeaderCount(1))
+	cluster.minResolvedTSTracker.update(stores, now.Add(3*time.Minute))
+	stores[2] = stores[2].Clone(core.SetLeaderCount(0))
+	status = cluster.minResolvedTSTracker.update(stores, now.Add(4*time.Minute))
+	re.Equal(uint64(3), status.MinStoreID)
+}
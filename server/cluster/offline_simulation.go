@@ -0,0 +1,348 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/errors"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule/placement"
+)
+
+// OfflinePlanStep is a step of a planned sequence of store offlines.
+type OfflinePlanStep struct {
+	StoreID uint64 `json:"store_id"`
+	// After is the time since the start of the plan when the store is set offline.
+	After typeutil.Duration `json:"after"`
+}
+
+// OfflineSimulationStep is the simulated state of the cluster when a store of
+// the plan is set offline.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type OfflineSimulationStep struct {
+	StoreID uint64            `json:"store_id"`
+	StartAt typeutil.Duration `json:"start_at"`
+	// FinishAt is the estimated time when all regions are moved off the store.
+	FinishAt typeutil.Duration `json:"finish_at"`
+	// Blocked means the store cannot be drained since no serving store can
+	// receive its regions.
+	Blocked     bool  `json:"blocked,omitempty"`
+	RegionCount int   `json:"region_count"`
+	RegionSize  int64 `json:"region_size"`
+	// DrainingStores are the stores of the plan still being drained when the
+	// step starts, including the store of the step.
+	DrainingStores []uint64 `json:"draining_stores"`
+	// Violations are the rules or replication config which cannot be satisfied
+	// by the remaining serving stores.
+	Violations []string `json:"violations,omitempty"`
+	// QuorumRiskRegions is the number of the regions which have at least half of
+	// their voters on the draining stores, they lose quorum if these stores fail.
+	QuorumRiskRegions int `json:"quorum_risk_regions"`
+	// CapacityShortfall is the size in MB which cannot be held by the remaining
+	// serving stores under the high space ratio.
+	CapacityShortfall int64 `json:"capacity_shortfall,omitempty"`
+}
+
+// OfflineSimulation is the result of simulating a planned sequence of store offlines.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type OfflineSimulation struct {
+	Steps []*OfflineSimulationStep `json:"steps"`
+	// FirstViolationStep is the index of the first step with violations, quorum
+	// risks or capacity shortfall, -1 if the plan is safe.
+	FirstViolationStep int               `json:"first_violation_step"`
+	Duration           typeutil.Duration `json:"duration"`
+}
+
+// SimulateStoreOffline simulates setting the stores offline in the planned
+// sequence against the placement rules and the capacity of the remaining
+// stores, nothing is changed. The stores are drained concurrently if their
+// steps overlap, sharing the add-peer limits of the remaining serving stores.
+func (c *RaftCluster) SimulateStoreOffline(plan []*OfflinePlanStep) (*OfflineSimulation, error) {
+	if len(plan) == 0 {
+		return nil, errors.New("the offline plan is empty")
+	}
+	planned := make(map[uint64]struct{}, len(plan))
+	for i, step := range plan {
+		store := c.GetStore(step.StoreID)
+		if store == nil {
+			return nil, errs.ErrStoreNotFound.FastGenByArgs(step.StoreID)
+		}
+		if !store.IsUp() {
+			return nil, errors.Errorf("store %d is not up", step.StoreID)
+		}
+		if _, ok := planned[step.StoreID]; ok {
+			return nil, errors.Errorf("store %d is planned more than once", step.StoreID)
+		}
+		planned[step.StoreID] = struct{}{}
+		if i > 0 && step.After.Duration < plan[i-1].After.Duration {
+			return nil, errors.Errorf("the steps should be ordered by time, step %d is earlier than step %d", i, i-1)
+		}
+	}
+
+	sim := &offlineSimulator{cluster: c, plan: plan}
+	sim.estimateFinishTimes()
+	result := &OfflineSimulation{FirstViolationStep: -1}
+	for i := range plan {
+		step := sim.evaluate(i)
+		if result.FirstViolationStep < 0 && (step.Blocked || len(step.Violations) > 0 || step.QuorumRiskRegions > 0 || step.CapacityShortfall > 0) {
+			result.FirstViolationStep = i
+		}
+		if step.FinishAt.Duration > result.Duration.Duration {
+			result.Duration = step.FinishAt
+		}
+		result.Steps = append(result.Steps, step)
+	}
+	return result, nil
+}
+
+// neverFinish is the finish time of the stores which cannot be drained.
+const neverFinish = time.Duration(math.MaxInt64)
+
+type offlineSimulator struct {
+	cluster  *RaftCluster
+	plan     []*OfflinePlanStep
+	finishAt []time.Duration
+}
+
+// estimateFinishTimes estimates when each store is drained. The stores being
+// drained at the same time share the add-peer limits of the serving stores.
+func (s *offlineSimulator) estimateFinishTimes() {
+	n := len(s.plan)
+	remaining := make([]float64, n)
+	for i, step := range s.plan {
+		remaining[i] = float64(s.cluster.GetStoreRegionCount(step.StoreID))
+	}
+	s.finishAt = make([]time.Duration, n)
+	finished := make([]bool, n)
+	now := time.Duration(0)
+	for next := 0; ; {
+		var active []int
+		for i := 0; i < next; i++ {
+			if !finished[i] {
+				active = append(active, i)
+			}
+		}
+		if len(active) == 0 {
+			if next == n {
+				return
+			}
+			now = s.plan[next].After.Duration
+			next++
+			continue
+		}
+		// the rate is in regions per second for each draining store.
+		rate := s.addPeerRate(next) / float64(len(active))
+		// the earliest time when a draining store is emptied.
+		first, wait := -1, neverFinish
+		for _, i := range active {
+			d := drainDuration(remaining[i], rate)
+			if d > neverFinish-now {
+				d = neverFinish
+			}
+			if d < wait {
+				first, wait = i, d
+			}
+		}
+		if next < n && (first < 0 || now+wait > s.plan[next].After.Duration) {
+			elapsed := s.plan[next].After.Duration - now
+			for _, i := range active {
+				remaining[i] -= rate * elapsed.Seconds()
+			}
+			now = s.plan[next].After.Duration
+			next++
+			continue
+		}
+		if first < 0 {
+			// no store can receive the regions, the remaining stores never finish.
+			for _, i := range active {
+				finished[i] = true
+				s.finishAt[i] = neverFinish
+			}
+			continue
+		}
+		for _, i := range active {
+			remaining[i] -= rate * wait.Seconds()
+		}
+		now += wait
+		finished[first] = true
+		s.finishAt[first] = now
+	}
+}
+
+// drainDuration returns the time to drain the regions at the rate in regions
+// per second, neverFinish if the time cannot be represented by time.Duration.
+func drainDuration(regions, rate float64) time.Duration {
+	if regions <= 0 {
+		return 0
+	}
+	if rate <= 0 {
+		return neverFinish
+	}
+	nanos := regions / rate * float64(time.Second)
+	// float64(neverFinish) is rounded up to 2^63, which overflows time.Duration.
+	if nanos >= float64(neverFinish) {
+		return neverFinish
+	}
+	return time.Duration(nanos)
+}
+
+// addPeerRate returns the regions per second which can be added to the serving
+// stores when the first started steps of the plan are started.
+func (s *offlineSimulator) addPeerRate(started int) float64 {
+	var rate float64
+	for _, storeID := range s.servingStores(started) {
+		rate += s.cluster.GetStoreLimitByType(storeID, storelimit.AddPeer) / 60
+	}
+	return rate
+}
+
+// servingStores returns the up stores which are not in the first started steps of the plan.
+func (s *offlineSimulator) servingStores(started int) []uint64 {
+	offline := make(map[uint64]struct{}, started)
+	for i := 0; i < started; i++ {
+		offline[s.plan[i].StoreID] = struct{}{}
+	}
+	var stores []uint64
+	for _, store := range s.cluster.GetStores() {
+		if _, ok := offline[store.GetID()]; !ok && store.IsUp() {
+			stores = append(stores, store.GetID())
+		}
+	}
+	sort.Slice(stores, func(i, j int) bool { return stores[i] < stores[j] })
+	return stores
+}
+
+// evaluate returns the state of the cluster when the i-th step starts.
+func (s *offlineSimulator) evaluate(i int) *OfflineSimulationStep {
+	step := s.plan[i]
+	result := &OfflineSimulationStep{
+		StoreID:     step.StoreID,
+		StartAt:     step.After,
+		FinishAt:    typeutil.NewDuration(s.finishAt[i]),
+		RegionCount: s.cluster.GetStoreRegionCount(step.StoreID),
+		RegionSize:  s.cluster.core.GetStoreRegionSize(step.StoreID),
+		Blocked:     s.finishAt[i] == neverFinish,
+	}
+	if result.Blocked {
+		result.FinishAt = typeutil.Duration{}
+	}
+	draining := make(map[uint64]struct{})
+	for j := 0; j <= i; j++ {
+		if j == i || s.finishAt[j] > step.After.Duration || s.finishAt[j] == neverFinish {
+			draining[s.plan[j].StoreID] = struct{}{}
+			result.DrainingStores = append(result.DrainingStores, s.plan[j].StoreID)
+		}
+	}
+	serving := make([]*core.StoreInfo, 0)
+	for _, storeID := range s.servingStores(i + 1) {
+		serving = append(serving, s.cluster.GetStore(storeID))
+	}
+	result.Violations = s.checkPlacement(serving)
+	result.QuorumRiskRegions = s.countQuorumRiskRegions(draining)
+	result.CapacityShortfall = s.capacityShortfall(serving)
+	return result
+}
+
+// checkPlacement returns the rules or the replication config which cannot be
+// satisfied by the serving stores.
+func (s *offlineSimulator) checkPlacement(serving []*core.StoreInfo) []string {
+	opt := s.cluster.GetOpts()
+	var violations []string
+	if !opt.IsPlacementRulesEnabled() {
+		if count := len(serving); count < opt.GetMaxReplicas() {
+			violations = append(violations, fmt.Sprintf("max-replicas needs %d stores, only %d left", opt.GetMaxReplicas(), count))
+		}
+		if level := opt.GetIsolationLevel(); level != "" {
+			if count := countDistinctLabelValues(serving, level); count < opt.GetMaxReplicas() {
+				violations = append(violations, fmt.Sprintf("isolation-level needs %d different %s, only %d left", opt.GetMaxReplicas(), level, count))
+			}
+		}
+		return violations
+	}
+	for _, rule := range s.cluster.GetRuleManager().GetAllRules() {
+		candidates := make([]*core.StoreInfo, 0, len(serving))
+		for _, store := range serving {
+			if placement.MatchLabelConstraints(store, rule.LabelConstraints) {
+				candidates = append(candidates, store)
+			}
+		}
+		if len(candidates) < rule.Count {
+			violations = append(violations, fmt.Sprintf("rule %s/%s needs %d stores, only %d left", rule.GroupID, rule.ID, rule.Count, len(candidates)))
+			continue
+		}
+		if rule.IsolationLevel != "" {
+			if count := countDistinctLabelValues(candidates, rule.IsolationLevel); count < rule.Count {
+				violations = append(violations, fmt.Sprintf("rule %s/%s needs %d different %s, only %d left", rule.GroupID, rule.ID, rule.Count, rule.IsolationLevel, count))
+			}
+		}
+	}
+	return violations
+}
+
+func countDistinctLabelValues(stores []*core.StoreInfo, label string) int {
+	values := make(map[string]struct{})
+	for _, store := range stores {
+		if value := store.GetLabelValue(label); value != "" {
+			values[value] = struct{}{}
+		}
+	}
+	return len(values)
+}
+
+// countQuorumRiskRegions returns the number of the regions which have at least
+// half of their voters on the draining stores.
+func (s *offlineSimulator) countQuorumRiskRegions(draining map[uint64]struct{}) int {
+	var count int
+	for _, region := range s.cluster.GetRegions() {
+		voters := region.GetVoters()
+		var onDraining int
+		for _, voter := range voters {
+			if _, ok := draining[voter.GetStoreId()]; ok {
+				onDraining++
+			}
+		}
+		if onDraining > 0 && onDraining*2 >= len(voters) {
+			count++
+		}
+	}
+	return count
+}
+
+// capacityShortfall returns the size in MB which exceeds the capacity of the
+// serving stores under the high space ratio after the stores are drained.
+func (s *offlineSimulator) capacityShortfall(serving []*core.StoreInfo) int64 {
+	var used, capacity float64
+	for _, store := range s.cluster.GetStores() {
+		if !store.IsRemoved() {
+			used += float64(store.GetUsedSize())
+		}
+	}
+	for _, store := range serving {
+		capacity += float64(store.GetCapacity())
+	}
+	shortfall := used - capacity*s.cluster.GetOpts().GetHighSpaceRatio()
+	if shortfall <= 0 {
+		return 0
+	}
+	return int64(shortfall / units.MiB)
+}
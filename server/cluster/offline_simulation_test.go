@@ -0,0 +1,118 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestSimulateStoreOffline(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	for _, store := range newTestStores(4, "6.0.0") {
+		stats := &pdpb.StoreStats{Capacity: 100 * units.GiB, UsedSize: 40 * units.GiB, Available: 60 * units.GiB}
+		cluster.core.PutStore(store.Clone(core.SetStoreStats(stats)))
+	}
+	// Each region has 3 voters and misses one of the 4 stores.
+	for i := uint64(0); i < 8; i++ {
+		peers := make([]*metapb.Peer, 0, 3)
+		for j := uint64(0); j < 3; j++ {
+			peers = append(peers, &metapb.Peer{Id: 100 + i*3 + j, StoreId: (i+j)%4 + 1})
+		}
+		region := &metapb.Region{Id: i + 1, Peers: peers, StartKey: []byte{byte(i)}, EndKey: []byte{byte(i + 1)}}
+		re.NoError(cluster.putRegion(core.NewRegionInfo(region, peers[0])))
+	}
+	plan := func(steps ...*OfflinePlanStep) []*OfflinePlanStep { return steps }
+
+	// The second store is offline after the first one is drained, and there are
+	// not enough stores for the default rule.
+	result, err := cluster.SimulateStoreOffline(plan(
+		&OfflinePlanStep{StoreID: 1},
+		&OfflinePlanStep{StoreID: 2, After: typeutil.NewDuration(time.Minute)},
+	))
+	re.NoError(err)
+	re.Len(result.Steps, 2)
+	re.Equal(1, result.FirstViolationStep)
+	first, second := result.Steps[0], result.Steps[1]
+	re.Equal(6, first.RegionCount)
+	re.Equal([]uint64{1}, first.DrainingStores)
+	re.Empty(first.Violations)
+	re.Zero(first.QuorumRiskRegions)
+	re.Zero(first.CapacityShortfall)
+	// 6 regions are added to 3 stores with 15 regions per minute each.
+	re.Equal(8*time.Second, first.FinishAt.Duration)
+	re.Equal([]uint64{2}, second.DrainingStores)
+	re.Len(second.Violations, 1)
+	re.Zero(second.QuorumRiskRegions)
+	re.Greater(second.CapacityShortfall, int64(0))
+
+	// The stores are drained at the same time and some regions lose quorum if
+	// they fail.
+	result, err = cluster.SimulateStoreOffline(plan(
+		&OfflinePlanStep{StoreID: 3},
+		&OfflinePlanStep{StoreID: 4},
+	))
+	re.NoError(err)
+	re.Equal(1, result.FirstViolationStep)
+	second = result.Steps[1]
+	re.Equal([]uint64{3, 4}, second.DrainingStores)
+	re.Equal(4, second.QuorumRiskRegions)
+	re.Equal(24*time.Second, result.Duration.Duration)
+	// 160GiB cannot be held by 2 stores under the high space ratio.
+	re.Equal(int64((160-200*opt.GetHighSpaceRatio())*units.GiB/units.MiB), second.CapacityShortfall)
+
+	// The store is blocked instead of finishing at an overflowed time if the
+	// add-peer limits are too small.
+	for _, store := range cluster.GetStores() {
+		opt.SetStoreLimit(store.GetID(), storelimit.AddPeer, 1e-300)
+	}
+	result, err = cluster.SimulateStoreOffline(plan(&OfflinePlanStep{StoreID: 1}))
+	re.NoError(err)
+	re.True(result.Steps[0].Blocked)
+	re.Zero(result.Duration.Duration)
+	re.Equal(neverFinish, drainDuration(6, 1e-300))
+	re.Equal(neverFinish, drainDuration(1e300, 1))
+	re.Equal(2*time.Second, drainDuration(6, 3))
+
+	// Invalid plans.
+	_, err = cluster.SimulateStoreOffline(nil)
+	re.Error(err)
+	_, err = cluster.SimulateStoreOffline(plan(&OfflinePlanStep{StoreID: 5}))
+	re.Error(err)
+	_, err = cluster.SimulateStoreOffline(plan(&OfflinePlanStep{StoreID: 1}, &OfflinePlanStep{StoreID: 1}))
+	re.Error(err)
+	_, err = cluster.SimulateStoreOffline(plan(
+		&OfflinePlanStep{StoreID: 1, After: typeutil.NewDuration(time.Minute)},
+		&OfflinePlanStep{StoreID: 2},
+	))
+	re.Error(err)
+}
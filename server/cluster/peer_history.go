@@ -0,0 +1,157 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/tikv/pd/pkg/cache"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+const (
+	// maxPeerHistoryRegions is the max number of the regions whose peer history
+	// is kept, the history of the least recently changed regions is evicted.
+	maxPeerHistoryRegions = 65536
+	// maxPeerHistoryLength is the max number of the changes kept for a region.
+	maxPeerHistoryLength = 32
+)
+
+// The actions of the peer changes.
+const (
+	PeerChangeAdd     = "add"
+	PeerChangeRemove  = "remove"
+	PeerChangePromote = "promote"
+	PeerChangeDemote  = "demote"
+)
+
+// PeerChange is a change of the membership or the role of a peer.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type PeerChange struct {
+	Time     time.Time `json:"time"`
+	Action   string    `json:"action"`
+	PeerID   uint64    `json:"peer_id"`
+	StoreID  uint64    `json:"store_id"`
+	FromRole string    `json:"from_role,omitempty"`
+	ToRole   string    `json:"to_role,omitempty"`
+	// ConfVer is the conf version of the region after the change.
+	ConfVer uint64 `json:"conf_ver"`
+	// OperatorID and OperatorDesc identify the operator running when the change
+	// is reported. They are empty if the change is not made by PD, e.g. by BR or
+	// the unsafe recovery of TiKV.
+	OperatorID   uint64 `json:"operator_id,omitempty"`
+	OperatorDesc string `json:"operator_desc,omitempty"`
+}
+
+// peerHistory keeps the recent peer changes of the regions, so that the users
+// can find out why a region has its current peers without searching the logs.
+type peerHistory struct {
+	syncutil.Mutex
+	regions cache.Cache
+}
+
+func newPeerHistory() *peerHistory {
+	return &peerHistory{regions: cache.NewCache(maxPeerHistoryRegions, cache.LRUCache)}
+}
+
+// diffPeers returns the changes of the peers from the origin region to the new one.
+func diffPeers(origin, region *core.RegionInfo) []*PeerChange {
+	var changes []*PeerChange
+	for _, peer := range region.GetPeers() {
+		old := origin.GetPeer(peer.GetId())
+		switch {
+		case old == nil:
+			changes = append(changes, &PeerChange{
+				Action:  PeerChangeAdd,
+				PeerID:  peer.GetId(),
+				StoreID: peer.GetStoreId(),
+				ToRole:  peer.GetRole().String(),
+			})
+		case old.GetRole() != peer.GetRole():
+			action := PeerChangeDemote
+			if peer.GetRole() == metapb.PeerRole_Voter || peer.GetRole() == metapb.PeerRole_IncomingVoter {
+				action = PeerChangePromote
+			}
+			changes = append(changes, &PeerChange{
+				Action:   action,
+				PeerID:   peer.GetId(),
+				StoreID:  peer.GetStoreId(),
+				FromRole: old.GetRole().String(),
+				ToRole:   peer.GetRole().String(),
+			})
+		}
+	}
+	for _, old := range origin.GetPeers() {
+		if region.GetPeer(old.GetId()) == nil {
+			changes = append(changes, &PeerChange{
+				Action:   PeerChangeRemove,
+				PeerID:   old.GetId(),
+				StoreID:  old.GetStoreId(),
+				FromRole: old.GetRole().String(),
+			})
+		}
+	}
+	return changes
+}
+
+// observe records the changes of the peers reported by the heartbeat. The
+// operator is the one running on the region, which may be nil.
+func (h *peerHistory) observe(origin, region *core.RegionInfo, op *operator.Operator, now time.Time) {
+	changes := diffPeers(origin, region)
+	if len(changes) == 0 {
+		return
+	}
+	for _, change := range changes {
+		change.Time = now
+		change.ConfVer = region.GetRegionEpoch().GetConfVer()
+		if op != nil {
+			change.OperatorID = op.ID()
+			change.OperatorDesc = op.Desc()
+		}
+	}
+	h.Lock()
+	defer h.Unlock()
+	var history []*PeerChange
+	if v, ok := h.regions.Get(region.GetID()); ok {
+		history = v.([]*PeerChange)
+	}
+	// Always allocate a new slice since the old one may be read by others.
+	merged := make([]*PeerChange, 0, len(history)+len(changes))
+	merged = append(merged, history...)
+	merged = append(merged, changes...)
+	if len(merged) > maxPeerHistoryLength {
+		merged = merged[len(merged)-maxPeerHistoryLength:]
+	}
+	h.regions.Put(region.GetID(), merged)
+}
+
+// get returns the peer changes of the region from the oldest to the newest.
+func (h *peerHistory) get(regionID uint64) []*PeerChange {
+	h.Lock()
+	defer h.Unlock()
+	if v, ok := h.regions.Peek(regionID); ok {
+		return v.([]*PeerChange)
+	}
+	return nil
+}
+
+// GetRegionPeerHistory returns the recent changes of the membership and the
+// roles of the peers of the region, from the oldest to the newest.
+func (c *RaftCluster) GetRegionPeerHistory(regionID uint64) []*PeerChange {
+	return c.peerHistory.get(regionID)
+}
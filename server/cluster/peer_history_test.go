@@ -0,0 +1,94 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestPeerHistory(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+
+	newRegion := func(confVer uint64, peers ...*metapb.Peer) *core.RegionInfo {
+		meta := newTestRegionMeta(1)
+		meta.RegionEpoch.ConfVer = confVer
+		meta.Peers = peers
+		return core.NewRegionInfo(meta, peers[0])
+	}
+	re.NoError(cluster.processRegionHeartbeat(newRegion(1,
+		&metapb.Peer{Id: 1, StoreId: 1},
+		&metapb.Peer{Id: 2, StoreId: 2},
+		&metapb.Peer{Id: 3, StoreId: 3, Role: metapb.PeerRole_Learner},
+	)))
+	re.Empty(cluster.GetRegionPeerHistory(1))
+
+	// The changes are not made by any operator.
+	re.NoError(cluster.processRegionHeartbeat(newRegion(3,
+		&metapb.Peer{Id: 1, StoreId: 1},
+		&metapb.Peer{Id: 3, StoreId: 3},
+		&metapb.Peer{Id: 4, StoreId: 4, Role: metapb.PeerRole_Learner},
+	)))
+	history := cluster.GetRegionPeerHistory(1)
+	re.Len(history, 3)
+	re.Equal(PeerChangePromote, history[0].Action)
+	re.Equal(uint64(3), history[0].PeerID)
+	re.Equal("Learner", history[0].FromRole)
+	re.Equal("Voter", history[0].ToRole)
+	re.Equal(PeerChangeAdd, history[1].Action)
+	re.Equal(uint64(4), history[1].StoreID)
+	re.Equal(PeerChangeRemove, history[2].Action)
+	re.Equal(uint64(2), history[2].PeerID)
+	for _, change := range history {
+		re.Equal(uint64(3), change.ConfVer)
+		re.Zero(change.OperatorID)
+	}
+
+	// The change made by the operator records its ID.
+	op := operator.NewTestOperator(1, nil, operator.OpRegion)
+	cluster.peerHistory.observe(cluster.GetRegion(1), newRegion(4,
+		&metapb.Peer{Id: 1, StoreId: 1},
+		&metapb.Peer{Id: 3, StoreId: 3, Role: metapb.PeerRole_DemotingVoter},
+		&metapb.Peer{Id: 4, StoreId: 4, Role: metapb.PeerRole_IncomingVoter},
+	), op, time.Now())
+	history = cluster.GetRegionPeerHistory(1)
+	re.Len(history, 5)
+	re.Equal(PeerChangeDemote, history[3].Action)
+	re.Equal(PeerChangePromote, history[4].Action)
+	re.Equal(op.ID(), history[4].OperatorID)
+	re.Equal(op.Desc(), history[4].OperatorDesc)
+
+	// The history is bounded.
+	origin := cluster.GetRegion(1)
+	for i := 0; i < maxPeerHistoryLength; i++ {
+		cluster.peerHistory.observe(origin, newRegion(5, &metapb.Peer{Id: 1, StoreId: 1}), nil, time.Now())
+	}
+	re.Len(cluster.GetRegionPeerHistory(1), maxPeerHistoryLength)
+}
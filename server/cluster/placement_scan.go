@@ -0,0 +1,325 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"go.uber.org/zap"
+)
+
+const (
+	placementScanCheckInterval = time.Minute
+	placementScanBatchSize     = 1024
+	// maxPlacementScanReports is the number of the reports kept for the trend analysis.
+	maxPlacementScanReports = 48
+	// maxPlacementViolations is the max number of the violating regions listed in a report.
+	maxPlacementViolations = 1000
+)
+
+// The categories of the placement violations.
+const (
+	// PlacementMissPeer means the region has fewer peers than the rules require.
+	PlacementMissPeer = "miss-peer"
+	// PlacementExtraPeer means the region has peers not required by any rule.
+	PlacementExtraPeer = "extra-peer"
+	// PlacementRoleMismatch means some peers have different roles from the rules.
+	PlacementRoleMismatch = "role-mismatch"
+	// PlacementDownPeer means some peers are down.
+	PlacementDownPeer = "down-peer"
+	// PlacementOfflinePeer means some peers are on the removing stores.
+	PlacementOfflinePeer = "offline-peer"
+)
+
+// PlacementViolation is a region violating the placement.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type PlacementViolation struct {
+	RegionID   uint64   `json:"region_id"`
+	Categories []string `json:"categories"`
+}
+
+// PlacementScanReport is the result of a full scan of the regions violating the
+// placement, with the estimated cost to repair them under the current limits.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type PlacementScanReport struct {
+	StartTime             time.Time `json:"start_time"`
+	EndTime               time.Time `json:"end_time"`
+	PlacementRulesEnabled bool      `json:"placement_rules_enabled"`
+	ScannedRegions        int       `json:"scanned_regions"`
+	ViolatingRegions      int       `json:"violating_regions"`
+	// Categories is the number of the violating regions of each category, a
+	// region may violate the placement in several ways.
+	Categories map[string]int        `json:"categories"`
+	Regions    []*PlacementViolation `json:"regions,omitempty"`
+	// Truncated is true if not all the violating regions are listed.
+	Truncated bool `json:"truncated"`
+	// EstimatedOperators is the number of the operators to repair the regions.
+	EstimatedOperators int `json:"estimated_operators"`
+	// EstimatedRepairTime is a lower bound of the time to repair the regions. It
+	// assumes the up stores run at their store limits in parallel, and does not
+	// consider the schedule limits.
+	EstimatedRepairTime typeutil.Duration `json:"estimated_repair_time"`
+}
+
+// summary returns the report without the listed regions.
+func (r *PlacementScanReport) summary() *PlacementScanReport {
+	summary := *r
+	summary.Regions = nil
+	return &summary
+}
+
+// placementRepair is the repair needed by a region, in the peers to add, remove
+// and change roles.
+type placementRepair struct {
+	categories                []string
+	operators                 int
+	addPeers, removePeers     int
+	roleChanges, replacements int
+}
+
+// placementScanner scans all the regions periodically at a limited rate to find
+// the ones violating the placement, and persists the reports of the scans.
+type placementScanner struct {
+	syncutil.RWMutex
+	cluster  *RaftCluster
+	lastScan time.Time
+	// reports are the recent reports in the order of time.
+	reports []*PlacementScanReport
+}
+
+func newPlacementScanner(cluster *RaftCluster) *placementScanner {
+	return &placementScanner{cluster: cluster}
+}
+
+// restore loads the persisted reports, so that the trend is kept across the
+// leader changes.
+func (s *placementScanner) restore() {
+	var reports []*PlacementScanReport
+	err := s.cluster.storage.LoadPlacementScanReports(func(k, v string) {
+		report := &PlacementScanReport{}
+		if err := json.Unmarshal([]byte(v), report); err != nil {
+			log.Warn("failed to unmarshal placement scan report", zap.String("key", k), errs.ZapError(errs.ErrJSONUnmarshal, err))
+			return
+		}
+		reports = append(reports, report)
+	})
+	if err != nil {
+		log.Warn("failed to load placement scan reports", errs.ZapError(err))
+		return
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.reports = reports
+	if len(reports) > 0 {
+		s.lastScan = reports[len(reports)-1].StartTime
+	}
+}
+
+func (s *placementScanner) run(ctx context.Context) {
+	ticker := time.NewTicker(placementScanCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			interval := s.cluster.opt.GetPlacementScanInterval()
+			s.RLock()
+			due := interval > 0 && now.Sub(s.lastScan) >= interval
+			s.RUnlock()
+			if due {
+				if report := s.scan(ctx, now); report != nil {
+					s.save(report)
+				}
+			}
+		}
+	}
+}
+
+// scan checks all the regions batch by batch, and waits between the batches to
+// keep the rate. It returns nil if the context is done before the scan finishes.
+func (s *placementScanner) scan(ctx context.Context, now time.Time) *PlacementScanReport {
+	report := &PlacementScanReport{
+		StartTime:             now,
+		PlacementRulesEnabled: s.cluster.opt.IsPlacementRulesEnabled(),
+		Categories:            make(map[string]int),
+	}
+	var addPeers, removePeers int
+	var startKey []byte
+	for {
+		regions := s.cluster.ScanRegions(startKey, nil, placementScanBatchSize)
+		for _, region := range regions {
+			report.ScannedRegions++
+			repair := s.check(region)
+			if len(repair.categories) == 0 {
+				continue
+			}
+			report.ViolatingRegions++
+			for _, category := range repair.categories {
+				report.Categories[category]++
+			}
+			if len(report.Regions) < maxPlacementViolations {
+				report.Regions = append(report.Regions, &PlacementViolation{RegionID: region.GetID(), Categories: repair.categories})
+			} else {
+				report.Truncated = true
+			}
+			report.EstimatedOperators += repair.operators
+			addPeers += repair.addPeers + repair.replacements
+			removePeers += repair.removePeers + repair.replacements
+		}
+		if len(regions) < placementScanBatchSize || len(regions[len(regions)-1].GetEndKey()) == 0 {
+			break
+		}
+		startKey = regions[len(regions)-1].GetEndKey()
+		rate := s.cluster.opt.GetPlacementScanRegionRate()
+		if rate == 0 {
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(time.Duration(len(regions)) * time.Second / time.Duration(rate)):
+		}
+	}
+	report.EstimatedRepairTime = typeutil.NewDuration(s.estimateRepairTime(addPeers, removePeers))
+	report.EndTime = time.Now()
+	placementViolationGauge.Reset()
+	for category, count := range report.Categories {
+		placementViolationGauge.WithLabelValues(category).Set(float64(count))
+	}
+	return report
+}
+
+// check finds how the region violates the placement and the repair needed.
+func (s *placementScanner) check(region *core.RegionInfo) placementRepair {
+	var repair placementRepair
+	if s.cluster.opt.IsPlacementRulesEnabled() {
+		fit := s.cluster.GetRuleManager().FitRegion(s.cluster, region)
+		for _, rf := range fit.RuleFits {
+			if missing := rf.Rule.Count - len(rf.Peers); missing > 0 {
+				repair.addPeers += missing
+			}
+			repair.roleChanges += len(rf.PeersWithDifferentRole)
+		}
+		repair.removePeers = len(fit.OrphanPeers)
+	} else {
+		maxReplicas := s.cluster.opt.GetMaxReplicas()
+		if peers := len(region.GetPeers()); peers < maxReplicas {
+			repair.addPeers = maxReplicas - peers
+		} else {
+			repair.removePeers = peers - maxReplicas
+		}
+	}
+	repair.replacements = len(region.GetDownPeers())
+	offlinePeers := 0
+	for _, peer := range region.GetPeers() {
+		if store := s.cluster.GetStore(peer.GetStoreId()); store != nil && store.IsRemoving() {
+			offlinePeers++
+		}
+	}
+	repair.replacements += offlinePeers
+
+	for _, c := range []struct {
+		category string
+		count    int
+	}{
+		{PlacementMissPeer, repair.addPeers},
+		{PlacementExtraPeer, repair.removePeers},
+		{PlacementRoleMismatch, repair.roleChanges},
+		{PlacementDownPeer, len(region.GetDownPeers())},
+		{PlacementOfflinePeer, offlinePeers},
+	} {
+		if c.count > 0 {
+			repair.categories = append(repair.categories, c.category)
+			repair.operators += c.count
+		}
+	}
+	return repair
+}
+
+// estimateRepairTime estimates the time to add and remove the peers under the
+// sum of the store limits of the up stores, which are in operators per minute.
+// It is a lower bound since the peers are not evenly spread among the stores.
+func (s *placementScanner) estimateRepairTime(addPeers, removePeers int) time.Duration {
+	var addRate, removeRate float64
+	for _, store := range s.cluster.GetStores() {
+		if !store.IsUp() {
+			continue
+		}
+		addRate += s.cluster.GetStoreLimitByType(store.GetID(), storelimit.AddPeer)
+		removeRate += s.cluster.GetStoreLimitByType(store.GetID(), storelimit.RemovePeer)
+	}
+	var minutes float64
+	for _, c := range []struct {
+		count int
+		rate  float64
+	}{{addPeers, addRate}, {removePeers, removeRate}} {
+		if c.count > 0 && c.rate > 0 && float64(c.count)/c.rate > minutes {
+			minutes = float64(c.count) / c.rate
+		}
+	}
+	return time.Duration(minutes * float64(time.Minute))
+}
+
+// save persists the report and removes the oldest ones beyond the capacity.
+func (s *placementScanner) save(report *PlacementScanReport) {
+	if err := s.cluster.storage.SavePlacementScanReport(report.StartTime.UnixNano(), report); err != nil {
+		log.Warn("failed to save placement scan report", errs.ZapError(err))
+	}
+	s.Lock()
+	defer s.Unlock()
+	s.lastScan = report.StartTime
+	s.reports = append(s.reports, report)
+	for len(s.reports) > maxPlacementScanReports {
+		if err := s.cluster.storage.DeletePlacementScanReport(s.reports[0].StartTime.UnixNano()); err != nil {
+			log.Warn("failed to delete placement scan report", errs.ZapError(err))
+		}
+		s.reports = s.reports[1:]
+	}
+	log.Info("placement scan finished",
+		zap.Int("scanned-regions", report.ScannedRegions),
+		zap.Int("violating-regions", report.ViolatingRegions),
+		zap.Int("estimated-operators", report.EstimatedOperators),
+		zap.Duration("estimated-repair-time", report.EstimatedRepairTime.Duration))
+}
+
+// getReports returns the summaries of the recent reports, the latest first.
+func (s *placementScanner) getReports(limit int) []*PlacementScanReport {
+	s.RLock()
+	defer s.RUnlock()
+	reports := make([]*PlacementScanReport, 0, len(s.reports))
+	for i := len(s.reports) - 1; i >= 0 && (limit <= 0 || len(reports) < limit); i-- {
+		reports = append(reports, s.reports[i].summary())
+	}
+	return reports
+}
+
+// getLatestReport returns the latest report with the violating regions.
+func (s *placementScanner) getLatestReport() *PlacementScanReport {
+	s.RLock()
+	defer s.RUnlock()
+	if len(s.reports) == 0 {
+		return nil
+	}
+	return s.reports[len(s.reports)-1]
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestPlacementScan(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	for _, store := range newTestStores(3, "2.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+		re.NoError(cluster.SetStoreLimit(store.GetID(), storelimit.AddPeer, 60))
+		re.NoError(cluster.SetStoreLimit(store.GetID(), storelimit.RemovePeer, 60))
+	}
+	regions := newTestRegions(4, 3, 3)
+	// region 1 misses a peer, region 2 has a down peer and region 3 has an extra peer.
+	regions[1] = regions[1].Clone(core.WithRemoveStorePeer(regions[1].GetPeers()[2].GetStoreId()))
+	regions[2] = regions[2].Clone(core.WithDownPeers([]*pdpb.PeerStats{{Peer: regions[2].GetPeers()[1], DownSeconds: 3600}}))
+	regions[3] = regions[3].Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 3}))
+	for _, region := range regions {
+		re.NoError(cluster.putRegion(region))
+	}
+
+	scanner := newPlacementScanner(cluster)
+	report := scanner.scan(ctx, time.Now())
+	re.NotNil(report)
+	re.False(report.PlacementRulesEnabled)
+	re.Equal(4, report.ScannedRegions)
+	re.Equal(3, report.ViolatingRegions)
+	re.Equal(map[string]int{PlacementMissPeer: 1, PlacementDownPeer: 1, PlacementExtraPeer: 1}, report.Categories)
+	re.Equal([]*PlacementViolation{
+		{RegionID: 1, Categories: []string{PlacementMissPeer}},
+		{RegionID: 2, Categories: []string{PlacementDownPeer}},
+		{RegionID: 3, Categories: []string{PlacementExtraPeer}},
+	}, report.Regions)
+	re.False(report.Truncated)
+	re.Equal(3, report.EstimatedOperators)
+	// 2 peers to add and 2 peers to remove under 180 operators per minute.
+	re.InDelta(float64(2*time.Minute/180), float64(report.EstimatedRepairTime.Duration), float64(time.Millisecond))
+
+	// The rules are used if the placement rules are enabled.
+	opt.SetPlacementRuleEnabled(true)
+	re.NoError(cluster.GetRuleManager().Initialize(opt.GetMaxReplicas(), opt.GetLocationLabels()))
+	report = scanner.scan(ctx, time.Now())
+	re.True(report.PlacementRulesEnabled)
+	re.Equal(3, report.ViolatingRegions)
+	re.Equal(1, report.Categories[PlacementMissPeer])
+	re.Equal(1, report.Categories[PlacementExtraPeer])
+}
+
+func TestPlacementScanReports(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	scanner := newPlacementScanner(cluster)
+	re.Nil(scanner.getLatestReport())
+
+	start := time.Now()
+	for i := 0; i < maxPlacementScanReports+2; i++ {
+		scanner.save(&PlacementScanReport{
+			StartTime:        start.Add(time.Duration(i) * time.Hour),
+			ScannedRegions:   100,
+			ViolatingRegions: i,
+			Regions:          []*PlacementViolation{{RegionID: uint64(i), Categories: []string{PlacementMissPeer}}},
+		})
+	}
+	reports := scanner.getReports(3)
+	re.Len(reports, 3)
+	for i, report := range reports {
+		re.Equal(maxPlacementScanReports+1-i, report.ViolatingRegions)
+		re.Nil(report.Regions)
+	}
+	re.Len(scanner.getLatestReport().Regions, 1)
+
+	// The trend is restored from the storage, without the removed reports.
+	scanner = newPlacementScanner(cluster)
+	scanner.restore()
+	reports = scanner.getReports(0)
+	re.Len(reports, maxPlacementScanReports)
+	re.Equal(2, reports[len(reports)-1].ViolatingRegions)
+	re.Equal(start.Add(time.Duration(maxPlacementScanReports+1)*time.Hour).UnixNano(), scanner.lastScan.UnixNano())
+}
@@ -0,0 +1,181 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"go.uber.org/zap"
+)
+
+const (
+	// RegionAnnotationRulePrefix is the prefix of the IDs of the label rules which
+	// hold the annotations of the key ranges.
+	RegionAnnotationRulePrefix = "region-annotation-"
+	// regionAnnotationRuleIndex makes the annotations override the labels of the
+	// same keys assigned to the larger key ranges. The annotations of a nested key
+	// range have a larger index than the ones of the key ranges covering it.
+	regionAnnotationRuleIndex = 1
+	maxRegionAnnotations      = 16
+	maxRegionAnnotationLength = 128
+)
+
+// regionAnnotationRuleID returns the ID of the rule which holds the annotations
+// of the key range, so the annotations belong to the key range rather than a
+// region ID.
+func regionAnnotationRuleID(startKey, endKey []byte) string {
+	return RegionAnnotationRulePrefix + hex.EncodeToString(startKey) + "-" + hex.EncodeToString(endKey)
+}
+
+// regionAnnotationRange returns the key range of the annotation rule.
+func regionAnnotationRange(rule *labeler.LabelRule) (startKey, endKey []byte, ok bool) {
+	ranges, ok := rule.Data.([]*labeler.KeyRangeRule)
+	if !ok || len(ranges) != 1 {
+		return nil, nil, false
+	}
+	return ranges[0].StartKey, ranges[0].EndKey, true
+}
+
+// rangeContains returns true if the key range [startKey, endKey) contains the region.
+func rangeContains(startKey, endKey []byte, region *core.RegionInfo) bool {
+	return bytes.Compare(region.GetStartKey(), startKey) >= 0 &&
+		(len(endKey) == 0 || (len(region.GetEndKey()) > 0 && bytes.Compare(region.GetEndKey(), endKey) <= 0))
+}
+
+// rangeOverlaps returns true if the key range [startKey, endKey) overlaps the region.
+func rangeOverlaps(startKey, endKey []byte, region *core.RegionInfo) bool {
+	return (len(endKey) == 0 || bytes.Compare(region.GetStartKey(), endKey) < 0) &&
+		(len(region.GetEndKey()) == 0 || bytes.Compare(startKey, region.GetEndKey()) < 0)
+}
+
+// regionAnnotationRules returns the annotation rules whose key ranges satisfy f,
+// ordered by their indexes.
+func (c *RaftCluster) regionAnnotationRules(f func(startKey, endKey []byte) bool) []*labeler.LabelRule {
+	var rules []*labeler.LabelRule
+	for _, rule := range c.regionLabeler.GetAllLabelRules() {
+		if !strings.HasPrefix(rule.ID, RegionAnnotationRulePrefix) {
+			continue
+		}
+		if startKey, endKey, ok := regionAnnotationRange(rule); ok && f(startKey, endKey) {
+			rules = append(rules, rule)
+		}
+	}
+	sort.SliceStable(rules, func(i, j int) bool { return rules[i].Index < rules[j].Index })
+	return rules
+}
+
+// SetRegionAnnotations replaces the annotations of the key range of the region,
+// which are small key/value pairs read by the filters and the schedulers, e.g.
+// "pinned-leader-store". They are saved as a label rule of the key range, so the
+// regions split from it keep the annotations, and they are removed once the
+// region is merged with a region out of the key range.
+func (c *RaftCluster) SetRegionAnnotations(region *core.RegionInfo, annotations map[string]string) error {
+	if len(annotations) == 0 {
+		return errs.ErrRegionRuleContent.FastGenByArgs("no region annotations")
+	}
+	if len(annotations) > maxRegionAnnotations {
+		return errs.ErrRegionRuleContent.FastGenByArgs(fmt.Sprintf("too many region annotations, the max is %d", maxRegionAnnotations))
+	}
+	labels := make([]labeler.RegionLabel, 0, len(annotations))
+	for key, value := range annotations {
+		if len(key) > maxRegionAnnotationLength || len(value) > maxRegionAnnotationLength {
+			return errs.ErrRegionRuleContent.FastGenByArgs(fmt.Sprintf("region annotation %s is longer than %d", key, maxRegionAnnotationLength))
+		}
+		if key == labeler.PinnedLeaderStoreLabel {
+			storeID, err := strconv.ParseUint(value, 10, 64)
+			if err != nil || c.GetStore(storeID) == nil {
+				return errs.ErrRegionRuleContent.FastGenByArgs(fmt.Sprintf("%s should be the ID of an existing store", key))
+			}
+		}
+		labels = append(labels, labeler.RegionLabel{Key: key, Value: value})
+	}
+	sort.Slice(labels, func(i, j int) bool { return labels[i].Key < labels[j].Key })
+	id := regionAnnotationRuleID(region.GetStartKey(), region.GetEndKey())
+	index := regionAnnotationRuleIndex
+	for _, rule := range c.regionAnnotationRules(func(startKey, endKey []byte) bool { return rangeContains(startKey, endKey, region) }) {
+		if rule.ID != id && rule.Index >= index {
+			index = rule.Index + 1
+		}
+	}
+	rule := &labeler.LabelRule{
+		ID:       id,
+		Index:    index,
+		Labels:   labels,
+		RuleType: labeler.KeyRange,
+		Data:     []interface{}{map[string]interface{}{"start_key": hex.EncodeToString(region.GetStartKey()), "end_key": hex.EncodeToString(region.GetEndKey())}},
+	}
+	if err := c.regionLabeler.SetLabelRule(rule); err != nil {
+		return err
+	}
+	log.Info("region annotations are set", zap.Uint64("region-id", region.GetID()), zap.String("rule-id", id), zap.Any("annotations", annotations))
+	return nil
+}
+
+// GetRegionAnnotations returns the annotations of the region, which are set to the
+// key ranges containing the region. The annotations of the nested key ranges
+// override the ones of the outer key ranges.
+func (c *RaftCluster) GetRegionAnnotations(region *core.RegionInfo) map[string]string {
+	annotations := make(map[string]string)
+	for _, rule := range c.regionAnnotationRules(func(startKey, endKey []byte) bool { return rangeContains(startKey, endKey, region) }) {
+		for _, label := range rule.Labels {
+			annotations[label.Key] = label.Value
+		}
+	}
+	return annotations
+}
+
+// DeleteRegionAnnotations removes the annotations set to the key range of the region.
+// The annotations inherited from a larger key range should be deleted on the
+// region which they are set to.
+func (c *RaftCluster) DeleteRegionAnnotations(region *core.RegionInfo) error {
+	id := regionAnnotationRuleID(region.GetStartKey(), region.GetEndKey())
+	if c.regionLabeler.GetLabelRule(id) == nil {
+		return errs.ErrRegionRuleNotFound.FastGenByArgs(id)
+	}
+	if err := c.regionLabeler.DeleteLabelRule(id); err != nil {
+		return err
+	}
+	log.Info("region annotations are deleted", zap.Uint64("region-id", region.GetID()), zap.String("rule-id", id))
+	return nil
+}
+
+// cleanupRegionAnnotations removes the annotations of the key ranges which the
+// region is merged across, since the annotated key range no longer matches any
+// region. It is called once the region overlaps the others in the cache.
+func (c *RaftCluster) cleanupRegionAnnotations(region *core.RegionInfo) {
+	for _, rule := range c.regionAnnotationRules(func(startKey, endKey []byte) bool {
+		return rangeOverlaps(startKey, endKey, region) && !rangeContains(startKey, endKey, region)
+	}) {
+		if err := c.regionLabeler.DeleteLabelRule(rule.ID); err != nil {
+			log.Warn("failed to delete the annotations of the merged region",
+				zap.Uint64("region-id", region.GetID()),
+				zap.String("rule-id", rule.ID),
+				errs.ZapError(err))
+			continue
+		}
+		log.Info("region annotations are deleted since the region is merged",
+			zap.Uint64("region-id", region.GetID()),
+			zap.String("rule-id", rule.ID))
+	}
+}
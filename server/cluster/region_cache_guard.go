@@ -0,0 +1,244 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/ratelimit"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+)
+
+const (
+	regionCacheDropTokenTTL = time.Minute
+	// regionCacheSnapshotTTL is how long the dropped regions are kept to be
+	// restored, the heartbeats have refreshed the cache after that.
+	regionCacheSnapshotTTL = 30 * time.Minute
+	regionCacheDropRate    = 1
+	regionCacheDropBurst   = 16
+)
+
+// The actions and results of the region cache mutations.
+const (
+	regionCacheActionDropRegion  = "drop-region"
+	regionCacheActionDropAll     = "drop-all-regions"
+	regionCacheActionRestore     = "restore-regions"
+	regionCacheActionIssueToken  = "issue-token"
+	regionCacheResultSucceeded   = "succeeded"
+	regionCacheResultRateLimited = "rate-limited"
+	regionCacheResultBadToken    = "bad-token"
+)
+
+// DroppedRegion is a region dropped from the cache by the API, which can be restored
+// if it has not been reported by the heartbeat again.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type DroppedRegion struct {
+	ID        uint64    `json:"id"`
+	StartKey  string    `json:"start_key"`
+	EndKey    string    `json:"end_key"`
+	DroppedAt time.Time `json:"dropped_at"`
+}
+
+type droppedRegion struct {
+	region    *core.RegionInfo
+	droppedAt time.Time
+}
+
+// regionCacheGuard guards dropping the region cache by the API. The dropped regions
+// are kept for a while to be restored, and if the safe mode is enabled, dropping all
+// the regions requires a token issued before and dropping the regions one by one is
+// rate limited. Each invocation is published as a cluster event.
+type regionCacheGuard struct {
+	syncutil.Mutex
+	cluster     *RaftCluster
+	limiter     *ratelimit.RateLimiter
+	token       string
+	tokenExpire time.Time
+	dropped     map[uint64]*droppedRegion
+}
+
+func newRegionCacheGuard(cluster *RaftCluster) *regionCacheGuard {
+	return &regionCacheGuard{
+		cluster: cluster,
+		limiter: ratelimit.NewRateLimiter(regionCacheDropRate, regionCacheDropBurst),
+		dropped: make(map[uint64]*droppedRegion),
+	}
+}
+
+// issueToken issues a one-time token to drop all the regions, which replaces the
+// previous one.
+func (g *regionCacheGuard) issueToken(now time.Time) (string, time.Time, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", time.Time{}, err
+	}
+	g.Lock()
+	g.token = hex.EncodeToString(b)
+	g.tokenExpire = now.Add(regionCacheDropTokenTTL)
+	token, expire := g.token, g.tokenExpire
+	g.Unlock()
+	g.audit(regionCacheActionIssueToken, regionCacheResultSucceeded, 0)
+	return token, expire, nil
+}
+
+func (g *regionCacheGuard) dropRegion(id uint64, now time.Time) error {
+	if g.cluster.opt.IsRegionCacheSafeModeEnabled() && !g.limiter.Allow() {
+		g.audit(regionCacheActionDropRegion, regionCacheResultRateLimited, 0, id)
+		return errs.ErrRegionCacheDropLimited.FastGenByArgs()
+	}
+	g.Lock()
+	g.pruneLocked(now)
+	if region := g.cluster.GetRegion(id); region != nil {
+		g.dropped[id] = &droppedRegion{region: region, droppedAt: now}
+	}
+	g.cluster.DropCacheRegion(id)
+	g.Unlock()
+	g.audit(regionCacheActionDropRegion, regionCacheResultSucceeded, 1, id)
+	return nil
+}
+
+// dropAllRegions drops all the regions and returns the number of them. The token
+// is consumed if it is accepted.
+func (g *regionCacheGuard) dropAllRegions(token string, now time.Time) (int, error) {
+	g.Lock()
+	if g.cluster.opt.IsRegionCacheSafeModeEnabled() {
+		var reason string
+		switch {
+		case token == "":
+			reason = "missing token"
+		case subtle.ConstantTimeCompare([]byte(token), []byte(g.token)) != 1:
+			reason = "token mismatch"
+		case now.After(g.tokenExpire):
+			reason = "token expired"
+		}
+		if reason != "" {
+			g.Unlock()
+			g.audit(regionCacheActionDropAll, regionCacheResultBadToken, 0)
+			return 0, errs.ErrRegionCacheDropToken.FastGenByArgs(reason)
+		}
+		g.token = ""
+	}
+	g.pruneLocked(now)
+	regions := g.cluster.GetRegions()
+	for _, region := range regions {
+		g.dropped[region.GetID()] = &droppedRegion{region: region, droppedAt: now}
+	}
+	g.cluster.DropCacheAllRegion()
+	g.Unlock()
+	g.audit(regionCacheActionDropAll, regionCacheResultSucceeded, len(regions))
+	return len(regions), nil
+}
+
+// restore puts the dropped regions back to the cache, or all of them if no region
+// is given, and returns the number of the restored regions. The regions which have
+// been reported by the heartbeat again or overlap the newer regions are skipped.
+// The regions are restored as heartbeats, so that the statistics, the storage and
+// the region syncer are updated as well.
+func (g *regionCacheGuard) restore(ids []uint64, now time.Time) int {
+	g.Lock()
+	g.pruneLocked(now)
+	if len(ids) == 0 {
+		for id := range g.dropped {
+			ids = append(ids, id)
+		}
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	var restored []uint64
+	for _, id := range ids {
+		d, ok := g.dropped[id]
+		if !ok {
+			continue
+		}
+		delete(g.dropped, id)
+		if g.cluster.GetRegion(id) != nil {
+			continue
+		}
+		// The loads in the snapshot are outdated, they shouldn't be counted
+		// by the hot statistics again.
+		region := d.region.Clone(
+			core.SetWrittenBytes(0), core.SetWrittenKeys(0), core.SetWrittenQuery(0),
+			core.SetReadBytes(0), core.SetReadKeys(0), core.SetReadQuery(0),
+		)
+		if err := g.cluster.processRegionHeartbeat(region); err != nil {
+			continue
+		}
+		restored = append(restored, id)
+	}
+	g.Unlock()
+	g.audit(regionCacheActionRestore, regionCacheResultSucceeded, len(restored), restored...)
+	return len(restored)
+}
+
+func (g *regionCacheGuard) getDroppedRegions(now time.Time) []DroppedRegion {
+	g.Lock()
+	defer g.Unlock()
+	g.pruneLocked(now)
+	regions := make([]DroppedRegion, 0, len(g.dropped))
+	for id, d := range g.dropped {
+		regions = append(regions, DroppedRegion{
+			ID:        id,
+			StartKey:  core.HexRegionKeyStr(d.region.GetStartKey()),
+			EndKey:    core.HexRegionKeyStr(d.region.GetEndKey()),
+			DroppedAt: d.droppedAt,
+		})
+	}
+	sort.Slice(regions, func(i, j int) bool { return regions[i].ID < regions[j].ID })
+	return regions
+}
+
+func (g *regionCacheGuard) pruneLocked(now time.Time) {
+	for id, d := range g.dropped {
+		if now.Sub(d.droppedAt) > regionCacheSnapshotTTL {
+			delete(g.dropped, id)
+		}
+	}
+}
+
+// audit publishes the invocation as a cluster event. Only the first regions are
+// listed in the attributes.
+func (g *regionCacheGuard) audit(action, result string, count int, regionIDs ...uint64) {
+	const maxListedRegions = 16
+	attributes := map[string]string{
+		"action": action,
+		"result": result,
+		"count":  strconv.Itoa(count),
+	}
+	if len(regionIDs) > 0 {
+		if len(regionIDs) > maxListedRegions {
+			regionIDs = regionIDs[:maxListedRegions]
+		}
+		ids := make([]byte, 0, 8*len(regionIDs))
+		for i, id := range regionIDs {
+			if i > 0 {
+				ids = append(ids, ',')
+			}
+			ids = strconv.AppendUint(ids, id, 10)
+		}
+		attributes["regions"] = string(ids)
+	}
+	regionCacheMutationCounter.WithLabelValues(action, result).Inc()
+	g.cluster.events.publish(&ClusterEvent{
+		Type:       EventRegionCacheMutated,
+		Message:    "the region cache is mutated by the API, " + action + " " + result,
+		Attributes: attributes,
+	})
+}
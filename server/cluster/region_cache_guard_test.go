@@ -0,0 +1,107 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestRegionCacheGuard(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.EnableRegionCacheSafeMode = true
+	opt.SetPDServerConfig(cfg)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	regions := newTestRegions(10, 3, 3)
+	for _, region := range regions {
+		re.NoError(cluster.putRegion(region))
+	}
+	guard := cluster.regionCacheGuard
+	now := time.Now()
+
+	// Dropping all the regions requires a valid token.
+	_, err = guard.dropAllRegions("", now)
+	re.True(errs.ErrRegionCacheDropToken.Equal(err))
+	token, expire, err := guard.issueToken(now)
+	re.NoError(err)
+	re.Equal(now.Add(regionCacheDropTokenTTL), expire)
+	_, err = guard.dropAllRegions(token, expire.Add(time.Second))
+	re.True(errs.ErrRegionCacheDropToken.Equal(err))
+	re.Equal(10, cluster.GetRegionCount())
+	count, err := guard.dropAllRegions(token, now)
+	re.NoError(err)
+	re.Equal(10, count)
+	re.Equal(0, cluster.GetRegionCount())
+	_, err = guard.dropAllRegions(token, now)
+	re.True(errs.ErrRegionCacheDropToken.Equal(err))
+	re.Len(guard.getDroppedRegions(now), 10)
+
+	// The regions reported again are not restored.
+	re.NoError(cluster.putRegion(regions[0].Clone(core.SetApproximateSize(200))))
+	re.Equal(2, guard.restore([]uint64{0, 1, 2}, now))
+	re.Equal(int64(200), cluster.GetRegion(0).GetApproximateSize())
+	re.NotNil(cluster.GetRegion(1))
+	re.Len(guard.getDroppedRegions(now), 7)
+
+	// The dropped regions expire.
+	re.Empty(guard.getDroppedRegions(now.Add(regionCacheSnapshotTTL + time.Second)))
+	re.Equal(0, guard.restore(nil, now))
+	re.Equal(3, cluster.GetRegionCount())
+
+	// The drops one by one are rate limited in the safe mode only.
+	for i := 0; i < regionCacheDropBurst; i++ {
+		re.NoError(guard.dropRegion(1, now))
+	}
+	re.True(errs.ErrRegionCacheDropLimited.Equal(guard.dropRegion(2, now)))
+	re.NotNil(cluster.GetRegion(2))
+	cfg = opt.GetPDServerConfig().Clone()
+	cfg.EnableRegionCacheSafeMode = false
+	opt.SetPDServerConfig(cfg)
+	re.NoError(guard.dropRegion(2, now))
+	re.Nil(cluster.GetRegion(2))
+	re.NoError(cluster.storage.DeleteRegion(regions[2].GetMeta()))
+	re.Equal(2, guard.restore(nil, now))
+	re.Equal(3, cluster.GetRegionCount())
+	// The restored regions are processed as heartbeats.
+	meta := &metapb.Region{}
+	ok, err := cluster.storage.LoadRegion(2, meta)
+	re.NoError(err)
+	re.True(ok)
+	re.Equal(uint64(2), meta.GetId())
+
+	// Each invocation is published as an event.
+	events := cluster.GetClusterEvents(0)
+	re.NotEmpty(events)
+	for _, event := range events {
+		re.Equal(EventRegionCacheMutated, event.Type)
+	}
+	re.Equal(regionCacheActionRestore, events[len(events)-1].Attributes["action"])
+	re.Equal("1,2", events[len(events)-1].Attributes["regions"])
+}
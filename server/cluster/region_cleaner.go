@@ -0,0 +1,105 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/server/core"
+	"go.uber.org/zap"
+)
+
+const regionCleanerQueueSize = 4096
+
+// regionCleaner deletes the regions overlapped by the heartbeats from the
+// storage in the background, so that a large number of overlaps, e.g. caused
+// by bulk ingestion, does not slow down the region heartbeats.
+type regionCleaner struct {
+	cluster *RaftCluster
+	queue   chan *metapb.Region
+}
+
+func newRegionCleaner(cluster *RaftCluster) *regionCleaner {
+	return &regionCleaner{
+		cluster: cluster,
+		queue:   make(chan *metapb.Region, regionCleanerQueueSize),
+	}
+}
+
+// push queues the region to be deleted. If the queue is full, the region is
+// deleted synchronously to apply back pressure to the heartbeats.
+func (rc *regionCleaner) push(region *metapb.Region) {
+	select {
+	case rc.queue <- region:
+		regionCleanerQueueGauge.Set(float64(len(rc.queue)))
+	default:
+		regionCleanerEventCounter.WithLabelValues("queue_full").Inc()
+		rc.delete(region, false)
+	}
+}
+
+// run deletes the queued regions until the context is done. The remaining
+// regions are deleted before it returns, so that no deletion is lost.
+func (rc *regionCleaner) run(ctx context.Context) {
+	for {
+		select {
+		case region := <-rc.queue:
+			regionCleanerQueueGauge.Set(float64(len(rc.queue)))
+			rc.delete(region, true)
+		case <-ctx.Done():
+			for {
+				select {
+				case region := <-rc.queue:
+					rc.delete(region, false)
+				default:
+					regionCleanerQueueGauge.Set(0)
+					return
+				}
+			}
+		}
+	}
+}
+
+// delete deletes the region from the storage. The deletion is idempotent, so
+// it can be retried safely.
+func (rc *regionCleaner) delete(region *metapb.Region, retry bool) {
+	// The region may be put back after being overlapped and saved again,
+	// deleting it would lose the newer one.
+	if rc.cluster.core.GetRegion(region.GetId()) != nil {
+		regionCleanerEventCounter.WithLabelValues("skip").Inc()
+		return
+	}
+	deleteRegion := func() error {
+		return rc.cluster.storage.DeleteRegion(region)
+	}
+	var err error
+	if retry {
+		err = rc.cluster.persistWithRetry("region", deleteRegion)
+	} else {
+		err = deleteRegion()
+	}
+	if err != nil {
+		log.Error("failed to delete region from storage",
+			zap.Uint64("region-id", region.GetId()),
+			logutil.ZapRedactStringer("region-meta", core.RegionToHexMeta(region)),
+			errs.ZapError(err))
+		return
+	}
+	regionCleanerEventCounter.WithLabelValues("deleted").Inc()
+}
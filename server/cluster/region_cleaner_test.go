@@ -0,0 +1,94 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestRegionCleaner(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	regions := newTestRegionMetas(3)
+	for _, region := range regions {
+		re.NoError(cluster.storage.SaveRegion(region))
+	}
+	// The region 2 is put back to the cache, so it must be kept.
+	cluster.core.PutRegion(core.NewRegionInfo(regions[2], nil))
+
+	cleaner := newRegionCleaner(cluster)
+	go cleaner.run(ctx)
+	for _, region := range regions {
+		cleaner.push(region)
+	}
+	exists := func(id uint64) bool {
+		ok, err := cluster.storage.LoadRegion(id, &metapb.Region{})
+		re.NoError(err)
+		return ok
+	}
+	testutil.Eventually(re, func() bool {
+		return !exists(0) && !exists(1)
+	})
+	re.True(exists(2))
+}
+
+func TestRegionCleanerDrain(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cleaner := newRegionCleaner(cluster)
+	regions := newTestRegionMetas(regionCleanerQueueSize + 1)
+	for _, region := range regions {
+		re.NoError(cluster.storage.SaveRegion(region))
+		// The last one is deleted synchronously since the queue is full.
+		cleaner.push(region)
+	}
+	ok, err := cluster.storage.LoadRegion(regionCleanerQueueSize, &metapb.Region{})
+	re.NoError(err)
+	re.False(ok)
+
+	// The queued regions are deleted before it exits.
+	cancel()
+	cleaner.run(ctx)
+	for _, region := range regions {
+		ok, err := cluster.storage.LoadRegion(region.GetId(), &metapb.Region{})
+		re.NoError(err)
+		re.False(ok)
+	}
+}
+
+func newTestRegionMetas(n int) []*metapb.Region {
+	regions := make([]*metapb.Region, 0, n)
+	for i := 0; i < n; i++ {
+		regions = append(regions, newTestRegionMeta(uint64(i)))
+	}
+	return regions
+}
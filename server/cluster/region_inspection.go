@@ -0,0 +1,180 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/cache"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+	"go.uber.org/zap"
+)
+
+const (
+	// maxQuarantinedRegions is the capacity of the region inspection queue.
+	maxQuarantinedRegions = 1024
+	// absurdJumpRatio is the ratio of the approximate size or keys between two
+	// heartbeats which is considered as an anomaly.
+	absurdJumpRatio = 1000
+	// absurdSizeJumpThreshold and absurdKeysJumpThreshold are the min approximate
+	// size (MB) and keys to detect the absurd jumps, to avoid reporting small regions.
+	absurdSizeJumpThreshold = units.TiB / units.MiB
+	absurdKeysJumpThreshold = 1000000000
+	// absurdJumpConfirmations is the number of the consecutive heartbeats which
+	// report the same jump of a region before it is accepted, since the stats of
+	// a region may genuinely jump, e.g. after ingesting a large amount of data.
+	absurdJumpConfirmations = 3
+)
+
+// QuarantinedRegion is a region heartbeat which is not applied due to anomalies,
+// and kept for inspection.
+type QuarantinedRegion struct {
+	RegionID        uint64              `json:"region_id"`
+	StartKey        string              `json:"start_key"`
+	EndKey          string              `json:"end_key"`
+	RegionEpoch     *metapb.RegionEpoch `json:"epoch,omitempty"`
+	Peers           []*metapb.Peer      `json:"peers,omitempty"`
+	ApproximateSize int64               `json:"approximate_size"`
+	ApproximateKeys int64               `json:"approximate_keys"`
+	Reason          string              `json:"reason"`
+	Time            time.Time           `json:"time"`
+}
+
+// regionInspectionQueue keeps the latest quarantined region heartbeats.
+type regionInspectionQueue struct {
+	*cache.FIFO
+	mu syncutil.Mutex
+	// jumps is the number of the consecutive heartbeats of each region whose
+	// stats jump from the region in cache.
+	jumps map[uint64]int
+}
+
+func newRegionInspectionQueue() *regionInspectionQueue {
+	return &regionInspectionQueue{
+		FIFO:  cache.NewFIFO(maxQuarantinedRegions),
+		jumps: make(map[uint64]int),
+	}
+}
+
+// checkStats returns the reason if the approximate size or keys of the region
+// jump absurdly from the region in cache. The jump is accepted once it is
+// reported by absurdJumpConfirmations consecutive heartbeats, so the region is
+// never quarantined forever.
+func (q *regionInspectionQueue) checkStats(region, origin *core.RegionInfo) string {
+	reason := inspectRegionStats(region, origin)
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	id := region.GetID()
+	if reason == "" {
+		delete(q.jumps, id)
+		return ""
+	}
+	if _, ok := q.jumps[id]; !ok && len(q.jumps) >= maxQuarantinedRegions {
+		return reason
+	}
+	q.jumps[id]++
+	if q.jumps[id] < absurdJumpConfirmations {
+		return reason
+	}
+	delete(q.jumps, id)
+	log.Info("the jump of the region stats is accepted after the consecutive heartbeats",
+		zap.Uint64("region-id", id), zap.String("jump", reason))
+	return ""
+}
+
+func (q *regionInspectionQueue) put(region *core.RegionInfo, reason string) {
+	q.Put(region.GetID(), &QuarantinedRegion{
+		RegionID:        region.GetID(),
+		StartKey:        core.HexRegionKeyStr(region.GetStartKey()),
+		EndKey:          core.HexRegionKeyStr(region.GetEndKey()),
+		RegionEpoch:     region.GetRegionEpoch(),
+		Peers:           region.GetPeers(),
+		ApproximateSize: region.GetApproximateSize(),
+		ApproximateKeys: region.GetApproximateKeys(),
+		Reason:          reason,
+		Time:            time.Now(),
+	})
+}
+
+func (q *regionInspectionQueue) list() []*QuarantinedRegion {
+	elems := q.Elems()
+	regions := make([]*QuarantinedRegion, 0, len(elems))
+	for _, elem := range elems {
+		regions = append(regions, elem.Value.(*QuarantinedRegion))
+	}
+	return regions
+}
+
+// inspectRegionHeartbeat checks the integrity of a region heartbeat against the
+// region in cache, and returns the reason if the region is malformed. The stale
+// heartbeats are not anomalies, they are rejected by PreCheckPutRegion. The
+// stats are checked by checkStats separately.
+func inspectRegionHeartbeat(region, origin *core.RegionInfo) string {
+	stores := make(map[uint64]struct{}, len(region.GetPeers()))
+	for _, peer := range region.GetPeers() {
+		if _, ok := stores[peer.GetStoreId()]; ok {
+			return fmt.Sprintf("duplicated peers on store %d", peer.GetStoreId())
+		}
+		stores[peer.GetStoreId()] = struct{}{}
+	}
+	if len(region.GetEndKey()) > 0 && bytes.Compare(region.GetStartKey(), region.GetEndKey()) >= 0 {
+		return fmt.Sprintf("invalid key range [%s, %s)", hex.EncodeToString(region.GetStartKey()), hex.EncodeToString(region.GetEndKey()))
+	}
+	if origin == nil {
+		return ""
+	}
+
+	r, o := region.GetRegionEpoch(), origin.GetRegionEpoch()
+	// The key range can only change along with the version. A zero-length key
+	// means the region reaches the boundary of the keyspace suddenly.
+	if r.GetVersion() == o.GetVersion() {
+		if len(region.GetStartKey()) == 0 && len(origin.GetStartKey()) != 0 {
+			return "zero-length start key in the middle of the keyspace, origin start key " + hex.EncodeToString(origin.GetStartKey())
+		}
+		if len(region.GetEndKey()) == 0 && len(origin.GetEndKey()) != 0 {
+			return "zero-length end key in the middle of the keyspace, origin end key " + hex.EncodeToString(origin.GetEndKey())
+		}
+	}
+	return ""
+}
+
+// inspectRegionStats returns the reason if the approximate size or keys of the
+// region jump absurdly from the region in cache.
+func inspectRegionStats(region, origin *core.RegionInfo) string {
+	if origin == nil {
+		return ""
+	}
+	if isAbsurdJump(origin.GetApproximateSize(), region.GetApproximateSize(), absurdSizeJumpThreshold) {
+		return fmt.Sprintf("approximate size jumps from %dMiB to %dMiB", origin.GetApproximateSize(), region.GetApproximateSize())
+	}
+	if isAbsurdJump(origin.GetApproximateKeys(), region.GetApproximateKeys(), absurdKeysJumpThreshold) {
+		return fmt.Sprintf("approximate keys jumps from %d to %d", origin.GetApproximateKeys(), region.GetApproximateKeys())
+	}
+	return ""
+}
+
+func isAbsurdJump(from, to, threshold int64) bool {
+	if to < threshold || from <= 0 {
+		return false
+	}
+	return to/from >= absurdJumpRatio
+}
@@ -0,0 +1,179 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"container/list"
+
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+// regionQueryCacheEntryOverhead is the estimated memory used by an entry
+// besides its key and value, i.e. the list element and the index entries.
+const regionQueryCacheEntryOverhead = 128
+
+const regionQueryByKeyPrefix = "region/key/"
+
+type regionQueryCacheEntry struct {
+	key      string
+	regionID uint64
+	value    interface{}
+	size     uint64
+}
+
+// RegionQueryCache caches the results of the region queries, e.g. the regions
+// of the hot keys asked by the router clients repeatedly. Each result belongs
+// to a region, and is invalidated when the region is notified as changed, that
+// is when its meta, leader, down peers or pending peers are changed. So the
+// statistics carried by a cached result, such as the approximate size and the
+// flow, may lag behind.
+//
+// The memory used by the cache is bounded by the capacity returned by
+// capacityFn, the least recently used results are evicted when it exceeds.
+// The cache is disabled if the capacity is 0.
+type RegionQueryCache struct {
+	syncutil.Mutex
+	capacityFn func() uint64
+	// generation increases on every invalidation, so that a result queried
+	// before the invalidation is not cached after it.
+	generation uint64
+	size       uint64
+	lru        *list.List
+	entries    map[string]*list.Element
+	regionKeys map[uint64]map[string]struct{}
+}
+
+// NewRegionQueryCache creates a RegionQueryCache.
+func NewRegionQueryCache(capacityFn func() uint64) *RegionQueryCache {
+	return &RegionQueryCache{
+		capacityFn: capacityFn,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		regionKeys: make(map[uint64]map[string]struct{}),
+	}
+}
+
+// GetOrLoad returns the cached result of the query identified by the key. If
+// it is not cached, load is called to do the query, and its result is cached
+// with the estimated size in bytes. A result whose region ID is 0, e.g. no
+// region is found, is not cached.
+func (c *RegionQueryCache) GetOrLoad(key string, load func() (value interface{}, regionID uint64, size uint64)) interface{} {
+	capacity := c.capacityFn()
+	c.Lock()
+	if capacity == 0 {
+		c.resetLocked()
+		c.Unlock()
+		value, _, _ := load()
+		return value
+	}
+	if ele, ok := c.entries[key]; ok {
+		c.lru.MoveToFront(ele)
+		c.Unlock()
+		regionQueryCacheCounter.WithLabelValues("hit").Inc()
+		return ele.Value.(*regionQueryCacheEntry).value
+	}
+	generation := c.generation
+	c.Unlock()
+
+	regionQueryCacheCounter.WithLabelValues("miss").Inc()
+	value, regionID, size := load()
+	if regionID == 0 {
+		return value
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	if c.generation != generation {
+		return value
+	}
+	if _, ok := c.entries[key]; ok {
+		return value
+	}
+	entry := &regionQueryCacheEntry{
+		key:      key,
+		regionID: regionID,
+		value:    value,
+		size:     uint64(len(key)) + size + regionQueryCacheEntryOverhead,
+	}
+	if entry.size > capacity {
+		return value
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+	keys, ok := c.regionKeys[regionID]
+	if !ok {
+		keys = make(map[string]struct{})
+		c.regionKeys[regionID] = keys
+	}
+	keys[key] = struct{}{}
+	c.size += entry.size
+	for c.size > capacity {
+		c.removeLocked(c.lru.Back())
+		regionQueryCacheCounter.WithLabelValues("evict").Inc()
+	}
+	regionQueryCacheSizeGauge.Set(float64(c.size))
+	return value
+}
+
+// Invalidate removes the cached results of the regions.
+func (c *RegionQueryCache) Invalidate(regionIDs ...uint64) {
+	c.Lock()
+	defer c.Unlock()
+	c.generation++
+	for _, id := range regionIDs {
+		for key := range c.regionKeys[id] {
+			c.removeLocked(c.entries[key])
+			regionQueryCacheCounter.WithLabelValues("invalidate").Inc()
+		}
+	}
+	regionQueryCacheSizeGauge.Set(float64(c.size))
+}
+
+// Reset removes all the cached results.
+func (c *RegionQueryCache) Reset() {
+	c.Lock()
+	defer c.Unlock()
+	c.resetLocked()
+}
+
+// Len returns the number of the cached results.
+func (c *RegionQueryCache) Len() int {
+	c.Lock()
+	defer c.Unlock()
+	return c.lru.Len()
+}
+
+func (c *RegionQueryCache) resetLocked() {
+	c.generation++
+	if c.lru.Len() == 0 {
+		return
+	}
+	c.size = 0
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.regionKeys = make(map[uint64]map[string]struct{})
+	regionQueryCacheSizeGauge.Set(0)
+}
+
+func (c *RegionQueryCache) removeLocked(ele *list.Element) {
+	entry := c.lru.Remove(ele).(*regionQueryCacheEntry)
+	delete(c.entries, entry.key)
+	if keys, ok := c.regionKeys[entry.regionID]; ok {
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(c.regionKeys, entry.regionID)
+		}
+	}
+	c.size -= entry.size
+}
@@ -0,0 +1,133 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestRegionQueryCache(t *testing.T) {
+	re := require.New(t)
+	capacity := uint64(0)
+	c := NewRegionQueryCache(func() uint64 { return capacity })
+	loads := 0
+	load := func(value string, regionID uint64) func() (interface{}, uint64, uint64) {
+		return func() (interface{}, uint64, uint64) {
+			loads++
+			return value, regionID, uint64(len(value))
+		}
+	}
+
+	// The cache is disabled.
+	re.Equal("a", c.GetOrLoad("a", load("a", 1)))
+	re.Equal("a", c.GetOrLoad("a", load("a", 1)))
+	re.Equal(2, loads)
+	re.Equal(0, c.Len())
+
+	capacity = 3 * (regionQueryCacheEntryOverhead + 2)
+	re.Equal("a", c.GetOrLoad("a", load("a", 1)))
+	re.Equal("a", c.GetOrLoad("a", load("x", 1)))
+	re.Equal(3, loads)
+	// The results without regions are not cached.
+	re.Nil(c.GetOrLoad("none", load("", 0)))
+	re.Equal(1, c.Len())
+
+	// The least recently used results are evicted.
+	c.GetOrLoad("b", load("b", 1))
+	c.GetOrLoad("c", load("c", 2))
+	c.GetOrLoad("a", load("a", 1))
+	c.GetOrLoad("d", load("d", 2))
+	re.Equal(3, c.Len())
+	loads = 0
+	re.Equal("b", c.GetOrLoad("b", load("b", 1)))
+	re.Equal(1, loads)
+
+	// The results of the invalidated regions are removed.
+	c.Invalidate(1)
+	re.Equal(1, c.Len())
+	re.Equal("d", c.GetOrLoad("d", load("x", 2)))
+	re.Equal(1, loads)
+
+	// The result loaded before an invalidation is not cached.
+	re.Equal("e", c.GetOrLoad("e", func() (interface{}, uint64, uint64) {
+		c.Invalidate(3)
+		return "e", 3, 1
+	}))
+	re.Equal(1, c.Len())
+
+	// Disabling the cache drops all the results.
+	capacity = 0
+	c.GetOrLoad("d", load("d", 2))
+	re.Equal(0, c.Len())
+}
+
+func TestCachedRegionByKey(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.RegionQueryCacheSize = typeutil.ByteSize(1024 * 1024)
+	opt.SetPDServerConfig(cfg)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	for _, store := range newTestStores(2, "2.0.0") {
+		re.NoError(cluster.putStoreLocked(store))
+	}
+
+	peers := []*metapb.Peer{{Id: 11, StoreId: 1}, {Id: 12, StoreId: 2}}
+	region := core.NewRegionInfo(&metapb.Region{
+		Id:          1,
+		StartKey:    []byte("a"),
+		EndKey:      []byte("z"),
+		Peers:       peers,
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+	}, peers[0])
+	re.NoError(cluster.processRegionHeartbeat(region))
+	re.Equal(region, cluster.GetCachedRegionByKey([]byte("m")))
+	re.Nil(cluster.GetCachedRegionByKey([]byte("zz")))
+	re.Equal(1, cluster.GetRegionQueryCache().Len())
+
+	// The leader is changed.
+	region = region.Clone(core.WithLeader(peers[1]))
+	re.NoError(cluster.processRegionHeartbeat(region))
+	re.Equal(0, cluster.GetRegionQueryCache().Len())
+	re.Equal(uint64(2), cluster.GetCachedRegionByKey([]byte("m")).GetLeader().GetStoreId())
+
+	// The region is split, the key belongs to the new region.
+	split := core.NewRegionInfo(&metapb.Region{
+		Id:          2,
+		StartKey:    []byte("a"),
+		EndKey:      []byte("n"),
+		Peers:       []*metapb.Peer{{Id: 21, StoreId: 1}, {Id: 22, StoreId: 2}},
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 2},
+	}, nil)
+	split = split.Clone(core.WithLeader(split.GetPeers()[0]))
+	re.NoError(cluster.processRegionHeartbeat(split))
+	re.Equal(uint64(2), cluster.GetCachedRegionByKey([]byte("m")).GetID())
+
+	cluster.DropCacheAllRegion()
+	re.Equal(0, cluster.GetRegionQueryCache().Len())
+}
@@ -0,0 +1,203 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"go.uber.org/zap"
+)
+
+const (
+	// ReplicaFreezeRulePrefix is the prefix of the IDs of the label rules which
+	// freeze the replicas.
+	ReplicaFreezeRulePrefix    = "replica-freeze-"
+	replicaFreezeCheckInterval = time.Second
+)
+
+// ReplicaFreeze freezes the replicas of the regions in a key range, e.g. for BR
+// to copy the regions with a stable topology. The leaders of the regions can
+// still be transferred.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type ReplicaFreeze struct {
+	ID       string    `json:"id"`
+	StartKey string    `json:"start_key"`
+	EndKey   string    `json:"end_key"`
+	ExpireAt time.Time `json:"expire_at"`
+}
+
+// newReplicaFreeze converts the label rule to the freeze. It returns nil if
+// the rule does not freeze the replicas or it is expired.
+func newReplicaFreeze(rule *labeler.LabelRule) *ReplicaFreeze {
+	if !strings.HasPrefix(rule.ID, ReplicaFreezeRulePrefix) || rule.RuleType != labeler.KeyRange {
+		return nil
+	}
+	ranges, ok := rule.Data.([]*labeler.KeyRangeRule)
+	if !ok || len(ranges) == 0 {
+		return nil
+	}
+	for _, label := range rule.Labels {
+		if label.Key != labeler.ReplicaOptionLabel || label.Value != labeler.ReplicaOptionValueFreeze {
+			continue
+		}
+		ttl, err := time.ParseDuration(label.TTL)
+		if err != nil {
+			return nil
+		}
+		startAt, err := time.Parse(time.UnixDate, label.StartAt)
+		if err != nil {
+			return nil
+		}
+		expireAt := startAt.Add(ttl)
+		if expireAt.Before(time.Now()) {
+			return nil
+		}
+		return &ReplicaFreeze{
+			ID:       strings.TrimPrefix(rule.ID, ReplicaFreezeRulePrefix),
+			StartKey: ranges[0].StartKeyHex,
+			EndKey:   ranges[0].EndKeyHex,
+			ExpireAt: expireAt,
+		}
+	}
+	return nil
+}
+
+// replicaFreezeTracker publishes an event once a replica freeze expires. The
+// freezes are label rules with ttl, so they are thawed by the labeler and kept
+// across the leader changes.
+type replicaFreezeTracker struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	freezes map[string]*ReplicaFreeze
+}
+
+func newReplicaFreezeTracker(cluster *RaftCluster) *replicaFreezeTracker {
+	return &replicaFreezeTracker{
+		cluster: cluster,
+		freezes: make(map[string]*ReplicaFreeze),
+	}
+}
+
+func (t *replicaFreezeTracker) track(freeze *ReplicaFreeze) {
+	t.Lock()
+	defer t.Unlock()
+	t.freezes[freeze.ID] = freeze
+}
+
+func (t *replicaFreezeTracker) untrack(id string) {
+	t.Lock()
+	defer t.Unlock()
+	delete(t.freezes, id)
+}
+
+// check publishes the events of the expired freezes.
+func (t *replicaFreezeTracker) check() {
+	t.Lock()
+	defer t.Unlock()
+	for id, freeze := range t.freezes {
+		if t.cluster.regionLabeler.GetLabelRule(ReplicaFreezeRulePrefix+id) != nil {
+			continue
+		}
+		delete(t.freezes, id)
+		log.Info("replica freeze is expired",
+			zap.String("id", id),
+			zap.String("start-key", freeze.StartKey),
+			zap.String("end-key", freeze.EndKey))
+		t.cluster.events.publish(&ClusterEvent{
+			Type:    EventReplicaFreezeExpired,
+			Message: fmt.Sprintf("the replicas in [%s, %s) are thawed since the freeze %s is expired", freeze.StartKey, freeze.EndKey, id),
+			Attributes: map[string]string{
+				"id":        id,
+				"start-key": freeze.StartKey,
+				"end-key":   freeze.EndKey,
+			},
+		})
+	}
+}
+
+func (t *replicaFreezeTracker) run(ctx context.Context) {
+	// Track the freezes made by the previous leader as well.
+	for _, freeze := range t.cluster.GetReplicaFreezes() {
+		t.track(freeze)
+	}
+	ticker := time.NewTicker(replicaFreezeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			t.check()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// FreezeReplicas freezes the replicas of the regions in the key range for the
+// ttl. Before they are thawed, the checkers skip the regions and the operators
+// changing their peers are rejected, but the leaders can still be transferred.
+// Freezing with an existing ID replaces the previous one.
+func (c *RaftCluster) FreezeReplicas(id string, startKey, endKey []byte, ttl time.Duration) (*ReplicaFreeze, error) {
+	if ttl <= 0 {
+		return nil, errors.Errorf("invalid ttl %v", ttl)
+	}
+	rule := &labeler.LabelRule{
+		ID:       ReplicaFreezeRulePrefix + id,
+		Labels:   []labeler.RegionLabel{{Key: labeler.ReplicaOptionLabel, Value: labeler.ReplicaOptionValueFreeze, TTL: ttl.String()}},
+		RuleType: labeler.KeyRange,
+		Data:     []interface{}{map[string]interface{}{"start_key": hex.EncodeToString(startKey), "end_key": hex.EncodeToString(endKey)}},
+	}
+	if err := c.regionLabeler.SetLabelRule(rule); err != nil {
+		return nil, err
+	}
+	freeze := newReplicaFreeze(rule)
+	c.replicaFreezes.track(freeze)
+	log.Info("replicas are frozen",
+		zap.String("id", id),
+		zap.String("start-key", freeze.StartKey),
+		zap.String("end-key", freeze.EndKey),
+		zap.Time("expire-at", freeze.ExpireAt))
+	return freeze, nil
+}
+
+// ThawReplicas thaws the replicas frozen by the ID before the freeze expires.
+func (c *RaftCluster) ThawReplicas(id string) error {
+	if err := c.regionLabeler.DeleteLabelRule(ReplicaFreezeRulePrefix + id); err != nil {
+		return err
+	}
+	c.replicaFreezes.untrack(id)
+	log.Info("replicas are thawed", zap.String("id", id))
+	return nil
+}
+
+// GetReplicaFreezes returns the replica freezes which are not expired.
+func (c *RaftCluster) GetReplicaFreezes() []*ReplicaFreeze {
+	freezes := make([]*ReplicaFreeze, 0)
+	for _, rule := range c.regionLabeler.GetAllLabelRules() {
+		if freeze := newReplicaFreeze(rule); freeze != nil {
+			freezes = append(freezes, freeze)
+		}
+	}
+	sort.Slice(freezes, func(i, j int) bool { return freezes[i].ID < freezes[j].ID })
+	return freezes
+}
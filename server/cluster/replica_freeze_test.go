@@ -0,0 +1,81 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+func TestReplicaFreeze(t *testing.T) {
+	re := require.New(t)
+	tc, co, cleanup := prepare(nil, nil, nil, re)
+	defer cleanup()
+
+	for i := uint64(1); i <= 4; i++ {
+		re.NoError(tc.addRegionStore(i, 0))
+	}
+	// Both regions miss a peer.
+	re.NoError(tc.addLeaderRegion(1, 1, 2))
+	re.NoError(tc.addLeaderRegion(2, 1, 2))
+
+	meta := newTestRegionMeta(1)
+	freeze, err := tc.FreezeReplicas("br", meta.GetStartKey(), meta.GetEndKey(), time.Minute)
+	re.NoError(err)
+	re.Equal("br", freeze.ID)
+	re.True(freeze.ExpireAt.After(time.Now()))
+	freezes := tc.GetReplicaFreezes()
+	re.Len(freezes, 1)
+	re.Equal(*freeze, *freezes[0])
+
+	// The checkers skip the frozen region.
+	checkRegionAndOperator(re, tc, co, 1, 0)
+	checkRegionAndOperator(re, tc, co, 2, 1)
+
+	// The operators changing the peers are rejected even if they are created by the admin.
+	region := tc.GetRegion(1)
+	for _, kind := range []operator.OpKind{operator.OpRegion, operator.OpAdmin | operator.OpRegion} {
+		op := newTestOperator(1, region.GetRegionEpoch(), kind, operator.AddPeer{ToStore: 3, PeerID: 100})
+		re.False(co.opController.AddOperator(op))
+	}
+	// But the leader can be transferred.
+	op := newTestOperator(1, region.GetRegionEpoch(), operator.OpLeader, operator.TransferLeader{FromStore: 1, ToStore: 2})
+	re.True(co.opController.AddOperator(op))
+
+	re.NoError(tc.ThawReplicas("br"))
+	re.Empty(tc.GetReplicaFreezes())
+	re.Error(tc.ThawReplicas("br"))
+	_, err = tc.FreezeReplicas("br", nil, nil, 0)
+	re.Error(err)
+	// Thawing manually doesn't publish any event.
+	tc.replicaFreezes.check()
+	re.Empty(tc.GetClusterEvents(0))
+
+	// The event is published once the freeze expires.
+	_, err = tc.FreezeReplicas("restore", nil, nil, time.Second)
+	re.NoError(err)
+	testutil.Eventually(re, func() bool {
+		tc.replicaFreezes.check()
+		return len(tc.GetClusterEvents(0)) == 1
+	})
+	event := tc.GetClusterEvents(0)[0]
+	re.Equal(EventReplicaFreezeExpired, event.Type)
+	re.Equal("restore", event.Attributes["id"])
+	re.Empty(tc.GetReplicaFreezes())
+}
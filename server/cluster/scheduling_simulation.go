@@ -0,0 +1,148 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/storage"
+)
+
+// SchedulerImpact is the operator volume of a scheduler before and after a
+// proposed config or rule change.
+type SchedulerImpact struct {
+	Name               string `json:"name"`
+	CurrentOperators   int    `json:"current_operators"`
+	SimulatedOperators int    `json:"simulated_operators"`
+	Diff               int    `json:"diff"`
+}
+
+// simulationCluster is a cacheCluster with the config and rules replaced
+// virtually, so that the schedulers can run against it without affecting the
+// real cluster.
+type simulationCluster struct {
+	*cacheCluster
+	opt         *config.PersistOptions
+	ruleManager *placement.RuleManager
+}
+
+// GetOpts returns the virtual options.
+func (c *simulationCluster) GetOpts() *config.PersistOptions {
+	return c.opt
+}
+
+// GetRuleManager returns the virtual rule manager.
+func (c *simulationCluster) GetRuleManager() *placement.RuleManager {
+	return c.ruleManager
+}
+
+// SimulateScheduling runs all schedulers in dry-run mode with the proposed
+// schedule config and rules applied virtually, and returns the diff in operator
+// volume per scheduler. A nil scheduleCfg keeps the current schedule config.
+func (c *RaftCluster) SimulateScheduling(scheduleCfg *config.ScheduleConfig, rules []*placement.Rule) ([]*SchedulerImpact, error) {
+	sim, err := c.newSimulationCluster(scheduleCfg, rules)
+	if err != nil {
+		return nil, err
+	}
+	return c.coordinator.simulateSchedulers(sim)
+}
+
+func (c *RaftCluster) newSimulationCluster(scheduleCfg *config.ScheduleConfig, rules []*placement.Rule) (*simulationCluster, error) {
+	if scheduleCfg == nil {
+		scheduleCfg = c.opt.GetScheduleConfig().Clone()
+	} else if err := scheduleCfg.Validate(); err != nil {
+		return nil, err
+	}
+	opt := config.NewPersistOptions(&config.Config{
+		Schedule:        *scheduleCfg,
+		Replication:     *c.opt.GetReplicationConfig().Clone(),
+		PDServerCfg:     *c.opt.GetPDServerConfig().Clone(),
+		ReplicationMode: *c.opt.GetReplicationModeConfig().Clone(),
+		LabelProperty:   c.opt.GetLabelPropertyConfig().Clone(),
+		ClusterVersion:  *c.opt.GetClusterVersion(),
+	})
+
+	ruleManager := c.ruleManager
+	if len(rules) > 0 {
+		if !opt.IsPlacementRulesEnabled() {
+			return nil, errs.ErrRuleContent.FastGenByArgs("placement rules feature is disabled")
+		}
+		// Copy the current rules to a rule manager with the memory storage.
+		ruleManager = placement.NewRuleManager(storage.NewStorageWithMemoryBackend(), c, opt)
+		if err := ruleManager.Initialize(opt.GetMaxReplicas(), opt.GetLocationLabels()); err != nil {
+			return nil, err
+		}
+		if err := ruleManager.SetAllGroupBundles(c.ruleManager.GetAllGroupBundles(), true); err != nil {
+			return nil, err
+		}
+		if err := ruleManager.SetRules(rules); err != nil {
+			return nil, err
+		}
+	}
+	return &simulationCluster{
+		cacheCluster: newCacheCluster(c),
+		opt:          opt,
+		ruleManager:  ruleManager,
+	}, nil
+}
+
+// simulateSchedulers runs all schedulers in dry-run mode against both the real
+// cluster and the simulation cluster. The running schedulers are not touched,
+// instead each side runs a fresh clone of the scheduler, so that both sides start
+// from the same scheduler state and see the same snapshot of the stores.
+func (c *coordinator) simulateSchedulers(sim *simulationCluster) ([]*SchedulerImpact, error) {
+	c.RLock()
+	schedulers := make([]schedule.Scheduler, 0, len(c.schedulers))
+	for _, s := range c.schedulers {
+		schedulers = append(schedulers, s.Scheduler)
+	}
+	c.RUnlock()
+	sort.Slice(schedulers, func(i, j int) bool { return schedulers[i].GetName() < schedulers[j].GetName() })
+
+	impacts := make([]*SchedulerImpact, 0, len(schedulers))
+	for _, s := range schedulers {
+		current, err := c.cloneScheduler(s)
+		if err != nil {
+			return nil, err
+		}
+		simulated, err := c.cloneScheduler(s)
+		if err != nil {
+			return nil, err
+		}
+		currentOps, _ := current.Schedule(sim.cacheCluster, true)
+		simulatedOps, _ := simulated.Schedule(sim, true)
+		impacts = append(impacts, &SchedulerImpact{
+			Name:               s.GetName(),
+			CurrentOperators:   len(currentOps),
+			SimulatedOperators: len(simulatedOps),
+			Diff:               len(simulatedOps) - len(currentOps),
+		})
+	}
+	return impacts, nil
+}
+
+// cloneScheduler creates a scheduler with the same config as the given one, its
+// config is saved to the memory storage instead of the real one.
+func (c *coordinator) cloneScheduler(s schedule.Scheduler) (schedule.Scheduler, error) {
+	data, err := s.EncodeConfig()
+	if err != nil {
+		return nil, err
+	}
+	return schedule.CreateScheduler(s.GetType(), c.opController, storage.NewStorageWithMemoryBackend(), schedule.ConfigJSONDecoder(data))
+}
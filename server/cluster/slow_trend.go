@@ -0,0 +1,108 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+)
+
+const (
+	slowTrendCauseValue  = "cause_value"
+	slowTrendCauseRate   = "cause_rate"
+	slowTrendResultValue = "result_value"
+	slowTrendResultRate  = "result_rate"
+)
+
+// slowTrendDetector keeps the series of the slow trends reported by the store
+// heartbeats in the window.
+type slowTrendDetector struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	stores  map[uint64][]core.SlowTrendSample
+}
+
+func newSlowTrendDetector(cluster *RaftCluster) *slowTrendDetector {
+	return &slowTrendDetector{
+		cluster: cluster,
+		stores:  make(map[uint64][]core.SlowTrendSample),
+	}
+}
+
+// observe records the slow trend reported by the store heartbeat, and returns it.
+// It returns nil if the store does not report the slow trend, e.g. an older TiKV.
+func (d *slowTrendDetector) observe(store *core.StoreInfo, now time.Time) *core.SlowTrend {
+	d.Lock()
+	defer d.Unlock()
+	storeID := store.GetID()
+	storeLabel := strconv.FormatUint(storeID, 10)
+	reported := store.GetStoreStats().GetSlowTrend()
+	if reported == nil {
+		delete(d.stores, storeID)
+		deleteSlowTrendMetrics(storeLabel)
+		return nil
+	}
+	trend := &core.SlowTrend{
+		CauseValue:  reported.GetCauseValue(),
+		CauseRate:   reported.GetCauseRate(),
+		ResultValue: reported.GetResultValue(),
+		ResultRate:  reported.GetResultRate(),
+	}
+	samples := append(d.stores[storeID], core.SlowTrendSample{Time: now, SlowTrend: trend})
+	window := d.cluster.opt.GetSlowTrendWindow()
+	for len(samples) > 1 && now.Sub(samples[0].Time) > window {
+		samples = samples[1:]
+	}
+	d.stores[storeID] = samples
+
+	storeSlowTrendGauge.WithLabelValues(storeLabel, slowTrendCauseValue).Set(trend.CauseValue)
+	storeSlowTrendGauge.WithLabelValues(storeLabel, slowTrendCauseRate).Set(trend.CauseRate)
+	storeSlowTrendGauge.WithLabelValues(storeLabel, slowTrendResultValue).Set(trend.ResultValue)
+	storeSlowTrendGauge.WithLabelValues(storeLabel, slowTrendResultRate).Set(trend.ResultRate)
+	return trend
+}
+
+// series returns the slow trends reported by the store in the window.
+func (d *slowTrendDetector) series(storeID uint64) []core.SlowTrendSample {
+	d.Lock()
+	defer d.Unlock()
+	samples := d.stores[storeID]
+	if len(samples) == 0 {
+		return nil
+	}
+	return append([]core.SlowTrendSample(nil), samples...)
+}
+
+// GetStoreSlowTrendSeries returns the slow trends reported by the store in the window.
+func (c *RaftCluster) GetStoreSlowTrendSeries(storeID uint64) []core.SlowTrendSample {
+	return c.slowTrend.series(storeID)
+}
+
+// forget removes the series of the store.
+func (d *slowTrendDetector) forget(storeID uint64) {
+	d.Lock()
+	defer d.Unlock()
+	delete(d.stores, storeID)
+	deleteSlowTrendMetrics(strconv.FormatUint(storeID, 10))
+}
+
+func deleteSlowTrendMetrics(storeLabel string) {
+	for _, typ := range []string{slowTrendCauseValue, slowTrendCauseRate, slowTrendResultValue, slowTrendResultRate} {
+		storeSlowTrendGauge.DeleteLabelValues(storeLabel, typ)
+	}
+}
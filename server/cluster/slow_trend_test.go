@@ -0,0 +1,69 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestSlowTrendDetector(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	store := newTestStores(1, "6.0.0")[0]
+	observe := func(now time.Time, trend *pdpb.SlowTrend) *core.SlowTrend {
+		stats := &pdpb.StoreStats{StoreId: 1, SlowTrend: trend}
+		return cluster.slowTrend.observe(store.Clone(core.SetStoreStats(stats)), now)
+	}
+
+	// The store does not report the slow trend.
+	start := time.Now()
+	re.Nil(observe(start, nil))
+	re.Nil(cluster.GetStoreSlowTrendSeries(1))
+
+	trend := observe(start, &pdpb.SlowTrend{CauseValue: 4, CauseRate: 0.1, ResultValue: 70, ResultRate: -1})
+	re.Equal(&core.SlowTrend{CauseValue: 4, CauseRate: 0.1, ResultValue: 70, ResultRate: -1}, trend)
+	observe(start.Add(time.Minute), &pdpb.SlowTrend{CauseValue: 5, CauseRate: 0.1, ResultValue: 60, ResultRate: -1})
+	series := cluster.GetStoreSlowTrendSeries(1)
+	re.Len(series, 2)
+	re.Equal(start, series[0].Time)
+	re.Equal(5.0, series[1].CauseValue)
+
+	// The old samples are dropped.
+	trend = observe(start.Add(10*time.Minute), &pdpb.SlowTrend{CauseValue: 1, CauseRate: -0.1, ResultValue: 100, ResultRate: 1})
+	re.Less(trend.CauseRate, 0.0)
+	re.Len(cluster.GetStoreSlowTrendSeries(1), 1)
+
+	// The series is dropped once the store stops reporting the slow trend.
+	re.Nil(observe(start.Add(11*time.Minute), nil))
+	re.Empty(cluster.slowTrend.stores)
+
+	// The store is forgotten after it is buried.
+	observe(start.Add(12*time.Minute), &pdpb.SlowTrend{CauseValue: 1})
+	cluster.slowTrend.forget(1)
+	re.Empty(cluster.slowTrend.stores)
+}
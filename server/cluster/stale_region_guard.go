@@ -0,0 +1,170 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/statistics"
+	"go.uber.org/zap"
+)
+
+// RegionCacheStaleness is the staleness of the region cache found by the last check.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type RegionCacheStaleness struct {
+	// Stale is true if the ratio of the stale regions exceeds the threshold, then
+	// the schedulers and the non-repair checkers stop generating operators.
+	Stale        bool       `json:"stale"`
+	StaleRegions int        `json:"stale_regions"`
+	TotalRegions int        `json:"total_regions"`
+	StaleRatio   float64    `json:"stale_ratio"`
+	Threshold    float64    `json:"threshold"`
+	StaleSince   *time.Time `json:"stale_since,omitempty"`
+	CheckedAt    time.Time  `json:"checked_at"`
+}
+
+// staleRegionGuard detects the stale region cache, e.g. the leader is partitioned
+// from most stores, so that the harmful operators are not generated from the
+// outdated regions.
+type staleRegionGuard struct {
+	syncutil.RWMutex
+	cluster *RaftCluster
+	status  RegionCacheStaleness
+
+	// lastHeartbeats records when PD received the last heartbeat of each region.
+	// The interval reported by the heartbeat can't be used, as the region cache
+	// isn't updated by the heartbeats which change nothing.
+	heartbeatMu    syncutil.Mutex
+	lastHeartbeats map[uint64]time.Time
+}
+
+func newStaleRegionGuard(cluster *RaftCluster) *staleRegionGuard {
+	return &staleRegionGuard{
+		cluster:        cluster,
+		lastHeartbeats: make(map[uint64]time.Time),
+	}
+}
+
+// observe records the heartbeat of the region received at the given time.
+func (g *staleRegionGuard) observe(regionID uint64, now time.Time) {
+	g.heartbeatMu.Lock()
+	defer g.heartbeatMu.Unlock()
+	g.lastHeartbeats[regionID] = now
+}
+
+// isStaleRegion returns true if the region has no heartbeat within the timeout.
+// The regions loaded from the storage have never sent heartbeats to the leader.
+func isStaleRegion(lastHeartbeat time.Time, now time.Time, timeout time.Duration) bool {
+	return lastHeartbeat.IsZero() || now.Sub(lastHeartbeat) > timeout
+}
+
+func (g *staleRegionGuard) check(now time.Time) {
+	opt := g.cluster.GetOpts()
+	threshold := opt.GetStaleRegionRatioThreshold()
+	status := RegionCacheStaleness{Threshold: threshold, CheckedAt: now}
+	if threshold > 0 {
+		timeout := time.Duration(opt.GetStaleRegionHeartbeatIntervals()*statistics.RegionHeartBeatReportInterval) * time.Second
+		regions := g.cluster.core.GetRegions()
+		g.heartbeatMu.Lock()
+		for _, region := range regions {
+			status.TotalRegions++
+			if isStaleRegion(g.lastHeartbeats[region.GetID()], now, timeout) {
+				status.StaleRegions++
+			}
+		}
+		// forget the regions removed from the cache, e.g. merged
+		if len(g.lastHeartbeats) > len(regions) {
+			for regionID := range g.lastHeartbeats {
+				if g.cluster.core.GetRegion(regionID) == nil {
+					delete(g.lastHeartbeats, regionID)
+				}
+			}
+		}
+		g.heartbeatMu.Unlock()
+		if status.TotalRegions > 0 {
+			status.StaleRatio = float64(status.StaleRegions) / float64(status.TotalRegions)
+		}
+		status.Stale = status.StaleRatio > threshold
+	}
+	staleRegionRatioGauge.Set(status.StaleRatio)
+
+	g.Lock()
+	wasStale := g.status.Stale
+	if status.Stale {
+		status.StaleSince = g.status.StaleSince
+		if !wasStale {
+			status.StaleSince = &now
+		}
+	}
+	g.status = status
+	g.Unlock()
+
+	if status.Stale == wasStale {
+		return
+	}
+	event := &ClusterEvent{
+		Time: now,
+		Attributes: map[string]string{
+			"stale-regions": strconv.Itoa(status.StaleRegions),
+			"total-regions": strconv.Itoa(status.TotalRegions),
+		},
+	}
+	if status.Stale {
+		log.Warn("region cache is stale, stop generating operators except for repairing the replicas",
+			zap.Int("stale-regions", status.StaleRegions), zap.Int("total-regions", status.TotalRegions), zap.Float64("threshold", threshold))
+		event.Type = EventRegionCacheStale
+		event.Message = fmt.Sprintf("%d of %d regions have no recent heartbeats, the scheduling is paused", status.StaleRegions, status.TotalRegions)
+	} else {
+		log.Info("region cache is recovered from staleness",
+			zap.Int("stale-regions", status.StaleRegions), zap.Int("total-regions", status.TotalRegions), zap.Float64("threshold", threshold))
+		event.Type = EventRegionCacheRecovered
+		event.Message = "the region cache is recovered, the scheduling is resumed"
+	}
+	g.cluster.events.publish(event)
+}
+
+func (g *staleRegionGuard) isStale() bool {
+	g.RLock()
+	defer g.RUnlock()
+	return g.status.Stale
+}
+
+func (g *staleRegionGuard) getStatus() *RegionCacheStaleness {
+	g.RLock()
+	defer g.RUnlock()
+	status := g.status
+	return &status
+}
+
+// isRepairOperator returns true if the operator repairs the replicas, which is
+// still allowed when the region cache is stale.
+func isRepairOperator(op *operator.Operator) bool {
+	return op.Kind()&operator.OpReplica != 0 && op.Kind()&operator.OpMerge == 0
+}
+
+// IsRegionCacheStale returns true if too many regions have no recent heartbeats.
+func (c *RaftCluster) IsRegionCacheStale() bool {
+	return c.staleRegions.isStale()
+}
+
+// GetRegionCacheStaleness returns the staleness of the region cache found by the last check.
+func (c *RaftCluster) GetRegionCacheStaleness() *RegionCacheStaleness {
+	return c.staleRegions.getStatus()
+}
@@ -0,0 +1,91 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestStaleRegionGuard(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	regions := newTestRegions(10, 3, 3)
+	for _, region := range regions {
+		re.NoError(cluster.putRegion(region))
+	}
+
+	// The guard is disabled by default.
+	now := time.Now()
+	cluster.staleRegions.check(now)
+	re.False(cluster.IsRegionCacheStale())
+	re.Empty(cluster.GetClusterEvents(0))
+
+	cfg := opt.GetScheduleConfig().Clone()
+	cfg.StaleRegionRatioThreshold = 0.5
+	opt.SetScheduleConfig(cfg)
+
+	// None of the regions has sent heartbeats.
+	cluster.staleRegions.check(now)
+	re.True(cluster.IsRegionCacheStale())
+	status := cluster.GetRegionCacheStaleness()
+	re.Equal(10, status.StaleRegions)
+	re.Equal(10, status.TotalRegions)
+	re.Equal(now, *status.StaleSince)
+	cluster.staleRegions.check(now.Add(time.Minute))
+	re.Equal(now, *cluster.GetRegionCacheStaleness().StaleSince)
+
+	// Only 4 of the regions are stale after the heartbeats, which change nothing
+	// in the region cache.
+	for _, region := range regions[:6] {
+		re.NoError(cluster.processRegionHeartbeat(region))
+	}
+	cluster.staleRegions.check(now.Add(time.Minute))
+	re.False(cluster.IsRegionCacheStale())
+	status = cluster.GetRegionCacheStaleness()
+	re.Equal(4, status.StaleRegions)
+	re.Nil(status.StaleSince)
+
+	// The heartbeats are outdated 3 intervals later.
+	cluster.staleRegions.check(now.Add(4 * time.Minute))
+	re.True(cluster.IsRegionCacheStale())
+
+	events := cluster.GetClusterEvents(0)
+	re.Len(events, 3)
+	re.Equal(EventRegionCacheStale, events[0].Type)
+	re.Equal(EventRegionCacheRecovered, events[1].Type)
+	re.Equal("4", events[1].Attributes["stale-regions"])
+	re.Equal(EventRegionCacheStale, events[2].Type)
+}
+
+func TestIsRepairOperator(t *testing.T) {
+	re := require.New(t)
+	re.True(isRepairOperator(operator.NewTestOperator(1, nil, operator.OpReplica|operator.OpRegion)))
+	re.False(isRepairOperator(operator.NewTestOperator(1, nil, operator.OpBalance|operator.OpRegion)))
+	re.False(isRepairOperator(operator.NewTestOperator(1, nil, operator.OpMerge|operator.OpReplica)))
+	re.False(isRepairOperator(operator.NewTestOperator(1, nil, operator.OpLeader)))
+}
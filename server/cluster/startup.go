@@ -0,0 +1,184 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/pkg/typeutil"
+	"go.uber.org/zap"
+)
+
+// The states of the subsystems during the startup of the cluster.
+const (
+	// SubsystemPending means the subsystem is waiting for its dependencies.
+	SubsystemPending = "pending"
+	// SubsystemRunning means the subsystem passes its health gate and is started.
+	SubsystemRunning = "running"
+	// SubsystemFailed means the health gate of the subsystem fails.
+	SubsystemFailed = "failed"
+	// SubsystemSkipped means the subsystem is disabled, or its dependencies are not running.
+	SubsystemSkipped = "skipped"
+)
+
+// SubsystemStatus is the startup status of a subsystem of the cluster.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type SubsystemStatus struct {
+	Name      string   `json:"name"`
+	DependsOn []string `json:"depends-on,omitempty"`
+	// Optional means the subsystem can be disabled, and its failure does not fail the startup.
+	Optional bool   `json:"optional"`
+	State    string `json:"state"`
+	Error    string `json:"error,omitempty"`
+	// Elapsed is the time spent on the health gate of the subsystem.
+	Elapsed typeutil.Duration `json:"elapsed"`
+}
+
+// subsystem is a part of the cluster started in the order of its dependencies.
+type subsystem struct {
+	name     string
+	deps     []string
+	optional bool
+	// gate initializes the subsystem and checks whether it is healthy to start,
+	// nil means the subsystem is always healthy.
+	gate func() error
+	// run is the background job of the subsystem, nil means no job.
+	run func()
+}
+
+// startupSequence starts the subsystems of the cluster. The health gates of all
+// the subsystems are checked in the order of their dependencies before any job
+// is run, so a failed startup leaves no job running.
+type startupSequence struct {
+	syncutil.RWMutex
+	subsystems []*subsystem
+	statuses   []*SubsystemStatus
+}
+
+func newStartupSequence() *startupSequence {
+	return &startupSequence{}
+}
+
+// reset clears the subsystems registered by the last startup.
+func (s *startupSequence) reset() {
+	s.Lock()
+	defer s.Unlock()
+	s.subsystems, s.statuses = nil, nil
+}
+
+// register adds a subsystem, its dependencies must be registered before it.
+func (s *startupSequence) register(sub *subsystem) {
+	s.Lock()
+	defer s.Unlock()
+	s.subsystems = append(s.subsystems, sub)
+	s.statuses = append(s.statuses, &SubsystemStatus{
+		Name:      sub.name,
+		DependsOn: sub.deps,
+		Optional:  sub.optional,
+		State:     SubsystemPending,
+	})
+}
+
+// start checks the health gates of the subsystems, then runs the jobs of the
+// healthy ones with the given function. The optional subsystems in disabled
+// are skipped. It returns the error of the first required subsystem failing.
+func (s *startupSequence) start(disabled []string, runJob func(func())) error {
+	disabledSet := make(map[string]struct{}, len(disabled))
+	for _, name := range disabled {
+		disabledSet[name] = struct{}{}
+	}
+	s.RLock()
+	subsystems := s.subsystems
+	s.RUnlock()
+
+	states := make(map[string]string, len(subsystems))
+	for i, sub := range subsystems {
+		state, reason, elapsed := s.check(sub, states, disabledSet)
+		states[sub.name] = state
+		s.setStatus(i, state, reason, elapsed)
+		switch state {
+		case SubsystemFailed:
+			log.Error("subsystem fails to start", zap.String("subsystem", sub.name), zap.String("reason", reason))
+			if !sub.optional {
+				return errs.ErrSubsystemStart.FastGenByArgs(sub.name, reason)
+			}
+		case SubsystemSkipped:
+			log.Warn("subsystem is skipped", zap.String("subsystem", sub.name), zap.String("reason", reason))
+			if !sub.optional {
+				return errs.ErrSubsystemStart.FastGenByArgs(sub.name, reason)
+			}
+		}
+	}
+	for _, sub := range subsystems {
+		if states[sub.name] == SubsystemRunning && sub.run != nil {
+			runJob(sub.run)
+		}
+	}
+	return nil
+}
+
+func (s *startupSequence) check(sub *subsystem, states map[string]string, disabled map[string]struct{}) (state, reason string, elapsed time.Duration) {
+	if _, ok := disabled[sub.name]; ok {
+		if sub.optional {
+			return SubsystemSkipped, "disabled by the config", 0
+		}
+		log.Warn("the required subsystem cannot be disabled", zap.String("subsystem", sub.name))
+	}
+	for _, dep := range sub.deps {
+		if states[dep] != SubsystemRunning {
+			return SubsystemSkipped, fmt.Sprintf("dependency %s is not running", dep), 0
+		}
+	}
+	if sub.gate == nil {
+		return SubsystemRunning, "", 0
+	}
+	start := time.Now()
+	err := sub.gate()
+	elapsed = time.Since(start)
+	if err != nil {
+		return SubsystemFailed, errors.Cause(err).Error(), elapsed
+	}
+	return SubsystemRunning, "", elapsed
+}
+
+func (s *startupSequence) setStatus(i int, state, reason string, elapsed time.Duration) {
+	s.Lock()
+	defer s.Unlock()
+	s.statuses[i].State = state
+	s.statuses[i].Error = reason
+	s.statuses[i].Elapsed = typeutil.NewDuration(elapsed)
+}
+
+// getStatuses returns the statuses of the subsystems in the startup order.
+func (s *startupSequence) getStatuses() []SubsystemStatus {
+	s.RLock()
+	defer s.RUnlock()
+	statuses := make([]SubsystemStatus, 0, len(s.statuses))
+	for _, status := range s.statuses {
+		statuses = append(statuses, *status)
+	}
+	return statuses
+}
+
+// GetStartupStatus returns the startup status of the subsystems of the cluster,
+// which is kept even if the startup fails.
+func (c *RaftCluster) GetStartupStatus() []SubsystemStatus {
+	return c.startup.getStatuses()
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestStartupSequence(t *testing.T) {
+	re := require.New(t)
+	var ran []string
+	runJob := func(run func()) { run() }
+	job := func(name string) func() {
+		return func() { ran = append(ran, name) }
+	}
+
+	s := newStartupSequence()
+	s.register(&subsystem{name: "a", run: job("a")})
+	s.register(&subsystem{name: "b", deps: []string{"a"}, optional: true, gate: func() error { return errors.New("unhealthy") }, run: job("b")})
+	s.register(&subsystem{name: "c", deps: []string{"b"}, optional: true, run: job("c")})
+	s.register(&subsystem{name: "d", deps: []string{"a"}, optional: true, run: job("d")})
+	s.register(&subsystem{name: "e", deps: []string{"a"}, optional: true, run: job("e")})
+	re.NoError(s.start([]string{"e"}, runJob))
+	re.Equal([]string{"a", "d"}, ran)
+
+	statuses := s.getStatuses()
+	re.Len(statuses, 5)
+	re.Equal(SubsystemRunning, statuses[0].State)
+	re.Equal(SubsystemFailed, statuses[1].State)
+	re.Equal("unhealthy", statuses[1].Error)
+	re.Equal(SubsystemSkipped, statuses[2].State)
+	re.Contains(statuses[2].Error, "dependency b")
+	re.Equal(SubsystemRunning, statuses[3].State)
+	re.Equal(SubsystemSkipped, statuses[4].State)
+
+	// a failed required subsystem fails the startup, and no job is run.
+	ran = nil
+	s.reset()
+	s.register(&subsystem{name: "a", run: job("a")})
+	s.register(&subsystem{name: "b", deps: []string{"a"}, gate: func() error { return errors.New("unhealthy") }})
+	s.register(&subsystem{name: "c", deps: []string{"b"}, run: job("c")})
+	// the required subsystems cannot be disabled.
+	re.Error(s.start([]string{"a"}, runJob))
+	re.Empty(ran)
+	statuses = s.getStatuses()
+	re.Equal(SubsystemRunning, statuses[0].State)
+	re.Equal(SubsystemFailed, statuses[1].State)
+	re.Equal(SubsystemPending, statuses[2].State)
+}
@@ -0,0 +1,202 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+)
+
+type statisticsTaskKind int
+
+const (
+	observeRegionStatsTask statisticsTaskKind = iota
+	observeLabelLevelStatsTask
+	clearDefunctRegionStatsTask
+)
+
+type statisticsTask struct {
+	kind       statisticsTaskKind
+	region     *core.RegionInfo
+	regionID   uint64
+	createTime time.Time
+}
+
+type statisticsTaskKey struct {
+	kind     statisticsTaskKind
+	regionID uint64
+}
+
+func (t *statisticsTask) key() statisticsTaskKey {
+	return statisticsTaskKey{kind: t.kind, regionID: t.regionID}
+}
+
+// statisticsObserver updates the region statistics in the background, so that
+// the heartbeats don't need to do it under the cluster lock. The tasks are
+// queued in the order of the heartbeats and applied one by one, which makes the
+// statistics eventually consistent with the regions in the cache.
+//
+// The pending tasks are coalesced by region, so the queue is bounded by the
+// number of regions and a backed-up queue always applies the latest region
+// info instead of the stale ones.
+type statisticsObserver struct {
+	cluster *RaftCluster
+	notify  chan struct{}
+	// applyMu makes the tasks applied in order no matter which goroutine drains
+	// the queue.
+	applyMu syncutil.Mutex
+	mu      struct {
+		syncutil.Mutex
+		tasks []*statisticsTask
+		// pending indexes the queued tasks which have not been superseded.
+		pending map[statisticsTaskKey]*statisticsTask
+	}
+}
+
+func newStatisticsObserver(cluster *RaftCluster) *statisticsObserver {
+	o := &statisticsObserver{
+		cluster: cluster,
+		notify:  make(chan struct{}, 1),
+	}
+	o.mu.pending = make(map[statisticsTaskKey]*statisticsTask)
+	return o
+}
+
+// observeRegion queues the region to update its statistics. The region info is
+// immutable, so it is safe to observe it after the cluster lock is released.
+func (o *statisticsObserver) observeRegion(region *core.RegionInfo) {
+	o.push(&statisticsTask{kind: observeRegionStatsTask, region: region, regionID: region.GetID(), createTime: time.Now()})
+}
+
+// observeLabelLevel queues the regions to update their label level statistics.
+func (o *statisticsObserver) observeLabelLevel(regions []*core.RegionInfo) {
+	now := time.Now()
+	tasks := make([]*statisticsTask, 0, len(regions))
+	for _, region := range regions {
+		tasks = append(tasks, &statisticsTask{kind: observeLabelLevelStatsTask, region: region, regionID: region.GetID(), createTime: now})
+	}
+	o.push(tasks...)
+}
+
+// clearDefunctRegion queues the overlapped region to remove its statistics.
+func (o *statisticsObserver) clearDefunctRegion(regionID uint64) {
+	o.push(&statisticsTask{kind: clearDefunctRegionStatsTask, regionID: regionID, createTime: time.Now()})
+}
+
+func (o *statisticsObserver) push(tasks ...*statisticsTask) {
+	if len(tasks) == 0 {
+		return
+	}
+	o.mu.Lock()
+	for _, task := range tasks {
+		o.enqueueLocked(task)
+	}
+	statisticsObserverPendingGauge.Set(float64(len(o.mu.pending)))
+	o.mu.Unlock()
+	select {
+	case o.notify <- struct{}{}:
+	default:
+	}
+}
+
+// enqueueLocked queues the task unless it can be merged into a pending one.
+// An observing task replaces the region of the pending task of the same kind
+// and keeps its position, which is safe because nothing queued after it can
+// touch the same region: a clearing task removes the pending observing tasks of
+// the region, so the region observed again after being cleared gets a new task.
+func (o *statisticsObserver) enqueueLocked(task *statisticsTask) {
+	if task.kind == clearDefunctRegionStatsTask {
+		for _, kind := range []statisticsTaskKind{observeRegionStatsTask, observeLabelLevelStatsTask} {
+			observeKey := statisticsTaskKey{kind: kind, regionID: task.regionID}
+			if pending, ok := o.mu.pending[observeKey]; ok {
+				// The superseded task stays in the queue but is skipped.
+				pending.region = nil
+				delete(o.mu.pending, observeKey)
+			}
+		}
+	}
+	key := task.key()
+	if pending, ok := o.mu.pending[key]; ok {
+		pending.region = task.region
+		return
+	}
+	o.mu.pending[key] = task
+	o.mu.tasks = append(o.mu.tasks, task)
+	// Drop the skipped tasks once they take up half of the queue.
+	if len(o.mu.tasks) > 2*len(o.mu.pending) {
+		tasks := o.mu.tasks[:0]
+		for _, t := range o.mu.tasks {
+			if t.kind == clearDefunctRegionStatsTask || t.region != nil {
+				tasks = append(tasks, t)
+			}
+		}
+		o.mu.tasks = tasks
+	}
+}
+
+// flush applies all the queued tasks. It is also called before reading the
+// statistics, so that the readers can see the results of the heartbeats which
+// have been handled.
+func (o *statisticsObserver) flush() {
+	o.applyMu.Lock()
+	defer o.applyMu.Unlock()
+	o.mu.Lock()
+	tasks := o.mu.tasks
+	o.mu.tasks = nil
+	o.mu.pending = make(map[statisticsTaskKey]*statisticsTask)
+	statisticsObserverPendingGauge.Set(0)
+	o.mu.Unlock()
+	if len(tasks) == 0 {
+		return
+	}
+	statisticsObserverLagGauge.Set(time.Since(tasks[0].createTime).Seconds())
+	for _, task := range tasks {
+		o.apply(task)
+	}
+}
+
+func (o *statisticsObserver) apply(task *statisticsTask) {
+	c := o.cluster
+	if task.kind != clearDefunctRegionStatsTask && task.region == nil {
+		return
+	}
+	switch task.kind {
+	case observeRegionStatsTask:
+		c.regionStats.Observe(task.region, c.getRegionStoresLocked(task.region))
+	case observeLabelLevelStatsTask:
+		c.labelLevelStats.Observe(task.region, c.getStoresWithoutLabelLocked(task.region, core.EngineKey, core.EngineTiFlash), c.opt.GetLocationLabels())
+	case clearDefunctRegionStatsTask:
+		c.regionStats.ClearDefunctRegion(task.regionID)
+		c.labelLevelStats.ClearDefunctRegion(task.regionID)
+	}
+}
+
+// run applies the queued tasks until the context is done. The remaining tasks
+// are applied before it returns.
+func (o *statisticsObserver) run(ctx context.Context) {
+	for {
+		select {
+		case <-o.notify:
+			o.flush()
+		case <-ctx.Done():
+			o.flush()
+			statisticsObserverLagGauge.Set(0)
+			return
+		}
+	}
+}
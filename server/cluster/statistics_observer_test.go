@@ -0,0 +1,104 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestStatisticsObserver(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	cluster.regionStats = statistics.NewRegionStatistics(cluster.GetOpts(), cluster.ruleManager, cluster.storeConfigManager)
+	cluster.statsObserver = newStatisticsObserver(cluster)
+	for _, store := range newTestStores(3, "6.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+
+	// The region misses a peer, but it is not observed until the tasks are applied.
+	regions := newTestRegions(2, 3, 2)
+	re.NoError(cluster.processRegionHeartbeat(regions[0]))
+	re.Empty(cluster.regionStats.GetRegionStatsByType(statistics.MissPeer))
+	// The readers apply the queued tasks first.
+	re.Len(cluster.GetRegionStatsByType(statistics.MissPeer), 1)
+
+	// The overlapped region is cleared in the order of the heartbeats.
+	peers := []*metapb.Peer{{Id: 10, StoreId: 0}, {Id: 11, StoreId: 1}, {Id: 12, StoreId: 2}}
+	region := core.NewRegionInfo(&metapb.Region{
+		Id:          10,
+		Peers:       peers,
+		StartKey:    regions[0].GetStartKey(),
+		EndKey:      regions[0].GetEndKey(),
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 3, Version: 3},
+	}, peers[0])
+	re.NoError(cluster.processRegionHeartbeat(region))
+	re.Empty(cluster.GetRegionStatsByType(statistics.MissPeer))
+
+	// The tasks are applied in the background once it is started.
+	go cluster.statsObserver.run(ctx)
+	re.NoError(cluster.processRegionHeartbeat(regions[1]))
+	testutil.Eventually(re, func() bool {
+		return len(cluster.regionStats.GetRegionStatsByType(statistics.MissPeer)) == 1
+	})
+}
+
+func TestStatisticsObserverCoalesce(t *testing.T) {
+	re := require.New(t)
+	o := newStatisticsObserver(nil)
+	regions := newTestRegions(2, 3, 2)
+	newer := regions[0].Clone(core.SetApproximateSize(100))
+
+	// The newer region replaces the pending one and keeps its position.
+	o.observeRegion(regions[0])
+	o.observeRegion(regions[1])
+	o.observeRegion(newer)
+	re.Len(o.mu.tasks, 2)
+	re.Same(newer, o.mu.tasks[0].region)
+	re.Len(o.mu.pending, 2)
+
+	// Clearing the region skips its pending tasks, and the region observed
+	// again after that is queued behind the clearing task.
+	o.observeLabelLevel([]*core.RegionInfo{regions[0]})
+	o.clearDefunctRegion(regions[0].GetID())
+	o.clearDefunctRegion(regions[0].GetID())
+	o.observeRegion(regions[0])
+	re.Len(o.mu.pending, 3)
+	var applied []statisticsTaskKey
+	for _, task := range o.mu.tasks {
+		if task.kind == clearDefunctRegionStatsTask || task.region != nil {
+			applied = append(applied, task.key())
+		}
+	}
+	re.Equal([]statisticsTaskKey{
+		{kind: observeRegionStatsTask, regionID: regions[1].GetID()},
+		{kind: clearDefunctRegionStatsTask, regionID: regions[0].GetID()},
+		{kind: observeRegionStatsTask, regionID: regions[0].GetID()},
+	}, applied)
+}
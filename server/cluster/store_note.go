@@ -0,0 +1,207 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"go.uber.org/zap"
+)
+
+// maxStoreNoteLength is the max length in bytes of the content of a store note.
+const maxStoreNoteLength = 4096
+
+func checkStoreNoteContent(content string) error {
+	if len(content) == 0 {
+		return errs.ErrStoreNoteContent.FastGenByArgs("content is empty")
+	}
+	if len(content) > maxStoreNoteLength {
+		return errs.ErrStoreNoteContent.FastGenByArgs("content is too long")
+	}
+	return nil
+}
+
+// AddStoreNote attaches a new note to the given store.
+func (c *RaftCluster) AddStoreNote(storeID uint64, content, author string) (*endpoint.StoreNote, error) {
+	if c.GetStore(storeID) == nil {
+		return nil, errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+	if err := checkStoreNoteContent(content); err != nil {
+		return nil, err
+	}
+
+	c.storeNotes.Lock()
+	defer c.storeNotes.Unlock()
+	if err := c.loadStoreNotesLocked(); err != nil {
+		return nil, err
+	}
+	id, err := c.id.Alloc()
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	note := &endpoint.StoreNote{
+		ID:        id,
+		StoreID:   storeID,
+		Content:   content,
+		Author:    author,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := c.storage.SaveStoreNote(note); err != nil {
+		return nil, err
+	}
+	c.storeNotes.notes[storeID] = append(c.storeNotes.notes[storeID], note)
+	return note, nil
+}
+
+// UpdateStoreNote updates the content of a store note.
+func (c *RaftCluster) UpdateStoreNote(storeID, noteID uint64, content, author string) (*endpoint.StoreNote, error) {
+	if err := checkStoreNoteContent(content); err != nil {
+		return nil, err
+	}
+
+	c.storeNotes.Lock()
+	defer c.storeNotes.Unlock()
+	idx, err := c.getStoreNoteLocked(storeID, noteID)
+	if err != nil {
+		return nil, err
+	}
+	note := *c.storeNotes.notes[storeID][idx]
+	note.Content = content
+	if len(author) > 0 {
+		note.Author = author
+	}
+	note.UpdatedAt = time.Now()
+	if err := c.storage.SaveStoreNote(&note); err != nil {
+		return nil, err
+	}
+	// Build a new slice since the old one may be held by the readers.
+	notes := append([]*endpoint.StoreNote(nil), c.storeNotes.notes[storeID]...)
+	notes[idx] = &note
+	c.storeNotes.notes[storeID] = notes
+	return &note, nil
+}
+
+// DeleteStoreNote removes a note of the given store.
+func (c *RaftCluster) DeleteStoreNote(storeID, noteID uint64) error {
+	c.storeNotes.Lock()
+	defer c.storeNotes.Unlock()
+	idx, err := c.getStoreNoteLocked(storeID, noteID)
+	if err != nil {
+		return err
+	}
+	if err := c.storage.DeleteStoreNote(storeID, noteID); err != nil {
+		return err
+	}
+	notes := c.storeNotes.notes[storeID]
+	if len(notes) == 1 {
+		delete(c.storeNotes.notes, storeID)
+		return nil
+	}
+	// Build a new slice since the old one may be held by the readers.
+	rest := make([]*endpoint.StoreNote, 0, len(notes)-1)
+	rest = append(rest, notes[:idx]...)
+	c.storeNotes.notes[storeID] = append(rest, notes[idx+1:]...)
+	return nil
+}
+
+// GetStoreNotes returns the notes of the given store in the order of creation.
+// The returned notes are shared with the cache and must not be modified.
+func (c *RaftCluster) GetStoreNotes(storeID uint64) ([]*endpoint.StoreNote, error) {
+	c.storeNotes.Lock()
+	defer c.storeNotes.Unlock()
+	if err := c.loadStoreNotesLocked(); err != nil {
+		return nil, err
+	}
+	return c.storeNotes.notes[storeID], nil
+}
+
+// GetAllStoreNotes returns the notes of all stores grouped by the store ID.
+// The returned notes are shared with the cache and must not be modified.
+func (c *RaftCluster) GetAllStoreNotes() (map[uint64][]*endpoint.StoreNote, error) {
+	c.storeNotes.Lock()
+	defer c.storeNotes.Unlock()
+	if err := c.loadStoreNotesLocked(); err != nil {
+		return nil, err
+	}
+	res := make(map[uint64][]*endpoint.StoreNote, len(c.storeNotes.notes))
+	for storeID, notes := range c.storeNotes.notes {
+		res[storeID] = notes
+	}
+	return res, nil
+}
+
+// resetStoreNotes drops the cached notes, so they are reloaded from the storage,
+// which may be updated by the previous leader, at the next access.
+func (c *RaftCluster) resetStoreNotes() {
+	c.storeNotes.Lock()
+	defer c.storeNotes.Unlock()
+	c.storeNotes.notes = nil
+}
+
+// loadStoreNotesLocked loads the notes from the storage if they are not cached.
+// A failed loading is retried at the next access.
+func (c *RaftCluster) loadStoreNotesLocked() error {
+	if c.storeNotes.notes != nil {
+		return nil
+	}
+	notes, err := c.storage.LoadAllStoreNotes()
+	if err != nil {
+		return err
+	}
+	c.storeNotes.notes = make(map[uint64][]*endpoint.StoreNote)
+	for _, note := range notes {
+		c.storeNotes.notes[note.StoreID] = append(c.storeNotes.notes[note.StoreID], note)
+	}
+	return nil
+}
+
+// getStoreNoteLocked returns the index of the note in the cached notes of the store.
+func (c *RaftCluster) getStoreNoteLocked(storeID, noteID uint64) (int, error) {
+	if err := c.loadStoreNotesLocked(); err != nil {
+		return 0, err
+	}
+	for i, note := range c.storeNotes.notes[storeID] {
+		if note.ID == noteID {
+			return i, nil
+		}
+	}
+	return 0, errs.ErrStoreNoteNotFound.FastGenByArgs(noteID, storeID)
+}
+
+// deleteStoreNotes removes the notes of a store which is physically deleted.
+func (c *RaftCluster) deleteStoreNotes(storeID uint64) {
+	if c.storage == nil {
+		return
+	}
+	c.storeNotes.Lock()
+	defer c.storeNotes.Unlock()
+	notes, err := c.storage.LoadStoreNotes(storeID)
+	if err != nil {
+		log.Warn("failed to load store notes", zap.Uint64("store-id", storeID), errs.ZapError(err))
+		return
+	}
+	for _, note := range notes {
+		if err := c.storage.DeleteStoreNote(storeID, note.ID); err != nil {
+			log.Warn("failed to delete store note", zap.Uint64("store-id", storeID), zap.Uint64("note-id", note.ID), errs.ZapError(err))
+		}
+	}
+	// The cache is reloaded at the next access in case some of them are not deleted.
+	c.storeNotes.notes = nil
+}
@@ -0,0 +1,107 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/core"
+)
+
+// StorePreparingDetail is the detail of a preparing store about when it can
+// become serving.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type StorePreparingDetail struct {
+	StoreID uint64 `json:"store_id"`
+	Address string `json:"address"`
+	// Threshold is the region size the store needs to become serving.
+	Threshold  float64 `json:"threshold"`
+	RegionSize float64 `json:"region_size"`
+	// TopologyWeight is the ratio of the expected region size of the store to
+	// the total size of the replicas it may hold, which is used to compute
+	// the threshold.
+	TopologyWeight float64           `json:"topology_weight"`
+	Uptime         typeutil.Duration `json:"uptime"`
+	// LeftSeconds is the estimated time to become serving, which is the less
+	// one of the time to reach the threshold at the current speed and the
+	// time to reach the max preparing time.
+	LeftSeconds float64   `json:"left_seconds"`
+	UpdateTime  time.Time `json:"update_time"`
+}
+
+func (c *RaftCluster) newPreparingDetail(store *core.StoreInfo, threshold, regionSize, weight float64) *StorePreparingDetail {
+	return &StorePreparingDetail{
+		StoreID:        store.GetID(),
+		Address:        store.GetAddress(),
+		Threshold:      threshold,
+		RegionSize:     regionSize,
+		TopologyWeight: weight,
+		Uptime:         typeutil.NewDuration(store.GetUptime()),
+		UpdateTime:     time.Now(),
+	}
+}
+
+// updatePreparingDetails replaces the details of the preparing stores with
+// the ones computed by the latest store check, and updates the metrics.
+func (c *RaftCluster) updatePreparingDetails(details map[uint64]*StorePreparingDetail) {
+	for storeID, detail := range details {
+		// The store may become serving in the check.
+		if store := c.GetStore(storeID); store == nil || !store.IsPreparing() {
+			delete(details, storeID)
+			continue
+		}
+		left := (c.opt.GetMaxStorePreparingTime() - detail.Uptime.Duration).Seconds()
+		if _, ls, _, err := c.progressManager.Status(encodePreparingProgressKey(storeID)); err == nil {
+			left = math.Min(left, ls)
+		}
+		detail.LeftSeconds = math.Max(left, 0)
+
+		storeLabel := strconv.FormatUint(storeID, 10)
+		storePreparingGauge.WithLabelValues(detail.Address, storeLabel, "threshold").Set(detail.Threshold)
+		storePreparingGauge.WithLabelValues(detail.Address, storeLabel, "region_size").Set(detail.RegionSize)
+		storePreparingGauge.WithLabelValues(detail.Address, storeLabel, "topology_weight").Set(detail.TopologyWeight)
+		storePreparingGauge.WithLabelValues(detail.Address, storeLabel, "left_seconds").Set(detail.LeftSeconds)
+	}
+
+	c.preparingDetails.Lock()
+	defer c.preparingDetails.Unlock()
+	for storeID, detail := range c.preparingDetails.stores {
+		if _, ok := details[storeID]; !ok {
+			storeLabel := strconv.FormatUint(storeID, 10)
+			for _, typ := range []string{"threshold", "region_size", "topology_weight", "left_seconds"} {
+				storePreparingGauge.DeleteLabelValues(detail.Address, storeLabel, typ)
+			}
+		}
+	}
+	c.preparingDetails.stores = details
+}
+
+// GetStorePreparingDetails returns the details of the preparing stores about
+// when they can become serving.
+func (c *RaftCluster) GetStorePreparingDetails() []*StorePreparingDetail {
+	c.preparingDetails.RLock()
+	defer c.preparingDetails.RUnlock()
+	details := make([]*StorePreparingDetail, 0, len(c.preparingDetails.stores))
+	for _, detail := range c.preparingDetails.stores {
+		d := *detail
+		details = append(details, &d)
+	}
+	sort.Slice(details, func(i, j int) bool { return details[i].StoreID < details[j].StoreID })
+	return details
+}
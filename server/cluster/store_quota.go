@@ -0,0 +1,98 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"strconv"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"go.uber.org/zap"
+)
+
+// storeQuotaStatus records whether a store exceeds its soft quotas.
+type storeQuotaStatus struct {
+	leader bool
+	region bool
+}
+
+// SetStoreQuota sets up a store's soft quotas of the leader and region count.
+// 0 means unlimited.
+func (c *RaftCluster) SetStoreQuota(storeID uint64, leaderQuota, regionQuota uint64) error {
+	store := c.GetStore(storeID)
+	if store == nil {
+		return errs.ErrStoreNotFound.FastGenByArgs(storeID)
+	}
+
+	if err := c.persistWithRetry("store-quota", func() error {
+		return c.storage.SaveStoreQuota(storeID, leaderQuota, regionQuota)
+	}); err != nil {
+		return err
+	}
+
+	return c.putStoreLocked(store.Clone(core.SetStoreQuota(leaderQuota, regionQuota)))
+}
+
+// checkStoreQuotas reports the stores which start or stop exceeding their soft quotas.
+func (c *RaftCluster) checkStoreQuotas() {
+	current := make(map[uint64]storeQuotaStatus)
+	for _, store := range c.GetStores() {
+		if store.IsRemoved() {
+			continue
+		}
+		status := storeQuotaStatus{
+			leader: store.ExceedsQuota(core.LeaderKind, 0),
+			region: store.ExceedsQuota(core.RegionKind, 0),
+		}
+		if status.leader || status.region {
+			current[store.GetID()] = status
+		}
+		prev := c.overQuotaStores[store.GetID()]
+		c.reportStoreQuota(store, core.LeaderKind, prev.leader, status.leader)
+		c.reportStoreQuota(store, core.RegionKind, prev.region, status.region)
+	}
+	c.overQuotaStores = current
+}
+
+func (c *RaftCluster) reportStoreQuota(store *core.StoreInfo, kind core.ResourceKind, prev, exceeded bool) {
+	if prev == exceeded {
+		return
+	}
+	var quota uint64
+	var count int
+	if kind == core.LeaderKind {
+		quota, count = store.GetLeaderQuota(), store.GetLeaderCount()
+	} else {
+		quota, count = store.GetRegionQuota(), store.GetRegionCount()
+	}
+	storeID := strconv.FormatUint(store.GetID(), 10)
+	if exceeded {
+		storeQuotaEventCounter.WithLabelValues(storeID, kind.String(), "exceeded").Inc()
+		log.Warn("store exceeds its soft quota",
+			zap.Uint64("store-id", store.GetID()),
+			zap.Stringer("kind", kind),
+			zap.Int("count", count),
+			zap.Uint64("quota", quota),
+			zap.Bool("bias-enabled", c.opt.IsStoreQuotaBiasEnabled()))
+		return
+	}
+	storeQuotaEventCounter.WithLabelValues(storeID, kind.String(), "recovered").Inc()
+	log.Info("store returns below its soft quota",
+		zap.Uint64("store-id", store.GetID()),
+		zap.Stringer("kind", kind),
+		zap.Int("count", count),
+		zap.Uint64("quota", quota))
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestStoreQuota(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	s := storage.NewStorageWithMemoryBackend()
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	for _, store := range newTestStores(2, "5.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+
+	re.True(errs.ErrStoreNotFound.Equal(cluster.SetStoreQuota(3, 1, 1)))
+	re.NoError(cluster.SetStoreQuota(1, 5, 10))
+	store := cluster.GetStore(1)
+	re.Equal(uint64(5), store.GetLeaderQuota())
+	re.Equal(uint64(10), store.GetRegionQuota())
+
+	// The quotas are persisted along with the store.
+	loaded := make(map[uint64]*core.StoreInfo)
+	re.NoError(s.LoadStores(func(store *core.StoreInfo) {
+		loaded[store.GetID()] = store
+	}))
+	re.Equal(uint64(5), loaded[1].GetLeaderQuota())
+	re.Equal(uint64(10), loaded[1].GetRegionQuota())
+	re.Equal(uint64(0), loaded[2].GetLeaderQuota())
+
+	cluster.checkStoreQuotas()
+	re.Empty(cluster.overQuotaStores)
+
+	cluster.core.PutStore(cluster.GetStore(1).Clone(core.SetLeaderCount(6), core.SetRegionCount(10)))
+	cluster.checkStoreQuotas()
+	re.Len(cluster.overQuotaStores, 1)
+	re.Equal(storeQuotaStatus{leader: true}, cluster.overQuotaStores[1])
+
+	cluster.core.PutStore(cluster.GetStore(1).Clone(core.SetLeaderCount(5), core.SetRegionCount(11)))
+	cluster.checkStoreQuotas()
+	re.Equal(storeQuotaStatus{region: true}, cluster.overQuotaStores[1])
+
+	// 0 means unlimited.
+	re.NoError(cluster.SetStoreQuota(1, 0, 0))
+	cluster.checkStoreQuotas()
+	re.Empty(cluster.overQuotaStores)
+}
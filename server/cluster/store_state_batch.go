@@ -0,0 +1,309 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/retryutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+	"go.uber.org/zap"
+)
+
+// StoreStateChange is the requested state of a store in a batch.
+type StoreStateChange struct {
+	StoreID uint64 `json:"store_id"`
+	// State is one of Up, Offline and Tombstone, case insensitive.
+	State string `json:"state"`
+	// PhysicallyDestroyed is only used when the state is Offline.
+	PhysicallyDestroyed bool `json:"physically_destroyed,omitempty"`
+	// Force is only used when the state is Tombstone, it allows burying a
+	// disconnected up store.
+	Force bool `json:"force,omitempty"`
+}
+
+type storeTransition struct {
+	change   *StoreStateChange
+	state    metapb.StoreState
+	origin   *core.StoreInfo
+	newStore *core.StoreInfo
+}
+
+// SetStoreStates changes the states of the stores at once. The combined final
+// state is validated once instead of validating each intermediate state. The
+// stores are put one by one, and if one of them fails to be persisted, the rest
+// are retried with backoff without holding the cluster lock. If they still
+// fail, the changed stores are reverted in the same way, so all the transitions
+// are applied or none of them is unless the storage keeps failing.
+func (c *RaftCluster) SetStoreStates(changes []*StoreStateChange) error {
+	c.Lock()
+	transitions, err := c.prepareStoreTransitions(changes)
+	if err == nil {
+		err = c.checkStoreTransitions(transitions)
+	}
+	if err != nil {
+		c.Unlock()
+		return err
+	}
+	done, err := c.putStoreTransitionsLocked(transitions, c.transitStoreLocked)
+	c.Unlock()
+	if err != nil {
+		log.Warn("failed to change the store states in a batch, retrying",
+			zap.Uint64("store-id", transitions[done].origin.GetID()), errs.ZapError(err))
+		n, err := c.retryStoreTransitions(transitions[done:], c.transitStoreLocked)
+		if err != nil {
+			if _, rerr := c.retryStoreTransitions(transitions[:done+n], c.revertStoreLocked); rerr != nil {
+				log.Error("failed to revert the store states changed in a batch", errs.ZapError(rerr))
+			}
+			return err
+		}
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	for _, t := range transitions {
+		log.Warn("store state has been changed in a batch",
+			zap.Uint64("store-id", t.origin.GetID()),
+			zap.String("store-address", t.origin.GetAddress()),
+			zap.String("origin-state", t.origin.GetNodeState().String()),
+			zap.String("state", t.newStore.GetNodeState().String()),
+			zap.Bool("physically-destroyed", t.newStore.IsPhysicallyDestroyed()))
+		switch t.state {
+		case metapb.StoreState_Up:
+			c.onStoreUpLocked(t.origin)
+		case metapb.StoreState_Offline:
+			c.onStoreOfflineLocked(t.origin)
+		case metapb.StoreState_Tombstone:
+			c.onStoreTombstoneLocked(t.origin)
+		}
+	}
+	c.onStoreVersionChangeLocked()
+	return nil
+}
+
+// putStoreTransitionsLocked puts the transitions in order until one of them
+// fails, and returns the number of the put ones.
+func (c *RaftCluster) putStoreTransitionsLocked(transitions []*storeTransition, put func(*storeTransition) error) (int, error) {
+	for i, t := range transitions {
+		if err := put(t); err != nil {
+			return i, err
+		}
+	}
+	return len(transitions), nil
+}
+
+// retryStoreTransitions puts the transitions with backoff. The cluster lock is
+// held by each attempt, but not while waiting for the next one.
+func (c *RaftCluster) retryStoreTransitions(transitions []*storeTransition, put func(*storeTransition) error) (int, error) {
+	ctx := c.ctx
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	done := 0
+	err := retryutil.DefaultBackoff.Do(ctx, func() error {
+		c.Lock()
+		defer c.Unlock()
+		n, err := c.putStoreTransitionsLocked(transitions[done:], put)
+		done += n
+		return err
+	})
+	return done, err
+}
+
+// transitStoreLocked puts the store with the new state. The new store is built
+// from the current one, which may be updated by the heartbeats while retrying.
+func (c *RaftCluster) transitStoreLocked(t *storeTransition) error {
+	store := c.GetStore(t.origin.GetID())
+	if store == nil {
+		return errs.ErrStoreNotFound.FastGenByArgs(t.origin.GetID())
+	}
+	switch t.state {
+	case metapb.StoreState_Up:
+		t.newStore = c.upStoreLocked(store)
+	case metapb.StoreState_Offline:
+		t.newStore = store.Clone(core.OfflineStore(t.change.PhysicallyDestroyed))
+	case metapb.StoreState_Tombstone:
+		t.newStore = store.Clone(core.TombstoneStore())
+	}
+	return c.putStoreLocked(t.newStore)
+}
+
+// revertStoreLocked puts the origin store back. The stats are shared by the
+// clones of a store, so only the last heartbeat time needs to be kept.
+func (c *RaftCluster) revertStoreLocked(t *storeTransition) error {
+	origin := t.origin
+	if store := c.GetStore(origin.GetID()); store != nil {
+		origin = origin.Clone(core.SetLastHeartbeatTS(store.GetLastHeartbeatTS()))
+	}
+	return c.putStoreLocked(origin)
+}
+
+// prepareStoreTransitions checks the preconditions of each store, which are the
+// same as the ones of a single state change, and builds the new stores. The
+// changes which have no effect are skipped.
+func (c *RaftCluster) prepareStoreTransitions(changes []*StoreStateChange) ([]*storeTransition, error) {
+	transitions := make([]*storeTransition, 0, len(changes))
+	seen := make(map[uint64]struct{}, len(changes))
+	for _, change := range changes {
+		storeID := change.StoreID
+		if _, ok := seen[storeID]; ok {
+			return nil, errs.ErrStoreStateChange.FastGenByArgs(storeID, "duplicated store")
+		}
+		seen[storeID] = struct{}{}
+		store := c.GetStore(storeID)
+		if store == nil {
+			return nil, errs.ErrStoreNotFound.FastGenByArgs(storeID)
+		}
+		t := &storeTransition{change: change, origin: store}
+		switch {
+		case strings.EqualFold(change.State, metapb.StoreState_Up.String()):
+			if store.IsRemoved() {
+				return nil, errs.ErrStoreRemoved.FastGenByArgs(storeID)
+			}
+			if store.IsPhysicallyDestroyed() {
+				return nil, errs.ErrStoreDestroyed.FastGenByArgs(storeID)
+			}
+			if store.IsUp() {
+				continue
+			}
+			t.state, t.newStore = metapb.StoreState_Up, c.upStoreLocked(store)
+		case strings.EqualFold(change.State, metapb.StoreState_Offline.String()):
+			if store.IsRemoving() && store.IsPhysicallyDestroyed() == change.PhysicallyDestroyed {
+				continue
+			}
+			if store.IsRemoved() {
+				return nil, errs.ErrStoreRemoved.FastGenByArgs(storeID)
+			}
+			if store.IsPhysicallyDestroyed() {
+				return nil, errs.ErrStoreDestroyed.FastGenByArgs(storeID)
+			}
+			t.state, t.newStore = metapb.StoreState_Offline, store.Clone(core.OfflineStore(change.PhysicallyDestroyed))
+		case strings.EqualFold(change.State, metapb.StoreState_Tombstone.String()):
+			if store.IsRemoved() {
+				continue
+			}
+			if store.IsUp() {
+				if !change.Force {
+					return nil, errs.ErrStoreIsUp.FastGenByArgs()
+				} else if !store.IsDisconnected() {
+					return nil, errs.ErrStoreStateChange.FastGenByArgs(storeID, "the store is not offline nor disconnected")
+				}
+			}
+			t.state, t.newStore = metapb.StoreState_Tombstone, store.Clone(core.TombstoneStore())
+		default:
+			return nil, errs.ErrStoreStateChange.FastGenByArgs(storeID, fmt.Sprintf("invalid state %s", change.State))
+		}
+		transitions = append(transitions, t)
+	}
+	return transitions, nil
+}
+
+// checkStoreTransitions validates the final state of the cluster after all the
+// transitions are applied, including the number of the up stores, the stores
+// for the leaders, the placement rules and the capacity.
+func (c *RaftCluster) checkStoreTransitions(transitions []*storeTransition) error {
+	newStores := make(map[uint64]*core.StoreInfo, len(transitions))
+	var leaving []uint64
+	checkReplica := false
+	for _, t := range transitions {
+		newStores[t.origin.GetID()] = t.newStore
+		if t.origin.IsUp() && !t.newStore.IsUp() {
+			leaving = append(leaving, t.origin.GetID())
+			// the same as RemoveStore, the replicas are not checked for the
+			// physically destroyed stores.
+			if t.state != metapb.StoreState_Offline || !t.change.PhysicallyDestroyed {
+				checkReplica = true
+			}
+		}
+	}
+	if len(leaving) == 0 {
+		return nil
+	}
+
+	var originUp, finalUp []*core.StoreInfo
+	for _, store := range c.GetStores() {
+		if store.IsUp() {
+			originUp = append(originUp, store)
+		}
+		if s, ok := newStores[store.GetID()]; ok {
+			store = s
+		}
+		if store.IsUp() {
+			finalUp = append(finalUp, store)
+		}
+	}
+
+	if checkReplica {
+		if len(finalUp) < c.opt.GetMaxReplicas() {
+			return errs.ErrStoresNotEnough.FastGenByArgs(leaving, len(finalUp), c.opt.GetMaxReplicas())
+		}
+		evictStores := make(map[uint64]struct{})
+		for _, id := range c.getEvictLeaderStores() {
+			evictStores[id] = struct{}{}
+		}
+		hasLeaderStore := false
+		for _, store := range finalUp {
+			if _, ok := evictStores[store.GetID()]; !ok {
+				hasLeaderStore = true
+				break
+			}
+		}
+		if !hasLeaderStore {
+			return errs.ErrNoStoreForRegionLeader.FastGenByArgs(leaving[0])
+		}
+	}
+
+	// the rules which can be satisfied before the changes should still be satisfied.
+	if c.opt.IsPlacementRulesEnabled() {
+		countStores := func(stores []*core.StoreInfo, rule *placement.Rule) int {
+			count := 0
+			for _, s := range stores {
+				if placement.MatchLabelConstraints(s, rule.LabelConstraints) {
+					count++
+				}
+			}
+			return count
+		}
+		for _, rule := range c.ruleManager.GetAllRules() {
+			if rule.Role == placement.Learner {
+				continue
+			}
+			before, after := countStores(originUp, rule), countStores(finalUp, rule)
+			if before >= rule.Count && after < rule.Count {
+				return errs.ErrStoreStatesViolateRule.FastGenByArgs(rule.GroupID+"/"+rule.ID, rule.Count, after)
+			}
+		}
+	}
+
+	// the regions on the leaving stores should fit in the rest up stores.
+	var leavingSize, availableSize int64
+	for _, id := range leaving {
+		leavingSize += c.core.GetStoreRegionSize(id)
+	}
+	for _, store := range finalUp {
+		availableSize += int64(store.GetAvailable() / units.MiB)
+	}
+	if leavingSize > availableSize {
+		return errs.ErrStoreStatesNoCapacity.FastGenByArgs(leavingSize, availableSize)
+	}
+	return nil
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/docker/go-units"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/pkg/retryutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestSetStoreStates(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	cluster.ruleManager = placement.NewRuleManager(storage.NewStorageWithMemoryBackend(), cluster, cluster.GetOpts())
+	re.NoError(cluster.ruleManager.Initialize(opt.GetMaxReplicas(), opt.GetLocationLabels()))
+	for _, store := range newTestStores(6, "5.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	checkUp := func(ids ...uint64) {
+		for _, store := range cluster.GetStores() {
+			up := false
+			for _, id := range ids {
+				up = up || store.GetID() == id
+			}
+			re.Equal(up, store.IsUp(), store.GetID())
+		}
+	}
+
+	// the stores are set as offline at once.
+	re.NoError(cluster.SetStoreStates([]*StoreStateChange{
+		{StoreID: 1, State: "offline"},
+		{StoreID: 2, State: "Offline"},
+	}))
+	checkUp(3, 4, 5, 6)
+
+	// the final state is validated, and nothing is changed if it fails.
+	err = cluster.SetStoreStates([]*StoreStateChange{
+		{StoreID: 3, State: "offline"},
+		{StoreID: 4, State: "offline"},
+	})
+	re.True(errs.ErrStoresNotEnough.Equal(err))
+	checkUp(3, 4, 5, 6)
+	for _, changes := range [][]*StoreStateChange{
+		{{StoreID: 3, State: "offline"}, {StoreID: 10, State: "offline"}},
+		{{StoreID: 3, State: "offline"}, {StoreID: 3, State: "up"}},
+		{{StoreID: 3, State: "offline"}, {StoreID: 4, State: "unknown"}},
+		{{StoreID: 3, State: "offline"}, {StoreID: 4, State: "tombstone"}},
+	} {
+		re.Error(cluster.SetStoreStates(changes))
+		checkUp(3, 4, 5, 6)
+	}
+
+	// the intermediate states are not validated.
+	re.NoError(cluster.SetStoreStates([]*StoreStateChange{
+		{StoreID: 1, State: "up"},
+		{StoreID: 3, State: "offline"},
+		{StoreID: 4, State: "offline"},
+	}))
+	checkUp(1, 5, 6)
+	re.NoError(cluster.SetStoreStates([]*StoreStateChange{
+		{StoreID: 2, State: "tombstone"},
+		// no effect
+		{StoreID: 5, State: "up"},
+	}))
+	re.True(cluster.GetStore(2).IsRemoved())
+	checkUp(1, 5, 6)
+
+	// the regions on the removed stores should fit in the rest up stores.
+	re.NoError(cluster.SetStoreStates([]*StoreStateChange{{StoreID: 3, State: "up"}}))
+	region := core.NewRegionInfo(&metapb.Region{
+		Id:    1,
+		Peers: []*metapb.Peer{{Id: 11, StoreId: 3}, {Id: 12, StoreId: 5}, {Id: 13, StoreId: 6}},
+	}, &metapb.Peer{Id: 11, StoreId: 3}, core.SetApproximateSize(100))
+	re.NoError(cluster.putRegion(region))
+	changes := []*StoreStateChange{{StoreID: 3, State: "offline"}, {StoreID: 4, State: "up"}}
+	err = cluster.SetStoreStates(changes)
+	re.True(errs.ErrStoreStatesNoCapacity.Equal(err))
+	checkUp(1, 3, 5, 6)
+	store := cluster.GetStore(4)
+	cluster.core.PutStore(store.Clone(core.SetStoreStats(&pdpb.StoreStats{Capacity: units.GiB, Available: units.GiB})))
+	re.NoError(cluster.SetStoreStates(changes))
+	checkUp(1, 4, 5, 6)
+
+	// the rules satisfied before should still be satisfied.
+	for _, id := range []uint64{1, 4} {
+		store := cluster.GetStore(id)
+		cluster.core.PutStore(store.Clone(core.SetStoreLabels([]*metapb.StoreLabel{{Key: "zone", Value: "z1"}})))
+	}
+	re.NoError(cluster.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "test",
+		ID:               "z1",
+		Role:             placement.Voter,
+		Count:            2,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z1"}}},
+	}))
+	err = cluster.SetStoreStates([]*StoreStateChange{{StoreID: 1, State: "offline"}, {StoreID: 3, State: "up"}})
+	re.True(errs.ErrStoreStatesViolateRule.Equal(err))
+	checkUp(1, 4, 5, 6)
+}
+
+// storeSaveFailStorage fails to save the given stores for the given times.
+type storeSaveFailStorage struct {
+	storage.Storage
+	failures map[uint64]int
+}
+
+func (s *storeSaveFailStorage) SaveStore(store *metapb.Store) error {
+	if s.failures[store.GetId()] > 0 {
+		s.failures[store.GetId()]--
+		return errors.New("failed to save store")
+	}
+	return s.Storage.SaveStore(store)
+}
+
+func TestSetStoreStatesPersistFailure(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	s := &storeSaveFailStorage{Storage: storage.NewStorageWithMemoryBackend(), failures: make(map[uint64]int)}
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	for _, store := range newTestStores(6, "5.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	changes := []*StoreStateChange{{StoreID: 1, State: "offline"}, {StoreID: 2, State: "offline"}}
+
+	// the failed store is retried.
+	s.failures[2] = 2
+	re.NoError(cluster.SetStoreStates(changes))
+	for _, id := range []uint64{1, 2} {
+		re.True(cluster.GetStore(id).IsRemoving())
+		meta := &metapb.Store{}
+		ok, err := s.LoadStore(id, meta)
+		re.NoError(err)
+		re.True(ok)
+		re.Equal(metapb.NodeState_Removing, meta.GetNodeState())
+	}
+
+	// the changed stores are reverted if the retries are exhausted.
+	changes = []*StoreStateChange{{StoreID: 1, State: "up"}, {StoreID: 2, State: "up"}}
+	s.failures[2] = retryutil.DefaultBackoff.MaxAttempts + 1
+	re.Error(cluster.SetStoreStates(changes))
+	for _, id := range []uint64{1, 2} {
+		re.True(cluster.GetStore(id).IsRemoving())
+		meta := &metapb.Store{}
+		ok, err := s.LoadStore(id, meta)
+		re.NoError(err)
+		re.True(ok)
+		re.Equal(metapb.NodeState_Removing, meta.GetNodeState())
+	}
+}
@@ -0,0 +1,155 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"sort"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+)
+
+// TopologyWeights is the topology weights of the stores, which are used to
+// compute the region size threshold for the preparing stores to become serving.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type TopologyWeights struct {
+	PlacementRulesEnabled bool `json:"placement_rules_enabled"`
+	// LocationLabels and Topology are used when the placement rules are disabled.
+	LocationLabels []string               `json:"location_labels,omitempty"`
+	Topology       map[string]interface{} `json:"topology,omitempty"`
+	// Rules are used when the placement rules are enabled.
+	Rules  []*RuleTopology        `json:"rules,omitempty"`
+	Stores []*StoreTopologyWeight `json:"stores"`
+}
+
+// RuleTopology is the topology tree of the stores matching a placement rule,
+// which is built by the location labels of the rule.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type RuleTopology struct {
+	GroupID        string                 `json:"group_id"`
+	ID             string                 `json:"id"`
+	LocationLabels []string               `json:"location_labels"`
+	StoreCount     int                    `json:"store_count"`
+	Topology       map[string]interface{} `json:"topology"`
+}
+
+// StoreTopologyWeight is the topology weight of a store.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type StoreTopologyWeight struct {
+	StoreID   uint64               `json:"store_id"`
+	Address   string               `json:"address"`
+	NodeState string               `json:"node_state"`
+	Labels    []*metapb.StoreLabel `json:"labels,omitempty"`
+	// Weight is the ratio of the expected region size of the store to the
+	// total size of the replicas it may hold.
+	Weight float64 `json:"weight"`
+	// Threshold is the region size the store needs to become serving if it
+	// is preparing.
+	Threshold float64 `json:"threshold"`
+	// RuleWeights is the weight of the store under each matched placement rule.
+	RuleWeights []*RuleTopologyWeight `json:"rule_weights,omitempty"`
+}
+
+// RuleTopologyWeight is the topology weight of a store under a placement rule.
+type RuleTopologyWeight struct {
+	GroupID string  `json:"group_id"`
+	ID      string  `json:"id"`
+	Weight  float64 `json:"weight"`
+}
+
+// GetTopologyWeights returns the topology weights of the stores under the current
+// location labels and placement rules. If storeIDs is empty, all the stores
+// which are not removed are returned.
+func (c *RaftCluster) GetTopologyWeights(storeIDs ...uint64) *TopologyWeights {
+	var stores []*core.StoreInfo
+	for _, store := range c.GetStores() {
+		if !store.IsRemoved() {
+			stores = append(stores, store)
+		}
+	}
+	targets := stores
+	if len(storeIDs) > 0 {
+		ids := make(map[uint64]struct{}, len(storeIDs))
+		for _, id := range storeIDs {
+			ids[id] = struct{}{}
+		}
+		targets = nil
+		for _, store := range stores {
+			if _, ok := ids[store.GetID()]; ok {
+				targets = append(targets, store)
+			}
+		}
+	}
+
+	weights := &TopologyWeights{
+		PlacementRulesEnabled: c.opt.IsPlacementRulesEnabled(),
+		Stores:                make([]*StoreTopologyWeight, 0, len(targets)),
+	}
+	var rules []*placement.Rule
+	if weights.PlacementRulesEnabled {
+		rules = c.ruleManager.GetAllRules()
+		for _, rule := range rules {
+			matchStores := matchRuleStores(stores, rule)
+			weights.Rules = append(weights.Rules, &RuleTopology{
+				GroupID:        rule.GroupID,
+				ID:             rule.ID,
+				LocationLabels: rule.LocationLabels,
+				StoreCount:     len(matchStores),
+				Topology:       buildTopologyTree(matchStores, rule.LocationLabels),
+			})
+		}
+	} else {
+		weights.LocationLabels = c.opt.GetLocationLabels()
+		weights.Topology = buildTopologyTree(stores, weights.LocationLabels)
+	}
+
+	for _, store := range targets {
+		threshold, weight := c.getThresholdWithWeight(stores, store)
+		storeWeight := &StoreTopologyWeight{
+			StoreID:   store.GetID(),
+			Address:   store.GetAddress(),
+			NodeState: store.GetNodeState().String(),
+			Labels:    store.GetLabels(),
+			Weight:    weight,
+			Threshold: threshold,
+		}
+		for _, rule := range rules {
+			if !placement.MatchLabelConstraints(store, rule.LabelConstraints) {
+				continue
+			}
+			storeWeight.RuleWeights = append(storeWeight.RuleWeights, &RuleTopologyWeight{
+				GroupID: rule.GroupID,
+				ID:      rule.ID,
+				Weight:  getStoreTopoWeight(store, matchRuleStores(stores, rule), rule.LocationLabels),
+			})
+		}
+		weights.Stores = append(weights.Stores, storeWeight)
+	}
+	sort.Slice(weights.Stores, func(i, j int) bool { return weights.Stores[i].StoreID < weights.Stores[j].StoreID })
+	return weights
+}
+
+// buildTopologyTree returns the topology tree of the serving and preparing stores
+// by the location labels, which is the same as the one used by getStoreTopoWeight.
+func buildTopologyTree(stores []*core.StoreInfo, locationLabels []string) map[string]interface{} {
+	topology := make(map[string]interface{})
+	for _, store := range stores {
+		if store.IsServing() || store.IsPreparing() {
+			updateTopology(topology, getSortedLabels(store.GetLabels(), locationLabels))
+		}
+	}
+	return topology
+}
@@ -0,0 +1,183 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/versioninfo"
+	"go.uber.org/zap"
+)
+
+// StoreVersionDistribution summarizes the versions of the stores.
+type StoreVersionDistribution struct {
+	ClusterVersion string `json:"cluster_version"`
+	// MinVersion and MaxVersion are the oldest and newest versions of the up stores.
+	MinVersion string `json:"min_version,omitempty"`
+	MaxVersion string `json:"max_version,omitempty"`
+	// Versions maps each version to the IDs of the stores running it.
+	Versions       map[string][]uint64 `json:"versions"`
+	MaxSkew        uint64              `json:"max_skew"`
+	Policy         string              `json:"policy"`
+	ExceededStores []uint64            `json:"exceeded_stores"`
+}
+
+// upStoreVersionRange returns the oldest and newest versions of the up stores,
+// the store with the given ID is ignored.
+func upStoreVersionRange(stores []*core.StoreInfo, ignoreID uint64) (minVersion, maxVersion *semver.Version) {
+	for _, s := range stores {
+		if !s.IsUp() || s.GetID() == ignoreID {
+			continue
+		}
+		v, err := versioninfo.ParseVersion(s.GetVersion())
+		if err != nil {
+			continue
+		}
+		if minVersion == nil || v.LessThan(*minVersion) {
+			minVersion = v
+		}
+		if maxVersion == nil || maxVersion.LessThan(*v) {
+			maxVersion = v
+		}
+	}
+	return
+}
+
+// versionLag returns the number of minor versions v lags behind the newest version.
+// It returns math.MaxUint64 if their major versions are different.
+func versionLag(v, newest *semver.Version) uint64 {
+	if newest == nil || !v.LessThan(*newest) {
+		return 0
+	}
+	lag, ok := versioninfo.MinorVersionDistance(*v, *newest)
+	if !ok {
+		return math.MaxUint64
+	}
+	return lag
+}
+
+// checkStoreVersionSkew refuses a store registering with a version which makes the
+// version skew of the up stores exceed the max skew, if the refuse policy is used.
+// The stores restarting with an unchanged version are not refused.
+func (c *RaftCluster) checkStoreVersionSkew(store *metapb.Store) error {
+	maxSkew := c.opt.GetMaxStoreVersionSkew()
+	if maxSkew == 0 || c.opt.GetStoreVersionSkewPolicy() != config.StoreVersionSkewPolicyRefuse {
+		return nil
+	}
+	if s := c.GetStore(store.GetId()); s != nil && s.GetVersion() == store.GetVersion() {
+		return nil
+	}
+	v, err := versioninfo.ParseVersion(store.GetVersion())
+	if err != nil {
+		return err
+	}
+	minVersion, maxVersion := upStoreVersionRange(c.GetStores(), store.GetId())
+	if minVersion == nil {
+		return nil
+	}
+	if versionLag(v, maxVersion) > maxSkew || versionLag(minVersion, v) > maxSkew {
+		return errs.ErrStoreVersionSkew.FastGenByArgs(v, store.GetId(), maxSkew, minVersion, maxVersion)
+	}
+	return nil
+}
+
+// updateStoreVersionLagsLocked updates the number of minor versions each store lags
+// behind the newest version of the up stores, which is used to exclude the stores
+// from the scheduling targets if the exclude policy is used. The lags are not
+// persisted, so they are recomputed once the stores are loaded.
+func (c *RaftCluster) updateStoreVersionLagsLocked() {
+	stores := c.GetStores()
+	_, c.newestStoreVersion = upStoreVersionRange(stores, 0)
+	for _, s := range stores {
+		if s.IsRemoved() {
+			storeVersionSkewedGauge.DeleteLabelValues(strconv.FormatUint(s.GetID(), 10))
+			continue
+		}
+		if lag, ok := c.storeVersionLagLocked(s); ok && lag != s.GetVersionLag() {
+			c.core.PutStore(s.ShallowClone(core.SetVersionLag(lag)))
+		}
+	}
+}
+
+// storeVersionLagLocked returns the number of minor versions the store lags behind
+// the newest version computed by the last updateStoreVersionLagsLocked, and updates
+// the metric of the store. It returns false if the version of the store is invalid.
+func (c *RaftCluster) storeVersionLagLocked(s *core.StoreInfo) (uint64, bool) {
+	v, err := versioninfo.ParseVersion(s.GetVersion())
+	if err != nil {
+		return 0, false
+	}
+	lag := versionLag(v, c.newestStoreVersion)
+	maxSkew := c.opt.GetMaxStoreVersionSkew()
+	exceeded := maxSkew > 0 && lag > maxSkew
+	storeID := strconv.FormatUint(s.GetID(), 10)
+	if exceeded {
+		storeVersionSkewedGauge.WithLabelValues(storeID).Set(1)
+	} else {
+		storeVersionSkewedGauge.WithLabelValues(storeID).Set(0)
+	}
+	if exceeded && lag != s.GetVersionLag() {
+		log.Warn("store version lags behind too much",
+			zap.Uint64("store-id", s.GetID()),
+			zap.Stringer("version", v),
+			zap.Stringer("newest-version", c.newestStoreVersion),
+			zap.Uint64("max-skew", maxSkew),
+			zap.String("policy", c.opt.GetStoreVersionSkewPolicy()))
+	}
+	return lag, true
+}
+
+// GetStoreVersionDistribution returns the summary of the versions of the stores.
+func (c *RaftCluster) GetStoreVersionDistribution() *StoreVersionDistribution {
+	stores := c.GetStores()
+	minVersion, maxVersion := upStoreVersionRange(stores, 0)
+	maxSkew := c.opt.GetMaxStoreVersionSkew()
+	dist := &StoreVersionDistribution{
+		ClusterVersion: c.opt.GetClusterVersion().String(),
+		Versions:       make(map[string][]uint64),
+		MaxSkew:        maxSkew,
+		Policy:         c.opt.GetStoreVersionSkewPolicy(),
+		ExceededStores: []uint64{},
+	}
+	if minVersion != nil {
+		dist.MinVersion, dist.MaxVersion = minVersion.String(), maxVersion.String()
+	}
+	for _, s := range stores {
+		if s.IsRemoved() {
+			continue
+		}
+		v, err := versioninfo.ParseVersion(s.GetVersion())
+		if err != nil {
+			continue
+		}
+		dist.Versions[v.String()] = append(dist.Versions[v.String()], s.GetID())
+		if maxSkew > 0 && versionLag(v, maxVersion) > maxSkew {
+			dist.ExceededStores = append(dist.ExceededStores, s.GetID())
+		}
+	}
+	for _, ids := range dist.Versions {
+		sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	}
+	sort.Slice(dist.ExceededStores, func(i, j int) bool { return dist.ExceededStores[i] < dist.ExceededStores[j] })
+	return dist
+}
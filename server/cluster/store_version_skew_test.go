@@ -0,0 +1,121 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"math"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestStoreVersionSkew(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cfg := opt.GetScheduleConfig().Clone()
+	cfg.MaxStoreVersionSkew = 1
+	cfg.StoreVersionSkewPolicy = config.StoreVersionSkewPolicyRefuse
+	opt.SetScheduleConfig(cfg)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	stores := newTestStores(4, "5.0.0")
+	for _, store := range stores[:3] {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+
+	// The store ahead of the others too much is refused.
+	s4 := stores[3].GetMeta()
+	s4.Version = "5.2.0"
+	re.True(errs.ErrStoreVersionSkew.Equal(cluster.PutStore(s4)))
+	re.Nil(cluster.GetStore(4))
+	s4.Version = "5.1.0"
+	re.NoError(cluster.PutStore(s4))
+	// Upgrading a store which makes the others lag behind too much is refused as well.
+	s1 := stores[0].GetMeta()
+	s1.Version = "5.2.0"
+	re.True(errs.ErrStoreVersionSkew.Equal(cluster.PutStore(s1)))
+
+	// The other policies accept the store.
+	cfg = opt.GetScheduleConfig().Clone()
+	cfg.StoreVersionSkewPolicy = config.StoreVersionSkewPolicyExclude
+	opt.SetScheduleConfig(cfg)
+	re.NoError(cluster.PutStore(s1))
+	re.Equal(uint64(0), cluster.GetStore(1).GetVersionLag())
+	re.Equal(uint64(2), cluster.GetStore(2).GetVersionLag())
+	re.Equal(uint64(1), cluster.GetStore(4).GetVersionLag())
+
+	dist := cluster.GetStoreVersionDistribution()
+	re.Equal("5.0.0", dist.ClusterVersion)
+	re.Equal("5.0.0", dist.MinVersion)
+	re.Equal("5.2.0", dist.MaxVersion)
+	re.Equal(map[string][]uint64{"5.0.0": {2, 3}, "5.1.0": {4}, "5.2.0": {1}}, dist.Versions)
+	re.Equal([]uint64{2, 3}, dist.ExceededStores)
+
+	// A store restarting with the unchanged version is not refused.
+	cfg = opt.GetScheduleConfig().Clone()
+	cfg.StoreVersionSkewPolicy = config.StoreVersionSkewPolicyRefuse
+	opt.SetScheduleConfig(cfg)
+	re.NoError(cluster.PutStore(stores[1].GetMeta()))
+
+	// The stores with a different major version always exceed the skew.
+	cfg = opt.GetScheduleConfig().Clone()
+	cfg.StoreVersionSkewPolicy = config.StoreVersionSkewPolicyWarn
+	opt.SetScheduleConfig(cfg)
+	s1.Version = "6.0.0"
+	re.NoError(cluster.PutStore(s1))
+	re.Equal(uint64(math.MaxUint64), cluster.GetStore(2).GetVersionLag())
+	re.Equal([]uint64{2, 3, 4}, cluster.GetStoreVersionDistribution().ExceededStores)
+}
+
+func TestStoreVersionLagRecomputed(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	s := storage.NewStorageWithMemoryBackend()
+	re.NoError(s.SaveMeta(&metapb.Cluster{Id: 1}))
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	stores := newTestStores(2, "5.0.0")
+	stores[1].GetMeta().Version = "5.2.0"
+	for _, store := range stores {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	re.Equal(uint64(2), cluster.GetStore(1).GetVersionLag())
+
+	// The lags are recomputed once the stores are loaded by the new leader.
+	cluster = newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, s, core.NewBasicCluster())
+	_, err = cluster.LoadClusterInfo()
+	re.NoError(err)
+	re.Equal(uint64(2), cluster.GetStore(1).GetVersionLag())
+	re.Equal(uint64(0), cluster.GetStore(2).GetVersionLag())
+
+	// The heartbeat restores the lag as well.
+	cluster.core.PutStore(cluster.GetStore(1).ShallowClone(core.SetVersionLag(0)))
+	re.NoError(cluster.HandleStoreHeartbeat(&pdpb.StoreStats{StoreId: 1, Capacity: 100, Available: 50}))
+	re.Equal(uint64(2), cluster.GetStore(1).GetVersionLag())
+}
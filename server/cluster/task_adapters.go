@@ -0,0 +1,498 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/server/schedule/checker"
+	"github.com/tikv/pd/server/schedule/operator"
+	"go.uber.org/zap"
+)
+
+// The types of the tasks.
+const (
+	TaskTypeScatter        = "scatter"
+	TaskTypeStoreDrain     = "store-drain"
+	TaskTypeCacheRebuild   = "cache-rebuild"
+	TaskTypeUnsafeRecovery = "unsafe-recovery"
+	TaskTypeRegionMerge    = "region-merge"
+	TaskTypeImportMode     = "import-mode"
+)
+
+// cacheRebuildBatchSize is the number of regions observed under the cluster
+// lock at a time when rebuilding the caches.
+const cacheRebuildBatchSize = 4096
+
+var taskAdapters = map[string]taskAdapter{
+	TaskTypeScatter:        scatterTaskAdapter{},
+	TaskTypeStoreDrain:     storeDrainTaskAdapter{},
+	TaskTypeCacheRebuild:   cacheRebuildTaskAdapter{},
+	TaskTypeUnsafeRecovery: unsafeRecoveryTaskAdapter{},
+	TaskTypeRegionMerge:    regionMergeTaskAdapter{},
+	TaskTypeImportMode:     importModeTaskAdapter{},
+}
+
+func decodeTaskParams(typ string, params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return nil
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return errs.ErrTaskInvalid.FastGenByArgs(typ, err.Error())
+	}
+	return nil
+}
+
+// ScatterTaskParams is the params of the scatter task. The regions in the key
+// range are scattered if both keys are specified, otherwise the regions with
+// the given IDs are scattered.
+type ScatterTaskParams struct {
+	RegionIDs  []uint64 `json:"regions_id,omitempty"`
+	StartKey   string   `json:"start_key,omitempty"`
+	EndKey     string   `json:"end_key,omitempty"`
+	Group      string   `json:"group,omitempty"`
+	RetryLimit int      `json:"retry_limit,omitempty"`
+}
+
+type scatterTaskAdapter struct{}
+
+func (scatterTaskAdapter) start(c *RaftCluster, params json.RawMessage) (taskRunner, error) {
+	p := &ScatterTaskParams{}
+	if err := decodeTaskParams(TaskTypeScatter, params, p); err != nil {
+		return nil, err
+	}
+	var (
+		ops      []*operator.Operator
+		failures map[uint64]error
+		err      error
+	)
+	switch {
+	case len(p.StartKey) > 0 && len(p.EndKey) > 0:
+		startKey, e := hex.DecodeString(p.StartKey)
+		if e != nil {
+			return nil, errs.ErrTaskInvalid.FastGenByArgs(TaskTypeScatter, "invalid start key")
+		}
+		endKey, e := hex.DecodeString(p.EndKey)
+		if e != nil {
+			return nil, errs.ErrTaskInvalid.FastGenByArgs(TaskTypeScatter, "invalid end key")
+		}
+		ops, failures, err = c.GetRegionScatter().ScatterRegionsByRange(startKey, endKey, p.Group, p.RetryLimit)
+	case len(p.RegionIDs) > 0:
+		ops, failures, err = c.GetRegionScatter().ScatterRegionsByID(p.RegionIDs, p.Group, p.RetryLimit)
+	default:
+		return nil, errs.ErrTaskInvalid.FastGenByArgs(TaskTypeScatter, "no region is specified")
+	}
+	if err != nil {
+		return nil, err
+	}
+	r := &scatterTaskRunner{cluster: c, failures: len(failures)}
+	for _, op := range ops {
+		op.AttachKind(operator.OpAdmin)
+		if c.GetOperatorController().AddOperator(op) {
+			r.ops = append(r.ops, op)
+		} else {
+			r.failures++
+		}
+	}
+	return r, nil
+}
+
+// resume returns nil since the operators are not persisted.
+func (scatterTaskAdapter) resume(*RaftCluster, json.RawMessage) taskRunner {
+	return nil
+}
+
+type scatterTaskRunner struct {
+	cluster  *RaftCluster
+	ops      []*operator.Operator
+	failures int
+}
+
+func (r *scatterTaskRunner) check() (float64, bool, error) {
+	if len(r.ops) == 0 && r.failures == 0 {
+		return 1, true, nil
+	}
+	ended, failures := 0, r.failures
+	for _, op := range r.ops {
+		if op.IsEnd() {
+			ended++
+			if !op.CheckSuccess() {
+				failures++
+			}
+		}
+	}
+	total := len(r.ops) + r.failures
+	progress := float64(ended+r.failures) / float64(total)
+	if ended < len(r.ops) {
+		return progress, false, nil
+	}
+	if failures > 0 {
+		return progress, true, errors.Errorf("%d of %d regions failed to be scattered", failures, total)
+	}
+	return 1, true, nil
+}
+
+func (r *scatterTaskRunner) cancel() error {
+	for _, op := range r.ops {
+		if !op.IsEnd() {
+			r.cluster.GetOperatorController().RemoveOperator(op)
+		}
+	}
+	return nil
+}
+
+// StoreDrainTaskParams is the params of the store drain task.
+type StoreDrainTaskParams struct {
+	StoreID             uint64 `json:"store_id"`
+	PhysicallyDestroyed bool   `json:"physically_destroyed,omitempty"`
+}
+
+type storeDrainTaskAdapter struct{}
+
+func (storeDrainTaskAdapter) start(c *RaftCluster, params json.RawMessage) (taskRunner, error) {
+	p := &StoreDrainTaskParams{}
+	if err := decodeTaskParams(TaskTypeStoreDrain, params, p); err != nil {
+		return nil, err
+	}
+	// The drain is not owned by the task if the store is already being removed,
+	// e.g. by another task or the store API.
+	store := c.GetStore(p.StoreID)
+	owned := store != nil && !store.IsRemoving()
+	if err := c.RemoveStore(p.StoreID, p.PhysicallyDestroyed); err != nil {
+		return nil, err
+	}
+	return &storeDrainTaskRunner{cluster: c, storeID: p.StoreID, owned: owned}, nil
+}
+
+// resume rebuilds the runner since the state of the store is persisted.
+func (storeDrainTaskAdapter) resume(c *RaftCluster, params json.RawMessage) taskRunner {
+	p := &StoreDrainTaskParams{}
+	if err := decodeTaskParams(TaskTypeStoreDrain, params, p); err != nil {
+		return nil
+	}
+	return &storeDrainTaskRunner{cluster: c, storeID: p.StoreID, owned: true}
+}
+
+type storeDrainTaskRunner struct {
+	cluster *RaftCluster
+	storeID uint64
+	// owned is true if the drain is started by the task, only the owned drain
+	// is undone when the task is cancelled.
+	owned bool
+}
+
+func (r *storeDrainTaskRunner) check() (float64, bool, error) {
+	store := r.cluster.GetStore(r.storeID)
+	if store == nil {
+		return 0, true, errs.ErrStoreNotFound.FastGenByArgs(r.storeID)
+	}
+	if store.IsRemoved() {
+		return 1, true, nil
+	}
+	if !store.IsRemoving() {
+		return 0, true, errors.Errorf("store %d is not being removed", r.storeID)
+	}
+	process, _, _, err := r.cluster.progressManager.Status(encodeRemovingProgressKey(r.storeID))
+	if err != nil {
+		// the progress is not tracked until the next store check.
+		return 0, false, nil
+	}
+	return process, false, nil
+}
+
+func (r *storeDrainTaskRunner) cancel() error {
+	if !r.owned {
+		return nil
+	}
+	return r.cluster.UpStore(r.storeID)
+}
+
+type cacheRebuildTaskAdapter struct{}
+
+// start resets the region query cache and observes the statistics of all
+// regions again in the background.
+func (cacheRebuildTaskAdapter) start(c *RaftCluster, _ json.RawMessage) (taskRunner, error) {
+	r := &cacheRebuildTaskRunner{cluster: c, total: int64(c.GetRegionCount())}
+	r.ctx, r.cancelFunc = context.WithCancel(c.ctx)
+	c.regionQueryCache.Reset()
+	go r.run()
+	return r, nil
+}
+
+// resume starts the rebuild again since it is idempotent.
+func (a cacheRebuildTaskAdapter) resume(c *RaftCluster, params json.RawMessage) taskRunner {
+	r, _ := a.start(c, params)
+	return r
+}
+
+type cacheRebuildTaskRunner struct {
+	cluster    *RaftCluster
+	ctx        context.Context
+	cancelFunc context.CancelFunc
+	total      int64
+	// rebuilt and done are accessed atomically.
+	rebuilt int64
+	done    int32
+}
+
+func (r *cacheRebuildTaskRunner) run() {
+	defer logutil.LogPanic()
+	c := r.cluster
+	var startKey []byte
+	for {
+		select {
+		case <-r.ctx.Done():
+			log.Info("cache rebuild is stopped", zap.Int64("rebuilt", atomic.LoadInt64(&r.rebuilt)))
+			return
+		default:
+		}
+		c.RLock()
+		regions := c.ScanRegions(startKey, nil, cacheRebuildBatchSize)
+		for _, region := range regions {
+			c.observeRegionStats(region)
+		}
+		c.updateRegionsLabelLevelStats(regions)
+		c.RUnlock()
+		atomic.AddInt64(&r.rebuilt, int64(len(regions)))
+		if len(regions) == 0 {
+			break
+		}
+		startKey = regions[len(regions)-1].GetEndKey()
+		if len(startKey) == 0 {
+			break
+		}
+	}
+	// The statistics may be updated by the observer in the background, the
+	// rebuild is done once they are applied.
+	c.waitStatisticsObserved()
+	atomic.StoreInt32(&r.done, 1)
+}
+
+func (r *cacheRebuildTaskRunner) check() (float64, bool, error) {
+	if atomic.LoadInt32(&r.done) == 1 {
+		return 1, true, nil
+	}
+	if r.total == 0 {
+		return 0, false, nil
+	}
+	progress := float64(atomic.LoadInt64(&r.rebuilt)) / float64(r.total)
+	if progress > 1 {
+		progress = 1
+	}
+	return progress, false, nil
+}
+
+func (r *cacheRebuildTaskRunner) cancel() error {
+	r.cancelFunc()
+	return nil
+}
+
+// UnsafeRecoveryTaskParams is the params of the unsafe recovery task.
+type UnsafeRecoveryTaskParams struct {
+	StoreIDs []uint64 `json:"stores"`
+	// Timeout is in seconds, it is 600 by default.
+	Timeout uint64 `json:"timeout,omitempty"`
+}
+
+type unsafeRecoveryTaskAdapter struct{}
+
+func (unsafeRecoveryTaskAdapter) start(c *RaftCluster, params json.RawMessage) (taskRunner, error) {
+	p := &UnsafeRecoveryTaskParams{Timeout: 600}
+	if err := decodeTaskParams(TaskTypeUnsafeRecovery, params, p); err != nil {
+		return nil, err
+	}
+	stores := make(map[uint64]struct{}, len(p.StoreIDs))
+	for _, id := range p.StoreIDs {
+		stores[id] = struct{}{}
+	}
+	if err := c.unsafeRecoveryController.RemoveFailedStores(stores, p.Timeout); err != nil {
+		return nil, err
+	}
+	return &unsafeRecoveryTaskRunner{controller: c.unsafeRecoveryController}, nil
+}
+
+// resume returns nil since the state of the recovery is not persisted.
+func (unsafeRecoveryTaskAdapter) resume(*RaftCluster, json.RawMessage) taskRunner {
+	return nil
+}
+
+type unsafeRecoveryTaskRunner struct {
+	controller *unsafeRecoveryController
+}
+
+func (r *unsafeRecoveryTaskRunner) check() (float64, bool, error) {
+	u := r.controller
+	u.RLock()
+	defer u.RUnlock()
+	switch u.stage {
+	case finished:
+		return 1, true, nil
+	case failed:
+		if u.err != nil {
+			return 1, true, u.err
+		}
+		return 1, true, errors.New("unsafe recovery failed")
+	case idle:
+		return 0, true, errors.New("unsafe recovery is interrupted")
+	default:
+		return float64(u.stage) / float64(finished), false, nil
+	}
+}
+
+// cancel is not supported, since the recovery can't be rolled back once the
+// failed stores are removed.
+func (*unsafeRecoveryTaskRunner) cancel() error {
+	return errs.ErrTaskInvalid.FastGenByArgs(TaskTypeUnsafeRecovery, "it can not be cancelled")
+}
+
+// RegionMergeTaskParams is the params of the region merge task, the source region
+// is merged into the adjacent target region.
+type RegionMergeTaskParams struct {
+	SourceID uint64 `json:"source_id"`
+	TargetID uint64 `json:"target_id"`
+}
+
+// RegionMergeHalf is the status of the operator on one of the merged regions.
+type RegionMergeHalf struct {
+	RegionID uint64 `json:"region_id"`
+	// Role is either "source" or "target".
+	Role   string `json:"role"`
+	Status string `json:"status"`
+}
+
+type regionMergeTaskAdapter struct{}
+
+// start validates the regions are mergeable under the current config, placement
+// rules and region labels, then adds the paired merge operators.
+func (regionMergeTaskAdapter) start(c *RaftCluster, params json.RawMessage) (taskRunner, error) {
+	p := &RegionMergeTaskParams{}
+	if err := decodeTaskParams(TaskTypeRegionMerge, params, p); err != nil {
+		return nil, err
+	}
+	source, target := c.GetRegion(p.SourceID), c.GetRegion(p.TargetID)
+	if source == nil || target == nil {
+		missing := p.SourceID
+		if source != nil {
+			missing = p.TargetID
+		}
+		return nil, errs.ErrTaskInvalid.FastGenByArgs(TaskTypeRegionMerge, fmt.Sprintf("region %d not found", missing))
+	}
+	if err := checker.CheckMergeable(c, source, target); err != nil {
+		return nil, err
+	}
+	ops, err := operator.CreateMergeRegionOperator("admin-merge-region", c, source, target, operator.OpAdmin)
+	if err != nil {
+		return nil, err
+	}
+	if !c.GetOperatorController().AddOperator(ops...) {
+		return nil, errs.ErrTaskConflict.FastGenByArgs(TaskTypeRegionMerge,
+			fmt.Sprintf("failed to add the merge operators of region %d and region %d, maybe they already have operators", p.SourceID, p.TargetID))
+	}
+	return &regionMergeTaskRunner{cluster: c, ops: ops}, nil
+}
+
+// resume watches the source region since the operators are not persisted. The
+// merge may still be finished by TiKV after the leader changes.
+func (regionMergeTaskAdapter) resume(c *RaftCluster, params json.RawMessage) taskRunner {
+	p := &RegionMergeTaskParams{}
+	if err := decodeTaskParams(TaskTypeRegionMerge, params, p); err != nil {
+		return nil
+	}
+	return &resumedRegionMergeTaskRunner{
+		cluster:  c,
+		sourceID: p.SourceID,
+		deadline: time.Now().Add(operator.SlowOperatorWaitTime),
+	}
+}
+
+// regionMergeTaskRunner tracks the paired merge operators, the first one is on
+// the source region and the second one is on the target region.
+type regionMergeTaskRunner struct {
+	cluster *RaftCluster
+	ops     []*operator.Operator
+}
+
+// check fails the task once either operator fails, and the other one is removed
+// since the merge can't be done without its pair.
+func (r *regionMergeTaskRunner) check() (float64, bool, error) {
+	ended := 0
+	for _, op := range r.ops {
+		if !op.IsEnd() {
+			continue
+		}
+		ended++
+		if !op.CheckSuccess() {
+			progress := float64(ended) / float64(len(r.ops))
+			_ = r.cancel()
+			return progress, true, errors.Errorf("the merge operator of region %d is %s",
+				op.RegionID(), operator.OpStatusToString(op.Status()))
+		}
+	}
+	return float64(ended) / float64(len(r.ops)), ended == len(r.ops), nil
+}
+
+func (r *regionMergeTaskRunner) cancel() error {
+	for _, op := range r.ops {
+		if !op.IsEnd() {
+			r.cluster.GetOperatorController().RemoveOperator(op)
+		}
+	}
+	return nil
+}
+
+// resumedRegionMergeTaskRunner tracks a merge started by the previous leader. The
+// merge is done once the source region is gone, and it fails if the source region
+// still exists after the operators would have timed out.
+type resumedRegionMergeTaskRunner struct {
+	cluster  *RaftCluster
+	sourceID uint64
+	deadline time.Time
+}
+
+func (r *resumedRegionMergeTaskRunner) check() (float64, bool, error) {
+	if r.cluster.GetRegion(r.sourceID) == nil {
+		return 1, true, nil
+	}
+	if time.Now().After(r.deadline) {
+		return 0, true, errors.Errorf("the source region %d is not merged after the leader change", r.sourceID)
+	}
+	return 0, false, nil
+}
+
+// cancel does nothing since the operators are lost with the previous leader.
+func (*resumedRegionMergeTaskRunner) cancel() error {
+	return nil
+}
+
+func (r *regionMergeTaskRunner) details() interface{} {
+	halves := make([]RegionMergeHalf, 0, len(r.ops))
+	for i, op := range r.ops {
+		role := "target"
+		if i == 0 {
+			role = "source"
+		}
+		halves = append(halves, RegionMergeHalf{RegionID: op.RegionID(), Role: role, Status: operator.OpStatusToString(op.Status())})
+	}
+	return halves
+}
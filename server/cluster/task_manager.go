@@ -0,0 +1,372 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"go.uber.org/zap"
+)
+
+// The states of the tasks.
+const (
+	TaskStateRunning   = "running"
+	TaskStateSucceeded = "succeeded"
+	TaskStateFailed    = "failed"
+	TaskStateCancelled = "cancelled"
+)
+
+// maxEndedTasks is the max number of the ended tasks kept in the storage, the
+// oldest ones are removed once it is exceeded.
+const maxEndedTasks = 128
+
+// taskAdapter adapts a kind of long-running action to the task manager.
+type taskAdapter interface {
+	// start starts the action with the given params, it is also used to retry
+	// a failed or cancelled task.
+	start(c *RaftCluster, params json.RawMessage) (taskRunner, error)
+	// resume rebuilds the runner of a running task after the leader changes. It
+	// returns nil if the state of the action is lost with the previous leader.
+	resume(c *RaftCluster, params json.RawMessage) taskRunner
+}
+
+// taskRunner tracks a started action.
+type taskRunner interface {
+	// check returns the progress in the range of [0, 1] and whether the action
+	// is done. The action fails if a non-nil error is returned.
+	check() (progress float64, done bool, err error)
+	cancel() error
+}
+
+// taskDetailsReporter is implemented by the runners which can report the status
+// of the parts of their actions.
+type taskDetailsReporter interface {
+	details() interface{}
+}
+
+// taskManager keeps the records of the long-running tasks, so that they can be
+// listed, inspected, cancelled and retried in a uniform way. The records are
+// persisted, and the progresses are refreshed by the node state check job, the
+// reads only return the records refreshed last time.
+type taskManager struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	tasks   map[uint64]*endpoint.TaskRecord
+	runners map[uint64]taskRunner
+	// retrying is the tasks whose actions are being started by the retries, so
+	// that a task is never retried concurrently.
+	retrying map[uint64]struct{}
+}
+
+func newTaskManager(cluster *RaftCluster) *taskManager {
+	return &taskManager{
+		cluster:  cluster,
+		tasks:    make(map[uint64]*endpoint.TaskRecord),
+		runners:  make(map[uint64]taskRunner),
+		retrying: make(map[uint64]struct{}),
+	}
+}
+
+func isTaskEnded(state string) bool {
+	return state != TaskStateRunning
+}
+
+// restore loads the task records from the storage. The running tasks whose
+// actions can't be resumed are marked as failed.
+func (m *taskManager) restore() {
+	records, err := m.cluster.storage.LoadTasks()
+	if err != nil {
+		log.Warn("failed to load tasks", errs.ZapError(err))
+		return
+	}
+	m.Lock()
+	defer m.Unlock()
+	for _, task := range records {
+		m.tasks[task.ID] = task
+		if isTaskEnded(task.State) {
+			continue
+		}
+		if adapter, ok := taskAdapters[task.Type]; ok {
+			if runner := adapter.resume(m.cluster, task.Params); runner != nil {
+				m.runners[task.ID] = runner
+				continue
+			}
+		}
+		m.endLocked(task, TaskStateFailed, "interrupted by the leader change")
+	}
+	log.Info("restored tasks", zap.Int("count", len(records)), zap.Int("running", len(m.runners)))
+}
+
+// submit starts a new task of the given type.
+func (m *taskManager) submit(typ string, params json.RawMessage) (*endpoint.TaskRecord, error) {
+	adapter, ok := taskAdapters[typ]
+	if !ok {
+		return nil, errs.ErrTaskInvalid.FastGenByArgs(typ, "unknown task type")
+	}
+	id, err := m.cluster.id.Alloc()
+	if err != nil {
+		return nil, err
+	}
+	runner, err := adapter.start(m.cluster, params)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	task := &endpoint.TaskRecord{
+		ID:        id,
+		Type:      typ,
+		Params:    params,
+		State:     TaskStateRunning,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	m.tasks[id] = task
+	m.runners[id] = runner
+	m.refreshLocked(id)
+	return m.copyLocked(id), nil
+}
+
+// refresh checks the progresses of all running tasks. The runners are checked
+// without holding the lock, so the reads are not blocked by the checks.
+func (m *taskManager) refresh() {
+	m.Lock()
+	runners := make(map[uint64]taskRunner, len(m.runners))
+	for id, runner := range m.runners {
+		runners[id] = runner
+	}
+	m.Unlock()
+
+	for id, runner := range runners {
+		progress, done, err := runner.check()
+		details := taskDetails(runner)
+		m.Lock()
+		// the task may be cancelled or retried while it is being checked.
+		if m.runners[id] == runner {
+			m.updateLocked(id, progress, done, err, details)
+		}
+		m.Unlock()
+	}
+}
+
+func (m *taskManager) refreshLocked(id uint64) {
+	runner := m.runners[id]
+	if runner == nil {
+		return
+	}
+	progress, done, err := runner.check()
+	m.updateLocked(id, progress, done, err, taskDetails(runner))
+}
+
+func (m *taskManager) updateLocked(id uint64, progress float64, done bool, err error, details []byte) {
+	task := m.tasks[id]
+	changed := details != nil && !bytes.Equal(details, task.Details)
+	if changed {
+		task.Details = details
+	}
+	switch {
+	case err != nil:
+		task.Progress = progress
+		m.endLocked(task, TaskStateFailed, err.Error())
+	case done:
+		task.Progress = 1
+		m.endLocked(task, TaskStateSucceeded, "")
+	case progress != task.Progress || changed:
+		task.Progress = progress
+		task.UpdatedAt = time.Now()
+		m.saveLocked(task)
+	}
+}
+
+// taskDetails returns the details reported by the runner, or nil if the runner
+// doesn't report them.
+func taskDetails(runner taskRunner) []byte {
+	reporter, ok := runner.(taskDetailsReporter)
+	if !ok {
+		return nil
+	}
+	details, err := json.Marshal(reporter.details())
+	if err != nil {
+		return nil
+	}
+	return details
+}
+
+func (m *taskManager) endLocked(task *endpoint.TaskRecord, state, reason string) {
+	delete(m.runners, task.ID)
+	task.State, task.Error, task.UpdatedAt = state, reason, time.Now()
+	m.saveLocked(task)
+	log.Info("task is ended", zap.Uint64("task-id", task.ID), zap.String("type", task.Type),
+		zap.String("state", state), zap.String("reason", reason))
+
+	var ended []*endpoint.TaskRecord
+	for _, t := range m.tasks {
+		if isTaskEnded(t.State) {
+			ended = append(ended, t)
+		}
+	}
+	if len(ended) <= maxEndedTasks {
+		return
+	}
+	sort.Slice(ended, func(i, j int) bool { return ended[i].UpdatedAt.Before(ended[j].UpdatedAt) })
+	for _, t := range ended[:len(ended)-maxEndedTasks] {
+		if err := m.cluster.storage.DeleteTask(t.ID); err != nil {
+			log.Warn("failed to delete task", zap.Uint64("task-id", t.ID), errs.ZapError(err))
+			continue
+		}
+		delete(m.tasks, t.ID)
+	}
+}
+
+func (m *taskManager) saveLocked(task *endpoint.TaskRecord) {
+	if err := m.cluster.storage.SaveTask(task); err != nil {
+		log.Warn("failed to save task", zap.Uint64("task-id", task.ID), errs.ZapError(err))
+	}
+}
+
+func (m *taskManager) copyLocked(id uint64) *endpoint.TaskRecord {
+	task := *m.tasks[id]
+	return &task
+}
+
+func (m *taskManager) get(id uint64) (*endpoint.TaskRecord, error) {
+	m.Lock()
+	defer m.Unlock()
+	if _, ok := m.tasks[id]; !ok {
+		return nil, errs.ErrTaskNotFound.FastGenByArgs(id)
+	}
+	return m.copyLocked(id), nil
+}
+
+func (m *taskManager) list() []*endpoint.TaskRecord {
+	m.Lock()
+	defer m.Unlock()
+	tasks := make([]*endpoint.TaskRecord, 0, len(m.tasks))
+	for id := range m.tasks {
+		tasks = append(tasks, m.copyLocked(id))
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+	return tasks
+}
+
+// cancel cancels a running task. The runner is called without holding the
+// lock, since cancelling the action may take the cluster lock.
+func (m *taskManager) cancel(id uint64) (*endpoint.TaskRecord, error) {
+	m.Lock()
+	task, ok := m.tasks[id]
+	if !ok {
+		m.Unlock()
+		return nil, errs.ErrTaskNotFound.FastGenByArgs(id)
+	}
+	runner := m.runners[id]
+	if runner == nil {
+		m.Unlock()
+		return nil, errs.ErrTaskState.FastGenByArgs("cancel", id, task.State)
+	}
+	m.Unlock()
+
+	if err := runner.cancel(); err != nil {
+		return nil, err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	// the task may be ended when it is being cancelled.
+	if m.runners[id] == runner {
+		m.endLocked(task, TaskStateCancelled, "")
+	}
+	return m.copyLocked(id), nil
+}
+
+// retry restarts a failed or cancelled task with the same params. The task is
+// marked as retrying while its action is being started, so the concurrent
+// retries are rejected instead of starting the action again.
+func (m *taskManager) retry(id uint64) (*endpoint.TaskRecord, error) {
+	m.Lock()
+	task, ok := m.tasks[id]
+	if !ok {
+		m.Unlock()
+		return nil, errs.ErrTaskNotFound.FastGenByArgs(id)
+	}
+	if _, ok := m.retrying[id]; ok {
+		m.Unlock()
+		return nil, errs.ErrTaskState.FastGenByArgs("retry", id, "retrying")
+	}
+	if task.State != TaskStateFailed && task.State != TaskStateCancelled {
+		m.Unlock()
+		return nil, errs.ErrTaskState.FastGenByArgs("retry", id, task.State)
+	}
+	typ, params := task.Type, task.Params
+	m.retrying[id] = struct{}{}
+	m.Unlock()
+	defer func() {
+		m.Lock()
+		delete(m.retrying, id)
+		m.Unlock()
+	}()
+
+	adapter, ok := taskAdapters[typ]
+	if !ok {
+		return nil, errs.ErrTaskInvalid.FastGenByArgs(typ, "unknown task type")
+	}
+	runner, err := adapter.start(m.cluster, params)
+	if err != nil {
+		return nil, err
+	}
+
+	m.Lock()
+	defer m.Unlock()
+	task.State, task.Progress, task.Error, task.Details = TaskStateRunning, 0, "", nil
+	task.Retries++
+	task.UpdatedAt = time.Now()
+	m.runners[id] = runner
+	m.saveLocked(task)
+	m.refreshLocked(id)
+	return m.copyLocked(id), nil
+}
+
+// SubmitTask starts a long-running task of the given type with the params.
+func (c *RaftCluster) SubmitTask(typ string, params json.RawMessage) (*endpoint.TaskRecord, error) {
+	return c.tasks.submit(typ, params)
+}
+
+// GetTasks returns all tasks in the order of their IDs.
+func (c *RaftCluster) GetTasks() []*endpoint.TaskRecord {
+	return c.tasks.list()
+}
+
+// GetTask returns the task with the given ID.
+func (c *RaftCluster) GetTask(id uint64) (*endpoint.TaskRecord, error) {
+	return c.tasks.get(id)
+}
+
+// CancelTask cancels a running task.
+func (c *RaftCluster) CancelTask(id uint64) (*endpoint.TaskRecord, error) {
+	return c.tasks.cancel(id)
+}
+
+// RetryTask restarts a failed or cancelled task.
+func (c *RaftCluster) RetryTask(id uint64) (*endpoint.TaskRecord, error) {
+	return c.tasks.retry(id)
+}
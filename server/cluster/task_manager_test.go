@@ -0,0 +1,256 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/hbstream"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/storage"
+	"github.com/tikv/pd/server/storage/endpoint"
+)
+
+func newTestTaskCluster(ctx context.Context, re *require.Assertions) *RaftCluster {
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	for _, store := range newTestStores(4, "6.0.0") {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	return cluster
+}
+
+func TestStoreDrainTask(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := newTestTaskCluster(ctx, re)
+
+	task, err := cluster.SubmitTask(TaskTypeStoreDrain, json.RawMessage(`{"store_id": 1}`))
+	re.NoError(err)
+	re.Equal(TaskStateRunning, task.State)
+	re.True(cluster.GetStore(1).IsRemoving())
+
+	_, err = cluster.RetryTask(task.ID)
+	re.True(errs.ErrTaskState.Equal(err))
+
+	// the store is up again after the task is cancelled.
+	task, err = cluster.CancelTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateCancelled, task.State)
+	re.True(cluster.GetStore(1).IsUp())
+	_, err = cluster.CancelTask(task.ID)
+	re.True(errs.ErrTaskState.Equal(err))
+
+	task, err = cluster.RetryTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateRunning, task.State)
+	re.Equal(1, task.Retries)
+	re.True(cluster.GetStore(1).IsRemoving())
+
+	// cancelling the task which doesn't start the drain keeps the store removing.
+	shared, err := cluster.SubmitTask(TaskTypeStoreDrain, json.RawMessage(`{"store_id": 1}`))
+	re.NoError(err)
+	_, err = cluster.CancelTask(shared.ID)
+	re.NoError(err)
+	re.True(cluster.GetStore(1).IsRemoving())
+	// a task being retried can't be retried again.
+	cluster.tasks.Lock()
+	cluster.tasks.retrying[shared.ID] = struct{}{}
+	cluster.tasks.Unlock()
+	_, err = cluster.RetryTask(shared.ID)
+	re.True(errs.ErrTaskState.Equal(err))
+	re.True(cluster.GetStore(1).IsRemoving())
+
+	re.NoError(cluster.BuryStore(1, false))
+	cluster.tasks.refresh()
+	task, err = cluster.GetTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateSucceeded, task.State)
+	re.Equal(1.0, task.Progress)
+
+	// the records are persisted.
+	records, err := cluster.storage.LoadTasks()
+	re.NoError(err)
+	re.Len(records, 2)
+	re.Equal(TaskStateSucceeded, records[0].State)
+	re.Equal(TaskStateCancelled, records[1].State)
+}
+
+func TestInvalidTask(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := newTestTaskCluster(ctx, re)
+
+	_, err := cluster.SubmitTask("unknown", nil)
+	re.True(errs.ErrTaskInvalid.Equal(err))
+	_, err = cluster.SubmitTask(TaskTypeScatter, json.RawMessage(`{}`))
+	re.True(errs.ErrTaskInvalid.Equal(err))
+	_, err = cluster.SubmitTask(TaskTypeStoreDrain, json.RawMessage(`{"store_id": 10}`))
+	re.True(errs.ErrStoreNotFound.Equal(err))
+	_, err = cluster.GetTask(10)
+	re.True(errs.ErrTaskNotFound.Equal(err))
+	re.Empty(cluster.GetTasks())
+}
+
+func TestCacheRebuildTask(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := newTestTaskCluster(ctx, re)
+	cluster.regionStats = statistics.NewRegionStatistics(cluster.GetOpts(), cluster.ruleManager, cluster.storeConfigManager)
+	// The observer is not running, the rebuild applies the queued updates itself.
+	cluster.statsObserver = newStatisticsObserver(cluster)
+	for _, region := range newTestRegions(100, 4, 3) {
+		re.NoError(cluster.putRegion(region))
+	}
+
+	task, err := cluster.SubmitTask(TaskTypeCacheRebuild, nil)
+	re.NoError(err)
+	re.Eventually(func() bool {
+		cluster.tasks.refresh()
+		cur, err := cluster.GetTask(task.ID)
+		return err == nil && cur.State == TaskStateSucceeded
+	}, 5*time.Second, 10*time.Millisecond)
+	task, err = cluster.GetTask(task.ID)
+	re.NoError(err)
+	re.Equal(1.0, task.Progress)
+	cluster.statsObserver.mu.Lock()
+	re.Empty(cluster.statsObserver.mu.tasks)
+	cluster.statsObserver.mu.Unlock()
+}
+
+func TestRestoreTasks(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := newTestTaskCluster(ctx, re)
+
+	drain, err := cluster.SubmitTask(TaskTypeStoreDrain, json.RawMessage(`{"store_id": 2}`))
+	re.NoError(err)
+	re.NoError(cluster.storage.SaveTask(&endpoint.TaskRecord{
+		ID:     drain.ID + 1,
+		Type:   TaskTypeScatter,
+		Params: json.RawMessage(`{"regions_id": [1]}`),
+		State:  TaskStateRunning,
+	}))
+
+	// the store drain is resumed while the scatter is lost with the previous leader.
+	cluster.tasks = newTaskManager(cluster)
+	cluster.tasks.restore()
+	tasks := cluster.GetTasks()
+	re.Len(tasks, 2)
+	re.Equal(drain.ID, tasks[0].ID)
+	re.Equal(TaskStateRunning, tasks[0].State)
+	re.Equal(TaskStateFailed, tasks[1].State)
+	re.NotEmpty(tasks[1].Error)
+}
+
+func TestRegionMergeTask(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := newTestTaskCluster(ctx, re)
+	cluster.coordinator = newCoordinator(ctx, cluster, hbstream.NewTestHeartbeatStreams(ctx, cluster.meta.GetId(), cluster, true))
+	cluster.regionLabeler, _ = labeler.NewRegionLabeler(ctx, cluster.storage, time.Second*5)
+	keys := []string{"", "b", "d", ""}
+	for i := 0; i < 3; i++ {
+		id := uint64(i + 1)
+		peers := []*metapb.Peer{{Id: id*10 + 1, StoreId: 1}, {Id: id*10 + 2, StoreId: 2}, {Id: id*10 + 3, StoreId: 3}}
+		region := &metapb.Region{
+			Id:          id,
+			StartKey:    []byte(keys[i]),
+			EndKey:      []byte(keys[i+1]),
+			Peers:       peers,
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 2, Version: 2},
+		}
+		re.NoError(cluster.putRegion(core.NewRegionInfo(region, peers[0], core.SetApproximateSize(10), core.SetApproximateKeys(100))))
+	}
+
+	_, err := cluster.SubmitTask(TaskTypeRegionMerge, json.RawMessage(`{"source_id": 1, "target_id": 3}`))
+	re.True(errs.ErrRegionNotMergeable.Equal(err))
+	_, err = cluster.SubmitTask(TaskTypeRegionMerge, json.RawMessage(`{"source_id": 1, "target_id": 4}`))
+	re.True(errs.ErrTaskInvalid.Equal(err))
+
+	task, err := cluster.SubmitTask(TaskTypeRegionMerge, json.RawMessage(`{"source_id": 2, "target_id": 1}`))
+	re.NoError(err)
+	re.Equal(TaskStateRunning, task.State)
+	var halves []RegionMergeHalf
+	re.NoError(json.Unmarshal(task.Details, &halves))
+	re.Equal([]RegionMergeHalf{
+		{RegionID: 2, Role: "source", Status: "Started"},
+		{RegionID: 1, Role: "target", Status: "Started"},
+	}, halves)
+	// region 2 already has an operator.
+	_, err = cluster.SubmitTask(TaskTypeRegionMerge, json.RawMessage(`{"source_id": 3, "target_id": 2}`))
+	re.True(errs.ErrTaskConflict.Equal(err))
+
+	// the task fails once either half fails, and the other half is removed.
+	oc := cluster.GetOperatorController()
+	re.True(oc.RemoveOperator(oc.GetOperator(1)))
+	// the task is refreshed by the background job only.
+	task, err = cluster.GetTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateRunning, task.State)
+	cluster.tasks.refresh()
+	task, err = cluster.GetTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateFailed, task.State)
+	re.Contains(task.Error, "region 1")
+	re.Nil(oc.GetOperator(2))
+	re.NoError(json.Unmarshal(task.Details, &halves))
+	re.Equal("Canceled", halves[0].Status)
+	re.Equal("Canceled", halves[1].Status)
+
+	// the regions are mergeable again after the retry.
+	task, err = cluster.RetryTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateRunning, task.State)
+	re.NotNil(oc.GetOperator(2))
+
+	// the task is resumed after the leader changes, and it is done once the
+	// source region is merged.
+	cluster.tasks = newTaskManager(cluster)
+	cluster.tasks.restore()
+	task, err = cluster.GetTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateRunning, task.State)
+	peers := []*metapb.Peer{{Id: 11, StoreId: 1}, {Id: 12, StoreId: 2}, {Id: 13, StoreId: 3}}
+	merged := &metapb.Region{
+		Id:          1,
+		StartKey:    []byte(""),
+		EndKey:      []byte("d"),
+		Peers:       peers,
+		RegionEpoch: &metapb.RegionEpoch{ConfVer: 2, Version: 3},
+	}
+	re.NoError(cluster.putRegion(core.NewRegionInfo(merged, peers[0])))
+	re.Nil(cluster.GetRegion(2))
+	cluster.tasks.refresh()
+	task, err = cluster.GetTask(task.ID)
+	re.NoError(err)
+	re.Equal(TaskStateSucceeded, task.State)
+}
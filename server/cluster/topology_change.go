@@ -0,0 +1,130 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+	"go.uber.org/zap"
+)
+
+const topologyChangeCheckInterval = time.Second
+
+// topologyChangeDetector finds the stores whose location labels are changed,
+// e.g. by renumbering the racks, and adds the regions with peers on them to the
+// suspect list at a throttled rate, so that the regions are re-fitted to the new
+// topology without waiting for the patrol.
+type topologyChangeDetector struct {
+	syncutil.Mutex
+	cluster *RaftCluster
+	// pending is the regions waiting to be added to the suspect list.
+	pending []uint64
+	queued  map[uint64]struct{}
+}
+
+func newTopologyChangeDetector(cluster *RaftCluster) *topologyChangeDetector {
+	return &topologyChangeDetector{
+		cluster: cluster,
+		queued:  make(map[uint64]struct{}),
+	}
+}
+
+// observe checks whether the location labels of the store are changed, and
+// enqueues the regions of the store if so.
+func (d *topologyChangeDetector) observe(origin, store *core.StoreInfo) {
+	if origin == nil || d.cluster.opt.GetTopologyChangeRegionRate() == 0 {
+		return
+	}
+	var changed []string
+	for _, key := range d.cluster.opt.GetLocationLabels() {
+		if origin.GetLabelValue(key) != store.GetLabelValue(key) {
+			changed = append(changed, key)
+		}
+	}
+	if len(changed) == 0 {
+		return
+	}
+	regions := d.cluster.core.GetStoreRegions(store.GetID())
+	log.Info("location labels of store are changed, its regions will be checked",
+		zap.Uint64("store-id", store.GetID()),
+		zap.Strings("label-keys", changed),
+		zap.Int("region-count", len(regions)))
+	topologyChangeCounter.WithLabelValues("store_changed").Inc()
+
+	d.Lock()
+	defer d.Unlock()
+	for _, region := range regions {
+		if _, ok := d.queued[region.GetID()]; ok {
+			continue
+		}
+		d.queued[region.GetID()] = struct{}{}
+		d.pending = append(d.pending, region.GetID())
+	}
+	topologyChangePendingGauge.Set(float64(len(d.pending)))
+}
+
+// pop returns at most limit pending regions.
+func (d *topologyChangeDetector) pop(limit int) []uint64 {
+	d.Lock()
+	defer d.Unlock()
+	if limit > len(d.pending) {
+		limit = len(d.pending)
+	}
+	ids := make([]uint64, limit)
+	copy(ids, d.pending)
+	d.pending = d.pending[limit:]
+	for _, id := range ids {
+		delete(d.queued, id)
+	}
+	topologyChangePendingGauge.Set(float64(len(d.pending)))
+	return ids
+}
+
+// pendingCount returns the number of the regions waiting to be checked.
+func (d *topologyChangeDetector) pendingCount() int {
+	d.Lock()
+	defer d.Unlock()
+	return len(d.pending)
+}
+
+// run adds the pending regions to the suspect list until the context is done.
+func (d *topologyChangeDetector) run(ctx context.Context) {
+	ticker := time.NewTicker(topologyChangeCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			d.enqueueSuspectRegions()
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+func (d *topologyChangeDetector) enqueueSuspectRegions() {
+	rate := d.cluster.opt.GetTopologyChangeRegionRate()
+	if rate == 0 {
+		return
+	}
+	limit := int(rate * uint64(topologyChangeCheckInterval/time.Second))
+	if ids := d.pop(limit); len(ids) > 0 {
+		d.cluster.AddSuspectRegions(ids...)
+		topologyChangeCounter.WithLabelValues("region_enqueued").Add(float64(len(ids)))
+	}
+}
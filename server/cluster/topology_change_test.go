@@ -0,0 +1,81 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestTopologyChangeDetector(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	cluster.topologyChanges = newTopologyChangeDetector(cluster)
+	cluster.opt.SetLocationLabels([]string{"zone", "rack"})
+
+	for _, store := range newTestStores(4, "6.0.0") {
+		meta := store.GetMeta()
+		meta.Labels = []*metapb.StoreLabel{{Key: "zone", Value: "z1"}, {Key: "rack", Value: "r1"}}
+		re.NoError(cluster.PutStore(meta))
+	}
+	// The peers of the regions are on the stores i%4 and (i+1)%4, so store 1 has the regions 0, 1, 4 and 5.
+	for _, region := range newTestRegions(6, 4, 2) {
+		re.NoError(cluster.putRegion(region))
+	}
+	re.Equal(0, cluster.topologyChanges.pendingCount())
+
+	// The label which is not a location label is ignored.
+	re.NoError(cluster.UpdateStoreLabels(1, []*metapb.StoreLabel{{Key: "disk", Value: "ssd"}}, false))
+	re.Equal(0, cluster.topologyChanges.pendingCount())
+	// The same location labels are ignored.
+	re.NoError(cluster.UpdateStoreLabels(1, []*metapb.StoreLabel{{Key: "rack", Value: "r1"}}, false))
+	re.Equal(0, cluster.topologyChanges.pendingCount())
+
+	re.NoError(cluster.UpdateStoreLabels(1, []*metapb.StoreLabel{{Key: "rack", Value: "r2"}}, false))
+	re.Equal(4, cluster.topologyChanges.pendingCount())
+	// Store 2 has the regions 1, 2 and 5, the regions already pending are not enqueued again.
+	re.NoError(cluster.UpdateStoreLabels(2, []*metapb.StoreLabel{{Key: "rack", Value: "r2"}}, false))
+	re.Equal(5, cluster.topologyChanges.pendingCount())
+
+	// The regions are added to the suspect list at a throttled rate.
+	cfg := opt.GetScheduleConfig().Clone()
+	cfg.TopologyChangeRegionRate = 4
+	opt.SetScheduleConfig(cfg)
+	cluster.topologyChanges.enqueueSuspectRegions()
+	re.Equal(1, cluster.topologyChanges.pendingCount())
+	re.Len(cluster.GetSuspectRegions(), 4)
+	cluster.topologyChanges.enqueueSuspectRegions()
+	re.Equal(0, cluster.topologyChanges.pendingCount())
+	re.Len(cluster.GetSuspectRegions(), 5)
+
+	// The detection is disabled.
+	cfg = opt.GetScheduleConfig().Clone()
+	cfg.TopologyChangeRegionRate = 0
+	opt.SetScheduleConfig(cfg)
+	re.NoError(cluster.UpdateStoreLabels(3, []*metapb.StoreLabel{{Key: "rack", Value: "r2"}}, false))
+	re.Equal(0, cluster.topologyChanges.pendingCount())
+}
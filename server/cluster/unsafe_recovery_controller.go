@@ -33,6 +33,8 @@ import (
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
 	"go.uber.org/zap"
 )
 
@@ -40,6 +42,10 @@ type unsafeRecoveryStage int
 
 const (
 	storeRequestInterval = time.Second * 40
+	// recoveryExemptionDuration is how long the operators repairing the regions
+	// changed by the unsafe recovery are exempted from the store limits after
+	// the recovery ends.
+	recoveryExemptionDuration = time.Hour
 )
 
 // Stage transition graph: for more details, please check `unsafeRecoveryController.HandleStoreHeartbeat()`
@@ -123,6 +129,11 @@ type unsafeRecoveryController struct {
 	affectedTableIDs    map[int64]struct{}
 	affectedMetaRegions map[uint64]struct{}
 	err                 error
+
+	// recoveredRegions are the regions demoted or created by the recovery, the
+	// operators repairing them are exempted from the store limits until exemptUntil.
+	recoveredRegions map[uint64]struct{}
+	exemptUntil      time.Time
 }
 
 // StageOutput is the information for one stage of the recovery process.
@@ -153,6 +164,8 @@ func (u *unsafeRecoveryController) reset() {
 	u.affectedTableIDs = make(map[int64]struct{}, 0)
 	u.affectedMetaRegions = make(map[uint64]struct{}, 0)
 	u.err = nil
+	u.recoveredRegions = make(map[uint64]struct{})
+	u.exemptUntil = time.Time{}
 }
 
 // IsRunning returns whether there is ongoing unsafe recovery process. If yes, further unsafe
@@ -468,9 +481,11 @@ func (u *unsafeRecoveryController) changeStage(stage unsafeRecoveryStage) {
 	case demoteFailedVoter:
 		output.Info = "Unsafe recovery enters demote failed voter stage"
 		output.Actions = u.getDemoteFailedVoterPlanDigest()
+		u.recordRecoveredRegions()
 	case createEmptyRegion:
 		output.Info = "Unsafe recovery enters create empty region stage"
 		output.Actions = u.getCreateEmptyRegionPlanDigest()
+		u.recordRecoveredRegions()
 	case exitForceLeader:
 		output.Info = "Unsafe recovery enters exit force leader stage"
 		if u.err != nil {
@@ -483,11 +498,13 @@ func (u *unsafeRecoveryController) changeStage(stage unsafeRecoveryStage) {
 		}
 		output.Info = "Unsafe recovery finished"
 		output.Details = u.getAffectedTableDigest()
+		u.exemptUntil = time.Now().Add(recoveryExemptionDuration)
 		u.storePlanExpires = make(map[uint64]time.Time)
 		u.storeRecoveryPlans = make(map[uint64]*pdpb.RecoveryPlan)
 	case failed:
 		output.Info = fmt.Sprintf("Unsafe recovery failed: %v", u.err)
 		output.Details = u.getAffectedTableDigest()
+		u.exemptUntil = time.Now().Add(recoveryExemptionDuration)
 		if u.numStoresReported != len(u.storeReports) {
 			// in collecting reports, print out which stores haven't reported yet
 			output.Details = append(output.Details, u.getReportStatus().Details...)
@@ -513,6 +530,35 @@ func (u *unsafeRecoveryController) changeStage(stage unsafeRecoveryStage) {
 	u.step += 1
 }
 
+// recordRecoveredRegions records the regions demoted or created by the plans
+// of the current stage.
+func (u *unsafeRecoveryController) recordRecoveredRegions() {
+	for _, plan := range u.storeRecoveryPlans {
+		for _, demote := range plan.GetDemotes() {
+			u.recoveredRegions[demote.GetRegionId()] = struct{}{}
+		}
+		for _, region := range plan.GetCreates() {
+			u.recoveredRegions[region.GetId()] = struct{}{}
+		}
+	}
+}
+
+// exemptOperators exempts the operators repairing the regions changed by the
+// recovery from the store limits for a while after it ends, so that the lost
+// replicas are restored without being blocked by the limits.
+func (u *unsafeRecoveryController) exemptOperators(ops []*operator.Operator) {
+	u.RLock()
+	defer u.RUnlock()
+	if len(u.recoveredRegions) == 0 || time.Now().After(u.exemptUntil) {
+		return
+	}
+	for _, op := range ops {
+		if _, ok := u.recoveredRegions[op.RegionID()]; ok && isRepairOperator(op) {
+			op.SetLimitExemption(operator.UnsafeRecoveryExemption)
+		}
+	}
+}
+
 func (u *unsafeRecoveryController) getForceLeaderPlanDigest() map[string][]string {
 	outputs := make(map[string][]string)
 	for storeID, plan := range u.storeRecoveryPlans {
@@ -646,6 +692,60 @@ func (u *unsafeRecoveryController) canElectLeader(region *metapb.Region, onlyInc
 	return hasQuorum(incomingVoters) && (onlyIncoming || hasQuorum(outgoingVoters))
 }
 
+// isWitness returns whether the peer on the store is a witness by the placement
+// rules of the key range, that is the store only matches the witness rules. The
+// witness holds no data, so it can't be the leader of the recovered region.
+func (u *unsafeRecoveryController) isWitness(startKey, endKey []byte, storeID uint64) bool {
+	store := u.cluster.GetStore(storeID)
+	if store == nil || !u.cluster.GetOpts().IsPlacementRulesEnabled() {
+		return false
+	}
+	witness := false
+	for _, rule := range u.cluster.GetRuleManager().GetRulesForApplyRange(startKey, endKey) {
+		if !placement.MatchLabelConstraints(store, rule.LabelConstraints) {
+			continue
+		}
+		if !rule.IsWitness {
+			return false
+		}
+		witness = true
+	}
+	return witness
+}
+
+// canHostLeader returns whether the peer on the store can be the leader of the
+// key range. Neither the TiFlash store nor the witness can. The peer forbidden
+// to be voter by the placement rules still can, the rule checker moves it after
+// the recovery.
+func (u *unsafeRecoveryController) canHostLeader(startKey, endKey []byte, storeID uint64) bool {
+	store := u.cluster.GetStore(storeID)
+	if store == nil || store.IsTiFlash() {
+		return false
+	}
+	return !u.isWitness(startKey, endKey, storeID)
+}
+
+// isVoterByRules returns whether the store matches a voter or leader rule of the
+// key range, the newly created region prefers such stores so that the rule
+// checker needn't move it afterwards.
+func (u *unsafeRecoveryController) isVoterByRules(startKey, endKey []byte, storeID uint64) bool {
+	if !u.cluster.GetOpts().IsPlacementRulesEnabled() {
+		return true
+	}
+	store := u.cluster.GetStore(storeID)
+	rules := u.cluster.GetRuleManager().GetRulesForApplyRange(startKey, endKey)
+	if len(rules) == 0 {
+		return true
+	}
+	for _, rule := range rules {
+		if !rule.IsWitness && (rule.Role == placement.Voter || rule.Role == placement.Leader) &&
+			placement.MatchLabelConstraints(store, rule.LabelConstraints) {
+			return true
+		}
+	}
+	return false
+}
+
 func (u *unsafeRecoveryController) getFailedPeers(region *metapb.Region) []*metapb.Peer {
 	// if it can form a quorum after exiting the joint state, then no need to demotes any peer
 	if u.canElectLeader(region, true) {
@@ -876,12 +976,23 @@ func (u *unsafeRecoveryController) buildUpFromReports() (*regionTree, map[uint64
 }
 
 func (u *unsafeRecoveryController) selectLeader(peersMap map[uint64][]*regionItem, region *metapb.Region) *regionItem {
-	var leader *regionItem
+	// prefer the peers holding data, the witness is only selected when no other
+	// peer survives
+	var leader, witness *regionItem
 	for _, peer := range peersMap[region.GetId()] {
+		if u.isWitness(region.GetStartKey(), region.GetEndKey(), peer.storeID) {
+			if witness == nil || witness.IsRaftStale(peer, u) {
+				witness = peer
+			}
+			continue
+		}
 		if leader == nil || leader.IsRaftStale(peer, u) {
 			leader = peer
 		}
 	}
+	if leader == nil {
+		return witness
+	}
 	return leader
 }
 
@@ -900,8 +1011,8 @@ func (u *unsafeRecoveryController) generateTombstoneTiFlashLearnerPlan(newestReg
 				return false
 			}
 			storeID := leader.storeID
-			if u.cluster.GetStore(storeID).IsTiFlash() {
-				// tombstone the tiflash learner, as it can't be leader
+			if !u.canHostLeader(region.GetStartKey(), region.GetEndKey(), storeID) {
+				// tombstone the tiflash learner or the witness, as it can't be leader
 				storeRecoveryPlan := u.getRecoveryPlan(storeID)
 				storeRecoveryPlan.Tombstones = append(storeRecoveryPlan.Tombstones, region.GetId())
 				u.recordAffectedRegion(region)
@@ -1064,13 +1175,18 @@ func (u *unsafeRecoveryController) generateCreateEmptyRegionPlan(newestRegionTre
 		}, nil
 	}
 
-	getRandomStoreID := func() uint64 {
+	getRandomStoreID := func(startKey, endKey []byte) uint64 {
+		var candidate uint64
 		for storeID := range u.storeReports {
-			if !u.cluster.GetStore(storeID).IsTiFlash() {
+			if !u.canHostLeader(startKey, endKey, storeID) {
+				continue
+			}
+			if u.isVoterByRules(startKey, endKey, storeID) {
 				return storeID
 			}
+			candidate = storeID
 		}
-		return 0
+		return candidate
 	}
 
 	// There may be ranges that are covered by no one. Find these empty ranges, create new
@@ -1081,11 +1197,12 @@ func (u *unsafeRecoveryController) generateCreateEmptyRegionPlan(newestRegionTre
 		region := item.(*regionItem).Region()
 		storeID := item.(*regionItem).storeID
 		if !bytes.Equal(region.StartKey, lastEnd) {
-			if u.cluster.GetStore(storeID).IsTiFlash() {
-				storeID = getRandomStoreID()
-				// can't create new region on tiflash store, choose a random one
+			if !u.canHostLeader(lastEnd, region.StartKey, storeID) || !u.isVoterByRules(lastEnd, region.StartKey, storeID) {
+				storeID = getRandomStoreID(lastEnd, region.StartKey)
+				// can't create new region on tiflash store or the witness, and the store
+				// forbidden to be voter by the placement rules is avoided, choose a random one
 				if storeID == 0 {
-					u.err = errors.New("can't find available store(exclude tiflash) to create new region")
+					u.err = errors.New("can't find available store(exclude tiflash and witness) to create new region")
 					return false
 				}
 			}
@@ -1127,11 +1244,11 @@ func (u *unsafeRecoveryController) generateCreateEmptyRegionPlan(newestRegionTre
 	}
 
 	if !bytes.Equal(lastEnd, []byte("")) || newestRegionTree.size() == 0 {
-		if lastStoreID == 0 {
+		if lastStoreID == 0 || !u.canHostLeader(lastEnd, []byte(""), lastStoreID) || !u.isVoterByRules(lastEnd, []byte(""), lastStoreID) {
 			// the last store id is invalid, so choose a random one
-			lastStoreID = getRandomStoreID()
+			lastStoreID = getRandomStoreID(lastEnd, []byte(""))
 			if lastStoreID == 0 {
-				u.err = errors.New("can't find available store(exclude tiflash) to create new region")
+				u.err = errors.New("can't find available store(exclude tiflash and witness) to create new region")
 				return false
 			}
 		}
@@ -28,6 +28,8 @@ import (
 	"github.com/tikv/pd/pkg/mock/mockid"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule/hbstream"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/storage"
 )
 
@@ -221,6 +223,22 @@ func TestFinished(t *testing.T) {
 		applyRecoveryPlan(re, storeID, reports, resp)
 	}
 	re.Equal(finished, recoveryController.GetStage())
+
+	// The operators repairing the recovered regions are exempted from the store limits.
+	ops := []*operator.Operator{
+		operator.NewTestOperator(1001, &metapb.RegionEpoch{}, operator.OpRegion|operator.OpReplica, operator.AddPeer{ToStore: 1, PeerID: 12}),
+		operator.NewTestOperator(1002, &metapb.RegionEpoch{}, operator.OpRegion|operator.OpReplica, operator.AddPeer{ToStore: 1, PeerID: 13}),
+		operator.NewTestOperator(1001, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 1, PeerID: 14}),
+	}
+	recoveryController.exemptOperators(ops)
+	re.Equal(operator.UnsafeRecoveryExemption, ops[0].GetLimitExemption())
+	re.Equal(operator.NoLimitExemption, ops[1].GetLimitExemption())
+	re.Equal(operator.NoLimitExemption, ops[2].GetLimitExemption())
+	// The exemption expires after a while.
+	recoveryController.exemptUntil = time.Now().Add(-time.Second)
+	op := operator.NewTestOperator(1001, &metapb.RegionEpoch{}, operator.OpRegion|operator.OpReplica, operator.AddPeer{ToStore: 1, PeerID: 15})
+	recoveryController.exemptOperators([]*operator.Operator{op})
+	re.Equal(operator.NoLimitExemption, op.GetLimitExemption())
 }
 
 func TestFailed(t *testing.T) {
@@ -744,6 +762,172 @@ func TestTiflashLearnerPeer(t *testing.T) {
 	}
 }
 
+func TestLearnerForbiddenByPlacementRules(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, _ := newTestScheduleConfig()
+	opt.SetPlacementRuleEnabled(true)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, hbstream.NewTestHeartbeatStreams(ctx, cluster.meta.GetId(), cluster, true))
+	cluster.coordinator.run()
+	for _, store := range newTestStores(5, "6.0.0") {
+		zone := "z1"
+		if store.GetID() == 3 {
+			zone = "z2"
+		}
+		store.GetMeta().Labels = []*metapb.StoreLabel{{Key: "zone", Value: zone}}
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	// voters are only allowed in zone z1, zone z2 only holds learners.
+	re.NoError(cluster.GetRuleManager().SetRule(&placement.Rule{
+		GroupID: "pd", ID: "default", Role: placement.Voter, Count: 3,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z1"}}},
+	}))
+	re.NoError(cluster.GetRuleManager().SetRule(&placement.Rule{
+		GroupID: "pd", ID: "learner", Role: placement.Learner, Count: 1,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z2"}}},
+	}))
+	recoveryController := newUnsafeRecoveryController(cluster)
+	re.NoError(recoveryController.RemoveFailedStores(map[uint64]struct{}{
+		4: {},
+		5: {},
+	}, 60))
+
+	reports := map[uint64]*pdpb.StoreReport{
+		1: {PeerReports: []*pdpb.PeerReport{}},
+		2: {PeerReports: []*pdpb.PeerReport{}},
+		3: {PeerReports: []*pdpb.PeerReport{
+			{
+				RaftState: &raft_serverpb.RaftLocalState{LastIndex: 10, HardState: &eraftpb.HardState{Term: 1, Commit: 10}},
+				RegionState: &raft_serverpb.RegionLocalState{
+					Region: &metapb.Region{
+						Id:          1001,
+						StartKey:    []byte(""),
+						EndKey:      []byte(""),
+						RegionEpoch: &metapb.RegionEpoch{ConfVer: 7, Version: 10},
+						Peers: []*metapb.Peer{
+							{Id: 31, StoreId: 3, Role: metapb.PeerRole_Learner},
+							{Id: 32, StoreId: 4},
+							{Id: 33, StoreId: 5},
+						}}}},
+		}},
+	}
+
+	advanceUntilFinished(re, recoveryController, reports)
+
+	// the learner in zone z2 is still promoted to keep the data, the rule checker
+	// moves it after the recovery.
+	expects := map[uint64]*pdpb.StoreReport{
+		3: {PeerReports: []*pdpb.PeerReport{
+			{
+				RaftState: &raft_serverpb.RaftLocalState{LastIndex: 10, HardState: &eraftpb.HardState{Term: 1, Commit: 10}},
+				RegionState: &raft_serverpb.RegionLocalState{
+					Region: &metapb.Region{
+						Id:          1001,
+						StartKey:    []byte(""),
+						EndKey:      []byte(""),
+						RegionEpoch: &metapb.RegionEpoch{ConfVer: 8, Version: 10},
+						Peers: []*metapb.Peer{
+							{Id: 31, StoreId: 3},
+							{Id: 32, StoreId: 4, Role: metapb.PeerRole_Learner},
+							{Id: 33, StoreId: 5, Role: metapb.PeerRole_Learner},
+						}}}},
+		}},
+	}
+
+	for storeID, report := range reports {
+		if result, ok := expects[storeID]; ok {
+			re.Equal(result.PeerReports, report.PeerReports)
+		} else {
+			re.Empty(len(report.PeerReports))
+		}
+	}
+}
+
+func TestWitnessByPlacementRules(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, _ := newTestScheduleConfig()
+	opt.SetPlacementRuleEnabled(true)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, hbstream.NewTestHeartbeatStreams(ctx, cluster.meta.GetId(), cluster, true))
+	cluster.coordinator.run()
+	for _, store := range newTestStores(5, "6.0.0") {
+		zone := "z1"
+		if store.GetID() == 3 {
+			zone = "z2"
+		}
+		store.GetMeta().Labels = []*metapb.StoreLabel{{Key: "zone", Value: zone}}
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	// zone z2 only holds the witness.
+	re.NoError(cluster.GetRuleManager().SetRule(&placement.Rule{
+		GroupID: "pd", ID: "default", Role: placement.Voter, Count: 2,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z1"}}},
+	}))
+	re.NoError(cluster.GetRuleManager().SetRule(&placement.Rule{
+		GroupID: "pd", ID: "witness", Role: placement.Voter, Count: 1, IsWitness: true,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: placement.In, Values: []string{"z2"}}},
+	}))
+	recoveryController := newUnsafeRecoveryController(cluster)
+	re.NoError(recoveryController.RemoveFailedStores(map[uint64]struct{}{
+		4: {},
+		5: {},
+	}, 60))
+
+	reports := map[uint64]*pdpb.StoreReport{
+		1: {PeerReports: []*pdpb.PeerReport{}},
+		2: {PeerReports: []*pdpb.PeerReport{}},
+		3: {PeerReports: []*pdpb.PeerReport{
+			{
+				RaftState: &raft_serverpb.RaftLocalState{LastIndex: 10, HardState: &eraftpb.HardState{Term: 1, Commit: 10}},
+				RegionState: &raft_serverpb.RegionLocalState{
+					Region: &metapb.Region{
+						Id:          1001,
+						StartKey:    []byte(""),
+						EndKey:      []byte(""),
+						RegionEpoch: &metapb.RegionEpoch{ConfVer: 7, Version: 10},
+						Peers: []*metapb.Peer{
+							{Id: 31, StoreId: 3},
+							{Id: 32, StoreId: 4},
+							{Id: 33, StoreId: 5},
+						}}}},
+		}},
+	}
+
+	advanceUntilFinished(re, recoveryController, reports)
+
+	// the witness in zone z2 holds no data and can't be leader, so it is tombstoned
+	// and an empty region is created on a store holding data instead.
+	re.Empty(reports[3].PeerReports)
+	created := 0
+	for storeID, report := range reports {
+		for _, p := range report.PeerReports {
+			re.NotEqual(uint64(3), storeID)
+			re.Equal(&pdpb.PeerReport{
+				RaftState: &raft_serverpb.RaftLocalState{LastIndex: 10, HardState: &eraftpb.HardState{Term: 1, Commit: 10}},
+				RegionState: &raft_serverpb.RegionLocalState{
+					Region: &metapb.Region{
+						Id:          1,
+						StartKey:    []byte(""),
+						EndKey:      []byte(""),
+						RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+						Peers: []*metapb.Peer{
+							{Id: 2, StoreId: storeID},
+						},
+					},
+				},
+			}, p)
+			created++
+		}
+	}
+	re.Equal(1, created)
+}
+
 func TestUninitializedPeer(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -0,0 +1,130 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/coreos/go-semver/semver"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/schedule/checker"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"github.com/tikv/pd/server/versioninfo"
+	"go.uber.org/zap"
+)
+
+const (
+	versionGateKindScheduler = "scheduler"
+	versionGateKindChecker   = "checker"
+	// versionGatePauseSeconds is how long the incompatible checkers are paused,
+	// they are expected to be resumed manually after they are upgraded.
+	versionGatePauseSeconds = int64(100 * 365 * 24 * time.Hour / time.Second)
+)
+
+// checkerComponent returns the name of the checker in the version registry.
+func checkerComponent(name string) string {
+	return name + "-checker"
+}
+
+// gateVersionChangeLocked disables the enabled schedulers and checkers which do
+// not support the new cluster version before the version is bumped, so the
+// upgrade cannot break the scheduling silently. The schedulers are removed and
+// the checkers are paused, and both are recorded in the storage.
+func (c *RaftCluster) gateVersionChangeLocked(version *semver.Version) {
+	if c.coordinator == nil {
+		return
+	}
+	for name, typ := range c.coordinator.getSchedulerTypes() {
+		if versioninfo.IsComponentSupported(version, typ) {
+			continue
+		}
+		if err := c.coordinator.removeScheduler(name); err != nil {
+			log.Error("failed to remove the scheduler which does not support the cluster version",
+				zap.String("scheduler-name", name), zap.Stringer("cluster-version", version), errs.ZapError(err))
+			continue
+		}
+		c.recordVersionGate(versionGateKindScheduler, name, typ, version)
+	}
+	for _, name := range checker.CheckerNames {
+		component := checkerComponent(name)
+		if versioninfo.IsComponentSupported(version, component) {
+			continue
+		}
+		if err := c.coordinator.pauseOrResumeChecker(name, versionGatePauseSeconds); err != nil {
+			log.Error("failed to pause the checker which does not support the cluster version",
+				zap.String("checker-name", name), zap.Stringer("cluster-version", version), errs.ZapError(err))
+			continue
+		}
+		c.recordVersionGate(versionGateKindChecker, name, component, version)
+	}
+}
+
+func (c *RaftCluster) recordVersionGate(kind, name, component string, version *semver.Version) {
+	record := &endpoint.VersionGateRecord{
+		Kind:           kind,
+		Component:      name,
+		ClusterVersion: version.String(),
+		Reason:         fmt.Sprintf("%s is not supported since cluster version %s", component, versioninfo.IncompatibleVersion(component)),
+		DisabledAt:     time.Now(),
+	}
+	if err := c.storage.SaveVersionGateRecord(record); err != nil {
+		log.Error("failed to save the version gate record", zap.String("kind", kind), zap.String("name", name), errs.ZapError(err))
+	}
+	c.events.publish(&ClusterEvent{
+		Type:    EventComponentDisabled,
+		Message: fmt.Sprintf("%s %s is disabled before upgrading the cluster version to %s, %s", kind, name, version, record.Reason),
+		Attributes: map[string]string{
+			"kind":            kind,
+			"name":            name,
+			"cluster-version": version.String(),
+		},
+	})
+}
+
+// restoreVersionGate pauses the checkers which are disabled by the version gate
+// again, since the pause states of the checkers are not persisted. The removed
+// schedulers are persisted in the schedule config already.
+func (c *RaftCluster) restoreVersionGate() {
+	records, err := c.storage.LoadVersionGateRecords()
+	if err != nil {
+		log.Warn("failed to load version gate records", errs.ZapError(err))
+		return
+	}
+	for _, record := range records {
+		if record.Kind != versionGateKindChecker {
+			continue
+		}
+		if err := c.coordinator.pauseOrResumeChecker(record.Component, versionGatePauseSeconds); err != nil {
+			log.Warn("failed to pause the checker disabled by the version gate",
+				zap.String("checker-name", record.Component), errs.ZapError(err))
+		}
+	}
+}
+
+// clearVersionGate removes the version gate record of the component when it is
+// enabled manually.
+func (c *RaftCluster) clearVersionGate(kind, name string) {
+	if err := c.storage.DeleteVersionGateRecord(kind, name); err != nil {
+		log.Warn("failed to delete the version gate record", zap.String("kind", kind), zap.String("name", name), errs.ZapError(err))
+	}
+}
+
+// GetVersionGateRecords returns the schedulers and checkers disabled because
+// they do not support the cluster version.
+func (c *RaftCluster) GetVersionGateRecords() ([]*endpoint.VersionGateRecord, error) {
+	return c.storage.LoadVersionGateRecords()
+}
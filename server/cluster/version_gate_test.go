@@ -0,0 +1,96 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockid"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedulers"
+	"github.com/tikv/pd/server/storage"
+	"github.com/tikv/pd/server/versioninfo"
+)
+
+func init() {
+	versioninfo.RegisterIncompatibleVersion(schedulers.ShuffleLeaderType, "99.0.0")
+	versioninfo.RegisterIncompatibleVersion(checkerComponent("merge"), "99.0.0")
+}
+
+func TestVersionGate(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, opt, err := newTestScheduleConfig()
+	re.NoError(err)
+	cluster := newTestRaftCluster(ctx, mockid.NewIDAllocator(), opt, storage.NewStorageWithMemoryBackend(), core.NewBasicCluster())
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	shuffle, err := schedule.CreateScheduler(schedulers.ShuffleLeaderType, cluster.coordinator.opController, cluster.storage, schedule.ConfigJSONDecoder([]byte("null")))
+	re.NoError(err)
+	re.NoError(cluster.AddScheduler(shuffle))
+
+	stores := newTestStores(2, "98.0.0")
+	for _, store := range stores {
+		re.NoError(cluster.PutStore(store.GetMeta()))
+	}
+	re.Equal("98.0.0", cluster.GetClusterVersion())
+	records, err := cluster.GetVersionGateRecords()
+	re.NoError(err)
+	re.Empty(records)
+
+	// Upgrade all stores.
+	for _, store := range stores {
+		meta := store.GetMeta()
+		meta.Version = "99.0.0"
+		re.NoError(cluster.PutStore(meta))
+	}
+	re.Equal("99.0.0", cluster.GetClusterVersion())
+	exist, err := cluster.IsSchedulerExisted(shuffle.GetName())
+	re.NoError(err)
+	re.False(exist)
+	paused, err := cluster.IsCheckerPaused("merge")
+	re.NoError(err)
+	re.True(paused)
+	paused, err = cluster.IsCheckerPaused("replica")
+	re.NoError(err)
+	re.False(paused)
+	records, err = cluster.GetVersionGateRecords()
+	re.NoError(err)
+	re.Len(records, 2)
+	events := cluster.GetClusterEvents(0)
+	re.Len(events, 2)
+	for _, event := range events {
+		re.Equal(EventComponentDisabled, event.Type)
+		re.Equal("99.0.0", event.Attributes["cluster-version"])
+	}
+
+	// The paused checker is restored after the leader changes.
+	cluster.coordinator = newCoordinator(ctx, cluster, nil)
+	cluster.restoreVersionGate()
+	paused, err = cluster.IsCheckerPaused("merge")
+	re.NoError(err)
+	re.True(paused)
+
+	// The records are cleared after the components are enabled manually.
+	re.NoError(cluster.PauseOrResumeChecker("merge", 0))
+	re.NoError(cluster.AddScheduler(shuffle))
+	records, err = cluster.GetVersionGateRecords()
+	re.NoError(err)
+	re.Empty(records)
+}
@@ -15,6 +15,7 @@
 package config
 
 import (
+	"compress/gzip"
 	"crypto/tls"
 	"encoding/json"
 	"flag"
@@ -34,6 +35,7 @@ import (
 	"github.com/tikv/pd/pkg/metricutil"
 	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
 	"github.com/tikv/pd/server/versioninfo"
 
@@ -80,6 +82,13 @@ type Config struct {
 	// Etcd only supports seconds TTL, so here is second too.
 	LeaderLease int64 `toml:"lease" json:"lease"`
 
+	// EnablePreCampaignCheck enables the self health check before campaigning
+	// the PD leader. The check refuses to campaign on a node whose etcd commit
+	// is too slow or which cannot reach a quorum of stores. It's disabled by default.
+	EnablePreCampaignCheck bool `toml:"enable-pre-campaign-check" json:"enable-pre-campaign-check"`
+	// PreCampaignMaxCommitLatency is the max etcd commit latency allowed by the pre-campaign check.
+	PreCampaignMaxCommitLatency typeutil.Duration `toml:"pre-campaign-max-commit-latency" json:"pre-campaign-max-commit-latency"`
+
 	// Log related config.
 	Log log.Config `toml:"log" json:"log"`
 
@@ -164,6 +173,8 @@ type Config struct {
 	Dashboard DashboardConfig `toml:"dashboard" json:"dashboard"`
 
 	ReplicationMode ReplicationModeConfig `toml:"replication-mode" json:"replication-mode"`
+
+	GRPC GRPCConfig `toml:"grpc" json:"grpc"`
 }
 
 // NewConfig creates a new config.
@@ -231,12 +242,19 @@ const (
 
 	defaultLeaderPriorityCheckInterval = time.Minute
 
+	defaultPreCampaignMaxCommitLatency = time.Second
+
 	defaultUseRegionStorage                 = true
 	defaultTraceRegionFlow                  = true
 	defaultFlowRoundByDigit                 = 3 // KB
 	maxTraceFlowRoundByDigit                = 5 // 0.1 MB
 	defaultMaxResetTSGap                    = 24 * time.Hour
 	defaultMinResolvedTSPersistenceInterval = 0
+	defaultMinResolvedTSMissingStoreHold    = 10 * time.Minute
+	defaultStoreMetricsEmitInterval         = time.Minute
+	defaultEnableRegionCacheSafeMode        = false
+	defaultEnableKeyVisual                  = false
+	defaultTraceSampleRatio                 = 1.0
 	defaultKeyType                          = "table"
 
 	defaultStrictlyMatchLabel   = false
@@ -257,6 +275,11 @@ const (
 
 	defaultLogFormat = "text"
 
+	// The same as the defaults of the embed etcd.
+	defaultGRPCKeepaliveMinTime  = 5 * time.Second
+	defaultGRPCKeepaliveInterval = 2 * time.Hour
+	defaultGRPCKeepaliveTimeout  = 20 * time.Second
+
 	defaultMaxMovableHotPeerSize = int64(512)
 )
 
@@ -544,6 +567,7 @@ func (c *Config) Adjust(meta *toml.MetaData, reloading bool) error {
 	}
 
 	adjustInt64(&c.LeaderLease, defaultLeaderLease)
+	adjustDuration(&c.PreCampaignMaxCommitLatency, defaultPreCampaignMaxCommitLatency)
 
 	adjustDuration(&c.TSOSaveInterval, defaultTSOSaveInterval)
 
@@ -599,6 +623,8 @@ func (c *Config) Adjust(meta *toml.MetaData, reloading bool) error {
 
 	c.ReplicationMode.adjust(configMetaData.Child("replication-mode"))
 
+	c.GRPC.adjust()
+
 	c.Security.Encryption.Adjust()
 
 	if len(c.Log.Format) == 0 {
@@ -671,6 +697,10 @@ type ScheduleConfig struct {
 	ReplicaScheduleLimit uint64 `toml:"replica-schedule-limit" json:"replica-schedule-limit"`
 	// MergeScheduleLimit is the max coexist merge schedules.
 	MergeScheduleLimit uint64 `toml:"merge-schedule-limit" json:"merge-schedule-limit"`
+	// LearnerBalanceScheduleLimit is the max coexist learner balance schedules created by the checker,
+	// which moves the learners of the patrolled regions off the stores with too many learners of their
+	// engine. 0 disables the checker, the learners can still be balanced by the balance-learner-scheduler.
+	LearnerBalanceScheduleLimit uint64 `toml:"learner-balance-schedule-limit" json:"learner-balance-schedule-limit"`
 	// HotRegionScheduleLimit is the max coexist hot region schedules.
 	HotRegionScheduleLimit uint64 `toml:"hot-region-schedule-limit" json:"hot-region-schedule-limit"`
 	// HotRegionCacheHitThreshold is the cache hits threshold of the hot region.
@@ -698,8 +728,21 @@ type ScheduleConfig struct {
 	HighSpaceRatio float64 `toml:"high-space-ratio" json:"high-space-ratio"`
 	// RegionScoreFormulaVersion is used to control the formula used to calculate region score.
 	RegionScoreFormulaVersion string `toml:"region-score-formula-version" json:"region-score-formula-version"`
+	// The parameters of the transition curve of the v3 region score formula.
+	// In the transition stage, the region score rises from the region size to
+	// RegionScoreV3Amplification times of it, following a power curve with
+	// RegionScoreV3Exponent. In the low space stage, RegionScoreV3LowSpacePenalty
+	// is added gradually until the store is full.
+	RegionScoreV3Amplification   float64 `toml:"region-score-v3-amplification" json:"region-score-v3-amplification"`
+	RegionScoreV3Exponent        float64 `toml:"region-score-v3-exponent" json:"region-score-v3-exponent"`
+	RegionScoreV3LowSpacePenalty float64 `toml:"region-score-v3-low-space-penalty" json:"region-score-v3-low-space-penalty"`
 	// SchedulerMaxWaitingOperator is the max coexist operators for each scheduler.
 	SchedulerMaxWaitingOperator uint64 `toml:"scheduler-max-waiting-operator" json:"scheduler-max-waiting-operator"`
+	// WaitingOperatorFairKeyPrefixLength is the length of the region start key
+	// prefix used to group the waiting operators. The groups are promoted in
+	// turn so that the operators of a key range cannot starve the others.
+	// 0 means the waiting operators are promoted in FIFO order.
+	WaitingOperatorFairKeyPrefixLength uint64 `toml:"waiting-operator-fair-key-prefix-length" json:"waiting-operator-fair-key-prefix-length"`
 	// WARN: DisableLearner is deprecated.
 	// DisableLearner is the option to disable using AddLearnerNode instead of AddNode.
 	DisableLearner bool `toml:"disable-raft-learner" json:"disable-raft-learner,string,omitempty"`
@@ -762,6 +805,115 @@ type ScheduleConfig struct {
 	// MaxMovableHotPeerSize is the threshold of region size for balance hot region and split bucket scheduler.
 	// Hot region must be split before moved if it's region size is greater than MaxMovableHotPeerSize.
 	MaxMovableHotPeerSize int64 `toml:"max-movable-hot-peer-size" json:"max-movable-hot-peer-size,omitempty"`
+
+	// SuspectKeyRangeGCAge is the max age of the persisted suspect key ranges,
+	// the older ones are dropped without being checked.
+	SuspectKeyRangeGCAge typeutil.Duration `toml:"suspect-key-range-gc-age" json:"suspect-key-range-gc-age"`
+
+	// StoreLimitGroupLabel is the location label used to group stores for the
+	// group store limit, such as "zone". Empty means the group store limit is disabled.
+	StoreLimitGroupLabel string `toml:"store-limit-group-label" json:"store-limit-group-label"`
+	// GroupStoreLimit is the total limit of scheduling for the stores in the same group,
+	// which is shared fairly among the stores. 0 means unlimited.
+	GroupStoreLimit StoreLimitConfig `toml:"group-store-limit" json:"group-store-limit"`
+	// ClusterStoreLimit is the total limit of scheduling for all stores in the cluster,
+	// which is shared fairly among the stores. 0 means unlimited.
+	ClusterStoreLimit StoreLimitConfig `toml:"cluster-store-limit" json:"cluster-store-limit"`
+	// StoreLimitSizeRate is the limit of the total approximate size of the Regions added to
+	// or removed from a store, in MB per second. 0 means it is translated from the store limit
+	// of the operator count, taking each Region as the default Region split size.
+	StoreLimitSizeRate float64 `toml:"store-limit-size-rate" json:"store-limit-size-rate"`
+
+	// MergeHotWriteRatio is the fraction of the load-based split threshold of the store. The
+	// regions whose write rate exceeds it are excluded from merging, as they may be split
+	// again soon. 0, the default, means the write rate is not checked.
+	MergeHotWriteRatio float64 `toml:"merge-hot-write-ratio" json:"merge-hot-write-ratio"`
+
+	// OperatorDrainTimeout is the max time to wait for the running operators to finish when
+	// the cluster is stopped, no new operator is created meanwhile. The operators still
+	// running after the timeout are canceled. 0 means the operators are abandoned at once.
+	OperatorDrainTimeout typeutil.Duration `toml:"operator-drain-timeout" json:"operator-drain-timeout"`
+
+	// SchedulerDiagnosisWindow is the time a scheduler has produced no operator
+	// before PD samples dry runs of it in background to explain why it does not
+	// schedule. 0 means the background sampling is disabled, which is the default.
+	SchedulerDiagnosisWindow typeutil.Duration `toml:"scheduler-diagnosis-window" json:"scheduler-diagnosis-window"`
+
+	// EnableStoreQuotaBias is the option to make the balance schedulers relieve
+	// the stores exceeding their soft quotas of the leader or region count, and
+	// avoid moving leaders or regions to the stores reaching their quotas.
+	EnableStoreQuotaBias bool `toml:"enable-store-quota-bias" json:"enable-store-quota-bias,string"`
+
+	// TopologyChangeRegionRate is the max number of regions added to the suspect list per
+	// second after the location labels of their stores are changed, so that they are
+	// re-fitted to the new topology. 0 means the topology changes are not detected.
+	TopologyChangeRegionRate uint64 `toml:"topology-change-region-rate" json:"topology-change-region-rate"`
+
+	// LowSpaceETAWarning is the predicted time before a store is full, below which an
+	// event is published to warn the store is running out of space. 0 means disabled.
+	LowSpaceETAWarning typeutil.Duration `toml:"low-space-eta-warning" json:"low-space-eta-warning"`
+	// LowSpaceETACritical is the predicted time before a store is full, below which an
+	// event is published and the add peer limit of the store is tightened. 0 means disabled.
+	LowSpaceETACritical typeutil.Duration `toml:"low-space-eta-critical" json:"low-space-eta-critical"`
+
+	// SlowTrendWindow is the time window of the slow trends reported by the store heartbeats,
+	// which are kept as the slow trend series of the store.
+	SlowTrendWindow typeutil.Duration `toml:"slow-trend-window" json:"slow-trend-window"`
+	// SlowTrendCauseValueThreshold is the min slow score of a store, at or above which the
+	// store can be detected as trending slow by the evict-slow-trend-scheduler.
+	SlowTrendCauseValueThreshold float64 `toml:"slow-trend-cause-value-threshold" json:"slow-trend-cause-value-threshold"`
+	// SlowTrendCauseRateThreshold is the min growth of the slow score per second, at or above
+	// which the store is detected as trending slow if its query rate is dropping as well.
+	SlowTrendCauseRateThreshold float64 `toml:"slow-trend-cause-rate-threshold" json:"slow-trend-cause-rate-threshold"`
+
+	// SchedulerExecutionBudget is the max time a scheduler can spend in a tick. The
+	// scheduler stops retrying once the budget is used up, and its next tick is delayed
+	// by the overrun if the budget is exceeded. 0 means unlimited.
+	SchedulerExecutionBudget typeutil.Duration `toml:"scheduler-execution-budget" json:"scheduler-execution-budget"`
+	// SchedulerExecutionBudgets overrides the execution budgets of the schedulers by name.
+	SchedulerExecutionBudgets map[string]typeutil.Duration `toml:"scheduler-execution-budgets" json:"scheduler-execution-budgets"`
+
+	// MaxStoreVersionSkew is the max number of minor versions a store can lag behind
+	// the newest version among the Up stores. The stores with different major versions
+	// always exceed it. 0 means the version skew is not checked.
+	MaxStoreVersionSkew uint64 `toml:"max-store-version-skew" json:"max-store-version-skew"`
+	// StoreVersionSkewPolicy is the policy applied to the stores exceeding the max version
+	// skew, there are some policies supported: ["warn", "refuse", "exclude"], default: "warn"
+	StoreVersionSkewPolicy string `toml:"store-version-skew-policy" json:"store-version-skew-policy"`
+
+	// StaleRegionRatioThreshold is the ratio of the stale regions, above which the region cache
+	// is considered stale, e.g. after a network partition, and the schedulers and the non-repair
+	// checkers stop generating operators until it recovers. 0 means the guard is disabled.
+	StaleRegionRatioThreshold float64 `toml:"stale-region-ratio-threshold" json:"stale-region-ratio-threshold"`
+	// StaleRegionHeartbeatIntervals is the number of the region heartbeat intervals, after which a
+	// region without heartbeats is considered stale.
+	StaleRegionHeartbeatIntervals uint64 `toml:"stale-region-heartbeat-intervals" json:"stale-region-heartbeat-intervals"`
+
+	// SplitTargetStores are the stores preferred to place the peers of the newly split regions
+	// when they are scattered, e.g. the newly added empty stores, to fill the added capacity faster.
+	SplitTargetStores []uint64 `toml:"split-target-stores" json:"split-target-stores"`
+	// SplitTargetFillRatio is the fraction of the average region count of the other stores, a
+	// split target store is no longer preferred once its region count reaches it.
+	SplitTargetFillRatio float64 `toml:"split-target-fill-ratio" json:"split-target-fill-ratio"`
+
+	// EmergencyStoreLimit is the limit of the operators exempted from the store limits, e.g. the
+	// ones repairing the regions changed by the unsafe recovery, in operators per minute for each
+	// store and each limit type. 0 means unlimited.
+	EmergencyStoreLimit float64 `toml:"emergency-store-limit" json:"emergency-store-limit"`
+
+	// HotPeerExemplarTopN is the number of the hottest peers of each store exported with the
+	// exemplars of their regions by the hotspot exemplars API. 0 disables the export.
+	HotPeerExemplarTopN int `toml:"hot-peer-exemplar-top-n" json:"hot-peer-exemplar-top-n"`
+	// HotPeerExemplarRefreshInterval is how often the exported hot peers are refreshed, the
+	// scrapes within the interval share the same result.
+	HotPeerExemplarRefreshInterval typeutil.Duration `toml:"hot-peer-exemplar-refresh-interval" json:"hot-peer-exemplar-refresh-interval"`
+
+	// PlacementScanInterval is the interval of the full scans finding the regions violating
+	// the placement, whose reports are persisted. 0 means the regions are not scanned.
+	PlacementScanInterval typeutil.Duration `toml:"placement-scan-interval" json:"placement-scan-interval"`
+	// PlacementScanRegionRate is the max number of regions checked per second by the
+	// placement scans. 0 means no limit.
+	PlacementScanRegionRate uint64 `toml:"placement-scan-region-rate" json:"placement-scan-region-rate"`
 }
 
 // Clone returns a cloned scheduling configuration.
@@ -774,42 +926,74 @@ func (c *ScheduleConfig) Clone() *ScheduleConfig {
 			storeLimit[k] = v
 		}
 	}
+	var budgets map[string]typeutil.Duration
+	if c.SchedulerExecutionBudgets != nil {
+		budgets = make(map[string]typeutil.Duration, len(c.SchedulerExecutionBudgets))
+		for k, v := range c.SchedulerExecutionBudgets {
+			budgets[k] = v
+		}
+	}
 	cfg := *c
 	cfg.StoreLimit = storeLimit
+	cfg.SchedulerExecutionBudgets = budgets
+	cfg.SplitTargetStores = append(c.SplitTargetStores[:0:0], c.SplitTargetStores...)
 	cfg.Schedulers = schedulers
 	cfg.SchedulersPayload = nil
 	return &cfg
 }
 
 const (
-	defaultMaxReplicas               = 3
-	defaultMaxSnapshotCount          = 64
-	defaultMaxPendingPeerCount       = 64
-	defaultMaxMergeRegionSize        = 20
-	defaultSplitMergeInterval        = 1 * time.Hour
-	defaultPatrolRegionInterval      = 10 * time.Millisecond
-	defaultMaxStoreDownTime          = 30 * time.Minute
-	defaultLeaderScheduleLimit       = 4
-	defaultRegionScheduleLimit       = 2048
-	defaultReplicaScheduleLimit      = 64
-	defaultMergeScheduleLimit        = 8
-	defaultHotRegionScheduleLimit    = 4
-	defaultTolerantSizeRatio         = 0
-	defaultLowSpaceRatio             = 0.8
-	defaultHighSpaceRatio            = 0.7
-	defaultRegionScoreFormulaVersion = "v2"
+	defaultMaxReplicas                  = 3
+	defaultMaxSnapshotCount             = 64
+	defaultMaxPendingPeerCount          = 64
+	defaultMaxMergeRegionSize           = 20
+	defaultSplitMergeInterval           = 1 * time.Hour
+	defaultPatrolRegionInterval         = 10 * time.Millisecond
+	defaultMaxStoreDownTime             = 30 * time.Minute
+	defaultLeaderScheduleLimit          = 4
+	defaultRegionScheduleLimit          = 2048
+	defaultReplicaScheduleLimit         = 64
+	defaultMergeScheduleLimit           = 8
+	defaultHotRegionScheduleLimit       = 4
+	defaultTolerantSizeRatio            = 0
+	defaultLowSpaceRatio                = 0.8
+	defaultHighSpaceRatio               = 0.7
+	defaultRegionScoreFormulaVersion    = "v2"
+	defaultRegionScoreV3Amplification   = 4
+	defaultRegionScoreV3Exponent        = 2
+	defaultRegionScoreV3LowSpacePenalty = 1e10
 	// defaultHotRegionCacheHitsThreshold is the low hit number threshold of the
 	// hot region.
 	defaultHotRegionCacheHitsThreshold = 3
 	defaultSchedulerMaxWaitingOperator = 5
 	defaultLeaderSchedulePolicy        = "count"
 	defaultStoreLimitMode              = "manual"
+	defaultStoreVersionSkewPolicy      = StoreVersionSkewPolicyWarn
 	defaultEnableJointConsensus        = true
 	defaultEnableCrossTableMerge       = true
 	defaultHotRegionsWriteInterval     = 10 * time.Minute
 	defaultHotRegionsReservedDays      = 7
 	// It means we skip the preparing stage after the 48 hours no matter if the store has finished preparing stage.
-	defaultMaxStorePreparingTime = 48 * time.Hour
+	defaultMaxStorePreparingTime    = 48 * time.Hour
+	defaultSuspectKeyRangeGCAge     = 10 * time.Minute
+	defaultTopologyChangeRegionRate = 1000
+	defaultLowSpaceETAWarning       = 24 * time.Hour
+	defaultLowSpaceETACritical      = 2 * time.Hour
+	defaultSlowTrendWindow          = 5 * time.Minute
+	defaultSlowTrendCauseValue      = 10
+	defaultSlowTrendCauseRate       = 0.05
+	defaultSchedulerExecutionBudget = time.Second
+	// defaultStaleRegionHeartbeatIntervals is the number of the region heartbeat intervals after
+	// which a region is considered stale.
+	defaultStaleRegionHeartbeatIntervals = 3
+	defaultSplitTargetFillRatio          = 1.0
+	defaultEmergencyStoreLimit           = 60
+	defaultHotPeerExemplarTopN           = 10
+	defaultHotPeerExemplarRefresh        = 30 * time.Second
+	defaultPlacementScanInterval         = time.Hour
+	defaultPlacementScanRegionRate       = 10000
+	// defaultOrphanLearnerGracePeriod is the time a learner must stay orphaned before it is removed.
+	defaultOrphanLearnerGracePeriod = 10 * time.Minute
 )
 
 func (c *ScheduleConfig) adjust(meta *configMetaData, reloading bool) error {
@@ -827,6 +1011,31 @@ func (c *ScheduleConfig) adjust(meta *configMetaData, reloading bool) error {
 	adjustDuration(&c.MaxStoreDownTime, defaultMaxStoreDownTime)
 	adjustDuration(&c.HotRegionsWriteInterval, defaultHotRegionsWriteInterval)
 	adjustDuration(&c.MaxStorePreparingTime, defaultMaxStorePreparingTime)
+	adjustDuration(&c.SuspectKeyRangeGCAge, defaultSuspectKeyRangeGCAge)
+	if !meta.IsDefined("topology-change-region-rate") {
+		adjustUint64(&c.TopologyChangeRegionRate, defaultTopologyChangeRegionRate)
+	}
+	if !meta.IsDefined("low-space-eta-warning") {
+		adjustDuration(&c.LowSpaceETAWarning, defaultLowSpaceETAWarning)
+	}
+	if !meta.IsDefined("low-space-eta-critical") {
+		adjustDuration(&c.LowSpaceETACritical, defaultLowSpaceETACritical)
+	}
+	if !meta.IsDefined("slow-trend-window") {
+		adjustDuration(&c.SlowTrendWindow, defaultSlowTrendWindow)
+	}
+	if !meta.IsDefined("slow-trend-cause-value-threshold") {
+		adjustFloat64(&c.SlowTrendCauseValueThreshold, defaultSlowTrendCauseValue)
+	}
+	if !meta.IsDefined("slow-trend-cause-rate-threshold") {
+		adjustFloat64(&c.SlowTrendCauseRateThreshold, defaultSlowTrendCauseRate)
+	}
+	if !meta.IsDefined("scheduler-execution-budget") {
+		adjustDuration(&c.SchedulerExecutionBudget, defaultSchedulerExecutionBudget)
+	}
+	if !meta.IsDefined("stale-region-heartbeat-intervals") {
+		adjustUint64(&c.StaleRegionHeartbeatIntervals, defaultStaleRegionHeartbeatIntervals)
+	}
 	if !meta.IsDefined("leader-schedule-limit") {
 		adjustUint64(&c.LeaderScheduleLimit, defaultLeaderScheduleLimit)
 	}
@@ -848,12 +1057,33 @@ func (c *ScheduleConfig) adjust(meta *configMetaData, reloading bool) error {
 	if !meta.IsDefined("tolerant-size-ratio") {
 		adjustFloat64(&c.TolerantSizeRatio, defaultTolerantSizeRatio)
 	}
+	if !meta.IsDefined("split-target-fill-ratio") {
+		adjustFloat64(&c.SplitTargetFillRatio, defaultSplitTargetFillRatio)
+	}
+	if !meta.IsDefined("emergency-store-limit") {
+		adjustFloat64(&c.EmergencyStoreLimit, defaultEmergencyStoreLimit)
+	}
+	if !meta.IsDefined("hot-peer-exemplar-top-n") {
+		adjustInt(&c.HotPeerExemplarTopN, defaultHotPeerExemplarTopN)
+	}
+	if !meta.IsDefined("hot-peer-exemplar-refresh-interval") {
+		adjustDuration(&c.HotPeerExemplarRefreshInterval, defaultHotPeerExemplarRefresh)
+	}
+	if !meta.IsDefined("placement-scan-interval") {
+		adjustDuration(&c.PlacementScanInterval, defaultPlacementScanInterval)
+	}
+	if !meta.IsDefined("placement-scan-region-rate") {
+		adjustUint64(&c.PlacementScanRegionRate, defaultPlacementScanRegionRate)
+	}
 	if !meta.IsDefined("scheduler-max-waiting-operator") {
 		adjustUint64(&c.SchedulerMaxWaitingOperator, defaultSchedulerMaxWaitingOperator)
 	}
 	if !meta.IsDefined("leader-schedule-policy") {
 		adjustString(&c.LeaderSchedulePolicy, defaultLeaderSchedulePolicy)
 	}
+	if !meta.IsDefined("store-version-skew-policy") {
+		adjustString(&c.StoreVersionSkewPolicy, defaultStoreVersionSkewPolicy)
+	}
 	if !meta.IsDefined("store-limit-mode") {
 		adjustString(&c.StoreLimitMode, defaultStoreLimitMode)
 	}
@@ -870,6 +1100,11 @@ func (c *ScheduleConfig) adjust(meta *configMetaData, reloading bool) error {
 	if !meta.IsDefined("region-score-formula-version") && !reloading {
 		adjustString(&c.RegionScoreFormulaVersion, defaultRegionScoreFormulaVersion)
 	}
+	adjustFloat64(&c.RegionScoreV3Amplification, defaultRegionScoreV3Amplification)
+	adjustFloat64(&c.RegionScoreV3Exponent, defaultRegionScoreV3Exponent)
+	if !meta.IsDefined("region-score-v3-low-space-penalty") {
+		adjustFloat64(&c.RegionScoreV3LowSpacePenalty, defaultRegionScoreV3LowSpacePenalty)
+	}
 
 	adjustSchedulers(&c.Schedulers, DefaultSchedulers)
 
@@ -907,6 +1142,15 @@ func (c *ScheduleConfig) migrateConfigurationMap() map[string][2]*bool {
 	}
 }
 
+// GetRegionScoreCurve returns the parameters of the transition curve of the v3 region score formula.
+func (c *ScheduleConfig) GetRegionScoreCurve() core.RegionScoreCurve {
+	return core.RegionScoreCurve{
+		Amplification:   c.RegionScoreV3Amplification,
+		Exponent:        c.RegionScoreV3Exponent,
+		LowSpacePenalty: c.RegionScoreV3LowSpacePenalty,
+	}
+}
+
 // GetMaxMergeRegionKeys returns the max merge keys.
 // it should keep consistent with tikv: https://github.com/tikv/tikv/pull/12484
 func (c *ScheduleConfig) GetMaxMergeRegionKeys() uint64 {
@@ -955,6 +1199,56 @@ func (c *ScheduleConfig) Validate() error {
 	if c.TolerantSizeRatio < 0 {
 		return errors.New("tolerant-size-ratio should be non-negative")
 	}
+	if c.MergeHotWriteRatio < 0 || c.MergeHotWriteRatio > 1 {
+		return errors.New("merge-hot-write-ratio should be between 0 and 1")
+	}
+	if c.OperatorDrainTimeout.Duration < 0 {
+		return errors.New("operator-drain-timeout should be non-negative")
+	}
+	if c.SchedulerDiagnosisWindow.Duration < 0 {
+		return errors.New("scheduler-diagnosis-window should be non-negative")
+	}
+	if c.StoreLimitSizeRate < 0 {
+		return errors.New("store-limit-size-rate should be non-negative")
+	}
+	if c.LowSpaceETAWarning.Duration < 0 || c.LowSpaceETACritical.Duration < 0 {
+		return errors.New("low-space-eta-warning and low-space-eta-critical should be non-negative")
+	}
+	if c.SlowTrendWindow.Duration <= 0 {
+		return errors.New("slow-trend-window should be positive")
+	}
+	if c.SlowTrendCauseValueThreshold < 0 || c.SlowTrendCauseRateThreshold < 0 {
+		return errors.New("slow-trend-cause-value-threshold and slow-trend-cause-rate-threshold should be non-negative")
+	}
+	if c.SchedulerExecutionBudget.Duration < 0 {
+		return errors.New("scheduler-execution-budget should be non-negative")
+	}
+	for name, budget := range c.SchedulerExecutionBudgets {
+		if budget.Duration < 0 {
+			return errors.Errorf("the execution budget of scheduler %s should be non-negative", name)
+		}
+	}
+	if c.StaleRegionRatioThreshold < 0 || c.StaleRegionRatioThreshold > 1 {
+		return errors.New("stale-region-ratio-threshold should between 0 and 1")
+	}
+	if c.SplitTargetFillRatio < 0 {
+		return errors.New("split-target-fill-ratio should be non-negative")
+	}
+	if c.EmergencyStoreLimit < 0 {
+		return errors.New("emergency-store-limit should be non-negative")
+	}
+	if err := c.validateStoreLimitCaps(); err != nil {
+		return err
+	}
+	if c.HotPeerExemplarTopN < 0 {
+		return errors.New("hot-peer-exemplar-top-n should be non-negative")
+	}
+	if c.HotPeerExemplarRefreshInterval.Duration < 0 {
+		return errors.New("hot-peer-exemplar-refresh-interval should be non-negative")
+	}
+	if c.PlacementScanInterval.Duration < 0 {
+		return errors.New("placement-scan-interval should be non-negative")
+	}
 	if c.LowSpaceRatio < 0 || c.LowSpaceRatio > 1 {
 		return errors.New("low-space-ratio should between 0 and 1")
 	}
@@ -964,9 +1258,26 @@ func (c *ScheduleConfig) Validate() error {
 	if c.LowSpaceRatio <= c.HighSpaceRatio {
 		return errors.New("low-space-ratio should be larger than high-space-ratio")
 	}
+	if c.RegionScoreFormulaVersion != "" && c.RegionScoreFormulaVersion != "v1" && c.RegionScoreFormulaVersion != "v2" && c.RegionScoreFormulaVersion != "v3" {
+		return errors.Errorf("region-score-formula-version %v is invalid", c.RegionScoreFormulaVersion)
+	}
+	if c.RegionScoreV3Amplification < 1 {
+		return errors.New("region-score-v3-amplification should not be less than 1")
+	}
+	if c.RegionScoreV3Exponent <= 0 {
+		return errors.New("region-score-v3-exponent should be positive")
+	}
+	if c.RegionScoreV3LowSpacePenalty < 0 {
+		return errors.New("region-score-v3-low-space-penalty should be non-negative")
+	}
 	if c.LeaderSchedulePolicy != "count" && c.LeaderSchedulePolicy != "size" {
 		return errors.Errorf("leader-schedule-policy %v is invalid", c.LeaderSchedulePolicy)
 	}
+	switch c.StoreVersionSkewPolicy {
+	case "", StoreVersionSkewPolicyWarn, StoreVersionSkewPolicyRefuse, StoreVersionSkewPolicyExclude:
+	default:
+		return errors.Errorf("store-version-skew-policy %v is invalid", c.StoreVersionSkewPolicy)
+	}
 	for _, scheduleConfig := range c.Schedulers {
 		if !IsSchedulerRegistered(scheduleConfig.Type) {
 			return errors.Errorf("create func of %v is not registered, maybe misspelled", scheduleConfig.Type)
@@ -975,6 +1286,30 @@ func (c *ScheduleConfig) Validate() error {
 	return nil
 }
 
+// validateStoreLimitCaps checks the group and cluster store limits. A group
+// limit is only meaningful with the group label, and it cannot be larger than
+// the cluster limit which covers every group.
+func (c *ScheduleConfig) validateStoreLimitCaps() error {
+	for _, typ := range []struct {
+		name           string
+		group, cluster float64
+	}{
+		{"add-peer", c.GroupStoreLimit.AddPeer, c.ClusterStoreLimit.AddPeer},
+		{"remove-peer", c.GroupStoreLimit.RemovePeer, c.ClusterStoreLimit.RemovePeer},
+	} {
+		if typ.group < 0 || typ.cluster < 0 {
+			return errors.Errorf("the %s of group-store-limit and cluster-store-limit should be non-negative", typ.name)
+		}
+		if typ.group > 0 && c.StoreLimitGroupLabel == "" {
+			return errors.Errorf("the %s of group-store-limit requires store-limit-group-label", typ.name)
+		}
+		if typ.group > 0 && typ.cluster > 0 && typ.group > typ.cluster {
+			return errors.Errorf("the %s of group-store-limit should not be larger than the one of cluster-store-limit", typ.name)
+		}
+	}
+	return nil
+}
+
 // Deprecated is used to find if there is an option has been deprecated.
 func (c *ScheduleConfig) Deprecated() error {
 	if c.DisableLearner {
@@ -1016,6 +1351,9 @@ type SchedulerConfig struct {
 	Args        []string `toml:"args" json:"args"`
 	Disable     bool     `toml:"disable" json:"disable"`
 	ArgsPayload string   `toml:"args-payload" json:"args-payload"`
+	// ObserveUntil is the unix time when the observe-only mode of the scheduler
+	// ends, in which its operators are recorded but not submitted.
+	ObserveUntil int64 `toml:"observe-until,omitempty" json:"observe-until,omitempty"`
 }
 
 // DefaultSchedulers are the schedulers be created by default.
@@ -1038,6 +1376,19 @@ func IsDefaultScheduler(typ string) bool {
 	return false
 }
 
+// The modes of the orphan learner checker.
+const (
+	OrphanLearnerCheckerDisabled = "disabled"
+	OrphanLearnerCheckerDryRun   = "dry-run"
+	OrphanLearnerCheckerEnabled  = "enabled"
+)
+
+// The roles of the temporary replica placed by the degraded replica fallback.
+const (
+	DegradedReplicaFallbackLearner = "learner"
+	DegradedReplicaFallbackWitness = "witness"
+)
+
 // ReplicationConfig is the replication configuration.
 // NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
 type ReplicationConfig struct {
@@ -1058,6 +1409,26 @@ type ReplicationConfig struct {
 	// EnablePlacementRuleCache controls whether use cache during rule checker
 	EnablePlacementRulesCache bool `toml:"enable-placement-rules-cache" json:"enable-placement-rules-cache,string"`
 
+	// EnableDegradedReplicaFallback allows the rule checker to place a temporary replica on
+	// a store outside the rule when a rule can not be made up or repaired, e.g. a cluster
+	// running 2 replicas loses one of its stores. The replica is removed once the rule
+	// is satisfied again. Without the placement rules, the replica checker places a learner
+	// regardless of the isolation level instead, which replaces the lost replica and is
+	// moved back to a better location once there is one.
+	EnableDegradedReplicaFallback bool `toml:"enable-degraded-replica-fallback" json:"enable-degraded-replica-fallback,string"`
+	// DegradedReplicaFallbackRole is the role of the temporary replica placed by the rule
+	// checker, "learner" or "witness". A witness takes part in the quorum but holds no data.
+	DegradedReplicaFallbackRole string `toml:"degraded-replica-fallback-role" json:"degraded-replica-fallback-role"`
+
+	// OrphanLearnerChecker is the mode of the checker removing the learners not covered by
+	// any rule, e.g. left by TiFlash or the external tools. "disabled" leaves them to the rule
+	// checker, "dry-run" only reports them, and "enabled" removes them.
+	OrphanLearnerChecker string `toml:"orphan-learner-checker" json:"orphan-learner-checker"`
+	// OrphanLearnerGracePeriod is the time a learner must stay orphaned before it is removed.
+	OrphanLearnerGracePeriod typeutil.Duration `toml:"orphan-learner-grace-period" json:"orphan-learner-grace-period"`
+	// OrphanLearnerExcludedEngines is the engines whose orphan learners are kept.
+	OrphanLearnerExcludedEngines typeutil.StringSlice `toml:"orphan-learner-excluded-engines" json:"orphan-learner-excluded-engines"`
+
 	// IsolationLevel is used to isolate replicas explicitly and forcibly if it's not empty.
 	// Its value must be empty or one of LocationLabels.
 	// Example:
@@ -1072,8 +1443,10 @@ type ReplicationConfig struct {
 // Clone makes a deep copy of the config.
 func (c *ReplicationConfig) Clone() *ReplicationConfig {
 	locationLabels := append(c.LocationLabels[:0:0], c.LocationLabels...)
+	excludedEngines := append(c.OrphanLearnerExcludedEngines[:0:0], c.OrphanLearnerExcludedEngines...)
 	cfg := *c
 	cfg.LocationLabels = locationLabels
+	cfg.OrphanLearnerExcludedEngines = excludedEngines
 	return &cfg
 }
 
@@ -1093,6 +1466,18 @@ func (c *ReplicationConfig) Validate() error {
 	if c.IsolationLevel != "" && !foundIsolationLevel {
 		return errors.New("isolation-level must be one of location-labels or empty")
 	}
+	switch c.OrphanLearnerChecker {
+	// The empty mode is persisted by the older versions.
+	case "", OrphanLearnerCheckerDisabled, OrphanLearnerCheckerDryRun, OrphanLearnerCheckerEnabled:
+	default:
+		return errors.Errorf("orphan-learner-checker %s is invalid", c.OrphanLearnerChecker)
+	}
+	switch c.DegradedReplicaFallbackRole {
+	// The empty role is persisted by the older versions.
+	case "", DegradedReplicaFallbackLearner, DegradedReplicaFallbackWitness:
+	default:
+		return errors.Errorf("degraded-replica-fallback-role %s is invalid", c.DegradedReplicaFallbackRole)
+	}
 	return nil
 }
 
@@ -1107,6 +1492,13 @@ func (c *ReplicationConfig) adjust(meta *configMetaData) error {
 	if !meta.IsDefined("location-labels") {
 		c.LocationLabels = defaultLocationLabels
 	}
+	if !meta.IsDefined("orphan-learner-checker") {
+		c.OrphanLearnerChecker = OrphanLearnerCheckerDisabled
+	}
+	if !meta.IsDefined("degraded-replica-fallback-role") {
+		c.DegradedReplicaFallbackRole = DegradedReplicaFallbackLearner
+	}
+	adjustDuration(&c.OrphanLearnerGracePeriod, defaultOrphanLearnerGracePeriod)
 	return c.Validate()
 }
 
@@ -1115,6 +1507,10 @@ func (c *ReplicationConfig) adjust(meta *configMetaData) error {
 type PDServerConfig struct {
 	// UseRegionStorage enables the independent region storage.
 	UseRegionStorage bool `toml:"use-region-storage" json:"use-region-storage,string"`
+	// RegionStorageDualWrite saves the regions to both the etcd and the independent
+	// region storage, and reads them from the latter first. It is used to migrate
+	// the regions to the independent region storage online.
+	RegionStorageDualWrite bool `toml:"region-storage-dual-write" json:"region-storage-dual-write,string"`
 	// MaxResetTSGap is the max gap to reset the TSO.
 	MaxResetTSGap typeutil.Duration `toml:"max-gap-reset-ts" json:"max-gap-reset-ts"`
 	// KeyType is option to specify the type of keys.
@@ -1134,6 +1530,63 @@ type PDServerConfig struct {
 	FlowRoundByDigit int `toml:"flow-round-by-digit" json:"flow-round-by-digit"`
 	// MinResolvedTSPersistenceInterval is the interval to save the min resolved ts.
 	MinResolvedTSPersistenceInterval typeutil.Duration `toml:"min-resolved-ts-persistence-interval" json:"min-resolved-ts-persistence-interval"`
+	// StoreMetricsEmitInterval is the interval to recompute and emit the metrics of all stores.
+	// Between two full recomputations, only the stores whose stats have changed are updated.
+	// 0 means recomputing every time the metrics are collected.
+	StoreMetricsEmitInterval typeutil.Duration `toml:"store-metrics-emit-interval" json:"store-metrics-emit-interval"`
+	// EnableTSOFollowerProxy enables the followers to accept the global TSO requests and
+	// forward them to the leader in batches.
+	EnableTSOFollowerProxy bool `toml:"enable-tso-follower-proxy" json:"enable-tso-follower-proxy,string"`
+	// EnableStoreTokenAuth makes the stores present a token issued by the cluster
+	// when registering themselves and sending store heartbeats. The region heartbeats
+	// are not checked, and the stores are not checked until a token is issued.
+	EnableStoreTokenAuth bool `toml:"enable-store-token-auth" json:"enable-store-token-auth,string"`
+	// EventWebhookURL is the URL which the cluster events, such as a store running out of
+	// space soon, are posted to in JSON. Empty means the events are not posted.
+	EventWebhookURL string `toml:"event-webhook-url" json:"event-webhook-url"`
+	// RegionQueryCacheSize is the memory limit of the cache of the region query results,
+	// which saves the repeated queries of the same keys. 0 means the cache is disabled.
+	RegionQueryCacheSize typeutil.ByteSize `toml:"region-query-cache-size" json:"region-query-cache-size"`
+	// GRPCStoreClass, GRPCSQLClass and GRPCToolClass are the gRPC settings of the streams opened by
+	// the stores, the SQL clients and the tools respectively. The class of a stream is decided by
+	// its method on the server side, so the clients can't choose the settings applied to them.
+	GRPCStoreClass GRPCClassConfig `toml:"grpc-store-class" json:"grpc-store-class"`
+	GRPCSQLClass   GRPCClassConfig `toml:"grpc-sql-class" json:"grpc-sql-class"`
+	GRPCToolClass  GRPCClassConfig `toml:"grpc-tool-class" json:"grpc-tool-class"`
+	// GRPCGzipLevel is the compression level of the gzip messages, from -2 (huffman only) to 9.
+	GRPCGzipLevel int `toml:"grpc-gzip-level" json:"grpc-gzip-level"`
+	// MetricsLabelGranularity is the granularity of the store labels of the metric families
+	// whose cardinality grows with the cluster, "store" keeps a series for each store while
+	// "aggregated" aggregates the series of all the stores into one.
+	MetricsLabelGranularity string `toml:"metrics-label-granularity" json:"metrics-label-granularity"`
+	// DisabledMetrics is the names of the controlled metric families which are not collected.
+	DisabledMetrics typeutil.StringSlice `toml:"disabled-metrics" json:"disabled-metrics"`
+	// MinResolvedTSMissingStorePolicy is the policy of the stores missing from the min resolved ts,
+	// i.e. the ones without leaders, e.g. their leaders are evicted, or disconnected. There are some
+	// policies supported: ["skip", "hold"], default: "skip"
+	MinResolvedTSMissingStorePolicy string `toml:"min-resolved-ts-missing-store-policy" json:"min-resolved-ts-missing-store-policy"`
+	// MinResolvedTSMissingStoreHoldTime is how long the last resolved ts of a missing store is
+	// honored with the "hold" policy. 0 means it is honored until the store is removed.
+	MinResolvedTSMissingStoreHoldTime typeutil.Duration `toml:"min-resolved-ts-missing-store-hold-time" json:"min-resolved-ts-missing-store-hold-time"`
+	// MinResolvedTSLagThreshold is the lag of the resolved ts of a store behind the median one of
+	// the stores, above which an event is published to alert the store. 0 means disabled.
+	MinResolvedTSLagThreshold typeutil.Duration `toml:"min-resolved-ts-lag-threshold" json:"min-resolved-ts-lag-threshold"`
+	// WorkerPoolSizes is the number of the workers of the worker pools by their names, e.g.
+	// "checker" and "hot-stat". The pools not configured use their default sizes.
+	WorkerPoolSizes map[string]int `toml:"worker-pool-sizes" json:"worker-pool-sizes"`
+	// EnableKeyVisual enables collecting the heatmap of the key ranges, which is kept in the
+	// local storage of PD. It takes effect when the cluster is started.
+	EnableKeyVisual bool `toml:"enable-key-visual" json:"enable-key-visual,string"`
+	// DisabledSubsystems is the names of the optional subsystems which are not started
+	// with the cluster, e.g. "key-visual". It takes effect when the cluster is started.
+	DisabledSubsystems typeutil.StringSlice `toml:"disabled-subsystems" json:"disabled-subsystems"`
+	// EnableRegionCacheSafeMode guards dropping the region cache by the API. Dropping all the
+	// regions requires a confirmation token, and dropping the regions one by one is rate limited.
+	EnableRegionCacheSafeMode bool `toml:"enable-region-cache-safe-mode" json:"enable-region-cache-safe-mode,string"`
+	// EnableTrace traces the operators across the region heartbeats and the dispatches.
+	EnableTrace bool `toml:"enable-trace" json:"enable-trace,string"`
+	// TraceSampleRatio is the fraction of the operators traced if the tracing is enabled.
+	TraceSampleRatio float64 `toml:"trace-sample-ratio" json:"trace-sample-ratio"`
 }
 
 func (c *PDServerConfig) adjust(meta *configMetaData) error {
@@ -1159,6 +1612,30 @@ func (c *PDServerConfig) adjust(meta *configMetaData) error {
 	if !meta.IsDefined("min-resolved-ts-persistence-interval") {
 		adjustDuration(&c.MinResolvedTSPersistenceInterval, defaultMinResolvedTSPersistenceInterval)
 	}
+	if !meta.IsDefined("store-metrics-emit-interval") {
+		adjustDuration(&c.StoreMetricsEmitInterval, defaultStoreMetricsEmitInterval)
+	}
+	if !meta.IsDefined("metrics-label-granularity") {
+		c.MetricsLabelGranularity = metricutil.LabelGranularityStore
+	}
+	if !meta.IsDefined("min-resolved-ts-missing-store-policy") {
+		c.MinResolvedTSMissingStorePolicy = MinResolvedTSMissingStoreSkip
+	}
+	if !meta.IsDefined("min-resolved-ts-missing-store-hold-time") {
+		adjustDuration(&c.MinResolvedTSMissingStoreHoldTime, defaultMinResolvedTSMissingStoreHold)
+	}
+	if !meta.IsDefined("enable-region-cache-safe-mode") {
+		c.EnableRegionCacheSafeMode = defaultEnableRegionCacheSafeMode
+	}
+	if !meta.IsDefined("enable-key-visual") {
+		c.EnableKeyVisual = defaultEnableKeyVisual
+	}
+	if !meta.IsDefined("trace-sample-ratio") {
+		c.TraceSampleRatio = defaultTraceSampleRatio
+	}
+	if !meta.IsDefined("grpc-gzip-level") {
+		c.GRPCGzipLevel = gzip.DefaultCompression
+	}
 	c.migrateConfigurationFromFile(meta)
 	return c.Validate()
 }
@@ -1191,8 +1668,18 @@ func (c *PDServerConfig) MigrateDeprecatedFlags() {
 // Clone returns a cloned PD server config.
 func (c *PDServerConfig) Clone() *PDServerConfig {
 	runtimeServices := append(c.RuntimeServices[:0:0], c.RuntimeServices...)
+	disabledMetrics := append(c.DisabledMetrics[:0:0], c.DisabledMetrics...)
+	disabledSubsystems := append(c.DisabledSubsystems[:0:0], c.DisabledSubsystems...)
 	cfg := *c
 	cfg.RuntimeServices = runtimeServices
+	cfg.DisabledMetrics = disabledMetrics
+	cfg.DisabledSubsystems = disabledSubsystems
+	if c.WorkerPoolSizes != nil {
+		cfg.WorkerPoolSizes = make(map[string]int, len(c.WorkerPoolSizes))
+		for name, size := range c.WorkerPoolSizes {
+			cfg.WorkerPoolSizes[name] = size
+		}
+	}
 	return &cfg
 }
 
@@ -1212,16 +1699,73 @@ func (c *PDServerConfig) Validate() error {
 	if c.FlowRoundByDigit < 0 {
 		return errs.ErrConfigItem.GenWithStack("flow round by digit cannot be negative number")
 	}
+	if c.StoreMetricsEmitInterval.Duration < 0 {
+		return errs.ErrConfigItem.GenWithStack("store metrics emit interval cannot be negative")
+	}
+	if c.EventWebhookURL != "" {
+		if err := ValidateURLWithScheme(c.EventWebhookURL); err != nil {
+			return err
+		}
+	}
+	for _, class := range []GRPCClassConfig{c.GRPCStoreClass, c.GRPCSQLClass, c.GRPCToolClass} {
+		if class.MaxStreams < 0 || class.IdleTimeout.Duration < 0 {
+			return errs.ErrConfigItem.GenWithStack("max grpc streams and idle timeout cannot be negative")
+		}
+	}
+	if c.GRPCGzipLevel < gzip.HuffmanOnly || c.GRPCGzipLevel > gzip.BestCompression {
+		return errs.ErrConfigItem.GenWithStack("grpc gzip level should be in [%d, %d]", gzip.HuffmanOnly, gzip.BestCompression)
+	}
+	switch c.MetricsLabelGranularity {
+	// The empty granularity is persisted by the older versions.
+	case "", metricutil.LabelGranularityStore, metricutil.LabelGranularityAggregated:
+	default:
+		return errs.ErrConfigItem.GenWithStack("metrics label granularity %s is invalid", c.MetricsLabelGranularity)
+	}
+	switch c.MinResolvedTSMissingStorePolicy {
+	case "", MinResolvedTSMissingStoreSkip, MinResolvedTSMissingStoreHold:
+	default:
+		return errs.ErrConfigItem.GenWithStack("min resolved ts missing store policy %s is invalid", c.MinResolvedTSMissingStorePolicy)
+	}
+	if c.MinResolvedTSMissingStoreHoldTime.Duration < 0 || c.MinResolvedTSLagThreshold.Duration < 0 {
+		return errs.ErrConfigItem.GenWithStack("min resolved ts hold time and lag threshold cannot be negative")
+	}
+	for name, size := range c.WorkerPoolSizes {
+		if size < 0 {
+			return errs.ErrConfigItem.GenWithStack("the size of worker pool %s cannot be negative", name)
+		}
+	}
+	if c.TraceSampleRatio < 0 || c.TraceSampleRatio > 1 {
+		return errs.ErrConfigItem.GenWithStack("trace sample ratio should be in [0, 1]")
+	}
 
 	return nil
 }
 
+// The policies of the stores missing from the min resolved ts.
+const (
+	// MinResolvedTSMissingStoreSkip skips the stores without leaders at once.
+	MinResolvedTSMissingStoreSkip = "skip"
+	// MinResolvedTSMissingStoreHold keeps honoring the last resolved ts of the stores
+	// without leaders or disconnected for a while before skipping them.
+	MinResolvedTSMissingStoreHold = "hold"
+)
+
 // StoreLabel is the config item of LabelPropertyConfig.
 type StoreLabel struct {
 	Key   string `toml:"key" json:"key"`
 	Value string `toml:"value" json:"value"`
 }
 
+// The policies applied to the stores exceeding the max version skew.
+const (
+	// StoreVersionSkewPolicyWarn only logs a warning and reports the skew by metrics.
+	StoreVersionSkewPolicyWarn = "warn"
+	// StoreVersionSkewPolicyRefuse refuses the registration of the store.
+	StoreVersionSkewPolicyRefuse = "refuse"
+	// StoreVersionSkewPolicyExclude excludes the store from the scheduling targets.
+	StoreVersionSkewPolicyExclude = "exclude"
+)
+
 // RejectLeader is the label property type that suggests a store should not
 // have any region leaders.
 const RejectLeader = "reject-leader"
@@ -1286,6 +1830,9 @@ func (c *Config) GenEmbedEtcdConfig() (*embed.Config, error) {
 	cfg.AutoCompactionRetention = c.AutoCompactionRetention
 	cfg.QuotaBackendBytes = int64(c.QuotaBackendBytes)
 	cfg.MaxRequestBytes = c.MaxRequestBytes
+	cfg.GRPCKeepAliveMinTime = c.GRPC.KeepaliveMinTime.Duration
+	cfg.GRPCKeepAliveInterval = c.GRPC.KeepaliveInterval.Duration
+	cfg.GRPCKeepAliveTimeout = c.GRPC.KeepaliveTimeout.Duration
 
 	allowedCN, serr := c.Security.GetOneAllowedCN()
 	if serr != nil {
@@ -1331,6 +1878,45 @@ func (c *Config) GenEmbedEtcdConfig() (*embed.Config, error) {
 	return cfg, nil
 }
 
+// GRPCConfig is the keepalive configuration of the gRPC connections, it is
+// applied when the server starts. A connection may carry the streams of all the
+// client classes, so the settings of each class, which can be adjusted at
+// runtime, are applied to the streams instead, see GRPCClassConfig.
+type GRPCConfig struct {
+	// KeepaliveMinTime is the minimum interval the clients are allowed to ping,
+	// the clients pinging more often are disconnected.
+	KeepaliveMinTime typeutil.Duration `toml:"keepalive-min-time" json:"keepalive-min-time"`
+	// KeepaliveInterval is the interval to ping the idle clients.
+	KeepaliveInterval typeutil.Duration `toml:"keepalive-interval" json:"keepalive-interval"`
+	// KeepaliveTimeout is how long to wait for the response of a ping before
+	// closing the connection.
+	KeepaliveTimeout typeutil.Duration `toml:"keepalive-timeout" json:"keepalive-timeout"`
+}
+
+func (c *GRPCConfig) adjust() {
+	adjustDuration(&c.KeepaliveMinTime, defaultGRPCKeepaliveMinTime)
+	adjustDuration(&c.KeepaliveInterval, defaultGRPCKeepaliveInterval)
+	adjustDuration(&c.KeepaliveTimeout, defaultGRPCKeepaliveTimeout)
+}
+
+// GRPCClassConfig is the gRPC settings of the streams of a client class, which
+// can be adjusted at runtime.
+type GRPCClassConfig struct {
+	// MaxStreams limits the concurrent streams of the class. The new streams
+	// exceeding the limit are rejected, while the existing ones are kept. 0 means
+	// unlimited.
+	MaxStreams int `toml:"max-streams" json:"max-streams"`
+	// EnableGzip allows the clients of the class to compress the messages with
+	// gzip, then the responses are compressed in the same way. The new streams
+	// compressed by gzip are rejected if it's disabled.
+	EnableGzip bool `toml:"enable-gzip" json:"enable-gzip,string"`
+	// IdleTimeout closes the streams which receive nothing from the clients for
+	// this long, e.g. the clients hang while their connections are kept alive.
+	// It's checked on every receive, so it also applies to the existing streams.
+	// 0 means never.
+	IdleTimeout typeutil.Duration `toml:"idle-timeout" json:"idle-timeout"`
+}
+
 // DashboardConfig is the configuration for tidb-dashboard.
 type DashboardConfig struct {
 	TiDBCAPath         string `toml:"tidb-cacert-path" json:"tidb-cacert-path"`
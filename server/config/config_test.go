@@ -15,6 +15,7 @@
 package config
 
 import (
+	"compress/gzip"
 	"encoding/json"
 	"fmt"
 	"math"
@@ -25,6 +26,8 @@ import (
 
 	"github.com/BurntSushi/toml"
 	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/metricutil"
 	"github.com/tikv/pd/server/storage"
 )
 
@@ -151,6 +154,18 @@ func TestValidation(t *testing.T) {
 	re.NoError(cfg.Schedule.Validate())
 	cfg.Schedule.TolerantSizeRatio = -0.6
 	re.Error(cfg.Schedule.Validate())
+	cfg.Schedule.TolerantSizeRatio = 0
+	re.NoError(cfg.Schedule.Validate())
+	// check the store limit caps
+	cfg.Schedule.ClusterStoreLimit = StoreLimitConfig{AddPeer: -1}
+	re.Error(cfg.Schedule.Validate())
+	cfg.Schedule.ClusterStoreLimit = StoreLimitConfig{AddPeer: 100}
+	cfg.Schedule.GroupStoreLimit = StoreLimitConfig{AddPeer: 50}
+	re.Error(cfg.Schedule.Validate())
+	cfg.Schedule.StoreLimitGroupLabel = "zone"
+	re.NoError(cfg.Schedule.Validate())
+	cfg.Schedule.GroupStoreLimit = StoreLimitConfig{AddPeer: 200}
+	re.Error(cfg.Schedule.Validate())
 	// check quota
 	re.Equal(defaultQuotaBackendBytes, cfg.QuotaBackendBytes)
 	// check request bytes
@@ -175,6 +190,7 @@ metric-storage = "http://127.0.0.1:9090"
 max-merge-region-size = 0
 enable-one-way-merge = true
 leader-schedule-limit = 0
+placement-scan-region-rate = 0
 `
 	cfg := NewConfig()
 	meta, err := toml.Decode(cfgData, &cfg)
@@ -193,10 +209,14 @@ leader-schedule-limit = 0
 	re.Equal(uint64(0), cfg.Schedule.MaxMergeRegionSize)
 	re.True(cfg.Schedule.EnableOneWayMerge)
 	re.Equal(uint64(0), cfg.Schedule.LeaderScheduleLimit)
+	re.Equal(uint64(0), cfg.Schedule.PlacementScanRegionRate)
 	// When undefined, use default values.
 	re.True(cfg.PreVote)
+	re.False(cfg.EnablePreCampaignCheck)
+	re.Equal(defaultPreCampaignMaxCommitLatency, cfg.PreCampaignMaxCommitLatency.Duration)
 	re.Equal("info", cfg.Log.Level)
 	re.Equal(uint64(0), cfg.Schedule.MaxMergeRegionKeys)
+	re.Zero(cfg.Schedule.MergeHotWriteRatio)
 	re.Equal("http://127.0.0.1:9090", cfg.PDServerCfg.MetricStorage)
 
 	re.Equal(DefaultTSOUpdatePhysicalInterval, cfg.TSOUpdatePhysicalInterval.Duration)
@@ -384,8 +404,17 @@ dashboard-address = "foo"
 		re.Equal(test.hasErr, err != nil)
 		if !test.hasErr {
 			re.Equal(test.dashboardAddress, cfg.PDServerCfg.DashboardAddress)
+			re.Equal(metricutil.LabelGranularityStore, cfg.PDServerCfg.MetricsLabelGranularity)
 		}
 	}
+
+	cfg := NewConfig()
+	meta, err := toml.Decode(`
+[pd-server]
+metrics-label-granularity = "region"
+`, &cfg)
+	re.NoError(err)
+	re.Error(cfg.Adjust(&meta, false))
 }
 
 func TestDashboardConfig(t *testing.T) {
@@ -428,6 +457,55 @@ tidb-cert-path = "/path/client.pem"
 	defaultEnableTelemetry = originalDefaultEnableTelemetry
 }
 
+func TestGRPCConfig(t *testing.T) {
+	re := require.New(t)
+	registerDefaultSchedulers()
+	cfg := NewConfig()
+	meta, err := toml.Decode(`
+[grpc]
+keepalive-interval = "1m"
+[pd-server.grpc-tool-class]
+max-streams = 8
+enable-gzip = true
+idle-timeout = "10m"
+`, &cfg)
+	re.NoError(err)
+	re.NoError(cfg.Adjust(&meta, false))
+	re.Equal(defaultGRPCKeepaliveMinTime, cfg.GRPC.KeepaliveMinTime.Duration)
+	re.Equal(time.Minute, cfg.GRPC.KeepaliveInterval.Duration)
+	re.Equal(defaultGRPCKeepaliveTimeout, cfg.GRPC.KeepaliveTimeout.Duration)
+	re.Equal(gzip.DefaultCompression, cfg.PDServerCfg.GRPCGzipLevel)
+
+	opt := NewPersistOptions(cfg)
+	toolClass := opt.GetGRPCClassConfig(grpcutil.ClientClassTool)
+	re.Equal(8, toolClass.MaxStreams)
+	re.True(toolClass.EnableGzip)
+	re.Equal(10*time.Minute, toolClass.IdleTimeout.Duration)
+	re.Equal(GRPCClassConfig{}, opt.GetGRPCClassConfig(grpcutil.ClientClassStore))
+
+	// the settings of the classes are adjusted at runtime.
+	pdServerCfg := opt.GetPDServerConfig().Clone()
+	pdServerCfg.GRPCStoreClass.EnableGzip = true
+	opt.SetPDServerConfig(pdServerCfg)
+	re.True(opt.GetGRPCClassConfig(grpcutil.ClientClassStore).EnableGzip)
+
+	cfg = NewConfig()
+	meta, err = toml.Decode(`
+[pd-server]
+grpc-gzip-level = 10
+`, &cfg)
+	re.NoError(err)
+	re.Error(cfg.Adjust(&meta, false))
+
+	cfg = NewConfig()
+	meta, err = toml.Decode(`
+[pd-server.grpc-sql-class]
+max-streams = -1
+`, &cfg)
+	re.NoError(err)
+	re.Error(cfg.Adjust(&meta, false))
+}
+
 func TestReplicationMode(t *testing.T) {
 	re := require.New(t)
 	registerDefaultSchedulers()
@@ -31,6 +31,8 @@ import (
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/cache"
 	"github.com/tikv/pd/pkg/etcdutil"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/metricutil"
 	"github.com/tikv/pd/pkg/slice"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server/core"
@@ -164,6 +166,42 @@ func (o *PersistOptions) IsPlacementRulesCacheEnabled() bool {
 	return o.GetReplicationConfig().EnablePlacementRulesCache
 }
 
+// IsDegradedReplicaFallbackEnabled returns if the checkers can place a temporary
+// replica when the replicas can not be fixed.
+func (o *PersistOptions) IsDegradedReplicaFallbackEnabled() bool {
+	return o.GetReplicationConfig().EnableDegradedReplicaFallback
+}
+
+// GetOrphanLearnerCheckerMode returns the mode of the orphan learner checker.
+func (o *PersistOptions) GetOrphanLearnerCheckerMode() string {
+	if mode := o.GetReplicationConfig().OrphanLearnerChecker; mode != "" {
+		return mode
+	}
+	return OrphanLearnerCheckerDisabled
+}
+
+// GetOrphanLearnerGracePeriod returns the time a learner must stay orphaned before it is removed.
+func (o *PersistOptions) GetOrphanLearnerGracePeriod() time.Duration {
+	if period := o.GetReplicationConfig().OrphanLearnerGracePeriod.Duration; period > 0 {
+		return period
+	}
+	return defaultOrphanLearnerGracePeriod
+}
+
+// GetOrphanLearnerExcludedEngines returns the engines whose orphan learners are kept.
+func (o *PersistOptions) GetOrphanLearnerExcludedEngines() []string {
+	return o.GetReplicationConfig().OrphanLearnerExcludedEngines
+}
+
+// GetDegradedReplicaFallbackRole returns the role of the temporary replica placed by the
+// degraded replica fallback.
+func (o *PersistOptions) GetDegradedReplicaFallbackRole() string {
+	if role := o.GetReplicationConfig().DegradedReplicaFallbackRole; role != "" {
+		return role
+	}
+	return DegradedReplicaFallbackLearner
+}
+
 // SetPlacementRulesCacheEnabled set EnablePlacementRulesCache
 func (o *PersistOptions) SetPlacementRulesCacheEnabled(enabled bool) {
 	v := o.GetReplicationConfig().Clone()
@@ -374,6 +412,11 @@ func (o *PersistOptions) GetMergeScheduleLimit() uint64 {
 	return o.getTTLUintOr(mergeScheduleLimitKey, o.GetScheduleConfig().MergeScheduleLimit)
 }
 
+// GetLearnerBalanceScheduleLimit returns the limit for the learner balance schedule of the checker.
+func (o *PersistOptions) GetLearnerBalanceScheduleLimit() uint64 {
+	return o.GetScheduleConfig().LearnerBalanceScheduleLimit
+}
+
 // GetHotRegionScheduleLimit returns the limit for hot region schedule.
 func (o *PersistOptions) GetHotRegionScheduleLimit() uint64 {
 	return o.getTTLUintOr(hotRegionScheduleLimitKey, o.GetScheduleConfig().HotRegionScheduleLimit)
@@ -439,6 +482,38 @@ func (o *PersistOptions) GetStoreLimitByType(storeID uint64, typ storelimit.Type
 	}
 }
 
+// GetStoreLimitGroupLabel returns the location label used to group stores for the group store limit.
+func (o *PersistOptions) GetStoreLimitGroupLabel() string {
+	return o.GetScheduleConfig().StoreLimitGroupLabel
+}
+
+// GetGroupStoreLimitByType returns the total limit of a store group with a given type.
+func (o *PersistOptions) GetGroupStoreLimitByType(typ storelimit.Type) float64 {
+	return getStoreLimitConfigByType(o.GetScheduleConfig().GroupStoreLimit, typ)
+}
+
+// GetClusterStoreLimitByType returns the total limit of the cluster with a given type.
+func (o *PersistOptions) GetClusterStoreLimitByType(typ storelimit.Type) float64 {
+	return getStoreLimitConfigByType(o.GetScheduleConfig().ClusterStoreLimit, typ)
+}
+
+// GetStoreLimitSizeRate returns the size limit of the Regions added to or removed from
+// a store in MB per second, 0 means it is translated from the store limit.
+func (o *PersistOptions) GetStoreLimitSizeRate() float64 {
+	return o.GetScheduleConfig().StoreLimitSizeRate
+}
+
+func getStoreLimitConfigByType(limit StoreLimitConfig, typ storelimit.Type) float64 {
+	switch typ {
+	case storelimit.AddPeer:
+		return limit.AddPeer
+	case storelimit.RemovePeer:
+		return limit.RemovePeer
+	default:
+		panic("no such limit type")
+	}
+}
+
 // GetAllStoresLimit returns the limit of all stores.
 func (o *PersistOptions) GetAllStoresLimit() map[uint64]StoreLimitConfig {
 	return o.GetScheduleConfig().StoreLimit
@@ -449,6 +524,18 @@ func (o *PersistOptions) GetStoreLimitMode() string {
 	return o.GetScheduleConfig().StoreLimitMode
 }
 
+// GetMergeHotWriteRatio returns the fraction of the load-based split threshold above which
+// the regions are excluded from merging.
+func (o *PersistOptions) GetMergeHotWriteRatio() float64 {
+	return o.GetScheduleConfig().MergeHotWriteRatio
+}
+
+// GetOperatorDrainTimeout returns the max time to wait for the running operators to finish
+// when the cluster is stopped.
+func (o *PersistOptions) GetOperatorDrainTimeout() time.Duration {
+	return o.GetScheduleConfig().OperatorDrainTimeout.Duration
+}
+
 // GetTolerantSizeRatio gets the tolerant size ratio.
 func (o *PersistOptions) GetTolerantSizeRatio() float64 {
 	return o.GetScheduleConfig().TolerantSizeRatio
@@ -469,6 +556,11 @@ func (o *PersistOptions) GetRegionScoreFormulaVersion() string {
 	return o.GetScheduleConfig().RegionScoreFormulaVersion
 }
 
+// GetRegionScoreCurve returns the parameters of the transition curve of the v3 region score formula.
+func (o *PersistOptions) GetRegionScoreCurve() core.RegionScoreCurve {
+	return o.GetScheduleConfig().GetRegionScoreCurve()
+}
+
 // GetSchedulerMaxWaitingOperator returns the number of the max waiting operators.
 func (o *PersistOptions) GetSchedulerMaxWaitingOperator() uint64 {
 	return o.getTTLUintOr(schedulerMaxWaitingOperatorKey, o.GetScheduleConfig().SchedulerMaxWaitingOperator)
@@ -499,6 +591,12 @@ func (o *PersistOptions) IsUseRegionStorage() bool {
 	return o.GetPDServerConfig().UseRegionStorage
 }
 
+// IsRegionStorageDualWrite returns if the regions are written to both the etcd and
+// the independent region storage.
+func (o *PersistOptions) IsRegionStorageDualWrite() bool {
+	return o.GetPDServerConfig().RegionStorageDualWrite
+}
+
 // IsRemoveDownReplicaEnabled returns if remove down replica is enabled.
 func (o *PersistOptions) IsRemoveDownReplicaEnabled() bool {
 	return o.GetScheduleConfig().EnableRemoveDownReplica
@@ -576,6 +674,141 @@ func (o *PersistOptions) GetHotRegionsWriteInterval() time.Duration {
 	return o.GetScheduleConfig().HotRegionsWriteInterval.Duration
 }
 
+// IsStoreQuotaBiasEnabled returns whether the balance schedulers take the soft quotas of the stores into account.
+func (o *PersistOptions) IsStoreQuotaBiasEnabled() bool {
+	return o.GetScheduleConfig().EnableStoreQuotaBias
+}
+
+// GetMaxStoreVersionSkew returns the max number of minor versions a store can lag
+// behind the newest version among the Up stores, 0 means unlimited.
+func (o *PersistOptions) GetMaxStoreVersionSkew() uint64 {
+	return o.GetScheduleConfig().MaxStoreVersionSkew
+}
+
+// GetStoreVersionSkewPolicy returns the policy applied to the stores exceeding the max version skew.
+func (o *PersistOptions) GetStoreVersionSkewPolicy() string {
+	if policy := o.GetScheduleConfig().StoreVersionSkewPolicy; policy != "" {
+		return policy
+	}
+	return StoreVersionSkewPolicyWarn
+}
+
+// GetSchedulerDiagnosisWindow returns the time a scheduler has produced no operator
+// before its dry runs are sampled in background.
+func (o *PersistOptions) GetSchedulerDiagnosisWindow() time.Duration {
+	return o.GetScheduleConfig().SchedulerDiagnosisWindow.Duration
+}
+
+// GetTopologyChangeRegionRate returns the max number of regions added to the suspect
+// list per second after the location labels of their stores are changed.
+func (o *PersistOptions) GetTopologyChangeRegionRate() uint64 {
+	return o.GetScheduleConfig().TopologyChangeRegionRate
+}
+
+// GetLowSpaceETAWarning returns the predicted time before a store is full, below
+// which the store is warned to be running out of space.
+func (o *PersistOptions) GetLowSpaceETAWarning() time.Duration {
+	return o.GetScheduleConfig().LowSpaceETAWarning.Duration
+}
+
+// GetLowSpaceETACritical returns the predicted time before a store is full, below
+// which the add peer limit of the store is tightened.
+func (o *PersistOptions) GetLowSpaceETACritical() time.Duration {
+	return o.GetScheduleConfig().LowSpaceETACritical.Duration
+}
+
+// GetSchedulerExecutionBudget returns the max time the scheduler can spend in a tick.
+// 0 means unlimited.
+func (o *PersistOptions) GetSchedulerExecutionBudget(name string) time.Duration {
+	cfg := o.GetScheduleConfig()
+	if budget, ok := cfg.SchedulerExecutionBudgets[name]; ok {
+		return budget.Duration
+	}
+	return cfg.SchedulerExecutionBudget.Duration
+}
+
+// GetStaleRegionRatioThreshold returns the ratio of the stale regions above which
+// the region cache is considered stale. 0 means the guard is disabled.
+func (o *PersistOptions) GetStaleRegionRatioThreshold() float64 {
+	return o.GetScheduleConfig().StaleRegionRatioThreshold
+}
+
+// GetStaleRegionHeartbeatIntervals returns the number of the region heartbeat
+// intervals after which a region without heartbeats is considered stale.
+func (o *PersistOptions) GetStaleRegionHeartbeatIntervals() uint64 {
+	if intervals := o.GetScheduleConfig().StaleRegionHeartbeatIntervals; intervals > 0 {
+		return intervals
+	}
+	return defaultStaleRegionHeartbeatIntervals
+}
+
+// GetSplitTargetStores returns the stores preferred by the newly split regions.
+func (o *PersistOptions) GetSplitTargetStores() []uint64 {
+	return o.GetScheduleConfig().SplitTargetStores
+}
+
+// GetSplitTargetFillRatio returns the fraction of the average region count, a split
+// target store is no longer preferred once its region count reaches it.
+func (o *PersistOptions) GetSplitTargetFillRatio() float64 {
+	return o.GetScheduleConfig().SplitTargetFillRatio
+}
+
+// GetEmergencyStoreLimit returns the limit of the operators exempted from the store limits,
+// in operators per minute for each store, 0 means unlimited.
+func (o *PersistOptions) GetEmergencyStoreLimit() float64 {
+	return o.GetScheduleConfig().EmergencyStoreLimit
+}
+
+// GetHotPeerExemplarTopN returns the number of the hottest peers of each store exported
+// with the exemplars, 0 means the export is disabled.
+func (o *PersistOptions) GetHotPeerExemplarTopN() int {
+	return o.GetScheduleConfig().HotPeerExemplarTopN
+}
+
+// GetHotPeerExemplarRefreshInterval returns how often the exported hot peers are refreshed.
+func (o *PersistOptions) GetHotPeerExemplarRefreshInterval() time.Duration {
+	return o.GetScheduleConfig().HotPeerExemplarRefreshInterval.Duration
+}
+
+// GetPlacementScanInterval returns the interval of the placement scans, 0 means the
+// regions are not scanned.
+func (o *PersistOptions) GetPlacementScanInterval() time.Duration {
+	return o.GetScheduleConfig().PlacementScanInterval.Duration
+}
+
+// GetPlacementScanRegionRate returns the max number of regions checked per second by
+// the placement scans.
+func (o *PersistOptions) GetPlacementScanRegionRate() uint64 {
+	return o.GetScheduleConfig().PlacementScanRegionRate
+}
+
+// GetSlowTrendWindow returns the time window of the slow trend series of a store.
+func (o *PersistOptions) GetSlowTrendWindow() time.Duration {
+	return o.GetScheduleConfig().SlowTrendWindow.Duration
+}
+
+// GetSlowTrendCauseValueThreshold returns the min slow score of a store trending slow.
+func (o *PersistOptions) GetSlowTrendCauseValueThreshold() float64 {
+	return o.GetScheduleConfig().SlowTrendCauseValueThreshold
+}
+
+// GetSlowTrendCauseRateThreshold returns the min growth of the slow score per second
+// of a store trending slow.
+func (o *PersistOptions) GetSlowTrendCauseRateThreshold() float64 {
+	return o.GetScheduleConfig().SlowTrendCauseRateThreshold
+}
+
+// GetSuspectKeyRangeGCAge returns the max age of the persisted suspect key ranges.
+func (o *PersistOptions) GetSuspectKeyRangeGCAge() time.Duration {
+	return o.GetScheduleConfig().SuspectKeyRangeGCAge.Duration
+}
+
+// GetWaitingOperatorFairKeyPrefixLength returns the length of the key prefix
+// used to group the waiting operators.
+func (o *PersistOptions) GetWaitingOperatorFairKeyPrefixLength() uint64 {
+	return o.GetScheduleConfig().WaitingOperatorFairKeyPrefixLength
+}
+
 // GetHotRegionsReservedDays gets days hot region information is kept.
 func (o *PersistOptions) GetHotRegionsReservedDays() uint64 {
 	return o.GetScheduleConfig().HotRegionsReservedDays
@@ -585,14 +818,18 @@ func (o *PersistOptions) GetHotRegionsReservedDays() uint64 {
 func (o *PersistOptions) AddSchedulerCfg(tp string, args []string) {
 	v := o.GetScheduleConfig().Clone()
 	for i, schedulerCfg := range v.Schedulers {
+		// The observe-only mode is not compared, since it is set after the
+		// scheduler is added.
+		cfg := schedulerCfg
+		cfg.ObserveUntil = 0
 		// comparing args is to cover the case that there are schedulers in same type but not with same name
 		// such as two schedulers of type "evict-leader",
 		// one name is "evict-leader-scheduler-1" and the other is "evict-leader-scheduler-2"
-		if reflect.DeepEqual(schedulerCfg, SchedulerConfig{Type: tp, Args: args, Disable: false}) {
+		if reflect.DeepEqual(cfg, SchedulerConfig{Type: tp, Args: args, Disable: false}) {
 			return
 		}
 
-		if reflect.DeepEqual(schedulerCfg, SchedulerConfig{Type: tp, Args: args, Disable: true}) {
+		if reflect.DeepEqual(cfg, SchedulerConfig{Type: tp, Args: args, Disable: true}) {
 			schedulerCfg.Disable = false
 			v.Schedulers[i] = schedulerCfg
 			o.SetScheduleConfig(v)
@@ -702,6 +939,112 @@ func (o *PersistOptions) GetMinResolvedTSPersistenceInterval() time.Duration {
 	return o.GetPDServerConfig().MinResolvedTSPersistenceInterval.Duration
 }
 
+// IsTSOFollowerProxyEnabled returns if the followers forward the TSO requests to the leader.
+func (o *PersistOptions) IsTSOFollowerProxyEnabled() bool {
+	return o.GetPDServerConfig().EnableTSOFollowerProxy
+}
+
+// IsStoreTokenAuthEnabled returns if the stores need to present a token when
+// registering themselves and sending heartbeats.
+func (o *PersistOptions) IsStoreTokenAuthEnabled() bool {
+	return o.GetPDServerConfig().EnableStoreTokenAuth
+}
+
+// IsKeyVisualEnabled returns whether the heatmap of the key ranges is collected.
+func (o *PersistOptions) IsKeyVisualEnabled() bool {
+	return o.GetPDServerConfig().EnableKeyVisual
+}
+
+// IsRegionCacheSafeModeEnabled returns if dropping the region cache by the API
+// is guarded by the confirmation token and the rate limit.
+func (o *PersistOptions) IsRegionCacheSafeModeEnabled() bool {
+	return o.GetPDServerConfig().EnableRegionCacheSafeMode
+}
+
+// IsTraceEnabled returns whether the operators are traced.
+func (o *PersistOptions) IsTraceEnabled() bool {
+	return o.GetPDServerConfig().EnableTrace
+}
+
+// GetTraceSampleRatio returns the fraction of the operators traced.
+func (o *PersistOptions) GetTraceSampleRatio() float64 {
+	return o.GetPDServerConfig().TraceSampleRatio
+}
+
+// GetMinResolvedTSMissingStorePolicy returns the policy of the stores missing from the min resolved ts.
+func (o *PersistOptions) GetMinResolvedTSMissingStorePolicy() string {
+	return o.GetPDServerConfig().MinResolvedTSMissingStorePolicy
+}
+
+// GetMinResolvedTSMissingStoreHoldTime returns how long the last resolved ts of a missing store is honored.
+func (o *PersistOptions) GetMinResolvedTSMissingStoreHoldTime() time.Duration {
+	return o.GetPDServerConfig().MinResolvedTSMissingStoreHoldTime.Duration
+}
+
+// GetMinResolvedTSLagThreshold returns the lag of the resolved ts of a store, above which it is alerted.
+func (o *PersistOptions) GetMinResolvedTSLagThreshold() time.Duration {
+	return o.GetPDServerConfig().MinResolvedTSLagThreshold.Duration
+}
+
+// GetWorkerPoolSizes returns the configured sizes of the worker pools.
+func (o *PersistOptions) GetWorkerPoolSizes() map[string]int {
+	return o.GetPDServerConfig().WorkerPoolSizes
+}
+
+// GetDisabledSubsystems returns the names of the optional subsystems which are not started.
+func (o *PersistOptions) GetDisabledSubsystems() []string {
+	return o.GetPDServerConfig().DisabledSubsystems
+}
+
+// GetEventWebhookURL returns the URL which the cluster events are posted to.
+func (o *PersistOptions) GetEventWebhookURL() string {
+	return o.GetPDServerConfig().EventWebhookURL
+}
+
+// GetRegionQueryCacheSize returns the memory limit of the region query cache.
+func (o *PersistOptions) GetRegionQueryCacheSize() uint64 {
+	return uint64(o.GetPDServerConfig().RegionQueryCacheSize)
+}
+
+// GetGRPCClassConfig returns the gRPC settings of the streams of the given
+// client class, the unknown classes are not limited.
+func (o *PersistOptions) GetGRPCClassConfig(class string) GRPCClassConfig {
+	cfg := o.GetPDServerConfig()
+	switch class {
+	case grpcutil.ClientClassStore:
+		return cfg.GRPCStoreClass
+	case grpcutil.ClientClassSQL:
+		return cfg.GRPCSQLClass
+	case grpcutil.ClientClassTool:
+		return cfg.GRPCToolClass
+	default:
+		return GRPCClassConfig{}
+	}
+}
+
+// GetGRPCGzipLevel returns the compression level of the gzip messages.
+func (o *PersistOptions) GetGRPCGzipLevel() int {
+	return o.GetPDServerConfig().GRPCGzipLevel
+}
+
+// GetMetricsLabelGranularity returns the granularity of the store labels of the controlled metric families.
+func (o *PersistOptions) GetMetricsLabelGranularity() string {
+	if granularity := o.GetPDServerConfig().MetricsLabelGranularity; granularity != "" {
+		return granularity
+	}
+	return metricutil.LabelGranularityStore
+}
+
+// GetDisabledMetrics returns the names of the metric families which are not collected.
+func (o *PersistOptions) GetDisabledMetrics() []string {
+	return o.GetPDServerConfig().DisabledMetrics
+}
+
+// GetStoreMetricsEmitInterval gets the interval to recompute and emit the metrics of all stores.
+func (o *PersistOptions) GetStoreMetricsEmitInterval() time.Duration {
+	return o.GetPDServerConfig().StoreMetricsEmitInterval.Duration
+}
+
 const ttlConfigPrefix = "/config/ttl"
 
 // SetTTLData set temporary configuration
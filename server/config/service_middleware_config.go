@@ -14,7 +14,10 @@
 
 package config
 
-import "github.com/tikv/pd/pkg/ratelimit"
+import (
+	"github.com/tikv/pd/pkg/ratelimit"
+	"github.com/tikv/pd/pkg/typeutil"
+)
 
 const (
 	defaultEnableAuditMiddleware     = false
@@ -67,6 +70,9 @@ type RateLimitConfig struct {
 	EnableRateLimit bool `json:"enable-rate-limit,string"`
 	// RateLimitConfig is the config of rate limit middleware
 	LimiterConfig map[string]ratelimit.DimensionConfig `json:"limiter-config"`
+	// OverloadLatencyThreshold is the p99 latency threshold of the APIs and gRPC methods,
+	// PD is regarded as overloaded if it is exceeded. 0 means the overload signal is disabled.
+	OverloadLatencyThreshold typeutil.Duration `json:"overload-latency-threshold"`
 }
 
 // Clone returns a cloned rate limit config.
@@ -17,6 +17,7 @@ package config
 import (
 	"errors"
 	"sync/atomic"
+	"time"
 
 	"github.com/pingcap/failpoint"
 	"github.com/tikv/pd/server/storage/endpoint"
@@ -67,6 +68,11 @@ func (o *ServiceMiddlewarePersistOptions) IsRateLimitEnabled() bool {
 	return o.GetRateLimitConfig().EnableRateLimit
 }
 
+// GetOverloadLatencyThreshold returns the latency threshold to regard PD as overloaded.
+func (o *ServiceMiddlewarePersistOptions) GetOverloadLatencyThreshold() time.Duration {
+	return o.GetRateLimitConfig().OverloadLatencyThreshold.Duration
+}
+
 // Persist saves the configuration to the storage.
 func (o *ServiceMiddlewarePersistOptions) Persist(storage endpoint.ServiceMiddlewareStorage) error {
 	cfg := &ServiceMiddlewareConfig{
@@ -22,6 +22,7 @@ import (
 	"reflect"
 	"sync/atomic"
 
+	"github.com/docker/go-units"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/netutil"
@@ -41,6 +42,10 @@ var (
 	defaultRegionMaxKey = uint64(1440000)
 	// default region split key is 960000
 	defaultRegionSplitKey = uint64(960000)
+	// default load-based split qps threshold is 3000
+	defaultSplitQPSThreshold = uint64(3000)
+	// default load-based split byte threshold is 30MB/s
+	defaultSplitByteThreshold = uint64(30 * units.MiB)
 )
 
 // StoreConfig is the config of store like TiKV.
@@ -48,6 +53,7 @@ var (
 // nolint
 type StoreConfig struct {
 	Coprocessor `json:"coprocessor"`
+	Split       `json:"split"`
 }
 
 // Coprocessor is the config of coprocessor.
@@ -63,6 +69,15 @@ type Coprocessor struct {
 	RegionBucketSize   string `json:"region-bucket-size"`
 }
 
+// Split is the config of load-based split.
+type Split struct {
+	// QPSThreshold is the qps of a region, above which the region will be split by load.
+	QPSThreshold int `json:"qps-threshold"`
+	// ByteThreshold is the flow of a region in bytes per second, above which the region
+	// will be split by load.
+	ByteThreshold int `json:"byte-threshold"`
+}
+
 // String implements fmt.Stringer interface.
 func (c *StoreConfig) String() string {
 	data, err := json.MarshalIndent(c, "", "  ")
@@ -104,6 +119,22 @@ func (c *StoreConfig) GetRegionMaxKeys() uint64 {
 	return uint64(c.Coprocessor.RegionMaxKeys)
 }
 
+// GetSplitQPSThreshold returns the qps threshold of load-based split.
+func (c *StoreConfig) GetSplitQPSThreshold() uint64 {
+	if c == nil || c.Split.QPSThreshold == 0 {
+		return defaultSplitQPSThreshold
+	}
+	return uint64(c.Split.QPSThreshold)
+}
+
+// GetSplitByteThreshold returns the byte threshold of load-based split in bytes per second.
+func (c *StoreConfig) GetSplitByteThreshold() uint64 {
+	if c == nil || c.Split.ByteThreshold == 0 {
+		return defaultSplitByteThreshold
+	}
+	return uint64(c.Split.ByteThreshold)
+}
+
 // IsEnableRegionBucket return ture if the region bucket is enabled.
 func (c *StoreConfig) IsEnableRegionBucket() bool {
 	if c == nil {
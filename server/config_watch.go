@@ -0,0 +1,188 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+// The kinds of the configurations which can be watched.
+const (
+	ConfigKindSchedule      = "schedule"
+	ConfigKindReplication   = "replication"
+	ConfigKindLabelProperty = "label-property"
+	ConfigKindPDServer      = "pd-server"
+)
+
+// configWatchHistorySize is the number of the changes kept for the watchers to
+// resume from.
+const configWatchHistorySize = 256
+
+var configWatchKinds = []string{ConfigKindSchedule, ConfigKindReplication, ConfigKindLabelProperty, ConfigKindPDServer}
+
+// ConfigChange is a change of the configuration, Config is the whole
+// configuration of the kind after the change.
+type ConfigChange struct {
+	Revision uint64      `json:"revision"`
+	Kind     string      `json:"kind"`
+	Config   interface{} `json:"config"`
+	Time     time.Time   `json:"time"`
+}
+
+// ConfigWatchResponse is the result of watching the configuration changes.
+type ConfigWatchResponse struct {
+	// Revision is the revision to resume watching from.
+	Revision uint64 `json:"revision"`
+	// Compacted means the changes after the requested revision are not
+	// available, then Changes contain the current configurations instead.
+	Compacted bool            `json:"compacted"`
+	Changes   []*ConfigChange `json:"changes"`
+}
+
+// configWatcher keeps the recent configuration changes and wakes up the
+// watchers. The revisions only increase in a leader term, it is reset with a
+// larger revision once the server becomes the leader, so the watchers resuming
+// from the previous leader always get the whole configurations.
+type configWatcher struct {
+	syncutil.Mutex
+	revision uint64
+	history  []*ConfigChange
+	// changed is closed and replaced on every change.
+	changed chan struct{}
+}
+
+func (w *configWatcher) changedLocked() chan struct{} {
+	if w.changed == nil {
+		w.changed = make(chan struct{})
+	}
+	return w.changed
+}
+
+func (w *configWatcher) reset() {
+	w.Lock()
+	defer w.Unlock()
+	if rev := uint64(time.Now().UnixNano()); rev > w.revision {
+		w.revision = rev
+	}
+	w.history = nil
+	close(w.changedLocked())
+	w.changed = nil
+}
+
+func (w *configWatcher) notify(kind string, cfg interface{}) {
+	w.Lock()
+	defer w.Unlock()
+	w.revision++
+	w.history = append(w.history, &ConfigChange{Revision: w.revision, Kind: kind, Config: cfg, Time: time.Now()})
+	if len(w.history) > configWatchHistorySize {
+		w.history = w.history[len(w.history)-configWatchHistorySize:]
+	}
+	close(w.changedLocked())
+	w.changed = nil
+}
+
+// changesAfterLocked returns the changes after the revision, ok is false if they are
+// not available.
+func (w *configWatcher) changesAfterLocked(revision uint64, kinds map[string]struct{}) (changes []*ConfigChange, ok bool) {
+	if revision == 0 || revision > w.revision {
+		return nil, false
+	}
+	if revision < w.revision && (len(w.history) == 0 || revision+1 < w.history[0].Revision) {
+		return nil, false
+	}
+	for _, change := range w.history {
+		if _, watched := kinds[change.Kind]; watched && change.Revision > revision {
+			changes = append(changes, change)
+		}
+	}
+	return changes, true
+}
+
+// watch waits until there are changes of the kinds after the revision or the
+// timeout is reached. The snapshot is used to build the current configurations
+// if the changes are not available.
+func (w *configWatcher) watch(ctx context.Context, revision uint64, kinds map[string]struct{}, timeout time.Duration,
+	snapshot func(kind string) interface{}) *ConfigWatchResponse {
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	for {
+		w.Lock()
+		current := w.revision
+		changes, ok := w.changesAfterLocked(revision, kinds)
+		changed := w.changedLocked()
+		w.Unlock()
+		if !ok {
+			resp := &ConfigWatchResponse{Revision: current, Compacted: true}
+			for _, kind := range configWatchKinds {
+				if _, watched := kinds[kind]; watched {
+					resp.Changes = append(resp.Changes, &ConfigChange{Revision: current, Kind: kind, Config: snapshot(kind), Time: time.Now()})
+				}
+			}
+			return resp
+		}
+		if len(changes) > 0 {
+			return &ConfigWatchResponse{Revision: current, Changes: changes}
+		}
+		select {
+		case <-changed:
+		case <-timer.C:
+			return &ConfigWatchResponse{Revision: current, Changes: []*ConfigChange{}}
+		case <-ctx.Done():
+			return &ConfigWatchResponse{Revision: current, Changes: []*ConfigChange{}}
+		}
+	}
+}
+
+// WatchConfig waits for the changes of the given kinds of configurations after
+// the revision until the timeout is reached. All kinds are watched if kinds is
+// empty. The current configurations are returned if the revision is 0 or the
+// changes after it are not available anymore.
+func (s *Server) WatchConfig(ctx context.Context, revision uint64, kinds []string, timeout time.Duration) (*ConfigWatchResponse, error) {
+	watched := make(map[string]struct{})
+	for _, kind := range kinds {
+		if s.configSnapshot(kind) == nil {
+			return nil, errs.ErrConfigWatchKind.FastGenByArgs(kind)
+		}
+		watched[kind] = struct{}{}
+	}
+	if len(watched) == 0 {
+		for _, kind := range configWatchKinds {
+			watched[kind] = struct{}{}
+		}
+	}
+	return s.configWatcher.watch(ctx, revision, watched, timeout, s.configSnapshot), nil
+}
+
+func (s *Server) configSnapshot(kind string) interface{} {
+	switch kind {
+	case ConfigKindSchedule:
+		return s.GetScheduleConfig()
+	case ConfigKindReplication:
+		return s.GetReplicationConfig()
+	case ConfigKindLabelProperty:
+		return s.GetLabelProperty()
+	case ConfigKindPDServer:
+		return s.GetPDServerConfig()
+	}
+	return nil
+}
+
+func (s *Server) notifyConfigChange(kind string) {
+	s.configWatcher.notify(kind, s.configSnapshot(kind))
+}
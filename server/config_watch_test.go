@@ -0,0 +1,64 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestConfigWatcher(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	w := &configWatcher{}
+	kinds := map[string]struct{}{ConfigKindSchedule: {}}
+	snapshot := func(kind string) interface{} { return kind }
+
+	w.reset()
+	resp := w.watch(ctx, 0, kinds, time.Second, snapshot)
+	re.True(resp.Compacted)
+	re.Len(resp.Changes, 1)
+	revision := resp.Revision
+
+	for i := 0; i < configWatchHistorySize+1; i++ {
+		w.notify(ConfigKindSchedule, i)
+	}
+	// The first change is evicted.
+	resp = w.watch(ctx, revision, kinds, time.Second, snapshot)
+	re.True(resp.Compacted)
+	re.Equal(revision+configWatchHistorySize+1, resp.Revision)
+	resp = w.watch(ctx, revision+1, kinds, time.Second, snapshot)
+	re.False(resp.Compacted)
+	re.Len(resp.Changes, configWatchHistorySize)
+	re.Equal(1, resp.Changes[0].Config)
+
+	// The revision of the previous leader is not comparable.
+	revision = resp.Revision
+	w.reset()
+	resp = w.watch(ctx, revision, kinds, time.Second, snapshot)
+	re.True(resp.Compacted)
+	re.Greater(resp.Revision, revision)
+
+	// The watcher returns when the context is done.
+	revision = resp.Revision
+	cancel()
+	resp = w.watch(ctx, revision, kinds, time.Minute, snapshot)
+	re.False(resp.Compacted)
+	re.Empty(resp.Changes)
+}
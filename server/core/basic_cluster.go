@@ -208,6 +208,15 @@ func (bc *BasicCluster) RandLeaderRegions(storeID uint64, ranges []KeyRange) []*
 	return bc.Regions.RandLeaderRegions(storeID, ranges, randomRegionMaxRetry)
 }
 
+// SampleRegions returns a uniform random sample of at most n regions overlapping
+// the key range, and the number of the regions sampled from. If storeID is not
+// 0, only the regions with a peer on the store are sampled.
+func (bc *BasicCluster) SampleRegions(n int, storeID uint64, startKey, endKey []byte) ([]*RegionInfo, int) {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.SampleRegions(n, storeID, startKey, endKey)
+}
+
 // RandPendingRegions returns a random region that has a pending peer on the store.
 func (bc *BasicCluster) RandPendingRegions(storeID uint64, ranges []KeyRange) []*RegionInfo {
 	bc.RLock()
@@ -257,6 +266,13 @@ func (bc *BasicCluster) GetStoreFollowerCount(storeID uint64) int {
 	return bc.Regions.GetStoreFollowerCount(storeID)
 }
 
+// GetStoreLearnerCount get the total count of a store's learner RegionInfo.
+func (bc *BasicCluster) GetStoreLearnerCount(storeID uint64) int {
+	bc.RLock()
+	defer bc.RUnlock()
+	return bc.Regions.GetStoreLearnerCount(storeID)
+}
+
 // GetStorePendingPeerCount gets the total count of a store's region that includes pending peer.
 func (bc *BasicCluster) GetStorePendingPeerCount(storeID uint64) int {
 	bc.RLock()
@@ -345,7 +361,8 @@ func (bc *BasicCluster) getRelevantRegions(region *RegionInfo) (origin *RegionIn
 	return
 }
 
-func isRegionRecreated(region *RegionInfo) bool {
+// IsRegionRecreated returns whether the region is recreated by online unsafe recover.
+func IsRegionRecreated(region *RegionInfo) bool {
 	// Regions recreated by online unsafe recover have both ver and conf ver equal to 1. To
 	// prevent stale bootstrap region (first region in a cluster which covers the entire key
 	// range) from reporting stale info, we exclude regions that covers the entire key range
@@ -360,7 +377,7 @@ func (bc *BasicCluster) PreCheckPutRegion(region *RegionInfo) (*RegionInfo, erro
 	origin, overlaps := bc.getRelevantRegions(region)
 	for _, item := range overlaps {
 		// PD ignores stale regions' heartbeats, unless it is recreated recently by unsafe recover operation.
-		if region.GetRegionEpoch().GetVersion() < item.GetRegionEpoch().GetVersion() && !isRegionRecreated(region) {
+		if region.GetRegionEpoch().GetVersion() < item.GetRegionEpoch().GetVersion() && !IsRegionRecreated(region) {
 			return nil, errRegionIsStale(region.GetMeta(), item.GetMeta())
 		}
 	}
@@ -373,7 +390,7 @@ func (bc *BasicCluster) PreCheckPutRegion(region *RegionInfo) (*RegionInfo, erro
 	// TiKV reports term after v3.0
 	isTermBehind := region.GetTerm() > 0 && region.GetTerm() < origin.GetTerm()
 	// Region meta is stale, return an error.
-	if (isTermBehind || r.GetVersion() < o.GetVersion() || r.GetConfVer() < o.GetConfVer()) && !isRegionRecreated(region) {
+	if (isTermBehind || r.GetVersion() < o.GetVersion() || r.GetConfVer() < o.GetConfVer()) && !IsRegionRecreated(region) {
 		return origin, errRegionIsStale(region.GetMeta(), origin.GetMeta())
 	}
 
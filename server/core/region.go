@@ -1065,6 +1065,17 @@ func (r *RegionsInfo) RandLearnerRegions(storeID uint64, ranges []KeyRange, n in
 	return r.learners[storeID].RandomRegions(n, ranges)
 }
 
+// SampleRegions returns a uniform random sample of at most n regions overlapping
+// the key range, in the order of their start keys, and the number of the regions
+// sampled from. If storeID is not 0, only the regions with a peer on the store
+// are sampled.
+func (r *RegionsInfo) SampleRegions(n int, storeID uint64, startKey, endKey []byte) ([]*RegionInfo, int) {
+	if storeID == 0 {
+		return sampleRegions([]*regionTree{r.tree}, n, startKey, endKey)
+	}
+	return sampleRegions([]*regionTree{r.leaders[storeID], r.followers[storeID], r.learners[storeID]}, n, startKey, endKey)
+}
+
 // GetLeader returns leader RegionInfo by storeID and regionID (now only used in test)
 func (r *RegionsInfo) GetLeader(storeID uint64, region *RegionInfo) *RegionInfo {
 	if leaders, ok := r.leaders[storeID]; ok {
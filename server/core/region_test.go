@@ -569,6 +569,61 @@ func TestShouldRemoveFromSubTree(t *testing.T) {
 	re.False(region.peersEqualTo(origin))
 }
 
+func TestSampleRegions(t *testing.T) {
+	re := require.New(t)
+	regions := NewRegionsInfo()
+	sample, total := regions.SampleRegions(10, 0, nil, nil)
+	re.Empty(sample)
+	re.Equal(0, total)
+
+	for i := uint64(1); i <= 100; i++ {
+		peers := []*metapb.Peer{{Id: i*10 + 1, StoreId: i%3 + 1}}
+		if i%2 == 0 {
+			peers = append(peers, &metapb.Peer{Id: i*10 + 2, StoreId: 4})
+		}
+		regions.SetRegion(NewRegionInfo(&metapb.Region{
+			Id:       i,
+			Peers:    peers,
+			StartKey: []byte(fmt.Sprintf("%20d", i*10)),
+			EndKey:   []byte(fmt.Sprintf("%20d", (i+1)*10)),
+		}, peers[0]))
+	}
+
+	checkSample := func(n int, storeID uint64, startKey, endKey []byte, expectCount, expectTotal int) {
+		sample, total := regions.SampleRegions(n, storeID, startKey, endKey)
+		re.Len(sample, expectCount)
+		re.Equal(expectTotal, total)
+		ids := make(map[uint64]struct{})
+		for i, region := range sample {
+			ids[region.GetID()] = struct{}{}
+			if i > 0 {
+				re.Less(string(sample[i-1].GetStartKey()), string(region.GetStartKey()))
+			}
+			if storeID != 0 {
+				re.NotNil(region.GetStorePeer(storeID))
+			}
+			if len(startKey) > 0 {
+				re.True(len(region.GetEndKey()) == 0 || string(region.GetEndKey()) > string(startKey))
+			}
+			if len(endKey) > 0 {
+				re.Less(string(region.GetStartKey()), string(endKey))
+			}
+		}
+		re.Len(ids, expectCount)
+	}
+	for i := 0; i < 10; i++ {
+		checkSample(10, 0, nil, nil, 10, 100)
+		checkSample(200, 0, nil, nil, 100, 100)
+		// Store 4 only has followers, store 1 only has leaders.
+		checkSample(10, 4, nil, nil, 10, 50)
+		checkSample(10, 1, nil, nil, 10, 33)
+		checkSample(100, 5, nil, nil, 0, 0)
+		// The region [105, 110) overlaps the start key 105.
+		checkSample(5, 0, []byte(fmt.Sprintf("%20d", 105)), []byte(fmt.Sprintf("%20d", 200)), 5, 10)
+		checkSample(20, 4, []byte(fmt.Sprintf("%20d", 100)), []byte(fmt.Sprintf("%20d", 200)), 5, 5)
+	}
+}
+
 func checkRegions(re *require.Assertions, regions *RegionsInfo) {
 	leaderMap := make(map[uint64]uint64)
 	followerMap := make(map[uint64]uint64)
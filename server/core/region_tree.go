@@ -16,6 +16,7 @@ package core
 import (
 	"bytes"
 	"math/rand"
+	"sort"
 	"time"
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
@@ -235,6 +236,26 @@ func (t *regionTree) getAdjacentRegions(region *RegionInfo) (*regionItem, *regio
 	return prev, next
 }
 
+// getIndexRange returns the range [start, end) of the indexes of the regions
+// overlapping the key range.
+func (t *regionTree) getIndexRange(startKey, endKey []byte) (int, int) {
+	var endIndex int
+	startRegion, startIndex := t.tree.GetWithIndex(&regionItem{region: &RegionInfo{meta: &metapb.Region{StartKey: startKey}}})
+
+	if len(endKey) != 0 {
+		_, endIndex = t.tree.GetWithIndex(&regionItem{region: &RegionInfo{meta: &metapb.Region{StartKey: endKey}}})
+	} else {
+		endIndex = t.tree.Len()
+	}
+
+	// Consider that the item in the tree may not be continuous,
+	// we need to check if the previous item contains the key.
+	if startIndex != 0 && startRegion == nil && t.tree.GetAt(startIndex-1).(*regionItem).Contains(startKey) {
+		startIndex--
+	}
+	return startIndex, endIndex
+}
+
 // RandomRegion is used to get a random region within ranges.
 func (t *regionTree) RandomRegion(ranges []KeyRange) *RegionInfo {
 	if t.length() == 0 {
@@ -246,22 +267,8 @@ func (t *regionTree) RandomRegion(ranges []KeyRange) *RegionInfo {
 	}
 
 	for _, i := range rand.Perm(len(ranges)) {
-		var endIndex int
 		startKey, endKey := ranges[i].StartKey, ranges[i].EndKey
-		startRegion, startIndex := t.tree.GetWithIndex(&regionItem{region: &RegionInfo{meta: &metapb.Region{StartKey: startKey}}})
-
-		if len(endKey) != 0 {
-			_, endIndex = t.tree.GetWithIndex(&regionItem{region: &RegionInfo{meta: &metapb.Region{StartKey: endKey}}})
-		} else {
-			endIndex = t.tree.Len()
-		}
-
-		// Consider that the item in the tree may not be continuous,
-		// we need to check if the previous item contains the key.
-		if startIndex != 0 && startRegion == nil && t.tree.GetAt(startIndex-1).(*regionItem).Contains(startKey) {
-			startIndex--
-		}
-
+		startIndex, endIndex := t.getIndexRange(startKey, endKey)
 		if endIndex <= startIndex {
 			if len(endKey) > 0 && bytes.Compare(startKey, endKey) > 0 {
 				log.Error("wrong range keys",
@@ -296,6 +303,60 @@ func (t *regionTree) RandomRegions(n int, ranges []KeyRange) []*RegionInfo {
 	return regions
 }
 
+// sampleRegions returns a uniform random sample of at most n regions without
+// replacement from the regions overlapping the key range in the trees, which
+// should not share any region. It also returns the number of the regions
+// sampled from. The regions are located by their indexes in the trees, so it
+// does not need to scan the regions.
+func sampleRegions(trees []*regionTree, n int, startKey, endKey []byte) ([]*RegionInfo, int) {
+	type indexRange struct {
+		tree       *regionTree
+		start, end int
+	}
+	ranges := make([]indexRange, 0, len(trees))
+	total := 0
+	for _, t := range trees {
+		if t.length() == 0 {
+			continue
+		}
+		start, end := t.getIndexRange(startKey, endKey)
+		if end <= start {
+			continue
+		}
+		ranges = append(ranges, indexRange{tree: t, start: start, end: end})
+		total += end - start
+	}
+	if n > total {
+		n = total
+	}
+	if n <= 0 {
+		return nil, total
+	}
+	// Floyd's algorithm picks n distinct offsets with the same probability.
+	offsets := make(map[int]struct{}, n)
+	for j := total - n; j < total; j++ {
+		offset := rand.Intn(j + 1)
+		if _, ok := offsets[offset]; ok {
+			offset = j
+		}
+		offsets[offset] = struct{}{}
+	}
+	regions := make([]*RegionInfo, 0, n)
+	for offset := range offsets {
+		for _, r := range ranges {
+			if offset < r.end-r.start {
+				regions = append(regions, r.tree.tree.GetAt(r.start+offset).(*regionItem).region)
+				break
+			}
+			offset -= r.end - r.start
+		}
+	}
+	sort.Slice(regions, func(i, j int) bool {
+		return bytes.Compare(regions[i].GetStartKey(), regions[j].GetStartKey()) < 0
+	})
+	return regions, total
+}
+
 func (t *regionTree) TotalSize() int64 {
 	if t.length() == 0 {
 		return 0
@@ -57,8 +57,14 @@ type StoreInfo struct {
 	lastPersistTime     time.Time
 	leaderWeight        float64
 	regionWeight        float64
+	leaderQuota         uint64  // the soft quota of the leader count, 0 means unlimited
+	regionQuota         uint64  // the soft quota of the region count, 0 means unlimited
+	addPeerLimitRatio   float64 // the ratio to tighten the add peer limit, 0 means not tightened
+	versionLag          uint64  // the minor versions lagging behind the newest Up store, MaxUint64 if the major version differs
+	slowTrend           *SlowTrend
 	limiter             map[storelimit.Type]*storelimit.StoreLimit
 	minResolvedTS       uint64
+	topology            *StoreTopology
 }
 
 // NewStoreInfo creates StoreInfo with meta data.
@@ -99,8 +105,14 @@ func (s *StoreInfo) Clone(opts ...StoreCreateOption) *StoreInfo {
 		lastPersistTime:     s.lastPersistTime,
 		leaderWeight:        s.leaderWeight,
 		regionWeight:        s.regionWeight,
+		leaderQuota:         s.leaderQuota,
+		regionQuota:         s.regionQuota,
+		addPeerLimitRatio:   s.addPeerLimitRatio,
+		slowTrend:           s.slowTrend,
+		versionLag:          s.versionLag,
 		limiter:             s.limiter,
 		minResolvedTS:       s.minResolvedTS,
+		topology:            s.topology,
 	}
 
 	for _, opt := range opts {
@@ -124,8 +136,14 @@ func (s *StoreInfo) ShallowClone(opts ...StoreCreateOption) *StoreInfo {
 		lastPersistTime:     s.lastPersistTime,
 		leaderWeight:        s.leaderWeight,
 		regionWeight:        s.regionWeight,
+		leaderQuota:         s.leaderQuota,
+		regionQuota:         s.regionQuota,
+		addPeerLimitRatio:   s.addPeerLimitRatio,
+		slowTrend:           s.slowTrend,
+		versionLag:          s.versionLag,
 		limiter:             s.limiter,
 		minResolvedTS:       s.minResolvedTS,
+		topology:            s.topology,
 	}
 
 	for _, opt := range opts {
@@ -199,6 +217,26 @@ func (s *StoreInfo) IsSlow() bool {
 	return s.rawStats.GetSlowScore() >= slowStoreThreshold
 }
 
+// SlowTrend is the trend of the slowness of a store reported by the store heartbeat.
+// The cause is the slow score, and the result is the query rate served by the store.
+type SlowTrend struct {
+	CauseValue  float64 `json:"cause_value"`
+	CauseRate   float64 `json:"cause_rate"`
+	ResultValue float64 `json:"result_value"`
+	ResultRate  float64 `json:"result_rate"`
+}
+
+// SlowTrendSample is a slow trend reported by the store at the time.
+type SlowTrendSample struct {
+	Time time.Time `json:"time"`
+	*SlowTrend
+}
+
+// GetSlowTrend returns the slow trend of the store, nil if the store does not report it.
+func (s *StoreInfo) GetSlowTrend() *SlowTrend {
+	return s.slowTrend
+}
+
 // IsPhysicallyDestroyed checks if the store's physically destroyed.
 func (s *StoreInfo) IsPhysicallyDestroyed() bool {
 	return s.GetMeta().GetPhysicallyDestroyed()
@@ -284,6 +322,45 @@ func (s *StoreInfo) GetRegionWeight() float64 {
 	return s.regionWeight
 }
 
+// GetLeaderQuota returns the soft quota of the leader count of the store, 0 means unlimited.
+func (s *StoreInfo) GetLeaderQuota() uint64 {
+	return s.leaderQuota
+}
+
+// GetRegionQuota returns the soft quota of the Region count of the store, 0 means unlimited.
+func (s *StoreInfo) GetRegionQuota() uint64 {
+	return s.regionQuota
+}
+
+// GetAddPeerLimitRatio returns the ratio applied to the add peer limit of the store,
+// which is less than 1 if the limit is tightened, e.g. when the store is nearly full.
+func (s *StoreInfo) GetAddPeerLimitRatio() float64 {
+	if s.addPeerLimitRatio <= 0 {
+		return 1
+	}
+	return s.addPeerLimitRatio
+}
+
+// ExceedsQuota returns true if the leader or Region count of the store exceeds
+// its soft quota after delta leaders or Regions are added.
+func (s *StoreInfo) ExceedsQuota(kind ResourceKind, delta int) bool {
+	var quota uint64
+	var count int
+	switch kind {
+	case LeaderKind:
+		quota, count = s.leaderQuota, s.GetLeaderCount()
+	case RegionKind:
+		quota, count = s.regionQuota, s.GetRegionCount()
+	}
+	return quota > 0 && count+delta > int(quota)
+}
+
+// GetVersionLag returns the number of minor versions the store lags behind the newest
+// version among the Up stores. It is math.MaxUint64 if their major versions differ.
+func (s *StoreInfo) GetVersionLag() uint64 {
+	return s.versionLag
+}
+
 // GetLastHeartbeatTS returns the last heartbeat timestamp of the store.
 func (s *StoreInfo) GetLastHeartbeatTS() time.Time {
 	return time.Unix(0, s.meta.GetLastHeartbeat())
@@ -316,12 +393,28 @@ func (s *StoreInfo) LeaderScore(policy SchedulePolicy, delta int64) float64 {
 	}
 }
 
+// RegionScoreCurve is the parameters of the transition curve of the v3 region
+// score formula.
+type RegionScoreCurve struct {
+	// Amplification is the multiple of the score to the region size when the
+	// store reaches the low space stage.
+	Amplification float64 `json:"amplification"`
+	// Exponent controls the shape of the transition stage. 1 means linear, and
+	// the larger it is, the later the score rises.
+	Exponent float64 `json:"exponent"`
+	// LowSpacePenalty is the score added to a full store in the low space stage.
+	LowSpacePenalty float64 `json:"low-space-penalty"`
+}
+
 // RegionScore returns the store's region score.
 // Deviation It is used to control the direction of the deviation considered
 // when calculating the region score. It is set to -1 when it is the source
 // store of balance, 1 when it is the target, and 0 in the rest of cases.
-func (s *StoreInfo) RegionScore(version string, highSpaceRatio, lowSpaceRatio float64, delta int64) float64 {
+// The curve is only used by the v3 formula.
+func (s *StoreInfo) RegionScore(version string, highSpaceRatio, lowSpaceRatio float64, curve RegionScoreCurve, delta int64) float64 {
 	switch version {
+	case "v3":
+		return s.regionScoreV3(highSpaceRatio, lowSpaceRatio, curve, delta)
 	case "v2":
 		return s.regionScoreV2(delta, lowSpaceRatio)
 	case "v1":
@@ -411,6 +504,39 @@ func (s *StoreInfo) regionScoreV2(delta int64, lowSpaceRatio float64) float64 {
 	return score / math.Max(s.GetRegionWeight(), minWeight)
 }
 
+func (s *StoreInfo) regionScoreV3(highSpaceRatio, lowSpaceRatio float64, curve RegionScoreCurve, delta int64) float64 {
+	available := float64(s.GetAvailable()) / units.MiB
+	used := float64(s.GetUsedSize()) / units.MiB
+	capacity := float64(s.GetCapacity()) / units.MiB
+	R := math.Max(float64(s.GetRegionSize()+delta), 0)
+	if capacity <= 0 {
+		return R / math.Max(s.GetRegionWeight(), minWeight)
+	}
+	amplification := 1.0
+	if s.GetRegionSize() != 0 && used != 0 {
+		// because of rocksdb compression, region size is larger than actual used size
+		amplification = float64(s.GetRegionSize()) / used
+	}
+	usedRatio := 1 - (available-float64(delta)/amplification)/capacity
+
+	var score float64
+	if usedRatio <= highSpaceRatio {
+		score = R
+	} else if usedRatio < lowSpaceRatio {
+		// the score rises from R to R * amplification along the curve.
+		t := (usedRatio - highSpaceRatio) / (lowSpaceRatio - highSpaceRatio)
+		score = R * (1 + (curve.Amplification-1)*math.Pow(t, curve.Exponent))
+	} else {
+		// the penalty rises linearly until the store is full.
+		t := 1.0
+		if lowSpaceRatio < 1 {
+			t = math.Min((usedRatio-lowSpaceRatio)/(1-lowSpaceRatio), 1)
+		}
+		score = R*curve.Amplification + curve.LowSpacePenalty*t
+	}
+	return score / math.Max(s.GetRegionWeight(), minWeight)
+}
+
 // StorageSize returns store's used storage size reported from tikv.
 func (s *StoreInfo) StorageSize() uint64 {
 	return s.GetUsedSize()
@@ -530,6 +656,20 @@ func (s *StoreInfo) GetLabelValue(key string) string {
 	return ""
 }
 
+// GetTopology returns the hardware topology reported by the store.
+func (s *StoreInfo) GetTopology() *StoreTopology {
+	return s.topology
+}
+
+// GetLabelOrTopologyValue returns a label's value, and falls back to the
+// reported hardware topology if the label does not exist.
+func (s *StoreInfo) GetLabelOrTopologyValue(key string) string {
+	if v := s.GetLabelValue(key); v != "" {
+		return v
+	}
+	return s.topology.GetValue(key)
+}
+
 // CompareLocation compares 2 stores' labels and returns at which level their
 // locations are different. It returns -1 if they are at the same location.
 func (s *StoreInfo) CompareLocation(other *StoreInfo, labels []string) int {
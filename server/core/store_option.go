@@ -184,6 +184,37 @@ func SetRegionWeight(regionWeight float64) StoreCreateOption {
 	}
 }
 
+// SetStoreQuota sets the soft quotas of the leader and Region count for the store.
+func SetStoreQuota(leaderQuota, regionQuota uint64) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.leaderQuota = leaderQuota
+		store.regionQuota = regionQuota
+	}
+}
+
+// SetAddPeerLimitRatio sets the ratio applied to the add peer limit for the store,
+// 0 means the limit is not tightened.
+func SetAddPeerLimitRatio(ratio float64) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.addPeerLimitRatio = ratio
+	}
+}
+
+// SetSlowTrend sets the slow trend of the store.
+func SetSlowTrend(trend *SlowTrend) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.slowTrend = trend
+	}
+}
+
+// SetVersionLag sets the number of minor versions the store lags behind the newest
+// version among the Up stores.
+func SetVersionLag(lag uint64) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.versionLag = lag
+	}
+}
+
 // SetLastHeartbeatTS sets the time of last heartbeat for the store.
 func SetLastHeartbeatTS(lastHeartbeatTS time.Time) StoreCreateOption {
 	return func(store *StoreInfo) {
@@ -223,6 +254,13 @@ func SetMinResolvedTS(minResolvedTS uint64) StoreCreateOption {
 	}
 }
 
+// SetStoreTopology sets the hardware topology for the store.
+func SetStoreTopology(topology *StoreTopology) StoreCreateOption {
+	return func(store *StoreInfo) {
+		store.topology = topology
+	}
+}
+
 // ResetStoreLimit resets the store limit for a store.
 func ResetStoreLimit(limitType storelimit.Type, ratePerSec ...float64) StoreCreateOption {
 	return func(store *StoreInfo) {
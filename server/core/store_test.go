@@ -123,11 +123,67 @@ func TestRegionScore(t *testing.T) {
 		SetStoreStats(stats),
 		SetRegionSize(1),
 	)
-	score := store.RegionScore("v1", 0.7, 0.9, 0)
+	score := store.RegionScore("v1", 0.7, 0.9, RegionScoreCurve{}, 0)
 	// Region score should never be NaN, or /store API would fail.
 	re.False(math.IsNaN(score))
 }
 
+func TestRegionScoreV3(t *testing.T) {
+	re := require.New(t)
+	curve := RegionScoreCurve{Amplification: 4, Exponent: 2, LowSpacePenalty: 1e6}
+	newStore := func(used uint64) *StoreInfo {
+		return NewStoreInfo(
+			&metapb.Store{Id: 1},
+			SetNewStoreStats(&pdpb.StoreStats{
+				Capacity:  1000 * units.MiB,
+				Available: (1000 - used) * units.MiB,
+				UsedSize:  used * units.MiB,
+			}),
+			SetRegionSize(int64(used)),
+		)
+	}
+	testCases := []struct {
+		used  uint64
+		score float64
+	}{
+		{0, 0},
+		{500, 500},
+		{700, 700},
+		// the middle of the transition stage: 750 * (1 + 3 * 0.5^2)
+		{750, 1312.5},
+		{800, 3200},
+		// the middle of the low space stage: 900 * 4 + 1e6 * 0.5
+		{900, 503600},
+		{1000, 1004000},
+	}
+	for _, testCase := range testCases {
+		score := newStore(testCase.used).RegionScore("v3", 0.7, 0.8, curve, 0)
+		re.InDelta(testCase.score, score, 1e-6, testCase.used)
+	}
+
+	// a larger exponent makes the curve flatter at the beginning of the transition stage.
+	steep := newStore(750).RegionScore("v3", 0.7, 0.8, curve, 0)
+	curve.Exponent = 4
+	flat := newStore(750).RegionScore("v3", 0.7, 0.8, curve, 0)
+	re.Less(flat, steep)
+}
+
+func TestStoreQuota(t *testing.T) {
+	re := require.New(t)
+	store := NewStoreInfo(&metapb.Store{Id: 1}, SetLeaderCount(10), SetRegionCount(20))
+	re.False(store.ExceedsQuota(LeaderKind, 100))
+	re.False(store.ExceedsQuota(RegionKind, 100))
+
+	store = store.Clone(SetStoreQuota(10, 30))
+	re.Equal(uint64(10), store.GetLeaderQuota())
+	re.Equal(uint64(30), store.GetRegionQuota())
+	re.False(store.ExceedsQuota(LeaderKind, 0))
+	re.True(store.ExceedsQuota(LeaderKind, 1))
+	re.False(store.ExceedsQuota(RegionKind, 10))
+	re.True(store.ExceedsQuota(RegionKind, 11))
+	re.Equal(uint64(10), store.ShallowClone().GetLeaderQuota())
+}
+
 func TestLowSpaceRatio(t *testing.T) {
 	re := require.New(t)
 	store := NewStoreInfoWithLabel(1, 20, nil)
@@ -0,0 +1,79 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package core
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pingcap/errors"
+)
+
+const (
+	// TopologyNUMANodesKey is the pseudo label key used to match the NUMA node count of a store.
+	TopologyNUMANodesKey = "hw-numa-nodes"
+	// TopologyDiskCountKey is the pseudo label key used to match the disk count of a store.
+	TopologyDiskCountKey = "hw-disk-count"
+	// TopologyDiskTypeKey is the pseudo label key used to match the disk type of a store.
+	TopologyDiskTypeKey = "hw-disk-type"
+)
+
+// StoreTopology records the hardware topology reported by a store.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type StoreTopology struct {
+	NUMANodes int    `json:"numa_nodes,omitempty"`
+	DiskCount int    `json:"disk_count,omitempty"`
+	DiskType  string `json:"disk_type,omitempty"`
+}
+
+// Validate checks whether the topology is valid.
+func (t *StoreTopology) Validate() error {
+	if t.NUMANodes < 0 {
+		return errors.Errorf("invalid numa node count %d", t.NUMANodes)
+	}
+	if t.DiskCount < 0 {
+		return errors.Errorf("invalid disk count %d", t.DiskCount)
+	}
+	return nil
+}
+
+// Equal returns whether the two topologies are the same.
+func (t *StoreTopology) Equal(other *StoreTopology) bool {
+	if t == nil || other == nil {
+		return t == other
+	}
+	return *t == *other
+}
+
+// GetValue returns the value of the given topology pseudo label key. It
+// returns an empty string if the key is unknown or the property is not reported.
+func (t *StoreTopology) GetValue(key string) string {
+	if t == nil {
+		return ""
+	}
+	switch strings.ToLower(key) {
+	case TopologyNUMANodesKey:
+		if t.NUMANodes > 0 {
+			return strconv.Itoa(t.NUMANodes)
+		}
+	case TopologyDiskCountKey:
+		if t.DiskCount > 0 {
+			return strconv.Itoa(t.DiskCount)
+		}
+	case TopologyDiskTypeKey:
+		return t.DiskType
+	}
+	return ""
+}
@@ -0,0 +1,102 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storelimit
+
+import (
+	"math"
+	"time"
+
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+// TranslateRegionSize is the Region size in MB used to translate the operator
+// count limit to the size limit when the size rate is not configured. It is the
+// default Region split size, so the two limits are equivalent for ordinary Regions.
+const TranslateRegionSize = 96
+
+// SizeLimit limits the total approximate size of the Regions whose snapshots are
+// sent or removed by a store. Unlike StoreLimit, a larger Region costs more.
+// The bucket may go into debt, so a Region larger than the burst can still be
+// scheduled once the bucket is full, and the debt is then paid off by the refill.
+type SizeLimit struct {
+	mu         syncutil.Mutex
+	ratePerSec float64
+	capacity   float64
+	tokens     float64
+	last       time.Time
+}
+
+// NewSizeLimit returns a SizeLimit object, the rate is in MB per second.
+func NewSizeLimit(ratePerSec float64) *SizeLimit {
+	// Allow one second of the rate in a burst, but at least one ordinary Region.
+	capacity := math.Max(ratePerSec, TranslateRegionSize)
+	return &SizeLimit{
+		ratePerSec: ratePerSec,
+		capacity:   capacity,
+		tokens:     capacity,
+		last:       time.Now(),
+	}
+}
+
+// Rate returns the fill rate of the bucket, in MB per second.
+func (l *SizeLimit) Rate() float64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.ratePerSec
+}
+
+// SetRate changes the fill rate of the bucket. The tokens filled at the old rate
+// and the debt are kept, so changing the rate doesn't refill the bucket.
+func (l *SizeLimit) SetRate(ratePerSec float64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := time.Now()
+	if l.ratePerSec < Unlimited {
+		l.refillLocked(now)
+	}
+	l.last = now
+	l.ratePerSec = ratePerSec
+	l.capacity = math.Max(ratePerSec, TranslateRegionSize)
+	l.tokens = math.Min(l.tokens, l.capacity)
+}
+
+// Available returns true if the bucket can afford a Region of the given size.
+func (l *SizeLimit) Available(size int64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ratePerSec >= Unlimited {
+		return true
+	}
+	l.refillLocked(time.Now())
+	return l.tokens >= math.Min(float64(size), l.capacity)
+}
+
+// Take takes the size from the bucket without blocking.
+func (l *SizeLimit) Take(size int64) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ratePerSec >= Unlimited {
+		return
+	}
+	l.refillLocked(time.Now())
+	l.tokens -= float64(size)
+}
+
+func (l *SizeLimit) refillLocked(now time.Time) {
+	if elapsed := now.Sub(l.last).Seconds(); elapsed > 0 {
+		l.tokens = math.Min(l.capacity, l.tokens+elapsed*l.ratePerSec)
+		l.last = now
+	}
+}
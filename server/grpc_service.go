@@ -16,9 +16,11 @@ package server
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"io"
 	"strconv"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -30,6 +32,7 @@ import (
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/pkg/tsoutil"
 	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/core"
@@ -55,6 +58,11 @@ const (
 
 	// global config
 	globalConfigPath = "/global/config/"
+
+	// slo
+	grpcLatencyPrefix = "grpc/"
+	// downgradedFlowRoundByDigit is the min flow round digit of the region heartbeats when PD is overloaded.
+	downgradedFlowRoundByDigit = 5 // 0.1 MB
 )
 
 // gRPC errors
@@ -92,6 +100,19 @@ func (s *GrpcServer) unaryMiddleware(ctx context.Context, header *pdpb.RequestHe
 	return nil, nil
 }
 
+// observeLatency records the latency of the gRPC method into the SLO tracker.
+func (s *GrpcServer) observeLatency(method string, start time.Time) {
+	s.sloTracker.Observe(grpcLatencyPrefix+method, time.Since(start))
+}
+
+// getDowngradedFlowRoundByDigit returns the flow round digit used when PD is overloaded.
+func getDowngradedFlowRoundByDigit(flowRoundByDigit int) int {
+	if flowRoundByDigit < downgradedFlowRoundByDigit {
+		return downgradedFlowRoundByDigit
+	}
+	return flowRoundByDigit
+}
+
 // GetMembers implements gRPC PDServer.
 func (s *GrpcServer) GetMembers(context.Context, *pdpb.GetMembersRequest) (*pdpb.GetMembersResponse, error) {
 	// Here we purposely do not check the cluster ID because the client does not know the correct cluster ID
@@ -135,12 +156,13 @@ func (s *GrpcServer) GetMembers(context.Context, *pdpb.GetMembersRequest) (*pdpb
 
 // Tso implements gRPC PDServer.
 func (s *GrpcServer) Tso(stream pdpb.PD_TsoServer) error {
-	var (
-		doneCh chan struct{}
-		errCh  chan error
-	)
-	ctx, cancel := context.WithCancel(stream.Context())
-	defer cancel()
+	cs, err := s.acquireStream(stream.Context(), grpcutil.ClientClassSQL)
+	if err != nil {
+		return err
+	}
+	defer cs.release()
+
+	var errCh chan error
 	for {
 		// Prevent unnecessary performance overhead of the channel.
 		if errCh != nil {
@@ -150,7 +172,11 @@ func (s *GrpcServer) Tso(stream pdpb.PD_TsoServer) error {
 			default:
 			}
 		}
-		request, err := stream.Recv()
+		var request *pdpb.TsoRequest
+		err := cs.recv(func() (err error) {
+			request, err = stream.Recv()
+			return
+		})
 		if err == io.EOF {
 			return nil
 		}
@@ -160,17 +186,20 @@ func (s *GrpcServer) Tso(stream pdpb.PD_TsoServer) error {
 
 		streamCtx := stream.Context()
 		forwardedHost := getForwardedHost(streamCtx)
+		if forwardedHost == "" {
+			forwardedHost = s.getTSOProxyTarget(request)
+		}
 		if !s.isLocalRequest(forwardedHost) {
 			if errCh == nil {
-				doneCh = make(chan struct{})
-				defer close(doneCh)
-				errCh = make(chan error)
+				errCh = make(chan error, 1)
 			}
-			s.dispatchTSORequest(ctx, &tsoRequest{
-				forwardedHost,
-				request,
-				stream,
-			}, forwardedHost, doneCh, errCh)
+			s.dispatchTSORequest(&tsoRequest{
+				forwardedHost: forwardedHost,
+				request:       request,
+				stream:        stream,
+				receivedTime:  time.Now(),
+				errCh:         errCh,
+			})
 			continue
 		}
 
@@ -203,22 +232,104 @@ type tsoRequest struct {
 	forwardedHost string
 	request       *pdpb.TsoRequest
 	stream        pdpb.PD_TsoServer
+	receivedTime  time.Time
+	// errCh is used to notify the stream which the request comes from of the forwarding
+	// error, it is buffered and shared by all the requests of the stream.
+	errCh chan error
 }
 
-func (s *GrpcServer) dispatchTSORequest(ctx context.Context, request *tsoRequest, forwardedHost string, doneCh <-chan struct{}, errCh chan<- error) {
-	tsoRequestChInterface, loaded := s.tsoDispatcher.LoadOrStore(forwardedHost, make(chan *tsoRequest, maxMergeTSORequests))
+// getTSOProxyTarget returns the leader address which the global TSO request is forwarded
+// to if the server is a follower with the TSO follower proxy enabled. Otherwise it returns
+// an empty string, which means the request is handled locally.
+func (s *GrpcServer) getTSOProxyTarget(request *pdpb.TsoRequest) string {
+	if !s.GetPersistOptions().IsTSOFollowerProxyEnabled() || s.member.IsLeader() {
+		return ""
+	}
+	if dcLocation := request.GetDcLocation(); dcLocation != "" && dcLocation != tso.GlobalDCLocation {
+		return ""
+	}
+	leaderURLs := s.GetLeader().GetClientUrls()
+	if len(leaderURLs) == 0 {
+		return ""
+	}
+	return leaderURLs[0]
+}
+
+// tsoRequestDispatcher holds the requests forwarded to a host. Once it is stopped,
+// no request can be sent to it, and the requests sent before are failed.
+type tsoRequestDispatcher struct {
+	requestCh chan *tsoRequest
+	// stopping is closed when the dispatcher starts to stop, so that the senders
+	// waiting for the full channel give up.
+	stopping chan struct{}
+	stopOnce sync.Once
+	// mu makes sending a request and stopping the dispatcher exclusive.
+	mu      syncutil.RWMutex
+	stopped bool
+}
+
+func newTSORequestDispatcher() *tsoRequestDispatcher {
+	return &tsoRequestDispatcher{
+		requestCh: make(chan *tsoRequest, maxMergeTSORequests),
+		stopping:  make(chan struct{}),
+	}
+}
+
+// send puts the request into the dispatcher, it returns false if the dispatcher
+// is stopped.
+func (d *tsoRequestDispatcher) send(request *tsoRequest) bool {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if d.stopped {
+		return false
+	}
+	select {
+	case d.requestCh <- request:
+		return true
+	case <-d.stopping:
+		return false
+	}
+}
+
+// stop stops the dispatcher and returns the requests sent to it but not handled.
+func (d *tsoRequestDispatcher) stop() []*tsoRequest {
+	d.stopOnce.Do(func() { close(d.stopping) })
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.stopped = true
+	var pending []*tsoRequest
+	for {
+		select {
+		case request := <-d.requestCh:
+			pending = append(pending, request)
+		default:
+			return pending
+		}
+	}
+}
+
+// dispatchTSORequest sends the request to the dispatcher of the forwarded host. The
+// dispatcher is shared by all the streams forwarding to the same host, so that their
+// requests are merged and forwarded over a single stream. It lives with the server
+// rather than any of the streams, and exits once the forwarding fails. The request
+// fails if the dispatcher is stopping.
+func (s *GrpcServer) dispatchTSORequest(request *tsoRequest) {
+	v, loaded := s.tsoDispatcher.LoadOrStore(request.forwardedHost, newTSORequestDispatcher())
+	dispatcher := v.(*tsoRequestDispatcher)
 	if !loaded {
 		tsDeadlineCh := make(chan deadline, 1)
-		go s.handleDispatcher(ctx, forwardedHost, tsoRequestChInterface.(chan *tsoRequest), tsDeadlineCh, doneCh, errCh)
-		go watchTSDeadline(ctx, tsDeadlineCh)
+		go s.handleDispatcher(s.ctx, request.forwardedHost, dispatcher, tsDeadlineCh)
+		go watchTSDeadline(s.ctx, tsDeadlineCh)
+	}
+	if !dispatcher.send(request) {
+		notifyTSORequest(request, errs.ErrGRPCSend.FastGenByArgs())
 	}
-	tsoRequestChInterface.(chan *tsoRequest) <- request
 }
 
-func (s *GrpcServer) handleDispatcher(ctx context.Context, forwardedHost string, tsoRequestCh <-chan *tsoRequest, tsDeadlineCh chan<- deadline, doneCh <-chan struct{}, errCh chan<- error) {
+func (s *GrpcServer) handleDispatcher(ctx context.Context, forwardedHost string, dispatcher *tsoRequestDispatcher, tsDeadlineCh chan<- deadline) {
+	tsoRequestCh := dispatcher.requestCh
 	dispatcherCtx, ctxCancel := context.WithCancel(ctx)
 	defer ctxCancel()
-	defer s.tsoDispatcher.Delete(forwardedHost)
 
 	var (
 		forwardStream pdpb.PD_TsoClient
@@ -233,17 +344,8 @@ func (s *GrpcServer) handleDispatcher(ctx context.Context, forwardedHost string,
 errHandling:
 	if err != nil || forwardStream == nil {
 		log.Error("create tso forwarding stream error", zap.String("forwarded-host", forwardedHost), errs.ZapError(errs.ErrGRPCCreateStream, err))
-		select {
-		case <-dispatcherCtx.Done():
-			return
-		case _, ok := <-doneCh:
-			if !ok {
-				return
-			}
-		case errCh <- err:
-			close(errCh)
-			return
-		}
+		s.stopDispatcher(forwardedHost, dispatcher, nil, err)
+		return
 	}
 	defer cancel()
 
@@ -265,30 +367,49 @@ errHandling:
 			select {
 			case tsDeadlineCh <- dl:
 			case <-dispatcherCtx.Done():
+				s.stopDispatcher(forwardedHost, dispatcher, requests[:pendingTSOReqCount], dispatcherCtx.Err())
 				return
 			}
 			err = s.processTSORequests(forwardStream, requests[:pendingTSOReqCount])
 			close(done)
 			if err != nil {
 				log.Error("proxy forward tso error", zap.String("forwarded-host", forwardedHost), errs.ZapError(errs.ErrGRPCSend, err))
-				select {
-				case <-dispatcherCtx.Done():
-					return
-				case _, ok := <-doneCh:
-					if !ok {
-						return
-					}
-				case errCh <- err:
-					close(errCh)
-					return
-				}
+				s.stopDispatcher(forwardedHost, dispatcher, requests[:pendingTSOReqCount], err)
+				return
 			}
 		case <-dispatcherCtx.Done():
+			s.stopDispatcher(forwardedHost, dispatcher, nil, dispatcherCtx.Err())
 			return
 		}
 	}
 }
 
+// stopDispatcher removes the dispatcher and notifies the streams of the given requests
+// and the pending ones of the error. The requests sent to the dispatcher later fail
+// in dispatchTSORequest, and the new requests go to a new dispatcher.
+func (s *GrpcServer) stopDispatcher(forwardedHost string, dispatcher *tsoRequestDispatcher, requests []*tsoRequest, err error) {
+	s.tsoDispatcher.Delete(forwardedHost)
+	if err == nil {
+		err = errs.ErrGRPCCreateStream.FastGenByArgs()
+	}
+	for _, request := range requests {
+		notifyTSORequest(request, err)
+	}
+	for _, request := range dispatcher.stop() {
+		notifyTSORequest(request, err)
+	}
+}
+
+// notifyTSORequest notifies the stream of the request of the error. The error
+// channel is buffered and shared by the requests of the stream, so the stream
+// is already notified if it is full.
+func notifyTSORequest(request *tsoRequest, err error) {
+	select {
+	case request.errCh <- err:
+	default:
+	}
+}
+
 func (s *GrpcServer) processTSORequests(forwardStream pdpb.PD_TsoClient, requests []*tsoRequest) error {
 	start := time.Now()
 	// Merge the requests
@@ -312,6 +433,10 @@ func (s *GrpcServer) processTSORequests(forwardStream pdpb.PD_TsoClient, request
 	}
 	tsoProxyHandleDuration.Observe(time.Since(start).Seconds())
 	tsoProxyBatchSize.Observe(float64(count))
+	tsoProxyBatchRequestCount.Observe(float64(len(requests)))
+	for _, request := range requests {
+		tsoProxyAddedDuration.Observe(time.Since(request.receivedTime).Seconds())
+	}
 	// Split the response
 	physical, logical, suffixBits := resp.GetTimestamp().GetPhysical(), resp.GetTimestamp().GetLogical(), resp.GetTimestamp().GetSuffixBits()
 	// `logical` is the largest ts's logical part here, we need to do the subtracting before we finish each TSO request.
@@ -449,6 +574,7 @@ func (s *GrpcServer) AllocID(ctx context.Context, request *pdpb.AllocIDRequest)
 
 // GetStore implements gRPC PDServer.
 func (s *GrpcServer) GetStore(ctx context.Context, request *pdpb.GetStoreRequest) (*pdpb.GetStoreResponse, error) {
+	defer s.observeLatency("GetStore", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).GetStore(ctx, request)
 	}
@@ -501,6 +627,10 @@ func (s *GrpcServer) PutStore(ctx context.Context, request *pdpb.PutStoreRequest
 		return rsp.(*pdpb.PutStoreResponse), err
 	}
 
+	if err := s.checkStoreToken(ctx); err != nil {
+		return nil, err
+	}
+
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.PutStoreResponse{Header: s.notBootstrappedHeader()}, nil
@@ -533,6 +663,7 @@ func (s *GrpcServer) PutStore(ctx context.Context, request *pdpb.PutStoreRequest
 
 // GetAllStores implements gRPC PDServer.
 func (s *GrpcServer) GetAllStores(ctx context.Context, request *pdpb.GetAllStoresRequest) (*pdpb.GetAllStoresResponse, error) {
+	defer s.observeLatency("GetAllStores", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).GetAllStores(ctx, request)
 	}
@@ -567,6 +698,7 @@ func (s *GrpcServer) GetAllStores(ctx context.Context, request *pdpb.GetAllStore
 
 // StoreHeartbeat implements gRPC PDServer.
 func (s *GrpcServer) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHeartbeatRequest) (*pdpb.StoreHeartbeatResponse, error) {
+	defer s.observeLatency("StoreHeartbeat", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).StoreHeartbeat(ctx, request)
 	}
@@ -579,6 +711,9 @@ func (s *GrpcServer) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHear
 	if request.GetStats() == nil {
 		return nil, errors.Errorf("invalid store heartbeat command, but %v", request)
 	}
+	if err := s.checkStoreToken(ctx); err != nil {
+		return nil, err
+	}
 	rc := s.GetRaftCluster()
 	if rc == nil {
 		return &pdpb.StoreHeartbeatResponse{Header: s.notBootstrappedHeader()}, nil
@@ -605,6 +740,11 @@ func (s *GrpcServer) StoreHeartbeat(ctx context.Context, request *pdpb.StoreHear
 		if err != nil {
 			return nil, status.Errorf(codes.Unknown, err.Error())
 		}
+		if topology := getStoreTopology(ctx); topology != nil {
+			if err := rc.UpdateStoreTopology(storeID, topology); err != nil {
+				log.Warn("failed to update the store topology", zap.Uint64("store-id", storeID), errs.ZapError(err))
+			}
+		}
 
 		s.handleDamagedStore(request.GetStats())
 
@@ -702,6 +842,12 @@ func (s *heartbeatServer) Recv() (*pdpb.RegionHeartbeatRequest, error) {
 
 // ReportBuckets implements gRPC PDServer
 func (s *GrpcServer) ReportBuckets(stream pdpb.PD_ReportBucketsServer) error {
+	cs, err := s.acquireStream(stream.Context(), grpcutil.ClientClassStore)
+	if err != nil {
+		return err
+	}
+	defer cs.release()
+
 	var (
 		server            = &bucketHeartbeatServer{stream: stream}
 		forwardStream     pdpb.PD_ReportBucketsClient
@@ -715,7 +861,11 @@ func (s *GrpcServer) ReportBuckets(stream pdpb.PD_ReportBucketsServer) error {
 		}
 	}()
 	for {
-		request, err := server.Recv()
+		var request *pdpb.ReportBucketsRequest
+		err := cs.recv(func() (err error) {
+			request, err = server.Recv()
+			return
+		})
 		if err == io.EOF {
 			return nil
 		}
@@ -786,14 +936,22 @@ func (s *GrpcServer) ReportBuckets(stream pdpb.PD_ReportBucketsServer) error {
 
 // RegionHeartbeat implements gRPC PDServer.
 func (s *GrpcServer) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error {
+	cs, err := s.acquireStream(stream.Context(), grpcutil.ClientClassStore)
+	if err != nil {
+		return err
+	}
+	defer cs.release()
+
 	var (
 		server            = &heartbeatServer{stream: stream}
 		flowRoundOption   = core.WithFlowRoundByDigit(s.persistOptions.GetPDServerConfig().FlowRoundByDigit)
+		downgradeOption   = core.WithFlowRoundByDigit(getDowngradedFlowRoundByDigit(s.persistOptions.GetPDServerConfig().FlowRoundByDigit))
 		forwardStream     pdpb.PD_RegionHeartbeatClient
 		cancel            context.CancelFunc
 		lastForwardedHost string
 		lastBind          time.Time
 		errCh             chan error
+		isShard           = isShardHeartbeatStream(stream.Context())
 	)
 	defer func() {
 		// cancel the forward stream
@@ -803,7 +961,11 @@ func (s *GrpcServer) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error
 	}()
 
 	for {
-		request, err := server.Recv()
+		var request *pdpb.RegionHeartbeatRequest
+		err := cs.recv(func() (err error) {
+			request, err = server.Recv()
+			return
+		})
 		if err == io.EOF {
 			return nil
 		}
@@ -868,13 +1030,24 @@ func (s *GrpcServer) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error
 
 		if time.Since(lastBind) > s.cfg.HeartbeatStreamBindInterval.Duration {
 			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "bind").Inc()
-			s.hbStreams.BindStream(storeID, server)
+			if isShard {
+				s.hbStreams.BindShardStream(storeID, server)
+			} else {
+				s.hbStreams.BindStream(storeID, server)
+			}
 			// refresh FlowRoundByDigit
 			flowRoundOption = core.WithFlowRoundByDigit(s.persistOptions.GetPDServerConfig().FlowRoundByDigit)
+			downgradeOption = core.WithFlowRoundByDigit(getDowngradedFlowRoundByDigit(s.persistOptions.GetPDServerConfig().FlowRoundByDigit))
 			lastBind = time.Now()
 		}
 
-		region := core.RegionFromHeartbeat(request, flowRoundOption, core.SetFromHeartbeat(true))
+		roundOption := flowRoundOption
+		if s.IsOverloaded() {
+			// Downgrade the heartbeat by rounding the flow more coarsely to reduce the updates of the region cache.
+			roundOption = downgradeOption
+			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "downgrade").Inc()
+		}
+		region := core.RegionFromHeartbeat(request, roundOption, core.SetFromHeartbeat(true))
 		if region.GetLeader() == nil {
 			log.Error("invalid request, the leader is nil", zap.Reflect("request", request), errs.ZapError(errs.ErrLeaderNil))
 			regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "invalid-leader").Inc()
@@ -908,12 +1081,14 @@ func (s *GrpcServer) RegionHeartbeat(stream pdpb.PD_RegionHeartbeatServer) error
 			continue
 		}
 		regionHeartbeatHandleDuration.WithLabelValues(storeAddress, storeLabel).Observe(time.Since(start).Seconds())
+		s.observeLatency("RegionHeartbeat", start)
 		regionHeartbeatCounter.WithLabelValues(storeAddress, storeLabel, "report", "ok").Inc()
 	}
 }
 
 // GetRegion implements gRPC PDServer.
 func (s *GrpcServer) GetRegion(ctx context.Context, request *pdpb.GetRegionRequest) (*pdpb.GetRegionResponse, error) {
+	defer s.observeLatency("GetRegion", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).GetRegion(ctx, request)
 	}
@@ -927,7 +1102,7 @@ func (s *GrpcServer) GetRegion(ctx context.Context, request *pdpb.GetRegionReque
 	if rc == nil {
 		return &pdpb.GetRegionResponse{Header: s.notBootstrappedHeader()}, nil
 	}
-	region := rc.GetRegionByKey(request.GetRegionKey())
+	region := rc.GetCachedRegionByKey(request.GetRegionKey())
 	if region == nil {
 		return &pdpb.GetRegionResponse{Header: s.header()}, nil
 	}
@@ -947,6 +1122,7 @@ func (s *GrpcServer) GetRegion(ctx context.Context, request *pdpb.GetRegionReque
 
 // GetPrevRegion implements gRPC PDServer
 func (s *GrpcServer) GetPrevRegion(ctx context.Context, request *pdpb.GetRegionRequest) (*pdpb.GetRegionResponse, error) {
+	defer s.observeLatency("GetPrevRegion", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).GetPrevRegion(ctx, request)
 	}
@@ -981,6 +1157,7 @@ func (s *GrpcServer) GetPrevRegion(ctx context.Context, request *pdpb.GetRegionR
 
 // GetRegionByID implements gRPC PDServer.
 func (s *GrpcServer) GetRegionByID(ctx context.Context, request *pdpb.GetRegionByIDRequest) (*pdpb.GetRegionResponse, error) {
+	defer s.observeLatency("GetRegionByID", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).GetRegionByID(ctx, request)
 	}
@@ -1014,6 +1191,7 @@ func (s *GrpcServer) GetRegionByID(ctx context.Context, request *pdpb.GetRegionB
 
 // ScanRegions implements gRPC PDServer.
 func (s *GrpcServer) ScanRegions(ctx context.Context, request *pdpb.ScanRegionsRequest) (*pdpb.ScanRegionsResponse, error) {
+	defer s.observeLatency("ScanRegions", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).ScanRegions(ctx, request)
 	}
@@ -1087,6 +1265,7 @@ func (s *GrpcServer) AskSplit(ctx context.Context, request *pdpb.AskSplitRequest
 
 // AskBatchSplit implements gRPC PDServer.
 func (s *GrpcServer) AskBatchSplit(ctx context.Context, request *pdpb.AskBatchSplitRequest) (*pdpb.AskBatchSplitResponse, error) {
+	defer s.observeLatency("AskBatchSplit", time.Now())
 	fn := func(ctx context.Context, client *grpc.ClientConn) (interface{}, error) {
 		return pdpb.NewPDClient(client).AskBatchSplit(ctx, request)
 	}
@@ -1713,6 +1892,54 @@ func getForwardedHost(ctx context.Context) string {
 	return ""
 }
 
+// checkStoreToken verifies the token presented by a store if the store
+// token authentication is enabled. The stores pass if no token is issued yet.
+func (s *GrpcServer) checkStoreToken(ctx context.Context) error {
+	if !s.persistOptions.IsStoreTokenAuthEnabled() {
+		return nil
+	}
+	var token string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if t := md.Get(grpcutil.StoreTokenMetadataKey); len(t) > 0 {
+			token = t[0]
+		}
+	}
+	if !s.storeTokenManager.VerifyIfIssued(token) {
+		return status.Errorf(codes.Unauthenticated, "invalid store token")
+	}
+	return nil
+}
+
+// getStoreTopology returns the hardware topology carried by a store heartbeat,
+// or nil if it's not carried or invalid.
+func getStoreTopology(ctx context.Context) *core.StoreTopology {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return nil
+	}
+	t := md.Get(grpcutil.StoreTopologyMetadataKey)
+	if len(t) == 0 {
+		return nil
+	}
+	topology := &core.StoreTopology{}
+	if err := json.Unmarshal([]byte(t[0]), topology); err != nil {
+		log.Warn("failed to unmarshal the store topology", zap.String("topology", t[0]), errs.ZapError(errs.ErrJSONUnmarshal, err))
+		return nil
+	}
+	return topology
+}
+
+// isShardHeartbeatStream checks whether the region heartbeat stream is one of
+// the multiple concurrent streams opened by a store.
+func isShardHeartbeatStream(ctx context.Context) bool {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return false
+	}
+	t, ok := md[grpcutil.HeartbeatShardMetadataKey]
+	return ok && len(t) > 0 && t[0] == "true"
+}
+
 func (s *GrpcServer) isLocalRequest(forwardedHost string) bool {
 	if forwardedHost == "" {
 		return true
@@ -0,0 +1,116 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// grpcStreamLimiter counts the concurrent gRPC streams of each client class,
+// so that the chatty clients of a class can't exhaust the server. The limits
+// are read when the streams are opened, so the changes of them only affect the
+// new streams.
+type grpcStreamLimiter struct {
+	mu      syncutil.Mutex
+	streams map[string]int
+}
+
+func newGRPCStreamLimiter() *grpcStreamLimiter {
+	return &grpcStreamLimiter{streams: make(map[string]int)}
+}
+
+// acquire counts a new stream of the class, it returns false if the number of
+// the streams reaches the limit. 0 limit means unlimited.
+func (l *grpcStreamLimiter) acquire(class string, limit int) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if limit > 0 && l.streams[class] >= limit {
+		grpcStreamRejectedCounter.WithLabelValues(class, "limit").Inc()
+		return false
+	}
+	l.streams[class]++
+	grpcStreamsGauge.WithLabelValues(class).Set(float64(l.streams[class]))
+	return true
+}
+
+func (l *grpcStreamLimiter) release(class string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.streams[class]--
+	grpcStreamsGauge.WithLabelValues(class).Set(float64(l.streams[class]))
+}
+
+// classStream is a gRPC stream counted against the settings of its client class.
+type classStream struct {
+	class   string
+	opt     *config.PersistOptions
+	limiter *grpcStreamLimiter
+}
+
+// release should be called when the stream ends.
+func (cs *classStream) release() {
+	cs.limiter.release(cs.class)
+}
+
+// recv receives a message by the given function. It fails if nothing is
+// received within the idle timeout of the class, which is read on every call,
+// then the caller should close the stream by returning the error.
+func (cs *classStream) recv(recv func() error) error {
+	timeout := cs.opt.GetGRPCClassConfig(cs.class).IdleTimeout.Duration
+	if timeout <= 0 {
+		return recv()
+	}
+	done := make(chan error, 1)
+	go func() { done <- recv() }()
+	select {
+	case err := <-done:
+		return err
+	case <-time.After(timeout):
+		grpcStreamIdleClosedCounter.WithLabelValues(cs.class).Inc()
+		return status.Errorf(codes.DeadlineExceeded, "the stream of %s clients is idle for %s", cs.class, timeout)
+	}
+}
+
+// getRecvCompress returns the name of the compressor of the messages sent by
+// the client, which is empty if they are not compressed.
+func getRecvCompress(ctx context.Context) string {
+	if s, ok := grpc.ServerTransportStreamFromContext(ctx).(interface{ RecvCompress() string }); ok {
+		return s.RecvCompress()
+	}
+	return ""
+}
+
+// acquireStream counts the stream against the settings of the client class,
+// which is decided by the method of the stream rather than declared by the
+// client, so that the clients can't escape from the settings of their class.
+func (s *GrpcServer) acquireStream(ctx context.Context, class string) (*classStream, error) {
+	cfg := s.persistOptions.GetGRPCClassConfig(class)
+	if !cfg.EnableGzip && getRecvCompress(ctx) == grpcutil.GzipCompressorName {
+		grpcStreamRejectedCounter.WithLabelValues(class, "gzip").Inc()
+		return nil, status.Errorf(codes.Unimplemented, "gzip is disabled for %s clients", class)
+	}
+	if !s.grpcStreams.acquire(class, cfg.MaxStreams) {
+		return nil, status.Errorf(codes.ResourceExhausted, "too many streams of %s clients", class)
+	}
+	return &classStream{class: class, opt: s.persistOptions, limiter: s.grpcStreams}, nil
+}
@@ -0,0 +1,71 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"io"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/config"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestGRPCStreamLimiter(t *testing.T) {
+	re := require.New(t)
+	l := newGRPCStreamLimiter()
+	re.True(l.acquire(grpcutil.ClientClassStore, 2))
+	re.True(l.acquire(grpcutil.ClientClassStore, 2))
+	re.False(l.acquire(grpcutil.ClientClassStore, 2))
+	// the classes are limited separately.
+	re.True(l.acquire(grpcutil.ClientClassTool, 1))
+	// raising the limit allows the new streams.
+	re.True(l.acquire(grpcutil.ClientClassStore, 3))
+	l.release(grpcutil.ClientClassStore)
+	l.release(grpcutil.ClientClassStore)
+	re.True(l.acquire(grpcutil.ClientClassStore, 2))
+	// 0 means unlimited.
+	re.True(l.acquire(grpcutil.ClientClassStore, 0))
+}
+
+func TestClassStreamIdleTimeout(t *testing.T) {
+	re := require.New(t)
+	opt := config.NewTestOptions()
+	cs := &classStream{class: grpcutil.ClientClassStore, opt: opt, limiter: newGRPCStreamLimiter()}
+	re.True(cs.limiter.acquire(cs.class, 0))
+	defer cs.release()
+
+	block := make(chan struct{})
+	defer close(block)
+	idle := func() error {
+		<-block
+		return nil
+	}
+	// the idle timeout is read on every receive.
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.GRPCStoreClass.IdleTimeout = typeutil.NewDuration(10 * time.Millisecond)
+	opt.SetPDServerConfig(cfg)
+	err := cs.recv(idle)
+	re.Error(err)
+	re.Equal(codes.DeadlineExceeded, status.Code(err))
+	re.NoError(cs.recv(func() error { return nil }))
+	// the other classes are not affected.
+	sqlStream := &classStream{class: grpcutil.ClientClassSQL, opt: opt, limiter: cs.limiter}
+	re.Equal(io.EOF, sqlStream.recv(func() error { return io.EOF }))
+}
@@ -38,7 +38,9 @@ import (
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
 	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/checker"
 	"github.com/tikv/pd/server/schedule/filter"
+	"github.com/tikv/pd/server/schedule/labeler"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/schedulers"
@@ -119,6 +121,15 @@ func (h *Handler) IsSchedulerPaused(name string) (bool, error) {
 	return rc.IsSchedulerPaused(name)
 }
 
+// GetSchedulerState returns a snapshot of the state of a scheduler.
+func (h *Handler) GetSchedulerState(name string) (*schedule.SchedulerStateSnapshot, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.GetSchedulerState(name)
+}
+
 // IsSchedulerDisabled returns whether scheduler is disabled.
 func (h *Handler) IsSchedulerDisabled(name string) (bool, error) {
 	rc, err := h.GetRaftCluster()
@@ -128,6 +139,26 @@ func (h *Handler) IsSchedulerDisabled(name string) (bool, error) {
 	return rc.IsSchedulerDisabled(name)
 }
 
+// GetSchedulerUnreadyReason returns the reason why the prerequisites of the
+// scheduler are not met, it is empty if the scheduler is ready.
+func (h *Handler) GetSchedulerUnreadyReason(name string) (string, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return "", err
+	}
+	return rc.GetSchedulerUnreadyReason(name)
+}
+
+// SimulateScheduling runs all schedulers in dry-run mode with the proposed
+// schedule config and rules applied virtually.
+func (h *Handler) SimulateScheduling(scheduleCfg *config.ScheduleConfig, rules []*placement.Rule) ([]*cluster.SchedulerImpact, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.SimulateScheduling(scheduleCfg, rules)
+}
+
 // IsSchedulerExisted returns whether scheduler is existed.
 func (h *Handler) IsSchedulerExisted(name string) (bool, error) {
 	rc, err := h.GetRaftCluster()
@@ -160,6 +191,16 @@ func (h *Handler) IsCheckerPaused(name string) (bool, error) {
 	return rc.IsCheckerPaused(name)
 }
 
+// DryRunChecker runs the checker on the given regions, or on the sampled
+// regions if no region is given, and returns the proposed operators.
+func (h *Handler) DryRunChecker(name string, regionIDs []uint64, sample int) ([]*checker.DryRunResult, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.DryRunChecker(name, regionIDs, sample)
+}
+
 // GetStores returns all stores in the cluster.
 func (h *Handler) GetStores() ([]*core.StoreInfo, error) {
 	rc := h.s.GetRaftCluster()
@@ -188,6 +229,15 @@ func (h *Handler) GetHotWriteRegions() *statistics.StoreHotPeersInfos {
 	return c.GetHotWriteRegions()
 }
 
+// GetHotPeerExemplars gets the hottest peers of each store with their regions.
+func (h *Handler) GetHotPeerExemplars() (*cluster.HotPeerExemplars, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return c.GetHotPeerExemplars(), nil
+}
+
 // GetHotReadRegions gets all hot read regions stats.
 func (h *Handler) GetHotReadRegions() *statistics.StoreHotPeersInfos {
 	c, err := h.GetRaftCluster()
@@ -218,6 +268,12 @@ func (h *Handler) GetStoresLoads() map[uint64][]float64 {
 
 // AddScheduler adds a scheduler.
 func (h *Handler) AddScheduler(name string, args ...string) error {
+	return h.AddObservingScheduler(0, name, args...)
+}
+
+// AddObservingScheduler adds a scheduler which records its operators instead
+// of submitting them for the first t seconds, or a normal one if t is 0.
+func (h *Handler) AddObservingScheduler(t int64, name string, args ...string) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -227,8 +283,8 @@ func (h *Handler) AddScheduler(name string, args ...string) error {
 	if err != nil {
 		return err
 	}
-	log.Info("create scheduler", zap.String("scheduler-name", s.GetName()), zap.Strings("scheduler-args", args))
-	if err = c.AddScheduler(s, args...); err != nil {
+	log.Info("create scheduler", zap.String("scheduler-name", s.GetName()), zap.Strings("scheduler-args", args), zap.Int64("observe-seconds", t))
+	if err = c.AddObservingScheduler(s, t, args...); err != nil {
 		log.Error("can not add scheduler", zap.String("scheduler-name", s.GetName()), zap.Strings("scheduler-args", args), errs.ZapError(err))
 	} else if err = h.opt.Persist(c.GetStorage()); err != nil {
 		log.Error("can not persist scheduler config", errs.ZapError(err))
@@ -276,6 +332,33 @@ func (h *Handler) PauseOrResumeScheduler(name string, t int64) error {
 	return err
 }
 
+// SetSchedulerObserveOnly makes a scheduler record its operators instead of
+// submitting them for t seconds.
+// t == 0 : leave the observe-only mode.
+// t > 0 : observe for t seconds.
+func (h *Handler) SetSchedulerObserveOnly(name string, t int64) error {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	if err = c.SetSchedulerObserveOnly(name, t); err != nil {
+		log.Error("can not set the observe-only mode of scheduler", zap.String("scheduler-name", name), errs.ZapError(err))
+	} else {
+		log.Info("set the observe-only mode of scheduler successfully", zap.String("scheduler-name", name), zap.Int64("observe-seconds", t))
+	}
+	return err
+}
+
+// GetSchedulerObservation returns the operators recorded by a scheduler in
+// observe-only mode.
+func (h *Handler) GetSchedulerObservation(name string) (*schedule.SchedulerObservation, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.GetSchedulerObservation(name)
+}
+
 // PauseOrResumeChecker pauses checker for delay seconds or resume checker
 // t == 0 : resume checker.
 // t > 0 : checker delays t seconds.
@@ -339,6 +422,11 @@ func (h *Handler) AddShuffleRegionScheduler() error {
 	return h.AddScheduler(schedulers.ShuffleRegionType)
 }
 
+// AddBalanceLearnerScheduler adds a balance-learner-scheduler.
+func (h *Handler) AddBalanceLearnerScheduler() error {
+	return h.AddScheduler(schedulers.BalanceLearnerType)
+}
+
 // AddShuffleHotRegionScheduler adds a shuffle-hot-region-scheduler.
 func (h *Handler) AddShuffleHotRegionScheduler(limit uint64) error {
 	return h.AddScheduler(schedulers.ShuffleHotRegionType, strconv.FormatUint(limit, 10))
@@ -349,6 +437,11 @@ func (h *Handler) AddEvictSlowStoreScheduler() error {
 	return h.AddScheduler(schedulers.EvictSlowStoreType)
 }
 
+// AddEvictSlowTrendScheduler adds a evict-slow-trend-scheduler.
+func (h *Handler) AddEvictSlowTrendScheduler() error {
+	return h.AddScheduler(schedulers.EvictSlowTrendType)
+}
+
 // AddSplitBucketScheduler adds a split-bucket-scheduler.
 func (h *Handler) AddSplitBucketScheduler() error {
 	return h.AddScheduler(schedulers.SplitBucketType)
@@ -410,6 +503,22 @@ func (h *Handler) RemoveOperator(regionID uint64) error {
 	return nil
 }
 
+// RemoveOperatorsByMetadata removes the operators whose metadata matches the
+// filter, and returns the number of the removed operators.
+func (h *Handler) RemoveOperatorsByMetadata(filter *operator.Metadata) (int, error) {
+	c, err := h.GetOperatorController()
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	for _, op := range c.GetOperators() {
+		if op.GetMetadata().Match(filter) && c.RemoveOperator(op, zap.String("reason", "removed by metadata")) {
+			removed++
+		}
+	}
+	return removed, nil
+}
+
 // GetOperators returns the running operators.
 func (h *Handler) GetOperators() ([]*operator.Operator, error) {
 	c, err := h.GetOperatorController()
@@ -536,8 +645,27 @@ func (h *Handler) SetStoreLimit(storeID uint64, ratePerMin float64, limitType st
 	return c.SetStoreLimit(storeID, limitType, ratePerMin)
 }
 
+// OperatorOption is used to customize the operators created by the handler.
+type OperatorOption func(op *operator.Operator)
+
+// WithOperatorMetadata attaches the metadata of the caller to the operators.
+func WithOperatorMetadata(metadata *operator.Metadata) OperatorOption {
+	return func(op *operator.Operator) {
+		op.SetMetadata(metadata)
+	}
+}
+
+func addOperators(c *cluster.RaftCluster, opts []OperatorOption, ops ...*operator.Operator) bool {
+	for _, op := range ops {
+		for _, opt := range opts {
+			opt(op)
+		}
+	}
+	return c.GetOperatorController().AddOperator(ops...)
+}
+
 // AddTransferLeaderOperator adds an operator to transfer leader to the store.
-func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) error {
+func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64, opts ...OperatorOption) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -558,14 +686,14 @@ func (h *Handler) AddTransferLeaderOperator(regionID uint64, storeID uint64) err
 		log.Debug("fail to create transfer leader operator", errs.ZapError(err))
 		return err
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
 // AddTransferRegionOperator adds an operator to transfer region to the stores.
-func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64]placement.PeerRoleType) error {
+func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64]placement.PeerRoleType, opts ...OperatorOption) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -602,14 +730,14 @@ func (h *Handler) AddTransferRegionOperator(regionID uint64, storeIDs map[uint64
 		log.Debug("fail to create move region operator", errs.ZapError(err))
 		return err
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
 // AddTransferPeerOperator adds an operator to transfer peer.
-func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreID uint64) error {
+func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreID uint64, opts ...OperatorOption) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -635,7 +763,59 @@ func (h *Handler) AddTransferPeerOperator(regionID uint64, fromStoreID, toStoreI
 		log.Debug("fail to create move peer operator", errs.ZapError(err))
 		return err
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
+		return errors.WithStack(ErrAddOperator)
+	}
+	return nil
+}
+
+// AddEvictPeerOperator adds an operator to move the peer of a region off a store.
+// The target store is selected without breaking the placement rules, and it
+// should match the label constraints if they are given.
+func (h *Handler) AddEvictPeerOperator(regionID uint64, fromStoreID uint64, constraints []placement.LabelConstraint, opts ...OperatorOption) error {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+
+	region := c.GetRegion(regionID)
+	if region == nil {
+		return ErrRegionNotFound(regionID)
+	}
+
+	oldPeer := region.GetStorePeer(fromStoreID)
+	if oldPeer == nil {
+		return errors.Errorf("region has no peer in store %v", fromStoreID)
+	}
+	source := c.GetStore(fromStoreID)
+	if source == nil {
+		return ErrStoreNotFound(fromStoreID)
+	}
+
+	scope := "admin-evict-peer"
+	filters := []filter.Filter{
+		filter.NewExcludedFilter(scope, nil, region.GetStoreIDs()),
+		&filter.StoreStateFilter{ActionScope: scope, MoveRegion: true},
+		filter.NewSpecialUseFilter(scope),
+		filter.NewPlacementSafeguard(scope, c.GetOpts(), c.GetBasicCluster(), c.GetRuleManager(), region, source),
+	}
+	if len(constraints) > 0 {
+		filters = append(filters, filter.NewLabelConstaintFilter(scope, constraints))
+	}
+	target := filter.NewCandidates(c.GetStores()).
+		FilterTarget(c.GetOpts(), filters...).
+		PickTheTopStore(filter.RegionScoreComparer(c.GetOpts()), true)
+	if target == nil {
+		return errors.Errorf("no available store to move the peer of region %v off store %v", regionID, fromStoreID)
+	}
+
+	newPeer := &metapb.Peer{StoreId: target.GetID(), Role: oldPeer.GetRole()}
+	op, err := operator.CreateMovePeerOperator(scope, c, region, operator.OpAdmin, fromStoreID, newPeer)
+	if err != nil {
+		log.Debug("fail to create evict peer operator", errs.ZapError(err))
+		return err
+	}
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
@@ -665,7 +845,7 @@ func (h *Handler) checkAdminAddPeerOperator(regionID uint64, toStoreID uint64) (
 }
 
 // AddAddPeerOperator adds an operator to add peer.
-func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
+func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64, opts ...OperatorOption) error {
 	c, region, err := h.checkAdminAddPeerOperator(regionID, toStoreID)
 	if err != nil {
 		return err
@@ -677,14 +857,14 @@ func (h *Handler) AddAddPeerOperator(regionID uint64, toStoreID uint64) error {
 		log.Debug("fail to create add peer operator", errs.ZapError(err))
 		return err
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
 // AddAddLearnerOperator adds an operator to add learner.
-func (h *Handler) AddAddLearnerOperator(regionID uint64, toStoreID uint64) error {
+func (h *Handler) AddAddLearnerOperator(regionID uint64, toStoreID uint64, opts ...OperatorOption) error {
 	c, region, err := h.checkAdminAddPeerOperator(regionID, toStoreID)
 	if err != nil {
 		return err
@@ -700,14 +880,85 @@ func (h *Handler) AddAddLearnerOperator(regionID uint64, toStoreID uint64) error
 		log.Debug("fail to create add learner operator", errs.ZapError(err))
 		return err
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
+		return errors.WithStack(ErrAddOperator)
+	}
+	return nil
+}
+
+// AddDemoteVoterOperator adds an operator to demote a voter to learner.
+func (h *Handler) AddDemoteVoterOperator(regionID uint64, storeID uint64, opts ...OperatorOption) error {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+
+	region := c.GetRegion(regionID)
+	if region == nil {
+		return ErrRegionNotFound(regionID)
+	}
+
+	if region.GetStoreVoter(storeID) == nil {
+		return errors.Errorf("region has no voter in store %v", storeID)
+	}
+
+	if !filter.IsRegionHealthy(region) {
+		return ErrRegionAbnormalPeer(regionID)
+	}
+
+	if err := checkDemoteVoter(c, region, storeID); err != nil {
+		return err
+	}
+
+	op, err := operator.NewBuilder("admin-demote-voter", c, region).
+		DemoteVoter(storeID).
+		Build(operator.OpAdmin)
+	if err != nil {
+		log.Debug("fail to create demote voter operator", errs.ZapError(err))
+		return err
+	}
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
+// checkDemoteVoter checks whether the region still keeps a healthy quorum and
+// satisfies the placement rules after the voter in the store is demoted.
+func checkDemoteVoter(c *cluster.RaftCluster, region *core.RegionInfo, storeID uint64) error {
+	peers := make([]*metapb.Peer, 0, len(region.GetPeers()))
+	for _, peer := range region.GetPeers() {
+		if peer.GetStoreId() == storeID {
+			peer = &metapb.Peer{Id: peer.GetId(), StoreId: peer.GetStoreId(), Role: metapb.PeerRole_Learner}
+		}
+		peers = append(peers, peer)
+	}
+	demoted := region.Clone(core.SetPeers(peers))
+
+	voters := demoted.GetVoters()
+	if len(voters) == 0 {
+		return errors.Errorf("cannot demote the last voter of region %v", region.GetID())
+	}
+	healthy := 0
+	for _, voter := range voters {
+		if store := c.GetStore(voter.GetStoreId()); store != nil && store.IsUp() && !store.IsUnhealthy() {
+			healthy++
+		}
+	}
+	if healthy <= len(voters)/2 {
+		return errors.Errorf("region %v will lose the quorum after demoting the voter in store %v", region.GetID(), storeID)
+	}
+
+	if c.GetOpts().IsPlacementRulesEnabled() {
+		if fit := c.GetRuleManager().FitRegion(c, demoted); !fit.IsSatisfied() {
+			return errors.Errorf("demoting the voter in store %v of region %v violates the placement rules", storeID, region.GetID())
+		}
+	}
+	return nil
+}
+
 // AddRemovePeerOperator adds an operator to remove peer.
-func (h *Handler) AddRemovePeerOperator(regionID uint64, fromStoreID uint64) error {
+func (h *Handler) AddRemovePeerOperator(regionID uint64, fromStoreID uint64, opts ...OperatorOption) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -727,14 +978,14 @@ func (h *Handler) AddRemovePeerOperator(regionID uint64, fromStoreID uint64) err
 		log.Debug("fail to create move peer operator", errs.ZapError(err))
 		return err
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
 // AddMergeRegionOperator adds an operator to merge region.
-func (h *Handler) AddMergeRegionOperator(regionID uint64, targetID uint64) error {
+func (h *Handler) AddMergeRegionOperator(regionID uint64, targetID uint64, opts ...OperatorOption) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -769,14 +1020,14 @@ func (h *Handler) AddMergeRegionOperator(regionID uint64, targetID uint64) error
 		log.Debug("fail to create merge region operator", errs.ZapError(err))
 		return err
 	}
-	if ok := c.GetOperatorController().AddOperator(ops...); !ok {
+	if ok := addOperators(c, opts, ops...); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
 // AddSplitRegionOperator adds an operator to split a region.
-func (h *Handler) AddSplitRegionOperator(regionID uint64, policyStr string, keys []string) error {
+func (h *Handler) AddSplitRegionOperator(regionID uint64, policyStr string, keys []string, opts ...OperatorOption) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -808,14 +1059,14 @@ func (h *Handler) AddSplitRegionOperator(regionID uint64, policyStr string, keys
 		return err
 	}
 
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
 // AddScatterRegionOperator adds an operator to scatter a region.
-func (h *Handler) AddScatterRegionOperator(regionID uint64, group string) error {
+func (h *Handler) AddScatterRegionOperator(regionID uint64, group string, opts ...OperatorOption) error {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return err
@@ -838,14 +1089,14 @@ func (h *Handler) AddScatterRegionOperator(regionID uint64, group string) error
 	if op == nil {
 		return nil
 	}
-	if ok := c.GetOperatorController().AddOperator(op); !ok {
+	if ok := addOperators(c, opts, op); !ok {
 		return errors.WithStack(ErrAddOperator)
 	}
 	return nil
 }
 
 // AddScatterRegionsOperators add operators to scatter regions and return the processed percentage and error
-func (h *Handler) AddScatterRegionsOperators(regionIDs []uint64, startRawKey, endRawKey, group string, retryLimit int) (int, error) {
+func (h *Handler) AddScatterRegionsOperators(regionIDs []uint64, startRawKey, endRawKey, group string, retryLimit int, opts ...OperatorOption) (int, error) {
 	c, err := h.GetRaftCluster()
 	if err != nil {
 		return 0, err
@@ -875,7 +1126,7 @@ func (h *Handler) AddScatterRegionsOperators(regionIDs []uint64, startRawKey, en
 	// If there existed any operator failed to be added into Operator Controller, add its regions into unProcessedRegions
 	for _, op := range ops {
 		op.AttachKind(operator.OpAdmin)
-		if ok := c.GetOperatorController().AddOperator(op); !ok {
+		if ok := addOperators(c, opts, op); !ok {
 			failures[op.RegionID()] = fmt.Errorf("region %v failed to add operator", op.RegionID())
 		}
 	}
@@ -1101,17 +1352,19 @@ func (h *Handler) redirectSchedulerUpdate(name string, storeID float64) error {
 	return apiutil.PostJSONIgnoreResp(h.s.GetHTTPClient(), updateURL, body)
 }
 
-// AddEvictOrGrant add evict leader scheduler or grant leader scheduler.
-func (h *Handler) AddEvictOrGrant(storeID float64, name string) error {
+// AddEvictOrGrant add evict leader scheduler or grant leader scheduler. A new
+// scheduler records its operators instead of submitting them for the first
+// observeSeconds seconds.
+func (h *Handler) AddEvictOrGrant(storeID float64, name string, observeSeconds int64) error {
 	if exist, err := h.IsSchedulerExisted(name); !exist {
 		if err != nil && !errors.ErrorEqual(err, errs.ErrSchedulerNotFound.FastGenByArgs()) {
 			return err
 		}
 		switch name {
 		case schedulers.EvictLeaderName:
-			err = h.AddEvictLeaderScheduler(uint64(storeID))
+			err = h.AddObservingScheduler(observeSeconds, schedulers.EvictLeaderType, strconv.FormatUint(uint64(storeID), 10))
 		case schedulers.GrantLeaderName:
-			err = h.AddGrantLeaderScheduler(uint64(storeID))
+			err = h.AddObservingScheduler(observeSeconds, schedulers.GrantLeaderType, strconv.FormatUint(uint64(storeID), 10))
 		}
 		if err != nil {
 			return err
@@ -1142,3 +1395,170 @@ func (h *Handler) GetPausedSchedulerDelayUntil(name string) (int64, error) {
 	}
 	return rc.GetPausedSchedulerDelayUntil(name)
 }
+
+const (
+	// RestoreModeRuleID is the ID of the region label rule used to deny merge during the restore window.
+	RestoreModeRuleID = "restore-mode"
+	// restoreModeScatterFactor is the factor to raise the snapshot and pending peer limits during the restore window.
+	restoreModeScatterFactor = 4
+)
+
+// restoreModeTTLConfig returns the ttl configs used by the restore mode.
+func (h *Handler) restoreModeTTLConfig(rc *cluster.RaftCluster, storeLimit float64) map[string]interface{} {
+	// Use the persisted schedule config rather than the getters, which return
+	// the ttl overridden values, so that enabling the mode again does not
+	// scale the limits up once more.
+	scheduleCfg := rc.GetOpts().GetScheduleConfig()
+	cfg := map[string]interface{}{
+		"schedule.max-snapshot-count":     scheduleCfg.MaxSnapshotCount * restoreModeScatterFactor,
+		"schedule.max-pending-peer-count": scheduleCfg.MaxPendingPeerCount * restoreModeScatterFactor,
+	}
+	for _, store := range rc.GetStores() {
+		if store.IsRemoved() {
+			continue
+		}
+		cfg[fmt.Sprintf("add-peer-%v", store.GetID())] = storeLimit
+		cfg[fmt.Sprintf("remove-peer-%v", store.GetID())] = storeLimit
+	}
+	return cfg
+}
+
+// EnableRestoreMode adjusts the scheduling for a restore window of BR. During
+// the window, the store limits are raised to the given rate, the snapshot and
+// pending peer limits are raised to speed up scatter, and the regions in the
+// restored key range are not allowed to be merged. All of them are temporary
+// settings with ttl, so the previous settings take effect again automatically
+// after the window expires.
+func (h *Handler) EnableRestoreMode(startKey, endKey []byte, storeLimit float64, ttl time.Duration) error {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	if ttl <= 0 {
+		return errors.Errorf("invalid ttl %v", ttl)
+	}
+	if storeLimit <= 0 {
+		return errors.Errorf("invalid store limit %v", storeLimit)
+	}
+	rule := &labeler.LabelRule{
+		ID:       RestoreModeRuleID,
+		Labels:   []labeler.RegionLabel{{Key: "merge_option", Value: "deny", TTL: ttl.String()}},
+		RuleType: labeler.KeyRange,
+		Data:     []interface{}{map[string]interface{}{"start_key": hex.EncodeToString(startKey), "end_key": hex.EncodeToString(endKey)}},
+	}
+	if err := rc.GetRegionLabeler().SetLabelRule(rule); err != nil {
+		return err
+	}
+	if err := h.s.SaveTTLConfig(h.restoreModeTTLConfig(rc, storeLimit), ttl); err != nil {
+		return err
+	}
+	log.Info("restore mode is enabled",
+		zap.String("start-key", hex.EncodeToString(startKey)),
+		zap.String("end-key", hex.EncodeToString(endKey)),
+		zap.Float64("store-limit", storeLimit),
+		zap.Duration("ttl", ttl))
+	return nil
+}
+
+// DisableRestoreMode ends the restore window in advance.
+func (h *Handler) DisableRestoreMode() error {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	if rc.GetRegionLabeler().GetLabelRule(RestoreModeRuleID) != nil {
+		if err := rc.GetRegionLabeler().DeleteLabelRule(RestoreModeRuleID); err != nil {
+			return err
+		}
+	}
+	if err := h.resetRestoreModeTTLConfig(rc); err != nil {
+		return err
+	}
+	log.Info("restore mode is disabled")
+	return nil
+}
+
+// resetRestoreModeTTLConfig cleans up the temporary settings shared by the
+// restore mode and the import mode, unless either of them is still active.
+func (h *Handler) resetRestoreModeTTLConfig(rc *cluster.RaftCluster) error {
+	if rc.GetRegionLabeler().GetLabelRule(RestoreModeRuleID) != nil || len(rc.GetImportModes()) > 0 {
+		return nil
+	}
+	// A ttl of 0 cleans up the temporary settings.
+	return h.s.SaveTTLConfig(h.restoreModeTTLConfig(rc, 0), 0)
+}
+
+// GetRestoreMode returns the label rule of the current restore window, it
+// returns nil if there is no restore window.
+func (h *Handler) GetRestoreMode() (*labeler.LabelRule, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.GetRegionLabeler().GetLabelRule(RestoreModeRuleID), nil
+}
+
+// EnableImportMode prepares a key range for a bulk import of Lightning or BR.
+// The regions are pre-split and scattered by a task in the background, the
+// merge is denied in the range, and the store limits are raised like the
+// restore mode before the ttl expires.
+func (h *Handler) EnableImportMode(id string, startKey, endKey []byte, regionCount int, storeLimit, tolerance float64, ttl time.Duration) (*cluster.ImportModeStatus, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	if storeLimit <= 0 {
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the store limit should be positive")
+	}
+	if ttl <= 0 {
+		return nil, errs.ErrImportModeInvalid.FastGenByArgs("the ttl should be positive")
+	}
+	status, err := rc.EnableImportMode(id, startKey, endKey, regionCount, tolerance, ttl)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.s.SaveTTLConfig(h.restoreModeTTLConfig(rc, storeLimit), ttl); err != nil {
+		return nil, err
+	}
+	return status, nil
+}
+
+// DisableImportMode ends the import in advance. The raised store limits are
+// restored once there is no import left and the restore mode is not enabled.
+func (h *Handler) DisableImportMode(id string) error {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return err
+	}
+	if err := rc.DisableImportMode(id); err != nil {
+		return err
+	}
+	return h.resetRestoreModeTTLConfig(rc)
+}
+
+// GetImportMode returns the status of the import with the given ID.
+func (h *Handler) GetImportMode(id string) (*cluster.ImportModeStatus, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.GetImportMode(id)
+}
+
+// GetImportModes returns the status of all the imports.
+func (h *Handler) GetImportModes() ([]*cluster.ImportModeStatus, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.GetImportModes(), nil
+}
+
+// CheckInvariants runs the invariant checks against the current cluster state.
+func (h *Handler) CheckInvariants() (*cluster.InvariantReport, error) {
+	rc, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	return rc.CheckInvariants()
+}
@@ -35,6 +35,9 @@ type Allocator interface {
 	// which also resets the end of the allocator. (base, end) is the range that can
 	// be allocated in memory.
 	Rebase() error
+	// Current returns the largest ID which may have been allocated, without
+	// allocating a new one.
+	Current() (uint64, error)
 }
 
 const allocStep = uint64(1000)
@@ -81,6 +84,27 @@ func (alloc *allocatorImpl) Rebase() error {
 	return alloc.rebaseLocked()
 }
 
+// Current returns the persistent window boundary, which is not less than any ID
+// allocated by the current or the previous leaders.
+func (alloc *allocatorImpl) Current() (uint64, error) {
+	value, err := etcdutil.GetValue(alloc.client, alloc.getAllocIDPath())
+	if err != nil {
+		return 0, err
+	}
+	var current uint64
+	if value != nil {
+		if current, err = typeutil.BytesToUint64(value); err != nil {
+			return 0, err
+		}
+	}
+	alloc.mu.Lock()
+	defer alloc.mu.Unlock()
+	if current < alloc.base {
+		current = alloc.base
+	}
+	return current, nil
+}
+
 func (alloc *allocatorImpl) rebaseLocked() error {
 	key := alloc.getAllocIDPath()
 	value, err := etcdutil.GetValue(alloc.client, key)
@@ -0,0 +1,291 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvisual
+
+import (
+	"bytes"
+	"sort"
+	"time"
+
+	"github.com/tikv/pd/pkg/keyutil"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/statistics/buckets"
+)
+
+// Column is the loads of continuous key ranges in a period of time. Values[kind][i] is
+// the load of the given statistics.RegionStatKind on the key range [Keys[i], Keys[i+1]).
+// The first key is empty if the key ranges are unbounded on the left, and the last key
+// is empty if they are unbounded on the right.
+//
+// The load of a key range is the load per second of the bucket which the key range
+// belongs to, so a key range keeps the load of its bucket after being split, and the
+// key ranges take the max load after being merged.
+type Column struct {
+	StartTime time.Time  `json:"start_time"`
+	EndTime   time.Time  `json:"end_time"`
+	Keys      [][]byte   `json:"keys"`
+	Values    [][]uint64 `json:"values"`
+}
+
+// newColumn creates a column from the bucket stats. The gaps between the buckets are
+// filled by key ranges without load.
+func newColumn(stats map[uint64][]*buckets.BucketStat, startTime, endTime time.Time) *Column {
+	var bucketStats []*buckets.BucketStat
+	for _, regionStats := range stats {
+		bucketStats = append(bucketStats, regionStats...)
+	}
+	sort.Slice(bucketStats, func(i, j int) bool {
+		return bytes.Compare(bucketStats[i].StartKey, bucketStats[j].StartKey) < 0
+	})
+
+	c := &Column{StartTime: startTime, EndTime: endTime, Values: make([][]uint64, statistics.RegionStatCount)}
+	appendRange := func(startKey, endKey []byte, loads []uint64) {
+		if len(c.Keys) == 0 {
+			c.Keys = append(c.Keys, startKey)
+		}
+		c.Keys = append(c.Keys, endKey)
+		for kind := range c.Values {
+			var load uint64
+			if kind < len(loads) {
+				load = loads[kind]
+			}
+			c.Values[kind] = append(c.Values[kind], load)
+		}
+	}
+	for _, stat := range bucketStats {
+		if len(c.Keys) > 0 {
+			lastKey := c.Keys[len(c.Keys)-1]
+			// Skip the bucket overlapping with the previous one, which is out of date.
+			if len(lastKey) == 0 || bytes.Compare(stat.StartKey, lastKey) < 0 {
+				continue
+			}
+			if bytes.Compare(lastKey, stat.StartKey) < 0 {
+				appendRange(lastKey, stat.StartKey, nil)
+			}
+		} else if len(stat.StartKey) > 0 {
+			appendRange(nil, stat.StartKey, nil)
+		}
+		appendRange(stat.StartKey, stat.EndKey, stat.Loads)
+	}
+	if len(c.Keys) > 0 && len(c.Keys[len(c.Keys)-1]) > 0 {
+		appendRange(c.Keys[len(c.Keys)-1], nil, nil)
+	}
+	return c
+}
+
+// rangeCount returns the number of the key ranges.
+func (c *Column) rangeCount() int {
+	if len(c.Keys) == 0 {
+		return 0
+	}
+	return len(c.Keys) - 1
+}
+
+// isEmpty returns true if no key range of the column has load.
+func (c *Column) isEmpty() bool {
+	for _, values := range c.Values {
+		for _, v := range values {
+			if v > 0 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (c *Column) startKey() []byte {
+	return c.Keys[0]
+}
+
+func (c *Column) endKey() []byte {
+	return c.Keys[len(c.Keys)-1]
+}
+
+// locate returns the index of the key range which the key belongs to, or -1 if the
+// key is out of the column.
+func (c *Column) locate(key []byte) int {
+	n := c.rangeCount()
+	if n == 0 || bytes.Compare(key, c.startKey()) < 0 || !lessEndKey(key, c.endKey()) {
+		return -1
+	}
+	return sort.Search(n, func(i int) bool {
+		endKey := c.Keys[i+1]
+		return (i+1 == n && len(endKey) == 0) || bytes.Compare(key, endKey) < 0
+	})
+}
+
+// project returns the loads of the key ranges split by the axis, which must be finer
+// than the keys of the column. The key ranges out of the column have no load.
+func (c *Column) project(axis [][]byte) [][]uint64 {
+	values := make([][]uint64, len(c.Values))
+	for kind := range values {
+		values[kind] = make([]uint64, len(axis)-1)
+	}
+	for i := 0; i+1 < len(axis); i++ {
+		j := c.locate(axis[i])
+		if j < 0 {
+			continue
+		}
+		for kind := range values {
+			values[kind][i] = c.Values[kind][j]
+		}
+	}
+	return values
+}
+
+// clip returns the part of the column in the key range [startKey, endKey), or nil if
+// they have no intersection.
+func (c *Column) clip(startKey, endKey []byte) *Column {
+	if c.rangeCount() == 0 {
+		return nil
+	}
+	start := keyutil.MaxKey(startKey, c.startKey())
+	end := minEndKey(endKey, c.endKey())
+	if !lessEndKey(start, end) {
+		return nil
+	}
+	axis := [][]byte{start}
+	for _, key := range c.Keys[1 : len(c.Keys)-1] {
+		if bytes.Compare(start, key) < 0 && lessEndKey(key, end) {
+			axis = append(axis, key)
+		}
+	}
+	axis = append(axis, end)
+	return &Column{StartTime: c.StartTime, EndTime: c.EndTime, Keys: axis, Values: c.project(axis)}
+}
+
+// compact merges the adjacent key ranges evenly so that there are at most maxRanges
+// key ranges. The merged key range takes the max load of the key ranges.
+func (c *Column) compact(maxRanges int) {
+	n := c.rangeCount()
+	if maxRanges <= 0 || n <= maxRanges {
+		return
+	}
+	c.Keys, c.Values = compactRanges(c.Keys, c.Values, maxRanges)
+}
+
+// compactRanges merges the adjacent key ranges evenly, values are several series of the
+// loads of the key ranges.
+func compactRanges(keys [][]byte, values [][]uint64, maxRanges int) ([][]byte, [][]uint64) {
+	n := len(keys) - 1
+	group := (n + maxRanges - 1) / maxRanges
+	newKeys := make([][]byte, 0, maxRanges+1)
+	newValues := make([][]uint64, len(values))
+	for i := 0; i < n; i += group {
+		newKeys = append(newKeys, keys[i])
+		for k := range values {
+			var load uint64
+			for j := i; j < i+group && j < n; j++ {
+				if values[k][j] > load {
+					load = values[k][j]
+				}
+			}
+			newValues[k] = append(newValues[k], load)
+		}
+	}
+	newKeys = append(newKeys, keys[n])
+	return newKeys, newValues
+}
+
+// mergeColumns merges the columns of continuous periods into one column, whose load
+// is the average load of the columns.
+func mergeColumns(columns []*Column) *Column {
+	axis := unionAxis(columns)
+	merged := &Column{
+		StartTime: columns[0].StartTime,
+		EndTime:   columns[len(columns)-1].EndTime,
+		Keys:      axis,
+		Values:    make([][]uint64, statistics.RegionStatCount),
+	}
+	if len(axis) == 0 {
+		return merged
+	}
+	for kind := range merged.Values {
+		merged.Values[kind] = make([]uint64, len(axis)-1)
+	}
+	for _, c := range columns {
+		values := c.project(axis)
+		for kind := range merged.Values {
+			for i, load := range values[kind] {
+				merged.Values[kind][i] += load
+			}
+		}
+	}
+	for kind := range merged.Values {
+		for i := range merged.Values[kind] {
+			merged.Values[kind][i] /= uint64(len(columns))
+		}
+	}
+	return merged
+}
+
+// unionAxis returns the keys which split the key ranges of all the columns.
+func unionAxis(columns []*Column) [][]byte {
+	var (
+		start, end []byte
+		found      bool
+		keys       = make(map[string]struct{})
+	)
+	for _, c := range columns {
+		if c.rangeCount() == 0 {
+			continue
+		}
+		if !found {
+			start, end, found = c.startKey(), c.endKey(), true
+		}
+		start = keyutil.MinKey(start, c.startKey())
+		end = maxEndKey(end, c.endKey())
+		for _, key := range c.Keys {
+			if len(key) > 0 {
+				keys[string(key)] = struct{}{}
+			}
+		}
+	}
+	if !found {
+		return nil
+	}
+	axis := make([][]byte, 0, len(keys)+2)
+	for key := range keys {
+		if bytes.Compare(start, []byte(key)) < 0 && lessEndKey([]byte(key), end) {
+			axis = append(axis, []byte(key))
+		}
+	}
+	sort.Slice(axis, func(i, j int) bool { return bytes.Compare(axis[i], axis[j]) < 0 })
+	axis = append([][]byte{start}, axis...)
+	return append(axis, end)
+}
+
+// lessEndKey returns if the key is less than the end key, the empty end key is larger
+// than any key.
+func lessEndKey(key, endKey []byte) bool {
+	return len(endKey) == 0 || bytes.Compare(key, endKey) < 0
+}
+
+func minEndKey(a, b []byte) []byte {
+	if len(a) == 0 {
+		return b
+	}
+	if len(b) == 0 {
+		return a
+	}
+	return keyutil.MinKey(a, b)
+}
+
+func maxEndKey(a, b []byte) []byte {
+	if len(a) == 0 || len(b) == 0 {
+		return nil
+	}
+	return keyutil.MaxKey(a, b)
+}
@@ -0,0 +1,83 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvisual
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/statistics/buckets"
+)
+
+func newBucketStat(regionID uint64, startKey, endKey string, writeBytes uint64) *buckets.BucketStat {
+	loads := make([]uint64, statistics.RegionStatCount)
+	loads[statistics.RegionWriteBytes] = writeBytes
+	return &buckets.BucketStat{RegionID: regionID, StartKey: []byte(startKey), EndKey: []byte(endKey), Loads: loads}
+}
+
+func toStrings(keys [][]byte) []string {
+	res := make([]string, 0, len(keys))
+	for _, key := range keys {
+		res = append(res, string(key))
+	}
+	return res
+}
+
+func TestNewColumn(t *testing.T) {
+	re := require.New(t)
+	now := time.Now()
+	stats := map[uint64][]*buckets.BucketStat{
+		1: {newBucketStat(1, "a", "b", 10), newBucketStat(1, "b", "c", 20)},
+		2: {newBucketStat(2, "d", "e", 30)},
+	}
+	c := newColumn(stats, now.Add(-time.Minute), now)
+	re.Equal([]string{"", "a", "b", "c", "d", "e", ""}, toStrings(c.Keys))
+	re.Equal([]uint64{0, 10, 20, 0, 30, 0}, c.Values[statistics.RegionWriteBytes])
+	re.Equal([]uint64{0, 0, 0, 0, 0, 0}, c.Values[statistics.RegionReadBytes])
+
+	re.Equal(0, c.locate([]byte("")))
+	re.Equal(1, c.locate([]byte("a")))
+	re.Equal(1, c.locate([]byte("a1")))
+	re.Equal(5, c.locate([]byte("f")))
+
+	clipped := c.clip([]byte("a1"), []byte("d1"))
+	re.Equal([]string{"a1", "b", "c", "d", "d1"}, toStrings(clipped.Keys))
+	re.Equal([]uint64{10, 20, 0, 30}, clipped.Values[statistics.RegionWriteBytes])
+	re.Nil(c.clip([]byte("b"), []byte("a")))
+
+	c.compact(3)
+	re.Equal([]string{"", "b", "d", ""}, toStrings(c.Keys))
+	re.Equal([]uint64{10, 20, 30}, c.Values[statistics.RegionWriteBytes])
+}
+
+func TestMergeColumns(t *testing.T) {
+	re := require.New(t)
+	now := time.Now()
+	c1 := newColumn(map[uint64][]*buckets.BucketStat{
+		1: {newBucketStat(1, "", "b", 10), newBucketStat(1, "b", "", 20)},
+	}, now.Add(-2*time.Minute), now.Add(-time.Minute))
+	c2 := newColumn(map[uint64][]*buckets.BucketStat{
+		1: {newBucketStat(1, "", "c", 30), newBucketStat(1, "c", "", 40)},
+	}, now.Add(-time.Minute), now)
+
+	merged := mergeColumns([]*Column{c1, c2})
+	re.Equal(c1.StartTime, merged.StartTime)
+	re.Equal(c2.EndTime, merged.EndTime)
+	re.Equal([]string{"", "b", "c", ""}, toStrings(merged.Keys))
+	// (10+30)/2, (20+30)/2, (20+40)/2
+	re.Equal([]uint64{20, 25, 30}, merged.Values[statistics.RegionWriteBytes])
+}
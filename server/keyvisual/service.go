@@ -0,0 +1,239 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvisual
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"math"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/statistics/buckets"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"go.uber.org/zap"
+)
+
+const (
+	collectInterval = time.Minute
+	// allBucketsDegree is the min hot degree to collect all the buckets.
+	allBucketsDegree = math.MinInt32
+	// The columns are kept in layers. Once a layer is full, its oldest columns are merged
+	// into one column of the next layer, and the last layer drops its oldest columns. So
+	// the columns of the i-th layer last for layerMergeRatio^i collect intervals.
+	layerCount      = 5
+	layerCapacity   = 60
+	layerMergeRatio = 2
+	// maxColumnRanges is the max number of key ranges in a column.
+	maxColumnRanges = 256
+	// DefaultMatrixRanges is the default number of key ranges in a matrix.
+	DefaultMatrixRanges = 64
+	// MaxMatrixRanges is the max number of key ranges in a matrix.
+	MaxMatrixRanges = 1024
+)
+
+// Service aggregates the bucket stats into a heatmap of time and key ranges.
+type Service struct {
+	informer buckets.BucketStatInformer
+	// storage persists the columns, which is the local storage of PD rather than etcd.
+	// The columns are only kept in memory if it's nil.
+	storage endpoint.KeyVisualStorage
+
+	mu     sync.RWMutex
+	layers [layerCount][]*Column
+	// lastCollectTime is the end time of the last column.
+	lastCollectTime time.Time
+}
+
+// columnChange is a column saved to or deleted from the storage.
+type columnChange struct {
+	layer   int
+	column  *Column
+	deleted bool
+}
+
+// NewService creates a key visual service. The storage can be nil.
+func NewService(informer buckets.BucketStatInformer, storage endpoint.KeyVisualStorage) *Service {
+	return &Service{
+		informer: informer,
+		storage:  storage,
+	}
+}
+
+// Run loads the persisted columns and collects the bucket stats periodically.
+func (s *Service) Run(ctx context.Context) {
+	if err := s.load(); err != nil {
+		log.Warn("failed to load key visual columns", errs.ZapError(err))
+	}
+	ticker := time.NewTicker(collectInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Info("key visual service has been stopped")
+			return
+		case now := <-ticker.C:
+			s.collect(now)
+		}
+	}
+}
+
+func (s *Service) load() error {
+	if s.storage == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i := range s.layers {
+		var columns []*Column
+		err := s.storage.LoadKeyVisualColumns(i, func(k, v string) {
+			c := &Column{}
+			if err := json.Unmarshal([]byte(v), c); err != nil {
+				log.Warn("failed to unmarshal key visual column", zap.Int("layer", i), zap.String("key", k), errs.ZapError(errs.ErrJSONUnmarshal, err))
+				return
+			}
+			columns = append(columns, c)
+		})
+		if err != nil {
+			return err
+		}
+		s.layers[i] = columns
+	}
+	if columns := s.layers[0]; len(columns) > 0 {
+		s.lastCollectTime = columns[len(columns)-1].EndTime
+	}
+	return nil
+}
+
+func (s *Service) collect(now time.Time) {
+	stats := s.informer.BucketsStats(allBucketsDegree)
+	s.mu.RLock()
+	startTime := s.lastCollectTime
+	s.mu.RUnlock()
+	if startTime.IsZero() || now.Sub(startTime) > 2*collectInterval {
+		startTime = now.Add(-collectInterval)
+	}
+	c := newColumn(stats, startTime, now)
+	// The periods without load are left as gaps of the heatmap.
+	if c.isEmpty() {
+		return
+	}
+	c.compact(maxColumnRanges)
+
+	s.mu.Lock()
+	s.lastCollectTime = now
+	changes := s.push(0, c, nil)
+	s.mu.Unlock()
+	s.persist(changes)
+}
+
+// push appends the column to the layer and merges the oldest columns into the next layer
+// if the layer is full. It returns the changes to be persisted.
+func (s *Service) push(layer int, c *Column, changes []columnChange) []columnChange {
+	s.layers[layer] = append(s.layers[layer], c)
+	changes = append(changes, columnChange{layer: layer, column: c})
+	if len(s.layers[layer]) <= layerCapacity {
+		return changes
+	}
+	oldest := s.layers[layer][:layerMergeRatio]
+	s.layers[layer] = append([]*Column(nil), s.layers[layer][layerMergeRatio:]...)
+	for _, old := range oldest {
+		changes = append(changes, columnChange{layer: layer, column: old, deleted: true})
+	}
+	if layer+1 < layerCount {
+		merged := mergeColumns(oldest)
+		merged.compact(maxColumnRanges)
+		changes = s.push(layer+1, merged, changes)
+	}
+	return changes
+}
+
+// persist applies the changes to the storage, which is done outside the lock.
+func (s *Service) persist(changes []columnChange) {
+	if s.storage == nil {
+		return
+	}
+	for _, change := range changes {
+		ts := change.column.StartTime.UnixNano()
+		if change.deleted {
+			if err := s.storage.DeleteKeyVisualColumn(change.layer, ts); err != nil {
+				log.Warn("failed to delete key visual column", zap.Int("layer", change.layer), errs.ZapError(err))
+			}
+			continue
+		}
+		if err := s.storage.SaveKeyVisualColumn(change.layer, ts, change.column); err != nil {
+			log.Warn("failed to save key visual column", zap.Int("layer", change.layer), errs.ZapError(err))
+		}
+	}
+}
+
+// Matrix is the heatmap of a kind of load. Data[i][j] is the load per second of the key
+// range [Keys[j], Keys[j+1]) in the period [Times[i], Times[i+1]).
+type Matrix struct {
+	// Keys are hex encoded.
+	Keys []string `json:"keys"`
+	// Times are unix timestamps in seconds.
+	Times []int64    `json:"times"`
+	Data  [][]uint64 `json:"data"`
+}
+
+// GetMatrix returns the heatmap of the load in the key range [startKey, endKey) and the
+// period [startTime, endTime), with at most maxRanges key ranges.
+func (s *Service) GetMatrix(kind statistics.RegionStatKind, startKey, endKey []byte, startTime, endTime time.Time, maxRanges int) *Matrix {
+	s.mu.RLock()
+	var columns []*Column
+	for _, layer := range s.layers {
+		for _, c := range layer {
+			if c.EndTime.After(startTime) && c.StartTime.Before(endTime) {
+				if clipped := c.clip(startKey, endKey); clipped != nil {
+					columns = append(columns, clipped)
+				}
+			}
+		}
+	}
+	s.mu.RUnlock()
+
+	matrix := &Matrix{Keys: []string{}, Times: []int64{}, Data: [][]uint64{}}
+	if len(columns) == 0 {
+		return matrix
+	}
+	sort.Slice(columns, func(i, j int) bool { return columns[i].StartTime.Before(columns[j].StartTime) })
+	axis := unionAxis(columns)
+	values := make([][]uint64, len(columns))
+	for i, c := range columns {
+		values[i] = c.project(axis)[kind]
+	}
+	// Compact the key ranges of all the periods in the same way.
+	if maxRanges > 0 && len(axis)-1 > maxRanges {
+		var compacted [][]byte
+		compacted, values = compactRanges(axis, values, maxRanges)
+		axis = compacted
+	}
+
+	for _, key := range axis {
+		matrix.Keys = append(matrix.Keys, hex.EncodeToString(key))
+	}
+	for _, c := range columns {
+		matrix.Times = append(matrix.Times, c.StartTime.Unix())
+	}
+	matrix.Times = append(matrix.Times, columns[len(columns)-1].EndTime.Unix())
+	matrix.Data = values
+	return matrix
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package keyvisual
+
+import (
+	"encoding/hex"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/statistics"
+	"github.com/tikv/pd/server/statistics/buckets"
+	"github.com/tikv/pd/server/storage"
+)
+
+type mockInformer struct {
+	stats map[uint64][]*buckets.BucketStat
+}
+
+func (m *mockInformer) BucketsStats(degree int) map[uint64][]*buckets.BucketStat {
+	return m.stats
+}
+
+func TestServiceLayers(t *testing.T) {
+	re := require.New(t)
+	informer := &mockInformer{stats: map[uint64][]*buckets.BucketStat{
+		1: {newBucketStat(1, "a", "b", 10)},
+	}}
+	store := storage.NewStorageWithMemoryBackend()
+	s := NewService(informer, store)
+
+	start := time.Now().Add(-24 * time.Hour)
+	now := start
+	for i := 0; i < layerCapacity+layerMergeRatio*layerCapacity+1; i++ {
+		now = now.Add(collectInterval)
+		s.collect(now)
+	}
+	// Each full layer merges its oldest columns into the next layer.
+	re.Len(s.layers[0], layerCapacity-1)
+	re.Len(s.layers[1], layerCapacity-1)
+	re.Len(s.layers[2], 1)
+	re.Equal(now, s.layers[0][layerCapacity-2].EndTime)
+	re.Equal(start, s.layers[2][0].StartTime)
+	re.Equal(start.Add(layerMergeRatio*layerMergeRatio*collectInterval), s.layers[2][0].EndTime)
+
+	// The columns are restored from the storage.
+	restored := NewService(informer, store)
+	re.NoError(restored.load())
+	for i := range s.layers {
+		re.Len(restored.layers[i], len(s.layers[i]))
+	}
+	re.Equal(now.UnixNano(), restored.lastCollectTime.UnixNano())
+
+	matrix := s.GetMatrix(statistics.RegionWriteBytes, []byte("a"), []byte("c"), start, now, DefaultMatrixRanges)
+	re.Equal([]string{hex.EncodeToString([]byte("a")), hex.EncodeToString([]byte("b")), hex.EncodeToString([]byte("c"))}, matrix.Keys)
+	re.Len(matrix.Data, 1+2*(layerCapacity-1))
+	re.Len(matrix.Times, len(matrix.Data)+1)
+	re.Equal(start.Unix(), matrix.Times[0])
+	re.Equal(now.Unix(), matrix.Times[len(matrix.Times)-1])
+	for _, row := range matrix.Data {
+		re.Equal([]uint64{10, 0}, row)
+	}
+
+	matrix = s.GetMatrix(statistics.RegionWriteBytes, nil, nil, now.Add(-collectInterval), now, 1)
+	re.Len(matrix.Data, 1)
+	re.Equal([]uint64{10}, matrix.Data[0])
+}
+
+func TestServiceSkipEmptyColumns(t *testing.T) {
+	re := require.New(t)
+	informer := &mockInformer{stats: map[uint64][]*buckets.BucketStat{
+		1: {newBucketStat(1, "a", "b", 0)},
+	}}
+	// The columns are only kept in memory without the storage.
+	s := NewService(informer, nil)
+	re.NoError(s.load())
+
+	now := time.Now()
+	s.collect(now)
+	re.Empty(s.layers[0])
+	re.True(s.lastCollectTime.IsZero())
+
+	informer.stats[1] = []*buckets.BucketStat{newBucketStat(1, "a", "b", 10)}
+	now = now.Add(collectInterval)
+	s.collect(now)
+	re.Len(s.layers[0], 1)
+	re.Equal(now.Add(-collectInterval), s.layers[0][0].StartTime)
+	re.Equal(now, s.lastCollectTime)
+}
@@ -0,0 +1,94 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package member
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+	"go.etcd.io/etcd/clientv3"
+)
+
+const (
+	preCampaignPath        = "pre_campaign"
+	storeStatusDialTimeout = time.Second
+)
+
+// PreCampaignCheck checks whether the member is healthy enough to be the PD leader.
+// It fails if the etcd commit latency exceeds maxCommitLatency, or the member
+// cannot reach a quorum of the given store status addresses.
+func (m *Member) PreCampaignCheck(ctx context.Context, maxCommitLatency time.Duration, storeStatusAddrs []string) error {
+	latency, err := m.measureCommitLatency(ctx, maxCommitLatency)
+	if err != nil {
+		return errs.ErrPreCampaignCheck.FastGenByArgs(err.Error())
+	}
+	if latency > maxCommitLatency {
+		return errs.ErrPreCampaignCheck.FastGenByArgs(fmt.Sprintf("etcd commit latency %v exceeds %v", latency, maxCommitLatency))
+	}
+	if len(storeStatusAddrs) == 0 {
+		return nil
+	}
+	reachable := countReachable(ctx, storeStatusAddrs)
+	if reachable <= len(storeStatusAddrs)/2 {
+		return errs.ErrPreCampaignCheck.FastGenByArgs(fmt.Sprintf("only %d of %d stores are reachable", reachable, len(storeStatusAddrs)))
+	}
+	return nil
+}
+
+// measureCommitLatency writes a key of the member to etcd and returns the latency.
+func (m *Member) measureCommitLatency(ctx context.Context, timeout time.Duration) (time.Duration, error) {
+	// Leave enough time to tell a slow commit from a failed one.
+	ctx, cancel := context.WithTimeout(ctx, 2*timeout)
+	defer cancel()
+	key := path.Join(m.rootPath, preCampaignPath, strconv.FormatUint(m.ID(), 10))
+	start := time.Now()
+	_, err := m.client.Txn(ctx).Then(clientv3.OpPut(key, strconv.FormatInt(start.UnixNano(), 10))).Commit()
+	if err != nil {
+		return 0, errs.ErrEtcdKVPut.Wrap(err).GenWithStackByCause()
+	}
+	return time.Since(start), nil
+}
+
+// countReachable returns the number of addresses which can be connected.
+func countReachable(ctx context.Context, addrs []string) int {
+	var (
+		wg        sync.WaitGroup
+		mu        sync.Mutex
+		reachable int
+		dialer    = net.Dialer{Timeout: storeStatusDialTimeout}
+	)
+	for _, addr := range addrs {
+		wg.Add(1)
+		go func(addr string) {
+			defer wg.Done()
+			conn, err := dialer.DialContext(ctx, "tcp", addr)
+			if err != nil {
+				return
+			}
+			conn.Close()
+			mu.Lock()
+			reachable++
+			mu.Unlock()
+		}(addr)
+	}
+	wg.Wait()
+	return reachable
+}
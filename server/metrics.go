@@ -73,6 +73,24 @@ var (
 			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
 		})
 
+	tsoProxyBatchRequestCount = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "handle_tso_proxy_batch_request_count",
+			Help:      "Bucketed histogram of the number of tso requests merged into a proxy request.",
+			Buckets:   prometheus.ExponentialBuckets(1, 2, 13),
+		})
+
+	tsoProxyAddedDuration = prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "tso_proxy_added_duration_seconds",
+			Help:      "Bucketed histogram of the latency (s) added by the tso proxy, from receiving a request to getting its response from the leader.",
+			Buckets:   prometheus.ExponentialBuckets(0.0005, 2, 13),
+		})
+
 	tsoProxyBatchSize = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "pd",
@@ -135,6 +153,30 @@ var (
 			Help:      "Indicate the pd server info, and the value is the start timestamp (s).",
 		}, []string{"version", "hash"})
 
+	grpcStreamsGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "grpc_streams",
+			Help:      "The number of the concurrent gRPC streams of each client class.",
+		}, []string{"class"})
+
+	grpcStreamRejectedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "grpc_stream_rejected_total",
+			Help:      "Counter of the gRPC streams rejected by the settings of their client class.",
+		}, []string{"class", "reason"})
+
+	grpcStreamIdleClosedCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "server",
+			Name:      "grpc_stream_idle_closed_total",
+			Help:      "Counter of the gRPC streams closed by the idle timeout of their client class.",
+		}, []string{"class"})
+
 	serviceAuditHistogram = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: "pd",
@@ -153,6 +195,8 @@ func init() {
 	prometheus.MustRegister(etcdStateGauge)
 	prometheus.MustRegister(tsoProxyHandleDuration)
 	prometheus.MustRegister(tsoProxyBatchSize)
+	prometheus.MustRegister(tsoProxyBatchRequestCount)
+	prometheus.MustRegister(tsoProxyAddedDuration)
 	prometheus.MustRegister(tsoHandleDuration)
 	prometheus.MustRegister(regionHeartbeatHandleDuration)
 	prometheus.MustRegister(storeHeartbeatHandleDuration)
@@ -161,4 +205,7 @@ func init() {
 	prometheus.MustRegister(bucketReportLatency)
 	prometheus.MustRegister(serviceAuditHistogram)
 	prometheus.MustRegister(bucketReportInterval)
+	prometheus.MustRegister(grpcStreamsGauge)
+	prometheus.MustRegister(grpcStreamRejectedCounter)
+	prometheus.MustRegister(grpcStreamIdleClosedCounter)
 }
@@ -0,0 +1,234 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+
+	"github.com/pingcap/errors"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/filter"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/schedule/plan"
+)
+
+const precheckScope = "admin-precheck"
+
+// The names of the checks run by the peer change precheck.
+const (
+	PrecheckRegionHealth  = "region-health"
+	PrecheckStoreState    = "store-state"
+	PrecheckSpecialUse    = "special-use"
+	PrecheckRuleFit       = "rule-fit"
+	PrecheckLabelProperty = "label-property"
+	PrecheckOperator      = "operator"
+	PrecheckStoreLimit    = "store-limit"
+)
+
+// PeerChange is a proposed change of a region peer. The peer is moved if both
+// the source and the target stores are given, otherwise it is added to the
+// target store or removed from the source store.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type PeerChange struct {
+	RegionID    uint64 `json:"region_id"`
+	FromStoreID uint64 `json:"from_store_id,omitempty"`
+	ToStoreID   uint64 `json:"to_store_id,omitempty"`
+	// Role is the role of the added peer, "voter" or "learner". The moved peer
+	// keeps its role if it is empty.
+	Role string `json:"role,omitempty"`
+}
+
+// PrecheckResult is the result of a check in the precheck.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type PrecheckResult struct {
+	Name       string          `json:"name"`
+	Pass       bool            `json:"pass"`
+	StatusCode plan.StatusCode `json:"status_code"`
+	Status     string          `json:"status"`
+	Reason     string          `json:"reason,omitempty"`
+}
+
+// PeerChangePrecheck is the result of the precheck of a peer change.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type PeerChangePrecheck struct {
+	Pass   bool              `json:"pass"`
+	Checks []*PrecheckResult `json:"checks"`
+}
+
+func (p *PeerChangePrecheck) add(name string, status plan.Status) bool {
+	p.Checks = append(p.Checks, &PrecheckResult{
+		Name:       name,
+		Pass:       status.IsOK(),
+		StatusCode: status.StatusCode,
+		Status:     plan.StatusText(status.StatusCode),
+		Reason:     status.DetailedReason,
+	})
+	if !status.IsOK() {
+		p.Pass = false
+	}
+	return status.IsOK()
+}
+
+// PrecheckPeerChange runs the peer change through the checks applied to the operators
+// without creating any operator, so that the external schedulers know whether PD
+// would accept it. All the checks are run even if some of them fail.
+func (h *Handler) PrecheckPeerChange(change *PeerChange) (*PeerChangePrecheck, error) {
+	c, err := h.GetRaftCluster()
+	if err != nil {
+		return nil, err
+	}
+	if change.FromStoreID == 0 && change.ToStoreID == 0 {
+		return nil, errors.New("missing store id to change the peer")
+	}
+	region := c.GetRegion(change.RegionID)
+	if region == nil {
+		return nil, ErrRegionNotFound(change.RegionID)
+	}
+	var oldPeer, newPeer *metapb.Peer
+	if change.FromStoreID != 0 {
+		if oldPeer = region.GetStorePeer(change.FromStoreID); oldPeer == nil {
+			return nil, errors.Errorf("region has no peer in store %v", change.FromStoreID)
+		}
+	}
+	if change.ToStoreID != 0 {
+		newPeer = &metapb.Peer{StoreId: change.ToStoreID, Role: oldPeer.GetRole()}
+		switch change.Role {
+		case "":
+		case "voter":
+			newPeer.Role = metapb.PeerRole_Voter
+		case "learner":
+			newPeer.Role = metapb.PeerRole_Learner
+		default:
+			return nil, errors.Errorf("invalid peer role %s", change.Role)
+		}
+	}
+
+	result := &PeerChangePrecheck{Pass: true}
+	regionStatus := plan.NewStatus(plan.StatusOK)
+	if !filter.IsRegionHealthy(region) {
+		regionStatus = plan.NewStatus(plan.StatusRegionUnhealthy, "the region has down or pending peers")
+	}
+	result.add(PrecheckRegionHealth, regionStatus)
+	if newPeer != nil {
+		storeStatus, useStatus := precheckTargetStore(c, region, newPeer.GetStoreId())
+		result.add(PrecheckStoreState, storeStatus)
+		result.add(PrecheckSpecialUse, useStatus)
+	}
+	result.add(PrecheckRuleFit, precheckRuleFit(c, region, oldPeer, newPeer))
+	result.add(PrecheckLabelProperty, precheckLabelProperty(c, region, oldPeer, newPeer))
+
+	var op *operator.Operator
+	switch {
+	case oldPeer != nil && newPeer != nil:
+		op, err = operator.CreateMovePeerOperator("admin-move-peer", c, region, operator.OpAdmin, oldPeer.GetStoreId(), newPeer)
+	case newPeer != nil:
+		op, err = operator.CreateAddPeerOperator("admin-add-peer", c, region, newPeer, operator.OpAdmin)
+	default:
+		op, err = operator.CreateRemovePeerOperator("admin-remove-peer", c, operator.OpAdmin, region, oldPeer.GetStoreId())
+	}
+	if err != nil {
+		result.add(PrecheckOperator, plan.NewStatus(plan.StatusNoNeed, err.Error()))
+		return result, nil
+	}
+	result.add(PrecheckOperator, plan.NewStatus(plan.StatusOK))
+	limitStatus := plan.NewStatus(plan.StatusOK)
+	if c.GetOperatorController().ExceedStoreLimit(op) {
+		limitStatus = plan.NewStatus(plan.StatusStoreThrottled, "the store limit is exceeded")
+	}
+	result.add(PrecheckStoreLimit, limitStatus)
+	return result, nil
+}
+
+// precheckTargetStore checks the state and the special use of the target store.
+func precheckTargetStore(c *cluster.RaftCluster, region *core.RegionInfo, storeID uint64) (state plan.Status, use plan.Status) {
+	store := c.GetStore(storeID)
+	if store == nil {
+		notExisted := plan.NewStatus(plan.StatusStoreNotExisted, fmt.Sprintf("store %d not found", storeID))
+		return notExisted, notExisted
+	}
+	if region.GetStorePeer(storeID) != nil {
+		state = plan.NewStatus(plan.StatusStoreExcluded, fmt.Sprintf("region already has peer in store %d", storeID))
+	} else {
+		state = (&filter.StoreStateFilter{ActionScope: precheckScope, MoveRegion: true}).Target(c.GetOpts(), store)
+	}
+	use = filter.NewSpecialUseFilter(precheckScope).Target(c.GetOpts(), store)
+	return state, use
+}
+
+// precheckRuleFit checks the placement of the region does not become worse after the change.
+func precheckRuleFit(c *cluster.RaftCluster, region *core.RegionInfo, oldPeer, newPeer *metapb.Peer) plan.Status {
+	opts := c.GetOpts()
+	for _, peer := range []*metapb.Peer{oldPeer, newPeer} {
+		if peer != nil && c.GetStore(peer.GetStoreId()) == nil {
+			return plan.NewStatus(plan.StatusStoreNotExisted, fmt.Sprintf("store %d not found", peer.GetStoreId()))
+		}
+	}
+	if oldPeer != nil && newPeer != nil {
+		source := c.GetStore(oldPeer.GetStoreId())
+		safeguard := filter.NewPlacementSafeguard(precheckScope, opts, c.GetBasicCluster(), c.GetRuleManager(), region, source)
+		return safeguard.Target(opts, c.GetStore(newPeer.GetStoreId()))
+	}
+
+	var changed *core.RegionInfo
+	if newPeer != nil {
+		changed = region.Clone(core.WithAddPeer(newPeer))
+	} else {
+		changed = region.Clone(core.WithRemoveStorePeer(oldPeer.GetStoreId()))
+	}
+	if opts.IsPlacementRulesEnabled() {
+		ruleManager := c.GetRuleManager()
+		oldFit := ruleManager.FitRegionWithoutCache(c.GetBasicCluster(), region)
+		newFit := ruleManager.FitRegionWithoutCache(c.GetBasicCluster(), changed)
+		if placement.CompareRegionFit(oldFit, newFit) > 0 {
+			return plan.NewStatus(plan.StatusRuleNotMatch, "the placement rules become less satisfied")
+		}
+		return plan.NewStatus(plan.StatusOK)
+	}
+	voters := len(changed.GetVoters())
+	maxReplicas := opts.GetMaxReplicas()
+	switch {
+	case newPeer != nil && newPeer.GetRole() != metapb.PeerRole_Learner && voters > maxReplicas:
+		return plan.NewStatus(plan.StatusRuleNotMatch, fmt.Sprintf("the region will have %d voters more than max replicas %d", voters, maxReplicas))
+	case oldPeer != nil && oldPeer.GetRole() != metapb.PeerRole_Learner && voters < maxReplicas:
+		return plan.NewStatus(plan.StatusRegionNotReplicated, fmt.Sprintf("the region will have %d voters less than max replicas %d", voters, maxReplicas))
+	}
+	return plan.NewStatus(plan.StatusOK)
+}
+
+// precheckLabelProperty checks the leader can be transferred to a store not rejecting
+// leaders if the peer of the leader is removed.
+func precheckLabelProperty(c *cluster.RaftCluster, region *core.RegionInfo, oldPeer, newPeer *metapb.Peer) plan.Status {
+	if oldPeer == nil || oldPeer.GetId() != region.GetLeader().GetId() {
+		return plan.NewStatus(plan.StatusOK)
+	}
+	candidates := append([]*metapb.Peer(nil), region.GetVoters()...)
+	if newPeer != nil && newPeer.GetRole() != metapb.PeerRole_Learner {
+		candidates = append(candidates, newPeer)
+	}
+	for _, peer := range candidates {
+		if peer.GetStoreId() == oldPeer.GetStoreId() {
+			continue
+		}
+		store := c.GetStore(peer.GetStoreId())
+		if store != nil && !c.GetOpts().CheckLabelProperty(config.RejectLeader, store.GetLabels()) {
+			return plan.NewStatus(plan.StatusOK)
+		}
+	}
+	return plan.NewStatus(plan.StatusStoreBlocked, "no store is available to take over the leader")
+}
@@ -0,0 +1,310 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/storage/endpoint"
+)
+
+// The types of the parameters of the operator templates.
+const (
+	OperatorTemplateParamUint64     = "uint64"
+	OperatorTemplateParamString     = "string"
+	OperatorTemplateParamUint64List = "uint64-list"
+)
+
+var (
+	operatorTemplateNamePattern  = regexp.MustCompile(`^[a-z0-9][a-z0-9-]*$`)
+	operatorTemplateParamPattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+	operatorTemplatePlaceholder  = regexp.MustCompile(`\{([a-z0-9_-]+)\}`)
+)
+
+// builtInOperatorTemplates returns the templates of the common manual
+// interventions, which cannot be modified or deleted.
+func builtInOperatorTemplates() []*endpoint.OperatorTemplate {
+	regionParam := endpoint.OperatorTemplateParam{Name: "region_id", Type: OperatorTemplateParamUint64, Required: true}
+	storeParam := endpoint.OperatorTemplateParam{Name: "store_id", Type: OperatorTemplateParamUint64, Required: true, Description: "The store to move the peer off."}
+	return []*endpoint.OperatorTemplate{
+		{
+			Name:        "move-region-off-store",
+			Description: "Move the peer of a region off a store, the target store is selected without breaking the placement rules.",
+			Operator:    "evict-peer",
+			Params:      []endpoint.OperatorTemplateParam{regionParam, storeParam},
+			Args:        map[string]interface{}{"region_id": "{region_id}", "from_store_id": "{store_id}"},
+			BuiltIn:     true,
+		},
+		{
+			Name:        "rebuild-replica-on-label",
+			Description: "Rebuild the peer of a region on a store with the given label, the target store is selected without breaking the placement rules.",
+			Operator:    "evict-peer",
+			Params: []endpoint.OperatorTemplateParam{
+				regionParam,
+				storeParam,
+				{Name: "label_key", Type: OperatorTemplateParamString, Required: true},
+				{Name: "label_value", Type: OperatorTemplateParamString, Required: true},
+			},
+			Args: map[string]interface{}{
+				"region_id":      "{region_id}",
+				"from_store_id":  "{store_id}",
+				"to_label_key":   "{label_key}",
+				"to_label_value": "{label_value}",
+			},
+			BuiltIn: true,
+		},
+	}
+}
+
+// GetOperatorTemplates returns all operator templates ordered by name.
+func (h *Handler) GetOperatorTemplates() ([]*endpoint.OperatorTemplate, error) {
+	templates, err := h.s.storage.LoadOperatorTemplates()
+	if err != nil {
+		return nil, err
+	}
+	templates = append(templates, builtInOperatorTemplates()...)
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// GetOperatorTemplate returns the operator template with the given name.
+func (h *Handler) GetOperatorTemplate(name string) (*endpoint.OperatorTemplate, error) {
+	templates, err := h.GetOperatorTemplates()
+	if err != nil {
+		return nil, err
+	}
+	for _, template := range templates {
+		if template.Name == name {
+			return template, nil
+		}
+	}
+	return nil, errs.ErrOperatorTemplateNotFound.FastGenByArgs(name)
+}
+
+// SaveOperatorTemplate creates or replaces an operator template after it is
+// validated. The operator of the template is not checked here.
+func (h *Handler) SaveOperatorTemplate(template *endpoint.OperatorTemplate) error {
+	if err := validateOperatorTemplate(template); err != nil {
+		return err
+	}
+	return h.s.storage.SaveOperatorTemplate(template)
+}
+
+// DeleteOperatorTemplate deletes an operator template.
+func (h *Handler) DeleteOperatorTemplate(name string) error {
+	template, err := h.GetOperatorTemplate(name)
+	if err != nil {
+		return err
+	}
+	if template.BuiltIn {
+		return errs.ErrOperatorTemplateInvalid.FastGenByArgs(name, "the built-in template cannot be deleted")
+	}
+	return h.s.storage.DeleteOperatorTemplate(name)
+}
+
+// ExpandOperatorTemplate validates the parameters and expands the operator
+// template into the input of the operator creation API.
+func (h *Handler) ExpandOperatorTemplate(name string, params map[string]interface{}) (map[string]interface{}, error) {
+	template, err := h.GetOperatorTemplate(name)
+	if err != nil {
+		return nil, err
+	}
+	declared := make(map[string]struct{}, len(template.Params))
+	values := make(map[string]interface{}, len(template.Params))
+	for _, param := range template.Params {
+		declared[param.Name] = struct{}{}
+		value, ok := params[param.Name]
+		if !ok || value == nil {
+			if param.Default == nil {
+				if param.Required {
+					return nil, errs.ErrOperatorTemplateParam.FastGenByArgs(param.Name, name, "it is required")
+				}
+				continue
+			}
+			value = param.Default
+		}
+		if values[param.Name], err = normalizeOperatorTemplateParam(param.Type, value); err != nil {
+			return nil, errs.ErrOperatorTemplateParam.FastGenByArgs(param.Name, name, err.Error())
+		}
+	}
+	for param := range params {
+		if _, ok := declared[param]; !ok {
+			return nil, errs.ErrOperatorTemplateParam.FastGenByArgs(param, name, "it is not declared")
+		}
+	}
+	input, _ := expandOperatorTemplateValue(template.Args, values).(map[string]interface{})
+	if input == nil {
+		input = make(map[string]interface{})
+	}
+	input["name"] = template.Operator
+	return input, nil
+}
+
+func validateOperatorTemplate(template *endpoint.OperatorTemplate) error {
+	if !operatorTemplateNamePattern.MatchString(template.Name) {
+		return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, "the name should consist of lower case letters, digits and hyphens")
+	}
+	for _, builtIn := range builtInOperatorTemplates() {
+		if builtIn.Name == template.Name {
+			return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, "the built-in template cannot be modified")
+		}
+	}
+	if template.BuiltIn {
+		return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, "the template cannot be marked as built-in")
+	}
+	if template.Operator == "" {
+		return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, "the operator is missing")
+	}
+	if _, ok := template.Args["name"]; ok {
+		return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, "the operator should be set by the operator field instead of the name argument")
+	}
+	declared := make(map[string]struct{}, len(template.Params))
+	for _, param := range template.Params {
+		if !operatorTemplateParamPattern.MatchString(param.Name) {
+			return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, fmt.Sprintf("invalid parameter name %q", param.Name))
+		}
+		if _, ok := declared[param.Name]; ok {
+			return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, fmt.Sprintf("duplicated parameter %s", param.Name))
+		}
+		declared[param.Name] = struct{}{}
+		switch param.Type {
+		case OperatorTemplateParamUint64, OperatorTemplateParamString, OperatorTemplateParamUint64List:
+		default:
+			return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, fmt.Sprintf("unknown type %q of parameter %s", param.Type, param.Name))
+		}
+		if param.Default != nil {
+			if _, err := normalizeOperatorTemplateParam(param.Type, param.Default); err != nil {
+				return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, fmt.Sprintf("invalid default value of parameter %s, %v", param.Name, err))
+			}
+		}
+	}
+	var undeclared []string
+	walkOperatorTemplateStrings(template.Args, func(s string) {
+		for _, match := range operatorTemplatePlaceholder.FindAllStringSubmatch(s, -1) {
+			if _, ok := declared[match[1]]; !ok {
+				undeclared = append(undeclared, match[1])
+			}
+		}
+	})
+	if len(undeclared) > 0 {
+		return errs.ErrOperatorTemplateInvalid.FastGenByArgs(template.Name, fmt.Sprintf("undeclared parameters %s", strings.Join(undeclared, ", ")))
+	}
+	return nil
+}
+
+// normalizeOperatorTemplateParam checks the value of a parameter and converts
+// it into the form decoded from JSON, which is expected by the operator
+// creation API.
+func normalizeOperatorTemplateParam(typ string, value interface{}) (interface{}, error) {
+	switch typ {
+	case OperatorTemplateParamUint64:
+		return normalizeUint64Param(value)
+	case OperatorTemplateParamString:
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("%v is not a string", value)
+		}
+		return s, nil
+	case OperatorTemplateParamUint64List:
+		list, ok := value.([]interface{})
+		if !ok {
+			return nil, fmt.Errorf("%v is not a list", value)
+		}
+		result := make([]interface{}, 0, len(list))
+		for _, item := range list {
+			v, err := normalizeUint64Param(item)
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, v)
+		}
+		return result, nil
+	}
+	return nil, fmt.Errorf("unknown type %q", typ)
+}
+
+func normalizeUint64Param(value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case float64:
+		if v >= 0 && v == math.Trunc(v) {
+			return v, nil
+		}
+	case string:
+		if n, err := strconv.ParseUint(v, 10, 64); err == nil {
+			return float64(n), nil
+		}
+	}
+	return nil, fmt.Errorf("%v is not an unsigned integer", value)
+}
+
+// expandOperatorTemplateValue replaces the placeholders in the value. A string
+// which is exactly a placeholder is replaced with the value of the parameter,
+// keeping its type, and it is dropped if the parameter is not given.
+func expandOperatorTemplateValue(value interface{}, params map[string]interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if match := operatorTemplatePlaceholder.FindStringSubmatch(v); match != nil && match[0] == v {
+			return params[match[1]]
+		}
+		return operatorTemplatePlaceholder.ReplaceAllStringFunc(v, func(placeholder string) string {
+			param, ok := params[placeholder[1:len(placeholder)-1]]
+			if !ok {
+				return ""
+			}
+			if f, ok := param.(float64); ok {
+				return strconv.FormatUint(uint64(f), 10)
+			}
+			return fmt.Sprint(param)
+		})
+	case []interface{}:
+		result := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			if expanded := expandOperatorTemplateValue(item, params); expanded != nil {
+				result = append(result, expanded)
+			}
+		}
+		return result
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for key, item := range v {
+			if expanded := expandOperatorTemplateValue(item, params); expanded != nil {
+				result[key] = expanded
+			}
+		}
+		return result
+	}
+	return value
+}
+
+func walkOperatorTemplateStrings(value interface{}, f func(string)) {
+	switch v := value.(type) {
+	case string:
+		f(v)
+	case []interface{}:
+		for _, item := range v {
+			walkOperatorTemplateStrings(item, f)
+		}
+	case map[string]interface{}:
+		for _, item := range v {
+			walkOperatorTemplateStrings(item, f)
+		}
+	}
+}
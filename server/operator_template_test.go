@@ -0,0 +1,104 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/storage"
+	"github.com/tikv/pd/server/storage/endpoint"
+)
+
+func TestOperatorTemplate(t *testing.T) {
+	re := require.New(t)
+	h := &Handler{s: &Server{storage: storage.NewStorageWithMemoryBackend()}}
+
+	templates, err := h.GetOperatorTemplates()
+	re.NoError(err)
+	re.Len(templates, 2)
+	re.Equal("move-region-off-store", templates[0].Name)
+	re.Equal("rebuild-replica-on-label", templates[1].Name)
+
+	input, err := h.ExpandOperatorTemplate("rebuild-replica-on-label", map[string]interface{}{
+		"region_id":   float64(1),
+		"store_id":    "2",
+		"label_key":   "zone",
+		"label_value": "z1",
+	})
+	re.NoError(err)
+	re.Equal(map[string]interface{}{
+		"name":           "evict-peer",
+		"region_id":      float64(1),
+		"from_store_id":  float64(2),
+		"to_label_key":   "zone",
+		"to_label_value": "z1",
+	}, input)
+	// The required parameter is missing.
+	_, err = h.ExpandOperatorTemplate("move-region-off-store", map[string]interface{}{"region_id": float64(1)})
+	re.True(errs.ErrOperatorTemplateParam.Equal(err))
+	// The parameter is not an unsigned integer.
+	_, err = h.ExpandOperatorTemplate("move-region-off-store", map[string]interface{}{"region_id": float64(1), "store_id": -1.0})
+	re.True(errs.ErrOperatorTemplateParam.Equal(err))
+	// The parameter is not declared.
+	_, err = h.ExpandOperatorTemplate("move-region-off-store", map[string]interface{}{"region_id": float64(1), "store_id": float64(2), "x": "y"})
+	re.True(errs.ErrOperatorTemplateParam.Equal(err))
+	_, err = h.ExpandOperatorTemplate("unknown", nil)
+	re.True(errs.ErrOperatorTemplateNotFound.Equal(err))
+
+	template := &endpoint.OperatorTemplate{
+		Name:     "scatter-table",
+		Operator: "scatter-regions",
+		Params: []endpoint.OperatorTemplateParam{
+			{Name: "regions", Type: OperatorTemplateParamUint64List, Required: true},
+			{Name: "table", Type: OperatorTemplateParamString, Default: "t"},
+			{Name: "limit", Type: OperatorTemplateParamUint64},
+		},
+		Args: map[string]interface{}{
+			"region_ids":  "{regions}",
+			"group":       "table-{table}",
+			"retry_limit": "{limit}",
+		},
+	}
+	re.NoError(h.SaveOperatorTemplate(template))
+	input, err = h.ExpandOperatorTemplate("scatter-table", map[string]interface{}{"regions": []interface{}{float64(1), "2"}})
+	re.NoError(err)
+	re.Equal(map[string]interface{}{
+		"name":       "scatter-regions",
+		"region_ids": []interface{}{float64(1), float64(2)},
+		"group":      "table-t",
+	}, input)
+	templates, err = h.GetOperatorTemplates()
+	re.NoError(err)
+	re.Len(templates, 3)
+	re.Equal("scatter-table", templates[2].Name)
+
+	// Invalid templates.
+	for _, invalid := range []*endpoint.OperatorTemplate{
+		{Name: "Upper", Operator: "add-peer"},
+		{Name: "move-region-off-store", Operator: "add-peer"},
+		{Name: "no-operator"},
+		{Name: "bad-type", Operator: "add-peer", Params: []endpoint.OperatorTemplateParam{{Name: "a", Type: "int"}}},
+		{Name: "bad-default", Operator: "add-peer", Params: []endpoint.OperatorTemplateParam{{Name: "a", Type: OperatorTemplateParamUint64, Default: "x"}}},
+		{Name: "undeclared", Operator: "add-peer", Args: map[string]interface{}{"region_id": "{region}"}},
+	} {
+		re.True(errs.ErrOperatorTemplateInvalid.Equal(h.SaveOperatorTemplate(invalid)), invalid.Name)
+	}
+
+	re.True(errs.ErrOperatorTemplateInvalid.Equal(h.DeleteOperatorTemplate("move-region-off-store")))
+	re.NoError(h.DeleteOperatorTemplate("scatter-table"))
+	re.True(errs.ErrOperatorTemplateNotFound.Equal(h.DeleteOperatorTemplate("scatter-table")))
+}
@@ -0,0 +1,294 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+)
+
+const (
+	// MaxPersistFileSize is the size limit of a file replicated to the members.
+	MaxPersistFileSize = 1 << 20
+	// PersistFileContentType is the only content type accepted by the members
+	// when a file is replicated to them.
+	PersistFileContentType = "application/json"
+)
+
+// PersistedFile is a file persisted in the data directory of a member. The
+// version increases every time the file is persisted since the member starts,
+// it is 0 if the file is only persisted before the member starts.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type PersistedFile struct {
+	Name       string    `json:"name"`
+	Size       int       `json:"size"`
+	Checksum   string    `json:"checksum"`
+	Version    uint64    `json:"version"`
+	UpdateTime time.Time `json:"update_time"`
+}
+
+// FileReplica is the state of a replicated file on a member.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type FileReplica struct {
+	MemberID   uint64 `json:"member_id"`
+	MemberName string `json:"member_name"`
+	// ReplicatedChecksum is the checksum of the data sent to the member by
+	// the current member last time, empty if it is never sent.
+	ReplicatedChecksum string    `json:"replicated_checksum,omitempty"`
+	ReplicateTime      time.Time `json:"replicate_time,omitempty"`
+	ReplicateError     string    `json:"replicate_error,omitempty"`
+	// Checksum and Version are reported by the member, the checksum is empty
+	// if the file does not exist on the member.
+	Checksum string `json:"checksum,omitempty"`
+	Version  uint64 `json:"version,omitempty"`
+	Diverged bool   `json:"diverged"`
+	// Error is set if the files of the member cannot be listed.
+	Error string `json:"error,omitempty"`
+}
+
+// ReplicatedFile shows whether the replicas of a file on the members diverge.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type ReplicatedFile struct {
+	Name string `json:"name"`
+	// Checksum is the expected checksum of the file, which is the checksum of
+	// the data replicated by the current member last time, or the checksum
+	// held by the most members if the current member never replicates it.
+	Checksum string        `json:"checksum"`
+	Diverged bool          `json:"diverged"`
+	Replicas []FileReplica `json:"replicas"`
+}
+
+type memberReplicateRecord struct {
+	name     string
+	checksum string
+	time     time.Time
+	err      string
+}
+
+// persistFileRecords records the versions of the files persisted by the current
+// member, and the files replicated to the members by it.
+type persistFileRecords struct {
+	syncutil.Mutex
+	versions map[string]uint64
+	// replicated is the checksum of the data last replicated for each file.
+	replicated map[string]string
+	members    map[string]map[uint64]*memberReplicateRecord
+}
+
+func (r *persistFileRecords) recordPersisted(name string) {
+	r.Lock()
+	defer r.Unlock()
+	if r.versions == nil {
+		r.versions = make(map[string]uint64)
+	}
+	r.versions[name]++
+}
+
+func (r *persistFileRecords) recordReplicated(name string, member *pdpb.Member, checksum string, err error) {
+	r.Lock()
+	defer r.Unlock()
+	if r.replicated == nil {
+		r.replicated = make(map[string]string)
+		r.members = make(map[string]map[uint64]*memberReplicateRecord)
+	}
+	r.replicated[name] = checksum
+	members, ok := r.members[name]
+	if !ok {
+		members = make(map[uint64]*memberReplicateRecord)
+		r.members[name] = members
+	}
+	record := &memberReplicateRecord{name: member.GetName(), checksum: checksum, time: time.Now()}
+	if err != nil {
+		record.err = err.Error()
+	}
+	members[member.GetMemberId()] = record
+}
+
+func (r *persistFileRecords) getVersion(name string) uint64 {
+	r.Lock()
+	defer r.Unlock()
+	return r.versions[name]
+}
+
+// listPersistedFiles lists the files persisted in the directory, which are the
+// regular files in JSON format within the size limit. The other files and the
+// subdirectories, e.g. the ones of etcd, are skipped.
+func listPersistedFiles(dir string, version func(name string) uint64) ([]PersistedFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errs.ErrListPersistFiles.Wrap(err).GenWithStackByCause()
+	}
+	files := make([]PersistedFile, 0, len(entries))
+	for _, entry := range entries {
+		if !entry.Type().IsRegular() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil || info.Size() > MaxPersistFileSize {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(dir, entry.Name()))
+		if err != nil || !json.Valid(data) {
+			continue
+		}
+		files = append(files, PersistedFile{
+			Name:       entry.Name(),
+			Size:       len(data),
+			Checksum:   fileChecksum(data),
+			Version:    version(entry.Name()),
+			UpdateTime: info.ModTime(),
+		})
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].Name < files[j].Name })
+	return files, nil
+}
+
+func fileChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// ValidatePersistFile checks the name and the size of a file to be persisted.
+// The name should be a plain file name, so that the file is always inside the
+// data directory.
+func ValidatePersistFile(name string, data []byte) error {
+	if name == "" || name == "." || name == ".." || filepath.Base(name) != name {
+		return errs.ErrPersistFileName.FastGenByArgs(name)
+	}
+	if len(data) > MaxPersistFileSize {
+		return errs.ErrPersistFileTooLarge.FastGenByArgs(len(data), MaxPersistFileSize)
+	}
+	return nil
+}
+
+// GetPersistedFiles returns the files persisted in the data directory of the
+// current member, including the ones persisted before it starts.
+func (s *Server) GetPersistedFiles() ([]PersistedFile, error) {
+	return listPersistedFiles(s.GetConfig().DataDir, s.persistFiles.getVersion)
+}
+
+// getMemberPersistedFiles lists the files persisted by a member.
+func (s *Server) getMemberPersistedFiles(ctx context.Context, member *pdpb.Member) ([]PersistedFile, error) {
+	if member.GetMemberId() == s.member.ID() {
+		return s.GetPersistedFiles()
+	}
+	clientUrls := member.GetClientUrls()
+	if len(clientUrls) == 0 {
+		return nil, errs.ErrClientURLEmpty.FastGenByArgs()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, clientUrls[0]+"/pd/api/v1/admin/persist-files", nil)
+	if err != nil {
+		return nil, errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	req.Header.Set("PD-Allow-follower-handle", "true")
+	res, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, errs.ErrSendRequest.Wrap(err).GenWithStackByCause()
+	}
+	defer res.Body.Close()
+	if res.StatusCode != http.StatusOK {
+		return nil, errs.ErrSendRequest.FastGenByArgs()
+	}
+	var files []PersistedFile
+	if err := json.NewDecoder(res.Body).Decode(&files); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return files, nil
+}
+
+// CheckPersistFileDivergence lists the files persisted by all members, and
+// checks whether the replicas of each file diverge.
+func (s *Server) CheckPersistFileDivergence(ctx context.Context) ([]ReplicatedFile, error) {
+	members, err := s.GetMembers()
+	if err != nil {
+		return nil, err
+	}
+	memberFiles := make([]map[string]PersistedFile, len(members))
+	listErrors := make([]error, len(members))
+	names := make(map[string]struct{})
+	for i, member := range members {
+		files, err := s.getMemberPersistedFiles(ctx, member)
+		if err != nil {
+			listErrors[i] = err
+			continue
+		}
+		memberFiles[i] = make(map[string]PersistedFile, len(files))
+		for _, file := range files {
+			memberFiles[i][file.Name] = file
+			names[file.Name] = struct{}{}
+		}
+	}
+
+	s.persistFiles.Lock()
+	defer s.persistFiles.Unlock()
+	for name := range s.persistFiles.replicated {
+		names[name] = struct{}{}
+	}
+	result := make([]ReplicatedFile, 0, len(names))
+	for name := range names {
+		file := ReplicatedFile{Name: name, Checksum: s.persistFiles.replicated[name]}
+		if file.Checksum == "" {
+			file.Checksum = mostCommonChecksum(name, memberFiles)
+		}
+		for i, member := range members {
+			replica := FileReplica{MemberID: member.GetMemberId(), MemberName: member.GetName()}
+			if record, ok := s.persistFiles.members[name][member.GetMemberId()]; ok {
+				replica.ReplicatedChecksum = record.checksum
+				replica.ReplicateTime = record.time
+				replica.ReplicateError = record.err
+			}
+			if listErrors[i] != nil {
+				replica.Error = listErrors[i].Error()
+			} else {
+				persisted := memberFiles[i][name]
+				replica.Checksum, replica.Version = persisted.Checksum, persisted.Version
+				replica.Diverged = replica.Checksum != file.Checksum
+			}
+			file.Diverged = file.Diverged || replica.Diverged
+			file.Replicas = append(file.Replicas, replica)
+		}
+		result = append(result, file)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+// mostCommonChecksum returns the checksum of the file held by the most members.
+func mostCommonChecksum(name string, memberFiles []map[string]PersistedFile) string {
+	counts := make(map[string]int)
+	var checksum string
+	for _, files := range memberFiles {
+		file, ok := files[name]
+		if !ok {
+			continue
+		}
+		counts[file.Checksum]++
+		if c := counts[file.Checksum]; c > counts[checksum] || (c == counts[checksum] && file.Checksum < checksum) {
+			checksum = file.Checksum
+		}
+	}
+	return checksum
+}
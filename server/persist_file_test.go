@@ -0,0 +1,48 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestListPersistedFiles(t *testing.T) {
+	re := require.New(t)
+	dir := t.TempDir()
+	re.NoError(os.WriteFile(filepath.Join(dir, "DR_STATE"), []byte(`{"state":"sync"}`), 0600))
+	re.NoError(os.WriteFile(filepath.Join(dir, "join"), []byte("pd1=http://127.0.0.1:2380"), 0600))
+	re.NoError(os.Mkdir(filepath.Join(dir, "member"), 0700))
+
+	// The files persisted before the member starts are listed with the version 0.
+	records := &persistFileRecords{}
+	files, err := listPersistedFiles(dir, records.getVersion)
+	re.NoError(err)
+	re.Len(files, 1)
+	re.Equal("DR_STATE", files[0].Name)
+	re.Equal(fileChecksum([]byte(`{"state":"sync"}`)), files[0].Checksum)
+	re.Zero(files[0].Version)
+
+	records.recordPersisted("DR_STATE")
+	files, err = listPersistedFiles(dir, records.getVersion)
+	re.NoError(err)
+	re.Equal(uint64(1), files[0].Version)
+
+	_, err = listPersistedFiles(filepath.Join(dir, "missing"), records.getVersion)
+	re.Error(err)
+}
@@ -0,0 +1,245 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/tikv/pd/pkg/grpcutil"
+	"github.com/tikv/pd/pkg/regionquerypb"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/statistics"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+const (
+	defaultRegionQueryBatchSize = 1024
+	maxRegionQueryBatchSize     = 10240
+)
+
+// regionCheckTypes maps the check types to the region statistic types, which
+// are the same as the paths of the `/regions/check` HTTP API.
+var regionCheckTypes = map[string]statistics.RegionStatisticType{
+	"miss-peer":          statistics.MissPeer,
+	"extra-peer":         statistics.ExtraPeer,
+	"pending-peer":       statistics.PendingPeer,
+	"down-peer":          statistics.DownPeer,
+	"learner-peer":       statistics.LearnerPeer,
+	"empty-region":       statistics.EmptyRegion,
+	"offline-peer":       statistics.OfflinePeer,
+	"oversized-region":   statistics.OversizedRegion,
+	"undersized-region":  statistics.UndersizedRegion,
+	"degraded-region":    statistics.DegradedRegion,
+	"at-risk-region":     statistics.AtRiskRegion,
+	"unavailable-region": statistics.UnavailableRegion,
+}
+
+// RegionQueryServer wraps Server to provide the region query service, which
+// mirrors the common region queries of the HTTP API with streaming responses.
+type RegionQueryServer struct {
+	*Server
+}
+
+// GetRegionByKey implements gRPC RegionQueryServer.
+func (s *RegionQueryServer) GetRegionByKey(request *regionquerypb.RegionQueryRequest, stream regionquerypb.RegionQuery_GetRegionByKeyServer) error {
+	return s.query(request, stream, func(rc *cluster.RaftCluster) ([]*core.RegionInfo, error) {
+		if region := rc.GetCachedRegionByKey(request.GetKey()); region != nil {
+			return []*core.RegionInfo{region}, nil
+		}
+		return nil, nil
+	})
+}
+
+// ScanRegions implements gRPC RegionQueryServer.
+func (s *RegionQueryServer) ScanRegions(request *regionquerypb.RegionQueryRequest, stream regionquerypb.RegionQuery_ScanRegionsServer) error {
+	return s.query(request, stream, func(rc *cluster.RaftCluster) ([]*core.RegionInfo, error) {
+		limit := int(request.GetLimit())
+		if limit <= 0 {
+			limit = -1
+		}
+		return rc.ScanRegions(request.GetKey(), request.GetEndKey(), limit), nil
+	})
+}
+
+// GetStoreRegions implements gRPC RegionQueryServer.
+func (s *RegionQueryServer) GetStoreRegions(request *regionquerypb.RegionQueryRequest, stream regionquerypb.RegionQuery_GetStoreRegionsServer) error {
+	return s.query(request, stream, func(rc *cluster.RaftCluster) ([]*core.RegionInfo, error) {
+		storeID := request.GetStoreId()
+		if rc.GetStore(storeID) == nil {
+			return nil, status.Errorf(codes.NotFound, "store %d not found", storeID)
+		}
+		return rc.GetStoreRegions(storeID), nil
+	})
+}
+
+// GetCheckRegions implements gRPC RegionQueryServer.
+func (s *RegionQueryServer) GetCheckRegions(request *regionquerypb.RegionQueryRequest, stream regionquerypb.RegionQuery_GetCheckRegionsServer) error {
+	return s.query(request, stream, func(rc *cluster.RaftCluster) ([]*core.RegionInfo, error) {
+		typ, ok := regionCheckTypes[request.GetCheckType()]
+		if !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "unknown check type %q", request.GetCheckType())
+		}
+		if typ == statistics.OfflinePeer {
+			return rc.GetOfflineRegionStatsByType(typ), nil
+		}
+		return rc.GetRegionStatsByType(typ), nil
+	})
+}
+
+// regionQueryStream is the server side stream shared by the region queries.
+type regionQueryStream interface {
+	Send(*regionquerypb.RegionQueryResponse) error
+	grpc.ServerStream
+}
+
+// query gets the regions with f and streams them in batches.
+func (s *RegionQueryServer) query(request *regionquerypb.RegionQueryRequest, stream regionQueryStream,
+	f func(rc *cluster.RaftCluster) ([]*core.RegionInfo, error)) error {
+	grpcServer := &GrpcServer{Server: s.Server}
+	if err := grpcServer.validateRequest(request.GetHeader()); err != nil {
+		return err
+	}
+	cs, err := grpcServer.acquireStream(stream.Context(), grpcutil.ClientClassTool)
+	if err != nil {
+		return err
+	}
+	defer cs.release()
+	mask, err := newRegionFieldMask(request.GetFieldMask())
+	if err != nil {
+		return err
+	}
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return stream.Send(&regionquerypb.RegionQueryResponse{Header: grpcServer.notBootstrappedHeader()})
+	}
+	regions, err := f(rc)
+	if err != nil {
+		return err
+	}
+	if limit := int(request.GetLimit()); limit > 0 && len(regions) > limit {
+		regions = regions[:limit]
+	}
+
+	batchSize := int(request.GetBatchSize())
+	if batchSize <= 0 {
+		batchSize = defaultRegionQueryBatchSize
+	}
+	if batchSize > maxRegionQueryBatchSize {
+		batchSize = maxRegionQueryBatchSize
+	}
+	// Always send at least one response, so that the client can get the header.
+	for start := 0; start == 0 || start < len(regions); start += batchSize {
+		end := start + batchSize
+		if end > len(regions) {
+			end = len(regions)
+		}
+		resp := &regionquerypb.RegionQueryResponse{
+			Header:  grpcServer.header(),
+			Regions: make([]*pdpb.Region, 0, end-start),
+		}
+		for _, region := range regions[start:end] {
+			resp.Regions = append(resp.Regions, mask.apply(region))
+		}
+		if err := stream.Send(resp); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// regionFieldMask selects the fields of pdpb.Region. An empty mask selects all the fields.
+type regionFieldMask map[string]struct{}
+
+// regionFieldMaskPaths are the valid paths of the field mask.
+var regionFieldMaskPaths = map[string]struct{}{
+	"region":                 {},
+	"region.id":              {},
+	"region.start_key":       {},
+	"region.end_key":         {},
+	"region.region_epoch":    {},
+	"region.peers":           {},
+	"region.encryption_meta": {},
+	"leader":                 {},
+	"down_peers":             {},
+	"pending_peers":          {},
+	"buckets":                {},
+}
+
+func newRegionFieldMask(paths []string) (regionFieldMask, error) {
+	mask := make(regionFieldMask, len(paths))
+	for _, path := range paths {
+		if _, ok := regionFieldMaskPaths[path]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid field mask path %q", path)
+		}
+		mask[path] = struct{}{}
+	}
+	return mask, nil
+}
+
+func (m regionFieldMask) has(path string) bool {
+	if len(m) == 0 {
+		return true
+	}
+	_, ok := m[path]
+	return ok
+}
+
+func (m regionFieldMask) apply(region *core.RegionInfo) *pdpb.Region {
+	resp := &pdpb.Region{}
+	if m.has("region") {
+		resp.Region = region.GetMeta()
+	} else {
+		meta := region.GetMeta()
+		sub := &metapb.Region{}
+		selected := false
+		if m.has("region.id") {
+			sub.Id, selected = meta.GetId(), true
+		}
+		if m.has("region.start_key") {
+			sub.StartKey, selected = meta.GetStartKey(), true
+		}
+		if m.has("region.end_key") {
+			sub.EndKey, selected = meta.GetEndKey(), true
+		}
+		if m.has("region.region_epoch") {
+			sub.RegionEpoch, selected = meta.GetRegionEpoch(), true
+		}
+		if m.has("region.peers") {
+			sub.Peers, selected = meta.GetPeers(), true
+		}
+		if m.has("region.encryption_meta") {
+			sub.EncryptionMeta, selected = meta.GetEncryptionMeta(), true
+		}
+		if selected {
+			resp.Region = sub
+		}
+	}
+	if m.has("leader") {
+		resp.Leader = region.GetLeader()
+	}
+	if m.has("down_peers") {
+		resp.DownPeers = region.GetDownPeers()
+	}
+	if m.has("pending_peers") {
+		resp.PendingPeers = region.GetPendingPeers()
+	}
+	if m.has("buckets") {
+		resp.Buckets = region.GetBuckets()
+	}
+	return resp
+}
@@ -0,0 +1,168 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/logutil"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/storage"
+	"go.uber.org/zap"
+)
+
+// RegionStorageMigrationStatus is the status of migrating the regions from the
+// etcd to the independent region storage.
+type RegionStorageMigrationStatus struct {
+	UseRegionStorage bool                               `json:"use-region-storage"`
+	DualWrite        bool                               `json:"dual-write"`
+	Verifying        bool                               `json:"verifying"`
+	LastVerification *storage.RegionStorageVerification `json:"last-verification,omitempty"`
+	LastVerifyTime   *time.Time                         `json:"last-verify-time,omitempty"`
+	LastVerifyError  string                             `json:"last-verify-error,omitempty"`
+}
+
+// regionStorageMigration keeps the state of the verification job.
+type regionStorageMigration struct {
+	syncutil.Mutex
+	verifying        bool
+	lastVerification *storage.RegionStorageVerification
+	lastVerifyTime   time.Time
+	lastVerifyError  error
+}
+
+func (m *regionStorageMigration) reset() {
+	m.Lock()
+	defer m.Unlock()
+	m.lastVerification = nil
+	m.lastVerifyTime = time.Time{}
+	m.lastVerifyError = nil
+}
+
+// GetRegionStorageMigrationStatus returns the status of the region storage migration.
+func (s *Server) GetRegionStorageMigrationStatus() *RegionStorageMigrationStatus {
+	status := &RegionStorageMigrationStatus{
+		UseRegionStorage: s.persistOptions.IsUseRegionStorage(),
+		DualWrite:        storage.IsRegionStorageDualWrite(s.storage),
+	}
+	m := &s.regionStorageMigration
+	m.Lock()
+	defer m.Unlock()
+	status.Verifying = m.verifying
+	status.LastVerification = m.lastVerification
+	if !m.lastVerifyTime.IsZero() {
+		t := m.lastVerifyTime
+		status.LastVerifyTime = &t
+	}
+	if m.lastVerifyError != nil {
+		status.LastVerifyError = m.lastVerifyError.Error()
+	}
+	return status
+}
+
+// SetRegionStorageDualWrite enables or disables the dual-write mode of the
+// region storage. It can only be enabled before switching to the independent
+// region storage.
+func (s *Server) SetRegionStorageDualWrite(enable bool) error {
+	cfg := s.GetPDServerConfig()
+	if enable && cfg.UseRegionStorage {
+		return errs.ErrRegionStorageMigration.FastGenByArgs("the independent region storage is already in use")
+	}
+	if cfg.RegionStorageDualWrite == enable {
+		return nil
+	}
+	if storage.TryGetLocalRegionStorage(s.storage) == nil {
+		return errs.ErrRegionStorageUnavailable.FastGenByArgs()
+	}
+	cfg.RegionStorageDualWrite = enable
+	if err := s.SetPDServerConfig(*cfg); err != nil {
+		return err
+	}
+	// The previous result is meaningless once the dual-write mode is changed.
+	s.regionStorageMigration.reset()
+	return nil
+}
+
+// StartRegionStorageVerification starts a background job to compare the
+// regions in the etcd with the ones in the independent region storage. If
+// repair is true, the independent region storage will be fixed.
+func (s *Server) StartRegionStorageVerification(repair bool) error {
+	if !storage.IsRegionStorageDualWrite(s.storage) {
+		return errs.ErrRegionStorageMigration.FastGenByArgs("the dual-write mode is not enabled")
+	}
+	m := &s.regionStorageMigration
+	m.Lock()
+	defer m.Unlock()
+	if m.verifying {
+		return errs.ErrRegionStorageMigration.FastGenByArgs("the verification is in progress")
+	}
+	m.verifying = true
+
+	ctx := s.serverLoopCtx
+	go func() {
+		defer logutil.LogPanic()
+		start := time.Now()
+		result, err := storage.VerifyRegionStorage(ctx, s.storage, repair)
+		if err != nil {
+			log.Error("failed to verify the region storage", errs.ZapError(err))
+		} else {
+			log.Info("region storage is verified",
+				zap.Bool("repair", repair),
+				zap.Reflect("result", result),
+				zap.Duration("cost", time.Since(start)))
+		}
+		m.Lock()
+		defer m.Unlock()
+		m.verifying = false
+		m.lastVerification, m.lastVerifyError = result, err
+		m.lastVerifyTime = time.Now()
+	}()
+	return nil
+}
+
+// CutoverRegionStorage switches to the independent region storage and turns
+// off the dual-write mode. It requires the last verification to be consistent.
+func (s *Server) CutoverRegionStorage() error {
+	if !storage.IsRegionStorageDualWrite(s.storage) {
+		return errs.ErrRegionStorageMigration.FastGenByArgs("the dual-write mode is not enabled")
+	}
+	m := &s.regionStorageMigration
+	m.Lock()
+	defer m.Unlock()
+	if m.verifying {
+		return errs.ErrRegionStorageMigration.FastGenByArgs("the verification is in progress")
+	}
+	if m.lastVerification == nil || !m.lastVerification.IsConsistent() {
+		return errs.ErrRegionStorageMigration.FastGenByArgs("the region storage has not been verified to be consistent")
+	}
+
+	// Switch the storage before turning off the dual-write mode, so that no
+	// region is only written to the etcd.
+	storage.TrySwitchRegionStorage(s.storage, true)
+	cfg := s.GetPDServerConfig()
+	cfg.UseRegionStorage = true
+	cfg.RegionStorageDualWrite = false
+	if err := s.SetPDServerConfig(*cfg); err != nil {
+		storage.TrySwitchRegionStorage(s.storage, false)
+		return err
+	}
+	m.lastVerification = nil
+	m.lastVerifyTime = time.Time{}
+	m.lastVerifyError = nil
+	log.Info("server cutover to the independent region storage")
+	return nil
+}
@@ -16,7 +16,9 @@ package syncer
 
 import (
 	"strconv"
+	"time"
 
+	"github.com/docker/go-units"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/syncutil"
@@ -28,8 +30,48 @@ import (
 const (
 	historyKey        = "historyIndex"
 	defaultFlushCount = 100
+
+	// maxHistoryBufferSize is the max capacity the history buffer can grow to.
+	maxHistoryBufferSize = 1000000
+	// defaultHistoryMemoryLimit is the max memory used by the records in the
+	// history buffer, the oldest records are dropped when it is exceeded.
+	defaultHistoryMemoryLimit = 512 * units.MiB
+	// regionRecordOverhead is the approximate memory used by a region record
+	// besides its meta.
+	regionRecordOverhead = 256
+	// historyLagFactor is the ratio of the capacity to the max observed lag.
+	historyLagFactor = 2
+	// historyLagWindow is how long an observed lag affects the capacity.
+	historyLagWindow = 30 * time.Minute
+)
+
+// The results of the history synchronization requested by the followers.
+const (
+	syncFromHistory = "history"
+	syncInSync      = "in-sync"
+	syncFull        = "full"
+	syncMissed      = "missed"
 )
 
+// HistoryStatus is the status of the history buffer of the region syncer.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type HistoryStatus struct {
+	Capacity       int     `json:"capacity"`
+	MinCapacity    int     `json:"min_capacity"`
+	MaxCapacity    int     `json:"max_capacity"`
+	Size           int     `json:"size"`
+	Utilization    float64 `json:"utilization"`
+	FirstIndex     uint64  `json:"first_index"`
+	NextIndex      uint64  `json:"next_index"`
+	MemoryUsage    int64   `json:"memory_usage"`
+	MemoryLimit    int64   `json:"memory_limit"`
+	MaxObservedLag uint64  `json:"max_observed_lag"`
+	// SyncCounts counts the history synchronizations by the result, the
+	// "full" and "missed" ones are the fallbacks which cannot be served by
+	// the history.
+	SyncCounts map[string]uint64 `json:"sync_counts"`
+}
+
 type historyBuffer struct {
 	syncutil.RWMutex
 	index      uint64
@@ -39,25 +81,53 @@ type historyBuffer struct {
 	size       int
 	kv         kv.Base
 	flushCount int
+
+	// The capacity is adjusted between minSize and maxSize according to the
+	// max lag of the followers observed in the last historyLagWindow.
+	minSize     int
+	maxSize     int
+	maxLag      uint64
+	maxLagTime  time.Time
+	memoryUsage int64
+	memoryLimit int64
+	syncCounts  map[string]uint64
+	// lostIndex is the index where the last change missing from the history
+	// should be, it is valid only if lost is true.
+	lostIndex uint64
+	lost      bool
 }
 
 func newHistoryBuffer(size int, kv kv.Base) *historyBuffer {
-	// use an empty space to simplify operation
-	size++
-	if size < 2 {
-		size = 2
+	if size < 1 {
+		size = 1
+	}
+	maxSize := maxHistoryBufferSize
+	if maxSize < size {
+		maxSize = size
 	}
-	records := make([]*core.RegionInfo, size)
 	h := &historyBuffer{
-		records:    records,
-		size:       size,
-		kv:         kv,
-		flushCount: defaultFlushCount,
+		// use an empty space to simplify operation
+		records:     make([]*core.RegionInfo, size+1),
+		size:        size + 1,
+		kv:          kv,
+		flushCount:  defaultFlushCount,
+		minSize:     size,
+		maxSize:     maxSize,
+		memoryLimit: defaultHistoryMemoryLimit,
+		syncCounts:  make(map[string]uint64),
 	}
 	h.reload()
 	return h
 }
 
+func regionRecordSize(r *core.RegionInfo) int64 {
+	return int64(r.GetMeta().Size()) + regionRecordOverhead
+}
+
+func (h *historyBuffer) capacity() int {
+	return h.size - 1
+}
+
 func (h *historyBuffer) len() int {
 	return h.distanceToTail(h.head)
 }
@@ -82,9 +152,13 @@ func (h *historyBuffer) Record(r *core.RegionInfo) {
 	defer h.Unlock()
 	regionSyncerStatus.WithLabelValues("sync_index").Set(float64(h.index))
 	h.records[h.tail] = r
+	h.memoryUsage += regionRecordSize(r)
 	h.tail = (h.tail + 1) % h.size
 	if h.tail == h.head {
-		h.head = (h.head + 1) % h.size
+		h.evictHead()
+	}
+	for h.memoryUsage > h.memoryLimit && h.len() > 1 {
+		h.evictHead()
 	}
 	h.index++
 	h.flushCount--
@@ -110,13 +184,135 @@ func (h *historyBuffer) RecordsFrom(index uint64) []*core.RegionInfo {
 	return records
 }
 
+// recordsSince returns at most limit records since the index, or all of them
+// if limit is not positive, along with the index following the last returned
+// record. ok is false if some changes since the index are not in the history.
+func (h *historyBuffer) recordsSince(index uint64, limit int) (records []*core.RegionInfo, next uint64, ok bool) {
+	h.RLock()
+	defer h.RUnlock()
+	if index > h.nextIndex() || index < h.firstIndex() || (h.lost && index <= h.lostIndex) {
+		return nil, h.nextIndex(), false
+	}
+	for i := index; i < h.nextIndex() && (limit <= 0 || len(records) < limit); i++ {
+		records = append(records, h.get(i))
+	}
+	return records, index + uint64(len(records)), true
+}
+
+// markLost marks a change missing from the history, which would be recorded
+// after the pending ones.
+func (h *historyBuffer) markLost(pending uint64) {
+	h.Lock()
+	defer h.Unlock()
+	if index := h.index + pending; !h.lost || index > h.lostIndex {
+		h.lostIndex, h.lost = index, true
+	}
+}
+
 func (h *historyBuffer) ResetWithIndex(index uint64) {
 	h.Lock()
 	defer h.Unlock()
 	h.index = index
+	h.records = make([]*core.RegionInfo, h.size)
 	h.head = 0
 	h.tail = 0
+	h.memoryUsage = 0
 	h.flushCount = defaultFlushCount
+	h.lost = false
+}
+
+// evictHead drops the oldest record.
+func (h *historyBuffer) evictHead() {
+	h.memoryUsage -= regionRecordSize(h.records[h.head])
+	h.records[h.head] = nil
+	h.head = (h.head + 1) % h.size
+}
+
+// resize changes the capacity of the buffer, the oldest records are dropped
+// if the new capacity is not enough.
+func (h *historyBuffer) resize(capacity int) {
+	for h.len() > capacity {
+		h.evictHead()
+	}
+	records := make([]*core.RegionInfo, capacity+1)
+	n := h.len()
+	for i := 0; i < n; i++ {
+		records[i] = h.records[(h.head+i)%h.size]
+	}
+	h.records, h.size, h.head, h.tail = records, capacity+1, 0, n
+	regionSyncerStatus.WithLabelValues("history_capacity").Set(float64(capacity))
+}
+
+// observeSync records the result of a history synchronization requested by a
+// follower from startIndex, and grows the buffer if the follower lags too
+// far behind.
+func (h *historyBuffer) observeSync(startIndex uint64, result string, now time.Time) {
+	h.Lock()
+	defer h.Unlock()
+	h.syncCounts[result]++
+	regionSyncerHistorySyncCounter.WithLabelValues(result).Inc()
+	// The start index of a full synchronization is 0, and the start index
+	// may be larger than the next index if the leader is changed.
+	if startIndex == 0 || startIndex >= h.index {
+		return
+	}
+	if lag := h.index - startIndex; lag >= h.maxLag || now.Sub(h.maxLagTime) > historyLagWindow {
+		h.maxLag, h.maxLagTime = lag, now
+	}
+	h.adjustLocked(now)
+}
+
+// adjust adjusts the capacity of the buffer according to the observed lag.
+func (h *historyBuffer) adjust(now time.Time) {
+	h.Lock()
+	defer h.Unlock()
+	h.adjustLocked(now)
+}
+
+func (h *historyBuffer) adjustLocked(now time.Time) {
+	if now.Sub(h.maxLagTime) > historyLagWindow {
+		h.maxLag = 0
+	}
+	target := h.maxSize
+	if h.maxLag < uint64(h.maxSize/historyLagFactor) {
+		target = int(h.maxLag) * historyLagFactor
+	}
+	if target < h.minSize {
+		target = h.minSize
+	}
+	capacity := h.capacity()
+	switch {
+	case target > capacity:
+		log.Info("grow the region syncer history", zap.Int("from", capacity), zap.Int("to", target), zap.Uint64("max-lag", h.maxLag))
+		h.resize(target)
+	case target < capacity/2:
+		// shrink by half at most to avoid dropping the history suddenly.
+		log.Info("shrink the region syncer history", zap.Int("from", capacity), zap.Int("to", capacity/2), zap.Uint64("max-lag", h.maxLag))
+		h.resize(capacity / 2)
+	}
+}
+
+// GetStatus returns the status of the buffer.
+func (h *historyBuffer) GetStatus() *HistoryStatus {
+	h.RLock()
+	defer h.RUnlock()
+	syncCounts := make(map[string]uint64, len(h.syncCounts))
+	for result, count := range h.syncCounts {
+		syncCounts[result] = count
+	}
+	return &HistoryStatus{
+		Capacity:       h.capacity(),
+		MinCapacity:    h.minSize,
+		MaxCapacity:    h.maxSize,
+		Size:           h.len(),
+		Utilization:    float64(h.len()) / float64(h.capacity()),
+		FirstIndex:     h.firstIndex(),
+		NextIndex:      h.nextIndex(),
+		MemoryUsage:    h.memoryUsage,
+		MemoryLimit:    h.memoryLimit,
+		MaxObservedLag: h.maxLag,
+		SyncCounts:     syncCounts,
+	}
 }
 
 func (h *historyBuffer) GetNextIndex() uint64 {
@@ -150,6 +346,7 @@ func (h *historyBuffer) reload() {
 func (h *historyBuffer) persist() {
 	regionSyncerStatus.WithLabelValues("first_index").Set(float64(h.firstIndex()))
 	regionSyncerStatus.WithLabelValues("last_index").Set(float64(h.nextIndex()))
+	regionSyncerStatus.WithLabelValues("history_memory").Set(float64(h.memoryUsage))
 	err := h.kv.Save(historyKey, strconv.FormatUint(h.nextIndex(), 10))
 	if err != nil {
 		log.Warn("persist history index failed", zap.Uint64("persist-index", h.nextIndex()), errs.ZapError(err))
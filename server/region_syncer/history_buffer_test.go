@@ -16,6 +16,7 @@ package syncer
 
 import (
 	"testing"
+	"time"
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/stretchr/testify/require"
@@ -86,3 +87,60 @@ func TestBufferSize(t *testing.T) {
 	re.Equal(uint64(7), h2.firstIndex())
 	re.Equal(regions[1:], histories)
 }
+
+func TestAdaptiveSize(t *testing.T) {
+	re := require.New(t)
+	h := newHistoryBuffer(10, kv.NewMemoryKV())
+	for i := 0; i < 100; i++ {
+		h.Record(core.NewRegionInfo(&metapb.Region{Id: uint64(i)}, nil))
+	}
+	re.Equal(10, h.len())
+	re.Equal(uint64(90), h.firstIndex())
+
+	// A follower lags behind the history.
+	now := time.Now()
+	h.observeSync(50, syncMissed, now)
+	status := h.GetStatus()
+	re.Equal(100, status.Capacity)
+	re.Equal(uint64(50), status.MaxObservedLag)
+	re.Equal(uint64(1), status.SyncCounts[syncMissed])
+	// The records are kept after growing.
+	re.Equal(10, h.len())
+	re.Equal(uint64(90), h.firstIndex())
+	for i := 100; i < 200; i++ {
+		h.Record(core.NewRegionInfo(&metapb.Region{Id: uint64(i)}, nil))
+	}
+	re.Len(h.RecordsFrom(100), 100)
+	h.observeSync(150, syncFromHistory, now)
+	re.Equal(100, h.capacity())
+
+	// Keep the capacity within the lag window.
+	h.adjust(now.Add(historyLagWindow / 2))
+	re.Equal(100, h.capacity())
+	// Shrink gradually after the lag window.
+	h.adjust(now.Add(historyLagWindow * 2))
+	re.Equal(50, h.capacity())
+	re.Equal(50, h.len())
+	re.Equal(uint64(150), h.firstIndex())
+	h.adjust(now.Add(historyLagWindow * 2))
+	re.Equal(25, h.capacity())
+	h.adjust(now.Add(historyLagWindow * 2))
+	re.Equal(12, h.capacity())
+	h.adjust(now.Add(historyLagWindow * 2))
+	re.Equal(12, h.capacity())
+	re.Equal(uint64(199), h.get(199).GetID())
+}
+
+func TestMemoryLimit(t *testing.T) {
+	re := require.New(t)
+	h := newHistoryBuffer(100, kv.NewMemoryKV())
+	r := core.NewRegionInfo(&metapb.Region{Id: 1}, nil)
+	h.memoryLimit = regionRecordSize(r) * 10
+	for i := 0; i < 20; i++ {
+		h.Record(core.NewRegionInfo(&metapb.Region{Id: 1}, nil))
+	}
+	re.Equal(10, h.len())
+	re.Equal(h.memoryLimit, h.GetStatus().MemoryUsage)
+	h.ResetWithIndex(100)
+	re.Equal(int64(0), h.GetStatus().MemoryUsage)
+}
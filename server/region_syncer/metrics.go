@@ -24,6 +24,15 @@ var regionSyncerStatus = prometheus.NewGaugeVec(
 		Help:      "Inner status of the region syncer.",
 	}, []string{"type"})
 
+var regionSyncerHistorySyncCounter = prometheus.NewCounterVec(
+	prometheus.CounterOpts{
+		Namespace: "pd",
+		Subsystem: "region_syncer",
+		Name:      "history_sync_total",
+		Help:      "Counter of the history synchronizations requested by the followers.",
+	}, []string{"result"})
+
 func init() {
 	prometheus.MustRegister(regionSyncerStatus)
+	prometheus.MustRegister(regionSyncerHistorySyncCounter)
 }
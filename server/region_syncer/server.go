@@ -17,6 +17,7 @@ package syncer
 import (
 	"context"
 	"io"
+	"sort"
 	"sync"
 	"time"
 
@@ -71,13 +72,47 @@ type Server interface {
 	GetBasicCluster() *core.BasicCluster
 }
 
+// SyncerStatus is the status of the region syncer server.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type SyncerStatus struct {
+	Paused    bool              `json:"paused"`
+	History   *HistoryStatus    `json:"history"`
+	Followers []*FollowerStatus `json:"followers"`
+}
+
+// FollowerStatus is the synchronization status of a follower.
+type FollowerStatus struct {
+	Name string `json:"name"`
+	// Index is the next index of the history to be sent to the follower.
+	Index uint64 `json:"index"`
+	// Lag is the number of the records which have not been sent to the follower.
+	Lag          uint64    `json:"lag"`
+	LastSendTime time.Time `json:"last_send_time"`
+}
+
+// followerStream is the stream bound to a follower. After being bound, it is
+// only sent by the goroutine running the server, outside the lock of the syncer.
+// The index and lastSend are written by the goroutine under the lock.
+type followerStream struct {
+	ServerStream
+	index    uint64
+	lastSend time.Time
+	closed   chan struct{}
+}
+
 // RegionSyncer is used to sync the region information without raft.
 type RegionSyncer struct {
 	mu struct {
 		syncutil.RWMutex
-		streams      map[string]ServerStream
+		streams      map[string]*followerStream
 		clientCtx    context.Context
 		clientCancel context.CancelFunc
+		// paused stops sending the regions to the followers, the records are
+		// still kept in the history and sent after being resumed.
+		paused bool
+		// fullSyncRequests is the followers which are forced to do the full
+		// synchronization next time.
+		fullSyncRequests map[string]struct{}
 	}
 	server    Server
 	wg        sync.WaitGroup
@@ -102,7 +137,8 @@ func NewRegionSyncer(s Server) *RegionSyncer {
 		limit:     ratelimit.NewRateLimiter(defaultBucketRate, defaultBucketCapacity),
 		tlsConfig: s.GetTLSConfig(),
 	}
-	syncer.mu.streams = make(map[string]ServerStream)
+	syncer.mu.streams = make(map[string]*followerStream)
+	syncer.mu.fullSyncRequests = make(map[string]struct{})
 	return syncer
 }
 
@@ -118,7 +154,9 @@ func (s *RegionSyncer) RunServer(ctx context.Context, regionNotifier <-chan *cor
 	defer func() {
 		ticker.Stop()
 		s.mu.Lock()
-		s.mu.streams = make(map[string]ServerStream)
+		for name := range s.mu.streams {
+			s.closeStreamLocked(name)
+		}
 		s.mu.Unlock()
 	}()
 
@@ -163,6 +201,7 @@ func (s *RegionSyncer) RunServer(ctx context.Context, regionNotifier <-chan *cor
 			}
 			s.broadcast(regions)
 		case <-ticker.C:
+			s.history.adjust(time.Now())
 			alive := &pdpb.SyncRegionResponse{
 				Header:     &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()},
 				StartIndex: s.history.GetNextIndex(),
@@ -176,6 +215,133 @@ func (s *RegionSyncer) RunServer(ctx context.Context, regionNotifier <-chan *cor
 	}
 }
 
+// GetHistoryStatus returns the status of the history buffer.
+func (s *RegionSyncer) GetHistoryStatus() *HistoryStatus {
+	return s.history.GetStatus()
+}
+
+// RegionChanges is the regions changed since a watermark.
+type RegionChanges struct {
+	Since uint64
+	// Next is the watermark to get the following changes from.
+	Next uint64
+	// Watermark is the current watermark.
+	Watermark uint64
+	// Truncated is true if some changes since the watermark are missing, the
+	// routes cached before the watermark should be reloaded completely.
+	Truncated bool
+	// Regions are the latest versions of the changed regions, in the order of
+	// the last changes.
+	Regions []*core.RegionInfo
+}
+
+// GetWatermark returns the region change watermark, which is increased once a
+// region changes. It is the next index of the history.
+func (s *RegionSyncer) GetWatermark() uint64 {
+	return s.history.GetNextIndex()
+}
+
+// GetChangesSince returns the regions changed since the watermark, at most
+// limit changes are returned if limit is positive.
+func (s *RegionSyncer) GetChangesSince(since uint64, limit int) *RegionChanges {
+	records, next, ok := s.history.recordsSince(since, limit)
+	changes := &RegionChanges{
+		Since:     since,
+		Next:      next,
+		Watermark: s.GetWatermark(),
+		Truncated: !ok,
+	}
+	// Only keep the last change of each region.
+	last := make(map[uint64]int, len(records))
+	for i, r := range records {
+		last[r.GetID()] = i
+	}
+	changes.Regions = make([]*core.RegionInfo, 0, len(last))
+	for i, r := range records {
+		if last[r.GetID()] == i {
+			changes.Regions = append(changes.Regions, r)
+		}
+	}
+	return changes
+}
+
+// MarkChangeLost marks a region change which is not sent to the region
+// syncer, pending is the number of the changes queued before it.
+func (s *RegionSyncer) MarkChangeLost(pending int) {
+	s.history.markLost(uint64(pending))
+}
+
+// GetStatus returns the status of the region syncer server.
+func (s *RegionSyncer) GetStatus() *SyncerStatus {
+	history := s.history.GetStatus()
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	followers := make([]*FollowerStatus, 0, len(s.mu.streams))
+	for name, stream := range s.mu.streams {
+		follower := &FollowerStatus{
+			Name:         name,
+			Index:        stream.index,
+			LastSendTime: stream.lastSend,
+		}
+		if history.NextIndex > stream.index {
+			follower.Lag = history.NextIndex - stream.index
+		}
+		followers = append(followers, follower)
+	}
+	sort.Slice(followers, func(i, j int) bool { return followers[i].Name < followers[j].Name })
+	return &SyncerStatus{
+		Paused:    s.mu.paused,
+		History:   history,
+		Followers: followers,
+	}
+}
+
+// Pause stops sending the regions to the followers and accepting new followers.
+func (s *RegionSyncer) Pause() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.paused = true
+	log.Info("region syncer is paused")
+}
+
+// Resume resumes the region syncer. The records missed by the followers are
+// sent from the history along with the next broadcast.
+func (s *RegionSyncer) Resume() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.mu.paused = false
+	log.Info("region syncer is resumed")
+}
+
+// IsPaused returns whether the region syncer is paused.
+func (s *RegionSyncer) IsPaused() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mu.paused
+}
+
+// ForceFullSync closes the stream of the follower and makes it do the full
+// synchronization after reconnecting.
+func (s *RegionSyncer) ForceFullSync(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.mu.streams[name]; !ok {
+		return errs.ErrRegionSyncerFollowerNotFound.FastGenByArgs(name)
+	}
+	s.mu.fullSyncRequests[name] = struct{}{}
+	s.closeStreamLocked(name)
+	log.Info("force the server to do full synchronization", zap.String("requested-server", name))
+	return nil
+}
+
+func (s *RegionSyncer) takeFullSyncRequest(name string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, ok := s.mu.fullSyncRequests[name]
+	delete(s.mu.fullSyncRequests, name)
+	return ok
+}
+
 // GetAllDownstreamNames tries to get the all bind stream's name.
 // Only for test
 func (s *RegionSyncer) GetAllDownstreamNames() []string {
@@ -191,112 +357,161 @@ func (s *RegionSyncer) GetAllDownstreamNames() []string {
 // Sync firstly tries to sync the history records to client.
 // then to sync the latest records.
 func (s *RegionSyncer) Sync(ctx context.Context, stream pdpb.PD_SyncRegionsServer) error {
+	// Receive the requests in another goroutine, so that the stream can be
+	// closed by the leader, e.g. to force the follower to do full synchronization.
+	requests := make(chan *pdpb.SyncRegionRequest)
+	recvErr := make(chan error, 1)
+	go func() {
+		for {
+			request, err := stream.Recv()
+			if err != nil {
+				recvErr <- err
+				return
+			}
+			select {
+			case requests <- request:
+			case <-stream.Context().Done():
+				return
+			}
+		}
+	}()
+
+	var closed <-chan struct{}
 	for {
+		var request *pdpb.SyncRegionRequest
 		select {
 		case <-ctx.Done():
 			return nil
-		default:
-		}
-
-		request, err := stream.Recv()
-		if err == io.EOF {
-			return nil
-		}
-		if err != nil {
+		case <-closed:
+			return status.Errorf(codes.Aborted, "the sync region stream is closed by the leader")
+		case err := <-recvErr:
+			if err == io.EOF {
+				return nil
+			}
 			return errors.WithStack(err)
+		case request = <-requests:
 		}
+
 		clusterID := request.GetHeader().GetClusterId()
 		if clusterID != s.server.ClusterID() {
 			return status.Errorf(codes.FailedPrecondition, "mismatch cluster id, need %d but got %d", s.server.ClusterID(), clusterID)
 		}
+		if s.IsPaused() {
+			return status.Errorf(codes.Unavailable, "region syncer is paused")
+		}
 		log.Info("establish sync region stream",
 			zap.String("requested-server", request.GetMember().GetName()),
 			zap.String("url", request.GetMember().GetClientUrls()[0]))
 
-		err = s.syncHistoryRegion(ctx, request, stream)
+		nextIndex, err := s.syncHistoryRegion(ctx, request, stream)
 		if err != nil {
 			return err
 		}
-		s.bindStream(request.GetMember().GetName(), stream)
+		closed = s.bindStream(request.GetMember().GetName(), stream, nextIndex)
 	}
 }
 
-func (s *RegionSyncer) syncHistoryRegion(ctx context.Context, request *pdpb.SyncRegionRequest, stream pdpb.PD_SyncRegionsServer) error {
+// syncHistoryRegion syncs the history records to the requested server, and
+// returns the next index of the history to be sent to it.
+func (s *RegionSyncer) syncHistoryRegion(ctx context.Context, request *pdpb.SyncRegionRequest, stream ServerStream) (uint64, error) {
 	startIndex := request.GetStartIndex()
 	name := request.GetMember().GetName()
+	if s.takeFullSyncRequest(name) {
+		s.history.observeSync(startIndex, syncFull, time.Now())
+		return s.syncAllRegions(ctx, name, stream)
+	}
 	records := s.history.RecordsFrom(startIndex)
 	if len(records) == 0 {
-		if s.history.GetNextIndex() == startIndex {
+		nextIndex := s.history.GetNextIndex()
+		if nextIndex == startIndex {
+			s.history.observeSync(startIndex, syncInSync, time.Now())
 			log.Info("requested server has already in sync with server",
 				zap.String("requested-server", name), zap.String("server", s.server.Name()), zap.Uint64("last-index", startIndex))
-			return nil
+			return startIndex, nil
 		}
 		// do full synchronization
 		if startIndex == 0 {
-			regions := s.server.GetRegions()
-			lastIndex := 0
-			start := time.Now()
-			metas := make([]*metapb.Region, 0, maxSyncRegionBatchSize)
-			stats := make([]*pdpb.RegionStat, 0, maxSyncRegionBatchSize)
-			leaders := make([]*metapb.Peer, 0, maxSyncRegionBatchSize)
-			buckets := make([]*metapb.Buckets, 0, maxSyncRegionBatchSize)
-			for syncedIndex, r := range regions {
-				select {
-				case <-ctx.Done():
-					log.Info("discontinue sending sync region response")
-					failpoint.Inject("noFastExitSync", func() {
-						failpoint.Goto("doSync")
-					})
-					return nil
-				default:
-				}
-				failpoint.Label("doSync")
-				metas = append(metas, r.GetMeta())
-				stats = append(stats, r.GetStat())
-				leader := &metapb.Peer{}
-				if r.GetLeader() != nil {
-					leader = r.GetLeader()
-				}
-				leaders = append(leaders, leader)
-				bucket := &metapb.Buckets{}
-				if r.GetBuckets() != nil {
-					bucket = r.GetBuckets()
-				}
-				buckets = append(buckets, bucket)
-				if len(metas) < maxSyncRegionBatchSize && syncedIndex < len(regions)-1 {
-					continue
-				}
-				resp := &pdpb.SyncRegionResponse{
-					Header:        &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()},
-					Regions:       metas,
-					StartIndex:    uint64(lastIndex),
-					RegionStats:   stats,
-					RegionLeaders: leaders,
-					Buckets:       buckets,
-				}
-				s.limit.WaitN(ctx, resp.Size())
-				lastIndex += len(metas)
-				if err := stream.Send(resp); err != nil {
-					log.Error("failed to send sync region response", errs.ZapError(errs.ErrGRPCSend, err))
-					return err
-				}
-				metas = metas[:0]
-				stats = stats[:0]
-				leaders = leaders[:0]
-				buckets = buckets[:0]
-			}
-			log.Info("requested server has completed full synchronization with server",
-				zap.String("requested-server", name), zap.String("server", s.server.Name()), zap.Duration("cost", time.Since(start)))
-			return nil
+			s.history.observeSync(startIndex, syncFull, time.Now())
+			return s.syncAllRegions(ctx, name, stream)
 		}
+		s.history.observeSync(startIndex, syncMissed, time.Now())
 		log.Warn("no history regions from index, the leader may be restarted", zap.Uint64("index", startIndex))
-		return nil
+		return nextIndex, nil
 	}
+	s.history.observeSync(startIndex, syncFromHistory, time.Now())
 	log.Info("sync the history regions with server",
 		zap.String("server", name),
 		zap.Uint64("from-index", startIndex),
 		zap.Uint64("last-index", s.history.GetNextIndex()),
 		zap.Int("records-length", len(records)))
+	if err := stream.Send(s.newHistoryResponse(startIndex, records)); err != nil {
+		return 0, err
+	}
+	return startIndex + uint64(len(records)), nil
+}
+
+// syncAllRegions sends all regions to the requested server.
+func (s *RegionSyncer) syncAllRegions(ctx context.Context, name string, stream ServerStream) (uint64, error) {
+	// The records after it may be sent again, which does no harm.
+	nextIndex := s.history.GetNextIndex()
+	regions := s.server.GetRegions()
+	lastIndex := 0
+	start := time.Now()
+	metas := make([]*metapb.Region, 0, maxSyncRegionBatchSize)
+	stats := make([]*pdpb.RegionStat, 0, maxSyncRegionBatchSize)
+	leaders := make([]*metapb.Peer, 0, maxSyncRegionBatchSize)
+	buckets := make([]*metapb.Buckets, 0, maxSyncRegionBatchSize)
+	for syncedIndex, r := range regions {
+		select {
+		case <-ctx.Done():
+			log.Info("discontinue sending sync region response")
+			failpoint.Inject("noFastExitSync", func() {
+				failpoint.Goto("doSync")
+			})
+			return nextIndex, nil
+		default:
+		}
+		failpoint.Label("doSync")
+		metas = append(metas, r.GetMeta())
+		stats = append(stats, r.GetStat())
+		leader := &metapb.Peer{}
+		if r.GetLeader() != nil {
+			leader = r.GetLeader()
+		}
+		leaders = append(leaders, leader)
+		bucket := &metapb.Buckets{}
+		if r.GetBuckets() != nil {
+			bucket = r.GetBuckets()
+		}
+		buckets = append(buckets, bucket)
+		if len(metas) < maxSyncRegionBatchSize && syncedIndex < len(regions)-1 {
+			continue
+		}
+		resp := &pdpb.SyncRegionResponse{
+			Header:        &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()},
+			Regions:       metas,
+			StartIndex:    uint64(lastIndex),
+			RegionStats:   stats,
+			RegionLeaders: leaders,
+			Buckets:       buckets,
+		}
+		s.limit.WaitN(ctx, resp.Size())
+		lastIndex += len(metas)
+		if err := stream.Send(resp); err != nil {
+			log.Error("failed to send sync region response", errs.ZapError(errs.ErrGRPCSend, err))
+			return 0, err
+		}
+		metas = metas[:0]
+		stats = stats[:0]
+		leaders = leaders[:0]
+		buckets = buckets[:0]
+	}
+	log.Info("requested server has completed full synchronization with server",
+		zap.String("requested-server", name), zap.String("server", s.server.Name()), zap.Duration("cost", time.Since(start)))
+	return nextIndex, nil
+}
+
+func (s *RegionSyncer) newHistoryResponse(startIndex uint64, records []*core.RegionInfo) *pdpb.SyncRegionResponse {
 	regions := make([]*metapb.Region, len(records))
 	stats := make([]*pdpb.RegionStat, len(records))
 	leaders := make([]*metapb.Peer, len(records))
@@ -315,7 +530,7 @@ func (s *RegionSyncer) syncHistoryRegion(ctx context.Context, request *pdpb.Sync
 			buckets[i] = r.GetBuckets()
 		}
 	}
-	resp := &pdpb.SyncRegionResponse{
+	return &pdpb.SyncRegionResponse{
 		Header:        &pdpb.ResponseHeader{ClusterId: s.server.ClusterID()},
 		Regions:       regions,
 		StartIndex:    startIndex,
@@ -323,33 +538,88 @@ func (s *RegionSyncer) syncHistoryRegion(ctx context.Context, request *pdpb.Sync
 		RegionLeaders: leaders,
 		Buckets:       buckets,
 	}
-	return stream.Send(resp)
 }
 
-// bindStream binds the established server stream.
-func (s *RegionSyncer) bindStream(name string, stream ServerStream) {
+// bindStream binds the established server stream, and returns the channel
+// which is closed when the stream is closed by the leader.
+func (s *RegionSyncer) bindStream(name string, stream ServerStream, nextIndex uint64) <-chan struct{} {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.mu.streams[name] = stream
+	// The previous stream of the same server is no longer used.
+	if _, ok := s.mu.streams[name]; ok {
+		s.closeStreamLocked(name)
+	}
+	fs := &followerStream{
+		ServerStream: stream,
+		index:        nextIndex,
+		lastSend:     time.Now(),
+		closed:       make(chan struct{}),
+	}
+	s.mu.streams[name] = fs
+	return fs.closed
+}
+
+func (s *RegionSyncer) closeStreamLocked(name string) {
+	if fs, ok := s.mu.streams[name]; ok {
+		close(fs.closed)
+		delete(s.mu.streams, name)
+	}
 }
 
+// broadcast sends the regions to the followers. The streams are sent outside the
+// lock, so that a slow follower does not block the others and the status calls.
 func (s *RegionSyncer) broadcast(regions *pdpb.SyncRegionResponse) {
-	var failed []string
 	s.mu.RLock()
-	for name, sender := range s.mu.streams {
-		err := sender.Send(regions)
+	if s.mu.paused {
+		s.mu.RUnlock()
+		return
+	}
+	streams := make(map[string]*followerStream, len(s.mu.streams))
+	for name, fs := range s.mu.streams {
+		streams[name] = fs
+	}
+	s.mu.RUnlock()
+
+	for name, fs := range streams {
+		err := s.sendToFollower(name, fs, regions)
 		if err != nil {
 			log.Error("region syncer send data meet error", errs.ZapError(errs.ErrGRPCSend, err))
-			failed = append(failed, name)
+			s.mu.Lock()
+			// The stream may have been replaced by a new one of the follower meanwhile.
+			if s.mu.streams[name] == fs {
+				s.closeStreamLocked(name)
+				log.Info("region syncer delete the stream", zap.String("stream", name))
+			}
+			s.mu.Unlock()
 		}
 	}
-	s.mu.RUnlock()
-	if len(failed) > 0 {
-		s.mu.Lock()
-		for _, name := range failed {
-			delete(s.mu.streams, name)
-			log.Info("region syncer delete the stream", zap.String("stream", name))
+}
+
+// sendToFollower sends the response to the follower. If the follower has
+// missed some records, e.g. when the syncer is paused, they are sent from the
+// history instead, and the follower is forced to do full synchronization if
+// they are no longer in the history.
+func (s *RegionSyncer) sendToFollower(name string, fs *followerStream, resp *pdpb.SyncRegionResponse) error {
+	if startIndex := resp.GetStartIndex(); fs.index != startIndex {
+		// The regions have been sent along with the history.
+		if len(resp.GetRegions()) > 0 && fs.index >= startIndex+uint64(len(resp.GetRegions())) {
+			return nil
 		}
-		s.mu.Unlock()
+		records := s.history.RecordsFrom(fs.index)
+		if len(records) == 0 {
+			s.mu.Lock()
+			s.mu.fullSyncRequests[name] = struct{}{}
+			s.mu.Unlock()
+			return errs.ErrRegionSyncerFollowerMissed.FastGenByArgs(name, fs.index)
+		}
+		resp = s.newHistoryResponse(fs.index, records)
+	}
+	if err := fs.Send(resp); err != nil {
+		return err
 	}
+	s.mu.Lock()
+	fs.index = resp.GetStartIndex() + uint64(len(resp.GetRegions()))
+	fs.lastSend = time.Now()
+	s.mu.Unlock()
+	return nil
 }
@@ -0,0 +1,260 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package syncer
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage"
+)
+
+type mockServerStream struct {
+	responses []*pdpb.SyncRegionResponse
+	err       error
+}
+
+func (s *mockServerStream) Send(resp *pdpb.SyncRegionResponse) error {
+	if s.err != nil {
+		return s.err
+	}
+	s.responses = append(s.responses, resp)
+	return nil
+}
+
+func (s *mockServerStream) last() *pdpb.SyncRegionResponse {
+	return s.responses[len(s.responses)-1]
+}
+
+func recordAndBroadcast(rs *RegionSyncer, n int) {
+	startIndex := rs.history.GetNextIndex()
+	metas := make([]*metapb.Region, 0, n)
+	for i := 0; i < n; i++ {
+		region := core.NewRegionInfo(&metapb.Region{Id: startIndex + uint64(i) + 1}, nil)
+		rs.history.Record(region)
+		metas = append(metas, region.GetMeta())
+	}
+	rs.broadcast(&pdpb.SyncRegionResponse{StartIndex: startIndex, Regions: metas})
+}
+
+func keepalive(rs *RegionSyncer) {
+	rs.broadcast(&pdpb.SyncRegionResponse{StartIndex: rs.history.GetNextIndex()})
+}
+
+func TestPauseAndResume(t *testing.T) {
+	re := require.New(t)
+	server := &mockServer{
+		ctx:     context.Background(),
+		storage: storage.NewCoreStorage(storage.NewStorageWithMemoryBackend(), storage.NewStorageWithMemoryBackend()),
+		bc:      core.NewBasicCluster(),
+	}
+	rs := NewRegionSyncer(server)
+	rs.history = newHistoryBuffer(3, storage.NewStorageWithMemoryBackend())
+	stream := &mockServerStream{}
+	closed := rs.bindStream("pd2", stream, rs.history.GetNextIndex())
+
+	recordAndBroadcast(rs, 2)
+	re.Len(stream.responses, 1)
+	status := rs.GetStatus()
+	re.False(status.Paused)
+	re.Len(status.Followers, 1)
+	re.Equal("pd2", status.Followers[0].Name)
+	re.Equal(uint64(2), status.Followers[0].Index)
+	re.Zero(status.Followers[0].Lag)
+
+	// The records are kept in the history when the syncer is paused.
+	rs.Pause()
+	re.True(rs.IsPaused())
+	recordAndBroadcast(rs, 2)
+	keepalive(rs)
+	re.Len(stream.responses, 1)
+	status = rs.GetStatus()
+	re.True(status.Paused)
+	re.Equal(uint64(2), status.Followers[0].Lag)
+
+	// The missed records are sent along with the next broadcast.
+	rs.Resume()
+	re.False(rs.IsPaused())
+	keepalive(rs)
+	re.Len(stream.responses, 2)
+	re.Equal(uint64(2), stream.last().GetStartIndex())
+	re.Len(stream.last().GetRegions(), 2)
+	re.Zero(rs.GetStatus().Followers[0].Lag)
+	keepalive(rs)
+	re.Len(stream.responses, 3)
+	re.Empty(stream.last().GetRegions())
+
+	// The follower is forced to do full synchronization if the missed records
+	// are no longer in the history.
+	rs.Pause()
+	recordAndBroadcast(rs, 4)
+	rs.Resume()
+	keepalive(rs)
+	re.Len(stream.responses, 3)
+	re.Empty(rs.GetAllDownstreamNames())
+	re.True(rs.takeFullSyncRequest("pd2"))
+	re.False(rs.takeFullSyncRequest("pd2"))
+	select {
+	case <-closed:
+	default:
+		re.FailNow("the stream should be closed")
+	}
+}
+
+// blockingServerStream blocks the sending until it is released.
+type blockingServerStream struct {
+	mockServerStream
+	sending chan struct{}
+	release chan struct{}
+}
+
+func (s *blockingServerStream) Send(resp *pdpb.SyncRegionResponse) error {
+	s.sending <- struct{}{}
+	<-s.release
+	return s.mockServerStream.Send(resp)
+}
+
+func TestSlowFollower(t *testing.T) {
+	re := require.New(t)
+	server := &mockServer{
+		ctx:     context.Background(),
+		storage: storage.NewCoreStorage(storage.NewStorageWithMemoryBackend(), storage.NewStorageWithMemoryBackend()),
+		bc:      core.NewBasicCluster(),
+	}
+	rs := NewRegionSyncer(server)
+	stream := &blockingServerStream{sending: make(chan struct{}), release: make(chan struct{})}
+	rs.bindStream("pd2", stream, rs.history.GetNextIndex())
+
+	done := make(chan struct{})
+	go func() {
+		recordAndBroadcast(rs, 1)
+		close(done)
+	}()
+	<-stream.sending
+	// The syncer is not blocked by the follower which is sending.
+	status := rs.GetStatus()
+	re.Len(status.Followers, 1)
+	re.Zero(status.Followers[0].Index)
+	rs.Pause()
+	re.True(rs.IsPaused())
+	rs.Resume()
+
+	close(stream.release)
+	<-done
+	re.Len(stream.responses, 1)
+	re.Equal(uint64(1), rs.GetStatus().Followers[0].Index)
+}
+
+func TestForceFullSync(t *testing.T) {
+	re := require.New(t)
+	server := &mockServer{
+		ctx:     context.Background(),
+		storage: storage.NewCoreStorage(storage.NewStorageWithMemoryBackend(), storage.NewStorageWithMemoryBackend()),
+		bc:      core.NewBasicCluster(),
+	}
+	rs := NewRegionSyncer(server)
+	re.Error(rs.ForceFullSync("pd2"))
+
+	closed := rs.bindStream("pd2", &mockServerStream{}, rs.history.GetNextIndex())
+	re.NoError(rs.ForceFullSync("pd2"))
+	re.Empty(rs.GetAllDownstreamNames())
+	_, ok := <-closed
+	re.False(ok)
+
+	// The forced full synchronization sends all regions even if the history
+	// contains the requested index.
+	for i := uint64(1); i <= 3; i++ {
+		region := core.NewRegionInfo(&metapb.Region{Id: i, StartKey: []byte{byte(i)}, EndKey: []byte{byte(i + 1)}}, nil)
+		server.bc.PutRegion(region)
+		rs.history.Record(region)
+	}
+	stream := &mockServerStream{}
+	request := &pdpb.SyncRegionRequest{Member: &pdpb.Member{Name: "pd2"}, StartIndex: 2}
+	nextIndex, err := rs.syncHistoryRegion(context.Background(), request, stream)
+	re.NoError(err)
+	re.Equal(uint64(3), nextIndex)
+	re.Len(stream.responses, 1)
+	re.Zero(stream.last().GetStartIndex())
+	re.Len(stream.last().GetRegions(), 3)
+
+	// The history is used afterwards.
+	stream = &mockServerStream{}
+	nextIndex, err = rs.syncHistoryRegion(context.Background(), request, stream)
+	re.NoError(err)
+	re.Equal(uint64(3), nextIndex)
+	re.Len(stream.responses, 1)
+	re.Equal(uint64(2), stream.last().GetStartIndex())
+	re.Len(stream.last().GetRegions(), 1)
+
+	// The stream is deleted if it fails to send.
+	rs.bindStream("pd3", &mockServerStream{err: errors.New("closed")}, rs.history.GetNextIndex())
+	keepalive(rs)
+	re.Empty(rs.GetAllDownstreamNames())
+}
+
+func TestRegionChanges(t *testing.T) {
+	re := require.New(t)
+	server := &mockServer{
+		ctx:     context.Background(),
+		storage: storage.NewCoreStorage(storage.NewStorageWithMemoryBackend(), storage.NewStorageWithMemoryBackend()),
+		bc:      core.NewBasicCluster(),
+	}
+	rs := NewRegionSyncer(server)
+	re.Zero(rs.GetWatermark())
+	for _, id := range []uint64{1, 2, 1} {
+		rs.history.Record(core.NewRegionInfo(&metapb.Region{Id: id}, nil))
+	}
+	re.Equal(uint64(3), rs.GetWatermark())
+
+	// Only the last change of each region is returned.
+	changes := rs.GetChangesSince(0, 0)
+	re.False(changes.Truncated)
+	re.Equal(uint64(3), changes.Next)
+	re.Equal(uint64(3), changes.Watermark)
+	re.Len(changes.Regions, 2)
+	re.Equal(uint64(2), changes.Regions[0].GetID())
+	re.Equal(uint64(1), changes.Regions[1].GetID())
+
+	// The changes can be got in pages.
+	changes = rs.GetChangesSince(0, 2)
+	re.False(changes.Truncated)
+	re.Equal(uint64(2), changes.Next)
+	re.Len(changes.Regions, 2)
+	changes = rs.GetChangesSince(changes.Next, 2)
+	re.Equal(uint64(3), changes.Next)
+	re.Len(changes.Regions, 1)
+
+	// Nothing is changed since the current watermark.
+	changes = rs.GetChangesSince(3, 0)
+	re.False(changes.Truncated)
+	re.Empty(changes.Regions)
+	// The watermark is unknown.
+	re.True(rs.GetChangesSince(4, 0).Truncated)
+
+	// The changes are truncated if some of them are missing.
+	rs.MarkChangeLost(1)
+	re.True(rs.GetChangesSince(0, 0).Truncated)
+	re.True(rs.GetChangesSince(4, 0).Truncated)
+	rs.history.Record(core.NewRegionInfo(&metapb.Region{Id: 3}, nil))
+	rs.history.Record(core.NewRegionInfo(&metapb.Region{Id: 4}, nil))
+	changes = rs.GetChangesSince(5, 0)
+	re.False(changes.Truncated)
+	re.Empty(changes.Regions)
+}
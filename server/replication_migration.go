@@ -0,0 +1,256 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/cluster"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+	"go.uber.org/zap"
+)
+
+const (
+	// DefaultReplicationMigrationSampleSize is the default number of regions
+	// whose fit is verified before switching the replication mode.
+	DefaultReplicationMigrationSampleSize = 1000
+	maxReportedMismatchedRegions          = 16
+)
+
+// ReplicationMigrationResult is the result of a replication mode migration.
+type ReplicationMigrationResult struct {
+	EnablePlacementRules bool                 `json:"enable-placement-rules"`
+	MaxReplicas          uint64               `json:"max-replicas"`
+	LocationLabels       typeutil.StringSlice `json:"location-labels"`
+	IsolationLevel       string               `json:"isolation-level"`
+	Rules                []*placement.Rule    `json:"rules,omitempty"`
+	SampledRegions       int                  `json:"sampled-regions"`
+	// MismatchedRegions lists some of the sampled regions which are
+	// satisfied in one mode but not in the other.
+	MismatchedRegions []uint64 `json:"mismatched-regions,omitempty"`
+	MismatchedCount   int      `json:"mismatched-count"`
+	Applied           bool     `json:"applied"`
+}
+
+// MigrateReplicationMode switches between max-replicas based replication and
+// placement rules based replication. The current config is converted into
+// the equivalent one of the other mode, the fit of a sample of regions is
+// verified to be the same in both modes, and only then the flag is flipped.
+// Any change made along the way is rolled back if the migration fails.
+func (s *Server) MigrateReplicationMode(enablePlacementRules bool, sampleSize int) (*ReplicationMigrationResult, error) {
+	s.replicationMigrationMu.Lock()
+	defer s.replicationMigrationMu.Unlock()
+
+	rc := s.GetRaftCluster()
+	if rc == nil {
+		return nil, errs.ErrNotBootstrapped.GenWithStackByArgs()
+	}
+	if sampleSize <= 0 {
+		sampleSize = DefaultReplicationMigrationSampleSize
+	}
+	cfg := s.GetReplicationConfig()
+	if cfg.EnablePlacementRules == enablePlacementRules {
+		return nil, errs.ErrReplicationMigration.FastGenByArgs(fmt.Sprintf("enable-placement-rules is already %t", enablePlacementRules))
+	}
+	if enablePlacementRules {
+		return s.migrateToPlacementRules(rc, cfg, sampleSize)
+	}
+	return s.migrateToMaxReplicas(rc, cfg, sampleSize)
+}
+
+func (s *Server) migrateToPlacementRules(rc *cluster.RaftCluster, cfg *config.ReplicationConfig, sampleSize int) (*ReplicationMigrationResult, error) {
+	ruleManager := rc.GetRuleManager()
+	// It creates the default rule from the current config if there is no rule,
+	// and the created rule is deleted if the migration fails.
+	created, err := ruleManager.TryInitialize(int(cfg.MaxReplicas), cfg.LocationLabels)
+	if err != nil {
+		return nil, err
+	}
+	rollback := func() {}
+	if created {
+		rollback = func() {
+			if err := ruleManager.Uninitialize(); err != nil {
+				log.Error("failed to roll back the created default rule", errs.ZapError(err))
+			}
+		}
+	}
+	// The default rule may be left by a previous period of placement rules,
+	// so convert the current config into it again.
+	if origin := ruleManager.GetRule("pd", "default"); !created && isWholeKeySpaceRule(origin) {
+		rule := origin.Clone()
+		rule.Count = int(cfg.MaxReplicas)
+		rule.LocationLabels = cfg.LocationLabels
+		rule.IsolationLevel = cfg.IsolationLevel
+		if rule.String() != origin.String() {
+			if err := ruleManager.SetRule(rule); err != nil {
+				return nil, err
+			}
+			rollback = func() {
+				if err := ruleManager.SetRule(origin); err != nil {
+					log.Error("failed to roll back the default rule", zap.String("rule", origin.String()), errs.ZapError(err))
+				}
+			}
+		}
+	}
+
+	cfg.EnablePlacementRules = true
+	result := newReplicationMigrationResult(cfg, ruleManager)
+	verifyReplicationMigration(rc, cfg, sampleSize, result)
+	if err := checkReplicationMigration(result); err != nil {
+		rollback()
+		return result, err
+	}
+	if err := s.SetReplicationConfig(*cfg); err != nil {
+		rollback()
+		return result, err
+	}
+	result.Applied = true
+	log.Info("migrated to placement rules", zap.Int("sampled-regions", result.SampledRegions))
+	return result, nil
+}
+
+func (s *Server) migrateToMaxReplicas(rc *cluster.RaftCluster, cfg *config.ReplicationConfig, sampleSize int) (*ReplicationMigrationResult, error) {
+	ruleManager := rc.GetRuleManager()
+	rules := ruleManager.GetAllRules()
+	if len(rules) != 1 || !isWholeKeySpaceRule(rules[0]) || rules[0].Role != placement.Voter || len(rules[0].LabelConstraints) > 0 {
+		return nil, errs.ErrReplicationMigration.FastGenByArgs("only a single voter rule without label constraints covering the whole key space can be converted to max-replicas")
+	}
+	rule := rules[0]
+	cfg.EnablePlacementRules = false
+	cfg.MaxReplicas = uint64(rule.Count)
+	cfg.LocationLabels = rule.LocationLabels
+	cfg.IsolationLevel = rule.IsolationLevel
+
+	result := newReplicationMigrationResult(cfg, ruleManager)
+	verifyReplicationMigration(rc, cfg, sampleSize, result)
+	if err := checkReplicationMigration(result); err != nil {
+		return result, err
+	}
+	// The rules are kept, nothing needs to be rolled back if it fails.
+	if err := s.SetReplicationConfig(*cfg); err != nil {
+		return result, err
+	}
+	result.Applied = true
+	log.Info("migrated to max-replicas", zap.Int("sampled-regions", result.SampledRegions))
+	return result, nil
+}
+
+func newReplicationMigrationResult(cfg *config.ReplicationConfig, ruleManager *placement.RuleManager) *ReplicationMigrationResult {
+	return &ReplicationMigrationResult{
+		EnablePlacementRules: cfg.EnablePlacementRules,
+		MaxReplicas:          cfg.MaxReplicas,
+		LocationLabels:       cfg.LocationLabels,
+		IsolationLevel:       cfg.IsolationLevel,
+		Rules:                ruleManager.GetAllRules(),
+	}
+}
+
+// verifyReplicationMigration compares whether the sampled regions are
+// satisfied with max-replicas and with the placement rules. Max-replicas is
+// converted into the equivalent rule, so both of them are checked by the fit,
+// including the location labels and the isolation level.
+func verifyReplicationMigration(rc *cluster.RaftCluster, cfg *config.ReplicationConfig, sampleSize int, result *ReplicationMigrationResult) {
+	ruleManager := rc.GetRuleManager()
+	maxReplicasRules := []*placement.Rule{{
+		GroupID:        "pd",
+		ID:             "default",
+		Role:           placement.Voter,
+		Count:          int(cfg.MaxReplicas),
+		LocationLabels: cfg.LocationLabels,
+		IsolationLevel: cfg.IsolationLevel,
+	}}
+	for _, region := range sampleRegions(rc.GetRegions(), sampleSize) {
+		result.SampledRegions++
+		satisfied := isRegionFitPlaced(placement.FitRegionWithRules(rc, region, maxReplicasRules))
+		if satisfied != isRegionFitPlaced(ruleManager.FitRegionWithoutCache(rc, region)) {
+			result.MismatchedCount++
+			if len(result.MismatchedRegions) < maxReportedMismatchedRegions {
+				result.MismatchedRegions = append(result.MismatchedRegions, region.GetID())
+			}
+		}
+	}
+}
+
+// isRegionFitPlaced returns whether the region satisfies the rules, and the
+// peers of each rule are isolated at the isolation level of the rule, which is
+// not required by the fit itself.
+func isRegionFitPlaced(fit *placement.RegionFit) bool {
+	if !fit.IsSatisfied() {
+		return false
+	}
+	stores := make(map[uint64]*core.StoreInfo)
+	for _, store := range fit.GetRegionStores() {
+		stores[store.GetID()] = store
+	}
+	for _, rf := range fit.RuleFits {
+		level := -1
+		for i, label := range rf.Rule.LocationLabels {
+			if label == rf.Rule.IsolationLevel {
+				level = i
+				break
+			}
+		}
+		if rf.Rule.IsolationLevel == "" || level < 0 {
+			continue
+		}
+		locations := make(map[string]struct{}, len(rf.Peers))
+		for _, peer := range rf.Peers {
+			store, ok := stores[peer.GetStoreId()]
+			if !ok {
+				return false
+			}
+			values := make([]string, 0, level+1)
+			for _, label := range rf.Rule.LocationLabels[:level+1] {
+				values = append(values, store.GetLabelValue(label))
+			}
+			location := strings.Join(values, "/")
+			if _, ok := locations[location]; ok {
+				return false
+			}
+			locations[location] = struct{}{}
+		}
+	}
+	return true
+}
+
+func checkReplicationMigration(result *ReplicationMigrationResult) error {
+	if result.MismatchedCount == 0 {
+		return nil
+	}
+	return errs.ErrReplicationMigration.FastGenByArgs(fmt.Sprintf("the fit of %d of %d sampled regions differs, e.g. %v",
+		result.MismatchedCount, result.SampledRegions, result.MismatchedRegions))
+}
+
+// sampleRegions picks at most n regions evenly from the given regions.
+func sampleRegions(regions []*core.RegionInfo, n int) []*core.RegionInfo {
+	if len(regions) <= n {
+		return regions
+	}
+	sampled := make([]*core.RegionInfo, 0, n)
+	for i := 0; i < n; i++ {
+		sampled = append(sampled, regions[i*len(regions)/n])
+	}
+	return sampled
+}
+
+func isWholeKeySpaceRule(rule *placement.Rule) bool {
+	return rule != nil && len(rule.StartKey) == 0 && len(rule.EndKey) == 0
+}
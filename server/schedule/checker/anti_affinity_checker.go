@@ -0,0 +1,143 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/filter"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/statistics"
+)
+
+const (
+	antiAffinityCheckerName = "anti_affinity_checker"
+	// antiAffinityLabel is the region label which enables the anti-affinity
+	// checker for the key range of the label rule.
+	antiAffinityLabel = "anti-affinity"
+	// maxAntiAffinityRegionCount is the max region count of a key range which
+	// is regarded as a small table.
+	maxAntiAffinityRegionCount = 16
+)
+
+// AntiAffinityChecker keeps the few regions of a small table from clustering on
+// one store. It only works for the key ranges labeled with anti-affinity.
+type AntiAffinityChecker struct {
+	PauseController
+	cluster     schedule.Cluster
+	ruleManager *placement.RuleManager
+	labeler     *labeler.RegionLabeler
+	counter     *prometheus.CounterVec
+}
+
+// NewAntiAffinityChecker creates an anti-affinity checker.
+func NewAntiAffinityChecker(cluster schedule.Cluster, ruleManager *placement.RuleManager, labeler *labeler.RegionLabeler) *AntiAffinityChecker {
+	return &AntiAffinityChecker{
+		cluster:     cluster,
+		ruleManager: ruleManager,
+		labeler:     labeler,
+		counter:     checkerCounter,
+	}
+}
+
+// dryRunCopy returns a copy of the checker for the dry runs.
+func (c *AntiAffinityChecker) dryRunCopy() *AntiAffinityChecker {
+	cp := *c
+	cp.counter = dryRunCheckerCounter
+	return &cp
+}
+
+// GetType returns the checker's type.
+func (c *AntiAffinityChecker) GetType() string {
+	return "anti-affinity-checker"
+}
+
+// Check verifies whether the peers of the region cluster on some stores, creating an Operator if need.
+func (c *AntiAffinityChecker) Check(region *core.RegionInfo) *operator.Operator {
+	if c.IsPaused() {
+		c.counter.WithLabelValues(antiAffinityCheckerName, "paused").Inc()
+		return nil
+	}
+	if c.labeler == nil {
+		return nil
+	}
+	startKey, endKey, ok := c.labeler.GetRegionLabelKeyRange(region, antiAffinityLabel)
+	if !ok {
+		return nil
+	}
+	regions := c.cluster.ScanRegions(startKey, endKey, maxAntiAffinityRegionCount+1)
+	if len(regions) > maxAntiAffinityRegionCount {
+		c.counter.WithLabelValues(antiAffinityCheckerName, "too-many-regions").Inc()
+		return nil
+	}
+
+	spread := statistics.CalculateRegionSpread(regions, c.cluster.GetStores(), "")
+	idealCount := spread.GetIdealPeerCount()
+	for _, peer := range region.GetPeers() {
+		sourceCount := spread.StorePeerCount[peer.GetStoreId()]
+		if sourceCount <= idealCount {
+			continue
+		}
+		source := c.cluster.GetStore(peer.GetStoreId())
+		if source == nil {
+			continue
+		}
+		target := c.selectTarget(region, source, spread.StorePeerCount)
+		if target == nil {
+			c.counter.WithLabelValues(antiAffinityCheckerName, "no-target-store").Inc()
+			continue
+		}
+		newPeer := &metapb.Peer{StoreId: target.GetID(), Role: peer.GetRole()}
+		op, err := operator.CreateMovePeerOperator("anti-affinity", c.cluster, region, operator.OpRegion, source.GetID(), newPeer)
+		if err != nil {
+			log.Debug("fail to create anti-affinity operator", errs.ZapError(err))
+			continue
+		}
+		c.counter.WithLabelValues(antiAffinityCheckerName, "new-operator").Inc()
+		return op
+	}
+	return nil
+}
+
+// selectTarget selects the store with the fewest peers of the key range as the target.
+func (c *AntiAffinityChecker) selectTarget(region *core.RegionInfo, source *core.StoreInfo, peerCount map[uint64]int) *core.StoreInfo {
+	filters := []filter.Filter{
+		filter.NewExcludedFilter(antiAffinityCheckerName, nil, region.GetStoreIDs()),
+		&filter.StoreStateFilter{ActionScope: antiAffinityCheckerName, MoveRegion: true},
+		filter.NewPlacementSafeguard(antiAffinityCheckerName, c.cluster.GetOpts(), c.cluster.GetBasicCluster(), c.ruleManager, region, source),
+	}
+	sourceCount := peerCount[source.GetID()]
+	var target *core.StoreInfo
+	for _, store := range c.cluster.GetStores() {
+		count := peerCount[store.GetID()]
+		// Avoid moving the peers back and forth.
+		if count+1 >= sourceCount {
+			continue
+		}
+		if target != nil && count >= peerCount[target.GetID()] {
+			continue
+		}
+		if filter.Target(c.cluster.GetOpts(), store, filters) {
+			target = store
+		}
+	}
+	return target
+}
@@ -0,0 +1,72 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+func TestAntiAffinityChecker(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := mockcluster.NewCluster(ctx, config.NewTestOptions())
+	cluster.SetEnablePlacementRules(false)
+	cluster.SetMaxReplicas(1)
+	checker := NewAntiAffinityChecker(cluster, cluster.RuleManager, cluster.RegionLabeler)
+	for i := uint64(1); i <= 4; i++ {
+		cluster.AddRegionStore(i, 10)
+	}
+	// All regions of the range [a, e) are on store 1.
+	keys := []string{"a", "b", "c", "d", "e"}
+	for i := 0; i < 4; i++ {
+		cluster.AddLeaderRegionWithRange(uint64(i+1), keys[i], keys[i+1], 1)
+	}
+
+	// No anti-affinity label.
+	re.Nil(checker.Check(cluster.GetRegion(1)))
+
+	re.NoError(cluster.RegionLabeler.SetLabelRule(&labeler.LabelRule{
+		ID:       "small-table",
+		Labels:   []labeler.RegionLabel{{Key: antiAffinityLabel, Value: "true"}},
+		RuleType: labeler.KeyRange,
+		Data:     makeKeyRanges("61", "65"),
+	}))
+	op := checker.Check(cluster.GetRegion(1))
+	re.NotNil(op)
+	re.Equal("anti-affinity", op.Desc())
+	re.NotZero(op.Kind() & operator.OpRegion)
+	re.Equal(uint64(1), op.Step(op.Len()-1).(operator.RemovePeer).FromStore)
+
+	// The regions are spread evenly.
+	for i := 0; i < 4; i++ {
+		cluster.AddLeaderRegionWithRange(uint64(i+1), keys[i], keys[i+1], uint64(i+1))
+	}
+	for i := uint64(1); i <= 4; i++ {
+		re.Nil(checker.Check(cluster.GetRegion(i)))
+	}
+
+	// Paused.
+	checker.PauseOrResume(60)
+	cluster.AddLeaderRegionWithRange(2, keys[1], keys[2], 1)
+	re.Nil(checker.Check(cluster.GetRegion(1)))
+}
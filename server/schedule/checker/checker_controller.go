@@ -43,6 +43,9 @@ type Controller struct {
 	splitChecker      *SplitChecker
 	mergeChecker      *MergeChecker
 	jointStateChecker *JointStateChecker
+	antiAffinity      *AntiAffinityChecker
+	orphanLearner     *OrphanLearnerChecker
+	learnerBalance    *LearnerBalanceChecker
 	priorityInspector *PriorityInspector
 	regionWaitingList cache.Cache
 	suspectRegions    *cache.TTLUint64 // suspectRegions are regions that may need fix
@@ -63,6 +66,9 @@ func NewController(ctx context.Context, cluster schedule.Cluster, ruleManager *p
 		splitChecker:      NewSplitChecker(cluster, ruleManager, labeler),
 		mergeChecker:      NewMergeChecker(ctx, cluster),
 		jointStateChecker: NewJointStateChecker(cluster),
+		antiAffinity:      NewAntiAffinityChecker(cluster, ruleManager, labeler),
+		orphanLearner:     NewOrphanLearnerChecker(cluster),
+		learnerBalance:    NewLearnerBalanceChecker(cluster, opController),
 		priorityInspector: NewPriorityInspector(cluster),
 		regionWaitingList: regionWaitingList,
 		suspectRegions:    cache.NewIDTTL(ctx, time.Minute, 3*time.Minute),
@@ -80,16 +86,22 @@ func (c *Controller) CheckRegion(region *core.RegionInfo) []*operator.Operator {
 		return []*operator.Operator{op}
 	}
 
+	replicaFrozen := false
 	if cl, ok := c.cluster.(interface{ GetRegionLabeler() *labeler.RegionLabeler }); ok {
 		l := cl.GetRegionLabeler()
 		if l.ScheduleDisabled(region) {
 			return nil
 		}
+		replicaFrozen = l.ReplicaFrozen(region)
 	}
 
 	if op := c.splitChecker.Check(region); op != nil {
 		return []*operator.Operator{op}
 	}
+	// The other checkers may change the peers of the region.
+	if replicaFrozen {
+		return nil
+	}
 
 	if c.opts.IsPlacementRulesEnabled() {
 		fit := c.priorityInspector.Inspect(region)
@@ -99,6 +111,12 @@ func (c *Controller) CheckRegion(region *core.RegionInfo) []*operator.Operator {
 			}
 			operator.OperatorLimitCounter.WithLabelValues(c.ruleChecker.GetType(), operator.OpReplica.String()).Inc()
 			c.regionWaitingList.Put(region.GetID(), nil)
+		} else if op := c.orphanLearner.CheckWithFit(region, fit); op != nil {
+			if opController.OperatorCount(operator.OpReplica) < c.opts.GetReplicaScheduleLimit() {
+				return []*operator.Operator{op}
+			}
+			operator.OperatorLimitCounter.WithLabelValues(c.orphanLearner.GetType(), operator.OpReplica.String()).Inc()
+			c.regionWaitingList.Put(region.GetID(), nil)
 		}
 	} else {
 		if op := c.learnerChecker.Check(region); op != nil {
@@ -113,6 +131,18 @@ func (c *Controller) CheckRegion(region *core.RegionInfo) []*operator.Operator {
 		}
 	}
 
+	if op := c.antiAffinity.Check(region); op != nil {
+		if opController.OperatorCount(operator.OpRegion) < c.opts.GetRegionScheduleLimit() {
+			return []*operator.Operator{op}
+		}
+		operator.OperatorLimitCounter.WithLabelValues(c.antiAffinity.GetType(), operator.OpRegion.String()).Inc()
+	}
+
+	// The learner balance checker has its own limit.
+	if op := c.learnerBalance.Check(region); op != nil {
+		return []*operator.Operator{op}
+	}
+
 	if c.mergeChecker != nil {
 		allowed := opController.OperatorCount(operator.OpMerge) < c.opts.GetMergeScheduleLimit()
 		if !allowed {
@@ -210,6 +240,9 @@ func (c *Controller) IsPendingRegion(regionID uint64) bool {
 	return exist
 }
 
+// CheckerNames are the names of the checkers which can be paused.
+var CheckerNames = []string{"learner", "replica", "rule", "split", "merge", "joint-state", "anti-affinity", "orphan-learner", "learner-balance"}
+
 // GetPauseController returns pause controller of the checker
 func (c *Controller) GetPauseController(name string) (*PauseController, error) {
 	switch name {
@@ -225,6 +258,12 @@ func (c *Controller) GetPauseController(name string) (*PauseController, error) {
 		return &c.mergeChecker.PauseController, nil
 	case "joint-state":
 		return &c.jointStateChecker.PauseController, nil
+	case "anti-affinity":
+		return &c.antiAffinity.PauseController, nil
+	case "orphan-learner":
+		return &c.orphanLearner.PauseController, nil
+	case "learner-balance":
+		return &c.learnerBalance.PauseController, nil
 	default:
 		return nil, errs.ErrCheckerNotFound.FastGenByArgs()
 	}
@@ -0,0 +1,124 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/labeler"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+// The reasons reported by the dry-run of a checker.
+const (
+	DryRunProposed                = "proposed"
+	DryRunNoOperator              = "no-operator"
+	DryRunCheckerPaused           = "checker-paused"
+	DryRunScheduleDisabled        = "schedule-disabled"
+	DryRunReplicaFrozen           = "replica-frozen"
+	DryRunPlacementRulesEnabled   = "placement-rules-enabled"
+	DryRunPlacementRulesDisabled  = "placement-rules-disabled"
+	DryRunMergeCheckerUnavailable = "merge-checker-unavailable"
+)
+
+// DryRunResult is the result of running a checker on a region without adding
+// the operators it proposes.
+type DryRunResult struct {
+	RegionID  uint64
+	Operators []*operator.Operator
+	// Reason is the description of the first proposed operator, or why the
+	// checker proposes nothing.
+	Reason string
+}
+
+// DryRun runs the checker with the given name on the region and returns the
+// proposed operators instead of adding them. Unlike CheckRegion, the operator
+// limits are not considered, so it shows what the checker alone wants to do.
+// The dry run uses a copy of the checker, which reads the state of the checker
+// but never changes it, e.g. the waiting list or the grace periods of the
+// orphan learners, and its events are not counted in the metrics.
+func (c *Controller) DryRun(name string, region *core.RegionInfo) (*DryRunResult, error) {
+	p, err := c.GetPauseController(name)
+	if err != nil {
+		return nil, err
+	}
+	result := &DryRunResult{RegionID: region.GetID()}
+	if p.IsPaused() {
+		result.Reason = DryRunCheckerPaused
+		return result, nil
+	}
+	if cl, ok := c.cluster.(interface{ GetRegionLabeler() *labeler.RegionLabeler }); ok {
+		l := cl.GetRegionLabeler()
+		if l.ScheduleDisabled(region) {
+			result.Reason = DryRunScheduleDisabled
+			return result, nil
+		}
+		// Only the split and joint state checkers are allowed to handle the
+		// regions whose replicas are frozen.
+		if l.ReplicaFrozen(region) && name != "split" && name != "joint-state" {
+			result.Reason = DryRunReplicaFrozen
+			return result, nil
+		}
+	}
+
+	var op *operator.Operator
+	switch name {
+	case "learner":
+		op = c.learnerChecker.dryRunCopy().Check(region)
+	case "replica":
+		if c.opts.IsPlacementRulesEnabled() {
+			result.Reason = DryRunPlacementRulesEnabled
+			return result, nil
+		}
+		op = c.replicaChecker.dryRunCopy().Check(region)
+	case "rule":
+		if !c.opts.IsPlacementRulesEnabled() {
+			result.Reason = DryRunPlacementRulesDisabled
+			return result, nil
+		}
+		op = c.ruleChecker.dryRunCopy().Check(region)
+	case "orphan-learner":
+		if !c.opts.IsPlacementRulesEnabled() {
+			result.Reason = DryRunPlacementRulesDisabled
+			return result, nil
+		}
+		op = c.orphanLearner.dryRunCopy().CheckWithFit(region, c.cluster.GetRuleManager().FitRegion(c.cluster, region))
+	case "split":
+		op = c.splitChecker.dryRunCopy().Check(region)
+	case "merge":
+		if c.mergeChecker == nil {
+			result.Reason = DryRunMergeCheckerUnavailable
+			return result, nil
+		}
+		result.Operators = c.mergeChecker.dryRunCopy().Check(region)
+	case "joint-state":
+		op = c.jointStateChecker.dryRunCopy().Check(region)
+	case "anti-affinity":
+		op = c.antiAffinity.dryRunCopy().Check(region)
+	case "learner-balance":
+		op = c.learnerBalance.dryRunCopy().Check(region)
+	default:
+		return nil, errs.ErrCheckerNotFound.FastGenByArgs()
+	}
+	if op != nil {
+		result.Operators = []*operator.Operator{op}
+	}
+	if len(result.Operators) == 0 {
+		result.Reason = DryRunNoOperator
+	} else {
+		result.Reason = result.Operators[0].Desc()
+	}
+	return result, nil
+}
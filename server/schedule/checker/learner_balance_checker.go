@@ -0,0 +1,268 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"math"
+	"sort"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/filter"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+const (
+	learnerBalanceCheckerName = "learner_balance_checker"
+	// BalanceLearnerDesc is the description of the operators balancing the
+	// learners, which are created by both the checker and the scheduler.
+	BalanceLearnerDesc = "balance-learner"
+	// learnerBalanceTolerantRatio is the ratio of the average learner count of
+	// the stores with the same engine, within which the checker leaves them.
+	learnerBalanceTolerantRatio = 0.05
+)
+
+// LearnerGroup is the learner distribution of the stores with the same engine.
+type LearnerGroup struct {
+	Engine string `json:"engine"`
+	// Stores are ordered by the learner count descending.
+	Stores []LearnerStore `json:"stores"`
+	Skew   int            `json:"skew"`
+}
+
+// LearnerStore is the learner count of a store, including the influence of
+// the pending learner balance operators.
+type LearnerStore struct {
+	StoreID      uint64 `json:"store-id"`
+	LearnerCount int    `json:"learner-count"`
+}
+
+// Tolerance returns the learner count difference between two stores of the
+// group, within which they are considered balanced.
+func (g LearnerGroup) Tolerance(tolerantRatio float64) float64 {
+	total := 0
+	for _, store := range g.Stores {
+		total += store.LearnerCount
+	}
+	// Moving a learner between the stores whose difference is less than 2
+	// only swaps their counts.
+	return math.Max(2, tolerantRatio*float64(total)/float64(len(g.Stores)))
+}
+
+// CollectLearnerGroups groups the stores which learners can be moved from and
+// to by engine, and the groups are ordered by the skew descending. The learner
+// counts include the influence of the given operators balancing the learners,
+// because the heartbeats don't reflect them until they finish.
+func CollectLearnerGroups(cluster schedule.Cluster, filters []filter.Filter, ops []*operator.Operator) ([]LearnerGroup, map[uint64]*core.StoreInfo) {
+	pending := make([]*operator.Operator, 0, len(ops))
+	for _, op := range ops {
+		if op.Desc() == BalanceLearnerDesc {
+			pending = append(pending, op)
+		}
+	}
+	influence := schedule.NewTotalOpInfluence(pending, cluster)
+
+	opts := cluster.GetOpts()
+	candidates := filter.NewCandidates(cluster.GetStores()).
+		FilterSource(opts, filters...).
+		FilterTarget(opts, filters...)
+	stores := make(map[uint64]*core.StoreInfo, len(candidates.Stores))
+	byEngine := make(map[string][]LearnerStore)
+	for _, store := range candidates.Stores {
+		engine := store.GetLabelValue(core.EngineKey)
+		if engine == "" {
+			engine = core.EngineTiKV
+		}
+		stores[store.GetID()] = store
+		count := cluster.GetBasicCluster().GetStoreLearnerCount(store.GetID()) +
+			int(influence.GetStoreInfluence(store.GetID()).RegionCount)
+		byEngine[engine] = append(byEngine[engine], LearnerStore{StoreID: store.GetID(), LearnerCount: count})
+	}
+	groups := make([]LearnerGroup, 0, len(byEngine))
+	for engine, group := range byEngine {
+		sort.Slice(group, func(i, j int) bool {
+			if group[i].LearnerCount != group[j].LearnerCount {
+				return group[i].LearnerCount > group[j].LearnerCount
+			}
+			return group[i].StoreID < group[j].StoreID
+		})
+		skew := group[0].LearnerCount - group[len(group)-1].LearnerCount
+		groups = append(groups, LearnerGroup{Engine: engine, Stores: group, Skew: skew})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		if groups[i].Skew != groups[j].Skew {
+			return groups[i].Skew > groups[j].Skew
+		}
+		return groups[i].Engine < groups[j].Engine
+	})
+	return groups, stores
+}
+
+// SelectLearnerTarget returns the store with the fewest learners in the group
+// which the learner of the region on the source store can be moved to without
+// breaking the placement, or 0 if there is none.
+func SelectLearnerTarget(cluster schedule.Cluster, scope string, region *core.RegionInfo, group LearnerGroup, stores map[uint64]*core.StoreInfo, source LearnerStore, tolerance float64) uint64 {
+	filters := []filter.Filter{
+		filter.NewExcludedFilter(scope, nil, region.GetStoreIDs()),
+		filter.NewPlacementSafeguard(scope, cluster.GetOpts(), cluster.GetBasicCluster(), cluster.GetRuleManager(), region, stores[source.StoreID]),
+	}
+	for i := len(group.Stores) - 1; i >= 0; i-- {
+		target := group.Stores[i]
+		if float64(source.LearnerCount-target.LearnerCount) < tolerance {
+			break
+		}
+		if filter.Target(cluster.GetOpts(), stores[target.StoreID], filters) {
+			return target.StoreID
+		}
+	}
+	return 0
+}
+
+// IsWitnessPeer checks whether the peer of the region is placed by a witness
+// rule. The witnesses hold no data, so they are not counted as the learners to
+// balance and they are never moved by the learner balancing.
+func IsWitnessPeer(cluster schedule.Cluster, region *core.RegionInfo, peerID uint64) bool {
+	if !cluster.GetOpts().IsPlacementRulesEnabled() {
+		return false
+	}
+	fit := cluster.GetRuleManager().FitRegion(cluster, region)
+	rf := fit.GetRuleFit(peerID)
+	return rf != nil && rf.Rule.IsWitness
+}
+
+// LearnerBalanceChecker moves the learners of the patrolled regions off the
+// stores which have much more learners than the other stores with the same
+// engine, so that the skew is fixed as the regions are patrolled instead of
+// waiting for the balance-learner-scheduler.
+type LearnerBalanceChecker struct {
+	PauseController
+	cluster      schedule.Cluster
+	opController *schedule.OperatorController
+	filters      []filter.Filter
+	counter      *prometheus.CounterVec
+}
+
+// NewLearnerBalanceChecker creates a learner balance checker.
+func NewLearnerBalanceChecker(cluster schedule.Cluster, opController *schedule.OperatorController) *LearnerBalanceChecker {
+	return &LearnerBalanceChecker{
+		cluster:      cluster,
+		opController: opController,
+		filters: []filter.Filter{
+			&filter.StoreStateFilter{ActionScope: learnerBalanceCheckerName, MoveRegion: true},
+			filter.NewSpecialUseFilter(learnerBalanceCheckerName),
+		},
+		counter: checkerCounter,
+	}
+}
+
+// dryRunCopy returns a copy of the checker for the dry runs.
+func (c *LearnerBalanceChecker) dryRunCopy() *LearnerBalanceChecker {
+	cp := *c
+	cp.counter = dryRunCheckerCounter
+	return &cp
+}
+
+// GetType returns the checker's type.
+func (c *LearnerBalanceChecker) GetType() string {
+	return "learner-balance-checker"
+}
+
+// isAllowed checks whether the running learner balance operators are under the
+// limit of the checker.
+func (c *LearnerBalanceChecker) isAllowed(ops []*operator.Operator) bool {
+	limit := c.cluster.GetOpts().GetLearnerBalanceScheduleLimit()
+	running := uint64(0)
+	for _, op := range ops {
+		if op.Desc() == BalanceLearnerDesc {
+			running++
+		}
+	}
+	return running < limit
+}
+
+// Check verifies whether the learners of the region are on the stores with too
+// many learners, creating an Operator to move one of them if need.
+func (c *LearnerBalanceChecker) Check(region *core.RegionInfo) *operator.Operator {
+	if c.cluster.GetOpts().GetLearnerBalanceScheduleLimit() == 0 {
+		return nil
+	}
+	if c.IsPaused() {
+		c.counter.WithLabelValues(learnerBalanceCheckerName, "paused").Inc()
+		return nil
+	}
+	if len(region.GetLearners()) == 0 {
+		return nil
+	}
+	if filter.SelectOneRegion([]*core.RegionInfo{region}, filter.NewRegionPengdingFilter(),
+		filter.NewRegionDownFilter(), filter.NewRegionReplicatedFilter(c.cluster)) == nil {
+		c.counter.WithLabelValues(learnerBalanceCheckerName, "unhealthy-region").Inc()
+		return nil
+	}
+	ops := c.opController.GetOperators()
+	if !c.isAllowed(ops) {
+		operator.OperatorLimitCounter.WithLabelValues(c.GetType(), operator.OpRegion.String()).Inc()
+		return nil
+	}
+	groups, stores := CollectLearnerGroups(c.cluster, c.filters, ops)
+	for _, learner := range region.GetLearners() {
+		group, source, ok := findLearnerStore(groups, learner.GetStoreId())
+		if !ok {
+			continue
+		}
+		tolerance := group.Tolerance(learnerBalanceTolerantRatio)
+		if float64(source.LearnerCount-group.Stores[len(group.Stores)-1].LearnerCount) < tolerance {
+			continue
+		}
+		if IsWitnessPeer(c.cluster, region, learner.GetId()) {
+			c.counter.WithLabelValues(learnerBalanceCheckerName, "skip-witness").Inc()
+			continue
+		}
+		target := SelectLearnerTarget(c.cluster, learnerBalanceCheckerName, region, group, stores, source, tolerance)
+		if target == 0 {
+			c.counter.WithLabelValues(learnerBalanceCheckerName, "no-target-store").Inc()
+			continue
+		}
+		op, err := operator.CreateMovePeerOperator(BalanceLearnerDesc, c.cluster, region, operator.OpRegion,
+			source.StoreID, &metapb.Peer{StoreId: target, Role: learner.GetRole()})
+		if err != nil {
+			log.Debug("fail to create balance learner operator", errs.ZapError(err))
+			c.counter.WithLabelValues(learnerBalanceCheckerName, "create-operator-fail").Inc()
+			return nil
+		}
+		op.AddReasons(operator.NewReason(learnerBalanceCheckerName, "learner-skew").
+			With("engine", group.Engine).
+			With("source-learner-count", source.LearnerCount).
+			With("tolerance", tolerance))
+		c.counter.WithLabelValues(learnerBalanceCheckerName, "new-operator").Inc()
+		return op
+	}
+	return nil
+}
+
+// findLearnerStore returns the group of the store and its learner count.
+func findLearnerStore(groups []LearnerGroup, storeID uint64) (LearnerGroup, LearnerStore, bool) {
+	for _, group := range groups {
+		for _, store := range group.Stores {
+			if store.StoreID == storeID {
+				return group, store, true
+			}
+		}
+	}
+	return LearnerGroup{}, LearnerStore{}, false
+}
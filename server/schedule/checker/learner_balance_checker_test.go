@@ -0,0 +1,85 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/hbstream"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/versioninfo"
+)
+
+func TestLearnerBalanceChecker(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := mockcluster.NewCluster(ctx, config.NewTestOptions())
+	cluster.SetClusterVersion(versioninfo.MinSupportedVersion(versioninfo.Version4_0))
+	cluster.SetEnablePlacementRules(true)
+	re.NoError(cluster.RuleManager.SetRule(&placement.Rule{
+		GroupID:          "tiflash",
+		ID:               "learner",
+		Role:             placement.Learner,
+		Count:            1,
+		LabelConstraints: []placement.LabelConstraint{{Key: core.EngineKey, Op: placement.In, Values: []string{core.EngineTiFlash}}},
+	}))
+	oc := schedule.NewOperatorController(ctx, cluster, hbstream.NewTestHeartbeatStreams(ctx, cluster.ID, cluster, false))
+	checker := NewLearnerBalanceChecker(cluster, oc)
+
+	// Stores 1, 2, 3 are TiKV, and stores 4, 5, 6 are TiFlash.
+	for id := uint64(1); id <= 3; id++ {
+		cluster.AddRegionStore(id, 5)
+	}
+	for id := uint64(4); id <= 6; id++ {
+		cluster.AddLabelsStore(id, 0, map[string]string{core.EngineKey: core.EngineTiFlash})
+	}
+	// The learners on the TiFlash stores are 4, 1, 0.
+	for id := uint64(1); id <= 4; id++ {
+		cluster.AddRegionWithLearner(id, 1, []uint64{2, 3}, []uint64{4})
+	}
+	cluster.AddRegionWithLearner(5, 1, []uint64{2, 3}, []uint64{5})
+
+	// The checker is disabled by default.
+	re.Nil(checker.Check(cluster.GetRegion(1)))
+
+	cluster.SetLearnerBalanceScheduleLimit(1)
+	op := checker.Check(cluster.GetRegion(1))
+	testutil.CheckTransferLearner(re, op, operator.OpRegion, 4, 6)
+	re.Equal(BalanceLearnerDesc, op.Desc())
+	// The learner on store 5 is within the tolerance.
+	re.Nil(checker.Check(cluster.GetRegion(5)))
+
+	// The running operators are limited.
+	re.Equal(1, oc.AddWaitingOperator(op))
+	re.Nil(checker.Check(cluster.GetRegion(2)))
+
+	// The running operators are counted, the learners are 3, 1, 1 with it.
+	cluster.SetLearnerBalanceScheduleLimit(2)
+	op = checker.Check(cluster.GetRegion(2))
+	testutil.CheckTransferLearner(re, op, operator.OpRegion, 4, 6)
+	// The learners are 2, 1, 2 with both of them.
+	re.Equal(1, oc.AddWaitingOperator(op))
+	cluster.SetLearnerBalanceScheduleLimit(3)
+	re.Nil(checker.Check(cluster.GetRegion(3)))
+}
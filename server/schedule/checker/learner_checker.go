@@ -16,6 +16,7 @@ package checker
 
 import (
 	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
@@ -26,19 +27,28 @@ import (
 type LearnerChecker struct {
 	PauseController
 	cluster schedule.Cluster
+	counter *prometheus.CounterVec
 }
 
 // NewLearnerChecker creates a learner checker.
 func NewLearnerChecker(cluster schedule.Cluster) *LearnerChecker {
 	return &LearnerChecker{
 		cluster: cluster,
+		counter: checkerCounter,
 	}
 }
 
+// dryRunCopy returns a copy of the checker for the dry runs.
+func (l *LearnerChecker) dryRunCopy() *LearnerChecker {
+	cp := *l
+	cp.counter = dryRunCheckerCounter
+	return &cp
+}
+
 // Check verifies a region's role, creating an Operator if need.
 func (l *LearnerChecker) Check(region *core.RegionInfo) *operator.Operator {
 	if l.IsPaused() {
-		checkerCounter.WithLabelValues("learner_checker", "paused").Inc()
+		l.counter.WithLabelValues("learner_checker", "paused").Inc()
 		return nil
 	}
 	for _, p := range region.GetLearners() {
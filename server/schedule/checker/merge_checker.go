@@ -17,9 +17,11 @@ package checker
 import (
 	"bytes"
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/pkg/cache"
 	"github.com/tikv/pd/pkg/codec"
 	"github.com/tikv/pd/pkg/errs"
@@ -31,6 +33,7 @@ import (
 	"github.com/tikv/pd/server/schedule/labeler"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/statistics"
 )
 
 const (
@@ -52,6 +55,10 @@ type MergeChecker struct {
 	opts       *config.PersistOptions
 	splitCache *cache.TTLUint64
 	startTime  time.Time // it's used to judge whether server recently start.
+	counter    *prometheus.CounterVec
+	// dryRun is true for the copies created for the dry runs, which never
+	// change the state shared with the checker.
+	dryRun bool
 }
 
 // NewMergeChecker creates a merge checker.
@@ -63,9 +70,17 @@ func NewMergeChecker(ctx context.Context, cluster schedule.Cluster) *MergeChecke
 		opts:       opts,
 		splitCache: splitCache,
 		startTime:  time.Now(),
+		counter:    checkerCounter,
 	}
 }
 
+// dryRunCopy returns a copy of the checker for the dry runs.
+func (m *MergeChecker) dryRunCopy() *MergeChecker {
+	cp := *m
+	cp.counter, cp.dryRun = dryRunCheckerCounter, true
+	return &cp
+}
+
 // GetType return MergeChecker's type
 func (m *MergeChecker) GetType() string {
 	return "merge-checker"
@@ -81,22 +96,24 @@ func (m *MergeChecker) RecordRegionSplit(regionIDs []uint64) {
 
 // Check verifies a region's replicas, creating an Operator if need.
 func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
-	checkerCounter.WithLabelValues("merge_checker", "check").Inc()
+	m.counter.WithLabelValues("merge_checker", "check").Inc()
 
 	if m.IsPaused() {
-		checkerCounter.WithLabelValues("merge_checker", "paused").Inc()
+		m.counter.WithLabelValues("merge_checker", "paused").Inc()
 		return nil
 	}
 
 	expireTime := m.startTime.Add(m.opts.GetSplitMergeInterval())
 	if time.Now().Before(expireTime) {
-		checkerCounter.WithLabelValues("merge_checker", "recently-start").Inc()
+		m.counter.WithLabelValues("merge_checker", "recently-start").Inc()
 		return nil
 	}
 
-	m.splitCache.UpdateTTL(m.opts.GetSplitMergeInterval())
+	if !m.dryRun {
+		m.splitCache.UpdateTTL(m.opts.GetSplitMergeInterval())
+	}
 	if m.splitCache.Exists(region.GetID()) {
-		checkerCounter.WithLabelValues("merge_checker", "recently-split").Inc()
+		m.counter.WithLabelValues("merge_checker", "recently-split").Inc()
 		return nil
 	}
 
@@ -105,30 +122,36 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	// pd don't know the real size of one region until the first heartbeat of the region
 	// thus here when size is 0, just skip.
 	if region.GetApproximateSize() == 0 {
-		checkerCounter.WithLabelValues("merge_checker", "skip").Inc()
+		m.counter.WithLabelValues("merge_checker", "skip").Inc()
 		return nil
 	}
 
 	// region is not small enough
 	if !region.NeedMerge(int64(m.opts.GetMaxMergeRegionSize()), int64(m.opts.GetMaxMergeRegionKeys())) {
-		checkerCounter.WithLabelValues("merge_checker", "no-need").Inc()
+		m.counter.WithLabelValues("merge_checker", "no-need").Inc()
 		return nil
 	}
 
 	// skip region has down peers or pending peers
 	if !filter.IsRegionHealthy(region) {
-		checkerCounter.WithLabelValues("merge_checker", "special-peer").Inc()
+		m.counter.WithLabelValues("merge_checker", "special-peer").Inc()
 		return nil
 	}
 
 	if !filter.IsRegionReplicated(m.cluster, region) {
-		checkerCounter.WithLabelValues("merge_checker", "abnormal-replica").Inc()
+		m.counter.WithLabelValues("merge_checker", "abnormal-replica").Inc()
 		return nil
 	}
 
 	// skip hot region
 	if m.cluster.IsRegionHot(region) {
-		checkerCounter.WithLabelValues("merge_checker", "hot-region").Inc()
+		m.counter.WithLabelValues("merge_checker", "hot-region").Inc()
+		return nil
+	}
+
+	// skip region which is about to be hot, as it may be split by load soon
+	if m.isNearHotWrite(region) {
+		m.counter.WithLabelValues("merge_checker", "near-hot-write").Inc()
 		return nil
 	}
 
@@ -145,7 +168,7 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 	}
 
 	if target == nil {
-		checkerCounter.WithLabelValues("merge_checker", "no-target").Inc()
+		m.counter.WithLabelValues("merge_checker", "no-target").Inc()
 		return nil
 	}
 
@@ -155,18 +178,18 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 		maxTargetRegionSizeThreshold = maxTargetRegionSize
 	}
 	if target.GetApproximateSize() > maxTargetRegionSizeThreshold {
-		checkerCounter.WithLabelValues("merge_checker", "target-too-large").Inc()
+		m.counter.WithLabelValues("merge_checker", "target-too-large").Inc()
 		return nil
 	}
 	if err := m.cluster.GetStoreConfig().CheckRegionSize(uint64(target.GetApproximateSize()+region.GetApproximateSize()),
 		m.opts.GetMaxMergeRegionSize()); err != nil {
-		checkerCounter.WithLabelValues("merge_checker", "split-size-after-merge").Inc()
+		m.counter.WithLabelValues("merge_checker", "split-size-after-merge").Inc()
 		return nil
 	}
 
 	if err := m.cluster.GetStoreConfig().CheckRegionKeys(uint64(target.GetApproximateKeys()+region.GetApproximateKeys()),
 		m.opts.GetMaxMergeRegionKeys()); err != nil {
-		checkerCounter.WithLabelValues("merge_checker", "split-keys-after-merge").Inc()
+		m.counter.WithLabelValues("merge_checker", "split-keys-after-merge").Inc()
 		return nil
 	}
 
@@ -178,53 +201,122 @@ func (m *MergeChecker) Check(region *core.RegionInfo) []*operator.Operator {
 		log.Warn("create merge region operator failed", errs.ZapError(err))
 		return nil
 	}
-	checkerCounter.WithLabelValues("merge_checker", "new-operator").Inc()
+	reason := operator.NewReason(m.GetType(), "small-region").
+		With("region-size", region.GetApproximateSize()).
+		With("region-keys", region.GetApproximateKeys()).
+		With("max-merge-region-size", m.opts.GetMaxMergeRegionSize()).
+		With("max-merge-region-keys", m.opts.GetMaxMergeRegionKeys()).
+		With("target-region-id", target.GetID())
+	for _, op := range ops {
+		op.AddReasons(reason)
+	}
+	m.counter.WithLabelValues("merge_checker", "new-operator").Inc()
 	if region.GetApproximateSize() > target.GetApproximateSize() ||
 		region.GetApproximateKeys() > target.GetApproximateKeys() {
-		checkerCounter.WithLabelValues("merge_checker", "larger-source").Inc()
+		m.counter.WithLabelValues("merge_checker", "larger-source").Inc()
 	}
 	return ops
 }
 
 func (m *MergeChecker) checkTarget(region, adjacent *core.RegionInfo) bool {
 	if adjacent == nil {
-		checkerCounter.WithLabelValues("merge_checker", "adj-not-exist").Inc()
+		m.counter.WithLabelValues("merge_checker", "adj-not-exist").Inc()
 		return false
 	}
 
 	if m.splitCache.Exists(adjacent.GetID()) {
-		checkerCounter.WithLabelValues("merge_checker", "adj-recently-split").Inc()
+		m.counter.WithLabelValues("merge_checker", "adj-recently-split").Inc()
 		return false
 	}
 
 	if m.cluster.IsRegionHot(adjacent) {
-		checkerCounter.WithLabelValues("merge_checker", "adj-region-hot").Inc()
+		m.counter.WithLabelValues("merge_checker", "adj-region-hot").Inc()
+		return false
+	}
+
+	if m.isNearHotWrite(adjacent) {
+		m.counter.WithLabelValues("merge_checker", "adj-near-hot-write").Inc()
 		return false
 	}
 
 	if !AllowMerge(m.cluster, region, adjacent) {
-		checkerCounter.WithLabelValues("merge_checker", "adj-disallow-merge").Inc()
+		m.counter.WithLabelValues("merge_checker", "adj-disallow-merge").Inc()
 		return false
 	}
 
 	if !checkPeerStore(m.cluster, region, adjacent) {
-		checkerCounter.WithLabelValues("merge_checker", "adj-abnormal-peerstore").Inc()
+		m.counter.WithLabelValues("merge_checker", "adj-abnormal-peerstore").Inc()
 		return false
 	}
 
 	if !filter.IsRegionHealthy(adjacent) {
-		checkerCounter.WithLabelValues("merge_checker", "adj-special-peer").Inc()
+		m.counter.WithLabelValues("merge_checker", "adj-special-peer").Inc()
 		return false
 	}
 
 	if !filter.IsRegionReplicated(m.cluster, adjacent) {
-		checkerCounter.WithLabelValues("merge_checker", "adj-abnormal-replica").Inc()
+		m.counter.WithLabelValues("merge_checker", "adj-abnormal-replica").Inc()
 		return false
 	}
 
 	return true
 }
 
+// CheckMergeable returns nil if the region can be merged with the adjacent target
+// under the current config, placement rules and region labels. Unlike Check, the
+// regions are not required to be small or cold, since the merge is requested
+// explicitly.
+func CheckMergeable(cluster schedule.Cluster, region, target *core.RegionInfo) error {
+	notMergeable := func(reason string) error {
+		return errs.ErrRegionNotMergeable.FastGenByArgs(region.GetID(), target.GetID(), reason)
+	}
+	if (!bytes.Equal(region.GetEndKey(), target.GetStartKey()) || len(region.GetEndKey()) == 0) &&
+		(!bytes.Equal(target.GetEndKey(), region.GetStartKey()) || len(target.GetEndKey()) == 0) {
+		return notMergeable("they are not adjacent")
+	}
+	for _, r := range []*core.RegionInfo{region, target} {
+		if !filter.IsRegionHealthy(r) {
+			return notMergeable(fmt.Sprintf("region %d has down or pending peers", r.GetID()))
+		}
+		if !filter.IsRegionReplicated(cluster, r) {
+			return notMergeable(fmt.Sprintf("region %d is not replicated as configured", r.GetID()))
+		}
+	}
+	if !AllowMerge(cluster, region, target) {
+		return notMergeable("it is denied by the key type, the placement rules or the region labels")
+	}
+	if !checkPeerStore(cluster, region, target) {
+		return notMergeable("the target region has peers on the removing stores")
+	}
+	opts, storeConfig := cluster.GetOpts(), cluster.GetStoreConfig()
+	if err := storeConfig.CheckRegionSize(uint64(region.GetApproximateSize()+target.GetApproximateSize()), opts.GetMaxMergeRegionSize()); err != nil {
+		return notMergeable("the merged region will be split and merged again by size")
+	}
+	if err := storeConfig.CheckRegionKeys(uint64(region.GetApproximateKeys()+target.GetApproximateKeys()), opts.GetMaxMergeRegionKeys()); err != nil {
+		return notMergeable("the merged region will be split and merged again by keys")
+	}
+	return nil
+}
+
+// isNearHotWrite returns true if the write rate of any peer of the region exceeds
+// the configured fraction of the load-based split threshold.
+func (m *MergeChecker) isNearHotWrite(region *core.RegionInfo) bool {
+	ratio := m.opts.GetMergeHotWriteRatio()
+	if ratio <= 0 {
+		return false
+	}
+	storeConfig := m.cluster.GetStoreConfig()
+	byteThreshold := float64(storeConfig.GetSplitByteThreshold()) * ratio
+	queryThreshold := float64(storeConfig.GetSplitQPSThreshold()) * ratio
+	for _, stat := range m.cluster.RegionPeerWriteStats(region) {
+		if stat.GetLoad(statistics.RegionWriteBytes) >= byteThreshold ||
+			stat.GetLoad(statistics.RegionWriteQuery) >= queryThreshold {
+			return true
+		}
+	}
+	return false
+}
+
 // AllowMerge returns true if two regions can be merged according to the key type.
 func AllowMerge(cluster schedule.Cluster, region, adjacent *core.RegionInfo) bool {
 	var start, end []byte
@@ -22,6 +22,7 @@ import (
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/stretchr/testify/suite"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/mock/mockcluster"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server/config"
@@ -32,6 +33,7 @@ import (
 	"github.com/tikv/pd/server/schedule/labeler"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/statistics"
 	"github.com/tikv/pd/server/versioninfo"
 	"go.uber.org/goleak"
 )
@@ -247,6 +249,46 @@ func (suite *mergeCheckerTestSuite) TestBasic() {
 	suite.NotNil(ops)
 }
 
+func (suite *mergeCheckerTestSuite) TestNearHotWrite() {
+	suite.cluster.SetSplitMergeInterval(0)
+	suite.cluster.SetMergeHotWriteRatio(0.5)
+	ops := suite.mc.Check(suite.regions[2])
+	suite.NotNil(ops)
+
+	threshold := float64(suite.cluster.GetStoreConfig().GetSplitByteThreshold())
+	newWriteStat := func(regionID, storeID uint64, bytes float64) *statistics.HotPeerStat {
+		loads := make([]float64, statistics.RegionStatCount)
+		loads[statistics.RegionWriteBytes] = bytes
+		return &statistics.HotPeerStat{
+			StoreID:  storeID,
+			RegionID: regionID,
+			Kind:     statistics.Write,
+			Loads:    loads,
+		}
+	}
+	// the write rate is lower than the fraction of the split threshold.
+	suite.cluster.HotCache.Update(newWriteStat(3, 6, threshold*0.3))
+	ops = suite.mc.Check(suite.regions[2])
+	suite.NotNil(ops)
+
+	// the region is about to be hot.
+	suite.cluster.HotCache.Update(newWriteStat(3, 6, threshold*0.6))
+	ops = suite.mc.Check(suite.regions[2])
+	suite.Nil(ops)
+
+	// the check is disabled.
+	suite.cluster.SetMergeHotWriteRatio(0)
+	ops = suite.mc.Check(suite.regions[2])
+	suite.NotNil(ops)
+
+	// the adjacent region is about to be hot.
+	suite.cluster.SetMergeHotWriteRatio(0.5)
+	suite.cluster.HotCache.Update(newWriteStat(3, 6, 0))
+	suite.cluster.HotCache.Update(newWriteStat(2, 4, threshold*0.6))
+	ops = suite.mc.Check(suite.regions[2])
+	suite.Nil(ops)
+}
+
 func (suite *mergeCheckerTestSuite) TestMatchPeers() {
 	suite.cluster.SetSplitMergeInterval(0)
 	// partial store overlap not including leader
@@ -509,6 +551,35 @@ func (suite *mergeCheckerTestSuite) TestStoreLimitWithMerge() {
 	}
 }
 
+func (suite *mergeCheckerTestSuite) TestCheckMergeable() {
+	checkNotMergeable := func(region, target *core.RegionInfo) {
+		err := CheckMergeable(suite.cluster, region, target)
+		suite.True(errs.ErrRegionNotMergeable.Equal(err), err)
+	}
+	// The regions are not required to be small.
+	suite.NoError(CheckMergeable(suite.cluster, suite.regions[2], suite.regions[1]))
+	suite.NoError(CheckMergeable(suite.cluster, suite.regions[1], suite.regions[2]))
+	// The regions are not adjacent.
+	checkNotMergeable(suite.regions[0], suite.regions[2])
+	// Region 4 is not replicated.
+	checkNotMergeable(suite.regions[2], suite.regions[3])
+
+	// The merge is denied by the label.
+	suite.cluster.GetRegionLabeler().SetLabelRule(&labeler.LabelRule{
+		ID:       "test",
+		Labels:   []labeler.RegionLabel{{Key: mergeOptionLabel, Value: mergeOptionValueDeny}},
+		RuleType: labeler.KeyRange,
+		Data:     makeKeyRanges("", "74"),
+	})
+	checkNotMergeable(suite.regions[2], suite.regions[1])
+	suite.cluster.GetRegionLabeler().DeleteLabelRule("test")
+	suite.NoError(CheckMergeable(suite.cluster, suite.regions[2], suite.regions[1]))
+
+	// The merged region will be split and merged again.
+	suite.cluster.PutRegion(suite.regions[1].Clone(core.SetApproximateSize(192)))
+	checkNotMergeable(suite.regions[2], suite.cluster.GetRegion(2))
+}
+
 func (suite *mergeCheckerTestSuite) TestCache() {
 	cfg := config.NewTestOptions()
 	suite.cluster = mockcluster.NewCluster(suite.ctx, cfg)
@@ -17,13 +17,17 @@ package checker
 import "github.com/prometheus/client_golang/prometheus"
 
 var (
-	checkerCounter = prometheus.NewCounterVec(
-		prometheus.CounterOpts{
-			Namespace: "pd",
-			Subsystem: "checker",
-			Name:      "event_count",
-			Help:      "Counter of checker events.",
-		}, []string{"type", "name"})
+	checkerCounterOpts = prometheus.CounterOpts{
+		Namespace: "pd",
+		Subsystem: "checker",
+		Name:      "event_count",
+		Help:      "Counter of checker events.",
+	}
+
+	checkerCounter = prometheus.NewCounterVec(checkerCounterOpts, []string{"type", "name"})
+	// dryRunCheckerCounter counts the events of the checkers copied for the dry
+	// runs. It is not registered, so the dry runs are not mixed into the metrics.
+	dryRunCheckerCounter = prometheus.NewCounterVec(checkerCounterOpts, []string{"type", "name"})
 )
 
 func init() {
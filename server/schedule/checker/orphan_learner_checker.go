@@ -0,0 +1,186 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/slice"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
+	"go.uber.org/zap"
+)
+
+const (
+	orphanLearnerCheckerName = "orphan_learner_checker"
+	// orphanLearnerForgetTime is the time after which a learner not seen by the
+	// checker is forgotten, e.g. the region is merged.
+	orphanLearnerForgetTime = time.Hour
+)
+
+type orphanLearner struct {
+	firstSeen time.Time
+	lastSeen  time.Time
+	reported  bool
+}
+
+// OrphanLearnerChecker removes the learners not covered by any rule, e.g. left
+// by TiFlash or the external tools, after they stay orphaned for a grace period.
+// Unlike the orphan peers removed by the rule checker, the learners of the
+// excluded engines are kept, and they are only reported in the dry-run mode.
+type OrphanLearnerChecker struct {
+	PauseController
+	*orphanLearnerState
+	cluster schedule.Cluster
+	counter *prometheus.CounterVec
+	// dryRun is true for the copies created for the dry runs, which never
+	// change the state shared with the checker.
+	dryRun bool
+}
+
+type orphanLearnerState struct {
+	mu         syncutil.Mutex
+	learners   map[uint64]*orphanLearner // peer ID -> orphan learner
+	lastForget time.Time
+}
+
+// NewOrphanLearnerChecker creates an orphan learner checker.
+func NewOrphanLearnerChecker(cluster schedule.Cluster) *OrphanLearnerChecker {
+	return &OrphanLearnerChecker{
+		orphanLearnerState: &orphanLearnerState{learners: make(map[uint64]*orphanLearner)},
+		cluster:            cluster,
+		counter:            checkerCounter,
+	}
+}
+
+// dryRunCopy returns a copy of the checker for the dry runs, which shares the
+// orphan learners seen by the checker.
+func (c *OrphanLearnerChecker) dryRunCopy() *OrphanLearnerChecker {
+	cp := *c
+	cp.counter, cp.dryRun = dryRunCheckerCounter, true
+	return &cp
+}
+
+// GetType returns the checker's type.
+func (c *OrphanLearnerChecker) GetType() string {
+	return "orphan-learner-checker"
+}
+
+// CheckWithFit verifies whether the region has orphan learners, creating an Operator if need.
+func (c *OrphanLearnerChecker) CheckWithFit(region *core.RegionInfo, fit *placement.RegionFit) *operator.Operator {
+	opts := c.cluster.GetOpts()
+	mode := opts.GetOrphanLearnerCheckerMode()
+	if mode == config.OrphanLearnerCheckerDisabled {
+		return nil
+	}
+	if c.IsPaused() {
+		c.counter.WithLabelValues(orphanLearnerCheckerName, "paused").Inc()
+		return nil
+	}
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.dryRun {
+		c.forgetLocked(now)
+	}
+	if fit == nil || len(fit.OrphanPeers) == 0 {
+		return nil
+	}
+	// The learners may be placed by the rule checker as the degraded fallback
+	// before all rules are satisfied.
+	for _, rf := range fit.RuleFits {
+		if !rf.IsSatisfied() {
+			c.counter.WithLabelValues(orphanLearnerCheckerName, "skip-rule-not-satisfied").Inc()
+			return nil
+		}
+	}
+	excludedEngines := opts.GetOrphanLearnerExcludedEngines()
+	gracePeriod := opts.GetOrphanLearnerGracePeriod()
+	for _, peer := range fit.OrphanPeers {
+		if !core.IsLearner(peer) || region.GetPendingPeer(peer.GetId()) != nil {
+			continue
+		}
+		if store := c.cluster.GetStore(peer.GetStoreId()); store != nil {
+			engine := store.GetLabelValue(core.EngineKey)
+			if engine == "" {
+				engine = core.EngineTiKV
+			}
+			if slice.AnyOf(excludedEngines, func(i int) bool { return excludedEngines[i] == engine }) {
+				c.counter.WithLabelValues(orphanLearnerCheckerName, "excluded-engine").Inc()
+				continue
+			}
+		}
+		// The dry runs see the learners as the checker does, but don't start
+		// the grace periods of the new ones.
+		learner, ok := c.learners[peer.GetId()]
+		if !ok {
+			learner = &orphanLearner{firstSeen: now}
+			if !c.dryRun {
+				c.learners[peer.GetId()] = learner
+			}
+		}
+		if !c.dryRun {
+			learner.lastSeen = now
+		}
+		orphanedFor := now.Sub(learner.firstSeen)
+		if orphanedFor < gracePeriod {
+			c.counter.WithLabelValues(orphanLearnerCheckerName, "in-grace-period").Inc()
+			continue
+		}
+		if mode == config.OrphanLearnerCheckerDryRun {
+			if !learner.reported && !c.dryRun {
+				log.Info("found orphan learner in dry-run mode",
+					zap.Uint64("region-id", region.GetID()),
+					zap.Uint64("peer-id", peer.GetId()),
+					zap.Uint64("store-id", peer.GetStoreId()),
+					zap.Duration("orphaned-for", orphanedFor))
+				learner.reported = true
+			}
+			c.counter.WithLabelValues(orphanLearnerCheckerName, "dry-run-remove-orphan-learner").Inc()
+			continue
+		}
+		op, err := operator.CreateRemovePeerOperator("remove-orphan-learner", c.cluster, 0, region, peer.GetStoreId())
+		if err != nil {
+			log.Debug("fail to create remove orphan learner operator", errs.ZapError(err))
+			continue
+		}
+		op.AddReasons(operator.NewReason(orphanLearnerCheckerName, "orphan-learner").
+			With("peer-id", peer.GetId()).
+			With("store-id", peer.GetStoreId()).
+			With("orphaned-for", orphanedFor))
+		c.counter.WithLabelValues(orphanLearnerCheckerName, "remove-orphan-learner").Inc()
+		return op
+	}
+	return nil
+}
+
+func (c *OrphanLearnerChecker) forgetLocked(now time.Time) {
+	if now.Sub(c.lastForget) < orphanLearnerForgetTime {
+		return
+	}
+	for id, learner := range c.learners {
+		if now.Sub(learner.lastSeen) >= orphanLearnerForgetTime {
+			delete(c.learners, id)
+		}
+	}
+	c.lastForget = now
+}
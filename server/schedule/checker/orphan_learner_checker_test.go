@@ -0,0 +1,97 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package checker
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/cache"
+	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/pkg/typeutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/operator"
+)
+
+func TestOrphanLearnerChecker(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster := mockcluster.NewCluster(ctx, config.NewTestOptions())
+	cluster.SetEnablePlacementRules(true)
+	checker := NewOrphanLearnerChecker(cluster)
+	ruleChecker := NewRuleChecker(cluster, cluster.RuleManager, cache.NewDefaultCache(10))
+	for i := uint64(1); i <= 3; i++ {
+		cluster.AddRegionStore(i, 1)
+	}
+	cluster.AddLabelsStore(4, 1, map[string]string{core.EngineKey: core.EngineTiFlash})
+	cluster.AddRegionStore(5, 1)
+	// Both regions have a learner not covered by the default rule.
+	cluster.AddRegionWithLearner(1, 1, []uint64{2, 3}, []uint64{4})
+	cluster.AddRegionWithLearner(2, 1, []uint64{2, 3}, []uint64{5})
+	check := func(regionID uint64) *operator.Operator {
+		region := cluster.GetRegion(regionID)
+		return checker.CheckWithFit(region, cluster.RuleManager.FitRegion(cluster, region))
+	}
+	setConfig := func(mode string, excludedEngines ...string) {
+		cfg := cluster.GetReplicationConfig().Clone()
+		cfg.OrphanLearnerChecker = mode
+		cfg.OrphanLearnerGracePeriod = typeutil.NewDuration(50 * time.Millisecond)
+		cfg.OrphanLearnerExcludedEngines = excludedEngines
+		cluster.SetReplicationConfig(cfg)
+	}
+
+	// The checker is disabled by default, and the rule checker removes the orphan learners.
+	re.Nil(check(2))
+	op := ruleChecker.Check(cluster.GetRegion(2))
+	re.NotNil(op)
+	re.Equal("remove-orphan-peer", op.Desc())
+
+	setConfig(config.OrphanLearnerCheckerDryRun, core.EngineTiFlash)
+	re.Nil(ruleChecker.Check(cluster.GetRegion(2)))
+	re.Nil(check(1))
+	re.Nil(check(2))
+	time.Sleep(100 * time.Millisecond)
+	// The learner is only reported in the dry-run mode.
+	re.Nil(check(2))
+	re.True(checker.learners[cluster.GetRegion(2).GetStorePeer(5).GetId()].reported)
+
+	setConfig(config.OrphanLearnerCheckerEnabled, core.EngineTiFlash)
+	op = check(2)
+	re.NotNil(op)
+	re.Equal("remove-orphan-learner", op.Desc())
+	re.Equal(uint64(5), op.Step(0).(operator.RemovePeer).FromStore)
+	// The learner of TiFlash is excluded.
+	re.Nil(check(1))
+
+	// The grace period starts once the learner of TiFlash is not excluded, but
+	// not by the dry runs.
+	setConfig(config.OrphanLearnerCheckerEnabled)
+	region := cluster.GetRegion(1)
+	re.Nil(checker.dryRunCopy().CheckWithFit(region, cluster.RuleManager.FitRegion(cluster, region)))
+	re.NotContains(checker.learners, region.GetStorePeer(4).GetId())
+	re.Nil(check(1))
+	time.Sleep(100 * time.Millisecond)
+	op = check(1)
+	re.NotNil(op)
+	re.Equal(uint64(4), op.Step(0).(operator.RemovePeer).FromStore)
+
+	// The learners are kept if the rules are not satisfied.
+	cluster.AddRegionWithLearner(3, 1, []uint64{2}, []uint64{5})
+	re.Nil(check(3))
+}
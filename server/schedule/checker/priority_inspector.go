@@ -18,6 +18,7 @@ import (
 	"time"
 
 	"github.com/tikv/pd/pkg/cache"
+	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
@@ -31,7 +32,9 @@ const defaultPriorityQueueSize = 1280
 type PriorityInspector struct {
 	cluster schedule.Cluster
 	opts    *config.PersistOptions
-	queue   *cache.PriorityQueue
+	// mu protects the queue, since the regions may be inspected concurrently.
+	mu    syncutil.Mutex
+	queue *cache.PriorityQueue
 }
 
 // NewPriorityInspector creates a priority inspector.
@@ -99,6 +102,8 @@ func (p *PriorityInspector) inspectRegionInReplica(region *core.RegionInfo) (mak
 // it will remove if region's priority equal 0
 // it's Attempt will increase if region's priority equal last
 func (p *PriorityInspector) addOrRemoveRegion(priority int, regionID uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	if priority < 0 {
 		if entry := p.queue.Get(regionID); entry != nil && entry.Priority == priority {
 			e := entry.Value.(*RegionPriorityEntry)
@@ -114,6 +119,8 @@ func (p *PriorityInspector) addOrRemoveRegion(priority int, regionID uint64) {
 
 // GetPriorityRegions returns all regions in priority queue that needs rerun
 func (p *PriorityInspector) GetPriorityRegions() (ids []uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	entries := p.queue.Elems()
 	for _, e := range entries {
 		re := e.Value.(*RegionPriorityEntry)
@@ -128,5 +135,7 @@ func (p *PriorityInspector) GetPriorityRegions() (ids []uint64) {
 
 // RemovePriorityRegion removes priority region from priority queue
 func (p *PriorityInspector) RemovePriorityRegion(regionID uint64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
 	p.queue.Remove(regionID)
 }
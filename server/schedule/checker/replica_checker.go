@@ -16,9 +16,11 @@ package checker
 
 import (
 	"fmt"
+	"strings"
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/pkg/cache"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server/config"
@@ -47,6 +49,10 @@ type ReplicaChecker struct {
 	cluster           schedule.Cluster
 	opts              *config.PersistOptions
 	regionWaitingList cache.Cache
+	counter           *prometheus.CounterVec
+	// dryRun is true for the copies created for the dry runs, which never
+	// change the state shared with the checker.
+	dryRun bool
 }
 
 // NewReplicaChecker creates a replica checker.
@@ -55,6 +61,20 @@ func NewReplicaChecker(cluster schedule.Cluster, regionWaitingList cache.Cache)
 		cluster:           cluster,
 		opts:              cluster.GetOpts(),
 		regionWaitingList: regionWaitingList,
+		counter:           checkerCounter,
+	}
+}
+
+// dryRunCopy returns a copy of the checker for the dry runs.
+func (r *ReplicaChecker) dryRunCopy() *ReplicaChecker {
+	cp := *r
+	cp.counter, cp.dryRun = dryRunCheckerCounter, true
+	return &cp
+}
+
+func (r *ReplicaChecker) putToWaitingList(regionID uint64) {
+	if !r.dryRun {
+		r.regionWaitingList.Put(regionID, nil)
 	}
 }
 
@@ -65,32 +85,32 @@ func (r *ReplicaChecker) GetType() string {
 
 // Check verifies a region's replicas, creating an operator.Operator if need.
 func (r *ReplicaChecker) Check(region *core.RegionInfo) *operator.Operator {
-	checkerCounter.WithLabelValues("replica_checker", "check").Inc()
+	r.counter.WithLabelValues("replica_checker", "check").Inc()
 	if r.IsPaused() {
-		checkerCounter.WithLabelValues("replica_checker", "paused").Inc()
+		r.counter.WithLabelValues("replica_checker", "paused").Inc()
 		return nil
 	}
 	if op := r.checkDownPeer(region); op != nil {
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		r.counter.WithLabelValues("replica_checker", "new-operator").Inc()
 		op.SetPriorityLevel(core.HighPriority)
 		return op
 	}
 	if op := r.checkOfflinePeer(region); op != nil {
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		r.counter.WithLabelValues("replica_checker", "new-operator").Inc()
 		op.SetPriorityLevel(core.HighPriority)
 		return op
 	}
 	if op := r.checkMakeUpReplica(region); op != nil {
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		r.counter.WithLabelValues("replica_checker", "new-operator").Inc()
 		op.SetPriorityLevel(core.HighPriority)
 		return op
 	}
 	if op := r.checkRemoveExtraReplica(region); op != nil {
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		r.counter.WithLabelValues("replica_checker", "new-operator").Inc()
 		return op
 	}
 	if op := r.checkLocationReplacement(region); op != nil {
-		checkerCounter.WithLabelValues("replica_checker", "new-operator").Inc()
+		r.counter.WithLabelValues("replica_checker", "new-operator").Inc()
 		return op
 	}
 	return nil
@@ -116,7 +136,14 @@ func (r *ReplicaChecker) checkDownPeer(region *core.RegionInfo) *operator.Operat
 		if store.DownTime() < r.opts.GetMaxStoreDownTime() {
 			continue
 		}
-		return r.fixPeer(region, storeID, downStatus)
+		op := r.fixPeer(region, storeID, downStatus)
+		if op != nil {
+			op.AddReasons(operator.NewReason(replicaCheckerName, "down-peer").
+				With("store-id", storeID).
+				With("down-time", store.DownTime()).
+				With("max-store-down-time", r.opts.GetMaxStoreDownTime()))
+		}
+		return op
 	}
 	return nil
 }
@@ -142,7 +169,13 @@ func (r *ReplicaChecker) checkOfflinePeer(region *core.RegionInfo) *operator.Ope
 			continue
 		}
 
-		return r.fixPeer(region, storeID, offlineStatus)
+		op := r.fixPeer(region, storeID, offlineStatus)
+		if op != nil {
+			op.AddReasons(operator.NewReason(replicaCheckerName, "offline-peer").
+				With("store-id", storeID).
+				With("store-state", store.GetNodeState()))
+		}
+		return op
 	}
 
 	return nil
@@ -160,11 +193,12 @@ func (r *ReplicaChecker) checkMakeUpReplica(region *core.RegionInfo) *operator.O
 	target, filterByTempState := r.strategy(region).SelectStoreToAdd(regionStores)
 	if target == 0 {
 		log.Debug("no store to add replica", zap.Uint64("region-id", region.GetID()))
-		checkerCounter.WithLabelValues("replica_checker", "no-target-store").Inc()
+		r.counter.WithLabelValues("replica_checker", "no-target-store").Inc()
 		if filterByTempState {
-			r.regionWaitingList.Put(region.GetID(), nil)
+			r.putToWaitingList(region.GetID())
+			return nil
 		}
-		return nil
+		return r.addDegradedFallbackPeer(region, "miss-replica")
 	}
 	newPeer := &metapb.Peer{StoreId: target}
 	op, err := operator.CreateAddPeerOperator("make-up-replica", r.cluster, region, newPeer, operator.OpReplica)
@@ -172,6 +206,9 @@ func (r *ReplicaChecker) checkMakeUpReplica(region *core.RegionInfo) *operator.O
 		log.Debug("create make-up-replica operator fail", errs.ZapError(err))
 		return nil
 	}
+	op.AddReasons(operator.NewReason(replicaCheckerName, "miss-replica").
+		With("peer-count", len(region.GetPeers())).
+		With("max-replicas", r.opts.GetMaxReplicas()))
 	return op
 }
 
@@ -188,15 +225,18 @@ func (r *ReplicaChecker) checkRemoveExtraReplica(region *core.RegionInfo) *opera
 	regionStores := r.cluster.GetRegionStores(region)
 	old := r.strategy(region).SelectStoreToRemove(regionStores)
 	if old == 0 {
-		checkerCounter.WithLabelValues("replica_checker", "no-worst-peer").Inc()
-		r.regionWaitingList.Put(region.GetID(), nil)
+		r.counter.WithLabelValues("replica_checker", "no-worst-peer").Inc()
+		r.putToWaitingList(region.GetID())
 		return nil
 	}
 	op, err := operator.CreateRemovePeerOperator("remove-extra-replica", r.cluster, operator.OpReplica, region, old)
 	if err != nil {
-		checkerCounter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
+		r.counter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
 		return nil
 	}
+	op.AddReasons(operator.NewReason(replicaCheckerName, "extra-replica").
+		With("voter-count", len(region.GetVoters())).
+		With("max-replicas", r.opts.GetMaxReplicas()))
 	return op
 }
 
@@ -209,22 +249,26 @@ func (r *ReplicaChecker) checkLocationReplacement(region *core.RegionInfo) *oper
 	regionStores := r.cluster.GetRegionStores(region)
 	oldStore := strategy.SelectStoreToRemove(regionStores)
 	if oldStore == 0 {
-		checkerCounter.WithLabelValues("replica_checker", "all-right").Inc()
+		r.counter.WithLabelValues("replica_checker", "all-right").Inc()
 		return nil
 	}
 	newStore, _ := strategy.SelectStoreToImprove(regionStores, oldStore)
 	if newStore == 0 {
 		log.Debug("no better peer", zap.Uint64("region-id", region.GetID()))
-		checkerCounter.WithLabelValues("replica_checker", "not-better").Inc()
+		r.counter.WithLabelValues("replica_checker", "not-better").Inc()
 		return nil
 	}
 
 	newPeer := &metapb.Peer{StoreId: newStore}
 	op, err := operator.CreateMovePeerOperator("move-to-better-location", r.cluster, region, operator.OpReplica, oldStore, newPeer)
 	if err != nil {
-		checkerCounter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
+		r.counter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
 		return nil
 	}
+	op.AddReasons(operator.NewReason(replicaCheckerName, "better-location").
+		With("source-store", oldStore).
+		With("target-store", newStore).
+		With("location-labels", strings.Join(r.opts.GetLocationLabels(), ",")))
 	return op
 }
 
@@ -235,7 +279,7 @@ func (r *ReplicaChecker) fixPeer(region *core.RegionInfo, storeID uint64, status
 		op, err := operator.CreateRemovePeerOperator(removeExtra, r.cluster, operator.OpReplica, region, storeID)
 		if err != nil {
 			reason := fmt.Sprintf("%s-fail", removeExtra)
-			checkerCounter.WithLabelValues("replica_checker", reason).Inc()
+			r.counter.WithLabelValues("replica_checker", reason).Inc()
 			return nil
 		}
 		return op
@@ -245,21 +289,60 @@ func (r *ReplicaChecker) fixPeer(region *core.RegionInfo, storeID uint64, status
 	target, filterByTempState := r.strategy(region).SelectStoreToFix(regionStores, storeID)
 	if target == 0 {
 		reason := fmt.Sprintf("no-store-%s", status)
-		checkerCounter.WithLabelValues("replica_checker", reason).Inc()
+		r.counter.WithLabelValues("replica_checker", reason).Inc()
 		log.Debug("no best store to add replica", zap.Uint64("region-id", region.GetID()))
 		if filterByTempState {
-			r.regionWaitingList.Put(region.GetID(), nil)
+			r.putToWaitingList(region.GetID())
+			return nil
 		}
-		return nil
+		return r.addDegradedFallbackPeer(region, status+"-replica")
 	}
 	newPeer := &metapb.Peer{StoreId: target}
 	replace := fmt.Sprintf("replace-%s-replica", status)
 	op, err := operator.CreateMovePeerOperator(replace, r.cluster, region, operator.OpReplica, storeID, newPeer)
 	if err != nil {
 		reason := fmt.Sprintf("%s-fail", replace)
-		checkerCounter.WithLabelValues("replica_checker", reason).Inc()
+		r.counter.WithLabelValues("replica_checker", reason).Inc()
+		return nil
+	}
+	return op
+}
+
+// addDegradedFallbackPeer places a learner regardless of the isolation level when
+// the replica can not be made up or replaced, so that the region still gets an
+// extra copy of data. The learner is promoted by the learner checker, then the
+// extra down or offline replica is removed, and the location replacement moves
+// the replica back to a better location once there is one.
+func (r *ReplicaChecker) addDegradedFallbackPeer(region *core.RegionInfo, cause string) *operator.Operator {
+	if !r.opts.IsDegradedReplicaFallbackEnabled() {
+		return nil
+	}
+	// only one fallback learner is placed for a region.
+	if len(region.GetLearners()) > 0 {
+		r.counter.WithLabelValues("replica_checker", "degraded-fallback-exist").Inc()
+		return nil
+	}
+	strategy := &ReplicaStrategy{
+		checkerName:    replicaCheckerName,
+		cluster:        r.cluster,
+		locationLabels: r.opts.GetLocationLabels(),
+		region:         region,
+	}
+	target, _ := strategy.SelectStoreToAdd(r.cluster.GetRegionStores(region))
+	if target == 0 {
+		r.counter.WithLabelValues("replica_checker", "no-store-degraded-fallback").Inc()
+		return nil
+	}
+	r.counter.WithLabelValues("replica_checker", "add-degraded-fallback").Inc()
+	peer := &metapb.Peer{StoreId: target, Role: metapb.PeerRole_Learner}
+	op, err := operator.CreateAddPeerOperator("add-degraded-fallback-learner", r.cluster, region, peer, operator.OpReplica)
+	if err != nil {
+		r.counter.WithLabelValues("replica_checker", "create-operator-fail").Inc()
 		return nil
 	}
+	op.AddReasons(operator.NewReason(replicaCheckerName, "degraded-fallback").
+		With("cause", cause).
+		With("isolation-level", r.opts.GetIsolationLevel()))
 	return op
 }
 
@@ -551,6 +551,25 @@ func (suite *replicaCheckerTestSuite) TestFixDownPeer() {
 
 	tc.SetIsolationLevel("zone")
 	suite.Nil(rc.Check(region))
+
+	// place a learner regardless of the isolation level.
+	tc.SetEnableDegradedReplicaFallback(true)
+	op := rc.Check(region)
+	suite.NotNil(op)
+	suite.Equal("add-degraded-fallback-learner", op.Desc())
+	suite.Equal(core.HighPriority, op.GetPriorityLevel())
+	suite.Equal(uint64(2), op.Step(0).(operator.AddLearner).ToStore)
+
+	// only one fallback learner is placed.
+	region = region.Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 2, Role: metapb.PeerRole_Learner}))
+	suite.Nil(rc.Check(region))
+
+	// the down peer is removed after the learner is promoted.
+	region = region.Clone(core.WithPromoteLearner(100))
+	op = rc.Check(region)
+	suite.NotNil(op)
+	suite.Equal("remove-extra-down-replica", op.Desc())
+	suite.Equal(uint64(4), op.Step(0).(operator.RemovePeer).FromStore)
 }
 
 // See issue: https://github.com/tikv/pd/issues/3705
@@ -15,15 +15,22 @@
 package checker
 
 import (
+	"bytes"
+	"encoding/hex"
 	"errors"
 	"math"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/pingcap/log"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/tikv/pd/pkg/cache"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/schedule/filter"
@@ -41,6 +48,10 @@ var (
 
 const maxPendingListLen = 100000
 
+// degradedFallbackWitnessRulePrefix is the ID prefix of the temporary rules placing
+// the fallback witnesses, which is followed by the region ID.
+const degradedFallbackWitnessRulePrefix = "degraded-fallback-witness-"
+
 // RuleChecker fix/improve region by placement rules.
 type RuleChecker struct {
 	PauseController
@@ -50,6 +61,10 @@ type RuleChecker struct {
 	regionWaitingList cache.Cache
 	pendingList       cache.Cache
 	record            *recorder
+	counter           *prometheus.CounterVec
+	// dryRun is true for the copies created for the dry runs, which never
+	// change the state shared with the checker.
+	dryRun bool
 }
 
 // NewRuleChecker creates a checker instance.
@@ -61,9 +76,17 @@ func NewRuleChecker(cluster schedule.Cluster, ruleManager *placement.RuleManager
 		regionWaitingList: regionWaitingList,
 		pendingList:       cache.NewDefaultCache(maxPendingListLen),
 		record:            newRecord(),
+		counter:           checkerCounter,
 	}
 }
 
+// dryRunCopy returns a copy of the checker for the dry runs.
+func (c *RuleChecker) dryRunCopy() *RuleChecker {
+	cp := *c
+	cp.counter, cp.dryRun = dryRunCheckerCounter, true
+	return &cp
+}
+
 // GetType returns RuleChecker's Type
 func (c *RuleChecker) GetType() string {
 	return "rule-checker"
@@ -79,7 +102,7 @@ func (c *RuleChecker) Check(region *core.RegionInfo) *operator.Operator {
 // CheckWithFit is similar with Checker with placement.RegionFit
 func (c *RuleChecker) CheckWithFit(region *core.RegionInfo, fit *placement.RegionFit) (op *operator.Operator) {
 	if c.IsPaused() {
-		checkerCounter.WithLabelValues("rule_checker", "paused").Inc()
+		c.counter.WithLabelValues("rule_checker", "paused").Inc()
 		return nil
 	}
 	// If the fit is fetched from cache, it seems that the region doesn't need cache
@@ -87,7 +110,7 @@ func (c *RuleChecker) CheckWithFit(region *core.RegionInfo, fit *placement.Regio
 		failpoint.Inject("assertShouldNotCache", func() {
 			panic("cached shouldn't be used")
 		})
-		checkerCounter.WithLabelValues("rule_checker", "get-cache").Inc()
+		c.counter.WithLabelValues("rule_checker", "get-cache").Inc()
 		return nil
 	}
 	failpoint.Inject("assertShouldCache", func() {
@@ -96,13 +119,17 @@ func (c *RuleChecker) CheckWithFit(region *core.RegionInfo, fit *placement.Regio
 
 	// If the fit is calculated by FitRegion, which means we get a new fit result, thus we should
 	// invalid the cache if it exists
-	c.ruleManager.InvalidCache(region.GetID())
-
-	checkerCounter.WithLabelValues("rule_checker", "check").Inc()
-	c.record.refresh(c.cluster)
+	if !c.dryRun {
+		c.ruleManager.InvalidCache(region.GetID())
+		c.record.refresh(c.cluster)
+	}
+	c.counter.WithLabelValues("rule_checker", "check").Inc()
 
+	if c.cleanDegradedFallbackWitnessRules(region, fit) {
+		return nil
+	}
 	if len(fit.RuleFits) == 0 {
-		checkerCounter.WithLabelValues("rule_checker", "need-split").Inc()
+		c.counter.WithLabelValues("rule_checker", "need-split").Inc()
 		// If the region matches no rules, the most possible reason is it spans across
 		// multiple rules.
 		return nil
@@ -111,7 +138,7 @@ func (c *RuleChecker) CheckWithFit(region *core.RegionInfo, fit *placement.Regio
 	if err != nil {
 		log.Debug("fail to fix orphan peer", errs.ZapError(err))
 	} else if op != nil {
-		c.pendingList.Remove(region.GetID())
+		c.removeFromPendingList(region.GetID())
 		return op
 	}
 	for _, rf := range fit.RuleFits {
@@ -121,15 +148,18 @@ func (c *RuleChecker) CheckWithFit(region *core.RegionInfo, fit *placement.Regio
 			continue
 		}
 		if op != nil {
-			c.pendingList.Remove(region.GetID())
+			op.AddReasons(operator.NewReason(c.name, "rule-not-fit").
+				With("rule-group", rf.Rule.GroupID).
+				With("rule-id", rf.Rule.ID))
+			c.removeFromPendingList(region.GetID())
 			return op
 		}
 	}
-	if c.cluster.GetOpts().IsPlacementRulesCacheEnabled() {
+	if c.cluster.GetOpts().IsPlacementRulesCacheEnabled() && !c.dryRun {
 		if placement.ValidateFit(fit) && placement.ValidateRegion(region) && placement.ValidateStores(fit.GetRegionStores()) {
 			// If there is no need to fix, we will cache the fit
 			c.ruleManager.SetRegionFitCache(region, fit)
-			checkerCounter.WithLabelValues("rule_checker", "set-cache").Inc()
+			c.counter.WithLabelValues("rule_checker", "set-cache").Inc()
 		}
 	}
 	return nil
@@ -138,17 +168,37 @@ func (c *RuleChecker) CheckWithFit(region *core.RegionInfo, fit *placement.Regio
 func (c *RuleChecker) fixRulePeer(region *core.RegionInfo, fit *placement.RegionFit, rf *placement.RuleFit) (*operator.Operator, error) {
 	// make up peers.
 	if len(rf.Peers) < rf.Rule.Count {
-		return c.addRulePeer(region, rf)
+		op, err := c.addRulePeer(region, rf)
+		if err == errNoStoreToAdd {
+			return c.addDegradedFallbackPeer(region, fit, rf, err)
+		}
+		return op, err
 	}
 	// fix down/offline peers.
 	for _, peer := range rf.Peers {
 		if c.isDownPeer(region, peer) {
-			checkerCounter.WithLabelValues("rule_checker", "replace-down").Inc()
-			return c.replaceUnexpectRulePeer(region, rf, fit, peer, downStatus)
+			c.counter.WithLabelValues("rule_checker", "replace-down").Inc()
+			op, err := c.replaceUnexpectRulePeer(region, rf, fit, peer, downStatus)
+			if err == errNoStoreToReplace {
+				return c.addDegradedFallbackPeer(region, fit, rf, err)
+			}
+			if op != nil {
+				op.AddReasons(operator.NewReason(c.name, "down-peer").
+					With("store-id", peer.GetStoreId()).
+					With("down-time", c.cluster.GetStore(peer.GetStoreId()).DownTime()).
+					With("max-store-down-time", c.cluster.GetOpts().GetMaxStoreDownTime()))
+			}
+			return op, err
 		}
 		if c.isOfflinePeer(peer) {
-			checkerCounter.WithLabelValues("rule_checker", "replace-offline").Inc()
-			return c.replaceUnexpectRulePeer(region, rf, fit, peer, offlineStatus)
+			c.counter.WithLabelValues("rule_checker", "replace-offline").Inc()
+			op, err := c.replaceUnexpectRulePeer(region, rf, fit, peer, offlineStatus)
+			if op != nil {
+				op.AddReasons(operator.NewReason(c.name, "offline-peer").
+					With("store-id", peer.GetStoreId()).
+					With("store-state", c.cluster.GetStore(peer.GetStoreId()).GetNodeState()))
+			}
+			return op, err
 		}
 	}
 	// fix loose matched peers.
@@ -158,6 +208,10 @@ func (c *RuleChecker) fixRulePeer(region *core.RegionInfo, fit *placement.Region
 			return nil, err
 		}
 		if op != nil {
+			op.AddReasons(operator.NewReason(c.name, "peer-role-mismatch").
+				With("peer-id", peer.GetId()).
+				With("peer-role", peer.GetRole()).
+				With("rule-role", rf.Rule.Role))
 			return op, nil
 		}
 	}
@@ -165,11 +219,11 @@ func (c *RuleChecker) fixRulePeer(region *core.RegionInfo, fit *placement.Region
 }
 
 func (c *RuleChecker) addRulePeer(region *core.RegionInfo, rf *placement.RuleFit) (*operator.Operator, error) {
-	checkerCounter.WithLabelValues("rule_checker", "add-rule-peer").Inc()
+	c.counter.WithLabelValues("rule_checker", "add-rule-peer").Inc()
 	ruleStores := c.getRuleFitStores(rf)
 	store, filterByTempState := c.strategy(region, rf.Rule).SelectStoreToAdd(ruleStores)
 	if store == 0 {
-		checkerCounter.WithLabelValues("rule_checker", "no-store-add").Inc()
+		c.counter.WithLabelValues("rule_checker", "no-store-add").Inc()
 		c.handleFilterState(region, filterByTempState)
 		return nil, errNoStoreToAdd
 	}
@@ -178,6 +232,9 @@ func (c *RuleChecker) addRulePeer(region *core.RegionInfo, rf *placement.RuleFit
 	if err != nil {
 		return nil, err
 	}
+	op.AddReasons(operator.NewReason(c.name, "miss-peer").
+		With("peer-count", len(rf.Peers)).
+		With("rule-count", rf.Rule.Count))
 	op.SetPriorityLevel(core.HighPriority)
 	return op, nil
 }
@@ -187,7 +244,7 @@ func (c *RuleChecker) replaceUnexpectRulePeer(region *core.RegionInfo, rf *place
 	ruleStores := c.getRuleFitStores(rf)
 	store, filterByTempState := c.strategy(region, rf.Rule).SelectStoreToFix(ruleStores, peer.GetStoreId())
 	if store == 0 {
-		checkerCounter.WithLabelValues("rule_checker", "no-store-replace").Inc()
+		c.counter.WithLabelValues("rule_checker", "no-store-replace").Inc()
 		c.handleFilterState(region, filterByTempState)
 		return nil, errNoStoreToReplace
 	}
@@ -205,7 +262,7 @@ func (c *RuleChecker) replaceUnexpectRulePeer(region *core.RegionInfo, rf *place
 				if region.GetDownPeer(p.GetId()) != nil || region.GetPendingPeer(p.GetId()) != nil {
 					return false
 				}
-				return c.allowLeader(fit, p)
+				return c.allowLeader(fit, region, p)
 			}
 			if minCount > count && checkPeerhealth() {
 				minCount = count
@@ -224,44 +281,183 @@ func (c *RuleChecker) replaceUnexpectRulePeer(region *core.RegionInfo, rf *place
 	if err != nil {
 		return nil, err
 	}
-	if newLeader != nil {
+	if newLeader != nil && !c.dryRun {
 		c.record.incOfflineLeaderCount(newLeader.GetStoreId())
 	}
 	op.SetPriorityLevel(core.HighPriority)
 	return op, nil
 }
 
+// addDegradedFallbackPeer places a learner on a store outside the rule when the
+// rule can not be made up or repaired, so that the region still keeps an extra
+// copy of data. The learner is an orphan peer of the fit, and it is removed by
+// fixOrphanPeers after all rules are satisfied again.
+func (c *RuleChecker) addDegradedFallbackPeer(region *core.RegionInfo, fit *placement.RegionFit, rf *placement.RuleFit, cause error) (*operator.Operator, error) {
+	if !c.cluster.GetOpts().IsDegradedReplicaFallbackEnabled() || rf.Rule.Role == placement.Learner || isDegradedFallbackWitnessRule(rf.Rule) {
+		return nil, cause
+	}
+	if c.cluster.GetOpts().GetDegradedReplicaFallbackRole() == config.DegradedReplicaFallbackWitness {
+		return c.addDegradedFallbackWitness(region, fit, rf, cause)
+	}
+	// only one fallback learner is placed for a region.
+	for _, p := range fit.OrphanPeers {
+		if core.IsLearner(p) && region.GetDownPeer(p.GetId()) == nil {
+			c.counter.WithLabelValues("rule_checker", "degraded-fallback-exist").Inc()
+			return nil, cause
+		}
+	}
+	strategy := &ReplicaStrategy{
+		checkerName:    c.name,
+		cluster:        c.cluster,
+		locationLabels: rf.Rule.LocationLabels,
+		region:         region,
+		extraFilters:   []filter.Filter{filter.NewEngineFilter(c.name, filter.NotSpecialEngines)},
+	}
+	store, _ := strategy.SelectStoreToAdd(c.getRuleFitStores(rf))
+	if store == 0 {
+		c.counter.WithLabelValues("rule_checker", "no-store-degraded-fallback").Inc()
+		return nil, cause
+	}
+	c.counter.WithLabelValues("rule_checker", "add-degraded-fallback").Inc()
+	peer := &metapb.Peer{StoreId: store, Role: metapb.PeerRole_Learner}
+	op, err := operator.CreateAddPeerOperator("add-degraded-fallback-learner", c.cluster, region, peer, operator.OpReplica)
+	if err != nil {
+		return nil, err
+	}
+	op.AddReasons(operator.NewReason(c.name, "degraded-fallback").
+		With("cause", cause.Error()).
+		With("rule-group", rf.Rule.GroupID).
+		With("rule-id", rf.Rule.ID))
+	op.SetPriorityLevel(core.HighPriority)
+	return op, nil
+}
+
+// addDegradedFallbackWitness places a witness by a temporary rule covering only the
+// region, so that the witness is known by the others as the witness rules, such as
+// the unsafe recovery. The rule follows the rule which can not be fixed, so that the
+// peers are fitted to that rule first. It is deleted by cleanDegradedFallbackWitnessRules
+// once the other rules are healthy again, then the witness is removed as an orphan peer.
+func (c *RuleChecker) addDegradedFallbackWitness(region *core.RegionInfo, fit *placement.RegionFit, rf *placement.RuleFit, cause error) (*operator.Operator, error) {
+	// only one fallback witness is placed for a region.
+	for _, f := range fit.RuleFits {
+		if isDegradedFallbackWitnessRule(f.Rule) {
+			c.counter.WithLabelValues("rule_checker", "degraded-fallback-exist").Inc()
+			return nil, cause
+		}
+	}
+	if c.dryRun {
+		return nil, cause
+	}
+	rule := &placement.Rule{
+		GroupID:        rf.Rule.GroupID,
+		ID:             degradedFallbackWitnessRulePrefix + strconv.FormatUint(region.GetID(), 10),
+		Index:          rf.Rule.Index + 1,
+		StartKeyHex:    hex.EncodeToString(region.GetStartKey()),
+		EndKeyHex:      hex.EncodeToString(region.GetEndKey()),
+		Role:           placement.Voter,
+		Count:          1,
+		IsWitness:      true,
+		LocationLabels: rf.Rule.LocationLabels,
+	}
+	if err := c.ruleManager.SetRule(rule); err != nil {
+		return nil, err
+	}
+	c.counter.WithLabelValues("rule_checker", "add-degraded-fallback-witness-rule").Inc()
+	// make up the witness with the new rule right away.
+	for _, f := range c.ruleManager.FitRegion(c.cluster, region).RuleFits {
+		if f.Rule.GroupID != rule.GroupID || f.Rule.ID != rule.ID {
+			continue
+		}
+		op, err := c.addRulePeer(region, f)
+		if err != nil {
+			return nil, err
+		}
+		op.AddReasons(operator.NewReason(c.name, "degraded-fallback").
+			With("cause", cause.Error()).
+			With("rule-group", rf.Rule.GroupID).
+			With("rule-id", rf.Rule.ID))
+		return op, nil
+	}
+	return nil, cause
+}
+
+// cleanDegradedFallbackWitnessRules deletes the rules placing the fallback witnesses
+// which are no longer needed, i.e. the other rules are healthy again, or the rule
+// doesn't match the range of its region after the region is split or merged. It
+// returns true if any rule is deleted, then the region is checked with the new rules
+// next time.
+func (c *RuleChecker) cleanDegradedFallbackWitnessRules(region *core.RegionInfo, fit *placement.RegionFit) bool {
+	if c.dryRun {
+		return false
+	}
+	var rules []*placement.Rule
+	if len(fit.RuleFits) == 0 {
+		// The region may span the range of a stale rule.
+		for _, rule := range c.ruleManager.GetAllRules() {
+			if isDegradedFallbackWitnessRule(rule) &&
+				(len(rule.EndKey) == 0 || bytes.Compare(region.GetStartKey(), rule.EndKey) < 0) &&
+				(len(region.GetEndKey()) == 0 || bytes.Compare(rule.StartKey, region.GetEndKey()) < 0) {
+				rules = append(rules, rule)
+			}
+		}
+	}
+	for _, rf := range fit.RuleFits {
+		if isDegradedFallbackWitnessRule(rf.Rule) {
+			rules = append(rules, rf.Rule)
+		}
+	}
+	var deleted bool
+	for _, rule := range rules {
+		if rule.ID == degradedFallbackWitnessRulePrefix+strconv.FormatUint(region.GetID(), 10) &&
+			bytes.Equal(rule.StartKey, region.GetStartKey()) && bytes.Equal(rule.EndKey, region.GetEndKey()) &&
+			!c.isFitHealthy(region, fit, isDegradedFallbackWitnessRule) {
+			continue
+		}
+		if err := c.ruleManager.DeleteRule(rule.GroupID, rule.ID); err != nil {
+			log.Warn("failed to delete the degraded fallback witness rule", zap.String("rule-id", rule.ID), errs.ZapError(err))
+			continue
+		}
+		c.counter.WithLabelValues("rule_checker", "remove-degraded-fallback-witness-rule").Inc()
+		deleted = true
+	}
+	return deleted
+}
+
+func isDegradedFallbackWitnessRule(rule *placement.Rule) bool {
+	return strings.HasPrefix(rule.ID, degradedFallbackWitnessRulePrefix)
+}
+
 func (c *RuleChecker) fixLooseMatchPeer(region *core.RegionInfo, fit *placement.RegionFit, rf *placement.RuleFit, peer *metapb.Peer) (*operator.Operator, error) {
 	if core.IsLearner(peer) && rf.Rule.Role != placement.Learner {
-		checkerCounter.WithLabelValues("rule_checker", "fix-peer-role").Inc()
+		c.counter.WithLabelValues("rule_checker", "fix-peer-role").Inc()
 		return operator.CreatePromoteLearnerOperator("fix-peer-role", c.cluster, region, peer)
 	}
 	if region.GetLeader().GetId() != peer.GetId() && rf.Rule.Role == placement.Leader {
-		checkerCounter.WithLabelValues("rule_checker", "fix-leader-role").Inc()
-		if c.allowLeader(fit, peer) {
+		c.counter.WithLabelValues("rule_checker", "fix-leader-role").Inc()
+		if c.allowLeader(fit, region, peer) {
 			return operator.CreateTransferLeaderOperator("fix-leader-role", c.cluster, region, region.GetLeader().StoreId, peer.GetStoreId(), []uint64{}, 0)
 		}
-		checkerCounter.WithLabelValues("rule_checker", "not-allow-leader")
+		c.counter.WithLabelValues("rule_checker", "not-allow-leader")
 		return nil, errPeerCannotBeLeader
 	}
 	if region.GetLeader().GetId() == peer.GetId() && rf.Rule.Role == placement.Follower {
-		checkerCounter.WithLabelValues("rule_checker", "fix-follower-role").Inc()
+		c.counter.WithLabelValues("rule_checker", "fix-follower-role").Inc()
 		for _, p := range region.GetPeers() {
-			if c.allowLeader(fit, p) {
+			if c.allowLeader(fit, region, p) {
 				return operator.CreateTransferLeaderOperator("fix-follower-role", c.cluster, region, peer.GetStoreId(), p.GetStoreId(), []uint64{}, 0)
 			}
 		}
-		checkerCounter.WithLabelValues("rule_checker", "no-new-leader").Inc()
+		c.counter.WithLabelValues("rule_checker", "no-new-leader").Inc()
 		return nil, errNoNewLeader
 	}
 	if core.IsVoter(peer) && rf.Rule.Role == placement.Learner {
-		checkerCounter.WithLabelValues("rule_checker", "demote-voter-role").Inc()
+		c.counter.WithLabelValues("rule_checker", "demote-voter-role").Inc()
 		return operator.CreateDemoteVoterOperator("fix-demote-voter", c.cluster, region, peer)
 	}
 	return nil, nil
 }
 
-func (c *RuleChecker) allowLeader(fit *placement.RegionFit, peer *metapb.Peer) bool {
+func (c *RuleChecker) allowLeader(fit *placement.RegionFit, region *core.RegionInfo, peer *metapb.Peer) bool {
 	if core.IsLearner(peer) {
 		return false
 	}
@@ -273,6 +469,11 @@ func (c *RuleChecker) allowLeader(fit *placement.RegionFit, peer *metapb.Peer) b
 	if !stateFilter.Target(c.cluster.GetOpts(), s).IsOK() {
 		return false
 	}
+	// The leader is kept in the store which it is pinned to by the annotation.
+	if pinnedFilter := filter.NewPinnedLeaderFilter("rule-checker", c.cluster, region); pinnedFilter != nil &&
+		!pinnedFilter.Target(c.cluster.GetOpts(), s).IsOK() {
+		return false
+	}
 	for _, rf := range fit.RuleFits {
 		if (rf.Rule.Role == placement.Leader || rf.Rule.Role == placement.Voter) &&
 			placement.MatchLabelConstraints(s, rf.Rule.LabelConstraints) {
@@ -299,9 +500,17 @@ func (c *RuleChecker) fixBetterLocation(region *core.RegionInfo, rf *placement.R
 		c.handleFilterState(region, filterByTempState)
 		return nil, nil
 	}
-	checkerCounter.WithLabelValues("rule_checker", "move-to-better-location").Inc()
+	c.counter.WithLabelValues("rule_checker", "move-to-better-location").Inc()
 	newPeer := &metapb.Peer{StoreId: newStore, Role: rf.Rule.Role.MetaPeerRole()}
-	return operator.CreateMovePeerOperator("move-to-better-location", c.cluster, region, operator.OpReplica, oldStore, newPeer)
+	op, err := operator.CreateMovePeerOperator("move-to-better-location", c.cluster, region, operator.OpReplica, oldStore, newPeer)
+	if err != nil {
+		return nil, err
+	}
+	op.AddReasons(operator.NewReason(c.name, "better-location").
+		With("source-store", oldStore).
+		With("target-store", newStore).
+		With("location-labels", strings.Join(rf.Rule.LocationLabels, ",")))
+	return op, nil
 }
 
 func (c *RuleChecker) fixOrphanPeers(region *core.RegionInfo, fit *placement.RegionFit) (*operator.Operator, error) {
@@ -310,29 +519,59 @@ func (c *RuleChecker) fixOrphanPeers(region *core.RegionInfo, fit *placement.Reg
 	}
 	// remove orphan peers only when all rules are satisfied (count+role) and all peers selected
 	// by RuleFits is not pending or down.
+	if !c.isFitHealthy(region, fit, nil) {
+		c.counter.WithLabelValues("rule_checker", "skip-remove-orphan-peer").Inc()
+		return nil, nil
+	}
+	orphanPeers := fit.OrphanPeers
+	// The orphan learners are left to the orphan learner checker if it is not disabled.
+	if c.cluster.GetOpts().GetOrphanLearnerCheckerMode() != config.OrphanLearnerCheckerDisabled {
+		orphanPeers = make([]*metapb.Peer, 0, len(fit.OrphanPeers))
+		for _, p := range fit.OrphanPeers {
+			if !core.IsLearner(p) {
+				orphanPeers = append(orphanPeers, p)
+			}
+		}
+		if len(orphanPeers) == 0 {
+			return nil, nil
+		}
+	}
+	c.counter.WithLabelValues("rule_checker", "remove-orphan-peer").Inc()
+	peer := orphanPeers[0]
+	op, err := operator.CreateRemovePeerOperator("remove-orphan-peer", c.cluster, 0, region, peer.StoreId)
+	if err != nil {
+		return nil, err
+	}
+	op.AddReasons(operator.NewReason(c.name, "orphan-peer").
+		With("peer-id", peer.GetId()).
+		With("store-id", peer.GetStoreId()))
+	return op, nil
+}
+
+// isFitHealthy returns true if all rules are satisfied (count+role) and all peers selected
+// by RuleFits is not pending or down. The rules matched by skip are ignored.
+func (c *RuleChecker) isFitHealthy(region *core.RegionInfo, fit *placement.RegionFit, skip func(*placement.Rule) bool) bool {
 	for _, rf := range fit.RuleFits {
+		if skip != nil && skip(rf.Rule) {
+			continue
+		}
 		if !rf.IsSatisfied() {
-			checkerCounter.WithLabelValues("rule_checker", "skip-remove-orphan-peer").Inc()
-			return nil, nil
+			return false
 		}
 		for _, p := range rf.Peers {
 			for _, pendingPeer := range region.GetPendingPeers() {
 				if pendingPeer.Id == p.Id {
-					checkerCounter.WithLabelValues("rule_checker", "skip-remove-orphan-peer").Inc()
-					return nil, nil
+					return false
 				}
 			}
 			for _, downPeer := range region.GetDownPeers() {
 				if downPeer.Peer.Id == p.Id {
-					checkerCounter.WithLabelValues("rule_checker", "skip-remove-orphan-peer").Inc()
-					return nil, nil
+					return false
 				}
 			}
 		}
 	}
-	checkerCounter.WithLabelValues("rule_checker", "remove-orphan-peer").Inc()
-	peer := fit.OrphanPeers[0]
-	return operator.CreateRemovePeerOperator("remove-orphan-peer", c.cluster, 0, region, peer.StoreId)
+	return true
 }
 
 func (c *RuleChecker) isDownPeer(region *core.RegionInfo, peer *metapb.Peer) bool {
@@ -385,7 +624,16 @@ func (c *RuleChecker) getRuleFitStores(rf *placement.RuleFit) []*core.StoreInfo
 	return stores
 }
 
+func (c *RuleChecker) removeFromPendingList(regionID uint64) {
+	if !c.dryRun {
+		c.pendingList.Remove(regionID)
+	}
+}
+
 func (c *RuleChecker) handleFilterState(region *core.RegionInfo, filterByTempState bool) {
+	if c.dryRun {
+		return
+	}
 	if filterByTempState {
 		c.regionWaitingList.Put(region.GetID(), nil)
 		c.pendingList.Remove(region.GetID())
@@ -395,6 +643,7 @@ func (c *RuleChecker) handleFilterState(region *core.RegionInfo, filterByTempSta
 }
 
 type recorder struct {
+	mu                   syncutil.Mutex
 	offlineLeaderCounter map[uint64]uint64
 	lastUpdateTime       time.Time
 }
@@ -407,10 +656,14 @@ func newRecord() *recorder {
 }
 
 func (o *recorder) getOfflineLeaderCount(storeID uint64) uint64 {
+	o.mu.Lock()
+	defer o.mu.Unlock()
 	return o.offlineLeaderCounter[storeID]
 }
 
 func (o *recorder) incOfflineLeaderCount(storeID uint64) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
 	o.offlineLeaderCounter[storeID] += 1
 	o.lastUpdateTime = time.Now()
 }
@@ -419,6 +672,8 @@ func (o *recorder) incOfflineLeaderCount(storeID uint64) {
 var offlineCounterTTL = 5 * time.Minute
 
 func (o *recorder) refresh(cluster schedule.Cluster) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
 	// re-count the offlineLeaderCounter if the store is already tombstone or store is gone.
 	if len(o.offlineLeaderCounter) > 0 && time.Since(o.lastUpdateTime) > offlineCounterTTL {
 		needClean := false
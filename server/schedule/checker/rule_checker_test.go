@@ -121,6 +121,12 @@ func (suite *ruleCheckerTestSuite) TestFixPeer() {
 	suite.NotNil(op)
 	suite.Equal("replace-rule-down-peer", op.Desc())
 	suite.Equal(core.HighPriority, op.GetPriorityLevel())
+	reasons := op.Reasons()
+	suite.Len(reasons, 2)
+	suite.Equal("down-peer", reasons[0].Cause)
+	suite.Equal("2", reasons[0].Details["store-id"])
+	suite.Equal("rule-not-fit", reasons[1].Cause)
+	suite.Equal("default", reasons[1].Details["rule-id"])
 	var add operator.AddLearner
 	suite.IsType(add, op.Step(0))
 	suite.cluster.SetStoreUp(2)
@@ -130,6 +136,7 @@ func (suite *ruleCheckerTestSuite) TestFixPeer() {
 	suite.Equal("replace-rule-offline-peer", op.Desc())
 	suite.Equal(core.HighPriority, op.GetPriorityLevel())
 	suite.IsType(add, op.Step(0))
+	suite.Equal("offline-peer", op.Reasons()[0].Cause)
 
 	suite.cluster.SetStoreUp(2)
 	// leader store offline
@@ -549,6 +556,98 @@ func (suite *ruleCheckerTestSuite) TestFixDownPeer() {
 	suite.Nil(suite.rc.Check(region))
 }
 
+func (suite *ruleCheckerTestSuite) TestDegradedFallback() {
+	suite.cluster.AddLabelsStore(1, 1, map[string]string{"zone": "z1"})
+	suite.cluster.AddLabelsStore(2, 1, map[string]string{"zone": "z1"})
+	suite.cluster.AddLabelsStore(3, 1, map[string]string{"zone": "z2"})
+	suite.cluster.AddLeaderRegion(1, 1, 2)
+	suite.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "pd",
+		ID:               "test",
+		Index:            100,
+		Override:         true,
+		Role:             placement.Voter,
+		Count:            2,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: "in", Values: []string{"z1"}}},
+	})
+
+	region := suite.cluster.GetRegion(1)
+	suite.Nil(suite.rc.Check(region))
+
+	// no store in z1 can replace the down peer.
+	suite.cluster.SetStoreDown(2)
+	region = region.Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: region.GetStorePeer(2), DownSeconds: 6000},
+	}))
+	suite.Nil(suite.rc.Check(region))
+
+	// place a learner outside the rule.
+	suite.cluster.SetEnableDegradedReplicaFallback(true)
+	op := suite.rc.Check(region)
+	suite.NotNil(op)
+	suite.Equal("add-degraded-fallback-learner", op.Desc())
+	suite.Equal(core.HighPriority, op.GetPriorityLevel())
+	suite.Equal(uint64(3), op.Step(0).(operator.AddLearner).ToStore)
+
+	// only one fallback learner is placed.
+	region = region.Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 3, Role: metapb.PeerRole_Learner}))
+	suite.Nil(suite.rc.Check(region))
+
+	// the learner is removed after the rule is satisfied again.
+	suite.cluster.SetStoreUp(2)
+	region = region.Clone(core.WithDownPeers(nil))
+	op = suite.rc.Check(region)
+	suite.NotNil(op)
+	suite.Equal("remove-orphan-peer", op.Desc())
+	suite.Equal(uint64(3), op.Step(0).(operator.RemovePeer).FromStore)
+}
+
+func (suite *ruleCheckerTestSuite) TestDegradedFallbackWitness() {
+	suite.cluster.AddLabelsStore(1, 1, map[string]string{"zone": "z1"})
+	suite.cluster.AddLabelsStore(2, 1, map[string]string{"zone": "z1"})
+	suite.cluster.AddLabelsStore(3, 1, map[string]string{"zone": "z2"})
+	suite.cluster.AddLeaderRegion(1, 1, 2)
+	suite.ruleManager.SetRule(&placement.Rule{
+		GroupID:          "pd",
+		ID:               "test",
+		Index:            100,
+		Override:         true,
+		Role:             placement.Voter,
+		Count:            2,
+		LabelConstraints: []placement.LabelConstraint{{Key: "zone", Op: "in", Values: []string{"z1"}}},
+	})
+	suite.cluster.SetEnableDegradedReplicaFallback(true)
+	suite.cluster.SetDegradedReplicaFallbackRole(config.DegradedReplicaFallbackWitness)
+
+	// no store in z1 can replace the down peer, so a witness is placed by a temporary rule.
+	suite.cluster.SetStoreDown(2)
+	region := suite.cluster.GetRegion(1).Clone(core.WithDownPeers([]*pdpb.PeerStats{
+		{Peer: suite.cluster.GetRegion(1).GetStorePeer(2), DownSeconds: 6000},
+	}))
+	op := suite.rc.Check(region)
+	suite.NotNil(op)
+	suite.Equal("add-rule-peer", op.Desc())
+	suite.Equal(uint64(3), op.Step(0).(operator.AddLearner).ToStore)
+	rule := suite.ruleManager.GetRule("pd", "degraded-fallback-witness-1")
+	suite.NotNil(rule)
+	suite.True(rule.IsWitness)
+
+	// only one fallback witness is placed.
+	region = region.Clone(core.WithAddPeer(&metapb.Peer{Id: 100, StoreId: 3}))
+	suite.Nil(suite.rc.Check(region))
+	suite.True(IsWitnessPeer(suite.cluster, region, 100))
+
+	// the rule is deleted after the other rules are satisfied again, then the witness is removed.
+	suite.cluster.SetStoreUp(2)
+	region = region.Clone(core.WithDownPeers(nil))
+	suite.Nil(suite.rc.Check(region))
+	suite.Nil(suite.ruleManager.GetRule("pd", "degraded-fallback-witness-1"))
+	op = suite.rc.Check(region)
+	suite.NotNil(op)
+	suite.Equal("remove-orphan-peer", op.Desc())
+	suite.Equal(uint64(3), op.Step(0).(operator.RemovePeer).FromStore)
+}
+
 // See issue: https://github.com/tikv/pd/issues/3705
 func (suite *ruleCheckerTestSuite) TestFixOfflinePeer() {
 	suite.cluster.AddLabelsStore(1, 1, map[string]string{"zone": "z1"})
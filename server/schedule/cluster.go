@@ -29,6 +29,7 @@ type Cluster interface {
 
 	statistics.RegionStatInformer
 	statistics.StoreStatInformer
+	statistics.ZoneLatencyInformer
 	buckets.BucketStatInformer
 
 	operator.ClusterInformer
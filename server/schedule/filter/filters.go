@@ -25,6 +25,7 @@ import (
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
+	"github.com/tikv/pd/server/schedule/labeler"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/schedule/plan"
 	"go.uber.org/zap"
@@ -37,8 +38,8 @@ func SelectSourceStores(stores []*core.StoreInfo, filters []Filter, opt *config.
 			if !filters[i].Source(opt, s).IsOK() {
 				sourceID := strconv.FormatUint(s.GetID(), 10)
 				targetID := ""
-				filterCounter.WithLabelValues("filter-source", s.GetAddress(),
-					sourceID, filters[i].Scope(), filters[i].Type(), sourceID, targetID).Inc()
+				incFilterCounter("filter-source", s.GetAddress(),
+					sourceID, filters[i].Scope(), filters[i].Type(), sourceID, targetID)
 				return false
 			}
 			return true
@@ -58,8 +59,8 @@ func SelectTargetStores(stores []*core.StoreInfo, filters []Filter, opt *config.
 				if ok {
 					sourceID = strconv.FormatUint(cfilter.GetSourceStoreID(), 10)
 				}
-				filterCounter.WithLabelValues("filter-target", s.GetAddress(),
-					targetID, filters[i].Scope(), filters[i].Type(), sourceID, targetID).Inc()
+				incFilterCounter("filter-target", s.GetAddress(),
+					targetID, filters[i].Scope(), filters[i].Type(), sourceID, targetID)
 				return false
 			}
 			return true
@@ -102,8 +103,8 @@ func Source(opt *config.PersistOptions, store *core.StoreInfo, filters []Filter)
 		if !filter.Source(opt, store).IsOK() {
 			sourceID := storeID
 			targetID := ""
-			filterCounter.WithLabelValues("filter-source", storeAddress,
-				sourceID, filter.Scope(), filter.Type(), sourceID, targetID).Inc()
+			incFilterCounter("filter-source", storeAddress,
+				sourceID, filter.Scope(), filter.Type(), sourceID, targetID)
 			return false
 		}
 	}
@@ -122,8 +123,8 @@ func Target(opt *config.PersistOptions, store *core.StoreInfo, filters []Filter)
 			if ok {
 				sourceID = strconv.FormatUint(cfilter.GetSourceStoreID(), 10)
 			}
-			filterCounter.WithLabelValues("filter-target", storeAddress,
-				targetID, filter.Scope(), filter.Type(), sourceID, targetID).Inc()
+			incFilterCounter("filter-target", storeAddress,
+				targetID, filter.Scope(), filter.Type(), sourceID, targetID)
 			return false
 		}
 	}
@@ -352,6 +353,16 @@ func (f *StoreStateFilter) slowStoreEvicted(opt *config.PersistOptions, store *c
 	return statusOK
 }
 
+func (f *StoreStateFilter) isVersionSkewed(opt *config.PersistOptions, store *core.StoreInfo) plan.Status {
+	if maxSkew := opt.GetMaxStoreVersionSkew(); maxSkew > 0 && store.GetVersionLag() > maxSkew &&
+		opt.GetStoreVersionSkewPolicy() == config.StoreVersionSkewPolicyExclude {
+		f.Reason = "version-skewed"
+		return statusStoreVersionSkewed
+	}
+	f.Reason = ""
+	return statusOK
+}
+
 func (f *StoreStateFilter) isDisconnected(opt *config.PersistOptions, store *core.StoreInfo) plan.Status {
 	if !f.AllowTemporaryStates && store.IsDisconnected() {
 		f.Reason = "disconnected"
@@ -423,13 +434,13 @@ func (f *StoreStateFilter) hasRejectLeaderProperty(opts *config.PersistOptions,
 // N: the condition is expected to be true for a long time.
 // X means when the condition is true, the store CANNOT be selected.
 //
-// Condition    Down Offline Tomb Pause Disconn Busy RmLimit AddLimit Snap Pending Reject
-// IsTemporary  N    N       N    N     Y       Y    Y       Y        Y    Y       N
+// Condition    Down Offline Tomb Pause Disconn Busy RmLimit AddLimit Snap Pending Reject Skew
+// IsTemporary  N    N       N    N     Y       Y    Y       Y        Y    Y       N      N
 //
 // LeaderSource X            X    X     X
 // RegionSource                                 X    X                X
-// LeaderTarget X    X       X    X     X       X                                  X
-// RegionTarget X    X       X          X       X            X        X    X
+// LeaderTarget X    X       X    X     X       X                                  X      X
+// RegionTarget X    X       X          X       X            X        X    X              X
 
 const (
 	leaderSource = iota
@@ -448,12 +459,12 @@ func (f *StoreStateFilter) anyConditionMatch(typ int, opt *config.PersistOptions
 		funcs = []conditionFunc{f.isBusy, f.exceedRemoveLimit, f.tooManySnapshots}
 	case leaderTarget:
 		funcs = []conditionFunc{f.isRemoved, f.isRemoving, f.isDown, f.pauseLeaderTransfer,
-			f.slowStoreEvicted, f.isDisconnected, f.isBusy, f.hasRejectLeaderProperty}
+			f.slowStoreEvicted, f.isDisconnected, f.isBusy, f.hasRejectLeaderProperty, f.isVersionSkewed}
 	case regionTarget:
 		funcs = []conditionFunc{f.isRemoved, f.isRemoving, f.isDown, f.isDisconnected, f.isBusy,
-			f.exceedAddLimit, f.tooManySnapshots, f.tooManyPendingPeers}
+			f.exceedAddLimit, f.tooManySnapshots, f.tooManyPendingPeers, f.isVersionSkewed}
 	case scatterRegionTarget:
-		funcs = []conditionFunc{f.isRemoved, f.isRemoving, f.isDown, f.isDisconnected, f.isBusy}
+		funcs = []conditionFunc{f.isRemoved, f.isRemoving, f.isDown, f.isDisconnected, f.isBusy, f.isVersionSkewed}
 	}
 	for _, cf := range funcs {
 		if status := cf(opt, store); !status.IsOK() {
@@ -661,6 +672,50 @@ func NewPlacementLeaderSafeguard(scope string, opt *config.PersistOptions, clust
 	return nil
 }
 
+type pinnedLeaderFilter struct {
+	scope   string
+	storeID uint64
+}
+
+// NewPinnedLeaderFilter creates a filter that keeps the leader of the region in the
+// store which it is pinned to by the "pinned-leader-store" annotation. It returns nil
+// if the region is not pinned, or the cluster does not support the annotations.
+func NewPinnedLeaderFilter(scope string, cluster interface{}, region *core.RegionInfo) Filter {
+	cl, ok := cluster.(interface{ GetRegionLabeler() *labeler.RegionLabeler })
+	if !ok || cl.GetRegionLabeler() == nil {
+		return nil
+	}
+	storeID := cl.GetRegionLabeler().PinnedLeaderStore(region)
+	if storeID == 0 {
+		return nil
+	}
+	return &pinnedLeaderFilter{scope: scope, storeID: storeID}
+}
+
+func (f *pinnedLeaderFilter) Scope() string {
+	return f.scope
+}
+
+func (f *pinnedLeaderFilter) Type() string {
+	return "pinned-leader-filter"
+}
+
+// Source rejects to transfer the leader out of the pinned store.
+func (f *pinnedLeaderFilter) Source(_ *config.PersistOptions, store *core.StoreInfo) plan.Status {
+	if store.GetID() == f.storeID {
+		return statusStorePinnedLeader
+	}
+	return statusOK
+}
+
+// Target only accepts the pinned store.
+func (f *pinnedLeaderFilter) Target(_ *config.PersistOptions, store *core.StoreInfo) plan.Status {
+	if store.GetID() != f.storeID {
+		return statusStorePinnedLeader
+	}
+	return statusOK
+}
+
 type engineFilter struct {
 	scope      string
 	constraint placement.LabelConstraint
@@ -853,7 +908,7 @@ type RegionScoreFilter struct {
 func NewRegionScoreFilter(scope string, source *core.StoreInfo, opt *config.PersistOptions) Filter {
 	return &RegionScoreFilter{
 		scope: scope,
-		score: source.RegionScore(opt.GetRegionScoreFormulaVersion(), opt.GetHighSpaceRatio(), opt.GetLowSpaceRatio(), 0),
+		score: source.RegionScore(opt.GetRegionScoreFormulaVersion(), opt.GetHighSpaceRatio(), opt.GetLowSpaceRatio(), opt.GetRegionScoreCurve(), 0),
 	}
 }
 
@@ -874,9 +929,40 @@ func (f *RegionScoreFilter) Source(opt *config.PersistOptions, _ *core.StoreInfo
 
 // Target return true if target's score less than source's score
 func (f *RegionScoreFilter) Target(opt *config.PersistOptions, store *core.StoreInfo) plan.Status {
-	score := store.RegionScore(opt.GetRegionScoreFormulaVersion(), opt.GetHighSpaceRatio(), opt.GetLowSpaceRatio(), 0)
+	score := store.RegionScore(opt.GetRegionScoreFormulaVersion(), opt.GetHighSpaceRatio(), opt.GetLowSpaceRatio(), opt.GetRegionScoreCurve(), 0)
 	if score < f.score {
 		return statusOK
 	}
 	return statusNoNeed
 }
+
+// storeQuotaFilter filters out the target stores which reach their soft quotas
+// of the leader or region count. It only works when the store quota bias is enabled.
+type storeQuotaFilter struct {
+	scope string
+	kind  core.ResourceKind
+}
+
+// NewStoreQuotaFilter creates a Filter that filters out the target stores reaching their soft quotas.
+func NewStoreQuotaFilter(scope string, kind core.ResourceKind) Filter {
+	return &storeQuotaFilter{scope: scope, kind: kind}
+}
+
+func (f *storeQuotaFilter) Scope() string {
+	return f.scope
+}
+
+func (f *storeQuotaFilter) Type() string {
+	return "store-quota-filter"
+}
+
+func (f *storeQuotaFilter) Source(opt *config.PersistOptions, _ *core.StoreInfo) plan.Status {
+	return statusOK
+}
+
+func (f *storeQuotaFilter) Target(opt *config.PersistOptions, store *core.StoreInfo) plan.Status {
+	if opt.IsStoreQuotaBiasEnabled() && store.ExceedsQuota(f.kind, 1) {
+		return statusStoreQuota
+	}
+	return statusOK
+}
@@ -24,6 +24,7 @@ import (
 	"github.com/tikv/pd/pkg/mock/mockcluster"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/labeler"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/schedule/plan"
 )
@@ -185,6 +186,26 @@ func TestStoreStateFilter(t *testing.T) {
 		{3, plan.StatusOK, plan.StatusOK},
 	}
 	check(store, testCases)
+
+	// Version skewed
+	store = store.Clone(core.SetStoreStats(&pdpb.StoreStats{}), core.SetVersionLag(2))
+	cfg := opt.GetScheduleConfig().Clone()
+	cfg.MaxStoreVersionSkew = 2
+	cfg.StoreVersionSkewPolicy = config.StoreVersionSkewPolicyExclude
+	opt.SetScheduleConfig(cfg)
+	testCases = []testCase{
+		{2, plan.StatusOK, plan.StatusOK},
+	}
+	check(store, testCases)
+	cfg.MaxStoreVersionSkew = 1
+	opt.SetScheduleConfig(cfg)
+	testCases = []testCase{
+		{0, plan.StatusOK, plan.StatusStoreBlocked},
+		{1, plan.StatusOK, plan.StatusStoreBlocked},
+		{2, plan.StatusOK, plan.StatusStoreBlocked},
+		{3, plan.StatusOK, plan.StatusStoreBlocked},
+	}
+	check(store, testCases)
 }
 
 func TestStoreStateFilterReason(t *testing.T) {
@@ -394,3 +415,39 @@ func BenchmarkCloneRegionTest(b *testing.B) {
 		_ = createRegionForRuleFit(region.GetStartKey(), region.GetEndKey(), region.GetPeers(), region.GetLeader())
 	}
 }
+
+func TestPinnedLeaderFilter(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opt := config.NewTestOptions()
+	testCluster := mockcluster.NewCluster(ctx, opt)
+	for id := uint64(1); id <= 3; id++ {
+		testCluster.AddLeaderStore(id, 1)
+	}
+	region := core.NewRegionInfo(&metapb.Region{Id: 1, StartKey: []byte("a"), EndKey: []byte("b"), Peers: []*metapb.Peer{
+		{StoreId: 1, Id: 1},
+		{StoreId: 2, Id: 2},
+		{StoreId: 3, Id: 3},
+	}}, &metapb.Peer{StoreId: 1, Id: 1})
+	re.Nil(NewPinnedLeaderFilter("", testCluster, region))
+
+	err := testCluster.RegionLabeler.SetLabelRule(&labeler.LabelRule{
+		ID:       "pin",
+		Labels:   []labeler.RegionLabel{{Key: labeler.PinnedLeaderStoreLabel, Value: "2"}},
+		RuleType: labeler.KeyRange,
+		Data:     []interface{}{map[string]interface{}{"start_key": "61", "end_key": "62"}},
+	})
+	re.NoError(err)
+	f := NewPinnedLeaderFilter("", testCluster, region)
+	re.NotNil(f)
+	re.True(f.Source(testCluster.GetOpts(), testCluster.GetStore(1)).IsOK())
+	re.False(f.Source(testCluster.GetOpts(), testCluster.GetStore(2)).IsOK())
+	re.True(f.Target(testCluster.GetOpts(), testCluster.GetStore(2)).IsOK())
+	re.False(f.Target(testCluster.GetOpts(), testCluster.GetStore(3)).IsOK())
+
+	// the other regions are not pinned.
+	other := region.Clone(core.WithStartKey([]byte("b")), core.WithEndKey([]byte("c")))
+	re.Nil(NewPinnedLeaderFilter("", testCluster, other))
+}
@@ -14,7 +14,14 @@
 
 package filter
 
-import "github.com/prometheus/client_golang/prometheus"
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikv/pd/pkg/metricutil"
+)
+
+// filterFamily is the name of the metric family of filterCounter, whose
+// cardinality grows with the number of the stores.
+const filterFamily = "pd_schedule_filter"
 
 var (
 	filterCounter = prometheus.NewCounterVec(
@@ -28,4 +35,17 @@ var (
 
 func init() {
 	prometheus.MustRegister(filterCounter)
+	metricutil.RegisterControlledFamily(filterFamily, filterCounter.Reset)
+}
+
+// incFilterCounter counts a store filtered out. The store labels are dropped
+// if the series of the stores are aggregated.
+func incFilterCounter(action, address, storeID, scope, typ, sourceID, targetID string) {
+	if metricutil.IsFamilyDisabled(filterFamily) {
+		return
+	}
+	if metricutil.IsAggregated() {
+		address, storeID, sourceID, targetID = "", metricutil.AggregatedStoreLabel, "", ""
+	}
+	filterCounter.WithLabelValues(action, address, storeID, scope, typ, sourceID, targetID).Inc()
 }
@@ -38,6 +38,9 @@ var (
 	statusStorePauseLeader        = plan.NewStatus(plan.StatusStoreBlocked, "the store is not allowed to transfer leader, there might be an evict-leader-scheduler")
 	statusStoreRejectLeader       = plan.NewStatus(plan.StatusStoreBlocked, "the store is not allowed to transfer leader, please check 'label-property'")
 	statusStoreSlow               = plan.NewStatus(plan.StatusStoreBlocked, "the store is slow and are evicting leaders, there might be an evict-slow-store-scheduler")
+	statusStoreQuota              = plan.NewStatus(plan.StatusStoreBlocked, "the store reaches its soft quota, please check the store quota")
+	statusStoreVersionSkewed      = plan.NewStatus(plan.StatusStoreBlocked, "the store version lags behind too much, please check 'max-store-version-skew'")
+	statusStorePinnedLeader       = plan.NewStatus(plan.StatusStoreBlocked, "the leader of the region is pinned to another store, please check the 'pinned-leader-store' annotation of the region")
 
 	// region filter status
 	statusRegionPendingPeer   = plan.NewStatus(plan.StatusRegionUnhealthy, "region has pending peers")
@@ -38,11 +38,16 @@ type HeartbeatStream interface {
 const (
 	heartbeatStreamKeepAliveInterval = time.Minute
 	heartbeatChanCapacity            = 1024
+	// maxHeartbeatStreamsPerStore is the max number of sharded streams kept for one store.
+	maxHeartbeatStreamsPerStore = 8
 )
 
 type streamUpdate struct {
 	storeID uint64
 	stream  HeartbeatStream
+	// shard indicates the stream is added to the existing streams of the store
+	// instead of replacing them.
+	shard bool
 }
 
 // HeartbeatStreams is the bridge of communication with TIKV instance.
@@ -51,7 +56,7 @@ type HeartbeatStreams struct {
 	hbStreamCtx    context.Context
 	hbStreamCancel context.CancelFunc
 	clusterID      uint64
-	streams        map[uint64]HeartbeatStream
+	streams        map[uint64][]HeartbeatStream
 	msgCh          chan *pdpb.RegionHeartbeatResponse
 	streamCh       chan streamUpdate
 	storeInformer  core.StoreSetInformer
@@ -75,7 +80,7 @@ func newHbStreams(ctx context.Context, clusterID uint64, storeInformer core.Stor
 		hbStreamCtx:    hbStreamCtx,
 		hbStreamCancel: hbStreamCancel,
 		clusterID:      clusterID,
-		streams:        make(map[uint64]HeartbeatStream),
+		streams:        make(map[uint64][]HeartbeatStream),
 		msgCh:          make(chan *pdpb.RegionHeartbeatResponse, heartbeatChanCapacity),
 		streamCh:       make(chan streamUpdate, 1),
 		storeInformer:  storeInformer,
@@ -101,7 +106,7 @@ func (s *HeartbeatStreams) run() {
 	for {
 		select {
 		case update := <-s.streamCh:
-			s.streams[update.storeID] = update.stream
+			s.updateStream(update)
 		case msg := <-s.msgCh:
 			storeID := msg.GetTargetPeer().GetStoreId()
 			storeLabel := strconv.FormatUint(storeID, 10)
@@ -114,23 +119,30 @@ func (s *HeartbeatStreams) run() {
 				continue
 			}
 			storeAddress := store.GetAddress()
-			if stream, ok := s.streams[storeID]; ok {
+			if len(s.streams[storeID]) == 0 {
+				log.Debug("heartbeat stream not found, skip send message",
+					zap.Uint64("region-id", msg.RegionId),
+					zap.Uint64("store-id", storeID))
+				heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "push", "skip").Inc()
+				continue
+			}
+			// Try the stream the region is sharded to first, and fall back to the
+			// remaining streams of the store if it fails.
+			for len(s.streams[storeID]) > 0 {
+				streams := s.streams[storeID]
+				stream := streams[msg.RegionId%uint64(len(streams))]
 				if err := stream.Send(msg); err != nil {
 					log.Error("send heartbeat message fail",
 						zap.Uint64("region-id", msg.RegionId), errs.ZapError(errs.ErrGRPCSend.Wrap(err).GenWithStackByArgs()))
-					delete(s.streams, storeID)
+					s.removeStream(storeID, stream)
 					heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "push", "err").Inc()
-				} else {
-					heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "push", "ok").Inc()
+					continue
 				}
-			} else {
-				log.Debug("heartbeat stream not found, skip send message",
-					zap.Uint64("region-id", msg.RegionId),
-					zap.Uint64("store-id", storeID))
-				heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "push", "skip").Inc()
+				heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "push", "ok").Inc()
+				break
 			}
 		case <-keepAliveTicker.C:
-			for storeID, stream := range s.streams {
+			for storeID, streams := range s.streams {
 				store := s.storeInformer.GetStore(storeID)
 				if store == nil {
 					log.Error("failed to get store", zap.Uint64("store-id", storeID), errs.ZapError(errs.ErrGetSourceStore))
@@ -139,14 +151,16 @@ func (s *HeartbeatStreams) run() {
 				}
 				storeAddress := store.GetAddress()
 				storeLabel := strconv.FormatUint(storeID, 10)
-				if err := stream.Send(keepAlive); err != nil {
-					log.Warn("send keepalive message fail, store maybe disconnected",
-						zap.Uint64("target-store-id", storeID),
-						errs.ZapError(err))
-					delete(s.streams, storeID)
-					heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "keepalive", "err").Inc()
-				} else {
-					heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "keepalive", "ok").Inc()
+				for _, stream := range streams {
+					if err := stream.Send(keepAlive); err != nil {
+						log.Warn("send keepalive message fail, store maybe disconnected",
+							zap.Uint64("target-store-id", storeID),
+							errs.ZapError(err))
+						s.removeStream(storeID, stream)
+						heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "keepalive", "err").Inc()
+					} else {
+						heartbeatStreamCounter.WithLabelValues(storeAddress, storeLabel, "keepalive", "ok").Inc()
+					}
 				}
 			}
 		case <-s.hbStreamCtx.Done():
@@ -155,18 +169,64 @@ func (s *HeartbeatStreams) run() {
 	}
 }
 
+func (s *HeartbeatStreams) updateStream(update streamUpdate) {
+	if !update.shard {
+		s.streams[update.storeID] = []HeartbeatStream{update.stream}
+		return
+	}
+	streams := s.streams[update.storeID]
+	for _, stream := range streams {
+		if stream == update.stream {
+			return
+		}
+	}
+	streams = append(streams, update.stream)
+	if len(streams) > maxHeartbeatStreamsPerStore {
+		streams = streams[len(streams)-maxHeartbeatStreamsPerStore:]
+	}
+	s.streams[update.storeID] = streams
+}
+
+func (s *HeartbeatStreams) removeStream(storeID uint64, target HeartbeatStream) {
+	streams := s.streams[storeID]
+	for i, stream := range streams {
+		if stream == target {
+			streams = append(streams[:i:i], streams[i+1:]...)
+			break
+		}
+	}
+	if len(streams) == 0 {
+		delete(s.streams, storeID)
+		return
+	}
+	s.streams[storeID] = streams
+}
+
 // Close closes background running.
 func (s *HeartbeatStreams) Close() {
 	s.hbStreamCancel()
 	s.wg.Wait()
 }
 
-// BindStream binds a stream with a specified store.
+// BindStream binds a stream with a specified store, replacing all streams bound before.
 func (s *HeartbeatStreams) BindStream(storeID uint64, stream HeartbeatStream) {
-	update := streamUpdate{
+	s.sendStreamUpdate(streamUpdate{
 		storeID: storeID,
 		stream:  stream,
-	}
+	})
+}
+
+// BindShardStream adds a stream to the streams of a specified store. Messages of a
+// region are dispatched to one of the streams sharded by the region ID.
+func (s *HeartbeatStreams) BindShardStream(storeID uint64, stream HeartbeatStream) {
+	s.sendStreamUpdate(streamUpdate{
+		storeID: storeID,
+		stream:  stream,
+		shard:   true,
+	})
+}
+
+func (s *HeartbeatStreams) sendStreamUpdate(update streamUpdate) {
 	select {
 	case s.streamCh <- update:
 	case <-s.hbStreamCtx.Done():
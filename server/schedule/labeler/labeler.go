@@ -15,13 +15,16 @@
 package labeler
 
 import (
+	"bytes"
 	"context"
 	"encoding/json"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/retryutil"
 	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule/rangelist"
@@ -32,6 +35,9 @@ import (
 // RegionLabeler is utility to label regions.
 type RegionLabeler struct {
 	storage endpoint.RuleStorage
+	// persistMu serializes the changes of the rules, so the RWMutex is not
+	// held while the rules are being persisted.
+	persistMu syncutil.Mutex
 	syncutil.RWMutex
 	labelRules map[string]*LabelRule
 	rangeList  rangelist.List // sorted LabelRules of the type `KeyRange`
@@ -72,6 +78,8 @@ func (l *RegionLabeler) doGC(gcInterval time.Duration) {
 
 func (l *RegionLabeler) checkAndClearExpiredLabels() {
 	now := time.Now()
+	l.persistMu.Lock()
+	defer l.persistMu.Unlock()
 	l.Lock()
 	defer l.Unlock()
 
@@ -153,14 +161,16 @@ func (l *RegionLabeler) GetSplitKeys(start, end []byte) [][]byte {
 	return l.rangeList.GetSplitKeys(start, end)
 }
 
-// GetAllLabelRules returns all the rules.
+// GetAllLabelRules returns all the rules. The expired labels are left out.
 func (l *RegionLabeler) GetAllLabelRules() []*LabelRule {
-	l.checkAndClearExpiredLabels()
+	now := time.Now()
 	l.RLock()
 	defer l.RUnlock()
 	rules := make([]*LabelRule, 0, len(l.labelRules))
 	for _, rule := range l.labelRules {
-		rules = append(rules, rule)
+		if rule = rule.withoutExpiredLabels(now); rule != nil {
+			rules = append(rules, rule)
+		}
 	}
 	return rules
 }
@@ -182,23 +192,17 @@ func (l *RegionLabeler) GetLabelRule(id string) *LabelRule {
 	return l.getAndCheckRule(id, time.Now())
 }
 
+// getAndCheckRule returns the rule without the expired labels. It only reads
+// the rules, the expired labels are cleared physically by the GC, so it is not
+// blocked by the changes being persisted.
 func (l *RegionLabeler) getAndCheckRule(id string, now time.Time) *LabelRule {
-	l.Lock()
-	defer l.Unlock()
+	l.RLock()
+	defer l.RUnlock()
 	rule, ok := l.labelRules[id]
 	if !ok {
 		return nil
 	}
-	if !rule.checkAndRemoveExpireLabels(now) {
-		return rule
-	}
-	if len(rule.Labels) == 0 {
-		l.storage.DeleteRegionRule(id)
-		delete(l.labelRules, id)
-		return nil
-	}
-	l.storage.SaveRegionRule(id, rule)
-	return rule
+	return rule.withoutExpiredLabels(now)
 }
 
 // SetLabelRule inserts or updates a LabelRule.
@@ -206,11 +210,15 @@ func (l *RegionLabeler) SetLabelRule(rule *LabelRule) error {
 	if err := rule.checkAndAdjust(); err != nil {
 		return err
 	}
-	l.Lock()
-	defer l.Unlock()
-	if err := l.storage.SaveRegionRule(rule.ID, rule); err != nil {
+	l.persistMu.Lock()
+	defer l.persistMu.Unlock()
+	if err := l.persist(func() error {
+		return l.storage.SaveRegionRule(rule.ID, rule)
+	}); err != nil {
 		return err
 	}
+	l.Lock()
+	defer l.Unlock()
 	l.labelRules[rule.ID] = rule
 	l.buildRangeList()
 	return nil
@@ -218,14 +226,21 @@ func (l *RegionLabeler) SetLabelRule(rule *LabelRule) error {
 
 // DeleteLabelRule removes a LabelRule.
 func (l *RegionLabeler) DeleteLabelRule(id string) error {
-	l.Lock()
-	defer l.Unlock()
-	if _, ok := l.labelRules[id]; !ok {
+	l.persistMu.Lock()
+	defer l.persistMu.Unlock()
+	l.RLock()
+	_, ok := l.labelRules[id]
+	l.RUnlock()
+	if !ok {
 		return errs.ErrRegionRuleNotFound.FastGenByArgs(id)
 	}
-	if err := l.storage.DeleteRegionRule(id); err != nil {
+	if err := l.persist(func() error {
+		return l.storage.DeleteRegionRule(id)
+	}); err != nil {
 		return err
 	}
+	l.Lock()
+	defer l.Unlock()
 	delete(l.labelRules, id)
 	l.buildRangeList()
 	return nil
@@ -239,16 +254,23 @@ func (l *RegionLabeler) Patch(patch LabelRulePatch) error {
 		}
 	}
 
+	l.persistMu.Lock()
+	defer l.persistMu.Unlock()
 	// save to storage
-	for _, key := range patch.DeleteRules {
-		if err := l.storage.DeleteRegionRule(key); err != nil {
-			return err
+	if err := l.persist(func() error {
+		for _, key := range patch.DeleteRules {
+			if err := l.storage.DeleteRegionRule(key); err != nil {
+				return err
+			}
 		}
-	}
-	for _, rule := range patch.SetRules {
-		if err := l.storage.SaveRegionRule(rule.ID, rule); err != nil {
-			return err
+		for _, rule := range patch.SetRules {
+			if err := l.storage.SaveRegionRule(rule.ID, rule); err != nil {
+				return err
+			}
 		}
+		return nil
+	}); err != nil {
+		return err
 	}
 
 	// update inmemory states.
@@ -265,6 +287,12 @@ func (l *RegionLabeler) Patch(patch LabelRulePatch) error {
 	return nil
 }
 
+// persist runs the persist function with retries. It must be called with
+// persistMu held and without holding the RWMutex.
+func (l *RegionLabeler) persist(persist func() error) error {
+	return retryutil.DefaultBackoff.Do(l.ctx, persist)
+}
+
 // GetRegionLabel returns the label of the region for a key.
 // If there are multiple rules that match the key, the one with max rule index will be returned.
 func (l *RegionLabeler) GetRegionLabel(region *core.RegionInfo, key string) string {
@@ -292,12 +320,66 @@ func (l *RegionLabeler) GetRegionLabel(region *core.RegionInfo, key string) stri
 	return value
 }
 
+// GetRegionLabelKeyRange returns the key range of the rule which assigns the label
+// of the key to the region. It returns false if the region does not have the label.
+func (l *RegionLabeler) GetRegionLabelKeyRange(region *core.RegionInfo, key string) ([]byte, []byte, bool) {
+	l.RLock()
+	defer l.RUnlock()
+	now := time.Now()
+	var matched *LabelRule
+	// search ranges
+	if i, data := l.rangeList.GetData(region.GetStartKey(), region.GetEndKey()); i != -1 {
+		for _, rule := range data {
+			r := rule.(*LabelRule)
+			if matched != nil && r.Index <= matched.Index {
+				continue
+			}
+			for _, l := range r.Labels {
+				if !l.expireBefore(now) && l.Key == key {
+					matched = r
+				}
+			}
+		}
+	}
+	if matched == nil || matched.RuleType != KeyRange {
+		return nil, nil, false
+	}
+	for _, r := range matched.Data.([]*KeyRangeRule) {
+		if bytes.Compare(region.GetStartKey(), r.StartKey) >= 0 &&
+			(len(r.EndKey) == 0 || (len(region.GetEndKey()) > 0 && bytes.Compare(region.GetEndKey(), r.EndKey) <= 0)) {
+			return r.StartKey, r.EndKey, true
+		}
+	}
+	return nil, nil, false
+}
+
 // ScheduleDisabled returns true if the region is lablelld with schedule-disabled.
 func (l *RegionLabeler) ScheduleDisabled(region *core.RegionInfo) bool {
 	v := l.GetRegionLabel(region, scheduleOptionLabel)
 	return strings.EqualFold(v, scheduleOptioonValueDeny)
 }
 
+// ReplicaFrozen returns true if the region is labelled with replica-frozen, then
+// only the leader of the region can be transferred.
+func (l *RegionLabeler) ReplicaFrozen(region *core.RegionInfo) bool {
+	v := l.GetRegionLabel(region, ReplicaOptionLabel)
+	return strings.EqualFold(v, ReplicaOptionValueFreeze)
+}
+
+// PinnedLeaderStore returns the store which the leader of the region is pinned to,
+// or 0 if the region is not pinned.
+func (l *RegionLabeler) PinnedLeaderStore(region *core.RegionInfo) uint64 {
+	v := l.GetRegionLabel(region, PinnedLeaderStoreLabel)
+	if v == "" {
+		return 0
+	}
+	storeID, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return 0
+	}
+	return storeID
+}
+
 // GetRegionLabels returns the labels of the region.
 // For each key, the label with max rule index will be returned.
 func (l *RegionLabeler) GetRegionLabels(region *core.RegionInfo) []*RegionLabel {
@@ -313,7 +313,11 @@ func TestLabelerRuleTTL(t *testing.T) {
 	// rule2 should be exist since `GetRegionLabels` won't clear it physically.
 	checkRuleInMemoryAndStoage(re, labeler, "rule2", true)
 	re.Nil(labeler.GetLabelRule("rule2"))
-	// rule2 should be physically clear.
+	re.NotContains(labeler.GetAllLabelRules(), labeler.labelRules["rule2"])
+	// rule2 should be exist since the lookups won't clear it physically either.
+	checkRuleInMemoryAndStoage(re, labeler, "rule2", true)
+	labeler.checkAndClearExpiredLabels()
+	// rule2 should be physically clear by the GC.
 	checkRuleInMemoryAndStoage(re, labeler, "rule2", false)
 
 	re.Equal("", labeler.GetRegionLabel(region, "k2"))
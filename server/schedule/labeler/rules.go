@@ -56,6 +56,15 @@ const (
 const (
 	scheduleOptionLabel      = "schedule"
 	scheduleOptioonValueDeny = "deny"
+
+	// ReplicaOptionLabel is the label to freeze the replicas of the regions.
+	ReplicaOptionLabel = "replica_option"
+	// ReplicaOptionValueFreeze means the peers of the regions can't be changed.
+	ReplicaOptionValueFreeze = "freeze"
+
+	// PinnedLeaderStoreLabel is the label to pin the leaders of the regions to a store,
+	// its value is the store ID.
+	PinnedLeaderStoreLabel = "pinned-leader-store"
 )
 
 // KeyRangeRule contains the start key and end key of the LabelRule.
@@ -130,6 +139,31 @@ func (rule *LabelRule) checkAndRemoveExpireLabels(now time.Time) bool {
 	return true
 }
 
+// withoutExpiredLabels returns the rule itself if none of its labels expires,
+// or a copy with the unexpired labels only. It returns nil if all the labels
+// expire. The rule is not changed, so it can be called with the read lock.
+func (rule *LabelRule) withoutExpiredLabels(now time.Time) *LabelRule {
+	labels := make([]RegionLabel, 0, len(rule.Labels))
+	for _, l := range rule.Labels {
+		if !l.expireBefore(now) {
+			labels = append(labels, l)
+		}
+	}
+	if len(labels) == len(rule.Labels) {
+		return rule
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	return &LabelRule{
+		ID:       rule.ID,
+		Index:    rule.Index,
+		Labels:   labels,
+		RuleType: rule.RuleType,
+		Data:     rule.Data,
+	}
+}
+
 func (rule *LabelRule) checkAndAdjust() error {
 	if rule.ID == "" {
 		return errs.ErrRegionRuleContent.FastGenByArgs("empty rule id")
@@ -25,6 +25,14 @@ var (
 			Help:      "Counter of schedule operators.",
 		}, []string{"type", "event"})
 
+	operatorComponentCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "operators_by_component_count",
+			Help:      "Counter of the operators created by external callers, by the known component in their metadata.",
+		}, []string{"component", "event"})
+
 	operatorDuration = prometheus.NewHistogramVec(
 		prometheus.HistogramOpts{
 			Namespace: "pd",
@@ -60,6 +68,30 @@ var (
 			Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
 		}, []string{"type"})
 
+	waitingOperatorQueueGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "waiting_operators_queue_depth",
+			Help:      "Number of the waiting operators queued by the key prefix.",
+		}, []string{"key_prefix"})
+
+	waitingOperatorQueueWaitGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "waiting_operators_queue_wait_seconds",
+			Help:      "Waiting time (s) of the last operator promoted from the queue of the key prefix which still has waiting operators.",
+		}, []string{"key_prefix"})
+
+	waitingOperatorRestoreCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "waiting_operators_restore_count",
+			Help:      "Counter of the waiting operators restored from or discarded in the snapshot of the previous leader.",
+		}, []string{"event"})
+
 	storeLimitCostCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "pd",
@@ -68,6 +100,22 @@ var (
 			Help:      "limit rate cost of store.",
 		}, []string{"store", "limit_type"})
 
+	storeLimitSizeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "store_limit_size",
+			Help:      "Region size in MB taken from the size limit of store.",
+		}, []string{"store", "limit_type"})
+
+	storeLimitExemptionCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "pd",
+			Subsystem: "schedule",
+			Name:      "store_limit_exemption",
+			Help:      "Counter of the operators exempted from the store limits.",
+		}, []string{"exemption", "store", "limit_type", "event"})
+
 	scatterCounter = prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Namespace: "pd",
@@ -88,9 +136,15 @@ var (
 func init() {
 	prometheus.MustRegister(operatorCounter)
 	prometheus.MustRegister(operatorDuration)
+	prometheus.MustRegister(operatorComponentCounter)
 	prometheus.MustRegister(operatorWaitDuration)
 	prometheus.MustRegister(storeLimitCostCounter)
+	prometheus.MustRegister(storeLimitSizeCounter)
+	prometheus.MustRegister(storeLimitExemptionCounter)
 	prometheus.MustRegister(operatorWaitCounter)
+	prometheus.MustRegister(waitingOperatorQueueGauge)
+	prometheus.MustRegister(waitingOperatorQueueWaitGauge)
+	prometheus.MustRegister(waitingOperatorRestoreCounter)
 	prometheus.MustRegister(scatterCounter)
 	prometheus.MustRegister(scatterDistributionCounter)
 	prometheus.MustRegister(operatorSizeHist)
@@ -0,0 +1,54 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+// LimitExemption is the class of the operators exempted from the store limits,
+// which are limited by the emergency store limit instead.
+type LimitExemption string
+
+const (
+	// NoLimitExemption is the class of the ordinary operators.
+	NoLimitExemption LimitExemption = ""
+	// UnsafeRecoveryExemption is the class of the operators repairing the
+	// regions changed by the unsafe recovery.
+	UnsafeRecoveryExemption LimitExemption = "unsafe-recovery"
+)
+
+// SetLimitExemption sets the class the operator is exempted from the store
+// limits for.
+func (o *Operator) SetLimitExemption(exemption LimitExemption) {
+	o.exemption = exemption
+}
+
+// GetLimitExemption returns the class the operator is exempted from the store
+// limits for, or NoLimitExemption if it is an ordinary operator.
+func (o *Operator) GetLimitExemption() LimitExemption {
+	return o.exemption
+}
+
+// CommonLimitExemption returns the exemption class of the operators, which are
+// added together. They are exempted only if all of them are of the same class.
+func CommonLimitExemption(ops ...*Operator) LimitExemption {
+	if len(ops) == 0 {
+		return NoLimitExemption
+	}
+	exemption := ops[0].GetLimitExemption()
+	for _, op := range ops[1:] {
+		if op.GetLimitExemption() != exemption {
+			return NoLimitExemption
+		}
+	}
+	return exemption
+}
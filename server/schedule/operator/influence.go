@@ -41,6 +41,9 @@ type StoreInfluence struct {
 	LeaderSize  int64
 	LeaderCount int64
 	StepCost    map[storelimit.Type]int64
+	// StepSize is the total approximate size of the Regions added to or
+	// removed from the store, which is used by the size limit.
+	StepSize map[storelimit.Type]int64
 }
 
 // ResourceProperty returns delta size of leader/region by influence.
@@ -77,6 +80,21 @@ func (s *StoreInfluence) addStepCost(limitType storelimit.Type, cost int64) {
 	s.StepCost[limitType] += cost
 }
 
+// GetStepSize returns the specific type step size
+func (s StoreInfluence) GetStepSize(limitType storelimit.Type) int64 {
+	if s.StepSize == nil {
+		return 0
+	}
+	return s.StepSize[limitType]
+}
+
+func (s *StoreInfluence) addStepSize(limitType storelimit.Type, size int64) {
+	if s.StepSize == nil {
+		s.StepSize = make(map[storelimit.Type]int64)
+	}
+	s.StepSize[limitType] += size
+}
+
 // AdjustStepCost adjusts the step cost of specific type store limit according to region size
 func (s *StoreInfluence) AdjustStepCost(limitType storelimit.Type, regionSize int64) {
 	if regionSize > storelimit.SmallRegionThreshold {
@@ -84,4 +102,7 @@ func (s *StoreInfluence) AdjustStepCost(limitType storelimit.Type, regionSize in
 	} else if regionSize > core.EmptyRegionApproximateSize {
 		s.addStepCost(limitType, storelimit.SmallRegionInfluence[limitType])
 	}
+	if regionSize > core.EmptyRegionApproximateSize {
+		s.addStepSize(limitType, regionSize)
+	}
 }
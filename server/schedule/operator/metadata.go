@@ -0,0 +1,115 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import "strings"
+
+// Metadata is attached to an operator by the external caller which creates
+// it, so that the operator can be traced back to the caller.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type Metadata struct {
+	// Component is the component or tool which creates the operator.
+	Component string `json:"component,omitempty"`
+	// TicketID is the ID of the ticket the operator is created for.
+	TicketID string `json:"ticket_id,omitempty"`
+	Comment  string `json:"comment,omitempty"`
+}
+
+// IsEmpty returns whether none of the fields is set.
+func (m *Metadata) IsEmpty() bool {
+	return m == nil || (m.Component == "" && m.TicketID == "" && m.Comment == "")
+}
+
+// Match returns whether the metadata matches all the non-empty fields of the
+// filter. An empty filter matches any metadata, including nil.
+func (m *Metadata) Match(filter *Metadata) bool {
+	if filter.IsEmpty() {
+		return true
+	}
+	if m == nil {
+		return false
+	}
+	return (filter.Component == "" || filter.Component == m.Component) &&
+		(filter.TicketID == "" || filter.TicketID == m.TicketID) &&
+		(filter.Comment == "" || filter.Comment == m.Comment)
+}
+
+func (m *Metadata) String() string {
+	fields := make([]string, 0, 3)
+	if m.Component != "" {
+		fields = append(fields, "component="+m.Component)
+	}
+	if m.TicketID != "" {
+		fields = append(fields, "ticket-id="+m.TicketID)
+	}
+	if m.Comment != "" {
+		fields = append(fields, "comment="+m.Comment)
+	}
+	return strings.Join(fields, ", ")
+}
+
+// SetMetadata attaches the metadata of the caller to the operator. It must be
+// called before the operator is added to the controller.
+func (o *Operator) SetMetadata(metadata *Metadata) {
+	if metadata.IsEmpty() {
+		o.metadata = nil
+		return
+	}
+	o.metadata = metadata
+}
+
+// GetMetadata returns the metadata of the caller, nil if there is none.
+func (o *Operator) GetMetadata() *Metadata {
+	return o.metadata
+}
+
+// OtherComponent is the metric label of the components which are not known.
+const OtherComponent = "other"
+
+// knownComponents are the components reported in the metrics by their names.
+// The component is supplied by the caller, so the others are reported as
+// OtherComponent to bound the cardinality of the metrics.
+var knownComponents = map[string]struct{}{
+	"pd-ctl":        {},
+	"tidb":          {},
+	"tiup":          {},
+	"tidb-operator": {},
+	"br":            {},
+	"lightning":     {},
+	"dm":            {},
+	"ticdc":         {},
+}
+
+// GetComponentLabel returns the metric label of the component which creates
+// the operator, or an empty string if it is unknown.
+func (o *Operator) GetComponentLabel() string {
+	component := o.GetComponent()
+	if component == "" {
+		return ""
+	}
+	if _, ok := knownComponents[component]; !ok {
+		return OtherComponent
+	}
+	return component
+}
+
+// GetComponent returns the component which creates the operator, or an empty
+// string if it is unknown.
+func (o *Operator) GetComponent() string {
+	if o.metadata == nil {
+		return ""
+	}
+	return o.metadata.Component
+}
@@ -24,6 +24,7 @@ import (
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/prometheus/client_golang/prometheus"
+	"github.com/tikv/pd/pkg/trace"
 	"github.com/tikv/pd/server/core"
 )
 
@@ -39,9 +40,14 @@ const (
 	SlowOperatorWaitTime = 10 * time.Minute
 )
 
+// operatorIDAllocator allocates the IDs of the operators, which are unique
+// within the PD server.
+var operatorIDAllocator uint64
+
 // Operator contains execution steps generated by scheduler.
 // NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
 type Operator struct {
+	id               uint64
 	desc             string
 	brief            string
 	regionID         uint64
@@ -56,6 +62,11 @@ type Operator struct {
 	FinishedCounters []prometheus.Counter
 	AdditionalInfos  map[string]string
 	ApproximateSize  int64
+	reasons          []Reason
+	metadata         *Metadata
+	exemption        LimitExemption
+	bypassedChecks   map[string]string
+	span             *trace.Span
 }
 
 // NewOperator creates a new operator.
@@ -64,7 +75,12 @@ func NewOperator(desc, brief string, regionID uint64, regionEpoch *metapb.Region
 	if kind&OpAdmin != 0 {
 		level = core.HighPriority
 	}
+	span := trace.StartRootSpan("operator")
+	span.SetAttribute("desc", desc)
+	span.SetAttribute("region-id", regionID)
 	return &Operator{
+		id:              atomic.AddUint64(&operatorIDAllocator, 1),
+		span:            span,
 		desc:            desc,
 		brief:           brief,
 		regionID:        regionID,
@@ -87,6 +103,18 @@ func (o *Operator) String() string {
 	s := fmt.Sprintf("%s {%s} (kind:%s, region:%v(%v, %v), createAt:%s, startAt:%s, currentStep:%v, size:%d, steps:[%s])",
 		o.desc, o.brief, o.kind, o.regionID, o.regionEpoch.GetVersion(), o.regionEpoch.GetConfVer(), o.GetCreateTime(),
 		o.GetStartTime(), atomic.LoadInt32(&o.currentStep), o.ApproximateSize, strings.Join(stepStrs, ", "))
+	if len(o.reasons) > 0 {
+		s += " reasons:[" + o.GetReasonChain() + "]"
+	}
+	if o.metadata != nil {
+		s += " metadata:{" + o.metadata.String() + "}"
+	}
+	if o.exemption != NoLimitExemption {
+		s += " exemption:" + string(o.exemption)
+	}
+	if sc := o.SpanContext(); sc.IsValid() {
+		s += " trace-id:" + sc.TraceID.String()
+	}
 	if o.CheckSuccess() {
 		s += " finished"
 	}
@@ -101,6 +129,11 @@ func (o *Operator) MarshalJSON() ([]byte, error) {
 	return []byte(`"` + o.String() + `"`), nil
 }
 
+// ID returns the ID of the operator, which is unique within the PD server.
+func (o *Operator) ID() uint64 {
+	return o.id
+}
+
 // Desc returns the operator's short description.
 func (o *Operator) Desc() string {
 	return o.desc
@@ -264,6 +297,17 @@ func (o *Operator) getCurrentTimeAndStep() (startTime time.Time, opStep OpStep)
 	return
 }
 
+// Rewind moves the recorded times of the operator back by d, as if d has
+// elapsed. It is used by the simulators which run on a simulated clock.
+func (o *Operator) Rewind(d time.Duration) {
+	o.status.rewind(d)
+	for i := range o.stepsTime {
+		if t := atomic.LoadInt64(&o.stepsTime[i]); t != 0 {
+			atomic.StoreInt64(&o.stepsTime[i], t-d.Nanoseconds())
+		}
+	}
+}
+
 // Check checks if current step is finished, returns next step to take action.
 // If operator is at an end status, check returns nil.
 // It's safe to be called by multiple goroutine concurrently.
@@ -332,6 +376,8 @@ type OpHistory struct {
 	FinishTime time.Time
 	From, To   uint64
 	Kind       core.ResourceKind
+	Reasons    []Reason  `json:",omitempty"`
+	Metadata   *Metadata `json:",omitempty"`
 }
 
 // History transfers the operator's steps to operator histories.
@@ -347,6 +393,8 @@ func (o *Operator) History() []OpHistory {
 				From:       s.FromStore,
 				To:         s.ToStore,
 				Kind:       core.LeaderKind,
+				Reasons:    o.reasons,
+				Metadata:   o.metadata,
 			})
 		case AddPeer:
 			addPeerStores = append(addPeerStores, s.ToStore)
@@ -363,6 +411,8 @@ func (o *Operator) History() []OpHistory {
 				From:       removePeerStores[i],
 				To:         addPeerStores[i],
 				Kind:       core.RegionKind,
+				Reasons:    o.reasons,
+				Metadata:   o.metadata,
 			})
 		}
 	}
@@ -401,6 +451,24 @@ func (o *Operator) Record(finishTime time.Time) *OpRecord {
 	return record
 }
 
+// SetBypassedCheck marks the safety check as bypassed with the cause when the
+// operator is added, or clears the mark if the cause is empty.
+func (o *Operator) SetBypassedCheck(check, cause string) {
+	if len(cause) == 0 {
+		delete(o.bypassedChecks, check)
+		return
+	}
+	if o.bypassedChecks == nil {
+		o.bypassedChecks = make(map[string]string)
+	}
+	o.bypassedChecks[check] = cause
+}
+
+// GetBypassedChecks returns the bypassed safety checks with their causes.
+func (o *Operator) GetBypassedChecks() map[string]string {
+	return o.bypassedChecks
+}
+
 // GetAdditionalInfo returns additional info with string
 func (o *Operator) GetAdditionalInfo() string {
 	if len(o.AdditionalInfos) != 0 {
@@ -412,6 +480,20 @@ func (o *Operator) GetAdditionalInfo() string {
 	return ""
 }
 
+// ChangesMembership returns true if the operator changes the peers of the
+// region or merges it, while transferring the leader and splitting the region
+// keep the peers unchanged.
+func (o *Operator) ChangesMembership() bool {
+	for _, step := range o.steps {
+		switch step.(type) {
+		case TransferLeader, SplitRegion:
+		default:
+			return true
+		}
+	}
+	return false
+}
+
 // IsLeaveJointStateOperator returns true if the desc is OpDescLeaveJointState.
 func (o *Operator) IsLeaveJointStateOperator() bool {
 	return strings.EqualFold(o.desc, OpDescLeaveJointState)
@@ -524,3 +524,101 @@ func (suite *operatorTestSuite) TestRecord() {
 	suite.Equal(now, ob.FinishTime)
 	suite.Greater(ob.duration.Seconds(), time.Second.Seconds())
 }
+
+func (suite *operatorTestSuite) TestReasons() {
+	steps := []OpStep{
+		AddPeer{ToStore: 1, PeerID: 1},
+		RemovePeer{FromStore: 2},
+	}
+	op := suite.newTestOperator(1, OpRegion, steps...)
+	suite.Empty(op.Reasons())
+	suite.NotContains(op.String(), "reasons")
+
+	down := NewReason("rule-checker", "down-peer").With("store-id", 2).With("down-time", time.Hour)
+	rule := NewReason("rule-checker", "rule-not-fit").With("rule-id", "default")
+	op.AddReasons(down, rule)
+	suite.Len(op.Reasons(), 2)
+	suite.Equal("2", op.Reasons()[0].Details["store-id"])
+	suite.Equal("rule-checker:down-peer{down-time=1h0m0s, store-id=2} -> rule-checker:rule-not-fit{rule-id=default}", op.GetReasonChain())
+	suite.Contains(op.String(), "reasons:["+op.GetReasonChain()+"]")
+
+	// With does not modify the original reason.
+	suite.Len(down.With("max-store-down-time", time.Minute).Details, 3)
+	suite.Len(down.Details, 2)
+
+	histories := op.History()
+	suite.Len(histories, 1)
+	suite.Equal(op.Reasons(), histories[0].Reasons)
+}
+
+func (suite *operatorTestSuite) TestMetadata() {
+	op := suite.newTestOperator(1, OpAdmin|OpLeader, TransferLeader{FromStore: 1, ToStore: 2})
+	suite.Nil(op.GetMetadata())
+	suite.Empty(op.GetComponent())
+	suite.True(op.GetMetadata().Match(&Metadata{}))
+	suite.False(op.GetMetadata().Match(&Metadata{Component: "br"}))
+
+	op.SetMetadata(&Metadata{})
+	suite.Nil(op.GetMetadata())
+	op.SetMetadata(&Metadata{Component: "br", TicketID: "T-1", Comment: "restore"})
+	suite.Equal("br", op.GetComponent())
+	suite.Equal("br", op.GetComponentLabel())
+	suite.True(op.GetMetadata().Match(&Metadata{Component: "br"}))
+	suite.True(op.GetMetadata().Match(&Metadata{Component: "br", TicketID: "T-1"}))
+	suite.False(op.GetMetadata().Match(&Metadata{Component: "br", TicketID: "T-2"}))
+	suite.Contains(op.String(), "metadata:{component=br, ticket-id=T-1, comment=restore}")
+	suite.Equal(op.GetMetadata(), op.History()[0].Metadata)
+
+	// The unknown components are reported as the other one in the metrics.
+	op.SetMetadata(&Metadata{Component: "my-script-1"})
+	suite.Equal("my-script-1", op.GetComponent())
+	suite.Equal(OtherComponent, op.GetComponentLabel())
+}
+
+func (suite *operatorTestSuite) TestSnapshot() {
+	steps := []OpStep{
+		AddLearner{ToStore: 3, PeerID: 3},
+		PromoteLearner{ToStore: 3, PeerID: 3},
+		ChangePeerV2Enter{PromoteLearners: []PromoteLearner{{ToStore: 4, PeerID: 4}}},
+		ChangePeerV2Leave{PromoteLearners: []PromoteLearner{{ToStore: 4, PeerID: 4}}},
+		TransferLeader{FromStore: 1, ToStore: 3, ToStores: []uint64{3, 4}},
+		RemovePeer{FromStore: 1, PeerID: 1, IsDownStore: true},
+		SplitRegion{StartKey: []byte("a"), EndKey: []byte("b"), SplitKeys: [][]byte{[]byte("aa")}},
+		MergeRegion{FromRegion: &metapb.Region{Id: 1}, ToRegion: &metapb.Region{Id: 2}, IsPassive: true},
+	}
+	op := suite.newTestOperator(1, OpAdmin|OpRegion, steps...)
+	op.AdditionalInfos["key"] = "value"
+	op.AddReasons(NewReason("replica-checker", "miss-peer"))
+	op.SetMetadata(&Metadata{Component: "br"})
+	op.SetLimitExemption(UnsafeRecoveryExemption)
+	snapshot, err := op.Snapshot()
+	suite.NoError(err)
+	data, err := json.Marshal(snapshot)
+	suite.NoError(err)
+	snapshot = &Snapshot{}
+	suite.NoError(json.Unmarshal(data, snapshot))
+
+	rebuilt, err := snapshot.Rebuild()
+	suite.NoError(err)
+	suite.NotEqual(op.ID(), rebuilt.ID())
+	suite.Equal(op.Desc(), rebuilt.Desc())
+	suite.Equal(op.RegionID(), rebuilt.RegionID())
+	suite.Equal(op.RegionEpoch(), rebuilt.RegionEpoch())
+	suite.Equal(op.Kind(), rebuilt.Kind())
+	suite.Equal(op.GetPriorityLevel(), rebuilt.GetPriorityLevel())
+	suite.Equal(op.AdditionalInfos, rebuilt.AdditionalInfos)
+	suite.Equal(op.Reasons(), rebuilt.Reasons())
+	suite.Equal(op.GetMetadata(), rebuilt.GetMetadata())
+	suite.Equal(UnsafeRecoveryExemption, rebuilt.GetLimitExemption())
+	suite.Equal(op.Len(), rebuilt.Len())
+	for i := 0; i < op.Len(); i++ {
+		suite.Equal(op.Step(i).String(), rebuilt.Step(i).String())
+	}
+	suite.Equal(op.Step(0), rebuilt.Step(0))
+	suite.Equal(op.Step(7), rebuilt.Step(7))
+
+	// The snapshot with an unknown step cannot be rebuilt.
+	snapshot.Steps = append(snapshot.Steps, StepSnapshot{Type: "unknown"})
+	_, err = snapshot.Rebuild()
+	suite.Error(err)
+}
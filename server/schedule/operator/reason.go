@@ -0,0 +1,86 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Reason is a structured cause of creating an operator, such as the rule
+// violated, the store of the down peer or the threshold exceeded.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type Reason struct {
+	// Source is the checker or scheduler which creates the operator.
+	Source string `json:"source"`
+	// Cause is a short name of the cause, such as "down-peer".
+	Cause string `json:"cause"`
+	// Details are the inputs which lead to the cause, such as the threshold values.
+	Details map[string]string `json:"details,omitempty"`
+}
+
+// NewReason creates a new reason.
+func NewReason(source, cause string) Reason {
+	return Reason{Source: source, Cause: cause}
+}
+
+// With returns the reason with the detail added.
+func (r Reason) With(key string, value interface{}) Reason {
+	details := make(map[string]string, len(r.Details)+1)
+	for k, v := range r.Details {
+		details[k] = v
+	}
+	details[key] = fmt.Sprint(value)
+	r.Details = details
+	return r
+}
+
+func (r Reason) String() string {
+	if len(r.Details) == 0 {
+		return r.Source + ":" + r.Cause
+	}
+	keys := make([]string, 0, len(r.Details))
+	for k := range r.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	details := make([]string, 0, len(keys))
+	for _, k := range keys {
+		details = append(details, k+"="+r.Details[k])
+	}
+	return fmt.Sprintf("%s:%s{%s}", r.Source, r.Cause, strings.Join(details, ", "))
+}
+
+// AddReasons appends the reasons to the reason chain of the operator. The chain
+// is ordered from the direct cause of the operator to the broader context, e.g.
+// a down peer followed by the placement rule it breaks.
+func (o *Operator) AddReasons(reasons ...Reason) {
+	o.reasons = append(o.reasons, reasons...)
+}
+
+// Reasons returns the reason chain of the operator.
+func (o *Operator) Reasons() []Reason {
+	return o.reasons
+}
+
+// GetReasonChain returns the reason chain of the operator as a string.
+func (o *Operator) GetReasonChain() string {
+	reasons := make([]string, 0, len(o.reasons))
+	for _, r := range o.reasons {
+		reasons = append(reasons, r.String())
+	}
+	return strings.Join(reasons, " -> ")
+}
@@ -0,0 +1,185 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import (
+	"encoding/json"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+)
+
+// The types of the steps in the snapshots.
+const (
+	transferLeaderStepType    = "transfer-leader"
+	addPeerStepType           = "add-peer"
+	addLearnerStepType        = "add-learner"
+	promoteLearnerStepType    = "promote-learner"
+	removePeerStepType        = "remove-peer"
+	mergeRegionStepType       = "merge-region"
+	splitRegionStepType       = "split-region"
+	changePeerV2EnterStepType = "change-peer-v2-enter"
+	changePeerV2LeaveStepType = "change-peer-v2-leave"
+)
+
+// StepSnapshot is a step in the snapshot of an operator.
+type StepSnapshot struct {
+	Type string          `json:"type"`
+	Step json.RawMessage `json:"step"`
+}
+
+// Snapshot is a compact representation of the inputs to create an operator,
+// which is persisted so that the operator can be rebuilt by another PD server.
+// The execution state of the operator is not included.
+type Snapshot struct {
+	RegionID        uint64              `json:"region_id"`
+	RegionEpoch     *metapb.RegionEpoch `json:"region_epoch"`
+	Kind            OpKind              `json:"kind"`
+	Desc            string              `json:"desc"`
+	Brief           string              `json:"brief,omitempty"`
+	Priority        core.PriorityLevel  `json:"priority"`
+	ApproximateSize int64               `json:"approximate_size"`
+	Steps           []StepSnapshot      `json:"steps"`
+	AdditionalInfos map[string]string   `json:"additional_infos,omitempty"`
+	Reasons         []Reason            `json:"reasons,omitempty"`
+	Metadata        *Metadata           `json:"metadata,omitempty"`
+	Exemption       LimitExemption      `json:"exemption,omitempty"`
+}
+
+// Snapshot takes a snapshot of the inputs to create the operator. It returns
+// an error if some steps cannot be persisted.
+func (o *Operator) Snapshot() (*Snapshot, error) {
+	snapshot := &Snapshot{
+		RegionID:        o.regionID,
+		RegionEpoch:     o.regionEpoch,
+		Kind:            o.kind,
+		Desc:            o.desc,
+		Brief:           o.brief,
+		Priority:        o.level,
+		ApproximateSize: o.ApproximateSize,
+		Steps:           make([]StepSnapshot, 0, len(o.steps)),
+		Reasons:         o.reasons,
+		Metadata:        o.metadata,
+		Exemption:       o.exemption,
+	}
+	if len(o.AdditionalInfos) > 0 {
+		snapshot.AdditionalInfos = o.AdditionalInfos
+	}
+	for _, step := range o.steps {
+		var typ string
+		switch step.(type) {
+		case TransferLeader:
+			typ = transferLeaderStepType
+		case AddPeer:
+			typ = addPeerStepType
+		case AddLearner:
+			typ = addLearnerStepType
+		case PromoteLearner:
+			typ = promoteLearnerStepType
+		case RemovePeer:
+			typ = removePeerStepType
+		case MergeRegion:
+			typ = mergeRegionStepType
+		case SplitRegion:
+			typ = splitRegionStepType
+		case ChangePeerV2Enter:
+			typ = changePeerV2EnterStepType
+		case ChangePeerV2Leave:
+			typ = changePeerV2LeaveStepType
+		default:
+			return nil, errs.ErrUnknownOperatorStep.FastGenByArgs()
+		}
+		data, err := json.Marshal(step)
+		if err != nil {
+			return nil, errs.ErrJSONMarshal.Wrap(err).GenWithStackByCause()
+		}
+		snapshot.Steps = append(snapshot.Steps, StepSnapshot{Type: typ, Step: data})
+	}
+	return snapshot, nil
+}
+
+// Rebuild creates a new operator from the snapshot. The new operator is not
+// started, and its creation time is the time it is rebuilt.
+func (s *Snapshot) Rebuild() (*Operator, error) {
+	steps := make([]OpStep, 0, len(s.Steps))
+	for _, ss := range s.Steps {
+		step, err := ss.decode()
+		if err != nil {
+			return nil, err
+		}
+		steps = append(steps, step)
+	}
+	op := NewOperator(s.Desc, s.Brief, s.RegionID, s.RegionEpoch, s.Kind, s.ApproximateSize, steps...)
+	op.SetPriorityLevel(s.Priority)
+	for k, v := range s.AdditionalInfos {
+		op.AdditionalInfos[k] = v
+	}
+	op.AddReasons(s.Reasons...)
+	op.SetMetadata(s.Metadata)
+	op.SetLimitExemption(s.Exemption)
+	return op, nil
+}
+
+func (ss StepSnapshot) decode() (OpStep, error) {
+	var (
+		step OpStep
+		err  error
+	)
+	switch ss.Type {
+	case transferLeaderStepType:
+		var s TransferLeader
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case addPeerStepType:
+		var s AddPeer
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case addLearnerStepType:
+		var s AddLearner
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case promoteLearnerStepType:
+		var s PromoteLearner
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case removePeerStepType:
+		var s RemovePeer
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case mergeRegionStepType:
+		var s MergeRegion
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case splitRegionStepType:
+		var s SplitRegion
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case changePeerV2EnterStepType:
+		var s ChangePeerV2Enter
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	case changePeerV2LeaveStepType:
+		var s ChangePeerV2Leave
+		err = json.Unmarshal(ss.Step, &s)
+		step = s
+	default:
+		return nil, errs.ErrUnknownOperatorStep.FastGenByArgs()
+	}
+	if err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return step, nil
+}
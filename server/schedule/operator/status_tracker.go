@@ -94,6 +94,17 @@ func (trk *OpStatusTracker) setTime(st OpStatus, t time.Time) {
 	}
 }
 
+// rewind moves the reach times back by d.
+func (trk *OpStatusTracker) rewind(d time.Duration) {
+	trk.rw.Lock()
+	defer trk.rw.Unlock()
+	for i, t := range trk.reachTimes {
+		if !t.IsZero() {
+			trk.reachTimes[i] = t.Add(-d)
+		}
+	}
+}
+
 // IsEnd checks whether the current status is an end status.
 func (trk *OpStatusTracker) IsEnd() bool {
 	trk.rw.RLock()
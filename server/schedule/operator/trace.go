@@ -0,0 +1,38 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package operator
+
+import "github.com/tikv/pd/pkg/trace"
+
+// SpanContext returns the span context of the operator. The spans of the
+// region heartbeats and the dispatches of the operator are the children of
+// it, so that the whole life of the operator can be traced by its trace ID.
+func (o *Operator) SpanContext() trace.SpanContext {
+	return o.span.Context()
+}
+
+// PinSpan keeps the trace of the operator in the recorder until the operator
+// finishes. It is called when the operator is added.
+func (o *Operator) PinSpan() {
+	o.span.Pin()
+}
+
+// FinishSpan ends the span of the operator with its final status. It is
+// called when the operator is buried.
+func (o *Operator) FinishSpan() {
+	o.span.SetAttribute("status", OpStatusToString(o.Status()))
+	o.span.SetAttribute("steps", len(o.steps))
+	o.span.Finish()
+}
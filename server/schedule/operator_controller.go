@@ -18,7 +18,10 @@ import (
 	"container/heap"
 	"context"
 	"fmt"
+	"math"
+	"sort"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/pingcap/failpoint"
@@ -29,6 +32,7 @@ import (
 	"github.com/tikv/pd/pkg/cache"
 	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/pkg/trace"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
 	"github.com/tikv/pd/server/schedule/hbstream"
@@ -37,6 +41,26 @@ import (
 	"go.uber.org/zap"
 )
 
+// The safety checks of adding operators which can be loosened in the break-glass mode.
+const (
+	// BreakGlassFrozenReplica allows changing the replicas of the regions which
+	// are frozen.
+	BreakGlassFrozenReplica = "frozen-replica"
+	// BreakGlassScheduleDisabled allows the schedulers and the checkers to add
+	// operators to the regions whose scheduling is disabled by the labels.
+	BreakGlassScheduleDisabled = "schedule-disabled"
+	// BreakGlassPausedScheduler allows the paused schedulers to keep adding
+	// operators.
+	BreakGlassPausedScheduler = "paused-scheduler"
+)
+
+// breakGlassCluster is the cluster which can loosen the safety checks
+// temporarily and record the actions taken.
+type breakGlassCluster interface {
+	AllowBreakGlass(check string) bool
+	RecordBreakGlassAction(check, target, cause string)
+}
+
 // The source of dispatched region.
 const (
 	DispatchFromHeartBeat     = "heartbeat"
@@ -67,12 +91,25 @@ type OperatorController struct {
 	opRecords       *OperatorRecords
 	wop             WaitingOperator
 	wopStatus       *WaitingOperatorStatus
+	wopPromoted     chan struct{} // notified when the waiting operators are promoted
 	opNotifierQueue operatorQueue
+	// sizeLimits limits the total size of the Regions added to or removed
+	// from each store, in addition to the operator count limit.
+	sizeLimits map[uint64]map[storelimit.Type]*storelimit.SizeLimit
+	// fairShares caches the fair share levels of the group and cluster store
+	// limits for each type, see getStoreLimitRate.
+	fairShares map[storelimit.Type]*fairShareLevels
+	// emergencyLimits limits the operators exempted from the store limits,
+	// separately for each exemption class.
+	emergencyLimits map[emergencyLimitKey]*storelimit.StoreLimit
+	// draining is true if no new operator is accepted, see StartDraining.
+	draining bool
 }
 
 // NewOperatorController creates a OperatorController.
 func NewOperatorController(ctx context.Context, cluster Cluster, hbStreams *hbstream.HeartbeatStreams) *OperatorController {
-	return &OperatorController{
+	wop := NewRandBuckets()
+	oc := &OperatorController{
 		ctx:             ctx,
 		cluster:         cluster,
 		operators:       make(map[uint64]*operator.Operator),
@@ -80,10 +117,35 @@ func NewOperatorController(ctx context.Context, cluster Cluster, hbStreams *hbst
 		fastOperators:   cache.NewIDTTL(ctx, time.Minute, FastOperatorFinishTime),
 		counts:          make(map[operator.OpKind]uint64),
 		opRecords:       NewOperatorRecords(ctx),
-		wop:             NewRandBuckets(),
+		wop:             wop,
 		wopStatus:       NewWaitingOperatorStatus(),
+		wopPromoted:     make(chan struct{}, 1),
 		opNotifierQueue: make(operatorQueue, 0),
+		sizeLimits:      make(map[uint64]map[storelimit.Type]*storelimit.SizeLimit),
+		fairShares:      make(map[storelimit.Type]*fairShareLevels),
+		emergencyLimits: make(map[emergencyLimitKey]*storelimit.StoreLimit),
 	}
+	wop.setKeyFunc(oc.waitingOperatorKey)
+	return oc
+}
+
+// waitingOperatorKey returns the start key prefix of the operator's region,
+// which is used to promote the waiting operators of different key ranges in
+// turn.
+func (oc *OperatorController) waitingOperatorKey(op *operator.Operator) string {
+	length := oc.cluster.GetOpts().GetWaitingOperatorFairKeyPrefixLength()
+	if length == 0 {
+		return ""
+	}
+	region := oc.cluster.GetRegion(op.RegionID())
+	if region == nil {
+		return ""
+	}
+	key := region.GetStartKey()
+	if uint64(len(key)) > length {
+		key = key[:length]
+	}
+	return string(key)
 }
 
 // Ctx returns a context which will be canceled once RaftCluster is stopped.
@@ -117,7 +179,7 @@ func (oc *OperatorController) Dispatch(region *core.RegionInfo, source string) {
 			if source == DispatchFromHeartBeat && oc.checkStaleOperator(op, step, region) {
 				return
 			}
-			oc.SendScheduleCommand(region, step, source)
+			oc.dispatchStep(op, region, step, source)
 		case operator.SUCCESS:
 			if oc.RemoveOperator(op) {
 				operatorWaitCounter.WithLabelValues(op.Desc(), "promote-success").Inc()
@@ -367,6 +429,36 @@ func (oc *OperatorController) PromoteWaitingOperator() {
 			break
 		}
 	}
+	select {
+	case oc.wopPromoted <- struct{}{}:
+	default:
+	}
+}
+
+// WaitingOperatorsPromoted returns a channel notified when the waiting operators
+// are promoted. The notifications are coalesced.
+func (oc *OperatorController) WaitingOperatorsPromoted() <-chan struct{} {
+	return oc.wopPromoted
+}
+
+// allowBreakGlass returns true if the check is loosened by the break-glass mode
+// of the cluster.
+func (oc *OperatorController) allowBreakGlass(check string) bool {
+	cl, ok := oc.cluster.(breakGlassCluster)
+	return ok && cl.AllowBreakGlass(check)
+}
+
+// recordBypassedChecksLocked records the safety checks bypassed by the operator
+// once it is added.
+func (oc *OperatorController) recordBypassedChecksLocked(op *operator.Operator) {
+	cl, ok := oc.cluster.(breakGlassCluster)
+	if !ok {
+		return
+	}
+	target := fmt.Sprintf("operator %s of region %d", op.Desc(), op.RegionID())
+	for check, cause := range op.GetBypassedChecks() {
+		cl.RecordBreakGlassAction(check, target, cause)
+	}
 }
 
 // checkAddOperator checks if the operator can be added.
@@ -376,7 +468,19 @@ func (oc *OperatorController) PromoteWaitingOperator() {
 // - The region already has a higher priority or same priority operator.
 // - Exceed the max number of waiting operators
 // - At least one operator is expired.
+// - The controller is draining.
+// - The replicas of the region are frozen while the operator changes them.
+// - The operator is created by a paused scheduler.
+// The checks of the frozen replicas, the disabled scheduling and the paused
+// schedulers can be loosened by the break-glass mode of the cluster. The
+// bypassed checks are marked on the operator and recorded once it is added.
 func (oc *OperatorController) checkAddOperator(isPromoting bool, ops ...*operator.Operator) bool {
+	if oc.draining {
+		for _, op := range ops {
+			operatorWaitCounter.WithLabelValues(op.Desc(), "draining").Inc()
+		}
+		return false
+	}
 	for _, op := range ops {
 		region := oc.cluster.GetRegion(op.RegionID())
 		if region == nil {
@@ -417,16 +521,43 @@ func (oc *OperatorController) checkAddOperator(isPromoting bool, ops ...*operato
 			operatorWaitCounter.WithLabelValues(op.Desc(), "exceed-max").Inc()
 			return false
 		}
+		// The mode may have expired since the paused scheduler created the operator.
+		if _, ok := op.GetBypassedChecks()[BreakGlassPausedScheduler]; ok && !oc.allowBreakGlass(BreakGlassPausedScheduler) {
+			log.Debug("scheduler paused", zap.Uint64("region-id", op.RegionID()))
+			operatorWaitCounter.WithLabelValues(op.Desc(), "scheduler-paused").Inc()
+			return false
+		}
 
-		if op.SchedulerKind() == operator.OpAdmin || op.IsLeaveJointStateOperator() {
+		// The labels may have changed since the operator was checked last time.
+		op.SetBypassedCheck(BreakGlassFrozenReplica, "")
+		op.SetBypassedCheck(BreakGlassScheduleDisabled, "")
+		if op.IsLeaveJointStateOperator() {
+			continue
+		}
+		if cl, ok := oc.cluster.(interface{ GetRegionLabeler() *labeler.RegionLabeler }); ok && op.ChangesMembership() {
+			// The frozen replicas are not allowed to be changed even by the admin
+			// until they are thawed.
+			if cl.GetRegionLabeler().ReplicaFrozen(region) {
+				if !oc.allowBreakGlass(BreakGlassFrozenReplica) {
+					log.Debug("replica frozen", zap.Uint64("region-id", op.RegionID()))
+					operatorWaitCounter.WithLabelValues(op.Desc(), "replica-frozen").Inc()
+					return false
+				}
+				op.SetBypassedCheck(BreakGlassFrozenReplica, "replica frozen")
+			}
+		}
+		if op.SchedulerKind() == operator.OpAdmin {
 			continue
 		}
 		if cl, ok := oc.cluster.(interface{ GetRegionLabeler() *labeler.RegionLabeler }); ok {
 			l := cl.GetRegionLabeler()
 			if l.ScheduleDisabled(region) {
-				log.Debug("schedule disabled", zap.Uint64("region-id", op.RegionID()))
-				operatorWaitCounter.WithLabelValues(op.Desc(), "schedule-disabled").Inc()
-				return false
+				if !oc.allowBreakGlass(BreakGlassScheduleDisabled) {
+					log.Debug("schedule disabled", zap.Uint64("region-id", op.RegionID()))
+					operatorWaitCounter.WithLabelValues(op.Desc(), "schedule-disabled").Inc()
+					return false
+				}
+				op.SetBypassedCheck(BreakGlassScheduleDisabled, "schedule disabled")
 			}
 		}
 	}
@@ -446,6 +577,8 @@ func isHigherPriorityOperator(new, old *operator.Operator) bool {
 
 func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 	regionID := op.RegionID()
+	span, _ := trace.StartChildSpan(trace.ContextWithSpanContext(context.Background(), op.SpanContext()), "add-operator")
+	defer span.Finish()
 
 	log.Info("add operator",
 		zap.Uint64("region-id", regionID),
@@ -472,7 +605,11 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 		return false
 	}
 	oc.operators[regionID] = op
+	op.PinSpan()
 	operatorCounter.WithLabelValues(op.Desc(), "start").Inc()
+	if component := op.GetComponentLabel(); component != "" {
+		operatorComponentCounter.WithLabelValues(component, "start").Inc()
+	}
 	operatorSizeHist.WithLabelValues(op.Desc()).Observe(float64(op.ApproximateSize))
 	operatorWaitDuration.WithLabelValues(op.Desc()).Observe(op.ElapsedTime().Seconds())
 	opInfluence := NewTotalOpInfluence([]*operator.Operator{op}, oc.cluster)
@@ -483,16 +620,26 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 			return false
 		}
 		for n, v := range storelimit.TypeNameValue {
-			storeLimit := store.GetStoreLimit(v)
-			if storeLimit == nil {
-				continue
-			}
 			stepCost := opInfluence.GetStoreInfluence(storeID).GetStepCost(v)
 			if stepCost == 0 {
 				continue
 			}
+			if op.GetLimitExemption() != operator.NoLimitExemption {
+				oc.takeEmergencyLimitLocked(op, store, n, v, stepCost)
+				continue
+			}
+			storeLimit := store.GetStoreLimit(v)
+			if storeLimit == nil {
+				continue
+			}
 			storeLimit.Take(stepCost)
 			storeLimitCostCounter.WithLabelValues(strconv.FormatUint(storeID, 10), n).Add(float64(stepCost) / float64(storelimit.RegionInfluence[v]))
+			if stepSize := opInfluence.GetStoreInfluence(storeID).GetStepSize(v); stepSize > 0 {
+				if sizeLimit := oc.getOrCreateSizeLimit(store, v); sizeLimit != nil {
+					sizeLimit.Take(stepSize)
+					storeLimitSizeCounter.WithLabelValues(strconv.FormatUint(storeID, 10), n).Add(float64(stepSize))
+				}
+			}
 		}
 	}
 	oc.updateCounts(oc.operators)
@@ -500,7 +647,7 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 	var step operator.OpStep
 	if region := oc.cluster.GetRegion(op.RegionID()); region != nil {
 		if step = op.Check(region); step != nil {
-			oc.SendScheduleCommand(region, step, DispatchFromCreate)
+			oc.dispatchStep(op, region, step, DispatchFromCreate)
 		}
 	}
 
@@ -509,9 +656,38 @@ func (oc *OperatorController) addOperatorLocked(op *operator.Operator) bool {
 	for _, counter := range op.Counters {
 		counter.Inc()
 	}
+	oc.recordBypassedChecksLocked(op)
 	return true
 }
 
+// StartDraining stops accepting new operators, so that the running operators can be
+// finished before the controller is stopped.
+func (oc *OperatorController) StartDraining() {
+	oc.Lock()
+	defer oc.Unlock()
+	oc.draining = true
+}
+
+// IsDraining returns if the controller has stopped accepting new operators.
+func (oc *OperatorController) IsDraining() bool {
+	oc.RLock()
+	defer oc.RUnlock()
+	return oc.draining
+}
+
+// CancelOperators cancels all the running operators, the reason is appended to their
+// reason chains. It returns the number of the canceled operators.
+func (oc *OperatorController) CancelOperators(reason operator.Reason) int {
+	canceled := 0
+	for _, op := range oc.GetOperators() {
+		op.AddReasons(reason)
+		if oc.RemoveOperator(op, zap.String("reason", reason.String())) {
+			canceled++
+		}
+	}
+	return canceled
+}
+
 // RemoveOperator removes a operator from the running operators.
 func (oc *OperatorController) RemoveOperator(op *operator.Operator, extraFields ...zap.Field) bool {
 	oc.Lock()
@@ -606,7 +782,11 @@ func (oc *OperatorController) buryOperator(op *operator.Operator, extraFields ..
 		)
 		operatorCounter.WithLabelValues(op.Desc(), "cancel").Inc()
 	}
+	if component := op.GetComponentLabel(); component != "" {
+		operatorComponentCounter.WithLabelValues(component, strings.ToLower(operator.OpStatusToString(st))).Inc()
+	}
 
+	op.FinishSpan()
 	oc.opRecords.Put(op)
 }
 
@@ -719,6 +899,16 @@ func (oc *OperatorController) SendScheduleCommand(region *core.RegionInfo, step
 	oc.hbStreams.SendMsg(region, cmd)
 }
 
+// dispatchStep sends the schedule command of the step within a span of the
+// operator's trace.
+func (oc *OperatorController) dispatchStep(op *operator.Operator, region *core.RegionInfo, step operator.OpStep, source string) {
+	span, _ := trace.StartChildSpan(trace.ContextWithSpanContext(context.Background(), op.SpanContext()), "dispatch")
+	span.SetAttribute("step", step)
+	span.SetAttribute("source", source)
+	defer span.Finish()
+	oc.SendScheduleCommand(region, step, source)
+}
+
 func addNode(id, storeID uint64) *pdpb.RegionHeartbeatResponse {
 	return &pdpb.RegionHeartbeatResponse{
 		ChangePeer: &pdpb.ChangePeer{
@@ -915,14 +1105,24 @@ func (oc *OperatorController) ExceedStoreLimit(ops ...*operator.Operator) bool {
 }
 
 // exceedStoreLimitLocked returns true if the store exceeds the cost limit after adding the operator. Otherwise, returns false.
+// The operators exempted from the store limits are checked against the emergency
+// limit of their exemption class instead.
 func (oc *OperatorController) exceedStoreLimitLocked(ops ...*operator.Operator) bool {
 	opInfluence := NewTotalOpInfluence(ops, oc.cluster)
+	exemption := operator.CommonLimitExemption(ops...)
 	for storeID := range opInfluence.StoresInfluence {
-		for _, v := range storelimit.TypeNameValue {
+		for n, v := range storelimit.TypeNameValue {
 			stepCost := opInfluence.GetStoreInfluence(storeID).GetStepCost(v)
 			if stepCost == 0 {
 				continue
 			}
+			if exemption != operator.NoLimitExemption {
+				if limit := oc.getOrCreateEmergencyLimit(exemption, storeID, v); limit != nil && !limit.Available(stepCost) {
+					storeLimitExemptionCounter.WithLabelValues(string(exemption), strconv.FormatUint(storeID, 10), n, "exceed").Inc()
+					return true
+				}
+				continue
+			}
 			limiter := oc.getOrCreateStoreLimit(storeID, v)
 			if limiter == nil {
 				return false
@@ -930,19 +1130,70 @@ func (oc *OperatorController) exceedStoreLimitLocked(ops ...*operator.Operator)
 			if !limiter.Available(stepCost) {
 				return true
 			}
+			if stepSize := opInfluence.GetStoreInfluence(storeID).GetStepSize(v); stepSize > 0 {
+				if sizeLimit := oc.getOrCreateSizeLimit(oc.cluster.GetStore(storeID), v); sizeLimit != nil && !sizeLimit.Available(stepSize) {
+					return true
+				}
+			}
 		}
 	}
 	return false
 }
 
+// emergencyLimitKey identifies the emergency limit of a store for an exemption class.
+type emergencyLimitKey struct {
+	exemption operator.LimitExemption
+	storeID   uint64
+	limitType storelimit.Type
+}
+
+// getOrCreateEmergencyLimit returns the emergency limit of a store for the
+// exemption class, or nil if it is unlimited. The limit is recreated once the
+// rate changes.
+func (oc *OperatorController) getOrCreateEmergencyLimit(exemption operator.LimitExemption, storeID uint64, limitType storelimit.Type) *storelimit.StoreLimit {
+	rate := oc.cluster.GetOpts().GetEmergencyStoreLimit()
+	if rate <= 0 {
+		return nil
+	}
+	ratePerSec := rate / StoreBalanceBaseTime
+	key := emergencyLimitKey{exemption: exemption, storeID: storeID, limitType: limitType}
+	if limit, ok := oc.emergencyLimits[key]; ok && limit.Rate() == ratePerSec {
+		return limit
+	}
+	limit := storelimit.NewStoreLimit(ratePerSec, storelimit.RegionInfluence[limitType])
+	oc.emergencyLimits[key] = limit
+	return limit
+}
+
+// takeEmergencyLimitLocked takes the cost of an exempted operator from the
+// emergency limit instead of the store limit, and audits it if the store limit
+// is bypassed.
+func (oc *OperatorController) takeEmergencyLimitLocked(op *operator.Operator, store *core.StoreInfo, typeName string, limitType storelimit.Type, stepCost int64) {
+	exemption := op.GetLimitExemption()
+	storeID := strconv.FormatUint(store.GetID(), 10)
+	if limit := oc.getOrCreateEmergencyLimit(exemption, store.GetID(), limitType); limit != nil {
+		limit.Take(stepCost)
+	}
+	storeLimitExemptionCounter.WithLabelValues(string(exemption), storeID, typeName, "exempt").Inc()
+	if storeLimit := store.GetStoreLimit(limitType); storeLimit != nil && !storeLimit.Available(stepCost) {
+		storeLimitExemptionCounter.WithLabelValues(string(exemption), storeID, typeName, "bypass").Inc()
+		log.Info("operator bypasses the store limit",
+			zap.Uint64("region-id", op.RegionID()),
+			zap.String("exemption", string(exemption)),
+			zap.Uint64("store-id", store.GetID()),
+			zap.String("limit-type", typeName),
+			zap.Stringer("operator", op))
+	}
+}
+
 // getOrCreateStoreLimit is used to get or create the limit of a store.
 func (oc *OperatorController) getOrCreateStoreLimit(storeID uint64, limitType storelimit.Type) *storelimit.StoreLimit {
-	ratePerSec := oc.cluster.GetOpts().GetStoreLimitByType(storeID, limitType) / StoreBalanceBaseTime
 	s := oc.cluster.GetStore(storeID)
 	if s == nil {
 		log.Error("invalid store ID", zap.Uint64("store-id", storeID))
 		return nil
 	}
+	ratePerSec := oc.getStoreLimitRate(s, limitType) / StoreBalanceBaseTime
 	if s.GetStoreLimit(limitType) == nil {
 		oc.cluster.GetBasicCluster().ResetStoreLimit(storeID, limitType, ratePerSec)
 	}
@@ -951,3 +1202,156 @@ func (oc *OperatorController) getOrCreateStoreLimit(storeID uint64, limitType st
 	}
 	return s.GetStoreLimit(limitType)
 }
+
+// getOrCreateSizeLimit is used to get or create the size limit of a store. The
+// rate of the existing limit is changed in place, so the tokens are kept.
+func (oc *OperatorController) getOrCreateSizeLimit(store *core.StoreInfo, limitType storelimit.Type) *storelimit.SizeLimit {
+	if store == nil {
+		return nil
+	}
+	ratePerSec := oc.getStoreLimitSizeRate(store, limitType)
+	limits, ok := oc.sizeLimits[store.GetID()]
+	if !ok {
+		limits = make(map[storelimit.Type]*storelimit.SizeLimit)
+		oc.sizeLimits[store.GetID()] = limits
+	}
+	if limit, ok := limits[limitType]; ok {
+		if limit.Rate() != ratePerSec {
+			limit.SetRate(ratePerSec)
+		}
+		return limit
+	}
+	limit := storelimit.NewSizeLimit(ratePerSec)
+	limits[limitType] = limit
+	return limit
+}
+
+// getStoreLimitSizeRate returns the size limit of a store in MB per second. If the
+// size rate is not configured, it is translated from the operator count limit, so the
+// stores without the configuration keep the same rate for the ordinary Regions.
+func (oc *OperatorController) getStoreLimitSizeRate(store *core.StoreInfo, limitType storelimit.Type) float64 {
+	countRate := oc.getStoreLimitRate(store, limitType)
+	if countRate >= storelimit.Unlimited {
+		return storelimit.Unlimited
+	}
+	if rate := oc.cluster.GetOpts().GetStoreLimitSizeRate(); rate > 0 {
+		return rate
+	}
+	return countRate / StoreBalanceBaseTime * storelimit.TranslateRegionSize
+}
+
+// getStoreLimitRate returns the limit of a store with a given type. If the group
+// or cluster store limit is set, the limit is also bounded by the fair share of the
+// store, so that the total of the stores in the group or cluster never exceeds it.
+// The share which is not used by the stores with lower limits is redistributed to
+// the others. The add peer limit is scaled if it is tightened for the store.
+func (oc *OperatorController) getStoreLimitRate(store *core.StoreInfo, limitType storelimit.Type) float64 {
+	opts := oc.cluster.GetOpts()
+	rate := oc.getOwnStoreLimitRate(store, limitType)
+	clusterLimit := opts.GetClusterStoreLimitByType(limitType)
+	groupLabel := opts.GetStoreLimitGroupLabel()
+	var groupLimit float64
+	if groupLabel != "" {
+		groupLimit = opts.GetGroupStoreLimitByType(limitType)
+	}
+	if clusterLimit <= 0 && groupLimit <= 0 {
+		return rate
+	}
+
+	levels, ok := oc.fairShares[limitType]
+	if !ok || !levels.valid(groupLabel, groupLimit, clusterLimit, time.Now()) {
+		levels = oc.calcFairShareLevels(limitType, groupLabel, groupLimit, clusterLimit)
+		oc.fairShares[limitType] = levels
+	}
+	if level, ok := levels.groups[store.GetLabelValue(groupLabel)]; ok {
+		rate = math.Min(rate, level)
+	}
+	return math.Min(rate, levels.cluster)
+}
+
+// fairShareRefreshInterval is the interval to recompute the fair share levels
+// even if the limits are not changed, so that the changes of the stores are
+// taken into account.
+const fairShareRefreshInterval = 10 * time.Second
+
+// fairShareLevels is the fair share levels of a type of store limit, which take
+// all the stores to calculate, so they are cached instead of being calculated
+// for every check of the limit.
+type fairShareLevels struct {
+	groupLabel   string
+	groupLimit   float64
+	clusterLimit float64
+	calculatedAt time.Time
+
+	groups  map[string]float64
+	cluster float64
+}
+
+func (l *fairShareLevels) valid(groupLabel string, groupLimit, clusterLimit float64, now time.Time) bool {
+	return l.groupLabel == groupLabel && l.groupLimit == groupLimit && l.clusterLimit == clusterLimit &&
+		now.Sub(l.calculatedAt) < fairShareRefreshInterval
+}
+
+// calcFairShareLevels calculates the level of each group and the cluster, which
+// the limits of the stores in them are capped to.
+func (oc *OperatorController) calcFairShareLevels(limitType storelimit.Type, groupLabel string, groupLimit, clusterLimit float64) *fairShareLevels {
+	groups := make(map[string][]float64)
+	for _, s := range oc.cluster.GetStores() {
+		if s.IsRemoved() {
+			continue
+		}
+		group := s.GetLabelValue(groupLabel)
+		groups[group] = append(groups[group], oc.getOwnStoreLimitRate(s, limitType))
+	}
+	groupLevels := make(map[string]float64, len(groups))
+	rates := make([]float64, 0, len(groups))
+	for group, groupRates := range groups {
+		level := math.Inf(1)
+		if groupLimit > 0 {
+			level = fairShareLevel(groupRates, groupLimit)
+		}
+		groupLevels[group] = level
+		for _, r := range groupRates {
+			rates = append(rates, math.Min(r, level))
+		}
+	}
+	clusterLevel := math.Inf(1)
+	if clusterLimit > 0 && len(rates) > 0 {
+		clusterLevel = fairShareLevel(rates, clusterLimit)
+	}
+	return &fairShareLevels{
+		groupLabel:   groupLabel,
+		groupLimit:   groupLimit,
+		clusterLimit: clusterLimit,
+		calculatedAt: time.Now(),
+		groups:       groupLevels,
+		cluster:      clusterLevel,
+	}
+}
+
+// getOwnStoreLimitRate returns the limit of a store without the group and cluster caps.
+func (oc *OperatorController) getOwnStoreLimitRate(store *core.StoreInfo, limitType storelimit.Type) float64 {
+	rate := oc.cluster.GetOpts().GetStoreLimitByType(store.GetID(), limitType)
+	if limitType == storelimit.AddPeer && rate < storelimit.Unlimited {
+		rate *= store.GetAddPeerLimitRatio()
+	}
+	return rate
+}
+
+// fairShareLevel returns the max-min fair share of the total for the rates, which
+// is the level that each rate is capped to. The stores whose rates are lower than
+// an equal share keep their rates, and the rest of the total is split equally among
+// the others.
+func fairShareLevel(rates []float64, total float64) float64 {
+	sorted := append([]float64(nil), rates...)
+	sort.Float64s(sorted)
+	for i, r := range sorted {
+		share := total / float64(len(sorted)-i)
+		if r > share {
+			return share
+		}
+		total -= r
+	}
+	// The total is enough for all the rates.
+	return math.Inf(1)
+}
@@ -18,6 +18,7 @@ import (
 	"container/heap"
 	"context"
 	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"sync"
 	"testing"
@@ -28,6 +29,7 @@ import (
 	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/stretchr/testify/suite"
 	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/pkg/trace"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/core/storelimit"
@@ -134,6 +136,44 @@ func (suite *operatorControllerTestSuite) TestOperatorStatus() {
 	suite.Equal(pdpb.OperatorStatus_SUCCESS, oc.GetOperatorStatus(2).Status)
 }
 
+func (suite *operatorControllerTestSuite) TestOperatorTrace() {
+	recorder := trace.NewMemoryRecorder(trace.DefaultMaxTraces)
+	defer trace.SetRecorder(trace.GetRecorder())
+	trace.SetRecorder(recorder)
+	defer trace.SetSampler(trace.GetSampler())
+	trace.SetSampler(trace.AlwaysSample)
+
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(suite.ctx, opt)
+	stream := hbstream.NewTestHeartbeatStreams(suite.ctx, tc.ID, tc, false /* no need to run */)
+	oc := NewOperatorController(suite.ctx, tc, stream)
+	tc.AddLeaderStore(1, 2)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1)
+	op := operator.NewTestOperator(1, tc.GetRegion(1).GetRegionEpoch(), operator.OpRegion, operator.AddPeer{ToStore: 2, PeerID: 4})
+	suite.True(op.SpanContext().IsValid())
+	suite.Contains(op.String(), "trace-id:"+op.SpanContext().TraceID.String())
+	suite.True(oc.AddOperator(op))
+	oc.Dispatch(tc.GetRegion(1), DispatchFromHeartBeat)
+	// the operator span is recorded after the operator is finished.
+	ApplyOperator(tc, op)
+	oc.Dispatch(tc.GetRegion(1), DispatchFromHeartBeat)
+	suite.Equal(operator.SUCCESS, op.Status())
+
+	names := make(map[string]int)
+	var root *trace.SpanData
+	for _, span := range recorder.GetTrace(op.SpanContext().TraceID) {
+		names[span.Name]++
+		if span.Name == "operator" {
+			root = span
+		}
+	}
+	suite.Equal(map[string]int{"add-operator": 1, "dispatch": 2, "operator": 1}, names)
+	suite.NotNil(root)
+	suite.Empty(root.ParentID)
+	suite.Contains(root.Attributes, trace.Attribute{Key: "status", Value: "SUCCESS"})
+}
+
 func (suite *operatorControllerTestSuite) TestFastFailOperator() {
 	opt := config.NewTestOptions()
 	tc := mockcluster.NewCluster(suite.ctx, opt)
@@ -434,6 +474,163 @@ func (suite *operatorControllerTestSuite) TestStoreLimit() {
 	suite.False(oc.RemoveOperator(op))
 }
 
+func (suite *operatorControllerTestSuite) TestHierarchicalStoreLimit() {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(suite.ctx, opt)
+	stream := hbstream.NewTestHeartbeatStreams(suite.ctx, tc.ID, tc, false /* no need to run */)
+	oc := NewOperatorController(suite.ctx, tc, stream)
+	tc.AddLabelsStore(1, 0, map[string]string{"zone": "z1"})
+	tc.AddLabelsStore(2, 0, map[string]string{"zone": "z1"})
+	tc.AddLabelsStore(3, 0, map[string]string{"zone": "z2"})
+	tc.AddLabelsStore(4, 0, map[string]string{"zone": "z2"})
+	tc.SetAllStoresLimit(storelimit.AddPeer, 600)
+	for i := uint64(1); i <= 10; i++ {
+		tc.AddLeaderRegion(i, 1)
+		// make it small region
+		tc.PutRegion(tc.GetRegion(i).Clone(core.SetApproximateSize(10)))
+	}
+	suite.Equal(600.0, oc.getStoreLimitRate(tc.GetStore(2), storelimit.AddPeer))
+	// the add peer limit is tightened, e.g. the store is nearly full.
+	tc.PutStore(tc.GetStore(2).Clone(core.SetAddPeerLimitRatio(0.5)))
+	suite.Equal(300.0, oc.getStoreLimitRate(tc.GetStore(2), storelimit.AddPeer))
+	suite.Equal(opt.GetStoreLimitByType(2, storelimit.RemovePeer), oc.getStoreLimitRate(tc.GetStore(2), storelimit.RemovePeer))
+	tc.PutStore(tc.GetStore(2).Clone(core.SetAddPeerLimitRatio(0)))
+
+	// the cluster limit is shared by 4 stores.
+	scheduleCfg := opt.GetScheduleConfig().Clone()
+	scheduleCfg.ClusterStoreLimit = config.StoreLimitConfig{AddPeer: 480}
+	opt.SetScheduleConfig(scheduleCfg)
+	suite.Equal(120.0, oc.getStoreLimitRate(tc.GetStore(2), storelimit.AddPeer))
+	// the group limit is shared by the stores in the same zone.
+	scheduleCfg = opt.GetScheduleConfig().Clone()
+	scheduleCfg.StoreLimitGroupLabel = "zone"
+	scheduleCfg.GroupStoreLimit = config.StoreLimitConfig{AddPeer: 120}
+	opt.SetScheduleConfig(scheduleCfg)
+	suite.Equal(60.0, oc.getStoreLimitRate(tc.GetStore(2), storelimit.AddPeer))
+	// the remove peer limit is not affected.
+	suite.Equal(opt.GetStoreLimitByType(2, storelimit.RemovePeer), oc.getStoreLimitRate(tc.GetStore(2), storelimit.RemovePeer))
+
+	// 60 per minute, so only 5 small regions can be added.
+	for i := uint64(1); i <= 5; i++ {
+		op := operator.NewTestOperator(i, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 2, PeerID: i})
+		suite.True(oc.AddOperator(op))
+		suite.checkRemoveOperatorSuccess(oc, op)
+	}
+	op := operator.NewTestOperator(6, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 2, PeerID: 6})
+	suite.True(oc.ExceedStoreLimit(op))
+	suite.False(oc.AddOperator(op))
+	// the store in another zone is not affected.
+	op = operator.NewTestOperator(6, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 3, PeerID: 6})
+	suite.False(oc.ExceedStoreLimit(op))
+
+	// the share unused by the tightened store is redistributed to the other store in the zone.
+	tc.PutStore(tc.GetStore(1).Clone(core.SetAddPeerLimitRatio(0.0625)))
+	// the cached levels are kept until they are refreshed.
+	suite.Equal(60.0, oc.getStoreLimitRate(tc.GetStore(2), storelimit.AddPeer))
+	oc.fairShares[storelimit.AddPeer].calculatedAt = time.Now().Add(-fairShareRefreshInterval)
+	suite.Equal(37.5, oc.getStoreLimitRate(tc.GetStore(1), storelimit.AddPeer))
+	suite.Equal(82.5, oc.getStoreLimitRate(tc.GetStore(2), storelimit.AddPeer))
+	// and the share of the cluster limit unused by it is split by the others.
+	scheduleCfg = opt.GetScheduleConfig().Clone()
+	scheduleCfg.ClusterStoreLimit = config.StoreLimitConfig{AddPeer: 200}
+	opt.SetScheduleConfig(scheduleCfg)
+	suite.Equal(37.5, oc.getStoreLimitRate(tc.GetStore(1), storelimit.AddPeer))
+	suite.InDelta(162.5/3, oc.getStoreLimitRate(tc.GetStore(2), storelimit.AddPeer), 1e-9)
+	suite.InDelta(162.5/3, oc.getStoreLimitRate(tc.GetStore(3), storelimit.AddPeer), 1e-9)
+}
+
+func (suite *operatorControllerTestSuite) TestStoreLimitSize() {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(suite.ctx, opt)
+	stream := hbstream.NewTestHeartbeatStreams(suite.ctx, tc.ID, tc, false /* no need to run */)
+	oc := NewOperatorController(suite.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderRegion(1, 1)
+	tc.PutRegion(tc.GetRegion(1).Clone(core.SetApproximateSize(1000)))
+	tc.AddLeaderRegion(2, 1)
+	tc.PutRegion(tc.GetRegion(2).Clone(core.SetApproximateSize(10)))
+	tc.SetStoreLimit(2, storelimit.AddPeer, 600)
+
+	// the size rate is translated from the count limit.
+	suite.Equal(600/StoreBalanceBaseTime*storelimit.TranslateRegionSize, oc.getStoreLimitSizeRate(tc.GetStore(2), storelimit.AddPeer))
+	tc.SetStoreLimitSizeRate(100)
+	suite.Equal(100.0, oc.getStoreLimitSizeRate(tc.GetStore(2), storelimit.AddPeer))
+
+	// the large region can be added when the bucket is full, and takes more than the burst.
+	op := operator.NewTestOperator(1, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 2, PeerID: 1})
+	suite.False(oc.ExceedStoreLimit(op))
+	suite.True(oc.AddOperator(op))
+	suite.checkRemoveOperatorSuccess(oc, op)
+	// the count limit allows more operators, but the size limit is in debt.
+	op = operator.NewTestOperator(2, &metapb.RegionEpoch{}, operator.OpRegion, operator.AddPeer{ToStore: 2, PeerID: 2})
+	suite.True(oc.ExceedStoreLimit(op))
+	suite.False(oc.AddOperator(op))
+	// the debt is kept when the rate changes.
+	tc.SetStoreLimitSizeRate(200)
+	suite.True(oc.ExceedStoreLimit(op))
+	suite.Equal(200.0, oc.sizeLimits[2][storelimit.AddPeer].Rate())
+	// unlimited store limit also disables the size limit.
+	tc.SetStoreLimit(2, storelimit.AddPeer, storelimit.Unlimited)
+	suite.Equal(storelimit.Unlimited, oc.getStoreLimitSizeRate(tc.GetStore(2), storelimit.AddPeer))
+}
+
+func (suite *operatorControllerTestSuite) TestStoreLimitExemption() {
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(suite.ctx, opt)
+	stream := hbstream.NewTestHeartbeatStreams(suite.ctx, tc.ID, tc, false /* no need to run */)
+	oc := NewOperatorController(suite.ctx, tc, stream)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	for i := uint64(1); i <= 20; i++ {
+		tc.AddLeaderRegion(i, 1)
+		// make it small region
+		tc.PutRegion(tc.GetRegion(i).Clone(core.SetApproximateSize(10)))
+	}
+	newOperator := func(regionID uint64, exemption operator.LimitExemption) *operator.Operator {
+		op := operator.NewTestOperator(regionID, &metapb.RegionEpoch{}, operator.OpRegion|operator.OpReplica, operator.AddPeer{ToStore: 2, PeerID: regionID})
+		op.SetLimitExemption(exemption)
+		return op
+	}
+
+	// The store limit is used up by the ordinary operators.
+	tc.SetStoreLimit(2, storelimit.AddPeer, 60)
+	for i := uint64(1); i <= 5; i++ {
+		op := newOperator(i, operator.NoLimitExemption)
+		suite.True(oc.AddOperator(op))
+		suite.checkRemoveOperatorSuccess(oc, op)
+	}
+	suite.True(oc.ExceedStoreLimit(newOperator(6, operator.NoLimitExemption)))
+
+	// The exempted operators bypass the store limit, and are limited by the
+	// emergency limit instead.
+	scheduleCfg := opt.GetScheduleConfig().Clone()
+	scheduleCfg.EmergencyStoreLimit = 120
+	opt.SetScheduleConfig(scheduleCfg)
+	for i := uint64(6); i <= 15; i++ {
+		op := newOperator(i, operator.UnsafeRecoveryExemption)
+		suite.False(oc.ExceedStoreLimit(op))
+		suite.True(oc.AddOperator(op))
+		suite.Contains(op.String(), "exemption:unsafe-recovery")
+		suite.checkRemoveOperatorSuccess(oc, op)
+	}
+	op := newOperator(16, operator.UnsafeRecoveryExemption)
+	suite.True(oc.ExceedStoreLimit(op))
+	suite.False(oc.AddOperator(op))
+	// The ordinary operators are still limited by the store limit.
+	suite.True(oc.ExceedStoreLimit(newOperator(17, operator.NoLimitExemption)))
+	// The operators are exempted only if all of them are.
+	suite.Equal(operator.UnsafeRecoveryExemption, operator.CommonLimitExemption(op, newOperator(17, operator.UnsafeRecoveryExemption)))
+	suite.Equal(operator.NoLimitExemption, operator.CommonLimitExemption(op, newOperator(17, operator.NoLimitExemption)))
+
+	// 0 means the emergency limit is unlimited.
+	scheduleCfg = opt.GetScheduleConfig().Clone()
+	scheduleCfg.EmergencyStoreLimit = 0
+	opt.SetScheduleConfig(scheduleCfg)
+	suite.False(oc.ExceedStoreLimit(op))
+	suite.True(oc.AddOperator(op))
+}
+
 // #1652
 func (suite *operatorControllerTestSuite) TestDispatchOutdatedRegion() {
 	cluster := mockcluster.NewCluster(suite.ctx, config.NewTestOptions())
@@ -703,3 +900,143 @@ func (suite *operatorControllerTestSuite) TestAddWaitingOperator() {
 	// no space left, new operator can not be added.
 	suite.Equal(0, controller.AddWaitingOperator(addPeerOp(0)))
 }
+
+func (suite *operatorControllerTestSuite) TestRestoreWaitingOperators() {
+	opts := config.NewTestOptions()
+	cluster := mockcluster.NewCluster(suite.ctx, opts)
+	stream := hbstream.NewTestHeartbeatStreams(suite.ctx, cluster.ID, cluster, false /* no need to run */)
+	controller := NewOperatorController(suite.ctx, cluster, stream)
+	for storeID := uint64(1); storeID <= 3; storeID++ {
+		cluster.AddLabelsStore(storeID, 1, map[string]string{"host": fmt.Sprintf("host%d", storeID)})
+	}
+	addPeerOp := func(regionID, storeID uint64) *operator.Operator {
+		start := fmt.Sprintf("%da", regionID)
+		end := fmt.Sprintf("%db", regionID)
+		region := newRegionInfo(regionID, start, end, 1, 1, []uint64{regionID * 100, 1}, []uint64{regionID * 100, 1})
+		cluster.PutRegion(region)
+		op, err := operator.CreateAddPeerOperator("add-peer", cluster, region, &metapb.Peer{StoreId: storeID}, operator.OpKind(0))
+		suite.NoError(err)
+		return op
+	}
+	op1 := addPeerOp(1, 2)
+	op1.SetMetadata(&operator.Metadata{Component: "test"})
+	op2 := addPeerOp(2, 3)
+	source := newRegionInfo(3, "3a", "3b", 1, 1, []uint64{301, 1}, []uint64{301, 1})
+	target := newRegionInfo(4, "4a", "4b", 1, 1, []uint64{401, 1}, []uint64{401, 1})
+	cluster.PutRegion(source)
+	cluster.PutRegion(target)
+	mergeOps, err := operator.CreateMergeRegionOperator("merge-region", cluster, source, target, operator.OpMerge)
+	suite.NoError(err)
+	for _, op := range append([]*operator.Operator{op1, op2}, mergeOps...) {
+		controller.wop.PutOperator(op)
+	}
+
+	// The snapshot is bounded by the size.
+	snapshot := controller.SnapshotWaitingOperators(1)
+	suite.Empty(snapshot.Groups)
+	suite.Equal(3, snapshot.Truncated)
+	snapshot = controller.SnapshotWaitingOperators(1 << 20)
+	suite.Len(snapshot.Groups, 3)
+	suite.Zero(snapshot.Truncated)
+	suite.False(snapshot.IsStale(time.Minute))
+	data, err := json.Marshal(snapshot)
+	suite.NoError(err)
+	snapshot = &WaitingOperatorSnapshot{}
+	suite.NoError(json.Unmarshal(data, snapshot))
+
+	// The operator adding the peer to the offline store is discarded on the new leader.
+	cluster.SetStoreOffline(3)
+	newController := NewOperatorController(suite.ctx, cluster, stream)
+	restored, discarded := newController.RestoreWaitingOperators(snapshot)
+	suite.Equal(2, restored)
+	suite.Equal(1, discarded)
+
+	// The operator already running on the previous leader is discarded.
+	region := cluster.GetRegion(1)
+	learner := op1.Step(0).(operator.AddLearner)
+	cluster.PutRegion(region.Clone(core.WithAddPeer(&metapb.Peer{Id: learner.PeerID, StoreId: learner.ToStore, Role: metapb.PeerRole_Learner})))
+	restored, discarded = NewOperatorController(suite.ctx, cluster, stream).RestoreWaitingOperators(snapshot)
+	suite.Equal(1, restored)
+	suite.Equal(2, discarded)
+	op := newController.GetOperator(1)
+	suite.NotNil(op)
+	suite.Equal(op1.Desc(), op.Desc())
+	suite.Equal(op1.Kind(), op.Kind())
+	suite.Equal(op1.Len(), op.Len())
+	for i := 0; i < op.Len(); i++ {
+		suite.Equal(op1.Step(i), op.Step(i))
+	}
+	suite.Equal("test", op.GetComponent())
+	suite.Nil(newController.GetOperator(2))
+	suite.NotNil(newController.GetOperator(3))
+	suite.NotNil(newController.GetOperator(4))
+}
+
+// breakGlassMockCluster loosens the selected safety checks of the mock cluster.
+type breakGlassMockCluster struct {
+	*mockcluster.Cluster
+	checks   map[string]bool
+	bypassed []string
+}
+
+func (c *breakGlassMockCluster) AllowBreakGlass(check string) bool {
+	return c.checks[check]
+}
+
+func (c *breakGlassMockCluster) RecordBreakGlassAction(check, target, _ string) {
+	c.bypassed = append(c.bypassed, check+" "+target)
+}
+
+func (suite *operatorControllerTestSuite) TestBreakGlass() {
+	opts := config.NewTestOptions()
+	cluster := &breakGlassMockCluster{Cluster: mockcluster.NewCluster(suite.ctx, opts), checks: make(map[string]bool)}
+	stream := hbstream.NewTestHeartbeatStreams(suite.ctx, cluster.ID, cluster, false /* no need to run */)
+	controller := NewOperatorController(suite.ctx, cluster, stream)
+	cluster.AddLeaderStore(1, 1)
+	cluster.AddLeaderStore(2, 0)
+	region := newRegionInfo(1, "1a", "1b", 1, 1, []uint64{101, 1}, []uint64{101, 1})
+	cluster.PutRegion(region)
+	cluster.GetRegionLabeler().SetLabelRule(&labeler.LabelRule{
+		ID:       "schedulelabel",
+		Labels:   []labeler.RegionLabel{{Key: "schedule", Value: "deny"}},
+		RuleType: labeler.KeyRange,
+		Data:     []interface{}{map[string]interface{}{"start_key": "1a", "end_key": "1b"}},
+	})
+	newOp := func() *operator.Operator {
+		op, err := operator.CreateAddPeerOperator("add-peer", cluster, region, &metapb.Peer{StoreId: 2}, operator.OpReplica)
+		suite.NoError(err)
+		return op
+	}
+
+	// The other checks do not loosen the disabled scheduling.
+	cluster.checks[BreakGlassFrozenReplica] = true
+	suite.False(controller.AddOperator(newOp()))
+	suite.Empty(cluster.bypassed)
+	cluster.checks[BreakGlassScheduleDisabled] = true
+	// Nothing is recorded if the operator is rejected by the later checks.
+	suite.False(controller.AddOperator(newOp(), operator.NewTestOperator(2, &metapb.RegionEpoch{}, operator.OpRegion)))
+	suite.Empty(cluster.bypassed)
+	op := newOp()
+	suite.True(controller.AddOperator(op))
+	suite.Equal([]string{"schedule-disabled operator add-peer of region 1"}, cluster.bypassed)
+
+	// The waiting operator is recorded once although it is checked again on promotion.
+	suite.True(controller.RemoveOperator(op))
+	suite.Equal(1, controller.AddWaitingOperator(newOp()))
+	suite.NotNil(controller.GetOperator(1))
+	suite.Len(cluster.bypassed, 2)
+
+	// The operators of the paused schedulers are rejected once the check is not loosened.
+	suite.True(controller.RemoveOperator(controller.GetOperator(1)))
+	op = newOp()
+	op.SetBypassedCheck(BreakGlassPausedScheduler, "scheduler paused")
+	suite.False(controller.AddOperator(op))
+	cluster.checks[BreakGlassPausedScheduler] = true
+	op = newOp()
+	op.SetBypassedCheck(BreakGlassPausedScheduler, "scheduler paused")
+	suite.True(controller.AddOperator(op))
+	suite.ElementsMatch([]string{
+		"schedule-disabled operator add-peer of region 1",
+		"paused-scheduler operator add-peer of region 1",
+	}, cluster.bypassed[2:])
+}
@@ -148,6 +148,17 @@ type StoreSet interface {
 	GetStore(id uint64) *core.StoreInfo
 }
 
+// FitRegionWithRules fits a region to the given rules instead of the rules it
+// matches in the rule manager, e.g. the rules converted from the replication
+// config. The result is not cached.
+func FitRegionWithRules(storeSet StoreSet, region *core.RegionInfo, rules []*Rule) *RegionFit {
+	stores := getStoresByRegion(storeSet, region)
+	fit := fitRegion(stores, region, rules)
+	fit.regionStores = stores
+	fit.rules = rules
+	return fit
+}
+
 // fitRegion tries to fit peers of a region to the rules.
 func fitRegion(stores []*core.StoreInfo, region *core.RegionInfo, rules []*Rule) *RegionFit {
 	w := newFitWorker(stores, region, rules)
@@ -50,18 +50,20 @@ type LabelConstraint struct {
 }
 
 // MatchStore checks if a store matches the constraint.
+// Besides the store labels, the key can also refer to the hardware topology
+// reported by the store, such as `hw-disk-type`.
 func (c *LabelConstraint) MatchStore(store *core.StoreInfo) bool {
 	switch c.Op {
 	case In:
-		label := store.GetLabelValue(c.Key)
+		label := store.GetLabelOrTopologyValue(c.Key)
 		return label != "" && slice.AnyOf(c.Values, func(i int) bool { return c.Values[i] == label })
 	case NotIn:
-		label := store.GetLabelValue(c.Key)
+		label := store.GetLabelOrTopologyValue(c.Key)
 		return label == "" || slice.NoneOf(c.Values, func(i int) bool { return c.Values[i] == label })
 	case Exists:
-		return store.GetLabelValue(c.Key) != ""
+		return store.GetLabelOrTopologyValue(c.Key) != ""
 	case NotExists:
-		return store.GetLabelValue(c.Key) == ""
+		return store.GetLabelOrTopologyValue(c.Key) == ""
 	}
 	return false
 }
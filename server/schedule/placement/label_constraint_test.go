@@ -96,3 +96,36 @@ func TestLabelConstraints(t *testing.T) {
 		re.Equal(expect[i], matched)
 	}
 }
+
+func TestLabelConstraintWithTopology(t *testing.T) {
+	re := require.New(t)
+	topologies := []*core.StoreTopology{
+		nil,                              // 1
+		{NUMANodes: 2, DiskType: "nvme"}, // 2
+		{NUMANodes: 4, DiskType: "ssd"},  // 3
+		{DiskCount: 2, DiskType: "nvme"}, // 4
+		{NUMANodes: 2, DiskCount: 1},     // 5
+	}
+	constraints := []LabelConstraint{
+		{Key: core.TopologyDiskTypeKey, Op: "in", Values: []string{"nvme"}},
+		{Key: core.TopologyNUMANodesKey, Op: "notIn", Values: []string{"4"}},
+		{Key: core.TopologyDiskCountKey, Op: "exists"},
+		{Key: core.TopologyDiskTypeKey, Op: "notExists"},
+	}
+	expect := [][]int{
+		{2, 4},
+		{1, 2, 4, 5},
+		{4, 5},
+		{1, 5},
+	}
+	for i, constraint := range constraints {
+		var matched []int
+		for j, topology := range topologies {
+			store := core.NewStoreInfoWithLabel(uint64(j), 0, nil).Clone(core.SetStoreTopology(topology))
+			if constraint.MatchStore(store) {
+				matched = append(matched, j+1)
+			}
+		}
+		re.Equal(expect[i], matched)
+	}
+}
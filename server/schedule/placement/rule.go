@@ -64,6 +64,7 @@ type Rule struct {
 	EndKeyHex        string            `json:"end_key"`                     // hex format end key, for marshal/unmarshal
 	Role             PeerRoleType      `json:"role"`                        // expected role of the peers
 	Count            int               `json:"count"`                       // expected count of the peers
+	IsWitness        bool              `json:"is_witness,omitempty"`        // the peers only keep the raft log and hold no data
 	LabelConstraints []LabelConstraint `json:"label_constraints,omitempty"` // used to select stores to place peers
 	LocationLabels   []string          `json:"location_labels,omitempty"`   // used to make peers isolated physically
 	IsolationLevel   string            `json:"isolation_level,omitempty"`   // used to isolate replicas explicitly and forcibly
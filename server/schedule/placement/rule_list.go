@@ -17,6 +17,7 @@ package placement
 import (
 	"encoding/hex"
 	"fmt"
+	"sort"
 	"strings"
 
 	"github.com/pingcap/errors"
@@ -131,3 +132,27 @@ func (rl ruleList) getRulesForApplyRange(start, end []byte) []*Rule {
 	}
 	return rl.ranges[i].applyRules
 }
+
+// shadowedRules returns the rules which are not applied to any range, i.e.
+// they are fully shadowed by the override rules or rule groups.
+func (rl ruleList) shadowedRules() []*Rule {
+	applied := make(map[[2]string]struct{})
+	for _, r := range rl.ranges {
+		for _, rule := range r.applyRules {
+			applied[rule.Key()] = struct{}{}
+		}
+	}
+	var shadowed []*Rule
+	for _, r := range rl.ranges {
+		for _, rule := range r.rules {
+			if _, ok := applied[rule.Key()]; ok {
+				continue
+			}
+			// mark it as applied to skip it in the other ranges.
+			applied[rule.Key()] = struct{}{}
+			shadowed = append(shadowed, rule)
+		}
+	}
+	sort.Slice(shadowed, func(i, j int) bool { return compareRule(shadowed[i], shadowed[j]) < 0 })
+	return shadowed
+}
@@ -16,6 +16,7 @@ package placement
 
 import (
 	"bytes"
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
@@ -27,6 +28,7 @@ import (
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/codec"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/pkg/retryutil"
 	"github.com/tikv/pd/pkg/slice"
 	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/server/config"
@@ -39,6 +41,9 @@ import (
 // It is thread safe.
 type RuleManager struct {
 	storage endpoint.RuleStorage
+	// patchMu serializes the patches, so the RWMutex can be released while the
+	// patch is being persisted.
+	patchMu syncutil.Mutex
 	syncutil.RWMutex
 	initialized bool
 	ruleConfig  *ruleConfig
@@ -65,18 +70,27 @@ func NewRuleManager(storage endpoint.RuleStorage, storeSetInformer core.StoreSet
 // Initialize loads rules from storage. If Placement Rules feature is never enabled, it creates default rule that is
 // compatible with previous configuration.
 func (m *RuleManager) Initialize(maxReplica int, locationLabels []string) error {
-	m.Lock()
-	defer m.Unlock()
+	_, err := m.TryInitialize(maxReplica, locationLabels)
+	return err
+}
+
+// TryInitialize is the same as Initialize, and it also returns whether the
+// default rule is created from the given configuration, which can be undone by
+// Uninitialize.
+func (m *RuleManager) TryInitialize(maxReplica int, locationLabels []string) (bool, error) {
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	if m.initialized {
-		return nil
+		return false, nil
 	}
 
 	if err := m.loadRules(); err != nil {
-		return err
+		return false, err
 	}
 	if err := m.loadGroups(); err != nil {
-		return err
+		return false, err
 	}
+	created := false
 	if len(m.ruleConfig.rules) == 0 {
 		// migrate from old config.
 		defaultRule := &Rule{
@@ -87,17 +101,38 @@ func (m *RuleManager) Initialize(maxReplica int, locationLabels []string) error
 			LocationLabels: locationLabels,
 		}
 		if err := m.storage.SaveRule(defaultRule.StoreKey(), defaultRule); err != nil {
-			return err
+			return false, err
 		}
 		m.ruleConfig.setRule(defaultRule)
+		created = true
 	}
 	m.ruleConfig.adjust()
 	ruleList, err := buildRuleList(m.ruleConfig)
 	if err != nil {
-		return err
+		return false, err
 	}
 	m.ruleList = ruleList
 	m.initialized = true
+	return created, nil
+}
+
+// Uninitialize deletes the default rule created by TryInitialize and discards
+// the loaded rules, so the rules are loaded or created from the configuration
+// again by the next initialization. It is only used to undo the initialization
+// before any rule is changed.
+func (m *RuleManager) Uninitialize() error {
+	m.lockForPatch()
+	defer m.unlockForPatch()
+	if !m.initialized {
+		return nil
+	}
+	defaultRule := &Rule{GroupID: "pd", ID: "default"}
+	if err := m.storage.DeleteRule(defaultRule.StoreKey()); err != nil {
+		return err
+	}
+	m.ruleConfig = newRuleConfig()
+	m.ruleList = ruleList{}
+	m.initialized = false
 	return nil
 }
 
@@ -204,6 +239,9 @@ func (m *RuleManager) adjustRule(r *Rule, groupID string) (err error) {
 	if r.Role == Leader && r.Count > 1 {
 		return errs.ErrRuleContent.FastGenByArgs(fmt.Sprintf("define multiple leaders by count %d", r.Count))
 	}
+	if r.IsWitness && r.Role != Voter && r.Role != Follower {
+		return errs.ErrRuleContent.FastGenByArgs(fmt.Sprintf("witness can't be %s", r.Role))
+	}
 	for _, c := range r.LabelConstraints {
 		if !validateOp(c.Op) {
 			return errs.ErrRuleContent.FastGenByArgs(fmt.Sprintf("invalid op %s", c.Op))
@@ -232,24 +270,46 @@ func (m *RuleManager) GetRule(group, id string) *Rule {
 
 // SetRule inserts or updates a Rule.
 func (m *RuleManager) SetRule(rule *Rule) error {
+	_, err := m.SetRuleWithShadowCheck(rule, false)
+	return err
+}
+
+// SetRuleWithShadowCheck inserts or updates a Rule, and returns the rules
+// which become fully shadowed by the override rules or rule groups after the
+// update. If rejectShadowed is true, the rule is not updated when any rule
+// becomes shadowed.
+func (m *RuleManager) SetRuleWithShadowCheck(rule *Rule, rejectShadowed bool) ([]*Rule, error) {
 	if err := m.adjustRule(rule, ""); err != nil {
-		return err
+		return nil, err
 	}
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	p := m.beginPatch()
 	p.setRule(rule)
-	if err := m.tryCommitPatch(p); err != nil {
-		return err
+	shadowed, err := m.tryCommitPatchWithShadowCheck(p, rejectShadowed)
+	if err != nil {
+		return shadowed, err
 	}
 	log.Info("placement rule updated", zap.String("rule", fmt.Sprint(rule)))
-	return nil
+	return shadowed, nil
+}
+
+// GetShadowedRules returns the rules which are fully shadowed by the override
+// rules or rule groups, so they are never applied to any region.
+func (m *RuleManager) GetShadowedRules() []*Rule {
+	m.RLock()
+	defer m.RUnlock()
+	var rules []*Rule
+	for _, r := range m.ruleList.shadowedRules() {
+		rules = append(rules, r.Clone())
+	}
+	return rules
 }
 
 // DeleteRule removes a Rule.
 func (m *RuleManager) DeleteRule(group, id string) error {
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	p := m.beginPatch()
 	p.deleteRule(group, id)
 	if err := m.tryCommitPatch(p); err != nil {
@@ -327,6 +387,16 @@ func (m *RuleManager) FitRegion(storeSet StoreSet, region *core.RegionInfo) *Reg
 			return fit
 		}
 	}
+	return m.fitRegion(regionStores, region, rules)
+}
+
+// FitRegionWithoutCache fits a region to the rules it matches, ignoring the
+// cached fit results.
+func (m *RuleManager) FitRegionWithoutCache(storeSet StoreSet, region *core.RegionInfo) *RegionFit {
+	return m.fitRegion(getStoresByRegion(storeSet, region), region, m.GetRulesForApplyRegion(region))
+}
+
+func (m *RuleManager) fitRegion(regionStores []*core.StoreInfo, region *core.RegionInfo, rules []*Rule) *RegionFit {
 	fit := fitRegion(regionStores, region, rules)
 	fit.regionStores = regionStores
 	fit.rules = rules
@@ -343,30 +413,82 @@ func (m *RuleManager) InvalidCache(regionID uint64) {
 	m.cache.Invalid(regionID)
 }
 
+// lockForPatch locks the manager before beginning a patch.
+func (m *RuleManager) lockForPatch() {
+	m.patchMu.Lock()
+	m.Lock()
+}
+
+func (m *RuleManager) unlockForPatch() {
+	m.Unlock()
+	m.patchMu.Unlock()
+}
+
 func (m *RuleManager) beginPatch() *ruleConfigPatch {
 	return m.ruleConfig.beginPatch()
 }
 
 func (m *RuleManager) tryCommitPatch(patch *ruleConfigPatch) error {
+	_, err := m.tryCommitPatchWithShadowCheck(patch, false)
+	return err
+}
+
+// tryCommitPatchWithShadowCheck commits the patch and returns the rules which
+// become shadowed by the patch. The patch is not committed if rejectShadowed
+// is true and any rule becomes shadowed. It must be called after lockForPatch.
+func (m *RuleManager) tryCommitPatchWithShadowCheck(patch *ruleConfigPatch, rejectShadowed bool) ([]*Rule, error) {
 	patch.adjust()
 
 	ruleList, err := buildRuleList(patch)
 	if err != nil {
-		return err
+		return nil, err
+	}
+
+	shadowed := newlyShadowedRules(m.ruleList, ruleList)
+	if len(shadowed) > 0 {
+		keys := make([]string, 0, len(shadowed))
+		for _, r := range shadowed {
+			keys = append(keys, r.GroupID+"/"+r.ID)
+		}
+		if rejectShadowed {
+			return shadowed, errs.ErrRuleShadowed.FastGenByArgs(strings.Join(keys, ", "))
+		}
+		log.Warn("placement rules become shadowed", zap.Strings("rules", keys))
 	}
 
 	patch.trim()
 
-	// save updates
-	err = m.savePatch(patch.mut)
+	// save updates without blocking the readers during the retries. The other
+	// patches wait for patchMu, so the patch is still valid after relocking.
+	m.Unlock()
+	err = retryutil.DefaultBackoff.Do(context.Background(), func() error {
+		return m.savePatch(patch.mut)
+	})
+	m.Lock()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// update in-memory state
 	patch.commit()
 	m.ruleList = ruleList
-	return nil
+	return shadowed, nil
+}
+
+// newlyShadowedRules returns the rules which are shadowed in the new rule list
+// but not in the old one.
+func newlyShadowedRules(oldList, newList ruleList) []*Rule {
+	oldShadowed := make(map[[2]string]struct{})
+	for _, r := range oldList.shadowedRules() {
+		oldShadowed[r.Key()] = struct{}{}
+	}
+	var rules []*Rule
+	for _, r := range newList.shadowedRules() {
+		if _, ok := oldShadowed[r.Key()]; !ok {
+			rules = append(rules, r)
+		}
+	}
+	return rules
 }
 
 func (m *RuleManager) savePatch(p *ruleConfig) error {
@@ -403,8 +525,8 @@ func (m *RuleManager) savePatch(p *ruleConfig) error {
 
 // SetRules inserts or updates lots of Rules at once.
 func (m *RuleManager) SetRules(rules []*Rule) error {
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	p := m.beginPatch()
 	for _, r := range rules {
 		if err := m.adjustRule(r, ""); err != nil {
@@ -454,8 +576,8 @@ func (m *RuleManager) Batch(todo []RuleOp) error {
 		}
 	}
 
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 
 	patch := m.beginPatch()
 	for _, t := range todo {
@@ -507,8 +629,8 @@ func (m *RuleManager) GetRuleGroups() []*RuleGroup {
 
 // SetRuleGroup updates a RuleGroup.
 func (m *RuleManager) SetRuleGroup(group *RuleGroup) error {
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	p := m.beginPatch()
 	p.setGroup(group)
 	if err := m.tryCommitPatch(p); err != nil {
@@ -520,8 +642,8 @@ func (m *RuleManager) SetRuleGroup(group *RuleGroup) error {
 
 // DeleteRuleGroup removes a RuleGroup.
 func (m *RuleManager) DeleteRuleGroup(id string) error {
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	p := m.beginPatch()
 	p.deleteGroup(id)
 	if err := m.tryCommitPatch(p); err != nil {
@@ -580,8 +702,8 @@ func (m *RuleManager) GetGroupBundle(id string) (b GroupBundle) {
 
 // SetAllGroupBundles resets configuration. If override is true, all old configurations are dropped.
 func (m *RuleManager) SetAllGroupBundles(groups []GroupBundle, override bool) error {
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	p := m.beginPatch()
 	matchID := func(a string) bool {
 		for _, g := range groups {
@@ -624,8 +746,8 @@ func (m *RuleManager) SetAllGroupBundles(groups []GroupBundle, override bool) er
 // SetGroupBundle resets a Group and all rules belong to it. All old rules
 // belong to the Group are dropped.
 func (m *RuleManager) SetGroupBundle(group GroupBundle) error {
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	p := m.beginPatch()
 	if _, ok := m.ruleConfig.groups[group.ID]; ok {
 		for k := range m.ruleConfig.rules {
@@ -655,8 +777,8 @@ func (m *RuleManager) SetGroupBundle(group GroupBundle) error {
 // DeleteGroupBundle removes a Group and all rules belong to it. If `regex` is
 // true, `id` is a regexp expression.
 func (m *RuleManager) DeleteGroupBundle(id string, regex bool) error {
-	m.Lock()
-	defer m.Unlock()
+	m.lockForPatch()
+	defer m.unlockForPatch()
 	matchID := func(a string) bool { return a == id }
 	if regex {
 		r, err := regexp.Compile(id)
@@ -18,9 +18,11 @@ import (
 	"encoding/hex"
 	"testing"
 
+	"github.com/pingcap/errors"
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/stretchr/testify/require"
 	"github.com/tikv/pd/pkg/codec"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/storage"
 	"github.com/tikv/pd/server/storage/endpoint"
@@ -50,6 +52,29 @@ func TestDefault(t *testing.T) {
 	re.Equal([]string{"zone", "rack", "host"}, rules[0].LocationLabels)
 }
 
+func TestUninitialize(t *testing.T) {
+	re := require.New(t)
+	store := storage.NewStorageWithMemoryBackend()
+	manager := NewRuleManager(store, nil, nil)
+	created, err := manager.TryInitialize(3, []string{"zone"})
+	re.NoError(err)
+	re.True(created)
+	re.NoError(manager.Uninitialize())
+	re.False(manager.IsInitialized())
+	var count int
+	re.NoError(store.LoadRules(func(k, v string) { count++ }))
+	re.Zero(count)
+
+	// the default rule is created from the new config again.
+	created, err = manager.TryInitialize(5, nil)
+	re.NoError(err)
+	re.True(created)
+	re.Equal(5, manager.GetRule("pd", "default").Count)
+	created, err = manager.TryInitialize(3, nil)
+	re.NoError(err)
+	re.False(created)
+}
+
 func TestAdjustRule(t *testing.T) {
 	re := require.New(t)
 	_, manager := newTestManager(t)
@@ -64,6 +89,7 @@ func TestAdjustRule(t *testing.T) {
 		{GroupID: "group", ID: "id", StartKeyHex: "123abc", EndKeyHex: "123abf", Role: "voter", Count: 0},
 		{GroupID: "group", ID: "id", StartKeyHex: "123abc", EndKeyHex: "123abf", Role: "voter", Count: -1},
 		{GroupID: "group", ID: "id", StartKeyHex: "123abc", EndKeyHex: "123abf", Role: "voter", Count: 3, LabelConstraints: []LabelConstraint{{Op: "foo"}}},
+		{GroupID: "group", ID: "id", StartKeyHex: "123abc", EndKeyHex: "123abf", Role: "learner", Count: 1, IsWitness: true},
 	}
 	re.NoError(manager.adjustRule(&rules[0], "group"))
 
@@ -283,6 +309,47 @@ func TestRangeGap(t *testing.T) {
 	re.Error(err)
 }
 
+func TestShadowedRules(t *testing.T) {
+	re := require.New(t)
+	_, manager := newTestManager(t)
+	re.Empty(manager.GetShadowedRules())
+
+	// A partial override rule does not shadow the default rule.
+	shadowed, err := manager.SetRuleWithShadowCheck(&Rule{GroupID: "pd", ID: "foo", Index: 1, Override: true, StartKeyHex: "", EndKeyHex: "abcd", Role: "voter", Count: 1}, true)
+	re.NoError(err)
+	re.Empty(shadowed)
+	re.Empty(manager.GetShadowedRules())
+
+	// Reject the rule which shadows the others.
+	bar := &Rule{GroupID: "pd", ID: "bar", Index: 2, Override: true, StartKeyHex: "", EndKeyHex: "", Role: "voter", Count: 3}
+	shadowed, err = manager.SetRuleWithShadowCheck(bar, true)
+	re.True(errs.ErrRuleShadowed.Equal(err))
+	re.Len(shadowed, 2)
+	re.Nil(manager.GetRule("pd", "bar"))
+	re.Empty(manager.GetShadowedRules())
+
+	shadowed, err = manager.SetRuleWithShadowCheck(bar, false)
+	re.NoError(err)
+	re.Len(shadowed, 2)
+	re.Equal("default", shadowed[0].ID)
+	re.Equal("foo", shadowed[1].ID)
+	re.Len(manager.GetShadowedRules(), 2)
+
+	// Only the newly shadowed rules are returned.
+	re.NoError(manager.SetRuleGroup(&RuleGroup{ID: "g2", Index: 10, Override: true}))
+	shadowed, err = manager.SetRuleWithShadowCheck(&Rule{GroupID: "g2", ID: "baz", Role: "voter", Count: 3}, false)
+	re.NoError(err)
+	re.Len(shadowed, 1)
+	re.Equal("bar", shadowed[0].ID)
+	rules := manager.GetShadowedRules()
+	re.Len(rules, 3)
+	for _, r := range rules {
+		re.Equal("pd", r.GroupID)
+	}
+	re.NoError(manager.DeleteRule("g2", "baz"))
+	re.Len(manager.GetShadowedRules(), 2)
+}
+
 func TestGroupConfig(t *testing.T) {
 	re := require.New(t)
 	_, manager := newTestManager(t)
@@ -417,3 +484,39 @@ func dhex(hk string) []byte {
 	}
 	return k
 }
+
+type flakyRuleStorage struct {
+	endpoint.RuleStorage
+	failures int
+	// onSave is called before each save of the rules.
+	onSave func()
+}
+
+func (s *flakyRuleStorage) SaveRule(ruleKey string, rule interface{}) error {
+	if s.onSave != nil {
+		s.onSave()
+	}
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("injected error")
+	}
+	return s.RuleStorage.SaveRule(ruleKey, rule)
+}
+
+func TestSaveRuleRetryWithoutLock(t *testing.T) {
+	re := require.New(t)
+	store, manager := newTestManager(t)
+	flaky := &flakyRuleStorage{RuleStorage: store, failures: 2}
+	manager.storage = flaky
+	// the readers are not blocked while the rule is being persisted.
+	flaky.onSave = func() {
+		re.Len(manager.GetAllRules(), 1)
+	}
+	re.NoError(manager.SetRule(&Rule{GroupID: "g", ID: "id", Role: "voter", Count: 1}))
+	re.Equal(0, flaky.failures)
+	re.NotNil(manager.GetRule("g", "id"))
+
+	flaky.failures = 10
+	re.Error(manager.SetRule(&Rule{GroupID: "g", ID: "id2", Role: "voter", Count: 1}))
+	re.Nil(manager.GetRule("g", "id2"))
+}
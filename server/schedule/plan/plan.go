@@ -17,3 +17,9 @@ package plan
 // Plan is the basic unit for both scheduling and diagnosis.
 // TODO: for each scheduler/checker, we can have an individual definition but need to implement the common interfaces.
 type Plan interface{}
+
+// StatusReporter is implemented by the plans which can tell why they can or
+// cannot be scheduled.
+type StatusReporter interface {
+	GetStatus() *Status
+}
@@ -41,6 +41,9 @@ const regionScatterName = "region-scatter"
 var gcInterval = time.Minute
 var gcTTL = time.Minute * 3
 
+// splitRegionTTL is how long a newly split region prefers the split target stores.
+var splitRegionTTL = 10 * time.Minute
+
 type selectedStores struct {
 	mu                syncutil.RWMutex
 	groupDistribution *cache.TTLString // value type: map[uint64]uint64, group -> StoreID -> count
@@ -122,6 +125,11 @@ type RegionScatterer struct {
 	cluster        Cluster
 	ordinaryEngine engineContext
 	specialEngines sync.Map
+	// splitRegions are the recently split regions, which prefer the split target stores.
+	splitRegions *cache.TTLUint64
+	// splitPlaced counts the split regions recently moved to each split target store,
+	// which are not reflected in the region counts reported by the heartbeats yet.
+	splitPlaced *selectedStores
 }
 
 // NewRegionScatterer creates a region scatterer.
@@ -134,9 +142,60 @@ func NewRegionScatterer(ctx context.Context, cluster Cluster) *RegionScatterer {
 		ordinaryEngine: newEngineContext(ctx, func() filter.Filter {
 			return filter.NewEngineFilter(regionScatterName, filter.NotSpecialEngines)
 		}),
+		splitRegions: cache.NewIDTTL(ctx, gcInterval, splitRegionTTL),
+		splitPlaced:  newSelectedStores(ctx),
 	}
 }
 
+// RecordSplitRegions records the newly split regions, whose peers prefer the split
+// target stores when they are scattered.
+func (r *RegionScatterer) RecordSplitRegions(regionIDs []uint64) {
+	if len(r.cluster.GetOpts().GetSplitTargetStores()) == 0 {
+		return
+	}
+	for _, id := range regionIDs {
+		r.splitRegions.Put(id, nil)
+	}
+}
+
+// splitTargetStores returns the split target stores preferred by the region. It returns
+// nil if the region is not split recently. The target stores whose region count, including
+// the split regions recently moved to them, reaches the fill ratio of the average one of the
+// other stores are excluded, so that they are not overloaded by the new regions of a batch.
+func (r *RegionScatterer) splitTargetStores(region *core.RegionInfo) map[uint64]struct{} {
+	opts := r.cluster.GetOpts()
+	targets := opts.GetSplitTargetStores()
+	if len(targets) == 0 || !r.splitRegions.Exists(region.GetID()) {
+		return nil
+	}
+	targetSet := make(map[uint64]struct{}, len(targets))
+	for _, id := range targets {
+		targetSet[id] = struct{}{}
+	}
+	engineFilter := filter.NewEngineFilter(r.name, filter.NotSpecialEngines)
+	var totalCount, storeCount int
+	for _, store := range r.cluster.GetStores() {
+		if _, ok := targetSet[store.GetID()]; ok || !store.IsUp() || !engineFilter.Target(opts, store).IsOK() {
+			continue
+		}
+		totalCount += store.GetRegionCount()
+		storeCount++
+	}
+	limit := math.MaxFloat64
+	if storeCount > 0 {
+		limit = opts.GetSplitTargetFillRatio() * float64(totalCount) / float64(storeCount)
+	}
+	preferred := make(map[uint64]struct{}, len(targetSet))
+	for id := range targetSet {
+		store := r.cluster.GetStore(id)
+		if store == nil || float64(store.GetRegionCount())+float64(r.splitPlaced.TotalCountByStore(id)) >= limit {
+			continue
+		}
+		preferred[id] = struct{}{}
+	}
+	return preferred
+}
+
 type filterFunc func() filter.Filter
 
 type engineContext struct {
@@ -299,17 +358,18 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *
 		}
 	}
 
-	targetPeers := make(map[uint64]*metapb.Peer, len(region.GetPeers()))                  // StoreID -> Peer
-	selectedStores := make(map[uint64]struct{}, len(region.GetPeers()))                   // StoreID set
-	scatterWithSameEngine := func(peers map[uint64]*metapb.Peer, context engineContext) { // peers: StoreID -> Peer
+	targetPeers := make(map[uint64]*metapb.Peer, len(region.GetPeers())) // StoreID -> Peer
+	selectedStores := make(map[uint64]struct{}, len(region.GetPeers()))  // StoreID set
+	// peers: StoreID -> Peer, preferred: StoreID set
+	scatterWithSameEngine := func(peers map[uint64]*metapb.Peer, context engineContext, preferred map[uint64]struct{}) {
 		for _, peer := range peers {
 			if _, ok := selectedStores[peer.GetStoreId()]; ok {
 				// It is both sourcePeer and targetPeer itself, no need to select.
 				continue
 			}
 			for {
-				candidates := r.selectCandidates(region, peer.GetStoreId(), selectedStores, context)
-				newPeer := r.selectStore(group, peer, peer.GetStoreId(), candidates, context)
+				candidates := r.selectCandidates(region, peer.GetStoreId(), selectedStores, context, preferred)
+				newPeer := r.selectStore(group, peer, peer.GetStoreId(), candidates, context, preferred)
 				targetPeers[newPeer.GetStoreId()] = newPeer
 				selectedStores[newPeer.GetStoreId()] = struct{}{}
 				// If the selected peer is a peer other than origin peer in this region,
@@ -322,7 +382,8 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *
 		}
 	}
 
-	scatterWithSameEngine(ordinaryPeers, r.ordinaryEngine)
+	preferred := r.splitTargetStores(region)
+	scatterWithSameEngine(ordinaryPeers, r.ordinaryEngine, preferred)
 	// FIXME: target leader only considers the ordinary stores, maybe we need to consider the
 	// special engine stores if the engine supports to become a leader. But now there is only
 	// one engine, tiflash, which does not support the leader, so don't consider it for now.
@@ -340,7 +401,7 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *
 			})
 			r.specialEngines.Store(engine, ctx)
 		}
-		scatterWithSameEngine(peers, ctx.(engineContext))
+		scatterWithSameEngine(peers, ctx.(engineContext), nil)
 	}
 
 	if isSameDistribution(region, targetPeers, targetLeader) {
@@ -361,6 +422,11 @@ func (r *RegionScatterer) scatterRegion(region *core.RegionInfo, group string) *
 	if op != nil {
 		scatterCounter.WithLabelValues("success", "").Inc()
 		r.Put(targetPeers, targetLeader, group)
+		for storeID := range targetPeers {
+			if _, ok := preferred[storeID]; ok && region.GetStorePeer(storeID) == nil {
+				r.splitPlaced.Put(storeID, group)
+			}
+		}
 		op.SetPriorityLevel(core.HighPriority)
 	}
 	return op
@@ -376,7 +442,7 @@ func isSameDistribution(region *core.RegionInfo, targetPeers map[uint64]*metapb.
 	return region.GetLeader().GetStoreId() == targetLeader
 }
 
-func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreID uint64, selectedStores map[uint64]struct{}, context engineContext) []uint64 {
+func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreID uint64, selectedStores map[uint64]struct{}, context engineContext, preferred map[uint64]struct{}) []uint64 {
 	sourceStore := r.cluster.GetStore(sourceStoreID)
 	if sourceStore == nil {
 		log.Error("failed to get the store", zap.Uint64("store-id", sourceStoreID), errs.ZapError(errs.ErrGetSourceStore))
@@ -405,10 +471,11 @@ func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreI
 	}
 	for _, store := range stores {
 		storeCount := context.selectedPeer.TotalCountByStore(store.GetID())
+		_, isPreferred := preferred[store.GetID()]
 		// If storeCount is equal to the maxStoreTotalCount, we should skip this store as candidate.
 		// If the storeCount are all the same for the whole cluster(maxStoreTotalCount == minStoreTotalCount), any store
-		// could be selected as candidate.
-		if storeCount < maxStoreTotalCount || maxStoreTotalCount == minStoreTotalCount {
+		// could be selected as candidate. The preferred stores are not skipped, as they are expected to take more peers.
+		if isPreferred || storeCount < maxStoreTotalCount || maxStoreTotalCount == minStoreTotalCount {
 			if filter.Target(r.cluster.GetOpts(), store, filters) {
 				candidates = append(candidates, store.GetID())
 			}
@@ -417,10 +484,23 @@ func (r *RegionScatterer) selectCandidates(region *core.RegionInfo, sourceStoreI
 	return candidates
 }
 
-func (r *RegionScatterer) selectStore(group string, peer *metapb.Peer, sourceStoreID uint64, candidates []uint64, context engineContext) *metapb.Peer {
+func (r *RegionScatterer) selectStore(group string, peer *metapb.Peer, sourceStoreID uint64, candidates []uint64, context engineContext, preferred map[uint64]struct{}) *metapb.Peer {
 	if len(candidates) < 1 {
 		return peer
 	}
+	if _, ok := preferred[sourceStoreID]; ok {
+		return peer
+	}
+	// Only the preferred stores are considered if any of them is available.
+	preferredCandidates := make([]uint64, 0, len(preferred))
+	for _, storeID := range candidates {
+		if _, ok := preferred[storeID]; ok {
+			preferredCandidates = append(preferredCandidates, storeID)
+		}
+	}
+	if len(preferredCandidates) > 0 {
+		candidates = preferredCandidates
+	}
 	var newPeer *metapb.Peer
 	minCount := uint64(math.MaxUint64)
 	for _, storeID := range candidates {
@@ -576,3 +576,59 @@ func isPeerCountChanged(op *operator.Operator) bool {
 	}
 	return add != remove
 }
+
+func TestScatterToSplitTargetStores(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := config.NewTestOptions()
+	scheduleCfg := opt.GetScheduleConfig().Clone()
+	scheduleCfg.SplitTargetStores = []uint64{5}
+	scheduleCfg.SplitTargetFillRatio = 0.5
+	opt.SetScheduleConfig(scheduleCfg)
+	tc := mockcluster.NewCluster(ctx, opt)
+	for i := uint64(1); i <= 4; i++ {
+		tc.AddRegionStore(i, 100)
+		tc.SetStoreLastHeartbeatInterval(i, -10*time.Minute)
+	}
+	tc.AddRegionStore(5, 0)
+	tc.SetStoreLastHeartbeatInterval(5, -10*time.Minute)
+	scatterer := NewRegionScatterer(ctx, tc)
+
+	addsPeerTo := func(op *operator.Operator, storeID uint64) bool {
+		if op == nil {
+			return false
+		}
+		for i := 0; i < op.Len(); i++ {
+			if step, ok := op.Step(i).(operator.AddLearner); ok && step.ToStore == storeID {
+				return true
+			}
+		}
+		return false
+	}
+
+	// The regions not split recently have no preference.
+	region := tc.AddLeaderRegion(1, 1, 2, 3)
+	re.Nil(scatterer.splitTargetStores(region))
+
+	scatterer.RecordSplitRegions([]uint64{2, 3, 4, 5})
+	region = tc.AddLeaderRegion(2, 1, 2, 3)
+	re.Len(scatterer.splitTargetStores(region), 1)
+	re.True(addsPeerTo(scatterer.scatterRegion(region, "group"), 5))
+
+	// The regions moved to the target store are counted before the heartbeats report them.
+	tc.AddRegionStore(5, 48)
+	tc.SetStoreLastHeartbeatInterval(5, -10*time.Minute)
+	region = tc.AddLeaderRegion(3, 1, 2, 3)
+	re.Len(scatterer.splitTargetStores(region), 1)
+	re.True(addsPeerTo(scatterer.scatterRegion(region, "group"), 5))
+	region = tc.AddLeaderRegion(4, 1, 2, 3)
+	re.Empty(scatterer.splitTargetStores(region))
+
+	// The target store is not preferred once it is filled up to the ratio.
+	scatterer.splitPlaced = newSelectedStores(ctx)
+	tc.AddRegionStore(5, 50)
+	tc.SetStoreLastHeartbeatInterval(5, -10*time.Minute)
+	region = tc.AddLeaderRegion(5, 1, 2, 3)
+	re.Empty(scatterer.splitTargetStores(region))
+}
@@ -55,6 +55,8 @@ func NewSplitRegionsHandler(cluster Cluster, oc *OperatorController) SplitRegion
 type RegionSplitter struct {
 	cluster Cluster
 	handler SplitRegionsHandler
+	// onSplit is called with the newly split regions.
+	onSplit func(regionIDs []uint64)
 }
 
 // NewRegionSplitter return a region splitter
@@ -65,6 +67,12 @@ func NewRegionSplitter(cluster Cluster, handler SplitRegionsHandler) *RegionSpli
 	}
 }
 
+// SetSplitObserver sets the function called with the newly split regions, e.g.
+// to make them prefer the split target stores when they are scattered.
+func (r *RegionSplitter) SetSplitObserver(onSplit func(regionIDs []uint64)) {
+	r.onSplit = onSplit
+}
+
 // SplitRegions support splitRegions by given split keys.
 func (r *RegionSplitter) SplitRegions(ctx context.Context, splitKeys [][]byte, retryLimit int) (int, []uint64) {
 	if len(splitKeys) < 1 {
@@ -84,6 +92,9 @@ func (r *RegionSplitter) SplitRegions(ctx context.Context, splitKeys [][]byte, r
 	for regionID := range newRegions {
 		returned = append(returned, regionID)
 	}
+	if r.onSplit != nil && len(returned) > 0 {
+		r.onSplit(returned)
+	}
 	return 100 - len(unprocessedKeys)*100/len(splitKeys), returned
 }
 
@@ -16,10 +16,13 @@ package schedule
 
 import (
 	"encoding/json"
+	"fmt"
 	"net/http"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/coreos/go-semver/semver"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
@@ -27,6 +30,7 @@ import (
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/plan"
 	"github.com/tikv/pd/server/storage/endpoint"
+	"github.com/tikv/pd/server/versioninfo"
 	"go.uber.org/zap"
 )
 
@@ -45,6 +49,150 @@ type Scheduler interface {
 	IsScheduleAllowed(cluster Cluster) bool
 }
 
+// SchedulerState is implemented by schedulers which can report a snapshot of
+// their internal state, such as the pending influence of the operators they
+// created. The returned value must be JSON-serializable.
+type SchedulerState interface {
+	GetState() interface{}
+}
+
+// SchedulerStateSnapshot is a uniform, JSON-serializable view of a running
+// scheduler.
+type SchedulerStateSnapshot struct {
+	Name   string `json:"name"`
+	Type   string `json:"type"`
+	Paused bool   `json:"paused"`
+	// Observing is true if the operators of the scheduler are only recorded
+	// but not submitted.
+	Observing bool            `json:"observing"`
+	Config    json.RawMessage `json:"config,omitempty"`
+	// LastScheduleTime is the zero time if the scheduler has not run yet.
+	LastScheduleTime  time.Time   `json:"last-schedule-time"`
+	LastOperatorCount int         `json:"last-operator-count"`
+	State             interface{} `json:"state,omitempty"`
+	// Diagnosis is set only if the scheduler has produced no operator for a while.
+	Diagnosis *SchedulerDiagnosis `json:"diagnosis,omitempty"`
+}
+
+// SchedulerDiagnosis summarizes why a scheduler has produced no operator for a
+// while, which is sampled from the dry runs of the scheduler in background.
+type SchedulerDiagnosis struct {
+	// SilentSince is the time since which the scheduler has produced no operator.
+	SilentSince    time.Time          `json:"silent-since"`
+	LastSampleTime time.Time          `json:"last-sample-time"`
+	Samples        int                `json:"samples"`
+	Reasons        []*DiagnosisReason `json:"reasons"`
+}
+
+// SchedulerObservation is the result of a scheduler in observe-only mode, in
+// which the operators are recorded instead of being submitted to the operator
+// controller.
+type SchedulerObservation struct {
+	Observing bool `json:"observing"`
+	// ObserveSince and ObserveUntil are the zero time if the scheduler is not
+	// observing.
+	ObserveSince time.Time `json:"observe-since"`
+	ObserveUntil time.Time `json:"observe-until"`
+	// Operators are the latest recorded operators, the oldest first.
+	Operators []*ObservedOperator `json:"operators"`
+}
+
+// ObservedOperator is an operator recorded in observe-only mode.
+type ObservedOperator struct {
+	Time     time.Time `json:"time"`
+	RegionID uint64    `json:"region-id"`
+	Desc     string    `json:"desc"`
+	Kind     string    `json:"kind"`
+	Detail   string    `json:"detail"`
+}
+
+// DiagnosisReason is a reason why a scheduler does not schedule, along with
+// the number of times it is found in the samples.
+type DiagnosisReason struct {
+	Reason string `json:"reason"`
+	Count  int    `json:"count"`
+}
+
+// MaxDiagnosisReasons is the max number of the reasons kept in a diagnosis, the
+// less frequent ones are merged into DiagnosisReasonOthers.
+const MaxDiagnosisReasons = 16
+
+// DiagnosisReasonOthers is the reason merged from the less frequent reasons.
+const DiagnosisReasonOthers = "Others"
+
+// AddSample merges the reasons found in a sample into the diagnosis. The
+// reasons are sorted by the count in descending order, and at most
+// MaxDiagnosisReasons of them are kept. It returns true if the ranking of the
+// reasons is changed by the sample.
+func (d *SchedulerDiagnosis) AddSample(reasons map[string]int, at time.Time) bool {
+	d.Samples++
+	d.LastSampleTime = at
+	ranking := make([]string, 0, len(d.Reasons))
+	var others *DiagnosisReason
+	for _, r := range d.Reasons {
+		ranking = append(ranking, r.Reason)
+		if r.Reason == DiagnosisReasonOthers {
+			others = r
+		}
+	}
+	if others != nil {
+		d.Reasons = d.Reasons[:len(d.Reasons)-1]
+	}
+	for _, r := range d.Reasons {
+		if count, ok := reasons[r.Reason]; ok {
+			r.Count += count
+			delete(reasons, r.Reason)
+		}
+	}
+	for reason, count := range reasons {
+		d.Reasons = append(d.Reasons, &DiagnosisReason{Reason: reason, Count: count})
+	}
+	sort.SliceStable(d.Reasons, func(i, j int) bool {
+		if d.Reasons[i].Count != d.Reasons[j].Count {
+			return d.Reasons[i].Count > d.Reasons[j].Count
+		}
+		return d.Reasons[i].Reason < d.Reasons[j].Reason
+	})
+	// The merged reason takes a place of the kept ones.
+	limit := MaxDiagnosisReasons
+	if others != nil {
+		limit--
+	}
+	if len(d.Reasons) > limit {
+		if others == nil {
+			others = &DiagnosisReason{Reason: DiagnosisReasonOthers}
+		}
+		for _, r := range d.Reasons[MaxDiagnosisReasons-1:] {
+			others.Count += r.Count
+		}
+		d.Reasons = d.Reasons[:MaxDiagnosisReasons-1]
+	}
+	if others != nil {
+		d.Reasons = append(d.Reasons, others)
+	}
+
+	if len(ranking) != len(d.Reasons) {
+		return true
+	}
+	for i, r := range d.Reasons {
+		if ranking[i] != r.Reason {
+			return true
+		}
+	}
+	return false
+}
+
+// Clone returns a deep copy of the diagnosis.
+func (d *SchedulerDiagnosis) Clone() *SchedulerDiagnosis {
+	cloned := *d
+	cloned.Reasons = make([]*DiagnosisReason, 0, len(d.Reasons))
+	for _, r := range d.Reasons {
+		reason := *r
+		cloned.Reasons = append(cloned.Reasons, &reason)
+	}
+	return &cloned
+}
+
 // EncodeConfig encode the custom config for each scheduler.
 func EncodeConfig(v interface{}) ([]byte, error) {
 	marshaled, err := json.Marshal(v)
@@ -92,6 +240,45 @@ type CreateSchedulerFunc func(opController *OperatorController, storage endpoint
 
 var schedulerMap = make(map[string]CreateSchedulerFunc)
 var schedulerArgsToDecoder = make(map[string]ConfigSliceDecoderBuilder)
+var schedulerPrerequisites = make(map[string]*Prerequisites)
+
+// Prerequisites declares the conditions which should be met before a scheduler
+// is allowed to schedule.
+type Prerequisites struct {
+	// MinUpStoreCount is the minimum number of Up stores in the cluster.
+	MinUpStoreCount int
+	// MinClusterVersion is the minimum cluster version. Nil means no limitation.
+	MinClusterVersion *semver.Version
+	// RequiredFeatures are the features which should be supported by the cluster.
+	RequiredFeatures []versioninfo.Feature
+}
+
+// check returns the reason why the prerequisites are not met. It returns an
+// empty string if all the prerequisites are met.
+func (p *Prerequisites) check(cluster Cluster) string {
+	if p.MinUpStoreCount > 0 {
+		var upStoreCount int
+		for _, store := range cluster.GetStores() {
+			if store.IsUp() {
+				upStoreCount++
+			}
+		}
+		if upStoreCount < p.MinUpStoreCount {
+			return fmt.Sprintf("need at least %d up stores, got %d", p.MinUpStoreCount, upStoreCount)
+		}
+	}
+	clusterVersion := cluster.GetOpts().GetClusterVersion()
+	if p.MinClusterVersion != nil && clusterVersion.LessThan(*p.MinClusterVersion) {
+		return fmt.Sprintf("need cluster version %s at least, got %s", p.MinClusterVersion, clusterVersion)
+	}
+	for _, feature := range p.RequiredFeatures {
+		if !versioninfo.IsFeatureSupported(clusterVersion, feature) {
+			return fmt.Sprintf("need cluster version %s at least to support the required feature, got %s",
+				versioninfo.MinSupportedVersion(feature), clusterVersion)
+		}
+	}
+	return ""
+}
 
 // RegisterScheduler binds a scheduler creator. It should be called in init()
 // func of a package.
@@ -112,6 +299,28 @@ func RegisterSliceDecoderBuilder(typ string, builder ConfigSliceDecoderBuilder)
 	config.RegisterScheduler(typ)
 }
 
+// RegisterPrerequisites declares the prerequisites of a scheduler type. It
+// should be called in init() func of a package.
+func RegisterPrerequisites(typ string, prerequisites *Prerequisites) {
+	if _, ok := schedulerPrerequisites[typ]; ok {
+		log.Fatal("duplicated scheduler prerequisites", zap.String("type", typ), errs.ZapError(errs.ErrSchedulerDuplicated))
+	}
+	schedulerPrerequisites[typ] = prerequisites
+}
+
+// CheckPrerequisites checks whether the cluster meets the prerequisites of the
+// given scheduler. It returns nil if the prerequisites are met or not declared.
+func CheckPrerequisites(s Scheduler, cluster Cluster) error {
+	prerequisites, ok := schedulerPrerequisites[s.GetType()]
+	if !ok {
+		return nil
+	}
+	if reason := prerequisites.check(cluster); reason != "" {
+		return errs.ErrSchedulerPrerequisitesNotMet.FastGenByArgs(s.GetName(), reason)
+	}
+	return nil
+}
+
 // CreateScheduler creates a scheduler with registered creator func.
 func CreateScheduler(typ string, opController *OperatorController, storage endpoint.ConfigStorage, dec ConfigDecoder) (Scheduler, error) {
 	fn, ok := schedulerMap[typ]
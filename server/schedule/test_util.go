@@ -64,6 +64,22 @@ func ApplyOperatorStep(region *core.RegionInfo, op *operator.Operator) *core.Reg
 				StoreId: s.ToStore,
 			}
 			region = region.Clone(core.WithRemoveStorePeer(s.ToStore), core.WithAddPeer(peer))
+		case operator.DemoteVoter:
+			region = setPeerRole(region, s.ToStore, s.PeerID, metapb.PeerRole_Learner)
+		case operator.ChangePeerV2Enter:
+			for _, pl := range s.PromoteLearners {
+				region = setPeerRole(region, pl.ToStore, pl.PeerID, metapb.PeerRole_IncomingVoter)
+			}
+			for _, dv := range s.DemoteVoters {
+				region = setPeerRole(region, dv.ToStore, dv.PeerID, metapb.PeerRole_DemotingVoter)
+			}
+		case operator.ChangePeerV2Leave:
+			for _, pl := range s.PromoteLearners {
+				region = setPeerRole(region, pl.ToStore, pl.PeerID, metapb.PeerRole_Voter)
+			}
+			for _, dv := range s.DemoteVoters {
+				region = setPeerRole(region, dv.ToStore, dv.PeerID, metapb.PeerRole_Learner)
+			}
 		default:
 			panic("Unknown operator step")
 		}
@@ -71,6 +87,18 @@ func ApplyOperatorStep(region *core.RegionInfo, op *operator.Operator) *core.Reg
 	return region
 }
 
+func setPeerRole(region *core.RegionInfo, storeID, peerID uint64, role metapb.PeerRole) *core.RegionInfo {
+	if region.GetStorePeer(storeID) == nil {
+		panic("Change the role of peer that doesn't exist")
+	}
+	peer := &metapb.Peer{
+		Id:      peerID,
+		StoreId: storeID,
+		Role:    role,
+	}
+	return region.Clone(core.WithRemoveStorePeer(storeID), core.WithAddPeer(peer))
+}
+
 // ApplyOperator applies operator. Only for test purpose.
 func ApplyOperator(mc *mockcluster.Cluster, op *operator.Operator) {
 	origin := mc.GetRegion(op.RegionID())
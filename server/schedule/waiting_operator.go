@@ -15,6 +15,7 @@
 package schedule
 
 import (
+	"encoding/hex"
 	"math/rand"
 	"time"
 
@@ -29,53 +30,167 @@ type WaitingOperator interface {
 	PutOperator(op *operator.Operator)
 	GetOperator() []*operator.Operator
 	ListOperator() []*operator.Operator
+	// ListOperatorGroups lists the operators queued together, such as a pair
+	// of merge operators.
+	ListOperatorGroups() [][]*operator.Operator
 }
 
-// Bucket is used to maintain the operators created by a specific scheduler.
+// waitingOperatorKeyFunc returns the key used to group the waiting operators
+// for fairness.
+type waitingOperatorKeyFunc func(op *operator.Operator) string
+
+// waitingGroup is an operator or two merge operators waiting to be promoted.
+type waitingGroup struct {
+	ops     []*operator.Operator
+	putTime time.Time
+}
+
+// fairQueue is a FIFO queue of the waiting operators with the same key.
+type fairQueue struct {
+	key    string
+	groups []*waitingGroup
+}
+
+// Bucket is used to maintain the operators with a specific priority. The
+// operators are queued by their keys, and the queues are served in turn.
 type Bucket struct {
 	weight float64
-	ops    []*operator.Operator
+	queues map[string]*fairQueue
+	// order is the serving order of the non-empty queues.
+	order []*fairQueue
+	next  int
+}
+
+func newBucket(weight float64) *Bucket {
+	return &Bucket{
+		weight: weight,
+		queues: make(map[string]*fairQueue),
+	}
+}
+
+func (b *Bucket) empty() bool {
+	return len(b.order) == 0
+}
+
+func (b *Bucket) put(key string, group *waitingGroup) {
+	q, ok := b.queues[key]
+	if !ok {
+		q = &fairQueue{key: key}
+		b.queues[key] = q
+		b.order = append(b.order, q)
+	}
+	q.groups = append(q.groups, group)
+}
+
+// pop pops the group from the next queue in turn, and returns the key of
+// the queue.
+func (b *Bucket) pop() (string, *waitingGroup) {
+	if b.empty() {
+		return "", nil
+	}
+	if b.next >= len(b.order) {
+		b.next = 0
+	}
+	q := b.order[b.next]
+	group := q.groups[0]
+	q.groups = q.groups[1:]
+	if len(q.groups) == 0 {
+		delete(b.queues, q.key)
+		b.order = append(b.order[:b.next], b.order[b.next+1:]...)
+	} else {
+		b.next++
+	}
+	return q.key, group
+}
+
+func (b *Bucket) list() []*operator.Operator {
+	var ops []*operator.Operator
+	for _, q := range b.order {
+		for _, group := range q.groups {
+			ops = append(ops, group.ops...)
+		}
+	}
+	return ops
 }
 
 // RandBuckets is an implementation of waiting operators
 type RandBuckets struct {
 	totalWeight float64
 	buckets     []*Bucket
+	keyFunc     waitingOperatorKeyFunc
+	// keyCounts is the number of the waiting groups of each key.
+	keyCounts map[string]int
+	// pendingMerge is the first operator of a pair of merge operators, which
+	// waits for the second one to be queued together.
+	pendingMerge *operator.Operator
 }
 
 // NewRandBuckets creates a random buckets.
 func NewRandBuckets() *RandBuckets {
 	var buckets []*Bucket
 	for i := 0; i < len(PriorityWeight); i++ {
-		buckets = append(buckets, &Bucket{
-			weight: PriorityWeight[i],
-		})
+		buckets = append(buckets, newBucket(PriorityWeight[i]))
 	}
-	return &RandBuckets{buckets: buckets}
+	return &RandBuckets{buckets: buckets, keyCounts: make(map[string]int)}
+}
+
+// setKeyFunc sets the function to group the waiting operators, the operators
+// are queued in FIFO order if it is not set.
+func (b *RandBuckets) setKeyFunc(f waitingOperatorKeyFunc) {
+	b.keyFunc = f
 }
 
 // PutOperator puts an operator into the random buckets.
 func (b *RandBuckets) PutOperator(op *operator.Operator) {
-	priority := op.GetPriorityLevel()
-	bucket := b.buckets[priority]
-	if len(bucket.ops) == 0 {
+	ops := []*operator.Operator{op}
+	// Merge operation has two operators, and thus they should be queued together.
+	if op.Kind()&operator.OpMerge != 0 {
+		if b.pendingMerge == nil {
+			b.pendingMerge = op
+			return
+		}
+		ops = []*operator.Operator{b.pendingMerge, op}
+		b.pendingMerge = nil
+	}
+	var key string
+	if b.keyFunc != nil {
+		key = b.keyFunc(ops[0])
+	}
+	bucket := b.buckets[ops[0].GetPriorityLevel()]
+	if bucket.empty() {
 		b.totalWeight += bucket.weight
 	}
-	bucket.ops = append(bucket.ops, op)
+	bucket.put(key, &waitingGroup{ops: ops, putTime: time.Now()})
+	b.keyCounts[key]++
+	waitingOperatorQueueGauge.WithLabelValues(waitingOperatorKeyLabel(key)).Set(float64(b.keyCounts[key]))
 }
 
 // ListOperator lists all operator in the random buckets.
 func (b *RandBuckets) ListOperator() []*operator.Operator {
 	var ops []*operator.Operator
 	for i := range b.buckets {
-		bucket := b.buckets[i]
-		for j := range bucket.ops {
-			ops = append(ops, bucket.ops[j])
-		}
+		ops = append(ops, b.buckets[i].list()...)
+	}
+	if b.pendingMerge != nil {
+		ops = append(ops, b.pendingMerge)
 	}
 	return ops
 }
 
+// ListOperatorGroups lists all operator groups in the random buckets, without
+// the merge operator waiting for its pair.
+func (b *RandBuckets) ListOperatorGroups() [][]*operator.Operator {
+	var groups [][]*operator.Operator
+	for _, bucket := range b.buckets {
+		for _, q := range bucket.order {
+			for _, group := range q.groups {
+				groups = append(groups, group.ops)
+			}
+		}
+	}
+	return groups
+}
+
 // GetOperator gets an operator from the random buckets.
 func (b *RandBuckets) GetOperator() []*operator.Operator {
 	if b.totalWeight == 0 {
@@ -85,30 +200,43 @@ func (b *RandBuckets) GetOperator() []*operator.Operator {
 	var sum float64
 	for i := range b.buckets {
 		bucket := b.buckets[i]
-		if len(bucket.ops) == 0 {
+		if bucket.empty() {
 			continue
 		}
 		proportion := bucket.weight / b.totalWeight
 		if r >= sum && r < sum+proportion {
-			var res []*operator.Operator
-			res = append(res, bucket.ops[0])
-			// Merge operation has two operators, and thus it should be handled specifically.
-			if bucket.ops[0].Kind()&operator.OpMerge != 0 {
-				res = append(res, bucket.ops[1])
-				bucket.ops = bucket.ops[2:]
-			} else {
-				bucket.ops = bucket.ops[1:]
-			}
-			if len(bucket.ops) == 0 {
+			key, group := bucket.pop()
+			if bucket.empty() {
 				b.totalWeight -= bucket.weight
 			}
-			return res
+			b.observePop(key, group)
+			return group.ops
 		}
 		sum += proportion
 	}
 	return nil
 }
 
+func (b *RandBuckets) observePop(key string, group *waitingGroup) {
+	label := waitingOperatorKeyLabel(key)
+	b.keyCounts[key]--
+	if b.keyCounts[key] > 0 {
+		waitingOperatorQueueGauge.WithLabelValues(label).Set(float64(b.keyCounts[key]))
+		waitingOperatorQueueWaitGauge.WithLabelValues(label).Set(time.Since(group.putTime).Seconds())
+		return
+	}
+	delete(b.keyCounts, key)
+	waitingOperatorQueueGauge.DeleteLabelValues(label)
+	waitingOperatorQueueWaitGauge.DeleteLabelValues(label)
+}
+
+func waitingOperatorKeyLabel(key string) string {
+	if len(key) == 0 {
+		return "all"
+	}
+	return hex.EncodeToString([]byte(key))
+}
+
 // WaitingOperatorStatus is used to limit the count of each kind of operators.
 type WaitingOperatorStatus struct {
 	ops map[string]uint64
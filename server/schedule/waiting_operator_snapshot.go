@@ -0,0 +1,145 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedule
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/schedule/operator"
+	"go.uber.org/zap"
+)
+
+// WaitingOperatorSnapshot is a compact snapshot of the waiting operators. It is
+// persisted periodically so that a new PD leader can continue the operators
+// instead of creating them from scratch.
+type WaitingOperatorSnapshot struct {
+	// Timestamp is the unix time in seconds when the snapshot is taken.
+	Timestamp int64 `json:"timestamp"`
+	// Groups are the operators queued together, such as a pair of merge operators.
+	Groups [][]*operator.Snapshot `json:"groups"`
+	// Truncated is the number of the groups left out to bound the size of the snapshot.
+	Truncated int `json:"truncated,omitempty"`
+}
+
+// IsStale returns true if the snapshot is older than the given staleness.
+func (s *WaitingOperatorSnapshot) IsStale(maxStaleness time.Duration) bool {
+	return time.Since(time.Unix(s.Timestamp, 0)) > maxStaleness
+}
+
+// SnapshotWaitingOperators takes a snapshot of the waiting operators. The
+// operators which cannot be persisted are skipped, and the groups are left out
+// once the encoded size of the snapshot reaches maxSize.
+func (oc *OperatorController) SnapshotWaitingOperators(maxSize int) *WaitingOperatorSnapshot {
+	oc.RLock()
+	defer oc.RUnlock()
+	snapshot := &WaitingOperatorSnapshot{Timestamp: time.Now().Unix()}
+	size := 0
+	for _, ops := range oc.wop.ListOperatorGroups() {
+		if size >= maxSize {
+			snapshot.Truncated++
+			continue
+		}
+		group := make([]*operator.Snapshot, 0, len(ops))
+		for _, op := range ops {
+			s, err := op.Snapshot()
+			if err != nil {
+				log.Warn("failed to take snapshot of waiting operator", zap.Stringer("operator", op), errs.ZapError(err))
+				group = nil
+				break
+			}
+			group = append(group, s)
+		}
+		if group == nil {
+			continue
+		}
+		data, err := json.Marshal(group)
+		if err != nil {
+			log.Warn("failed to encode snapshot of waiting operators", errs.ZapError(err))
+			continue
+		}
+		if size += len(data); size > maxSize {
+			snapshot.Truncated++
+			continue
+		}
+		snapshot.Groups = append(snapshot.Groups, group)
+	}
+	return snapshot
+}
+
+// RestoreWaitingOperators rebuilds the waiting operators from the snapshot,
+// and returns the number of the groups restored and discarded. The operators
+// are validated again as the newly created ones, and discarded if the regions
+// have changed, the target stores are no longer available, or they were
+// already running on the previous leader.
+func (oc *OperatorController) RestoreWaitingOperators(snapshot *WaitingOperatorSnapshot) (restored, discarded int) {
+	for _, group := range snapshot.Groups {
+		ops, err := oc.rebuildWaitingOperators(group)
+		if err == nil && oc.AddWaitingOperator(ops...) > 0 {
+			restored++
+			continue
+		}
+		if err != nil {
+			log.Info("discard waiting operator in snapshot", errs.ZapError(err))
+		}
+		discarded++
+	}
+	waitingOperatorRestoreCounter.WithLabelValues("restored").Add(float64(restored))
+	waitingOperatorRestoreCounter.WithLabelValues("discarded").Add(float64(discarded))
+	return restored, discarded
+}
+
+func (oc *OperatorController) rebuildWaitingOperators(group []*operator.Snapshot) ([]*operator.Operator, error) {
+	ops := make([]*operator.Operator, 0, len(group))
+	for _, s := range group {
+		op, err := s.Rebuild()
+		if err != nil {
+			return nil, err
+		}
+		// The previous leader may have promoted the operator after the snapshot
+		// is taken, in which case its first step is already finished.
+		if region := oc.cluster.GetRegion(op.RegionID()); region != nil && op.Len() > 0 && op.Step(0).IsFinish(region) {
+			return nil, errs.ErrCreateOperator.FastGenByArgs("operator was already running")
+		}
+		for i := 0; i < op.Len(); i++ {
+			for _, storeID := range waitingOperatorTargetStores(op.Step(i)) {
+				if store := oc.cluster.GetStore(storeID); store == nil || store.IsRemoving() || store.IsRemoved() {
+					return nil, errs.ErrCreateOperator.FastGenByArgs("target store is not available")
+				}
+			}
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// waitingOperatorTargetStores returns the stores the step moves the peers or
+// the leader to.
+func waitingOperatorTargetStores(step operator.OpStep) []uint64 {
+	switch s := step.(type) {
+	case operator.AddPeer:
+		return []uint64{s.ToStore}
+	case operator.AddLearner:
+		return []uint64{s.ToStore}
+	case operator.TransferLeader:
+		if len(s.ToStores) > 0 {
+			return s.ToStores
+		}
+		return []uint64{s.ToStore}
+	}
+	return nil
+}
@@ -111,3 +111,34 @@ func TestRandomBucketsWithMergeRegion(t *testing.T) {
 		re.Nil(rb.GetOperator())
 	}
 }
+
+func TestRandBucketsFairness(t *testing.T) {
+	re := require.New(t)
+	rb := NewRandBuckets()
+	rb.setKeyFunc(func(op *operator.Operator) string {
+		if op.RegionID() < 100 {
+			return "a"
+		}
+		return "b"
+	})
+	newOperator := func(regionID uint64) *operator.Operator {
+		return operator.NewTestOperator(regionID, &metapb.RegionEpoch{}, operator.OpRegion, []operator.OpStep{
+			operator.RemovePeer{FromStore: uint64(1)},
+		}...)
+	}
+	for i := uint64(1); i <= 5; i++ {
+		rb.PutOperator(newOperator(i))
+	}
+	rb.PutOperator(newOperator(100))
+	rb.PutOperator(newOperator(101))
+	re.Len(rb.ListOperator(), 7)
+
+	// The queues are served in turn.
+	var regionIDs []uint64
+	for ops := rb.GetOperator(); ops != nil; ops = rb.GetOperator() {
+		re.Len(ops, 1)
+		regionIDs = append(regionIDs, ops[0].RegionID())
+	}
+	re.Equal([]uint64{1, 100, 2, 101, 3, 4, 5}, regionIDs)
+	re.Empty(rb.keyCounts)
+}
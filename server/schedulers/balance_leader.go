@@ -33,7 +33,9 @@ import (
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/schedule/filter"
 	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/schedule/plan"
+	"github.com/tikv/pd/server/statistics"
 	"github.com/tikv/pd/server/storage/endpoint"
 	"github.com/unrolled/render"
 	"go.uber.org/zap"
@@ -55,6 +57,13 @@ const (
 
 	transferIn  = "transfer-in"
 	transferOut = "transfer-out"
+
+	// defaultZoneLabel is the label of the stores used to find their zones
+	// in the latency-aware mode.
+	defaultZoneLabel = "zone"
+	// zoneBiasScore is added to the leader score of a store outside the zone
+	// preferred by the latency-aware mode, so that it is drained first.
+	zoneBiasScore float64 = 1e12
 )
 
 func init() {
@@ -92,6 +101,15 @@ type balanceLeaderSchedulerConfig struct {
 	Ranges  []core.KeyRange `json:"ranges"`
 	// Batch is used to generate multiple operators by one scheduling
 	Batch int `json:"batch"`
+	// BucketAware balances leaders within each of the configured key ranges
+	// independently, instead of treating all leaders of the ranges equally.
+	BucketAware bool `json:"bucket-aware"`
+	// ClientZone enables the latency-aware mode if it is not empty, the
+	// leaders are preferred to be placed in the zone closest to the client
+	// zone according to the latencies between the zones.
+	ClientZone string `json:"client-zone"`
+	// ZoneLabel is the label of the stores indicating their zones.
+	ZoneLabel string `json:"zone-label"`
 }
 
 func (conf *balanceLeaderSchedulerConfig) Update(data []byte) (int, interface{}) {
@@ -133,9 +151,19 @@ func (conf *balanceLeaderSchedulerConfig) Clone() *balanceLeaderSchedulerConfig
 	ranges := make([]core.KeyRange, len(conf.Ranges))
 	copy(ranges, conf.Ranges)
 	return &balanceLeaderSchedulerConfig{
-		Ranges: ranges,
-		Batch:  conf.Batch,
+		Ranges:      ranges,
+		Batch:       conf.Batch,
+		BucketAware: conf.BucketAware,
+		ClientZone:  conf.ClientZone,
+		ZoneLabel:   conf.ZoneLabel,
+	}
+}
+
+func (conf *balanceLeaderSchedulerConfig) getZoneLabel() string {
+	if conf.ZoneLabel == "" {
+		return defaultZoneLabel
 	}
+	return conf.ZoneLabel
 }
 
 func (conf *balanceLeaderSchedulerConfig) persistLocked() error {
@@ -204,6 +232,7 @@ func newBalanceLeaderScheduler(opController *schedule.OperatorController, conf *
 	s.filters = []filter.Filter{
 		&filter.StoreStateFilter{ActionScope: s.GetName(), TransferLeader: true},
 		filter.NewSpecialUseFilter(s.GetName()),
+		filter.NewStoreQuotaFilter(s.GetName(), core.LeaderKind),
 	}
 	return s
 }
@@ -348,54 +377,127 @@ func (l *balanceLeaderScheduler) Schedule(cluster schedule.Cluster, dryRun bool)
 	defer l.conf.mu.RUnlock()
 	batch := l.conf.Batch
 	schedulerCounter.WithLabelValues(l.GetName(), "schedule").Inc()
+	if !l.conf.BucketAware || len(l.conf.Ranges) <= 1 {
+		return l.scheduleInRanges(cluster, l.conf.Ranges, batch), nil
+	}
 
+	// In bucket-aware mode, each key range is isolated as a sub cluster so that
+	// the leader scores only take the leaders inside the range into account.
+	result := make([]*operator.Operator, 0, batch)
+	for _, keyRange := range l.conf.Ranges {
+		c := schedule.GenRangeCluster(cluster, keyRange.StartKey, keyRange.EndKey)
+		ops := l.scheduleInRanges(c, []core.KeyRange{keyRange}, batch-len(result))
+		result = append(result, ops...)
+		if len(result) >= batch {
+			break
+		}
+	}
+	return result, nil
+}
+
+// scheduleInRanges generates at most batch operators which transfer leaders of
+// the regions inside the given ranges.
+func (l *balanceLeaderScheduler) scheduleInRanges(cluster schedule.Cluster, ranges []core.KeyRange, batch int) []*operator.Operator {
 	leaderSchedulePolicy := cluster.GetOpts().GetLeaderSchedulePolicy()
 	opInfluence := l.opController.GetOpInfluence(cluster)
 	kind := core.NewScheduleKind(core.LeaderKind, leaderSchedulePolicy)
 	plan := newBalancePlan(kind, cluster, opInfluence)
 
 	stores := cluster.GetStores()
+	opts := cluster.GetOpts()
+	plan.leaderZone = l.preferredLeaderZone(cluster, filter.SelectTargetStores(stores, l.filters, opts))
 	scoreFunc := func(store *core.StoreInfo) float64 {
-		return store.LeaderScore(plan.kind.Policy, plan.GetOpInfluence(store.GetID()))
+		score := store.LeaderScore(plan.kind.Policy, plan.GetOpInfluence(store.GetID()))
+		if isOverQuota(opts, store, core.LeaderKind) {
+			score += quotaBiasScore
+		}
+		if !l.inLeaderZone(plan, store) {
+			score += zoneBiasScore
+		}
+		return score
 	}
-	sourceCandidate := newCandidateStores(filter.SelectSourceStores(stores, l.filters, cluster.GetOpts()), false, scoreFunc)
-	targetCandidate := newCandidateStores(filter.SelectTargetStores(stores, l.filters, cluster.GetOpts()), true, scoreFunc)
+	sourceCandidate := newCandidateStores(filter.SelectSourceStores(stores, l.filters, opts), false, scoreFunc)
+	targetCandidate := newCandidateStores(filter.SelectTargetStores(stores, l.targetFilters(plan), opts), true, scoreFunc)
 	usedRegions := make(map[uint64]struct{})
 
 	result := make([]*operator.Operator, 0, batch)
 	for sourceCandidate.hasStore() || targetCandidate.hasStore() {
 		// first choose source
 		if sourceCandidate.hasStore() {
-			op := createTransferLeaderOperator(sourceCandidate, transferOut, l, plan, usedRegions)
+			op := createTransferLeaderOperator(sourceCandidate, transferOut, l, plan, ranges, usedRegions)
 			if op != nil {
 				result = append(result, op)
 				if len(result) >= batch {
-					return result, nil
+					return result
 				}
 				makeInfluence(op, plan, usedRegions, sourceCandidate, targetCandidate)
 			}
 		}
 		// next choose target
 		if targetCandidate.hasStore() {
-			op := createTransferLeaderOperator(targetCandidate, transferIn, l, plan, usedRegions)
+			op := createTransferLeaderOperator(targetCandidate, transferIn, l, plan, ranges, usedRegions)
 			if op != nil {
 				result = append(result, op)
 				if len(result) >= batch {
-					return result, nil
+					return result
 				}
 				makeInfluence(op, plan, usedRegions, sourceCandidate, targetCandidate)
 			}
 		}
 	}
 	l.retryQuota.GC(append(sourceCandidate.stores, targetCandidate.stores...))
-	return result, nil
+	return result
+}
+
+// preferredLeaderZone returns the zone closest to the client zone among the
+// zones of the given stores. It returns an empty string if the latency-aware
+// mode is disabled or none of the latencies to the zones is known.
+func (l *balanceLeaderScheduler) preferredLeaderZone(cluster schedule.Cluster, stores []*core.StoreInfo) string {
+	if l.conf.ClientZone == "" {
+		return ""
+	}
+	zoneLabel := l.conf.getZoneLabel()
+	zones := make([]string, 0)
+	found := make(map[string]struct{})
+	for _, store := range stores {
+		zone := store.GetLabelValue(zoneLabel)
+		if _, ok := found[zone]; ok || zone == "" {
+			continue
+		}
+		found[zone] = struct{}{}
+		zones = append(zones, zone)
+	}
+	zone, ok := statistics.ClosestZone(cluster, l.conf.ClientZone, zones)
+	if !ok {
+		schedulerCounter.WithLabelValues(l.GetName(), "no-zone-latency").Inc()
+		return ""
+	}
+	return zone
+}
+
+// inLeaderZone returns true if the store is in the zone preferred by the
+// latency-aware mode in the plan, or the mode is disabled.
+func (l *balanceLeaderScheduler) inLeaderZone(plan *balancePlan, store *core.StoreInfo) bool {
+	return plan.leaderZone == "" || store.GetLabelValue(l.conf.getZoneLabel()) == plan.leaderZone
+}
+
+// targetFilters returns the filters of the target stores, which only select
+// the stores in the preferred zone in the latency-aware mode.
+func (l *balanceLeaderScheduler) targetFilters(plan *balancePlan) []filter.Filter {
+	if plan.leaderZone == "" {
+		return l.filters
+	}
+	zoneFilter := filter.NewLabelConstaintFilter(l.GetName(), []placement.LabelConstraint{
+		{Key: l.conf.getZoneLabel(), Op: placement.In, Values: []string{plan.leaderZone}},
+	})
+	return append(l.filters[:len(l.filters):len(l.filters)], zoneFilter)
 }
 
 func createTransferLeaderOperator(cs *candidateStores, dir string, l *balanceLeaderScheduler,
-	plan *balancePlan, usedRegions map[uint64]struct{}) *operator.Operator {
+	plan *balancePlan, ranges []core.KeyRange, usedRegions map[uint64]struct{}) *operator.Operator {
 	store := cs.getStore()
 	retryLimit := l.retryQuota.GetLimit(store)
-	var creator func(*balancePlan) *operator.Operator
+	var creator func(*balancePlan, []core.KeyRange) *operator.Operator
 	switch dir {
 	case transferOut:
 		plan.source, plan.target = store, nil
@@ -409,7 +511,7 @@ func createTransferLeaderOperator(cs *candidateStores, dir string, l *balanceLea
 	var op *operator.Operator
 	for i := 0; i < retryLimit; i++ {
 		schedulerCounter.WithLabelValues(l.GetName(), "total").Inc()
-		if op = creator(plan); op != nil {
+		if op = creator(plan, ranges); op != nil {
 			if _, ok := usedRegions[op.RegionID()]; !ok {
 				break
 			}
@@ -445,8 +547,8 @@ func makeInfluence(op *operator.Operator, plan *balancePlan, usedRegions map[uin
 // transferLeaderOut transfers leader from the source store.
 // It randomly selects a health region from the source store, then picks
 // the best follower peer and transfers the leader.
-func (l *balanceLeaderScheduler) transferLeaderOut(plan *balancePlan) *operator.Operator {
-	plan.region = filter.SelectOneRegion(plan.RandLeaderRegions(plan.SourceStoreID(), l.conf.Ranges),
+func (l *balanceLeaderScheduler) transferLeaderOut(plan *balancePlan, ranges []core.KeyRange) *operator.Operator {
+	plan.region = filter.SelectOneRegion(plan.RandLeaderRegions(plan.SourceStoreID(), ranges),
 		filter.NewRegionPengdingFilter(), filter.NewRegionDownFilter())
 	if plan.region == nil {
 		log.Debug("store has no leader", zap.String("scheduler", l.GetName()), zap.Uint64("store-id", plan.SourceStoreID()))
@@ -454,10 +556,13 @@ func (l *balanceLeaderScheduler) transferLeaderOut(plan *balancePlan) *operator.
 		return nil
 	}
 	targets := plan.GetFollowerStores(plan.region)
-	finalFilters := l.filters
+	finalFilters := l.targetFilters(plan)
 	opts := plan.GetOpts()
 	if leaderFilter := filter.NewPlacementLeaderSafeguard(l.GetName(), opts, plan.GetBasicCluster(), plan.GetRuleManager(), plan.region, plan.source); leaderFilter != nil {
-		finalFilters = append(l.filters, leaderFilter)
+		finalFilters = append(finalFilters[:len(finalFilters):len(finalFilters)], leaderFilter)
+	}
+	if pinnedFilter := filter.NewPinnedLeaderFilter(l.GetName(), plan.Cluster, plan.region); pinnedFilter != nil {
+		finalFilters = append(finalFilters[:len(finalFilters):len(finalFilters)], pinnedFilter)
 	}
 	targets = filter.SelectTargetStores(targets, finalFilters, opts)
 	leaderSchedulePolicy := opts.GetLeaderSchedulePolicy()
@@ -479,8 +584,8 @@ func (l *balanceLeaderScheduler) transferLeaderOut(plan *balancePlan) *operator.
 // transferLeaderIn transfers leader to the target store.
 // It randomly selects a health region from the target store, then picks
 // the worst follower peer and transfers the leader.
-func (l *balanceLeaderScheduler) transferLeaderIn(plan *balancePlan) *operator.Operator {
-	plan.region = filter.SelectOneRegion(plan.RandFollowerRegions(plan.TargetStoreID(), l.conf.Ranges),
+func (l *balanceLeaderScheduler) transferLeaderIn(plan *balancePlan, ranges []core.KeyRange) *operator.Operator {
+	plan.region = filter.SelectOneRegion(plan.RandFollowerRegions(plan.TargetStoreID(), ranges),
 		filter.NewRegionPengdingFilter(), filter.NewRegionDownFilter())
 	if plan.region == nil {
 		log.Debug("store has no follower", zap.String("scheduler", l.GetName()), zap.Uint64("store-id", plan.TargetStoreID()))
@@ -498,10 +603,13 @@ func (l *balanceLeaderScheduler) transferLeaderIn(plan *balancePlan) *operator.O
 		schedulerCounter.WithLabelValues(l.GetName(), "no-leader").Inc()
 		return nil
 	}
-	finalFilters := l.filters
+	finalFilters := l.targetFilters(plan)
 	opts := plan.GetOpts()
 	if leaderFilter := filter.NewPlacementLeaderSafeguard(l.GetName(), opts, plan.GetBasicCluster(), plan.GetRuleManager(), plan.region, plan.source); leaderFilter != nil {
-		finalFilters = append(l.filters, leaderFilter)
+		finalFilters = append(finalFilters[:len(finalFilters):len(finalFilters)], leaderFilter)
+	}
+	if pinnedFilter := filter.NewPinnedLeaderFilter(l.GetName(), plan.Cluster, plan.region); pinnedFilter != nil {
+		finalFilters = append(finalFilters[:len(finalFilters):len(finalFilters)], pinnedFilter)
 	}
 	target := filter.NewCandidates([]*core.StoreInfo{plan.target}).
 		FilterTarget(opts, finalFilters...).
@@ -525,7 +633,8 @@ func (l *balanceLeaderScheduler) createOperator(plan *balancePlan) *operator.Ope
 		return nil
 	}
 
-	if !plan.shouldBalance(l.GetName()) {
+	// The leaders outside the preferred zone are moved into it regardless of the scores.
+	if !plan.shouldBalance(l.GetName()) && l.inLeaderZone(plan, plan.source) {
 		schedulerCounter.WithLabelValues(l.GetName(), "skip").Inc()
 		return nil
 	}
@@ -545,5 +654,6 @@ func (l *balanceLeaderScheduler) createOperator(plan *balancePlan) *operator.Ope
 	)
 	op.AdditionalInfos["sourceScore"] = strconv.FormatFloat(plan.sourceScore, 'f', 2, 64)
 	op.AdditionalInfos["targetScore"] = strconv.FormatFloat(plan.targetScore, 'f', 2, 64)
+	op.AddReasons(plan.reason(l.GetName()))
 	return op
 }
@@ -0,0 +1,276 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/tikv/pd/pkg/apiutil"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/checker"
+	"github.com/tikv/pd/server/schedule/filter"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/plan"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"github.com/unrolled/render"
+)
+
+const (
+	// BalanceLearnerName is balance learner scheduler name.
+	BalanceLearnerName = "balance-learner-scheduler"
+	// BalanceLearnerType is balance learner scheduler type, which is also the
+	// description of the operators shared with the learner balance checker.
+	BalanceLearnerType = checker.BalanceLearnerDesc
+	// balanceLearnerRetryLimit is the limit to retry selecting a learner on the source store.
+	balanceLearnerRetryLimit = 10
+
+	defaultBalanceLearnerLimit         = 4
+	defaultBalanceLearnerTolerantRatio = 0.05
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder(BalanceLearnerType, func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+	schedule.RegisterScheduler(BalanceLearnerType, func(opController *schedule.OperatorController, storage endpoint.ConfigStorage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		conf := &balanceLearnerSchedulerConfig{
+			storage:       storage,
+			Limit:         defaultBalanceLearnerLimit,
+			TolerantRatio: defaultBalanceLearnerTolerantRatio,
+		}
+		if err := decoder(conf); err != nil {
+			return nil, err
+		}
+		return newBalanceLearnerScheduler(opController, conf), nil
+	})
+	// There is nothing to balance with less than 2 stores.
+	schedule.RegisterPrerequisites(BalanceLearnerType, &schedule.Prerequisites{MinUpStoreCount: 2})
+}
+
+type balanceLearnerSchedulerConfig struct {
+	mu      syncutil.RWMutex
+	storage endpoint.ConfigStorage
+	// Limit is the max number of the running operators of the scheduler, which
+	// is independent of the region schedule limit.
+	Limit uint64 `json:"limit"`
+	// TolerantRatio is the ratio of the average learner count of the stores
+	// with the same engine, within which the learner counts are balanced.
+	TolerantRatio float64 `json:"tolerant-ratio"`
+}
+
+func (conf *balanceLearnerSchedulerConfig) Clone() *balanceLearnerSchedulerConfig {
+	conf.mu.RLock()
+	defer conf.mu.RUnlock()
+	return &balanceLearnerSchedulerConfig{
+		Limit:         conf.Limit,
+		TolerantRatio: conf.TolerantRatio,
+	}
+}
+
+func (conf *balanceLearnerSchedulerConfig) EncodeConfig() ([]byte, error) {
+	conf.mu.RLock()
+	defer conf.mu.RUnlock()
+	return schedule.EncodeConfig(conf)
+}
+
+func (conf *balanceLearnerSchedulerConfig) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	router := mux.NewRouter()
+	router.HandleFunc("/list", conf.handleGetConfig).Methods(http.MethodGet)
+	router.HandleFunc("/config", conf.handleSetConfig).Methods(http.MethodPost)
+	router.ServeHTTP(w, r)
+}
+
+func (conf *balanceLearnerSchedulerConfig) handleGetConfig(w http.ResponseWriter, r *http.Request) {
+	rd := render.New(render.Options{IndentJSON: true})
+	rd.JSON(w, http.StatusOK, conf.Clone())
+}
+
+func (conf *balanceLearnerSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *http.Request) {
+	rd := render.New(render.Options{IndentJSON: true})
+	input := conf.Clone()
+	if err := apiutil.ReadJSONRespondError(rd, w, r.Body, input); err != nil {
+		return
+	}
+	if input.Limit == 0 {
+		rd.JSON(w, http.StatusBadRequest, "limit should be positive")
+		return
+	}
+	if input.TolerantRatio < 0 || input.TolerantRatio >= 1 {
+		rd.JSON(w, http.StatusBadRequest, "tolerant-ratio should be in [0, 1)")
+		return
+	}
+
+	conf.mu.Lock()
+	defer conf.mu.Unlock()
+	oldLimit, oldRatio := conf.Limit, conf.TolerantRatio
+	conf.Limit, conf.TolerantRatio = input.Limit, input.TolerantRatio
+	if err := conf.persistLocked(); err != nil {
+		conf.Limit, conf.TolerantRatio = oldLimit, oldRatio
+		rd.JSON(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	rd.JSON(w, http.StatusOK, "The config is updated.")
+}
+
+func (conf *balanceLearnerSchedulerConfig) persistLocked() error {
+	data, err := schedule.EncodeConfig(conf)
+	if err != nil {
+		return err
+	}
+	return conf.storage.SaveScheduleConfig(BalanceLearnerName, data)
+}
+
+type balanceLearnerScheduler struct {
+	*BaseScheduler
+	conf    *balanceLearnerSchedulerConfig
+	filters []filter.Filter
+
+	mu     syncutil.RWMutex
+	groups []checker.LearnerGroup
+}
+
+// newBalanceLearnerScheduler creates a scheduler that balances the learner
+// counts of the stores with the same engine, which are ignored by the
+// balance-region-scheduler, e.g. the learners on TiFlash or the backup stores.
+func newBalanceLearnerScheduler(opController *schedule.OperatorController, conf *balanceLearnerSchedulerConfig) schedule.Scheduler {
+	return &balanceLearnerScheduler{
+		BaseScheduler: NewBaseScheduler(opController),
+		conf:          conf,
+		filters: []filter.Filter{
+			&filter.StoreStateFilter{ActionScope: BalanceLearnerName, MoveRegion: true},
+			filter.NewSpecialUseFilter(BalanceLearnerName),
+		},
+	}
+}
+
+func (s *balanceLearnerScheduler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.conf.ServeHTTP(w, r)
+}
+
+func (s *balanceLearnerScheduler) GetName() string {
+	return BalanceLearnerName
+}
+
+func (s *balanceLearnerScheduler) GetType() string {
+	return BalanceLearnerType
+}
+
+func (s *balanceLearnerScheduler) EncodeConfig() ([]byte, error) {
+	return s.conf.EncodeConfig()
+}
+
+// GetState returns the learner distribution of the stores grouped by engine
+// at the last schedule.
+func (s *balanceLearnerScheduler) GetState() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string]interface{}{
+		"groups": s.groups,
+	}
+}
+
+func (s *balanceLearnerScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
+	running := uint64(0)
+	for _, op := range s.OpController.GetOperators() {
+		if op.Desc() == BalanceLearnerType {
+			running++
+		}
+	}
+	allowed := running < s.conf.Clone().Limit
+	if !allowed {
+		operator.OperatorLimitCounter.WithLabelValues(s.GetType(), operator.OpRegion.String()).Inc()
+	}
+	return allowed
+}
+
+func (s *balanceLearnerScheduler) Schedule(cluster schedule.Cluster, dryRun bool) ([]*operator.Operator, []plan.Plan) {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	groups, stores := checker.CollectLearnerGroups(cluster, s.filters, s.OpController.GetOperators())
+	for _, group := range groups {
+		learnerSkewGauge.WithLabelValues(group.Engine).Set(float64(group.Skew))
+	}
+	s.mu.Lock()
+	s.groups = groups
+	s.mu.Unlock()
+
+	tolerantRatio := s.conf.Clone().TolerantRatio
+	for _, group := range groups {
+		if op := s.scheduleGroup(cluster, group, stores, tolerantRatio); op != nil {
+			return []*operator.Operator{op}, nil
+		}
+	}
+	schedulerCounter.WithLabelValues(s.GetName(), "no-operator").Inc()
+	return nil, nil
+}
+
+// scheduleGroup moves a learner from the store with more learners to the one
+// with fewer learners in the group, if their difference exceeds the tolerance.
+// The learner counts include the running operators, so the skew isn't fixed
+// repeatedly before the heartbeats reflect the moves.
+func (s *balanceLearnerScheduler) scheduleGroup(cluster schedule.Cluster, group checker.LearnerGroup, stores map[uint64]*core.StoreInfo, tolerantRatio float64) *operator.Operator {
+	if len(group.Stores) < 2 {
+		return nil
+	}
+	tolerance := group.Tolerance(tolerantRatio)
+	minCount := group.Stores[len(group.Stores)-1].LearnerCount
+	ranges := []core.KeyRange{core.NewKeyRange("", "")}
+	pendingFilter := filter.NewRegionPengdingFilter()
+	downFilter := filter.NewRegionDownFilter()
+	replicaFilter := filter.NewRegionReplicatedFilter(cluster)
+	for _, source := range group.Stores {
+		if float64(source.LearnerCount-minCount) < tolerance {
+			break
+		}
+		for i := 0; i < balanceLearnerRetryLimit; i++ {
+			region := filter.SelectOneRegion(cluster.RandLearnerRegions(source.StoreID, ranges),
+				pendingFilter, downFilter, replicaFilter)
+			if region == nil {
+				schedulerCounter.WithLabelValues(s.GetName(), "no-learner-region").Inc()
+				break
+			}
+			if s.OpController.GetOperator(region.GetID()) != nil {
+				schedulerCounter.WithLabelValues(s.GetName(), "region-has-operator").Inc()
+				continue
+			}
+			oldPeer := region.GetStorePeer(source.StoreID)
+			if checker.IsWitnessPeer(cluster, region, oldPeer.GetId()) {
+				schedulerCounter.WithLabelValues(s.GetName(), "skip-witness").Inc()
+				continue
+			}
+			if target := checker.SelectLearnerTarget(cluster, s.GetName(), region, group, stores, source, tolerance); target != 0 {
+				op, err := operator.CreateMovePeerOperator(BalanceLearnerType, cluster, region, operator.OpRegion,
+					source.StoreID, &metapb.Peer{StoreId: target, Role: oldPeer.GetRole()})
+				if err != nil {
+					schedulerCounter.WithLabelValues(s.GetName(), "create-operator-fail").Inc()
+					return nil
+				}
+				op.AddReasons(operator.NewReason(s.GetName(), "learner-skew").
+					With("engine", group.Engine).
+					With("source-learner-count", source.LearnerCount).
+					With("tolerance", tolerance))
+				op.Counters = append(op.Counters, schedulerCounter.WithLabelValues(s.GetName(), "new-operator"))
+				return op
+			}
+			schedulerCounter.WithLabelValues(s.GetName(), "no-target-store").Inc()
+		}
+	}
+	return nil
+}
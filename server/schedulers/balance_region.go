@@ -148,10 +148,14 @@ func (s *balanceRegionScheduler) Schedule(cluster schedule.Cluster, dryRun bool)
 	plan := newBalancePlan(kind, cluster, opInfluence)
 
 	sort.Slice(stores, func(i, j int) bool {
+		// The stores exceeding their soft quotas are relieved first.
+		if iOver, jOver := isOverQuota(opts, stores[i], core.RegionKind), isOverQuota(opts, stores[j], core.RegionKind); iOver != jOver {
+			return iOver
+		}
 		iOp := plan.GetOpInfluence(stores[i].GetID())
 		jOp := plan.GetOpInfluence(stores[j].GetID())
-		return stores[i].RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), iOp) >
-			stores[j].RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), jOp)
+		return stores[i].RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), opts.GetRegionScoreCurve(), iOp) >
+			stores[j].RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), opts.GetRegionScoreCurve(), jOp)
 	})
 
 	pendingFilter := filter.NewRegionPengdingFilter()
@@ -224,9 +228,13 @@ func (s *balanceRegionScheduler) transferPeer(plan *balancePlan) *operator.Opera
 	filters := []filter.Filter{
 		filter.NewExcludedFilter(s.GetName(), nil, plan.region.GetStoreIDs()),
 		filter.NewPlacementSafeguard(s.GetName(), plan.GetOpts(), plan.GetBasicCluster(), plan.GetRuleManager(), plan.region, plan.source),
-		filter.NewRegionScoreFilter(s.GetName(), plan.source, plan.GetOpts()),
 		filter.NewSpecialUseFilter(s.GetName()),
 		&filter.StoreStateFilter{ActionScope: s.GetName(), MoveRegion: true},
+		filter.NewStoreQuotaFilter(s.GetName(), core.RegionKind),
+	}
+	// The store exceeding its soft quota can move regions to the stores with higher scores.
+	if !isOverQuota(plan.GetOpts(), plan.source, core.RegionKind) {
+		filters = append(filters, filter.NewRegionScoreFilter(s.GetName(), plan.source, plan.GetOpts()))
 	}
 
 	candidates := filter.NewCandidates(plan.GetStores()).
@@ -260,6 +268,7 @@ func (s *balanceRegionScheduler) transferPeer(plan *balancePlan) *operator.Opera
 		)
 		op.AdditionalInfos["sourceScore"] = strconv.FormatFloat(plan.sourceScore, 'f', 2, 64)
 		op.AdditionalInfos["targetScore"] = strconv.FormatFloat(plan.targetScore, 'f', 2, 64)
+		op.AddReasons(plan.reason(s.GetName()))
 		return op
 	}
 
@@ -20,6 +20,7 @@ import (
 	"math/rand"
 	"sort"
 	"testing"
+	"time"
 
 	"github.com/docker/go-units"
 	"github.com/pingcap/kvprotov2/pkg/metapb"
@@ -449,6 +450,66 @@ func (suite *balanceLeaderSchedulerTestSuite) TestLeaderWeight() {
 	testutil.CheckTransferLeader(suite.Require(), suite.schedule()[0], operator.OpKind(0), 1, 3)
 }
 
+func (suite *balanceLeaderSchedulerTestSuite) TestLeaderQuota() {
+	// Stores:     1       2       3       4
+	// Leaders:    10      12      14      16
+	// Quota:      8       0       0       0
+	// Region1:    F       F       F       L
+	// Region2:    L       F       F       F
+	suite.tc.SetTolerantSizeRatio(2.5)
+	suite.tc.AddLeaderStore(1, 10)
+	suite.tc.AddLeaderStore(2, 12)
+	suite.tc.AddLeaderStore(3, 14)
+	suite.tc.AddLeaderStore(4, 16)
+	suite.tc.UpdateStoreQuota(1, 8, 0)
+	suite.tc.AddLeaderRegion(1, 4, 1, 2, 3)
+	suite.tc.AddLeaderRegion(2, 1, 2, 3, 4)
+	// The quotas are ignored without the bias.
+	testutil.CheckTransferLeader(suite.Require(), suite.schedule()[0], operator.OpKind(0), 4, 1)
+
+	// Store 1 exceeds its quota and is relieved although it has the fewest leaders.
+	suite.tc.SetEnableStoreQuotaBias(true)
+	testutil.CheckTransferLeader(suite.Require(), suite.schedule()[0], operator.OpKind(0), 1, 2)
+
+	// Store 2 reaches its quota, so it is not selected as the target.
+	suite.tc.UpdateStoreQuota(2, 12, 0)
+	testutil.CheckTransferLeader(suite.Require(), suite.schedule()[0], operator.OpKind(0), 1, 3)
+}
+
+func (suite *balanceLeaderSchedulerTestSuite) TestLatencyAwareLeaderZone() {
+	// Stores:     1       2       3       4
+	// Zone:       z1      z1      z2      z2
+	// Leaders:    10      12      16      15
+	// Region1:    L       F       F       F
+	// Region2:    F       L       F       F
+	// Region3:    F       F       L       F
+	suite.tc.SetTolerantSizeRatio(2.5)
+	for id, zone := range map[uint64]string{1: "z1", 2: "z1", 3: "z2", 4: "z2"} {
+		suite.tc.AddLabelsStore(id, 0, map[string]string{"zone": zone})
+	}
+	suite.tc.UpdateLeaderCount(1, 10)
+	suite.tc.UpdateLeaderCount(2, 12)
+	suite.tc.UpdateLeaderCount(3, 16)
+	suite.tc.UpdateLeaderCount(4, 15)
+	suite.tc.AddLeaderRegion(1, 1, 2, 3, 4)
+	suite.tc.AddLeaderRegion(2, 2, 1, 3, 4)
+	suite.tc.AddLeaderRegion(3, 3, 1, 2, 4)
+	suite.lb.(*balanceLeaderScheduler).conf.ClientZone = "c"
+	// The latencies are unknown, so the leaders are balanced as usual.
+	testutil.CheckTransferLeader(suite.Require(), suite.schedule()[0], operator.OpKind(0), 3, 1)
+
+	// The leaders are moved into z2 which is closer to the client zone.
+	suite.tc.ObserveZoneLatency("c", "z1", 10*time.Millisecond)
+	suite.tc.ObserveZoneLatency("c", "z2", 2*time.Millisecond)
+	testutil.CheckTransferLeader(suite.Require(), suite.schedule()[0], operator.OpKind(0), 2, 4)
+
+	// The client zone has stores itself.
+	suite.tc.AddLabelsStore(5, 0, map[string]string{"zone": "c"})
+	suite.tc.UpdateLeaderCount(5, 20)
+	suite.tc.AddLeaderRegion(4, 4, 1, 2, 5)
+	testutil.CheckTransferLeader(suite.Require(), suite.schedule()[0], operator.OpKind(0), 4, 5)
+}
+
 func (suite *balanceLeaderSchedulerTestSuite) TestBalancePolicy() {
 	// Stores:       1    2     3    4
 	// LeaderCount: 20   66     6   20
@@ -626,6 +687,29 @@ func (suite *balanceLeaderRangeSchedulerTestSuite) TestMultiRangeBalance() {
 	suite.Empty(ops)
 }
 
+func (suite *balanceLeaderRangeSchedulerTestSuite) TestBucketAwareBalance() {
+	// Stores:     1       2       3
+	// Leaders:    10      10      10
+	// Range a-f:  20      0       0
+	for i := uint64(1); i <= 3; i++ {
+		suite.tc.AddLeaderStore(i, 10)
+	}
+	for i := 1; i <= 20; i++ {
+		suite.tc.AddLeaderRegionWithRange(uint64(i), fmt.Sprintf("a%02d", i), fmt.Sprintf("a%02d", i+1), 1, 2, 3)
+	}
+	lb, err := schedule.CreateScheduler(BalanceLeaderType, suite.oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(BalanceLeaderType, []string{"a", "f", "g", "z"}))
+	suite.NoError(err)
+	ops, _ := lb.Schedule(suite.tc, false)
+	suite.Empty(ops)
+
+	lb.(*balanceLeaderScheduler).conf.BucketAware = true
+	ops, _ = lb.Schedule(suite.tc, false)
+	suite.NotEmpty(ops)
+	for _, op := range ops {
+		suite.Equal(uint64(1), op.Step(0).(operator.TransferLeader).FromStore)
+	}
+}
+
 func (suite *balanceLeaderRangeSchedulerTestSuite) TestBatchBalance() {
 	suite.tc.AddLeaderStore(1, 100)
 	suite.tc.AddLeaderStore(2, 0)
@@ -1044,6 +1128,45 @@ func TestBalanceRegionStoreWeight(t *testing.T) {
 	testutil.CheckTransferPeer(re, op, operator.OpKind(0), 1, 3)
 }
 
+func TestBalanceRegionStoreQuota(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(ctx, opt)
+	tc.SetPlacementRuleEnabled(false)
+	tc.SetClusterVersion(versioninfo.MinSupportedVersion(versioninfo.Version4_0))
+	oc := schedule.NewOperatorController(ctx, nil, nil)
+
+	sb, err := schedule.CreateScheduler(BalanceRegionType, oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(BalanceRegionType, []string{"", ""}))
+	re.NoError(err)
+	opt.SetMaxReplicas(1)
+
+	// Store 1 has the fewest regions but exceeds its quota.
+	tc.AddRegionStore(1, 10)
+	tc.AddRegionStore(2, 20)
+	tc.AddRegionStore(3, 30)
+	tc.UpdateStoreQuota(1, 0, 5)
+	tc.AddLeaderRegion(1, 1)
+	tc.AddLeaderRegion(2, 3)
+
+	ops, _ := sb.Schedule(tc, false)
+	re.NotEmpty(ops)
+	testutil.CheckTransferPeer(re, ops[0], operator.OpKind(0), 3, 1)
+
+	tc.SetEnableStoreQuotaBias(true)
+	ops, _ = sb.Schedule(tc, false)
+	re.NotEmpty(ops)
+	testutil.CheckTransferPeer(re, ops[0], operator.OpKind(0), 1, 2)
+
+	// The store reaching its quota is not selected as the target.
+	tc.UpdateStoreQuota(1, 0, 10)
+	ops, _ = sb.Schedule(tc, false)
+	re.NotEmpty(ops)
+	testutil.CheckTransferPeer(re, ops[0], operator.OpKind(0), 3, 2)
+}
+
 func TestBalanceRegionReplacePendingRegion(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -86,6 +86,12 @@ func (s *BaseScheduler) GetNextInterval(interval time.Duration) time.Duration {
 	return intervalGrow(interval, MaxScheduleInterval, exponentialGrowth)
 }
 
+// GetState returns the internal state of the scheduler. The basic scheduler
+// has no state of its own.
+func (s *BaseScheduler) GetState() interface{} {
+	return nil
+}
+
 // Prepare does some prepare work
 func (s *BaseScheduler) Prepare(cluster schedule.Cluster) error { return nil }
 
@@ -329,6 +329,9 @@ func scheduleEvictLeaderOnce(name, typ string, cluster schedule.Cluster, conf ev
 		}
 
 		filters = append(filters, &filter.StoreStateFilter{ActionScope: name, TransferLeader: true})
+		if pinnedFilter := filter.NewPinnedLeaderFilter(name, cluster, region); pinnedFilter != nil {
+			filters = append(filters, pinnedFilter)
+		}
 		candidates := filter.NewCandidates(cluster.GetFollowerStores(region)).
 			FilterTarget(cluster.GetOpts(), filters...)
 		// Compatible with old TiKV transfer leader logic.
@@ -17,6 +17,7 @@ package schedulers
 import (
 	"bytes"
 	"context"
+	"encoding/hex"
 	"testing"
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
@@ -27,6 +28,7 @@ import (
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/labeler"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/storage"
 )
@@ -57,6 +59,39 @@ func TestEvictLeader(t *testing.T) {
 	re.True(ops[0].Step(0).(operator.TransferLeader).IsFinish(tc.MockRegionInfo(1, 2, []uint64{1, 3}, []uint64{}, &metapb.RegionEpoch{ConfVer: 0, Version: 0})))
 }
 
+func TestEvictLeaderWithPinnedLeader(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(ctx, opt)
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderStore(3, 0)
+	tc.AddLeaderRegion(1, 1, 2, 3)
+	region := tc.GetRegion(1)
+	pin := func(storeID string) {
+		re.NoError(tc.GetRegionLabeler().SetLabelRule(&labeler.LabelRule{
+			ID:       "pin",
+			Labels:   []labeler.RegionLabel{{Key: labeler.PinnedLeaderStoreLabel, Value: storeID}},
+			RuleType: labeler.KeyRange,
+			Data:     []interface{}{map[string]interface{}{"start_key": hex.EncodeToString(region.GetStartKey()), "end_key": hex.EncodeToString(region.GetEndKey())}},
+		}))
+	}
+
+	sl, err := schedule.CreateScheduler(EvictLeaderType, schedule.NewOperatorController(ctx, nil, nil), storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(EvictLeaderType, []string{"1"}))
+	re.NoError(err)
+	// The leader is only transferred to the pinned store.
+	pin("3")
+	ops, _ := sl.Schedule(tc, false)
+	testutil.CheckMultiTargetTransferLeader(re, ops[0], operator.OpLeader, 1, []uint64{3})
+	// The leader pinned to the evicted store is kept.
+	pin("1")
+	ops, _ = sl.Schedule(tc, false)
+	re.Empty(ops)
+}
+
 func TestEvictLeaderWithUnhealthyPeer(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
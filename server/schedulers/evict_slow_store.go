@@ -18,6 +18,7 @@ import (
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/log"
+	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/schedule/operator"
@@ -135,13 +136,18 @@ func (s *evictSlowStoreScheduler) Cleanup(cluster schedule.Cluster) {
 }
 
 func (s *evictSlowStoreScheduler) prepareEvictLeader(cluster schedule.Cluster, storeID uint64) error {
-	err := s.conf.setStoreAndPersist(storeID)
-	if err != nil {
+	// Mark the store before persisting, so that a store evicted by another scheduler
+	// is neither persisted nor recovered by this one.
+	if err := cluster.SlowStoreEvicted(storeID); err != nil {
+		return err
+	}
+	if err := s.conf.setStoreAndPersist(storeID); err != nil {
 		log.Info("evict-slow-store-scheduler persist config failed", zap.Uint64("store-id", storeID))
+		s.conf.EvictedStores = []uint64{}
+		cluster.SlowStoreRecovered(storeID)
 		return err
 	}
-
-	return cluster.SlowStoreEvicted(storeID)
+	return nil
 }
 
 func (s *evictSlowStoreScheduler) cleanupEvictLeader(cluster schedule.Cluster) {
@@ -181,7 +187,7 @@ func (s *evictSlowStoreScheduler) Schedule(cluster schedule.Cluster, dryRun bool
 			// slow node next time.
 			log.Info("slow store has been removed",
 				zap.Uint64("store-id", store.GetID()))
-		} else if store.GetSlowScore() <= slowStoreRecoverThreshold {
+		} else if isSlowStoreRecovered(store, cluster.GetOpts()) {
 			log.Info("slow store has been recovered",
 				zap.Uint64("store-id", store.GetID()))
 		} else {
@@ -194,11 +200,12 @@ func (s *evictSlowStoreScheduler) Schedule(cluster schedule.Cluster, dryRun bool
 	var slowStore *core.StoreInfo
 
 	for _, store := range cluster.GetStores() {
-		if store.IsRemoved() {
+		// Skip the stores already evicted by another scheduler.
+		if store.IsRemoved() || store.EvictedAsSlowStore() {
 			continue
 		}
 
-		if (store.IsPreparing() || store.IsServing()) && store.IsSlow() {
+		if (store.IsPreparing() || store.IsServing()) && isSlowStore(store, cluster.GetOpts()) {
 			// Do nothing if there is more than one slow store.
 			if slowStore != nil {
 				return ops, nil
@@ -207,7 +214,7 @@ func (s *evictSlowStoreScheduler) Schedule(cluster schedule.Cluster, dryRun bool
 		}
 	}
 
-	if slowStore == nil || slowStore.GetSlowScore() < slowStoreEvictThreshold {
+	if slowStore == nil || (slowStore.GetSlowTrend() == nil && slowStore.GetSlowScore() < slowStoreEvictThreshold) {
 		return ops, nil
 	}
 
@@ -222,6 +229,24 @@ func (s *evictSlowStoreScheduler) Schedule(cluster schedule.Cluster, dryRun bool
 	return s.schedulerEvictLeader(cluster), nil
 }
 
+// isSlowStore checks if the store is slow by the slow trend reported by the store. The
+// slow score is only used for the stores not reporting the slow trend.
+func isSlowStore(store *core.StoreInfo, opts *config.PersistOptions) bool {
+	if trend := store.GetSlowTrend(); trend != nil {
+		return isTrendingSlow(trend, opts)
+	}
+	return store.IsSlow()
+}
+
+// isSlowStoreRecovered checks if the evicted store is recovered by the slow trend
+// reported by the store, or by the slow score if the store does not report it.
+func isSlowStoreRecovered(store *core.StoreInfo, opts *config.PersistOptions) bool {
+	if trend := store.GetSlowTrend(); trend != nil {
+		return isSlowTrendRecovered(trend, opts)
+	}
+	return store.GetSlowScore() <= slowStoreRecoverThreshold
+}
+
 // newEvictSlowStoreScheduler creates a scheduler that detects and evicts slow stores.
 func newEvictSlowStoreScheduler(opController *schedule.OperatorController, conf *evictSlowStoreSchedulerConfig) schedule.Scheduler {
 	base := NewBaseScheduler(opController)
@@ -121,6 +121,26 @@ func (suite *evictSlowStoreTestSuite) TestEvictSlowStore() {
 	suite.Zero(persistValue.evictStore())
 }
 
+func (suite *evictSlowStoreTestSuite) TestEvictSlowStoreBySlowTrend() {
+	// The slow score is ignored if the store reports the slow trend.
+	storeInfo := suite.tc.GetStore(1)
+	suite.tc.PutStore(storeInfo.Clone(func(store *core.StoreInfo) {
+		store.GetStoreStats().SlowScore = 100
+	}, core.SetSlowTrend(&core.SlowTrend{CauseValue: 20, CauseRate: 0, ResultValue: 100, ResultRate: 0})))
+	ops, _ := suite.es.Schedule(suite.tc, false)
+	suite.Empty(ops)
+
+	suite.tc.PutStore(storeInfo.Clone(core.SetSlowTrend(&core.SlowTrend{CauseValue: 20, CauseRate: 0.1, ResultValue: 100, ResultRate: -1})))
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	testutil.CheckMultiTargetTransferLeader(suite.Require(), ops[0], operator.OpLeader, 1, []uint64{2})
+	suite.True(suite.tc.GetStore(1).EvictedAsSlowStore())
+
+	suite.tc.PutStore(storeInfo.Clone(core.SetSlowTrend(&core.SlowTrend{CauseValue: 1, CauseRate: -0.1, ResultValue: 100, ResultRate: 1})))
+	ops, _ = suite.es.Schedule(suite.tc, false)
+	suite.Empty(ops)
+	suite.False(suite.tc.GetStore(1).EvictedAsSlowStore())
+}
+
 func (suite *evictSlowStoreTestSuite) TestEvictSlowStorePrepare() {
 	es2, ok := suite.es.(*evictSlowStoreScheduler)
 	suite.True(ok)
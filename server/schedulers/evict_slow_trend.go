@@ -0,0 +1,266 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"sync"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/schedule/plan"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"go.uber.org/zap"
+)
+
+const (
+	// EvictSlowTrendName is evict leader by slow trend scheduler name.
+	EvictSlowTrendName = "evict-slow-trend-scheduler"
+	// EvictSlowTrendType is evict leader by slow trend scheduler type.
+	EvictSlowTrendType = "evict-slow-trend"
+)
+
+func init() {
+	schedule.RegisterSliceDecoderBuilder(EvictSlowTrendType, func(args []string) schedule.ConfigDecoder {
+		return func(v interface{}) error {
+			return nil
+		}
+	})
+
+	schedule.RegisterScheduler(EvictSlowTrendType, func(opController *schedule.OperatorController, storage endpoint.ConfigStorage, decoder schedule.ConfigDecoder) (schedule.Scheduler, error) {
+		conf := &evictSlowTrendSchedulerConfig{storage: storage, EvictedStores: make([]uint64, 0)}
+		if err := decoder(conf); err != nil {
+			return nil, err
+		}
+		return newEvictSlowTrendScheduler(opController, conf), nil
+	})
+}
+
+type evictSlowTrendSchedulerConfig struct {
+	storage       endpoint.ConfigStorage
+	EvictedStores []uint64 `json:"evict-stores"`
+}
+
+func (conf *evictSlowTrendSchedulerConfig) Persist() error {
+	data, err := schedule.EncodeConfig(conf)
+	if err != nil {
+		return err
+	}
+	return conf.storage.SaveScheduleConfig(EvictSlowTrendName, data)
+}
+
+func (conf *evictSlowTrendSchedulerConfig) getStores() []uint64 {
+	return conf.EvictedStores
+}
+
+func (conf *evictSlowTrendSchedulerConfig) getKeyRangesByID(id uint64) []core.KeyRange {
+	if conf.evictStore() != id {
+		return nil
+	}
+	return []core.KeyRange{core.NewKeyRange("", "")}
+}
+
+func (conf *evictSlowTrendSchedulerConfig) evictStore() uint64 {
+	if len(conf.EvictedStores) == 0 {
+		return 0
+	}
+	return conf.EvictedStores[0]
+}
+
+func (conf *evictSlowTrendSchedulerConfig) setStoreAndPersist(id uint64) error {
+	conf.EvictedStores = []uint64{id}
+	return conf.Persist()
+}
+
+func (conf *evictSlowTrendSchedulerConfig) clearAndPersist() (oldID uint64, err error) {
+	oldID = conf.evictStore()
+	if oldID > 0 {
+		conf.EvictedStores = []uint64{}
+		err = conf.Persist()
+	}
+	return
+}
+
+// evictSlowTrendScheduler evicts the leaders of a store whose slow trend, reported
+// by the store heartbeats, shows the slow score keeps growing while the query rate
+// is dropping.
+type evictSlowTrendScheduler struct {
+	*BaseScheduler
+	conf *evictSlowTrendSchedulerConfig
+
+	mu     sync.RWMutex
+	trends map[uint64]*core.SlowTrend // the slow trends of the stores at the last schedule
+}
+
+func (s *evictSlowTrendScheduler) GetName() string {
+	return EvictSlowTrendName
+}
+
+func (s *evictSlowTrendScheduler) GetType() string {
+	return EvictSlowTrendType
+}
+
+func (s *evictSlowTrendScheduler) EncodeConfig() ([]byte, error) {
+	return schedule.EncodeConfig(s.conf)
+}
+
+// GetState returns the evicted store and the slow trends of the stores at the last
+// schedule.
+func (s *evictSlowTrendScheduler) GetState() interface{} {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return map[string]interface{}{
+		"evict-store": s.conf.evictStore(),
+		"trends":      s.trends,
+	}
+}
+
+func (s *evictSlowTrendScheduler) Prepare(cluster schedule.Cluster) error {
+	evictStore := s.conf.evictStore()
+	if evictStore != 0 {
+		return cluster.SlowStoreEvicted(evictStore)
+	}
+	return nil
+}
+
+func (s *evictSlowTrendScheduler) Cleanup(cluster schedule.Cluster) {
+	s.cleanupEvictLeader(cluster)
+}
+
+func (s *evictSlowTrendScheduler) prepareEvictLeader(cluster schedule.Cluster, storeID uint64) error {
+	// Mark the store before persisting, so that a store evicted by another scheduler
+	// is neither persisted nor recovered by this one.
+	if err := cluster.SlowStoreEvicted(storeID); err != nil {
+		return err
+	}
+	if err := s.conf.setStoreAndPersist(storeID); err != nil {
+		log.Info("evict-slow-trend-scheduler persist config failed", zap.Uint64("store-id", storeID))
+		s.conf.EvictedStores = []uint64{}
+		cluster.SlowStoreRecovered(storeID)
+		return err
+	}
+	return nil
+}
+
+func (s *evictSlowTrendScheduler) cleanupEvictLeader(cluster schedule.Cluster) {
+	evictSlowStore, err := s.conf.clearAndPersist()
+	if err != nil {
+		log.Info("evict-slow-trend-scheduler persist config failed", zap.Uint64("store-id", evictSlowStore))
+	}
+	if evictSlowStore == 0 {
+		return
+	}
+	cluster.SlowStoreRecovered(evictSlowStore)
+}
+
+func (s *evictSlowTrendScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
+	if s.conf.evictStore() != 0 {
+		allowed := s.OpController.OperatorCount(operator.OpLeader) < cluster.GetOpts().GetLeaderScheduleLimit()
+		if !allowed {
+			operator.OperatorLimitCounter.WithLabelValues(s.GetType(), operator.OpLeader.String()).Inc()
+		}
+		return allowed
+	}
+	return true
+}
+
+func (s *evictSlowTrendScheduler) Schedule(cluster schedule.Cluster, dryRun bool) ([]*operator.Operator, []plan.Plan) {
+	schedulerCounter.WithLabelValues(s.GetName(), "schedule").Inc()
+	opts := cluster.GetOpts()
+	stores := cluster.GetStores()
+	trends := make(map[uint64]*core.SlowTrend, len(stores))
+	for _, store := range stores {
+		if trend := store.GetSlowTrend(); trend != nil {
+			trends[store.GetID()] = trend
+		}
+	}
+	s.mu.Lock()
+	s.trends = trends
+	s.mu.Unlock()
+
+	if evictStore := s.conf.evictStore(); evictStore != 0 {
+		store := cluster.GetStore(evictStore)
+		if store == nil || store.IsRemoved() {
+			log.Info("slow store has been removed",
+				zap.Uint64("store-id", evictStore))
+		} else if isSlowTrendRecovered(store.GetSlowTrend(), opts) {
+			log.Info("slow store has been recovered",
+				zap.Uint64("store-id", evictStore))
+		} else {
+			return scheduleEvictLeaderBatch(s.GetName(), s.GetType(), cluster, s.conf, EvictLeaderBatchSize), nil
+		}
+		s.cleanupEvictLeader(cluster)
+		return nil, nil
+	}
+
+	var slowStore *core.StoreInfo
+	for _, store := range stores {
+		// Skip the stores already evicted by another scheduler.
+		if store.IsRemoved() || store.EvictedAsSlowStore() || !(store.IsPreparing() || store.IsServing()) {
+			continue
+		}
+		if isTrendingSlow(store.GetSlowTrend(), opts) {
+			// Do nothing if there is more than one slow store.
+			if slowStore != nil {
+				return nil, nil
+			}
+			slowStore = store
+		}
+	}
+	if slowStore == nil {
+		return nil, nil
+	}
+
+	trend := slowStore.GetSlowTrend()
+	log.Info("detected slow trend of store, start to evict leaders",
+		zap.Uint64("store-id", slowStore.GetID()),
+		zap.Float64("cause-value", trend.CauseValue),
+		zap.Float64("cause-rate", trend.CauseRate),
+		zap.Float64("result-rate", trend.ResultRate))
+	if err := s.prepareEvictLeader(cluster, slowStore.GetID()); err != nil {
+		log.Info("prepare for evicting leader failed", zap.Error(err), zap.Uint64("store-id", slowStore.GetID()))
+		return nil, nil
+	}
+	return scheduleEvictLeaderBatch(s.GetName(), s.GetType(), cluster, s.conf, EvictLeaderBatchSize), nil
+}
+
+// isTrendingSlow checks if the slow score of the store reaches the threshold and
+// keeps growing, while the query rate served by the store is dropping.
+func isTrendingSlow(trend *core.SlowTrend, opts *config.PersistOptions) bool {
+	return trend != nil &&
+		trend.CauseValue >= opts.GetSlowTrendCauseValueThreshold() &&
+		trend.CauseRate >= opts.GetSlowTrendCauseRateThreshold() &&
+		trend.ResultRate < 0
+}
+
+// isSlowTrendRecovered checks if the slow score of the evicted store drops below the
+// threshold and stops growing. The store is not recovered if its slow trend is unknown,
+// e.g. no heartbeat is received after the PD leader changes.
+func isSlowTrendRecovered(trend *core.SlowTrend, opts *config.PersistOptions) bool {
+	return trend != nil &&
+		trend.CauseValue < opts.GetSlowTrendCauseValueThreshold() &&
+		trend.CauseRate <= 0
+}
+
+// newEvictSlowTrendScheduler creates a scheduler that detects the stores trending
+// slow and evicts their leaders.
+func newEvictSlowTrendScheduler(opController *schedule.OperatorController, conf *evictSlowTrendSchedulerConfig) schedule.Scheduler {
+	return &evictSlowTrendScheduler{
+		BaseScheduler: NewBaseScheduler(opController),
+		conf:          conf,
+	}
+}
@@ -0,0 +1,103 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package schedulers
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/mock/mockcluster"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/operator"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestEvictSlowTrend(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	tc := mockcluster.NewCluster(ctx, config.NewTestOptions())
+	tc.AddLeaderStore(1, 0)
+	tc.AddLeaderStore(2, 0)
+	tc.AddLeaderStore(3, 0)
+	tc.AddLeaderRegion(1, 1, 2)
+	tc.AddLeaderRegion(2, 2, 1)
+	tc.UpdateLeaderCount(2, 16)
+	setTrend := func(storeID uint64, trend *core.SlowTrend) {
+		tc.PutStore(tc.GetStore(storeID).Clone(core.SetSlowTrend(trend)))
+	}
+
+	oc := schedule.NewOperatorController(ctx, nil, nil)
+	es, err := schedule.CreateScheduler(EvictSlowTrendType, oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(EvictSlowTrendType, nil))
+	re.NoError(err)
+	conf := es.(*evictSlowTrendScheduler).conf
+
+	// The slow score grows, but the query rate does not drop.
+	setTrend(1, &core.SlowTrend{CauseValue: 20, CauseRate: 0.1, ResultValue: 100, ResultRate: 0})
+	ops, _ := es.Schedule(tc, false)
+	re.Empty(ops)
+	// The slow score is below the threshold.
+	setTrend(1, &core.SlowTrend{CauseValue: 5, CauseRate: 0.1, ResultValue: 100, ResultRate: -1})
+	ops, _ = es.Schedule(tc, false)
+	re.Empty(ops)
+	// Do nothing if there is more than one store trending slow.
+	setTrend(1, &core.SlowTrend{CauseValue: 20, CauseRate: 0.1, ResultValue: 100, ResultRate: -1})
+	setTrend(2, &core.SlowTrend{CauseValue: 20, CauseRate: 0.1, ResultValue: 100, ResultRate: -1})
+	ops, _ = es.Schedule(tc, false)
+	re.Empty(ops)
+
+	// Evict the leaders of store 1.
+	setTrend(2, nil)
+	ops, _ = es.Schedule(tc, false)
+	re.Len(ops, 1)
+	testutil.CheckMultiTargetTransferLeader(re, ops[0], operator.OpLeader, 1, []uint64{2})
+	re.Equal(EvictSlowTrendType, ops[0].Desc())
+	re.Equal(uint64(1), conf.evictStore())
+	re.True(tc.GetStore(1).EvictedAsSlowStore())
+	state := es.(schedule.SchedulerState).GetState().(map[string]interface{})
+	re.Equal(uint64(1), state["evict-store"])
+	re.Len(state["trends"], 1)
+
+	// The slow score stops growing but is still high, keep evicting.
+	setTrend(1, &core.SlowTrend{CauseValue: 20, CauseRate: 0, ResultValue: 80, ResultRate: 0})
+	ops, _ = es.Schedule(tc, false)
+	re.Len(ops, 1)
+	// The store recovers.
+	setTrend(1, &core.SlowTrend{CauseValue: 1, CauseRate: -0.1, ResultValue: 100, ResultRate: 1})
+	ops, _ = es.Schedule(tc, false)
+	re.Empty(ops)
+	re.Zero(conf.evictStore())
+	re.False(tc.GetStore(1).EvictedAsSlowStore())
+
+	// Skip the store already evicted by the evict-slow-store-scheduler.
+	ss, err := schedule.CreateScheduler(EvictSlowStoreType, oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(EvictSlowStoreType, nil))
+	re.NoError(err)
+	setTrend(1, &core.SlowTrend{CauseValue: 20, CauseRate: 0.1, ResultValue: 100, ResultRate: -1})
+	ops, _ = ss.Schedule(tc, false)
+	re.Len(ops, 1)
+	re.Equal(EvictSlowStoreType, ops[0].Desc())
+	ops, _ = es.Schedule(tc, false)
+	re.Empty(ops)
+	re.Zero(conf.evictStore())
+	// The cleanup does not clear the eviction of the other scheduler.
+	es.Cleanup(tc)
+	re.True(tc.GetStore(1).EvictedAsSlowStore())
+	ss.Cleanup(tc)
+	re.False(tc.GetStore(1).EvictedAsSlowStore())
+}
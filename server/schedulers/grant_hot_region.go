@@ -359,6 +359,9 @@ func (s *grantHotRegionScheduler) transfer(cluster schedule.Cluster, regionID ui
 	var candidate []uint64
 	if isLeader {
 		filters = append(filters, &filter.StoreStateFilter{ActionScope: s.GetName(), TransferLeader: true})
+		if pinnedFilter := filter.NewPinnedLeaderFilter(s.GetName(), cluster, srcRegion); pinnedFilter != nil {
+			filters = append(filters, pinnedFilter)
+		}
 		candidate = []uint64{s.conf.GetStoreLeaderID()}
 	} else {
 		filters = append(filters, &filter.StoreStateFilter{ActionScope: s.GetName(), MoveRegion: true},
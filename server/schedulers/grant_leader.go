@@ -245,6 +245,12 @@ func (s *grantLeaderScheduler) Schedule(cluster schedule.Cluster, dryRun bool) (
 			schedulerCounter.WithLabelValues(s.GetName(), "no-follower").Inc()
 			continue
 		}
+		if pinnedFilter := filter.NewPinnedLeaderFilter(s.GetName(), cluster, region); pinnedFilter != nil {
+			if store := cluster.GetStore(id); store == nil || !filter.Target(cluster.GetOpts(), store, []filter.Filter{pinnedFilter}) {
+				schedulerCounter.WithLabelValues(s.GetName(), "pinned-leader").Inc()
+				continue
+			}
+		}
 
 		op, err := operator.CreateForceTransferLeaderOperator(GrantLeaderType, cluster, region, region.GetLeader().GetStoreId(), id, operator.OpLeader)
 		if err != nil {
@@ -105,7 +105,11 @@ type hotScheduler struct {
 	stLoadInfos [resourceTypeLen]map[uint64]*statistics.StoreLoadDetail
 
 	// config of hot scheduler
-	conf                *hotRegionSchedulerConfig
+	conf *hotRegionSchedulerConfig
+	// roundConf is the snapshot of conf used by the current scheduling round.
+	roundConf *hotRegionSchedulerConfig
+	// activeConfVersion is the config version used by the latest scheduling round.
+	activeConfVersion   uint64
 	searchRevertRegions [resourceTypeLen]bool // Whether to search revert regions.
 }
 
@@ -153,6 +157,40 @@ func (h *hotScheduler) IsScheduleAllowed(cluster schedule.Cluster) bool {
 	return allowed
 }
 
+// hotPendingInfluence is the JSON view of a pending influence.
+type hotPendingInfluence struct {
+	RegionID   uint64    `json:"region-id"`
+	From       uint64    `json:"from"`
+	To         uint64    `json:"to"`
+	Loads      []float64 `json:"loads"`
+	Count      float64   `json:"count"`
+	CreateTime time.Time `json:"create-time"`
+}
+
+// GetState returns the pending influences of the operators created by the
+// scheduler which have not been garbage collected yet.
+func (h *hotScheduler) GetState() interface{} {
+	h.RLock()
+	defer h.RUnlock()
+	pendings := make([]hotPendingInfluence, 0, len(h.regionPendings))
+	for id, p := range h.regionPendings {
+		pendings = append(pendings, hotPendingInfluence{
+			RegionID:   id,
+			From:       p.from,
+			To:         p.to,
+			Loads:      append([]float64(nil), p.origin.Loads...),
+			Count:      p.origin.Count,
+			CreateTime: p.op.GetCreateTime(),
+		})
+	}
+	sort.Slice(pendings, func(i, j int) bool { return pendings[i].RegionID < pendings[j].RegionID })
+	return map[string]interface{}{
+		"pending-influence":   pendings,
+		"active-conf-version": h.activeConfVersion,
+		"latest-conf-version": h.conf.GetVersion(),
+	}
+}
+
 func (h *hotScheduler) Schedule(cluster schedule.Cluster, dryRun bool) ([]*operator.Operator, []plan.Plan) {
 	schedulerCounter.WithLabelValues(h.GetName(), "schedule").Inc()
 	return h.dispatch(h.types[h.r.Int()%len(h.types)], cluster), nil
@@ -162,9 +200,14 @@ func (h *hotScheduler) dispatch(typ statistics.RWType, cluster schedule.Cluster)
 	h.Lock()
 	defer h.Unlock()
 
+	// take a snapshot of the config so that the whole round sees the same version.
+	h.roundConf = h.conf.snapshot()
+	h.activeConfVersion = h.roundConf.Version
+	defer func() { h.roundConf = nil }()
+
 	h.prepareForBalance(typ, cluster)
 	// it can not move earlier to support to use api and metrics.
-	if h.conf.IsForbidRWType(typ) {
+	if h.roundConf.IsForbidRWType(typ) {
 		return nil
 	}
 
@@ -397,6 +440,7 @@ func (s *solution) isAvailable() bool {
 type balanceSolver struct {
 	schedule.Cluster
 	sche         *hotScheduler
+	conf         *hotRegionSchedulerConfig
 	stLoadDetail map[uint64]*statistics.StoreLoadDetail
 	rwTy         statistics.RWType
 	opTy         opType
@@ -446,25 +490,25 @@ func (bs *balanceSolver) init() {
 	}
 
 	rankStepRatios := []float64{
-		statistics.ByteDim:  bs.sche.conf.GetByteRankStepRatio(),
-		statistics.KeyDim:   bs.sche.conf.GetKeyRankStepRatio(),
-		statistics.QueryDim: bs.sche.conf.GetQueryRateRankStepRatio()}
+		statistics.ByteDim:  bs.conf.GetByteRankStepRatio(),
+		statistics.KeyDim:   bs.conf.GetKeyRankStepRatio(),
+		statistics.QueryDim: bs.conf.GetQueryRateRankStepRatio()}
 	stepLoads := make([]float64, statistics.DimLen)
 	for i := range stepLoads {
 		stepLoads[i] = maxCur.Loads[i] * rankStepRatios[i]
 	}
 	bs.rankStep = &statistics.StoreLoad{
 		Loads: stepLoads,
-		Count: maxCur.Count * bs.sche.conf.GetCountRankStepRatio(),
+		Count: maxCur.Count * bs.conf.GetCountRankStepRatio(),
 	}
 
 	bs.firstPriority, bs.secondPriority = prioritiesToDim(bs.getPriorities())
-	bs.greatDecRatio, bs.minorDecRatio = bs.sche.conf.GetGreatDecRatio(), bs.sche.conf.GetMinorDecRatio()
-	bs.maxPeerNum = bs.sche.conf.GetMaxPeerNumber()
+	bs.greatDecRatio, bs.minorDecRatio = bs.conf.GetGreatDecRatio(), bs.conf.GetMinorDecRatio()
+	bs.maxPeerNum = bs.conf.GetMaxPeerNumber()
 	bs.minHotDegree = bs.GetOpts().GetHotRegionCacheHitsThreshold()
 
 	bs.pick = slice.AnyOf
-	if bs.sche.conf.IsStrictPickingStoreEnabled() {
+	if bs.conf.IsStrictPickingStoreEnabled() {
 		bs.pick = slice.AllOf
 	}
 }
@@ -479,20 +523,25 @@ func (bs *balanceSolver) getPriorities() []string {
 	// For write, they are different
 	switch bs.resourceTy {
 	case readLeader, readPeer:
-		return adjustConfig(querySupport, bs.sche.conf.GetReadPriorities(), getReadPriorities)
+		return adjustConfig(querySupport, bs.conf.GetReadPriorities(), getReadPriorities)
 	case writeLeader:
-		return adjustConfig(querySupport, bs.sche.conf.GetWriteLeaderPriorities(), getWriteLeaderPriorities)
+		return adjustConfig(querySupport, bs.conf.GetWriteLeaderPriorities(), getWriteLeaderPriorities)
 	case writePeer:
-		return adjustConfig(querySupport, bs.sche.conf.GetWritePeerPriorities(), getWritePeerPriorities)
+		return adjustConfig(querySupport, bs.conf.GetWritePeerPriorities(), getWritePeerPriorities)
 	}
 	log.Error("illegal type or illegal operator while getting the priority", zap.String("type", bs.rwTy.String()), zap.String("operator", bs.opTy.String()))
 	return []string{}
 }
 
 func newBalanceSolver(sche *hotScheduler, cluster schedule.Cluster, rwTy statistics.RWType, opTy opType) *balanceSolver {
+	conf := sche.roundConf
+	if conf == nil {
+		conf = sche.conf.snapshot()
+	}
 	solver := &balanceSolver{
 		Cluster: cluster,
 		sche:    sche,
+		conf:    conf,
 		rwTy:    rwTy,
 		opTy:    opTy,
 	}
@@ -532,7 +581,7 @@ func (bs *balanceSolver) solve() []*operator.Operator {
 	}
 
 	// Whether to allow move region peer from dstStore to srcStore
-	searchRevertRegions := bs.sche.searchRevertRegions[bs.resourceTy] && !bs.sche.conf.IsStrictPickingStoreEnabled()
+	searchRevertRegions := bs.sche.searchRevertRegions[bs.resourceTy] && !bs.conf.IsStrictPickingStoreEnabled()
 	var allowRevertRegion func(region *core.RegionInfo, srcStoreID uint64) bool
 	if bs.opTy == transferLeader {
 		allowRevertRegion = func(region *core.RegionInfo, srcStoreID uint64) bool {
@@ -623,15 +672,15 @@ func (bs *balanceSolver) tryAddPendingInfluence() bool {
 	var maxZombieDur time.Duration
 	switch bs.resourceTy {
 	case writeLeader:
-		maxZombieDur = bs.sche.conf.GetRegionsStatZombieDuration()
+		maxZombieDur = bs.conf.GetRegionsStatZombieDuration()
 	case writePeer:
 		if bs.best.srcStore.IsTiFlash() {
-			maxZombieDur = bs.sche.conf.GetRegionsStatZombieDuration()
+			maxZombieDur = bs.conf.GetRegionsStatZombieDuration()
 		} else {
-			maxZombieDur = bs.sche.conf.GetStoreStatZombieDuration()
+			maxZombieDur = bs.conf.GetStoreStatZombieDuration()
 		}
 	default:
-		maxZombieDur = bs.sche.conf.GetStoreStatZombieDuration()
+		maxZombieDur = bs.conf.GetStoreStatZombieDuration()
 	}
 
 	// TODO: Process operators atomically.
@@ -657,8 +706,8 @@ func (bs *balanceSolver) tryAddPendingInfluence() bool {
 // its expectation * ratio, the store would be selected as hot source store
 func (bs *balanceSolver) filterSrcStores() map[uint64]*statistics.StoreLoadDetail {
 	ret := make(map[uint64]*statistics.StoreLoadDetail)
-	confSrcToleranceRatio := bs.sche.conf.GetSrcToleranceRatio()
-	confEnableForTiFlash := bs.sche.conf.GetEnableForTiFlash()
+	confSrcToleranceRatio := bs.conf.GetSrcToleranceRatio()
+	confEnableForTiFlash := bs.conf.GetEnableForTiFlash()
 	for id, detail := range bs.stLoadDetail {
 		srcToleranceRatio := confSrcToleranceRatio
 		if detail.IsTiFlash() {
@@ -845,6 +894,9 @@ func (bs *balanceSolver) filterDstStores() map[uint64]*statistics.StoreLoadDetai
 		if leaderFilter := filter.NewPlacementLeaderSafeguard(bs.sche.GetName(), bs.GetOpts(), bs.GetBasicCluster(), bs.GetRuleManager(), bs.cur.region, srcStore); leaderFilter != nil {
 			filters = append(filters, leaderFilter)
 		}
+		if pinnedFilter := filter.NewPinnedLeaderFilter(bs.sche.GetName(), bs.Cluster, bs.cur.region); pinnedFilter != nil {
+			filters = append(filters, pinnedFilter)
+		}
 
 		for _, peer := range bs.cur.region.GetFollowers() {
 			if detail, ok := bs.stLoadDetail[peer.GetStoreId()]; ok {
@@ -860,11 +912,17 @@ func (bs *balanceSolver) filterDstStores() map[uint64]*statistics.StoreLoadDetai
 
 func (bs *balanceSolver) pickDstStores(filters []filter.Filter, candidates []*statistics.StoreLoadDetail) map[uint64]*statistics.StoreLoadDetail {
 	ret := make(map[uint64]*statistics.StoreLoadDetail, len(candidates))
-	confDstToleranceRatio := bs.sche.conf.GetDstToleranceRatio()
-	confEnableForTiFlash := bs.sche.conf.GetEnableForTiFlash()
+	confDstToleranceRatio := bs.conf.GetDstToleranceRatio()
+	confEnableForTiFlash := bs.conf.GetEnableForTiFlash()
+	srcZone := bs.getSrcZone()
+	sameZone := make(map[uint64]*statistics.StoreLoadDetail, len(candidates))
 	for _, detail := range candidates {
 		store := detail.StoreInfo
 		dstToleranceRatio := confDstToleranceRatio
+		inSameZone := srcZone != "" && store.GetLabelValue(bs.conf.GetZoneLabel()) == srcZone
+		if srcZone != "" && !inSameZone {
+			dstToleranceRatio += bs.conf.GetCrossZonePenalty()
+		}
 		if detail.IsTiFlash() {
 			if !confEnableForTiFlash {
 				continue
@@ -878,15 +936,36 @@ func (bs *balanceSolver) pickDstStores(filters []filter.Filter, candidates []*st
 			id := store.GetID()
 			if bs.checkDstByPriorityAndTolerance(detail.LoadPred.Max(), &detail.LoadPred.Expect, dstToleranceRatio) {
 				ret[id] = detail
+				if inSameZone {
+					sameZone[id] = detail
+				}
 				hotSchedulerResultCounter.WithLabelValues("dst-store-succ", strconv.FormatUint(id, 10)).Inc()
 			} else {
 				hotSchedulerResultCounter.WithLabelValues("dst-store-failed", strconv.FormatUint(id, 10)).Inc()
 			}
 		}
 	}
+	// Prefer to keep the hot peer in the same zone if there is a viable target,
+	// otherwise fall back to the stores in other zones with the penalty.
+	if len(sameZone) > 0 {
+		return sameZone
+	}
 	return ret
 }
 
+// getSrcZone returns the zone of the source store if the zone-aware preference
+// takes effect, otherwise it returns an empty string.
+func (bs *balanceSolver) getSrcZone() string {
+	if bs.opTy != movePeer {
+		return ""
+	}
+	zoneLabel := bs.conf.GetZoneLabel()
+	if zoneLabel == "" {
+		return ""
+	}
+	return bs.cur.srcStore.GetLabelValue(zoneLabel)
+}
+
 func (bs *balanceSolver) checkDstByPriorityAndTolerance(maxLoad, expect *statistics.StoreLoad, toleranceRatio float64) bool {
 	return bs.pick(maxLoad.Loads, func(i int) bool {
 		if bs.isSelectedDim(i) {
@@ -1001,11 +1080,11 @@ func (bs *balanceSolver) isNotWorsened(dim int) bool {
 func (bs *balanceSolver) getMinRate(dim int) float64 {
 	switch dim {
 	case statistics.KeyDim:
-		return bs.sche.conf.GetMinHotKeyRate()
+		return bs.conf.GetMinHotKeyRate()
 	case statistics.ByteDim:
-		return bs.sche.conf.GetMinHotByteRate()
+		return bs.conf.GetMinHotByteRate()
 	case statistics.QueryDim:
-		return bs.sche.conf.GetMinHotQueryRate()
+		return bs.conf.GetMinHotQueryRate()
 	}
 	return -1
 }
@@ -20,6 +20,7 @@ import (
 	"errors"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/gorilla/mux"
@@ -47,6 +48,9 @@ const (
 	// Scheduling has a bigger impact on TiFlash, so it needs to be corrected in configuration items
 	// In the default config, the TiKV difference is 1.05*1.05-1 = 0.1025, and the TiFlash difference is 1.15*1.15-1 = 0.3225
 	tiflashToleranceRatioCorrection = 0.1
+
+	// hotConfigVersionHeader is the response header which carries the config version after an update.
+	hotConfigVersionHeader = "PD-Config-Version"
 )
 
 var defaultConfig = prioritiesConfig{
@@ -81,6 +85,7 @@ func initHotRegionScheduleConfig() *hotRegionSchedulerConfig {
 		StrictPickingStore:     true,
 		EnableForTiFlash:       true,
 		ForbidRWType:           "none",
+		CrossZonePenalty:       0.1,
 	}
 	cfg.apply(defaultConfig)
 	return cfg
@@ -106,9 +111,34 @@ func (conf *hotRegionSchedulerConfig) getValidConf() *hotRegionSchedulerConfig {
 		WritePeerPriorities:    adjustConfig(conf.lastQuerySupported, conf.WritePeerPriorities, getWritePeerPriorities),
 		StrictPickingStore:     conf.StrictPickingStore,
 		EnableForTiFlash:       conf.EnableForTiFlash,
+		ZoneLabel:              conf.ZoneLabel,
+		CrossZonePenalty:       conf.CrossZonePenalty,
+		Version:                conf.Version,
 	}
 }
 
+// snapshot returns a copy of the config which is used during a whole
+// scheduling round, so that the updates via HTTP only take effect from the
+// next round.
+func (conf *hotRegionSchedulerConfig) snapshot() *hotRegionSchedulerConfig {
+	conf.RLock()
+	defer conf.RUnlock()
+	c := conf.getValidConf()
+	c.lastQuerySupported = conf.lastQuerySupported
+	c.ReadPriorities = append(conf.ReadPriorities[:0:0], conf.ReadPriorities...)
+	c.WriteLeaderPriorities = append(conf.WriteLeaderPriorities[:0:0], conf.WriteLeaderPriorities...)
+	c.WritePeerPriorities = append(conf.WritePeerPriorities[:0:0], conf.WritePeerPriorities...)
+	c.ForbidRWType = conf.ForbidRWType
+	return c
+}
+
+// GetVersion returns the version of the config, which is increased every time the config is changed.
+func (conf *hotRegionSchedulerConfig) GetVersion() uint64 {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.Version
+}
+
 type hotRegionSchedulerConfig struct {
 	syncutil.RWMutex
 	storage            endpoint.ConfigStorage
@@ -139,8 +169,16 @@ type hotRegionSchedulerConfig struct {
 
 	// Separately control whether to start hotspot scheduling for TiFlash
 	EnableForTiFlash bool `json:"enable-for-tiflash,string"`
+	// ZoneLabel is the location label used to keep hot peer moves within the same zone.
+	// Empty means the preference is disabled.
+	ZoneLabel string `json:"zone-label"`
+	// CrossZonePenalty is added to the dst tolerance ratio of the stores in other zones
+	// when no viable target is found in the same zone as the source store.
+	CrossZonePenalty float64 `json:"cross-zone-penalty"`
 	// forbid read or write scheduler, only for test
 	ForbidRWType string `json:"forbid-rw-type,omitempty"`
+	// Version is increased every time the config is changed. It can not be set via HTTP.
+	Version uint64 `json:"version"`
 }
 
 func (conf *hotRegionSchedulerConfig) EncodeConfig() ([]byte, error) {
@@ -251,6 +289,18 @@ func (conf *hotRegionSchedulerConfig) SetEnableForTiFlash(enable bool) {
 	conf.EnableForTiFlash = enable
 }
 
+func (conf *hotRegionSchedulerConfig) GetZoneLabel() string {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.ZoneLabel
+}
+
+func (conf *hotRegionSchedulerConfig) GetCrossZonePenalty() float64 {
+	conf.RLock()
+	defer conf.RUnlock()
+	return conf.CrossZonePenalty
+}
+
 func (conf *hotRegionSchedulerConfig) GetMinHotQueryRate() float64 {
 	conf.RLock()
 	defer conf.RUnlock()
@@ -301,6 +351,16 @@ func (conf *hotRegionSchedulerConfig) handleGetConfig(w http.ResponseWriter, r *
 	rd.JSON(w, http.StatusOK, conf.getValidConf())
 }
 
+func (conf *hotRegionSchedulerConfig) valid() error {
+	if err := conf.validPriority(); err != nil {
+		return err
+	}
+	if conf.CrossZonePenalty < 0 {
+		return errors.New("cross-zone-penalty should not be negative")
+	}
+	return nil
+}
+
 func (conf *hotRegionSchedulerConfig) validPriority() error {
 	isValid := func(priorities []string) (map[string]bool, error) {
 		priorityMap := map[string]bool{}
@@ -338,6 +398,7 @@ func (conf *hotRegionSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *
 	defer conf.Unlock()
 	rd := render.New(render.Options{IndentJSON: true})
 	oldc, _ := json.Marshal(conf)
+	oldVersion := conf.Version
 	data, err := io.ReadAll(r.Body)
 	r.Body.Close()
 	if err != nil {
@@ -349,7 +410,8 @@ func (conf *hotRegionSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *
 		rd.JSON(w, http.StatusInternalServerError, err.Error())
 		return
 	}
-	if err := conf.validPriority(); err != nil {
+	conf.Version = oldVersion
+	if err := conf.valid(); err != nil {
 		// revert to old version
 		if err2 := json.Unmarshal(oldc, conf); err2 != nil {
 			rd.JSON(w, http.StatusInternalServerError, err2.Error())
@@ -360,8 +422,11 @@ func (conf *hotRegionSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *
 	}
 	newc, _ := json.Marshal(conf)
 	if !bytes.Equal(oldc, newc) {
+		conf.Version++
 		conf.persistLocked()
+		w.Header().Set(hotConfigVersionHeader, strconv.FormatUint(conf.Version, 10))
 		rd.Text(w, http.StatusOK, "success")
+		return
 	}
 
 	m := make(map[string]interface{})
@@ -371,6 +436,7 @@ func (conf *hotRegionSchedulerConfig) handleSetConfig(w http.ResponseWriter, r *
 	}
 	ok := reflectutil.FindSameFieldByJSON(conf, m)
 	if ok {
+		w.Header().Set(hotConfigVersionHeader, strconv.FormatUint(conf.Version, 10))
 		rd.Text(w, http.StatusOK, "no changed")
 		return
 	}
@@ -18,6 +18,9 @@ import (
 	"context"
 	"encoding/hex"
 	"math"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
 	"time"
 
@@ -641,6 +644,59 @@ func TestHotWriteRegionScheduleWithKeyRate(t *testing.T) {
 	}
 }
 
+func TestHotWriteRegionScheduleWithZonePreference(t *testing.T) {
+	re := require.New(t)
+	statistics.Denoising = false
+
+	checkZonePreference := func(store5Rate, penalty float64, expectDst uint64) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		opt := config.NewTestOptions()
+		hb, err := schedule.CreateScheduler(statistics.Write.String(), schedule.NewOperatorController(ctx, nil, nil), storage.NewStorageWithMemoryBackend(), nil)
+		re.NoError(err)
+		hb.(*hotScheduler).conf.SetDstToleranceRatio(1)
+		hb.(*hotScheduler).conf.SetSrcToleranceRatio(1)
+		hb.(*hotScheduler).conf.ZoneLabel = "zone"
+		hb.(*hotScheduler).conf.CrossZonePenalty = penalty
+
+		tc := mockcluster.NewCluster(ctx, opt)
+		tc.SetHotRegionCacheHitsThreshold(0)
+		tc.AddLabelsStore(1, 20, map[string]string{"zone": "z1"})
+		tc.AddLabelsStore(2, 20, map[string]string{"zone": "z2"})
+		tc.AddLabelsStore(3, 20, map[string]string{"zone": "z3"})
+		tc.AddLabelsStore(4, 20, map[string]string{"zone": "z2"})
+		tc.AddLabelsStore(5, 20, map[string]string{"zone": "z1"})
+
+		rates := map[uint64]float64{1: 16, 2: 6, 3: 6, 4: 1, 5: store5Rate}
+		for id, rate := range rates {
+			tc.UpdateStorageWrittenStats(id, rate*units.MiB*statistics.StoreHeartBeatReportInterval, rate*units.MiB*statistics.StoreHeartBeatReportInterval)
+		}
+		addRegionInfo(tc, statistics.Write, []testRegionInfo{
+			{1, []uint64{1, 2, 3}, 0.5 * units.MiB, 0.5 * units.MiB, 0},
+			{2, []uint64{1, 2, 3}, 0.5 * units.MiB, 0.5 * units.MiB, 0},
+			{3, []uint64{1, 2, 3}, 0.5 * units.MiB, 0.5 * units.MiB, 0},
+		})
+
+		ops, _ := hb.Schedule(tc, false)
+		if expectDst == 0 {
+			// only the leader is allowed to be transferred.
+			for _, op := range ops {
+				re.Zero(op.Kind() & operator.OpRegion)
+			}
+			return
+		}
+		re.Len(ops, 1)
+		testutil.CheckTransferPeer(re, ops[0], operator.OpHotRegion, 1, expectDst)
+	}
+
+	// store 5 is in the same zone as store 1, so it is preferred even if store 4 has less load.
+	checkZonePreference(4, 0.1, 5)
+	// store 5 is too hot to be the target, fall back to store 4 in another zone.
+	checkZonePreference(9, 0.1, 4)
+	// the penalty makes the cross-zone store 4 unqualified.
+	checkZonePreference(9, 10, 0)
+}
+
 func TestHotWriteRegionScheduleUnhealthyStore(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -2363,6 +2419,62 @@ func TestCompatibilityConfig(t *testing.T) {
 	})
 }
 
+func TestHotConfigVersion(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(ctx, opt)
+	storage := storage.NewStorageWithMemoryBackend()
+	sche, err := schedule.CreateScheduler(HotRegionType, schedule.NewOperatorController(ctx, tc, nil), storage, schedule.ConfigJSONDecoder([]byte("null")))
+	re.NoError(err)
+	hb := sche.(*hotScheduler)
+	re.Equal(uint64(0), hb.conf.GetVersion())
+
+	setConfig := func(body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/config", strings.NewReader(body))
+		rec := httptest.NewRecorder()
+		hb.ServeHTTP(rec, req)
+		return rec
+	}
+
+	// the version is increased and returned after the config is changed.
+	rec := setConfig(`{"src-tolerance-ratio": 1.1}`)
+	re.Equal(http.StatusOK, rec.Code)
+	re.Equal("1", rec.Header().Get(hotConfigVersionHeader))
+	re.Equal(uint64(1), hb.conf.GetVersion())
+	// the version is kept if nothing is changed.
+	rec = setConfig(`{"src-tolerance-ratio": 1.1}`)
+	re.Equal(http.StatusOK, rec.Code)
+	re.Equal("1", rec.Header().Get(hotConfigVersionHeader))
+	// the version can not be set directly.
+	setConfig(`{"version": 100}`)
+	re.Equal(uint64(1), hb.conf.GetVersion())
+	// the version is not changed by an invalid config.
+	rec = setConfig(`{"read-priorities": ["byte"]}`)
+	re.Equal(http.StatusBadRequest, rec.Code)
+	re.Equal(uint64(1), hb.conf.GetVersion())
+	// the version is persisted.
+	_, data, err := storage.LoadAllScheduleConfig()
+	re.NoError(err)
+	re.Len(data, 1)
+	re.Contains(data[0], `"version":1`)
+
+	// the snapshot of a round is not affected by the following updates.
+	hb.roundConf = hb.conf.snapshot()
+	solver := newBalanceSolver(hb, tc, statistics.Read, transferLeader)
+	setConfig(`{"src-tolerance-ratio": 1.2}`)
+	re.Equal(uint64(2), hb.conf.GetVersion())
+	re.Equal(1.1, solver.conf.GetSrcToleranceRatio())
+	re.Equal(uint64(1), solver.conf.GetVersion())
+	hb.roundConf = nil
+
+	hb.Schedule(tc, false)
+	state := hb.GetState().(map[string]interface{})
+	re.Equal(uint64(2), state["active-conf-version"])
+	re.Equal(uint64(2), state["latest-conf-version"])
+}
+
 func TestConfigValidation(t *testing.T) {
 	re := require.New(t)
 	hc := initHotRegionScheduleConfig()
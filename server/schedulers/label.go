@@ -124,10 +124,16 @@ func (s *labelScheduler) Schedule(cluster schedule.Cluster, dryRun bool) ([]*ope
 			for _, p := range region.GetPendingPeers() {
 				excludeStores[p.GetStoreId()] = struct{}{}
 			}
-			f := filter.NewExcludedFilter(s.GetName(), nil, excludeStores)
+			filters := []filter.Filter{
+				&filter.StoreStateFilter{ActionScope: LabelName, TransferLeader: true},
+				filter.NewExcludedFilter(s.GetName(), nil, excludeStores),
+			}
+			if pinnedFilter := filter.NewPinnedLeaderFilter(s.GetName(), cluster, region); pinnedFilter != nil {
+				filters = append(filters, pinnedFilter)
+			}
 
 			target := filter.NewCandidates(cluster.GetFollowerStores(region)).
-				FilterTarget(cluster.GetOpts(), &filter.StoreStateFilter{ActionScope: LabelName, TransferLeader: true}, f).
+				FilterTarget(cluster.GetOpts(), filters...).
 				RandomPick()
 			if target == nil {
 				log.Debug("label scheduler no target found for region", zap.Uint64("region-id", region.GetID()))
@@ -56,6 +56,14 @@ var balanceLeaderCounter = prometheus.NewCounterVec(
 		Help:      "Counter of balance leader scheduler.",
 	}, []string{"type", "store"})
 
+var learnerSkewGauge = prometheus.NewGaugeVec(
+	prometheus.GaugeOpts{
+		Namespace: "pd",
+		Subsystem: "scheduler",
+		Name:      "learner_skew",
+		Help:      "The difference between the max and min learner count of the stores with the same engine.",
+	}, []string{"engine"})
+
 var balanceRegionCounter = prometheus.NewCounterVec(
 	prometheus.CounterOpts{
 		Namespace: "pd",
@@ -125,4 +133,5 @@ func init() {
 	prometheus.MustRegister(opInfluenceStatus)
 	prometheus.MustRegister(tolerantResourceStatus)
 	prometheus.MustRegister(hotPendingStatus)
+	prometheus.MustRegister(learnerSkewGauge)
 }
@@ -26,6 +26,7 @@ import (
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/plan"
 	"github.com/tikv/pd/server/storage/endpoint"
+	"github.com/tikv/pd/server/versioninfo"
 )
 
 const (
@@ -58,6 +59,9 @@ func init() {
 		}
 		return newRandomMergeScheduler(opController, conf), nil
 	})
+	schedule.RegisterPrerequisites(RandomMergeType, &schedule.Prerequisites{
+		RequiredFeatures: []versioninfo.Feature{versioninfo.RegionMerge},
+	})
 }
 
 type randomMergeSchedulerConfig struct {
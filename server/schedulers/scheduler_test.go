@@ -26,6 +26,8 @@ import (
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
+	"github.com/tikv/pd/server/schedule/checker"
+	"github.com/tikv/pd/server/schedule/hbstream"
 	"github.com/tikv/pd/server/schedule/operator"
 	"github.com/tikv/pd/server/schedule/placement"
 	"github.com/tikv/pd/server/statistics"
@@ -64,6 +66,37 @@ func TestShuffleLeader(t *testing.T) {
 	}
 }
 
+func TestSchedulerPrerequisites(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(ctx, opt)
+	oc := schedule.NewOperatorController(ctx, nil, nil)
+
+	sl, err := schedule.CreateScheduler(ShuffleLeaderType, oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(ShuffleLeaderType, []string{"", ""}))
+	re.NoError(err)
+	tc.AddLeaderStore(1, 1)
+	re.Error(schedule.CheckPrerequisites(sl, tc))
+	tc.AddLeaderStore(2, 1)
+	re.NoError(schedule.CheckPrerequisites(sl, tc))
+	tc.SetStoreOffline(2)
+	re.Error(schedule.CheckPrerequisites(sl, tc))
+
+	rm, err := schedule.CreateScheduler(RandomMergeType, oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(RandomMergeType, []string{"", ""}))
+	re.NoError(err)
+	tc.SetClusterVersion(versioninfo.MinSupportedVersion(versioninfo.Base))
+	re.Error(schedule.CheckPrerequisites(rm, tc))
+	tc.SetClusterVersion(versioninfo.MinSupportedVersion(versioninfo.RegionMerge))
+	re.NoError(schedule.CheckPrerequisites(rm, tc))
+
+	// Schedulers without prerequisites are always ready.
+	bl, err := schedule.CreateScheduler(BalanceLeaderType, oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(BalanceLeaderType, []string{"", ""}))
+	re.NoError(err)
+	re.NoError(schedule.CheckPrerequisites(bl, tc))
+}
+
 func TestRejectLeader(t *testing.T) {
 	re := require.New(t)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -510,3 +543,82 @@ func TestBalanceLeaderWithConflictRule(t *testing.T) {
 		}
 	}
 }
+
+func TestBalanceLearner(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	opt := config.NewTestOptions()
+	tc := mockcluster.NewCluster(ctx, opt)
+	tc.SetClusterVersion(versioninfo.MinSupportedVersion(versioninfo.Version4_0))
+	tc.SetEnablePlacementRules(true)
+	re.NoError(tc.RuleManager.SetRule(&placement.Rule{
+		GroupID:          "tiflash",
+		ID:               "learner",
+		Role:             placement.Learner,
+		Count:            1,
+		LabelConstraints: []placement.LabelConstraint{{Key: core.EngineKey, Op: placement.In, Values: []string{core.EngineTiFlash}}},
+	}))
+
+	oc := schedule.NewOperatorController(ctx, tc, hbstream.NewTestHeartbeatStreams(ctx, tc.ID, tc, false))
+	sl, err := schedule.CreateScheduler(BalanceLearnerType, oc, storage.NewStorageWithMemoryBackend(), schedule.ConfigSliceDecoder(BalanceLearnerType, nil))
+	re.NoError(err)
+	re.True(sl.IsScheduleAllowed(tc))
+	ops, _ := sl.Schedule(tc, false)
+	re.Empty(ops)
+
+	// Stores 1, 2, 3 are TiKV, and stores 4, 5, 6 are TiFlash.
+	for id := uint64(1); id <= 3; id++ {
+		tc.AddLabelsStore(id, 6, nil)
+	}
+	for id := uint64(4); id <= 6; id++ {
+		tc.AddLabelsStore(id, 0, map[string]string{core.EngineKey: core.EngineTiFlash})
+	}
+	// The learners on the TiFlash stores are 2, 1, 1.
+	tc.AddRegionWithLearner(1, 1, []uint64{2, 3}, []uint64{4})
+	tc.AddRegionWithLearner(2, 1, []uint64{2, 3}, []uint64{4})
+	tc.AddRegionWithLearner(3, 1, []uint64{2, 3}, []uint64{5})
+	tc.AddRegionWithLearner(4, 1, []uint64{2, 3}, []uint64{6})
+	ops, _ = sl.Schedule(tc, false)
+	re.Empty(ops)
+
+	// The learners on the TiFlash stores are 4, 1, 0.
+	tc.AddRegionWithLearner(4, 1, []uint64{2, 3}, []uint64{4})
+	tc.AddRegionWithLearner(5, 1, []uint64{2, 3}, []uint64{4})
+	ops, _ = sl.Schedule(tc, false)
+	re.Len(ops, 1)
+	re.Equal(BalanceLearnerType, ops[0].Desc())
+	testutil.CheckTransferLearner(re, ops[0], operator.OpRegion, 4, 6)
+
+	groups := sl.(*balanceLearnerScheduler).GetState().(map[string]interface{})["groups"].([]checker.LearnerGroup)
+	re.Len(groups, 2)
+	re.Equal(core.EngineTiFlash, groups[0].Engine)
+	re.Equal(4, groups[0].Skew)
+	re.Equal([]checker.LearnerStore{{StoreID: 4, LearnerCount: 4}, {StoreID: 5, LearnerCount: 1}, {StoreID: 6, LearnerCount: 0}}, groups[0].Stores)
+	re.Equal(core.EngineTiKV, groups[1].Engine)
+	re.Equal(0, groups[1].Skew)
+
+	// The learners on the TiFlash stores are 10, 8, 8, whose difference is
+	// within the tolerance of half the average count.
+	regionID := uint64(6)
+	for storeID, count := range map[uint64]int{4: 6, 5: 7, 6: 8} {
+		for i := 0; i < count; i++ {
+			tc.AddRegionWithLearner(regionID, 1, []uint64{2, 3}, []uint64{storeID})
+			regionID++
+		}
+	}
+	sl.(*balanceLearnerScheduler).conf.TolerantRatio = 0.5
+	ops, _ = sl.Schedule(tc, false)
+	re.Empty(ops)
+	sl.(*balanceLearnerScheduler).conf.TolerantRatio = defaultBalanceLearnerTolerantRatio
+	ops, _ = sl.Schedule(tc, false)
+	re.Len(ops, 1)
+	testutil.CheckTransferLearner(re, ops[0], operator.OpRegion, 4, 6)
+
+	// The running operators are counted before the heartbeats reflect them,
+	// the learners on the TiFlash stores are 9, 8, 9 with it.
+	re.Equal(1, oc.AddWaitingOperator(ops...))
+	ops, _ = sl.Schedule(tc, false)
+	re.Empty(ops)
+}
@@ -56,6 +56,8 @@ func init() {
 		}
 		return newShuffleLeaderScheduler(opController, conf), nil
 	})
+	// There is nothing to shuffle with less than 2 stores.
+	schedule.RegisterPrerequisites(ShuffleLeaderType, &schedule.Prerequisites{MinUpStoreCount: 2})
 }
 
 type shuffleLeaderSchedulerConfig struct {
@@ -123,6 +125,11 @@ func (s *shuffleLeaderScheduler) Schedule(cluster schedule.Cluster, dryRun bool)
 		schedulerCounter.WithLabelValues(s.GetName(), "no-follower").Inc()
 		return nil, nil
 	}
+	if pinnedFilter := filter.NewPinnedLeaderFilter(s.GetName(), cluster, region); pinnedFilter != nil &&
+		!filter.Target(cluster.GetOpts(), targetStore, []filter.Filter{pinnedFilter}) {
+		schedulerCounter.WithLabelValues(s.GetName(), "pinned-leader").Inc()
+		return nil, nil
+	}
 	op, err := operator.CreateTransferLeaderOperator(ShuffleLeaderType, cluster, region, region.GetLeader().GetId(), targetStore.GetID(), []uint64{}, operator.OpAdmin)
 	if err != nil {
 		log.Debug("fail to create shuffle leader operator", errs.ZapError(err))
@@ -57,6 +57,8 @@ func init() {
 		}
 		return newShuffleRegionScheduler(opController, conf), nil
 	})
+	// There is nothing to shuffle with less than 2 stores.
+	schedule.RegisterPrerequisites(ShuffleRegionType, &schedule.Prerequisites{MinUpStoreCount: 2})
 }
 
 type shuffleRegionScheduler struct {
@@ -21,6 +21,7 @@ import (
 
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 	"github.com/tikv/pd/server/schedule"
 	"github.com/tikv/pd/server/schedule/operator"
@@ -36,6 +37,9 @@ const (
 	influenceAmp                 int64   = 5
 	defaultMinRetryLimit                 = 1
 	defaultRetryQuotaAttenuation         = 2
+	// quotaBiasScore is added to the leader score of a store exceeding its soft
+	// quota, so that it is picked as the source before the other stores.
+	quotaBiasScore float64 = 1e12
 )
 
 type balancePlan struct {
@@ -50,6 +54,10 @@ type balancePlan struct {
 
 	sourceScore float64
 	targetScore float64
+
+	// leaderZone is the zone preferred by the latency-aware mode of the
+	// balance-leader scheduler, it is empty if the mode is disabled.
+	leaderZone string
 }
 
 func newBalancePlan(kind core.ScheduleKind, cluster schedule.Cluster, opInfluence operator.OpInfluence) *balancePlan {
@@ -111,8 +119,8 @@ func (p *balancePlan) shouldBalance(scheduleName string) bool {
 		p.targetScore = p.target.LeaderScore(p.kind.Policy, targetDelta)
 	case core.RegionKind:
 		sourceDelta, targetDelta := sourceInfluence*influenceAmp-tolerantResource, targetInfluence*influenceAmp+tolerantResource
-		p.sourceScore = p.source.RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), sourceDelta)
-		p.targetScore = p.target.RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), targetDelta)
+		p.sourceScore = p.source.RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), opts.GetRegionScoreCurve(), sourceDelta)
+		p.targetScore = p.target.RegionScore(opts.GetRegionScoreFormulaVersion(), opts.GetHighSpaceRatio(), opts.GetLowSpaceRatio(), opts.GetRegionScoreCurve(), targetDelta)
 	}
 	if opts.IsDebugMetricsEnabled() {
 		opInfluenceStatus.WithLabelValues(scheduleName, strconv.FormatUint(sourceID, 10), "source").Set(float64(sourceInfluence))
@@ -121,6 +129,10 @@ func (p *balancePlan) shouldBalance(scheduleName string) bool {
 	}
 	// Make sure after move, source score is still greater than target score.
 	shouldBalance := p.sourceScore > p.targetScore
+	// The store exceeding its soft quota is relieved regardless of the scores.
+	if !shouldBalance && isOverQuota(opts, p.source, p.kind.Resource) && !p.target.ExceedsQuota(p.kind.Resource, 1) {
+		shouldBalance = true
+	}
 
 	if !shouldBalance {
 		log.Debug("skip balance "+p.kind.Resource.String(),
@@ -146,6 +158,16 @@ func (p *balancePlan) getTolerantResource() int64 {
 	return int64(float64(regionSize) * p.tolerantSizeRatio)
 }
 
+// reason returns the reason of the operator created by the plan.
+func (p *balancePlan) reason(scheduleName string) operator.Reason {
+	return operator.NewReason(scheduleName, "score-imbalance").
+		With("source-store", p.SourceStoreID()).
+		With("target-store", p.TargetStoreID()).
+		With("source-score", strconv.FormatFloat(p.sourceScore, 'f', 2, 64)).
+		With("target-score", strconv.FormatFloat(p.targetScore, 'f', 2, 64)).
+		With("tolerant-resource", p.getTolerantResource())
+}
+
 func adjustTolerantRatio(cluster schedule.Cluster, kind core.ScheduleKind) float64 {
 	var tolerantSizeRatio float64
 	switch c := cluster.(type) {
@@ -179,6 +201,12 @@ func adjustTolerantRatio(cluster schedule.Cluster, kind core.ScheduleKind) float
 	return tolerantSizeRatio
 }
 
+// isOverQuota returns true if the store exceeds its soft quota of the given kind
+// and the balance schedulers are expected to relieve it.
+func isOverQuota(opts *config.PersistOptions, store *core.StoreInfo, kind core.ResourceKind) bool {
+	return opts.IsStoreQuotaBiasEnabled() && store.ExceedsQuota(kind, 0)
+}
+
 func getKeyRanges(args []string) ([]core.KeyRange, error) {
 	var ranges []core.KeyRange
 	for len(args) > 1 {
@@ -47,7 +47,10 @@ import (
 	"github.com/tikv/pd/pkg/jsonutil"
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/pkg/ratelimit"
+	"github.com/tikv/pd/pkg/regionquerypb"
+	"github.com/tikv/pd/pkg/slo"
 	"github.com/tikv/pd/pkg/systimemon"
+	"github.com/tikv/pd/pkg/trace"
 	"github.com/tikv/pd/pkg/typeutil"
 	"github.com/tikv/pd/server/cluster"
 	"github.com/tikv/pd/server/config"
@@ -63,6 +66,7 @@ import (
 	"github.com/tikv/pd/server/storage"
 	"github.com/tikv/pd/server/storage/endpoint"
 	"github.com/tikv/pd/server/storage/kv"
+	"github.com/tikv/pd/server/storeauth"
 	"github.com/tikv/pd/server/tso"
 	"github.com/tikv/pd/server/versioninfo"
 	"github.com/urfave/negroni"
@@ -112,6 +116,9 @@ type Server struct {
 
 	// for PD leader election.
 	member *member.Member
+	// preCampaignRefusals is the number of consecutive refusals to campaign
+	// because of the pre-campaign check. It's only accessed in the leader loop.
+	preCampaignRefusals int
 	// etcd client
 	client *clientv3.Client
 	// http client
@@ -130,12 +137,18 @@ type Server struct {
 	storage storage.Storage
 	// safepoint manager
 	gcSafePointManager *gc.SafePointManager
+	// for the tokens presented by stores
+	storeTokenManager *storeauth.TokenManager
+	// for the limits of the gRPC streams of each client class
+	grpcStreams *grpcStreamLimiter
 	// for basicCluster operation.
 	basicCluster *core.BasicCluster
 	// for tso.
 	tsoAllocatorManager *tso.AllocatorManager
 	// for raft cluster
 	cluster *cluster.RaftCluster
+	// serializes the migrations between replication modes.
+	replicationMigrationMu sync.Mutex
 	// For async region heartbeat.
 	hbStreams *hbstream.HeartbeatStreams
 	// Zap logger
@@ -152,15 +165,22 @@ type Server struct {
 	clientConns sync.Map
 	// tsoDispatcher is used to dispatch different TSO requests to
 	// the corresponding forwarding TSO channel.
-	tsoDispatcher sync.Map /* Store as map[string]chan *tsoRequest */
+	tsoDispatcher sync.Map /* Store as map[string]*tsoRequestDispatcher */
 
 	serviceRateLimiter *ratelimit.Limiter
+	sloTracker         *slo.Tracker
 	serviceLabels      map[string][]apiutil.AccessPath
 	apiServiceLabelMap map[apiutil.AccessPath]string
 
 	serviceAuditBackendLabels map[string]*audit.BackendLabels
 
 	auditBackends []audit.Backend
+
+	regionStorageMigration regionStorageMigration
+
+	configWatcher configWatcher
+
+	persistFiles persistFileRecords
 }
 
 // HandlerBuilder builds a server HTTP handler.
@@ -233,6 +253,16 @@ func combineBuilderServerHTTPService(ctx context.Context, svr *Server, serviceBu
 	return userHandlers, nil
 }
 
+// traceSampler samples the traces by the PD server config.
+type traceSampler struct {
+	opt *config.PersistOptions
+}
+
+// ShouldSample implements trace.Sampler.
+func (s traceSampler) ShouldSample(name string) bool {
+	return s.opt.IsTraceEnabled() && trace.RatioSampler(s.opt.GetTraceSampleRatio()).ShouldSample(name)
+}
+
 // CreateServer creates the UNINITIALIZED pd server with given configuration.
 func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...HandlerBuilder) (*Server, error) {
 	log.Info("PD Config", zap.Reflect("config", cfg))
@@ -250,6 +280,7 @@ func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...Ha
 		DiagnosticsServer:               sysutil.NewDiagnosticsServer(cfg.Log.File.Filename),
 	}
 	s.handler = newHandler(s)
+	trace.SetSampler(traceSampler{s.persistOptions})
 
 	// create audit backend
 	s.auditBackends = []audit.Backend{
@@ -259,6 +290,8 @@ func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...Ha
 	s.serviceRateLimiter = ratelimit.NewLimiter()
 	s.serviceAuditBackendLabels = make(map[string]*audit.BackendLabels)
 	s.serviceRateLimiter = ratelimit.NewLimiter()
+	s.sloTracker = slo.NewTracker(slo.DefaultWindowSize)
+	s.grpcStreams = newGRPCStreamLimiter()
 	s.serviceLabels = make(map[string][]apiutil.AccessPath)
 	s.apiServiceLabelMap = make(map[apiutil.AccessPath]string)
 
@@ -274,9 +307,11 @@ func CreateServer(ctx context.Context, cfg *config.Config, serviceBuilders ...Ha
 		}
 		etcdCfg.UserHandlers = userHandlers
 	}
+	grpcutil.RegisterGzipCompressor(s.persistOptions.GetGRPCGzipLevel)
 	etcdCfg.ServiceRegister = func(gs *grpc.Server) {
 		pdpb.RegisterPDServer(gs, &GrpcServer{Server: s})
 		diagnosticspb.RegisterDiagnosticsServer(gs, s)
+		regionquerypb.RegisterRegionQueryServer(gs, &RegionQueryServer{Server: s})
 	}
 	s.etcdCfg = etcdCfg
 	s.lg = cfg.GetZapLogger()
@@ -403,6 +438,7 @@ func (s *Server) startServer(ctx context.Context) error {
 	defaultStorage := storage.NewStorageWithEtcdBackend(s.client, s.rootPath)
 	s.storage = storage.NewCoreStorage(defaultStorage, regionStorage)
 	s.gcSafePointManager = gc.NewSafePointManager(s.storage)
+	s.storeTokenManager = storeauth.NewTokenManager(s.storage)
 	s.basicCluster = core.NewBasicCluster()
 	s.cluster = cluster.NewRaftCluster(ctx, s.clusterID, syncer.NewRegionSyncer(s), s.client, s.httpClient)
 	s.hbStreams = hbstream.NewHeartbeatStreams(ctx, s.clusterID, s.cluster)
@@ -643,6 +679,13 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 	regionPath := endpoint.AppendToRootPath(s.rootPath, endpoint.RegionPath(req.GetRegion().GetId()))
 	ops = append(ops, clientv3.OpPut(regionPath, string(regionValue)))
 
+	// Apply the staged bootstrap bundle.
+	bundleOps, applyBundle, err := s.bootstrapBundleOps(storeMeta)
+	if err != nil {
+		return nil, err
+	}
+	ops = append(ops, bundleOps...)
+
 	// TODO: we must figure out a better way to handle bootstrap failed, maybe intervene manually.
 	bootstrapCmp := clientv3.Compare(clientv3.CreateRevision(clusterRootPath), "=", 0)
 	resp, err := kv.NewSlowLogTxn(s.client).If(bootstrapCmp).Then(ops...).Commit()
@@ -655,6 +698,9 @@ func (s *Server) bootstrapCluster(req *pdpb.BootstrapRequest) (*pdpb.BootstrapRe
 	}
 
 	log.Info("bootstrap cluster ok", zap.Uint64("cluster-id", clusterID))
+	if applyBundle != nil {
+		applyBundle()
+	}
 	err = s.storage.SaveRegion(req.GetRegion())
 	if err != nil {
 		log.Warn("save the bootstrap region failed", errs.ZapError(err))
@@ -739,6 +785,11 @@ func (s *Server) GetStorage() storage.Storage {
 	return s.storage
 }
 
+// GetStoreTokenManager returns the manager of the tokens presented by stores.
+func (s *Server) GetStoreTokenManager() *storeauth.TokenManager {
+	return s.storeTokenManager
+}
+
 // GetHistoryHotRegionStorage returns the backend storage of historyHotRegion.
 func (s *Server) GetHistoryHotRegionStorage() *storage.HotRegionStorage {
 	return s.hotRegionStorage
@@ -870,6 +921,7 @@ func (s *Server) SetScheduleConfig(cfg config.ScheduleConfig) error {
 		return err
 	}
 	log.Info("schedule config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.notifyConfigChange(ConfigKindSchedule)
 	return nil
 }
 
@@ -956,6 +1008,7 @@ func (s *Server) SetReplicationConfig(cfg config.ReplicationConfig) error {
 		return err
 	}
 	log.Info("replication config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.notifyConfigChange(ConfigKindReplication)
 	return nil
 }
 
@@ -1061,7 +1114,11 @@ func (s *Server) SetPDServerConfig(cfg config.PDServerConfig) error {
 			errs.ZapError(err))
 		return err
 	}
+	if cfg.RegionStorageDualWrite != old.RegionStorageDualWrite {
+		storage.TrySetRegionStorageDualWrite(s.storage, cfg.RegionStorageDualWrite)
+	}
 	log.Info("PD server config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.notifyConfigChange(ConfigKindPDServer)
 	return nil
 }
 
@@ -1078,6 +1135,7 @@ func (s *Server) SetLabelPropertyConfig(cfg config.LabelPropertyConfig) error {
 		return err
 	}
 	log.Info("label property config is updated", zap.Reflect("new", cfg), zap.Reflect("old", old))
+	s.notifyConfigChange(ConfigKindLabelProperty)
 	return nil
 }
 
@@ -1097,6 +1155,7 @@ func (s *Server) SetLabelProperty(typ, labelKey, labelValue string) error {
 	}
 
 	log.Info("label property config is updated", zap.Reflect("config", s.persistOptions.GetLabelPropertyConfig()))
+	s.notifyConfigChange(ConfigKindLabelProperty)
 	return nil
 }
 
@@ -1116,6 +1175,7 @@ func (s *Server) DeleteLabelProperty(typ, labelKey, labelValue string) error {
 	}
 
 	log.Info("label property config is deleted", zap.Reflect("config", s.persistOptions.GetLabelPropertyConfig()))
+	s.notifyConfigChange(ConfigKindLabelProperty)
 	return nil
 }
 
@@ -1164,6 +1224,24 @@ func (s *Server) GetRaftCluster() *cluster.RaftCluster {
 	return s.cluster
 }
 
+// GetClusterStartupStatus returns the startup status of the subsystems of the
+// Raft cluster. Unlike GetRaftCluster, it works when the startup fails.
+func (s *Server) GetClusterStartupStatus() []cluster.SubsystemStatus {
+	if s.cluster == nil {
+		return nil
+	}
+	return s.cluster.GetStartupStatus()
+}
+
+// GetRegionLoadingProgress returns the progress of loading the regions when the
+// Raft cluster starts. Unlike GetRaftCluster, it works before the cluster is running.
+func (s *Server) GetRegionLoadingProgress() (process, leftSeconds, currentSpeed float64, err error) {
+	if s.cluster == nil {
+		return 0, 0, 0, errs.ErrNotBootstrapped.FastGenByArgs()
+	}
+	return s.cluster.GetRegionLoadingProgress()
+}
+
 // GetCluster gets cluster.
 func (s *Server) GetCluster() *metapb.Cluster {
 	return &metapb.Cluster{
@@ -1256,6 +1334,16 @@ func (s *Server) IsInRateLimitAllowList(serviceLabel string) bool {
 	return s.serviceRateLimiter.IsInAllowList(serviceLabel)
 }
 
+// GetSLOTracker is used to get the tracker of the API and gRPC latencies.
+func (s *Server) GetSLOTracker() *slo.Tracker {
+	return s.sloTracker
+}
+
+// IsOverloaded returns whether the p99 latency of any API or gRPC method exceeds the overload threshold.
+func (s *Server) IsOverloaded() bool {
+	return s.sloTracker.IsOverloaded(s.serviceMiddlewarePersistOptions.GetOverloadLatencyThreshold())
+}
+
 // UpdateServiceRateLimiter is used to update RateLimiter
 func (s *Server) UpdateServiceRateLimiter(serviceLabel string, opts ...ratelimit.Option) ratelimit.UpdateStatus {
 	return s.serviceRateLimiter.Update(serviceLabel, opts...)
@@ -1380,7 +1468,50 @@ func (s *Server) leaderLoop() {
 	}
 }
 
+// maxPreCampaignRefusals is the max number of consecutive refusals to campaign
+// because of the pre-campaign check, which avoids the cluster having no leader
+// when all members fail the check.
+const maxPreCampaignRefusals = 10
+
+// preCampaignCheck returns true if the server is healthy enough to campaign the PD leader.
+func (s *Server) preCampaignCheck() bool {
+	if !s.cfg.EnablePreCampaignCheck {
+		return true
+	}
+	var storeStatusAddrs []string
+	if err := s.storage.LoadStores(func(store *core.StoreInfo) {
+		if !store.IsRemoved() && store.GetStatusAddress() != "" {
+			storeStatusAddrs = append(storeStatusAddrs, store.GetStatusAddress())
+		}
+	}); err != nil {
+		log.Warn("failed to load stores for pre-campaign check", errs.ZapError(err))
+	}
+	err := s.member.PreCampaignCheck(s.serverLoopCtx, s.cfg.PreCampaignMaxCommitLatency.Duration, storeStatusAddrs)
+	if err == nil {
+		s.preCampaignRefusals = 0
+		return true
+	}
+	s.preCampaignRefusals++
+	if s.preCampaignRefusals > maxPreCampaignRefusals {
+		log.Warn("pre-campaign check keeps failing, campaign pd leader anyway",
+			zap.String("campaign-pd-leader-name", s.Name()),
+			zap.Int("refusals", s.preCampaignRefusals),
+			errs.ZapError(err))
+		s.preCampaignRefusals = 0
+		return true
+	}
+	log.Warn("refuse to campaign pd leader since the pre-campaign check fails",
+		zap.String("campaign-pd-leader-name", s.Name()),
+		zap.Int("refusals", s.preCampaignRefusals),
+		errs.ZapError(err))
+	return false
+}
+
 func (s *Server) campaignLeader() {
+	if !s.preCampaignCheck() {
+		time.Sleep(200 * time.Millisecond)
+		return
+	}
 	log.Info("start to campaign pd leader", zap.String("campaign-pd-leader-name", s.Name()))
 	if err := s.member.CampaignLeader(s.cfg.LeaderLease); err != nil {
 		if err.Error() == errs.ErrEtcdTxnConflict.Error() {
@@ -1438,6 +1569,11 @@ func (s *Server) campaignLeader() {
 		return
 	}
 
+	if err := s.storeTokenManager.Reload(); err != nil {
+		log.Error("failed to reload store tokens", errs.ZapError(err))
+		return
+	}
+
 	if err := s.encryptionKeyManager.SetLeadership(s.member.GetLeadership()); err != nil {
 		log.Error("failed to initialize encryption", errs.ZapError(err))
 		return
@@ -1517,6 +1653,9 @@ func (s *Server) reloadConfigFromKV() error {
 		return err
 	}
 	s.loadRateLimitConfig()
+	// The changes made by the previous leader are unknown, let the watchers
+	// get the whole configurations again.
+	s.configWatcher.reset()
 	useRegionStorage := s.persistOptions.IsUseRegionStorage()
 	regionStorage := storage.TrySwitchRegionStorage(s.storage, useRegionStorage)
 	if regionStorage != nil {
@@ -1526,6 +1665,10 @@ func (s *Server) reloadConfigFromKV() error {
 			log.Info("server disable region storage")
 		}
 	}
+	if storage.TrySetRegionStorageDualWrite(s.storage, s.persistOptions.IsRegionStorageDualWrite()) &&
+		s.persistOptions.IsRegionStorageDualWrite() {
+		log.Info("server enable region storage dual-write")
+	}
 	return nil
 }
 
@@ -1540,7 +1683,14 @@ func (s *Server) loadRateLimitConfig() {
 // ReplicateFileToMember is used to synchronize state to a member.
 // Each member will write `data` to a local file named `name`.
 // For security reason, data should be in JSON format.
-func (s *Server) ReplicateFileToMember(ctx context.Context, member *pdpb.Member, name string, data []byte) error {
+// The checksum of the data is recorded for each member to detect divergence.
+func (s *Server) ReplicateFileToMember(ctx context.Context, member *pdpb.Member, name string, data []byte) (err error) {
+	if err := ValidatePersistFile(name, data); err != nil {
+		return err
+	}
+	defer func() {
+		s.persistFiles.recordReplicated(name, member, fileChecksum(data), err)
+	}()
 	clientUrls := member.GetClientUrls()
 	if len(clientUrls) == 0 {
 		log.Warn("failed to replicate file", zap.String("name", name), zap.String("member", member.GetName()))
@@ -1549,6 +1699,7 @@ func (s *Server) ReplicateFileToMember(ctx context.Context, member *pdpb.Member,
 	url := clientUrls[0] + filepath.Join("/pd/api/v1/admin/persist-file", name)
 	req, _ := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewBuffer(data))
 	req.Header.Set("PD-Allow-follower-handle", "true")
+	req.Header.Set("Content-Type", PersistFileContentType)
 	res, err := s.httpClient.Do(req)
 	if err != nil {
 		log.Warn("failed to replicate file", zap.String("name", name), zap.String("member", member.GetName()), errs.ZapError(err))
@@ -1565,8 +1716,15 @@ func (s *Server) ReplicateFileToMember(ctx context.Context, member *pdpb.Member,
 
 // PersistFile saves a file in DataDir.
 func (s *Server) PersistFile(name string, data []byte) error {
+	if err := ValidatePersistFile(name, data); err != nil {
+		return err
+	}
 	log.Info("persist file", zap.String("name", name), zap.Binary("data", data))
-	return os.WriteFile(filepath.Join(s.GetConfig().DataDir, name), data, 0644) // #nosec
+	if err := os.WriteFile(filepath.Join(s.GetConfig().DataDir, name), data, 0644); err != nil { // #nosec
+		return err
+	}
+	s.persistFiles.recordPersisted(name)
+	return nil
 }
 
 // SaveTTLConfig save ttl config
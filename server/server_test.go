@@ -125,6 +125,14 @@ func (suite *leaderServerTestSuite) newTestServersWithCfgs(ctx context.Context,
 	return svrs, cleanup
 }
 
+func (suite *leaderServerTestSuite) TestPreCampaignCheckDisabledByDefault() {
+	for _, svr := range suite.svrs {
+		suite.False(svr.cfg.EnablePreCampaignCheck)
+		// the stores are not reachable, but the check is skipped.
+		suite.True(svr.preCampaignCheck())
+	}
+}
+
 func (suite *leaderServerTestSuite) TestCheckClusterID() {
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
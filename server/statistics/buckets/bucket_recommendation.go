@@ -0,0 +1,166 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buckets
+
+import (
+	"bytes"
+	"sort"
+
+	"github.com/tikv/pd/server/core"
+)
+
+const (
+	// DefaultRecommendHotDegree is the default hot degree from which a bucket is recommended to be split.
+	DefaultRecommendHotDegree = 3
+	// DefaultRecommendColdDegree is the default hot degree under which a bucket is recommended to be merged.
+	DefaultRecommendColdDegree = -3
+
+	// SplitReasonHot means the key isolates a hot bucket.
+	SplitReasonHot = "hot"
+	// SplitReasonOversized means the key divides an oversized region.
+	SplitReasonOversized = "oversized"
+)
+
+// RecommendOption is the option used to recommend the split keys and merge candidates of the buckets.
+type RecommendOption struct {
+	// HotDegree is the minimum hot degree of the buckets to split.
+	HotDegree int
+	// ColdDegree is the maximum hot degree of the buckets to merge.
+	ColdDegree int
+	// RegionSize is the approximate size of the region in MB.
+	RegionSize int64
+	// RegionMaxSize and RegionSplitSize come from the store config, in MB.
+	RegionMaxSize   uint64
+	RegionSplitSize uint64
+	// BucketSize is the expected size of a bucket in MB, 0 means no limit.
+	BucketSize uint64
+}
+
+// SplitKeyRecommendation is a key recommended to split the region at.
+type SplitKeyRecommendation struct {
+	Key     string   `json:"key"`
+	Reasons []string `json:"reasons"`
+}
+
+// MergeRecommendation is a run of adjacent cold buckets recommended to be merged.
+type MergeRecommendation struct {
+	StartKey        string `json:"start_key"`
+	EndKey          string `json:"end_key"`
+	BucketCount     int    `json:"bucket_count"`
+	ApproximateSize int64  `json:"approximate_size"`
+}
+
+// RegionBucketsRecommendation is the recommendation for the buckets of a region.
+type RegionBucketsRecommendation struct {
+	RegionID        uint64                    `json:"region_id"`
+	StartKey        string                    `json:"start_key"`
+	EndKey          string                    `json:"end_key"`
+	BucketCount     int                       `json:"bucket_count"`
+	ApproximateSize int64                     `json:"approximate_size"`
+	SplitKeys       []*SplitKeyRecommendation `json:"split_keys,omitempty"`
+	MergeCandidates []*MergeRecommendation    `json:"merge_candidates,omitempty"`
+}
+
+// IsEmpty returns true if there is nothing to recommend.
+func (r *RegionBucketsRecommendation) IsEmpty() bool {
+	return len(r.SplitKeys) == 0 && len(r.MergeCandidates) == 0
+}
+
+// RecommendBuckets recommends the split keys and merge candidates according to the stats of the buckets
+// of a region, which must be sorted by key. The size of the buckets is estimated by the region size, so
+// the recommendation is only a hint for the operators and is never executed by PD itself.
+func RecommendBuckets(stats []*BucketStat, opt *RecommendOption) *RegionBucketsRecommendation {
+	if len(stats) == 0 {
+		return nil
+	}
+	first, last := stats[0], stats[len(stats)-1]
+	bucketSize := opt.RegionSize / int64(len(stats))
+	rec := &RegionBucketsRecommendation{
+		RegionID:        first.RegionID,
+		StartKey:        core.HexRegionKeyStr(first.StartKey),
+		EndKey:          core.HexRegionKeyStr(last.EndKey),
+		BucketCount:     len(stats),
+		ApproximateSize: opt.RegionSize,
+	}
+
+	splitKeys := make(map[string]*SplitKeyRecommendation)
+	var rawKeys [][]byte
+	addSplitKey := func(key []byte, reason string) {
+		// the boundaries of the region can't be used to split it.
+		if len(key) == 0 || bytes.Equal(key, first.StartKey) || bytes.Equal(key, last.EndKey) {
+			return
+		}
+		if r, ok := splitKeys[string(key)]; ok {
+			for _, existed := range r.Reasons {
+				if existed == reason {
+					return
+				}
+			}
+			r.Reasons = append(r.Reasons, reason)
+			return
+		}
+		splitKeys[string(key)] = &SplitKeyRecommendation{Key: core.HexRegionKeyStr(key), Reasons: []string{reason}}
+		rawKeys = append(rawKeys, key)
+	}
+
+	// isolate the hot buckets so that their load can be scheduled independently.
+	for _, stat := range stats {
+		if stat.HotDegree >= opt.HotDegree {
+			addSplitKey(stat.StartKey, SplitReasonHot)
+			addSplitKey(stat.EndKey, SplitReasonHot)
+		}
+	}
+	// divide the oversized region at the bucket boundaries.
+	if opt.RegionMaxSize > 0 && opt.RegionSplitSize > 0 && opt.RegionSize > int64(opt.RegionMaxSize) {
+		var acc int64
+		for _, stat := range stats {
+			if acc > 0 && acc+bucketSize > int64(opt.RegionSplitSize) {
+				addSplitKey(stat.StartKey, SplitReasonOversized)
+				acc = 0
+			}
+			acc += bucketSize
+		}
+	}
+	sort.Slice(rawKeys, func(i, j int) bool { return bytes.Compare(rawKeys[i], rawKeys[j]) < 0 })
+	for _, key := range rawKeys {
+		rec.SplitKeys = append(rec.SplitKeys, splitKeys[string(key)])
+	}
+
+	// merge the adjacent cold buckets as long as the merged one is not bigger than the expected size.
+	var run []*BucketStat
+	flush := func() {
+		if len(run) > 1 {
+			rec.MergeCandidates = append(rec.MergeCandidates, &MergeRecommendation{
+				StartKey:        core.HexRegionKeyStr(run[0].StartKey),
+				EndKey:          core.HexRegionKeyStr(run[len(run)-1].EndKey),
+				BucketCount:     len(run),
+				ApproximateSize: bucketSize * int64(len(run)),
+			})
+		}
+		run = run[:0]
+	}
+	for _, stat := range stats {
+		if stat.HotDegree > opt.ColdDegree {
+			flush()
+			continue
+		}
+		if opt.BucketSize > 0 && len(run) > 0 && bucketSize*int64(len(run)+1) > int64(opt.BucketSize) {
+			flush()
+		}
+		run = append(run, stat)
+	}
+	flush()
+	return rec
+}
@@ -0,0 +1,115 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package buckets
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/core"
+)
+
+func newTestBucketStats(regionID uint64, keys []string, degrees []int) []*BucketStat {
+	stats := make([]*BucketStat, 0, len(degrees))
+	for i, degree := range degrees {
+		stats = append(stats, &BucketStat{
+			RegionID:  regionID,
+			StartKey:  []byte(keys[i]),
+			EndKey:    []byte(keys[i+1]),
+			HotDegree: degree,
+		})
+	}
+	return stats
+}
+
+func TestRecommendBuckets(t *testing.T) {
+	re := require.New(t)
+	hex := func(key string) string { return core.HexRegionKeyStr([]byte(key)) }
+	keys := []string{"a", "b", "c", "d", "e", "f"}
+	opt := &RecommendOption{
+		HotDegree:       DefaultRecommendHotDegree,
+		ColdDegree:      DefaultRecommendColdDegree,
+		RegionSize:      50,
+		RegionMaxSize:   144,
+		RegionSplitSize: 96,
+		BucketSize:      96,
+	}
+
+	// case1: no buckets.
+	re.Nil(RecommendBuckets(nil, opt))
+
+	// case2: nothing to recommend.
+	rec := RecommendBuckets(newTestBucketStats(1, keys, []int{0, 0, 0, 0, 0}), opt)
+	re.True(rec.IsEmpty())
+	re.Equal(5, rec.BucketCount)
+	re.Equal(hex("a"), rec.StartKey)
+	re.Equal(hex("f"), rec.EndKey)
+
+	// case3: the hot buckets are isolated, but the boundaries of the region are not used.
+	rec = RecommendBuckets(newTestBucketStats(1, keys, []int{5, 0, 3, 0, 0}), opt)
+	re.Len(rec.SplitKeys, 3)
+	re.Equal(hex("b"), rec.SplitKeys[0].Key)
+	re.Equal(hex("c"), rec.SplitKeys[1].Key)
+	re.Equal(hex("d"), rec.SplitKeys[2].Key)
+	re.Equal([]string{SplitReasonHot}, rec.SplitKeys[0].Reasons)
+	re.Empty(rec.MergeCandidates)
+
+	// case4: the adjacent cold buckets are merged.
+	rec = RecommendBuckets(newTestBucketStats(1, keys, []int{-5, -3, 0, -10, -4}), opt)
+	re.Empty(rec.SplitKeys)
+	re.Len(rec.MergeCandidates, 2)
+	re.Equal(hex("a"), rec.MergeCandidates[0].StartKey)
+	re.Equal(hex("c"), rec.MergeCandidates[0].EndKey)
+	re.Equal(2, rec.MergeCandidates[0].BucketCount)
+	re.Equal(int64(20), rec.MergeCandidates[0].ApproximateSize)
+	re.Equal(hex("d"), rec.MergeCandidates[1].StartKey)
+	re.Equal(hex("f"), rec.MergeCandidates[1].EndKey)
+
+	// case5: the merged bucket should not be bigger than the bucket size.
+	opt.BucketSize = 25
+	rec = RecommendBuckets(newTestBucketStats(1, keys, []int{-5, -5, -5, -5, -5}), opt)
+	re.Len(rec.MergeCandidates, 2)
+	re.Equal(2, rec.MergeCandidates[0].BucketCount)
+	re.Equal(2, rec.MergeCandidates[1].BucketCount)
+	re.Equal(hex("c"), rec.MergeCandidates[1].StartKey)
+	re.Equal(hex("e"), rec.MergeCandidates[1].EndKey)
+
+	// case6: the oversized region is divided at the bucket boundaries.
+	opt.RegionSize = 200
+	rec = RecommendBuckets(newTestBucketStats(1, keys, []int{0, 0, 0, 0, 5}), opt)
+	re.Len(rec.SplitKeys, 2)
+	re.Equal(hex("c"), rec.SplitKeys[0].Key)
+	re.Equal([]string{SplitReasonOversized}, rec.SplitKeys[0].Reasons)
+	re.Equal(hex("e"), rec.SplitKeys[1].Key)
+	re.Equal([]string{SplitReasonHot, SplitReasonOversized}, rec.SplitKeys[1].Reasons)
+}
+
+func TestCollectRegionBucketsTask(t *testing.T) {
+	re := require.New(t)
+	ctx, cancelFn := context.WithCancel(context.Background())
+	defer cancelFn()
+	hotCache := NewBucketsCache(ctx)
+	re.True(hotCache.CheckAsync(NewCheckPeerTask(newTestBuckets(1, 1, [][]byte{[]byte("a"), []byte("b"), []byte("c")}, 0))))
+	re.True(hotCache.CheckAsync(NewCheckPeerTask(newTestBuckets(2, 1, [][]byte{[]byte("c"), []byte("d")}, 0))))
+
+	task := NewCollectRegionBucketsTask(1, 3)
+	re.True(hotCache.CheckAsync(task))
+	stats := task.WaitRet(ctx)
+	re.Len(stats, 1)
+	re.Len(stats[1], 2)
+	re.Equal([]byte("a"), stats[1][0].StartKey)
+	re.Equal([]byte("c"), stats[1][1].EndKey)
+}
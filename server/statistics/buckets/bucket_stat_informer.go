@@ -109,6 +109,27 @@ func (b *BucketTreeItem) equals(origin *BucketTreeItem) bool {
 	return bytes.Equal(b.startKey, origin.startKey) && bytes.Equal(b.endKey, origin.endKey)
 }
 
+// intersects returns whether the key range of the bucket intersects with the item.
+func (b *BucketTreeItem) intersects(stat *BucketStat) bool {
+	left := keyutil.MaxKey(b.startKey, stat.StartKey)
+	right := keyutil.MinKey(b.endKey, stat.EndKey)
+	if len(b.endKey) == 0 {
+		right = stat.EndKey
+	}
+	if len(stat.EndKey) == 0 {
+		right = b.endKey
+	}
+	return len(right) == 0 || bytes.Compare(left, right) < 0
+}
+
+// covers returns whether the key range of the item contains the other one.
+func (b *BucketTreeItem) covers(other *BucketTreeItem) bool {
+	if bytes.Compare(b.startKey, other.startKey) > 0 {
+		return false
+	}
+	return len(b.endKey) == 0 || (len(other.endKey) != 0 && bytes.Compare(b.endKey, other.endKey) >= 0)
+}
+
 // cloneBucketItemByRange returns a new item with the same key range.
 // item must have some debris for the given key range
 func (b *BucketTreeItem) cloneBucketItemByRange(startKey, endKey []byte) *BucketTreeItem {
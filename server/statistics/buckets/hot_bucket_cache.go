@@ -24,6 +24,7 @@ import (
 	"github.com/tikv/pd/pkg/keyutil"
 	"github.com/tikv/pd/pkg/logutil"
 	"github.com/tikv/pd/pkg/rangetree"
+	"github.com/tikv/pd/server/core"
 	"go.uber.org/zap"
 )
 
@@ -49,10 +50,86 @@ const (
 type HotBucketCache struct {
 	tree            *rangetree.RangeTree       // regionId -> BucketTreeItem
 	bucketsOfRegion map[uint64]*BucketTreeItem // regionId -> BucketTreeItem
+	lineages        map[uint64]*BucketLineage  // regionId -> BucketLineage
 	taskQueue       chan flowBucketsItemTask
 	ctx             context.Context
 }
 
+// BucketLineage records the regions whose bucket stats are inherited by a
+// region, such as the region it is split from or the regions merged into it.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type BucketLineage struct {
+	RegionID uint64 `json:"region_id"`
+	// Version is the version of the buckets when the stats are inherited.
+	Version uint64                 `json:"version"`
+	Parents []*BucketLineageParent `json:"parents"`
+}
+
+// BucketLineageParent is a region whose bucket stats are inherited.
+type BucketLineageParent struct {
+	RegionID uint64 `json:"region_id"`
+	// InheritedBuckets is the number of the buckets of the parent covered by the
+	// child, and Proportion is its ratio to the buckets of the parent in the cache.
+	InheritedBuckets int     `json:"inherited_buckets"`
+	Proportion       float64 `json:"proportion"`
+	// HotDegree is the max hot degree of the inherited buckets.
+	HotDegree int `json:"hot_degree"`
+}
+
+func (l *BucketLineage) clone() *BucketLineage {
+	c := &BucketLineage{RegionID: l.RegionID, Version: l.Version, Parents: make([]*BucketLineageParent, 0, len(l.Parents))}
+	for _, parent := range l.Parents {
+		p := *parent
+		c.Parents = append(c.Parents, &p)
+	}
+	return c
+}
+
+// newBucketLineage returns the lineage of the item if it inherits the bucket
+// stats from the other regions, otherwise it returns nil.
+func newBucketLineage(item *BucketTreeItem, overlaps []*BucketTreeItem) *BucketLineage {
+	var (
+		parents []*BucketLineageParent
+		totals  []int
+		indexes = make(map[uint64]int)
+	)
+	for _, overlap := range overlaps {
+		if overlap.regionID == item.regionID {
+			continue
+		}
+		idx, ok := indexes[overlap.regionID]
+		if !ok {
+			idx = len(parents)
+			indexes[overlap.regionID] = idx
+			parents = append(parents, &BucketLineageParent{RegionID: overlap.regionID, HotDegree: minHotDegree})
+			totals = append(totals, 0)
+		}
+		parent := parents[idx]
+		for _, stat := range overlap.stats {
+			totals[idx]++
+			if !item.intersects(stat) {
+				continue
+			}
+			parent.InheritedBuckets++
+			if stat.HotDegree > parent.HotDegree {
+				parent.HotDegree = stat.HotDegree
+			}
+		}
+	}
+	lineage := &BucketLineage{RegionID: item.regionID, Version: item.version}
+	for i, parent := range parents {
+		if parent.InheritedBuckets == 0 {
+			continue
+		}
+		parent.Proportion = float64(parent.InheritedBuckets) / float64(totals[i])
+		lineage.Parents = append(lineage.Parents, parent)
+	}
+	if len(lineage.Parents) == 0 {
+		return nil
+	}
+	return lineage
+}
+
 // GetHotBucketStats returns the hot stats of the regions that great than degree.
 func (h *HotBucketCache) GetHotBucketStats(degree int) map[uint64][]*BucketStat {
 	rst := make(map[uint64][]*BucketStat)
@@ -112,6 +189,7 @@ func NewBucketsCache(ctx context.Context) *HotBucketCache {
 	bucketCache := &HotBucketCache{
 		ctx:             ctx,
 		bucketsOfRegion: make(map[uint64]*BucketTreeItem),
+		lineages:        make(map[uint64]*BucketLineage),
 		tree:            rangetree.NewRangeTree(bucketBtreeDegree, bucketDebrisFactory),
 		taskQueue:       make(chan flowBucketsItemTask, queue),
 	}
@@ -133,10 +211,36 @@ func (h *HotBucketCache) putItem(item *BucketTreeItem, overlaps []*BucketTreeIte
 				logutil.ZapRedactByteString("start-key", overlap.GetStartKey()),
 				logutil.ZapRedactByteString("end-key", overlap.GetEndKey()))
 			delete(h.bucketsOfRegion, overlap.regionID)
+			// the region is merged if the new item covers it.
+			if item.covers(overlap) {
+				delete(h.lineages, overlap.regionID)
+			}
 		}
 	}
+	if lineage := newBucketLineage(item, overlaps); lineage != nil {
+		h.lineages[item.regionID] = lineage
+	}
 	h.bucketsOfRegion[item.regionID] = item
 	h.tree.Update(item)
+	// The lineages of the regions which are merged or not reported any more are
+	// dropped lazily.
+	if len(h.lineages) > 2*len(h.bucketsOfRegion) {
+		for regionID := range h.lineages {
+			if _, ok := h.bucketsOfRegion[regionID]; !ok {
+				delete(h.lineages, regionID)
+			}
+		}
+	}
+}
+
+// getBucketLineage returns the lineage of the region, nil if the region does
+// not inherit the bucket stats from the other regions.
+func (h *HotBucketCache) getBucketLineage(regionID uint64) *BucketLineage {
+	lineage, ok := h.lineages[regionID]
+	if !ok {
+		return nil
+	}
+	return lineage.clone()
 }
 
 // CheckAsync returns true if the task queue is not full.
@@ -241,3 +345,35 @@ func getStartKey(buckets *metapb.Buckets) []byte {
 	}
 	return buckets.Keys[0]
 }
+
+// BucketInfo is the stats of a bucket.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type BucketInfo struct {
+	StartKey  string `json:"start_key"`
+	EndKey    string `json:"end_key"`
+	HotDegree int    `json:"hot_degree"`
+	// Loads are ordered as statistics.RegionStatKind.
+	Loads []uint64 `json:"loads"`
+}
+
+// RegionBuckets is the buckets of a region and where their stats are inherited from.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type RegionBuckets struct {
+	RegionID uint64         `json:"region_id"`
+	Buckets  []*BucketInfo  `json:"buckets"`
+	Lineage  *BucketLineage `json:"lineage,omitempty"`
+}
+
+// NewRegionBuckets creates the RegionBuckets with the bucket stats and the lineage.
+func NewRegionBuckets(regionID uint64, stats []*BucketStat, lineage *BucketLineage) *RegionBuckets {
+	buckets := make([]*BucketInfo, 0, len(stats))
+	for _, stat := range stats {
+		buckets = append(buckets, &BucketInfo{
+			StartKey:  core.HexRegionKeyStr(stat.StartKey),
+			EndKey:    core.HexRegionKeyStr(stat.EndKey),
+			HotDegree: stat.HotDegree,
+			Loads:     stat.Loads,
+		})
+	}
+	return &RegionBuckets{RegionID: regionID, Buckets: buckets, Lineage: lineage}
+}
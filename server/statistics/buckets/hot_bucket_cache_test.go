@@ -93,6 +93,49 @@ func TestPutItem(t *testing.T) {
 	}
 }
 
+func TestBucketLineage(t *testing.T) {
+	re := require.New(t)
+	cache := NewBucketsCache(context.Background())
+	parent := newTestBuckets(1, 1, [][]byte{[]byte("10"), []byte("20"), []byte("30"), []byte("40"), []byte("50")}, 0)
+	item, overlaps := cache.checkBucketsFlow(parent)
+	for i, degree := range []int{1, 5, 8, 2} {
+		item.stats[i].HotDegree = degree
+	}
+	cache.putItem(item, overlaps)
+	re.Nil(cache.getBucketLineage(1))
+
+	// region 2 is split from region 1 and inherits the buckets [30, 50).
+	cache.putItem(cache.checkBucketsFlow(newTestBuckets(2, 2, [][]byte{[]byte("30"), []byte("50")}, 0)))
+	lineage := cache.getBucketLineage(2)
+	re.NotNil(lineage)
+	re.Equal(uint64(2), lineage.Version)
+	re.Len(lineage.Parents, 1)
+	re.Equal(uint64(1), lineage.Parents[0].RegionID)
+	re.Equal(2, lineage.Parents[0].InheritedBuckets)
+	re.Equal(0.5, lineage.Parents[0].Proportion)
+	re.Equal(8, lineage.Parents[0].HotDegree)
+	// the hot degree is inherited instead of being reset.
+	re.Equal(7, cache.bucketsOfRegion[2].stats[0].HotDegree)
+
+	// region 1 shrinks to [10, 30) and has no lineage.
+	cache.putItem(cache.checkBucketsFlow(newTestBuckets(1, 2, [][]byte{[]byte("10"), []byte("20"), []byte("30")}, 0)))
+	re.Nil(cache.getBucketLineage(1))
+	re.Equal(4, cache.bucketsOfRegion[1].stats[1].HotDegree)
+
+	// region 3 is merged from region 1 and 2.
+	cache.putItem(cache.checkBucketsFlow(newTestBuckets(3, 3, [][]byte{[]byte("10"), []byte("50")}, 0)))
+	lineage = cache.getBucketLineage(3)
+	re.NotNil(lineage)
+	re.Len(lineage.Parents, 2)
+	re.ElementsMatch([]uint64{1, 2}, []uint64{lineage.Parents[0].RegionID, lineage.Parents[1].RegionID})
+	for _, parent := range lineage.Parents {
+		re.Equal(1.0, parent.Proportion)
+	}
+	// the lineages of the merged regions are dropped.
+	re.Len(cache.bucketsOfRegion, 1)
+	re.Nil(cache.getBucketLineage(2))
+}
+
 func TestConvertToBucketTreeStat(t *testing.T) {
 	re := require.New(t)
 	buckets := &metapb.Buckets{
@@ -25,6 +25,8 @@ type flowItemTaskKind uint32
 const (
 	checkBucketsTaskType flowItemTaskKind = iota
 	collectBucketStatsTaskType
+	collectRegionBucketsTaskType
+	collectBucketLineageTaskType
 )
 
 func (kind flowItemTaskKind) String() string {
@@ -33,6 +35,10 @@ func (kind flowItemTaskKind) String() string {
 		return "check_buckets"
 	case collectBucketStatsTaskType:
 		return "collect_bucket_stats"
+	case collectRegionBucketsTaskType:
+		return "collect_region_buckets"
+	case collectBucketLineageTaskType:
+		return "collect_bucket_lineage"
 	}
 	return "unknown"
 }
@@ -94,3 +100,83 @@ func (t *collectBucketStatsTask) WaitRet(ctx context.Context) map[uint64][]*Buck
 		return ret
 	}
 }
+
+type collectRegionBucketsTask struct {
+	regionIDs []uint64
+	ret       chan map[uint64][]*BucketStat // RegionID ==>Buckets
+}
+
+// NewCollectRegionBucketsTask creates task to collect the stats of all buckets of the given regions.
+func NewCollectRegionBucketsTask(regionIDs ...uint64) *collectRegionBucketsTask {
+	return &collectRegionBucketsTask{
+		regionIDs: regionIDs,
+		ret:       make(chan map[uint64][]*BucketStat, 1),
+	}
+}
+
+func (t *collectRegionBucketsTask) taskType() flowItemTaskKind {
+	return collectRegionBucketsTaskType
+}
+
+func (t *collectRegionBucketsTask) runTask(cache *HotBucketCache) {
+	ret := make(map[uint64][]*BucketStat, len(t.regionIDs))
+	for _, regionID := range t.regionIDs {
+		item, ok := cache.bucketsOfRegion[regionID]
+		if !ok {
+			continue
+		}
+		stats := make([]*BucketStat, 0, len(item.stats))
+		for _, stat := range item.stats {
+			stats = append(stats, stat.clone())
+		}
+		ret[regionID] = stats
+	}
+	t.ret <- ret
+}
+
+// WaitRet returns the result of the task.
+func (t *collectRegionBucketsTask) WaitRet(ctx context.Context) map[uint64][]*BucketStat {
+	select {
+	case <-ctx.Done():
+		return nil
+	case ret := <-t.ret:
+		return ret
+	}
+}
+
+type collectBucketLineageTask struct {
+	regionIDs []uint64
+	ret       chan map[uint64]*BucketLineage // RegionID ==> Lineage
+}
+
+// NewCollectBucketLineageTask creates task to collect the bucket lineages of the given regions.
+func NewCollectBucketLineageTask(regionIDs ...uint64) *collectBucketLineageTask {
+	return &collectBucketLineageTask{
+		regionIDs: regionIDs,
+		ret:       make(chan map[uint64]*BucketLineage, 1),
+	}
+}
+
+func (t *collectBucketLineageTask) taskType() flowItemTaskKind {
+	return collectBucketLineageTaskType
+}
+
+func (t *collectBucketLineageTask) runTask(cache *HotBucketCache) {
+	ret := make(map[uint64]*BucketLineage, len(t.regionIDs))
+	for _, regionID := range t.regionIDs {
+		if lineage := cache.getBucketLineage(regionID); lineage != nil {
+			ret[regionID] = lineage
+		}
+	}
+	t.ret <- ret
+}
+
+// WaitRet returns the result of the task.
+func (t *collectBucketLineageTask) WaitRet(ctx context.Context) map[uint64]*BucketLineage {
+	select {
+	case <-ctx.Done():
+		return nil
+	case ret := <-t.ret:
+		return ret
+	}
+}
@@ -19,6 +19,7 @@ import (
 	"time"
 
 	"github.com/tikv/pd/pkg/movingaverage"
+	"github.com/tikv/pd/pkg/workerpool"
 	"github.com/tikv/pd/server/core"
 )
 
@@ -28,79 +29,223 @@ var Denoising = true
 
 const queueCap = 20000
 
+// HotStatPoolName is the name of the worker pool of the hot statistics.
+const HotStatPoolName = "hot-stat"
+
+// defaultHotStatPoolSize makes the read and write flows run in parallel.
+const defaultHotStatPoolSize = 2
+
+// hotStatShards is the number of the shards of the hot peer cache of each kind.
+// The peers are sharded by their region IDs, so that the heartbeats can be
+// handled by up to 2*hotStatShards workers in parallel. The shards of a kind
+// share the source of the hot thresholds, which are got from the top TopNN peers
+// of the store across all the shards.
+const hotStatShards = 4
+
 // HotCache is a cache hold hot regions.
 type HotCache struct {
-	ctx        context.Context
-	writeCache *hotPeerCache
-	readCache  *hotPeerCache
+	ctx         context.Context
+	writeShards []*hotPeerCache
+	readShards  []*hotPeerCache
+	// pool runs the tasks of the caches. The tasks are keyed by the shard, since
+	// a shard is only accessed by one task at a time, and in order.
+	pool *workerpool.Pool
 }
 
 // NewHotCache creates a new hot spot cache.
 func NewHotCache(ctx context.Context) *HotCache {
 	w := &HotCache{
-		ctx:        ctx,
-		writeCache: NewHotPeerCache(Write),
-		readCache:  NewHotPeerCache(Read),
+		ctx:  ctx,
+		pool: workerpool.New(ctx, HotStatPoolName, defaultHotStatPoolSize, queueCap),
+	}
+	writeThresholds, readThresholds := newHotThresholdSource(), newHotThresholdSource()
+	for i := 0; i < hotStatShards; i++ {
+		w.writeShards = append(w.writeShards, newHotPeerCacheShard(Write, writeThresholds))
+		w.readShards = append(w.readShards, newHotPeerCacheShard(Read, readThresholds))
 	}
-	go w.updateItems(w.readCache.taskQueue, w.runReadTask)
-	go w.updateItems(w.writeCache.taskQueue, w.runWriteTask)
 	return w
 }
 
+// GetWorkerPool returns the worker pool of the hot cache.
+func (w *HotCache) GetWorkerPool() *workerpool.Pool {
+	return w.pool
+}
+
+func shardOf(regionID uint64) int {
+	return int(regionID % hotStatShards)
+}
+
+// shardKey returns the key of the tasks of the shard in the pool.
+func shardKey(kind RWType, shard int) uint64 {
+	return uint64(kind)*hotStatShards + uint64(shard)
+}
+
+func (w *HotCache) getShard(kind RWType, shard int) *hotPeerCache {
+	if kind == Write {
+		return w.writeShards[shard]
+	}
+	return w.readShards[shard]
+}
+
+// submit puts the task into the queue of the shard.
+func (w *HotCache) submit(kind RWType, shard int, task flowItemTask) bool {
+	cache := w.getShard(kind, shard)
+	return w.pool.TrySubmitKeyed(shardKey(kind, shard), func() {
+		if task != nil {
+			// TODO: do we need a run-task timeout to protect the queue won't be stuck by a task?
+			task.runTask(cache)
+		}
+	})
+}
+
+// checkAsync puts the task into the queue of the shard of its region, or of all
+// the shards if it isn't the task of a region.
+func (w *HotCache) checkAsync(kind RWType, task flowItemTask) bool {
+	if t, ok := task.(regionTask); ok {
+		return w.submit(kind, shardOf(t.regionID()), task)
+	}
+	succ := true
+	for i := 0; i < hotStatShards; i++ {
+		succ = w.submit(kind, i, task) && succ
+	}
+	return succ
+}
+
 // CheckWriteAsync puts the flowItem into queue, and check it asynchronously
 func (w *HotCache) CheckWriteAsync(task flowItemTask) bool {
-	select {
-	case w.writeCache.taskQueue <- task:
-		return true
-	default:
-		return false
-	}
+	return w.checkAsync(Write, task)
 }
 
 // CheckReadAsync puts the flowItem into queue, and check it asynchronously
 func (w *HotCache) CheckReadAsync(task flowItemTask) bool {
-	select {
-	case w.readCache.taskQueue <- task:
-		return true
-	default:
-		return false
-	}
+	return w.checkAsync(Read, task)
 }
 
 // RegionStats returns hot items according to kind
 func (w *HotCache) RegionStats(kind RWType, minHotDegree int) map[uint64][]*HotPeerStat {
-	task := newCollectRegionStatsTask(minHotDegree)
-	var succ bool
-	switch kind {
-	case Write:
-		succ = w.CheckWriteAsync(task)
-	case Read:
-		succ = w.CheckReadAsync(task)
+	tasks := make([]*collectRegionStatsTask, hotStatShards)
+	for i := range tasks {
+		tasks[i] = newCollectRegionStatsTask(minHotDegree)
+		if !w.submit(kind, i, tasks[i]) {
+			return nil
+		}
 	}
-	if !succ {
-		return nil
+	ret := make(map[uint64][]*HotPeerStat)
+	for _, task := range tasks {
+		for storeID, stats := range task.waitRet(w.ctx) {
+			ret[storeID] = append(ret[storeID], stats...)
+		}
 	}
-	return task.waitRet(w.ctx)
+	return ret
+}
+
+// Snapshot returns the snapshot of the hot peers according to kind, which keeps
+// at most MaxHotPeerSnapshotItems hottest peers.
+func (w *HotCache) Snapshot(kind RWType) *HotPeerSnapshot {
+	tasks := make([]*snapshotTask, hotStatShards)
+	for i := range tasks {
+		tasks[i] = newSnapshotTask()
+		if !w.submit(kind, i, tasks[i]) {
+			return nil
+		}
+	}
+	var ret *HotPeerSnapshot
+	for _, task := range tasks {
+		snapshot := task.waitRet(w.ctx)
+		if snapshot == nil {
+			return nil
+		}
+		if ret == nil {
+			ret = snapshot
+		} else {
+			ret.Items = append(ret.Items, snapshot.Items...)
+		}
+	}
+	ret.bound(MaxHotPeerSnapshotItems)
+	return ret
+}
+
+// Restore restores the hot peers from the snapshot according to kind,
+// and returns the number of the restored peers.
+func (w *HotCache) Restore(kind RWType, snapshot *HotPeerSnapshot) int {
+	shards := make([]*HotPeerSnapshot, hotStatShards)
+	for i := range shards {
+		shards[i] = &HotPeerSnapshot{Timestamp: snapshot.Timestamp}
+	}
+	for _, item := range snapshot.Items {
+		shard := shards[shardOf(item.RegionID)]
+		shard.Items = append(shard.Items, item)
+	}
+	tasks := make([]*restoreSnapshotTask, hotStatShards)
+	for i := range tasks {
+		tasks[i] = newRestoreSnapshotTask(shards[i])
+		if !w.submit(kind, i, tasks[i]) {
+			tasks = tasks[:i]
+			break
+		}
+	}
+	count := 0
+	for _, task := range tasks {
+		count += task.waitRet(w.ctx)
+	}
+	return count
 }
 
 // IsRegionHot checks if the region is hot.
 func (w *HotCache) IsRegionHot(region *core.RegionInfo, minHotDegree int) bool {
+	shard := shardOf(region.GetID())
 	writeIsRegionHotTask := newIsRegionHotTask(region, minHotDegree)
 	readIsRegionHotTask := newIsRegionHotTask(region, minHotDegree)
-	succ1 := w.CheckWriteAsync(writeIsRegionHotTask)
-	succ2 := w.CheckReadAsync(readIsRegionHotTask)
+	succ1 := w.submit(Write, shard, writeIsRegionHotTask)
+	succ2 := w.submit(Read, shard, readIsRegionHotTask)
 	if succ1 && succ2 {
 		return writeIsRegionHotTask.waitRet(w.ctx) || readIsRegionHotTask.waitRet(w.ctx)
 	}
 	return false
 }
 
+// GetRegionPeerStats returns the stats of the peers of the region according to kind,
+// regardless of their hot degree.
+func (w *HotCache) GetRegionPeerStats(kind RWType, region *core.RegionInfo) []*HotPeerStat {
+	task := newGetRegionPeerStatsTask(region)
+	if !w.submit(kind, shardOf(region.GetID()), task) {
+		return nil
+	}
+	return task.waitRet(w.ctx)
+}
+
 // CollectMetrics collects the hot cache metrics.
 func (w *HotCache) CollectMetrics() {
-	writeMetricsTask := newCollectMetricsTask("write")
-	readMetricsTask := newCollectMetricsTask("read")
-	w.CheckWriteAsync(writeMetricsTask)
-	w.CheckReadAsync(readMetricsTask)
+	for _, kind := range []RWType{Write, Read} {
+		tasks := make([]*collectMetricsTask, 0, hotStatShards)
+		queueLen := 0
+		for i := 0; i < hotStatShards; i++ {
+			if l := w.pool.KeyedQueueLen(shardKey(kind, i)); l > queueLen {
+				queueLen = l
+			}
+			task := newCollectMetricsTask()
+			if w.submit(kind, i, task) {
+				tasks = append(tasks, task)
+			}
+		}
+		hotCacheFlowQueueStatusGauge.WithLabelValues(kind.String()).Set(float64(queueLen))
+		merged := make(map[uint64]*hotPeerStoreMetrics)
+		for _, task := range tasks {
+			for storeID, metrics := range task.waitRet(w.ctx) {
+				if m, ok := merged[storeID]; ok {
+					m.merge(metrics)
+				} else {
+					merged[storeID] = metrics
+				}
+			}
+		}
+		// export the thresholds in effect now rather than the ones got by the tasks.
+		thresholds := w.getShard(kind, 0).thresholds
+		for storeID, metrics := range merged {
+			metrics.thresholds = thresholds.calcHotThresholds(kind, storeID)
+			setHotPeerStoreMetrics(storeID, kind.String(), metrics)
+		}
+	}
 }
 
 // ResetMetrics resets the hot cache metrics.
@@ -118,77 +263,39 @@ func incMetrics(name string, storeID uint64, kind RWType) {
 	}
 }
 
-func (w *HotCache) updateItems(queue <-chan flowItemTask, runTask func(task flowItemTask)) {
-	for {
-		select {
-		case <-w.ctx.Done():
-			return
-		case task := <-queue:
-			runTask(task)
-		}
-	}
-}
-
-func (w *HotCache) runReadTask(task flowItemTask) {
-	if task != nil {
-		// TODO: do we need a run-task timeout to protect the queue won't be stuck by a task?
-		task.runTask(w.readCache)
-		hotCacheFlowQueueStatusGauge.WithLabelValues(Read.String()).Set(float64(len(w.readCache.taskQueue)))
-	}
-}
-
-func (w *HotCache) runWriteTask(task flowItemTask) {
-	if task != nil {
-		// TODO: do we need a run-task timeout to protect the queue won't be stuck by a task?
-		task.runTask(w.writeCache)
-		hotCacheFlowQueueStatusGauge.WithLabelValues(Write.String()).Set(float64(len(w.writeCache.taskQueue)))
-	}
-}
-
 // Update updates the cache.
 // This is used for mockcluster, for test purpose.
 func (w *HotCache) Update(item *HotPeerStat) {
-	switch item.Kind {
-	case Write:
-		w.writeCache.updateStat(item)
-	case Read:
-		w.readCache.updateStat(item)
-	}
+	w.getShard(item.Kind, shardOf(item.RegionID)).updateStat(item)
 }
 
 // CheckWritePeerSync checks the write status, returns update items.
 // This is used for mockcluster, for test purpose.
 func (w *HotCache) CheckWritePeerSync(peer *core.PeerInfo, region *core.RegionInfo) *HotPeerStat {
-	return w.writeCache.checkPeerFlow(peer, region)
+	return w.getShard(Write, shardOf(region.GetID())).checkPeerFlow(peer, region)
 }
 
 // CheckReadPeerSync checks the read status, returns update items.
 // This is used for mockcluster, for test purpose.
 func (w *HotCache) CheckReadPeerSync(peer *core.PeerInfo, region *core.RegionInfo) *HotPeerStat {
-	return w.readCache.checkPeerFlow(peer, region)
+	return w.getShard(Read, shardOf(region.GetID())).checkPeerFlow(peer, region)
 }
 
 // ExpiredReadItems returns the read items which are already expired.
 // This is used for mockcluster, for test purpose.
 func (w *HotCache) ExpiredReadItems(region *core.RegionInfo) []*HotPeerStat {
-	return w.readCache.collectExpiredItems(region)
+	return w.getShard(Read, shardOf(region.GetID())).collectExpiredItems(region)
 }
 
 // ExpiredWriteItems returns the write items which are already expired.
 // This is used for mockcluster, for test purpose.
 func (w *HotCache) ExpiredWriteItems(region *core.RegionInfo) []*HotPeerStat {
-	return w.writeCache.collectExpiredItems(region)
+	return w.getShard(Write, shardOf(region.GetID())).collectExpiredItems(region)
 }
 
 // GetFilledPeriod returns filled period.
 // This is used for mockcluster, for test purpose.
 func (w *HotCache) GetFilledPeriod(kind RWType) int {
-	var reportIntervalSecs int
-	switch kind {
-	case Write:
-		reportIntervalSecs = w.writeCache.reportIntervalSecs
-	case Read:
-		reportIntervalSecs = w.readCache.reportIntervalSecs
-	}
+	reportIntervalSecs := w.getShard(kind, 0).reportIntervalSecs
 	return movingaverage.NewTimeMedian(DefaultAotSize, rollingWindowsSize, time.Duration(reportIntervalSecs)*time.Second).GetFilledPeriod()
 }
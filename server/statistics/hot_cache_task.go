@@ -29,6 +29,9 @@ const (
 	collectRegionStatsTaskType
 	isRegionHotTaskType
 	collectMetricsTaskType
+	snapshotTaskType
+	restoreSnapshotTaskType
+	getRegionPeerStatsTaskType
 )
 
 // flowItemTask indicates the task in flowItem queue
@@ -37,6 +40,12 @@ type flowItemTask interface {
 	runTask(cache *hotPeerCache)
 }
 
+// regionTask is the flowItemTask of a region, which only runs on the shard of
+// the region. The other tasks run on all the shards.
+type regionTask interface {
+	regionID() uint64
+}
+
 type checkPeerTask struct {
 	peerInfo   *core.PeerInfo
 	regionInfo *core.RegionInfo
@@ -54,6 +63,10 @@ func (t *checkPeerTask) taskType() flowItemTaskKind {
 	return checkPeerTaskType
 }
 
+func (t *checkPeerTask) regionID() uint64 {
+	return t.regionInfo.GetID()
+}
+
 func (t *checkPeerTask) runTask(cache *hotPeerCache) {
 	stat := cache.checkPeerFlow(t.peerInfo, t.regionInfo)
 	if stat != nil {
@@ -76,6 +89,10 @@ func (t *checkExpiredTask) taskType() flowItemTaskKind {
 	return checkExpiredTaskType
 }
 
+func (t *checkExpiredTask) regionID() uint64 {
+	return t.region.GetID()
+}
+
 func (t *checkExpiredTask) runTask(cache *hotPeerCache) {
 	expiredStats := cache.collectExpiredItems(t.region)
 	for _, stat := range expiredStats {
@@ -172,12 +189,12 @@ func (t *isRegionHotTask) waitRet(ctx context.Context) bool {
 }
 
 type collectMetricsTask struct {
-	typ string
+	ret chan map[uint64]*hotPeerStoreMetrics
 }
 
-func newCollectMetricsTask(typ string) *collectMetricsTask {
+func newCollectMetricsTask() *collectMetricsTask {
 	return &collectMetricsTask{
-		typ: typ,
+		ret: make(chan map[uint64]*hotPeerStoreMetrics, 1),
 	}
 }
 
@@ -186,5 +203,99 @@ func (t *collectMetricsTask) taskType() flowItemTaskKind {
 }
 
 func (t *collectMetricsTask) runTask(cache *hotPeerCache) {
-	cache.collectMetrics(t.typ)
+	t.ret <- cache.collectMetrics()
+}
+
+func (t *collectMetricsTask) waitRet(ctx context.Context) map[uint64]*hotPeerStoreMetrics {
+	select {
+	case <-ctx.Done():
+		return nil
+	case r := <-t.ret:
+		return r
+	}
+}
+
+type snapshotTask struct {
+	ret chan *HotPeerSnapshot
+}
+
+func newSnapshotTask() *snapshotTask {
+	return &snapshotTask{
+		ret: make(chan *HotPeerSnapshot, 1),
+	}
+}
+
+func (t *snapshotTask) taskType() flowItemTaskKind {
+	return snapshotTaskType
+}
+
+func (t *snapshotTask) runTask(cache *hotPeerCache) {
+	t.ret <- cache.snapshot()
+}
+
+func (t *snapshotTask) waitRet(ctx context.Context) *HotPeerSnapshot {
+	select {
+	case <-ctx.Done():
+		return nil
+	case r := <-t.ret:
+		return r
+	}
+}
+
+type restoreSnapshotTask struct {
+	snapshot *HotPeerSnapshot
+	ret      chan int
+}
+
+func newRestoreSnapshotTask(snapshot *HotPeerSnapshot) *restoreSnapshotTask {
+	return &restoreSnapshotTask{
+		snapshot: snapshot,
+		ret:      make(chan int, 1),
+	}
+}
+
+func (t *restoreSnapshotTask) taskType() flowItemTaskKind {
+	return restoreSnapshotTaskType
+}
+
+func (t *restoreSnapshotTask) runTask(cache *hotPeerCache) {
+	t.ret <- cache.restore(t.snapshot)
+}
+
+func (t *restoreSnapshotTask) waitRet(ctx context.Context) int {
+	select {
+	case <-ctx.Done():
+		return 0
+	case r := <-t.ret:
+		return r
+	}
+}
+
+type getRegionPeerStatsTask struct {
+	region *core.RegionInfo
+	ret    chan []*HotPeerStat
+}
+
+func newGetRegionPeerStatsTask(region *core.RegionInfo) *getRegionPeerStatsTask {
+	return &getRegionPeerStatsTask{
+		region: region,
+		ret:    make(chan []*HotPeerStat, 1),
+	}
+}
+
+func (t *getRegionPeerStatsTask) taskType() flowItemTaskKind {
+	return getRegionPeerStatsTaskType
+}
+
+func (t *getRegionPeerStatsTask) runTask(cache *hotPeerCache) {
+	t.ret <- cache.getRegionPeerStats(t.region)
+}
+
+func (t *getRegionPeerStatsTask) waitRet(ctx context.Context) []*HotPeerStat {
+	select {
+	case <-ctx.Done():
+		return nil
+	case r := <-t.ret:
+		return r
+	}
 }
@@ -22,6 +22,7 @@ import (
 	"github.com/pingcap/kvprotov2/pkg/metapb"
 	"github.com/pingcap/log"
 	"github.com/tikv/pd/pkg/slice"
+	"github.com/tikv/pd/pkg/syncutil"
 	"github.com/tikv/pd/server/core"
 )
 
@@ -58,19 +59,25 @@ type hotPeerCache struct {
 	peersOfStore       map[uint64]*TopN               // storeID -> hot peers
 	storesOfRegion     map[uint64]map[uint64]struct{} // regionID -> storeIDs
 	regionsOfStore     map[uint64]map[uint64]struct{} // storeID -> regionIDs
+	thresholds         *hotThresholdSource            // the top peers to get the hot thresholds
 	topNTTL            time.Duration
 	reportIntervalSecs int
-	taskQueue          chan flowItemTask
 }
 
 // NewHotPeerCache creates a hotPeerCache
 func NewHotPeerCache(kind RWType) *hotPeerCache {
+	return newHotPeerCacheShard(kind, newHotThresholdSource())
+}
+
+// newHotPeerCacheShard creates a hotPeerCache holding a shard of the peers, the
+// hot thresholds are got from the given source shared by all the shards.
+func newHotPeerCacheShard(kind RWType, thresholds *hotThresholdSource) *hotPeerCache {
 	c := &hotPeerCache{
 		kind:           kind,
+		thresholds:     thresholds,
 		peersOfStore:   make(map[uint64]*TopN),
 		storesOfRegion: make(map[uint64]map[uint64]struct{}),
 		regionsOfStore: make(map[uint64]map[uint64]struct{}),
-		taskQueue:      make(chan flowItemTask, queueCap),
 	}
 	if kind == Write {
 		c.reportIntervalSecs = WriteReportInterval
@@ -81,6 +88,63 @@ func NewHotPeerCache(kind RWType) *hotPeerCache {
 	return c
 }
 
+// hotThresholdSource keeps the peers of each store of all the shards of a kind,
+// so that the hot thresholds are got from the top TopNN peers of the store as if
+// the cache is not sharded. It can be accessed by the shards concurrently.
+type hotThresholdSource struct {
+	syncutil.RWMutex
+	peersOfStore map[uint64]*TopN
+}
+
+func newHotThresholdSource() *hotThresholdSource {
+	return &hotThresholdSource{peersOfStore: make(map[uint64]*TopN)}
+}
+
+func (s *hotThresholdSource) getPeers(storeID uint64) *TopN {
+	s.RLock()
+	defer s.RUnlock()
+	return s.peersOfStore[storeID]
+}
+
+func (s *hotThresholdSource) put(item *HotPeerStat, ttl time.Duration) {
+	peers := s.getPeers(item.StoreID)
+	if peers == nil {
+		s.Lock()
+		if peers = s.peersOfStore[item.StoreID]; peers == nil {
+			peers = NewTopN(DimLen, TopNN, ttl)
+			s.peersOfStore[item.StoreID] = peers
+		}
+		s.Unlock()
+	}
+	peers.Put(item)
+}
+
+func (s *hotThresholdSource) remove(item *HotPeerStat) {
+	if peers := s.getPeers(item.StoreID); peers != nil {
+		peers.Remove(item.RegionID)
+	}
+}
+
+// calcHotThresholds returns the hot thresholds of the store, which are the loads
+// of the TopNN-th hottest peers with a ratio, or the min thresholds if the store
+// doesn't have enough peers.
+func (s *hotThresholdSource) calcHotThresholds(kind RWType, storeID uint64) []float64 {
+	statKinds := kind.RegionStats()
+	mins := make([]float64, len(statKinds))
+	for i, k := range statKinds {
+		mins[i] = minHotThresholds[k]
+	}
+	tn := s.getPeers(storeID)
+	if tn == nil || tn.Len() < TopNN {
+		return mins
+	}
+	ret := make([]float64, len(statKinds))
+	for i := range ret {
+		ret[i] = math.Max(tn.GetTopNMin(i).(*HotPeerStat).GetLoad(statKinds[i])*HotThresholdRatio, mins[i])
+	}
+	return ret
+}
+
 // TODO: rename RegionStats as PeerStats
 // RegionStats returns hot items
 func (f *hotPeerCache) RegionStats(minHotDegree int) map[uint64][]*HotPeerStat {
@@ -250,16 +314,35 @@ func (f *hotPeerCache) checkColdPeer(storeID uint64, reportRegions map[uint64]*c
 	return
 }
 
-func (f *hotPeerCache) collectMetrics(typ string) {
+// hotPeerStoreMetrics is the metrics of the hot peers of a store.
+type hotPeerStoreMetrics struct {
+	length     int
+	thresholds []float64
+}
+
+// merge adds the length of another shard, the thresholds are shared by the shards.
+func (m *hotPeerStoreMetrics) merge(other *hotPeerStoreMetrics) {
+	m.length += other.length
+}
+
+func (f *hotPeerCache) collectMetrics() map[uint64]*hotPeerStoreMetrics {
+	metrics := make(map[uint64]*hotPeerStoreMetrics, len(f.peersOfStore))
 	for storeID, peers := range f.peersOfStore {
-		store := storeTag(storeID)
-		thresholds := f.calcHotThresholds(storeID)
-		hotCacheStatusGauge.WithLabelValues("total_length", store, typ).Set(float64(peers.Len()))
-		hotCacheStatusGauge.WithLabelValues("byte-rate-threshold", store, typ).Set(thresholds[ByteDim])
-		hotCacheStatusGauge.WithLabelValues("key-rate-threshold", store, typ).Set(thresholds[KeyDim])
-		// for compatibility
-		hotCacheStatusGauge.WithLabelValues("hotThreshold", store, typ).Set(thresholds[ByteDim])
+		metrics[storeID] = &hotPeerStoreMetrics{
+			length:     peers.Len(),
+			thresholds: f.calcHotThresholds(storeID),
+		}
 	}
+	return metrics
+}
+
+func setHotPeerStoreMetrics(storeID uint64, typ string, metrics *hotPeerStoreMetrics) {
+	store := storeTag(storeID)
+	hotCacheStatusGauge.WithLabelValues("total_length", store, typ).Set(float64(metrics.length))
+	hotCacheStatusGauge.WithLabelValues("byte-rate-threshold", store, typ).Set(metrics.thresholds[ByteDim])
+	hotCacheStatusGauge.WithLabelValues("key-rate-threshold", store, typ).Set(metrics.thresholds[KeyDim])
+	// for compatibility
+	hotCacheStatusGauge.WithLabelValues("hotThreshold", store, typ).Set(metrics.thresholds[ByteDim])
 }
 
 func (f *hotPeerCache) getOldHotPeerStat(regionID, storeID uint64) *HotPeerStat {
@@ -271,21 +354,18 @@ func (f *hotPeerCache) getOldHotPeerStat(regionID, storeID uint64) *HotPeerStat
 	return nil
 }
 
-func (f *hotPeerCache) calcHotThresholds(storeID uint64) []float64 {
-	statKinds := f.kind.RegionStats()
-	mins := make([]float64, len(statKinds))
-	for i, k := range statKinds {
-		mins[i] = minHotThresholds[k]
-	}
-	tn, ok := f.peersOfStore[storeID]
-	if !ok || tn.Len() < TopNN {
-		return mins
-	}
-	ret := make([]float64, len(statKinds))
-	for i := range ret {
-		ret[i] = math.Max(tn.GetTopNMin(i).(*HotPeerStat).GetLoad(statKinds[i])*HotThresholdRatio, mins[i])
+func (f *hotPeerCache) getRegionPeerStats(region *core.RegionInfo) []*HotPeerStat {
+	var stats []*HotPeerStat
+	for _, peer := range region.GetPeers() {
+		if stat := f.getOldHotPeerStat(region.GetID(), peer.GetStoreId()); stat != nil {
+			stats = append(stats, stat)
+		}
 	}
-	return ret
+	return stats
+}
+
+func (f *hotPeerCache) calcHotThresholds(storeID uint64) []float64 {
+	return f.thresholds.calcHotThresholds(f.kind, storeID)
 }
 
 // gets the storeIDs, including old region and new region
@@ -471,6 +551,7 @@ func (f *hotPeerCache) putItem(item *HotPeerStat) {
 		f.peersOfStore[item.StoreID] = peers
 	}
 	peers.Put(item)
+	f.thresholds.put(item, f.topNTTL)
 	stores, ok := f.storesOfRegion[item.RegionID]
 	if !ok {
 		stores = make(map[uint64]struct{})
@@ -489,6 +570,7 @@ func (f *hotPeerCache) removeItem(item *HotPeerStat) {
 	if peers, ok := f.peersOfStore[item.StoreID]; ok {
 		peers.Remove(item.RegionID)
 	}
+	f.thresholds.remove(item)
 	if stores, ok := f.storesOfRegion[item.RegionID]; ok {
 		delete(stores, item.StoreID)
 	}
@@ -15,6 +15,7 @@
 package statistics
 
 import (
+	"context"
 	"math/rand"
 	"sort"
 	"testing"
@@ -632,6 +633,51 @@ func TestUnstableData(t *testing.T) {
 	}
 }
 
+func TestHotPeerSnapshot(t *testing.T) {
+	re := require.New(t)
+	for _, kind := range []RWType{Read, Write} {
+		cache := NewHotPeerCache(kind)
+		region := buildRegion(kind, 3, 60)
+		for i := 0; i < 3; i++ {
+			checkAndUpdate(re, cache, region, 3)
+		}
+		snapshot := cache.snapshot()
+		re.Len(snapshot.Items, 3)
+		re.False(snapshot.IsStale(time.Minute))
+
+		restored := NewHotPeerCache(kind)
+		re.Equal(3, restored.restore(snapshot))
+		for _, peer := range region.GetPeers() {
+			origin := cache.getOldHotPeerStat(region.GetID(), peer.GetStoreId())
+			item := restored.getOldHotPeerStat(region.GetID(), peer.GetStoreId())
+			re.NotNil(item)
+			re.Equal(origin.HotDegree, item.HotDegree)
+			re.Equal(origin.AntiCount, item.AntiCount)
+			re.Equal(origin.IsLeader(), item.IsLeader())
+			re.Equal(origin.GetLoads(), item.GetLoads())
+		}
+		// the peers which are already in the cache are skipped.
+		re.Equal(0, restored.restore(snapshot))
+
+		// the snapshot is split into the chunks with the same timestamp.
+		chunks := snapshot.Split(2)
+		re.Len(chunks, 2)
+		re.Len(chunks[0].Items, 2)
+		re.Len(chunks[1].Items, 1)
+		re.Equal(snapshot.Timestamp, chunks[1].Timestamp)
+		re.Len((&HotPeerSnapshot{}).Split(2), 1)
+
+		// the hottest items are kept.
+		snapshot.Items[1].HotDegree = snapshot.Items[0].HotDegree + 1
+		hottest := snapshot.Items[1]
+		snapshot.bound(1)
+		re.Equal([]*HotPeerSnapshotItem{hottest}, snapshot.Items)
+
+		snapshot.Timestamp = time.Now().Add(-10 * time.Minute).Unix()
+		re.True(snapshot.IsStale(5 * time.Minute))
+	}
+}
+
 func BenchmarkCheckRegionFlow(b *testing.B) {
 	cache := NewHotPeerCache(Read)
 	region := buildRegion(Read, 3, 10)
@@ -654,3 +700,39 @@ func BenchmarkCheckRegionFlow(b *testing.B) {
 		}
 	}
 }
+
+func TestHotCacheShards(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cache := NewHotCache(ctx)
+	count := 2 * hotStatShards
+	for regionID := uint64(1); regionID <= uint64(count); regionID++ {
+		cache.Update(&HotPeerStat{StoreID: 1, RegionID: regionID, Kind: Write, Loads: make([]float64, RegionStatCount), actionType: Add})
+	}
+	// the results of all the shards are merged.
+	re.Len(cache.RegionStats(Write, 0)[1], count)
+	re.Empty(cache.RegionStats(Read, 0))
+	re.True(cache.IsRegionHot(core.NewRegionInfo(&metapb.Region{Id: 3, Peers: []*metapb.Peer{{StoreId: 1}}}, nil), 0))
+
+	snapshot := cache.Snapshot(Write)
+	re.Len(snapshot.Items, count)
+	restored := NewHotCache(ctx)
+	re.Equal(count, restored.Restore(Write, snapshot))
+	re.Len(restored.RegionStats(Write, 0)[1], count)
+
+	// the hot thresholds are got from the top TopNN peers of all the shards.
+	for regionID := uint64(1); regionID <= TopNN; regionID++ {
+		loads := make([]float64, RegionStatCount)
+		loads[RegionWriteBytes] = float64(regionID * units.MiB)
+		cache.Update(&HotPeerStat{StoreID: 2, RegionID: regionID, Kind: Write, Loads: loads, actionType: Add})
+		for i := 0; i < hotStatShards; i++ {
+			thresholds := cache.getShard(Write, i).calcHotThresholds(2)
+			if regionID < TopNN {
+				re.Equal(minHotThresholds[RegionWriteBytes], thresholds[ByteDim])
+			} else {
+				re.Equal(units.MiB*HotThresholdRatio, thresholds[ByteDim])
+			}
+		}
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+)
+
+const (
+	// MaxHotPeerSnapshotItems is the max number of the items in a snapshot, the
+	// hottest ones are kept.
+	MaxHotPeerSnapshotItems = 16384
+	// HotPeerSnapshotChunkSize is the max number of the items persisted in a key,
+	// which keeps the value far below the request size limit of etcd.
+	HotPeerSnapshotChunkSize = 1024
+)
+
+// HotPeerSnapshot is a compact snapshot of the hot peer cache. It is persisted
+// periodically so that a new PD leader can warm up its hot peer cache instead of
+// rebuilding it from scratch.
+type HotPeerSnapshot struct {
+	// Timestamp is the unix time in seconds when the snapshot is taken.
+	Timestamp int64                  `json:"timestamp"`
+	Items     []*HotPeerSnapshotItem `json:"items"`
+}
+
+// HotPeerSnapshotItem records the statistics of one hot peer in the snapshot.
+type HotPeerSnapshotItem struct {
+	StoreID      uint64    `json:"store_id"`
+	RegionID     uint64    `json:"region_id"`
+	HotDegree    int       `json:"hot_degree"`
+	AntiCount    int       `json:"anti_count"`
+	Loads        []float64 `json:"loads"`
+	IsLeader     bool      `json:"is_leader"`
+	PeerStoreIDs []uint64  `json:"peer_store_ids"`
+}
+
+// IsStale returns true if the snapshot is older than the given staleness.
+func (s *HotPeerSnapshot) IsStale(maxStaleness time.Duration) bool {
+	return time.Since(time.Unix(s.Timestamp, 0)) > maxStaleness
+}
+
+// bound keeps at most the given number of the hottest items.
+func (s *HotPeerSnapshot) bound(maxItems int) {
+	if len(s.Items) <= maxItems {
+		return
+	}
+	sort.SliceStable(s.Items, func(i, j int) bool { return s.Items[i].HotDegree > s.Items[j].HotDegree })
+	s.Items = s.Items[:maxItems]
+}
+
+// Split splits the snapshot into the chunks with at most the given number of items,
+// which have the same timestamp. An empty snapshot is split into one empty chunk.
+func (s *HotPeerSnapshot) Split(chunkSize int) []*HotPeerSnapshot {
+	chunks := []*HotPeerSnapshot{{Timestamp: s.Timestamp}}
+	for _, item := range s.Items {
+		chunk := chunks[len(chunks)-1]
+		if len(chunk.Items) >= chunkSize {
+			chunk = &HotPeerSnapshot{Timestamp: s.Timestamp}
+			chunks = append(chunks, chunk)
+		}
+		chunk.Items = append(chunk.Items, item)
+	}
+	return chunks
+}
+
+// snapshot takes a snapshot of all peers in the cache.
+func (f *hotPeerCache) snapshot() *HotPeerSnapshot {
+	regionStats := f.kind.RegionStats()
+	snapshot := &HotPeerSnapshot{Timestamp: time.Now().Unix()}
+	for _, peers := range f.peersOfStore {
+		for _, v := range peers.GetAll() {
+			stat := v.(*HotPeerStat)
+			loads := make([]float64, RegionStatCount)
+			for i, k := range regionStats {
+				if len(stat.rollingLoads) > i {
+					loads[k] = stat.rollingLoads[i].Get()
+				} else if len(stat.Loads) > int(k) {
+					loads[k] = stat.Loads[k]
+				}
+			}
+			storeIDs := make([]uint64, 0, len(stat.peers))
+			for _, peer := range stat.peers {
+				storeIDs = append(storeIDs, peer.GetStoreId())
+			}
+			snapshot.Items = append(snapshot.Items, &HotPeerSnapshotItem{
+				StoreID:      stat.StoreID,
+				RegionID:     stat.RegionID,
+				HotDegree:    stat.HotDegree,
+				AntiCount:    stat.AntiCount,
+				Loads:        loads,
+				IsLeader:     stat.isLeader,
+				PeerStoreIDs: storeIDs,
+			})
+		}
+	}
+	return snapshot
+}
+
+// restore puts the items of the snapshot into the cache. The peers which are
+// already reported by heartbeats are skipped.
+func (f *hotPeerCache) restore(snapshot *HotPeerSnapshot) int {
+	regionStats := f.kind.RegionStats()
+	reportInterval := time.Duration(f.reportIntervalSecs) * time.Second
+	lastUpdateTime := time.Unix(snapshot.Timestamp, 0)
+	count := 0
+	for _, item := range snapshot.Items {
+		if len(item.Loads) != int(RegionStatCount) || f.getOldHotPeerStat(item.RegionID, item.StoreID) != nil {
+			continue
+		}
+		peers := make([]*metapb.Peer, 0, len(item.PeerStoreIDs))
+		for _, storeID := range item.PeerStoreIDs {
+			peers = append(peers, &metapb.Peer{StoreId: storeID})
+		}
+		stat := &HotPeerStat{
+			StoreID:        item.StoreID,
+			RegionID:       item.RegionID,
+			HotDegree:      item.HotDegree,
+			AntiCount:      item.AntiCount,
+			Kind:           f.kind,
+			Loads:          item.Loads,
+			LastUpdateTime: lastUpdateTime,
+			actionType:     Add,
+			isLeader:       item.IsLeader,
+			interval:       uint64(f.reportIntervalSecs),
+			thresholds:     f.calcHotThresholds(item.StoreID),
+			peers:          peers,
+			source:         direct,
+			allowInherited: true,
+			rollingLoads:   make([]*dimStat, len(regionStats)),
+		}
+		for i, k := range regionStats {
+			ds := newDimStat(k, reportInterval)
+			ds.rolling.Set(item.Loads[k])
+			stat.rollingLoads[i] = ds
+		}
+		f.putItem(stat)
+		count++
+	}
+	return count
+}
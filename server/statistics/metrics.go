@@ -89,6 +89,13 @@ var (
 			Name:      "label_level",
 			Help:      "Number of regions in the different label level.",
 		}, []string{"type"})
+	regionLabelComplianceGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Namespace: "pd",
+			Subsystem: "regions",
+			Name:      "label_compliance",
+			Help:      "Number of regions with the region label and the number of them not satisfying placement rules.",
+		}, []string{"key", "value", "type"})
 	readByteHist = prometheus.NewHistogram(
 		prometheus.HistogramOpts{
 			Namespace: "pd",
@@ -196,6 +203,7 @@ func init() {
 	prometheus.MustRegister(configStatusGauge)
 	prometheus.MustRegister(StoreLimitGauge)
 	prometheus.MustRegister(regionLabelLevelGauge)
+	prometheus.MustRegister(regionLabelComplianceGauge)
 	prometheus.MustRegister(readByteHist)
 	prometheus.MustRegister(readKeyHist)
 	prometheus.MustRegister(writeKeyHist)
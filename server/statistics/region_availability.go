@@ -0,0 +1,81 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import "github.com/tikv/pd/server/core"
+
+// RegionAvailability is the availability risk of a region, which is derived
+// from the health of its voters.
+type RegionAvailability int
+
+// The availability risks of the regions, from low to high.
+const (
+	// RegionHealthy means all peers are healthy and the voters are enough.
+	RegionHealthy RegionAvailability = iota
+	// RegionDegraded means some peers are unhealthy or missing, but the region
+	// can still tolerate the failure of another voter.
+	RegionDegraded
+	// RegionAtRisk means the region will lose the quorum if another voter fails.
+	RegionAtRisk
+	// RegionUnavailable means the region has lost the quorum of the voters.
+	RegionUnavailable
+)
+
+var regionAvailabilityNames = [...]string{
+	RegionHealthy:     "healthy",
+	RegionDegraded:    "degraded",
+	RegionAtRisk:      "at-risk",
+	RegionUnavailable: "unavailable",
+}
+
+func (a RegionAvailability) String() string {
+	if a < 0 || int(a) >= len(regionAvailabilityNames) {
+		return "unknown"
+	}
+	return regionAvailabilityNames[a]
+}
+
+// GetRegionAvailability returns the availability risk of the region.
+// desiredVoters is the number of the voters required by the replication
+// config or the placement rules.
+//
+// The down voters cannot vote, and the pending voters may not be able to
+// catch up the log in time, so only the voters which are neither down nor
+// pending are regarded as healthy.
+func GetRegionAvailability(region *core.RegionInfo, desiredVoters int) RegionAvailability {
+	voters := region.GetVoters()
+	quorum := len(voters)/2 + 1
+	var alive, healthy int
+	for _, voter := range voters {
+		if region.GetDownPeer(voter.GetId()) != nil {
+			continue
+		}
+		alive++
+		if region.GetPendingPeer(voter.GetId()) == nil {
+			healthy++
+		}
+	}
+	switch {
+	case alive < quorum:
+		return RegionUnavailable
+	case healthy <= quorum && (healthy < len(voters) || len(voters) < desiredVoters):
+		return RegionAtRisk
+	case healthy < len(voters) || len(voters) < desiredVoters ||
+		len(region.GetDownPeers()) > 0 || len(region.GetPendingPeers()) > 0:
+		return RegionDegraded
+	default:
+		return RegionHealthy
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/config"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/schedule/placement"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestRegionAvailability(t *testing.T) {
+	re := require.New(t)
+	peers := []*metapb.Peer{
+		{Id: 1, StoreId: 1},
+		{Id: 2, StoreId: 2},
+		{Id: 3, StoreId: 3},
+		{Id: 4, StoreId: 4},
+		{Id: 5, StoreId: 5},
+		{Id: 6, StoreId: 6, Role: metapb.PeerRole_Learner},
+	}
+	down := func(ids ...int) core.RegionCreateOption {
+		stats := make([]*pdpb.PeerStats, 0, len(ids))
+		for _, id := range ids {
+			stats = append(stats, &pdpb.PeerStats{Peer: peers[id], DownSeconds: 3600})
+		}
+		return core.WithDownPeers(stats)
+	}
+	pending := func(ids ...int) core.RegionCreateOption {
+		pendingPeers := make([]*metapb.Peer, 0, len(ids))
+		for _, id := range ids {
+			pendingPeers = append(pendingPeers, peers[id])
+		}
+		return core.WithPendingPeers(pendingPeers)
+	}
+	newRegion := func(voters int, opts ...core.RegionCreateOption) *core.RegionInfo {
+		meta := &metapb.Region{Id: 1, Peers: append(append([]*metapb.Peer{}, peers[:voters]...), peers[5])}
+		return core.NewRegionInfo(meta, peers[0], opts...)
+	}
+
+	testCases := []struct {
+		region        *core.RegionInfo
+		desiredVoters int
+		expect        RegionAvailability
+	}{
+		{newRegion(3), 3, RegionHealthy},
+		{newRegion(1), 1, RegionHealthy},
+		// The learner is unhealthy.
+		{newRegion(3, down(5)), 3, RegionDegraded},
+		{newRegion(5, down(4)), 5, RegionDegraded},
+		{newRegion(3), 5, RegionDegraded},
+		{newRegion(3, down(2)), 3, RegionAtRisk},
+		{newRegion(3, pending(2)), 3, RegionAtRisk},
+		{newRegion(2), 3, RegionAtRisk},
+		{newRegion(5, down(3), pending(4)), 5, RegionAtRisk},
+		{newRegion(3, down(1, 2)), 3, RegionUnavailable},
+		{newRegion(5, down(2, 3, 4)), 5, RegionUnavailable},
+	}
+	for i, testCase := range testCases {
+		re.Equal(testCase.expect, GetRegionAvailability(testCase.region, testCase.desiredVoters), "case %d", i)
+	}
+	re.Equal("at-risk", RegionAtRisk.String())
+
+	// The availability risks are recorded in the region statistics.
+	manager := placement.NewRuleManager(storage.NewStorageWithMemoryBackend(), nil, nil)
+	re.NoError(manager.Initialize(3, []string{"zone", "rack", "host"}))
+	opt := config.NewTestOptions()
+	opt.SetPlacementRuleEnabled(true)
+	regionStats := NewRegionStatistics(opt, manager, nil)
+	meta := &metapb.Region{Id: 1, Peers: peers[:3]}
+	region := core.NewRegionInfo(meta, peers[0], down(2))
+	regionStats.Observe(region, nil)
+	re.True(regionStats.IsRegionStatsType(1, AtRiskRegion))
+	re.Equal(1, regionStats.GetRegionStatsCountByType(AtRiskRegion))
+	region = core.NewRegionInfo(meta, peers[0], down(1, 2))
+	regionStats.Observe(region, nil)
+	re.False(regionStats.IsRegionStatsType(1, AtRiskRegion))
+	re.True(regionStats.IsRegionStatsType(1, UnavailableRegion))
+	region = core.NewRegionInfo(meta, peers[0])
+	regionStats.Observe(region, nil)
+	re.Zero(regionStats.GetRegionStatsCountByType(DegradedRegion))
+	re.Zero(regionStats.GetRegionStatsCountByType(AtRiskRegion))
+	re.Zero(regionStats.GetRegionStatsCountByType(UnavailableRegion))
+}
@@ -38,6 +38,11 @@ const (
 	EmptyRegion
 	OversizedRegion
 	UndersizedRegion
+	// DegradedRegion, AtRiskRegion and UnavailableRegion are the availability
+	// risks of the regions, see RegionAvailability.
+	DegradedRegion
+	AtRiskRegion
+	UnavailableRegion
 )
 
 const nonIsolation = "none"
@@ -80,6 +85,9 @@ func NewRegionStatistics(opt *config.PersistOptions, ruleManager *placement.Rule
 	r.stats[EmptyRegion] = make(map[uint64]*RegionInfo)
 	r.stats[OversizedRegion] = make(map[uint64]*RegionInfo)
 	r.stats[UndersizedRegion] = make(map[uint64]*RegionInfo)
+	r.stats[DegradedRegion] = make(map[uint64]*RegionInfo)
+	r.stats[AtRiskRegion] = make(map[uint64]*RegionInfo)
+	r.stats[UnavailableRegion] = make(map[uint64]*RegionInfo)
 
 	r.offlineStats[MissPeer] = make(map[uint64]*core.RegionInfo)
 	r.offlineStats[ExtraPeer] = make(map[uint64]*core.RegionInfo)
@@ -101,6 +109,13 @@ func (r *RegionStatistics) GetRegionStatsByType(typ RegionStatisticType) []*core
 	return res
 }
 
+// GetRegionStatsCountByType gets the number of the regions of the given type.
+func (r *RegionStatistics) GetRegionStatsCountByType(typ RegionStatisticType) int {
+	r.RLock()
+	defer r.RUnlock()
+	return len(r.stats[typ])
+}
+
 // IsRegionStatsType returns whether the status of the region is the given type.
 func (r *RegionStatistics) IsRegionStatsType(regionID uint64, typ RegionStatisticType) bool {
 	r.RLock()
@@ -189,6 +204,8 @@ func (r *RegionStatistics) Observe(region *core.RegionInfo, stores []*core.Store
 		}
 	}
 
+	availability := GetRegionAvailability(region, desiredVoters)
+
 	// Better to make sure once any of these conditions changes, it will trigger the heartbeat `save_cache`.
 	// Otherwise, the state may be out-of-date for a long time, which needs another way to apply the change ASAP.
 	// For example, see `RegionStatsNeedUpdate` above to know how `OversizedRegion` and ``UndersizedRegion` are updated.
@@ -207,6 +224,9 @@ func (r *RegionStatistics) Observe(region *core.RegionInfo, stores []*core.Store
 			int64(r.opt.GetMaxMergeRegionSize()),
 			int64(r.opt.GetMaxMergeRegionKeys()),
 		),
+		DegradedRegion:    availability == RegionDegraded,
+		AtRiskRegion:      availability == RegionAtRisk,
+		UnavailableRegion: availability == RegionUnavailable,
 	}
 
 	for typ, c := range conditions {
@@ -282,6 +302,9 @@ func (r *RegionStatistics) Collect() {
 	regionStatusGauge.WithLabelValues("empty-region-count").Set(float64(len(r.stats[EmptyRegion])))
 	regionStatusGauge.WithLabelValues("oversized-region-count").Set(float64(len(r.stats[OversizedRegion])))
 	regionStatusGauge.WithLabelValues("undersized-region-count").Set(float64(len(r.stats[UndersizedRegion])))
+	regionStatusGauge.WithLabelValues("degraded-region-count").Set(float64(len(r.stats[DegradedRegion])))
+	regionStatusGauge.WithLabelValues("at-risk-region-count").Set(float64(len(r.stats[AtRiskRegion])))
+	regionStatusGauge.WithLabelValues("unavailable-region-count").Set(float64(len(r.stats[UnavailableRegion])))
 
 	offlineRegionStatusGauge.WithLabelValues("miss-peer-region-count").Set(float64(len(r.offlineStats[MissPeer])))
 	offlineRegionStatusGauge.WithLabelValues("extra-peer-region-count").Set(float64(len(r.offlineStats[ExtraPeer])))
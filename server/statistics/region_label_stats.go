@@ -0,0 +1,166 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"sort"
+	"sync"
+)
+
+const (
+	// maxTrackedRegionLabels is the max number of distinct label key-value pairs
+	// tracked by RegionLabelStatistics, which bounds the cardinality of its metrics.
+	maxTrackedRegionLabels = 64
+	// otherRegionLabel is used to aggregate the label key-value pairs beyond the limit.
+	otherRegionLabel = "other"
+)
+
+// RegionLabelStat is the statistics of regions with the same region label.
+type RegionLabelStat struct {
+	Key              string `json:"key"`
+	Value            string `json:"value"`
+	RegionCount      int    `json:"region_count"`
+	UnsatisfiedCount int    `json:"unsatisfied_count"`
+}
+
+type regionLabelKey struct {
+	key   string
+	value string
+}
+
+type regionLabelObservation struct {
+	labels    []regionLabelKey
+	satisfied bool
+}
+
+// RegionLabelStatistics records the region counts per region label and how many
+// of them do not satisfy their placement rules. It is used to verify the data
+// residency constraints of regions with the keyspace or compliance labels.
+type RegionLabelStatistics struct {
+	sync.RWMutex
+	regions map[uint64]regionLabelObservation
+	stats   map[regionLabelKey]*RegionLabelStat
+}
+
+// NewRegionLabelStatistics creates a new RegionLabelStatistics.
+func NewRegionLabelStatistics() *RegionLabelStatistics {
+	return &RegionLabelStatistics{
+		regions: make(map[uint64]regionLabelObservation),
+		stats:   make(map[regionLabelKey]*RegionLabelStat),
+	}
+}
+
+// Observe records the labels of the region and whether it satisfies the placement rules.
+func (s *RegionLabelStatistics) Observe(regionID uint64, labels map[string]string, satisfied bool) {
+	s.Lock()
+	defer s.Unlock()
+	s.removeLocked(regionID)
+	if len(labels) == 0 {
+		return
+	}
+	observation := regionLabelObservation{satisfied: satisfied}
+	overflowed := false
+	for k, v := range labels {
+		key := regionLabelKey{key: k, value: v}
+		if _, ok := s.stats[key]; !ok {
+			if len(s.stats) >= maxTrackedRegionLabels {
+				overflowed = true
+				continue
+			}
+			s.stats[key] = &RegionLabelStat{Key: k, Value: v}
+		}
+		observation.labels = append(observation.labels, key)
+	}
+	// The region is aggregated into the other label only if none of its labels
+	// is tracked, so it is never counted in both.
+	if overflowed && len(observation.labels) == 0 {
+		key := regionLabelKey{key: otherRegionLabel, value: otherRegionLabel}
+		if _, ok := s.stats[key]; !ok {
+			s.stats[key] = &RegionLabelStat{Key: key.key, Value: key.value}
+		}
+		observation.labels = append(observation.labels, key)
+	}
+	for _, key := range observation.labels {
+		stat := s.stats[key]
+		stat.RegionCount++
+		if !satisfied {
+			stat.UnsatisfiedCount++
+		}
+	}
+	s.regions[regionID] = observation
+}
+
+// ClearDefunctRegion is used to handle the overlap region.
+func (s *RegionLabelStatistics) ClearDefunctRegion(regionID uint64) {
+	s.Lock()
+	defer s.Unlock()
+	s.removeLocked(regionID)
+}
+
+func (s *RegionLabelStatistics) removeLocked(regionID uint64) {
+	observation, ok := s.regions[regionID]
+	if !ok {
+		return
+	}
+	for _, key := range observation.labels {
+		stat, ok := s.stats[key]
+		if !ok {
+			continue
+		}
+		stat.RegionCount--
+		if !observation.satisfied {
+			stat.UnsatisfiedCount--
+		}
+		if stat.RegionCount <= 0 {
+			delete(s.stats, key)
+		}
+	}
+	delete(s.regions, regionID)
+}
+
+// GetStats returns the statistics of all tracked region labels sorted by key and value.
+func (s *RegionLabelStatistics) GetStats() []*RegionLabelStat {
+	s.RLock()
+	defer s.RUnlock()
+	res := make([]*RegionLabelStat, 0, len(s.stats))
+	for _, stat := range s.stats {
+		cloned := *stat
+		res = append(res, &cloned)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Key != res[j].Key {
+			return res[i].Key < res[j].Key
+		}
+		return res[i].Value < res[j].Value
+	})
+	return res
+}
+
+// Collect collects the metrics of the region label statistics.
+func (s *RegionLabelStatistics) Collect() {
+	s.RLock()
+	defer s.RUnlock()
+	// Labels may disappear, so reset the gauge before setting the values.
+	regionLabelComplianceGauge.Reset()
+	for _, stat := range s.stats {
+		regionLabelComplianceGauge.WithLabelValues(stat.Key, stat.Value, "region-count").Set(float64(stat.RegionCount))
+		regionLabelComplianceGauge.WithLabelValues(stat.Key, stat.Value, "unsatisfied-count").Set(float64(stat.UnsatisfiedCount))
+	}
+}
+
+// Reset resets the metrics of the region label statistics.
+func (s *RegionLabelStatistics) Reset() {
+	regionLabelComplianceGauge.Reset()
+}
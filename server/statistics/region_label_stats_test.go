@@ -0,0 +1,85 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegionLabelStatistics(t *testing.T) {
+	re := require.New(t)
+	stats := NewRegionLabelStatistics()
+	stats.Observe(1, map[string]string{"keyspace": "1", "compliance": "eu"}, true)
+	stats.Observe(2, map[string]string{"keyspace": "1"}, false)
+	stats.Observe(3, nil, false)
+	re.Equal([]*RegionLabelStat{
+		{Key: "compliance", Value: "eu", RegionCount: 1},
+		{Key: "keyspace", Value: "1", RegionCount: 2, UnsatisfiedCount: 1},
+	}, stats.GetStats())
+
+	// Observe again replaces the old labels.
+	stats.Observe(1, map[string]string{"keyspace": "2"}, false)
+	re.Equal([]*RegionLabelStat{
+		{Key: "keyspace", Value: "1", RegionCount: 1, UnsatisfiedCount: 1},
+		{Key: "keyspace", Value: "2", RegionCount: 1, UnsatisfiedCount: 1},
+	}, stats.GetStats())
+
+	stats.ClearDefunctRegion(2)
+	re.Equal([]*RegionLabelStat{
+		{Key: "keyspace", Value: "2", RegionCount: 1, UnsatisfiedCount: 1},
+	}, stats.GetStats())
+
+	// Label pairs beyond the limit are aggregated.
+	for i := 0; i < maxTrackedRegionLabels+10; i++ {
+		stats.Observe(uint64(100+i), map[string]string{"keyspace": fmt.Sprintf("k%d", i)}, true)
+	}
+	res := stats.GetStats()
+	re.Len(res, maxTrackedRegionLabels+1)
+	last := res[len(res)-1]
+	re.Equal(otherRegionLabel, last.Key)
+	re.Equal(11, last.RegionCount)
+}
+
+func TestRegionLabelStatisticsOtherBucket(t *testing.T) {
+	re := require.New(t)
+	stats := NewRegionLabelStatistics()
+	for i := 0; i < maxTrackedRegionLabels; i++ {
+		stats.Observe(uint64(i), map[string]string{"keyspace": fmt.Sprintf("k%d", i)}, true)
+	}
+	// A region with a tracked label is not counted as other.
+	stats.Observe(100, map[string]string{"keyspace": "k0", "compliance": "eu"}, true)
+	// A region with several untracked labels is counted as other once.
+	stats.Observe(101, map[string]string{"compliance": "eu", "tier": "hot"}, false)
+	total := 0
+	for _, stat := range stats.GetStats() {
+		total += stat.RegionCount
+		if stat.Key == otherRegionLabel {
+			re.Equal(1, stat.RegionCount)
+			re.Equal(1, stat.UnsatisfiedCount)
+		}
+	}
+	re.Equal(maxTrackedRegionLabels+2, total)
+
+	stats.ClearDefunctRegion(101)
+	total = 0
+	for _, stat := range stats.GetStats() {
+		re.NotEqual(otherRegionLabel, stat.Key)
+		total += stat.RegionCount
+	}
+	re.Equal(maxTrackedRegionLabels+1, total)
+}
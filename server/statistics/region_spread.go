@@ -0,0 +1,127 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"github.com/tikv/pd/server/core"
+)
+
+// RegionSpread shows how evenly the regions of a key range are distributed.
+// Each score is in (0, 1], and 1 means the peers or leaders are spread as evenly as possible.
+type RegionSpread struct {
+	RegionCount         int            `json:"region_count"`
+	PeerScore           float64        `json:"peer_score"`
+	LeaderScore         float64        `json:"leader_score"`
+	LocationScore       float64        `json:"location_score,omitempty"`
+	StorePeerCount      map[uint64]int `json:"store_peer_count"`
+	StoreLeaderCount    map[uint64]int `json:"store_leader_count"`
+	LocationPeerCount   map[string]int `json:"location_peer_count,omitempty"`
+	candidateStoreCount int
+	locationCount       int
+}
+
+// CalculateRegionSpread calculates the spread of the regions over the stores that are up.
+// If locationLabel is not empty, the spread over the values of the label is also calculated.
+func CalculateRegionSpread(regions []*core.RegionInfo, stores []*core.StoreInfo, locationLabel string) *RegionSpread {
+	spread := &RegionSpread{
+		RegionCount:      len(regions),
+		StorePeerCount:   make(map[uint64]int),
+		StoreLeaderCount: make(map[uint64]int),
+	}
+	storeLocations := make(map[uint64]string)
+	locations := make(map[string]struct{})
+	for _, store := range stores {
+		if !store.IsUp() {
+			continue
+		}
+		spread.candidateStoreCount++
+		if locationLabel != "" {
+			location := store.GetLabelValue(locationLabel)
+			storeLocations[store.GetID()] = location
+			locations[location] = struct{}{}
+		}
+	}
+	spread.locationCount = len(locations)
+	if locationLabel != "" {
+		spread.LocationPeerCount = make(map[string]int)
+	}
+
+	var peerCount, leaderCount int
+	for _, region := range regions {
+		for _, peer := range region.GetPeers() {
+			storeID := peer.GetStoreId()
+			spread.StorePeerCount[storeID]++
+			peerCount++
+			if location, ok := storeLocations[storeID]; ok {
+				spread.LocationPeerCount[location]++
+			}
+		}
+		if leader := region.GetLeader(); leader != nil {
+			spread.StoreLeaderCount[leader.GetStoreId()]++
+			leaderCount++
+		}
+	}
+	spread.PeerScore = spreadScore(maxStoreCount(spread.StorePeerCount), peerCount, spread.candidateStoreCount)
+	spread.LeaderScore = spreadScore(maxStoreCount(spread.StoreLeaderCount), leaderCount, spread.candidateStoreCount)
+	if locationLabel != "" {
+		var maxCount int
+		for _, count := range spread.LocationPeerCount {
+			if count > maxCount {
+				maxCount = count
+			}
+		}
+		spread.LocationScore = spreadScore(maxCount, peerCount, spread.locationCount)
+	}
+	return spread
+}
+
+// GetIdealPeerCount returns the max peer count of a store when the peers are spread evenly.
+func (s *RegionSpread) GetIdealPeerCount() int {
+	var total int
+	for _, count := range s.StorePeerCount {
+		total += count
+	}
+	return idealMaxCount(total, s.candidateStoreCount)
+}
+
+func maxStoreCount(counts map[uint64]int) int {
+	var maxCount int
+	for _, count := range counts {
+		if count > maxCount {
+			maxCount = count
+		}
+	}
+	return maxCount
+}
+
+// spreadScore returns the ratio of the ideal max count to the actual max count.
+func spreadScore(maxCount, total, n int) float64 {
+	if total == 0 || n == 0 || maxCount == 0 {
+		return 1
+	}
+	score := float64(idealMaxCount(total, n)) / float64(maxCount)
+	if score > 1 {
+		// The peers may be on the stores which are not up.
+		return 1
+	}
+	return score
+}
+
+func idealMaxCount(total, n int) int {
+	if n == 0 {
+		return total
+	}
+	return (total + n - 1) / n
+}
@@ -0,0 +1,64 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/core"
+)
+
+func TestCalculateRegionSpread(t *testing.T) {
+	re := require.New(t)
+	var stores []*core.StoreInfo
+	for i, zone := range []string{"z1", "z1", "z2", "z2"} {
+		stores = append(stores, core.NewStoreInfo(&metapb.Store{
+			Id:        uint64(i + 1),
+			NodeState: metapb.NodeState_Serving,
+			Labels:    []*metapb.StoreLabel{{Key: "zone", Value: zone}},
+		}))
+	}
+	newRegion := func(id uint64, storeIDs ...uint64) *core.RegionInfo {
+		peers := make([]*metapb.Peer, 0, len(storeIDs))
+		for _, storeID := range storeIDs {
+			peers = append(peers, &metapb.Peer{Id: id*10 + storeID, StoreId: storeID})
+		}
+		return core.NewRegionInfo(&metapb.Region{Id: id, Peers: peers}, peers[0])
+	}
+
+	// All regions are on the stores of z1.
+	spread := CalculateRegionSpread([]*core.RegionInfo{newRegion(1, 1, 2), newRegion(2, 1, 2)}, stores, "zone")
+	re.Equal(2, spread.RegionCount)
+	re.Equal(0.5, spread.PeerScore)
+	re.Equal(0.5, spread.LeaderScore)
+	re.Equal(0.5, spread.LocationScore)
+	re.Equal(map[uint64]int{1: 2, 2: 2}, spread.StorePeerCount)
+	re.Equal(map[string]int{"z1": 4}, spread.LocationPeerCount)
+	re.Equal(1, spread.GetIdealPeerCount())
+
+	// The regions are spread evenly.
+	spread = CalculateRegionSpread([]*core.RegionInfo{newRegion(1, 1, 3), newRegion(2, 4, 2)}, stores, "zone")
+	re.Equal(1.0, spread.PeerScore)
+	re.Equal(1.0, spread.LeaderScore)
+	re.Equal(1.0, spread.LocationScore)
+
+	// No location label.
+	spread = CalculateRegionSpread(nil, stores, "")
+	re.Equal(1.0, spread.PeerScore)
+	re.Zero(spread.LocationScore)
+	re.Nil(spread.LocationPeerCount)
+}
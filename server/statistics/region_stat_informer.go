@@ -25,4 +25,7 @@ type RegionStatInformer interface {
 	// RegionReadStats return the storeID -> read stat of peers on this store.
 	// The result only includes peers that are hot enough.
 	RegionReadStats() map[uint64][]*HotPeerStat
+	// RegionPeerWriteStats returns the write stats of the peers of the region,
+	// regardless of their hot degree.
+	RegionPeerWriteStats(region *core.RegionInfo) []*HotPeerStat
 }
@@ -17,8 +17,11 @@ package statistics
 import (
 	"fmt"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/tikv/pd/server/config"
 	"github.com/tikv/pd/server/core"
 )
@@ -48,6 +51,7 @@ type storeStatistics struct {
 	Serving         int
 	Removing        int
 	Removed         int
+	OverQuota       int
 }
 
 func newStoreStatistics(opt *config.PersistOptions, storeConfig *config.StoreConfig) *storeStatistics {
@@ -103,6 +107,9 @@ func (s *storeStatistics) Observe(store *core.StoreInfo, stats *StoresStats) {
 	if store.IsLowSpace(s.opt.GetLowSpaceRatio()) {
 		s.LowSpace++
 	}
+	if store.ExceedsQuota(core.LeaderKind, 0) || store.ExceedsQuota(core.RegionKind, 0) {
+		s.OverQuota++
+	}
 
 	// Store stats.
 	s.StorageSize += store.StorageSize()
@@ -110,7 +117,7 @@ func (s *storeStatistics) Observe(store *core.StoreInfo, stats *StoresStats) {
 	s.RegionCount += store.GetRegionCount()
 	s.LeaderCount += store.GetLeaderCount()
 
-	storeStatusGauge.WithLabelValues(storeAddress, id, "region_score").Set(store.RegionScore(s.opt.GetRegionScoreFormulaVersion(), s.opt.GetHighSpaceRatio(), s.opt.GetLowSpaceRatio(), 0))
+	storeStatusGauge.WithLabelValues(storeAddress, id, "region_score").Set(store.RegionScore(s.opt.GetRegionScoreFormulaVersion(), s.opt.GetHighSpaceRatio(), s.opt.GetLowSpaceRatio(), s.opt.GetRegionScoreCurve(), 0))
 	storeStatusGauge.WithLabelValues(storeAddress, id, "leader_score").Set(store.LeaderScore(s.opt.GetLeaderSchedulePolicy(), 0))
 	storeStatusGauge.WithLabelValues(storeAddress, id, "region_size").Set(float64(store.GetRegionSize()))
 	storeStatusGauge.WithLabelValues(storeAddress, id, "region_count").Set(float64(store.GetRegionCount()))
@@ -121,6 +128,8 @@ func (s *storeStatistics) Observe(store *core.StoreInfo, stats *StoresStats) {
 	storeStatusGauge.WithLabelValues(storeAddress, id, "store_capacity").Set(float64(store.GetCapacity()))
 	storeStatusGauge.WithLabelValues(storeAddress, id, "store_available_avg").Set(float64(store.GetAvgAvailable()))
 	storeStatusGauge.WithLabelValues(storeAddress, id, "store_available_deviation").Set(float64(store.GetAvailableDeviation()))
+	storeStatusGauge.WithLabelValues(storeAddress, id, "leader_quota").Set(float64(store.GetLeaderQuota()))
+	storeStatusGauge.WithLabelValues(storeAddress, id, "region_quota").Set(float64(store.GetRegionQuota()))
 
 	// Store flows.
 	storeFlowStats := stats.GetRollingStoreStats(store.GetID())
@@ -166,6 +175,7 @@ func (s *storeStatistics) Collect() {
 	metrics["store_serving_count"] = float64(s.Serving)
 	metrics["store_removing_count"] = float64(s.Removing)
 	metrics["store_removed_count"] = float64(s.Removed)
+	metrics["store_over_quota_count"] = float64(s.OverQuota)
 	metrics["region_count"] = float64(s.RegionCount)
 	metrics["leader_count"] = float64(s.LeaderCount)
 	metrics["storage_size"] = float64(s.StorageSize)
@@ -241,6 +251,8 @@ func (s *storeStatistics) resetStoreStatistics(storeAddress string, id string) {
 		"store_available",
 		"store_used",
 		"store_capacity",
+		"leader_quota",
+		"region_quota",
 		"store_write_rate_bytes",
 		"store_read_rate_bytes",
 		"store_write_rate_keys",
@@ -255,29 +267,217 @@ func (s *storeStatistics) resetStoreStatistics(storeAddress string, id string) {
 	}
 }
 
-type storeStatisticsMap struct {
-	opt   *config.PersistOptions
-	stats *storeStatistics
+// add adds the store statistics of o into s.
+func (s *storeStatistics) add(o *storeStatistics) {
+	s.Up += o.Up
+	s.Disconnect += o.Disconnect
+	s.Unhealthy += o.Unhealthy
+	s.Down += o.Down
+	s.Offline += o.Offline
+	s.Tombstone += o.Tombstone
+	s.LowSpace += o.LowSpace
+	s.Slow += o.Slow
+	s.StorageSize += o.StorageSize
+	s.StorageCapacity += o.StorageCapacity
+	s.RegionCount += o.RegionCount
+	s.LeaderCount += o.LeaderCount
+	s.Preparing += o.Preparing
+	s.Serving += o.Serving
+	s.Removing += o.Removing
+	s.Removed += o.Removed
+	s.OverQuota += o.OverQuota
+	for k, v := range o.LabelCounter {
+		s.LabelCounter[k] += v
+	}
+}
+
+// sub removes the store statistics of o from s.
+func (s *storeStatistics) sub(o *storeStatistics) {
+	s.Up -= o.Up
+	s.Disconnect -= o.Disconnect
+	s.Unhealthy -= o.Unhealthy
+	s.Down -= o.Down
+	s.Offline -= o.Offline
+	s.Tombstone -= o.Tombstone
+	s.LowSpace -= o.LowSpace
+	s.Slow -= o.Slow
+	s.StorageSize -= o.StorageSize
+	s.StorageCapacity -= o.StorageCapacity
+	s.RegionCount -= o.RegionCount
+	s.LeaderCount -= o.LeaderCount
+	s.Preparing -= o.Preparing
+	s.Serving -= o.Serving
+	s.Removing -= o.Removing
+	s.Removed -= o.Removed
+	s.OverQuota -= o.OverQuota
+	for k, v := range o.LabelCounter {
+		s.LabelCounter[k] -= v
+		if s.LabelCounter[k] <= 0 {
+			delete(s.LabelCounter, k)
+		}
+	}
+}
+
+// Store health states, which may change as time goes by even if the store has no heartbeat.
+const (
+	storeHealthUp = iota
+	storeHealthSlow
+	storeHealthDisconnected
+	storeHealthUnhealthy
+	storeHealthDown
+)
+
+func getStoreHealth(opt *config.PersistOptions, store *core.StoreInfo) int {
+	switch {
+	case store.DownTime() >= opt.GetMaxStoreDownTime():
+		return storeHealthDown
+	case store.IsUnhealthy():
+		return storeHealthUnhealthy
+	case store.IsDisconnected():
+		return storeHealthDisconnected
+	case store.IsSlow():
+		return storeHealthSlow
+	default:
+		return storeHealthUp
+	}
+}
+
+// storeObservation is the part of a store which its statistics are computed from.
+// Every heartbeat clones the StoreInfo even if nothing changes, so the fields are
+// compared rather than the StoreInfo itself.
+type storeObservation struct {
+	address      string
+	nodeState    metapb.NodeState
+	labels       string           // the values of the location labels
+	stats        *pdpb.StoreStats // replaced by every store heartbeat
+	health       int
+	leaderCount  int
+	regionCount  int
+	leaderSize   int64
+	regionSize   int64
+	leaderWeight float64
+	regionWeight float64
+	leaderQuota  uint64
+	regionQuota  uint64
+}
+
+func newStoreObservation(opt *config.PersistOptions, store *core.StoreInfo) storeObservation {
+	locationLabels := opt.GetLocationLabels()
+	labels := make([]string, 0, len(locationLabels))
+	for _, k := range locationLabels {
+		labels = append(labels, store.GetLabelValue(k))
+	}
+	return storeObservation{
+		address:      store.GetAddress(),
+		nodeState:    store.GetNodeState(),
+		labels:       strings.Join(labels, ","),
+		stats:        store.GetStoreStats(),
+		health:       getStoreHealth(opt, store),
+		leaderCount:  store.GetLeaderCount(),
+		regionCount:  store.GetRegionCount(),
+		leaderSize:   store.GetLeaderSize(),
+		regionSize:   store.GetRegionSize(),
+		leaderWeight: store.GetLeaderWeight(),
+		regionWeight: store.GetRegionWeight(),
+		leaderQuota:  store.GetLeaderQuota(),
+		regionQuota:  store.GetRegionQuota(),
+	}
+}
+
+// observedStoreKey identifies an observed store. seq tells apart the stores with
+// the same ID observed in the same round, which are counted separately.
+type observedStoreKey struct {
+	id  uint64
+	seq int
+}
+
+// observedStore records the statistics contributed by a store when it was last observed.
+type observedStore struct {
+	observation storeObservation
+	stats       *storeStatistics
+	// round is the collecting round in which the store was last observed.
+	round uint64
+}
+
+// StoreStatisticsMap collects the statistics of stores incrementally. A store whose
+// observed fields and health are the same as the last time is skipped. All the stores
+// are recomputed every StoreMetricsEmitInterval.
+type StoreStatisticsMap struct {
+	opt                *config.PersistOptions
+	storeConfigManager *config.StoreConfigManager
+	stats              *storeStatistics
+	stores             map[observedStoreKey]*observedStore
+	seqs               map[uint64]int // the times each store is observed in this round
+	round              uint64
+	lastFullCollect    time.Time
+	locationLabels     string
 }
 
-// NewStoreStatisticsMap creates a new storeStatisticsMap.
-func NewStoreStatisticsMap(opt *config.PersistOptions, storeConfig *config.StoreConfig) *storeStatisticsMap {
-	return &storeStatisticsMap{
-		opt:   opt,
-		stats: newStoreStatistics(opt, storeConfig),
+// NewStoreStatisticsMap creates a new StoreStatisticsMap.
+func NewStoreStatisticsMap(opt *config.PersistOptions, storeConfigManager *config.StoreConfigManager) *StoreStatisticsMap {
+	return &StoreStatisticsMap{
+		opt:                opt,
+		storeConfigManager: storeConfigManager,
+		stats:              newStoreStatistics(opt, nil),
+		stores:             make(map[observedStoreKey]*observedStore),
+		seqs:               make(map[uint64]int),
+		lastFullCollect:    time.Now(),
+		locationLabels:     strings.Join(opt.GetLocationLabels(), ","),
 	}
 }
 
-func (m *storeStatisticsMap) Observe(store *core.StoreInfo, stats *StoresStats) {
-	m.stats.Observe(store, stats)
+// Observe updates the statistics of the store if it has changed since the last time.
+func (m *StoreStatisticsMap) Observe(store *core.StoreInfo, stats *StoresStats) {
+	key := observedStoreKey{id: store.GetID(), seq: m.seqs[store.GetID()]}
+	m.seqs[store.GetID()]++
+	observation := newStoreObservation(m.opt, store)
+	prev, ok := m.stores[key]
+	if ok {
+		prev.round = m.round
+		if prev.observation == observation {
+			return
+		}
+		m.stats.sub(prev.stats)
+	}
+	storeStats := newStoreStatistics(m.opt, nil)
+	storeStats.Observe(store, stats)
+	m.stats.add(storeStats)
+	m.stores[key] = &observedStore{observation: observation, stats: storeStats, round: m.round}
 }
 
-func (m *storeStatisticsMap) Collect() {
+// Collect removes the stores which are not observed in this round and sets the
+// cluster metrics.
+func (m *StoreStatisticsMap) Collect() {
+	for key, s := range m.stores {
+		if s.round != m.round {
+			m.stats.sub(s.stats)
+			delete(m.stores, key)
+		}
+	}
+	m.seqs = make(map[uint64]int)
+	m.round++
+	m.stats.storeConfig = m.storeConfigManager.GetStoreConfig()
 	m.stats.Collect()
+
+	// Recompute all the stores in the next round if the interval elapses or the location
+	// labels, which the label counters depend on, are changed.
+	locationLabels := strings.Join(m.opt.GetLocationLabels(), ",")
+	if time.Since(m.lastFullCollect) >= m.opt.GetStoreMetricsEmitInterval() || locationLabels != m.locationLabels {
+		m.clear()
+		m.locationLabels = locationLabels
+	}
 }
 
-func (m *storeStatisticsMap) Reset() {
+// Reset resets the metrics and the collected statistics.
+func (m *StoreStatisticsMap) Reset() {
 	storeStatusGauge.Reset()
 	clusterStatusGauge.Reset()
 	placementStatusGauge.Reset()
+	m.clear()
+}
+
+func (m *StoreStatisticsMap) clear() {
+	m.stats = newStoreStatistics(m.opt, nil)
+	m.stores = make(map[observedStoreKey]*observedStore)
+	m.lastFullCollect = time.Now()
 }
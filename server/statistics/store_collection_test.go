@@ -15,6 +15,7 @@
 package statistics
 
 import (
+	"fmt"
 	"testing"
 	"time"
 
@@ -79,3 +80,75 @@ func TestStoreStatistics(t *testing.T) {
 	re.Equal(4, stats.LabelCounter["host:h2"])
 	re.Equal(2, stats.LabelCounter["zone:unknown"])
 }
+
+func TestStoreStatisticsIncremental(t *testing.T) {
+	re := require.New(t)
+	opt := config.NewTestOptions()
+	rep := opt.GetReplicationConfig().Clone()
+	rep.LocationLabels = []string{"zone"}
+	opt.SetReplicationConfig(rep)
+	pdServerCfg := opt.GetPDServerConfig().Clone()
+	pdServerCfg.StoreMetricsEmitInterval.Duration = time.Hour
+	opt.SetPDServerConfig(pdServerCfg)
+
+	storesStats := NewStoresStats()
+	stores := make([]*core.StoreInfo, 0, 3)
+	for i := uint64(1); i <= 3; i++ {
+		stores = append(stores, core.NewStoreInfo(&metapb.Store{
+			Id:      i,
+			Address: fmt.Sprintf("mock://tikv-%d", i),
+			Labels:  []*metapb.StoreLabel{{Key: "zone", Value: "z1"}},
+		}, core.SetLastHeartbeatTS(time.Now())))
+	}
+	storeStats := NewStoreStatisticsMap(opt, nil)
+	observe := func() {
+		for _, store := range stores {
+			storeStats.Observe(store, storesStats)
+		}
+		storeStats.Collect()
+	}
+	observe()
+	re.Equal(3, storeStats.stats.Up)
+	re.Equal(3, storeStats.stats.LabelCounter["zone:z1"])
+
+	// The unchanged stores are not counted twice.
+	observe()
+	re.Equal(3, storeStats.stats.Up)
+	re.Equal(3, storeStats.stats.LabelCounter["zone:z1"])
+
+	// The store cloned without changes is skipped.
+	observed := storeStats.stores[observedStoreKey{id: 1}]
+	stores[0] = stores[0].Clone()
+	observe()
+	re.Same(observed, storeStats.stores[observedStoreKey{id: 1}])
+
+	// The changed store replaces its former statistics.
+	stores[0] = stores[0].Clone(core.OfflineStore(false))
+	observe()
+	re.Equal(2, storeStats.stats.Up)
+	re.Equal(1, storeStats.stats.Offline)
+	re.Equal(3, storeStats.stats.LabelCounter["zone:z1"])
+
+	// The store whose health changes without a heartbeat is recomputed.
+	storeStats.stores[observedStoreKey{id: 2}].observation.health = storeHealthDown
+	observe()
+	re.Equal(storeHealthUp, storeStats.stores[observedStoreKey{id: 2}].observation.health)
+	re.Equal(2, storeStats.stats.Up)
+
+	// The store which is not observed any more is removed.
+	stores = stores[:2]
+	observe()
+	re.Equal(1, storeStats.stats.Up)
+	re.Equal(2, storeStats.stats.LabelCounter["zone:z1"])
+	re.Len(storeStats.stores, 2)
+
+	// All the stores are recomputed once the location labels are changed.
+	rep = opt.GetReplicationConfig().Clone()
+	rep.LocationLabels = []string{"host"}
+	opt.SetReplicationConfig(rep)
+	observe()
+	re.Empty(storeStats.stores)
+	observe()
+	re.NotContains(storeStats.stats.LabelCounter, "zone:z1")
+	re.Equal(2, storeStats.stats.LabelCounter["host:unknown"])
+}
@@ -0,0 +1,157 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"sort"
+	"time"
+
+	"github.com/tikv/pd/pkg/movingaverage"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/pkg/typeutil"
+)
+
+const (
+	// zoneLatencyDecay makes the smoothed latency follow the recent reports
+	// closely, the latencies between the zones are stable in general.
+	zoneLatencyDecay = 0.3
+	// ZoneLatencyExpiration is the time after which the latency between two
+	// zones is dropped if it is not reported again.
+	ZoneLatencyExpiration = 10 * time.Minute
+)
+
+// ZoneLatencyInformer provides access to the latencies between the zones.
+type ZoneLatencyInformer interface {
+	GetZoneLatency(zoneA, zoneB string) (time.Duration, bool)
+}
+
+// ZoneLatency is the round-trip time between two zones.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type ZoneLatency struct {
+	ZoneA      string            `json:"zone_a"`
+	ZoneB      string            `json:"zone_b"`
+	RTT        typeutil.Duration `json:"rtt"`
+	UpdateTime time.Time         `json:"update_time"`
+}
+
+type zonePair struct {
+	a, b string
+}
+
+func newZonePair(zoneA, zoneB string) zonePair {
+	if zoneA > zoneB {
+		zoneA, zoneB = zoneB, zoneA
+	}
+	return zonePair{a: zoneA, b: zoneB}
+}
+
+type zoneLatencyItem struct {
+	rtt        *movingaverage.EMA
+	updateTime time.Time
+}
+
+// ZoneLatencies is the matrix of the round-trip times between the zones, which
+// are reported by the stores or the probes deployed in the zones. The RTT is
+// symmetric, so the reports of both directions are merged.
+type ZoneLatencies struct {
+	syncutil.RWMutex
+	items map[zonePair]*zoneLatencyItem
+}
+
+// NewZoneLatencies creates a ZoneLatencies.
+func NewZoneLatencies() *ZoneLatencies {
+	return &ZoneLatencies{items: make(map[zonePair]*zoneLatencyItem)}
+}
+
+// ObserveZoneLatency records a round-trip time between two zones.
+func (l *ZoneLatencies) ObserveZoneLatency(zoneA, zoneB string, rtt time.Duration) {
+	if zoneA == zoneB {
+		return
+	}
+	l.Lock()
+	defer l.Unlock()
+	pair := newZonePair(zoneA, zoneB)
+	item, ok := l.items[pair]
+	if !ok || time.Since(item.updateTime) > ZoneLatencyExpiration {
+		item = &zoneLatencyItem{rtt: movingaverage.NewEMA(zoneLatencyDecay)}
+		l.items[pair] = item
+	}
+	item.rtt.Add(float64(rtt))
+	item.updateTime = time.Now()
+}
+
+// GetZoneLatency returns the smoothed round-trip time between two zones. The
+// latency inside a zone is 0. It returns false if the latency is unknown or
+// expired.
+func (l *ZoneLatencies) GetZoneLatency(zoneA, zoneB string) (time.Duration, bool) {
+	if zoneA == zoneB {
+		return 0, true
+	}
+	l.RLock()
+	defer l.RUnlock()
+	item, ok := l.items[newZonePair(zoneA, zoneB)]
+	if !ok || time.Since(item.updateTime) > ZoneLatencyExpiration {
+		return 0, false
+	}
+	return time.Duration(item.rtt.Get()), true
+}
+
+// GetZoneLatencyMatrix returns the latencies which are not expired, sorted by
+// the zones.
+func (l *ZoneLatencies) GetZoneLatencyMatrix() []ZoneLatency {
+	l.Lock()
+	defer l.Unlock()
+	matrix := make([]ZoneLatency, 0, len(l.items))
+	for pair, item := range l.items {
+		if time.Since(item.updateTime) > ZoneLatencyExpiration {
+			delete(l.items, pair)
+			continue
+		}
+		matrix = append(matrix, ZoneLatency{
+			ZoneA:      pair.a,
+			ZoneB:      pair.b,
+			RTT:        typeutil.NewDuration(time.Duration(item.rtt.Get())),
+			UpdateTime: item.updateTime,
+		})
+	}
+	sort.Slice(matrix, func(i, j int) bool {
+		if matrix[i].ZoneA != matrix[j].ZoneA {
+			return matrix[i].ZoneA < matrix[j].ZoneA
+		}
+		return matrix[i].ZoneB < matrix[j].ZoneB
+	})
+	return matrix
+}
+
+// ClosestZone returns the zone closest to the given zone among the candidates.
+// The given zone itself is the closest if it is one of the candidates. It
+// returns false if none of the latencies to the candidates is known.
+func ClosestZone(informer ZoneLatencyInformer, zone string, candidates []string) (string, bool) {
+	var (
+		closest string
+		minRTT  time.Duration
+		found   bool
+	)
+	for _, candidate := range candidates {
+		rtt, ok := informer.GetZoneLatency(zone, candidate)
+		if !ok {
+			continue
+		}
+		if !found || rtt < minRTT || (rtt == minRTT && candidate < closest) {
+			closest, minRTT, found = candidate, rtt, true
+		}
+	}
+	return closest, found
+}
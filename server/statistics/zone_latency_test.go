@@ -0,0 +1,60 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package statistics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestZoneLatencies(t *testing.T) {
+	re := require.New(t)
+	l := NewZoneLatencies()
+	_, ok := l.GetZoneLatency("z1", "z2")
+	re.False(ok)
+	rtt, ok := l.GetZoneLatency("z1", "z1")
+	re.True(ok)
+	re.Zero(rtt)
+
+	l.ObserveZoneLatency("z1", "z2", 10*time.Millisecond)
+	l.ObserveZoneLatency("z2", "z1", 20*time.Millisecond)
+	l.ObserveZoneLatency("z1", "z3", 2*time.Millisecond)
+	rtt, ok = l.GetZoneLatency("z2", "z1")
+	re.True(ok)
+	re.Equal(15*time.Millisecond, rtt)
+
+	matrix := l.GetZoneLatencyMatrix()
+	re.Len(matrix, 2)
+	re.Equal("z1", matrix[0].ZoneA)
+	re.Equal("z2", matrix[0].ZoneB)
+	re.Equal("z3", matrix[1].ZoneB)
+
+	zone, ok := ClosestZone(l, "z1", []string{"z2", "z3"})
+	re.True(ok)
+	re.Equal("z3", zone)
+	zone, ok = ClosestZone(l, "z1", []string{"z1", "z2", "z3"})
+	re.True(ok)
+	re.Equal("z1", zone)
+	_, ok = ClosestZone(l, "z2", []string{"z3"})
+	re.False(ok)
+
+	// The expired latencies are dropped.
+	l.items[newZonePair("z1", "z2")].updateTime = time.Now().Add(-ZoneLatencyExpiration - time.Second)
+	_, ok = l.GetZoneLatency("z1", "z2")
+	re.False(ok)
+	re.Len(l.GetZoneLatencyMatrix(), 1)
+}
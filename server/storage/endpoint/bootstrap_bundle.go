@@ -0,0 +1,56 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// BootstrapBundleStorage defines the storage operations on the bootstrap bundle.
+type BootstrapBundleStorage interface {
+	LoadBootstrapBundle(bundle interface{}) (bool, error)
+	SaveBootstrapBundle(bundle interface{}) error
+	RemoveBootstrapBundle() error
+}
+
+var _ BootstrapBundleStorage = (*StorageEndpoint)(nil)
+
+// LoadBootstrapBundle loads the staged bootstrap bundle.
+func (se *StorageEndpoint) LoadBootstrapBundle(bundle interface{}) (bool, error) {
+	value, err := se.Load(bootstrapBundlePath)
+	if err != nil || value == "" {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(value), bundle); err != nil {
+		return false, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return true, nil
+}
+
+// SaveBootstrapBundle stages the bootstrap bundle.
+func (se *StorageEndpoint) SaveBootstrapBundle(bundle interface{}) error {
+	value, err := json.Marshal(bundle)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return se.Save(bootstrapBundlePath, string(value))
+}
+
+// RemoveBootstrapBundle removes the staged bootstrap bundle.
+func (se *StorageEndpoint) RemoveBootstrapBundle() error {
+	return se.Remove(bootstrapBundlePath)
+}
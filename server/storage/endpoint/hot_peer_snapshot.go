@@ -0,0 +1,44 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"fmt"
+)
+
+// HotPeerSnapshotStorage defines the storage operations on the snapshots of the hot peer cache.
+// A snapshot is persisted in chunks, each of which is saved in a key.
+type HotPeerSnapshotStorage interface {
+	LoadHotPeerSnapshotChunks(kind string, f func(k, v string)) error
+	SaveHotPeerSnapshotChunk(kind string, chunk int, snapshot interface{}) error
+	DeleteHotPeerSnapshotChunk(kind string, chunk int) error
+}
+
+var _ HotPeerSnapshotStorage = (*StorageEndpoint)(nil)
+
+// LoadHotPeerSnapshotChunks loads the chunks of the snapshot of the hot peer cache with the given kind.
+func (se *StorageEndpoint) LoadHotPeerSnapshotChunks(kind string, f func(k, v string)) error {
+	return se.loadRangeByPrefix(hotPeerSnapshotKindPath(kind)+"/", f)
+}
+
+// SaveHotPeerSnapshotChunk saves a chunk of the snapshot of the hot peer cache with the given kind.
+func (se *StorageEndpoint) SaveHotPeerSnapshotChunk(kind string, chunk int, snapshot interface{}) error {
+	return se.saveJSON(hotPeerSnapshotKindPath(kind), fmt.Sprintf("%05d", chunk), snapshot)
+}
+
+// DeleteHotPeerSnapshotChunk removes a chunk of the snapshot of the hot peer cache with the given kind.
+func (se *StorageEndpoint) DeleteHotPeerSnapshotChunk(kind string, chunk int) error {
+	return se.Remove(hotPeerSnapshotChunkPath(kind, chunk))
+}
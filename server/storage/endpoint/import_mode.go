@@ -0,0 +1,73 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// ImportModeRecord is the persisted state of a key range prepared for the bulk
+// import, so that the import survives the leader change.
+type ImportModeRecord struct {
+	ID          string    `json:"id"`
+	StartKey    []byte    `json:"start_key"`
+	EndKey      []byte    `json:"end_key"`
+	RegionCount int       `json:"region_count"`
+	Tolerance   float64   `json:"tolerance"`
+	TaskID      uint64    `json:"task_id"`
+	ExpireAt    time.Time `json:"expire_at"`
+}
+
+// ImportModeStorage defines the storage operations on the import mode records.
+type ImportModeStorage interface {
+	LoadImportModes() ([]*ImportModeRecord, error)
+	SaveImportMode(record *ImportModeRecord) error
+	DeleteImportMode(id string) error
+}
+
+var _ ImportModeStorage = (*StorageEndpoint)(nil)
+
+// LoadImportModes loads all import mode records.
+func (se *StorageEndpoint) LoadImportModes() ([]*ImportModeRecord, error) {
+	var (
+		records []*ImportModeRecord
+		err     error
+	)
+	loadErr := se.loadRangeByPrefix(importModesPath()+"/", func(k, v string) {
+		record := &ImportModeRecord{}
+		if e := json.Unmarshal([]byte(v), record); e != nil {
+			err = errs.ErrJSONUnmarshal.Wrap(e).GenWithStackByArgs()
+			return
+		}
+		records = append(records, record)
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return records, err
+}
+
+// SaveImportMode saves an import mode record.
+func (se *StorageEndpoint) SaveImportMode(record *ImportModeRecord) error {
+	return se.saveJSON(importModesPath(), record.ID, record)
+}
+
+// DeleteImportMode removes an import mode record.
+func (se *StorageEndpoint) DeleteImportMode(id string) error {
+	return se.Remove(importModeKeyPath(id))
+}
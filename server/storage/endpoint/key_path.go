@@ -17,6 +17,7 @@ package endpoint
 import (
 	"fmt"
 	"path"
+	"strconv"
 )
 
 const (
@@ -34,6 +35,20 @@ const (
 	minResolvedTS              = "min_resolved_ts"
 	keySpaceSafePointPrefix    = "key_space/gc_safepoint"
 	keySpaceGCSafePointSuffix  = "gc"
+	suspectKeyRangePath        = "suspect_key_range"
+	hotPeerSnapshotPath        = "hot_peer_snapshot"
+	keyVisualPath              = "key_visual"
+	storeTokenPath             = "store_token"
+	storeNotePath              = "store_note"
+	schedulerDiagnosisPath     = "scheduler_diagnosis"
+	progressSnapshotPath       = "progress_snapshot"
+	operatorTemplatePath       = "operator_template"
+	versionGatePath            = "version_gate"
+	taskPath                   = "task"
+	placementScanPath          = "placement_scan"
+	waitingOperatorsPath       = "waiting_operators"
+	bootstrapBundlePath        = "bootstrap_bundle"
+	importModePath             = "import_mode"
 )
 
 // AppendToRootPath appends the given key to the rootPath.
@@ -68,6 +83,33 @@ func storeRegionWeightPath(storeID uint64) string {
 	return path.Join(schedulePath, "store_weight", fmt.Sprintf("%020d", storeID), "region")
 }
 
+func storeTopologyPath(storeID uint64) string {
+	return path.Join(schedulePath, "store_topology", fmt.Sprintf("%020d", storeID))
+}
+
+func storeLeaderQuotaPath(storeID uint64) string {
+	return path.Join(schedulePath, "store_quota", fmt.Sprintf("%020d", storeID), "leader")
+}
+
+func storeRegionQuotaPath(storeID uint64) string {
+	return path.Join(schedulePath, "store_quota", fmt.Sprintf("%020d", storeID), "region")
+}
+
+// ConfigPath returns the path to save the persisted config.
+func ConfigPath() string {
+	return configPath
+}
+
+// RulePath returns the path to save the placement rule with the given key.
+func RulePath(ruleKey string) string {
+	return ruleKeyPath(ruleKey)
+}
+
+// BootstrapBundlePath returns the path to save the staged bootstrap bundle.
+func BootstrapBundlePath() string {
+	return bootstrapBundlePath
+}
+
 // RegionPath returns the region meta info key path with the given region ID.
 func RegionPath(regionID uint64) string {
 	return path.Join(clusterPath, "r", fmt.Sprintf("%020d", regionID))
@@ -85,6 +127,34 @@ func regionLabelKeyPath(ruleKey string) string {
 	return path.Join(regionLabelPath, ruleKey)
 }
 
+func suspectKeyRangeKeyPath(key string) string {
+	return path.Join(suspectKeyRangePath, key)
+}
+
+func hotPeerSnapshotKindPath(kind string) string {
+	return path.Join(hotPeerSnapshotPath, kind)
+}
+
+func hotPeerSnapshotChunkPath(kind string, chunk int) string {
+	return path.Join(hotPeerSnapshotKindPath(kind), fmt.Sprintf("%05d", chunk))
+}
+
+func schedulerDiagnosisKeyPath(name string) string {
+	return path.Join(schedulerDiagnosisPath, name)
+}
+
+func keyVisualLayerPath(layer int) string {
+	return path.Join(keyVisualPath, strconv.Itoa(layer))
+}
+
+func keyVisualColumnPath(layer int, ts int64) string {
+	return path.Join(keyVisualLayerPath(layer), fmt.Sprintf("%020d", ts))
+}
+
+func placementScanReportPath(ts int64) string {
+	return path.Join(placementScanPath, fmt.Sprintf("%020d", ts))
+}
+
 func replicationModePath(mode string) string {
 	return path.Join(replicationPath, mode)
 }
@@ -107,6 +177,58 @@ func MinResolvedTSPath() string {
 	return path.Join(clusterPath, minResolvedTS)
 }
 
+func storeNotesRootPrefix() string {
+	return path.Join(clusterPath, storeNotePath) + "/"
+}
+
+func storeNotePrefix(storeID uint64) string {
+	return path.Join(clusterPath, storeNotePath, fmt.Sprintf("%020d", storeID)) + "/"
+}
+
+func storeNoteKeyPath(storeID, noteID uint64) string {
+	return path.Join(clusterPath, storeNotePath, fmt.Sprintf("%020d", storeID), fmt.Sprintf("%020d", noteID))
+}
+
+func operatorTemplatesPath() string {
+	return path.Join(clusterPath, operatorTemplatePath)
+}
+
+func operatorTemplateKeyPath(name string) string {
+	return path.Join(clusterPath, operatorTemplatePath, name)
+}
+
+func versionGatePrefix() string {
+	return path.Join(clusterPath, versionGatePath) + "/"
+}
+
+func versionGateKindPath(kind string) string {
+	return path.Join(clusterPath, versionGatePath, kind)
+}
+
+func versionGateKeyPath(kind, component string) string {
+	return path.Join(versionGateKindPath(kind), component)
+}
+
+func tasksPath() string {
+	return path.Join(clusterPath, taskPath)
+}
+
+func taskKeyPath(id uint64) string {
+	return path.Join(tasksPath(), fmt.Sprintf("%020d", id))
+}
+
+func importModesPath() string {
+	return path.Join(clusterPath, importModePath)
+}
+
+func importModeKeyPath(id string) string {
+	return path.Join(importModesPath(), id)
+}
+
+func storeTokensPath() string {
+	return path.Join(clusterPath, storeTokenPath)
+}
+
 // KeySpaceServiceSafePointPrefix returns the prefix of given service's service safe point.
 // Prefix: /key_space/gc_safepoint/{space_id}/service/
 func KeySpaceServiceSafePointPrefix(spaceID string) string {
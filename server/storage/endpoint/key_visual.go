@@ -0,0 +1,43 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"fmt"
+)
+
+// KeyVisualStorage defines the storage operations on the key visual heatmap columns.
+type KeyVisualStorage interface {
+	LoadKeyVisualColumns(layer int, f func(k, v string)) error
+	SaveKeyVisualColumn(layer int, ts int64, column interface{}) error
+	DeleteKeyVisualColumn(layer int, ts int64) error
+}
+
+var _ KeyVisualStorage = (*StorageEndpoint)(nil)
+
+// LoadKeyVisualColumns loads the columns of the given layer in the order of time.
+func (se *StorageEndpoint) LoadKeyVisualColumns(layer int, f func(k, v string)) error {
+	return se.loadRangeByPrefix(keyVisualLayerPath(layer)+"/", f)
+}
+
+// SaveKeyVisualColumn saves a column of the given layer, which is identified by its timestamp.
+func (se *StorageEndpoint) SaveKeyVisualColumn(layer int, ts int64, column interface{}) error {
+	return se.saveJSON(keyVisualLayerPath(layer), fmt.Sprintf("%020d", ts), column)
+}
+
+// DeleteKeyVisualColumn removes a column of the given layer.
+func (se *StorageEndpoint) DeleteKeyVisualColumn(layer int, ts int64) error {
+	return se.Remove(keyVisualColumnPath(layer, ts))
+}
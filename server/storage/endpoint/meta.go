@@ -16,8 +16,11 @@ package endpoint
 
 import (
 	"context"
+	"encoding/json"
 	"math"
+	"path"
 	"strconv"
+	"sync"
 	"time"
 
 	"github.com/gogo/protobuf/proto"
@@ -35,6 +38,8 @@ type MetaStorage interface {
 	LoadStore(storeID uint64, store *metapb.Store) (bool, error)
 	SaveStore(store *metapb.Store) error
 	SaveStoreWeight(storeID uint64, leader, region float64) error
+	SaveStoreQuota(storeID uint64, leader, region uint64) error
+	SaveStoreTopology(storeID uint64, topology *core.StoreTopology) error
 	LoadStores(f func(store *core.StoreInfo)) error
 	DeleteStore(store *metapb.Store) error
 	RegionStorage
@@ -91,6 +96,35 @@ func (se *StorageEndpoint) SaveStoreWeight(storeID uint64, leader, region float6
 	return se.Save(storeRegionWeightPath(storeID), regionValue)
 }
 
+// SaveStoreQuota saves a store's soft quotas of the leader and region count to storage.
+func (se *StorageEndpoint) SaveStoreQuota(storeID uint64, leader, region uint64) error {
+	if err := se.Save(storeLeaderQuotaPath(storeID), strconv.FormatUint(leader, 10)); err != nil {
+		return err
+	}
+	return se.Save(storeRegionQuotaPath(storeID), strconv.FormatUint(region, 10))
+}
+
+// SaveStoreTopology saves the hardware topology reported by a store to storage.
+func (se *StorageEndpoint) SaveStoreTopology(storeID uint64, topology *core.StoreTopology) error {
+	value, err := json.Marshal(topology)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return se.Save(storeTopologyPath(storeID), string(value))
+}
+
+func (se *StorageEndpoint) loadStoreTopology(storeID uint64) (*core.StoreTopology, error) {
+	value, err := se.Load(storeTopologyPath(storeID))
+	if err != nil || value == "" {
+		return nil, err
+	}
+	topology := &core.StoreTopology{}
+	if err := json.Unmarshal([]byte(value), topology); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return topology, nil
+}
+
 // LoadStores loads all stores from storage to StoresInfo.
 func (se *StorageEndpoint) LoadStores(f func(store *core.StoreInfo)) error {
 	nextID := uint64(0)
@@ -120,7 +154,23 @@ func (se *StorageEndpoint) LoadStores(f func(store *core.StoreInfo)) error {
 			if err != nil {
 				return err
 			}
-			newStoreInfo := core.NewStoreInfo(store, core.SetLeaderWeight(leaderWeight), core.SetRegionWeight(regionWeight))
+			leaderQuota, err := se.loadUint64WithDefaultValue(storeLeaderQuotaPath(store.GetId()), 0)
+			if err != nil {
+				return err
+			}
+			regionQuota, err := se.loadUint64WithDefaultValue(storeRegionQuotaPath(store.GetId()), 0)
+			if err != nil {
+				return err
+			}
+			topology, err := se.loadStoreTopology(store.GetId())
+			if err != nil {
+				return err
+			}
+			newStoreInfo := core.NewStoreInfo(store,
+				core.SetLeaderWeight(leaderWeight),
+				core.SetRegionWeight(regionWeight),
+				core.SetStoreQuota(leaderQuota, regionQuota),
+				core.SetStoreTopology(topology))
 
 			nextID = store.GetId() + 1
 			f(newStoreInfo)
@@ -146,6 +196,21 @@ func (se *StorageEndpoint) loadFloatWithDefaultValue(path string, def float64) (
 	return val, nil
 }
 
+func (se *StorageEndpoint) loadUint64WithDefaultValue(path string, def uint64) (uint64, error) {
+	res, err := se.Load(path)
+	if err != nil {
+		return 0, err
+	}
+	if res == "" {
+		return def, nil
+	}
+	val, err := strconv.ParseUint(res, 10, 64)
+	if err != nil {
+		return 0, errs.ErrStrconvParseUint.Wrap(err).GenWithStackByArgs()
+	}
+	return val, nil
+}
+
 // DeleteStore deletes one store from storage.
 func (se *StorageEndpoint) DeleteStore(store *metapb.Store) error {
 	return se.Remove(StorePath(store.GetId()))
@@ -165,8 +230,81 @@ func (se *StorageEndpoint) LoadRegion(regionID uint64, region *metapb.Region) (o
 	return true, err
 }
 
+// loadRegionsWorkerCount is the number of workers to decode the loaded regions
+// concurrently. The number of batches in flight is bounded by it as well, so at
+// most about 3 * loadRegionsWorkerCount * MaxKVRangeLimit regions are kept in memory.
+const loadRegionsWorkerCount = 4
+
+// regionBatch is a batch of regions loaded from a continuous key range.
+type regionBatch struct {
+	raw     []string
+	regions []*metapb.Region
+	err     error
+	// done is closed once the batch is decoded.
+	done chan struct{}
+}
+
 // LoadRegions loads all regions from storage to RegionsInfo.
+// The key range of the regions is scanned in batches, and the batches are decoded
+// by several workers concurrently. f is still called one by one in the order
+// of the region ID, the same as loading the regions serially.
 func (se *StorageEndpoint) LoadRegions(ctx context.Context, f func(region *core.RegionInfo) []*core.RegionInfo) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var (
+		wg       sync.WaitGroup
+		scanErr  error
+		decodeCh = make(chan *regionBatch, loadRegionsWorkerCount)
+		applyCh  = make(chan *regionBatch, 2*loadRegionsWorkerCount)
+	)
+	wg.Add(loadRegionsWorkerCount)
+	for i := 0; i < loadRegionsWorkerCount; i++ {
+		go func() {
+			defer wg.Done()
+			for batch := range decodeCh {
+				se.decodeRegionBatch(batch)
+			}
+		}()
+	}
+	go func() {
+		defer close(applyCh)
+		defer close(decodeCh)
+		scanErr = se.scanRegions(ctx, func(raw []string) bool {
+			batch := &regionBatch{raw: raw, done: make(chan struct{})}
+			// Enqueue the batch in order before decoding it, the applier
+			// will wait for it to be decoded.
+			select {
+			case applyCh <- batch:
+			case <-ctx.Done():
+				return false
+			}
+			decodeCh <- batch
+			return true
+		})
+	}()
+
+	var err error
+	for batch := range applyCh {
+		<-batch.done
+		// Drain the remaining batches if any error occurs.
+		if err != nil {
+			continue
+		}
+		if err = se.applyRegionBatch(batch, f); err != nil {
+			cancel()
+		}
+	}
+	wg.Wait()
+	if err != nil {
+		return err
+	}
+	return scanErr
+}
+
+// scanRegions scans the raw regions from storage in batches, and calls f with
+// each batch. It stops scanning if f returns false.
+func (se *StorageEndpoint) scanRegions(ctx context.Context, f func(raw []string) bool) error {
 	nextID := uint64(0)
 	endKey := RegionPath(math.MaxUint64)
 
@@ -180,7 +318,7 @@ func (se *StorageEndpoint) LoadRegions(ctx context.Context, f func(region *core.
 			time.Sleep(time.Second)
 		})
 		startKey := RegionPath(nextID)
-		_, res, err := se.LoadRange(startKey, endKey, rangeLimit)
+		keys, res, err := se.LoadRange(startKey, endKey, rangeLimit)
 		if err != nil {
 			if rangeLimit /= 2; rangeLimit >= MinKVRangeLimit {
 				continue
@@ -192,29 +330,54 @@ func (se *StorageEndpoint) LoadRegions(ctx context.Context, f func(region *core.
 			return ctx.Err()
 		default:
 		}
-
-		for _, r := range res {
-			region := &metapb.Region{}
-			if err := region.Unmarshal([]byte(r)); err != nil {
-				return errs.ErrProtoUnmarshal.Wrap(err).GenWithStackByArgs()
-			}
-			if err = encryption.DecryptRegion(region, se.encryptionKeyManager); err != nil {
-				return err
-			}
-
-			nextID = region.GetId() + 1
-			overlaps := f(core.NewRegionInfo(region, nil))
-			for _, item := range overlaps {
-				if err := se.DeleteRegion(item.GetMeta()); err != nil {
-					return err
-				}
-			}
+		if len(res) == 0 {
+			return nil
+		}
+		lastID, err := strconv.ParseUint(path.Base(keys[len(keys)-1]), 10, 64)
+		if err != nil {
+			return errs.ErrStrconvParseUint.Wrap(err).GenWithStackByArgs()
+		}
+		if !f(res) {
+			return ctx.Err()
 		}
-
 		if len(res) < rangeLimit {
 			return nil
 		}
+		nextID = lastID + 1
+	}
+}
+
+func (se *StorageEndpoint) decodeRegionBatch(batch *regionBatch) {
+	defer close(batch.done)
+	regions := make([]*metapb.Region, 0, len(batch.raw))
+	for _, r := range batch.raw {
+		region := &metapb.Region{}
+		if err := region.Unmarshal([]byte(r)); err != nil {
+			batch.err = errs.ErrProtoUnmarshal.Wrap(err).GenWithStackByArgs()
+			return
+		}
+		if err := encryption.DecryptRegion(region, se.encryptionKeyManager); err != nil {
+			batch.err = err
+			return
+		}
+		regions = append(regions, region)
+	}
+	batch.raw, batch.regions = nil, regions
+}
+
+func (se *StorageEndpoint) applyRegionBatch(batch *regionBatch, f func(region *core.RegionInfo) []*core.RegionInfo) error {
+	if batch.err != nil {
+		return batch.err
+	}
+	for _, region := range batch.regions {
+		overlaps := f(core.NewRegionInfo(region, nil))
+		for _, item := range overlaps {
+			if err := se.DeleteRegion(item.GetMeta()); err != nil {
+				return err
+			}
+		}
 	}
+	return nil
 }
 
 // SaveRegion saves one region to storage.
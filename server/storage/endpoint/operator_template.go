@@ -0,0 +1,84 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// OperatorTemplateParam is a parameter of an operator template.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type OperatorTemplateParam struct {
+	Name string `json:"name"`
+	// Type is one of "uint64", "string" and "uint64-list".
+	Type        string      `json:"type"`
+	Required    bool        `json:"required,omitempty"`
+	Default     interface{} `json:"default,omitempty"`
+	Description string      `json:"description,omitempty"`
+}
+
+// OperatorTemplate is a named operator with parameters, which is expanded into
+// the input of the operator creation API. The string values of the arguments
+// in the form of "{param}" are replaced with the values of the parameters.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type OperatorTemplate struct {
+	Name        string                  `json:"name"`
+	Description string                  `json:"description,omitempty"`
+	Operator    string                  `json:"operator"`
+	Params      []OperatorTemplateParam `json:"params,omitempty"`
+	Args        map[string]interface{}  `json:"args,omitempty"`
+	BuiltIn     bool                    `json:"built_in,omitempty"`
+}
+
+// OperatorTemplateStorage defines the storage operations on the operator templates.
+type OperatorTemplateStorage interface {
+	LoadOperatorTemplates() ([]*OperatorTemplate, error)
+	SaveOperatorTemplate(template *OperatorTemplate) error
+	DeleteOperatorTemplate(name string) error
+}
+
+var _ OperatorTemplateStorage = (*StorageEndpoint)(nil)
+
+// LoadOperatorTemplates loads all operator templates.
+func (se *StorageEndpoint) LoadOperatorTemplates() ([]*OperatorTemplate, error) {
+	var (
+		templates []*OperatorTemplate
+		err       error
+	)
+	loadErr := se.loadRangeByPrefix(operatorTemplatesPath()+"/", func(k, v string) {
+		template := &OperatorTemplate{}
+		if e := json.Unmarshal([]byte(v), template); e != nil {
+			err = errs.ErrJSONUnmarshal.Wrap(e).GenWithStackByArgs()
+			return
+		}
+		templates = append(templates, template)
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return templates, err
+}
+
+// SaveOperatorTemplate saves an operator template.
+func (se *StorageEndpoint) SaveOperatorTemplate(template *OperatorTemplate) error {
+	return se.saveJSON(operatorTemplatesPath(), template.Name, template)
+}
+
+// DeleteOperatorTemplate removes an operator template.
+func (se *StorageEndpoint) DeleteOperatorTemplate(name string) error {
+	return se.Remove(operatorTemplateKeyPath(name))
+}
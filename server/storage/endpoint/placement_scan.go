@@ -0,0 +1,43 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"fmt"
+)
+
+// PlacementScanStorage defines the storage operations on the reports of the placement scans.
+type PlacementScanStorage interface {
+	LoadPlacementScanReports(f func(k, v string)) error
+	SavePlacementScanReport(ts int64, report interface{}) error
+	DeletePlacementScanReport(ts int64) error
+}
+
+var _ PlacementScanStorage = (*StorageEndpoint)(nil)
+
+// LoadPlacementScanReports loads the reports of the placement scans in the order of time.
+func (se *StorageEndpoint) LoadPlacementScanReports(f func(k, v string)) error {
+	return se.loadRangeByPrefix(placementScanPath+"/", f)
+}
+
+// SavePlacementScanReport saves the report of a placement scan, which is identified by its timestamp.
+func (se *StorageEndpoint) SavePlacementScanReport(ts int64, report interface{}) error {
+	return se.saveJSON(placementScanPath, fmt.Sprintf("%020d", ts), report)
+}
+
+// DeletePlacementScanReport removes the report of a placement scan.
+func (se *StorageEndpoint) DeletePlacementScanReport(ts int64) error {
+	return se.Remove(placementScanReportPath(ts))
+}
@@ -0,0 +1,52 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// SchedulerDiagnosisStorage defines the storage operations on the diagnosis of the schedulers.
+type SchedulerDiagnosisStorage interface {
+	LoadSchedulerDiagnosis(name string, diagnosis interface{}) (bool, error)
+	SaveSchedulerDiagnosis(name string, diagnosis interface{}) error
+	RemoveSchedulerDiagnosis(name string) error
+}
+
+var _ SchedulerDiagnosisStorage = (*StorageEndpoint)(nil)
+
+// LoadSchedulerDiagnosis loads the diagnosis of the scheduler with the given name.
+func (se *StorageEndpoint) LoadSchedulerDiagnosis(name string, diagnosis interface{}) (bool, error) {
+	value, err := se.Load(schedulerDiagnosisKeyPath(name))
+	if err != nil || value == "" {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(value), diagnosis); err != nil {
+		return false, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return true, nil
+}
+
+// SaveSchedulerDiagnosis saves the diagnosis of the scheduler with the given name.
+func (se *StorageEndpoint) SaveSchedulerDiagnosis(name string, diagnosis interface{}) error {
+	return se.saveJSON(schedulerDiagnosisPath, name, diagnosis)
+}
+
+// RemoveSchedulerDiagnosis removes the diagnosis of the scheduler with the given name.
+func (se *StorageEndpoint) RemoveSchedulerDiagnosis(name string) error {
+	return se.Remove(schedulerDiagnosisKeyPath(name))
+}
@@ -0,0 +1,84 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// StoreNote is an annotation entered by operators for a store, such as the
+// maintenance done on it.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type StoreNote struct {
+	ID        uint64    `json:"id"`
+	StoreID   uint64    `json:"store_id"`
+	Content   string    `json:"content"`
+	Author    string    `json:"author,omitempty"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// StoreNoteStorage defines the storage operations on the store notes.
+type StoreNoteStorage interface {
+	LoadStoreNotes(storeID uint64) ([]*StoreNote, error)
+	LoadAllStoreNotes() ([]*StoreNote, error)
+	SaveStoreNote(note *StoreNote) error
+	DeleteStoreNote(storeID, noteID uint64) error
+}
+
+var _ StoreNoteStorage = (*StorageEndpoint)(nil)
+
+// LoadStoreNotes loads the notes of the given store in the order of creation.
+func (se *StorageEndpoint) LoadStoreNotes(storeID uint64) ([]*StoreNote, error) {
+	return se.loadStoreNotes(storeNotePrefix(storeID))
+}
+
+// LoadAllStoreNotes loads the notes of all stores.
+func (se *StorageEndpoint) LoadAllStoreNotes() ([]*StoreNote, error) {
+	return se.loadStoreNotes(storeNotesRootPrefix())
+}
+
+func (se *StorageEndpoint) loadStoreNotes(prefix string) ([]*StoreNote, error) {
+	var (
+		notes []*StoreNote
+		err   error
+	)
+	loadErr := se.loadRangeByPrefix(prefix, func(k, v string) {
+		note := &StoreNote{}
+		if e := json.Unmarshal([]byte(v), note); e != nil {
+			err = errs.ErrJSONUnmarshal.Wrap(e).GenWithStackByArgs()
+			return
+		}
+		notes = append(notes, note)
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return notes, err
+}
+
+// SaveStoreNote saves a store note.
+func (se *StorageEndpoint) SaveStoreNote(note *StoreNote) error {
+	return se.saveJSON(storeNotePrefix(note.StoreID), fmt.Sprintf("%020d", note.ID), note)
+}
+
+// DeleteStoreNote removes a store note.
+func (se *StorageEndpoint) DeleteStoreNote(storeID, noteID uint64) error {
+	return se.Remove(storeNoteKeyPath(storeID, noteID))
+}
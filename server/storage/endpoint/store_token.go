@@ -0,0 +1,60 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// StoreTokens records the digests of the tokens which the stores present to
+// authenticate themselves. The previous token stays valid until it is revoked,
+// so that the stores can be switched to the current one gradually.
+type StoreTokens struct {
+	Current  string `json:"current"`
+	Previous string `json:"previous,omitempty"`
+	IssuedAt int64  `json:"issued_at"`
+}
+
+// StoreTokenStorage defines the storage operations on the store tokens.
+type StoreTokenStorage interface {
+	LoadStoreTokens() (*StoreTokens, error)
+	SaveStoreTokens(tokens *StoreTokens) error
+}
+
+var _ StoreTokenStorage = (*StorageEndpoint)(nil)
+
+// LoadStoreTokens loads the store tokens. It returns nil if no token has been issued.
+func (se *StorageEndpoint) LoadStoreTokens() (*StoreTokens, error) {
+	value, err := se.Load(storeTokensPath())
+	if err != nil || value == "" {
+		return nil, err
+	}
+	tokens := &StoreTokens{}
+	if err := json.Unmarshal([]byte(value), tokens); err != nil {
+		return nil, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return tokens, nil
+}
+
+// SaveStoreTokens saves the store tokens.
+func (se *StorageEndpoint) SaveStoreTokens(tokens *StoreTokens) error {
+	value, err := json.Marshal(tokens)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return se.Save(storeTokensPath(), string(value))
+}
@@ -0,0 +1,76 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// Reasons of adding the suspect key ranges.
+const (
+	SuspectReasonRuleChanged      = "rule-changed"
+	SuspectReasonRuleGroupChanged = "rule-group-changed"
+	// SuspectReasonRemainingRange means the key range is the remaining part of
+	// a suspect key range which is partially checked.
+	SuspectReasonRemainingRange = "remaining-range"
+)
+
+// SuspectKeyRange is a key range whose regions may need to be fixed, e.g. after
+// the placement rules covering it are changed.
+type SuspectKeyRange struct {
+	StartKey []byte `json:"start_key"`
+	EndKey   []byte `json:"end_key"`
+	Reason   string `json:"reason"`
+	// Timestamp is the unix time in seconds when the key range is added.
+	Timestamp int64 `json:"timestamp"`
+}
+
+// SuspectKeyRangeStorage defines the storage operations on the suspect key ranges.
+type SuspectKeyRangeStorage interface {
+	LoadSuspectKeyRanges(f func(key string, keyRange *SuspectKeyRange)) error
+	SaveSuspectKeyRange(key string, keyRange *SuspectKeyRange) error
+	DeleteSuspectKeyRange(key string) error
+}
+
+var _ SuspectKeyRangeStorage = (*StorageEndpoint)(nil)
+
+// LoadSuspectKeyRanges loads all suspect key ranges from storage.
+func (se *StorageEndpoint) LoadSuspectKeyRanges(f func(key string, keyRange *SuspectKeyRange)) error {
+	var decodeErr error
+	err := se.loadRangeByPrefix(suspectKeyRangePath+"/", func(k, v string) {
+		keyRange := &SuspectKeyRange{}
+		if err := json.Unmarshal([]byte(v), keyRange); err != nil {
+			decodeErr = errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByArgs()
+			return
+		}
+		f(k, keyRange)
+	})
+	if err != nil {
+		return err
+	}
+	return decodeErr
+}
+
+// SaveSuspectKeyRange saves a suspect key range to storage.
+func (se *StorageEndpoint) SaveSuspectKeyRange(key string, keyRange *SuspectKeyRange) error {
+	return se.saveJSON(suspectKeyRangePath, key, keyRange)
+}
+
+// DeleteSuspectKeyRange removes a suspect key range from storage.
+func (se *StorageEndpoint) DeleteSuspectKeyRange(key string) error {
+	return se.Remove(suspectKeyRangeKeyPath(key))
+}
@@ -0,0 +1,82 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// TaskRecord is the persisted record of a long-running task, such as scattering
+// regions or draining a store.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type TaskRecord struct {
+	ID     uint64          `json:"id"`
+	Type   string          `json:"type"`
+	Params json.RawMessage `json:"params,omitempty"`
+	State  string          `json:"state"`
+	// Progress is in the range of [0, 1].
+	Progress float64 `json:"progress"`
+	// Error is the reason why the task fails.
+	Error string `json:"error,omitempty"`
+	// Details is the status of the parts of the task, such as the operators of a
+	// region merge. It depends on the type.
+	Details   json.RawMessage `json:"details,omitempty"`
+	Retries   int             `json:"retries"`
+	CreatedAt time.Time       `json:"created_at"`
+	UpdatedAt time.Time       `json:"updated_at"`
+}
+
+// TaskStorage defines the storage operations on the task records.
+type TaskStorage interface {
+	LoadTasks() ([]*TaskRecord, error)
+	SaveTask(task *TaskRecord) error
+	DeleteTask(id uint64) error
+}
+
+var _ TaskStorage = (*StorageEndpoint)(nil)
+
+// LoadTasks loads all task records in the order of their IDs.
+func (se *StorageEndpoint) LoadTasks() ([]*TaskRecord, error) {
+	var (
+		tasks []*TaskRecord
+		err   error
+	)
+	loadErr := se.loadRangeByPrefix(tasksPath()+"/", func(k, v string) {
+		task := &TaskRecord{}
+		if e := json.Unmarshal([]byte(v), task); e != nil {
+			err = errs.ErrJSONUnmarshal.Wrap(e).GenWithStackByArgs()
+			return
+		}
+		tasks = append(tasks, task)
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return tasks, err
+}
+
+// SaveTask saves a task record.
+func (se *StorageEndpoint) SaveTask(task *TaskRecord) error {
+	return se.saveJSON(tasksPath(), fmt.Sprintf("%020d", task.ID), task)
+}
+
+// DeleteTask removes a task record.
+func (se *StorageEndpoint) DeleteTask(id uint64) error {
+	return se.Remove(taskKeyPath(id))
+}
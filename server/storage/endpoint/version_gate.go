@@ -0,0 +1,74 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// VersionGateRecord records a scheduling component which is disabled because it
+// does not support the new cluster version.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type VersionGateRecord struct {
+	// Kind is either "scheduler" or "checker".
+	Kind string `json:"kind"`
+	// Component is the name of the scheduler or the checker.
+	Component      string    `json:"component"`
+	ClusterVersion string    `json:"cluster_version"`
+	Reason         string    `json:"reason"`
+	DisabledAt     time.Time `json:"disabled_at"`
+}
+
+// VersionGateStorage defines the storage operations on the version gate records.
+type VersionGateStorage interface {
+	LoadVersionGateRecords() ([]*VersionGateRecord, error)
+	SaveVersionGateRecord(record *VersionGateRecord) error
+	DeleteVersionGateRecord(kind, component string) error
+}
+
+var _ VersionGateStorage = (*StorageEndpoint)(nil)
+
+// LoadVersionGateRecords loads all version gate records.
+func (se *StorageEndpoint) LoadVersionGateRecords() ([]*VersionGateRecord, error) {
+	var (
+		records []*VersionGateRecord
+		err     error
+	)
+	loadErr := se.loadRangeByPrefix(versionGatePrefix(), func(k, v string) {
+		record := &VersionGateRecord{}
+		if e := json.Unmarshal([]byte(v), record); e != nil {
+			err = errs.ErrJSONUnmarshal.Wrap(e).GenWithStackByArgs()
+			return
+		}
+		records = append(records, record)
+	})
+	if loadErr != nil {
+		return nil, loadErr
+	}
+	return records, err
+}
+
+// SaveVersionGateRecord saves a version gate record.
+func (se *StorageEndpoint) SaveVersionGateRecord(record *VersionGateRecord) error {
+	return se.saveJSON(versionGateKindPath(record.Kind), record.Component, record)
+}
+
+// DeleteVersionGateRecord removes the version gate record of the component.
+func (se *StorageEndpoint) DeleteVersionGateRecord(kind, component string) error {
+	return se.Remove(versionGateKeyPath(kind, component))
+}
@@ -0,0 +1,50 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package endpoint
+
+import (
+	"encoding/json"
+
+	"github.com/tikv/pd/pkg/errs"
+)
+
+// WaitingOperatorSnapshotStorage defines the storage operations on the snapshot of the waiting operators.
+type WaitingOperatorSnapshotStorage interface {
+	LoadWaitingOperatorSnapshot(snapshot interface{}) (bool, error)
+	SaveWaitingOperatorSnapshot(snapshot interface{}) error
+}
+
+var _ WaitingOperatorSnapshotStorage = (*StorageEndpoint)(nil)
+
+// LoadWaitingOperatorSnapshot loads the snapshot of the waiting operators.
+func (se *StorageEndpoint) LoadWaitingOperatorSnapshot(snapshot interface{}) (bool, error) {
+	value, err := se.Load(waitingOperatorsPath)
+	if err != nil || value == "" {
+		return false, err
+	}
+	if err := json.Unmarshal([]byte(value), snapshot); err != nil {
+		return false, errs.ErrJSONUnmarshal.Wrap(err).GenWithStackByCause()
+	}
+	return true, nil
+}
+
+// SaveWaitingOperatorSnapshot saves the snapshot of the waiting operators.
+func (se *StorageEndpoint) SaveWaitingOperatorSnapshot(snapshot interface{}) error {
+	value, err := json.Marshal(snapshot)
+	if err != nil {
+		return errs.ErrJSONMarshal.Wrap(err).GenWithStackByArgs()
+	}
+	return se.Save(waitingOperatorsPath, string(value))
+}
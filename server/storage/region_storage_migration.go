@@ -0,0 +1,195 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/gogo/protobuf/proto"
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/errs"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/server/storage/endpoint"
+	"go.uber.org/zap"
+)
+
+// RegionStorageVerification is the result of comparing the regions persisted
+// in the defaultStorage with the ones in the regionStorage.
+type RegionStorageVerification struct {
+	// Total is the number of regions in the defaultStorage.
+	Total int `json:"total"`
+	// Missing is the number of regions which are only in the defaultStorage.
+	Missing int `json:"missing"`
+	// Mismatched is the number of regions which differ between the two storages.
+	Mismatched int `json:"mismatched"`
+	// Extra is the number of regions which are only in the regionStorage.
+	Extra int `json:"extra"`
+	// Repaired is the number of regions fixed in the regionStorage.
+	Repaired int `json:"repaired"`
+}
+
+// IsConsistent returns true if the regionStorage is the same as the defaultStorage.
+func (v *RegionStorageVerification) IsConsistent() bool {
+	return v.Missing == 0 && v.Mismatched == 0 && v.Extra == 0
+}
+
+// TrySetRegionStorageDualWrite tries to enable or disable the dual-write mode
+// of the region storage. In the dual-write mode, the region info is saved to
+// both the defaultStorage and the regionStorage, and is read from the
+// regionStorage first, falling back to the defaultStorage for the regions which
+// have not been migrated yet, which makes it possible to migrate the regions
+// between them online. Returns false if the storage does not support it.
+func TrySetRegionStorageDualWrite(s Storage, enable bool) bool {
+	ps, ok := s.(*coreStorage)
+	if !ok || ps.regionStorage == nil {
+		return false
+	}
+	if enable {
+		atomic.StoreInt32(&ps.regionDualWrite, 1)
+	} else {
+		atomic.StoreInt32(&ps.regionDualWrite, 0)
+	}
+	return true
+}
+
+// IsRegionStorageDualWrite returns whether the storage is in the dual-write mode.
+func IsRegionStorageDualWrite(s Storage) bool {
+	ps, ok := s.(*coreStorage)
+	return ok && ps.isRegionDualWrite()
+}
+
+func (ps *coreStorage) isRegionDualWrite() bool {
+	return atomic.LoadInt32(&ps.regionDualWrite) > 0
+}
+
+// loadRegionWithFallback loads the region from the regionStorage, or from the
+// defaultStorage if it is not migrated yet or the regionStorage fails. The
+// writes go to both storages in the dual-write mode, so a region found in the
+// regionStorage is never older than the one in the defaultStorage.
+func (ps *coreStorage) loadRegionWithFallback(regionID uint64, region *metapb.Region) (bool, error) {
+	ok, err := ps.regionStorage.LoadRegion(regionID, region)
+	if err == nil && ok {
+		return true, nil
+	}
+	if err != nil {
+		log.Warn("failed to load region from the region storage, fall back to the default storage",
+			zap.Uint64("region-id", regionID), errs.ZapError(err))
+	}
+	return ps.Storage.LoadRegion(regionID, region)
+}
+
+// loadRegionsWithFallback loads the regions from the regionStorage, and then
+// the ones which are not migrated yet from the defaultStorage. If the
+// regionStorage fails, all the regions are loaded from the defaultStorage.
+func (ps *coreStorage) loadRegionsWithFallback(ctx context.Context, f func(region *core.RegionInfo) []*core.RegionInfo) error {
+	loaded := make(map[uint64]struct{})
+	err := ps.regionStorage.LoadRegions(ctx, func(region *core.RegionInfo) []*core.RegionInfo {
+		loaded[region.GetID()] = struct{}{}
+		return deleteOverlaps(ps.Storage, f(region))
+	})
+	if err != nil {
+		log.Warn("failed to load regions from the region storage, fall back to the default storage", errs.ZapError(err))
+		return ps.Storage.LoadRegions(ctx, f)
+	}
+	return ps.Storage.LoadRegions(ctx, func(region *core.RegionInfo) []*core.RegionInfo {
+		if _, ok := loaded[region.GetID()]; ok {
+			return nil
+		}
+		return deleteOverlaps(ps.regionStorage, f(region))
+	})
+}
+
+// deleteOverlaps deletes the overlapped regions from the other storage, since
+// each storage only deletes the overlapped regions from itself when loading.
+func deleteOverlaps(s endpoint.RegionStorage, overlaps []*core.RegionInfo) []*core.RegionInfo {
+	for _, item := range overlaps {
+		if err := s.DeleteRegion(item.GetMeta()); err != nil {
+			log.Warn("failed to delete the overlapped region", zap.Uint64("region-id", item.GetID()), errs.ZapError(err))
+		}
+	}
+	return overlaps
+}
+
+// VerifyRegionStorage compares the regions in the defaultStorage with the ones
+// in the regionStorage. If repair is true, the regionStorage will be fixed to
+// be the same as the defaultStorage. Since the regions may be updated during
+// the verification, it should be done in the dual-write mode and be retried
+// until it is consistent.
+func VerifyRegionStorage(ctx context.Context, s Storage, repair bool) (*RegionStorageVerification, error) {
+	ps, ok := s.(*coreStorage)
+	if !ok || ps.regionStorage == nil {
+		return nil, errs.ErrRegionStorageUnavailable.FastGenByArgs()
+	}
+	// The regionStorage may cache the regions in memory before flushing.
+	if err := ps.regionStorage.Flush(); err != nil {
+		return nil, err
+	}
+	expected, err := loadRegionMetas(ctx, ps.Storage)
+	if err != nil {
+		return nil, err
+	}
+	actual, err := loadRegionMetas(ctx, ps.regionStorage)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &RegionStorageVerification{Total: len(expected)}
+	for id, region := range expected {
+		other, ok := actual[id]
+		switch {
+		case !ok:
+			result.Missing++
+		case !proto.Equal(region, other):
+			result.Mismatched++
+		default:
+			continue
+		}
+		if repair {
+			if err := ps.regionStorage.SaveRegion(region); err != nil {
+				return result, err
+			}
+			result.Repaired++
+		}
+	}
+	for id, region := range actual {
+		if _, ok := expected[id]; ok {
+			continue
+		}
+		result.Extra++
+		if repair {
+			if err := ps.regionStorage.DeleteRegion(region); err != nil {
+				return result, err
+			}
+			result.Repaired++
+		}
+	}
+	if repair && result.Repaired > 0 {
+		if err := ps.regionStorage.Flush(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+func loadRegionMetas(ctx context.Context, s endpoint.RegionStorage) (map[uint64]*metapb.Region, error) {
+	regions := make(map[uint64]*metapb.Region)
+	err := s.LoadRegions(ctx, func(region *core.RegionInfo) []*core.RegionInfo {
+		regions[region.GetID()] = region.GetMeta()
+		return nil
+	})
+	return regions, err
+}
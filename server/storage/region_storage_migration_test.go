@@ -0,0 +1,98 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/core"
+)
+
+func TestRegionStorageDualWrite(t *testing.T) {
+	re := require.New(t)
+	ctx := context.Background()
+	defaultStorage := NewStorageWithMemoryBackend()
+	localStorage := NewStorageWithMemoryBackend()
+	storage := NewCoreStorage(defaultStorage, localStorage)
+	re.False(TrySetRegionStorageDualWrite(defaultStorage, true))
+	_, err := VerifyRegionStorage(ctx, defaultStorage, false)
+	re.Error(err)
+
+	TrySwitchRegionStorage(storage, false)
+	mustSaveRegions(re, storage, 10)
+	re.True(TrySetRegionStorageDualWrite(storage, true))
+	re.True(IsRegionStorageDualWrite(storage))
+
+	// The regions which have not been migrated are read from the defaultStorage.
+	region := &metapb.Region{}
+	ok, err := storage.LoadRegion(3, region)
+	re.NoError(err)
+	re.True(ok)
+	re.Equal(uint64(3), region.GetId())
+	cache := core.NewRegionsInfo()
+	re.NoError(TryLoadRegionsOnce(ctx, storage, cache.SetRegion))
+	re.Len(cache.GetMetaRegions(), 10)
+	// The migrated regions are read from the regionStorage.
+	re.NoError(localStorage.SaveRegion(&metapb.Region{Id: 3}))
+	ok, err = storage.LoadRegion(3, region)
+	re.NoError(err)
+	re.True(ok)
+	re.Empty(region.GetStartKey())
+	re.NoError(localStorage.DeleteRegion(&metapb.Region{Id: 3}))
+
+	// The new writes go to both storages.
+	re.NoError(storage.SaveRegion(newTestRegionMeta(10)))
+	re.NoError(storage.DeleteRegion(newTestRegionMeta(0)))
+	ok, err = localStorage.LoadRegion(10, region)
+	re.NoError(err)
+	re.True(ok)
+	ok, err = defaultStorage.LoadRegion(0, region)
+	re.NoError(err)
+	re.False(ok)
+	re.NoError(localStorage.SaveRegion(newTestRegionMeta(100)))
+	re.NoError(localStorage.SaveRegion(&metapb.Region{Id: 1}))
+
+	result, err := VerifyRegionStorage(ctx, storage, false)
+	re.NoError(err)
+	re.Equal(10, result.Total)
+	re.Equal(8, result.Missing)
+	re.Equal(1, result.Mismatched)
+	re.Equal(1, result.Extra)
+	re.Zero(result.Repaired)
+	re.False(result.IsConsistent())
+
+	result, err = VerifyRegionStorage(ctx, storage, true)
+	re.NoError(err)
+	re.Equal(10, result.Repaired)
+	result, err = VerifyRegionStorage(ctx, storage, false)
+	re.NoError(err)
+	re.True(result.IsConsistent())
+
+	// The regionStorage is preferred once the regions are migrated.
+	re.NoError(localStorage.SaveRegion(newTestRegionMeta(200)))
+	ok, err = storage.LoadRegion(200, region)
+	re.NoError(err)
+	re.True(ok)
+	cache = core.NewRegionsInfo()
+	re.NoError(TryLoadRegionsOnce(ctx, storage, cache.SetRegion))
+	re.Len(cache.GetMetaRegions(), 11)
+	re.NotNil(cache.GetRegion(200))
+
+	re.True(TrySetRegionStorageDualWrite(storage, false))
+	re.False(IsRegionStorageDualWrite(storage))
+}
@@ -40,6 +40,20 @@ type Storage interface {
 	endpoint.GCSafePointStorage
 	endpoint.MinResolvedTSStorage
 	endpoint.KeySpaceGCSafePointStorage
+	endpoint.SuspectKeyRangeStorage
+	endpoint.HotPeerSnapshotStorage
+	endpoint.KeyVisualStorage
+	endpoint.StoreTokenStorage
+	endpoint.StoreNoteStorage
+	endpoint.SchedulerDiagnosisStorage
+	endpoint.ProgressSnapshotStorage
+	endpoint.OperatorTemplateStorage
+	endpoint.VersionGateStorage
+	endpoint.TaskStorage
+	endpoint.PlacementScanStorage
+	endpoint.WaitingOperatorSnapshotStorage
+	endpoint.BootstrapBundleStorage
+	endpoint.ImportModeStorage
 }
 
 // NewStorageWithMemoryBackend creates a new storage with memory backend.
@@ -68,8 +82,11 @@ type coreStorage struct {
 	regionStorage endpoint.RegionStorage
 
 	useRegionStorage int32
-	regionLoaded     bool
-	mu               syncutil.Mutex
+	// regionDualWrite is set when the regions are being migrated from the
+	// defaultStorage to the regionStorage, see TrySetRegionStorageDualWrite.
+	regionDualWrite int32
+	regionLoaded    bool
+	mu              syncutil.Mutex
 }
 
 // NewCoreStorage creates a new core storage with the given default and region storage.
@@ -124,6 +141,9 @@ func TryLoadRegionsOnce(ctx context.Context, s Storage, f func(region *core.Regi
 		return s.LoadRegions(ctx, f)
 	}
 
+	if ps.isRegionDualWrite() {
+		return ps.LoadRegions(ctx, f)
+	}
 	if atomic.LoadInt32(&ps.useRegionStorage) == 0 {
 		return ps.Storage.LoadRegions(ctx, f)
 	}
@@ -141,6 +161,9 @@ func TryLoadRegionsOnce(ctx context.Context, s Storage, f func(region *core.Regi
 
 // LoadRegion loads one region from storage.
 func (ps *coreStorage) LoadRegion(regionID uint64, region *metapb.Region) (ok bool, err error) {
+	if ps.isRegionDualWrite() {
+		return ps.loadRegionWithFallback(regionID, region)
+	}
 	if atomic.LoadInt32(&ps.useRegionStorage) > 0 {
 		return ps.regionStorage.LoadRegion(regionID, region)
 	}
@@ -149,6 +172,9 @@ func (ps *coreStorage) LoadRegion(regionID uint64, region *metapb.Region) (ok bo
 
 // LoadRegions loads all regions from storage to RegionsInfo.
 func (ps *coreStorage) LoadRegions(ctx context.Context, f func(region *core.RegionInfo) []*core.RegionInfo) error {
+	if ps.isRegionDualWrite() {
+		return ps.loadRegionsWithFallback(ctx, f)
+	}
 	if atomic.LoadInt32(&ps.useRegionStorage) > 0 {
 		return ps.regionStorage.LoadRegions(ctx, f)
 	}
@@ -157,6 +183,12 @@ func (ps *coreStorage) LoadRegions(ctx context.Context, f func(region *core.Regi
 
 // SaveRegion saves one region to storage.
 func (ps *coreStorage) SaveRegion(region *metapb.Region) error {
+	if ps.isRegionDualWrite() {
+		if err := ps.Storage.SaveRegion(region); err != nil {
+			return err
+		}
+		return ps.regionStorage.SaveRegion(region)
+	}
 	if atomic.LoadInt32(&ps.useRegionStorage) > 0 {
 		return ps.regionStorage.SaveRegion(region)
 	}
@@ -165,6 +197,12 @@ func (ps *coreStorage) SaveRegion(region *metapb.Region) error {
 
 // DeleteRegion deletes one region from storage.
 func (ps *coreStorage) DeleteRegion(region *metapb.Region) error {
+	if ps.isRegionDualWrite() {
+		if err := ps.Storage.DeleteRegion(region); err != nil {
+			return err
+		}
+		return ps.regionStorage.DeleteRegion(region)
+	}
 	if atomic.LoadInt32(&ps.useRegionStorage) > 0 {
 		return ps.regionStorage.DeleteRegion(region)
 	}
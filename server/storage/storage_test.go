@@ -282,6 +282,47 @@ func TestLoadRegionsExceedRangeLimit(t *testing.T) {
 	re.NoError(failpoint.Disable("github.com/tikv/pd/server/storage/kv/withRangeLimit"))
 }
 
+func TestLoadRegionsInOrder(t *testing.T) {
+	re := require.New(t)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/server/storage/kv/withRangeLimit", "return(200)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/server/storage/kv/withRangeLimit"))
+	}()
+	storage := NewStorageWithMemoryBackend()
+
+	n := 2000
+	mustSaveRegions(re, storage, n)
+	var ids []uint64
+	re.NoError(storage.LoadRegions(context.Background(), func(region *core.RegionInfo) []*core.RegionInfo {
+		ids = append(ids, region.GetID())
+		return nil
+	}))
+	re.Len(ids, n)
+	for i, id := range ids {
+		re.Equal(uint64(i), id)
+	}
+}
+
+func TestLoadRegionsWithBrokenRegion(t *testing.T) {
+	re := require.New(t)
+	re.NoError(failpoint.Enable("github.com/tikv/pd/server/storage/kv/withRangeLimit", "return(200)"))
+	defer func() {
+		re.NoError(failpoint.Disable("github.com/tikv/pd/server/storage/kv/withRangeLimit"))
+	}()
+	storage := NewStorageWithMemoryBackend()
+
+	n := 1000
+	mustSaveRegions(re, storage, n)
+	re.NoError(storage.Save(endpoint.RegionPath(500), "broken"))
+	count := 0
+	err := storage.LoadRegions(context.Background(), func(region *core.RegionInfo) []*core.RegionInfo {
+		count++
+		return nil
+	})
+	re.Error(err)
+	re.Less(count, n)
+}
+
 func TestTrySwitchRegionStorage(t *testing.T) {
 	re := require.New(t)
 	defaultStorage := NewStorageWithMemoryBackend()
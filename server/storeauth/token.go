@@ -0,0 +1,168 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeauth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"sync/atomic"
+	"time"
+
+	"github.com/pingcap/log"
+	"github.com/tikv/pd/pkg/syncutil"
+	"github.com/tikv/pd/server/storage/endpoint"
+)
+
+const tokenBytes = 32
+
+// notIssuedWarnInterval is the min interval of the warnings about the stores
+// passing the authentication since no token is issued.
+const notIssuedWarnInterval = time.Minute
+
+// TokenStatus is the status of the store tokens.
+// NOTE: This type is exported by HTTP API. Please pay more attention when modifying it.
+type TokenStatus struct {
+	Issued      bool      `json:"issued"`
+	IssuedAt    time.Time `json:"issued_at"`
+	HasPrevious bool      `json:"has_previous"`
+}
+
+// TokenManager issues, rotates and verifies the tokens which the stores
+// present when registering themselves and sending heartbeats. Only the
+// digests of the tokens are persisted.
+type TokenManager struct {
+	syncutil.RWMutex
+	storage endpoint.StoreTokenStorage
+	// tokens caches the persisted tokens, nil means no token is issued.
+	tokens *endpoint.StoreTokens
+	// lastNotIssuedWarn is the unix nano time of the last warning about no token
+	// being issued.
+	lastNotIssuedWarn int64
+}
+
+// NewTokenManager creates a TokenManager.
+func NewTokenManager(storage endpoint.StoreTokenStorage) *TokenManager {
+	return &TokenManager{storage: storage}
+}
+
+// Reload loads the tokens from the storage. It should be called once the
+// server becomes the leader, since the tokens may be rotated by others.
+func (m *TokenManager) Reload() error {
+	tokens, err := m.storage.LoadStoreTokens()
+	if err != nil {
+		return err
+	}
+	m.Lock()
+	defer m.Unlock()
+	m.tokens = tokens
+	return nil
+}
+
+// Issue generates a new token and returns it. The current token, if any,
+// becomes the previous one and stays valid until RevokePrevious is called.
+func (m *TokenManager) Issue() (string, error) {
+	buf := make([]byte, tokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	token := hex.EncodeToString(buf)
+
+	m.Lock()
+	defer m.Unlock()
+	tokens := &endpoint.StoreTokens{
+		Current:  digest(token),
+		IssuedAt: time.Now().Unix(),
+	}
+	if m.tokens != nil {
+		tokens.Previous = m.tokens.Current
+	}
+	if err := m.storage.SaveStoreTokens(tokens); err != nil {
+		return "", err
+	}
+	m.tokens = tokens
+	log.Info("store token is issued")
+	return token, nil
+}
+
+// RevokePrevious invalidates the previous token after a rotation.
+func (m *TokenManager) RevokePrevious() error {
+	m.Lock()
+	defer m.Unlock()
+	if m.tokens == nil || m.tokens.Previous == "" {
+		return nil
+	}
+	tokens := *m.tokens
+	tokens.Previous = ""
+	if err := m.storage.SaveStoreTokens(&tokens); err != nil {
+		return err
+	}
+	m.tokens = &tokens
+	log.Info("previous store token is revoked")
+	return nil
+}
+
+// Verify checks whether the token is the current or the previous one.
+func (m *TokenManager) Verify(token string) bool {
+	m.RLock()
+	defer m.RUnlock()
+	if m.tokens == nil || token == "" {
+		return false
+	}
+	d := digest(token)
+	return equal(d, m.tokens.Current) || (m.tokens.Previous != "" && equal(d, m.tokens.Previous))
+}
+
+// VerifyIfIssued is like Verify, but any token passes if no token is issued,
+// so that enabling the authentication before issuing a token doesn't lock out
+// all the stores. A warning is logged periodically meanwhile.
+func (m *TokenManager) VerifyIfIssued(token string) bool {
+	m.RLock()
+	issued := m.tokens != nil
+	m.RUnlock()
+	if issued {
+		return m.Verify(token)
+	}
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&m.lastNotIssuedWarn)
+	if now-last >= int64(notIssuedWarnInterval) && atomic.CompareAndSwapInt64(&m.lastNotIssuedWarn, last, now) {
+		log.Warn("store token authentication is enabled but no token is issued, the stores are not authenticated")
+	}
+	return true
+}
+
+// GetStatus returns the status of the tokens.
+func (m *TokenManager) GetStatus() *TokenStatus {
+	m.RLock()
+	defer m.RUnlock()
+	if m.tokens == nil {
+		return &TokenStatus{}
+	}
+	return &TokenStatus{
+		Issued:      true,
+		IssuedAt:    time.Unix(m.tokens.IssuedAt, 0),
+		HasPrevious: m.tokens.Previous != "",
+	}
+}
+
+func digest(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func equal(a, b string) bool {
+	return subtle.ConstantTimeCompare([]byte(a), []byte(b)) == 1
+}
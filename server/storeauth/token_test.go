@@ -0,0 +1,68 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package storeauth
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/server/storage"
+)
+
+func TestTokenRotation(t *testing.T) {
+	re := require.New(t)
+	s := storage.NewStorageWithMemoryBackend()
+	m := NewTokenManager(s)
+	re.NoError(m.Reload())
+	re.False(m.GetStatus().Issued)
+	re.False(m.Verify(""))
+	re.False(m.Verify("token"))
+	// Any token passes before a token is issued.
+	re.True(m.VerifyIfIssued(""))
+	re.True(m.VerifyIfIssued("token"))
+
+	first, err := m.Issue()
+	re.NoError(err)
+	re.True(m.Verify(first))
+	re.False(m.Verify("token"))
+	re.True(m.VerifyIfIssued(first))
+	re.False(m.VerifyIfIssued("token"))
+	re.True(m.GetStatus().Issued)
+	re.False(m.GetStatus().HasPrevious)
+
+	// Both tokens are valid during the rotation.
+	second, err := m.Issue()
+	re.NoError(err)
+	re.NotEqual(first, second)
+	re.True(m.Verify(first))
+	re.True(m.Verify(second))
+	re.True(m.GetStatus().HasPrevious)
+
+	re.NoError(m.RevokePrevious())
+	re.False(m.Verify(first))
+	re.True(m.Verify(second))
+
+	// The plain tokens are never persisted.
+	tokens, err := s.LoadStoreTokens()
+	re.NoError(err)
+	re.NotEqual(second, tokens.Current)
+	re.Empty(tokens.Previous)
+
+	// Another manager on the same storage sees the same tokens after reloading.
+	other := NewTokenManager(s)
+	re.False(other.Verify(second))
+	re.NoError(other.Reload())
+	re.True(other.Verify(second))
+}
@@ -0,0 +1,49 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package server
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTSORequestDispatcherStop(t *testing.T) {
+	re := require.New(t)
+	d := newTSORequestDispatcher()
+	for i := 0; i < maxMergeTSORequests; i++ {
+		re.True(d.send(&tsoRequest{}))
+	}
+	// the sender waiting for the full channel gives up once the dispatcher stops.
+	sent := make(chan bool)
+	go func() { sent <- d.send(&tsoRequest{}) }()
+	re.Len(d.stop(), maxMergeTSORequests)
+	re.False(<-sent)
+	// the late requests are rejected.
+	re.False(d.send(&tsoRequest{}))
+	re.Empty(d.stop())
+}
+
+func TestDispatchTSORequestToStoppedDispatcher(t *testing.T) {
+	re := require.New(t)
+	s := &GrpcServer{Server: &Server{}}
+	d := newTSORequestDispatcher()
+	d.stop()
+	s.tsoDispatcher.Store("host", d)
+	// the request fails instead of being dropped.
+	request := &tsoRequest{forwardedHost: "host", errCh: make(chan error, 1)}
+	s.dispatchTSORequest(request)
+	re.Error(<-request.errCh)
+}
@@ -68,3 +68,31 @@ func MinSupportedVersion(v Feature) *semver.Version {
 	version := MustParseVersion(target)
 	return version
 }
+
+// componentIncompatibleVersions records the scheduling components, the
+// scheduler types or the checker types, which do not support the cluster
+// version greater than or equal to the registered version.
+var componentIncompatibleVersions = make(map[string]*semver.Version)
+
+// RegisterIncompatibleVersion declares that the scheduling component does not
+// support the cluster version since the given version. It should be called in
+// init() func of a package.
+func RegisterIncompatibleVersion(component string, version string) {
+	if _, ok := componentIncompatibleVersions[component]; ok {
+		log.Fatal("duplicated incompatible version of the component", zap.String("component", component))
+	}
+	componentIncompatibleVersions[component] = MustParseVersion(version)
+}
+
+// IsComponentSupported returns whether the scheduling component supports the
+// cluster version. The components which are not registered support all versions.
+func IsComponentSupported(clusterVersion *semver.Version, component string) bool {
+	version, ok := componentIncompatibleVersions[component]
+	return !ok || clusterVersion.LessThan(*version)
+}
+
+// IncompatibleVersion returns the cluster version since which the scheduling
+// component is not supported, and nil if it supports all versions.
+func IncompatibleVersion(component string) *semver.Version {
+	return componentIncompatibleVersions[component]
+}
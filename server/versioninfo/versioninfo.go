@@ -67,6 +67,18 @@ func IsCompatible(a, b semver.Version) bool {
 	return a.Major == b.Major && a.Minor == b.Minor
 }
 
+// MinorVersionDistance returns the number of minor versions between the version a and b.
+// It returns false if their major versions are different.
+func MinorVersionDistance(a, b semver.Version) (uint64, bool) {
+	if a.Major != b.Major {
+		return 0, false
+	}
+	if a.Minor > b.Minor {
+		return uint64(a.Minor - b.Minor), true
+	}
+	return uint64(b.Minor - a.Minor), true
+}
+
 // IsFeatureSupported checks if the feature is supported by current cluster.
 func IsFeatureSupported(clusterVersion *semver.Version, f Feature) bool {
 	minSupportVersion := *MinSupportedVersion(f)
@@ -54,6 +54,8 @@ func (c *serverConfig) Generate(opts ...ConfigOption) (*config.Config, error) {
 		"--advertise-client-urls=" + c.AdvertiseClientURLs,
 		"--peer-urls=" + c.PeerURLs,
 		"--advertise-peer-urls=" + c.AdvertisePeerURLs,
+		// The stores in tests are usually not reachable.
+		"--disable-pre-campaign-check",
 	}
 	if c.Join {
 		arguments = append(arguments, "--join="+c.ClusterConfig.GetJoinAddr())
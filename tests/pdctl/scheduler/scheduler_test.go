@@ -292,6 +292,8 @@ func TestScheduler(t *testing.T) {
 		"write-peer-priorities":      []interface{}{"byte", "key"},
 		"strict-picking-store":       "true",
 		"enable-for-tiflash":         "true",
+		"zone-label":                 "",
+		"cross-zone-penalty":         0.1,
 	}
 	var conf map[string]interface{}
 	mustExec([]string{"-u", pdAddr, "scheduler", "config", "balance-hot-region-scheduler", "list"}, &conf)
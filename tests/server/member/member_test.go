@@ -20,6 +20,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"net"
 	"net/http"
 	"sync"
 	"testing"
@@ -30,6 +31,7 @@ import (
 	"github.com/pingcap/kvprotov2/pkg/pdpb"
 	"github.com/stretchr/testify/require"
 	"github.com/tikv/pd/pkg/assertutil"
+	"github.com/tikv/pd/pkg/errs"
 	"github.com/tikv/pd/pkg/etcdutil"
 	"github.com/tikv/pd/pkg/testutil"
 	"github.com/tikv/pd/server"
@@ -248,6 +250,37 @@ func TestLeaderResignWithBlock(t *testing.T) {
 	re.NoError(failpoint.Disable("github.com/tikv/pd/server/raftclusterIsBusy"))
 }
 
+func TestPreCampaignCheck(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster, err := tests.NewTestCluster(ctx, 1)
+	defer cluster.Destroy()
+	re.NoError(err)
+
+	err = cluster.RunInitialServers()
+	re.NoError(err)
+	member := cluster.GetServer(cluster.WaitLeader()).GetServer().GetMember()
+
+	listen := func() net.Listener {
+		l, err := net.Listen("tcp", "127.0.0.1:0")
+		re.NoError(err)
+		return l
+	}
+	reachable1, reachable2, unreachable := listen(), listen(), listen()
+	defer reachable1.Close()
+	defer reachable2.Close()
+	re.NoError(unreachable.Close())
+
+	re.NoError(member.PreCampaignCheck(ctx, time.Second, nil))
+	re.NoError(member.PreCampaignCheck(ctx, time.Second, []string{reachable1.Addr().String(), reachable2.Addr().String(), unreachable.Addr().String()}))
+	err = member.PreCampaignCheck(ctx, time.Second, []string{reachable1.Addr().String(), unreachable.Addr().String()})
+	re.True(errs.ErrPreCampaignCheck.Equal(err))
+	// the etcd commit is always slower than 1ns.
+	err = member.PreCampaignCheck(ctx, time.Nanosecond, nil)
+	re.True(errs.ErrPreCampaignCheck.Equal(err))
+}
+
 func waitLeaderChange(re *require.Assertions, cluster *tests.TestCluster, old string) string {
 	var leader string
 	testutil.Eventually(re, func() bool {
@@ -0,0 +1,170 @@
+// Copyright 2022 TiKV Project Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package regionquery_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/pingcap/kvprotov2/pkg/metapb"
+	"github.com/pingcap/kvprotov2/pkg/pdpb"
+	"github.com/stretchr/testify/require"
+	"github.com/tikv/pd/pkg/regionquerypb"
+	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/server/core"
+	"github.com/tikv/pd/tests"
+	"go.uber.org/goleak"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestMain(m *testing.M) {
+	goleak.VerifyTestMain(m, testutil.LeakOptions...)
+}
+
+func TestRegionQuery(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster, err := tests.NewTestCluster(ctx, 1)
+	re.NoError(err)
+	defer cluster.Destroy()
+	re.NoError(cluster.RunInitialServers())
+	cluster.WaitLeader()
+
+	leaderServer := cluster.GetServer(cluster.GetLeader())
+	re.NoError(leaderServer.BootstrapCluster())
+	clusterID := leaderServer.GetClusterID()
+	for i := 0; i < 10; i++ {
+		peer := &metapb.Peer{Id: uint64(100 + i), StoreId: 1}
+		region := core.NewRegionInfo(&metapb.Region{
+			Id:          uint64(10 + i),
+			StartKey:    []byte(fmt.Sprintf("k%02d", i)),
+			EndKey:      []byte(fmt.Sprintf("k%02d", i+1)),
+			Peers:       []*metapb.Peer{peer},
+			RegionEpoch: &metapb.RegionEpoch{ConfVer: 1, Version: 1},
+		}, peer)
+		re.NoError(cluster.HandleRegionHeartbeat(region))
+	}
+
+	conn, err := grpc.Dial(strings.TrimPrefix(leaderServer.GetAddr(), "http://"), grpc.WithInsecure())
+	re.NoError(err)
+	defer conn.Close()
+	client := regionquerypb.NewRegionQueryClient(conn)
+	newRequest := func() *regionquerypb.RegionQueryRequest {
+		return &regionquerypb.RegionQueryRequest{Header: testutil.NewRequestHeader(clusterID)}
+	}
+
+	// scan the regions in batches.
+	req := newRequest()
+	req.Key, req.EndKey, req.BatchSize = []byte("k00"), []byte("k10"), 3
+	stream, err := client.ScanRegions(ctx, req)
+	re.NoError(err)
+	resps, err := recvAll(stream)
+	re.NoError(err)
+	re.Len(resps, 4)
+	var regions []*pdpb.Region
+	for _, resp := range resps {
+		re.Nil(resp.GetHeader().GetError())
+		regions = append(regions, resp.GetRegions()...)
+	}
+	re.Len(regions, 10)
+	for i, region := range regions {
+		re.Equal(uint64(10+i), region.GetRegion().GetId())
+		re.Equal(uint64(100+i), region.GetLeader().GetId())
+	}
+
+	// only the selected fields are returned.
+	req.Limit, req.FieldMask = 2, []string{"region.id"}
+	stream, err = client.ScanRegions(ctx, req)
+	re.NoError(err)
+	resps, err = recvAll(stream)
+	re.NoError(err)
+	re.Len(resps, 1)
+	re.Len(resps[0].GetRegions(), 2)
+	for _, region := range resps[0].GetRegions() {
+		re.NotZero(region.GetRegion().GetId())
+		re.Empty(region.GetRegion().GetStartKey())
+		re.Empty(region.GetRegion().GetPeers())
+		re.Nil(region.GetLeader())
+	}
+
+	req = newRequest()
+	req.FieldMask = []string{"unknown"}
+	stream, err = client.ScanRegions(ctx, req)
+	re.NoError(err)
+	_, err = recvAll(stream)
+	re.Equal(codes.InvalidArgument, status.Code(err))
+
+	// get the region by key.
+	req = newRequest()
+	req.Key = []byte("k05")
+	stream, err = client.GetRegionByKey(ctx, req)
+	re.NoError(err)
+	resps, err = recvAll(stream)
+	re.NoError(err)
+	re.Len(resps, 1)
+	re.Len(resps[0].GetRegions(), 1)
+	re.Equal(uint64(15), resps[0].GetRegions()[0].GetRegion().GetId())
+
+	// get the regions of the store.
+	req = newRequest()
+	req.StoreId = 1
+	stream, err = client.GetStoreRegions(ctx, req)
+	re.NoError(err)
+	resps, err = recvAll(stream)
+	re.NoError(err)
+	re.Len(resps[0].GetRegions(), 10)
+
+	req.StoreId = 100
+	stream, err = client.GetStoreRegions(ctx, req)
+	re.NoError(err)
+	_, err = recvAll(stream)
+	re.Equal(codes.NotFound, status.Code(err))
+
+	// get the regions by the check type.
+	req = newRequest()
+	req.CheckType = "miss-peer"
+	stream, err = client.GetCheckRegions(ctx, req)
+	re.NoError(err)
+	_, err = recvAll(stream)
+	re.NoError(err)
+
+	req.CheckType = "unknown"
+	stream, err = client.GetCheckRegions(ctx, req)
+	re.NoError(err)
+	_, err = recvAll(stream)
+	re.Equal(codes.InvalidArgument, status.Code(err))
+}
+
+func recvAll(stream interface {
+	Recv() (*regionquerypb.RegionQueryResponse, error)
+}) ([]*regionquerypb.RegionQueryResponse, error) {
+	var resps []*regionquerypb.RegionQueryResponse
+	for {
+		resp, err := stream.Recv()
+		if err == io.EOF {
+			return resps, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		resps = append(resps, resp)
+	}
+}
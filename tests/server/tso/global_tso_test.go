@@ -28,6 +28,7 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/tikv/pd/pkg/grpcutil"
 	"github.com/tikv/pd/pkg/testutil"
+	"github.com/tikv/pd/pkg/tsoutil"
 	"github.com/tikv/pd/server/tso"
 	"github.com/tikv/pd/tests"
 )
@@ -185,3 +186,50 @@ func TestDelaySyncTimestamp(t *testing.T) {
 	re.NotNil(checkAndReturnTimestampResponse(re, req, resp))
 	re.NoError(failpoint.Disable("github.com/tikv/pd/server/tso/delaySyncTimestamp"))
 }
+
+func TestFollowerProxy(t *testing.T) {
+	re := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cluster, err := tests.NewTestCluster(ctx, 2)
+	re.NoError(err)
+	defer cluster.Destroy()
+
+	re.NoError(cluster.RunInitialServers())
+	cluster.WaitLeader()
+
+	var followerServer *tests.TestServer
+	for _, s := range cluster.GetServers() {
+		if s.GetConfig().Name != cluster.GetLeader() {
+			followerServer = s
+		}
+	}
+	re.NotNil(followerServer)
+	opt := followerServer.GetServer().GetPersistOptions()
+	cfg := opt.GetPDServerConfig().Clone()
+	cfg.EnableTSOFollowerProxy = true
+	opt.SetPDServerConfig(cfg)
+
+	grpcPDClient := testutil.MustNewGrpcClient(re, followerServer.GetAddr())
+	req := &pdpb.TsoRequest{
+		Header:     testutil.NewRequestHeader(followerServer.GetClusterID()),
+		Count:      tsoCount,
+		DcLocation: tso.GlobalDCLocation,
+	}
+	var wg sync.WaitGroup
+	wg.Add(tsoRequestConcurrencyNumber)
+	for i := 0; i < tsoRequestConcurrencyNumber; i++ {
+		go func() {
+			defer wg.Done()
+			var last *pdpb.Timestamp
+			for j := 0; j < tsoRequestRound; j++ {
+				ts := testGetTimestamp(re, ctx, grpcPDClient, req)
+				if last != nil {
+					re.Less(tsoutil.CompareTimestamp(last, ts), 0)
+				}
+				last = ts
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -20,6 +20,7 @@ package tools
 import (
 	_ "github.com/AlekSi/gocov-xml"
 	_ "github.com/axw/gocov/gocov"
+	_ "github.com/gogo/protobuf/protoc-gen-gogofaster"
 	_ "github.com/mgechev/revive"
 	_ "github.com/pingcap/errors/errdoc-gen"
 	_ "github.com/pingcap/failpoint/failpoint-ctl"
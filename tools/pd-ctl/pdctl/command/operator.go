@@ -129,6 +129,7 @@ func NewAddOperatorCommand() *cobra.Command {
 	c.AddCommand(NewTransferPeerCommand())
 	c.AddCommand(NewAddPeerCommand())
 	c.AddCommand(NewAddLearnerCommand())
+	c.AddCommand(NewDemoteVoterCommand())
 	c.AddCommand(NewRemovePeerCommand())
 	c.AddCommand(NewMergeRegionCommand())
 	c.AddCommand(NewSplitRegionCommand())
@@ -290,6 +291,35 @@ func addLearnerCommandFunc(cmd *cobra.Command, args []string) {
 	postJSON(cmd, operatorsPrefix, input)
 }
 
+// NewDemoteVoterCommand returns a command to demote a region voter to learner.
+func NewDemoteVoterCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "demote-voter <region_id> <store_id>",
+		Short: "demote a region voter on specified store to learner",
+		Run:   demoteVoterCommandFunc,
+	}
+	return c
+}
+
+func demoteVoterCommandFunc(cmd *cobra.Command, args []string) {
+	if len(args) != 2 {
+		cmd.Println(cmd.UsageString())
+		return
+	}
+
+	ids, err := parseUint64s(args)
+	if err != nil {
+		cmd.Println(err)
+		return
+	}
+
+	input := make(map[string]interface{})
+	input["name"] = cmd.Name()
+	input["region_id"] = ids[0]
+	input["store_id"] = ids[1]
+	postJSON(cmd, operatorsPrefix, input)
+}
+
 // NewMergeRegionCommand returns a command to merge two regions.
 func NewMergeRegionCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -143,10 +143,12 @@ func NewAddSchedulerCommand() *cobra.Command {
 	c.AddCommand(NewScatterRangeSchedulerCommand())
 	c.AddCommand(NewBalanceLeaderSchedulerCommand())
 	c.AddCommand(NewBalanceRegionSchedulerCommand())
+	c.AddCommand(NewBalanceLearnerSchedulerCommand())
 	c.AddCommand(NewBalanceHotRegionSchedulerCommand())
 	c.AddCommand(NewRandomMergeSchedulerCommand())
 	c.AddCommand(NewLabelSchedulerCommand())
 	c.AddCommand(NewEvictSlowStoreSchedulerCommand())
+	c.AddCommand(NewEvictSlowTrendSchedulerCommand())
 	c.AddCommand(NewGrantHotRegionSchedulerCommand())
 	c.AddCommand(NewSplitBucketSchedulerCommand())
 	return c
@@ -290,6 +292,16 @@ func NewEvictSlowStoreSchedulerCommand() *cobra.Command {
 	return c
 }
 
+// NewEvictSlowTrendSchedulerCommand returns a command to add a evict-slow-trend-scheduler.
+func NewEvictSlowTrendSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "evict-slow-trend-scheduler",
+		Short: "add a scheduler to detect the stores trending slow and evict their leaders",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
 // NewBalanceRegionSchedulerCommand returns a command to add a balance-region-scheduler.
 func NewBalanceRegionSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{
@@ -300,6 +312,16 @@ func NewBalanceRegionSchedulerCommand() *cobra.Command {
 	return c
 }
 
+// NewBalanceLearnerSchedulerCommand returns a command to add a balance-learner-scheduler.
+func NewBalanceLearnerSchedulerCommand() *cobra.Command {
+	c := &cobra.Command{
+		Use:   "balance-learner-scheduler",
+		Short: "add a scheduler to balance learners between stores with the same engine",
+		Run:   addSchedulerCommandFunc,
+	}
+	return c
+}
+
 // NewBalanceHotRegionSchedulerCommand returns a command to add a balance-hot-region-scheduler.
 func NewBalanceHotRegionSchedulerCommand() *cobra.Command {
 	c := &cobra.Command{